@@ -0,0 +1,642 @@
+// Package app wires the ledger service together: database, handlers,
+// background workers, router and HTTP server. main only builds a Config and
+// calls Run, so tests and other binaries can embed the same service.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"ledger-service/cache"
+	"ledger-service/config"
+	"ledger-service/cron"
+	"ledger-service/dbfailover"
+	"ledger-service/dbtrace"
+	"ledger-service/devdb"
+	"ledger-service/errreport"
+	"ledger-service/fraud"
+	"ledger-service/fx"
+	"ledger-service/handlers"
+	"ledger-service/ingest"
+	"ledger-service/jobqueue"
+	"ledger-service/metrics"
+	"ledger-service/middleware"
+	"ledger-service/money"
+	"ledger-service/notify"
+	"ledger-service/pii"
+	"ledger-service/policy"
+	"ledger-service/redact"
+	"ledger-service/store"
+	"ledger-service/tlsconfig"
+	"ledger-service/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// App is a configured service ready to Run
+type App struct {
+	cfg        Config
+	serverConf config.Server
+	port       string
+	// readOnly serves only the public reads and runs no background writers
+	readOnly bool
+
+	pool        *pgxpool.Pool
+	idempotency middleware.IdempotencyStore
+	scheduler   *cron.Scheduler
+	queue       *jobqueue.Worker
+	reporter    *errreport.Client
+	handler     http.Handler
+	server      *http.Server
+	redirect    *http.Server
+
+	// closers release what New acquired, in reverse order
+	closers []func()
+}
+
+// New connects to the database and configures the handlers and router.
+// Close releases what it acquired when Run is not called.
+func New(cfg Config) (app *App, err error) {
+	// Settings may name secrets in Vault or AWS Secrets Manager instead of
+	// holding them
+	cfg = cfg.withSecrets()
+	a := &App{cfg: cfg, port: cfg.envString("PORT", "8080")}
+	defer func() {
+		// A secret that could not be read looks unset to the code that asked
+		// for it, so report why it was missing instead
+		if serr := cfg.secrets.Err(); serr != nil {
+			app, err = nil, serr
+		}
+		if err != nil {
+			a.Close()
+		}
+	}()
+
+	// Mask personal data and secrets in logs, error reports and error
+	// responses, with REDACT_FIELDS overriding the per-field policies
+	policies, err := redact.ParsePolicies(cfg.getenv("REDACT_FIELDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDACT_FIELDS: %w", err)
+	}
+	redactor := redact.New(policies)
+	redact.SetDefault(redactor)
+	log.SetOutput(redactor.Writer(os.Stderr))
+	gin.DefaultWriter = redactor.Writer(os.Stdout)
+	gin.DefaultErrorWriter = redactor.Writer(os.Stderr)
+
+	appEnv := cfg.envString("APP_ENV", "production")
+	a.serverConf, err = config.ServerFromEnv(cfg.getenv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP server configuration: %w", err)
+	}
+
+	handlers.InitPostingHooks(cfg.PostingHooks)
+	handlers.InitMaxAmount(cfg.envFloat("MAX_AMOUNT", 0))
+	// Refuse writes while MAINTENANCE_MODE is set or an operator switches maintenance on
+	handlers.InitMaintenance(cfg.envBool("MAINTENANCE_MODE", false),
+		time.Duration(cfg.envInt("MAINTENANCE_RETRY_AFTER_SECONDS", 300))*time.Second)
+	// A read-only replica serves the public reads to less-trusted networks
+	if a.readOnly, err = cfg.readOnly(); err != nil {
+		return nil, err
+	}
+	if a.readOnly && cfg.Memory {
+		return nil, fmt.Errorf("API_MODE=read-only cannot be used with the in-memory store")
+	}
+	if cfg.Memory {
+		if appEnv == "production" {
+			return nil, fmt.Errorf("the in-memory store cannot be used when APP_ENV is production")
+		}
+		handlers.InitStore(store.NewMemory())
+		log.Println("Serving customers and transactions from memory; data is lost on exit")
+	} else if err := a.initDatabase(appEnv); err != nil {
+		return nil, err
+	}
+
+	// Accept signed notifications from the providers in INGEST_SOURCES
+	sources, err := ingest.ParseSources(cfg.getenv("INGEST_SOURCES"), cfg.getenv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid INGEST_SOURCES: %w", err)
+	}
+	handlers.InitIngestSources(sources)
+	if len(sources) > 0 {
+		log.Printf("Accepting notifications from %d sources", len(sources))
+	}
+
+	// Sign balance certificates with a key file or KMS key when configured
+	signer, err := cfg.certificateSigner()
+	if err != nil {
+		return nil, err
+	}
+	handlers.InitBalanceCertificates(signer, cfg.envString("BALANCE_CERTIFICATE_ISSUER", "ledger-service"),
+		time.Duration(cfg.envInt("BALANCE_CERTIFICATE_VALIDITY_DAYS", 30))*24*time.Hour)
+	if signer != nil {
+		log.Printf("Signing balance certificates with %s key %s", signer.Algorithm(), signer.KeyID())
+	}
+
+	// Encrypt customers' personal data with the keys in PII_ENCRYPTION_KEYS
+	piiKeys, err := pii.ParseKeyring(cfg.getenv("PII_ENCRYPTION_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PII_ENCRYPTION_KEYS: %w", err)
+	}
+	handlers.InitPII(piiKeys)
+	if piiKeys != nil {
+		log.Printf("Encrypting personal data with key %s", piiKeys.ActiveKeyID())
+	}
+
+	// Report panics and server errors when an error reporting DSN is configured
+	if dsn := cfg.getenv("SENTRY_DSN"); dsn != "" {
+		a.reporter, err = errreport.New(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SENTRY_DSN: %w", err)
+		}
+		a.reporter.Environment = cfg.getenv("SENTRY_ENVIRONMENT")
+		a.reporter.Release = cfg.getenv("SENTRY_RELEASE")
+		log.Println("Error reporting enabled")
+	}
+
+	router, err := NewRouter(cfg, RouterDeps{Pool: a.pool, Idempotency: a.idempotency, Reporter: a.reporter})
+	if err != nil {
+		return nil, err
+	}
+	a.handler = router
+
+	// Create HTTP server with timeouts
+	a.server = &http.Server{
+		Addr:              ":" + a.port,
+		Handler:           router,
+		ReadTimeout:       a.serverConf.ReadTimeout,
+		ReadHeaderTimeout: a.serverConf.ReadHeaderTimeout,
+		WriteTimeout:      a.serverConf.WriteTimeout,
+		IdleTimeout:       a.serverConf.IdleTimeout,
+		MaxHeaderBytes:    a.serverConf.MaxHeaderBytes,
+	}
+
+	// Serve TLS natively when a certificate or autocert hosts are configured
+	tlsConf := tlsconfig.Config{
+		CertFile:         cfg.getenv("TLS_CERT_FILE"),
+		KeyFile:          cfg.getenv("TLS_KEY_FILE"),
+		AutocertHosts:    cfg.envList("TLS_AUTOCERT_HOSTS"),
+		AutocertCacheDir: cfg.getenv("TLS_AUTOCERT_CACHE_DIR"),
+		AutocertEmail:    cfg.getenv("TLS_AUTOCERT_EMAIL"),
+	}
+	if tlsConf.Enabled() {
+		tlsCfg, manager, err := tlsConf.Load()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		a.server.TLSConfig = tlsCfg
+
+		// Redirect plain HTTP to HTTPS; ACME challenges need this listener on port 80
+		redirectPort := cfg.getenv("HTTP_REDIRECT_PORT")
+		if redirectPort == "" && manager != nil {
+			redirectPort = "80"
+		}
+		if redirectPort != "" {
+			var redirect http.Handler = tlsconfig.RedirectHandler(a.port)
+			if manager != nil {
+				redirect = manager.HTTPHandler(redirect)
+			}
+			a.redirect = &http.Server{
+				Addr:         ":" + redirectPort,
+				Handler:      redirect,
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+			}
+		}
+	}
+	return a, nil
+}
+
+// initDatabase connects to Postgres and configures every handler that
+// depends on it
+func (a *App) initDatabase(appEnv string) error {
+	cfg := a.cfg
+	dbURL := cfg.getenv("DATABASE_URL")
+	switch backend := cfg.envString("DB_BACKEND", "postgres"); backend {
+	case "postgres":
+		if dbURL == "" {
+			return fmt.Errorf("DATABASE_URL environment variable is required")
+		}
+	case "embedded":
+		// Run a private Postgres so contributors and demos need no database server
+		if appEnv == "production" {
+			return fmt.Errorf("DB_BACKEND=embedded cannot be used when APP_ENV is production")
+		}
+		server, err := devdb.Start(context.Background(), devdb.Config{
+			Dir:  cfg.envString("EMBEDDED_DB_DIR", ".ledger-dev/postgres"),
+			Port: uint32(cfg.envInt("EMBEDDED_DB_PORT", 5433)),
+		}, cfg.Schema)
+		if err != nil {
+			return fmt.Errorf("unable to start embedded database: %w", err)
+		}
+		a.onClose(func() { server.Stop() })
+		dbURL = server.URL
+	default:
+		return fmt.Errorf("invalid DB_BACKEND %q (want postgres or embedded)", backend)
+	}
+	dbConf, err := config.DatabaseFromEnv(cfg.getenv)
+	if err != nil {
+		return fmt.Errorf("invalid database configuration: %w", err)
+	}
+
+	// Initialize database connection pool (shared by request handlers and background workers)
+	poolConf, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return fmt.Errorf("invalid DATABASE_URL: %w", err)
+	}
+	poolConf.ConnConfig.RuntimeParams["application_name"] = "ledger-service"
+	dbConf.Apply(poolConf.ConnConfig)
+	// Postgres itself refuses writes from a read-only replica's sessions
+	if a.readOnly {
+		poolConf.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+	// New connections log in with the credentials of a rotated DATABASE_URL
+	// secret, while the ones already open finish their lifetime
+	var login atomic.Pointer[pgx.ConnConfig]
+	cfg.watchSecret("DATABASE_URL", func(url string) {
+		conf, err := pgx.ParseConfig(url)
+		if err != nil {
+			log.Printf("Ignoring rotated DATABASE_URL: %v", err)
+			return
+		}
+		login.Store(conf)
+		log.Println("DATABASE_URL was rotated; new connections use its credentials")
+	})
+	poolConf.BeforeConnect = func(ctx context.Context, c *pgx.ConnConfig) error {
+		if conf := login.Load(); conf != nil {
+			c.User, c.Password = conf.User, conf.Password
+		}
+		return nil
+	}
+	// Follow a Postgres failover to the next candidate without restarting
+	if _, err := dbfailover.Configure(poolConf, cfg.envList("DATABASE_FAILOVER_URLS"),
+		time.Duration(cfg.envInt("DB_FAILOVER_BACKOFF_MS", 100))*time.Millisecond,
+		time.Duration(cfg.envInt("DB_FAILOVER_MAX_BACKOFF_SECONDS", 10))*time.Second); err != nil {
+		return fmt.Errorf("invalid DATABASE_FAILOVER_URLS: %w", err)
+	}
+	if dbConf.TraceQueries || dbConf.SlowQueryThreshold > 0 {
+		poolConf.ConnConfig.Tracer = dbtrace.New(metrics.Default, dbConf.SlowQueryThreshold, dbConf.TraceQueries)
+	}
+	conn, err := pgxpool.NewWithConfig(context.Background(), poolConf)
+	if err != nil {
+		return fmt.Errorf("unable to connect to database: %w", err)
+	}
+	a.onClose(conn.Close)
+	a.pool = conn
+
+	// Verify database connection
+	if err := conn.Ping(context.Background()); err != nil {
+		return fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	// Initialize handlers with database connection
+	handlers.InitDB(conn)
+
+	// Enable SMS alerts when a provider is configured
+	if sid := cfg.getenv("TWILIO_ACCOUNT_SID"); sid != "" {
+		provider := notify.NewTwilioProvider(sid, cfg.getenv("TWILIO_AUTH_TOKEN"), cfg.getenv("TWILIO_FROM_NUMBER"))
+		if baseURL := cfg.getenv("TWILIO_BASE_URL"); baseURL != "" {
+			provider.BaseURL = baseURL
+		}
+		limiter := notify.NewRateLimiter(cfg.envInt("SMS_RATE_LIMIT_PER_HOUR", 5), time.Hour)
+		handlers.InitNotifier(notify.NewNotifier(provider, limiter,
+			cfg.envFloat("SMS_HIGH_VALUE_THRESHOLD", 1000),
+			cfg.envFloat("SMS_LOW_BALANCE_THRESHOLD", 100)))
+		log.Println("SMS notifications enabled")
+	}
+
+	// Enable currency conversion when exchange rate providers are
+	// configured, falling back down the list when one fails or is stale
+	rounding, err := money.ParsePolicy(cfg.getenv("FX_ROUNDING"), cfg.getenv("ROUNDING_BY_CURRENCY"))
+	if err != nil {
+		return fmt.Errorf("invalid FX_ROUNDING or ROUNDING_BY_CURRENCY: %w", err)
+	}
+	sources, err := cfg.fxSources()
+	if err != nil {
+		return err
+	}
+	var rates fx.Provider
+	if len(sources) > 0 {
+		rates = fx.NewChain(sources, time.Duration(cfg.envInt("FX_MAX_RATE_AGE_SECONDS", 0))*time.Second, metrics.Default)
+		// Absorb bursts of quotes for the same pair
+		ttl, err := cfg.microCacheTTL()
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			rates = fx.Cached(rates, ttl)
+		}
+	}
+	handlers.InitFX(rates, rounding, time.Duration(cfg.envInt("FX_QUOTE_TTL_SECONDS", 30))*time.Second)
+
+	// Limit unverified customers until KYC is complete
+	handlers.InitKYCLimits(handlers.KYCLimits{
+		MaxTransaction: cfg.envFloat("KYC_UNVERIFIED_MAX_TRANSACTION", 0),
+		DailyLimit:     cfg.envFloat("KYC_UNVERIFIED_DAILY_LIMIT", 0),
+	})
+
+	// Coordinate postings with actions in other services
+	actions, err := cfg.sagaActions()
+	if err != nil {
+		return err
+	}
+	// Pay withdrawals out through Stripe when configured
+	stripeClient, err := cfg.stripeClient()
+	if err != nil {
+		return err
+	}
+	if stripeClient != nil {
+		if _, dup := actions[handlers.StripePayoutAction]; dup {
+			return fmt.Errorf("invalid SAGA_ACTIONS: %s is taken by Stripe", handlers.StripePayoutAction)
+		}
+		actions[handlers.StripePayoutAction] = handlers.StripePayout{}
+		handlers.InitStripe(stripeClient, cfg.getenv("STRIPE_WEBHOOK_SECRET"))
+		cfg.watchSecret("STRIPE_WEBHOOK_SECRET", handlers.SetStripeWebhookSecret)
+		log.Println("Withdrawals enabled through Stripe")
+	}
+	handlers.InitSagas(actions)
+	if len(actions) > 0 {
+		log.Printf("Sagas can run %d actions", len(actions))
+	}
+
+	// Sync linked bank accounts through Plaid when configured
+	plaidClient, err := cfg.plaidClient()
+	if err != nil {
+		return err
+	}
+	if plaidClient != nil {
+		webhookURL := cfg.getenv("PLAID_WEBHOOK_URL")
+		if webhookURL != "" {
+			if err := webhook.ValidateURL(webhookURL); err != nil {
+				return fmt.Errorf("invalid PLAID_WEBHOOK_URL: %w", err)
+			}
+		}
+		handlers.InitBankFeed(plaidClient, webhookURL)
+		log.Println("Bank links enabled through Plaid")
+	}
+
+	// Identify accounts in OFX exports and number MT940 statements
+	yearlyNumbers := false
+	switch numbering := cfg.envString("MT940_NUMBERING", "continuous"); numbering {
+	case "continuous":
+	case "yearly":
+		yearlyNumbers = true
+	default:
+		return fmt.Errorf("invalid MT940_NUMBERING %q (want continuous or yearly)", numbering)
+	}
+	handlers.InitStatements(cfg.envString("STATEMENT_BANK_ID", "LEDGER"), yearlyNumbers)
+
+	// Send credit transfers to the bank in pain.001 files when configured
+	debtor, err := cfg.paymentDebtor()
+	if err != nil {
+		return err
+	}
+	handlers.InitPaymentFiles(debtor)
+	if debtor != nil {
+		log.Println("Credit transfers enabled through pain.001 payment files")
+	}
+
+	// Sign and deliver webhook events
+	handlers.InitWebhooks(webhook.NewSender(time.Duration(cfg.envInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second))
+
+	// Cache balances in Redis for read-heavy clients when enabled
+	switch kind := cfg.envString("BALANCE_CACHE", "none"); kind {
+	case "none":
+	case "redis":
+		redis, err := cfg.newRedis()
+		if err != nil {
+			return fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		a.onClose(func() { redis.Close() })
+		handlers.InitBalanceCache(cache.NewBalances(redis,
+			cfg.envString("BALANCE_CACHE_PREFIX", "ledger:balance:"),
+			time.Duration(cfg.envInt("BALANCE_CACHE_TTL_SECONDS", 60))*time.Second))
+		log.Println("Caching balances in Redis")
+	default:
+		return fmt.Errorf("invalid BALANCE_CACHE %q (want none or redis)", kind)
+	}
+
+	// Share idempotency keys between replicas so a retry that lands elsewhere still dedupes
+	idempotencyTTL := time.Duration(cfg.envInt("IDEMPOTENCY_KEY_TTL_HOURS", 24)) * time.Hour
+	switch kind := cfg.envString("IDEMPOTENCY_STORE", "postgres"); kind {
+	case "none":
+	case "postgres":
+		a.idempotency = handlers.NewIdempotencyKeys(idempotencyTTL)
+	case "redis":
+		redis, err := cfg.newRedis()
+		if err != nil {
+			return fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		a.onClose(func() { redis.Close() })
+		a.idempotency = cache.NewIdempotencyKeys(redis,
+			cfg.envString("IDEMPOTENCY_KEY_PREFIX", "ledger:idempotency:"), idempotencyTTL)
+		log.Println("Storing idempotency keys in Redis")
+	default:
+		return fmt.Errorf("invalid IDEMPOTENCY_STORE %q (want postgres, redis or none)", kind)
+	}
+
+	// Publish outbox events to a message bus when one is configured
+	publisher, err := cfg.newEventPublisher(cfg.envString("EVENT_PUBLISHER", "none"))
+	if err != nil {
+		return fmt.Errorf("invalid EVENT_PUBLISHER: %w", err)
+	}
+	if publisher != nil {
+		a.onClose(func() { publisher.Close() })
+		handlers.InitEventPublisher(publisher)
+	}
+
+	// Apply account type rules (savings debit limits, escrow approvals)
+	handlers.InitAccountPolicies(policy.Default(cfg.envInt("SAVINGS_MONTHLY_DEBIT_LIMIT", 6)))
+
+	// Load fraud rules evaluated on every transaction
+	handlers.InitFraudEngine(fraud.NewEngine())
+	if err := handlers.LoadFraudRules(context.Background()); err != nil {
+		log.Printf("Failed to load fraud rules: %v", err)
+	}
+
+	// Load transaction types registered beyond the builtin set
+	if err := handlers.LoadTransactionTypes(context.Background()); err != nil {
+		log.Printf("Failed to load transaction types: %v", err)
+	}
+
+	handlers.InitStandingOrders(cfg.envInt("STANDING_ORDER_MAX_RETRIES", 3))
+
+	// Released aliases stay held for their previous owner for
+	// ALIAS_HOLD_DAYS so nobody else can take them over straight away
+	handlers.InitAliases(time.Duration(cfg.envInt("ALIAS_HOLD_DAYS", 30)) * 24 * time.Hour)
+
+	// Flag accounts without activity as dormant when DORMANCY_DAYS is set
+	handlers.InitDormancy(handlers.DormancyConfig{
+		Days:         cfg.envInt("DORMANCY_DAYS", 0),
+		Fee:          cfg.envFloat("DORMANCY_FEE", 0),
+		FreezeDebits: cfg.envBool("DORMANCY_FREEZE_DEBITS", false),
+	})
+
+	return a.initJobs()
+}
+
+// initJobs registers the batch jobs with the scheduler and sets up the job
+// queue worker. Each batch job's schedule comes from <PREFIX>_SCHEDULE, or
+// runs every <PREFIX>_INTERVAL_SECONDS.
+func (a *App) initJobs() error {
+	cfg := a.cfg
+	// A run waits up to JOB_LOCK_TIMEOUT_SECONDS for another instance's run
+	// of the same job to finish, and is skipped if it does not
+	locker := handlers.NewJobLocker(time.Duration(cfg.envInt("JOB_LOCK_TIMEOUT_SECONDS", 0))*time.Second, metrics.Default)
+	a.scheduler = cron.NewScheduler(locker,
+		time.Duration(cfg.envInt("JOB_JITTER_SECONDS", 0))*time.Second, metrics.Default)
+	handlers.InitScheduler(a.scheduler)
+
+	type job struct {
+		name, prefix string
+		interval     int
+		run          func(ctx context.Context, now time.Time) (int, error)
+	}
+	jobs := []job{
+		{"standing-orders", "STANDING_ORDER", 300, handlers.ProcessStandingOrders},
+		{"loan-repayments", "LOAN_REPAYMENT", 300, handlers.ProcessLoanRepayments},
+		{"payment-link-expiry", "PAYMENT_LINK_SWEEP", 60, func(ctx context.Context, _ time.Time) (int, error) {
+			n, err := handlers.ExpirePaymentLinks(ctx)
+			return int(n), err
+		}},
+		{"dormancy", "DORMANCY", 3600, handlers.ProcessDormantAccounts},
+		{"ledger-anchor", "LEDGER_ANCHOR", 3600, handlers.AnchorLedger},
+	}
+	if cfg.getenv("PII_ENCRYPTION_KEYS") != "" {
+		jobs = append(jobs, job{"pii-rewrap", "PII_REWRAP", 3600, handlers.RewrapPII})
+	}
+	if cfg.getenv("PLAID_CLIENT_ID") != "" {
+		jobs = append(jobs, job{"bank-sync", "BANK_SYNC", 3600, handlers.SyncBankLinks})
+	}
+	if cfg.getenv("STRIPE_SECRET_KEY") != "" {
+		jobs = append(jobs, job{"stripe-reconcile", "STRIPE_RECONCILE", 3600, handlers.RefreshStripePayouts})
+	}
+	if _, ok := a.idempotency.(*handlers.IdempotencyKeys); ok {
+		jobs = append(jobs, job{"idempotency-key-sweep", "IDEMPOTENCY_SWEEP", 3600, func(ctx context.Context, _ time.Time) (int, error) {
+			n, err := handlers.PurgeIdempotencyKeys(ctx)
+			return int(n), err
+		}})
+	}
+
+	for _, j := range jobs {
+		spec, schedule, err := cfg.jobSchedule(j.prefix, j.interval)
+		if err != nil {
+			return err
+		}
+		if err := a.scheduler.Register(cron.Job{Name: j.name, Spec: spec, Schedule: schedule, Run: j.run}); err != nil {
+			return err
+		}
+	}
+
+	// Work queued in the background, such as webhook deliveries and
+	// statements asked for with Prefer: respond-async, is retried with
+	// backoff from JOB_QUEUE_RETRY_BASE_SECONDS up to
+	// JOB_QUEUE_RETRY_MAX_SECONDS
+	concurrency, err := cfg.queueConcurrency()
+	if err != nil {
+		return err
+	}
+	a.queue = jobqueue.NewWorker(handlers.NewJobQueue(), handlers.JobQueueKinds(concurrency), metrics.Default)
+	a.queue.BaseDelay = time.Duration(cfg.envInt("JOB_QUEUE_RETRY_BASE_SECONDS", 10)) * time.Second
+	a.queue.MaxDelay = time.Duration(cfg.envInt("JOB_QUEUE_RETRY_MAX_SECONDS", 3600)) * time.Second
+	return nil
+}
+
+// Handler is the service's router, for serving it some other way
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Run serves requests and runs the background workers until ctx is
+// cancelled, then shuts down gracefully and releases everything New acquired
+func (a *App) Run(ctx context.Context) error {
+	defer a.Close()
+
+	// Relay outbox events, replay webhooks, work through the job queue and
+	// pick up the maintenance switch in the background, and run the
+	// scheduled jobs: standing orders, loan installments, payment link
+	// expiry, dormancy, ledger anchoring, bank syncs, Stripe payout
+	// refreshes and the idempotency key sweep. A read-only replica leaves
+	// everything that writes to the full deployment.
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	// Pick up rotated database credentials, FX API key and Stripe webhook
+	// secret from the secrets manager
+	if interval := a.cfg.envInt("SECRETS_REFRESH_SECONDS", 0); interval > 0 {
+		go a.cfg.secrets.Run(workerCtx, time.Duration(interval)*time.Second)
+	}
+	if a.pool != nil {
+		cfg := a.cfg
+		go handlers.RunMaintenanceRefresher(workerCtx, time.Duration(cfg.envInt("MAINTENANCE_REFRESH_SECONDS", 5))*time.Second)
+		if !a.readOnly {
+			go handlers.RunOutboxRelay(workerCtx, time.Duration(cfg.envInt("OUTBOX_RELAY_INTERVAL_SECONDS", 2))*time.Second)
+			go handlers.RunWebhookReplays(workerCtx, time.Duration(cfg.envInt("WEBHOOK_REPLAY_INTERVAL_SECONDS", 5))*time.Second)
+			go a.queue.Run(workerCtx, time.Duration(cfg.envInt("JOB_QUEUE_POLL_MS", 1000))*time.Millisecond)
+			a.scheduler.Start(workerCtx)
+		}
+	}
+
+	serveErr := make(chan error, 2)
+	if a.redirect != nil {
+		go func() {
+			log.Printf("Redirecting HTTP on port %s to HTTPS\n", a.redirect.Addr[1:])
+			if err := a.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("redirect listen: %w", err)
+			}
+		}()
+	}
+	go func() {
+		var err error
+		if a.server.TLSConfig != nil {
+			log.Printf("Server starting with TLS on port %s\n", a.port)
+			err = a.server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server starting on port %s\n", a.port)
+			err = a.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			serveErr <- fmt.Errorf("listen: %w", err)
+		}
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+	}
+	log.Println("Shutting down server...")
+	stopWorkers()
+
+	// Create a deadline for server shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.serverConf.ShutdownGracePeriod)
+	defer cancel()
+	if err := a.server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	if a.redirect != nil {
+		a.redirect.Shutdown(shutdownCtx)
+	}
+	if a.reporter != nil {
+		a.reporter.Flush(2 * time.Second)
+	}
+	return err
+}
+
+// Close releases the database pool, clients and embedded database
+func (a *App) Close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+func (a *App) onClose(f func()) {
+	a.closers = append(a.closers, f)
+}
@@ -0,0 +1,373 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ledger-service/iso20022"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestNewInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a, err := New(Config{Getenv: env(map[string]string{"APP_ENV": "development"}), Memory: true})
+	require.NoError(t, err)
+	defer a.Close()
+
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"store":"memory"`)
+
+	req := httptest.NewRequest("POST", "/v1/customers", bytes.NewBufferString(`{"name":"Jane Doe","initial_balance":10}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Routes needing Postgres are not registered
+	w = httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/trial-balance", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestNewWithHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var exported []float64
+	a, err := New(Config{
+		Getenv: env(map[string]string{"APP_ENV": "development"}),
+		Memory: true,
+		PostingHooks: ledger.Hooks{
+			PrePosting: []ledger.PrePostingHook{func(_ context.Context, p ledger.Posting, _ store.Customer) error {
+				if p.Amount > 1000 {
+					return &ledger.ViolationError{Message: "Amount needs manual review"}
+				}
+				return nil
+			}},
+			PostPosting: []ledger.PostPostingHook{func(_ context.Context, p ledger.Posting, _ ledger.Result) error {
+				exported = append(exported, p.Amount)
+				return nil
+			}},
+		},
+		ResponseHooks: []middleware.ResponseHook{func(c *gin.Context, _ int, body []byte) ([]byte, error) {
+			c.Header("X-Hooked", "true")
+			return body, nil
+		}},
+	})
+	require.NoError(t, err)
+	defer a.Close()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		a.Handler().ServeHTTP(w, req)
+		return w
+	}
+	w := post("/v1/customers", `{"name":"Jane Doe","initial_balance":10}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Hooked"))
+	id := jsonString(t, w.Body.Bytes(), "customer_id")
+
+	w = post("/v1/transactions", `{"customer_id":"`+id+`","type":"credit","amount":5000}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = post("/v1/transactions", `{"customer_id":"`+id+`","type":"credit","amount":25}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []float64{25}, exported)
+}
+
+func TestNewRefusesDevelopmentFeaturesInProduction(t *testing.T) {
+	_, err := New(Config{Getenv: env(map[string]string{}), Memory: true})
+	assert.Error(t, err, "APP_ENV defaults to production")
+
+	_, err = New(Config{Getenv: env(map[string]string{"DB_BACKEND": "embedded"})})
+	assert.Error(t, err)
+}
+
+func TestNewRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := NewRouter(Config{Getenv: env(map[string]string{"ADMIN_API_KEY": "secret"})}, RouterDeps{})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/trial-balance", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/transaction-types", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Deprecation"), "legacy paths are marked deprecated")
+
+	_, err = NewRouter(Config{Getenv: env(map[string]string{"LEGACY_API_SUNSET": "soon"})}, RouterDeps{})
+	assert.Error(t, err)
+	_, err = NewRouter(Config{Getenv: env(map[string]string{"FAULT_INJECTION_RULES": "GET /v1/*:error=500"})}, RouterDeps{})
+	assert.Error(t, err, "fault injection is refused in production")
+	_, err = NewRouter(Config{Getenv: env(map[string]string{"OIDC_ISSUER": "https://sso.example.com"})}, RouterDeps{})
+	assert.Error(t, err, "OIDC_AUDIENCE is required")
+	_, err = NewRouter(Config{Getenv: env(map[string]string{
+		"OIDC_ISSUER": "https://sso.example.com", "OIDC_AUDIENCE": "ledger", "OIDC_GROUP_ROLES": "ledger-admins=owner",
+	})}, RouterDeps{})
+	assert.Error(t, err, "unknown roles are refused")
+}
+
+func TestRouterSurfaces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serve := func(router *gin.Engine, method, path string, headers map[string]string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The customer surface takes its own key; the admin key does not open it
+	router, err := NewRouter(Config{Getenv: env(map[string]string{"CUSTOMER_API_KEY": "client", "ADMIN_API_KEY": "secret"})}, RouterDeps{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, serve(router, "POST", "/v1/customers", nil))
+	assert.Equal(t, http.StatusUnauthorized, serve(router, "POST", "/v1/customers", map[string]string{"X-Admin-Key": "secret"}))
+	assert.Equal(t, http.StatusUnauthorized, serve(router, "GET", "/v1/admin/trial-balance", map[string]string{"X-API-Key": "client"}))
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/v1/transaction-types", nil), "public reads need no key")
+	assert.Equal(t, http.StatusUnauthorized, serve(router, "GET", "/v1/admin/transactions?reference=INV-1234", nil), "reference lookup spans customers")
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/transactions?reference=INV-1234", nil))
+
+	// A read-only replica serves the public reads and nothing else
+	router, err = NewRouter(Config{Getenv: env(map[string]string{"API_MODE": "read-only", "ADMIN_API_KEY": "secret"})}, RouterDeps{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/v1/transaction-types", nil))
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/transaction-types", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(router, "POST", "/v1/customers", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(router, "POST", "/v1/admin/adjustments", map[string]string{"X-Admin-Key": "secret"}))
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/admin/trial-balance", map[string]string{"X-Admin-Key": "secret"}))
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/customers/42", nil))
+
+	// Callers that keep guessing keys are locked out
+	router, err = NewRouter(Config{Getenv: env(map[string]string{"ADMIN_API_KEY": "secret", "AUTH_LOCKOUT_THRESHOLD": "3"})}, RouterDeps{})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, serve(router, "GET", "/v1/admin/trial-balance", map[string]string{"X-Admin-Key": "guess"}))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(router, "GET", "/v1/admin/trial-balance", map[string]string{"X-Admin-Key": "secret"}))
+	// and a forwarding header sent by the client does not change its address
+	router, err = NewRouter(Config{Getenv: env(map[string]string{"ADMIN_API_KEY": "secret", "AUTH_LOCKOUT_THRESHOLD": "3"})}, RouterDeps{})
+	require.NoError(t, err)
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		assert.Equal(t, http.StatusUnauthorized, serve(router, "GET", "/v1/admin/trial-balance", map[string]string{"X-Admin-Key": "guess-" + ip, "X-Forwarded-For": ip}))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(router, "GET", "/v1/admin/trial-balance", map[string]string{"X-Admin-Key": "secret", "X-Forwarded-For": "192.0.2.4"}))
+	_, err = NewRouter(Config{Getenv: env(map[string]string{"TRUSTED_PROXIES": "not-an-address"})}, RouterDeps{})
+	assert.Error(t, err)
+
+	_, err = NewRouter(Config{Getenv: env(map[string]string{"API_MODE": "replica"})}, RouterDeps{})
+	assert.Error(t, err)
+	_, err = New(Config{Getenv: env(map[string]string{"APP_ENV": "development", "API_MODE": "read-only"}), Memory: true})
+	assert.Error(t, err, "a read-only replica needs the database")
+}
+
+// jsonString reads a string field from a JSON object
+func jsonString(t *testing.T, body []byte, field string) string {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &m))
+	v, _ := m[field].(string)
+	return v
+}
+
+func TestFXSources(t *testing.T) {
+	sources, err := Config{Getenv: env(map[string]string{"FX_API_KEY": "key"})}.fxSources()
+	assert.NoError(t, err)
+	assert.Len(t, sources, 1)
+	assert.Equal(t, "exchangerate-api", sources[0].Name)
+
+	sources, err = Config{Getenv: env(map[string]string{
+		"FX_PROVIDERS": "frankfurter, exchangerate-api",
+		"FX_API_KEY":   "key",
+	})}.fxSources()
+	assert.NoError(t, err)
+	assert.Equal(t, "frankfurter", sources[0].Name)
+	assert.Equal(t, "exchangerate-api", sources[1].Name)
+
+	sources, err = Config{Getenv: env(map[string]string{})}.fxSources()
+	assert.NoError(t, err)
+	assert.Empty(t, sources)
+
+	_, err = Config{Getenv: env(map[string]string{"FX_PROVIDERS": "exchangerate-api"})}.fxSources()
+	assert.ErrorContains(t, err, "FX_API_KEY")
+	_, err = Config{Getenv: env(map[string]string{"FX_PROVIDERS": "oanda"})}.fxSources()
+	assert.ErrorContains(t, err, "unknown exchange rate provider")
+}
+
+func TestSagaActions(t *testing.T) {
+	actions, err := Config{Getenv: env(map[string]string{
+		"SAGA_ACTIONS": "payout=https://payouts.example.com/saga, card=https://cards.example.com/saga",
+	})}.sagaActions()
+	assert.NoError(t, err)
+	assert.Len(t, actions, 2)
+	assert.Contains(t, actions, "card")
+
+	actions, err = Config{Getenv: env(map[string]string{})}.sagaActions()
+	assert.NoError(t, err)
+	assert.Empty(t, actions)
+
+	_, err = Config{Getenv: env(map[string]string{"SAGA_ACTIONS": "payout"})}.sagaActions()
+	assert.ErrorContains(t, err, "want name=url")
+	_, err = Config{Getenv: env(map[string]string{"SAGA_ACTIONS": "ledger=https://example.com"})}.sagaActions()
+	assert.ErrorContains(t, err, "reserved")
+	_, err = Config{Getenv: env(map[string]string{"SAGA_ACTIONS": "payout=ftp://example.com"})}.sagaActions()
+	assert.ErrorContains(t, err, "http or https")
+}
+
+func TestQueueConcurrency(t *testing.T) {
+	concurrency, err := Config{Getenv: env(map[string]string{
+		"JOB_QUEUE_CONCURRENCY": "webhook.delivery=8, notification.sms=1",
+	})}.queueConcurrency()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"webhook.delivery": 8, "notification.sms": 1}, concurrency)
+
+	_, err = Config{Getenv: env(map[string]string{"JOB_QUEUE_CONCURRENCY": "webhook.delivery"})}.queueConcurrency()
+	assert.ErrorContains(t, err, "want kind=n")
+	_, err = Config{Getenv: env(map[string]string{"JOB_QUEUE_CONCURRENCY": "email=2"})}.queueConcurrency()
+	assert.ErrorContains(t, err, "unknown job kind")
+	_, err = Config{Getenv: env(map[string]string{"JOB_QUEUE_CONCURRENCY": "import.csv=0"})}.queueConcurrency()
+	assert.ErrorContains(t, err, "positive number")
+}
+
+func TestNewRejectsInvalidIngestSources(t *testing.T) {
+	_, err := New(Config{Getenv: env(map[string]string{
+		"APP_ENV":        "development",
+		"INGEST_SOURCES": `[{"name": "psp", "secret_env": "PSP_SECRET", "rules": []}]`,
+	}), Memory: true})
+	assert.ErrorContains(t, err, "invalid INGEST_SOURCES")
+}
+
+func TestPlaidClient(t *testing.T) {
+	client, err := Config{Getenv: env(map[string]string{})}.plaidClient()
+	assert.NoError(t, err)
+	assert.Nil(t, client)
+
+	client, err = Config{Getenv: env(map[string]string{
+		"PLAID_CLIENT_ID": "client", "PLAID_SECRET": "secret", "PLAID_ENV": "production", "PLAID_COUNTRY_CODES": "US,CA",
+	})}.plaidClient()
+	require.NoError(t, err)
+	assert.Equal(t, "https://production.plaid.com", client.BaseURL)
+	assert.Equal(t, []string{"US", "CA"}, client.CountryCodes)
+
+	_, err = Config{Getenv: env(map[string]string{"PLAID_CLIENT_ID": "client"})}.plaidClient()
+	assert.ErrorContains(t, err, "PLAID_SECRET")
+	_, err = Config{Getenv: env(map[string]string{"PLAID_CLIENT_ID": "client", "PLAID_SECRET": "secret", "PLAID_ENV": "staging"})}.plaidClient()
+	assert.ErrorContains(t, err, "invalid PLAID_ENV")
+}
+
+func TestCertificateSigner(t *testing.T) {
+	signer, err := Config{Getenv: env(map[string]string{})}.certificateSigner()
+	assert.NoError(t, err)
+	assert.Nil(t, signer)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	// Escaped line breaks are accepted, as environment variables carry them
+	signer, err = Config{Getenv: env(map[string]string{
+		"BALANCE_CERTIFICATE_KEY": strings.ReplaceAll(pemKey, "\n", `\n`),
+	})}.certificateSigner()
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", signer.Algorithm())
+
+	_, err = Config{Getenv: env(map[string]string{"BALANCE_CERTIFICATE_KEY": "not a key"})}.certificateSigner()
+	assert.ErrorContains(t, err, "invalid BALANCE_CERTIFICATE_KEY")
+	_, err = Config{Getenv: env(map[string]string{
+		"BALANCE_CERTIFICATE_KEY": pemKey, "BALANCE_CERTIFICATE_KMS_KEY_ID": "alias/balance-certificates",
+	})}.certificateSigner()
+	assert.ErrorContains(t, err, "set only one of")
+}
+
+func TestStripeClient(t *testing.T) {
+	client, err := Config{Getenv: env(map[string]string{})}.stripeClient()
+	assert.NoError(t, err)
+	assert.Nil(t, client)
+
+	client, err = Config{Getenv: env(map[string]string{
+		"STRIPE_SECRET_KEY": "sk_test_1", "STRIPE_WEBHOOK_SECRET": "whsec_1", "STRIPE_BASE_URL": "http://localhost:12111",
+	})}.stripeClient()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:12111", client.BaseURL)
+	assert.Equal(t, "sk_test_1", client.SecretKey)
+
+	_, err = Config{Getenv: env(map[string]string{"STRIPE_SECRET_KEY": "sk_test_1"})}.stripeClient()
+	assert.ErrorContains(t, err, "STRIPE_WEBHOOK_SECRET")
+}
+
+func TestPaymentDebtor(t *testing.T) {
+	debtor, err := Config{Getenv: env(map[string]string{})}.paymentDebtor()
+	assert.NoError(t, err)
+	assert.Nil(t, debtor)
+
+	debtor, err = Config{Getenv: env(map[string]string{
+		"PAIN_DEBTOR_IBAN": "de89 3704 0044 0532 0130 00", "PAIN_DEBTOR_NAME": "Ledger Service Ltd", "PAIN_DEBTOR_BIC": "cobadeffxxx",
+	})}.paymentDebtor()
+	require.NoError(t, err)
+	assert.Equal(t, iso20022.Party{Name: "Ledger Service Ltd", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"}, *debtor)
+
+	_, err = Config{Getenv: env(map[string]string{"PAIN_DEBTOR_IBAN": "DE89370400440532013000"})}.paymentDebtor()
+	assert.ErrorContains(t, err, "PAIN_DEBTOR_NAME")
+	_, err = Config{Getenv: env(map[string]string{"PAIN_DEBTOR_IBAN": "DE89370400440532013001", "PAIN_DEBTOR_NAME": "x"})}.paymentDebtor()
+	assert.ErrorContains(t, err, "PAIN_DEBTOR_IBAN")
+	_, err = Config{Getenv: env(map[string]string{"PAIN_DEBTOR_IBAN": "DE89370400440532013000", "PAIN_DEBTOR_NAME": "x", "PAIN_DEBTOR_BIC": "XX"})}.paymentDebtor()
+	assert.ErrorContains(t, err, "PAIN_DEBTOR_BIC")
+}
+
+func TestNewResolvesSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/ledger" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"admin_key":"from-vault"},"metadata":{"version":1}}}`))
+	}))
+	defer vault.Close()
+
+	settings := map[string]string{
+		"APP_ENV":       "development",
+		"VAULT_ADDR":    vault.URL,
+		"VAULT_TOKEN":   "s.token",
+		"ADMIN_API_KEY": "vault:secret/data/ledger#admin_key",
+	}
+	a, err := New(Config{Getenv: env(settings), Memory: true})
+	require.NoError(t, err)
+	defer a.Close()
+	assert.Equal(t, "from-vault", a.cfg.getenv("ADMIN_API_KEY"))
+
+	// A secret that cannot be read fails startup with the reason
+	settings["ADMIN_API_KEY"] = "vault:secret/data/missing#admin_key"
+	_, err = New(Config{Getenv: env(settings), Memory: true})
+	assert.ErrorContains(t, err, "unable to read ADMIN_API_KEY from the secrets manager")
+	settings["ADMIN_API_KEY"] = "aws-sm:ledger#admin_key"
+	_, err = New(Config{Getenv: env(settings), Memory: true})
+	assert.ErrorContains(t, err, "aws-sm secrets are not configured")
+}
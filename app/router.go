@@ -0,0 +1,255 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ledger-service/dbhealth"
+	"ledger-service/docs"
+	"ledger-service/errreport"
+	"ledger-service/handlers"
+	"ledger-service/metrics"
+	"ledger-service/middleware"
+	"ledger-service/openapi"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// RouterDeps are the services the router uses besides the handlers' own
+type RouterDeps struct {
+	// Pool backs the health checks and pool metrics; nil with the in-memory store
+	Pool *pgxpool.Pool
+	// Idempotency deduplicates write requests when set
+	Idempotency middleware.IdempotencyStore
+	// Reporter receives panics and server errors when set
+	Reporter *errreport.Client
+}
+
+// NewRouter builds the router with every route and middleware. With
+// cfg.Memory only the routes the in-memory store can serve are registered,
+// and with API_MODE=read-only only the public reads.
+func NewRouter(cfg Config, deps RouterDeps) (*gin.Engine, error) {
+	appEnv := cfg.envString("APP_ENV", "production")
+	readOnly, err := cfg.readOnly()
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize Gin router
+	router := gin.New()
+	// Forwarding headers name the client only when they come from a proxy in
+	// TRUSTED_PROXIES; otherwise the connection's address is the client's
+	if err := router.SetTrustedProxies(cfg.envList("TRUSTED_PROXIES")); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	// Assign a request ID first so logs, error reports and DB sessions can be correlated
+	router.Use(middleware.RequestID(), middleware.Logger(), gin.Recovery())
+
+	// A read-only replica refuses every write, whatever the path
+	if readOnly {
+		router.Use(middleware.ReadOnly())
+	}
+
+	// Inject latency, errors and dropped DB connections for resilience testing
+	if spec := cfg.getenv("FAULT_INJECTION_RULES"); spec != "" {
+		if appEnv == "production" {
+			return nil, fmt.Errorf("FAULT_INJECTION_RULES cannot be used when APP_ENV is production")
+		}
+		rules, err := middleware.ParseFaultRules(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FAULT_INJECTION_RULES: %w", err)
+		}
+		handlers.InitFaultInjection()
+		router.Use(middleware.FaultInjection(rules, nil))
+		log.Printf("Fault injection enabled with %d rules\n", len(rules))
+	}
+
+	// Report panics and server errors when an error reporting DSN is configured
+	if deps.Reporter != nil {
+		router.Use(middleware.ReportErrors(deps.Reporter))
+	}
+
+	// Add CORS middleware
+	router.Use(cors.Default())
+
+	// Compress large responses such as transaction history (COMPRESSION_LEVEL=0 disables)
+	if level := cfg.envInt("COMPRESSION_LEVEL", 5); level > 0 {
+		router.Use(middleware.Gzip(middleware.CompressionConfig{
+			Level:        level,
+			MinSize:      cfg.envInt("COMPRESSION_MIN_SIZE_BYTES", 1024),
+			ContentTypes: cfg.envList("COMPRESSION_CONTENT_TYPES"),
+		}))
+	}
+
+	// Root path handler (for Railway healthcheck) and health check endpoint;
+	// ?verbose=true adds pool and replication statistics. Aggressive pollers
+	// are answered from a sub-second cache when MICRO_CACHE_TTL_MS is set.
+	microCacheTTL, err := cfg.microCacheTTL()
+	if err != nil {
+		return nil, err
+	}
+	health := healthHandler(deps.Pool)
+	microCache := middleware.MicroCache(microCacheTTL)
+	router.GET("/", microCache, health)
+	router.GET("/health", microCache, health)
+
+	// Prometheus metrics such as database query durations, pool usage and replication lag
+	if deps.Pool != nil {
+		dbhealth.Register(metrics.Default, deps.Pool, 2*time.Second)
+	}
+	router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+
+	// Versioned API; write requests are size-limited, strictly decoded and
+	// deduplicated by Idempotency-Key, and every request gets a deadline
+	// Operators authenticate with the shared admin key or, when OIDC_ISSUER is
+	// set, with an access token from the corporate SSO provider
+	sso, err := cfg.newOperatorVerifier()
+	if err != nil {
+		return nil, err
+	}
+	// The customer surface takes CUSTOMER_API_KEY when one is set; the public
+	// reads stay open
+	auth := surfaceAuth{
+		customer: middleware.ClientAuth(cfg.getenv("CUSTOMER_API_KEY")),
+		admin:    middleware.OperatorAuth(cfg.getenv("ADMIN_API_KEY"), sso),
+	}
+	var apiMiddleware []gin.HandlerFunc
+	// Response hooks run outermost so they see exactly what the client would
+	if len(cfg.ResponseHooks) > 0 {
+		apiMiddleware = append(apiMiddleware, middleware.PreResponse(cfg.ResponseHooks...))
+	}
+	// Callers that keep failing authentication wait longer and longer, then
+	// are locked out; AUTH_LOCKOUT_THRESHOLD=0 turns this off
+	if threshold := cfg.envInt("AUTH_LOCKOUT_THRESHOLD", 20); threshold > 0 {
+		lockout := middleware.LockoutConfig{
+			FreeAttempts: cfg.envInt("AUTH_FAILURE_FREE_ATTEMPTS", 5),
+			BaseDelay:    time.Second,
+			MaxDelay:     time.Duration(cfg.envInt("AUTH_FAILURE_MAX_DELAY_SECONDS", 60)) * time.Second,
+			Threshold:    threshold,
+			Duration:     time.Duration(cfg.envInt("AUTH_LOCKOUT_SECONDS", 900)) * time.Second,
+			// A provider notification with a bad signature is not a login
+			Exempt: signedNotificationRoutes,
+		}
+		// Lockouts become security.lockout events where the outbox can be written
+		if deps.Pool != nil && !readOnly {
+			lockout.OnLockout = handlers.RecordLockout
+		}
+		apiMiddleware = append(apiMiddleware, middleware.BruteForceGuard(lockout))
+	}
+	// Self-service customer tokens may only read their own customer's data
+	if !cfg.Memory {
+		apiMiddleware = append(apiMiddleware, middleware.CustomerAuth(handlers.LookupCustomerToken, handlers.CustomerTokenRoutes...))
+		// Scoped API keys open only the routes their scopes cover, on any surface
+		apiMiddleware = append(apiMiddleware, middleware.ScopedKeyAuth(handlers.LookupAPIKey, handlers.RouteScope))
+	}
+	// Writes answer 503 in maintenance mode, except the switch itself and
+	// the bulk balance read
+	apiMiddleware = append(apiMiddleware, middleware.Maintenance(handlers.CurrentMaintenance, handlers.MaintenanceRoute, handlers.BalanceQueryRoute))
+	apiMiddleware = append(apiMiddleware, middleware.StrictJSON(int64(cfg.envInt("MAX_REQUEST_BODY_BYTES", 64*1024))))
+	if deps.Idempotency != nil {
+		apiMiddleware = append(apiMiddleware, middleware.Idempotency(deps.Idempotency))
+	}
+	// The deadline runs last so idempotency never records a response it rewrites
+	apiMiddleware = append(apiMiddleware, middleware.Deadline(middleware.DeadlineConfig{
+		Read:  time.Duration(cfg.envInt("REQUEST_TIMEOUT_READ_SECONDS", 10)) * time.Second,
+		Write: time.Duration(cfg.envInt("REQUEST_TIMEOUT_WRITE_SECONDS", 5)) * time.Second,
+		Routes: map[string]time.Duration{
+			"/admin/export": time.Duration(cfg.envInt("REQUEST_TIMEOUT_EXPORT_SECONDS", 10)) * time.Second,
+		},
+	}))
+	// Balances and transaction lists carry Cache-Control and an ETag so
+	// clients can revalidate instead of downloading them again
+	caching := readCaching{
+		balance:      middleware.CacheControl(cfg.cacheControl("CACHE_CONTROL_BALANCE")),
+		transactions: middleware.CacheControl(cfg.cacheControl("CACHE_CONTROL_TRANSACTIONS")),
+	}
+	v1 := router.Group("/v1", apiMiddleware...)
+	if cfg.Memory {
+		registerMemoryRoutes(v1, auth.customer, caching)
+		return router, nil
+	}
+	register := func(r *gin.RouterGroup) { registerV1Routes(r, auth, caching) }
+	if readOnly {
+		register = func(r *gin.RouterGroup) { registerReadRoutes(r, caching) }
+		log.Println("Serving the read-only API surface")
+	}
+	register(v1)
+
+	// Development helpers such as demo data seeding are never exposed in production
+	if appEnv == "development" && !readOnly {
+		v1.POST("/dev/seed", handlers.SeedDemoData)
+		log.Println("Development endpoints enabled")
+	}
+
+	// Legacy unversioned paths stay available as deprecated aliases of /v1
+	sunset, err := time.Parse("2006-01-02", cfg.envString("LEGACY_API_SUNSET", "2027-06-30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LEGACY_API_SUNSET: %w", err)
+	}
+	legacy := router.Group("", middleware.Deprecated(sunset, "/v1"))
+	legacy.Use(apiMiddleware...)
+	register(legacy)
+
+	// Swagger documentation
+	url := ginSwagger.URL("/swagger/doc.json") // The url pointing to API definition
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
+
+	// OpenAPI 3.1 document derived from the same annotations
+	openAPISpec, err := openapi.Convert([]byte(docs.SwaggerInfo.ReadDoc()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build OpenAPI document: %w", err)
+	}
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", openAPISpec)
+	})
+	return router, nil
+}
+
+// healthHandler reports whether the database answers; without a pool the
+// service runs from memory and is always healthy
+func healthHandler(pool *pgxpool.Pool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := gin.H{
+			"status":  "healthy",
+			"service": "ledger-service",
+			"version": "1.0",
+		}
+		if handlers.CurrentMaintenance().Enabled {
+			body["maintenance"] = true
+		}
+		if pool == nil {
+			body["store"] = "memory"
+			c.JSON(http.StatusOK, body)
+			return
+		}
+		// Check database connection
+		if err := pool.Ping(context.Background()); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "unhealthy",
+				"error":   "database connection failed",
+				"service": "ledger-service",
+				"version": "1.0",
+			})
+			return
+		}
+		if verbose, _ := strconv.ParseBool(c.Query("verbose")); verbose && c.FullPath() == "/health" {
+			body["pool"] = dbhealth.Pool(pool)
+			replicas, err := dbhealth.Replicas(c.Request.Context(), pool)
+			if err != nil {
+				body["replication_error"] = "failed to read replication status"
+			} else {
+				body["replicas"] = replicas
+			}
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
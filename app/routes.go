@@ -0,0 +1,243 @@
+package app
+
+import (
+	"ledger-service/handlers"
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readCaching holds the Cache-Control middleware of the reads clients poll
+type readCaching struct {
+	balance      gin.HandlerFunc
+	transactions gin.HandlerFunc
+}
+
+// surfaceAuth holds the guards of the customer and admin surfaces
+type surfaceAuth struct {
+	customer gin.HandlerFunc
+	admin    gin.HandlerFunc
+}
+
+// registerV1Routes wires the version 1 API onto r. A later version gets its
+// own register function so it can map the same paths to different handlers.
+// The API is split into surfaces, each behind its own guard: public reads,
+// the customer surface, and the admin surface under /admin. Provider
+// callbacks and payment links carry credentials of their own.
+func registerV1Routes(r *gin.RouterGroup, auth surfaceAuth, caching readCaching) {
+	registerReadRoutes(r, caching)
+	registerSignedRoutes(r)
+	registerCustomerRoutes(r.Group("", auth.customer))
+	registerAdminRoutes(r.Group("/admin", auth.admin))
+}
+
+// registerReadRoutes wires the public read surface: balances, transaction
+// history with its proofs and anchors, balance certificates and reference
+// data. It is all a read-only deployment serves.
+func registerReadRoutes(r *gin.RouterGroup, caching readCaching) {
+	r = r.Group("", middleware.ReadOnly())
+	r.GET("/customers/:customer_id/balance", caching.balance, handlers.GetBalance)
+	r.GET("/customers/:customer_id/balance/certificate", handlers.GetBalanceCertificate)
+	r.GET("/balance-certificates/keys", handlers.GetCertificateKeys)
+	r.GET("/customers/:customer_id/transactions", caching.transactions, handlers.GetTransactions)
+	r.GET("/customers/:customer_id/transactions/:transaction_id", handlers.GetTransaction)
+	r.GET("/customers/:customer_id/transactions/:transaction_id/proof", handlers.GetTransactionProof)
+	r.GET("/ledger/anchors", handlers.ListLedgerAnchors)
+	r.GET("/ledger/anchors/:sequence", handlers.GetLedgerAnchor)
+	r.GET("/customers/:customer_id/pending", handlers.GetPendingSummary)
+	r.GET("/customers/:customer_id/stats", handlers.GetCustomerStats)
+	r.GET("/transaction-types", handlers.ListTransactionTypes)
+	r.GET("/customers/:customer_id/sub-accounts/:sub_account_id/transactions", caching.transactions, handlers.GetSubAccountTransactions)
+	r.GET("/fx/rates", handlers.GetFXRates)
+}
+
+// signedNotificationRoutes are the signed routes providers post to
+var signedNotificationRoutes = []string{"/ingest/:source", "/plaid/webhook", "/stripe/webhook"}
+
+// registerSignedRoutes wires the routes whose requests authenticate
+// themselves: signed provider notifications, payment links, whose token is
+// the credential, and scoped API key introspection
+func registerSignedRoutes(r *gin.RouterGroup) {
+	r.POST("/ingest/:source", handlers.IngestNotification)
+	r.GET("/payment-links/:token", handlers.GetPaymentLink)
+	r.POST("/payment-links/:token/pay", handlers.PayPaymentLink)
+	r.POST("/plaid/webhook", handlers.PlaidWebhook)
+	r.POST("/stripe/webhook", handlers.StripeWebhook)
+	r.GET(handlers.APIKeyIntrospectionRoute, handlers.GetCurrentAPIKey)
+}
+
+// registerCustomerRoutes wires the customer surface, where customers and
+// their personal data are managed and money is moved
+func registerCustomerRoutes(r *gin.RouterGroup) {
+	r.POST("/customers", handlers.CreateCustomer)
+	r.GET("/customers/:customer_id", handlers.GetCustomer)
+	r.PATCH("/customers/:customer_id", handlers.UpdateCustomer)
+	r.POST("/customers/:customer_id/addresses", handlers.CreateAddress)
+	r.PUT("/customers/:customer_id/addresses/:address_id", handlers.UpdateAddress)
+	r.DELETE("/customers/:customer_id/addresses/:address_id", handlers.DeleteAddress)
+	r.POST("/transactions", handlers.CreateTransaction)
+	r.GET("/transactions/:transaction_id", handlers.GetTransactionSubmission)
+	r.POST(handlers.BalanceQueryRoute, handlers.QueryBalances)
+	r.POST("/transfers/split", handlers.CreateSplitTransfer)
+	r.POST("/transfers/reservations", handlers.CreateReservation)
+	r.GET("/transfers/reservations/:transfer_id", handlers.GetReservation)
+	r.POST("/transfers/reservations/:transfer_id/settle", handlers.SettleReservation)
+	r.POST("/transfers/reservations/:transfer_id/release", handlers.ReleaseReservation)
+	r.POST("/sagas", handlers.CreateSaga)
+	r.GET("/sagas/:saga_id", handlers.GetSaga)
+	r.GET("/customers/:customer_id/notifications", handlers.GetNotificationPreferences)
+	r.PUT("/customers/:customer_id/notifications", handlers.UpdateNotificationPreferences)
+	r.GET("/customers/:customer_id/kyc", handlers.GetKYCProfile)
+	r.POST("/customers/:customer_id/kyc/documents", handlers.SubmitKYCDocument)
+	r.POST("/customers/:customer_id/sub-accounts", handlers.CreateSubAccount)
+	r.GET("/customers/:customer_id/sub-accounts", handlers.ListSubAccounts)
+	r.POST("/customers/:customer_id/moves", handlers.MoveFunds)
+	r.POST("/fx/quotes", handlers.CreateFXQuote)
+	r.POST("/customers/:customer_id/standing-orders", handlers.CreateStandingOrder)
+	r.GET("/customers/:customer_id/standing-orders", handlers.ListStandingOrders)
+	r.POST("/customers/:customer_id/standing-orders/:standing_order_id/pause", handlers.PauseStandingOrder)
+	r.POST("/customers/:customer_id/standing-orders/:standing_order_id/resume", handlers.ResumeStandingOrder)
+	r.DELETE("/customers/:customer_id/standing-orders/:standing_order_id", handlers.CancelStandingOrder)
+	r.POST("/customers/:customer_id/mandates", handlers.CreateMandate)
+	r.GET("/customers/:customer_id/mandates", handlers.ListMandates)
+	r.GET("/customers/:customer_id/mandates/:mandate_id", handlers.GetMandate)
+	r.PUT("/customers/:customer_id/mandates/:mandate_id", handlers.UpdateMandate)
+	r.DELETE("/customers/:customer_id/mandates/:mandate_id", handlers.RevokeMandate)
+	r.POST("/pull-payments", handlers.CreatePullPayment)
+	r.POST("/payment-requests", handlers.CreatePaymentRequest)
+	r.GET("/customers/:customer_id/payment-requests", handlers.ListPaymentRequests)
+	r.POST("/payment-requests/:payment_request_id/accept", handlers.AcceptPaymentRequest)
+	r.POST("/payment-requests/:payment_request_id/decline", handlers.DeclinePaymentRequest)
+	r.POST("/customers/:customer_id/payment-links", handlers.CreatePaymentLink)
+	r.GET("/accounts/by-alias/:alias", handlers.GetAccountByAlias)
+	r.PUT("/customers/:customer_id/alias", handlers.SetAlias)
+	r.DELETE("/customers/:customer_id/alias", handlers.DeleteAlias)
+	r.GET("/customers/:customer_id/alias/history", handlers.GetAliasHistory)
+	r.GET("/customers/:customer_id/loans", handlers.ListLoans)
+	r.GET("/customers/:customer_id/loans/:loan_id", handlers.GetLoan)
+	r.GET("/customers/:customer_id/loans/:loan_id/schedule", handlers.GetLoanSchedule)
+	r.POST("/customers/:customer_id/loans/:loan_id/repay", handlers.RepayLoan)
+	r.POST("/customers/:customer_id/bank-links/link-token", handlers.CreateBankLinkToken)
+	r.POST("/customers/:customer_id/bank-links", handlers.CreateBankLink)
+	r.GET("/customers/:customer_id/bank-links", handlers.ListBankLinks)
+	r.GET("/customers/:customer_id/bank-links/:link_id/transactions", handlers.ListBankTransactions)
+	r.POST("/customers/:customer_id/bank-links/:link_id/sync", handlers.SyncBankLink)
+	r.DELETE("/customers/:customer_id/bank-links/:link_id", handlers.DeleteBankLink)
+	r.POST("/customers/:customer_id/withdrawals", handlers.CreateWithdrawal)
+	r.POST("/customers/:customer_id/credit-transfers", handlers.CreateCreditTransfer)
+	r.GET("/customers/:customer_id/credit-transfers", handlers.ListCreditTransfers)
+	r.POST("/customers/:customer_id/statements", handlers.CreateStatement)
+	r.GET("/customers/:customer_id/statements", handlers.ListStatements)
+	r.GET("/customers/:customer_id/statements/:statement_id/mt940", handlers.DownloadStatement)
+	r.GET("/customers/:customer_id/jobs/:job_id", handlers.GetCustomerJob)
+}
+
+// registerAdminRoutes wires the operator surface
+func registerAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/fraud/rules", handlers.ListFraudRules)
+	r.POST("/fraud/rules", handlers.CreateFraudRule)
+	r.PUT("/fraud/rules/:rule_id", handlers.UpdateFraudRule)
+	r.DELETE("/fraud/rules/:rule_id", handlers.DeleteFraudRule)
+	r.GET("/fraud/decisions", handlers.ListFraudDecisions)
+	r.POST("/fraud/decisions/:decision_id/review", handlers.ReviewFraudDecision)
+	r.PUT("/customers/:customer_id/verification", handlers.UpdateVerificationStatus)
+	r.PUT("/customers/:customer_id/allow-negative", handlers.SetAllowNegative)
+	r.GET("/customers/:customer_id/limits", handlers.GetCustomerLimits)
+	r.PUT("/customers/:customer_id/limits", handlers.SetCustomerLimits)
+	r.GET("/alias-reservations", handlers.ListAliasReservations)
+	r.POST("/alias-reservations", handlers.ReserveAlias)
+	r.DELETE("/alias-reservations/:alias", handlers.DeleteAliasReservation)
+	r.GET("/limits", handlers.GetLimitDefaults)
+	r.PUT("/limits", handlers.SetLimitDefaults)
+	r.GET("/customers/:customer_id/audit", handlers.GetCustomerAuditLog)
+	r.POST("/customers/:customer_id/reactivate", handlers.ReactivateAccount)
+	r.GET("/dormant-accounts", handlers.ListDormantAccounts)
+	r.GET("/customers/:customer_id/tokens", handlers.ListCustomerTokens)
+	r.POST("/customers/:customer_id/tokens", handlers.IssueCustomerToken)
+	r.DELETE("/customers/:customer_id/tokens/:token_id", handlers.RevokeCustomerToken)
+	r.GET("/transactions", handlers.FindTransactionsByReference)
+	r.POST("/transactions/:transaction_id/approve", handlers.ApproveTransaction)
+	r.POST("/transactions/:transaction_id/reject", handlers.RejectPendingTransaction)
+	r.POST("/adjustments", handlers.CreateAdjustment)
+	r.POST("/transactions/backdated", handlers.CreateBackdatedTransaction)
+	r.GET("/period-closes", handlers.ListPeriodCloses)
+	r.POST("/period-closes", handlers.ClosePeriod)
+	r.POST("/loans", handlers.CreateLoan)
+	r.POST("/transaction-types", handlers.CreateTransactionType)
+	r.GET("/export", handlers.ExportLedger)
+	r.GET("/audit", handlers.ListAuditLog)
+	r.GET("/trial-balance", handlers.GetTrialBalance)
+	r.GET("/fx/rounding", handlers.GetFXRoundingDifferences)
+	r.POST("/sagas/:saga_id/compensate", handlers.CompensateSaga)
+	r.PUT("/customers/:customer_id/stripe-account", handlers.SetStripeAccount)
+	r.GET("/stripe/reconciliation", handlers.GetStripeReconciliation)
+	r.PUT("/customers/:customer_id/statement-account", handlers.SetStatementAccount)
+	r.GET("/import-profiles", handlers.ListImportProfiles)
+	r.GET("/import-profiles/:name", handlers.GetImportProfile)
+	r.PUT("/import-profiles/:name", handlers.PutImportProfile)
+	r.DELETE("/import-profiles/:name", handlers.DeleteImportProfile)
+	r.POST("/customers/:customer_id/imports", handlers.ImportCSV)
+	r.GET("/customers/:customer_id/reconciliations", handlers.ListReconciliations)
+	r.POST("/customers/:customer_id/reconciliations", handlers.CreateReconciliation)
+	r.GET("/reconciliations/:reconciliation_id", handlers.GetReconciliation)
+	r.POST("/reconciliations/:reconciliation_id/close", handlers.CloseReconciliation)
+	r.POST("/reconciliations/:reconciliation_id/lines/:line_id/confirm", handlers.ConfirmReconciliationLine)
+	r.POST("/reconciliations/:reconciliation_id/lines/:line_id/unmatch", handlers.UnmatchReconciliationLine)
+	r.POST("/reconciliations/:reconciliation_id/lines/:line_id/adjust", handlers.AdjustReconciliationLine)
+	r.GET("/payment-files", handlers.ListPaymentFiles)
+	r.POST("/payment-files", handlers.CreatePaymentFile)
+	r.POST("/payment-files/status-reports", handlers.ReceivePaymentStatusReport)
+	r.GET("/payment-files/:file_id", handlers.GetPaymentFile)
+	r.GET("/payment-files/:file_id/xml", handlers.DownloadPaymentFile)
+	r.GET("/summary", handlers.GetAdminSummary)
+	r.GET("/jobs", handlers.ListScheduledJobs)
+	r.GET("/queue/jobs", handlers.ListQueueJobs)
+	r.GET("/queue/jobs/:job_id", handlers.GetQueueJob)
+	r.POST("/queue/jobs/:job_id/retry", handlers.RetryQueueJob)
+	r.POST("/queue/jobs/:job_id/cancel", handlers.CancelQueueJob)
+	r.GET("/queue/stats", handlers.GetQueueStats)
+	r.GET("/maintenance", handlers.GetMaintenanceMode)
+	r.PUT("/maintenance", handlers.SetMaintenanceMode)
+	r.GET("/accounts", handlers.ListGLAccounts)
+	r.POST("/accounts", handlers.CreateGLAccount)
+	r.GET("/accounts/:code", handlers.GetGLAccount)
+	r.GET("/accounts/:code/entries", handlers.GetGLAccountEntries)
+	r.GET("/webhooks", handlers.ListWebhooks)
+	r.POST("/webhooks", handlers.CreateWebhook)
+	r.GET("/webhooks/:webhook_id", handlers.GetWebhook)
+	r.PATCH("/webhooks/:webhook_id", handlers.UpdateWebhook)
+	r.DELETE("/webhooks/:webhook_id", handlers.DeleteWebhook)
+	r.POST("/webhooks/:webhook_id/test", handlers.TestWebhook)
+	r.POST("/webhooks/:webhook_id/replay", handlers.ReplayWebhook)
+	r.GET("/webhooks/:webhook_id/replays", handlers.ListWebhookReplays)
+	r.GET("/webhooks/:webhook_id/replays/:replay_id", handlers.GetWebhookReplay)
+	r.GET("/api-keys", handlers.ListAPIKeys)
+	r.POST("/api-keys", handlers.CreateAPIKey)
+	r.PATCH("/api-keys/:key_id", handlers.UpdateAPIKeyScopes)
+	r.DELETE("/api-keys/:key_id", handlers.RevokeAPIKey)
+}
+
+// registerMemoryRoutes wires the subset of the version 1 API that the
+// in-memory store can serve
+func registerMemoryRoutes(r *gin.RouterGroup, customerAuth gin.HandlerFunc, caching readCaching) {
+	read := r.Group("", middleware.ReadOnly())
+	read.GET("/customers/:customer_id/balance", caching.balance, handlers.GetBalance)
+	read.GET("/customers/:customer_id/balance/certificate", handlers.GetBalanceCertificate)
+	read.GET("/balance-certificates/keys", handlers.GetCertificateKeys)
+	read.GET("/customers/:customer_id/transactions", caching.transactions, handlers.GetTransactions)
+	read.GET("/transaction-types", handlers.ListTransactionTypes)
+
+	r.POST("/ingest/:source", handlers.IngestNotification)
+
+	customer := r.Group("", customerAuth)
+	customer.POST("/customers", handlers.CreateCustomer)
+	customer.GET("/customers/:customer_id", handlers.GetCustomer)
+	customer.POST("/customers/:customer_id/addresses", handlers.CreateAddress)
+	customer.POST("/transactions", handlers.CreateTransaction)
+	customer.POST(handlers.BalanceQueryRoute, handlers.QueryBalances)
+	customer.POST("/transfers/split", handlers.CreateSplitTransfer)
+	customer.POST("/transfers/reservations", handlers.CreateReservation)
+	customer.GET("/transfers/reservations/:transfer_id", handlers.GetReservation)
+	customer.POST("/transfers/reservations/:transfer_id/settle", handlers.SettleReservation)
+	customer.POST("/transfers/reservations/:transfer_id/release", handlers.ReleaseReservation)
+}
@@ -0,0 +1,200 @@
+// Package attest signs statements about the ledger, such as a customer's
+// balance, as compact JSON Web Signatures (RFC 7515) that third parties can
+// verify against the service's published keys
+package attest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Signing algorithms, by their JWS names
+const (
+	ES256 = "ES256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// ErrInvalidSignature is wrapped by every error for a token that is
+// malformed or not signed by the key it is checked against
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signer signs with a private key that may never leave a key management
+// service
+type Signer interface {
+	// Algorithm is the JWS alg the signatures are made with
+	Algorithm() string
+	// KeyID identifies the key in the published key set
+	KeyID() string
+	PublicKey() crypto.PublicKey
+	// Sign signs a JWS signing input, returning the signature in its JWS
+	// form
+	Sign(ctx context.Context, input []byte) ([]byte, error)
+}
+
+var b64 = base64.RawURLEncoding
+
+// Sign encodes claims as a compact JWS signed by s
+func Sign(ctx context.Context, s Signer, claims interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": s.Algorithm(), "kid": s.KeyID(), "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	sig, err := s.Sign(ctx, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64.EncodeToString(sig), nil
+}
+
+// Verify checks that token is signed by key and returns its payload
+func Verify(token string, key crypto.PublicKey) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a compact JWS", ErrInvalidSignature)
+	}
+	rawHeader, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidSignature)
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	// The algorithm must be the key's own, so a token cannot pick a
+	// weaker one
+	alg, err := algorithmOf(key)
+	if err != nil || alg != header.Alg {
+		return nil, fmt.Errorf("%w: algorithm %q does not match the key", ErrInvalidSignature, header.Alg)
+	}
+	input := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(input)
+	ok := false
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = len(sig) == 64 && ecdsa.Verify(k, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, input, sig)
+	}
+	if !ok {
+		return nil, ErrInvalidSignature
+	}
+	return payload, nil
+}
+
+// algorithmOf is the JWS algorithm signatures by key are made with. Only
+// P-256 elliptic curve keys, RSA keys of 2048 bits or more and Ed25519 keys
+// are supported.
+func algorithmOf(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported elliptic curve %s (want P-256)", k.Curve.Params().Name)
+		}
+		return ES256, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return "", fmt.Errorf("RSA key of %d bits is too short (want at least 2048)", k.N.BitLen())
+		}
+		return RS256, nil
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// PublicJWK is the JWK verifiers check s's signatures with
+func PublicJWK(s Signer) JWK {
+	k := publicJWK(s.PublicKey())
+	k.KeyID, k.Use, k.Algorithm = s.KeyID(), "sig", s.Algorithm()
+	return k
+}
+
+// publicJWK holds only the members that define key, the ones its
+// thumbprint is computed over
+func publicJWK(key crypto.PublicKey) JWK {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+		return JWK{KeyType: "EC", Curve: "P-256", X: b64.EncodeToString(x), Y: b64.EncodeToString(y)}
+	case *rsa.PublicKey:
+		return JWK{KeyType: "RSA", N: b64.EncodeToString(k.N.Bytes()), E: b64.EncodeToString(big.NewInt(int64(k.E)).Bytes())}
+	case ed25519.PublicKey:
+		return JWK{KeyType: "OKP", Curve: "Ed25519", X: b64.EncodeToString(k)}
+	}
+	return JWK{}
+}
+
+// Thumbprint is key's JWK thumbprint (RFC 7638), which serves as its key
+// ID: it stays the same wherever the key is loaded from
+func Thumbprint(key crypto.PublicKey) string {
+	k := publicJWK(key)
+	var canonical string
+	switch k.KeyType {
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Curve, k.X, k.Y)
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Curve, k.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return b64.EncodeToString(sum[:])
+}
+
+// rawECDSA converts an ASN.1 DER ECDSA signature to the fixed-width R || S
+// form JWS uses
+func rawECDSA(der []byte) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil || len(rest) > 0 {
+		return nil, errors.New("malformed ECDSA signature")
+	}
+	raw := make([]byte, 64)
+	sig.R.FillBytes(raw[:32])
+	sig.S.FillBytes(raw[32:])
+	return raw, nil
+}
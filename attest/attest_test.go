@@ -0,0 +1,142 @@
+package attest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ledger-service/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	claims := map[string]interface{}{"sub": "customer", "balance": 1250.5}
+
+	for alg, key := range map[string]crypto.Signer{ES256: ecKey, RS256: rsaKey, EdDSA: edKey} {
+		t.Run(alg, func(t *testing.T) {
+			s, err := NewKeySigner(key)
+			require.NoError(t, err)
+			assert.Equal(t, alg, s.Algorithm())
+			token, err := Sign(context.Background(), s, claims)
+			require.NoError(t, err)
+
+			payload, err := Verify(token, s.PublicKey())
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"sub": "customer", "balance": 1250.5}`, string(payload))
+
+			parts := strings.Split(token, ".")
+			forged := parts[0] + "." + b64.EncodeToString([]byte(`{"sub":"customer","balance":99999}`)) + "." + parts[2]
+			_, err = Verify(forged, s.PublicKey())
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+
+			jwk := PublicJWK(s)
+			assert.Equal(t, s.KeyID(), jwk.KeyID)
+			assert.Equal(t, alg, jwk.Algorithm)
+		})
+	}
+
+	// A token cannot choose another algorithm than its key's
+	s, _ := NewKeySigner(ecKey)
+	token, _ := Sign(context.Background(), s, claims)
+	header := b64.EncodeToString([]byte(`{"alg":"RS256","kid":"` + s.KeyID() + `"}`))
+	_, err := Verify(header+token[strings.Index(token, "."):], s.PublicKey())
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, err = NewKeySigner(p384)
+	assert.Error(t, err)
+}
+
+func TestThumbprint(t *testing.T) {
+	// The example key of RFC 7638 section 3.1
+	n, _ := b64.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", Thumbprint(key))
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(key)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+
+	s, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, ES256, s.Algorithm())
+	assert.Equal(t, Thumbprint(&key.PublicKey), s.KeyID())
+
+	require.NoError(t, os.WriteFile(path, []byte("not a key"), 0o600))
+	_, err = LoadKeyFile(path)
+	assert.Error(t, err)
+}
+
+func TestKMSSigner(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	var targets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targets = append(targets, r.Header.Get("X-Amz-Target"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+		var in struct {
+			KeyId       string
+			Message     []byte
+			MessageType string
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		assert.Equal(t, "alias/certificates", in.KeyId)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			json.NewEncoder(w).Encode(map[string]interface{}{"PublicKey": public, "KeySpec": "ECC_NIST_P256", "KeyUsage": "SIGN_VERIFY"})
+		case "TrentService.Sign":
+			assert.Equal(t, "DIGEST", in.MessageType)
+			sig, _ := ecdsa.SignASN1(rand.Reader, key, in.Message)
+			json.NewEncoder(w).Encode(map[string]interface{}{"Signature": sig})
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewKMSSigner(context.Background(), KMSConfig{
+		KeyID:       "alias/certificates",
+		Region:      "eu-west-1",
+		Endpoint:    server.URL,
+		Credentials: events.StaticAWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ES256, s.Algorithm())
+	token, err := Sign(context.Background(), s, map[string]string{"sub": "customer"})
+	require.NoError(t, err)
+	_, err = Verify(token, &key.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TrentService.GetPublicKey", "TrentService.Sign"}, targets)
+
+	// KMS errors come back as AWS errors
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type": "com.amazonaws.kms#NotFoundException", "message": "Alias does not exist"}`))
+	}))
+	defer failing.Close()
+	_, err = NewKMSSigner(context.Background(), KMSConfig{KeyID: "alias/certificates", Region: "eu-west-1", Endpoint: failing.URL,
+		Credentials: events.StaticAWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}})
+	var awsErr *events.AWSError
+	require.ErrorAs(t, err, &awsErr)
+	assert.Equal(t, "NotFoundException", awsErr.Code)
+}
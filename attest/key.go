@@ -0,0 +1,103 @@
+package attest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeySigner signs with a private key held in memory
+type KeySigner struct {
+	key crypto.Signer
+	alg string
+	kid string
+}
+
+// NewKeySigner signs with key, which must be a P-256 ECDSA, RSA or
+// Ed25519 key
+func NewKeySigner(key crypto.Signer) (*KeySigner, error) {
+	alg, err := algorithmOf(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{key: key, alg: alg, kid: Thumbprint(key.Public())}, nil
+}
+
+// LoadKeyFile reads a PEM private key, in PKCS #8, SEC 1 or PKCS #1 form,
+// and signs with it
+func LoadKeyFile(path string) (*KeySigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewKeySigner(key)
+}
+
+// ParsePrivateKey parses the first PEM private key in data
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q (want a private key)", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// Algorithm is the JWS alg of the key
+func (s *KeySigner) Algorithm() string { return s.alg }
+
+// KeyID is the key's thumbprint
+func (s *KeySigner) KeyID() string { return s.kid }
+
+// PublicKey is the key's public half
+func (s *KeySigner) PublicKey() crypto.PublicKey { return s.key.Public() }
+
+// Sign signs input with the key
+func (s *KeySigner) Sign(ctx context.Context, input []byte) ([]byte, error) {
+	if k, ok := s.key.(ed25519.PrivateKey); ok {
+		return ed25519.Sign(k, input), nil
+	}
+	digest := sha256.Sum256(input)
+	switch k := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		der, err := ecdsa.SignASN1(rand.Reader, k, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		return rawECDSA(der)
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	}
+	return nil, fmt.Errorf("unsupported key type %T", s.key)
+}
@@ -0,0 +1,110 @@
+package attest
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ledger-service/events"
+)
+
+// KMSConfig names an asymmetric AWS KMS signing key
+type KMSConfig struct {
+	// KeyID is the key's ID, ARN or alias, e.g. alias/balance-certificates
+	KeyID  string
+	Region string
+	// Endpoint overrides https://kms.{region}.amazonaws.com
+	Endpoint    string
+	Credentials events.AWSCredentialProvider
+	Timeout     time.Duration
+}
+
+// KMSSigner signs with a key that never leaves AWS KMS. Signing sends KMS
+// the SHA-256 digest of the input only.
+type KMSSigner struct {
+	cfg       KMSConfig
+	client    *http.Client
+	alg       string
+	algorithm string
+	kid       string
+	public    crypto.PublicKey
+}
+
+// kmsAlgorithms maps the key specs KMSSigner supports to their JWS alg and
+// KMS signing algorithm
+var kmsAlgorithms = map[string][2]string{
+	"ECC_NIST_P256": {ES256, "ECDSA_SHA_256"},
+	"RSA_2048":      {RS256, "RSASSA_PKCS1_V1_5_SHA_256"},
+	"RSA_3072":      {RS256, "RSASSA_PKCS1_V1_5_SHA_256"},
+	"RSA_4096":      {RS256, "RSASSA_PKCS1_V1_5_SHA_256"},
+}
+
+// NewKMSSigner fetches the public half of the key, so a key KMS cannot
+// sign with, or of a spec JWS has no algorithm for, is refused at startup
+func NewKMSSigner(ctx context.Context, cfg KMSConfig) (*KMSSigner, error) {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://kms." + cfg.Region + ".amazonaws.com/"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	s := &KMSSigner{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+	var out struct {
+		PublicKey []byte
+		KeySpec   string
+		KeyUsage  string
+	}
+	if err := s.call(ctx, "GetPublicKey", map[string]string{"KeyId": cfg.KeyID}, &out); err != nil {
+		return nil, err
+	}
+	if out.KeyUsage != "SIGN_VERIFY" {
+		return nil, fmt.Errorf("kms key %s is for %s, not SIGN_VERIFY", cfg.KeyID, out.KeyUsage)
+	}
+	algs, ok := kmsAlgorithms[out.KeySpec]
+	if !ok {
+		return nil, fmt.Errorf("kms key %s has unsupported spec %s (want ECC_NIST_P256 or RSA_2048/3072/4096)", cfg.KeyID, out.KeySpec)
+	}
+	public, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("kms key %s: %w", cfg.KeyID, err)
+	}
+	s.alg, s.algorithm, s.public, s.kid = algs[0], algs[1], public, Thumbprint(public)
+	return s, nil
+}
+
+func (s *KMSSigner) call(ctx context.Context, action string, in, out interface{}) error {
+	return events.AWSJSON(ctx, s.client, s.cfg.Credentials, s.cfg.Endpoint, s.cfg.Region, "kms", "TrentService."+action, in, out)
+}
+
+// Algorithm is the JWS alg of the key's spec
+func (s *KMSSigner) Algorithm() string { return s.alg }
+
+// KeyID is the thumbprint of the key's public half
+func (s *KMSSigner) KeyID() string { return s.kid }
+
+// PublicKey is the key's public half
+func (s *KMSSigner) PublicKey() crypto.PublicKey { return s.public }
+
+// Sign has KMS sign the digest of input
+func (s *KMSSigner) Sign(ctx context.Context, input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+	var out struct {
+		Signature []byte
+	}
+	if err := s.call(ctx, "Sign", map[string]interface{}{
+		"KeyId":            s.cfg.KeyID,
+		"Message":          digest[:],
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": s.algorithm,
+	}, &out); err != nil {
+		return nil, err
+	}
+	if s.alg == ES256 {
+		return rawECDSA(out.Signature)
+	}
+	return out.Signature, nil
+}
@@ -0,0 +1,246 @@
+// Package backup exports the ledger as a consistent snapshot and imports it
+// again, for environment cloning and disaster recovery drills. Rows are
+// carried as JSON documents produced by row_to_json, so exports follow schema
+// changes without per-column code.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Format is an export encoding
+type Format string
+
+const (
+	// JSONL writes a header line followed by one {"table", "row"} record per row
+	JSONL Format = "jsonl"
+	// SQL writes a psql-compatible script of INSERT statements in a single transaction
+	SQL Format = "sql"
+)
+
+// ParseFormat validates an export format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case JSONL, SQL:
+		return f, nil
+	}
+	return "", fmt.Errorf("format must be jsonl or sql")
+}
+
+// ContentType is the media type of an export in this format
+func (f Format) ContentType() string {
+	if f == SQL {
+		return "application/sql"
+	}
+	return "application/x-ndjson"
+}
+
+// formatName and formatVersion identify export files in the JSONL header
+const (
+	formatName    = "ledger-service-export"
+	formatVersion = 1
+)
+
+// table is an exported table, listed in foreign key dependency order
+type table struct {
+	name    string
+	orderBy string
+	// onConflict resolves rows that already exist, for reference data seeded
+	// by the migrations
+	onConflict string
+}
+
+// tables are every table holding money or what it is owed on; a restore
+// without one of them would no longer balance
+var tables = []table{
+	{name: "gl_accounts", orderBy: "code", onConflict: "ON CONFLICT (code) DO UPDATE SET balance = EXCLUDED.balance"},
+	{name: "transaction_types", orderBy: "code", onConflict: "ON CONFLICT DO NOTHING"},
+	{name: "customers", orderBy: "id"},
+	{name: "customer_addresses", orderBy: "id"},
+	{name: "sub_accounts", orderBy: "id"},
+	{name: "fx_quotes", orderBy: "id"},
+	{name: "moves", orderBy: "id"},
+	{name: "sub_account_transactions", orderBy: "id"},
+	{name: "transfers", orderBy: "id"},
+	{name: "transactions", orderBy: "id"},
+	{name: "gl_entries", orderBy: "id"},
+	{name: "fx_rounding_totals", orderBy: "currency", onConflict: "ON CONFLICT (currency) DO UPDATE SET carried = EXCLUDED.carried"},
+	{name: "fx_rounding_differences", orderBy: "id"},
+	{name: "loans", orderBy: "id"},
+	{name: "loan_installments", orderBy: "loan_id, number"},
+	{name: "loan_repayments", orderBy: "id"},
+	{name: "standing_orders", orderBy: "id"},
+	{name: "standing_order_runs", orderBy: "id"},
+	{name: "mandates", orderBy: "id"},
+	{name: "mandate_payments", orderBy: "id"},
+}
+
+// TableNames lists the exported tables in the order they are written
+func TableNames() []string {
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = t.name
+	}
+	return names
+}
+
+func lookupTable(name string) (table, bool) {
+	for _, t := range tables {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return table{}, false
+}
+
+// SnapshotOptions begins the read-only repeatable-read transaction an export
+// must run in, so every table is read from the same snapshot
+var SnapshotOptions = pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+
+// Header is the first line of a JSONL export
+type Header struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Tables     []string  `json:"tables"`
+}
+
+// Record is one row of a JSONL export
+type Record struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// Stats counts the rows exported or imported per table
+type Stats map[string]int
+
+// Export writes every exported table to w. tx should have been started with
+// SnapshotOptions; the caller ends it.
+func Export(ctx context.Context, tx pgx.Tx, w io.Writer, format Format) (Stats, error) {
+	bw := bufio.NewWriter(w)
+	stats := Stats{}
+	now := time.Now().UTC()
+
+	names := TableNames()
+	if format == SQL {
+		fmt.Fprintf(bw, "-- %s v%d exported at %s\nBEGIN;\n", formatName, formatVersion, now.Format(time.RFC3339))
+	} else {
+		header, _ := json.Marshal(Header{Format: formatName, Version: formatVersion, ExportedAt: now, Tables: names})
+		bw.Write(append(header, '\n'))
+	}
+
+	for _, t := range tables {
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t ORDER BY %s", t.name, t.orderBy))
+		if err != nil {
+			return stats, fmt.Errorf("read %s: %w", t.name, err)
+		}
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				rows.Close()
+				return stats, fmt.Errorf("read %s: %w", t.name, err)
+			}
+			if format == SQL {
+				fmt.Fprintf(bw, "%s;\n", insertStatement(t, quoteLiteral(row)))
+			} else {
+				line, _ := json.Marshal(Record{Table: t.name, Row: json.RawMessage(row)})
+				bw.Write(append(line, '\n'))
+			}
+			stats[t.name]++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, fmt.Errorf("read %s: %w", t.name, err)
+		}
+	}
+
+	if format == SQL {
+		bw.WriteString("COMMIT;\n")
+	}
+	return stats, bw.Flush()
+}
+
+// insertStatement inserts the JSON row given by value (a literal or a
+// placeholder) into t
+func insertStatement(t table, value string) string {
+	stmt := fmt.Sprintf("INSERT INTO %s SELECT * FROM json_populate_record(NULL::%s, %s)", t.name, t.name, value)
+	if t.onConflict != "" {
+		stmt += " " + t.onConflict
+	}
+	return stmt
+}
+
+// quoteLiteral quotes s as a standard SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// maxLineBytes bounds a single JSONL line on import
+const maxLineBytes = 16 << 20
+
+// Beginner starts a database transaction
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Import loads a JSONL export in a single transaction; nothing is written
+// unless every row is accepted. The target database must already have the
+// schema, and rows other than reference data must not exist yet.
+func Import(ctx context.Context, db Beginner, r io.Reader) (Stats, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("export is empty")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != formatName {
+		return nil, fmt.Errorf("not a %s file", formatName)
+	}
+	if header.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported export version %d", header.Version)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	stats := Stats{}
+	for line := 2; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		t, ok := lookupTable(rec.Table)
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown table %q", line, rec.Table)
+		}
+		if _, err := tx.Exec(ctx, insertStatement(t, "$1"), string(rec.Row)); err != nil {
+			return nil, fmt.Errorf("line %d: insert into %s: %w", line, t.name, err)
+		}
+		stats[t.name]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+)
+
+// Balances caches customer balances in Redis under <prefix><customer ID> as
+// "<amount> <currency>".
+// Entries expire after TTL, which bounds how long a balance can be stale if
+// an invalidation is lost.
+type Balances struct {
+	Redis  *Redis
+	Prefix string
+	TTL    time.Duration
+}
+
+// NewBalances creates a balance cache on r
+func NewBalances(r *Redis, prefix string, ttl time.Duration) *Balances {
+	return &Balances{Redis: r, Prefix: prefix, TTL: ttl}
+}
+
+func (b *Balances) key(customerID uuid.UUID) string {
+	return b.Prefix + customerID.String()
+}
+
+// Get returns the cached balance and whether there was one. Entries cached
+// before balances carried a currency count as misses.
+func (b *Balances) Get(ctx context.Context, customerID uuid.UUID) (store.Balance, bool, error) {
+	reply, err := b.Redis.Do(ctx, "GET", b.key(customerID))
+	if err != nil || reply == nil {
+		return store.Balance{}, false, err
+	}
+	s, _ := reply.(string)
+	amount, currency, ok := strings.Cut(s, " ")
+	if !ok {
+		return store.Balance{}, false, nil
+	}
+	balance, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return store.Balance{}, false, err
+	}
+	return store.Balance{Amount: balance, Currency: currency}, true, nil
+}
+
+// Set caches a balance read from the database
+func (b *Balances) Set(ctx context.Context, customerID uuid.UUID, balance store.Balance) error {
+	value := strconv.FormatFloat(balance.Amount, 'f', -1, 64) + " " + balance.Currency
+	_, err := b.Redis.Do(ctx, "SET", b.key(customerID), value,
+		"PX", strconv.FormatInt(b.TTL.Milliseconds(), 10))
+	return err
+}
+
+// Invalidate drops the cached balances of customerIDs
+func (b *Balances) Invalidate(ctx context.Context, customerIDs ...uuid.UUID) error {
+	if len(customerIDs) == 0 {
+		return nil
+	}
+	args := []string{"DEL"}
+	for _, id := range customerIDs {
+		args = append(args, b.key(id))
+	}
+	_, err := b.Redis.Do(ctx, args...)
+	return err
+}
+
+// Close closes the Redis connections
+func (b *Balances) Close() error {
+	return b.Redis.Close()
+}
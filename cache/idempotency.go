@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeys stores idempotency records in Redis under <prefix><key>.
+// SET NX lets exactly one replica claim a key, and Redis expires records
+// after TTL so no cleanup job is needed.
+type IdempotencyKeys struct {
+	Redis  *Redis
+	Prefix string
+	TTL    time.Duration
+}
+
+// NewIdempotencyKeys creates an idempotency store on r
+func NewIdempotencyKeys(r *Redis, prefix string, ttl time.Duration) *IdempotencyKeys {
+	return &IdempotencyKeys{Redis: r, Prefix: prefix, TTL: ttl}
+}
+
+func (s *IdempotencyKeys) ttl() string {
+	return strconv.FormatInt(s.TTL.Milliseconds(), 10)
+}
+
+// Claim stores record under key unless the key is already held, in which case
+// it returns the held record
+func (s *IdempotencyKeys) Claim(ctx context.Context, key string, record []byte) (bool, []byte, error) {
+	reply, err := s.Redis.Do(ctx, "SET", s.Prefix+key, string(record), "NX", "PX", s.ttl())
+	if err != nil {
+		return false, nil, err
+	}
+	if reply != nil {
+		return true, nil, nil
+	}
+
+	reply, err = s.Redis.Do(ctx, "GET", s.Prefix+key)
+	if err != nil || reply == nil {
+		return false, nil, err
+	}
+	existing, _ := reply.(string)
+	return false, []byte(existing), nil
+}
+
+// Save stores the finished request's record under key
+func (s *IdempotencyKeys) Save(ctx context.Context, key string, record []byte) error {
+	_, err := s.Redis.Do(ctx, "SET", s.Prefix+key, string(record), "XX", "PX", s.ttl())
+	return err
+}
+
+// Release deletes key
+func (s *IdempotencyKeys) Release(ctx context.Context, key string) error {
+	_, err := s.Redis.Do(ctx, "DEL", s.Prefix+key)
+	return err
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	server := newFakeRedis(t, "")
+	r, err := NewRedis("redis://"+server.ln.Addr().String(), time.Second, 1)
+	assert.NoError(t, err)
+	defer r.Close()
+	s := NewIdempotencyKeys(r, "ledger:idempotency:", time.Hour)
+	ctx := context.Background()
+
+	claimed, existing, err := s.Claim(ctx, "key-1", []byte(`{"fingerprint":"a"}`))
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Nil(t, existing)
+
+	// A second replica sees the first one's record
+	claimed, existing, err = s.Claim(ctx, "key-1", []byte(`{"fingerprint":"b"}`))
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, `{"fingerprint":"a"}`, string(existing))
+
+	assert.NoError(t, s.Save(ctx, "key-1", []byte(`{"fingerprint":"a","status":201}`)))
+	_, existing, _ = s.Claim(ctx, "key-1", []byte(`{"fingerprint":"a"}`))
+	assert.Equal(t, `{"fingerprint":"a","status":201}`, string(existing))
+
+	assert.NoError(t, s.Release(ctx, "key-1"))
+	claimed, _, err = s.Claim(ctx, "key-1", []byte(`{"fingerprint":"c"}`))
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, "NX PX 3600000", server.ttls["ledger:idempotency:key-1"])
+	assert.Contains(t, server.commands, `SET ledger:idempotency:key-1 {"fingerprint":"a","status":201} XX PX 3600000`)
+}
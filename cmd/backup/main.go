@@ -0,0 +1,122 @@
+// Command backup exports the ledger from one database and imports it into
+// another, for environment cloning and disaster recovery drills.
+//
+// Usage:
+//
+//	DATABASE_URL=postgres://... go run ./cmd/backup export [-format jsonl|sql] [-o file]
+//	DATABASE_URL=postgres://... go run ./cmd/backup import [-i file]
+//
+// Files default to stdout and stdin. SQL exports are applied with psql
+// rather than imported.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"ledger-service/backup"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		formatName := fs.String("format", string(backup.JSONL), "export format: jsonl or sql")
+		output := fs.String("o", "", "output file (default stdout)")
+		fs.Parse(os.Args[2:])
+
+		format, err := backup.ParseFormat(*formatName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var w io.Writer = os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				log.Fatalf("Unable to create %s: %v\n", *output, err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		conn := connect(ctx)
+		defer conn.Close()
+		tx, err := conn.BeginTx(ctx, backup.SnapshotOptions)
+		if err != nil {
+			log.Fatalf("Unable to start snapshot: %v\n", err)
+		}
+		defer tx.Rollback(ctx)
+		stats, err := backup.Export(ctx, tx, w, format)
+		if err != nil {
+			log.Fatalf("Export failed: %v\n", err)
+		}
+		report("Exported", stats)
+
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ExitOnError)
+		input := fs.String("i", "", "JSONL export to load (default stdin)")
+		fs.Parse(os.Args[2:])
+
+		var r io.Reader = os.Stdin
+		if *input != "" {
+			f, err := os.Open(*input)
+			if err != nil {
+				log.Fatalf("Unable to open %s: %v\n", *input, err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		conn := connect(ctx)
+		defer conn.Close()
+		stats, err := backup.Import(ctx, conn, r)
+		if err != nil {
+			log.Fatalf("Import failed, nothing was written: %v\n", err)
+		}
+		report("Imported", stats)
+
+	default:
+		usage()
+	}
+}
+
+func connect(ctx context.Context) *pgxpool.Pool {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+	conn, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v\n", err)
+	}
+	return conn
+}
+
+// report prints row counts to stderr so they never mix with an export on stdout
+func report(verb string, stats backup.Stats) {
+	tables := make([]string, 0, len(stats))
+	for t := range stats {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		fmt.Fprintf(os.Stderr, "%s %d %s rows\n", verb, stats[t], t)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: backup export [-format jsonl|sql] [-o file] | backup import [-i file]")
+	os.Exit(2)
+}
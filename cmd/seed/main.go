@@ -0,0 +1,49 @@
+// Command seed fills a development database with demo customers and
+// randomized transaction histories.
+//
+// Usage:
+//
+//	DATABASE_URL=postgres://... go run ./cmd/seed -customers 200 -transactions 100 -days 365
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"ledger-service/seed"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	defaults := seed.DefaultOptions()
+	var opts seed.Options
+	flag.IntVar(&opts.Customers, "customers", defaults.Customers, "number of customers to create")
+	flag.IntVar(&opts.MaxTransactions, "transactions", defaults.MaxTransactions, "maximum transactions per customer")
+	flag.IntVar(&opts.Days, "days", defaults.Days, "how many days of history to spread transactions over")
+	flag.Int64Var(&opts.Seed, "seed", 0, "random seed for reproducible data (0 picks one)")
+	flag.Parse()
+
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("Invalid options: %v\n", err)
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+	conn, err := pgxpool.New(context.Background(), dbURL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v\n", err)
+	}
+	defer conn.Close()
+
+	result, err := seed.Generate(context.Background(), conn, opts)
+	if err != nil {
+		log.Fatalf("Seeding failed: %v\n", err)
+	}
+	fmt.Printf("Created %d customers and %d transactions (seed %d)\n", result.Customers, result.Transactions, result.Seed)
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryExecModes maps DB_QUERY_EXEC_MODE values to pgx modes, using the names
+// pgx accepts as default_query_exec_mode in a connection string
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// Database holds how pgx prepares and traces statements
+type Database struct {
+	// QueryExecMode decides whether statements are prepared and cached;
+	// transaction-pooling proxies such as PgBouncer need exec or simple_protocol
+	QueryExecMode pgx.QueryExecMode
+	// StatementCacheCapacity bounds the prepared statements kept per connection
+	StatementCacheCapacity int
+	// DescriptionCacheCapacity bounds the statement descriptions kept per connection
+	DescriptionCacheCapacity int
+	// TraceQueries records every query's duration in the metrics
+	TraceQueries bool
+	// SlowQueryThreshold logs queries that take at least this long; 0 disables
+	SlowQueryThreshold time.Duration
+}
+
+// DefaultDatabase returns pgx's own defaults with tracing on
+func DefaultDatabase() Database {
+	return Database{
+		QueryExecMode:            pgx.QueryExecModeCacheStatement,
+		StatementCacheCapacity:   512,
+		DescriptionCacheCapacity: 512,
+		TraceQueries:             true,
+		SlowQueryThreshold:       500 * time.Millisecond,
+	}
+}
+
+// DatabaseFromEnv overrides the defaults with the DB_* variables found by getenv
+func DatabaseFromEnv(getenv func(string) string) (Database, error) {
+	d := DefaultDatabase()
+	if v := getenv("DB_QUERY_EXEC_MODE"); v != "" {
+		mode, ok := queryExecModes[v]
+		if !ok {
+			return Database{}, fmt.Errorf("DB_QUERY_EXEC_MODE must be one of cache_statement, cache_describe, describe_exec, exec, simple_protocol, got %q", v)
+		}
+		d.QueryExecMode = mode
+	}
+	counts := []struct {
+		key string
+		dst *int
+	}{
+		{"DB_STATEMENT_CACHE_CAPACITY", &d.StatementCacheCapacity},
+		{"DB_DESCRIPTION_CACHE_CAPACITY", &d.DescriptionCacheCapacity},
+	}
+	for _, c := range counts {
+		v := getenv(c.key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Database{}, fmt.Errorf("%s must be a whole number, got %q", c.key, v)
+		}
+		*c.dst = n
+	}
+	if v := getenv("DB_TRACE_QUERIES"); v != "" {
+		trace, err := strconv.ParseBool(v)
+		if err != nil {
+			return Database{}, fmt.Errorf("DB_TRACE_QUERIES must be true or false, got %q", v)
+		}
+		d.TraceQueries = trace
+	}
+	if v := getenv("DB_SLOW_QUERY_MS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Database{}, fmt.Errorf("DB_SLOW_QUERY_MS must be a whole number of milliseconds, got %q", v)
+		}
+		d.SlowQueryThreshold = time.Duration(n) * time.Millisecond
+	}
+	return d, d.Validate()
+}
+
+// Validate reports the first setting pgx would reject when connecting
+func (d Database) Validate() error {
+	switch {
+	case d.StatementCacheCapacity < 0:
+		return errors.New("statement cache capacity cannot be negative")
+	case d.DescriptionCacheCapacity < 0:
+		return errors.New("description cache capacity cannot be negative")
+	case d.QueryExecMode == pgx.QueryExecModeCacheStatement && d.StatementCacheCapacity == 0:
+		return errors.New("cache_statement mode needs a statement cache capacity")
+	case d.QueryExecMode == pgx.QueryExecModeCacheDescribe && d.DescriptionCacheCapacity == 0:
+		return errors.New("cache_describe mode needs a description cache capacity")
+	case d.SlowQueryThreshold < 0:
+		return errors.New("slow query threshold cannot be negative")
+	}
+	return nil
+}
+
+// Apply sets the statement caching options on a pgx connection config
+func (d Database) Apply(c *pgx.ConnConfig) {
+	c.DefaultQueryExecMode = d.QueryExecMode
+	c.StatementCacheCapacity = d.StatementCacheCapacity
+	c.DescriptionCacheCapacity = d.DescriptionCacheCapacity
+}
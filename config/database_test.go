@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    func(d *Database)
+		wantErr bool
+	}{
+		{name: "defaults", env: map[string]string{}},
+		{
+			name: "pgbouncer",
+			env: map[string]string{
+				"DB_QUERY_EXEC_MODE":          "exec",
+				"DB_STATEMENT_CACHE_CAPACITY": "0",
+				"DB_TRACE_QUERIES":            "false",
+				"DB_SLOW_QUERY_MS":            "0",
+			},
+			want: func(d *Database) {
+				d.QueryExecMode = pgx.QueryExecModeExec
+				d.StatementCacheCapacity = 0
+				d.TraceQueries = false
+				d.SlowQueryThreshold = 0
+			},
+		},
+		{
+			name: "larger caches",
+			env:  map[string]string{"DB_STATEMENT_CACHE_CAPACITY": "2048", "DB_SLOW_QUERY_MS": "250"},
+			want: func(d *Database) {
+				d.StatementCacheCapacity = 2048
+				d.SlowQueryThreshold = 250 * time.Millisecond
+			},
+		},
+		{name: "unknown mode", env: map[string]string{"DB_QUERY_EXEC_MODE": "prepare"}, wantErr: true},
+		{name: "cache mode without cache", env: map[string]string{"DB_STATEMENT_CACHE_CAPACITY": "0"}, wantErr: true},
+		{name: "negative capacity", env: map[string]string{"DB_DESCRIPTION_CACHE_CAPACITY": "-1"}, wantErr: true},
+		{name: "not a bool", env: map[string]string{"DB_TRACE_QUERIES": "sometimes"}, wantErr: true},
+		{name: "not a number", env: map[string]string{"DB_SLOW_QUERY_MS": "1s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DatabaseFromEnv(func(key string) string { return tt.env[key] })
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			want := DefaultDatabase()
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDatabaseApply(t *testing.T) {
+	conf, err := pgx.ParseConfig("postgres://localhost/ledger")
+	assert.NoError(t, err)
+	d := DefaultDatabase()
+	d.QueryExecMode = pgx.QueryExecModeSimpleProtocol
+	d.StatementCacheCapacity = 64
+	d.Apply(conf)
+	assert.Equal(t, pgx.QueryExecModeSimpleProtocol, conf.DefaultQueryExecMode)
+	assert.Equal(t, 64, conf.StatementCacheCapacity)
+	assert.Equal(t, 512, conf.DescriptionCacheCapacity)
+}
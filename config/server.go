@@ -0,0 +1,89 @@
+// Package config loads and validates settings that the service cannot start
+// without getting right.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Server holds the HTTP server's timeouts and limits
+type Server struct {
+	ReadTimeout         time.Duration
+	ReadHeaderTimeout   time.Duration
+	WriteTimeout        time.Duration
+	IdleTimeout         time.Duration
+	MaxHeaderBytes      int
+	ShutdownGracePeriod time.Duration
+}
+
+// DefaultServer returns the limits used when nothing is configured
+func DefaultServer() Server {
+	return Server{
+		ReadTimeout:         15 * time.Second,
+		ReadHeaderTimeout:   5 * time.Second,
+		WriteTimeout:        15 * time.Second,
+		IdleTimeout:         60 * time.Second,
+		MaxHeaderBytes:      http.DefaultMaxHeaderBytes,
+		ShutdownGracePeriod: 5 * time.Second,
+	}
+}
+
+// ServerFromEnv overrides the defaults with the HTTP_* variables found by
+// getenv. Timeouts are whole seconds; a write or idle timeout of 0 disables it.
+func ServerFromEnv(getenv func(string) string) (Server, error) {
+	s := DefaultServer()
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT_SECONDS", &s.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT_SECONDS", &s.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT_SECONDS", &s.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT_SECONDS", &s.IdleTimeout},
+		{"HTTP_SHUTDOWN_GRACE_SECONDS", &s.ShutdownGracePeriod},
+	}
+	for _, d := range durations {
+		v := getenv(d.key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Server{}, fmt.Errorf("%s must be a whole number of seconds, got %q", d.key, v)
+		}
+		*d.dst = time.Duration(n) * time.Second
+	}
+	if v := getenv("HTTP_MAX_HEADER_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Server{}, fmt.Errorf("HTTP_MAX_HEADER_BYTES must be a number of bytes, got %q", v)
+		}
+		s.MaxHeaderBytes = n
+	}
+	return s, s.Validate()
+}
+
+// Validate reports the first setting that would leave the server unusable
+func (s Server) Validate() error {
+	switch {
+	case s.ReadTimeout <= 0:
+		return errors.New("read timeout must be positive")
+	case s.ReadHeaderTimeout <= 0:
+		return errors.New("read header timeout must be positive")
+	case s.ReadHeaderTimeout > s.ReadTimeout:
+		return errors.New("read header timeout cannot exceed the read timeout")
+	case s.WriteTimeout < 0:
+		return errors.New("write timeout cannot be negative")
+	case s.IdleTimeout < 0:
+		return errors.New("idle timeout cannot be negative")
+	case s.MaxHeaderBytes < 4096 || s.MaxHeaderBytes > 16<<20:
+		return errors.New("max header bytes must be between 4096 and 16777216")
+	case s.ShutdownGracePeriod <= 0:
+		return errors.New("shutdown grace period must be positive")
+	}
+	return nil
+}
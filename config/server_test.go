@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    func(s *Server)
+		wantErr bool
+	}{
+		{name: "defaults", env: map[string]string{}},
+		{
+			name: "overrides",
+			env: map[string]string{
+				"HTTP_WRITE_TIMEOUT_SECONDS":  "300",
+				"HTTP_IDLE_TIMEOUT_SECONDS":   "0",
+				"HTTP_MAX_HEADER_BYTES":       "65536",
+				"HTTP_SHUTDOWN_GRACE_SECONDS": "30",
+			},
+			want: func(s *Server) {
+				s.WriteTimeout = 300 * time.Second
+				s.IdleTimeout = 0
+				s.MaxHeaderBytes = 65536
+				s.ShutdownGracePeriod = 30 * time.Second
+			},
+		},
+		{name: "not a number", env: map[string]string{"HTTP_READ_TIMEOUT_SECONDS": "15s"}, wantErr: true},
+		{name: "zero read timeout", env: map[string]string{"HTTP_READ_TIMEOUT_SECONDS": "0"}, wantErr: true},
+		{name: "header timeout above read timeout", env: map[string]string{"HTTP_READ_HEADER_TIMEOUT_SECONDS": "20"}, wantErr: true},
+		{name: "negative write timeout", env: map[string]string{"HTTP_WRITE_TIMEOUT_SECONDS": "-1"}, wantErr: true},
+		{name: "tiny header limit", env: map[string]string{"HTTP_MAX_HEADER_BYTES": "100"}, wantErr: true},
+		{name: "zero grace period", env: map[string]string{"HTTP_SHUTDOWN_GRACE_SECONDS": "0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ServerFromEnv(func(key string) string { return tt.env[key] })
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			want := DefaultServer()
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
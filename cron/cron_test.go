@@ -0,0 +1,157 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2025-04-08 17:09", "2025-04-08 17:15"},
+		{"0 3 * * *", "2025-04-08 17:09", "2025-04-09 03:00"},
+		{"0 3 * * *", "2025-04-09 03:00", "2025-04-10 03:00"},
+		{"@daily", "2025-12-31 23:59", "2026-01-01 00:00"},
+		{"@hourly", "2025-04-08 17:09", "2025-04-08 18:00"},
+		{"30 9 * * 1-5", "2025-04-11 10:00", "2025-04-14 09:30"},
+		{"0 0 * * 7", "2025-04-08 00:00", "2025-04-13 00:00"},
+		{"0 6 1,15 * *", "2025-04-02 00:00", "2025-04-15 06:00"},
+		{"0 0 31 * *", "2025-04-01 00:00", "2025-05-31 00:00"},
+		{"0 0 29 2 *", "2025-03-01 00:00", "2028-02-29 00:00"},
+		// Both day fields restricted: either matches
+		{"0 0 13 * 5", "2025-04-01 00:00", "2025-04-04 00:00"},
+		{"0 0 1 */3 *", "2025-02-10 00:00", "2025-04-01 00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" after "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, at(tt.want), s.Next(at(tt.from)))
+		})
+	}
+
+	t.Run("every", func(t *testing.T) {
+		s, err := Parse("@every 90s")
+		require.NoError(t, err)
+		assert.Equal(t, at("2025-04-08 17:10").Add(30*time.Second), s.Next(at("2025-04-08 17:09")))
+	})
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "5-1 * * * *", "*/0 * * * *", "0 0 30 2 *", "@every", "@every -1m", "@fortnightly"} {
+		t.Run("invalid "+spec, func(t *testing.T) {
+			_, err := Parse(spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// fakeLocker grants the lock unless held and records what it was given
+type fakeLocker struct {
+	held    bool
+	err     error
+	results []Result
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, job string) (func(context.Context, Result) error, bool, error) {
+	if l.err != nil || l.held {
+		return nil, false, l.err
+	}
+	return func(ctx context.Context, r Result) error {
+		l.results = append(l.results, r)
+		return nil
+	}, true, nil
+}
+
+func TestScheduler(t *testing.T) {
+	locker := &fakeLocker{}
+	reg := metrics.NewRegistry()
+	s := NewScheduler(locker, 0, reg)
+	now := at("2025-04-08 03:00")
+	s.now = func() time.Time { return now }
+
+	calls := 0
+	require.NoError(t, s.Register(Job{Name: "dormancy", Spec: "0 3 * * *", Schedule: Every(time.Hour),
+		Run: func(ctx context.Context, at time.Time) (int, error) {
+			calls++
+			assert.Equal(t, now, at)
+			if calls == 2 {
+				return 0, errors.New("database is down")
+			}
+			return 4, nil
+		}}))
+	assert.Error(t, s.Register(Job{Name: "dormancy"}), "duplicate name")
+
+	ctx := context.Background()
+	res := s.RunOnce(ctx, "dormancy")
+	assert.Equal(t, StatusSucceeded, res.Status)
+	assert.Equal(t, 4, res.Processed)
+
+	res = s.RunOnce(ctx, "dormancy")
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.EqualError(t, res.Err, "database is down")
+	require.Len(t, locker.results, 2, "every run that held the lock is recorded")
+
+	// Another instance holds the lock
+	locker.held = true
+	res = s.RunOnce(ctx, "dormancy")
+	assert.Equal(t, StatusSkipped, res.Status)
+	assert.Equal(t, 2, calls)
+
+	locker.held, locker.err = false, errors.New("connection refused")
+	res = s.RunOnce(ctx, "dormancy")
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.Equal(t, 2, calls)
+
+	status := s.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, "0 3 * * *", status[0].Spec)
+	assert.Equal(t, StatusFailed, status[0].LastRun.Status)
+
+	var out strings.Builder
+	reg.Write(&out)
+	assert.Contains(t, out.String(), `ledger_job_runs_total{job="dormancy",status="succeeded"} 1`)
+	assert.Contains(t, out.String(), `ledger_job_runs_total{job="dormancy",status="failed"} 2`)
+	assert.Contains(t, out.String(), `ledger_job_runs_total{job="dormancy",status="skipped"} 1`)
+	assert.Contains(t, out.String(), `ledger_job_duration_seconds_count{job="dormancy"} 2`)
+
+	t.Run("start runs jobs on schedule", func(t *testing.T) {
+		s := NewScheduler(&fakeLocker{}, 0, metrics.NewRegistry())
+		ran := make(chan struct{}, 1)
+		require.NoError(t, s.Register(Job{Name: "sweep", Schedule: Every(10 * time.Millisecond),
+			Run: func(ctx context.Context, _ time.Time) (int, error) {
+				select {
+				case ran <- struct{}{}:
+				default:
+				}
+				return 0, nil
+			}}))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.Start(ctx)
+		assert.Error(t, s.Register(Job{Name: "late"}), "registered after start")
+
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatal("job did not run")
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"ledger-service/metrics"
+)
+
+// Outcomes of a run
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusSkipped means another instance held the job's lock
+	StatusSkipped = "skipped"
+)
+
+// Job is recurring work registered with a Scheduler
+type Job struct {
+	// Name identifies the job in logs, metrics, status and its lock
+	Name string
+	// Spec is the schedule as configured, for display
+	Spec     string
+	Schedule Schedule
+	// Run does one pass of the work as of now and returns how many items it
+	// processed
+	Run func(ctx context.Context, now time.Time) (int, error)
+}
+
+// Result is the outcome of one run of a job
+type Result struct {
+	Job        string
+	Status     string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Processed  int
+	Err        error
+}
+
+// Locker keeps a job from running on two instances at once
+type Locker interface {
+	// TryLock claims job for one run, reporting ok false without an error
+	// when another instance holds it. unlock receives the run's result so it
+	// can be recorded before the lock is released.
+	TryLock(ctx context.Context, job string) (unlock func(context.Context, Result) error, ok bool, err error)
+}
+
+// JobStatus is a registered job as this instance sees it
+type JobStatus struct {
+	Name      string
+	Spec      string
+	NextRunAt time.Time
+	Running   bool
+	// LastRun is the last run this instance attempted, nil before the first
+	LastRun *Result
+}
+
+type entry struct {
+	job     Job
+	next    time.Time
+	running bool
+	last    *Result
+}
+
+// Scheduler runs each registered job on its schedule in its own goroutine.
+// A job never overlaps itself: a run that overruns its next slot makes the
+// scheduler skip that slot, and the Locker stops other instances running it
+// at the same time.
+type Scheduler struct {
+	locker Locker
+	// jitter delays each run by a random amount up to this long, so
+	// instances do not all contend for a lock at the same moment
+	jitter time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries []*entry
+	started bool
+
+	runs      *metrics.Counter
+	durations *metrics.Histogram
+}
+
+// NewScheduler creates a scheduler locking jobs with locker and reporting
+// their runs to r
+func NewScheduler(locker Locker, jitter time.Duration, r *metrics.Registry) *Scheduler {
+	s := &Scheduler{
+		locker: locker,
+		jitter: jitter,
+		now:    time.Now,
+		runs: r.NewCounter("ledger_job_runs_total",
+			"Scheduled job runs by outcome", "job", "status"),
+		durations: r.NewHistogram("ledger_job_duration_seconds",
+			"Time taken by scheduled job runs that held the lock",
+			[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}, "job"),
+	}
+	r.NewGaugeVecFunc("ledger_job_last_success_timestamp_seconds",
+		"When each scheduled job last succeeded on this instance", []string{"job"}, func() []metrics.Sample {
+			var samples []metrics.Sample
+			for _, st := range s.Status() {
+				if st.LastRun != nil && st.LastRun.Status == StatusSucceeded {
+					samples = append(samples, metrics.Sample{
+						LabelValues: []string{st.Name},
+						Value:       float64(st.LastRun.FinishedAt.Unix()),
+					})
+				}
+			}
+			return samples
+		})
+	return s
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("job %s registered after the scheduler started", job.Name)
+	}
+	for _, e := range s.entries {
+		if e.job.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+	s.entries = append(s.entries, &entry{job: job})
+	return nil
+}
+
+// Start runs every registered job until ctx is cancelled. It returns
+// immediately.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	now := s.now()
+	for _, e := range s.entries {
+		e.next = e.job.Schedule.Next(now)
+		go s.loop(ctx, e)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		s.mu.Lock()
+		next := e.next
+		s.mu.Unlock()
+
+		delay := next.Sub(s.now())
+		if s.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(s.jitter)))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.RunOnce(ctx, e.job.Name)
+
+		s.mu.Lock()
+		e.next = e.job.Schedule.Next(s.now())
+		s.mu.Unlock()
+	}
+}
+
+// RunOnce runs the named job now, under its lock, and returns the outcome
+func (s *Scheduler) RunOnce(ctx context.Context, name string) Result {
+	e := s.entry(name)
+	if e == nil {
+		return Result{Job: name, Status: StatusFailed, Err: fmt.Errorf("unknown job %s", name)}
+	}
+	s.mu.Lock()
+	e.running = true
+	s.mu.Unlock()
+
+	res := s.run(ctx, e.job)
+
+	s.mu.Lock()
+	e.running = false
+	e.last = &res
+	s.mu.Unlock()
+	s.runs.Inc(res.Job, res.Status)
+	return res
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) Result {
+	res := Result{Job: job.Name, StartedAt: s.now()}
+	unlock, ok, err := s.locker.TryLock(ctx, job.Name)
+	if err != nil {
+		res.Status, res.Err, res.FinishedAt = StatusFailed, err, s.now()
+		log.Printf("Job %s could not take its lock: %v", job.Name, err)
+		return res
+	}
+	if !ok {
+		res.Status, res.FinishedAt = StatusSkipped, s.now()
+		return res
+	}
+
+	res.Processed, res.Err = job.Run(ctx, res.StartedAt)
+	res.FinishedAt = s.now()
+	res.Status = StatusSucceeded
+	if res.Err != nil {
+		res.Status = StatusFailed
+		log.Printf("Job %s failed: %v", job.Name, res.Err)
+	} else if res.Processed > 0 {
+		log.Printf("Job %s processed %d items", job.Name, res.Processed)
+	}
+	s.durations.Observe(res.FinishedAt.Sub(res.StartedAt).Seconds(), job.Name)
+
+	if err := unlock(ctx, res); err != nil {
+		log.Printf("Job %s could not record its run: %v", job.Name, err)
+	}
+	return res
+}
+
+func (s *Scheduler) entry(name string) *entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.job.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Status lists the registered jobs by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		st := JobStatus{Name: e.job.Name, Spec: e.job.Spec, NextRunAt: e.next, Running: e.running}
+		if e.last != nil {
+			last := *e.last
+			st.LastRun = &last
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
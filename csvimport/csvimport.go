@@ -0,0 +1,465 @@
+// Package csvimport reads the CSV transaction exports of banks into
+// postings. Exports differ in delimiter, columns, date format and number
+// format, so each is described by a Profile rather than code.
+package csvimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxReferenceLength is the longest reference a row may carry, as long as
+// the ledger keeps
+const MaxReferenceLength = 140
+
+// Columns names where a row's values are: a header name, matched ignoring
+// case, or a 1-based position such as "3"
+type Columns struct {
+	Date string `json:"date" example:"Booking date"`
+	// Amount holds signed amounts. Exports with money out and money in in
+	// separate columns set Debit and Credit instead.
+	Amount string `json:"amount,omitempty" example:"Amount"`
+	Debit  string `json:"debit,omitempty" example:"Debit"`
+	Credit string `json:"credit,omitempty" example:"Credit"`
+	// Type is optional; its values are looked up in the profile's TypeMap
+	Type      string `json:"type,omitempty" example:"Transaction type"`
+	Reference string `json:"reference,omitempty" example:"Reference"`
+}
+
+// Profile describes the shape of one kind of export
+type Profile struct {
+	// Delimiter is a single character, "," when unset
+	Delimiter string `json:"delimiter,omitempty" example:";"`
+	// SkipRows lines are dropped before the header or first row, such as
+	// the account summary some banks start their exports with
+	SkipRows  int     `json:"skip_rows,omitempty" example:"0"`
+	HasHeader bool    `json:"has_header" example:"true"`
+	Columns   Columns `json:"columns"`
+	// DateFormat is written with YYYY, YY, MM, M, DD and D, e.g.
+	// DD.MM.YYYY; YYYY-MM-DD when unset
+	DateFormat string `json:"date_format,omitempty" example:"DD.MM.YYYY"`
+	// DecimalSeparator is "." or ",", "." when unset
+	DecimalSeparator string `json:"decimal_separator,omitempty" example:","`
+	// ThousandsSeparator, if the export groups digits, is ".", ",", " "
+	// or "'"
+	ThousandsSeparator string `json:"thousands_separator,omitempty" example:"."`
+	// TypeMap gives the transaction type of each value of the type
+	// column. Without a type column, or for values it does not list, a
+	// row's direction picks CreditType or DebitType.
+	TypeMap map[string]string `json:"type_map,omitempty"`
+	// CreditType and DebitType default to credit and debit
+	CreditType string `json:"credit_type,omitempty" example:"bank_credit"`
+	DebitType  string `json:"debit_type,omitempty" example:"bank_debit"`
+	// UniqueReferences makes a row's reference usable once per customer,
+	// so importing overlapping exports skips rows already imported
+	UniqueReferences bool `json:"unique_references" example:"true"`
+}
+
+// FieldError is a problem with one setting of a profile
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// SetDefaults fills in the settings left unset
+func (p *Profile) SetDefaults() {
+	if p.Delimiter == "" {
+		p.Delimiter = ","
+	}
+	if p.DateFormat == "" {
+		p.DateFormat = "YYYY-MM-DD"
+	}
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = "."
+	}
+	if p.CreditType == "" {
+		p.CreditType = "credit"
+	}
+	if p.DebitType == "" {
+		p.DebitType = "debit"
+	}
+}
+
+var (
+	dateFormatPattern = regexp.MustCompile(`^(YYYY|YY|MM|M|DD|D|[^A-Za-z0-9])+$`)
+	numberPattern     = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+	dateFormatTokens  = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "M", "1", "DD", "02", "D", "2")
+)
+
+// dateLayout turns a date format into a time layout, reporting false when
+// it is not one or misses the year, month or day
+func dateLayout(format string) (string, bool) {
+	if !dateFormatPattern.MatchString(format) {
+		return "", false
+	}
+	layout := dateFormatTokens.Replace(format)
+	sample := time.Date(2025, 11, 23, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, sample.Format(layout))
+	return layout, err == nil && parsed.Equal(sample)
+}
+
+// Validate checks a profile with its defaults set, returning every problem
+// found
+func (p *Profile) Validate() []FieldError {
+	var errs []FieldError
+	add := func(field, message string) {
+		errs = append(errs, FieldError{Field: field, Message: message})
+	}
+	if r, size := utf8.DecodeRuneInString(p.Delimiter); size != len(p.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		add("delimiter", "delimiter must be a single character other than a quote or line break")
+	}
+	if p.SkipRows < 0 || p.SkipRows > 100 {
+		add("skip_rows", "skip_rows must be between 0 and 100")
+	}
+	columns := []struct {
+		field, value string
+	}{
+		{"columns.date", p.Columns.Date},
+		{"columns.amount", p.Columns.Amount},
+		{"columns.debit", p.Columns.Debit},
+		{"columns.credit", p.Columns.Credit},
+		{"columns.type", p.Columns.Type},
+		{"columns.reference", p.Columns.Reference},
+	}
+	for _, c := range columns {
+		if c.value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(c.value); err == nil {
+			if n < 1 {
+				add(c.field, c.field+" must be a header name or a position from 1")
+			}
+		} else if !p.HasHeader {
+			add(c.field, c.field+" must be a position from 1 when the file has no header")
+		}
+	}
+	if p.Columns.Date == "" {
+		add("columns.date", "columns.date is required")
+	}
+	split := p.Columns.Debit != "" || p.Columns.Credit != ""
+	switch {
+	case p.Columns.Amount != "" && split:
+		add("columns.amount", "set columns.amount or columns.debit and columns.credit, not both")
+	case p.Columns.Amount == "" && !split:
+		add("columns.amount", "columns.amount, or columns.debit and columns.credit, is required")
+	case split && (p.Columns.Debit == "" || p.Columns.Credit == ""):
+		add("columns.debit", "columns.debit and columns.credit must be set together")
+	}
+	if _, ok := dateLayout(p.DateFormat); !ok {
+		add("date_format", "date_format must be built from YYYY or YY, MM or M, DD or D and separators, e.g. DD.MM.YYYY")
+	}
+	if p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		add("decimal_separator", `decimal_separator must be "." or ","`)
+	}
+	switch p.ThousandsSeparator {
+	case "", ".", ",", " ", "'":
+		if p.ThousandsSeparator == p.DecimalSeparator {
+			add("thousands_separator", "thousands_separator must differ from decimal_separator")
+		}
+	default:
+		add("thousands_separator", `thousands_separator must be ".", ",", " " or "'"`)
+	}
+	if len(p.TypeMap) > 0 && p.Columns.Type == "" {
+		add("type_map", "type_map needs columns.type")
+	}
+	for value, t := range p.TypeMap {
+		if t == "" {
+			add("type_map", fmt.Sprintf("type_map maps %q to no type", value))
+			break
+		}
+	}
+	return errs
+}
+
+// Row is a parsed line of an export
+type Row struct {
+	// Line is the row's line number in the file, counting from 1
+	Line      int       `json:"line"`
+	Date      time.Time `json:"date"`
+	Type      string    `json:"type"`
+	Amount    float64   `json:"amount"`
+	Reference string    `json:"reference,omitempty"`
+}
+
+// RowError is a line that could not be read
+type RowError struct {
+	Line    int
+	Message string
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// RowErrors are the lines of a file that could not be read
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more)", e[0].Error(), len(e)-1)
+}
+
+// ErrTooManyRows is returned for files with more rows than asked for
+var ErrTooManyRows = errors.New("too many rows")
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Read parses an export with p, which must have its defaults set and be
+// valid. It fails with RowErrors listing every line that cannot be read,
+// so a file is imported whole or not at all, and with ErrTooManyRows when
+// it has more than maxRows rows. Empty lines are skipped.
+func (p *Profile) Read(data []byte, maxRows int) ([]Row, error) {
+	layout, ok := dateLayout(p.DateFormat)
+	if !ok {
+		return nil, fmt.Errorf("invalid date format %q", p.DateFormat)
+	}
+	// The lines skipped are not read as CSV, as summaries often have fewer
+	// columns or stray quotes
+	data = bytes.TrimPrefix(data, utf8BOM)
+	for i := 0; i < p.SkipRows && len(data) > 0; i++ {
+		if n := bytes.IndexByte(data, '\n'); n >= 0 {
+			data = data[n+1:]
+		} else {
+			data = nil
+		}
+	}
+	delimiter, _ := utf8.DecodeRuneInString(p.Delimiter)
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = delimiter != ' ' && delimiter != '\t'
+
+	var header []string
+	if p.HasHeader {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil, errors.New("file has no header")
+		}
+		if err != nil {
+			return nil, err
+		}
+		header = record
+	}
+	cols, err := p.resolve(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	var errs RowErrors
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			errs = append(errs, RowError{Line: p.SkipRows + parseErr.Line, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if blank(record) {
+			continue
+		}
+		line, _ := r.FieldPos(0)
+		line += p.SkipRows
+		if len(rows)+len(errs) == maxRows {
+			return nil, ErrTooManyRows
+		}
+		row, msg := p.row(record, cols, layout)
+		if msg != "" {
+			errs = append(errs, RowError{Line: line, Message: msg})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return rows, nil
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// columnIndexes are the 0-based positions of a profile's columns, -1 for
+// columns not used
+type columnIndexes struct {
+	date, amount, debit, credit, kind, reference int
+}
+
+// resolve finds the profile's columns in header
+func (p *Profile) resolve(header []string) (columnIndexes, error) {
+	find := func(column string) (int, error) {
+		if column == "" {
+			return -1, nil
+		}
+		if n, err := strconv.Atoi(column); err == nil {
+			return n - 1, nil
+		}
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("header has no column %q", column)
+	}
+	var cols columnIndexes
+	var err error
+	for _, c := range []struct {
+		index  *int
+		column string
+	}{
+		{&cols.date, p.Columns.Date},
+		{&cols.amount, p.Columns.Amount},
+		{&cols.debit, p.Columns.Debit},
+		{&cols.credit, p.Columns.Credit},
+		{&cols.kind, p.Columns.Type},
+		{&cols.reference, p.Columns.Reference},
+	} {
+		if *c.index, err = find(c.column); err != nil {
+			return cols, err
+		}
+	}
+	return cols, nil
+}
+
+// row reads a record, returning why it cannot be imported if it cannot
+func (p *Profile) row(record []string, cols columnIndexes, layout string) (Row, string) {
+	value := func(i int) (string, bool) {
+		if i < 0 {
+			return "", true
+		}
+		if i >= len(record) {
+			return "", false
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+
+	var row Row
+	date, ok := value(cols.date)
+	if !ok {
+		return row, fmt.Sprintf("has no column %d for the date", cols.date+1)
+	}
+	d, err := time.Parse(layout, date)
+	if err != nil {
+		return row, fmt.Sprintf("date %q does not match %s", date, p.DateFormat)
+	}
+	row.Date = d
+
+	var amount float64
+	if cols.amount >= 0 {
+		v, ok := value(cols.amount)
+		if !ok {
+			return row, fmt.Sprintf("has no column %d for the amount", cols.amount+1)
+		}
+		if amount, err = p.parseAmount(v); err != nil {
+			return row, err.Error()
+		}
+	} else {
+		debit, okDebit := value(cols.debit)
+		credit, okCredit := value(cols.credit)
+		if !okDebit && !okCredit {
+			return row, "has no debit or credit column"
+		}
+		switch {
+		case debit != "" && credit != "":
+			return row, "has both a debit and a credit amount"
+		case debit != "":
+			if amount, err = p.parseAmount(debit); err != nil {
+				return row, err.Error()
+			}
+			amount = -math.Abs(amount)
+		case credit != "":
+			if amount, err = p.parseAmount(credit); err != nil {
+				return row, err.Error()
+			}
+			amount = math.Abs(amount)
+		default:
+			return row, "has neither a debit nor a credit amount"
+		}
+	}
+	if amount == 0 {
+		return row, "amount is zero"
+	}
+	row.Amount = math.Abs(amount)
+
+	// A mapped type holds whatever the amount's sign; the sign only picks
+	// the type of rows without one
+	row.Type = p.CreditType
+	if amount < 0 {
+		row.Type = p.DebitType
+	}
+	kind, ok := value(cols.kind)
+	if !ok {
+		return row, fmt.Sprintf("has no column %d for the type", cols.kind+1)
+	}
+	if t, mapped := p.TypeMap[kind]; mapped && kind != "" {
+		row.Type = t
+	}
+
+	if row.Reference, ok = value(cols.reference); !ok {
+		return row, fmt.Sprintf("has no column %d for the reference", cols.reference+1)
+	}
+	if utf8.RuneCountInString(row.Reference) > MaxReferenceLength {
+		return row, fmt.Sprintf("reference is longer than %d characters", MaxReferenceLength)
+	}
+	return row, ""
+}
+
+// parseAmount reads an amount in the profile's number format. A leading
+// minus, a trailing minus or parentheses make it negative.
+func (p *Profile) parseAmount(v string) (float64, error) {
+	s := v
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+		negative, s = true, s[1:len(s)-1]
+	case strings.HasSuffix(s, "-"):
+		negative, s = true, strings.TrimSuffix(s, "-")
+	}
+	if p.ThousandsSeparator != "" {
+		s = strings.ReplaceAll(s, p.ThousandsSeparator, "")
+	}
+	if p.DecimalSeparator != "." {
+		if strings.Contains(s, ".") {
+			return 0, fmt.Errorf("amount %q is not a number", v)
+		}
+		s = strings.Replace(s, p.DecimalSeparator, ".", 1)
+	}
+	s = strings.TrimSpace(s)
+	if !numberPattern.MatchString(s) {
+		return 0, fmt.Errorf("amount %q is not a number", v)
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is not a number", v)
+	}
+	if negative {
+		if amount < 0 {
+			return 0, fmt.Errorf("amount %q is not a number", v)
+		}
+		amount = -amount
+	}
+	return amount, nil
+}
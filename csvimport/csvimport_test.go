@@ -0,0 +1,107 @@
+package csvimport
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	// A German bank: a summary line, semicolons, DD.MM.YYYY dates and
+	// 1.234,56 amounts, with the booking text mapped to types
+	german := Profile{
+		Delimiter: ";",
+		SkipRows:  1,
+		HasHeader: true,
+		Columns: Columns{
+			Date:      "buchungstag",
+			Amount:    "Betrag",
+			Type:      "Buchungstext",
+			Reference: "Verwendungszweck",
+		},
+		DateFormat:         "DD.MM.YYYY",
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+		TypeMap:            map[string]string{"Entgelt": "fee"},
+	}
+	german.SetDefaults()
+	require.Empty(t, german.Validate())
+	rows, err := german.Read([]byte("\xEF\xBB\xBFKonto DE89370400440532013000;\"Stand\n"+
+		"Buchungstag;Buchungstext;Verwendungszweck;Betrag\n"+
+		"01.04.2025;Gutschrift;Gehalt April;1.234,56\n"+
+		"\n"+
+		"02.04.2025;Entgelt;Kontoführung;-4,90\n"+
+		"03.04.2025;Lastschrift;\"Miete; April\";-800\n"), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{Line: 3, Date: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), Type: "credit", Amount: 1234.56, Reference: "Gehalt April"},
+		{Line: 5, Date: time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC), Type: "fee", Amount: 4.9, Reference: "Kontoführung"},
+		{Line: 6, Date: time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC), Type: "debit", Amount: 800, Reference: "Miete; April"},
+	}, rows)
+
+	// No header, separate debit and credit columns, US dates
+	split := Profile{
+		Columns:    Columns{Date: "1", Debit: "3", Credit: "4", Reference: "2"},
+		DateFormat: "M/D/YY",
+		CreditType: "bank_credit",
+		DebitType:  "bank_debit",
+	}
+	split.SetDefaults()
+	require.Empty(t, split.Validate())
+	rows, err = split.Read([]byte("4/1/25,Payroll,,\"2,500.00\"\n4/12/25,Card,(12.50),\n"), 10)
+	assert.Error(t, err, "a thousands separator the profile does not know")
+	split.ThousandsSeparator = ","
+	rows, err = split.Read([]byte("4/1/25,Payroll,,\"2,500.00\"\n4/12/25,Card,(12.50),\n"), 10)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, Row{Line: 1, Date: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), Type: "bank_credit", Amount: 2500, Reference: "Payroll"}, rows[0])
+	assert.Equal(t, "bank_debit", rows[1].Type)
+	assert.Equal(t, 12.5, rows[1].Amount)
+
+	// Every bad line is reported and nothing is returned
+	rows, err = split.Read([]byte("4/1/25,A,,1\n13/1/25,B,,1\n4/3/25,C,1,1\n4/4/25,D,,\n4/5/25,E,,0x1p4\n4/6\n"), 10)
+	var rowErrs RowErrors
+	require.ErrorAs(t, err, &rowErrs)
+	assert.Nil(t, rows)
+	lines := []int{}
+	for _, e := range rowErrs {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []int{2, 3, 4, 5, 6}, lines)
+	assert.Contains(t, rowErrs[0].Message, "does not match M/D/YY")
+
+	_, err = split.Read([]byte("4/1/25,A,,1\n4/2/25,B,,1\n4/3/25,C,,1\n"), 2)
+	assert.True(t, errors.Is(err, ErrTooManyRows))
+	_, err = german.Read([]byte("summary\nDatum;Betrag\n"), 10)
+	assert.ErrorContains(t, err, `header has no column "buchungstag"`)
+}
+
+func TestValidate(t *testing.T) {
+	fields := func(p Profile) []string {
+		p.SetDefaults()
+		var names []string
+		for _, e := range p.Validate() {
+			names = append(names, e.Field)
+		}
+		return names
+	}
+
+	assert.Empty(t, fields(Profile{Columns: Columns{Date: "1", Amount: "2"}}))
+	assert.Equal(t, []string{"columns.date", "columns.amount"}, fields(Profile{}))
+	assert.Equal(t, []string{"columns.amount"}, fields(Profile{Columns: Columns{Date: "1", Amount: "2", Debit: "3", Credit: "4"}}))
+	assert.Equal(t, []string{"columns.debit"}, fields(Profile{Columns: Columns{Date: "1", Debit: "3"}}))
+	assert.Equal(t, []string{"columns.date"}, fields(Profile{Columns: Columns{Date: "Date", Amount: "2"}}), "names need a header")
+	assert.Equal(t, []string{"columns.amount"}, fields(Profile{Columns: Columns{Date: "1", Amount: "0"}}))
+	assert.Equal(t, []string{"delimiter"}, fields(Profile{Delimiter: ";;", Columns: Columns{Date: "1", Amount: "2"}}))
+	assert.Equal(t, []string{"type_map"}, fields(Profile{Columns: Columns{Date: "1", Amount: "2"}, TypeMap: map[string]string{"FEE": "fee"}}))
+	assert.Equal(t, []string{"thousands_separator"}, fields(Profile{Columns: Columns{Date: "1", Amount: "2"}, DecimalSeparator: ",", ThousandsSeparator: ","}))
+	for _, format := range []string{"DD.MM.", "YYYY-MM-DDTHH", "MM/YYYY", "Jan 2 2006"} {
+		assert.Equal(t, []string{"date_format"}, fields(Profile{Columns: Columns{Date: "1", Amount: "2"}, DateFormat: format}), format)
+	}
+	for _, format := range []string{"YYYYMMDD", "D.M.YYYY", "MM/DD/YY", "YYYY-MM-DD"} {
+		assert.Empty(t, fields(Profile{Columns: Columns{Date: "1", Amount: "2"}, DateFormat: format}), format)
+	}
+}
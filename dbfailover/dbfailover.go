@@ -0,0 +1,147 @@
+// Package dbfailover lets the connection pool follow a Postgres failover
+// without a restart. Every new connection tries the candidate servers in
+// order, resolving their host names again each time, and skips servers that
+// are read-only. When every candidate is failing, new connection attempts
+// back off exponentially.
+package dbfailover
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Failover tracks connection failures across all candidates
+type Failover struct {
+	// BaseDelay is the wait once every candidate has failed; it doubles with
+	// each further round of failures up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	candidates int
+	sleep      func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	failures int
+	current  string
+}
+
+// Configure adds the servers in urls as failover candidates after those in
+// conf, and installs the backoff and read-write check on conf. Candidates
+// must share conf's database, user and password since only their address
+// and TLS settings are used.
+func Configure(conf *pgxpool.Config, urls []string, baseDelay, maxDelay time.Duration) (*Failover, error) {
+	cc := conf.ConnConfig
+	for _, u := range urls {
+		candidate, err := pgconn.ParseConfig(u)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failover URL: %w", err)
+		}
+		if candidate.Database != cc.Database || candidate.User != cc.User || candidate.Password != cc.Password {
+			return nil, fmt.Errorf("failover URL for %s must use the same database, user and password as DATABASE_URL", candidate.Host)
+		}
+		cc.Fallbacks = append(cc.Fallbacks, &pgconn.FallbackConfig{Host: candidate.Host, Port: candidate.Port, TLSConfig: candidate.TLSConfig})
+		cc.Fallbacks = append(cc.Fallbacks, candidate.Fallbacks...)
+	}
+
+	f := &Failover{
+		BaseDelay:  baseDelay,
+		MaxDelay:   maxDelay,
+		candidates: 1 + len(cc.Fallbacks),
+		sleep:      sleepContext,
+	}
+
+	dial := cc.DialFunc
+	cc.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			f.failed()
+		}
+		return conn, err
+	}
+
+	// A demoted primary accepts connections but not writes
+	validate := cc.ValidateConnect
+	if validate == nil && f.candidates > 1 {
+		validate = pgconn.ValidateConnectTargetSessionAttrsReadWrite
+	}
+	cc.ValidateConnect = func(ctx context.Context, pgConn *pgconn.PgConn) error {
+		if validate != nil {
+			if err := validate(ctx, pgConn); err != nil {
+				f.failed()
+				return err
+			}
+		}
+		f.connected(pgConn.Conn().RemoteAddr().String())
+		return nil
+	}
+
+	beforeConnect := conf.BeforeConnect
+	conf.BeforeConnect = func(ctx context.Context, c *pgx.ConnConfig) error {
+		if err := f.sleep(ctx, f.Delay()); err != nil {
+			return err
+		}
+		if beforeConnect != nil {
+			return beforeConnect(ctx, c)
+		}
+		return nil
+	}
+	return f, nil
+}
+
+// Delay is how long the next connection attempt waits: nothing until a full
+// round of candidates has failed, then BaseDelay doubling per round
+func (f *Failover) Delay() time.Duration {
+	f.mu.Lock()
+	rounds := f.failures / f.candidates
+	f.mu.Unlock()
+	if rounds == 0 || f.BaseDelay <= 0 {
+		return 0
+	}
+	d := f.BaseDelay
+	for i := 1; i < rounds && d < f.MaxDelay; i++ {
+		d *= 2
+	}
+	if f.MaxDelay > 0 && d > f.MaxDelay {
+		d = f.MaxDelay
+	}
+	return d
+}
+
+func (f *Failover) failed() {
+	f.mu.Lock()
+	f.failures++
+	f.mu.Unlock()
+}
+
+// connected resets the backoff and logs when connections move to another server
+func (f *Failover) connected(addr string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = 0
+	if f.current != "" && f.current != addr {
+		log.Printf("Database connections moved from %s to %s", f.current, addr)
+	}
+	f.current = addr
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+// Package dbhealth reports connection pool usage and replication lag for
+// capacity planning, on the metrics endpoint and the verbose health check.
+package dbhealth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ledger-service/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolStats is a snapshot of the connection pool
+type PoolStats struct {
+	AcquiredConns int32 `json:"acquired_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	TotalConns    int32 `json:"total_conns"`
+	MaxConns      int32 `json:"max_conns"`
+	// AcquireCount counts every connection handed out
+	AcquireCount int64 `json:"acquire_count"`
+	// WaitedAcquireCount counts acquires that found no idle connection
+	WaitedAcquireCount int64 `json:"waited_acquire_count"`
+	// CanceledAcquireCount counts acquires abandoned because their context
+	// ended, which is how acquire timeouts show up
+	CanceledAcquireCount int64 `json:"canceled_acquire_count"`
+	// AcquireDurationSeconds is the total time spent acquiring connections
+	AcquireDurationSeconds float64 `json:"acquire_duration_seconds"`
+}
+
+// Pool reads the current statistics of p
+func Pool(p *pgxpool.Pool) PoolStats {
+	s := p.Stat()
+	return PoolStats{
+		AcquiredConns:          s.AcquiredConns(),
+		IdleConns:              s.IdleConns(),
+		TotalConns:             s.TotalConns(),
+		MaxConns:               s.MaxConns(),
+		AcquireCount:           s.AcquireCount(),
+		WaitedAcquireCount:     s.EmptyAcquireCount(),
+		CanceledAcquireCount:   s.CanceledAcquireCount(),
+		AcquireDurationSeconds: s.AcquireDuration().Seconds(),
+	}
+}
+
+// Replica is a standby streaming from the primary
+type Replica struct {
+	Name       string  `json:"name"`
+	State      string  `json:"state"`
+	LagSeconds float64 `json:"lag_seconds"`
+}
+
+// Querier runs the replication query; *pgxpool.Pool satisfies it
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Replicas lists the standbys attached to the primary with their replay
+// lag. It is empty when no read replicas are configured.
+func Replicas(ctx context.Context, q Querier) ([]Replica, error) {
+	rows, err := q.Query(ctx,
+		"SELECT COALESCE(application_name, ''), COALESCE(state, ''), COALESCE(EXTRACT(EPOCH FROM replay_lag), 0)::float8 FROM pg_stat_replication ORDER BY application_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replicas := []Replica{}
+	for rows.Next() {
+		var r Replica
+		if err := rows.Scan(&r.Name, &r.State, &r.LagSeconds); err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, r)
+	}
+	return replicas, rows.Err()
+}
+
+// Register adds pool and replication metrics to r. Replication lag is
+// queried at scrape time, allowing it timeout.
+func Register(r *metrics.Registry, p *pgxpool.Pool, timeout time.Duration) {
+	r.NewGaugeVecFunc("ledger_db_pool_connections", "Pool connections by state", []string{"state"}, func() []metrics.Sample {
+		s := Pool(p)
+		return []metrics.Sample{
+			{LabelValues: []string{"acquired"}, Value: float64(s.AcquiredConns)},
+			{LabelValues: []string{"idle"}, Value: float64(s.IdleConns)},
+		}
+	})
+	r.NewGaugeFunc("ledger_db_pool_max_connections", "Largest size the pool can grow to", func() float64 {
+		return float64(p.Stat().MaxConns())
+	})
+	r.NewCounterFunc("ledger_db_pool_acquires_total", "Connections acquired from the pool", func() float64 {
+		return float64(p.Stat().AcquireCount())
+	})
+	r.NewCounterFunc("ledger_db_pool_waited_acquires_total", "Acquires that had to wait for a connection", func() float64 {
+		return float64(p.Stat().EmptyAcquireCount())
+	})
+	r.NewCounterFunc("ledger_db_pool_canceled_acquires_total", "Acquires abandoned before a connection was free", func() float64 {
+		return float64(p.Stat().CanceledAcquireCount())
+	})
+	r.NewCounterFunc("ledger_db_pool_acquire_duration_seconds_total", "Time spent acquiring connections", func() float64 {
+		return p.Stat().AcquireDuration().Seconds()
+	})
+	r.NewGaugeVecFunc("ledger_db_replication_lag_seconds", "Replay lag of each streaming replica", []string{"replica"}, func() []metrics.Sample {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		replicas, err := Replicas(ctx, p)
+		if err != nil {
+			log.Printf("Failed to read replication lag: %v", err)
+			return nil
+		}
+		samples := make([]metrics.Sample, len(replicas))
+		for i, replica := range replicas {
+			samples[i] = metrics.Sample{LabelValues: []string{replica.Name}, Value: replica.LagSeconds}
+		}
+		return samples
+	})
+}
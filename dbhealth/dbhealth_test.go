@@ -0,0 +1,66 @@
+package dbhealth
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"ledger-service/metrics"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicas(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	mock.ExpectQuery("FROM pg_stat_replication").
+		WillReturnRows(pgxmock.NewRows([]string{"application_name", "state", "lag"}).
+			AddRow("replica-a", "streaming", 0.25).
+			AddRow("replica-b", "catchup", 12.5))
+
+	replicas, err := Replicas(context.Background(), mock)
+	assert.NoError(t, err)
+	assert.Equal(t, []Replica{
+		{Name: "replica-a", State: "streaming", LagSeconds: 0.25},
+		{Name: "replica-b", State: "catchup", LagSeconds: 12.5},
+	}, replicas)
+
+	// No replicas configured
+	mock.ExpectQuery("FROM pg_stat_replication").
+		WillReturnRows(pgxmock.NewRows([]string{"application_name", "state", "lag"}))
+	replicas, err = Replicas(context.Background(), mock)
+	assert.NoError(t, err)
+	assert.Empty(t, replicas)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPoolMetrics(t *testing.T) {
+	// The pool connects lazily, so statistics are readable without a server
+	conf, err := pgxpool.ParseConfig("postgres://ledger@127.0.0.1:1/ledger?pool_max_conns=7&connect_timeout=1")
+	assert.NoError(t, err)
+	pool, err := pgxpool.NewWithConfig(context.Background(), conf)
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	stats := Pool(pool)
+	assert.Equal(t, int32(7), stats.MaxConns)
+	assert.Equal(t, int32(0), stats.AcquiredConns)
+
+	r := metrics.NewRegistry()
+	Register(r, pool, 100*time.Millisecond)
+	var out bytes.Buffer
+	r.Write(&out)
+	assert.Contains(t, out.String(), `ledger_db_pool_connections{state="acquired"} 0`)
+	assert.Contains(t, out.String(), "ledger_db_pool_max_connections 7")
+	assert.Contains(t, out.String(), "# TYPE ledger_db_pool_acquires_total counter")
+	// Replication lag is left out when the database cannot be reached
+	assert.Contains(t, out.String(), "# TYPE ledger_db_replication_lag_seconds gauge\n")
+	assert.NotContains(t, out.String(), "ledger_db_replication_lag_seconds{")
+}
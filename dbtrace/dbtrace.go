@@ -0,0 +1,96 @@
+// Package dbtrace times every pgx query, recording durations in the metrics
+// registry and logging the slow ones.
+package dbtrace
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"ledger-service/metrics"
+	"ledger-service/middleware"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tracer is a pgx.QueryTracer. Durations are recorded per SQL operation
+// (select, insert, update, delete, other) so the metric stays small no matter
+// how many distinct statements run.
+type Tracer struct {
+	// SlowQuery is the duration at which a query is logged; 0 disables logging
+	SlowQuery time.Duration
+	// Metrics records durations and errors when set
+	Metrics bool
+	// Logf defaults to log.Printf
+	Logf func(format string, args ...interface{})
+
+	durations *metrics.Histogram
+	errors    *metrics.Counter
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+// New creates a tracer registering its metrics on r
+func New(r *metrics.Registry, slowQuery time.Duration, recordMetrics bool) *Tracer {
+	return &Tracer{
+		SlowQuery: slowQuery,
+		Metrics:   recordMetrics,
+		Logf:      log.Printf,
+		durations: r.NewHistogram("ledger_db_query_duration_seconds",
+			"Time taken by database queries", metrics.DefaultBuckets, "operation"),
+		errors: r.NewCounter("ledger_db_query_errors_total",
+			"Database queries that returned an error", "operation"),
+	}
+}
+
+// TraceQueryStart notes when a query began
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, start: time.Now()})
+}
+
+// TraceQueryEnd records how long the query took
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(q.start)
+	op := Operation(q.sql)
+	if t.Metrics {
+		t.durations.Observe(elapsed.Seconds(), op)
+		if data.Err != nil {
+			t.errors.Inc(op)
+		}
+	}
+	if t.SlowQuery > 0 && elapsed >= t.SlowQuery {
+		requestID := middleware.RequestIDFromContext(ctx)
+		if requestID == "" {
+			requestID = "-"
+		}
+		t.Logf("Slow query | %s | %v | %s", requestID, elapsed, compact(q.sql))
+	}
+}
+
+// Operation classifies sql by its leading keyword
+func Operation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch op := strings.ToLower(fields[0]); op {
+	case "select", "insert", "update", "delete":
+		return op
+	}
+	return "other"
+}
+
+// compact collapses whitespace so a multi-line statement logs on one line
+func compact(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
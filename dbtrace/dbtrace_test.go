@@ -0,0 +1,59 @@
+package dbtrace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"ledger-service/metrics"
+	"ledger-service/middleware"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperation(t *testing.T) {
+	assert.Equal(t, "select", Operation("SELECT balance FROM customers"))
+	assert.Equal(t, "insert", Operation("\n\t\tinsert INTO outbox"))
+	assert.Equal(t, "other", Operation("WITH moved AS (SELECT 1) SELECT * FROM moved"))
+	assert.Equal(t, "other", Operation(""))
+}
+
+func TestTracer(t *testing.T) {
+	registry := metrics.NewRegistry()
+	tracer := New(registry, time.Nanosecond, true)
+	var logged []string
+	tracer.Logf = func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	ctx := middleware.WithRequestID(context.Background(), "req-1")
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT balance\n  FROM customers"})
+	time.Sleep(time.Millisecond)
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{})
+	qctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "UPDATE customers SET balance = 0"})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("deadlock detected")})
+
+	assert.Len(t, logged, 2)
+	assert.Contains(t, logged[0], "| req-1 |")
+	assert.Contains(t, logged[0], "SELECT balance FROM customers")
+	assert.Contains(t, logged[1], "| - |")
+
+	var out bytes.Buffer
+	registry.Write(&out)
+	assert.Contains(t, out.String(), `ledger_db_query_duration_seconds_count{operation="select"} 1`)
+	assert.Contains(t, out.String(), `ledger_db_query_duration_seconds_count{operation="update"} 1`)
+	assert.Contains(t, out.String(), `ledger_db_query_errors_total{operation="update"} 1`)
+}
+
+func TestTracerFastQueriesNotLogged(t *testing.T) {
+	tracer := New(metrics.NewRegistry(), time.Hour, false)
+	tracer.Logf = func(format string, args ...interface{}) {
+		t.Errorf("unexpected log: "+format, args...)
+	}
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+}
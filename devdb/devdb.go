@@ -0,0 +1,82 @@
+// Package devdb runs a private Postgres server for local development and
+// demos, so the service can start without a database being provisioned.
+// The server binaries are downloaded once and cached; the data directory
+// survives restarts.
+package devdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// Config chooses where the embedded server keeps its files and listens
+type Config struct {
+	// Dir holds the binaries cache, runtime files and data directory
+	Dir  string
+	Port uint32
+}
+
+// Server is a running embedded Postgres
+type Server struct {
+	// URL connects to the ledger database on the server
+	URL string
+
+	pg *embeddedpostgres.EmbeddedPostgres
+}
+
+// Start launches the server and applies schema, which must be safe to run
+// again on an existing database like migrations/init.sql is
+func Start(ctx context.Context, cfg Config, schema string) (*Server, error) {
+	if os.Geteuid() == 0 {
+		return nil, errors.New("the embedded database cannot run as root")
+	}
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	pgConf := embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V16).
+		Port(cfg.Port).
+		Database("ledger_db").
+		Username("ledger").
+		Password("ledger").
+		CachePath(filepath.Join(dir, "cache")).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		Logger(log.Writer())
+	s := &Server{URL: pgConf.GetConnectionURL() + "?sslmode=disable", pg: embeddedpostgres.NewDatabase(pgConf)}
+
+	log.Printf("Starting embedded Postgres in %s on port %d", dir, cfg.Port)
+	if err := s.pg.Start(); err != nil {
+		return nil, fmt.Errorf("start embedded postgres: %w", err)
+	}
+	if err := s.migrate(ctx, schema); err != nil {
+		s.Stop()
+		return nil, fmt.Errorf("apply schema: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Server) migrate(ctx context.Context, schema string) error {
+	conn, err := pgx.Connect(ctx, s.URL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	// Without arguments the script runs over the simple protocol, which
+	// accepts many statements at once
+	_, err = conn.Exec(ctx, schema)
+	return err
+}
+
+// Stop shuts the server down, keeping its data
+func (s *Server) Stop() error {
+	return s.pg.Stop()
+}
@@ -0,0 +1,17 @@
+package devdb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartRefusesRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("only meaningful when running as root")
+	}
+	_, err := Start(context.Background(), Config{Dir: t.TempDir(), Port: 54329}, "")
+	assert.ErrorContains(t, err, "cannot run as root")
+}
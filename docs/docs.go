@@ -15,9 +15,93 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/customers": {
+        "/accounts/by-alias/{alias}": {
+            "get": {
+                "description": "Find the account behind an alias, e.g. to confirm a payee before paying them. The leading @ is optional and aliases are case-insensitive.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "aliases"
+                ],
+                "summary": "Look up an account by alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "@coffee-shop",
+                        "description": "Alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AliasLookup"
+                        }
+                    },
+                    "404": {
+                        "description": "Alias not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/accounts": {
+            "get": {
+                "description": "List the chart of accounts: the system accounts (fees income, interest expense, FX gains and losses, FX rounding differences, suspense) and any accounts opened by operators, with their balances on their normal side",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List general ledger accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "General ledger accounts",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.GLAccount"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Create a new customer account with initial balance",
+                "description": "Add an account to the chart of accounts. Transaction types can then name it as their counterparty.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,35 +109,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
                 ],
-                "summary": "Create a new customer account",
+                "summary": "Open a general ledger account",
                 "parameters": [
                     {
-                        "description": "Customer information",
-                        "name": "customer",
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Account",
+                        "name": "account",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.Customer"
+                            "$ref": "#/definitions/handlers.GLAccountRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created",
+                        "description": "Account opened",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CustomerResponse"
+                            "$ref": "#/definitions/handlers.GLAccount"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Account already exists",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -61,46 +164,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/customers/{customer_id}/balance": {
+        "/admin/accounts/{code}": {
             "get": {
-                "description": "Get the current balance for a customer",
+                "description": "Get a general ledger account and its current balance",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
                 ],
-                "summary": "Get customer balance",
+                "summary": "Get a general ledger account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Customer ID",
-                        "name": "customer_id",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Account code",
+                        "name": "code",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "General ledger account",
                         "schema": {
-                            "$ref": "#/definitions/handlers.BalanceResponse"
+                            "$ref": "#/definitions/handlers.GLAccount"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Invalid admin credentials",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Account not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -108,46 +218,56 @@ const docTemplate = `{
                 }
             }
         },
-        "/customers/{customer_id}/transactions": {
+        "/admin/accounts/{code}/entries": {
             "get": {
-                "description": "Get paginated transaction history for a customer",
+                "description": "List the postings to a general ledger account, most recent first, each linked to the customer transaction it balances",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "admin"
                 ],
-                "summary": "Get transaction history",
+                "summary": "List general ledger account entries",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Customer ID",
-                        "name": "customer_id",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Account code",
+                        "name": "code",
                         "in": "path",
                         "required": true
                     },
                     {
+                        "minimum": 1,
                         "type": "integer",
                         "default": 1,
-                        "description": "Page number",
+                        "description": "Page number (1-based)",
                         "name": "page",
                         "in": "query"
                     },
                     {
+                        "maximum": 100,
+                        "minimum": 1,
                         "type": "integer",
                         "default": 10,
-                        "description": "Items per page",
+                        "description": "Number of items per page",
                         "name": "page_size",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Account entries",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.Transaction"
+                                "$ref": "#/definitions/handlers.GLEntry"
                             }
                         },
                         "headers": {
@@ -161,7 +281,7 @@ const docTemplate = `{
                             },
                             "X-Total-Count": {
                                 "type": "string",
-                                "description": "Total number of transactions"
+                                "description": "Total number of entries"
                             },
                             "X-Total-Pages": {
                                 "type": "string",
@@ -170,19 +290,25 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Account not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -190,9 +316,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/transactions": {
+        "/admin/adjustments": {
             "post": {
-                "description": "Create a new credit or debit transaction for a customer",
+                "description": "Post a manual correction (write-off, goodwill credit or error fix) as an adjustment transaction. A reason code and justification are required, and the adjustment is recorded in the audit log under the calling operator.",
                 "consumes": [
                     "application/json"
                 ],
@@ -200,162 +326,15357 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "admin"
                 ],
-                "summary": "Create a new transaction",
+                "summary": "Adjust a customer balance",
                 "parameters": [
                     {
-                        "description": "Transaction information",
-                        "name": "transaction",
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator making the adjustment",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Adjustment",
+                        "name": "adjustment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.Transaction"
+                            "$ref": "#/definitions/handlers.AdjustmentRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created",
+                        "description": "Adjustment posted",
                         "schema": {
-                            "$ref": "#/definitions/handlers.TransactionResponse"
+                            "$ref": "#/definitions/handlers.AdjustmentResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Customer not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.BalanceResponse": {
-            "type": "object",
-            "properties": {
-                "balance": {
-                    "type": "number",
-                    "example": 800
-                },
-                "customer_id": {
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
-                }
-            }
         },
-        "handlers.Customer": {
-            "description": "Customer account information",
-            "type": "object",
-            "required": [
-                "name"
-            ],
-            "properties": {
-                "balance": {
-                    "type": "number",
-                    "example": 1000
-                },
-                "customer_id": {
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
-                },
-                "initial_balance": {
-                    "type": "number",
-                    "example": 1000
-                },
-                "name": {
-                    "type": "string",
-                    "example": "John Doe"
+        "/admin/alias-reservations": {
+            "get": {
+                "description": "List the aliases customers cannot claim: those reserved by operators, and those held for the customer who released them until they expire",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List alias reservations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reservations",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.AliasReservation"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Stop customers claiming an alias, such as one that could be mistaken for the bank. An alias a customer already holds cannot be reserved. The reservation is recorded in the audit log under the calling operator.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reserve an alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator making the reservation",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Reservation",
+                        "name": "reservation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AliasReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Alias reserved",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AliasReservation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Alias is held by a customer",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "handlers.CustomerResponse": {
-            "type": "object",
-            "properties": {
-                "balance": {
-                    "type": "number",
-                    "example": 1000
-                },
-                "customer_id": {
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
-                },
-                "name": {
-                    "type": "string",
-                    "example": "John Doe"
+        "/admin/alias-reservations/{alias}": {
+            "delete": {
+                "description": "Let customers claim a reserved alias again, including one held for the customer who released it. The removal is recorded in the audit log under the calling operator.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Remove an alias reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator removing the reservation",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "@support",
+                        "description": "Alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Reservation removed"
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reservation not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys": {
+            "get": {
+                "description": "List the scoped API keys, newest first, including revoked and expired ones. The keys themselves are not included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List API keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Keys",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a key for an integration, limited to the scopes it is granted. Sent in the ` + "`" + `X-API-Key` + "`" + ` header or as ` + "`" + `Authorization: Bearer \u003ckey\u003e` + "`" + `, it opens the routes its scopes cover and nothing else. Reads need a ` + "`" + `:read` + "`" + ` scope and writes a ` + "`" + `:write` + "`" + ` one; ` + "`" + `admin:adjust` + "`" + ` covers adjustments, backdated postings and reconciliation adjustments, and ` + "`" + `webhooks:manage` + "`" + ` the webhook endpoints. Keys cannot manage API keys. The key is returned once and only its hash is stored. Creation is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "API key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Key created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or unknown scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys/{key_id}": {
+            "delete": {
+                "description": "Revoke a scoped API key so it stops working immediately. Revocation is recorded in the audit log.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Key ID",
+                        "name": "key_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Key revoked"
+                    },
+                    "400": {
+                        "description": "Invalid key ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Key not found or already revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Replace the scopes of a live API key. The change applies from the key's next request, so the key need not be rotated. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Change an API key's scopes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Key ID",
+                        "name": "key_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scopes",
+                        "name": "scopes",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyScopesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Scopes changed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or unknown scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Key not found or revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/audit": {
+            "get": {
+                "description": "Search every recorded change by actor, entity and time, most recent first. format=csv or format=jsonl exports all matching entries instead of a page; exports are themselves audited.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Query the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "adjustment",
+                        "description": "Only changes to this entity type",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only changes to this entity",
+                        "name": "entity_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Changes at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Changes before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (1-based)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Audit entries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.AuditEntry"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "string",
+                                "description": "Current page number"
+                            },
+                            "X-Page-Size": {
+                                "type": "string",
+                                "description": "Items per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "string",
+                                "description": "Total number of entries"
+                            },
+                            "X-Total-Pages": {
+                                "type": "string",
+                                "description": "Total number of pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, format or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/allow-negative": {
+            "put": {
+                "description": "Let an internal or settlement account go below zero: postings and transfers debiting it skip the insufficient-balance check, and each one that takes it below zero is recorded in the audit log as balance.overdrawn. The change itself is audited under the calling operator. The flag cannot be cleared while the balance is negative.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Allow or forbid a negative balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator making the change",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Setting",
+                        "name": "setting",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AllowNegativeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Setting updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AllowNegativeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Balance is negative",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/audit": {
+            "get": {
+                "description": "List every recorded change to the customer's account, most recent first. Accepts the same filters and export formats as the audit log query.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a customer's audit trail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "adjustment",
+                        "description": "Only changes to this entity type",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only changes to this entity",
+                        "name": "entity_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Changes at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Changes before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "jsonl"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (1-based)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Audit entries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.AuditEntry"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "string",
+                                "description": "Current page number"
+                            },
+                            "X-Page-Size": {
+                                "type": "string",
+                                "description": "Items per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "string",
+                                "description": "Total number of entries"
+                            },
+                            "X-Total-Pages": {
+                                "type": "string",
+                                "description": "Total number of pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID, filter, format or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/imports": {
+            "post": {
+                "description": "Post the rows of a bank's CSV export to a customer, read with a stored import profile. The whole file is read first: if any line cannot be read, nothing is posted and every bad line is reported. Rows are then posted in file order, each on the date of its date column as value date, and each row's outcome is reported; a row that cannot be posted, e.g. for lack of funds, does not stop the rest. Rows whose reference was already used are reported as duplicates when the profile has unique_references. At most 1000 rows are imported at once, and the file is bounded by MAX_REQUEST_BODY_BYTES. With Prefer: respond-async the file is still read straight away, but its rows are posted in the background: the response is 202 with the queued job, whose status is at the Location header, and the job's result is the import's outcome. A queued import is attempted once, so rows are never posted twice.",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import a CSV export into a customer's account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "acme-bank",
+                        "description": "Import profile name",
+                        "name": "profile",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "respond-async to post the rows in the background",
+                        "name": "Prefer",
+                        "in": "header"
+                    },
+                    {
+                        "description": "CSV export",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rows imported",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImportResult"
+                        }
+                    },
+                    "202": {
+                        "description": "Import queued",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Job status"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or unreadable file; fields lists the bad lines",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer or import profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/limits": {
+            "get": {
+                "description": "Get a customer's own debit limits, the defaults, and the limits in force: each of the customer's limits, or the default where it has none.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a customer's limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Limits",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerLimits"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a customer's own debit limits; an omitted field falls back to the default. Sending no limits returns the customer to the defaults. The change is recorded in the audit log under the calling operator.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set a customer's limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator making the change",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Customer limits",
+                        "name": "limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Limits"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Limits updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerLimits"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/reactivate": {
+            "post": {
+                "description": "Clear an account's dormant flag so it can be debited again. The account goes dormant again after another DORMANCY_DAYS without a posted transaction. Reactivation is recorded in the audit log under the calling operator and publishes an account.reactivated event.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reactivate a dormant account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator reactivating the account",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason",
+                        "name": "reactivation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReactivateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account reactivated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReactivateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Account is not dormant",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/reconciliations": {
+            "get": {
+                "description": "List the statements reconciled for a customer, newest first, with their lines counted by status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List a customer's reconciliations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "open",
+                            "closed"
+                        ],
+                        "type": "string",
+                        "description": "Only reconciliations with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Reconciliations per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reconciliations",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Reconciliation"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Upload an external statement, such as a bank's CSV export read with a stored import profile, and match its lines against the customer's posted transactions. A line matches a transaction going the same way whose reference is the same, ignoring case and spaces, or else whose amount is within amount_tolerance and whose value date is within date_tolerance_days; the closest pairs are matched first and each transaction matches at most one line of any reconciliation. Matches are proposals until an operator confirms them. Lines left unmatched can be matched by hand or settled with an adjusting entry, and the posted transactions in the statement's period that no line matches are listed with the reconciliation.",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile a statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "acme-bank",
+                        "description": "Import profile the statement is read with",
+                        "name": "profile",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "maximum": 31,
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 3,
+                        "description": "Days a line's date may be from the transaction's value date",
+                        "name": "date_tolerance_days",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "default": 0,
+                        "description": "Amount a line may differ from the transaction by",
+                        "name": "amount_tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Statement CSV",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Statement reconciled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters or unreadable statement; fields lists the bad lines",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer or import profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/statement-account": {
+            "put": {
+                "description": "Set the account identification MT940 statements give the customer's account in field 25, such as an IBAN, and optionally the number the next statement gets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set a customer's statement account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Statement account",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StatementAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statement account set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StatementAccount"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/stripe-account": {
+            "put": {
+                "description": "Set the Stripe connected account the customer's withdrawals are paid out from",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set a customer's Stripe account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Stripe account",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StripeAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stripe account set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StripeAccount"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/tokens": {
+            "get": {
+                "description": "List the self-service tokens issued to a customer, newest first, including revoked and expired ones. The tokens themselves are not included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List a customer's tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CustomerToken"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Issue a self-service token bound to one customer. Sent as ` + "`" + `Authorization: Bearer \u003ctoken\u003e` + "`" + `, it can only read that customer's balance and transactions; any other request made with it is refused. The token is returned once and only its hash is stored. Issuing is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Issue a customer token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Token issued",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/tokens/{token_id}": {
+            "delete": {
+                "description": "Revoke a self-service token so it stops working immediately. Revocation is recorded in the audit log.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke a customer token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Token ID",
+                        "name": "token_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Token revoked"
+                    },
+                    "400": {
+                        "description": "Invalid customer or token ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Token not found or already revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/customers/{customer_id}/verification": {
+            "put": {
+                "description": "Set a customer's KYC verification status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update verification status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Verification decision",
+                        "name": "verification",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VerificationUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Verification status updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.KYCProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dormant-accounts": {
+            "get": {
+                "description": "List the accounts flagged as dormant for having no posted transaction in DORMANCY_DAYS, most recently flagged first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List dormant accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (1-based)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dormant accounts",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.DormantAccount"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "string",
+                                "description": "Current page number"
+                            },
+                            "X-Page-Size": {
+                                "type": "string",
+                                "description": "Items per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "string",
+                                "description": "Total number of dormant accounts"
+                            },
+                            "X-Total-Pages": {
+                                "type": "string",
+                                "description": "Total number of pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/export": {
+            "get": {
+                "description": "Stream every customer, address, transfer, transaction and transaction type from a single repeatable-read snapshot. JSONL exports can be loaded into another environment with ` + "`" + `go run ./cmd/backup import` + "`" + `; SQL exports are psql scripts. The export is recorded in the audit log.",
+                "produces": [
+                    "application/x-ndjson",
+                    "application/sql"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export the ledger",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "jsonl",
+                            "sql"
+                        ],
+                        "type": "string",
+                        "default": "jsonl",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ledger export",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/fraud/decisions": {
+            "get": {
+                "description": "List recorded fraud decisions, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List fraud decisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "open",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by review status",
+                        "name": "review_status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (1-based)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Fraud decisions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.FraudDecision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/fraud/decisions/{decision_id}/review": {
+            "post": {
+                "description": "Approve or reject a fraud decision. Approving a held transaction posts it; rejecting it discards it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Review a fraud decision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Decision ID",
+                        "name": "decision_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Review outcome",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FraudReviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Decision reviewed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FraudDecision"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Decision not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Decision already reviewed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/fraud/rules": {
+            "get": {
+                "description": "List all configured fraud rules",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List fraud rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Fraud rules",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/fraud.Rule"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a rule evaluated against every new transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create a fraud rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Fraud rule",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FraudRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Fraud rule created",
+                        "schema": {
+                            "$ref": "#/definitions/fraud.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/fraud/rules/{rule_id}": {
+            "put": {
+                "description": "Replace the configuration of an existing fraud rule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update a fraud rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Rule ID",
+                        "name": "rule_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fraud rule",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FraudRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Fraud rule updated",
+                        "schema": {
+                            "$ref": "#/definitions/fraud.Rule"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a fraud rule so it is no longer evaluated",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a fraud rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Rule ID",
+                        "name": "rule_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Fraud rule deleted"
+                    },
+                    "400": {
+                        "description": "Invalid rule ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Rule not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/fx/rounding": {
+            "get": {
+                "description": "Report, per currency, what conversions into it (moves between wallets and transactions posted in another currency) rounded away: the exact converted amounts less the rounded ones. Whole minor units are posted to the currency's fx_rounding account; the rest is carried until it makes one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get FX rounding differences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rounding differences by currency",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.FXRoundingSummary"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/import-profiles": {
+            "get": {
+                "description": "List the stored mappings of bank CSV exports, by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List CSV import profiles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import profiles",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ImportProfile"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/import-profiles/{name}": {
+            "get": {
+                "description": "Get a stored mapping of a bank CSV export, with its defaults filled in",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a CSV import profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "acme-bank",
+                        "description": "Profile name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import profile",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImportProfile"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Import profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Store how a bank's CSV export is read: its delimiter, lines to skip and header, which columns hold the date, amount (or separate debit and credit amounts), type and reference, by header name or 1-based position, the date format (YYYY, YY, MM, M, DD and D, e.g. DD.MM.YYYY), and the decimal and thousands separators. Each value of the type column can be mapped to a transaction type; other rows are posted as credit_type or debit_type by the sign of their amount. With unique_references a row's reference can be imported once per customer, so overlapping exports can be imported without posting rows twice. Changing a profile affects later imports only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create or replace a CSV import profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "acme-bank",
+                        "description": "Profile name: lowercase letters, digits, hyphens and underscores",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Mapping",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/csvimport.Profile"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import profile stored",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImportProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a stored mapping of a bank CSV export",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a CSV import profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "acme-bank",
+                        "description": "Profile name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Import profile deleted"
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Import profile not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs": {
+            "get": {
+                "description": "List the background jobs run on cron schedules, with each job's schedule, next run on this instance and last run on any instance. A run is skipped while another instance holds the job's lock, so the last run may come from a different instance.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List scheduled jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Jobs",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.ScheduledJob"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/limits": {
+            "get": {
+                "description": "Get the debit limits applied to customers without their own. An omitted field is not limited by default.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get default limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Default limits",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LimitDefaults"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the debit limits applied to customers without their own; an omitted field removes that default. Single, daily and velocity limits apply to debits posted through POST /transactions and to payments out by transfer, and the overdraft lets deposit accounts go that far below zero on any debit. Lowering an overdraft leaves balances already past it alone but refuses further debits. The change is recorded in the audit log under the calling operator.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set default limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator making the change",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Default limits",
+                        "name": "limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Limits"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Default limits updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LimitDefaults"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/loans": {
+            "post": {
+                "description": "Pay a loan's principal out to a customer's balance and generate its amortization schedule: equal installments of principal and interest, debited from the balance on each due date. The disbursement is recorded in the audit log under the calling operator.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Disburse a loan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator disbursing the loan",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Loan terms",
+                        "name": "loan",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Loan disbursed, with its schedule",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Loan"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/maintenance": {
+            "get": {
+                "description": "Report whether write requests are being refused with 503 for maintenance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get maintenance mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Maintenance switch",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MaintenanceMode"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Switch maintenance mode on or off for every instance. While it is on, every write request except this one is refused with 503, code ` + "`" + `maintenance` + "`" + ` and a Retry-After header; reads and health checks keep working. Other instances pick the switch up within MAINTENANCE_REFRESH_SECONDS. If the database cannot be reached, the switch still applies to this instance at once, ` + "`" + `shared` + "`" + ` is false, and it is saved when the database is back. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Switch maintenance mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Switch",
+                        "name": "maintenance",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MaintenanceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Maintenance switch",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MaintenanceMode"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Maintenance mode is forced on by configuration",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payment-files": {
+            "get": {
+                "description": "List the payment files generated, newest first, optionally only those with a status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List payment files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "generated",
+                            "accepted",
+                            "partially_accepted",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Only files with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Files per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment files",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.PaymentFile"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Write the credit transfers waiting to be sent into a pain.001.001.03 file for the bank's batch payment channel, up to 5000 at a time, and mark them exported. Transfers whose debit is still held are left for a later file. Download the file from /admin/payment-files/{file_id}/xml.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Generate a payment file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Which transfers, and when to pay them",
+                        "name": "file",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentFileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Payment file generated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentFile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "No credit transfers are waiting",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Credit transfers are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payment-files/status-reports": {
+            "post": {
+                "description": "Apply a pain.002 payment status report from the bank to the file it names by OrgnlMsgId. Payments reported accepted or settled are marked so; rejected ones are marked with the bank's reason and their debit is reversed with a reversal_credit. A report with only a group status applies it to every payment in the file. Reports can be applied again safely: a payment is only reversed once.",
+                "consumes": [
+                    "application/xml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Receive a payment status report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "pain.002 XML",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report applied",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentStatusResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Not a pain.002 report",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No payment file has the original message ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payment-files/{file_id}": {
+            "get": {
+                "description": "Get a payment file's status and the pain.002 reports received on it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a payment file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Payment file ID",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentFile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payment file ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment file not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/payment-files/{file_id}/xml": {
+            "get": {
+                "description": "Download a payment file's pain.001 XML, to upload to the bank",
+                "produces": [
+                    "application/xml"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Download a payment file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Payment file ID",
+                        "name": "file_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "pain.001 XML",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payment file ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment file not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/period-closes": {
+            "get": {
+                "description": "List every period close, latest first. Nothing can be backdated on or before the latest closed_through date.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List closed accounting periods",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Period closes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.PeriodClose"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Close the books through a past date. Closes only move forward, and once a period is closed no transaction can be backdated into it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Close an accounting period",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator closing the period",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Period close",
+                        "name": "period",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PeriodCloseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Period closed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PeriodClose"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Period already closed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/jobs": {
+            "get": {
+                "description": "List background jobs, latest first, optionally by kind and status. Dead jobs ran out of attempts or failed for good; last_error says why.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List queued jobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "webhook.delivery",
+                            "statement.render",
+                            "import.csv",
+                            "notification.sms",
+                            "transaction.post"
+                        ],
+                        "type": "string",
+                        "description": "Kind of job",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "queued",
+                            "running",
+                            "succeeded",
+                            "dead",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Job status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Jobs per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Jobs",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.QueueJob"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/jobs/{job_id}": {
+            "get": {
+                "description": "Get a background job with its payload, attempts, last error and result",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a queued job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Job ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid job ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/jobs/{job_id}/cancel": {
+            "post": {
+                "description": "Stop a job that is waiting for its first or next attempt from running. A job already running finishes its attempt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Cancel a queued job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Job ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid job ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Job is not queued",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/jobs/{job_id}/retry": {
+            "post": {
+                "description": "Queue a dead or cancelled job to run again straight away, with its attempts counted from zero, e.g. once the webhook endpoint it failed against is back",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retry a dead or cancelled job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Job ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job queued again",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid job ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Job is not dead or cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/queue/stats": {
+            "get": {
+                "description": "Count the background jobs of each kind by status, with how long the longest-waiting due job has been due, to spot a backlog or a growing dead-letter list",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get job queue statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Jobs by kind and status",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.QueueStats"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations/{reconciliation_id}": {
+            "get": {
+                "description": "Get a reconciliation with every statement line and its match, and the posted transactions in the statement's period that no line is matched with",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a reconciliation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Reconciliation ID",
+                        "name": "reconciliation_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reconciliation",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid reconciliation ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reconciliation not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations/{reconciliation_id}/close": {
+            "post": {
+                "description": "Close a reconciliation once every line is confirmed or adjusted. A closed reconciliation's lines cannot be changed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Close a reconciliation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Reconciliation ID",
+                        "name": "reconciliation_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reconciliation closed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Reconciliation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid reconciliation ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reconciliation not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Already closed, or lines are still matched without confirmation or unmatched",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations/{reconciliation_id}/lines/{line_id}/adjust": {
+            "post": {
+                "description": "Post an adjustment for an unmatched line, crediting or debiting the customer by the line's amount so the ledger agrees with the statement, and match the line with it. The adjustment is recorded with its reason and justification under the calling operator, like any other.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Settle a statement line with an adjusting entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator making the adjustment",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Reconciliation ID",
+                        "name": "reconciliation_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Statement line ID",
+                        "name": "line_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for the adjustment",
+                        "name": "adjustment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationAdjustmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Adjustment posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationLine"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Statement line not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Reconciliation closed, or the line is matched",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations/{reconciliation_id}/lines/{line_id}/confirm": {
+            "post": {
+                "description": "Accept the transaction the engine matched a line with, or match the line with another of the customer's posted transactions going the same way. A transaction can be matched with one statement line only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Confirm a statement line's match",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Reconciliation ID",
+                        "name": "reconciliation_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Statement line ID",
+                        "name": "line_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transaction to match the line with instead",
+                        "name": "match",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationMatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Match confirmed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationLine"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, or the transaction goes the other way",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Statement line or transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Reconciliation closed, line already settled, or transaction matched with another line",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations/{reconciliation_id}/lines/{line_id}/unmatch": {
+            "post": {
+                "description": "Reject the transaction a line is matched with, leaving the line unmatched and the transaction free to match another line",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unmatch a statement line",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Reconciliation ID",
+                        "name": "reconciliation_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Statement line ID",
+                        "name": "line_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Line unmatched",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReconciliationLine"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Statement line not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Reconciliation closed, or the line was settled with an adjusting entry",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sagas/{saga_id}/compensate": {
+            "post": {
+                "description": "Resume undoing a saga whose compensation failed, from the step that failed. Sagas left running or compensating for over five minutes, as after a crash, can be compensated too. The saga is claimed first, so concurrent retries cannot undo a step twice.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retry a saga's compensation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Saga ID",
+                        "name": "saga_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Compensation run; see the saga's status for the outcome",
+                        "schema": {
+                            "$ref": "#/definitions/saga.Saga"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid saga ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Saga not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Saga is not waiting for compensation",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/stripe/reconciliation": {
+            "get": {
+                "description": "Compare the platform's Stripe balance with what the ledger credited for Stripe charges and paid out for withdrawals, by currency, with the payouts still on their way",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile with Stripe",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reconciliation",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StripeReconciliation"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Stripe is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Stripe error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/summary": {
+            "get": {
+                "description": "Counts and totals for an operations dashboard in one call: customers, accounts active in the last 30 days, transactions posted today (UTC) and their value per currency, transactions awaiting review, webhook deliveries that failed in the last 24 hours, and the event outbox backlog",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Admin dashboard summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Summary",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AdminSummary"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transaction-types": {
+            "post": {
+                "description": "Add a transaction type that can be posted from then on. Types that move the bank's own money name the general ledger account (see /admin/accounts) posted on the other side.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Register a transaction type",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Transaction type",
+                        "name": "type",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransactionTypeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Transaction type registered",
+                        "schema": {
+                            "$ref": "#/definitions/txtype.Type"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or unknown general ledger account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction type already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions": {
+            "get": {
+                "description": "Find the transactions of any customer carrying an external reference, such as an invoice or payment reference, newest first, so support can locate a payment without knowing the customer or transaction ID. Transfer legs match on the transfer's reference. Each transaction includes its customer_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Find transactions by reference",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "INV-1234",
+                        "description": "External reference",
+                        "name": "reference",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum transactions to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Transaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Missing reference or invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions/backdated": {
+            "post": {
+                "description": "Post a transaction at an explicit historical timestamp, for migrations and corrections. The balance changes now, but the transaction is dated and value-dated at the timestamp in history and statements, with recorded_at holding when it was actually written. Timestamps in a closed accounting period are refused, and every backdated posting is recorded in the audit log under the calling operator.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Post a backdated transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operator posting the transaction",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Backdated transaction",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BackdatedTransactionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Transaction posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BackdatedTransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Timestamp falls in a closed period",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions/{transaction_id}/approve": {
+            "post": {
+                "description": "Record the calling operator's approval of an escrow withdrawal, or of a transfer, split transfer or reservation from an escrow account. The debit posts once the required number of distinct approvers have approved it; a transfer then credits its payees and a reservation holds the funds.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Approve a pending transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Approving operator",
+                        "name": "X-Actor",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Approval recorded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ApprovalResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction is not awaiting approval or was already approved by this operator",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transactions/{transaction_id}/reject": {
+            "post": {
+                "description": "Reject an escrow withdrawal, transfer, split transfer or reservation awaiting approval so it never posts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reject a pending transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction rejected",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ApprovalResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not awaiting approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/trial-balance": {
+            "get": {
+                "description": "Sum every customer and sub-account balance and reconcile it against the ledger movements (opening balance plus posted credits minus posted debits, or the reverse for credit accounts), all from one consistent snapshot. Deposit and credit accounts are reported as separate ledgers. Accounts that disagree are listed, largest difference first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Trial balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "maximum": 500,
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum imbalanced accounts to list",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Trial balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TrialBalance"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "description": "List webhook subscriptions. Secrets are not included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhooks",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe an HTTP endpoint to ledger events, optionally filtered by event type. The response carries the signing secret, which is not shown again; every delivery is signed with it in the X-Webhook-Signature header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Webhook created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{webhook_id}": {
+            "get": {
+                "description": "Get a webhook subscription. The secret is not included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a webhook subscription so it receives no further events",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Webhook deleted"
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change a webhook's URL, description or event filter, or enable and disable it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{webhook_id}/replay": {
+            "post": {
+                "description": "Re-deliver the events relayed between from and to to one webhook, oldest first, e.g. after the subscriber's outage. Events keep their original IDs so receivers can drop ones they already have. Delivery runs in the background; poll the returned replay for progress. Events not yet relayed are left to the relay.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay events to a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Replay events from this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Replay events before this time (RFC 3339); defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Replay started",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookReplay"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID or time range",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{webhook_id}/replays": {
+            "get": {
+                "description": "List a webhook's replays with their progress, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List a webhook's replays",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Replays",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.WebhookReplay"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{webhook_id}/replays/{replay_id}": {
+            "get": {
+                "description": "Get a replay's status and how many of its events have been delivered or failed so far",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a webhook replay",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Replay ID",
+                        "name": "replay_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Replay",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookReplay"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook or replay ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Replay not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{webhook_id}/test": {
+            "post": {
+                "description": "Deliver a signed sample webhook.test event to the endpoint right away, whether or not the webhook is enabled, and report how the endpoint responded",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Send a test event to a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook ID",
+                        "name": "webhook_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery attempted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookTestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api-keys/current": {
+            "get": {
+                "description": "Return the scoped API key the request is made with and the scopes it holds right now, so an integration can check what it may do before trying.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Introspect the current API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scoped API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyIntrospection"
+                        }
+                    },
+                    "401": {
+                        "description": "No scoped API key, or an invalid one",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/balance-certificates/keys": {
+            "get": {
+                "description": "Get the JSON Web Key Set that verifies balance certificates. A certificate's kid header names its key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get the balance certificate keys",
+                "responses": {
+                    "200": {
+                        "description": "Key set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CertificateKeys"
+                        }
+                    },
+                    "501": {
+                        "description": "Balance certificates are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/balances/query": {
+            "post": {
+                "description": "Get the current balances of up to 500 customers in one request, read together in a single query, each in its account's base currency. Balances come back in the order asked, once per customer; IDs that match no customer are listed in not_found rather than failing the request. The balance cache is not used, so every balance is as stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Query balances",
+                "parameters": [
+                    {
+                        "description": "Customers to read the balances of",
+                        "name": "query",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceQuery"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Balances",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceQueryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "No customer IDs, too many, or an invalid one",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers": {
+            "post": {
+                "description": "Create a new customer account with initial balance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Create a new customer account",
+                "parameters": [
+                    {
+                        "description": "Customer information",
+                        "name": "customer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Customer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Customer created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}": {
+            "get": {
+                "description": "Get a customer's account and contact details",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get a customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer details",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update a customer's name, contact details and timezone. The timezone sets where the customer's days and months begin for daily and monthly limits.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Update a customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "customer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/addresses": {
+            "post": {
+                "description": "Add a postal address to a customer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Add an address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Address"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Address created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Address"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/addresses/{address_id}": {
+            "put": {
+                "description": "Replace an existing customer address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Replace an address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Address ID",
+                        "name": "address_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Address"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Address updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Address"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Address not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove an address from a customer",
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Delete an address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Address ID",
+                        "name": "address_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Address deleted"
+                    },
+                    "400": {
+                        "description": "Invalid customer or address ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Address not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/alias": {
+            "put": {
+                "description": "Claim an alias for a customer, replacing any alias they had. Aliases are unique and case-insensitive: 3 to 30 letters, digits or hyphens, with an optional leading @. An alias the customer gives up stays reserved for them for ALIAS_HOLD_DAYS so nobody else can take it. Every change is kept in the alias history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "aliases"
+                ],
+                "summary": "Set a customer's alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Alias",
+                        "name": "alias",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AliasRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Alias set",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AccountAlias"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Alias is taken or reserved",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Give up a customer's alias. It stays reserved for the customer for ALIAS_HOLD_DAYS, and the change is kept in the alias history.",
+                "tags": [
+                    "aliases"
+                ],
+                "summary": "Remove a customer's alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Alias removed"
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer or alias not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/alias/history": {
+            "get": {
+                "description": "List every change of a customer's alias, most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "aliases"
+                ],
+                "summary": "Get a customer's alias history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Alias changes",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.AliasChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/balance": {
+            "get": {
+                "description": "Get the current balance for a customer in the account's base currency, optionally converted to another currency. On a credit account the balance is the amount the customer owes. With as_of, the balance at that time is rebuilt from the opening balance and the transactions posted up to then, and a conversion uses the stored rate that applied at that time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get customer balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "USD",
+                            "EUR",
+                            "GBP"
+                        ],
+                        "type": "string",
+                        "description": "Currency to convert the balance to; defaults to the account's base currency",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Past time to report the balance at (RFC 3339)",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a balance already held, to revalidate it",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Current balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Balance unchanged since the given ETag"
+                    },
+                    "400": {
+                        "description": "Invalid customer ID, currency or as_of",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found, or no rate stored for as_of",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Exchange rate provider error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Currency conversion is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/balance/certificate": {
+            "get": {
+                "description": "Get a signed attestation of a customer's balance, now or at a past time, for proof of funds. The certificate is a JWS (RFC 7515) signed with the ledger's certificate key, which may be held in a key management service; a third party checks it against the key set at /balance-certificates/keys without having to trust how the document reached them, and should check exp. The balance is read from the ledger itself, not from the balance cache.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get a balance certificate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Past time to attest the balance at (RFC 3339); now when omitted",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Signed balance certificate",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BalanceCertificate"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or as_of",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Balance certificates are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Key management service error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/bank-links": {
+            "get": {
+                "description": "List the customer's linked bank accounts with their sync status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "List bank links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bank links",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.BankLink"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Link a bank account the customer picked in Plaid Link. In mirror mode (the default) every bank transaction is posted to the ledger as a bank_credit or bank_debit; in reconcile mode each is matched against a posting already made. Transactions are pulled on the BANK_SYNC schedule, when Plaid reports new ones, and on demand.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "Link a bank account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Public token and account",
+                        "name": "link",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BankLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Bank account linked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BankLink"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Bank account already linked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Plaid error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/bank-links/link-token": {
+            "post": {
+                "description": "Create a token that opens Plaid Link for the customer, so they can pick the bank account to link",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "Create a Plaid Link token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Plaid error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/bank-links/{link_id}": {
+            "delete": {
+                "description": "Stop syncing a linked bank account. Plaid's access is revoked once no link uses the item; the transactions synced so far and their postings are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "Unlink a bank account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Bank link ID",
+                        "name": "link_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bank account unlinked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BankLink"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Linked bank account not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/bank-links/{link_id}/sync": {
+            "post": {
+                "description": "Pull the linked account's new bank transactions now and mirror or match them. A sync that fails part way stops with the link in error and resumes from the same point next time; transactions already posted are not posted again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "Sync a bank link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Bank link ID",
+                        "name": "link_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sync finished; status error when it stopped early",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BankSyncResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Bank link not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Bank link is unlinked or already syncing",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/bank-links/{link_id}/transactions": {
+            "get": {
+                "description": "List the bank transactions synced from a linked account, newest first, optionally only those with a status, such as the unmatched ones left to reconcile by hand",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "List a bank link's transactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Bank link ID",
+                        "name": "link_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "posted",
+                            "matched",
+                            "unmatched"
+                        ],
+                        "type": "string",
+                        "description": "Only transactions with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Transactions per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bank transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.BankTransaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/credit-transfers": {
+            "get": {
+                "description": "List the customer's credit transfers, newest first, with their status from the bank",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "credit transfers"
+                ],
+                "summary": "List a customer's credit transfers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Transfers per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Credit transfers",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CreditTransfer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Credit transfers are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Debit the customer as a credit_transfer and queue a payment to a bank account outside the ledger. Queued transfers go to the bank in the next pain.001 payment file; a held debit waits for review first. When the bank rejects the payment, the debit is reversed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "credit transfers"
+                ],
+                "summary": "Send a credit transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Credit transfer",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreditTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Transfer queued",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreditTransfer"
+                        }
+                    },
+                    "202": {
+                        "description": "Transfer queued; its debit is held for review",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreditTransfer"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, an invalid IBAN or BIC, or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Debit refused by a limit",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Debit rejected by fraud rules",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Credit transfers are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/jobs/{job_id}": {
+            "get": {
+                "description": "Get the status of work the customer queued with Prefer: respond-async, such as generating a statement. Once the job has succeeded, result holds what it produced.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get a customer's job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Job ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Job",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer or job ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/kyc": {
+            "get": {
+                "description": "Get the verification status and submitted documents for a customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kyc"
+                ],
+                "summary": "Get KYC details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "KYC details",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.KYCProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/kyc/documents": {
+            "post": {
+                "description": "Attach an identity document reference to a customer. Unverified customers move to pending review.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "kyc"
+                ],
+                "summary": "Submit a KYC document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Document reference",
+                        "name": "document",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.KYCDocument"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Document submitted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.KYCDocument"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/loans": {
+            "get": {
+                "description": "List a customer's loans, newest first, with the principal outstanding and the interest accrued since the last due date",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loans"
+                ],
+                "summary": "List loans",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Loans",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Loan"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/loans/{loan_id}": {
+            "get": {
+                "description": "Get a loan with its outstanding principal, the interest accrued since the last due date, the amount that would pay it off today and the next installment due",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loans"
+                ],
+                "summary": "Get a loan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Loan ID",
+                        "name": "loan_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Loan",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Loan"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer or loan ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Loan not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/loans/{loan_id}/repay": {
+            "post": {
+                "description": "Repay part or all of a loan ahead of schedule from the customer's balance. An amount below the outstanding principal repays that much principal and re-amortizes the remaining installments over the same due dates. Without an amount, or with one at least the outstanding principal, the loan is paid off: the outstanding principal plus the interest accrued since the last due date is debited and the remaining installments are cancelled. Overdue installments must be paid first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loans"
+                ],
+                "summary": "Repay a loan early",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Loan ID",
+                        "name": "loan_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Repayment",
+                        "name": "repayment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoanRepaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Repayment posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoanRepaymentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Loan not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Loan already repaid or an installment is overdue",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/loans/{loan_id}/schedule": {
+            "get": {
+                "description": "Get a loan's amortization schedule: every installment with its due date, principal and interest split, and whether it is due, paid or cancelled by an early payoff",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loans"
+                ],
+                "summary": "Get a loan's schedule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Loan ID",
+                        "name": "loan_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Installments",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.LoanInstallment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer or loan ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Loan not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/mandates": {
+            "get": {
+                "description": "List the mandates a customer has granted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mandates"
+                ],
+                "summary": "List mandates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mandates",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Mandate"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Authorize a merchant customer to pull funds from this customer, up to max_amount per payment and optionally monthly_limit per calendar month",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mandates"
+                ],
+                "summary": "Create a mandate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Paying customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Mandate details",
+                        "name": "mandate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MandateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Mandate created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Mandate"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer or merchant not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/mandates/{mandate_id}": {
+            "get": {
+                "description": "Get a single mandate granted by the customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mandates"
+                ],
+                "summary": "Get a mandate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Mandate ID",
+                        "name": "mandate_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mandate",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Mandate"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Mandate not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Change the limits or reference of an active mandate. The merchant cannot be changed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mandates"
+                ],
+                "summary": "Update mandate limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Mandate ID",
+                        "name": "mandate_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New limits",
+                        "name": "mandate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MandateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mandate updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Mandate"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Active mandate not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Revoke a mandate; any later pull payments under it are rejected",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mandates"
+                ],
+                "summary": "Revoke a mandate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Mandate ID",
+                        "name": "mandate_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Mandate revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Mandate"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Active mandate not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/moves": {
+            "post": {
+                "description": "Move money between a customer's main balance and sub-accounts. Moves are internal bookkeeping: they bypass KYC limits, account type rules and fraud rules. Omit a sub-account ID to use the main balance. Moves between currencies are converted at the provider's current rate, or at the rate of a previously issued FX quote, which is stored on the move.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sub-accounts"
+                ],
+                "summary": "Move funds between balances",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Move details",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MoveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Move completed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MoveResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer, sub-account or quote not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Quote has already been used",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Quote has expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Exchange rate provider error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Currency conversion is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/notifications": {
+            "get": {
+                "description": "Get the SMS notification settings for a customer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Get notification preferences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Notification preferences",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotificationPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set the phone number and SMS opt-in flag for a customer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Update notification preferences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Notification preferences",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotificationPreferencesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Notification preferences updated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.NotificationPreferences"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/payment-links": {
+            "post": {
+                "description": "Create a single-use link that anyone holding it can pay, crediting the customer with the amount",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-links"
+                ],
+                "summary": "Create a payment link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment link",
+                        "name": "link",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Payment link created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentLink"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/payment-requests": {
+            "get": {
+                "description": "List payment requests a customer has received (incoming, the default) or sent (outgoing)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "List payment requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "incoming",
+                            "outgoing"
+                        ],
+                        "type": "string",
+                        "default": "incoming",
+                        "description": "Requests received or sent",
+                        "name": "direction",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "accepted",
+                            "declined",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment requests",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.PaymentRequest"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID or filter",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/pending": {
+            "get": {
+                "description": "Total the customer's held and pending_approval transactions, which have not moved the balance yet, and the balance left available if the pending debits post",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get pending transaction summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Pending summary",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PendingSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/standing-orders": {
+            "get": {
+                "description": "List the standing orders a customer pays",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "standing-orders"
+                ],
+                "summary": "List standing orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Standing orders",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.StandingOrder"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Schedule a recurring transfer from the customer to another customer. Transfers run on the start date and then on every daily, weekly or monthly occurrence until the end date. The payee is given by payee_customer_id or by payee_alias.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "standing-orders"
+                ],
+                "summary": "Create a standing order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Paying customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Standing order details",
+                        "name": "standing_order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StandingOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Standing order created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StandingOrder"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer or payee not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/standing-orders/{standing_order_id}": {
+            "delete": {
+                "description": "Permanently cancel a standing order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "standing-orders"
+                ],
+                "summary": "Cancel a standing order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Standing order ID",
+                        "name": "standing_order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Standing order cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StandingOrder"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Standing order not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Standing order has already ended",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/standing-orders/{standing_order_id}/pause": {
+            "post": {
+                "description": "Stop an active standing order from running until it is resumed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "standing-orders"
+                ],
+                "summary": "Pause a standing order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Standing order ID",
+                        "name": "standing_order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Standing order paused",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StandingOrder"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Standing order not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Standing order is not active",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/standing-orders/{standing_order_id}/resume": {
+            "post": {
+                "description": "Resume a paused standing order. Occurrences missed while paused are skipped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "standing-orders"
+                ],
+                "summary": "Resume a standing order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Standing order ID",
+                        "name": "standing_order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Standing order resumed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StandingOrder"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Standing order not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Standing order is not paused",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/statements": {
+            "get": {
+                "description": "List the MT940 statements generated for the customer, latest period first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "List MT940 statements",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Statements per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statements",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Statement"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Generate a SWIFT MT940 statement of the customer's posted transactions value-dated in a period of past days, for treasury systems that import statement files. The opening and closing balances are the booked balances before and after the period. Each statement takes the account's next statement number; asking again for the same period returns the statement already generated, with its number. Download the file from /customers/{customer_id}/statements/{statement_id}/mt940. With Prefer: respond-async the statement is generated in the background: the response is 202 with the queued job, whose status is at the Location header, and the job's result is the statement once it has succeeded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Generate an MT940 statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "respond-async to generate the statement in the background",
+                        "name": "Prefer",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Statement period",
+                        "name": "statement",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StatementRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statement already generated for the period",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Statement"
+                        }
+                    },
+                    "201": {
+                        "description": "Statement generated",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Statement"
+                        }
+                    },
+                    "202": {
+                        "description": "Statement queued",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.QueueJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Job status"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/statements/{statement_id}/mt940": {
+            "get": {
+                "description": "Download a statement as MT940 text: the fields of the SWIFT message, with CRLF line ends, ending in a hyphen",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Download an MT940 statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Statement ID",
+                        "name": "statement_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "MT940 statement",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer or statement ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Statement not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/stats": {
+            "get": {
+                "description": "Get lifetime totals of the customer's posted transactions: credits and debits by the direction of their type, the transaction count and average amount, and when the first and latest transactions posted. Held, pending and rejected transactions are not counted, and the opening balance is not a transaction. Amounts are in the account's base currency.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get customer statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer statistics",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CustomerStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/sub-accounts": {
+            "get": {
+                "description": "List a customer's sub-accounts and their balances",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sub-accounts"
+                ],
+                "summary": "List sub-accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sub-accounts",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.SubAccount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Open a named sub-account (wallet) under a customer. Sub-accounts start with a zero balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sub-accounts"
+                ],
+                "summary": "Open a sub-account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sub-account details",
+                        "name": "sub_account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SubAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Sub-account created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SubAccount"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A sub-account with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/sub-accounts/{sub_account_id}/transactions": {
+            "get": {
+                "description": "Get paginated transaction history for a sub-account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sub-accounts"
+                ],
+                "summary": "Get sub-account history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Sub-account ID",
+                        "name": "sub_account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (1-based)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a page already held, to revalidate it",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Transaction"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "string",
+                                "description": "Current page number"
+                            },
+                            "X-Page-Size": {
+                                "type": "string",
+                                "description": "Items per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "string",
+                                "description": "Total number of transactions"
+                            },
+                            "X-Total-Pages": {
+                                "type": "string",
+                                "description": "Total number of pages"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page unchanged since the given ETag"
+                    },
+                    "400": {
+                        "description": "Invalid ID format or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Sub-account not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/transactions": {
+            "get": {
+                "description": "Get paginated transaction history for a customer. format=ofx or format=qif exports every posted transaction matching the value date filters instead of a page, oldest first, for personal finance tools: amounts are signed from the customer's side (money in positive, money out negative), dated by value date, and a credit account is written as a credit card.",
+                "produces": [
+                    "application/json",
+                    "application/x-ofx",
+                    "application/qif"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (1-based)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "amount",
+                            "type"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Field to sort by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date",
+                        "description": "Only transactions with a value date on or after this date (YYYY-MM-DD)",
+                        "name": "value_date_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date",
+                        "description": "Only transactions with a value date on or before this date (YYYY-MM-DD)",
+                        "name": "value_date_to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "posted",
+                            "held",
+                            "pending_approval",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Only transactions with this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "ofx",
+                            "qif"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a page already held, to revalidate it",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.Transaction"
+                            }
+                        },
+                        "headers": {
+                            "X-Page": {
+                                "type": "string",
+                                "description": "Current page number"
+                            },
+                            "X-Page-Size": {
+                                "type": "string",
+                                "description": "Items per page"
+                            },
+                            "X-Total-Count": {
+                                "type": "string",
+                                "description": "Total number of transactions"
+                            },
+                            "X-Total-Pages": {
+                                "type": "string",
+                                "description": "Total number of pages"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page unchanged since the given ETag"
+                    },
+                    "400": {
+                        "description": "Invalid customer ID format, pagination, sort, filter or format parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/transactions/{transaction_id}": {
+            "get": {
+                "description": "Get one of a customer's transactions with its status. Held and pending_approval transactions have not moved the balance yet; rejected ones never will.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get a transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer or transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/transactions/{transaction_id}/proof": {
+            "get": {
+                "description": "Get the proof that a posted transaction is included in a published ledger anchor. An auditor hashes leaf as SHA-256(0x00 || leaf), checks it describes the transaction they hold, and folds in the audit path as RFC 6962 describes; the result must equal the anchor's root, which they compare with the root published at the time. Transactions are anchored by a scheduled job, so a new one has no proof until the next run.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get a transaction's inclusion proof",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Inclusion proof",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransactionProof"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid customer or transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction not anchored yet",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/customers/{customer_id}/withdrawals": {
+            "post": {
+                "description": "Debit a withdrawal and pay it out from the customer's Stripe connected account, as a saga: when Stripe refuses the payout the withdrawal is reversed at once. Payouts that fail later, as Stripe reports by webhook, are reversed then. The outcome is in the returned saga's status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stripe"
+                ],
+                "summary": "Withdraw to a customer's bank",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Withdrawal",
+                        "name": "withdrawal",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WithdrawalRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Withdrawal run; see its status for the outcome",
+                        "schema": {
+                            "$ref": "#/definitions/saga.Saga"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Customer has no Stripe account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Stripe is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dev/seed": {
+            "post": {
+                "description": "Create demo customers with randomized transaction histories. Only registered when APP_ENV=development. Omitted fields use the defaults (50 customers, up to 40 transactions each, over 90 days); reusing a seed reproduces the same data.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dev"
+                ],
+                "summary": "Seed demo data",
+                "parameters": [
+                    {
+                        "description": "Size of the data set",
+                        "name": "options",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/seed.Options"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Demo data created",
+                        "schema": {
+                            "$ref": "#/definitions/seed.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid options",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fx/quotes": {
+            "post": {
+                "description": "Quote a conversion rate and amount that is locked for a short time. Pass the returned quote_id to a move to convert at exactly the quoted rate; each quote can be redeemed once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fx"
+                ],
+                "summary": "Create an FX quote",
+                "parameters": [
+                    {
+                        "description": "Quote request",
+                        "name": "quote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FXQuoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Quote created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FXQuote"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Exchange rate provider error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Currency conversion is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fx/rates": {
+            "get": {
+                "description": "List the exchange rates stored for a currency pair, most recent first. Every rate fetched for a conversion is stored with when it took effect. With date, the rates that applied during that UTC day are listed: the one in effect when the day began and any that took effect during it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fx"
+                ],
+                "summary": "Get stored exchange rates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "USD/EUR",
+                        "description": "Currency pair as FROM/TO",
+                        "name": "pair",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date",
+                        "description": "UTC day (YYYY-MM-DD) to list the rates of",
+                        "name": "date",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum rates to return",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stored rates",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FXRateHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pair, date or limit",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ingest/{source}": {
+            "post": {
+                "description": "Accept a signed notification from a configured source, such as a PSP settlement or a bank feed, and post the transaction its rules map it to. The signature is checked against the source's secret before anything is read. The first rule whose match holds picks the transaction type and the paths of the event ID, customer, amount and currency; a notification no rule matches is acknowledged and ignored. The posting uses \"\u003csource\u003e:\u003cevent ID\u003e\" as a unique reference, so a provider redelivering a notification gets the original transaction back instead of a second posting.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Receive a notification from an external provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source name from INGEST_SOURCES",
+                        "name": "source",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Notification in the provider's format",
+                        "name": "notification",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Notification ignored or already posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.IngestResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Transaction posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.IngestResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Transaction held for fraud review or awaiting escrow approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.IngestResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown source",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Notification cannot be mapped or posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/anchors": {
+            "get": {
+                "description": "List the published Merkle roots over the ledger's posted transactions, newest first. Each anchor's chain hash commits to its root and every root before it, so a copy of the latest chain hash kept outside the ledger pins the whole history.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ledger"
+                ],
+                "summary": "List ledger anchors",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Anchors per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Anchors",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.LedgerAnchor"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid pagination",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ledger/anchors/{sequence}": {
+            "get": {
+                "description": "Get a published Merkle root by its sequence number",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ledger"
+                ],
+                "summary": "Get a ledger anchor",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Anchor sequence number",
+                        "name": "sequence",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Anchor",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LedgerAnchor"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid sequence number",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Anchor not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-links/{token}": {
+            "get": {
+                "description": "Look up a payment link by its token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-links"
+                ],
+                "summary": "Get payment link status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment link token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment link",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentLink"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment link not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-links/{token}/pay": {
+            "post": {
+                "description": "Pay an active link, transferring its amount from the payer to the link's customer. Each link can be paid once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-links"
+                ],
+                "summary": "Pay a payment link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment link token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Paying customer",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentLinkPayment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment link paid",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentLink"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Payer refused by its account policy or limits, dormant, or needing approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment link or payer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment link has already been paid",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Payment link has expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests": {
+            "post": {
+                "description": "Ask another customer to pay an amount. The payer is given by payer_customer_id or by payer_alias. The payer can accept, which transfers the money, or decline until the request expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Request a payment",
+                "parameters": [
+                    {
+                        "description": "Payment request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRequestCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Payment request created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Requester or payer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{payment_request_id}/accept": {
+            "post": {
+                "description": "Pay a pending request, transferring the amount from the payer to the requester",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Accept a payment request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Payment request ID",
+                        "name": "payment_request_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Paying customer",
+                        "name": "decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRequestDecision"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment request accepted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Payer refused by its account policy or limits, dormant, or needing approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment request is no longer pending",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Payment request has expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{payment_request_id}/decline": {
+            "post": {
+                "description": "Decline a pending payment request",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Decline a payment request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Payment request ID",
+                        "name": "payment_request_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Paying customer",
+                        "name": "decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRequestDecision"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment request declined",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Payment request is no longer pending",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Payment request has expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/plaid/webhook": {
+            "post": {
+                "description": "Receive a webhook from Plaid, verified with its Plaid-Verification JWT. SYNC_UPDATES_AVAILABLE syncs the item's links; an item error puts them in error until the customer relinks. Other webhooks are acknowledged and ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "bank links"
+                ],
+                "summary": "Receive a Plaid webhook",
+                "parameters": [
+                    {
+                        "description": "Plaid webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlaidWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Links synced or marked in error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid webhook signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Bank links are not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pull-payments": {
+            "post": {
+                "description": "Pull funds from a customer under a mandate they granted to the merchant. Payments on revoked mandates or beyond the mandate's limits are rejected and recorded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mandates"
+                ],
+                "summary": "Collect a pull payment",
+                "parameters": [
+                    {
+                        "description": "Pull payment",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PullPaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Payment collected",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PullPaymentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Mandate has been revoked, or payer refused by its account policy or limits, dormant, or needing approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Mandate not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Amount exceeds the mandate limits",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sagas": {
+            "post": {
+                "description": "Post a transaction and then run actions in other services, such as a payout provider or card processor, in order. Each action is configured by name in SAGA_ACTIONS and called over HTTP. The saga is saved before and after every step. When an action fails, the steps taken are compensated last first (the failed one included, as a timeout leaves its outcome unknown) and the posting is reversed with a reversal_credit or reversal_debit. The outcome is in the returned saga's status: completed, failed (the posting was refused, so nothing ran), compensated, or compensation_failed (an operator can retry it).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sagas"
+                ],
+                "summary": "Start a saga",
+                "parameters": [
+                    {
+                        "description": "Saga",
+                        "name": "saga",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SagaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Saga run; see its status for the outcome",
+                        "schema": {
+                            "$ref": "#/definitions/saga.Saga"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, a type that cannot be posted or an unknown action",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sagas/{saga_id}": {
+            "get": {
+                "description": "Get a saga's status and the outcome of each of its steps",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sagas"
+                ],
+                "summary": "Get a saga",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Saga ID",
+                        "name": "saga_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Saga",
+                        "schema": {
+                            "$ref": "#/definitions/saga.Saga"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid saga ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Saga not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stripe/webhook": {
+            "post": {
+                "description": "Receive an event from Stripe, verified with its Stripe-Signature header and STRIPE_WEBHOOK_SECRET. Payout events update the payout's status; a withdrawal whose payout failed or was canceled is reversed. A succeeded charge whose metadata names a customer_id is credited to them as a card_payment, with \"stripe:\u003ccharge ID\u003e\" as its unique reference so a redelivered event is not credited twice. Other events are acknowledged and ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stripe"
+                ],
+                "summary": "Receive a Stripe webhook",
+                "parameters": [
+                    {
+                        "description": "Stripe event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event handled or ignored",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StripeWebhookResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid signature",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Charge cannot be credited",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Stripe is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transaction-types": {
+            "get": {
+                "description": "List the registered transaction types and the balance direction each one posts in",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "List transaction types",
+                "responses": {
+                    "200": {
+                        "description": "Transaction types",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/txtype.Type"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions": {
+            "post": {
+                "description": "Create a transaction for a customer. The type must be a postable registered transaction type (see /transaction-types); its direction decides whether the balance is credited or debited. An amount in another currency than the account's is converted at the current rate, and the original amount, currency, rate and provider are kept on the transaction. With unique_reference=true the reference may be used once per customer: a transaction repeating it answers 409 with the original transaction's ID, so re-submitted bank files cannot post a payment twice. With dry_run=true every check runs (customer, currency, value date, dormancy, KYC limits, account type rules and balance) and the would-be balance is returned, but nothing is written; fraud rules are not applied to dry runs. With Prefer: respond-async the transaction is queued once the request and customer check out, and answered with 202, its transaction_id and status accepted; GET /transactions/{transaction_id} then reports what became of it. Dry runs are always answered straight away.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Create a new transaction",
+                "parameters": [
+                    {
+                        "description": "Transaction information",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Transaction"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the transaction without posting it",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "respond-async"
+                        ],
+                        "type": "string",
+                        "description": "respond-async to post the transaction in the background",
+                        "name": "Prefer",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dry run: the transaction would be accepted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransactionValidation"
+                        }
+                    },
+                    "201": {
+                        "description": "Transaction processed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransactionResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Transaction held for fraud review or awaiting escrow approval, or accepted to be posted in the background",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransactionResponse"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Status of a transaction posted in the background"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data or insufficient balance",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Transaction exceeds KYC limits or account type rules, or debits a dormant account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Unique reference already used; transaction_id is the original transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Transaction rejected by fraud rules; code duplicate_transaction when a duplicate rule rejected it",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Exchange rate provider error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{transaction_id}": {
+            "get": {
+                "description": "Get the status of a transaction submitted with Prefer: respond-async. It is accepted until a worker takes it up and processing while it is posted. Then it has the status the synchronous request would have given it, with the balance after posting, or failed with the error and code the request would have been refused with. A held or pending_approval transaction shows its current status, so its approval or rejection is seen here too.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get a submitted transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transaction ID",
+                        "name": "transaction_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Submitted transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransactionSubmission"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No transaction was submitted with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/reservations": {
+            "post": {
+                "description": "Start a two-phase transfer for integrations that only learn later whether the receiving side succeeded. The amount is debited from the payer now, with a transfer_out, and held under the returned transfer ID until the reservation is settled, crediting the payee, or released, returning it to the payer. Both accounts must exist and share a base currency, and the payer's balance must allow the debit. The payer is held to the same account policy and limits as a debit: when its policy requires approval, as for escrow accounts, the reservation is pending_approval and nothing is reserved until the payer's transfer_out is approved.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Reserve a transfer",
+                "parameters": [
+                    {
+                        "description": "Transfer to reserve",
+                        "name": "reservation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Funds reserved",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Reservation awaiting approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, insufficient balance or accounts in different currencies",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Payer refused by its account policy or limits, or dormant",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payer or payee not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/reservations/{transfer_id}": {
+            "get": {
+                "description": "Get a reserve-then-settle transfer and whether it is still reserved, settled or released",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Get a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transfer ID",
+                        "name": "transfer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reservation",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reservation not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/reservations/{transfer_id}/release": {
+            "post": {
+                "description": "Cancel a reserved transfer: the held amount is returned to the payer with a transfer_release, and a transfer.released event is sent",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Release a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transfer ID",
+                        "name": "transfer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reservation released",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reservation not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Reservation already settled or released",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/reservations/{transfer_id}/settle": {
+            "post": {
+                "description": "Complete a reserved transfer: the held amount is credited to the payee with a transfer_in, and a transfer.completed event is sent. A credit the payee's account refuses leaves the reservation open, so it can still be released.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Settle a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Transfer ID",
+                        "name": "transfer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reservation settled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReservationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transfer ID or credit refused by the payee's account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reservation not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Reservation already settled or released",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfers/split": {
+            "post": {
+                "description": "Debit one customer once and credit several recipients with the amounts given, such as a marketplace paying out its sellers. The credits must add up to the amount. Everything is posted in one database transaction: the payer gets a single transfer_out for the whole amount, and each recipient a transfer_in, recorded as a transfer sharing the batch ID. All accounts must share a base currency. A recipient may appear more than once. The payer is held to the same account policy and limits as a debit: when its policy requires approval, as for escrow accounts, nothing moves until the payer's transfer_out is approved.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Create a split transfer",
+                "parameters": [
+                    {
+                        "description": "Split transfer",
+                        "name": "split",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SplitTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Split transfer posted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SplitTransferResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Split transfer awaiting approval",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SplitTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, credits not adding up, insufficient balance or accounts in different currencies",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Payer refused by its account policy or limits, or dormant",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payer or recipient not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "attest.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "csvimport.Columns": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount holds signed amounts. Exports with money out and money in in\nseparate columns set Debit and Credit instead.",
+                    "type": "string",
+                    "example": "Amount"
+                },
+                "credit": {
+                    "type": "string",
+                    "example": "Credit"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "Booking date"
+                },
+                "debit": {
+                    "type": "string",
+                    "example": "Debit"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Reference"
+                },
+                "type": {
+                    "description": "Type is optional; its values are looked up in the profile's TypeMap",
+                    "type": "string",
+                    "example": "Transaction type"
+                }
+            }
+        },
+        "csvimport.Profile": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "$ref": "#/definitions/csvimport.Columns"
+                },
+                "credit_type": {
+                    "description": "CreditType and DebitType default to credit and debit",
+                    "type": "string",
+                    "example": "bank_credit"
+                },
+                "date_format": {
+                    "description": "DateFormat is written with YYYY, YY, MM, M, DD and D, e.g.\nDD.MM.YYYY; YYYY-MM-DD when unset",
+                    "type": "string",
+                    "example": "DD.MM.YYYY"
+                },
+                "debit_type": {
+                    "type": "string",
+                    "example": "bank_debit"
+                },
+                "decimal_separator": {
+                    "description": "DecimalSeparator is \".\" or \",\", \".\" when unset",
+                    "type": "string",
+                    "example": ","
+                },
+                "delimiter": {
+                    "description": "Delimiter is a single character, \",\" when unset",
+                    "type": "string",
+                    "example": ";"
+                },
+                "has_header": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "skip_rows": {
+                    "description": "SkipRows lines are dropped before the header or first row, such as\nthe account summary some banks start their exports with",
+                    "type": "integer",
+                    "example": 0
+                },
+                "thousands_separator": {
+                    "description": "ThousandsSeparator, if the export groups digits, is \".\", \",\", \" \"\nor \"'\"",
+                    "type": "string",
+                    "example": "."
+                },
+                "type_map": {
+                    "description": "TypeMap gives the transaction type of each value of the type\ncolumn. Without a type column, or for values it does not list, a\nrow's direction picks CreditType or DebitType.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "unique_references": {
+                    "description": "UniqueReferences makes a row's reference usable once per customer,\nso importing overlapping exports skips rows already imported",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "fraud.Action": {
+            "type": "string",
+            "enum": [
+                "allow",
+                "flag",
+                "hold",
+                "reject"
+            ],
+            "x-enum-varnames": [
+                "ActionAllow",
+                "ActionFlag",
+                "ActionHold",
+                "ActionReject"
+            ]
+        },
+        "fraud.Match": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/fraud.Action"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "rule_id": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "$ref": "#/definitions/fraud.RuleType"
+                }
+            }
+        },
+        "fraud.Params": {
+            "type": "object",
+            "properties": {
+                "end_hour": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "lookback_days": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "max_count": {
+                    "description": "rapid_debits: match when more than MaxCount debits occur within WindowSeconds\nduplicate: match when the customer had a transaction of the same type\nand amount within WindowSeconds, unless the posting allows duplicates",
+                    "type": "integer",
+                    "example": 3
+                },
+                "min_history": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "multiplier": {
+                    "description": "amount_spike: match when amount \u003e Multiplier * average of the last\nLookbackDays, once at least MinHistory transactions exist",
+                    "type": "number",
+                    "example": 5
+                },
+                "start_hour": {
+                    "description": "unusual_hours: match when the customer's local hour falls in\n[StartHour, EndHour), wrapping past midnight when StartHour \u003e EndHour",
+                    "type": "integer",
+                    "example": 0
+                },
+                "window_seconds": {
+                    "type": "integer",
+                    "example": 60
+                }
+            }
+        },
+        "fraud.Rule": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/fraud.Action"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "$ref": "#/definitions/fraud.Params"
+                },
+                "rule_id": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "$ref": "#/definitions/fraud.RuleType"
+                }
+            }
+        },
+        "fraud.RuleType": {
+            "type": "string",
+            "enum": [
+                "amount_spike",
+                "rapid_debits",
+                "unusual_hours",
+                "duplicate"
+            ],
+            "x-enum-varnames": [
+                "RuleAmountSpike",
+                "RuleRapidDebits",
+                "RuleUnusualHours",
+                "RuleDuplicate"
+            ]
+        },
+        "handlers.APIKey": {
+            "description": "Scoped API key; the key itself is only returned when it is created",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-07-08T17:09:17Z"
+                },
+                "key": {
+                    "description": "Key is only returned when the key is created",
+                    "type": "string",
+                    "example": "lsk_9c1e0b7d3f5a2c4e6a8b0d2f4c6e8a0b1d3f5a7c9e1b3d5f7a9c0e2b4d6f8a0c"
+                },
+                "key_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Reporting dashboard"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-05-01T09:00:00Z"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "customers:read",
+                        "transactions:read"
+                    ]
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                }
+            }
+        },
+        "handlers.APIKeyIntrospection": {
+            "description": "The key a request was made with and its scopes",
+            "type": "object",
+            "properties": {
+                "key_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Reporting dashboard"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "customers:read",
+                        "transactions:read"
+                    ]
+                }
+            }
+        },
+        "handlers.APIKeyRequest": {
+            "description": "Label, scopes and lifetime of a new API key",
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_in_days": {
+                    "description": "ExpiresInDays defaults to a key that does not expire",
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 1,
+                    "example": 90
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Reporting dashboard"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "customers:read",
+                        "transactions:read"
+                    ]
+                }
+            }
+        },
+        "handlers.APIKeyScopesRequest": {
+            "description": "The scopes an API key is granted from its next request on",
+            "type": "object",
+            "required": [
+                "scopes"
+            ],
+            "properties": {
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "transactions:read"
+                    ]
+                }
+            }
+        },
+        "handlers.AccountAlias": {
+            "description": "Human-readable handle of an account",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "@coffee-shop"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.Address": {
+            "description": "Customer postal address",
+            "type": "object",
+            "properties": {
+                "address_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "city": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Springfield"
+                },
+                "country": {
+                    "type": "string",
+                    "maxLength": 2,
+                    "minLength": 2,
+                    "example": "US"
+                },
+                "is_primary": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "line1": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "1 Main Street"
+                },
+                "line2": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Apt 4"
+                },
+                "postal_code": {
+                    "type": "string",
+                    "maxLength": 20,
+                    "example": "62701"
+                },
+                "region": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "IL"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "home",
+                        "mailing",
+                        "business"
+                    ],
+                    "example": "home"
+                }
+            }
+        },
+        "handlers.AdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "customer_id",
+                "direction",
+                "justification",
+                "reason_code"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 15
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "direction": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "example": "credit"
+                },
+                "justification": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 10,
+                    "example": "Refund of duplicate card fee, ticket #4821"
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "write_off",
+                        "goodwill",
+                        "error_correction"
+                    ],
+                    "example": "goodwill"
+                }
+            }
+        },
+        "handlers.AdjustmentResponse": {
+            "description": "Posted balance adjustment",
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "adjustment_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "amount": {
+                    "type": "number",
+                    "example": 15
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 115
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "justification": {
+                    "type": "string",
+                    "example": "Refund of duplicate card fee, ticket #4821"
+                },
+                "reason_code": {
+                    "type": "string",
+                    "example": "goodwill"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "adjustment_credit",
+                        "adjustment_debit"
+                    ],
+                    "example": "adjustment_credit"
+                }
+            }
+        },
+        "handlers.AdminSummary": {
+            "description": "Ledger-wide counts and totals",
+            "type": "object",
+            "properties": {
+                "active_accounts": {
+                    "description": "ActiveAccounts have posted a transaction in the last 30 days",
+                    "type": "integer",
+                    "example": 830
+                },
+                "as_of": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "customers": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "failed_webhook_deliveries": {
+                    "description": "FailedWebhookDeliveries counts failed attempts in the last 24 hours",
+                    "type": "integer",
+                    "example": 2
+                },
+                "oldest_unpublished_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:00Z"
+                },
+                "outbox_backlog": {
+                    "description": "OutboxBacklog counts events not yet relayed; OldestUnpublishedAt is\nwhen the oldest of them was written",
+                    "type": "integer",
+                    "example": 0
+                },
+                "pending_transactions": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "transaction_value_today": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "transactions_today": {
+                    "description": "TransactionsToday counts transactions posted since midnight UTC;\nTransactionValueToday totals them per account currency",
+                    "type": "integer",
+                    "example": 5400
+                }
+            }
+        },
+        "handlers.AliasChange": {
+            "description": "Change of a customer's alias",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "description": "Alias is empty when the alias was removed",
+                    "type": "string",
+                    "example": "@coffee-shop"
+                },
+                "changed_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "changed_by": {
+                    "type": "string",
+                    "example": "customer"
+                },
+                "previous_alias": {
+                    "description": "PreviousAlias is empty when the customer had none",
+                    "type": "string",
+                    "example": "@coffee"
+                }
+            }
+        },
+        "handlers.AliasLookup": {
+            "description": "Account an alias belongs to",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "@coffee-shop"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Coffee Shop Ltd"
+                }
+            }
+        },
+        "handlers.AliasRequest": {
+            "type": "object",
+            "required": [
+                "alias"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "@coffee-shop"
+                }
+            }
+        },
+        "handlers.AliasReservation": {
+            "description": "Alias nobody can claim, or only the customer it is held for",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "@support"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "description": "CustomerID is set on an alias held for the customer who released it",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is empty for reservations that last until removed",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Could be mistaken for the bank"
+                },
+                "reserved_by": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "handlers.AliasReservationRequest": {
+            "type": "object",
+            "required": [
+                "alias",
+                "reason"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "@support"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Could be mistaken for the bank"
+                }
+            }
+        },
+        "handlers.AllowNegativeRequest": {
+            "type": "object",
+            "required": [
+                "allow_negative",
+                "reason"
+            ],
+            "properties": {
+                "allow_negative": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 10,
+                    "example": "Card scheme settlement account"
+                }
+            }
+        },
+        "handlers.AllowNegativeResponse": {
+            "description": "Negative balance setting",
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "allow_negative": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "balance": {
+                    "type": "number",
+                    "example": -2500
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.ApprovalResponse": {
+            "type": "object",
+            "properties": {
+                "approvals": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 800
+                },
+                "required_approvals": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending_approval",
+                        "posted",
+                        "rejected"
+                    ],
+                    "example": "pending_approval"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.AuditEntry": {
+            "description": "Who changed what, and when",
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "adjustment.created"
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "details": {
+                    "type": "object"
+                },
+                "entity_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "entity_type": {
+                    "type": "string",
+                    "example": "adjustment"
+                },
+                "id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.BackdatedTransactionRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "customer_id",
+                "justification",
+                "timestamp",
+                "type"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 250
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "justification": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 10,
+                    "example": "Opening balance migrated from the legacy core"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "credit"
+                }
+            }
+        },
+        "handlers.BackdatedTransactionResponse": {
+            "description": "Posted backdated transaction",
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "amount": {
+                    "type": "number",
+                    "example": 250
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 350
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "recorded_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-10T09:30:00Z"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "credit"
+                },
+                "value_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-08"
+                }
+            }
+        },
+        "handlers.BalanceCertificate": {
+            "description": "Balance attestation signed as a compact JWS, with its claims decoded for convenience",
+            "type": "object",
+            "properties": {
+                "certificate": {
+                    "description": "Certificate is the compact JWS to hand to the third party; its\npayload holds the claims",
+                    "type": "string",
+                    "example": "eyJhbGciOiJFUzI1NiIsImtpZCI6Ii4uLiIsInR5cCI6IkpXVCJ9.eyJpc3MiOiJsZWRnZXItc2VydmljZSJ9.c2ln"
+                },
+                "claims": {
+                    "$ref": "#/definitions/handlers.BalanceClaims"
+                },
+                "kid": {
+                    "description": "KeyID names the key in the published key set that verifies it",
+                    "type": "string",
+                    "example": "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+                }
+            }
+        },
+        "handlers.BalanceClaims": {
+            "description": "Claims of a balance certificate, as signed in its JWS payload",
+            "type": "object",
+            "properties": {
+                "as_of": {
+                    "description": "AsOf is the time the balance was held at",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-30T23:59:59Z"
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 1250.5
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "exp": {
+                    "type": "integer",
+                    "example": 1748649600
+                },
+                "iat": {
+                    "description": "IssuedAt and ExpiresAt are seconds since the Unix epoch",
+                    "type": "integer",
+                    "example": 1746057600
+                },
+                "iss": {
+                    "description": "Issuer names the ledger that signed the certificate",
+                    "type": "string",
+                    "example": "ledger-service"
+                },
+                "jti": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+                },
+                "name": {
+                    "description": "Name is the account holder's name",
+                    "type": "string",
+                    "example": "Jane Doe"
+                },
+                "sub": {
+                    "description": "Subject is the customer's ID",
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.BalanceQuery": {
+            "type": "object",
+            "required": [
+                "customer_ids"
+            ],
+            "properties": {
+                "customer_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.BalanceQueryResponse": {
+            "description": "Balances of the customers asked for, in the order asked",
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BalanceResponse"
+                    }
+                },
+                "not_found": {
+                    "description": "NotFound lists the IDs that matched no customer",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.BalanceResponse": {
+            "type": "object",
+            "properties": {
+                "as_of": {
+                    "description": "AsOf is set when the balance was asked for at a past time",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-03-31T23:59:59Z"
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 800
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "rate": {
+                    "description": "Rate, RateProvider and RateEffectiveAt describe the conversion, when\nthe balance was converted to another currency",
+                    "type": "number",
+                    "example": 0.9123
+                },
+                "rate_effective_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-03-31T00:00:01Z"
+                },
+                "rate_provider": {
+                    "type": "string",
+                    "example": "exchangerate-api"
+                }
+            }
+        },
+        "handlers.BankLink": {
+            "description": "A bank account linked through Plaid whose transactions are mirrored into the ledger or reconciled against it",
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string",
+                    "example": "BxBXxLj1m4HMXBm9WZZmCWVbPjX16EHwv99vp"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "item_id": {
+                    "type": "string",
+                    "example": "eVBnVMp7zdTJLkRNr33Rs6zr7KNJqBFL9DrE6"
+                },
+                "last_error": {
+                    "description": "LastError is why the last sync stopped, while status is error",
+                    "type": "string",
+                    "example": "plaid ITEM_LOGIN_REQUIRED: the login details of this item have changed"
+                },
+                "last_synced_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "link_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "mirror",
+                        "reconcile"
+                    ],
+                    "example": "mirror"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "error",
+                        "unlinked"
+                    ],
+                    "example": "active"
+                },
+                "transactions": {
+                    "description": "Transactions counts the bank transactions synced, and Unmatched those\na reconciled link found no posting for",
+                    "type": "integer",
+                    "example": 42
+                },
+                "unmatched": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "handlers.BankLinkRequest": {
+            "type": "object",
+            "required": [
+                "account_id",
+                "public_token"
+            ],
+            "properties": {
+                "account_id": {
+                    "description": "AccountID is the Plaid account to sync, of those the customer linked",
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "BxBXxLj1m4HMXBm9WZZmCWVbPjX16EHwv99vp"
+                },
+                "mode": {
+                    "type": "string",
+                    "default": "mirror",
+                    "enum": [
+                        "mirror",
+                        "reconcile"
+                    ],
+                    "example": "mirror"
+                },
+                "public_token": {
+                    "description": "PublicToken is what Plaid Link returned when the customer linked",
+                    "type": "string",
+                    "maxLength": 200,
+                    "example": "public-sandbox-5c224a01-8314-4491-a06f-39e193d5cddc"
+                }
+            }
+        },
+        "handlers.BankSyncResult": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "error": {
+                    "type": "string"
+                },
+                "link_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "matched": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "posted": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "removed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "error"
+                    ],
+                    "example": "active"
+                },
+                "unmatched": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "handlers.BankTransaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 12.5
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "date": {
+                    "type": "string",
+                    "format": "date"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Starbucks"
+                },
+                "direction": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "example": "debit"
+                },
+                "external_id": {
+                    "type": "string",
+                    "example": "lPNjeW1nR6CDn5okmGQ6hEpMo4lLNoSrzqDje"
+                },
+                "status": {
+                    "description": "Status is posted when the sync posted it, matched when a reconciled\nlink found its posting, and unmatched otherwise",
+                    "type": "string",
+                    "enum": [
+                        "posted",
+                        "matched",
+                        "unmatched"
+                    ],
+                    "example": "posted"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.CertificateKeys": {
+            "description": "JSON Web Key Set (RFC 7517) of the keys balance certificates are signed with",
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/attest.JWK"
+                    }
+                }
+            }
+        },
+        "handlers.CreditTransfer": {
+            "description": "A credit transfer to an external bank account, debited when created and sent to the bank in a pain.001 payment file",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 125.5
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "creditor_bic": {
+                    "type": "string",
+                    "example": "COBADEFFXXX"
+                },
+                "creditor_iban": {
+                    "type": "string",
+                    "example": "DE89370400440532013000"
+                },
+                "creditor_name": {
+                    "type": "string",
+                    "example": "Jane Doe"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "end_to_end_id": {
+                    "description": "EndToEndID identifies the payment in the file and the bank's reports",
+                    "type": "string",
+                    "example": "5b1f0e6c2d7a4c1e9f3b8a6d4c2e0f1a"
+                },
+                "payment_file_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Invoice 42"
+                },
+                "reversal_transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "description": "Status is pending until the transfer is written to a file, exported\nuntil the bank reports on it, then accepted, settled or rejected. A\nrejected transfer's debit is reversed.",
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "exported",
+                        "accepted",
+                        "settled",
+                        "rejected"
+                    ],
+                    "example": "pending"
+                },
+                "status_reason": {
+                    "type": "string",
+                    "example": "AC01 Incorrect account number"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "transfer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.CreditTransferRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "creditor_iban",
+                "creditor_name"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 125.5
+                },
+                "creditor_bic": {
+                    "description": "CreditorBIC is optional for SEPA payments",
+                    "type": "string",
+                    "example": "COBADEFFXXX"
+                },
+                "creditor_iban": {
+                    "type": "string",
+                    "maxLength": 42,
+                    "example": "DE89370400440532013000"
+                },
+                "creditor_name": {
+                    "type": "string",
+                    "maxLength": 70,
+                    "example": "Jane Doe"
+                },
+                "reference": {
+                    "description": "Reference is passed to the creditor as the remittance information",
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Invoice 42"
+                }
+            }
+        },
+        "handlers.Customer": {
+            "description": "Customer account information",
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "account_type": {
+                    "type": "string",
+                    "enum": [
+                        "checking",
+                        "savings",
+                        "escrow"
+                    ],
+                    "example": "checking"
+                },
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.Address"
+                    }
+                },
+                "balance": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 1000
+                },
+                "balance_type": {
+                    "description": "BalanceType credit makes the balance what the customer owes, up to\nCreditLimit",
+                    "type": "string",
+                    "default": "deposit",
+                    "enum": [
+                        "deposit",
+                        "credit"
+                    ],
+                    "example": "deposit"
+                },
+                "credit_limit": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 5000
+                },
+                "currency": {
+                    "description": "Currency is the account's base currency; every posting is in it",
+                    "type": "string",
+                    "default": "USD",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "date_of_birth": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "1990-01-31"
+                },
+                "email": {
+                    "type": "string",
+                    "format": "email",
+                    "example": "john.doe@example.com"
+                },
+                "initial_balance": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 1000
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1,
+                    "example": "John Doe"
+                },
+                "phone_number": {
+                    "type": "string",
+                    "example": "+15551234567"
+                },
+                "timezone": {
+                    "type": "string",
+                    "default": "UTC",
+                    "example": "America/New_York"
+                }
+            }
+        },
+        "handlers.CustomerLimits": {
+            "description": "A customer's debit limits, the defaults and the limits in force",
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "defaults": {
+                    "$ref": "#/definitions/handlers.Limits"
+                },
+                "effective": {
+                    "description": "Effective is each of the customer's limits, or the default where it\nhas none",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handlers.Limits"
+                        }
+                    ]
+                },
+                "limits": {
+                    "$ref": "#/definitions/handlers.Limits"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T10:00:00Z"
+                },
+                "updated_by": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "handlers.CustomerResponse": {
+            "type": "object",
+            "properties": {
+                "account_type": {
+                    "type": "string",
+                    "enum": [
+                        "checking",
+                        "savings",
+                        "escrow"
+                    ],
+                    "example": "checking"
+                },
+                "addresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.Address"
+                    }
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "balance_type": {
+                    "description": "BalanceType deposit means Balance is owed to the customer; credit\nmeans it is owed by the customer",
+                    "type": "string",
+                    "enum": [
+                        "deposit",
+                        "credit"
+                    ],
+                    "example": "deposit"
+                },
+                "credit_limit": {
+                    "type": "number",
+                    "example": 5000
+                },
+                "currency": {
+                    "type": "string",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "date_of_birth": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "1990-01-31"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "phone_number": {
+                    "type": "string",
+                    "example": "+15551234567"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "America/New_York"
+                },
+                "verification_status": {
+                    "type": "string",
+                    "enum": [
+                        "unverified",
+                        "pending",
+                        "verified",
+                        "rejected"
+                    ],
+                    "example": "unverified"
+                }
+            }
+        },
+        "handlers.CustomerStats": {
+            "description": "Lifetime totals of a customer's posted transactions",
+            "type": "object",
+            "properties": {
+                "average_amount": {
+                    "type": "number",
+                    "example": 242
+                },
+                "credit_count": {
+                    "type": "integer",
+                    "example": 14
+                },
+                "currency": {
+                    "description": "Currency is the account's base currency, which every amount is in",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "debit_count": {
+                    "type": "integer",
+                    "example": 86
+                },
+                "first_activity_at": {
+                    "description": "FirstActivityAt and LastActivityAt are when the first and latest\ntransactions posted; both are left out before the first one",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-01-15T09:30:00Z"
+                },
+                "last_activity_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "lifetime_credits": {
+                    "type": "number",
+                    "example": 12500
+                },
+                "lifetime_debits": {
+                    "type": "number",
+                    "example": 11700
+                },
+                "transaction_count": {
+                    "description": "TransactionCount and AverageAmount cover credits and debits alike",
+                    "type": "integer",
+                    "example": 100
+                }
+            }
+        },
+        "handlers.CustomerToken": {
+            "description": "Token an end-user app uses to read its own customer's balance and transactions",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-07-08T17:09:17Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Mobile app"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-05-01T09:00:00Z"
+                },
+                "token": {
+                    "description": "Token is only returned when the token is issued",
+                    "type": "string",
+                    "example": "ctk_3f9a1c0e5b7d2f4a6c8e0b1d3f5a7c9e1b3d5f7a9c0e2b4d6f8a0c2e4b6d8f0a"
+                },
+                "token_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.CustomerTokenRequest": {
+            "description": "Label and lifetime of a new customer token",
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "expires_in_days": {
+                    "description": "ExpiresInDays defaults to a token that does not expire",
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 1,
+                    "example": 90
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Mobile app"
+                }
+            }
+        },
+        "handlers.CustomerUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "phone_number": {
+                    "type": "string",
+                    "example": "+15551234567"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Berlin"
+                }
+            }
+        },
+        "handlers.DormantAccount": {
+            "description": "Dormant account",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 12.5
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "dormant_since": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T03:00:00Z"
+                },
+                "last_activity_at": {
+                    "description": "LastActivityAt is the last posted transaction before the account went\ndormant, omitted when it never had one",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-04-02T10:15:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Jane Doe"
+                }
+            }
+        },
+        "handlers.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "invalid_json"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Invalid input"
+                },
+                "fields": {
+                    "description": "Fields lists each invalid field when Code is validation_failed",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.FieldError"
+                    }
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "3f2c9a5e-1b7d-4c2e-9f4a-8d6b0e1c2a3f"
+                },
+                "trace_id": {
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
+                },
+                "transaction_id": {
+                    "description": "TransactionID is the transaction already holding the reference when\nCode is duplicate_reference",
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.FXQuote": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 100
+                },
+                "converted_amount": {
+                    "type": "number",
+                    "example": 91.23
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:47Z"
+                },
+                "from_currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "quote_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 0.9123
+                },
+                "rate_provider": {
+                    "description": "RateProvider names the provider the rate came from, which is a\nfallback when the primary failed or was stale",
+                    "type": "string",
+                    "example": "exchangerate-api"
+                },
+                "rounding": {
+                    "description": "Rounding is the mode applied to the converted amount",
+                    "type": "string",
+                    "enum": [
+                        "half_up",
+                        "half_even",
+                        "truncate",
+                        "down",
+                        "up"
+                    ],
+                    "example": "half_up"
+                },
+                "to_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                }
+            }
+        },
+        "handlers.FXQuoteRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "customer_id",
+                "from_currency",
+                "to_currency"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 100
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "from_currency": {
+                    "type": "string",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "USD"
+                },
+                "to_currency": {
+                    "type": "string",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "EUR"
+                }
+            }
+        },
+        "handlers.FXRate": {
+            "description": "Rate fetched from a provider, kept so past conversions can be repeated",
+            "type": "object",
+            "properties": {
+                "effective_at": {
+                    "description": "EffectiveAt is when the provider last updated the rate, or when it was\nfetched if the provider does not say",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T00:00:01Z"
+                },
+                "fetched_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T09:14:52Z"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "exchangerate-api"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 0.9123
+                }
+            }
+        },
+        "handlers.FXRateHistory": {
+            "description": "Stored rates of a currency pair, most recent first",
+            "type": "object",
+            "properties": {
+                "pair": {
+                    "type": "string",
+                    "example": "USD/EUR"
+                },
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.FXRate"
+                    }
+                }
+            }
+        },
+        "handlers.FXRoundingSummary": {
+            "description": "What conversions into a currency rounded away, and how much of it is posted",
+            "type": "object",
+            "properties": {
+                "account": {
+                    "description": "Account is the general ledger account the differences are posted to",
+                    "type": "string",
+                    "example": "fx_rounding_usd"
+                },
+                "carried": {
+                    "type": "number",
+                    "example": 0.0034
+                },
+                "conversions": {
+                    "type": "integer",
+                    "example": 1520
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "difference": {
+                    "description": "Difference is the exact converted amounts less the rounded ones",
+                    "type": "number",
+                    "example": 0.0734
+                },
+                "posted": {
+                    "description": "Posted is the account's balance, in whole minor units; Carried is the\nfraction of a minor unit not posted yet",
+                    "type": "number",
+                    "example": 0.07
+                }
+            }
+        },
+        "handlers.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "name"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "name must be between 1 and 255 characters"
+                }
+            }
+        },
+        "handlers.FraudDecision": {
+            "description": "Fraud decision recorded for review",
+            "type": "object",
+            "properties": {
+                "action": {
+                    "enum": [
+                        "flag",
+                        "hold",
+                        "reject"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/fraud.Action"
+                        }
+                    ],
+                    "example": "hold"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "decision_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fraud.Match"
+                    }
+                },
+                "review_note": {
+                    "type": "string"
+                },
+                "review_status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "approved",
+                        "rejected"
+                    ],
+                    "example": "open"
+                },
+                "reviewed_by": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.FraudReviewRequest": {
+            "type": "object",
+            "required": [
+                "outcome"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "example": "Customer confirmed the purchase"
+                },
+                "outcome": {
+                    "type": "string",
+                    "enum": [
+                        "approve",
+                        "reject"
+                    ],
+                    "example": "approve"
+                }
+            }
+        },
+        "handlers.FraudRuleRequest": {
+            "type": "object",
+            "required": [
+                "action",
+                "name",
+                "rule_type"
+            ],
+            "properties": {
+                "action": {
+                    "enum": [
+                        "flag",
+                        "hold",
+                        "reject"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/fraud.Action"
+                        }
+                    ],
+                    "example": "hold"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Large debit spike"
+                },
+                "params": {
+                    "$ref": "#/definitions/fraud.Params"
+                },
+                "rule_type": {
+                    "enum": [
+                        "amount_spike",
+                        "rapid_debits",
+                        "unusual_hours",
+                        "duplicate"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/fraud.RuleType"
+                        }
+                    ],
+                    "example": "amount_spike"
+                }
+            }
+        },
+        "handlers.GLAccount": {
+            "description": "General ledger account",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 1240.5
+                },
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "asset",
+                        "liability",
+                        "equity",
+                        "income",
+                        "expense"
+                    ],
+                    "example": "income"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "fees_income"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Fees income"
+                },
+                "normal_balance": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "example": "credit"
+                },
+                "system": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.GLAccountRequest": {
+            "type": "object",
+            "required": [
+                "category",
+                "code",
+                "name"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "asset",
+                        "liability",
+                        "equity",
+                        "income",
+                        "expense"
+                    ],
+                    "example": "expense"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "card_rewards_expense"
+                },
+                "currency": {
+                    "type": "string",
+                    "default": "USD",
+                    "example": "USD"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "Card rewards expense"
+                }
+            }
+        },
+        "handlers.GLEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 2.5
+                },
+                "entry_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "example": "credit"
+                }
+            }
+        },
+        "handlers.Imbalance": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 800
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "difference": {
+                    "type": "number",
+                    "example": 50
+                },
+                "expected": {
+                    "type": "number",
+                    "example": 750
+                },
+                "last_movement_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "ledger": {
+                    "type": "string",
+                    "enum": [
+                        "customer",
+                        "credit",
+                        "sub_account"
+                    ],
+                    "example": "customer"
+                },
+                "movements": {
+                    "type": "integer",
+                    "example": 14
+                }
+            }
+        },
+        "handlers.ImportProfile": {
+            "description": "Named mapping of a CSV export's columns and formats to transactions",
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "$ref": "#/definitions/csvimport.Columns"
+                },
+                "credit_type": {
+                    "description": "CreditType and DebitType default to credit and debit",
+                    "type": "string",
+                    "example": "bank_credit"
+                },
+                "date_format": {
+                    "description": "DateFormat is written with YYYY, YY, MM, M, DD and D, e.g.\nDD.MM.YYYY; YYYY-MM-DD when unset",
+                    "type": "string",
+                    "example": "DD.MM.YYYY"
+                },
+                "debit_type": {
+                    "type": "string",
+                    "example": "bank_debit"
+                },
+                "decimal_separator": {
+                    "description": "DecimalSeparator is \".\" or \",\", \".\" when unset",
+                    "type": "string",
+                    "example": ","
+                },
+                "delimiter": {
+                    "description": "Delimiter is a single character, \",\" when unset",
+                    "type": "string",
+                    "example": ";"
+                },
+                "has_header": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "type": "string",
+                    "example": "acme-bank"
+                },
+                "skip_rows": {
+                    "description": "SkipRows lines are dropped before the header or first row, such as\nthe account summary some banks start their exports with",
+                    "type": "integer",
+                    "example": 0
+                },
+                "thousands_separator": {
+                    "description": "ThousandsSeparator, if the export groups digits, is \".\", \",\", \" \"\nor \"'\"",
+                    "type": "string",
+                    "example": "."
+                },
+                "type_map": {
+                    "description": "TypeMap gives the transaction type of each value of the type\ncolumn. Without a type column, or for values it does not list, a\nrow's direction picks CreditType or DebitType.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "unique_references": {
+                    "description": "UniqueReferences makes a row's reference usable once per customer,\nso importing overlapping exports skips rows already imported",
+                    "type": "boolean",
+                    "example": true
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "handlers.ImportResult": {
+            "description": "Rows of a CSV import and what became of them",
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "duplicates": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "held": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "posted": {
+                    "type": "integer",
+                    "example": 41
+                },
+                "profile": {
+                    "type": "string",
+                    "example": "acme-bank"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ImportedRow"
+                    }
+                }
+            }
+        },
+        "handlers.ImportedRow": {
+            "description": "Outcome of one row of a CSV import",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 1234.56
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Insufficient balance"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Salary April"
+                },
+                "status": {
+                    "description": "Status is the posting's status, duplicate for a reference already\nimported, or failed",
+                    "type": "string",
+                    "enum": [
+                        "posted",
+                        "held",
+                        "pending_approval",
+                        "rejected",
+                        "duplicate",
+                        "failed"
+                    ],
+                    "example": "posted"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "credit"
+                },
+                "value_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-01"
+                }
+            }
+        },
+        "handlers.IngestResponse": {
+            "description": "Outcome of an inbound notification; a repeat delivery is acknowledged with the transaction it posted the first time",
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "Status is the posting's status, duplicate for a notification already\nposted, or ignored when no rule of the source matched it",
+                    "type": "string",
+                    "enum": [
+                        "posted",
+                        "held",
+                        "pending_approval",
+                        "duplicate",
+                        "ignored"
+                    ],
+                    "example": "posted"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.JobRun": {
+            "description": "Last run of a scheduled job",
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 4120
+                },
+                "error": {
+                    "type": "string",
+                    "example": "connection reset by peer"
+                },
+                "finished_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T03:00:04Z"
+                },
+                "processed": {
+                    "description": "Processed is how many items the run handled",
+                    "type": "integer",
+                    "example": 12
+                },
+                "started_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T03:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "succeeded",
+                        "failed"
+                    ],
+                    "example": "succeeded"
+                }
+            }
+        },
+        "handlers.KYCDocument": {
+            "description": "Identity document reference",
+            "type": "object",
+            "required": [
+                "document_type",
+                "reference"
+            ],
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "document_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "document_type": {
+                    "type": "string",
+                    "enum": [
+                        "passport",
+                        "drivers_license",
+                        "national_id",
+                        "proof_of_address"
+                    ],
+                    "example": "passport"
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "P1234567"
+                }
+            }
+        },
+        "handlers.KYCProfile": {
+            "description": "Customer KYC details",
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "date_of_birth": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "1990-01-31"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.KYCDocument"
+                    }
+                },
+                "verification_note": {
+                    "type": "string"
+                },
+                "verification_status": {
+                    "type": "string",
+                    "enum": [
+                        "unverified",
+                        "pending",
+                        "verified",
+                        "rejected"
+                    ],
+                    "example": "pending"
+                },
+                "verified_by": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LedgerAnchor": {
+            "description": "Merkle root over a batch of posted transactions, chained to the anchors before it",
+            "type": "object",
+            "properties": {
+                "anchored_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "chain_hash": {
+                    "description": "ChainHash is SHA-256(previous chain hash || root), committing to this\nroot and every earlier one",
+                    "type": "string",
+                    "example": "9f2c3e0d5b1a8e7f6c4d2b0a9e8f7d6c5b4a3928171605f4e3d2c1b0a9f8e7d6"
+                },
+                "previous_chain_hash": {
+                    "description": "PreviousChainHash is the chain hash of the anchor before, all zeros\nfor the first",
+                    "type": "string",
+                    "example": "0000000000000000000000000000000000000000000000000000000000000000"
+                },
+                "root": {
+                    "description": "Root is the hex RFC 6962 Merkle tree hash over the batch's leaves",
+                    "type": "string",
+                    "example": "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"
+                },
+                "sequence": {
+                    "description": "Sequence numbers anchors from 1 with no gaps",
+                    "type": "integer",
+                    "example": 42
+                },
+                "size": {
+                    "description": "Size is the number of transactions in the tree",
+                    "type": "integer",
+                    "example": 1280
+                }
+            }
+        },
+        "handlers.LimitDefaults": {
+            "description": "Deployment-wide default debit limits",
+            "type": "object",
+            "properties": {
+                "daily_debit_limit": {
+                    "type": "number",
+                    "example": 2500
+                },
+                "max_debits_per_day": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "max_debits_per_hour": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "max_single_debit": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "overdraft_limit": {
+                    "description": "OverdraftLimit is how far below zero a deposit account may go",
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 500
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T10:00:00Z"
+                },
+                "updated_by": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "handlers.Limits": {
+            "description": "Debit limits; omitted fields are not limited at this level",
+            "type": "object",
+            "properties": {
+                "daily_debit_limit": {
+                    "type": "number",
+                    "example": 2500
+                },
+                "max_debits_per_day": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "max_debits_per_hour": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "max_single_debit": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "overdraft_limit": {
+                    "description": "OverdraftLimit is how far below zero a deposit account may go",
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 500
+                }
+            }
+        },
+        "handlers.LinkTokenResponse": {
+            "type": "object",
+            "properties": {
+                "link_token": {
+                    "type": "string",
+                    "example": "link-sandbox-af1a0311-da53-4636-b754-dd15cc058176"
+                }
+            }
+        },
+        "handlers.Loan": {
+            "description": "Amortizing loan paid out to a customer's balance",
+            "type": "object",
+            "properties": {
+                "accrued_interest": {
+                    "type": "number",
+                    "example": 11.42
+                },
+                "annual_rate": {
+                    "type": "number",
+                    "example": 0.065
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "disbursed_on": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-05-15"
+                },
+                "disbursement_transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ],
+                    "example": "monthly"
+                },
+                "installments": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "loan_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "next_due_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-06-15"
+                },
+                "next_payment": {
+                    "type": "number",
+                    "example": 222.73
+                },
+                "outstanding_principal": {
+                    "type": "number",
+                    "example": 4215.3
+                },
+                "payoff_amount": {
+                    "type": "number",
+                    "example": 4226.72
+                },
+                "principal": {
+                    "type": "number",
+                    "example": 5000
+                },
+                "schedule": {
+                    "description": "Schedule is returned when the loan is disbursed",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LoanInstallment"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "repaid"
+                    ],
+                    "example": "active"
+                }
+            }
+        },
+        "handlers.LoanInstallment": {
+            "description": "Scheduled loan repayment",
+            "type": "object",
+            "properties": {
+                "due_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-06-15"
+                },
+                "failed_attempts": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "interest": {
+                    "type": "number",
+                    "example": 27.08
+                },
+                "last_failure_reason": {
+                    "type": "string",
+                    "example": "Insufficient balance"
+                },
+                "number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "payment": {
+                    "type": "number",
+                    "example": 222.73
+                },
+                "principal": {
+                    "type": "number",
+                    "example": 195.65
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "due",
+                        "paid",
+                        "cancelled"
+                    ],
+                    "example": "due"
+                }
+            }
+        },
+        "handlers.LoanRepaymentRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 1000
+                }
+            }
+        },
+        "handlers.LoanRepaymentResponse": {
+            "description": "Early loan repayment",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 840.5
+                },
+                "interest": {
+                    "type": "number",
+                    "example": 0
+                },
+                "loan_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "outstanding_principal": {
+                    "type": "number",
+                    "example": 3215.3
+                },
+                "principal": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "repayment_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LoanInstallment"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "repaid"
+                    ],
+                    "example": "active"
+                }
+            }
+        },
+        "handlers.LoanRequest": {
+            "type": "object",
+            "required": [
+                "customer_id",
+                "frequency",
+                "installments",
+                "principal"
+            ],
+            "properties": {
+                "annual_rate": {
+                    "type": "number",
+                    "maximum": 0.999999,
+                    "minimum": 0,
+                    "example": 0.065
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "first_due_date": {
+                    "description": "FirstDueDate defaults to one period after today",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-06-15"
+                },
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ],
+                    "example": "monthly"
+                },
+                "installments": {
+                    "type": "integer",
+                    "maximum": 600,
+                    "minimum": 1,
+                    "example": 24
+                },
+                "principal": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 5000
+                }
+            }
+        },
+        "handlers.MaintenanceMode": {
+            "description": "Whether write requests are refused while the service is maintained",
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "forced": {
+                    "description": "Forced is set when MAINTENANCE_MODE keeps this instance in maintenance\nwhatever the switch says",
+                    "type": "boolean",
+                    "example": false
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Upgrading Postgres to 16"
+                },
+                "retry_after_seconds": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "shared": {
+                    "description": "Shared is false when the switch could not be saved to the database, so\nit only applies to this instance until it can be",
+                    "type": "boolean",
+                    "example": true
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T22:00:00Z"
+                },
+                "updated_by": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "handlers.MaintenanceRequest": {
+            "description": "New state of the maintenance switch",
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "Upgrading Postgres to 16"
+                },
+                "retry_after_seconds": {
+                    "description": "RetryAfterSeconds is sent to refused callers in Retry-After, defaulting\nto MAINTENANCE_RETRY_AFTER_SECONDS",
+                    "type": "integer",
+                    "maximum": 86400,
+                    "minimum": 1,
+                    "example": 600
+                }
+            }
+        },
+        "handlers.Mandate": {
+            "description": "Direct debit mandate",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "mandate_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "max_amount": {
+                    "type": "number",
+                    "example": 100
+                },
+                "merchant_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "monthly_limit": {
+                    "type": "number",
+                    "example": 300
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Gym membership"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "revoked"
+                    ],
+                    "example": "active"
+                }
+            }
+        },
+        "handlers.MandateRequest": {
+            "type": "object",
+            "required": [
+                "max_amount"
+            ],
+            "properties": {
+                "max_amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 100
+                },
+                "merchant_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "monthly_limit": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 300
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Gym membership"
+                }
+            }
+        },
+        "handlers.MoveRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 100
+                },
+                "from_sub_account_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "quote_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "to_sub_account_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.MoveResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 100
+                },
+                "converted_amount": {
+                    "type": "number",
+                    "example": 91.23
+                },
+                "from_balance": {
+                    "type": "number",
+                    "example": 150
+                },
+                "from_currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "from_sub_account_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "move_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "quote_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 0.9123
+                },
+                "rate_provider": {
+                    "description": "RateProvider names the provider the rate came from",
+                    "type": "string",
+                    "example": "exchangerate-api"
+                },
+                "rounding": {
+                    "description": "Rounding is the mode applied to the converted amount",
+                    "type": "string",
+                    "enum": [
+                        "half_up",
+                        "half_even",
+                        "truncate",
+                        "down",
+                        "up"
+                    ],
+                    "example": "half_up"
+                },
+                "to_balance": {
+                    "type": "number",
+                    "example": 341.23
+                },
+                "to_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "to_sub_account_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.NotificationPreferences": {
+            "description": "Customer SMS notification settings",
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "phone_number": {
+                    "type": "string",
+                    "example": "+15551234567"
+                },
+                "sms_opt_in": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.NotificationPreferencesRequest": {
+            "type": "object",
+            "properties": {
+                "phone_number": {
+                    "type": "string",
+                    "example": "+15551234567"
+                },
+                "sms_opt_in": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.PaymentFile": {
+            "description": "A pain.001 credit transfer file and its status from the bank's pain.002 reports",
+            "type": "object",
+            "properties": {
+                "control_sum": {
+                    "type": "number",
+                    "example": 2210.25
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "execution_date": {
+                    "type": "string",
+                    "format": "date"
+                },
+                "file_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "message_id": {
+                    "type": "string",
+                    "example": "9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d"
+                },
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PaymentStatusReport"
+                    }
+                },
+                "status": {
+                    "description": "Status is generated until every transfer has been reported on, then\naccepted, partially_accepted or rejected",
+                    "type": "string",
+                    "enum": [
+                        "generated",
+                        "accepted",
+                        "partially_accepted",
+                        "rejected"
+                    ],
+                    "example": "generated"
+                },
+                "transfers": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "handlers.PaymentFileRequest": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Currency limits the file to transfers in one currency",
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "execution_date": {
+                    "description": "ExecutionDate is when the bank should pay; today when empty",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-09"
+                }
+            }
+        },
+        "handlers.PaymentLink": {
+            "description": "Shareable payment link",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 25
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Concert ticket"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "paid_by_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "payment_link_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "paid",
+                        "expired"
+                    ],
+                    "example": "active"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "kq2X9vH1cN3pLr7sTz0aYw4bMd8eFg6h"
+                },
+                "transfer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "/v1/payment-links/kq2X9vH1cN3pLr7sTz0aYw4bMd8eFg6h"
+                }
+            }
+        },
+        "handlers.PaymentLinkPayment": {
+            "type": "object",
+            "required": [
+                "payer_customer_id"
+            ],
+            "properties": {
+                "payer_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.PaymentLinkRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 25
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Concert ticket"
+                },
+                "expires_in_minutes": {
+                    "type": "integer",
+                    "default": 1440,
+                    "maximum": 43200,
+                    "minimum": 1,
+                    "example": 60
+                }
+            }
+        },
+        "handlers.PaymentRequest": {
+            "description": "Request for payment from another customer",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 42.5
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Dinner on Friday"
+                },
+                "payer_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "payment_request_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "requester_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "accepted",
+                        "declined",
+                        "expired"
+                    ],
+                    "example": "pending"
+                },
+                "transfer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.PaymentRequestCreate": {
+            "type": "object",
+            "required": [
+                "amount",
+                "requester_customer_id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 42.5
+                },
+                "expires_in_hours": {
+                    "type": "integer",
+                    "default": 168,
+                    "maximum": 720,
+                    "minimum": 1,
+                    "example": 168
+                },
+                "message": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Dinner on Friday"
+                },
+                "payer_alias": {
+                    "type": "string",
+                    "example": "@jane"
+                },
+                "payer_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "requester_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.PaymentRequestDecision": {
+            "type": "object",
+            "required": [
+                "payer_customer_id"
+            ],
+            "properties": {
+                "payer_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.PaymentStatusReport": {
+            "type": "object",
+            "properties": {
+                "group_status": {
+                    "type": "string",
+                    "example": "PART"
+                },
+                "message_id": {
+                    "type": "string",
+                    "example": "STS-20250409-1"
+                },
+                "received_at": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "handlers.PaymentStatusResult": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "file_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "file_status": {
+                    "type": "string",
+                    "example": "partially_accepted"
+                },
+                "rejected": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "settled": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "unknown": {
+                    "description": "Unknown lists end-to-end IDs the report names that are not in the file",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.PendingSummary": {
+            "description": "Held and pending-approval transactions and the balance they leave available",
+            "type": "object",
+            "properties": {
+                "available_balance": {
+                    "description": "AvailableBalance is the balance less pending debits, or for a credit\naccount the unused credit limit less pending debits. Pending credits\nare not counted until they post.",
+                    "type": "number",
+                    "example": 850
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "pending_count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "pending_credits": {
+                    "type": "number",
+                    "example": 0
+                },
+                "pending_debits": {
+                    "type": "number",
+                    "example": 150
+                }
+            }
+        },
+        "handlers.PeriodClose": {
+            "description": "Accounting period closed through a date",
+            "type": "object",
+            "properties": {
+                "closed_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-02T08:00:00Z"
+                },
+                "closed_by": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "closed_through": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-03-31"
+                },
+                "id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.PeriodCloseRequest": {
+            "type": "object",
+            "required": [
+                "closed_through"
+            ],
+            "properties": {
+                "closed_through": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-03-31"
+                }
+            }
+        },
+        "handlers.PlaidWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "object",
+                    "properties": {
+                        "error_code": {
+                            "type": "string"
+                        },
+                        "error_message": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "item_id": {
+                    "type": "string",
+                    "example": "eVBnVMp7zdTJLkRNr33Rs6zr7KNJqBFL9DrE6"
+                },
+                "webhook_code": {
+                    "type": "string",
+                    "example": "SYNC_UPDATES_AVAILABLE"
+                },
+                "webhook_type": {
+                    "type": "string",
+                    "example": "TRANSACTIONS"
+                }
+            }
+        },
+        "handlers.PullPaymentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "mandate_id",
+                "merchant_customer_id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 49.99
+                },
+                "mandate_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "merchant_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.PullPaymentResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 49.99
+                },
+                "mandate_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "payment_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "collected"
+                },
+                "transfer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.QueueJob": {
+            "description": "Queued background job and its outcome",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "finished_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "job_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "webhook.delivery",
+                        "statement.render",
+                        "import.csv",
+                        "notification.sms",
+                        "transaction.post"
+                    ],
+                    "example": "statement.render"
+                },
+                "last_error": {
+                    "type": "string",
+                    "example": "endpoint answered 503"
+                },
+                "max_attempts": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "payload": {
+                    "description": "Payload is what the job was given; it is only shown to operators",
+                    "type": "object"
+                },
+                "result": {
+                    "description": "Result is what a succeeded job produced, such as the statement it\ngenerated or the outcome of each imported row",
+                    "type": "object"
+                },
+                "run_at": {
+                    "description": "RunAt is when the job is next due",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "status": {
+                    "description": "Status is queued while waiting for its first or next attempt, and\ndead once it ran out of attempts",
+                    "type": "string",
+                    "enum": [
+                        "queued",
+                        "running",
+                        "succeeded",
+                        "dead",
+                        "cancelled"
+                    ],
+                    "example": "succeeded"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "handlers.QueueStats": {
+            "description": "Jobs of one kind by status",
+            "type": "object",
+            "properties": {
+                "cancelled": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "dead": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "webhook.delivery"
+                },
+                "oldest_due_at": {
+                    "description": "OldestDueAt is when the longest-waiting due job became due",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "queued": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "running": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 10452
+                }
+            }
+        },
+        "handlers.ReactivateRequest": {
+            "description": "Reactivation reason",
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "minLength": 10,
+                    "example": "Customer called in and verified their identity"
+                }
+            }
+        },
+        "handlers.ReactivateResponse": {
+            "description": "Reactivated account",
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "dormant_since": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T03:00:00Z"
+                },
+                "reactivated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-05-02T09:30:00Z"
+                }
+            }
+        },
+        "handlers.Reconciliation": {
+            "description": "External statement uploaded for a customer and its lines' matches with ledger transactions",
+            "type": "object",
+            "properties": {
+                "amount_tolerance": {
+                    "type": "number",
+                    "example": 0.01
+                },
+                "closed_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "closed_by": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "date_tolerance_days": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "lines": {
+                    "description": "Lines counts the lines by status",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "period_from": {
+                    "description": "PeriodFrom and PeriodTo are the first and last dates of the\nstatement's lines",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-01"
+                },
+                "period_to": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-30"
+                },
+                "profile": {
+                    "description": "Profile is the CSV import profile the statement was read with",
+                    "type": "string",
+                    "example": "acme-bank"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "closed"
+                    ],
+                    "example": "open"
+                }
+            }
+        },
+        "handlers.ReconciliationAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "justification",
+                "reason_code"
+            ],
+            "properties": {
+                "justification": {
+                    "type": "string",
+                    "maxLength": 1000,
+                    "minLength": 10,
+                    "example": "Bank fee missing from the ledger, statement line 12"
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "write_off",
+                        "goodwill",
+                        "error_correction"
+                    ],
+                    "example": "error_correction"
+                }
+            }
+        },
+        "handlers.ReconciliationDetail": {
+            "description": "Reconciliation with its lines and the ledger transactions no line is matched with",
+            "type": "object",
+            "properties": {
+                "amount_tolerance": {
+                    "type": "number",
+                    "example": 0.01
+                },
+                "closed_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "closed_by": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "date_tolerance_days": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ReconciliationLine"
+                    }
+                },
+                "lines": {
+                    "description": "Lines counts the lines by status",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "period_from": {
+                    "description": "PeriodFrom and PeriodTo are the first and last dates of the\nstatement's lines",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-01"
+                },
+                "period_to": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-30"
+                },
+                "profile": {
+                    "description": "Profile is the CSV import profile the statement was read with",
+                    "type": "string",
+                    "example": "acme-bank"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "closed"
+                    ],
+                    "example": "open"
+                },
+                "unmatched_transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ReconciliationTransaction"
+                    }
+                }
+            }
+        },
+        "handlers.ReconciliationLine": {
+            "description": "Statement line and the ledger transaction it is matched with",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 800
+                },
+                "date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-03"
+                },
+                "direction": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "example": "debit"
+                },
+                "id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "line": {
+                    "description": "Line is the line's number in the uploaded file",
+                    "type": "integer",
+                    "example": 4
+                },
+                "match_rule": {
+                    "type": "string",
+                    "enum": [
+                        "reference",
+                        "amount_date",
+                        "manual",
+                        "adjustment"
+                    ],
+                    "example": "amount_date"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Rent April"
+                },
+                "resolved_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "resolved_by": {
+                    "type": "string",
+                    "example": "jane.doe"
+                },
+                "status": {
+                    "description": "Status is matched for a match the engine proposed, confirmed once an\noperator accepted or made a match, and adjusted when an adjusting\nentry settled the line",
+                    "type": "string",
+                    "enum": [
+                        "matched",
+                        "unmatched",
+                        "confirmed",
+                        "adjusted"
+                    ],
+                    "example": "matched"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.ReconciliationMatchRequest": {
+            "type": "object",
+            "properties": {
+                "transaction_id": {
+                    "description": "TransactionID matches the line with another transaction than the\none proposed; it is required for an unmatched line",
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.ReconciliationTransaction": {
+            "description": "Posted transaction in a reconciliation's period that no statement line is matched with",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 42.5
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "INV-2025-0042"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "debit"
+                },
+                "value_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-08"
+                }
+            }
+        },
+        "handlers.ReservationRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 40
+                },
+                "from_customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Order 1042"
+                },
+                "to_customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+                }
+            }
+        },
+        "handlers.ReservationResponse": {
+            "description": "A transfer whose funds are held from the payer until it is settled to the payee or released back",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 40
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T09:14:52Z"
+                },
+                "from_balance": {
+                    "description": "FromBalance is set after reserving or releasing, ToBalance after\nsettling",
+                    "type": "number",
+                    "example": 960
+                },
+                "from_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Order 1042"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending_approval",
+                        "reserved",
+                        "settled",
+                        "released",
+                        "rejected"
+                    ],
+                    "example": "reserved"
+                },
+                "to_balance": {
+                    "type": "number",
+                    "example": 140
+                },
+                "to_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "transfer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.SagaRequest": {
+            "type": "object",
+            "required": [
+                "actions",
+                "amount",
+                "customer_id",
+                "type"
+            ],
+            "properties": {
+                "actions": {
+                    "description": "Actions are run in order once the posting succeeds",
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "payout"
+                    ]
+                },
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 250
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Payout 2025-04-08"
+                },
+                "type": {
+                    "description": "Type is a postable transaction type, posted before any action runs",
+                    "type": "string",
+                    "example": "payout"
+                }
+            }
+        },
+        "handlers.ScheduledJob": {
+            "description": "Background job with its schedule and last run",
+            "type": "object",
+            "properties": {
+                "last_run": {
+                    "$ref": "#/definitions/handlers.JobRun"
+                },
+                "last_success_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T03:00:04Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "dormancy"
+                },
+                "next_run_at": {
+                    "description": "NextRunAt is when this instance next tries the job, before jitter",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-09T03:00:00Z"
+                },
+                "running": {
+                    "description": "Running reports whether this instance is running the job now",
+                    "type": "boolean",
+                    "example": false
+                },
+                "schedule": {
+                    "type": "string",
+                    "example": "0 3 * * *"
+                }
+            }
+        },
+        "handlers.SplitCreditRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 60
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+                },
+                "reference": {
+                    "description": "Reference defaults to the split's reference",
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Order 1042"
+                }
+            }
+        },
+        "handlers.SplitTransferLeg": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 60
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Order 1042"
+                },
+                "to_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "transfer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.SplitTransferRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "credits"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount is debited once from the payer; the credits must add up to it",
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 100
+                },
+                "credits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SplitCreditRequest"
+                    }
+                },
+                "from_customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Payout 2025-04-08"
+                }
+            }
+        },
+        "handlers.SplitTransferResponse": {
+            "description": "One debit from the payer and a transfer to each recipient, sharing a batch ID. A pending_approval split has moved nothing yet.",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 100
+                },
+                "batch_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "from_balance": {
+                    "type": "number",
+                    "example": 900
+                },
+                "from_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "completed",
+                        "pending_approval"
+                    ],
+                    "example": "completed"
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SplitTransferLeg"
+                    }
+                }
+            }
+        },
+        "handlers.StandingOrder": {
+            "description": "Recurring transfer between two customers",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 250
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "end_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-12-01"
+                },
+                "failed_attempts": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ],
+                    "example": "monthly"
+                },
+                "last_failure_reason": {
+                    "type": "string",
+                    "example": "Insufficient balance"
+                },
+                "next_run_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-05-01"
+                },
+                "payee_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Rent"
+                },
+                "standing_order_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "start_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-05-01"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "paused",
+                        "cancelled",
+                        "completed"
+                    ],
+                    "example": "active"
+                }
+            }
+        },
+        "handlers.StandingOrderRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "frequency",
+                "start_date"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 250
+                },
+                "end_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-12-01"
+                },
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ],
+                    "example": "monthly"
+                },
+                "payee_alias": {
+                    "type": "string",
+                    "example": "@landlord"
+                },
+                "payee_customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Rent"
+                },
+                "start_date": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-05-01"
+                }
+            }
+        },
+        "handlers.Statement": {
+            "description": "An MT940 statement of a customer's posted transactions over a period of value dates",
+            "type": "object",
+            "properties": {
+                "account_identifier": {
+                    "description": "AccountIdentifier is the account named in field 25",
+                    "type": "string",
+                    "example": "DE89370400440532013000"
+                },
+                "closing_balance": {
+                    "type": "number",
+                    "example": 1310.9
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "from": {
+                    "type": "string",
+                    "format": "date"
+                },
+                "number": {
+                    "description": "Number is the statement's number for the account (field 28C)",
+                    "type": "integer",
+                    "example": 7
+                },
+                "opening_balance": {
+                    "type": "number",
+                    "example": 1520.4
+                },
+                "statement_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date"
+                },
+                "transactions": {
+                    "type": "integer",
+                    "example": 14
+                }
+            }
+        },
+        "handlers.StatementAccount": {
+            "type": "object",
+            "properties": {
+                "account_identifier": {
+                    "type": "string",
+                    "example": "DE89370400440532013000"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "next_statement_number": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "handlers.StatementAccountRequest": {
+            "type": "object",
+            "properties": {
+                "account_identifier": {
+                    "description": "AccountIdentifier is written in field 25, such as an IBAN or a bank\ncode and account number; the customer ID without dashes when not set",
+                    "type": "string",
+                    "maxLength": 35,
+                    "example": "DE89370400440532013000"
+                },
+                "next_statement_number": {
+                    "description": "NextStatementNumber is the number the next statement gets, to carry\non from statements the customer received before",
+                    "type": "integer",
+                    "maximum": 99999,
+                    "minimum": 1,
+                    "example": 42
+                }
+            }
+        },
+        "handlers.StatementRequest": {
+            "type": "object",
+            "required": [
+                "from",
+                "to"
+            ],
+            "properties": {
+                "from": {
+                    "description": "From and To are the first and last value dates covered, inclusive;\nTo must be before today in the customer's timezone",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-01"
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-30"
+                }
+            }
+        },
+        "handlers.StripeAccount": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string",
+                    "example": "acct_1PgK2nQ9x3yZ7aBc"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.StripeAccountRequest": {
+            "type": "object",
+            "required": [
+                "account_id"
+            ],
+            "properties": {
+                "account_id": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "acct_1PgK2nQ9x3yZ7aBc"
+                }
+            }
+        },
+        "handlers.StripeCurrencyTotals": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Available and Pending are the platform's Stripe balance",
+                    "type": "number",
+                    "example": 1840.25
+                },
+                "charges": {
+                    "description": "Charges is what the ledger credited for Stripe charges",
+                    "type": "number",
+                    "example": 2210.25
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "open_payouts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "payouts_open": {
+                    "type": "number",
+                    "example": 125.5
+                },
+                "payouts_paid": {
+                    "description": "PayoutsPaid and PayoutsOpen are withdrawals debited and paid out, or\non their way; PayoutsReversed are those that failed and were given back",
+                    "type": "number",
+                    "example": 250
+                },
+                "payouts_reversed": {
+                    "type": "number",
+                    "example": 0
+                },
+                "pending": {
+                    "type": "number",
+                    "example": 120
+                }
+            }
+        },
+        "handlers.StripeReconciliation": {
+            "type": "object",
+            "properties": {
+                "currencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.StripeCurrencyTotals"
+                    }
+                },
+                "generated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "handlers.StripeWebhookResponse": {
+            "description": "Outcome of a Stripe webhook event; a redelivered charge is acknowledged with the transaction it posted the first time",
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "Status is posted or held for a charge credited, duplicate for one\nalready credited, updated for a payout's new status, reversed when a\nfailed payout's withdrawal was reversed, and ignored otherwise",
+                    "type": "string",
+                    "enum": [
+                        "posted",
+                        "held",
+                        "pending_approval",
+                        "duplicate",
+                        "updated",
+                        "reversed",
+                        "compensation_failed",
+                        "ignored"
+                    ],
+                    "example": "posted"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.SubAccount": {
+            "description": "Named sub-account with its own balance",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 250
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "vacation fund"
+                },
+                "sub_account_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.SubAccountRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "USD"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "vacation fund"
+                }
+            }
+        },
+        "handlers.Transaction": {
+            "description": "Financial transaction information",
+            "type": "object",
+            "required": [
+                "amount",
+                "type"
+            ],
+            "properties": {
+                "allow_duplicate": {
+                    "description": "AllowDuplicate vouches for a legitimate repeat of a recent transaction\nof the same type and amount, which duplicate fraud rules let through.\nRequests with an Idempotency-Key are never treated as duplicates.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "amount": {
+                    "type": "number",
+                    "minimum": 0.01,
+                    "example": 200
+                },
+                "currency": {
+                    "description": "Currency defaults to the account's base currency. Amounts in another\ncurrency are converted at the current rate when FX is configured.",
+                    "type": "string",
+                    "enum": [
+                        "USD",
+                        "EUR",
+                        "GBP"
+                    ],
+                    "example": "USD"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "fx_rate": {
+                    "type": "number",
+                    "example": 1.0782
+                },
+                "original_amount": {
+                    "description": "OriginalAmount, OriginalCurrency, FXRate, RateProvider and Rounding\nare set in history on a transaction posted in another currency;\nAmount is then the converted amount",
+                    "type": "number",
+                    "example": 185.5
+                },
+                "original_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "rate_provider": {
+                    "type": "string",
+                    "example": "exchangerate-api"
+                },
+                "recorded_at": {
+                    "description": "RecordedAt is when a backdated transaction was actually written;\nTimestamp is the historical time it was posted at",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-10T09:30:00Z"
+                },
+                "reference": {
+                    "description": "Reference is the payment's external reference, such as the end-to-end\nID of a bank file line. With UniqueReference the customer may not have\nanother unique transaction with the same reference that was not rejected.",
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "INV-1234"
+                },
+                "rounding": {
+                    "type": "string",
+                    "enum": [
+                        "half_up",
+                        "half_even",
+                        "truncate",
+                        "down",
+                        "up"
+                    ],
+                    "example": "half_up"
+                },
+                "status": {
+                    "description": "Status is set in history: only posted transactions moved the balance",
+                    "type": "string",
+                    "enum": [
+                        "posted",
+                        "held",
+                        "pending_approval",
+                        "rejected"
+                    ],
+                    "example": "posted"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "transfer_id": {
+                    "description": "TransferID is set in reference lookups on a leg of a transfer",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit",
+                        "purchase",
+                        "refund",
+                        "fee",
+                        "interest"
+                    ],
+                    "example": "purchase"
+                },
+                "unique_reference": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "value_date": {
+                    "description": "ValueDate is the date the transaction takes effect for interest and\nstatements. It defaults to the posting date in the customer's\ntimezone and may be set up to valueDateWindow days either side of it.",
+                    "type": "string",
+                    "format": "date",
+                    "example": "2025-04-08"
+                }
+            }
+        },
+        "handlers.TransactionProof": {
+            "description": "Inclusion proof of a transaction in a published ledger anchor",
+            "type": "object",
+            "properties": {
+                "anchor": {
+                    "$ref": "#/definitions/handlers.LedgerAnchor"
+                },
+                "audit_path": {
+                    "description": "AuditPath holds the hex sibling hashes from the leaf up to the root",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "leaf": {
+                    "description": "Leaf is the transaction as hashed into the tree, in canonical JSON",
+                    "type": "string",
+                    "example": "{\"id\":\"...\",\"customer_id\":\"...\",\"type\":\"debit\",\"amount\":\"42.50\",\"value_date\":\"2025-04-08\",\"reference\":\"INV-2025-0042\",\"created_at\":\"2025-04-08T09:12:44.120551Z\"}"
+                },
+                "leaf_hash": {
+                    "description": "LeafHash is SHA-256(0x00 || leaf) in hex",
+                    "type": "string",
+                    "example": "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"
+                },
+                "leaf_index": {
+                    "type": "integer",
+                    "example": 17
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "verified": {
+                    "description": "Verified reports whether the leaf, as the ledger holds the\ntransaction now, proves against the anchor's root; false means the\ntransaction changed after it was anchored",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.TransactionResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount, OriginalAmount, OriginalCurrency, FXRate, RateProvider and\nRounding are set when the transaction was converted into the\naccount's currency",
+                    "type": "number",
+                    "example": 200
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 800
+                },
+                "fx_rate": {
+                    "type": "number",
+                    "example": 1.0782
+                },
+                "original_amount": {
+                    "type": "number",
+                    "example": 185.5
+                },
+                "original_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "rate_provider": {
+                    "type": "string",
+                    "example": "exchangerate-api"
+                },
+                "rounding": {
+                    "type": "string",
+                    "enum": [
+                        "half_up",
+                        "half_even",
+                        "truncate",
+                        "down",
+                        "up"
+                    ],
+                    "example": "half_up"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "success",
+                        "held",
+                        "pending_approval"
+                    ],
+                    "example": "success"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                }
+            }
+        },
+        "handlers.TransactionSubmission": {
+            "description": "Transaction accepted to be posted in the background, and what became of it",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount, OriginalAmount, OriginalCurrency, FXRate, RateProvider and\nRounding are set when the transaction was converted into the\naccount's currency",
+                    "type": "number",
+                    "example": 200
+                },
+                "balance": {
+                    "description": "Balance is the customer's balance right after the posting",
+                    "type": "number",
+                    "example": 800
+                },
+                "code": {
+                    "type": "string",
+                    "example": "duplicate_reference"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "duplicate_of": {
+                    "description": "DuplicateOf is the transaction already holding the reference when\nCode is duplicate_reference",
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "error": {
+                    "description": "Error and Code say why a failed or rejected transaction was refused,\nas the synchronous response would have",
+                    "type": "string",
+                    "example": "Insufficient balance"
+                },
+                "fx_rate": {
+                    "type": "number",
+                    "example": 1.0782
+                },
+                "original_amount": {
+                    "type": "number",
+                    "example": 185.5
+                },
+                "original_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "processed_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:18Z"
+                },
+                "rate_provider": {
+                    "type": "string",
+                    "example": "exchangerate-api"
+                },
+                "rounding": {
+                    "type": "string",
+                    "enum": [
+                        "half_up",
+                        "half_even",
+                        "truncate",
+                        "down",
+                        "up"
+                    ],
+                    "example": "half_up"
+                },
+                "status": {
+                    "description": "Status is accepted until a worker takes the transaction up and\nprocessing while it is posted. Then it is the transaction's own\nstatus, or failed when the posting was refused.",
+                    "type": "string",
+                    "enum": [
+                        "accepted",
+                        "processing",
+                        "posted",
+                        "held",
+                        "pending_approval",
+                        "rejected",
+                        "failed",
+                        "cancelled"
+                    ],
+                    "example": "posted"
+                },
+                "submitted_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "transaction_id": {
+                    "type": "string",
+                    "format": "uuid",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
                 }
             }
         },
-        "handlers.ErrorResponse": {
+        "handlers.TransactionTypeRequest": {
             "type": "object",
+            "required": [
+                "code",
+                "direction"
+            ],
             "properties": {
-                "error": {
+                "code": {
                     "type": "string",
-                    "example": "Invalid input"
+                    "example": "cashback"
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Card cashback reward"
+                },
+                "direction": {
+                    "type": "string",
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "example": "credit"
+                },
+                "gl_account": {
+                    "type": "string",
+                    "example": "card_rewards_expense"
+                },
+                "postable": {
+                    "type": "boolean",
+                    "default": true,
+                    "example": true
                 }
             }
         },
-        "handlers.Transaction": {
-            "description": "Financial transaction information",
+        "handlers.TransactionValidation": {
+            "description": "Would-be result of a transaction checked with dry_run=true; nothing is written",
             "type": "object",
-            "required": [
-                "amount",
-                "type"
-            ],
             "properties": {
-                "amount": {
+                "balance": {
                     "type": "number",
-                    "example": 200
+                    "example": 800
                 },
                 "customer_id": {
                     "type": "string",
+                    "format": "uuid",
                     "example": "550e8400-e29b-41d4-a716-446655440000"
                 },
-                "timestamp": {
+                "dry_run": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "previous_balance": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "status": {
+                    "description": "Status is pending_approval when account rules would hold the\ntransaction for approval. Fraud rules are not applied to dry runs.",
+                    "type": "string",
+                    "enum": [
+                        "posted",
+                        "pending_approval"
+                    ],
+                    "example": "posted"
+                }
+            }
+        },
+        "handlers.TrialBalance": {
+            "type": "object",
+            "properties": {
+                "as_of": {
                     "type": "string",
+                    "format": "date-time",
                     "example": "2025-04-08T17:09:17Z"
                 },
-                "transaction_id": {
+                "balanced": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "imbalances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.Imbalance"
+                    }
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TrialBalanceLine"
+                    }
+                }
+            }
+        },
+        "handlers.TrialBalanceLine": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "balance": {
+                    "type": "number",
+                    "example": 1523400.5
+                },
+                "currency": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "USD"
                 },
-                "type": {
+                "difference": {
+                    "type": "number",
+                    "example": 0
+                },
+                "expected": {
+                    "type": "number",
+                    "example": 1523400.5
+                },
+                "imbalanced_accounts": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "ledger": {
                     "type": "string",
                     "enum": [
+                        "customer",
                         "credit",
-                        "debit"
+                        "sub_account"
                     ],
-                    "example": "credit"
+                    "example": "customer"
                 }
             }
         },
-        "handlers.TransactionResponse": {
+        "handlers.VerificationUpdateRequest": {
             "type": "object",
+            "required": [
+                "status"
+            ],
             "properties": {
-                "balance": {
+                "note": {
+                    "type": "string",
+                    "example": "Passport checked"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "unverified",
+                        "pending",
+                        "verified",
+                        "rejected"
+                    ],
+                    "example": "verified"
+                }
+            }
+        },
+        "handlers.Webhook": {
+            "description": "Webhook subscription",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Accounting sync"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "transaction.posted",
+                        "transfer.completed"
+                    ]
+                },
+                "secret": {
+                    "description": "Secret signs deliveries; it is only returned when the webhook is created",
+                    "type": "string",
+                    "example": "whsec_5f2b..."
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T17:09:17Z"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/ledger"
+                },
+                "webhook_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.WebhookReplay": {
+            "description": "Webhook replay and its progress",
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T09:02:00Z"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T09:00:00Z"
+                },
+                "delivered": {
+                    "type": "integer",
+                    "example": 80
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "from": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T00:00:00Z"
+                },
+                "replay_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "running",
+                        "completed"
+                    ],
+                    "example": "running"
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-04-08T06:00:00Z"
+                },
+                "total": {
+                    "description": "Total is how many events the replay covers; Delivered and Failed count\nthe attempts made so far",
+                    "type": "integer",
+                    "example": 120
+                },
+                "webhook_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "handlers.WebhookRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "Accounting sync"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "default": true,
+                    "example": true
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "transaction.posted",
+                        "transfer.completed"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/ledger"
+                }
+            }
+        },
+        "handlers.WebhookTestResponse": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 84
+                },
+                "error": {
+                    "type": "string",
+                    "example": "endpoint returned status 500"
+                },
+                "event_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "status_code": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "handlers.WebhookUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Accounting sync"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "transaction.posted"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/ledger"
+                }
+            }
+        },
+        "handlers.WithdrawalRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
                     "type": "number",
-                    "example": 800
+                    "minimum": 0.01,
+                    "example": 125.5
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 140,
+                    "example": "Withdrawal to checking"
+                }
+            }
+        },
+        "saga.Saga": {
+            "description": "A ledger posting coordinated with actions in other services, compensated in reverse order when one fails",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 250
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "customer_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "payout failed: action returned status 502"
+                },
+                "id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "reference": {
+                    "type": "string",
+                    "example": "Payout 2025-04-08"
+                },
+                "reversal_transaction_id": {
+                    "type": "string",
+                    "format": "uuid"
                 },
                 "status": {
                     "type": "string",
-                    "example": "success"
+                    "enum": [
+                        "running",
+                        "completed",
+                        "failed",
+                        "compensating",
+                        "compensated",
+                        "compensation_failed"
+                    ],
+                    "example": "completed"
+                },
+                "steps": {
+                    "description": "Steps starts with the ledger posting, followed by the actions in order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/saga.Step"
+                    }
                 },
                 "transaction_id": {
+                    "description": "TransactionID is the posting; ReversalID the transaction undoing it",
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "format": "uuid"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "payout"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "format": "date-time"
+                }
+            }
+        },
+        "saga.Step": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "action returned status 502"
+                },
+                "external_id": {
+                    "description": "ExternalID is what the action's service calls the step, if it says",
+                    "type": "string",
+                    "example": "po_8f2k1"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "payout"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "running",
+                        "done",
+                        "failed",
+                        "compensated",
+                        "compensation_failed"
+                    ],
+                    "example": "done"
+                }
+            }
+        },
+        "seed.Options": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "days": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "max_transactions": {
+                    "type": "integer",
+                    "example": 40
+                },
+                "seed": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "seed.Result": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "seed": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "transactions": {
+                    "type": "integer",
+                    "example": 1012
+                }
+            }
+        },
+        "txtype.Direction": {
+            "type": "string",
+            "enum": [
+                "credit",
+                "debit"
+            ],
+            "x-enum-varnames": [
+                "Credit",
+                "Debit"
+            ]
+        },
+        "txtype.Type": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "purchase"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Card or merchant purchase"
+                },
+                "direction": {
+                    "enum": [
+                        "credit",
+                        "debit"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/txtype.Direction"
+                        }
+                    ],
+                    "example": "debit"
+                },
+                "gl_account": {
+                    "description": "GLAccount is the general ledger account posted on the other side of\nthe customer's balance, for types whose money comes from or goes to\nthe bank itself rather than another customer",
+                    "type": "string",
+                    "example": "fees_income"
+                },
+                "postable": {
+                    "description": "Postable types can be posted directly through the transactions API;\nthe rest are only written by internal flows such as transfers",
+                    "type": "boolean",
+                    "example": true
                 }
             }
         }
@@ -366,7 +15687,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8080",
-	BasePath:         "/",
+	BasePath:         "/v1",
 	Schemes:          []string{},
 	Title:            "Ledger Service API",
 	Description:      "A simple ledger service that maintains customer balances and transactions.",
@@ -0,0 +1,198 @@
+// Package errreport sends panics and server errors to Sentry, or any service
+// that accepts Sentry's store API.
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ledger-service/redact"
+)
+
+// Event is one error occurrence
+type Event struct {
+	Level     string            `json:"level"`
+	Message   string            `json:"message,omitempty"`
+	Exception *Exception        `json:"-"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Request   *Request          `json:"request,omitempty"`
+}
+
+// Exception describes a panic or error value
+type Exception struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace string `json:"-"`
+}
+
+// Request is the HTTP request an event happened in
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// payload is the wire format of Sentry's store endpoint
+type payload struct {
+	EventID     string `json:"event_id"`
+	Timestamp   string `json:"timestamp"`
+	Platform    string `json:"platform"`
+	Environment string `json:"environment,omitempty"`
+	Release     string `json:"release,omitempty"`
+	Event
+	Exception *struct {
+		Values []Exception `json:"values"`
+	} `json:"exception,omitempty"`
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// maxInFlight bounds how many events are being sent at once; further events are dropped
+const maxInFlight = 10
+
+// Client reports events to a Sentry-compatible endpoint in the background
+type Client struct {
+	Environment string
+	Release     string
+	HTTPClient  *http.Client
+
+	endpoint string
+	auth     string
+	slots    chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New parses a DSN of the form https://<key>@<host>/<project_id>
+func New(dsn string) (*Client, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	key := u.User.Username()
+	project := strings.Trim(u.Path, "/")
+	if u.Scheme == "" || u.Host == "" || key == "" || project == "" {
+		return nil, fmt.Errorf("DSN must look like https://<key>@<host>/<project_id>")
+	}
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 5 * time.Second},
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		auth:       fmt.Sprintf("Sentry sentry_version=7, sentry_client=ledger-service/1.0, sentry_key=%s", key),
+		slots:      make(chan struct{}, maxInFlight),
+	}, nil
+}
+
+// Capture sends ev without blocking the caller. Events are dropped when too
+// many are already in flight.
+func (c *Client) Capture(ev Event) {
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer func() {
+			<-c.slots
+			c.wg.Done()
+		}()
+		c.send(context.Background(), ev)
+	}()
+}
+
+// Flush waits up to timeout for events in flight to be sent
+func (c *Client) Flush(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (c *Client) send(ctx context.Context, ev Event) error {
+	ev = redactEvent(ev)
+	p := payload{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Platform:    "go",
+		Environment: c.Environment,
+		Release:     c.Release,
+		Event:       ev,
+	}
+	if p.Level == "" {
+		p.Level = "error"
+	}
+	if ev.Exception != nil {
+		p.Exception = &struct {
+			Values []Exception `json:"values"`
+		}{Values: []Exception{*ev.Exception}}
+		if ev.Exception.Stacktrace != "" {
+			p.Extra = map[string]string{"stacktrace": ev.Exception.Stacktrace}
+		}
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error reporting endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// redactEvent masks personal data and secrets in what ev carries out of
+// the service
+func redactEvent(ev Event) Event {
+	r := redact.Default()
+	ev.Message = r.String(ev.Message)
+	if ev.Exception != nil {
+		exc := *ev.Exception
+		exc.Value = r.String(exc.Value)
+		ev.Exception = &exc
+	}
+	if ev.Tags != nil {
+		tags := make(map[string]string, len(ev.Tags))
+		for k, v := range ev.Tags {
+			tags[k] = r.Field(k, v)
+		}
+		ev.Tags = tags
+	}
+	if ev.Request != nil {
+		req := *ev.Request
+		req.URL = r.URL(req.URL)
+		ev.Request = &req
+	}
+	return ev
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
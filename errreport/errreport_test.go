@@ -0,0 +1,99 @@
+package errreport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		dsn          string
+		wantEndpoint string
+		wantErr      bool
+	}{
+		{name: "sentry.io", dsn: "https://abc123@o1.ingest.sentry.io/42", wantEndpoint: "https://o1.ingest.sentry.io/api/42/store/"},
+		{name: "path prefix", dsn: "http://abc123@errors.internal/sentry/7", wantEndpoint: "http://errors.internal/sentry/api/7/store/"},
+		{name: "missing key", dsn: "https://o1.ingest.sentry.io/42", wantErr: true},
+		{name: "missing project", dsn: "https://abc123@o1.ingest.sentry.io", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.dsn)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEndpoint, c.endpoint)
+		})
+	}
+}
+
+func TestCapture(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("X-Sentry-Auth")
+		body, _ := io.ReadAll(r.Body)
+		var p map[string]interface{}
+		json.Unmarshal(body, &p)
+		received <- p
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c, err := New(strings.Replace(server.URL, "http://", "http://key1@", 1) + "/9")
+	assert.NoError(t, err)
+	c.Environment = "test"
+
+	c.Capture(Event{
+		Message:   "panic: boom",
+		Exception: &Exception{Type: "panic", Value: "boom", Stacktrace: "goroutine 1"},
+		Tags:      map[string]string{"route": "/v1/transactions"},
+	})
+	c.Flush(time.Second)
+
+	p := <-received
+	assert.Contains(t, auth, "sentry_key=key1")
+	assert.Equal(t, "error", p["level"])
+	assert.Equal(t, "test", p["environment"])
+	assert.Equal(t, "/v1/transactions", p["tags"].(map[string]interface{})["route"])
+	assert.Len(t, p["event_id"], 32)
+	values := p["exception"].(map[string]interface{})["values"].([]interface{})
+	assert.Equal(t, "boom", values[0].(map[string]interface{})["value"])
+}
+
+func TestCaptureRedacts(t *testing.T) {
+	received := make(chan payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p payload
+		json.NewDecoder(r.Body).Decode(&p)
+		received <- p
+	}))
+	defer server.Close()
+
+	c, err := New(strings.Replace(server.URL, "http://", "http://key1@", 1) + "/9")
+	assert.NoError(t, err)
+	c.Capture(Event{
+		Message:   "500 on POST /v1/customers: duplicate email jane@example.com",
+		Exception: &Exception{Type: "panic", Value: `decode {"name":"Jane Doe"}`},
+		Tags:      map[string]string{"route": "/v1/customers", "alias": "@jane"},
+		Request:   &Request{Method: "GET", URL: "/v1/customers?email=jane%40example.com"},
+	})
+	c.Flush(time.Second)
+
+	p := <-received
+	assert.Equal(t, "500 on POST /v1/customers: duplicate email j***@example.com", p.Message)
+	assert.Equal(t, `decode {"name":"J*** D***"}`, p.Exception.Values[0].Value)
+	assert.Equal(t, "@j***", p.Tags["alias"])
+	assert.Equal(t, "/v1/customers?email=j***@example.com", p.Request.URL)
+}
@@ -0,0 +1,281 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// amqpMessage is a message received by the fake broker
+type amqpMessage struct {
+	exchange, routingKey string
+	properties           []byte
+	body                 []byte
+}
+
+// fakeBroker is a minimal AMQP 0-9-1 broker. reply decides how each publish
+// is answered: "ack", "nack", "return", "drop" or "close".
+type fakeBroker struct {
+	ln net.Listener
+
+	mu        sync.Mutex
+	logins    []string
+	vhosts    []string
+	exchanges []string
+	messages  []amqpMessage
+	reply     func(n int) string
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	b := &fakeBroker{ln: ln, reply: func(int) string { return "ack" }}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *fakeBroker) url(userinfo, vhost string) string {
+	return "amqp://" + userinfo + b.ln.Addr().String() + vhost
+}
+
+func (b *fakeBroker) send(conn net.Conn, typ byte, channel uint16, payload []byte) {
+	var out bytes.Buffer
+	appendFrame(&out, typ, channel, payload)
+	conn.Write(out.Bytes())
+}
+
+func (b *fakeBroker) sendMethod(conn net.Conn, channel uint16, w *amqpWriter) {
+	b.send(conn, amqpFrameMethod, channel, w.Bytes())
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := &AMQPPublisher{r: bufio.NewReader(conn)}
+	var protocol [8]byte
+	if _, err := io.ReadFull(r.r, protocol[:]); err != nil || string(protocol[:]) != "AMQP\x00\x00\x09\x01" {
+		return
+	}
+	var w amqpWriter
+	w.method(amqpConnection, 10).octet(0).octet(9).table(nil).longstr("PLAIN").longstr("en_US")
+	b.sendMethod(conn, 0, &w)
+
+	deliveryTag := uint64(0)
+	for {
+		f, err := r.readFrame()
+		if err != nil || f.typ != amqpFrameMethod {
+			return
+		}
+		m := newAMQPReader(f.payload)
+		w.Reset()
+		switch {
+		case m.class == amqpConnection && m.method == 11: // StartOk
+			m.take(int(m.long()))
+			m.shortstr()
+			b.mu.Lock()
+			b.logins = append(b.logins, strings.ReplaceAll(string(m.take(int(m.long()))), "\x00", "|"))
+			b.mu.Unlock()
+			// A small frame-max makes larger bodies span several frames
+			w.method(amqpConnection, 30).short(2047).long(256).short(60)
+			b.sendMethod(conn, 0, &w)
+		case m.class == amqpConnection && m.method == 40: // Open
+			b.mu.Lock()
+			b.vhosts = append(b.vhosts, m.shortstr())
+			b.mu.Unlock()
+			w.method(amqpConnection, 41).shortstr("")
+			b.sendMethod(conn, 0, &w)
+		case m.class == amqpChannel && m.method == 10:
+			w.method(amqpChannel, 11).longstr("")
+			b.sendMethod(conn, f.channel, &w)
+		case m.class == amqpConfirm && m.method == 10:
+			w.method(amqpConfirm, 11)
+			b.sendMethod(conn, f.channel, &w)
+		case m.class == amqpExchange && m.method == 10:
+			m.short()
+			name, kind := m.shortstr(), m.shortstr()
+			b.mu.Lock()
+			b.exchanges = append(b.exchanges, name+":"+kind)
+			b.mu.Unlock()
+			w.method(amqpExchange, 11)
+			b.sendMethod(conn, f.channel, &w)
+		case m.class == amqpBasic && m.method == 40: // Publish
+			m.short()
+			msg := amqpMessage{exchange: m.shortstr(), routingKey: m.shortstr()}
+			header, err := r.readFrame()
+			if err != nil {
+				return
+			}
+			size := binary.BigEndian.Uint64(header.payload[4:12])
+			msg.properties = header.payload[14:]
+			for uint64(len(msg.body)) < size {
+				body, err := r.readFrame()
+				if err != nil {
+					return
+				}
+				msg.body = append(msg.body, body.payload...)
+			}
+			deliveryTag++
+
+			b.mu.Lock()
+			b.messages = append(b.messages, msg)
+			reply := b.reply(len(b.messages))
+			b.mu.Unlock()
+			switch reply {
+			case "drop":
+				return
+			case "close":
+				w.method(amqpChannel, 40).short(404).shortstr("NOT_FOUND - no exchange").short(amqpBasic).short(40)
+				b.sendMethod(conn, f.channel, &w)
+				continue
+			case "return":
+				w.method(amqpBasic, 50).short(312).shortstr("NO_ROUTE").shortstr(msg.exchange).shortstr(msg.routingKey)
+				b.sendMethod(conn, f.channel, &w)
+				b.send(conn, amqpFrameHeader, f.channel, header.payload)
+				b.send(conn, amqpFrameBody, f.channel, msg.body)
+				w.Reset()
+				reply = "ack"
+			}
+			// A heartbeat first, which the publisher must skip
+			b.send(conn, amqpFrameHeartbeat, 0, nil)
+			if reply == "nack" {
+				w.method(amqpBasic, 120).longlong(deliveryTag).octet(0)
+			} else {
+				w.method(amqpBasic, 80).longlong(deliveryTag).octet(0)
+			}
+			b.sendMethod(conn, f.channel, &w)
+		case m.class == amqpConnection && m.method == 50:
+			w.method(amqpConnection, 51)
+			b.sendMethod(conn, 0, &w)
+			return
+		}
+	}
+}
+
+func TestAMQPPublisher(t *testing.T) {
+	broker := newFakeBroker(t)
+	p := NewAMQPPublisher(broker.url("ledger:s3cret@", "/prod"), "ledger.events", "topic", "ledger.{type}", time.Second)
+	defer p.Close()
+
+	customerID := uuid.New()
+	posted, _ := New(TransactionPosted, &customerID, map[string]string{"memo": strings.Repeat("x", 600)})
+	created, _ := New(CustomerCreated, nil, map[string]string{})
+	assert.NoError(t, p.Publish(context.Background(), posted))
+	assert.NoError(t, p.Publish(context.Background(), created))
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Equal(t, []string{"|ledger|s3cret"}, broker.logins)
+	assert.Equal(t, []string{"prod"}, broker.vhosts)
+	assert.Equal(t, []string{"ledger.events:topic"}, broker.exchanges)
+	assert.Len(t, broker.messages, 2)
+
+	msg := broker.messages[0]
+	assert.Equal(t, "ledger.events", msg.exchange)
+	assert.Equal(t, "ledger.transaction.posted", msg.routingKey)
+	assert.Contains(t, string(msg.body), strings.Repeat("x", 600))
+	assert.Contains(t, string(msg.properties), "application/json")
+	assert.Contains(t, string(msg.properties), posted.ID.String())
+	assert.Contains(t, string(msg.properties), customerID.String())
+	assert.Equal(t, "ledger.customer.created", broker.messages[1].routingKey)
+}
+
+func TestAMQPPublisherDefaultExchange(t *testing.T) {
+	broker := newFakeBroker(t)
+	p := NewAMQPPublisher(broker.url("", ""), "", "", "ledger-events", time.Second)
+	defer p.Close()
+
+	ev, _ := New(TransactionPosted, nil, map[string]string{})
+	assert.NoError(t, p.Publish(context.Background(), ev))
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Equal(t, []string{"|guest|guest"}, broker.logins)
+	assert.Equal(t, []string{"/"}, broker.vhosts)
+	assert.Empty(t, broker.exchanges)
+	assert.Equal(t, "ledger-events", broker.messages[0].routingKey)
+}
+
+func TestAMQPPublisherFailures(t *testing.T) {
+	broker := newFakeBroker(t)
+	p := NewAMQPPublisher(broker.url("", ""), "ledger.events", "topic", "{type}", time.Second)
+	defer p.Close()
+	ev, _ := New(TransactionPosted, nil, map[string]string{})
+
+	setReply := func(reply string) {
+		broker.mu.Lock()
+		broker.reply = func(int) string { return reply }
+		broker.mu.Unlock()
+	}
+
+	var amqpErr *AMQPError
+	setReply("nack")
+	assert.ErrorAs(t, p.Publish(context.Background(), ev), &amqpErr)
+	assert.Contains(t, amqpErr.Text, "rejected")
+
+	setReply("return")
+	assert.ErrorAs(t, p.Publish(context.Background(), ev), &amqpErr)
+	assert.Equal(t, 312, amqpErr.Code)
+
+	// Confirms continue on the same connection after a nack or return
+	setReply("ack")
+	assert.NoError(t, p.Publish(context.Background(), ev))
+
+	setReply("close")
+	assert.ErrorAs(t, p.Publish(context.Background(), ev), &amqpErr)
+	assert.Equal(t, 404, amqpErr.Code)
+
+	// The closed channel takes the connection with it; the next publish reconnects
+	setReply("ack")
+	assert.NoError(t, p.Publish(context.Background(), ev))
+
+	broker.mu.Lock()
+	assert.Len(t, broker.logins, 2)
+	broker.mu.Unlock()
+}
+
+func TestAMQPPublisherReconnects(t *testing.T) {
+	broker := newFakeBroker(t)
+	p := NewAMQPPublisher(broker.url("", ""), "ledger.events", "topic", "{type}", time.Second)
+	defer p.Close()
+
+	// The broker drops the connection instead of confirming the first publish
+	broker.reply = func(n int) string {
+		if n == 1 {
+			return "drop"
+		}
+		return "ack"
+	}
+	ev, _ := New(TransactionPosted, nil, map[string]string{})
+	assert.NoError(t, p.Publish(context.Background(), ev))
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Len(t, broker.logins, 2)
+	assert.Len(t, broker.messages, 2)
+}
+
+func TestAMQPPublisherInvalidURL(t *testing.T) {
+	ev, _ := New(TransactionPosted, nil, map[string]string{})
+	assert.Error(t, NewAMQPPublisher("http://localhost", "", "", "{type}", time.Second).Publish(context.Background(), ev))
+	assert.Error(t, NewAMQPPublisher("amqp://127.0.0.1:1", "", "", "{type}", time.Second).Publish(context.Background(), ev))
+}
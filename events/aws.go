@@ -0,0 +1,399 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentials are the keys AWS requests are signed with
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expires is zero for credentials that do not expire
+	Expires time.Time
+}
+
+// AWSCredentialProvider supplies the credentials for each request
+type AWSCredentialProvider interface {
+	Credentials(ctx context.Context) (AWSCredentials, error)
+}
+
+// StaticAWSCredentials are fixed access keys, e.g. from AWS_ACCESS_KEY_ID
+type StaticAWSCredentials AWSCredentials
+
+// Credentials returns the keys
+func (s StaticAWSCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	return AWSCredentials(s), nil
+}
+
+// AWSRoleCredentials fetches temporary credentials for the IAM role the
+// service runs as. It tries, in order, a web identity token (EKS service
+// accounts), the container credentials endpoint (ECS and Fargate) and the
+// EC2 instance metadata service. Credentials are cached until five minutes
+// before they expire.
+type AWSRoleCredentials struct {
+	Client *http.Client
+
+	// RoleARN and WebIdentityTokenFile enable AssumeRoleWithWebIdentity
+	RoleARN              string
+	WebIdentityTokenFile string
+	// STSEndpoint defaults to https://sts.amazonaws.com
+	STSEndpoint string
+
+	// ContainerCredentialsURL enables the container endpoint, sending
+	// ContainerAuthToken as Authorization when set
+	ContainerCredentialsURL string
+	ContainerAuthToken      string
+
+	// IMDSEndpoint defaults to http://169.254.169.254
+	IMDSEndpoint string
+
+	mu     sync.Mutex
+	cached AWSCredentials
+}
+
+// Credentials returns cached credentials, refreshing them when they are
+// about to expire
+func (r *AWSRoleCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cached.AccessKeyID != "" && time.Until(r.cached.Expires) > 5*time.Minute {
+		return r.cached, nil
+	}
+
+	var creds AWSCredentials
+	var err error
+	switch {
+	case r.RoleARN != "" && r.WebIdentityTokenFile != "":
+		creds, err = r.webIdentity(ctx)
+	case r.ContainerCredentialsURL != "":
+		creds, err = r.container(ctx)
+	default:
+		creds, err = r.instanceMetadata(ctx)
+	}
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("aws credentials: %w", err)
+	}
+	r.cached = creds
+	return creds, nil
+}
+
+func (r *AWSRoleCredentials) webIdentity(ctx context.Context) (AWSCredentials, error) {
+	token, err := os.ReadFile(r.WebIdentityTokenFile)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	endpoint := r.STSEndpoint
+	if endpoint == "" {
+		endpoint = "https://sts.amazonaws.com"
+	}
+	params := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {r.RoleARN},
+		"RoleSessionName":  {"ledger-service"},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	// AssumeRoleWithWebIdentity is authenticated by the token, not signed
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	data, err := r.do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	var resp struct {
+		AccessKeyID     string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>AccessKeyId"`
+		SecretAccessKey string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SecretAccessKey"`
+		SessionToken    string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SessionToken"`
+		Expiration      time.Time `xml:"AssumeRoleWithWebIdentityResult>Credentials>Expiration"`
+	}
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return AWSCredentials{}, err
+	}
+	return AWSCredentials{resp.AccessKeyID, resp.SecretAccessKey, resp.SessionToken, resp.Expiration}, nil
+}
+
+// roleCredentials is the JSON shape served by the container endpoint and IMDS
+type roleCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (c roleCredentials) credentials() (AWSCredentials, error) {
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("response did not contain credentials")
+	}
+	return AWSCredentials{c.AccessKeyID, c.SecretAccessKey, c.Token, c.Expiration}, nil
+}
+
+func (r *AWSRoleCredentials) container(ctx context.Context) (AWSCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.ContainerCredentialsURL, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	if r.ContainerAuthToken != "" {
+		req.Header.Set("Authorization", r.ContainerAuthToken)
+	}
+	data, err := r.do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	var creds roleCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return AWSCredentials{}, err
+	}
+	return creds.credentials()
+}
+
+// instanceMetadata reads the instance profile's credentials over IMDSv2
+func (r *AWSRoleCredentials) instanceMetadata(ctx context.Context) (AWSCredentials, error) {
+	endpoint := r.IMDSEndpoint
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	token, err := r.do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return r.do(req)
+	}
+	roles, err := get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if role == "" {
+		return AWSCredentials{}, errors.New("no instance profile role attached")
+	}
+	data, err := get("/latest/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	var creds roleCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return AWSCredentials{}, err
+	}
+	return creds.credentials()
+}
+
+func (r *AWSRoleCredentials) do(req *http.Request) ([]byte, error) {
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s returned status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return data, nil
+}
+
+// AWSError is an error returned by an AWS API
+type AWSError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *AWSError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("aws: %s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("aws: %s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+// awsQuery posts an AWS Query API request signed for service in region and
+// decodes the XML response into out
+func awsQuery(ctx context.Context, client *http.Client, creds AWSCredentialProvider, endpoint, region, service string, params url.Values, out interface{}) error {
+	c, err := creds.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	body := []byte(awsEncode(params))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signAWS(req, body, c, region, service, time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("aws: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("aws: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		xml.Unmarshal(data, &e)
+		return &AWSError{StatusCode: resp.StatusCode, Code: e.Code, Message: e.Message}
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// AWSJSON posts an AWS JSON 1.1 API request, such as KMS's, signed for
+// service in region. target names the action, e.g. TrentService.Sign; in is
+// the request body and the response is decoded into out.
+func AWSJSON(ctx context.Context, client *http.Client, creds AWSCredentialProvider, endpoint, region, service, target string, in, out interface{}) error {
+	c, err := creds.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	signAWS(req, body, c, region, service, time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("aws: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("aws: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &e)
+		// The type may be qualified, e.g. com.amazonaws.kms#NotFoundException
+		if i := strings.LastIndex(e.Type, "#"); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return &AWSError{StatusCode: resp.StatusCode, Code: e.Type, Message: e.Message}
+	}
+	return json.Unmarshal(data, out)
+}
+
+// awsEncode form-encodes params the way Signature Version 4 expects: sorted,
+// with spaces as %20
+func awsEncode(params url.Values) string {
+	return strings.ReplaceAll(params.Encode(), "+", "%20")
+}
+
+// signAWS signs req, whose body is body, with AWS Signature Version 4. Every
+// header already set on req is signed along with Host.
+func signAWS(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		awsEncode(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
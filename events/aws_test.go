@@ -0,0 +1,166 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var exampleCredentials = AWSCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSignAWS(t *testing.T) {
+	signedAt := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		url         string
+		contentType string
+		service     string
+		want        string
+	}{
+		{
+			name:    "get-vanilla test suite request",
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:        "IAM ListUsers documentation example",
+			url:         "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			service:     "iam",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			signAWS(req, nil, exampleCredentials, "us-east-1", tt.service, signedAt)
+			assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+			assert.Equal(t, tt.want, req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestSignAWSSessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://sns.us-east-1.amazonaws.com/", nil)
+	creds := exampleCredentials
+	creds.SessionToken = "session"
+	signAWS(req, []byte("Action=Publish"), creds, "us-east-1", "sns", time.Now())
+
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,")
+}
+
+func TestAWSRoleCredentials(t *testing.T) {
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("instance metadata", func(t *testing.T) {
+		var fetches atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+				w.Write([]byte("imds-token"))
+			case r.Header.Get("X-aws-ec2-metadata-token") != "imds-token":
+				w.WriteHeader(http.StatusUnauthorized)
+			case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+				w.Write([]byte("ledger-role\n"))
+			case r.URL.Path == "/latest/meta-data/iam/security-credentials/ledger-role":
+				fetches.Add(1)
+				w.Write([]byte(`{"Code":"Success","AccessKeyId":"ASIAIMDS","SecretAccessKey":"secret","Token":"token","Expiration":"` + expires + `"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		r := &AWSRoleCredentials{IMDSEndpoint: server.URL}
+		creds, err := r.Credentials(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "ASIAIMDS", creds.AccessKeyID)
+		assert.Equal(t, "token", creds.SessionToken)
+
+		// Cached until shortly before expiry
+		_, err = r.Credentials(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("container endpoint", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "pod-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"AccessKeyId":"ASIAECS","SecretAccessKey":"secret","Token":"token","Expiration":"` + expires + `"}`))
+		}))
+		defer server.Close()
+
+		r := &AWSRoleCredentials{ContainerCredentialsURL: server.URL + "/v2/credentials/abc", ContainerAuthToken: "pod-token"}
+		creds, err := r.Credentials(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "ASIAECS", creds.AccessKeyID)
+
+		r = &AWSRoleCredentials{ContainerCredentialsURL: server.URL + "/v2/credentials/abc"}
+		_, err = r.Credentials(context.Background())
+		assert.ErrorContains(t, err, "status 403")
+	})
+
+	t.Run("web identity", func(t *testing.T) {
+		tokenFile := filepath.Join(t.TempDir(), "token")
+		os.WriteFile(tokenFile, []byte("jwt-token\n"), 0o600)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "jwt-token" ||
+				r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/ledger" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>` +
+				`<AccessKeyId>ASIASTS</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>` +
+				`<Expiration>` + expires + `</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+		}))
+		defer server.Close()
+
+		r := &AWSRoleCredentials{
+			RoleARN:              "arn:aws:iam::123456789012:role/ledger",
+			WebIdentityTokenFile: tokenFile,
+			STSEndpoint:          server.URL,
+		}
+		creds, err := r.Credentials(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "ASIASTS", creds.AccessKeyID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), creds.Expires, time.Minute)
+	})
+
+	t.Run("no role attached", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/security-credentials/") {
+				return
+			}
+			w.Write([]byte("imds-token"))
+		}))
+		defer server.Close()
+
+		_, err := (&AWSRoleCredentials{IMDSEndpoint: server.URL}).Credentials(context.Background())
+		assert.ErrorContains(t, err, "no instance profile role")
+	})
+}
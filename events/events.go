@@ -0,0 +1,79 @@
+// Package events defines the ledger events published to subscribers and the
+// envelope every event travels in, whatever the transport
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	TransactionPosted   = "transaction.posted"
+	TransactionHeld     = "transaction.held"
+	TransactionRejected = "transaction.rejected"
+	TransferCompleted   = "transfer.completed"
+	TransferReserved    = "transfer.reserved"
+	TransferReleased    = "transfer.released"
+	BalanceAdjusted     = "balance.adjusted"
+	CustomerCreated     = "customer.created"
+	AccountDormant      = "account.dormant"
+	AccountReactivated  = "account.reactivated"
+	LedgerAnchored      = "ledger.anchored"
+	SecurityLockout     = "security.lockout"
+	WebhookTest         = "webhook.test"
+)
+
+// Types lists every event type subscribers can filter on
+var Types = []string{
+	TransactionPosted,
+	TransactionHeld,
+	TransactionRejected,
+	TransferCompleted,
+	TransferReserved,
+	TransferReleased,
+	BalanceAdjusted,
+	CustomerCreated,
+	AccountDormant,
+	AccountReactivated,
+	LedgerAnchored,
+	SecurityLockout,
+	WebhookTest,
+}
+
+// Valid reports whether t is a known event type
+func Valid(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the envelope of a published event. Data holds the type-specific
+// payload.
+type Event struct {
+	ID         uuid.UUID       `json:"id" format:"uuid"`
+	Type       string          `json:"type" example:"transaction.posted"`
+	CustomerID *uuid.UUID      `json:"customer_id,omitempty" format:"uuid"`
+	OccurredAt time.Time       `json:"occurred_at" format:"date-time"`
+	Data       json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// New builds an event with a fresh ID, marshalling data as its payload
+func New(eventType string, customerID *uuid.UUID, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		CustomerID: customerID,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
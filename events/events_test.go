@@ -0,0 +1,34 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	for _, typ := range Types {
+		assert.True(t, Valid(typ), typ)
+	}
+	assert.False(t, Valid("transaction.deleted"))
+	assert.False(t, Valid(""))
+}
+
+func TestNew(t *testing.T) {
+	customerID := uuid.New()
+	ev, err := New(TransactionPosted, &customerID, map[string]float64{"amount": 12.5})
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, ev.ID)
+	assert.Equal(t, TransactionPosted, ev.Type)
+	assert.Equal(t, &customerID, ev.CustomerID)
+	assert.JSONEq(t, `{"amount":12.5}`, string(ev.Data))
+
+	b, err := json.Marshal(ev)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"customer_id":"`+customerID.String()+`"`)
+
+	_, err = New(TransactionPosted, nil, make(chan int))
+	assert.Error(t, err)
+}
@@ -0,0 +1,346 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNoResponders is returned when no JetStream stream captures a subject
+var errNoResponders = errors.New("no JetStream stream is listening on the subject")
+
+// JetStreamError is an error reported by the JetStream API, e.g. a rejected
+// publish. Retrying on a new connection will not help.
+type JetStreamError struct {
+	Code        int    `json:"code"`
+	ErrCode     int    `json:"err_code"`
+	Description string `json:"description"`
+}
+
+func (e *JetStreamError) Error() string {
+	return fmt.Sprintf("jetstream: %s (code %d)", e.Description, e.Code)
+}
+
+// NATSPublisher publishes events to NATS JetStream, one subject per event
+// type: <SubjectPrefix>.<event type>, e.g. ledger.transaction.posted. Every
+// publish waits for the stream's acknowledgement and carries the event ID as
+// Nats-Msg-Id, so redeliveries inside the stream's duplicate window are
+// dropped by the server.
+//
+// It speaks the NATS client protocol directly over one connection, which is
+// dialled lazily and replaced after any I/O error.
+type NATSPublisher struct {
+	// URL is nats://[user:password@|token@]host[:port]; tls:// forces TLS
+	URL           string
+	SubjectPrefix string
+	// Stream is created on first connect, capturing <SubjectPrefix>.>, when
+	// it does not exist yet. Leave empty to manage the stream elsewhere.
+	Stream  string
+	Timeout time.Duration
+
+	mu          sync.Mutex
+	conn        net.Conn
+	r           *bufio.Reader
+	inbox       string
+	next        uint64
+	streamReady bool
+}
+
+// NewNATSPublisher creates a publisher for the server at rawURL
+func NewNATSPublisher(rawURL, subjectPrefix, stream string, timeout time.Duration) *NATSPublisher {
+	return &NATSPublisher{URL: rawURL, SubjectPrefix: subjectPrefix, Stream: stream, Timeout: timeout}
+}
+
+// Subject returns the subject events of eventType are published on
+func (p *NATSPublisher) Subject(eventType string) string {
+	return p.SubjectPrefix + "." + eventType
+}
+
+// Publish sends ev and waits for JetStream to store it. A broken connection
+// is retried once on a fresh one.
+func (p *NATSPublisher) Publish(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	header := "NATS/1.0\r\nNats-Msg-Id: " + ev.ID.String() + "\r\n\r\n"
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if err = p.ensureConn(ctx); err == nil {
+			var reply []byte
+			if reply, err = p.request(ctx, p.Subject(ev.Type), header, body); err == nil {
+				return ackError(reply)
+			}
+		}
+		var jsErr *JetStreamError
+		if errors.As(err, &jsErr) || errors.Is(err, errNoResponders) || attempt > 0 {
+			return err
+		}
+		p.closeConn()
+	}
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
+
+func (p *NATSPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// ackError decodes a JetStream API response, returning its error if any
+func ackError(reply []byte) error {
+	var ack struct {
+		Error *JetStreamError `json:"error"`
+	}
+	if err := json.Unmarshal(reply, &ack); err != nil {
+		return fmt.Errorf("jetstream: malformed response: %w", err)
+	}
+	if ack.Error != nil {
+		return ack.Error
+	}
+	return nil
+}
+
+// natsInfo is the subset of the server's INFO message the publisher needs
+type natsInfo struct {
+	Headers     bool `json:"headers"`
+	TLSRequired bool `json:"tls_required"`
+}
+
+func (p *NATSPublisher) ensureConn(ctx context.Context) error {
+	if p.conn != nil {
+		return nil
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("nats: invalid URL %q", p.URL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	dialer := net.Dialer{Timeout: p.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	p.conn, p.r = conn, bufio.NewReader(conn)
+	if err := p.handshake(ctx, u); err != nil {
+		p.closeConn()
+		return err
+	}
+
+	if p.Stream != "" && !p.streamReady {
+		if err := p.ensureStream(ctx); err != nil {
+			p.closeConn()
+			return err
+		}
+		p.streamReady = true
+	}
+	return nil
+}
+
+// handshake reads the server INFO, upgrades to TLS when asked to, sends
+// CONNECT and subscribes to the publisher's reply inbox
+func (p *NATSPublisher) handshake(ctx context.Context, u *url.URL) error {
+	p.setDeadline(ctx)
+	line, err := p.readLine()
+	if err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	var info natsInfo
+	if !strings.HasPrefix(line, "INFO ") || json.Unmarshal([]byte(line[5:]), &info) != nil {
+		return fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	if !info.Headers {
+		return errors.New("nats: server does not support message headers")
+	}
+	if info.TLSRequired || u.Scheme == "tls" {
+		tlsConn := tls.Client(p.conn, &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("nats: %w", err)
+		}
+		p.conn, p.r = tlsConn, bufio.NewReader(tlsConn)
+		p.setDeadline(ctx)
+	}
+
+	opts := map[string]interface{}{
+		"verbose":       false,
+		"pedantic":      false,
+		"headers":       true,
+		"no_responders": true,
+		"name":          "ledger-service",
+		"lang":          "go",
+		"version":       "1.0.0",
+		"protocol":      1,
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(opts)
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	p.inbox = "_INBOX." + hex.EncodeToString(b)
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", connect, p.inbox); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return fmt.Errorf("nats: %w", err)
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// ensureStream creates the stream unless it already exists
+func (p *NATSPublisher) ensureStream(ctx context.Context) error {
+	reply, err := p.request(ctx, "$JS.API.STREAM.INFO."+p.Stream, "", nil)
+	if err != nil {
+		return err
+	}
+	err = ackError(reply)
+	var jsErr *JetStreamError
+	if !errors.As(err, &jsErr) || jsErr.Code != 404 {
+		return err
+	}
+
+	config, _ := json.Marshal(map[string]interface{}{
+		"name":     p.Stream,
+		"subjects": []string{p.SubjectPrefix + ".>"},
+		"storage":  "file",
+	})
+	if reply, err = p.request(ctx, "$JS.API.STREAM.CREATE."+p.Stream, "", config); err != nil {
+		return err
+	}
+	return ackError(reply)
+}
+
+// request publishes body to subject and returns the payload of the reply.
+// header, when set, is a complete NATS/1.0 header block.
+func (p *NATSPublisher) request(ctx context.Context, subject, header string, body []byte) ([]byte, error) {
+	p.next++
+	reply := p.inbox + "." + strconv.FormatUint(p.next, 10)
+	p.setDeadline(ctx)
+
+	var buf bytes.Buffer
+	if header == "" {
+		fmt.Fprintf(&buf, "PUB %s %s %d\r\n", subject, reply, len(body))
+	} else {
+		fmt.Fprintf(&buf, "HPUB %s %s %d %d\r\n%s", subject, reply, len(header), len(header)+len(body), header)
+	}
+	buf.Write(body)
+	buf.WriteString("\r\n")
+	if _, err := p.conn.Write(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("nats: %w", err)
+		}
+		switch {
+		case line == "PING":
+			if _, err := io.WriteString(p.conn, "PONG\r\n"); err != nil {
+				return nil, fmt.Errorf("nats: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return nil, fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		case strings.HasPrefix(line, "MSG ") || strings.HasPrefix(line, "HMSG "):
+			msgSubject, hdr, payload, err := p.readMsg(line)
+			if err != nil {
+				return nil, fmt.Errorf("nats: %w", err)
+			}
+			// Replies to earlier requests that timed out are skipped
+			if msgSubject != reply {
+				continue
+			}
+			if strings.HasPrefix(string(hdr), "NATS/1.0 503") {
+				return nil, errNoResponders
+			}
+			return payload, nil
+		}
+	}
+}
+
+// readMsg reads the body of a MSG or HMSG whose control line is line:
+// MSG <subject> <sid> [reply] <size> or
+// HMSG <subject> <sid> [reply] <header size> <total size>
+func (p *NATSPublisher) readMsg(line string) (string, []byte, []byte, error) {
+	fields := strings.Fields(line)
+	headerSize, total := 0, 0
+	var err error
+	if fields[0] == "HMSG" {
+		if len(fields) < 5 {
+			return "", nil, nil, fmt.Errorf("malformed message %q", line)
+		}
+		if headerSize, err = strconv.Atoi(fields[len(fields)-2]); err != nil {
+			return "", nil, nil, fmt.Errorf("malformed message %q", line)
+		}
+	} else if len(fields) < 4 {
+		return "", nil, nil, fmt.Errorf("malformed message %q", line)
+	}
+	if total, err = strconv.Atoi(fields[len(fields)-1]); err != nil || headerSize > total {
+		return "", nil, nil, fmt.Errorf("malformed message %q", line)
+	}
+
+	buf := make([]byte, total+2)
+	if _, err := io.ReadFull(p.r, buf); err != nil {
+		return "", nil, nil, err
+	}
+	return fields[1], buf[:headerSize], buf[headerSize:total], nil
+}
+
+func (p *NATSPublisher) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setDeadline bounds the next exchange by Timeout or ctx, whichever ends first
+func (p *NATSPublisher) setDeadline(ctx context.Context) {
+	deadline := time.Now().Add(p.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	p.conn.SetDeadline(deadline)
+}
@@ -0,0 +1,224 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJetStream is a minimal NATS server that acknowledges publishes the way
+// a JetStream stream does
+type fakeJetStream struct {
+	ln net.Listener
+
+	mu        sync.Mutex
+	connects  []string
+	subjects  []string
+	msgIDs    []string
+	payloads  []string
+	streams   map[string]bool
+	respond   func(subject string) string
+	dropAfter int
+	published int
+}
+
+func newFakeJetStream(t *testing.T) *fakeJetStream {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeJetStream{ln: ln, streams: map[string]bool{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeJetStream) url() string {
+	return "nats://" + s.ln.Addr().String()
+}
+
+func (s *fakeJetStream) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"headers\":true,\"jetstream\":true}\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			s.mu.Lock()
+			s.connects = append(s.connects, strings.TrimSpace(strings.TrimPrefix(line, "CONNECT")))
+			s.mu.Unlock()
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "PUB", "HPUB":
+			subject, reply := fields[1], fields[2]
+			hdrLen := 0
+			if fields[0] == "HPUB" {
+				hdrLen, _ = strconv.Atoi(fields[3])
+			}
+			total, _ := strconv.Atoi(fields[len(fields)-1])
+			buf := make([]byte, total+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			if !s.handle(conn, subject, reply, string(buf[:hdrLen]), string(buf[hdrLen:total])) {
+				return
+			}
+		}
+	}
+}
+
+// handle answers one request; it reports false when the connection should be
+// dropped instead
+func (s *fakeJetStream) handle(conn net.Conn, subject, reply, header, payload string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resp string
+	switch {
+	case strings.HasPrefix(subject, "$JS.API.STREAM.INFO."):
+		if !s.streams[strings.TrimPrefix(subject, "$JS.API.STREAM.INFO.")] {
+			resp = `{"error":{"code":404,"err_code":10059,"description":"stream not found"}}`
+		} else {
+			resp = `{"config":{}}`
+		}
+	case strings.HasPrefix(subject, "$JS.API.STREAM.CREATE."):
+		s.streams[strings.TrimPrefix(subject, "$JS.API.STREAM.CREATE.")] = true
+		resp = `{"config":{}}`
+	default:
+		if s.dropAfter > 0 && s.published == s.dropAfter {
+			s.dropAfter = 0
+			return false
+		}
+		s.published++
+		s.subjects = append(s.subjects, subject)
+		for _, h := range strings.Split(header, "\r\n") {
+			if id, ok := strings.CutPrefix(h, "Nats-Msg-Id: "); ok {
+				s.msgIDs = append(s.msgIDs, id)
+			}
+		}
+		s.payloads = append(s.payloads, payload)
+		if s.respond != nil {
+			resp = s.respond(subject)
+		} else {
+			resp = fmt.Sprintf(`{"stream":"LEDGER","seq":%d}`, s.published)
+		}
+	}
+	if resp == "" {
+		fmt.Fprintf(conn, "HMSG %s 1 16 16\r\nNATS/1.0 503\r\n\r\n\r\n", reply)
+		return true
+	}
+	// A stray reply to another inbox first, as left behind by a timed-out request
+	fmt.Fprintf(conn, "PING\r\nMSG other.inbox 1 2\r\n{}\r\nMSG %s 1 %d\r\n%s\r\n", reply, len(resp), resp)
+	return true
+}
+
+func TestNATSPublisher(t *testing.T) {
+	server := newFakeJetStream(t)
+	p := NewNATSPublisher(server.url(), "ledger", "LEDGER", time.Second)
+	defer p.Close()
+
+	posted, _ := New(TransactionPosted, nil, map[string]float64{"amount": 10})
+	transfer, _ := New(TransferCompleted, nil, map[string]float64{"amount": 5})
+	assert.NoError(t, p.Publish(context.Background(), posted))
+	assert.NoError(t, p.Publish(context.Background(), transfer))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, []string{"ledger.transaction.posted", "ledger.transfer.completed"}, server.subjects)
+	assert.Equal(t, []string{posted.ID.String(), transfer.ID.String()}, server.msgIDs)
+	assert.Contains(t, server.payloads[0], `"type":"transaction.posted"`)
+	assert.True(t, server.streams["LEDGER"])
+	assert.Len(t, server.connects, 1)
+	assert.Contains(t, server.connects[0], `"headers":true`)
+}
+
+func TestNATSPublisherCredentials(t *testing.T) {
+	server := newFakeJetStream(t)
+	addr := server.ln.Addr().String()
+
+	p := NewNATSPublisher("nats://ledger:s3cret@"+addr, "ledger", "", time.Second)
+	ev, _ := New(CustomerCreated, nil, map[string]string{})
+	assert.NoError(t, p.Publish(context.Background(), ev))
+	p.Close()
+
+	p = NewNATSPublisher("nats://tok3n@"+addr, "ledger", "", time.Second)
+	assert.NoError(t, p.Publish(context.Background(), ev))
+	p.Close()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Contains(t, server.connects[0], `"user":"ledger"`)
+	assert.Contains(t, server.connects[0], `"pass":"s3cret"`)
+	assert.Contains(t, server.connects[1], `"auth_token":"tok3n"`)
+	// No stream configured, so none is created
+	assert.Empty(t, server.streams)
+}
+
+func TestNATSPublisherReconnects(t *testing.T) {
+	server := newFakeJetStream(t)
+	p := NewNATSPublisher(server.url(), "ledger", "", time.Second)
+	defer p.Close()
+
+	first, _ := New(TransactionPosted, nil, map[string]string{})
+	second, _ := New(TransactionPosted, nil, map[string]string{})
+	assert.NoError(t, p.Publish(context.Background(), first))
+
+	// The server drops the connection instead of acknowledging the second event
+	server.mu.Lock()
+	server.dropAfter = 1
+	server.mu.Unlock()
+	assert.NoError(t, p.Publish(context.Background(), second))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Len(t, server.connects, 2)
+	assert.Equal(t, []string{first.ID.String(), second.ID.String()}, server.msgIDs)
+}
+
+func TestNATSPublisherErrors(t *testing.T) {
+	server := newFakeJetStream(t)
+	p := NewNATSPublisher(server.url(), "ledger", "", time.Second)
+	defer p.Close()
+	ev, _ := New(TransactionPosted, nil, map[string]string{})
+
+	server.respond = func(string) string {
+		return `{"error":{"code":503,"err_code":10077,"description":"maximum messages exceeded"}}`
+	}
+	err := p.Publish(context.Background(), ev)
+	var jsErr *JetStreamError
+	assert.ErrorAs(t, err, &jsErr)
+	assert.Equal(t, 10077, jsErr.ErrCode)
+
+	server.mu.Lock()
+	server.respond = func(string) string { return "" }
+	server.mu.Unlock()
+	assert.ErrorIs(t, p.Publish(context.Background(), ev), errNoResponders)
+
+	unreachable := NewNATSPublisher("nats://127.0.0.1:1", "ledger", "", time.Second)
+	assert.Error(t, unreachable.Publish(context.Background(), ev))
+}
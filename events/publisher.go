@@ -0,0 +1,20 @@
+package events
+
+import "context"
+
+// Publisher delivers events to a message bus. Publish returns only once the
+// bus has accepted the event, so the outbox relay can mark it as sent; an
+// event may be published more than once, and consumers dedupe on its ID.
+type Publisher interface {
+	Publish(ctx context.Context, ev Event) error
+	Close() error
+}
+
+// BatchPublisher is implemented by publishers that can send several events
+// in one round trip. PublishBatch sends evs in order and returns how many
+// leading events the bus accepted; when that is fewer than len(evs) the
+// error describes the first one that was not.
+type BatchPublisher interface {
+	Publisher
+	PublishBatch(ctx context.Context, evs []Event) (int, error)
+}
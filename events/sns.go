@@ -0,0 +1,267 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SNS PublishBatch and SQS SendMessageBatch accept at most 10 entries and
+// 256 KiB of messages per request
+const (
+	awsBatchEntries = 10
+	awsBatchBytes   = 256 * 1024
+)
+
+// awsEntry is one event ready to be sent in a batch
+type awsEntry struct {
+	ev   Event
+	body string
+}
+
+// awsBatchResult maps batch entry IDs onto their outcome
+type awsBatchResult struct {
+	ok     map[string]bool
+	failed map[string]*AWSError
+}
+
+// awsBatchEntry is the ID, and on failure the reason, of one batch result
+type awsBatchEntry struct {
+	ID      string `xml:"Id"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func newAWSBatchResult(ok, failed []awsBatchEntry) awsBatchResult {
+	res := awsBatchResult{ok: map[string]bool{}, failed: map[string]*AWSError{}}
+	for _, e := range ok {
+		res.ok[e.ID] = true
+	}
+	for _, e := range failed {
+		res.failed[e.ID] = &AWSError{Code: e.Code, Message: e.Message}
+	}
+	return res
+}
+
+// publishAWSBatches splits evs into batches within the service limits and
+// sends them in order, stopping at the first event that is not accepted.
+// Entry IDs are the event's index within its batch.
+func publishAWSBatches(ctx context.Context, evs []Event, send func(context.Context, []awsEntry) (awsBatchResult, error)) (int, error) {
+	published := 0
+	for published < len(evs) {
+		var batch []awsEntry
+		size := 0
+		for _, ev := range evs[published:] {
+			body, err := json.Marshal(ev)
+			if err != nil {
+				return published, err
+			}
+			if len(batch) == awsBatchEntries || (len(batch) > 0 && size+len(body) > awsBatchBytes) {
+				break
+			}
+			batch = append(batch, awsEntry{ev: ev, body: string(body)})
+			size += len(body)
+		}
+
+		res, err := send(ctx, batch)
+		if err != nil {
+			return published, err
+		}
+		for i, e := range batch {
+			id := strconv.Itoa(i)
+			if failure := res.failed[id]; failure != nil {
+				return published, fmt.Errorf("event %s: %w", e.ev.ID, failure)
+			}
+			if !res.ok[id] {
+				return published, fmt.Errorf("event %s: missing from batch response", e.ev.ID)
+			}
+			published++
+		}
+	}
+	return published, nil
+}
+
+// awsMessageAttributes is the event type and, when set, the customer ID, so
+// subscribers can filter without parsing the body
+func awsMessageAttributes(ev Event) [][2]string {
+	attrs := [][2]string{{"event_type", ev.Type}}
+	if ev.CustomerID != nil {
+		attrs = append(attrs, [2]string{"customer_id", ev.CustomerID.String()})
+	}
+	return attrs
+}
+
+// awsMessageGroup is the FIFO message group: events for one customer stay in
+// order while different customers are delivered in parallel
+func awsMessageGroup(ev Event) string {
+	if ev.CustomerID != nil {
+		return ev.CustomerID.String()
+	}
+	return "ledger"
+}
+
+// SNSPublisher publishes events to an SNS topic with PublishBatch. Each
+// message carries event_type and customer_id message attributes for
+// subscription filter policies. On a FIFO topic messages are grouped by
+// customer and deduplicated on the event ID.
+type SNSPublisher struct {
+	TopicARN string
+	Region   string
+	// Endpoint defaults to https://sns.<region>.amazonaws.com
+	Endpoint    string
+	Credentials AWSCredentialProvider
+	Client      *http.Client
+}
+
+// NewSNSPublisher creates a publisher for topicARN. The region defaults to the
+// one in the ARN.
+func NewSNSPublisher(topicARN, region, endpoint string, creds AWSCredentialProvider, timeout time.Duration) *SNSPublisher {
+	if region == "" {
+		if parts := strings.Split(topicARN, ":"); len(parts) == 6 {
+			region = parts[3]
+		}
+	}
+	if endpoint == "" {
+		endpoint = "https://sns." + region + ".amazonaws.com/"
+	}
+	return &SNSPublisher{
+		TopicARN:    topicARN,
+		Region:      region,
+		Endpoint:    endpoint,
+		Credentials: creds,
+		Client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish sends a single event
+func (p *SNSPublisher) Publish(ctx context.Context, ev Event) error {
+	_, err := p.PublishBatch(ctx, []Event{ev})
+	return err
+}
+
+// PublishBatch sends evs ten at a time
+func (p *SNSPublisher) PublishBatch(ctx context.Context, evs []Event) (int, error) {
+	return publishAWSBatches(ctx, evs, p.send)
+}
+
+// Close is a no-op; requests share the HTTP client's connection pool
+func (p *SNSPublisher) Close() error {
+	return nil
+}
+
+func (p *SNSPublisher) send(ctx context.Context, batch []awsEntry) (awsBatchResult, error) {
+	fifo := strings.HasSuffix(p.TopicARN, ".fifo")
+	params := url.Values{
+		"Action":   {"PublishBatch"},
+		"Version":  {"2010-03-31"},
+		"TopicArn": {p.TopicARN},
+	}
+	for i, e := range batch {
+		prefix := fmt.Sprintf("PublishBatchRequestEntries.member.%d.", i+1)
+		params.Set(prefix+"Id", strconv.Itoa(i))
+		params.Set(prefix+"Message", e.body)
+		for j, attr := range awsMessageAttributes(e.ev) {
+			attrPrefix := fmt.Sprintf("%sMessageAttributes.entry.%d.", prefix, j+1)
+			params.Set(attrPrefix+"Name", attr[0])
+			params.Set(attrPrefix+"Value.DataType", "String")
+			params.Set(attrPrefix+"Value.StringValue", attr[1])
+		}
+		if fifo {
+			params.Set(prefix+"MessageGroupId", awsMessageGroup(e.ev))
+			params.Set(prefix+"MessageDeduplicationId", e.ev.ID.String())
+		}
+	}
+
+	var resp struct {
+		Successful []awsBatchEntry `xml:"PublishBatchResult>Successful>member"`
+		Failed     []awsBatchEntry `xml:"PublishBatchResult>Failed>member"`
+	}
+	if err := awsQuery(ctx, p.Client, p.Credentials, p.Endpoint, p.Region, "sns", params, &resp); err != nil {
+		return awsBatchResult{}, err
+	}
+	return newAWSBatchResult(resp.Successful, resp.Failed), nil
+}
+
+// SQSPublisher sends events straight to an SQS queue with SendMessageBatch,
+// with the same message attributes and FIFO handling as SNSPublisher
+type SQSPublisher struct {
+	QueueURL    string
+	Region      string
+	Credentials AWSCredentialProvider
+	Client      *http.Client
+}
+
+// NewSQSPublisher creates a publisher for queueURL. The region defaults to the
+// one in the queue's host name.
+func NewSQSPublisher(queueURL, region string, creds AWSCredentialProvider, timeout time.Duration) *SQSPublisher {
+	if region == "" {
+		if u, err := url.Parse(queueURL); err == nil {
+			// sqs.<region>.amazonaws.com, or the legacy <region>.queue.amazonaws.com
+			parts := strings.Split(u.Hostname(), ".")
+			if len(parts) >= 4 && parts[0] == "sqs" {
+				region = parts[1]
+			} else if len(parts) >= 4 && parts[1] == "queue" {
+				region = parts[0]
+			}
+		}
+	}
+	return &SQSPublisher{
+		QueueURL:    queueURL,
+		Region:      region,
+		Credentials: creds,
+		Client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish sends a single event
+func (p *SQSPublisher) Publish(ctx context.Context, ev Event) error {
+	_, err := p.PublishBatch(ctx, []Event{ev})
+	return err
+}
+
+// PublishBatch sends evs ten at a time
+func (p *SQSPublisher) PublishBatch(ctx context.Context, evs []Event) (int, error) {
+	return publishAWSBatches(ctx, evs, p.send)
+}
+
+// Close is a no-op; requests share the HTTP client's connection pool
+func (p *SQSPublisher) Close() error {
+	return nil
+}
+
+func (p *SQSPublisher) send(ctx context.Context, batch []awsEntry) (awsBatchResult, error) {
+	fifo := strings.HasSuffix(p.QueueURL, ".fifo")
+	params := url.Values{
+		"Action":  {"SendMessageBatch"},
+		"Version": {"2012-11-05"},
+	}
+	for i, e := range batch {
+		prefix := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i+1)
+		params.Set(prefix+"Id", strconv.Itoa(i))
+		params.Set(prefix+"MessageBody", e.body)
+		for j, attr := range awsMessageAttributes(e.ev) {
+			attrPrefix := fmt.Sprintf("%sMessageAttribute.%d.", prefix, j+1)
+			params.Set(attrPrefix+"Name", attr[0])
+			params.Set(attrPrefix+"Value.DataType", "String")
+			params.Set(attrPrefix+"Value.StringValue", attr[1])
+		}
+		if fifo {
+			params.Set(prefix+"MessageGroupId", awsMessageGroup(e.ev))
+			params.Set(prefix+"MessageDeduplicationId", e.ev.ID.String())
+		}
+	}
+
+	var resp struct {
+		Successful []awsBatchEntry `xml:"SendMessageBatchResult>SendMessageBatchResultEntry"`
+		Failed     []awsBatchEntry `xml:"SendMessageBatchResult>BatchResultErrorEntry"`
+	}
+	if err := awsQuery(ctx, p.Client, p.Credentials, p.QueueURL, p.Region, "sqs", params, &resp); err != nil {
+		return awsBatchResult{}, err
+	}
+	return newAWSBatchResult(resp.Successful, resp.Failed), nil
+}
@@ -0,0 +1,198 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAWS records batch requests and answers them like SNS or SQS. fail lists
+// the entry IDs of each request to report as failed.
+type fakeAWS struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []url.Values
+	auth     []string
+	fail     func(request int) []string
+	status   int
+}
+
+func newFakeAWS(t *testing.T) *fakeAWS {
+	f := &fakeAWS{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAWS) serve(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.PostForm)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>AuthorizationError</Code><Message>not authorized</Message></Error></ErrorResponse>`)
+		return
+	}
+
+	failed := map[string]bool{}
+	if f.fail != nil {
+		for _, id := range f.fail(len(f.requests)) {
+			failed[id] = true
+		}
+	}
+	sqs := r.PostForm.Get("Action") == "SendMessageBatch"
+	var ok, bad strings.Builder
+	for i := 1; ; i++ {
+		id := r.PostForm.Get(fmt.Sprintf("PublishBatchRequestEntries.member.%d.Id", i))
+		if sqs {
+			id = r.PostForm.Get(fmt.Sprintf("SendMessageBatchRequestEntry.%d.Id", i))
+		}
+		if id == "" {
+			break
+		}
+		switch {
+		case failed[id] && sqs:
+			fmt.Fprintf(&bad, "<BatchResultErrorEntry><Id>%s</Id><Code>InternalError</Code><Message>try again</Message><SenderFault>false</SenderFault></BatchResultErrorEntry>", id)
+		case failed[id]:
+			fmt.Fprintf(&bad, "<member><Id>%s</Id><Code>InternalError</Code><Message>try again</Message><SenderFault>false</SenderFault></member>", id)
+		case sqs:
+			fmt.Fprintf(&ok, "<SendMessageBatchResultEntry><Id>%s</Id><MessageId>%s</MessageId></SendMessageBatchResultEntry>", id, uuid.New())
+		default:
+			fmt.Fprintf(&ok, "<member><Id>%s</Id><MessageId>%s</MessageId></member>", id, uuid.New())
+		}
+	}
+	if sqs {
+		fmt.Fprintf(w, "<SendMessageBatchResponse><SendMessageBatchResult>%s%s</SendMessageBatchResult></SendMessageBatchResponse>", ok.String(), bad.String())
+		return
+	}
+	fmt.Fprintf(w, "<PublishBatchResponse><PublishBatchResult><Successful>%s</Successful><Failed>%s</Failed></PublishBatchResult></PublishBatchResponse>", ok.String(), bad.String())
+}
+
+func testEvents(n int) []Event {
+	evs := make([]Event, n)
+	for i := range evs {
+		customerID := uuid.New()
+		evs[i], _ = New(TransactionPosted, &customerID, map[string]int{"n": i})
+	}
+	return evs
+}
+
+func TestSNSPublisher(t *testing.T) {
+	server := newFakeAWS(t)
+	topic := "arn:aws:sns:eu-west-1:123456789012:ledger-events"
+	p := NewSNSPublisher(topic, "", server.URL, StaticAWSCredentials(exampleCredentials), time.Second)
+	assert.Equal(t, "eu-west-1", p.Region)
+
+	evs := testEvents(12)
+	n, err := p.PublishBatch(context.Background(), evs)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// Ten per request
+	assert.Len(t, server.requests, 2)
+	first := server.requests[0]
+	assert.Equal(t, "PublishBatch", first.Get("Action"))
+	assert.Equal(t, topic, first.Get("TopicArn"))
+	assert.Equal(t, "0", first.Get("PublishBatchRequestEntries.member.1.Id"))
+	assert.Contains(t, first.Get("PublishBatchRequestEntries.member.1.Message"), evs[0].ID.String())
+	assert.Equal(t, "event_type", first.Get("PublishBatchRequestEntries.member.1.MessageAttributes.entry.1.Name"))
+	assert.Equal(t, TransactionPosted, first.Get("PublishBatchRequestEntries.member.1.MessageAttributes.entry.1.Value.StringValue"))
+	assert.Equal(t, "customer_id", first.Get("PublishBatchRequestEntries.member.1.MessageAttributes.entry.2.Name"))
+	assert.Equal(t, evs[0].CustomerID.String(), first.Get("PublishBatchRequestEntries.member.1.MessageAttributes.entry.2.Value.StringValue"))
+	assert.Empty(t, first.Get("PublishBatchRequestEntries.member.1.MessageGroupId"))
+	assert.NotEmpty(t, first.Get("PublishBatchRequestEntries.member.10.Id"))
+	assert.Empty(t, first.Get("PublishBatchRequestEntries.member.11.Id"))
+	assert.Contains(t, server.requests[1].Get("PublishBatchRequestEntries.member.2.Message"), evs[11].ID.String())
+	assert.Contains(t, server.auth[0], "Credential=AKIDEXAMPLE/")
+	assert.Contains(t, server.auth[0], "/eu-west-1/sns/aws4_request")
+}
+
+func TestSNSPublisherFIFO(t *testing.T) {
+	server := newFakeAWS(t)
+	p := NewSNSPublisher("arn:aws:sns:us-east-1:123456789012:ledger.fifo", "", server.URL, StaticAWSCredentials(exampleCredentials), time.Second)
+
+	ev, _ := New(CustomerCreated, nil, map[string]string{})
+	assert.NoError(t, p.Publish(context.Background(), ev))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	req := server.requests[0]
+	assert.Equal(t, "ledger", req.Get("PublishBatchRequestEntries.member.1.MessageGroupId"))
+	assert.Equal(t, ev.ID.String(), req.Get("PublishBatchRequestEntries.member.1.MessageDeduplicationId"))
+	// No customer ID attribute for events without a customer
+	assert.Empty(t, req.Get("PublishBatchRequestEntries.member.1.MessageAttributes.entry.2.Name"))
+}
+
+func TestSNSPublisherFailures(t *testing.T) {
+	server := newFakeAWS(t)
+	p := NewSNSPublisher("arn:aws:sns:us-east-1:123456789012:ledger", "", server.URL, StaticAWSCredentials(exampleCredentials), time.Second)
+	evs := testEvents(15)
+
+	// The fourth entry of the second request fails: 13 events went out in order
+	server.fail = func(request int) []string {
+		if request == 2 {
+			return []string{"3", "4"}
+		}
+		return nil
+	}
+	n, err := p.PublishBatch(context.Background(), evs)
+	assert.Equal(t, 13, n)
+	var awsErr *AWSError
+	assert.ErrorAs(t, err, &awsErr)
+	assert.Equal(t, "InternalError", awsErr.Code)
+	assert.ErrorContains(t, err, evs[13].ID.String())
+
+	server.mu.Lock()
+	server.fail = nil
+	server.status = http.StatusForbidden
+	server.mu.Unlock()
+	n, err = p.PublishBatch(context.Background(), evs[13:])
+	assert.Equal(t, 0, n)
+	assert.ErrorAs(t, err, &awsErr)
+	assert.Equal(t, http.StatusForbidden, awsErr.StatusCode)
+	assert.Equal(t, "AuthorizationError", awsErr.Code)
+}
+
+func TestSQSPublisher(t *testing.T) {
+	server := newFakeAWS(t)
+	p := NewSQSPublisher(server.URL+"/123456789012/ledger-events.fifo", "us-west-2", StaticAWSCredentials(exampleCredentials), time.Second)
+
+	evs := testEvents(3)
+	n, err := p.PublishBatch(context.Background(), evs)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	server.fail = func(int) []string { return []string{"0"} }
+	assert.Error(t, p.Publish(context.Background(), evs[0]))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	req := server.requests[0]
+	assert.Equal(t, "SendMessageBatch", req.Get("Action"))
+	assert.Contains(t, req.Get("SendMessageBatchRequestEntry.3.MessageBody"), evs[2].ID.String())
+	assert.Equal(t, "event_type", req.Get("SendMessageBatchRequestEntry.1.MessageAttribute.1.Name"))
+	assert.Equal(t, "String", req.Get("SendMessageBatchRequestEntry.1.MessageAttribute.2.Value.DataType"))
+	assert.Equal(t, evs[0].CustomerID.String(), req.Get("SendMessageBatchRequestEntry.1.MessageAttribute.2.Value.StringValue"))
+	assert.Equal(t, evs[1].CustomerID.String(), req.Get("SendMessageBatchRequestEntry.2.MessageGroupId"))
+	assert.Equal(t, evs[1].ID.String(), req.Get("SendMessageBatchRequestEntry.2.MessageDeduplicationId"))
+	assert.Contains(t, server.auth[0], "/us-west-2/sqs/aws4_request")
+}
+
+func TestSQSPublisherRegion(t *testing.T) {
+	assert.Equal(t, "eu-central-1", NewSQSPublisher("https://sqs.eu-central-1.amazonaws.com/123456789012/ledger", "", nil, time.Second).Region)
+	assert.Equal(t, "ap-south-1", NewSQSPublisher("https://ap-south-1.queue.amazonaws.com/123456789012/ledger", "", nil, time.Second).Region)
+}
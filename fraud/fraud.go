@@ -0,0 +1,260 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Action is the outcome a rule applies when it matches
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionFlag   Action = "flag"
+	ActionHold   Action = "hold"
+	ActionReject Action = "reject"
+)
+
+// severity orders actions so the strictest matching rule wins
+var severity = map[Action]int{
+	ActionAllow:  0,
+	ActionFlag:   1,
+	ActionHold:   2,
+	ActionReject: 3,
+}
+
+// RuleType identifies the check a rule performs
+type RuleType string
+
+const (
+	RuleAmountSpike  RuleType = "amount_spike"
+	RuleRapidDebits  RuleType = "rapid_debits"
+	RuleUnusualHours RuleType = "unusual_hours"
+	RuleDuplicate    RuleType = "duplicate"
+)
+
+// Params holds the tunables for all rule types; only the fields relevant to
+// a rule's type are used
+type Params struct {
+	// amount_spike: match when amount > Multiplier * average of the last
+	// LookbackDays, once at least MinHistory transactions exist
+	Multiplier   float64 `json:"multiplier,omitempty" example:"5"`
+	LookbackDays int     `json:"lookback_days,omitempty" example:"30"`
+	MinHistory   int     `json:"min_history,omitempty" example:"5"`
+
+	// rapid_debits: match when more than MaxCount debits occur within WindowSeconds
+	// duplicate: match when the customer had a transaction of the same type
+	// and amount within WindowSeconds, unless the posting allows duplicates
+	MaxCount      int `json:"max_count,omitempty" example:"3"`
+	WindowSeconds int `json:"window_seconds,omitempty" example:"60"`
+
+	// unusual_hours: match when the customer's local hour falls in
+	// [StartHour, EndHour), wrapping past midnight when StartHour > EndHour
+	StartHour int `json:"start_hour,omitempty" example:"0"`
+	EndHour   int `json:"end_hour,omitempty" example:"5"`
+}
+
+// Rule is a configured fraud check
+type Rule struct {
+	ID      uuid.UUID `json:"rule_id"`
+	Name    string    `json:"name"`
+	Type    RuleType  `json:"rule_type"`
+	Action  Action    `json:"action"`
+	Params  Params    `json:"params"`
+	Enabled bool      `json:"enabled"`
+}
+
+// Validate checks that a rule's type, action and parameters are usable
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionFlag, ActionHold, ActionReject:
+	default:
+		return fmt.Errorf("action must be one of flag, hold, reject")
+	}
+
+	p := r.Params
+	switch r.Type {
+	case RuleAmountSpike:
+		if p.Multiplier <= 1 || p.LookbackDays <= 0 {
+			return fmt.Errorf("amount_spike requires multiplier > 1 and lookback_days > 0")
+		}
+	case RuleRapidDebits:
+		if p.MaxCount <= 0 || p.WindowSeconds <= 0 {
+			return fmt.Errorf("rapid_debits requires max_count > 0 and window_seconds > 0")
+		}
+	case RuleUnusualHours:
+		if p.StartHour < 0 || p.StartHour > 23 || p.EndHour < 0 || p.EndHour > 24 || p.StartHour == p.EndHour {
+			return fmt.Errorf("unusual_hours requires distinct start_hour and end_hour between 0 and 24")
+		}
+	case RuleDuplicate:
+		if p.WindowSeconds <= 0 {
+			return fmt.Errorf("duplicate requires window_seconds > 0")
+		}
+	default:
+		return fmt.Errorf("rule_type must be one of amount_spike, rapid_debits, unusual_hours, duplicate")
+	}
+	return nil
+}
+
+// Transaction is the posting being evaluated
+type Transaction struct {
+	CustomerID uuid.UUID
+	Type       string // balance direction, credit or debit
+	// Code is the registered transaction type, such as purchase
+	Code   string
+	Amount float64
+	// Time is in the customer's timezone, which decides the local hour
+	Time time.Time
+	// AllowDuplicate is set when the caller vouched for a legitimate repeat
+	// or sent an idempotency key; duplicate rules let it through
+	AllowDuplicate bool
+}
+
+// StatsSource provides the customer history the rules are evaluated against
+type StatsSource interface {
+	// AverageAmount returns the average amount and count of txType transactions since the given time
+	AverageAmount(ctx context.Context, customerID uuid.UUID, txType string, since time.Time) (float64, int, error)
+	// CountDebits returns the number of debits since the given time
+	CountDebits(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error)
+	// CountSame returns the number of transactions of type code and amount
+	// since the given time
+	CountSame(ctx context.Context, customerID uuid.UUID, code string, amount float64, since time.Time) (int, error)
+}
+
+// Match records a rule that fired and why
+type Match struct {
+	RuleID uuid.UUID `json:"rule_id"`
+	Name   string    `json:"name"`
+	Type   RuleType  `json:"rule_type,omitempty"`
+	Action Action    `json:"action"`
+	Reason string    `json:"reason"`
+}
+
+// Decision is the combined result of all matching rules
+type Decision struct {
+	Action  Action
+	Matches []Match
+}
+
+// Rejects reports whether a matching rule of type t rejected the transaction
+func (d Decision) Rejects(t RuleType) bool {
+	for _, m := range d.Matches {
+		if m.Type == t && m.Action == ActionReject {
+			return true
+		}
+	}
+	return false
+}
+
+// Engine evaluates the current rule set against transactions
+type Engine struct {
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// NewEngine creates an engine with no rules
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// SetRules replaces the rule set used for evaluation
+func (e *Engine) SetRules(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = append([]Rule(nil), rules...)
+}
+
+// Active reports whether any enabled rules are configured
+func (e *Engine) Active() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, r := range e.rules {
+		if r.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate runs all enabled rules and returns the strictest action among the matches
+func (e *Engine) Evaluate(ctx context.Context, stats StatsSource, tx Transaction) (Decision, error) {
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	decision := Decision{Action: ActionAllow}
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		reason, matched, err := evaluateRule(ctx, stats, r, tx)
+		if err != nil {
+			return Decision{}, err
+		}
+		if !matched {
+			continue
+		}
+		decision.Matches = append(decision.Matches, Match{RuleID: r.ID, Name: r.Name, Type: r.Type, Action: r.Action, Reason: reason})
+		if severity[r.Action] > severity[decision.Action] {
+			decision.Action = r.Action
+		}
+	}
+	return decision, nil
+}
+
+func evaluateRule(ctx context.Context, stats StatsSource, r Rule, tx Transaction) (string, bool, error) {
+	p := r.Params
+	switch r.Type {
+	case RuleAmountSpike:
+		since := tx.Time.AddDate(0, 0, -p.LookbackDays)
+		avg, count, err := stats.AverageAmount(ctx, tx.CustomerID, tx.Type, since)
+		if err != nil {
+			return "", false, err
+		}
+		if count < p.MinHistory || avg <= 0 {
+			return "", false, nil
+		}
+		if tx.Amount > avg*p.Multiplier {
+			return fmt.Sprintf("amount %.2f exceeds %.1fx the %d-day average of %.2f",
+				tx.Amount, p.Multiplier, p.LookbackDays, avg), true, nil
+		}
+	case RuleRapidDebits:
+		if tx.Type != "debit" {
+			return "", false, nil
+		}
+		since := tx.Time.Add(-time.Duration(p.WindowSeconds) * time.Second)
+		count, err := stats.CountDebits(ctx, tx.CustomerID, since)
+		if err != nil {
+			return "", false, err
+		}
+		if count+1 > p.MaxCount {
+			return fmt.Sprintf("%d debits within %d seconds", count+1, p.WindowSeconds), true, nil
+		}
+	case RuleUnusualHours:
+		hour := tx.Time.Hour()
+		inWindow := hour >= p.StartHour && hour < p.EndHour
+		if p.StartHour > p.EndHour {
+			inWindow = hour >= p.StartHour || hour < p.EndHour
+		}
+		if inWindow {
+			return fmt.Sprintf("posted at %02d:00 UTC, outside normal hours", hour), true, nil
+		}
+	case RuleDuplicate:
+		if tx.AllowDuplicate {
+			return "", false, nil
+		}
+		since := tx.Time.Add(-time.Duration(p.WindowSeconds) * time.Second)
+		count, err := stats.CountSame(ctx, tx.CustomerID, tx.Code, tx.Amount, since)
+		if err != nil {
+			return "", false, err
+		}
+		if count > 0 {
+			return fmt.Sprintf("same %s of %.2f already posted within %d seconds", tx.Code, tx.Amount, p.WindowSeconds), true, nil
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,165 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStats struct {
+	avg    float64
+	count  int
+	debits int
+	same   int
+}
+
+func (f fakeStats) AverageAmount(ctx context.Context, customerID uuid.UUID, txType string, since time.Time) (float64, int, error) {
+	return f.avg, f.count, nil
+}
+
+func (f fakeStats) CountDebits(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error) {
+	return f.debits, nil
+}
+
+func (f fakeStats) CountSame(ctx context.Context, customerID uuid.UUID, code string, amount float64, since time.Time) (int, error) {
+	return f.same, nil
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	spike := Rule{ID: uuid.New(), Name: "spike", Type: RuleAmountSpike, Action: ActionHold, Enabled: true,
+		Params: Params{Multiplier: 5, LookbackDays: 30, MinHistory: 3}}
+	rapid := Rule{ID: uuid.New(), Name: "rapid", Type: RuleRapidDebits, Action: ActionReject, Enabled: true,
+		Params: Params{MaxCount: 3, WindowSeconds: 60}}
+	night := Rule{ID: uuid.New(), Name: "night", Type: RuleUnusualHours, Action: ActionFlag, Enabled: true,
+		Params: Params{StartHour: 23, EndHour: 5}}
+	duplicate := Rule{ID: uuid.New(), Name: "duplicate", Type: RuleDuplicate, Action: ActionReject, Enabled: true,
+		Params: Params{WindowSeconds: 120}}
+
+	noon := time.Date(2025, 4, 8, 12, 0, 0, 0, time.UTC)
+	midnight := time.Date(2025, 4, 8, 1, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		rules       []Rule
+		stats       fakeStats
+		tx          Transaction
+		wantAction  Action
+		wantMatches int
+	}{
+		{
+			name:       "no rules",
+			tx:         Transaction{Type: "debit", Amount: 100, Time: noon},
+			wantAction: ActionAllow,
+		},
+		{
+			name:        "amount spike",
+			rules:       []Rule{spike},
+			stats:       fakeStats{avg: 100, count: 10},
+			tx:          Transaction{Type: "debit", Amount: 600, Time: noon},
+			wantAction:  ActionHold,
+			wantMatches: 1,
+		},
+		{
+			name:       "amount spike without enough history",
+			rules:      []Rule{spike},
+			stats:      fakeStats{avg: 100, count: 2},
+			tx:         Transaction{Type: "debit", Amount: 600, Time: noon},
+			wantAction: ActionAllow,
+		},
+		{
+			name:       "rapid debits under limit",
+			rules:      []Rule{rapid},
+			stats:      fakeStats{debits: 2},
+			tx:         Transaction{Type: "debit", Amount: 10, Time: noon},
+			wantAction: ActionAllow,
+		},
+		{
+			name:        "rapid debits over limit",
+			rules:       []Rule{rapid},
+			stats:       fakeStats{debits: 3},
+			tx:          Transaction{Type: "debit", Amount: 10, Time: noon},
+			wantAction:  ActionReject,
+			wantMatches: 1,
+		},
+		{
+			name:       "rapid debits ignores credits",
+			rules:      []Rule{rapid},
+			stats:      fakeStats{debits: 10},
+			tx:         Transaction{Type: "credit", Amount: 10, Time: noon},
+			wantAction: ActionAllow,
+		},
+		{
+			name:        "unusual hours wrapping midnight",
+			rules:       []Rule{night},
+			tx:          Transaction{Type: "credit", Amount: 10, Time: midnight},
+			wantAction:  ActionFlag,
+			wantMatches: 1,
+		},
+		{
+			name:        "unusual hours use the customer's local time",
+			rules:       []Rule{night},
+			tx:          Transaction{Type: "credit", Amount: 10, Time: noon.In(time.FixedZone("UTC+12", 12*60*60))},
+			wantAction:  ActionFlag,
+			wantMatches: 1,
+		},
+		{
+			name:        "duplicate within the window",
+			rules:       []Rule{duplicate},
+			stats:       fakeStats{same: 1},
+			tx:          Transaction{Type: "debit", Code: "purchase", Amount: 25, Time: noon},
+			wantAction:  ActionReject,
+			wantMatches: 1,
+		},
+		{
+			name:       "no duplicate within the window",
+			rules:      []Rule{duplicate},
+			tx:         Transaction{Type: "debit", Code: "purchase", Amount: 25, Time: noon},
+			wantAction: ActionAllow,
+		},
+		{
+			name:       "allowed duplicate passes the check",
+			rules:      []Rule{duplicate},
+			stats:      fakeStats{same: 1},
+			tx:         Transaction{Type: "debit", Code: "purchase", Amount: 25, Time: noon, AllowDuplicate: true},
+			wantAction: ActionAllow,
+		},
+		{
+			name:        "strictest action wins",
+			rules:       []Rule{night, spike, rapid},
+			stats:       fakeStats{avg: 100, count: 10, debits: 5},
+			tx:          Transaction{Type: "debit", Amount: 600, Time: midnight},
+			wantAction:  ActionReject,
+			wantMatches: 3,
+		},
+		{
+			name:       "disabled rule ignored",
+			rules:      []Rule{{Name: "off", Type: RuleUnusualHours, Action: ActionReject, Params: Params{StartHour: 0, EndHour: 24}}},
+			tx:         Transaction{Type: "debit", Amount: 10, Time: noon},
+			wantAction: ActionAllow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine()
+			e.SetRules(tt.rules)
+			d, err := e.Evaluate(context.Background(), tt.stats, tt.tx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAction, d.Action)
+			assert.Len(t, d.Matches, tt.wantMatches)
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	assert.NoError(t, Rule{Type: RuleRapidDebits, Action: ActionFlag, Params: Params{MaxCount: 1, WindowSeconds: 10}}.Validate())
+	assert.Error(t, Rule{Type: RuleRapidDebits, Action: ActionFlag}.Validate())
+	assert.Error(t, Rule{Type: RuleAmountSpike, Action: ActionHold, Params: Params{Multiplier: 0.5, LookbackDays: 30}}.Validate())
+	assert.Error(t, Rule{Type: RuleUnusualHours, Action: "block", Params: Params{StartHour: 1, EndHour: 2}}.Validate())
+	assert.NoError(t, Rule{Type: RuleDuplicate, Action: ActionFlag, Params: Params{WindowSeconds: 60}}.Validate())
+	assert.Error(t, Rule{Type: RuleDuplicate, Action: ActionReject}.Validate())
+	assert.Error(t, Rule{Type: "velocity", Action: ActionFlag}.Validate())
+}
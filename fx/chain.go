@@ -0,0 +1,113 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ledger-service/metrics"
+)
+
+// ErrStale is returned for a rate older than the chain accepts
+var ErrStale = errors.New("exchange rate is stale")
+
+// Source is a provider in a Chain
+type Source struct {
+	Name     string
+	Provider Provider
+}
+
+// Chain asks its sources in priority order and returns the first fresh rate,
+// stamped with the name of the source that gave it. A source that fails, or
+// whose rate was last updated more than maxAge ago, is passed over for the
+// next one. Every attempt is counted per source, and the outcome of each
+// source's latest attempt is exposed as ledger_fx_provider_up.
+type Chain struct {
+	sources []Source
+	maxAge  time.Duration
+	now     func() time.Time
+
+	requests  *metrics.Counter
+	durations *metrics.Histogram
+	mu        sync.Mutex
+	up        map[string]bool
+}
+
+// NewChain creates a chain over sources, reporting to r. A zero maxAge
+// accepts rates of any age.
+func NewChain(sources []Source, maxAge time.Duration, r *metrics.Registry) *Chain {
+	c := &Chain{
+		sources: sources,
+		maxAge:  maxAge,
+		now:     time.Now,
+		requests: r.NewCounter("ledger_fx_provider_requests_total",
+			"Exchange rate lookups by provider and outcome", "provider", "result"),
+		durations: r.NewHistogram("ledger_fx_provider_duration_seconds",
+			"Time taken by exchange rate providers to answer", metrics.DefaultBuckets, "provider"),
+		up: map[string]bool{},
+	}
+	r.NewGaugeVecFunc("ledger_fx_provider_up",
+		"Whether each exchange rate provider's latest lookup gave a fresh rate", []string{"provider"}, func() []metrics.Sample {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			var samples []metrics.Sample
+			for _, s := range c.sources {
+				up, seen := c.up[s.Name]
+				if !seen {
+					continue
+				}
+				value := 0.0
+				if up {
+					value = 1
+				}
+				samples = append(samples, metrics.Sample{LabelValues: []string{s.Name}, Value: value})
+			}
+			return samples
+		})
+	return c
+}
+
+// Rate returns the first fresh rate, or the last source's error when none
+// has one
+func (c *Chain) Rate(ctx context.Context, from, to string) (Rate, error) {
+	err := errors.New("no exchange rate providers configured")
+	for i, s := range c.sources {
+		if ctx.Err() != nil {
+			return Rate{}, ctx.Err()
+		}
+		start := time.Now()
+		var rate Rate
+		rate, err = s.Provider.Rate(ctx, from, to)
+		c.durations.Observe(time.Since(start).Seconds(), s.Name)
+		if err == nil && c.maxAge > 0 && !rate.UpdatedAt.IsZero() && c.now().Sub(rate.UpdatedAt) > c.maxAge {
+			err = fmt.Errorf("%w: %s/%s last updated %s", ErrStale, from, to, rate.UpdatedAt.Format(time.RFC3339))
+		}
+		c.record(s.Name, err)
+		if err == nil {
+			rate.Provider = s.Name
+			if i > 0 {
+				log.Printf("Exchange rate %s/%s taken from fallback provider %s", from, to, s.Name)
+			}
+			return rate, nil
+		}
+		log.Printf("Exchange rate provider %s failed for %s/%s: %v", s.Name, from, to, err)
+	}
+	return Rate{}, err
+}
+
+func (c *Chain) record(name string, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, ErrStale):
+		result = "stale"
+	case err != nil:
+		result = "error"
+	}
+	c.requests.Inc(name, result)
+	c.mu.Lock()
+	c.up[name] = err == nil
+	c.mu.Unlock()
+}
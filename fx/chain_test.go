@@ -0,0 +1,100 @@
+package fx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ledger-service/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedRate answers every lookup with rate, or with err when set
+type fixedRate struct {
+	rate  Rate
+	err   error
+	calls int
+}
+
+func (p *fixedRate) Rate(ctx context.Context, from, to string) (Rate, error) {
+	p.calls++
+	return p.rate, p.err
+}
+
+func TestChain(t *testing.T) {
+	now := time.Date(2025, 4, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		primary      *fixedRate
+		fallback     *fixedRate
+		wantProvider string
+		wantErr      string
+		wantMetrics  []string
+	}{
+		{
+			name:         "primary answers",
+			primary:      &fixedRate{rate: Rate{Value: 0.91, UpdatedAt: now.Add(-time.Hour)}},
+			fallback:     &fixedRate{rate: Rate{Value: 0.92}},
+			wantProvider: "primary",
+			wantMetrics: []string{
+				`ledger_fx_provider_requests_total{provider="primary",result="ok"} 1`,
+				`ledger_fx_provider_up{provider="primary"} 1`,
+			},
+		},
+		{
+			name:         "primary fails",
+			primary:      &fixedRate{err: errors.New("timeout")},
+			fallback:     &fixedRate{rate: Rate{Value: 0.92}},
+			wantProvider: "fallback",
+			wantMetrics: []string{
+				`ledger_fx_provider_requests_total{provider="primary",result="error"} 1`,
+				`ledger_fx_provider_requests_total{provider="fallback",result="ok"} 1`,
+				`ledger_fx_provider_up{provider="primary"} 0`,
+				`ledger_fx_provider_up{provider="fallback"} 1`,
+			},
+		},
+		{
+			name:         "primary is stale",
+			primary:      &fixedRate{rate: Rate{Value: 0.91, UpdatedAt: now.Add(-48 * time.Hour)}},
+			fallback:     &fixedRate{rate: Rate{Value: 0.92, UpdatedAt: now.Add(-time.Hour)}},
+			wantProvider: "fallback",
+			wantMetrics: []string{
+				`ledger_fx_provider_requests_total{provider="primary",result="stale"} 1`,
+			},
+		},
+		{
+			name:     "every provider fails",
+			primary:  &fixedRate{err: errors.New("timeout")},
+			fallback: &fixedRate{rate: Rate{Value: 0.92, UpdatedAt: now.Add(-72 * time.Hour)}},
+			wantErr:  "exchange rate is stale",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			chain := NewChain([]Source{
+				{Name: "primary", Provider: tt.primary},
+				{Name: "fallback", Provider: tt.fallback},
+			}, 26*time.Hour, registry)
+			chain.now = func() time.Time { return now }
+
+			rate, err := chain.Rate(context.Background(), "USD", "EUR")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantProvider, rate.Provider)
+
+			var out bytes.Buffer
+			registry.Write(&out)
+			for _, line := range tt.wantMetrics {
+				assert.Contains(t, out.String(), line)
+			}
+		})
+	}
+}
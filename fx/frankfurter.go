@@ -0,0 +1,67 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Frankfurter fetches the European Central Bank reference rates published by
+// frankfurter.app, which needs no API key. The ECB updates them once each
+// working day, so they suit a fallback better than a primary provider.
+type Frankfurter struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewFrankfurter creates a provider targeting the public frankfurter.app API
+func NewFrankfurter() *Frankfurter {
+	return &Frankfurter{
+		BaseURL: "https://api.frankfurter.app",
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Rate returns the latest reference rate for the currency pair
+func (p *Frankfurter) Rate(ctx context.Context, from, to string) (Rate, error) {
+	if from == to {
+		return Rate{Value: 1}, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/latest?from=%s&to=%s",
+		strings.TrimRight(p.BaseURL, "/"), url.QueryEscape(from), url.QueryEscape(to))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Rate{}, fmt.Errorf("failed to build Frankfurter request: %v", err)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Rate{}, fmt.Errorf("failed to call Frankfurter: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Rate{}, fmt.Errorf("Frankfurter returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Date  string             `json:"date"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Rate{}, fmt.Errorf("failed to decode Frankfurter response: %v", err)
+	}
+	value := result.Rates[to]
+	if value <= 0 {
+		return Rate{}, fmt.Errorf("Frankfurter returned no rate for %s/%s", from, to)
+	}
+	rate := Rate{Value: value}
+	if date, err := time.Parse("2006-01-02", result.Date); err == nil {
+		rate.UpdatedAt = date
+	}
+	return rate, nil
+}
@@ -0,0 +1,137 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider returns the rate to convert one unit of a currency into another
+type Provider interface {
+	Rate(ctx context.Context, from, to string) (Rate, error)
+}
+
+// Rate is a conversion rate and where it came from
+type Rate struct {
+	Value float64
+	// Provider names the provider that supplied the rate, when it went
+	// through a Chain
+	Provider string
+	// UpdatedAt is when the provider last refreshed the rate, zero when it
+	// does not say
+	UpdatedAt time.Time
+}
+
+// ExchangeRateAPI fetches rates from exchangerate-api.com or any service
+// exposing a compatible pair endpoint
+type ExchangeRateAPI struct {
+	// APIKey is read under mu once requests may be in flight; change it
+	// with SetAPIKey then
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+
+	mu sync.RWMutex
+}
+
+// NewExchangeRateAPI creates a provider targeting the public exchangerate-api.com API
+func NewExchangeRateAPI(apiKey string) *ExchangeRateAPI {
+	return &ExchangeRateAPI{
+		APIKey:  apiKey,
+		BaseURL: "https://v6.exchangerate-api.com",
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetAPIKey replaces the API key, for example after it is rotated
+func (p *ExchangeRateAPI) SetAPIKey(key string) {
+	p.mu.Lock()
+	p.APIKey = key
+	p.mu.Unlock()
+}
+
+// Rate returns the conversion rate for the currency pair
+func (p *ExchangeRateAPI) Rate(ctx context.Context, from, to string) (Rate, error) {
+	if from == to {
+		return Rate{Value: 1}, nil
+	}
+
+	p.mu.RLock()
+	apiKey := p.APIKey
+	p.mu.RUnlock()
+	endpoint := fmt.Sprintf("%s/v6/%s/pair/%s/%s",
+		strings.TrimRight(p.BaseURL, "/"), url.PathEscape(apiKey), url.PathEscape(from), url.PathEscape(to))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Rate{}, fmt.Errorf("failed to build exchange rate request: %v", err)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Rate{}, fmt.Errorf("failed to call exchange rate API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Result         string  `json:"result"`
+		ErrorType      string  `json:"error-type"`
+		ConversionRate float64 `json:"conversion_rate"`
+		LastUpdateUnix int64   `json:"time_last_update_unix"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Rate{}, fmt.Errorf("failed to decode exchange rate response: %v", err)
+	}
+	if result.Result != "success" {
+		return Rate{}, fmt.Errorf("exchange rate API error: %s", result.ErrorType)
+	}
+	if result.ConversionRate <= 0 {
+		return Rate{}, fmt.Errorf("exchange rate API returned invalid rate %v", result.ConversionRate)
+	}
+	rate := Rate{Value: result.ConversionRate}
+	if result.LastUpdateUnix > 0 {
+		rate.UpdatedAt = time.Unix(result.LastUpdateUnix, 0).UTC()
+	}
+	return rate, nil
+}
+
+// Cached remembers each pair's rate from p for ttl, so a burst of quotes for
+// the same pair costs one call to the rate provider. Failures are not kept.
+func Cached(p Provider, ttl time.Duration) Provider {
+	return &cachedProvider{provider: p, ttl: ttl, rates: map[string]cachedRate{}}
+}
+
+type cachedProvider struct {
+	provider Provider
+	ttl      time.Duration
+	mu       sync.Mutex
+	rates    map[string]cachedRate
+}
+
+type cachedRate struct {
+	rate    Rate
+	expires time.Time
+}
+
+func (p *cachedProvider) Rate(ctx context.Context, from, to string) (Rate, error) {
+	pair := from + "/" + to
+	p.mu.Lock()
+	cached, ok := p.rates[pair]
+	p.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.rate, nil
+	}
+
+	rate, err := p.provider.Rate(ctx, from, to)
+	if err != nil {
+		return Rate{}, err
+	}
+	p.mu.Lock()
+	p.rates[pair] = cachedRate{rate: rate, expires: time.Now().Add(p.ttl)}
+	p.mu.Unlock()
+	return rate, nil
+}
@@ -0,0 +1,104 @@
+package fx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeRateAPI(t *testing.T) {
+	key := "test-key"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v6/"+key+"/pair/USD/EUR", r.URL.Path)
+		w.Write([]byte(`{"result":"success","time_last_update_unix":1712534401,"base_code":"USD","target_code":"EUR","conversion_rate":0.9123}`))
+	}))
+	defer srv.Close()
+
+	p := NewExchangeRateAPI("test-key")
+	p.BaseURL = srv.URL
+
+	rate, err := p.Rate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, Rate{Value: 0.9123, UpdatedAt: time.Date(2024, 4, 8, 0, 0, 1, 0, time.UTC)}, rate)
+
+	// Same-currency conversions never call the API
+	rate, err = p.Rate(context.Background(), "GBP", "GBP")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), rate.Value)
+
+	// A rotated key is used from the next request
+	key = "rotated-key"
+	p.SetAPIKey(key)
+	_, err = p.Rate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+}
+
+func TestExchangeRateAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":"error","error-type":"invalid-key"}`))
+	}))
+	defer srv.Close()
+
+	p := NewExchangeRateAPI("bad")
+	p.BaseURL = srv.URL
+
+	_, err := p.Rate(context.Background(), "USD", "EUR")
+	assert.ErrorContains(t, err, "invalid-key")
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"result":"success","conversion_rate":0.9123}`))
+	}))
+	defer srv.Close()
+
+	api := NewExchangeRateAPI("test-key")
+	api.BaseURL = srv.URL
+	p := Cached(api, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		rate, err := p.Rate(context.Background(), "USD", "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, 0.9123, rate.Value)
+	}
+	assert.Equal(t, 1, calls)
+
+	// Other pairs and expired rates go back to the provider
+	_, err := p.Rate(context.Background(), "USD", "GBP")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	time.Sleep(60 * time.Millisecond)
+	_, err = p.Rate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestFrankfurter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/latest", r.URL.Path)
+		assert.Equal(t, "USD", r.URL.Query().Get("from"))
+		if r.URL.Query().Get("to") != "EUR" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+		w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2024-04-05","rates":{"EUR":0.9231}}`))
+	}))
+	defer srv.Close()
+
+	p := NewFrankfurter()
+	p.BaseURL = srv.URL
+
+	rate, err := p.Rate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, Rate{Value: 0.9231, UpdatedAt: time.Date(2024, 4, 5, 0, 0, 0, 0, time.UTC)}, rate)
+
+	_, err = p.Rate(context.Background(), "USD", "XXX")
+	assert.ErrorContains(t, err, "status 404")
+}
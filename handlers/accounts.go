@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// GLAccount is a general ledger account held by the bank itself
+// @Description General ledger account
+type GLAccount struct {
+	Code          string  `json:"code" example:"fees_income"`
+	Name          string  `json:"name" example:"Fees income"`
+	Category      string  `json:"category" example:"income" enums:"asset,liability,equity,income,expense"`
+	NormalBalance string  `json:"normal_balance" example:"credit" enums:"credit,debit"`
+	Currency      string  `json:"currency" example:"USD"`
+	Balance       float64 `json:"balance" example:"1240.5"`
+	System        bool    `json:"system" example:"true"`
+	CreatedAt     string  `json:"created_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// GLAccountRequest represents the payload for opening a general ledger account
+type GLAccountRequest struct {
+	Code     string `json:"code" binding:"required" example:"card_rewards_expense"`
+	Name     string `json:"name" binding:"required,max=100" example:"Card rewards expense" maxLength:"100"`
+	Category string `json:"category" binding:"required,oneof=asset liability equity income expense" example:"expense" enums:"asset,liability,equity,income,expense"`
+	Currency string `json:"currency,omitempty" binding:"omitempty,currency" example:"USD" default:"USD"`
+}
+
+// GLEntry is one posting to a general ledger account
+type GLEntry struct {
+	EntryID       uuid.UUID  `json:"entry_id" format:"uuid"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+	Type          string     `json:"type" example:"credit" enums:"credit,debit"`
+	Amount        float64    `json:"amount" example:"2.5"`
+	Timestamp     string     `json:"timestamp" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// glAccountCodePattern matches general ledger account codes
+var glAccountCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,29}$`)
+
+// errGLAccountNotFound is returned when a posting names an unknown account
+var errGLAccountNotFound = errors.New("general ledger account not found")
+
+// normalBalance is the side that increases an account of the given category
+func normalBalance(category string) string {
+	if category == "asset" || category == "expense" {
+		return string(txtype.Debit)
+	}
+	return string(txtype.Credit)
+}
+
+const glAccountColumns = "code, name, category, normal_balance, currency, balance, system, created_at"
+
+func scanGLAccount(row pgx.Row) (GLAccount, error) {
+	var a GLAccount
+	var createdAt time.Time
+	if err := row.Scan(&a.Code, &a.Name, &a.Category, &a.NormalBalance, &a.Currency, &a.Balance, &a.System, &createdAt); err != nil {
+		return GLAccount{}, err
+	}
+	a.CreatedAt = createdAt.Format(time.RFC3339)
+	return a, nil
+}
+
+// postCounterparty books the general ledger side of a posted customer
+// transaction whose type names a GL account. A customer debit credits the
+// account and a customer credit debits it, so the money always has a source.
+func postCounterparty(ctx context.Context, q execer, transactionID uuid.UUID, txType string, amount float64) error {
+	t, ok := transactionTypes.Lookup(txType)
+	if !ok || t.GLAccount == "" {
+		return nil
+	}
+	side := string(txtype.Debit)
+	if t.Direction == txtype.Debit {
+		side = string(txtype.Credit)
+	}
+	return postGLEntry(ctx, q, t.GLAccount, &transactionID, side, amount)
+}
+
+// postGLEntry posts amount to the credit or debit side of a general ledger
+// account, adjusting its balance on its normal side
+func postGLEntry(ctx context.Context, q execer, account string, transactionID *uuid.UUID, side string, amount float64) error {
+	tag, err := q.Exec(ctx,
+		"UPDATE gl_accounts SET balance = balance + CASE WHEN normal_balance = $2 THEN $3::numeric ELSE -$3::numeric END WHERE code = $1",
+		account, side, amount)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", errGLAccountNotFound, account)
+	}
+	_, err = q.Exec(ctx,
+		"INSERT INTO gl_entries (id, account_code, transaction_id, type, amount) VALUES ($1, $2, $3, $4, $5)",
+		uuid.New(), account, transactionID, side, amount)
+	return err
+}
+
+// @Summary List general ledger accounts
+// @Description List the chart of accounts: the system accounts (fees income, interest expense, FX gains and losses, FX rounding differences, suspense) and any accounts opened by operators, with their balances on their normal side
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} GLAccount "General ledger accounts"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/accounts [get]
+func ListGLAccounts(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+glAccountColumns+" FROM gl_accounts ORDER BY code")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list accounts"})
+		return
+	}
+	defer rows.Close()
+
+	accounts := []GLAccount{}
+	for rows.Next() {
+		a, err := scanGLAccount(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan account"})
+			return
+		}
+		accounts = append(accounts, a)
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// @Summary Open a general ledger account
+// @Description Add an account to the chart of accounts. Transaction types can then name it as their counterparty.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param account body GLAccountRequest true "Account"
+// @Success 201 {object} GLAccount "Account opened"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 409 {object} ErrorResponse "Account already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/accounts [post]
+func CreateGLAccount(c *gin.Context) {
+	var req GLAccountRequest
+	if !bindRequest(c, &req, "Invalid input: code, name and category (asset/liability/equity/income/expense) are required") {
+		return
+	}
+	if !glAccountCodePattern.MatchString(req.Code) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: code must be 2-30 lowercase letters, digits or underscores"})
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = mainCurrency
+	}
+
+	account, err := scanGLAccount(db.QueryRow(c.Request.Context(),
+		"INSERT INTO gl_accounts (code, name, category, normal_balance, currency) VALUES ($1, $2, $3, $4, $5) RETURNING "+glAccountColumns,
+		req.Code, req.Name, req.Category, normalBalance(req.Category), req.Currency))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			respondError(c, http.StatusConflict, ErrorResponse{Error: "Account already exists"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// @Summary Get a general ledger account
+// @Description Get a general ledger account and its current balance
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param code path string true "Account code"
+// @Success 200 {object} GLAccount "General ledger account"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Account not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/accounts/{code} [get]
+func GetGLAccount(c *gin.Context) {
+	account, err := scanGLAccount(db.QueryRow(c.Request.Context(),
+		"SELECT "+glAccountColumns+" FROM gl_accounts WHERE code = $1",
+		c.Param("code")))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Account not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// @Summary List general ledger account entries
+// @Description List the postings to a general ledger account, most recent first, each linked to the customer transaction it balances
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param code path string true "Account code"
+// @Param page query int false "Page number (1-based)" minimum(1) default(1)
+// @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Success 200 {array} GLEntry "Account entries"
+// @Failure 400 {object} ErrorResponse "Invalid pagination parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Account not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Header 200 {string} X-Total-Count "Total number of entries"
+// @Header 200 {string} X-Page "Current page number"
+// @Header 200 {string} X-Page-Size "Items per page"
+// @Header 200 {string} X-Total-Pages "Total number of pages"
+// @Router /admin/accounts/{code}/entries [get]
+func GetGLAccountEntries(c *gin.Context) {
+	code := c.Param("code")
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var exists bool
+	if err := db.QueryRow(c.Request.Context(),
+		"SELECT EXISTS(SELECT 1 FROM gl_accounts WHERE code = $1)",
+		code).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify account"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Account not found"})
+		return
+	}
+
+	var totalCount int
+	if err := db.QueryRow(c.Request.Context(),
+		"SELECT COUNT(*) FROM gl_entries WHERE account_code = $1",
+		code).Scan(&totalCount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get total count"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT id, transaction_id, type, amount, created_at FROM gl_entries WHERE account_code = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		code, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch entries"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []GLEntry{}
+	for rows.Next() {
+		var e GLEntry
+		var createdAt time.Time
+		if err := rows.Scan(&e.EntryID, &e.TransactionID, &e.Type, &e.Amount, &createdAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan entry"})
+			return
+		}
+		e.Timestamp = createdAt.Format(time.RFC3339)
+		entries = append(entries, e)
+	}
+
+	c.Header("X-Total-Count", fmt.Sprintf("%d", totalCount))
+	c.Header("X-Page", fmt.Sprintf("%d", page))
+	c.Header("X-Page-Size", fmt.Sprintf("%d", pageSize))
+	c.Header("X-Total-Pages", fmt.Sprintf("%d", (totalCount+pageSize-1)/pageSize))
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var glAccountRowColumns = []string{"code", "name", "category", "normal_balance", "currency", "balance", "system", "created_at"}
+
+func TestPostCounterparty(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	transactionID := uuid.New()
+
+	// A fee debits the customer and credits fees income
+	mock.ExpectExec(`UPDATE gl_accounts SET balance = balance \+ CASE WHEN normal_balance = \$2`).
+		WithArgs("fees_income", "credit", 2.5).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO gl_entries`).
+		WithArgs(pgxmock.AnyArg(), "fees_income", &transactionID, "credit", 2.5).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	assert.NoError(t, postCounterparty(context.Background(), mock, transactionID, "fee", 2.5))
+
+	// Interest credits the customer and debits interest expense
+	mock.ExpectExec(`UPDATE gl_accounts`).
+		WithArgs("interest_expense", "debit", 0.4).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO gl_entries`).
+		WithArgs(pgxmock.AnyArg(), "interest_expense", &transactionID, "debit", 0.4).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	assert.NoError(t, postCounterparty(context.Background(), mock, transactionID, "interest", 0.4))
+
+	// Customer-to-customer types have no general ledger side
+	assert.NoError(t, postCounterparty(context.Background(), mock, transactionID, "purchase", 10))
+
+	mock.ExpectExec(`UPDATE gl_accounts`).
+		WithArgs("fees_income", "credit", 1.0).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.ErrorIs(t, postCounterparty(context.Background(), mock, transactionID, "fee", 1), errGLAccountNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGLAccount(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/accounts", CreateGLAccount)
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "opened",
+			payload:    map[string]interface{}{"code": "card_rewards_expense", "name": "Card rewards expense", "category": "expense"},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`INSERT INTO gl_accounts \(code, name, category, normal_balance, currency\)`).
+					WithArgs("card_rewards_expense", "Card rewards expense", "expense", "debit", "USD").
+					WillReturnRows(pgxmock.NewRows(glAccountRowColumns).
+						AddRow("card_rewards_expense", "Card rewards expense", "expense", "debit", "USD", 0.0, false, time.Now()))
+			},
+		},
+		{
+			name:       "already exists",
+			payload:    map[string]interface{}{"code": "suspense", "name": "Suspense", "category": "liability"},
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectQuery(`INSERT INTO gl_accounts`).
+					WithArgs("suspense", "Suspense", "liability", "credit", "USD").
+					WillReturnError(&pgconn.PgError{Code: "23505"})
+			},
+		},
+		{
+			name:       "invalid category",
+			payload:    map[string]interface{}{"code": "misc", "name": "Miscellaneous", "category": "other"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "invalid code",
+			payload:    map[string]interface{}{"code": "Misc Income", "name": "Miscellaneous", "category": "income"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/accounts", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetGLAccount(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/accounts", ListGLAccounts)
+	router.GET("/admin/accounts/:code", GetGLAccount)
+	router.GET("/admin/accounts/:code/entries", GetGLAccountEntries)
+
+	t.Run("list", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT code, name, category, normal_balance, currency, balance, system, created_at FROM gl_accounts ORDER BY code`).
+			WillReturnRows(pgxmock.NewRows(glAccountRowColumns).
+				AddRow("fees_income", "Fees income", "income", "credit", "USD", 12.5, true, time.Now()).
+				AddRow("suspense", "Suspense", "liability", "credit", "USD", 0.0, true, time.Now()))
+
+		req := httptest.NewRequest("GET", "/admin/accounts", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var accounts []GLAccount
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accounts))
+		assert.Len(t, accounts, 2)
+		assert.Equal(t, 12.5, accounts[0].Balance)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery(`FROM gl_accounts WHERE code = \$1`).
+			WithArgs("nowhere").
+			WillReturnError(pgx.ErrNoRows)
+
+		req := httptest.NewRequest("GET", "/admin/accounts/nowhere", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		transactionID := uuid.New()
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM gl_accounts WHERE code = \$1\)`).
+			WithArgs("fees_income").
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM gl_entries WHERE account_code = \$1`).
+			WithArgs("fees_income").
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`FROM gl_entries WHERE account_code = \$1 ORDER BY created_at DESC LIMIT \$2 OFFSET \$3`).
+			WithArgs("fees_income", 10, 0).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "transaction_id", "type", "amount", "created_at"}).
+				AddRow(uuid.New(), &transactionID, "credit", 2.5, time.Now()))
+
+		req := httptest.NewRequest("GET", "/admin/accounts/fees_income/entries", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Header().Get("X-Total-Count"))
+		var entries []GLEntry
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+		assert.Len(t, entries, 1)
+		assert.Equal(t, &transactionID, entries[0].TransactionID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
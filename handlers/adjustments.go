@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AdjustmentRequest represents an operator's manual balance correction
+type AdjustmentRequest struct {
+	CustomerID    uuid.UUID `json:"customer_id" binding:"required,uuid" format:"uuid"`
+	Direction     string    `json:"direction" binding:"required,oneof=credit debit" example:"credit" enums:"credit,debit"`
+	Amount        float64   `json:"amount" binding:"required,money" example:"15" minimum:"0.01"`
+	ReasonCode    string    `json:"reason_code" binding:"required,oneof=write_off goodwill error_correction" example:"goodwill" enums:"write_off,goodwill,error_correction"`
+	Justification string    `json:"justification" binding:"required,min=10,max=1000" example:"Refund of duplicate card fee, ticket #4821" minLength:"10" maxLength:"1000"`
+}
+
+// AdjustmentResponse describes a posted adjustment
+// @Description Posted balance adjustment
+type AdjustmentResponse struct {
+	AdjustmentID  uuid.UUID `json:"adjustment_id" format:"uuid"`
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	CustomerID    uuid.UUID `json:"customer_id" format:"uuid"`
+	Type          string    `json:"type" example:"adjustment_credit" enums:"adjustment_credit,adjustment_debit"`
+	Amount        float64   `json:"amount" example:"15"`
+	ReasonCode    string    `json:"reason_code" example:"goodwill"`
+	Justification string    `json:"justification" example:"Refund of duplicate card fee, ticket #4821"`
+	Actor         string    `json:"actor" example:"jane.doe"`
+	Balance       float64   `json:"balance" example:"115"`
+}
+
+// @Summary Adjust a customer balance
+// @Description Post a manual correction (write-off, goodwill credit or error fix) as an adjustment transaction. A reason code and justification are required, and the adjustment is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator making the adjustment"
+// @Param adjustment body AdjustmentRequest true "Adjustment"
+// @Success 201 {object} AdjustmentResponse "Adjustment posted"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/adjustments [post]
+func CreateAdjustment(c *gin.Context) {
+	var req AdjustmentRequest
+	if !bindRequest(c, &req, "Invalid input: customer_id, direction (credit/debit), amount (> 0), reason_code (write_off/goodwill/error_correction) and justification (at least 10 characters) are required") {
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	resp, err := postAdjustment(ctx, tx, c.GetString(middleware.ActorKey), req)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	case errors.Is(err, ledger.ErrInsufficientBalance), errors.Is(err, ledger.ErrOverpayment):
+		respondBalanceError(c, err)
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post adjustment"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, req.CustomerID)
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// postAdjustment books an operator's adjustment within tx: the balance
+// change, its transaction and general ledger entry, the adjustment record,
+// the audit entry and the event. It fails with store.ErrNotFound for an
+// unknown customer and with the ledger's balance errors.
+func postAdjustment(ctx context.Context, tx pgx.Tx, actor string, req AdjustmentRequest) (AdjustmentResponse, error) {
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, req.CustomerID)
+	if err != nil {
+		return AdjustmentResponse{}, err
+	}
+
+	// Adjustments keep the zero floor on accounts allowed to go negative or
+	// with an overdraft
+	previous := account.Balance
+	account.AllowNegative, account.Overdraft = false, 0
+	balance, err := ledger.Apply(account, txtype.Direction(req.Direction), req.Amount)
+	if err != nil {
+		return AdjustmentResponse{}, err
+	}
+
+	resp := AdjustmentResponse{
+		AdjustmentID:  uuid.New(),
+		TransactionID: uuid.New(),
+		CustomerID:    req.CustomerID,
+		Type:          "adjustment_" + req.Direction,
+		Amount:        req.Amount,
+		ReasonCode:    req.ReasonCode,
+		Justification: req.Justification,
+		Actor:         actor,
+		Balance:       balance,
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE customers SET balance = $1 WHERE id = $2",
+		resp.Balance, req.CustomerID); err != nil {
+		return resp, err
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO transactions (id, customer_id, type, amount, status) VALUES ($1, $2, $3, $4, 'posted')",
+		resp.TransactionID, req.CustomerID, resp.Type, req.Amount); err != nil {
+		return resp, err
+	}
+	if err := postCounterparty(ctx, tx, resp.TransactionID, resp.Type, req.Amount); err != nil {
+		return resp, err
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO adjustments (id, transaction_id, customer_id, reason_code, justification, actor) VALUES ($1, $2, $3, $4, $5, $6)",
+		resp.AdjustmentID, resp.TransactionID, req.CustomerID, req.ReasonCode, req.Justification, actor); err != nil {
+		return resp, err
+	}
+	if err := recordAudit(ctx, tx, actor, "balance.adjusted", "adjustment", resp.AdjustmentID, &req.CustomerID, map[string]interface{}{
+		"transaction_id":   resp.TransactionID,
+		"type":             resp.Type,
+		"amount":           req.Amount,
+		"reason_code":      req.ReasonCode,
+		"justification":    req.Justification,
+		"previous_balance": previous,
+		"new_balance":      resp.Balance,
+	}); err != nil {
+		return resp, err
+	}
+	return resp, enqueueEvent(ctx, tx, events.BalanceAdjusted, &req.CustomerID, BalanceAdjustedEventData{
+		AdjustmentID:  resp.AdjustmentID,
+		TransactionID: resp.TransactionID,
+		Type:          resp.Type,
+		Amount:        req.Amount,
+		ReasonCode:    req.ReasonCode,
+		Balance:       resp.Balance,
+	})
+}
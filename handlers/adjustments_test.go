@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAdjustment(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/adjustments", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "jane")
+	}, CreateAdjustment)
+
+	customerID := uuid.New()
+	payload := func(direction string, amount float64, reason string) map[string]interface{} {
+		return map[string]interface{}{
+			"customer_id":   customerID,
+			"direction":     direction,
+			"amount":        amount,
+			"reason_code":   reason,
+			"justification": "Duplicate fee charged on 2024-03-02",
+		}
+	}
+	expectLocked := func(balance float64) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(customerID).
+			WillReturnRows(lockedCustomer(balance, "checking", false))
+	}
+
+	tests := []struct {
+		name        string
+		payload     map[string]interface{}
+		wantStatus  int
+		wantBalance float64
+		setupMock   func()
+	}{
+		{
+			name:        "goodwill credit",
+			payload:     payload("credit", 15, "goodwill"),
+			wantStatus:  http.StatusCreated,
+			wantBalance: 115,
+			setupMock: func() {
+				expectLocked(100)
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(115), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "adjustment_credit", float64(15)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE gl_accounts SET balance`).
+					WithArgs("suspense", "debit", float64(15)).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO gl_entries`).
+					WithArgs(pgxmock.AnyArg(), "suspense", pgxmock.AnyArg(), "debit", float64(15)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO adjustments`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), customerID, "goodwill", pgxmock.AnyArg(), "jane").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "jane", "balance.adjusted", "adjustment", pgxmock.AnyArg(), &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.BalanceAdjusted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "write-off beyond balance",
+			payload:    payload("debit", 150, "write_off"),
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() { expectLocked(100) },
+		},
+		{
+			name:       "unknown reason code",
+			payload:    payload("credit", 15, "because"),
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "missing justification",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"direction":   "credit",
+				"amount":      15,
+				"reason_code": "goodwill",
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/adjustments", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp AdjustmentResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantBalance, resp.Balance)
+				assert.Equal(t, "jane", resp.Actor)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
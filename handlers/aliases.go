@@ -0,0 +1,581 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// aliasPattern is a normalized alias: 3 to 30 lowercase letters, digits and
+// hyphens, starting and ending with a letter or digit
+var aliasPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,28}[a-z0-9]$`)
+
+// aliasLockClass namespaces the advisory locks, keyed by (aliasLockClass,
+// hashtext(alias)), that stop an alias being claimed and reserved at once
+const aliasLockClass = 0x616c73
+
+// aliasReleaser is the actor recorded on aliases held after their customer
+// released them
+const aliasReleaser = "system"
+
+// aliasCustomer is the actor recorded on alias changes made through the
+// customer API
+const aliasCustomer = "customer"
+
+var errAliasNotFound = errors.New("alias not found")
+
+var aliasHold time.Duration
+
+// InitAliases sets how long an alias a customer gives up stays reserved for
+// them, so nobody else can take it and receive payments meant for them.
+// Zero frees it at once.
+func InitAliases(hold time.Duration) {
+	aliasHold = hold
+}
+
+// normalizeAlias lowercases alias and strips a leading @, reporting whether
+// the result is a valid alias
+func normalizeAlias(alias string) (string, bool) {
+	alias = strings.ToLower(strings.TrimPrefix(alias, "@"))
+	return alias, aliasPattern.MatchString(alias)
+}
+
+// AccountAlias is a customer's handle
+// @Description Human-readable handle of an account
+type AccountAlias struct {
+	Alias      string    `json:"alias" example:"@coffee-shop"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	CreatedAt  string    `json:"created_at,omitempty" format:"date-time"`
+}
+
+// AliasRequest claims or changes a customer's alias
+type AliasRequest struct {
+	Alias string `json:"alias" binding:"required,alias" example:"@coffee-shop"`
+}
+
+// AliasLookup identifies the account behind an alias
+// @Description Account an alias belongs to
+type AliasLookup struct {
+	Alias      string    `json:"alias" example:"@coffee-shop"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	Name       string    `json:"name" example:"Coffee Shop Ltd"`
+	Currency   string    `json:"currency" example:"USD"`
+}
+
+// AliasChange is one entry of a customer's alias history
+// @Description Change of a customer's alias
+type AliasChange struct {
+	// Alias is empty when the alias was removed
+	Alias string `json:"alias,omitempty" example:"@coffee-shop"`
+	// PreviousAlias is empty when the customer had none
+	PreviousAlias string `json:"previous_alias,omitempty" example:"@coffee"`
+	ChangedBy     string `json:"changed_by" example:"customer"`
+	ChangedAt     string `json:"changed_at" format:"date-time"`
+}
+
+// AliasReservation keeps an alias from being claimed
+// @Description Alias nobody can claim, or only the customer it is held for
+type AliasReservation struct {
+	Alias string `json:"alias" example:"@support"`
+	// CustomerID is set on an alias held for the customer who released it
+	CustomerID *uuid.UUID `json:"customer_id,omitempty" format:"uuid"`
+	Reason     string     `json:"reason" example:"Could be mistaken for the bank"`
+	ReservedBy string     `json:"reserved_by" example:"alice"`
+	// ExpiresAt is empty for reservations that last until removed
+	ExpiresAt string `json:"expires_at,omitempty" format:"date-time"`
+	CreatedAt string `json:"created_at" format:"date-time"`
+}
+
+// AliasReservationRequest reserves an alias
+type AliasReservationRequest struct {
+	Alias  string `json:"alias" binding:"required,alias" example:"@support"`
+	Reason string `json:"reason" binding:"required,max=500" example:"Could be mistaken for the bank"`
+}
+
+// withAt renders a stored alias the way clients write it
+func withAt(alias string) string {
+	if alias == "" {
+		return ""
+	}
+	return "@" + alias
+}
+
+// resolveAlias returns the customer an alias belongs to
+func resolveAlias(ctx context.Context, q rowQuerier, alias string) (uuid.UUID, error) {
+	alias, ok := normalizeAlias(alias)
+	if !ok {
+		return uuid.Nil, errAliasNotFound
+	}
+	var customerID uuid.UUID
+	err := q.QueryRow(ctx, "SELECT customer_id FROM account_aliases WHERE alias = $1", alias).Scan(&customerID)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, errAliasNotFound
+	}
+	return customerID, err
+}
+
+// resolveParty returns id, or the customer alias belongs to when id is not
+// set, answering notFound when the alias is unknown. It reports whether the
+// handler should continue.
+func resolveParty(c *gin.Context, id *uuid.UUID, alias, notFound string) bool {
+	if alias == "" {
+		return true
+	}
+	if *id != uuid.Nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: send a customer ID or an alias, not both"})
+		return false
+	}
+	resolved, err := resolveAlias(c.Request.Context(), db, alias)
+	if errors.Is(err, errAliasNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: notFound})
+		return false
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to look up alias"})
+		return false
+	}
+	*id = resolved
+	return true
+}
+
+// @Summary Look up an account by alias
+// @Description Find the account behind an alias, e.g. to confirm a payee before paying them. The leading @ is optional and aliases are case-insensitive.
+// @Tags aliases
+// @Produce json
+// @Param alias path string true "Alias" example(@coffee-shop)
+// @Success 200 {object} AliasLookup "Account"
+// @Failure 404 {object} ErrorResponse "Alias not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /accounts/by-alias/{alias} [get]
+func GetAccountByAlias(c *gin.Context) {
+	alias, ok := normalizeAlias(c.Param("alias"))
+	if !ok {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Alias not found"})
+		return
+	}
+	lookup := AliasLookup{Alias: withAt(alias)}
+	err := db.QueryRow(c.Request.Context(),
+		"SELECT c.id, c.name, c.currency FROM account_aliases a JOIN customers c ON c.id = a.customer_id WHERE a.alias = $1",
+		alias).Scan(&lookup.CustomerID, &lookup.Name, &lookup.Currency)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Alias not found"})
+		return
+	}
+	if err == nil {
+		err = piiKeys.DecryptAll(&lookup.Name)
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to look up alias"})
+		return
+	}
+	c.JSON(http.StatusOK, lookup)
+}
+
+// @Summary Set a customer's alias
+// @Description Claim an alias for a customer, replacing any alias they had. Aliases are unique and case-insensitive: 3 to 30 letters, digits or hyphens, with an optional leading @. An alias the customer gives up stays reserved for them for ALIAS_HOLD_DAYS so nobody else can take it. Every change is kept in the alias history.
+// @Tags aliases
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param alias body AliasRequest true "Alias"
+// @Success 200 {object} AccountAlias "Alias set"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Alias is taken or reserved"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/alias [put]
+func SetAlias(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req AliasRequest
+	if !bindRequest(c, &req, "Invalid input: alias is required") {
+		return
+	}
+	alias, _ := normalizeAlias(req.Alias)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	current, ok := lockAliasOwner(c, tx, customerID)
+	if !ok {
+		return
+	}
+	if current == alias {
+		c.JSON(http.StatusOK, AccountAlias{Alias: withAt(alias), CustomerID: customerID})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", aliasLockClass, alias); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to lock alias"})
+		return
+	}
+	var heldFor *uuid.UUID
+	err = tx.QueryRow(ctx,
+		"SELECT customer_id FROM alias_reservations WHERE alias = $1 AND (expires_at IS NULL OR expires_at > NOW())",
+		alias).Scan(&heldFor)
+	if err == nil && (heldFor == nil || *heldFor != customerID) {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Alias is reserved", Code: "alias_reserved"})
+		return
+	}
+	if err != nil && err != pgx.ErrNoRows {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to check alias reservations"})
+		return
+	}
+
+	if !releaseAlias(c, tx, customerID, current) {
+		return
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM alias_reservations WHERE alias = $1", alias); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update alias reservations"})
+		return
+	}
+	var createdAt time.Time
+	err = tx.QueryRow(ctx,
+		"INSERT INTO account_aliases (alias, customer_id) VALUES ($1, $2) RETURNING created_at",
+		alias, customerID).Scan(&createdAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			respondError(c, http.StatusConflict, ErrorResponse{Error: "Alias is already taken", Code: "alias_taken"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to set alias"})
+		}
+		return
+	}
+	if !recordAliasChange(c, tx, customerID, alias, current) {
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AccountAlias{Alias: withAt(alias), CustomerID: customerID, CreatedAt: createdAt.UTC().Format(time.RFC3339)})
+}
+
+// @Summary Remove a customer's alias
+// @Description Give up a customer's alias. It stays reserved for the customer for ALIAS_HOLD_DAYS, and the change is kept in the alias history.
+// @Tags aliases
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 204 "Alias removed"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer or alias not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/alias [delete]
+func DeleteAlias(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	current, ok := lockAliasOwner(c, tx, customerID)
+	if !ok {
+		return
+	}
+	if current == "" {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer has no alias"})
+		return
+	}
+	if !releaseAlias(c, tx, customerID, current) || !recordAliasChange(c, tx, customerID, "", current) {
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// lockAliasOwner locks the customer's row, so their alias changes one at a
+// time, and returns their current alias
+func lockAliasOwner(c *gin.Context, tx pgx.Tx, customerID uuid.UUID) (string, bool) {
+	ctx := c.Request.Context()
+	var current *string
+	err := tx.QueryRow(ctx,
+		"SELECT (SELECT alias FROM account_aliases WHERE customer_id = c.id) FROM customers c WHERE c.id = $1 FOR UPDATE",
+		customerID).Scan(&current)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return "", false
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get customer"})
+		return "", false
+	}
+	if current == nil {
+		return "", true
+	}
+	return *current, true
+}
+
+// releaseAlias frees the customer's alias, holding it for them for aliasHold
+func releaseAlias(c *gin.Context, tx pgx.Tx, customerID uuid.UUID, alias string) bool {
+	if alias == "" {
+		return true
+	}
+	ctx := c.Request.Context()
+	if _, err := tx.Exec(ctx, "DELETE FROM account_aliases WHERE alias = $1", alias); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to release alias"})
+		return false
+	}
+	if aliasHold <= 0 {
+		return true
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO alias_reservations (alias, customer_id, reason, reserved_by, expires_at) VALUES ($1, $2, 'Released by the customer', $3, $4)
+		ON CONFLICT (alias) DO UPDATE SET customer_id = EXCLUDED.customer_id, reason = EXCLUDED.reason,
+			reserved_by = EXCLUDED.reserved_by, expires_at = EXCLUDED.expires_at, created_at = NOW()`,
+		alias, customerID, aliasReleaser, time.Now().Add(aliasHold).UTC()); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to reserve released alias"})
+		return false
+	}
+	return true
+}
+
+func recordAliasChange(c *gin.Context, tx pgx.Tx, customerID uuid.UUID, alias, previous string) bool {
+	if _, err := tx.Exec(c.Request.Context(),
+		"INSERT INTO alias_changes (id, customer_id, alias, previous_alias, changed_by) VALUES ($1, $2, $3, $4, $5)",
+		uuid.New(), customerID, nullableString(alias), nullableString(previous), aliasCustomer); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record alias change"})
+		return false
+	}
+	return true
+}
+
+// @Summary Get a customer's alias history
+// @Description List every change of a customer's alias, most recent first
+// @Tags aliases
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} AliasChange "Alias changes"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/alias/history [get]
+func GetAliasHistory(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT COALESCE(alias, ''), COALESCE(previous_alias, ''), changed_by, created_at FROM alias_changes WHERE customer_id = $1 ORDER BY created_at DESC",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch alias history"})
+		return
+	}
+	defer rows.Close()
+
+	changes := []AliasChange{}
+	for rows.Next() {
+		var change AliasChange
+		var changedAt time.Time
+		if err := rows.Scan(&change.Alias, &change.PreviousAlias, &change.ChangedBy, &changedAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan alias change"})
+			return
+		}
+		change.Alias = withAt(change.Alias)
+		change.PreviousAlias = withAt(change.PreviousAlias)
+		change.ChangedAt = changedAt.UTC().Format(time.RFC3339)
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch alias history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// @Summary List alias reservations
+// @Description List the aliases customers cannot claim: those reserved by operators, and those held for the customer who released them until they expire
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} AliasReservation "Reservations"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/alias-reservations [get]
+func ListAliasReservations(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT alias, customer_id, reason, reserved_by, expires_at, created_at FROM alias_reservations WHERE expires_at IS NULL OR expires_at > NOW() ORDER BY alias")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch alias reservations"})
+		return
+	}
+	defer rows.Close()
+
+	reservations := []AliasReservation{}
+	for rows.Next() {
+		var r AliasReservation
+		var expiresAt *time.Time
+		var createdAt time.Time
+		if err := rows.Scan(&r.Alias, &r.CustomerID, &r.Reason, &r.ReservedBy, &expiresAt, &createdAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan alias reservation"})
+			return
+		}
+		r.Alias = withAt(r.Alias)
+		if expiresAt != nil {
+			r.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		}
+		r.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		reservations = append(reservations, r)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch alias reservations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
+// @Summary Reserve an alias
+// @Description Stop customers claiming an alias, such as one that could be mistaken for the bank. An alias a customer already holds cannot be reserved. The reservation is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator making the reservation"
+// @Param reservation body AliasReservationRequest true "Reservation"
+// @Success 201 {object} AliasReservation "Alias reserved"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 409 {object} ErrorResponse "Alias is held by a customer"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/alias-reservations [post]
+func ReserveAlias(c *gin.Context) {
+	var req AliasReservationRequest
+	if !bindRequest(c, &req, "Invalid input: alias and reason are required") {
+		return
+	}
+	alias, _ := normalizeAlias(req.Alias)
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock out a customer claiming the alias meanwhile
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", aliasLockClass, alias); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to lock alias"})
+		return
+	}
+	var taken bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM account_aliases WHERE alias = $1)", alias).Scan(&taken); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to check alias"})
+		return
+	}
+	if taken {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Alias is already taken", Code: "alias_taken"})
+		return
+	}
+
+	reservation := AliasReservation{Alias: withAt(alias), Reason: req.Reason, ReservedBy: actor}
+	var createdAt time.Time
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO alias_reservations (alias, reason, reserved_by) VALUES ($1, $2, $3)
+		ON CONFLICT (alias) DO UPDATE SET customer_id = NULL, reason = EXCLUDED.reason, reserved_by = EXCLUDED.reserved_by,
+			expires_at = NULL, created_at = NOW()
+		RETURNING created_at`,
+		alias, req.Reason, actor).Scan(&createdAt); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to reserve alias"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "alias.reserved", "alias_reservation", uuid.New(), nil, map[string]interface{}{
+		"alias":  alias,
+		"reason": req.Reason,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	reservation.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// @Summary Remove an alias reservation
+// @Description Let customers claim a reserved alias again, including one held for the customer who released it. The removal is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator removing the reservation"
+// @Param alias path string true "Alias" example(@support)
+// @Success 204 "Reservation removed"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Reservation not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/alias-reservations/{alias} [delete]
+func DeleteAliasReservation(c *gin.Context) {
+	alias, ok := normalizeAlias(c.Param("alias"))
+	if !ok {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Reservation not found"})
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM alias_reservations WHERE alias = $1 AND (expires_at IS NULL OR expires_at > NOW())", alias)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to remove reservation"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Reservation not found"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "alias.reservation_removed", "alias_reservation", uuid.New(), nil, map[string]interface{}{
+		"alias": alias,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
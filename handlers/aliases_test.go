@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+const lockAliasOwnerQuery = `SELECT \(SELECT alias FROM account_aliases WHERE customer_id = c.id\) FROM customers c WHERE c.id = \$1 FOR UPDATE`
+
+func TestSetAlias(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitAliases(30 * 24 * time.Hour)
+	defer InitAliases(0)
+	router.PUT("/customers/:customer_id/alias", SetAlias)
+
+	customerID := uuid.New()
+	otherID := uuid.New()
+	expectAliasLock := func(alias string) {
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).
+			WithArgs(aliasLockClass, alias).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	}
+	expectReservation := func(alias string, heldFor *uuid.UUID, found bool) {
+		q := mock.ExpectQuery(`SELECT customer_id FROM alias_reservations WHERE alias = \$1 AND \(expires_at IS NULL OR expires_at > NOW\(\)\)`).
+			WithArgs(alias)
+		if found {
+			q.WillReturnRows(pgxmock.NewRows([]string{"customer_id"}).AddRow(heldFor))
+		} else {
+			q.WillReturnError(pgx.ErrNoRows)
+		}
+	}
+	tests := []struct {
+		name       string
+		alias      string
+		wantStatus int
+		wantCode   string
+		setupMock  func()
+	}{
+		{
+			name:       "first alias",
+			alias:      "@Coffee-Shop",
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAliasOwnerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow((*string)(nil)))
+				expectAliasLock("coffee-shop")
+				expectReservation("coffee-shop", nil, false)
+				mock.ExpectExec(`DELETE FROM alias_reservations WHERE alias = \$1`).
+					WithArgs("coffee-shop").
+					WillReturnResult(pgxmock.NewResult("DELETE", 0))
+				mock.ExpectQuery(`INSERT INTO account_aliases \(alias, customer_id\) VALUES \(\$1, \$2\) RETURNING created_at`).
+					WithArgs("coffee-shop", customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+				mock.ExpectExec(`INSERT INTO alias_changes`).
+					WithArgs(pgxmock.AnyArg(), customerID, pgxmock.AnyArg(), pgxmock.AnyArg(), aliasCustomer).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "changing alias holds the old one",
+			alias:      "coffee-shop",
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				old := "coffee"
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAliasOwnerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow(&old))
+				expectAliasLock("coffee-shop")
+				expectReservation("coffee-shop", &customerID, true)
+				mock.ExpectExec(`DELETE FROM account_aliases WHERE alias = \$1`).
+					WithArgs("coffee").
+					WillReturnResult(pgxmock.NewResult("DELETE", 1))
+				mock.ExpectExec(`INSERT INTO alias_reservations \(alias, customer_id, reason, reserved_by, expires_at\)`).
+					WithArgs("coffee", customerID, aliasReleaser, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`DELETE FROM alias_reservations WHERE alias = \$1`).
+					WithArgs("coffee-shop").
+					WillReturnResult(pgxmock.NewResult("DELETE", 1))
+				mock.ExpectQuery(`INSERT INTO account_aliases`).
+					WithArgs("coffee-shop", customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+				mock.ExpectExec(`INSERT INTO alias_changes`).
+					WithArgs(pgxmock.AnyArg(), customerID, pgxmock.AnyArg(), pgxmock.AnyArg(), aliasCustomer).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "unchanged alias",
+			alias:      "@coffee-shop",
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				current := "coffee-shop"
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAliasOwnerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow(&current))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "reserved by an operator",
+			alias:      "@support",
+			wantStatus: http.StatusConflict,
+			wantCode:   "alias_reserved",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAliasOwnerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow((*string)(nil)))
+				expectAliasLock("support")
+				expectReservation("support", nil, true)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "held for another customer",
+			alias:      "@jane",
+			wantStatus: http.StatusConflict,
+			wantCode:   "alias_reserved",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAliasOwnerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow((*string)(nil)))
+				expectAliasLock("jane")
+				expectReservation("jane", &otherID, true)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "taken",
+			alias:      "@jane",
+			wantStatus: http.StatusConflict,
+			wantCode:   "alias_taken",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockAliasOwnerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow((*string)(nil)))
+				expectAliasLock("jane")
+				expectReservation("jane", nil, false)
+				mock.ExpectExec(`DELETE FROM alias_reservations`).
+					WithArgs("jane").
+					WillReturnResult(pgxmock.NewResult("DELETE", 0))
+				mock.ExpectQuery(`INSERT INTO account_aliases`).
+					WithArgs("jane", customerID).
+					WillReturnError(&pgconn.PgError{Code: "23505"})
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "invalid alias",
+			alias:      "@-x",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(AliasRequest{Alias: tt.alias})
+			req := httptest.NewRequest("PUT", "/customers/"+customerID.String()+"/alias", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				var resp ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantCode, resp.Code)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var resp AccountAlias
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "@coffee-shop", resp.Alias)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetAccountByAlias(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/accounts/by-alias/:alias", GetAccountByAlias)
+
+	customerID := uuid.New()
+	mock.ExpectQuery(`SELECT c.id, c.name, c.currency FROM account_aliases a JOIN customers c ON c.id = a.customer_id WHERE a.alias = \$1`).
+		WithArgs("coffee-shop").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "currency"}).AddRow(customerID, "Coffee Shop Ltd", "USD"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/accounts/by-alias/@Coffee-Shop", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp AliasLookup
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, AliasLookup{Alias: "@coffee-shop", CustomerID: customerID, Name: "Coffee Shop Ltd", Currency: "USD"}, resp)
+
+	mock.ExpectQuery(`FROM account_aliases a JOIN customers c`).
+		WithArgs("nobody").
+		WillReturnError(pgx.ErrNoRows)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/accounts/by-alias/nobody", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveAlias(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/alias-reservations", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "alice")
+		ReserveAlias(c)
+	})
+
+	tests := []struct {
+		name       string
+		taken      bool
+		wantStatus int
+	}{
+		{
+			name:       "free alias",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "alias held by a customer",
+			taken:      true,
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT pg_advisory_xact_lock`).
+				WithArgs(aliasLockClass, "support").
+				WillReturnResult(pgxmock.NewResult("SELECT", 1))
+			mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM account_aliases WHERE alias = \$1\)`).
+				WithArgs("support").
+				WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(tt.taken))
+			if tt.taken {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectQuery(`INSERT INTO alias_reservations \(alias, reason, reserved_by\)`).
+					WithArgs("support", "Could be mistaken for the bank", "alice").
+					WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "alice", "alias.reserved", "alias_reservation", pgxmock.AnyArg(), (*uuid.UUID)(nil), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			}
+
+			jsonBytes, _ := json.Marshal(AliasReservationRequest{Alias: "@Support", Reason: "Could be mistaken for the bank"})
+			req := httptest.NewRequest("POST", "/admin/alias-reservations", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp AliasReservation
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "@support", resp.Alias)
+				assert.Equal(t, "alice", resp.ReservedBy)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/merkle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// anchorBatchSize caps the transactions one anchor's tree holds; a run with
+// more to anchor makes several anchors
+const anchorBatchSize = 10000
+
+// LedgerAnchor is a published Merkle root over a batch of posted
+// transactions
+// @Description Merkle root over a batch of posted transactions, chained to the anchors before it
+type LedgerAnchor struct {
+	// Sequence numbers anchors from 1 with no gaps
+	Sequence int64 `json:"sequence" example:"42"`
+	// Root is the hex RFC 6962 Merkle tree hash over the batch's leaves
+	Root string `json:"root" example:"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"`
+	// Size is the number of transactions in the tree
+	Size int `json:"size" example:"1280"`
+	// PreviousChainHash is the chain hash of the anchor before, all zeros
+	// for the first
+	PreviousChainHash string `json:"previous_chain_hash" example:"0000000000000000000000000000000000000000000000000000000000000000"`
+	// ChainHash is SHA-256(previous chain hash || root), committing to this
+	// root and every earlier one
+	ChainHash  string `json:"chain_hash" example:"9f2c3e0d5b1a8e7f6c4d2b0a9e8f7d6c5b4a3928171605f4e3d2c1b0a9f8e7d6"`
+	AnchoredAt string `json:"anchored_at" format:"date-time"`
+}
+
+// TransactionProof shows a transaction is included in an anchor
+// @Description Inclusion proof of a transaction in a published ledger anchor
+type TransactionProof struct {
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	// Leaf is the transaction as hashed into the tree, in canonical JSON
+	Leaf string `json:"leaf" example:"{\"id\":\"...\",\"customer_id\":\"...\",\"type\":\"debit\",\"amount\":\"42.50\",\"value_date\":\"2025-04-08\",\"reference\":\"INV-2025-0042\",\"created_at\":\"2025-04-08T09:12:44.120551Z\"}"`
+	// LeafHash is SHA-256(0x00 || leaf) in hex
+	LeafHash  string `json:"leaf_hash" example:"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"`
+	LeafIndex int    `json:"leaf_index" example:"17"`
+	// AuditPath holds the hex sibling hashes from the leaf up to the root
+	AuditPath []string     `json:"audit_path"`
+	Anchor    LedgerAnchor `json:"anchor"`
+	// Verified reports whether the leaf, as the ledger holds the
+	// transaction now, proves against the anchor's root; false means the
+	// transaction changed after it was anchored
+	Verified bool `json:"verified" example:"true"`
+}
+
+// LedgerAnchoredEventData is the payload of ledger.anchored events
+type LedgerAnchoredEventData struct {
+	Sequence  int64  `json:"sequence" example:"42"`
+	Root      string `json:"root" example:"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"`
+	ChainHash string `json:"chain_hash" example:"9f2c3e0d5b1a8e7f6c4d2b0a9e8f7d6c5b4a3928171605f4e3d2c1b0a9f8e7d6"`
+	Size      int    `json:"size" example:"1280"`
+}
+
+// anchorLeaf is the canonical form a transaction is hashed in. It holds
+// only what does not change once a transaction is posted.
+func anchorLeaf(id, customerID uuid.UUID, txType string, amount float64, valueDate time.Time, reference string, createdAt time.Time) []byte {
+	leaf, _ := json.Marshal(struct {
+		ID         string `json:"id"`
+		CustomerID string `json:"customer_id"`
+		Type       string `json:"type"`
+		Amount     string `json:"amount"`
+		ValueDate  string `json:"value_date"`
+		Reference  string `json:"reference"`
+		CreatedAt  string `json:"created_at"`
+	}{id.String(), customerID.String(), txType, strconv.FormatFloat(amount, 'f', 2, 64), valueDate.Format(dateLayout), reference,
+		createdAt.UTC().Format(time.RFC3339Nano)})
+	return leaf
+}
+
+const anchorColumns = "sequence, root, size, previous_chain_hash, chain_hash, created_at"
+
+func scanAnchor(row pgx.Row) (LedgerAnchor, error) {
+	var a LedgerAnchor
+	var at time.Time
+	if err := row.Scan(&a.Sequence, &a.Root, &a.Size, &a.PreviousChainHash, &a.ChainHash, &at); err != nil {
+		return a, err
+	}
+	a.AnchoredAt = at.UTC().Format(time.RFC3339)
+	return a, nil
+}
+
+// AnchorLedger is the scheduled job that anchors every posted transaction
+// not yet anchored, in batches of anchorBatchSize, each batch's Merkle root
+// chained to the anchor before. It returns how many transactions it
+// anchored.
+func AnchorLedger(ctx context.Context, _ time.Time) (int, error) {
+	anchored := 0
+	for {
+		n, err := anchorBatch(ctx)
+		anchored += n
+		if err != nil || n < anchorBatchSize {
+			return anchored, err
+		}
+	}
+}
+
+// anchorBatch anchors up to anchorBatchSize transactions, oldest first
+func anchorBatch(ctx context.Context) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var sequence int64
+	previous := merkle.Hash{}
+	var chain string
+	err = tx.QueryRow(ctx, "SELECT sequence, chain_hash FROM ledger_anchors ORDER BY sequence DESC LIMIT 1").Scan(&sequence, &chain)
+	switch {
+	case err == pgx.ErrNoRows:
+	case err != nil:
+		return 0, err
+	default:
+		if previous, err = merkle.ParseHash(chain); err != nil {
+			return 0, fmt.Errorf("anchor %d: %w", sequence, err)
+		}
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT t.id, t.customer_id, t.type, t.amount, t.value_date, COALESCE(t.reference, ''), t.created_at FROM transactions t
+		WHERE t.status = 'posted' AND NOT EXISTS (SELECT 1 FROM ledger_anchor_leaves l WHERE l.transaction_id = t.id)
+		ORDER BY t.created_at, t.id LIMIT $1`,
+		anchorBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var ids []uuid.UUID
+	var leaves []merkle.Hash
+	for rows.Next() {
+		var id, customerID uuid.UUID
+		var txType, reference string
+		var amount float64
+		var valueDate, createdAt time.Time
+		if err := rows.Scan(&id, &customerID, &txType, &amount, &valueDate, &reference, &createdAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		leaves = append(leaves, merkle.LeafHash(anchorLeaf(id, customerID, txType, amount, valueDate, reference, createdAt)))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	root := merkle.Root(leaves)
+	anchor := LedgerAnchoredEventData{Sequence: sequence + 1, Root: root.String(), ChainHash: merkle.Chain(previous, root).String(), Size: len(ids)}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO ledger_anchors (sequence, root, size, previous_chain_hash, chain_hash) VALUES ($1, $2, $3, $4, $5)",
+		anchor.Sequence, anchor.Root, anchor.Size, previous.String(), anchor.ChainHash); err != nil {
+		return 0, err
+	}
+	hashes := make([]string, len(leaves))
+	for i, h := range leaves {
+		hashes[i] = h.String()
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO ledger_anchor_leaves (anchor_sequence, leaf_index, transaction_id, leaf_hash)
+		SELECT $1, n - 1, id, hash FROM unnest($2::uuid[], $3::text[]) WITH ORDINALITY AS l(id, hash, n)`,
+		anchor.Sequence, ids, hashes); err != nil {
+		return 0, err
+	}
+	if err := enqueueEvent(ctx, tx, events.LedgerAnchored, nil, anchor); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// @Summary List ledger anchors
+// @Description List the published Merkle roots over the ledger's posted transactions, newest first. Each anchor's chain hash commits to its root and every root before it, so a copy of the latest chain hash kept outside the ledger pins the whole history.
+// @Tags ledger
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Anchors per page" default(10)
+// @Success 200 {array} LedgerAnchor "Anchors"
+// @Failure 400 {object} ErrorResponse "Invalid pagination"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /ledger/anchors [get]
+func ListLedgerAnchors(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+anchorColumns+" FROM ledger_anchors ORDER BY sequence DESC LIMIT $1 OFFSET $2",
+		pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch anchors"})
+		return
+	}
+	defer rows.Close()
+	anchors := []LedgerAnchor{}
+	for rows.Next() {
+		a, err := scanAnchor(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan anchor"})
+			return
+		}
+		anchors = append(anchors, a)
+	}
+	c.JSON(http.StatusOK, anchors)
+}
+
+// @Summary Get a ledger anchor
+// @Description Get a published Merkle root by its sequence number
+// @Tags ledger
+// @Produce json
+// @Param sequence path int true "Anchor sequence number"
+// @Success 200 {object} LedgerAnchor "Anchor"
+// @Failure 400 {object} ErrorResponse "Invalid sequence number"
+// @Failure 404 {object} ErrorResponse "Anchor not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /ledger/anchors/{sequence} [get]
+func GetLedgerAnchor(c *gin.Context) {
+	sequence, err := strconv.ParseInt(c.Param("sequence"), 10, 64)
+	if err != nil || sequence < 1 {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid sequence number"})
+		return
+	}
+	a, err := scanAnchor(db.QueryRow(c.Request.Context(), "SELECT "+anchorColumns+" FROM ledger_anchors WHERE sequence = $1", sequence))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Anchor not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch anchor"})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// errNotAnchored is returned for a transaction no anchor holds yet
+var errNotAnchored = errors.New("transaction not anchored")
+
+// @Summary Get a transaction's inclusion proof
+// @Description Get the proof that a posted transaction is included in a published ledger anchor. An auditor hashes leaf as SHA-256(0x00 || leaf), checks it describes the transaction they hold, and folds in the audit path as RFC 6962 describes; the result must equal the anchor's root, which they compare with the root published at the time. Transactions are anchored by a scheduled job, so a new one has no proof until the next run.
+// @Tags transactions
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param transaction_id path string true "Transaction ID" format(uuid)
+// @Success 200 {object} TransactionProof "Inclusion proof"
+// @Failure 400 {object} ErrorResponse "Invalid customer or transaction ID"
+// @Failure 404 {object} ErrorResponse "Transaction not found"
+// @Failure 409 {object} ErrorResponse "Transaction not anchored yet"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/transactions/{transaction_id}/proof [get]
+func GetTransactionProof(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	transactionID, err := uuid.Parse(c.Param("transaction_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	proof, err := transactionProof(ctx, customerID, transactionID)
+	switch {
+	case err == pgx.ErrNoRows:
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+	case errors.Is(err, errNotAnchored):
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction has not been anchored yet", TransactionID: &transactionID})
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to build proof"})
+	default:
+		c.JSON(http.StatusOK, proof)
+	}
+}
+
+// transactionProof builds the inclusion proof of one of a customer's
+// transactions from the leaf hashes stored with its anchor
+func transactionProof(ctx context.Context, customerID, transactionID uuid.UUID) (TransactionProof, error) {
+	p := TransactionProof{TransactionID: transactionID}
+	var txType, reference string
+	var amount float64
+	var valueDate, createdAt time.Time
+	var sequence *int64
+	var index *int
+	err := db.QueryRow(ctx,
+		`SELECT t.type, t.amount, t.value_date, COALESCE(t.reference, ''), t.created_at, l.anchor_sequence, l.leaf_index
+		FROM transactions t LEFT JOIN ledger_anchor_leaves l ON l.transaction_id = t.id
+		WHERE t.id = $1 AND t.customer_id = $2`,
+		transactionID, customerID).Scan(&txType, &amount, &valueDate, &reference, &createdAt, &sequence, &index)
+	if err != nil {
+		return p, err
+	}
+	if sequence == nil {
+		return p, errNotAnchored
+	}
+	if p.Anchor, err = scanAnchor(db.QueryRow(ctx, "SELECT "+anchorColumns+" FROM ledger_anchors WHERE sequence = $1", *sequence)); err != nil {
+		return p, err
+	}
+
+	rows, err := db.Query(ctx, "SELECT leaf_hash FROM ledger_anchor_leaves WHERE anchor_sequence = $1 ORDER BY leaf_index", *sequence)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+	leaves := make([]merkle.Hash, 0, p.Anchor.Size)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return p, err
+		}
+		h, err := merkle.ParseHash(s)
+		if err != nil {
+			return p, err
+		}
+		leaves = append(leaves, h)
+	}
+	if err := rows.Err(); err != nil {
+		return p, err
+	}
+	path, err := merkle.Proof(leaves, *index)
+	if err != nil {
+		return p, err
+	}
+	root, err := merkle.ParseHash(p.Anchor.Root)
+	if err != nil {
+		return p, err
+	}
+
+	leaf := anchorLeaf(transactionID, customerID, txType, amount, valueDate, reference, createdAt)
+	leafHash := merkle.LeafHash(leaf)
+	p.Leaf, p.LeafHash, p.LeafIndex = string(leaf), leafHash.String(), *index
+	p.AuditPath = make([]string, len(path))
+	for i, h := range path {
+		p.AuditPath[i] = h.String()
+	}
+	p.Verified = merkle.Verify(leafHash, *index, len(leaves), path, root)
+	return p, nil
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/merkle"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anchoredTransaction is a transaction as anchorLeaf hashes it
+type anchoredTransaction struct {
+	id, customerID uuid.UUID
+	txType         string
+	amount         float64
+	valueDate      time.Time
+	reference      string
+	createdAt      time.Time
+}
+
+func (a anchoredTransaction) leafHash() merkle.Hash {
+	return merkle.LeafHash(anchorLeaf(a.id, a.customerID, a.txType, a.amount, a.valueDate, a.reference, a.createdAt))
+}
+
+func sampleAnchoredTransactions() []anchoredTransaction {
+	customerID := uuid.New()
+	created := time.Date(2025, 4, 8, 9, 12, 44, 120551000, time.UTC)
+	return []anchoredTransaction{
+		{uuid.New(), customerID, "credit", 2500, created, "PAYROLL", created},
+		{uuid.New(), customerID, "debit", 42.5, created, "INV-2025-0042", created.Add(time.Minute)},
+		{uuid.New(), uuid.New(), "fee", 2, created, "", created.Add(2 * time.Minute)},
+	}
+}
+
+func TestAnchorLedger(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	transactions := sampleAnchoredTransactions()
+	previous := merkle.LeafHash([]byte("earlier anchor"))
+	leaves := make([]merkle.Hash, len(transactions))
+	rows := pgxmock.NewRows([]string{"id", "customer_id", "type", "amount", "value_date", "reference", "created_at"})
+	for i, tx := range transactions {
+		leaves[i] = tx.leafHash()
+		rows.AddRow(tx.id, tx.customerID, tx.txType, tx.amount, tx.valueDate, tx.reference, tx.createdAt)
+	}
+	root := merkle.Root(leaves)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT sequence, chain_hash FROM ledger_anchors ORDER BY sequence DESC LIMIT 1`).
+		WillReturnRows(pgxmock.NewRows([]string{"sequence", "chain_hash"}).AddRow(int64(6), previous.String()))
+	mock.ExpectQuery(`FROM transactions t\s+WHERE t.status = 'posted' AND NOT EXISTS`).
+		WithArgs(anchorBatchSize).
+		WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO ledger_anchors`).
+		WithArgs(int64(7), root.String(), 3, previous.String(), merkle.Chain(previous, root).String()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	hashes := &capturedArg{}
+	mock.ExpectExec(`INSERT INTO ledger_anchor_leaves .* unnest`).
+		WithArgs(int64(7), []uuid.UUID{transactions[0].id, transactions[1].id, transactions[2].id}, hashes).
+		WillReturnResult(pgxmock.NewResult("INSERT", 3))
+	expectEvent(events.LedgerAnchored)
+	mock.ExpectCommit()
+
+	n, err := AnchorLedger(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{leaves[0].String(), leaves[1].String(), leaves[2].String()}, hashes.value)
+
+	// Nothing new to anchor makes no anchor
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT sequence, chain_hash FROM ledger_anchors`).
+		WillReturnRows(pgxmock.NewRows([]string{"sequence", "chain_hash"}).AddRow(int64(7), merkle.Chain(previous, root).String()))
+	mock.ExpectQuery(`FROM transactions t`).WithArgs(anchorBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "type", "amount", "value_date", "reference", "created_at"}))
+	mock.ExpectRollback()
+	n, err = AnchorLedger(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTransactionProof(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.GET("/customers/:customer_id/transactions/:transaction_id/proof", GetTransactionProof)
+
+	transactions := sampleAnchoredTransactions()
+	leaves := make([]merkle.Hash, len(transactions))
+	leafRows := pgxmock.NewRows([]string{"leaf_hash"})
+	for i, tx := range transactions {
+		leaves[i] = tx.leafHash()
+		leafRows.AddRow(leaves[i].String())
+	}
+	root := merkle.Root(leaves)
+	target := transactions[1]
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/"+target.customerID.String()+"/transactions/"+target.id.String()+"/proof", nil))
+		return w
+	}
+	expectProof := func(amount float64, rows *pgxmock.Rows) {
+		sequence, index := int64(7), 1
+		mock.ExpectQuery(`FROM transactions t LEFT JOIN ledger_anchor_leaves l`).
+			WithArgs(target.id, target.customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"type", "amount", "value_date", "reference", "created_at", "anchor_sequence", "leaf_index"}).
+				AddRow(target.txType, amount, target.valueDate, target.reference, target.createdAt, &sequence, &index))
+		mock.ExpectQuery(`FROM ledger_anchors WHERE sequence = \$1`).
+			WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"sequence", "root", "size", "previous_chain_hash", "chain_hash", "created_at"}).
+				AddRow(int64(7), root.String(), 3, merkle.Hash{}.String(), merkle.Chain(merkle.Hash{}, root).String(), time.Now()))
+		mock.ExpectQuery(`SELECT leaf_hash FROM ledger_anchor_leaves WHERE anchor_sequence = \$1 ORDER BY leaf_index`).
+			WithArgs(int64(7)).
+			WillReturnRows(rows)
+	}
+
+	expectProof(target.amount, leafRows)
+	w := get()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var proof TransactionProof
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &proof))
+	assert.True(t, proof.Verified)
+	assert.Contains(t, proof.Leaf, `"amount":"42.50"`)
+
+	// An auditor can check the proof from the response alone
+	leafHash := merkle.LeafHash([]byte(proof.Leaf))
+	assert.Equal(t, leafHash.String(), proof.LeafHash)
+	path := make([]merkle.Hash, len(proof.AuditPath))
+	for i, s := range proof.AuditPath {
+		path[i], err = merkle.ParseHash(s)
+		require.NoError(t, err)
+	}
+	published, _ := merkle.ParseHash(proof.Anchor.Root)
+	assert.True(t, merkle.Verify(leafHash, proof.LeafIndex, proof.Anchor.Size, path, published))
+
+	// A transaction changed after it was anchored no longer proves
+	tampered := pgxmock.NewRows([]string{"leaf_hash"})
+	for _, h := range leaves {
+		tampered.AddRow(h.String())
+	}
+	expectProof(4250, tampered)
+	w = get()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &proof))
+	assert.False(t, proof.Verified)
+
+	mock.ExpectQuery(`FROM transactions t LEFT JOIN ledger_anchor_leaves l`).
+		WithArgs(target.id, target.customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"type", "amount", "value_date", "reference", "created_at", "anchor_sequence", "leaf_index"}).
+			AddRow(target.txType, target.amount, target.valueDate, target.reference, target.createdAt, (*int64)(nil), (*int)(nil)))
+	assert.Equal(t, http.StatusConflict, get().Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
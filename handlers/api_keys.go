@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Scopes a scoped API key can be granted
+const (
+	ScopeCustomersRead     = "customers:read"
+	ScopeCustomersWrite    = "customers:write"
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeAdminRead         = "admin:read"
+	ScopeAdminWrite        = "admin:write"
+	ScopeAdminAdjust       = "admin:adjust"
+	ScopeWebhooksManage    = "webhooks:manage"
+)
+
+// Scopes lists every scope, in the order they are documented
+var Scopes = []string{
+	ScopeCustomersRead, ScopeCustomersWrite,
+	ScopeTransactionsRead, ScopeTransactionsWrite,
+	ScopeAdminRead, ScopeAdminWrite, ScopeAdminAdjust,
+	ScopeWebhooksManage,
+}
+
+// APIKeyIntrospectionRoute is where a scoped key reads its own grants
+const APIKeyIntrospectionRoute = "/api-keys/current"
+
+// adjustRoutes post corrections to balances and need admin:adjust
+var adjustRoutes = map[string]bool{
+	"/admin/adjustments":            true,
+	"/admin/transactions/backdated": true,
+	"/admin/reconciliations/:reconciliation_id/lines/:line_id/adjust": true,
+}
+
+// queryRoutes are posted to but only read, so a :read scope is enough
+var queryRoutes = map[string]bool{
+	BalanceQueryRoute: true,
+}
+
+// transactionSegments mark the routes about money rather than customers
+var transactionSegments = []string{
+	"/transactions", "/transaction-types", "/balance", "/balances", "/balance-certificates", "/pending", "/stats",
+	"/transfers", "/moves", "/withdrawals", "/credit-transfers", "/sagas",
+	"/pull-payments", "/payment-requests", "/payment-links", "/fx", "/ledger",
+	"/standing-orders", "/mandates", "/loans", "/statements",
+	"/ingest", "/plaid", "/stripe",
+}
+
+// RouteScope is the scope a scoped API key needs for a route, for both
+// the versioned and legacy paths. It satisfies middleware.RouteScope.
+// Reads need a :read scope and everything else a :write one; webhooks
+// and balance corrections have scopes of their own. Keys cannot manage
+// keys, so a key can never grant itself more.
+func RouteScope(method, route string) (string, bool) {
+	route = strings.TrimPrefix(route, "/v1")
+	read := method == http.MethodGet || method == http.MethodHead || queryRoutes[route]
+	switch {
+	case route == APIKeyIntrospectionRoute:
+		return "", true
+	case strings.HasPrefix(route, "/admin/api-keys"):
+		return "", false
+	case strings.HasPrefix(route, "/admin/webhooks"):
+		return ScopeWebhooksManage, true
+	case adjustRoutes[route]:
+		return ScopeAdminAdjust, true
+	case strings.HasPrefix(route, "/admin/"):
+		if read {
+			return ScopeAdminRead, true
+		}
+		return ScopeAdminWrite, true
+	}
+	for _, segment := range transactionSegments {
+		if strings.Contains(route+"/", segment+"/") {
+			if read {
+				return ScopeTransactionsRead, true
+			}
+			return ScopeTransactionsWrite, true
+		}
+	}
+	if read {
+		return ScopeCustomersRead, true
+	}
+	return ScopeCustomersWrite, true
+}
+
+// APIKey is a key for integrations, limited to the scopes it was granted
+// @Description Scoped API key; the key itself is only returned when it is created
+type APIKey struct {
+	ID     uuid.UUID `json:"key_id" format:"uuid"`
+	Name   string    `json:"name" example:"Reporting dashboard"`
+	Scopes []string  `json:"scopes" example:"customers:read,transactions:read"`
+	// Key is only returned when the key is created
+	Key       string `json:"key,omitempty" example:"lsk_9c1e0b7d3f5a2c4e6a8b0d2f4c6e8a0b1d3f5a7c9e1b3d5f7a9c0e2b4d6f8a0c"`
+	ExpiresAt string `json:"expires_at,omitempty" example:"2025-07-08T17:09:17Z" format:"date-time"`
+	RevokedAt string `json:"revoked_at,omitempty" example:"2025-05-01T09:00:00Z" format:"date-time"`
+	CreatedAt string `json:"created_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	UpdatedAt string `json:"updated_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// APIKeyRequest creates a scoped API key
+// @Description Label, scopes and lifetime of a new API key
+type APIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100" example:"Reporting dashboard"`
+	Scopes []string `json:"scopes" binding:"required,min=1" example:"customers:read,transactions:read"`
+	// ExpiresInDays defaults to a key that does not expire
+	ExpiresInDays *int `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=365" example:"90"`
+}
+
+// APIKeyScopesRequest replaces a key's scopes
+// @Description The scopes an API key is granted from its next request on
+type APIKeyScopesRequest struct {
+	Scopes []string `json:"scopes" binding:"required,min=1" example:"transactions:read"`
+}
+
+// APIKeyIntrospection is what a scoped key may do
+// @Description The key a request was made with and its scopes
+type APIKeyIntrospection struct {
+	ID     string   `json:"key_id" format:"uuid"`
+	Name   string   `json:"name" example:"Reporting dashboard"`
+	Scopes []string `json:"scopes" example:"customers:read,transactions:read"`
+}
+
+const apiKeyColumns = "id, name, scopes, expires_at, revoked_at, created_at, updated_at"
+
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var k APIKey
+	var expiresAt, revokedAt *time.Time
+	var createdAt, updatedAt time.Time
+	if err := row.Scan(&k.ID, &k.Name, &k.Scopes, &expiresAt, &revokedAt, &createdAt, &updatedAt); err != nil {
+		return APIKey{}, err
+	}
+	if expiresAt != nil {
+		k.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+	if revokedAt != nil {
+		k.RevokedAt = revokedAt.UTC().Format(time.RFC3339)
+	}
+	k.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	k.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	return k, nil
+}
+
+// newAPIKey returns a random key and the hash it is stored under
+func newAPIKey() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key := middleware.APIKeyPrefix + hex.EncodeToString(b)
+	return key, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes sorts and deduplicates scopes, returning the first one
+// that is unknown
+func normalizeScopes(scopes []string) ([]string, string) {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range scopes {
+		if !hasString(Scopes, s) {
+			return nil, s
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out, ""
+}
+
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupAPIKey returns the live key a secret belongs to with its current
+// scopes, or nil when it is unknown, revoked or expired. It satisfies
+// middleware.APIKeyLookup.
+func LookupAPIKey(ctx context.Context, key string) (*middleware.APIKey, error) {
+	var id uuid.UUID
+	k := &middleware.APIKey{}
+	err := db.QueryRow(ctx,
+		"SELECT id, name, scopes FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())",
+		hashAPIKey(key)).Scan(&id, &k.Name, &k.Scopes)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.ID = id.String()
+	return k, nil
+}
+
+// @Summary Create an API key
+// @Description Create a key for an integration, limited to the scopes it is granted. Sent in the `X-API-Key` header or as `Authorization: Bearer <key>`, it opens the routes its scopes cover and nothing else. Reads need a `:read` scope and writes a `:write` one; `admin:adjust` covers adjustments, backdated postings and reconciliation adjustments, and `webhooks:manage` the webhook endpoints. Keys cannot manage API keys. The key is returned once and only its hash is stored. Creation is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param key body APIKeyRequest true "API key"
+// @Success 201 {object} APIKey "Key created"
+// @Failure 400 {object} ErrorResponse "Invalid input data or unknown scope"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var req APIKeyRequest
+	if !bindRequest(c, &req, "Invalid input: name and scopes are required") {
+		return
+	}
+	scopes, unknown := normalizeScopes(req.Scopes)
+	if unknown != "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Unknown scope: " + unknown})
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		t := time.Now().UTC().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+	ctx := c.Request.Context()
+
+	key, hash, err := newAPIKey()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate key"})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	k, err := scanAPIKey(tx.QueryRow(ctx,
+		"INSERT INTO api_keys (id, name, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING "+apiKeyColumns,
+		uuid.New(), req.Name, hash, scopes, expiresAt))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create key"})
+		return
+	}
+	if err := recordAudit(ctx, tx, c.GetString(middleware.ActorKey), "api_key.created", "api_key", k.ID, nil, map[string]interface{}{
+		"name":       k.Name,
+		"scopes":     k.Scopes,
+		"expires_at": k.ExpiresAt,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	k.Key = key
+
+	c.JSON(http.StatusCreated, k)
+}
+
+// @Summary List API keys
+// @Description List the scoped API keys, newest first, including revoked and expired ones. The keys themselves are not included.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} APIKey "Keys"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/api-keys [get]
+func ListAPIKeys(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+apiKeyColumns+" FROM api_keys ORDER BY created_at DESC")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch keys"})
+		return
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan key"})
+			return
+		}
+		keys = append(keys, k)
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// @Summary Change an API key's scopes
+// @Description Replace the scopes of a live API key. The change applies from the key's next request, so the key need not be rotated. The change is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param key_id path string true "Key ID" format(uuid)
+// @Param scopes body APIKeyScopesRequest true "Scopes"
+// @Success 200 {object} APIKey "Scopes changed"
+// @Failure 400 {object} ErrorResponse "Invalid input data or unknown scope"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Key not found or revoked"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/api-keys/{key_id} [patch]
+func UpdateAPIKeyScopes(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid key ID"})
+		return
+	}
+	var req APIKeyScopesRequest
+	if !bindRequest(c, &req, "Invalid input: scopes are required") {
+		return
+	}
+	scopes, unknown := normalizeScopes(req.Scopes)
+	if unknown != "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Unknown scope: " + unknown})
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	k, err := scanAPIKey(tx.QueryRow(ctx,
+		"UPDATE api_keys SET scopes = $2, updated_at = NOW() WHERE id = $1 AND revoked_at IS NULL RETURNING "+apiKeyColumns,
+		keyID, scopes))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Key not found or revoked"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update key"})
+		return
+	}
+	if err := recordAudit(ctx, tx, c.GetString(middleware.ActorKey), "api_key.scopes_changed", "api_key", keyID, nil, map[string]interface{}{
+		"scopes": k.Scopes,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, k)
+}
+
+// @Summary Revoke an API key
+// @Description Revoke a scoped API key so it stops working immediately. Revocation is recorded in the audit log.
+// @Tags admin
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param key_id path string true "Key ID" format(uuid)
+// @Success 204 "Key revoked"
+// @Failure 400 {object} ErrorResponse "Invalid key ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Key not found or already revoked"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/api-keys/{key_id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid key ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE api_keys SET revoked_at = NOW(), updated_at = NOW() WHERE id = $1 AND revoked_at IS NULL",
+		keyID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke key"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Key not found or already revoked"})
+		return
+	}
+	if err := recordAudit(ctx, tx, c.GetString(middleware.ActorKey), "api_key.revoked", "api_key", keyID, nil, map[string]interface{}{}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Introspect the current API key
+// @Description Return the scoped API key the request is made with and the scopes it holds right now, so an integration can check what it may do before trying.
+// @Tags api-keys
+// @Produce json
+// @Param X-API-Key header string true "Scoped API key"
+// @Success 200 {object} APIKeyIntrospection "Key"
+// @Failure 401 {object} ErrorResponse "No scoped API key, or an invalid one"
+// @Router /api-keys/current [get]
+func GetCurrentAPIKey(c *gin.Context) {
+	key := middleware.CurrentAPIKey(c)
+	if key == nil {
+		respondError(c, http.StatusUnauthorized, ErrorResponse{Error: "A scoped API key is required"})
+		return
+	}
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	c.JSON(http.StatusOK, APIKeyIntrospection{ID: key.ID, Name: key.Name, Scopes: scopes})
+}
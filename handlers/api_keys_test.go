@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKeyRowColumns = []string{"id", "name", "scopes", "expires_at", "revoked_at", "created_at", "updated_at"}
+
+func TestRouteScope(t *testing.T) {
+	tests := []struct {
+		method, route string
+		want          string
+		allowed       bool
+	}{
+		{"GET", "/v1/customers/:customer_id", ScopeCustomersRead, true},
+		{"PATCH", "/customers/:customer_id", ScopeCustomersWrite, true},
+		{"GET", "/v1/customers/:customer_id/balance", ScopeTransactionsRead, true},
+		{"GET", "/v1/balance-certificates/keys", ScopeTransactionsRead, true},
+		{"POST", "/v1/transactions", ScopeTransactionsWrite, true},
+		{"POST", "/v1/balances/query", ScopeTransactionsRead, true},
+		{"GET", "/v1/customers/:customer_id/stats", ScopeTransactionsRead, true},
+		{"POST", "/v1/customers/:customer_id/withdrawals", ScopeTransactionsWrite, true},
+		{"GET", "/v1/admin/trial-balance", ScopeAdminRead, true},
+		{"GET", "/v1/admin/transactions", ScopeAdminRead, true},
+		{"PUT", "/v1/admin/limits", ScopeAdminWrite, true},
+		{"POST", "/v1/admin/adjustments", ScopeAdminAdjust, true},
+		{"POST", "/v1/admin/reconciliations/:reconciliation_id/lines/:line_id/adjust", ScopeAdminAdjust, true},
+		{"GET", "/v1/admin/webhooks", ScopeWebhooksManage, true},
+		{"POST", "/admin/webhooks/:webhook_id/replay", ScopeWebhooksManage, true},
+		{"POST", "/v1/admin/api-keys", "", false},
+		{"GET", "/v1/api-keys/current", "", true},
+	}
+	for _, tt := range tests {
+		scope, allowed := RouteScope(tt.method, tt.route)
+		assert.Equal(t, tt.want, scope, tt.method+" "+tt.route)
+		assert.Equal(t, tt.allowed, allowed, tt.method+" "+tt.route)
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST("/admin/api-keys", CreateAPIKey)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/api-keys", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Scopes are stored sorted and without duplicates
+	scopes := []string{ScopeCustomersRead, ScopeTransactionsRead}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO api_keys \(id, name, key_hash, scopes, expires_at\)`).
+		WithArgs(pgxmock.AnyArg(), "Reporting dashboard", pgxmock.AnyArg(), scopes, (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows(apiKeyRowColumns).
+			AddRow(uuid.New(), "Reporting dashboard", scopes, (*time.Time)(nil), (*time.Time)(nil), time.Now(), time.Now()))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(pgxmock.AnyArg(), "", "api_key.created", "api_key", pgxmock.AnyArg(), (*uuid.UUID)(nil), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	w := post(`{"name": "Reporting dashboard", "scopes": ["transactions:read", "customers:read", "transactions:read"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var key APIKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &key))
+	assert.True(t, strings.HasPrefix(key.Key, middleware.APIKeyPrefix))
+	assert.Equal(t, scopes, key.Scopes)
+
+	w = post(`{"name": "Reporting dashboard", "scopes": ["ledger:everything"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "ledger:everything")
+	w = post(`{"name": "Reporting dashboard", "scopes": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAPIKeyScopes(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.PATCH("/admin/api-keys/:key_id", UpdateAPIKeyScopes)
+
+	keyID := uuid.New()
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/admin/api-keys/"+keyID.String(), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	scopes := []string{ScopeAdminAdjust}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE api_keys SET scopes = \$2, updated_at = NOW\(\) WHERE id = \$1 AND revoked_at IS NULL`).
+		WithArgs(keyID, scopes).
+		WillReturnRows(pgxmock.NewRows(apiKeyRowColumns).
+			AddRow(keyID, "Finance ops", scopes, (*time.Time)(nil), (*time.Time)(nil), time.Now(), time.Now()))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(pgxmock.AnyArg(), "", "api_key.scopes_changed", "api_key", keyID, (*uuid.UUID)(nil), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	w := patch(`{"scopes": ["admin:adjust"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), `"key"`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE api_keys`).
+		WithArgs(keyID, scopes).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusNotFound, patch(`{"scopes": ["admin:adjust"]}`).Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLookupAPIKey(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	keyID := uuid.New()
+	mock.ExpectQuery(`SELECT id, name, scopes FROM api_keys WHERE key_hash = \$1 AND revoked_at IS NULL`).
+		WithArgs(hashAPIKey("lsk_live")).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "scopes"}).AddRow(keyID, "Reporting dashboard", []string{ScopeTransactionsRead}))
+	mock.ExpectQuery(`SELECT id, name, scopes FROM api_keys`).
+		WithArgs(hashAPIKey("lsk_revoked")).
+		WillReturnError(pgx.ErrNoRows)
+
+	key, err := LookupAPIKey(context.Background(), "lsk_live")
+	require.NoError(t, err)
+	assert.Equal(t, keyID.String(), key.ID)
+	assert.True(t, key.HasScope(ScopeTransactionsRead))
+	assert.False(t, key.HasScope(ScopeTransactionsWrite))
+
+	key, err = LookupAPIKey(context.Background(), "lsk_revoked")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/policy"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ApprovalResponse represents the state of a transaction awaiting approval
+type ApprovalResponse struct {
+	TransactionID     uuid.UUID `json:"transaction_id" format:"uuid"`
+	Status            string    `json:"status" example:"pending_approval" enums:"pending_approval,posted,rejected"`
+	Approvals         int       `json:"approvals" example:"1"`
+	RequiredApprovals int       `json:"required_approvals" example:"2"`
+	Balance           float64   `json:"balance" example:"800"`
+}
+
+var (
+	accountPolicies policy.Policies
+)
+
+// InitAccountPolicies sets the account type rules consulted by the posting path
+func InitAccountPolicies(p policy.Policies) {
+	accountPolicies = p
+}
+
+// debitTypes matches the transactions that take money from a customer: debit
+// postings and the payer's leg of transfers, splits and reservations
+const debitTypes = "(type = 'transfer_out' OR type IN (SELECT code FROM transaction_types WHERE postable AND direction = 'debit'))"
+
+// policyUsage answers account policy questions from the transactions table
+type policyUsage struct {
+	q rowQuerier
+}
+
+func (u policyUsage) MonthlyDebitCount(ctx context.Context, customerID uuid.UUID) (int, error) {
+	var count int
+	err := u.q.QueryRow(ctx,
+		"SELECT COUNT(*) FROM transactions WHERE customer_id = $1 AND "+debitTypes+" AND status = 'posted' AND created_at >= "+customerMonthStart,
+		customerID).Scan(&count)
+	return count, err
+}
+
+// @Summary Approve a pending transaction
+// @Description Record the calling operator's approval of an escrow withdrawal, or of a transfer, split transfer or reservation from an escrow account. The debit posts once the required number of distinct approvers have approved it; a transfer then credits its payees and a reservation holds the funds.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Approving operator"
+// @Param transaction_id path string true "Transaction ID" format(uuid)
+// @Success 200 {object} ApprovalResponse "Approval recorded"
+// @Failure 400 {object} ErrorResponse "Invalid transaction ID or insufficient balance"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Transaction not found"
+// @Failure 409 {object} ErrorResponse "Transaction is not awaiting approval or was already approved by this operator"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/transactions/{transaction_id}/approve [post]
+func ApproveTransaction(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("transaction_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+	approver := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var customerID uuid.UUID
+	var txType, status string
+	var amount float64
+	err = tx.QueryRow(ctx,
+		"SELECT customer_id, type, amount, status FROM transactions WHERE id = $1 FOR UPDATE",
+		transactionID).Scan(&customerID, &txType, &amount, &status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get transaction"})
+		}
+		return
+	}
+	if status != "pending_approval" {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction is not awaiting approval"})
+		return
+	}
+
+	tag, err := tx.Exec(ctx,
+		"INSERT INTO transaction_approvals (transaction_id, approver) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		transactionID, approver)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record approval"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction already approved by this operator"})
+		return
+	}
+
+	var approvals int
+	if err := tx.QueryRow(ctx,
+		"SELECT COUNT(*) FROM transaction_approvals WHERE transaction_id = $1",
+		transactionID).Scan(&approvals); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to count approvals"})
+		return
+	}
+
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+		return
+	}
+
+	balance := account.Balance
+	required := accountPolicies.For(policy.AccountType(account.AccountType)).RequiredApprovals
+	var credited []uuid.UUID
+	if approvals >= required {
+		if txType == "transfer_out" {
+			// A held transfer, split or reservation is carried out by the ledger,
+			// which credits its payees too
+			balance, credited, err = postings().Approve(ctx, store.NewPostgresTx(tx), customerID, transactionID)
+			if err != nil {
+				switch {
+				case errors.Is(err, ledger.ErrInsufficientBalance), errors.Is(err, ledger.ErrOverpayment):
+					respondBalanceError(c, err)
+				case errors.Is(err, ledger.ErrNotAwaitingApproval):
+					respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction is not awaiting approval"})
+				default:
+					respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to approve transfer"})
+				}
+				return
+			}
+		} else {
+			// Approved postings keep the zero floor on accounts allowed to go negative
+			account.AllowNegative = false
+			if balance, err = ledger.Apply(account, directionOf(txType), amount); err != nil {
+				respondBalanceError(c, err)
+				return
+			}
+			if _, err := tx.Exec(ctx,
+				"UPDATE customers SET balance = $1 WHERE id = $2",
+				balance, customerID); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update balance"})
+				return
+			}
+			if _, err := tx.Exec(ctx,
+				"UPDATE transactions SET status = 'posted' WHERE id = $1",
+				transactionID); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update transaction"})
+				return
+			}
+			if err := postCounterparty(ctx, tx, transactionID, txType, amount); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post general ledger entry"})
+				return
+			}
+			if err := postReleasedRoundingDifference(ctx, tx, transactionID); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post rounding difference"})
+				return
+			}
+		}
+		status = "posted"
+		if err := enqueueEvent(ctx, tx, events.TransactionPosted, &customerID, TransactionEventData{
+			TransactionID: transactionID,
+			Type:          txType,
+			Amount:        amount,
+			Status:        status,
+		}); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record event"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, append(credited, customerID)...)
+
+	c.JSON(http.StatusOK, ApprovalResponse{
+		TransactionID:     transactionID,
+		Status:            status,
+		Approvals:         approvals,
+		RequiredApprovals: required,
+		Balance:           balance,
+	})
+}
+
+// @Summary Reject a pending transaction
+// @Description Reject an escrow withdrawal, transfer, split transfer or reservation awaiting approval so it never posts
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param transaction_id path string true "Transaction ID" format(uuid)
+// @Success 200 {object} ApprovalResponse "Transaction rejected"
+// @Failure 400 {object} ErrorResponse "Invalid transaction ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Transaction not awaiting approval"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/transactions/{transaction_id}/reject [post]
+func RejectPendingTransaction(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("transaction_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var customerID uuid.UUID
+	var transferID, batchID *uuid.UUID
+	var txType string
+	var amount float64
+	err = tx.QueryRow(ctx,
+		"UPDATE transactions SET status = 'rejected' WHERE id = $1 AND status = 'pending_approval' RETURNING customer_id, type, amount, transfer_id, batch_id",
+		transactionID).Scan(&customerID, &txType, &amount, &transferID, &batchID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Transaction not awaiting approval"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to reject transaction"})
+		}
+		return
+	}
+	// A held transfer, split or reservation is rejected with its payer's debit
+	if txType == "transfer_out" {
+		if _, err := tx.Exec(ctx,
+			"UPDATE transfers SET status = 'rejected', closed_at = NOW() WHERE status = 'pending_approval' AND (id = $1 OR batch_id = $2)",
+			transferID, batchID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to reject transfer"})
+			return
+		}
+	}
+	if err := enqueueEvent(ctx, tx, events.TransactionRejected, &customerID, TransactionEventData{
+		TransactionID: transactionID,
+		Type:          txType,
+		Amount:        amount,
+		Status:        "rejected",
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record event"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ApprovalResponse{
+		TransactionID: transactionID,
+		Status:        "rejected",
+	})
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+	"ledger-service/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransactionAccountPolicies(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitAccountPolicies(policy.Default(3))
+	defer InitAccountPolicies(nil)
+
+	router.POST("/transactions", CreateTransaction)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name        string
+		accountType string
+		wantStatus  int
+		setupMock   func()
+	}{
+		{
+			name:        "savings debit over monthly limit",
+			accountType: "savings",
+			wantStatus:  http.StatusForbidden,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE customer_id = \$1 AND \(type = 'transfer_out' OR type IN \(SELECT code FROM transaction_types WHERE postable AND direction = 'debit'\)\) AND status = 'posted' AND created_at >= date_trunc\('month', NOW\(\), \(SELECT timezone FROM customers WHERE id = \$1\)\)`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:        "escrow debit awaits approval",
+			accountType: "escrow",
+			wantStatus:  http.StatusAccepted,
+			setupMock: func() {
+				mock.ExpectExec(`INSERT INTO transactions`).
+					WithArgs(pgxmock.AnyArg(), customerID, "debit", float64(100), "pending_approval").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionHeld)
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockCustomerQuery).
+				WithArgs(customerID).
+				WillReturnRows(lockedCustomer(float64(1000), tt.accountType, false))
+			expectNoDebitLimits(customerID)
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "debit",
+				"amount":      100,
+			})
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestApproveTransaction(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitAccountPolicies(policy.Default(0))
+	defer InitAccountPolicies(nil)
+
+	router.POST("/admin/transactions/:transaction_id/approve", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, c.GetHeader("X-Actor"))
+	}, ApproveTransaction)
+
+	transactionID := uuid.New()
+	customerID := uuid.New()
+	expectPending := func(approver string, approvals int) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT customer_id, type, amount, status FROM transactions WHERE id = \$1 FOR UPDATE`).
+			WithArgs(transactionID).
+			WillReturnRows(pgxmock.NewRows([]string{"customer_id", "type", "amount", "status"}).
+				AddRow(customerID, "debit", float64(100), "pending_approval"))
+		mock.ExpectExec(`INSERT INTO transaction_approvals`).
+			WithArgs(transactionID, approver).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transaction_approvals WHERE transaction_id = \$1`).
+			WithArgs(transactionID).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(approvals))
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(customerID).
+			WillReturnRows(lockedCustomer(float64(1000), "escrow", false))
+	}
+
+	approve := func(approver string) ApprovalResponse {
+		req := httptest.NewRequest("POST", "/admin/transactions/"+transactionID.String()+"/approve", nil)
+		req.Header.Set("X-Actor", approver)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp ApprovalResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// First approval leaves the debit pending
+	expectPending("alice", 1)
+	mock.ExpectCommit()
+	resp := approve("alice")
+	assert.Equal(t, "pending_approval", resp.Status)
+	assert.Equal(t, 2, resp.RequiredApprovals)
+
+	// Second distinct approval posts it
+	expectPending("bob", 2)
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(float64(900), customerID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE transactions SET status = 'posted' WHERE id = \$1`).
+		WithArgs(transactionID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectUnconverted(transactionID)
+	expectEvent(events.TransactionPosted)
+	mock.ExpectCommit()
+	resp = approve("bob")
+	assert.Equal(t, "posted", resp.Status)
+	assert.Equal(t, float64(900), resp.Balance)
+
+	// Repeat approval by the same operator is refused
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT customer_id, type, amount, status FROM transactions WHERE id = \$1 FOR UPDATE`).
+		WithArgs(transactionID).
+		WillReturnRows(pgxmock.NewRows([]string{"customer_id", "type", "amount", "status"}).
+			AddRow(customerID, "debit", float64(100), "pending_approval"))
+	mock.ExpectExec(`INSERT INTO transaction_approvals`).
+		WithArgs(transactionID, "alice").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectRollback()
+	req := httptest.NewRequest("POST", "/admin/transactions/"+transactionID.String()+"/approve", nil)
+	req.Header.Set("X-Actor", "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// rejectedRow is what rejecting a pending transaction returns
+func rejectedRow(customerID uuid.UUID, txType string, transferID, batchID *uuid.UUID) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"customer_id", "type", "amount", "transfer_id", "batch_id"}).
+		AddRow(customerID, txType, float64(100), transferID, batchID)
+}
+
+func TestApproveHeldTransfer(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitAccountPolicies(policy.Default(0))
+	defer InitAccountPolicies(nil)
+
+	router.POST("/admin/transactions/:transaction_id/approve", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, c.GetHeader("X-Actor"))
+	}, ApproveTransaction)
+
+	transactionID, transferID := uuid.New(), uuid.New()
+	customerID, payeeID := uuid.New(), uuid.New()
+	createdAt := time.Date(2025, 4, 8, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT customer_id, type, amount, status FROM transactions WHERE id = \$1 FOR UPDATE`).
+		WithArgs(transactionID).
+		WillReturnRows(pgxmock.NewRows([]string{"customer_id", "type", "amount", "status"}).
+			AddRow(customerID, "transfer_out", float64(100), "pending_approval"))
+	mock.ExpectExec(`INSERT INTO transaction_approvals`).
+		WithArgs(transactionID, "bob").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transaction_approvals WHERE transaction_id = \$1`).
+		WithArgs(transactionID).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(lockCustomerQuery).
+		WithArgs(customerID).
+		WillReturnRows(lockedCustomer(float64(1000), "escrow", false))
+
+	// The ledger carries out the held transfer: the payer is debited, the
+	// payee credited and the transfer completed
+	mock.ExpectQuery(`FROM transactions WHERE id = \$1 AND customer_id = \$2`).
+		WithArgs(transactionID, customerID).
+		WillReturnRows(transactionRows().AddRow(transactionID, "transfer_out", float64(100), "pending_approval", createdAt, (*time.Time)(nil), createdAt, (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), (*string)(nil), false))
+	mock.ExpectQuery(`SELECT id, from_customer_id, to_customer_id, amount, reference, batch_id, status, approved_status, created_at FROM transfers WHERE id = \(SELECT transfer_id FROM transactions WHERE id = \$1\) OR batch_id = \(SELECT batch_id FROM transactions WHERE id = \$1\) ORDER BY created_at, id FOR UPDATE`).
+		WithArgs(transactionID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "from_customer_id", "to_customer_id", "amount", "reference", "batch_id", "status", "approved_status", "created_at"}).
+			AddRow(transferID, customerID, payeeID, float64(100), (*string)(nil), (*uuid.UUID)(nil), "pending_approval", &[]string{"completed"}[0], createdAt))
+	expectTransferLocks(customerID, payeeID, 1000, 5)
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(float64(900), customerID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE transactions SET status = \$2 WHERE id = \$1`).
+		WithArgs(transactionID, "posted").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE transfers SET status = \$2`).
+		WithArgs(transferID, "completed").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status, transfer_id\)`).
+		WithArgs(pgxmock.AnyArg(), payeeID, "transfer_in", float64(100), "posted", transferID).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(float64(105), payeeID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectEvent(events.TransferCompleted)
+	expectEvent(events.TransactionPosted)
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest("POST", "/admin/transactions/"+transactionID.String()+"/approve", nil)
+	req.Header.Set("X-Actor", "bob")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp ApprovalResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "posted", resp.Status)
+	assert.Equal(t, float64(900), resp.Balance)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRejectPendingTransaction(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/transactions/:transaction_id/reject", RejectPendingTransaction)
+
+	transactionID := uuid.New()
+	customerID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE transactions SET status = 'rejected' WHERE id = \$1 AND status = 'pending_approval' RETURNING customer_id, type, amount, transfer_id, batch_id`).
+		WithArgs(transactionID).
+		WillReturnRows(rejectedRow(customerID, "debit", nil, nil))
+	expectEvent(events.TransactionRejected)
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest("POST", "/admin/transactions/"+transactionID.String()+"/reject", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Rejecting a held split rejects every transfer of its batch
+	batchID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE transactions SET status = 'rejected'`).
+		WithArgs(transactionID).
+		WillReturnRows(rejectedRow(customerID, "transfer_out", nil, &batchID))
+	mock.ExpectExec(`UPDATE transfers SET status = 'rejected', closed_at = NOW\(\) WHERE status = 'pending_approval' AND \(id = \$1 OR batch_id = \$2\)`).
+		WithArgs((*uuid.UUID)(nil), &batchID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	expectEvent(events.TransactionRejected)
+	mock.ExpectCommit()
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/transactions/"+transactionID.String()+"/reject", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Nothing awaiting approval
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE transactions SET status = 'rejected'`).
+		WithArgs(transactionID).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	req = httptest.NewRequest("POST", "/admin/transactions/"+transactionID.String()+"/reject", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer is satisfied by both the connection and an open transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+// recordAudit appends an entry to the audit log. Callers pass their open
+// transaction so the entry commits or rolls back with the change it describes.
+func recordAudit(ctx context.Context, q execer, actor, action, entityType string, entityID uuid.UUID, customerID *uuid.UUID, details interface{}) error {
+	payload, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		"INSERT INTO audit_log (id, actor, action, entity_type, entity_id, customer_id, details) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		uuid.New(), actor, action, entityType, entityID, customerID, payload)
+	return err
+}
+
+// AuditEntry is one change recorded in the audit log
+// @Description Who changed what, and when
+type AuditEntry struct {
+	ID         uuid.UUID       `json:"id" format:"uuid"`
+	Actor      string          `json:"actor" example:"alice"`
+	Action     string          `json:"action" example:"adjustment.created"`
+	EntityType string          `json:"entity_type" example:"adjustment"`
+	EntityID   uuid.UUID       `json:"entity_id" format:"uuid"`
+	CustomerID *uuid.UUID      `json:"customer_id,omitempty" format:"uuid"`
+	Details    json.RawMessage `json:"details" swaggertype:"object"`
+	CreatedAt  string          `json:"created_at" example:"2025-04-08T17:09:17Z"`
+}
+
+// auditFormats are the representations an audit query can be returned in.
+// json pages through the log; csv and jsonl export every matching entry.
+var auditFormats = map[string]string{
+	"json":  "application/json",
+	"csv":   "text/csv",
+	"jsonl": "application/x-ndjson",
+}
+
+// auditColumns lists the audit_log columns in the order scanAuditEntry reads them
+const auditColumns = "id, actor, action, entity_type, entity_id, customer_id, details, created_at"
+
+// auditWhere matches the arguments built by parseAuditFilter
+const auditWhere = "($1 = '' OR actor = $1) AND ($2 = '' OR entity_type = $2) AND ($3::uuid IS NULL OR entity_id = $3) AND ($4::timestamptz IS NULL OR created_at >= $4) AND ($5::timestamptz IS NULL OR created_at < $5) AND ($6::uuid IS NULL OR customer_id = $6)"
+
+func scanAuditEntry(row pgx.Row) (AuditEntry, error) {
+	var e AuditEntry
+	var details []byte
+	var createdAt time.Time
+	if err := row.Scan(&e.ID, &e.Actor, &e.Action, &e.EntityType, &e.EntityID, &e.CustomerID, &details, &createdAt); err != nil {
+		return e, err
+	}
+	e.Details = details
+	e.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return e, nil
+}
+
+// parseAuditFilter reads the actor, entity, entity_id, from and to query
+// parameters into auditWhere's arguments, writing a 400 response and
+// returning false when one is malformed
+func parseAuditFilter(c *gin.Context, customerID *uuid.UUID) ([]interface{}, bool) {
+	var entityID *uuid.UUID
+	if value := c.Query("entity_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid entity_id"})
+			return nil, false
+		}
+		entityID = &id
+	}
+	var from, to *time.Time
+	for _, p := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid " + p.name + ": must be an RFC 3339 time"})
+			return nil, false
+		}
+		*p.dest = &t
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid time range: from must be before to"})
+		return nil, false
+	}
+	return []interface{}{c.Query("actor"), c.Query("entity"), entityID, from, to, customerID}, true
+}
+
+// @Summary Query the audit log
+// @Description Search every recorded change by actor, entity and time, most recent first. format=csv or format=jsonl exports all matching entries instead of a page; exports are themselves audited.
+// @Tags admin
+// @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param actor query string false "Only changes made by this actor"
+// @Param entity query string false "Only changes to this entity type" example(adjustment)
+// @Param entity_id query string false "Only changes to this entity" format(uuid)
+// @Param from query string false "Changes at or after this time (RFC 3339)" format(date-time)
+// @Param to query string false "Changes before this time (RFC 3339)" format(date-time)
+// @Param format query string false "Response format" Enums(json, csv, jsonl) default(json)
+// @Param page query int false "Page number (1-based)" minimum(1) default(1)
+// @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Success 200 {array} AuditEntry "Audit entries"
+// @Failure 400 {object} ErrorResponse "Invalid filter, format or pagination parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Header 200 {string} X-Total-Count "Total number of entries"
+// @Header 200 {string} X-Page "Current page number"
+// @Header 200 {string} X-Page-Size "Items per page"
+// @Header 200 {string} X-Total-Pages "Total number of pages"
+// @Router /admin/audit [get]
+func ListAuditLog(c *gin.Context) {
+	queryAuditLog(c, nil)
+}
+
+// @Summary Get a customer's audit trail
+// @Description List every recorded change to the customer's account, most recent first. Accepts the same filters and export formats as the audit log query.
+// @Tags admin
+// @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param actor query string false "Only changes made by this actor"
+// @Param entity query string false "Only changes to this entity type" example(adjustment)
+// @Param entity_id query string false "Only changes to this entity" format(uuid)
+// @Param from query string false "Changes at or after this time (RFC 3339)" format(date-time)
+// @Param to query string false "Changes before this time (RFC 3339)" format(date-time)
+// @Param format query string false "Response format" Enums(json, csv, jsonl) default(json)
+// @Param page query int false "Page number (1-based)" minimum(1) default(1)
+// @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Success 200 {array} AuditEntry "Audit entries"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID, filter, format or pagination parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Header 200 {string} X-Total-Count "Total number of entries"
+// @Header 200 {string} X-Page "Current page number"
+// @Header 200 {string} X-Page-Size "Items per page"
+// @Header 200 {string} X-Total-Pages "Total number of pages"
+// @Router /admin/customers/{customer_id}/audit [get]
+func GetCustomerAuditLog(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var exists bool
+	if err := db.QueryRow(c.Request.Context(),
+		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+		customerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	queryAuditLog(c, &customerID)
+}
+
+// queryAuditLog answers an audit query, limited to one customer's entries
+// when customerID is set
+func queryAuditLog(c *gin.Context, customerID *uuid.UUID) {
+	format := c.DefaultQuery("format", "json")
+	if _, ok := auditFormats[format]; !ok {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid format: must be json, csv or jsonl"})
+		return
+	}
+	args, ok := parseAuditFilter(c, customerID)
+	if !ok {
+		return
+	}
+	if format != "json" {
+		exportAuditLog(c, format, customerID, args)
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var totalCount int
+	if err := db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_log WHERE "+auditWhere, args...).Scan(&totalCount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get total count"})
+		return
+	}
+
+	rows, err := db.Query(ctx,
+		"SELECT "+auditColumns+" FROM audit_log WHERE "+auditWhere+" ORDER BY created_at DESC, id LIMIT $7 OFFSET $8",
+		append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch audit log"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan audit entry"})
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	c.Header("X-Total-Count", fmt.Sprintf("%d", totalCount))
+	c.Header("X-Page", fmt.Sprintf("%d", page))
+	c.Header("X-Page-Size", fmt.Sprintf("%d", pageSize))
+	c.Header("X-Total-Pages", fmt.Sprintf("%d", (totalCount+pageSize-1)/pageSize))
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// exportAuditLog streams every entry matching args, oldest first, as CSV or
+// JSON lines, then records the export itself in the audit log
+func exportAuditLog(c *gin.Context, format string, customerID *uuid.UUID, args []interface{}) {
+	ctx := c.Request.Context()
+	rows, err := db.Query(ctx,
+		"SELECT "+auditColumns+" FROM audit_log WHERE "+auditWhere+" ORDER BY created_at, id",
+		args...)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch audit log"})
+		return
+	}
+	defer rows.Close()
+
+	exportID := uuid.New()
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Type", auditFormats[format])
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("X-Export-ID", exportID.String())
+	c.Status(http.StatusOK)
+
+	// As with ledger exports, a failure once streaming has started leaves a
+	// truncated file and is only logged
+	csvWriter := csv.NewWriter(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	if format == "csv" {
+		csvWriter.Write([]string{"id", "created_at", "actor", "action", "entity_type", "entity_id", "customer_id", "details"})
+	}
+	count := 0
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			log.Printf("Audit export %s failed: %v", exportID, err)
+			return
+		}
+		if format == "csv" {
+			customer := ""
+			if e.CustomerID != nil {
+				customer = e.CustomerID.String()
+			}
+			err = csvWriter.Write([]string{e.ID.String(), e.CreatedAt, e.Actor, e.Action, e.EntityType, e.EntityID.String(), customer, string(e.Details)})
+		} else {
+			err = encoder.Encode(e)
+		}
+		if err != nil {
+			log.Printf("Audit export %s failed: %v", exportID, err)
+			return
+		}
+		count++
+	}
+	csvWriter.Flush()
+	if err := rows.Err(); err != nil {
+		log.Printf("Audit export %s failed: %v", exportID, err)
+		return
+	}
+	if err := csvWriter.Error(); err != nil {
+		log.Printf("Audit export %s failed: %v", exportID, err)
+		return
+	}
+
+	actor := c.GetString(middleware.ActorKey)
+	if err := recordAudit(ctx, db, actor, "audit.exported", "export", exportID, customerID, map[string]interface{}{
+		"format": format,
+		"query":  c.Request.URL.RawQuery,
+		"rows":   count,
+	}); err != nil {
+		log.Printf("Failed to audit audit log export %s: %v", exportID, err)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var auditRowColumns = []string{"id", "actor", "action", "entity_type", "entity_id", "customer_id", "details", "created_at"}
+
+func TestListAuditLog(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/audit", ListAuditLog)
+
+	customerID, entityID := uuid.New(), uuid.New()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2025, 4, 8, 17, 9, 17, 0, time.UTC)
+	noID, noTime := (*uuid.UUID)(nil), (*time.Time)(nil)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+		setupMock  func()
+	}{
+		{
+			name:       "filters by actor and entity",
+			query:      "?actor=alice&entity=adjustment&from=2025-04-01T00:00:00Z&page_size=5",
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM audit_log WHERE \(\$1 = '' OR actor = \$1\)`).
+					WithArgs("alice", "adjustment", noID, &from, noTime, noID).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(`SELECT id, actor, action, entity_type, entity_id, customer_id, details, created_at FROM audit_log WHERE .* ORDER BY created_at DESC, id LIMIT \$7 OFFSET \$8`).
+					WithArgs("alice", "adjustment", noID, &from, noTime, noID, 5, 0).
+					WillReturnRows(pgxmock.NewRows(auditRowColumns).
+						AddRow(uuid.New(), "alice", "adjustment.created", "adjustment", entityID, &customerID, []byte(`{"reason_code":"goodwill"}`), createdAt))
+			},
+		},
+		{
+			name:       "exports csv",
+			query:      "?format=csv&entity_id=" + entityID.String(),
+			wantStatus: http.StatusOK,
+			wantBody:   "id,created_at,actor,action,entity_type,entity_id,customer_id,details\n",
+			setupMock: func() {
+				mock.ExpectQuery(`FROM audit_log WHERE .* ORDER BY created_at, id`).
+					WithArgs("", "", &entityID, noTime, noTime, noID).
+					WillReturnRows(pgxmock.NewRows(auditRowColumns).
+						AddRow(uuid.New(), "alice", "adjustment.created", "adjustment", entityID, &customerID, []byte(`{"reason_code":"goodwill"}`), createdAt))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "", "audit.exported", "export", pgxmock.AnyArg(), noID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name:       "invalid format",
+			query:      "?format=xml",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "invalid time",
+			query:      "?from=yesterday",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "from after to",
+			query:      "?from=2025-04-08T00:00:00Z&to=2025-04-01T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			req := httptest.NewRequest("GET", "/admin/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.True(t, strings.HasPrefix(w.Body.String(), tt.wantBody))
+				assert.Contains(t, w.Body.String(), `"{""reason_code"":""goodwill""}"`)
+			} else if tt.wantStatus == http.StatusOK {
+				var entries []AuditEntry
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+				assert.Len(t, entries, 1)
+				assert.Equal(t, "2025-04-08T17:09:17Z", entries[0].CreatedAt)
+				assert.Equal(t, "1", w.Header().Get("X-Total-Count"))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetCustomerAuditLog(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/customers/:customer_id/audit", GetCustomerAuditLog)
+
+	customerID := uuid.New()
+	noID, noTime := (*uuid.UUID)(nil), (*time.Time)(nil)
+
+	t.Run("lists the customer's entries", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM customers WHERE id = \$1\)`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM audit_log`).
+			WithArgs("", "", noID, noTime, noTime, &customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`FROM audit_log WHERE .* LIMIT \$7 OFFSET \$8`).
+			WithArgs("", "", noID, noTime, noTime, &customerID, 10, 0).
+			WillReturnRows(pgxmock.NewRows(auditRowColumns))
+
+		req := httptest.NewRequest("GET", "/admin/customers/"+customerID.String()+"/audit", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+
+		req := httptest.NewRequest("GET", "/admin/customers/"+customerID.String()+"/audit", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PeriodCloseRequest closes the books through a date
+type PeriodCloseRequest struct {
+	ClosedThrough string `json:"closed_through" binding:"required" example:"2025-03-31" format:"date"`
+}
+
+// PeriodClose is a closed accounting period
+// @Description Accounting period closed through a date
+type PeriodClose struct {
+	ID            uuid.UUID `json:"id" format:"uuid"`
+	ClosedThrough string    `json:"closed_through" example:"2025-03-31" format:"date"`
+	ClosedBy      string    `json:"closed_by" example:"jane.doe"`
+	ClosedAt      string    `json:"closed_at" example:"2025-04-02T08:00:00Z" format:"date-time"`
+}
+
+// BackdatedTransactionRequest posts a transaction at a historical time
+type BackdatedTransactionRequest struct {
+	CustomerID    uuid.UUID `json:"customer_id" binding:"required,uuid" format:"uuid"`
+	Type          string    `json:"type" binding:"required" example:"credit"`
+	Amount        float64   `json:"amount" binding:"required,money" example:"250" minimum:"0.01"`
+	Timestamp     time.Time `json:"timestamp" binding:"required" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	Justification string    `json:"justification" binding:"required,min=10,max=1000" example:"Opening balance migrated from the legacy core" minLength:"10" maxLength:"1000"`
+}
+
+// BackdatedTransactionResponse describes a posted backdated transaction
+// @Description Posted backdated transaction
+type BackdatedTransactionResponse struct {
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	CustomerID    uuid.UUID `json:"customer_id" format:"uuid"`
+	Type          string    `json:"type" example:"credit"`
+	Amount        float64   `json:"amount" example:"250"`
+	Timestamp     string    `json:"timestamp" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	ValueDate     string    `json:"value_date" example:"2025-04-08" format:"date"`
+	RecordedAt    string    `json:"recorded_at" example:"2025-04-10T09:30:00Z" format:"date-time"`
+	Actor         string    `json:"actor" example:"jane.doe"`
+	Balance       float64   `json:"balance" example:"350"`
+}
+
+// latestPeriodClose returns the date the books are closed through, or nil
+// when no period has been closed. The row is locked FOR mode (SHARE or
+// UPDATE) so a backdated posting and a close cannot interleave.
+func latestPeriodClose(ctx context.Context, tx pgx.Tx, mode string) (*time.Time, error) {
+	var closedThrough time.Time
+	err := tx.QueryRow(ctx,
+		"SELECT closed_through FROM period_closes ORDER BY closed_through DESC LIMIT 1 FOR "+mode).Scan(&closedThrough)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &closedThrough, nil
+}
+
+// @Summary List closed accounting periods
+// @Description List every period close, latest first. Nothing can be backdated on or before the latest closed_through date.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} PeriodClose "Period closes"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/period-closes [get]
+func ListPeriodCloses(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT id, closed_through, closed_by, closed_at FROM period_closes ORDER BY closed_through DESC")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list period closes"})
+		return
+	}
+	defer rows.Close()
+
+	closes := []PeriodClose{}
+	for rows.Next() {
+		var p PeriodClose
+		var closedThrough, closedAt time.Time
+		if err := rows.Scan(&p.ID, &closedThrough, &p.ClosedBy, &closedAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan period close"})
+			return
+		}
+		p.ClosedThrough = closedThrough.Format(dateLayout)
+		p.ClosedAt = closedAt.Format(time.RFC3339)
+		closes = append(closes, p)
+	}
+
+	c.JSON(http.StatusOK, closes)
+}
+
+// @Summary Close an accounting period
+// @Description Close the books through a past date. Closes only move forward, and once a period is closed no transaction can be backdated into it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator closing the period"
+// @Param period body PeriodCloseRequest true "Period close"
+// @Success 201 {object} PeriodClose "Period closed"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 409 {object} ErrorResponse "Period already closed"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/period-closes [post]
+func ClosePeriod(c *gin.Context) {
+	var req PeriodCloseRequest
+	if !bindRequest(c, &req, "Invalid input: closed_through (YYYY-MM-DD) is required") {
+		return
+	}
+	closedThrough, err := time.Parse(dateLayout, req.ClosedThrough)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: closed_through must be in YYYY-MM-DD format"})
+		return
+	}
+	if !closedThrough.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: closed_through must be in the past"})
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	latest, err := latestPeriodClose(ctx, tx, "UPDATE")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to read period closes"})
+		return
+	}
+	if latest != nil && !closedThrough.After(*latest) {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Period is already closed through " + latest.Format(dateLayout)})
+		return
+	}
+
+	resp := PeriodClose{
+		ID:            uuid.New(),
+		ClosedThrough: req.ClosedThrough,
+		ClosedBy:      actor,
+	}
+	var closedAt time.Time
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO period_closes (id, closed_through, closed_by) VALUES ($1, $2, $3) RETURNING closed_at",
+		resp.ID, closedThrough, actor).Scan(&closedAt); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to close period"})
+		return
+	}
+	resp.ClosedAt = closedAt.Format(time.RFC3339)
+	if err := recordAudit(ctx, tx, actor, "period.closed", "period_close", resp.ID, nil, map[string]interface{}{
+		"closed_through": req.ClosedThrough,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// @Summary Post a backdated transaction
+// @Description Post a transaction at an explicit historical timestamp, for migrations and corrections. The balance changes now, but the transaction is dated and value-dated at the timestamp in history and statements, with recorded_at holding when it was actually written. Timestamps in a closed accounting period are refused, and every backdated posting is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator posting the transaction"
+// @Param transaction body BackdatedTransactionRequest true "Backdated transaction"
+// @Success 201 {object} BackdatedTransactionResponse "Transaction posted"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Timestamp falls in a closed period"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/transactions/backdated [post]
+func CreateBackdatedTransaction(c *gin.Context) {
+	var req BackdatedTransactionRequest
+	if !bindRequest(c, &req, "Invalid input: customer_id, type, amount (> 0), timestamp (RFC 3339) and justification (at least 10 characters) are required") {
+		return
+	}
+	t, ok := transactionTypes.Lookup(req.Type)
+	if !ok || !t.Postable {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: unknown transaction type " + req.Type})
+		return
+	}
+	recordedAt := time.Now().UTC()
+	timestamp := req.Timestamp.UTC()
+	if !timestamp.Before(recordedAt) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: timestamp must be in the past"})
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	latest, err := latestPeriodClose(ctx, tx, "SHARE")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to read period closes"})
+		return
+	}
+	if latest != nil && timestamp.Before(latest.AddDate(0, 0, 1)) {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Period is closed through " + latest.Format(dateLayout)})
+		return
+	}
+
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, req.CustomerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+		}
+		return
+	}
+	if msg := validatePrecision("amount", req.Amount, account.Currency); msg != "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + msg})
+		return
+	}
+	previous := account.Balance
+	balance, err := ledger.Apply(account, t.Direction, req.Amount)
+	if err != nil {
+		respondBalanceError(c, err)
+		return
+	}
+
+	resp := BackdatedTransactionResponse{
+		TransactionID: uuid.New(),
+		CustomerID:    req.CustomerID,
+		Type:          req.Type,
+		Amount:        req.Amount,
+		Timestamp:     timestamp.Format(time.RFC3339),
+		ValueDate:     timestamp.Format(dateLayout),
+		RecordedAt:    recordedAt.Format(time.RFC3339),
+		Actor:         actor,
+		Balance:       balance,
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE customers SET balance = $1 WHERE id = $2",
+		resp.Balance, req.CustomerID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update balance"})
+		return
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO transactions (id, customer_id, type, amount, status, created_at, recorded_at, value_date) VALUES ($1, $2, $3, $4, 'posted', $5, $6, $7)",
+		resp.TransactionID, req.CustomerID, req.Type, req.Amount, timestamp, recordedAt, timestamp.Truncate(24*time.Hour)); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create transaction"})
+		return
+	}
+	if err := postCounterparty(ctx, tx, resp.TransactionID, req.Type, req.Amount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post general ledger entry"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "transaction.backdated", "transaction", resp.TransactionID, &req.CustomerID, map[string]interface{}{
+		"type":             req.Type,
+		"amount":           req.Amount,
+		"timestamp":        resp.Timestamp,
+		"justification":    req.Justification,
+		"previous_balance": previous,
+		"new_balance":      resp.Balance,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := enqueueEvent(ctx, tx, events.TransactionPosted, &req.CustomerID, TransactionEventData{
+		TransactionID: resp.TransactionID,
+		Type:          req.Type,
+		Amount:        req.Amount,
+		Status:        ledger.StatusPosted,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record event"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, req.CustomerID)
+
+	c.JSON(http.StatusCreated, resp)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+const latestCloseQuery = `SELECT closed_through FROM period_closes ORDER BY closed_through DESC LIMIT 1 FOR`
+
+func TestCreateBackdatedTransaction(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/transactions/backdated", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "jane")
+	}, CreateBackdatedTransaction)
+
+	customerID := uuid.New()
+	timestamp := time.Date(2025, 4, 8, 17, 9, 17, 0, time.UTC)
+	payload := func(txType string, amount float64, at time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"customer_id":   customerID,
+			"type":          txType,
+			"amount":        amount,
+			"timestamp":     at.Format(time.RFC3339),
+			"justification": "Opening balance migrated from the legacy core",
+		}
+	}
+	expectClosedThrough := func(closed *time.Time) {
+		mock.ExpectBegin()
+		rows := pgxmock.NewRows([]string{"closed_through"})
+		if closed != nil {
+			rows.AddRow(*closed)
+		}
+		mock.ExpectQuery(latestCloseQuery + ` SHARE`).WillReturnRows(rows)
+	}
+	march := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		payload     map[string]interface{}
+		wantStatus  int
+		wantBalance float64
+		setupMock   func()
+	}{
+		{
+			name:        "credit after the closed period",
+			payload:     payload("credit", 250, timestamp),
+			wantStatus:  http.StatusCreated,
+			wantBalance: 350,
+			setupMock: func() {
+				expectClosedThrough(&march)
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(100, "checking", false))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(350), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status, created_at, recorded_at, value_date\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "credit", float64(250), timestamp, pgxmock.AnyArg(), april).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "jane", "transaction.backdated", "transaction", pgxmock.AnyArg(), &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "timestamp in a closed period",
+			payload:    payload("credit", 250, timestamp),
+			wantStatus: http.StatusConflict,
+			setupMock:  func() { expectClosedThrough(&april) },
+		},
+		{
+			name:       "debit beyond balance",
+			payload:    payload("debit", 150, timestamp),
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				expectClosedThrough(nil)
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(100, "checking", false))
+			},
+		},
+		{
+			name:       "timestamp in the future",
+			payload:    payload("credit", 250, time.Now().Add(time.Hour)),
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "type that cannot be posted directly",
+			payload:    payload("adjustment_credit", 250, timestamp),
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/transactions/backdated", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp BackdatedTransactionResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantBalance, resp.Balance)
+				assert.Equal(t, "2025-04-08T17:09:17Z", resp.Timestamp)
+				assert.NotEqual(t, resp.Timestamp, resp.RecordedAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClosePeriod(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/period-closes", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "jane")
+	}, ClosePeriod)
+
+	march := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		through    string
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "moves the close forward",
+			through:    "2025-04-30",
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(latestCloseQuery + ` UPDATE`).
+					WillReturnRows(pgxmock.NewRows([]string{"closed_through"}).AddRow(march))
+				mock.ExpectQuery(`INSERT INTO period_closes \(id, closed_through, closed_by\)`).
+					WithArgs(pgxmock.AnyArg(), time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC), "jane").
+					WillReturnRows(pgxmock.NewRows([]string{"closed_at"}).AddRow(time.Now()))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "jane", "period.closed", "period_close", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "already closed",
+			through:    "2025-03-15",
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(latestCloseQuery + ` UPDATE`).
+					WillReturnRows(pgxmock.NewRows([]string{"closed_through"}).AddRow(march))
+			},
+		},
+		{
+			name:       "today is still open",
+			through:    time.Now().UTC().Format(dateLayout),
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "not a date",
+			through:    "31/03/2025",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(map[string]string{"closed_through": tt.through})
+			req := httptest.NewRequest("POST", "/admin/period-closes", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ledger-service/backup"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLedger(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/export", ExportLedger)
+
+	t.Run("streams a JSONL snapshot", func(t *testing.T) {
+		mock.ExpectBeginTx(backup.SnapshotOptions)
+		for _, table := range backup.TableNames() {
+			rows := pgxmock.NewRows([]string{"row_to_json"})
+			if table == "customers" {
+				rows.AddRow(`{"id":"c1","name":"John Doe","balance":100}`)
+			}
+			mock.ExpectQuery(`FROM ` + table + ` t`).WillReturnRows(rows)
+		}
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), "ledger.exported", "export", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectRollback()
+
+		req := httptest.NewRequest("GET", "/admin/export", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), `attachment; filename="ledger-`)
+		assert.NotEmpty(t, w.Header().Get("X-Export-ID"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[1], `"table":"customers"`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/export?format=csv", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+)
+
+// BalanceCache holds customer balances for GetBalance so polling clients do
+// not hit the primary on every read
+type BalanceCache interface {
+	Get(ctx context.Context, customerID uuid.UUID) (store.Balance, bool, error)
+	Set(ctx context.Context, customerID uuid.UUID, balance store.Balance) error
+	Invalidate(ctx context.Context, customerIDs ...uuid.UUID) error
+}
+
+var balanceCache BalanceCache
+
+// InitBalanceCache sets the balance cache; nil reads balances from Postgres
+// every time
+func InitBalanceCache(c BalanceCache) {
+	balanceCache = c
+}
+
+// readBalance returns a customer's balance from the cache, falling through to
+// Postgres on a miss or cache error and caching what it read
+func readBalance(ctx context.Context, customerID uuid.UUID) (store.Balance, error) {
+	if balanceCache != nil {
+		balance, ok, err := balanceCache.Get(ctx, customerID)
+		if err != nil {
+			log.Printf("Balance cache read failed for customer %s: %v", customerID, err)
+		} else if ok {
+			return balance, nil
+		}
+	}
+
+	balance, err := postings().Balance(ctx, customerID)
+	if err != nil {
+		return store.Balance{}, err
+	}
+	if balanceCache != nil {
+		if err := balanceCache.Set(ctx, customerID, balance); err != nil {
+			log.Printf("Balance cache write failed for customer %s: %v", customerID, err)
+		}
+	}
+	return balance, nil
+}
+
+// invalidateBalances drops cached balances once a change to them has
+// committed; deleting any earlier would let a concurrent miss re-cache the
+// old value. A read racing the delete, or a failed delete, leaves a stale
+// entry only until its TTL.
+func invalidateBalances(ctx context.Context, customerIDs ...uuid.UUID) {
+	if balanceCache == nil {
+		return
+	}
+	if err := balanceCache.Invalidate(context.WithoutCancel(ctx), customerIDs...); err != nil {
+		log.Printf("Balance cache invalidation failed for customers %v: %v", customerIDs, err)
+	}
+}
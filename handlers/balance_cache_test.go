@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBalanceCache struct {
+	balances    map[uuid.UUID]store.Balance
+	err         error
+	invalidated []uuid.UUID
+}
+
+func (f *fakeBalanceCache) Get(ctx context.Context, customerID uuid.UUID) (store.Balance, bool, error) {
+	if f.err != nil {
+		return store.Balance{}, false, f.err
+	}
+	balance, ok := f.balances[customerID]
+	return balance, ok, nil
+}
+
+func (f *fakeBalanceCache) Set(ctx context.Context, customerID uuid.UUID, balance store.Balance) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.balances[customerID] = balance
+	return nil
+}
+
+func (f *fakeBalanceCache) Invalidate(ctx context.Context, customerIDs ...uuid.UUID) error {
+	f.invalidated = append(f.invalidated, customerIDs...)
+	for _, id := range customerIDs {
+		delete(f.balances, id)
+	}
+	return f.err
+}
+
+func TestGetBalanceCached(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	defer InitBalanceCache(nil)
+
+	router.GET("/customers/:customer_id/balance", GetBalance)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name        string
+		cache       *fakeBalanceCache
+		wantBalance float64
+		wantCached  bool
+		setupMock   func()
+	}{
+		{
+			name:        "hit skips the database",
+			cache:       &fakeBalanceCache{balances: map[uuid.UUID]store.Balance{customerID: {Amount: 420, Currency: "USD"}}},
+			wantBalance: 420,
+			wantCached:  true,
+			setupMock:   func() {},
+		},
+		{
+			name:        "miss reads through and fills the cache",
+			cache:       &fakeBalanceCache{balances: map[uuid.UUID]store.Balance{}},
+			wantBalance: 1000,
+			wantCached:  true,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT balance, currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(1000), "USD"))
+			},
+		},
+		{
+			name:        "cache failure falls through to the database",
+			cache:       &fakeBalanceCache{balances: map[uuid.UUID]store.Balance{}, err: errors.New("connection refused")},
+			wantBalance: 1000,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT balance, currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(1000), "USD"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			InitBalanceCache(tt.cache)
+			tt.setupMock()
+			req := httptest.NewRequest("GET", "/customers/"+customerID.String()+"/balance", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantBalance, response["balance"])
+			_, cached := tt.cache.balances[customerID]
+			assert.Equal(t, tt.wantCached, cached)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestInvalidateBalances(t *testing.T) {
+	defer InitBalanceCache(nil)
+	payer, payee := uuid.New(), uuid.New()
+
+	// No cache configured
+	InitBalanceCache(nil)
+	invalidateBalances(context.Background(), payer)
+
+	cache := &fakeBalanceCache{balances: map[uuid.UUID]store.Balance{payer: {Amount: 10}, payee: {Amount: 20}}}
+	InitBalanceCache(cache)
+	invalidateBalances(context.Background(), payer, payee)
+	assert.Equal(t, []uuid.UUID{payer, payee}, cache.invalidated)
+	assert.Empty(t, cache.balances)
+
+	// Failures are logged, not returned; the entry lapses at its TTL
+	cache.err = errors.New("timeout")
+	invalidateBalances(context.Background(), payer)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ledger-service/attest"
+	"ledger-service/ledger"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	certificateSigner   attest.Signer
+	certificateIssuer   string
+	certificateValidity time.Duration
+)
+
+// InitBalanceCertificates sets the key balance certificates are signed
+// with, the issuer they name and how long they are valid; a nil signer
+// turns certificates off
+func InitBalanceCertificates(signer attest.Signer, issuer string, validity time.Duration) {
+	certificateSigner = signer
+	certificateIssuer = issuer
+	certificateValidity = validity
+}
+
+// BalanceClaims is what a balance certificate attests
+// @Description Claims of a balance certificate, as signed in its JWS payload
+type BalanceClaims struct {
+	// Issuer names the ledger that signed the certificate
+	Issuer string `json:"iss" example:"ledger-service"`
+	// Subject is the customer's ID
+	Subject string `json:"sub" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	// Name is the account holder's name
+	Name     string  `json:"name" example:"Jane Doe"`
+	Balance  float64 `json:"balance" example:"1250.5"`
+	Currency string  `json:"currency" example:"USD"`
+	// AsOf is the time the balance was held at
+	AsOf string `json:"as_of" example:"2025-04-30T23:59:59Z" format:"date-time"`
+	// IssuedAt and ExpiresAt are seconds since the Unix epoch
+	IssuedAt  int64  `json:"iat" example:"1746057600"`
+	ExpiresAt int64  `json:"exp" example:"1748649600"`
+	ID        string `json:"jti" example:"7c9e6679-7425-40de-944b-e07fc1f90ae7" format:"uuid"`
+}
+
+// BalanceCertificate is a signed attestation of a customer's balance
+// @Description Balance attestation signed as a compact JWS, with its claims decoded for convenience
+type BalanceCertificate struct {
+	// Certificate is the compact JWS to hand to the third party; its
+	// payload holds the claims
+	Certificate string `json:"certificate" example:"eyJhbGciOiJFUzI1NiIsImtpZCI6Ii4uLiIsInR5cCI6IkpXVCJ9.eyJpc3MiOiJsZWRnZXItc2VydmljZSJ9.c2ln"`
+	// KeyID names the key in the published key set that verifies it
+	KeyID  string        `json:"kid" example:"NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"`
+	Claims BalanceClaims `json:"claims"`
+}
+
+// CertificateKeys is the JSON Web Key Set that verifies certificates
+// @Description JSON Web Key Set (RFC 7517) of the keys balance certificates are signed with
+type CertificateKeys struct {
+	Keys []attest.JWK `json:"keys"`
+}
+
+// @Summary Get a balance certificate
+// @Description Get a signed attestation of a customer's balance, now or at a past time, for proof of funds. The certificate is a JWS (RFC 7515) signed with the ledger's certificate key, which may be held in a key management service; a third party checks it against the key set at /balance-certificates/keys without having to trust how the document reached them, and should check exp. The balance is read from the ledger itself, not from the balance cache.
+// @Tags customers
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param as_of query string false "Past time to attest the balance at (RFC 3339); now when omitted" format(date-time)
+// @Success 200 {object} BalanceCertificate "Signed balance certificate"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID or as_of"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Balance certificates are not configured"
+// @Failure 502 {object} ErrorResponse "Key management service error"
+// @Router /customers/{customer_id}/balance/certificate [get]
+func GetBalanceCertificate(c *gin.Context) {
+	if certificateSigner == nil {
+		respondError(c, http.StatusNotImplemented, ErrorResponse{Error: "Balance certificates are not configured"})
+		return
+	}
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	asOf := now
+	if s := c.Query("as_of"); s != "" {
+		asOf, err = time.Parse(time.RFC3339, s)
+		if err != nil || asOf.After(now) {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid as_of: use a past RFC 3339 time"})
+			return
+		}
+		if db == nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "as_of needs the database store"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	customer, err := ledgerStore.GetCustomer(ctx, customerID)
+	if errors.Is(err, store.ErrNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	var balance store.Balance
+	if c.Query("as_of") == "" {
+		balance, err = postings().Balance(ctx, customerID)
+	} else {
+		balance, err = balanceAsOf(ctx, customerID, asOf)
+	}
+	if errors.Is(err, ledger.ErrCustomerNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to read balance"})
+		return
+	}
+
+	claims := BalanceClaims{
+		Issuer:    certificateIssuer,
+		Subject:   customerID.String(),
+		Name:      customer.Name,
+		Balance:   balance.Amount,
+		Currency:  balance.Currency,
+		AsOf:      asOf.UTC().Format(time.RFC3339),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(certificateValidity).Unix(),
+		ID:        uuid.NewString(),
+	}
+	token, err := attest.Sign(ctx, certificateSigner, claims)
+	if err != nil {
+		log.Printf("Signing balance certificate for customer %s failed: %v", customerID, err)
+		respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to sign certificate"})
+		return
+	}
+	c.JSON(http.StatusOK, BalanceCertificate{Certificate: token, KeyID: certificateSigner.KeyID(), Claims: claims})
+}
+
+// @Summary Get the balance certificate keys
+// @Description Get the JSON Web Key Set that verifies balance certificates. A certificate's kid header names its key.
+// @Tags customers
+// @Produce json
+// @Success 200 {object} CertificateKeys "Key set"
+// @Failure 501 {object} ErrorResponse "Balance certificates are not configured"
+// @Router /balance-certificates/keys [get]
+func GetCertificateKeys(c *gin.Context) {
+	if certificateSigner == nil {
+		respondError(c, http.StatusNotImplemented, ErrorResponse{Error: "Balance certificates are not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, CertificateKeys{Keys: []attest.JWK{attest.PublicJWK(certificateSigner)}})
+}
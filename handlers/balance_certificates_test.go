@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/attest"
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBalanceCertificate(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.GET("/customers/:customer_id/balance/certificate", GetBalanceCertificate)
+	router.GET("/balance-certificates/keys", GetCertificateKeys)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	customer := store.Customer{ID: uuid.New(), Name: "Jane Doe", Balance: 1250.5, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(context.Background(), &customer))
+	path := "/customers/" + customer.ID.String() + "/balance/certificate"
+	assert.Equal(t, http.StatusNotImplemented, get(path).Code)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signer, err := attest.NewKeySigner(key)
+	require.NoError(t, err)
+	InitBalanceCertificates(signer, "ledger-test", 24*time.Hour)
+	defer InitBalanceCertificates(nil, "", 0)
+
+	w := get(path)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var cert BalanceCertificate
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cert))
+	assert.Equal(t, signer.KeyID(), cert.KeyID)
+
+	// What a third party checks is the signed payload, not the decoded claims
+	payload, err := attest.Verify(cert.Certificate, &key.PublicKey)
+	require.NoError(t, err)
+	var claims BalanceClaims
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, cert.Claims, claims)
+	assert.Equal(t, "ledger-test", claims.Issuer)
+	assert.Equal(t, customer.ID.String(), claims.Subject)
+	assert.Equal(t, "Jane Doe", claims.Name)
+	assert.Equal(t, 1250.5, claims.Balance)
+	assert.Equal(t, int64(24*3600), claims.ExpiresAt-claims.IssuedAt)
+
+	w = get("/balance-certificates/keys")
+	require.Equal(t, http.StatusOK, w.Code)
+	var keys CertificateKeys
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	require.Len(t, keys.Keys, 1)
+	assert.Equal(t, cert.KeyID, keys.Keys[0].KeyID)
+	assert.Equal(t, "ES256", keys.Keys[0].Algorithm)
+
+	assert.Equal(t, http.StatusNotFound, get("/customers/"+uuid.NewString()+"/balance/certificate").Code)
+	assert.Equal(t, http.StatusBadRequest, get(path+"?as_of=2999-01-01T00:00:00Z").Code)
+}
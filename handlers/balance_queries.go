@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BalanceQueryRoute reads balances in bulk. It is posted to but only
+// reads, so it stays open in maintenance mode.
+const BalanceQueryRoute = "/balances/query"
+
+// maxBalanceQueryIDs caps the customers one balance query may ask for
+const maxBalanceQueryIDs = 500
+
+// BalanceQuery asks for the balances of several customers at once
+type BalanceQuery struct {
+	CustomerIDs []uuid.UUID `json:"customer_ids" binding:"required" minItems:"1" maxItems:"500"`
+}
+
+// BalanceQueryResponse holds the balances a query asked for
+// @Description Balances of the customers asked for, in the order asked
+type BalanceQueryResponse struct {
+	Balances []BalanceResponse `json:"balances"`
+	// NotFound lists the IDs that matched no customer
+	NotFound []uuid.UUID `json:"not_found"`
+}
+
+// @Summary Query balances
+// @Description Get the current balances of up to 500 customers in one request, read together in a single query, each in its account's base currency. Balances come back in the order asked, once per customer; IDs that match no customer are listed in not_found rather than failing the request. The balance cache is not used, so every balance is as stored.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param query body BalanceQuery true "Customers to read the balances of"
+// @Success 200 {object} BalanceQueryResponse "Balances"
+// @Failure 400 {object} ErrorResponse "No customer IDs, too many, or an invalid one"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /balances/query [post]
+func QueryBalances(c *gin.Context) {
+	var req BalanceQuery
+	if !bindRequest(c, &req, "Invalid input: customer_ids must be a list of customer IDs") {
+		return
+	}
+	var fields fieldErrors
+	if len(req.CustomerIDs) == 0 || len(req.CustomerIDs) > maxBalanceQueryIDs {
+		fields.add("customer_ids", fmt.Sprintf("customer_ids must list 1 to %d customers", maxBalanceQueryIDs))
+		respondValidationError(c, fields)
+		return
+	}
+
+	// Each customer is answered once, where it was first asked for
+	ids := make([]uuid.UUID, 0, len(req.CustomerIDs))
+	seen := make(map[uuid.UUID]bool, len(req.CustomerIDs))
+	for _, id := range req.CustomerIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	balances, err := ledgerStore.GetBalances(c.Request.Context(), ids)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch balances"})
+		return
+	}
+	resp := BalanceQueryResponse{Balances: []BalanceResponse{}, NotFound: []uuid.UUID{}}
+	for _, id := range ids {
+		balance, ok := balances[id]
+		if !ok {
+			resp.NotFound = append(resp.NotFound, id)
+			continue
+		}
+		resp.Balances = append(resp.Balances, BalanceResponse{CustomerID: id, Balance: balance.Amount, Currency: balance.Currency})
+	}
+	c.JSON(http.StatusOK, resp)
+}
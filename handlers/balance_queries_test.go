@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryBalances(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST(BalanceQueryRoute, QueryBalances)
+
+	send := func(body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", BalanceQueryRoute, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(map[string]interface{}{"customer_ids": []string{}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"customer_ids"`)
+	tooMany := make([]uuid.UUID, maxBalanceQueryIDs+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	assert.Equal(t, http.StatusBadRequest, send(map[string]interface{}{"customer_ids": tooMany}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(map[string]interface{}{"customer_ids": []string{"nope"}}).Code)
+
+	// Every balance is read in one query, and each customer answered once
+	first, second, missing := uuid.New(), uuid.New(), uuid.New()
+	mock.ExpectQuery(`SELECT id, balance, currency FROM customers WHERE id = ANY\(\$1\)`).
+		WithArgs([]uuid.UUID{second, missing, first}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "balance", "currency"}).
+			AddRow(first, float64(100), "USD").
+			AddRow(second, float64(-25.5), "EUR"))
+	w = send(map[string]interface{}{"customer_ids": []uuid.UUID{second, missing, first, second}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp BalanceQueryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []BalanceResponse{
+		{CustomerID: second, Balance: -25.5, Currency: "EUR"},
+		{CustomerID: first, Balance: 100, Currency: "USD"},
+	}, resp.Balances, "in the order asked")
+	assert.Equal(t, []uuid.UUID{missing}, resp.NotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,753 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"ledger-service/ledger"
+	"ledger-service/plaid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// BankFeed links customers' bank accounts and pulls their transactions;
+// *plaid.Client implements it
+type BankFeed interface {
+	CreateLinkToken(ctx context.Context, userID, webhookURL string) (string, error)
+	ExchangePublicToken(ctx context.Context, publicToken string) (accessToken, itemID string, err error)
+	SyncTransactions(ctx context.Context, accessToken, cursor string) (plaid.SyncPage, error)
+	RemoveItem(ctx context.Context, accessToken string) error
+	VerifyWebhook(ctx context.Context, token string, body []byte) error
+}
+
+var (
+	bankFeed       BankFeed
+	bankWebhookURL string
+)
+
+// InitBankFeed sets the bank feed linked accounts sync from, and the URL
+// its webhooks are sent to; a nil feed turns bank links off
+func InitBankFeed(f BankFeed, webhookURL string) {
+	bankFeed = f
+	bankWebhookURL = webhookURL
+}
+
+// Bank link modes: a mirrored account posts every bank transaction to the
+// ledger, a reconciled one matches them against postings already made
+const (
+	bankLinkMirror    = "mirror"
+	bankLinkReconcile = "reconcile"
+)
+
+// bankMatchDays is how far a ledger posting's value date may be from the
+// bank's date for the two to match
+const bankMatchDays = 3
+
+// BankLinkRequest represents the payload for linking a bank account
+type BankLinkRequest struct {
+	// PublicToken is what Plaid Link returned when the customer linked
+	PublicToken string `json:"public_token" binding:"required,max=200" example:"public-sandbox-5c224a01-8314-4491-a06f-39e193d5cddc"`
+	// AccountID is the Plaid account to sync, of those the customer linked
+	AccountID string `json:"account_id" binding:"required,max=100" example:"BxBXxLj1m4HMXBm9WZZmCWVbPjX16EHwv99vp"`
+	Mode      string `json:"mode,omitempty" binding:"omitempty,oneof=mirror reconcile" example:"mirror" enums:"mirror,reconcile" default:"mirror"`
+}
+
+// BankLink is a customer's linked bank account and how its sync is going
+// @Description A bank account linked through Plaid whose transactions are mirrored into the ledger or reconciled against it
+type BankLink struct {
+	ID         uuid.UUID `json:"link_id" format:"uuid"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	ItemID     string    `json:"item_id" example:"eVBnVMp7zdTJLkRNr33Rs6zr7KNJqBFL9DrE6"`
+	AccountID  string    `json:"account_id" example:"BxBXxLj1m4HMXBm9WZZmCWVbPjX16EHwv99vp"`
+	Mode       string    `json:"mode" example:"mirror" enums:"mirror,reconcile"`
+	Status     string    `json:"status" example:"active" enums:"active,error,unlinked"`
+	// LastError is why the last sync stopped, while status is error
+	LastError    string  `json:"last_error,omitempty" example:"plaid ITEM_LOGIN_REQUIRED: the login details of this item have changed"`
+	LastSyncedAt *string `json:"last_synced_at,omitempty" format:"date-time"`
+	// Transactions counts the bank transactions synced, and Unmatched those
+	// a reconciled link found no posting for
+	Transactions int    `json:"transactions" example:"42"`
+	Unmatched    int    `json:"unmatched" example:"0"`
+	CreatedAt    string `json:"created_at" format:"date-time"`
+}
+
+// BankTransaction is a bank transaction synced from a linked account
+type BankTransaction struct {
+	ExternalID  string  `json:"external_id" example:"lPNjeW1nR6CDn5okmGQ6hEpMo4lLNoSrzqDje"`
+	Amount      float64 `json:"amount" example:"12.5"`
+	Direction   string  `json:"direction" example:"debit" enums:"credit,debit"`
+	Currency    string  `json:"currency,omitempty" example:"USD"`
+	Date        string  `json:"date" format:"date"`
+	Description string  `json:"description,omitempty" example:"Starbucks"`
+	// Status is posted when the sync posted it, matched when a reconciled
+	// link found its posting, and unmatched otherwise
+	Status        string     `json:"status" example:"posted" enums:"posted,matched,unmatched"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+}
+
+// BankSyncResult summarizes one sync of a link
+type BankSyncResult struct {
+	LinkID    uuid.UUID `json:"link_id" format:"uuid"`
+	Status    string    `json:"status" example:"active" enums:"active,error"`
+	Error     string    `json:"error,omitempty"`
+	Added     int       `json:"added" example:"3"`
+	Posted    int       `json:"posted" example:"3"`
+	Matched   int       `json:"matched" example:"0"`
+	Unmatched int       `json:"unmatched" example:"0"`
+	Removed   int       `json:"removed" example:"0"`
+}
+
+// LinkTokenResponse carries a token that opens Plaid Link
+type LinkTokenResponse struct {
+	LinkToken string `json:"link_token" example:"link-sandbox-af1a0311-da53-4636-b754-dd15cc058176"`
+}
+
+const bankLinkColumns = `l.id, l.customer_id, l.item_id, l.account_id, l.mode, l.status, COALESCE(l.last_error, ''), l.last_synced_at, l.created_at,
+	(SELECT COUNT(*) FROM bank_transactions b WHERE b.link_id = l.id),
+	(SELECT COUNT(*) FROM bank_transactions b WHERE b.link_id = l.id AND b.status = 'unmatched')`
+
+func scanBankLink(row pgx.Row) (BankLink, error) {
+	var l BankLink
+	var lastSynced *time.Time
+	var createdAt time.Time
+	if err := row.Scan(&l.ID, &l.CustomerID, &l.ItemID, &l.AccountID, &l.Mode, &l.Status, &l.LastError,
+		&lastSynced, &createdAt, &l.Transactions, &l.Unmatched); err != nil {
+		return BankLink{}, err
+	}
+	if lastSynced != nil {
+		s := lastSynced.Format(time.RFC3339)
+		l.LastSyncedAt = &s
+	}
+	l.CreatedAt = createdAt.Format(time.RFC3339)
+	return l, nil
+}
+
+// bankLinkParams reads and checks the customer and link IDs in the path,
+// and that bank links are configured
+func bankLinkParams(c *gin.Context, withLink bool) (uuid.UUID, uuid.UUID, bool) {
+	if bankFeed == nil {
+		respondError(c, http.StatusNotImplemented, ErrorResponse{Error: "Bank links are not configured"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !withLink {
+		return customerID, uuid.Nil, true
+	}
+	linkID, err := uuid.Parse(c.Param("link_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid link ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return customerID, linkID, true
+}
+
+// @Summary Create a Plaid Link token
+// @Description Create a token that opens Plaid Link for the customer, so they can pick the bank account to link
+// @Tags bank links
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {object} LinkTokenResponse "Link token"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Failure 502 {object} ErrorResponse "Plaid error"
+// @Router /customers/{customer_id}/bank-links/link-token [post]
+func CreateBankLinkToken(c *gin.Context) {
+	customerID, _, ok := bankLinkParams(c, false)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	token, err := bankFeed.CreateLinkToken(ctx, customerID.String(), bankWebhookURL)
+	if err != nil {
+		log.Printf("Failed to create Plaid Link token for customer %s: %v", customerID, err)
+		respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to create link token"})
+		return
+	}
+	c.JSON(http.StatusOK, LinkTokenResponse{LinkToken: token})
+}
+
+// @Summary Link a bank account
+// @Description Link a bank account the customer picked in Plaid Link. In mirror mode (the default) every bank transaction is posted to the ledger as a bank_credit or bank_debit; in reconcile mode each is matched against a posting already made. Transactions are pulled on the BANK_SYNC schedule, when Plaid reports new ones, and on demand.
+// @Tags bank links
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param link body BankLinkRequest true "Public token and account"
+// @Success 201 {object} BankLink "Bank account linked"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Bank account already linked"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Failure 502 {object} ErrorResponse "Plaid error"
+// @Router /customers/{customer_id}/bank-links [post]
+func CreateBankLink(c *gin.Context) {
+	customerID, _, ok := bankLinkParams(c, false)
+	if !ok {
+		return
+	}
+	var req BankLinkRequest
+	if !bindRequest(c, &req, "Invalid input: public_token and account_id are required") {
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = bankLinkMirror
+	}
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	accessToken, itemID, err := bankFeed.ExchangePublicToken(ctx, req.PublicToken)
+	if err != nil {
+		log.Printf("Failed to exchange Plaid public token for customer %s: %v", customerID, err)
+		respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to exchange public token"})
+		return
+	}
+	link, err := scanBankLink(db.QueryRow(ctx,
+		`WITH l AS (
+			INSERT INTO bank_links (id, customer_id, item_id, access_token, account_id, mode)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (item_id, account_id) DO NOTHING
+			RETURNING *)
+		SELECT `+bankLinkColumns+` FROM l`,
+		uuid.New(), customerID, itemID, accessToken, req.AccountID, req.Mode))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Bank account is already linked"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to link bank account"})
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// @Summary List bank links
+// @Description List the customer's linked bank accounts with their sync status
+// @Tags bank links
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} BankLink "Bank links"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Router /customers/{customer_id}/bank-links [get]
+func ListBankLinks(c *gin.Context) {
+	customerID, _, ok := bankLinkParams(c, false)
+	if !ok {
+		return
+	}
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+bankLinkColumns+" FROM bank_links l WHERE l.customer_id = $1 ORDER BY l.created_at DESC",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch bank links"})
+		return
+	}
+	defer rows.Close()
+	links := []BankLink{}
+	for rows.Next() {
+		l, err := scanBankLink(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan bank link"})
+			return
+		}
+		links = append(links, l)
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// @Summary List a bank link's transactions
+// @Description List the bank transactions synced from a linked account, newest first, optionally only those with a status, such as the unmatched ones left to reconcile by hand
+// @Tags bank links
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param link_id path string true "Bank link ID" format(uuid)
+// @Param status query string false "Only transactions with this status" Enums(posted, matched, unmatched)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Transactions per page" default(10)
+// @Success 200 {array} BankTransaction "Bank transactions"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Router /customers/{customer_id}/bank-links/{link_id}/transactions [get]
+func ListBankTransactions(c *gin.Context) {
+	customerID, linkID, ok := bankLinkParams(c, true)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "posted", "matched", "unmatched":
+	default:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status: must be posted, matched or unmatched"})
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	where := "b.link_id = $1 AND l.customer_id = $2 AND ($3 = '' OR b.status = $3)"
+
+	var totalCount int
+	if err := db.QueryRow(ctx,
+		"SELECT COUNT(*) FROM bank_transactions b JOIN bank_links l ON l.id = b.link_id WHERE "+where,
+		linkID, customerID, status).Scan(&totalCount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get total count"})
+		return
+	}
+	rows, err := db.Query(ctx,
+		`SELECT b.external_id, b.amount, b.direction, COALESCE(b.currency, ''), b.date, COALESCE(b.description, ''), b.status, b.transaction_id
+		FROM bank_transactions b JOIN bank_links l ON l.id = b.link_id
+		WHERE `+where+` ORDER BY b.date DESC, b.external_id LIMIT $4 OFFSET $5`,
+		linkID, customerID, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch bank transactions"})
+		return
+	}
+	defer rows.Close()
+	transactions := []BankTransaction{}
+	for rows.Next() {
+		var t BankTransaction
+		var date time.Time
+		if err := rows.Scan(&t.ExternalID, &t.Amount, &t.Direction, &t.Currency, &date, &t.Description, &t.Status, &t.TransactionID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan bank transaction"})
+			return
+		}
+		t.Date = date.Format(dateLayout)
+		transactions = append(transactions, t)
+	}
+
+	c.Header("X-Total-Count", fmt.Sprintf("%d", totalCount))
+	c.Header("X-Page", fmt.Sprintf("%d", page))
+	c.Header("X-Page-Size", fmt.Sprintf("%d", pageSize))
+	c.Header("X-Total-Pages", fmt.Sprintf("%d", (totalCount+pageSize-1)/pageSize))
+	c.JSON(http.StatusOK, transactions)
+}
+
+// @Summary Sync a bank link
+// @Description Pull the linked account's new bank transactions now and mirror or match them. A sync that fails part way stops with the link in error and resumes from the same point next time; transactions already posted are not posted again.
+// @Tags bank links
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param link_id path string true "Bank link ID" format(uuid)
+// @Success 200 {object} BankSyncResult "Sync finished; status error when it stopped early"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 404 {object} ErrorResponse "Bank link not found"
+// @Failure 409 {object} ErrorResponse "Bank link is unlinked or already syncing"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Router /customers/{customer_id}/bank-links/{link_id}/sync [post]
+func SyncBankLink(c *gin.Context) {
+	customerID, linkID, ok := bankLinkParams(c, true)
+	if !ok {
+		return
+	}
+	// Finish the sync even if the client gives up, so the cursor is saved
+	ctx := context.WithoutCancel(c.Request.Context())
+	result, claimed, err := syncBankLink(ctx, linkID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to sync bank link"})
+		return
+	}
+	if !claimed {
+		var exists bool
+		if err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM bank_links WHERE id = $1 AND customer_id = $2)", linkID, customerID).Scan(&exists); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to sync bank link"})
+		} else if !exists {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Bank link not found"})
+		} else {
+			respondError(c, http.StatusConflict, ErrorResponse{Error: "Bank link is unlinked or already syncing"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Unlink a bank account
+// @Description Stop syncing a linked bank account. Plaid's access is revoked once no link uses the item; the transactions synced so far and their postings are kept.
+// @Tags bank links
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param link_id path string true "Bank link ID" format(uuid)
+// @Success 200 {object} BankLink "Bank account unlinked"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 404 {object} ErrorResponse "Linked bank account not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Router /customers/{customer_id}/bank-links/{link_id} [delete]
+func DeleteBankLink(c *gin.Context) {
+	customerID, linkID, ok := bankLinkParams(c, true)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	var accessToken string
+	var shared bool
+	link, err := scanBankLink(db.QueryRow(ctx,
+		`WITH l AS (
+			UPDATE bank_links SET status = 'unlinked', syncing_since = NULL
+			WHERE id = $1 AND customer_id = $2 AND status <> 'unlinked'
+			RETURNING *)
+		SELECT `+bankLinkColumns+` FROM l`,
+		linkID, customerID))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Linked bank account not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to unlink bank account"})
+		return
+	}
+	// Other accounts of the same item keep the access token alive
+	if err := db.QueryRow(ctx,
+		`SELECT access_token, EXISTS(SELECT 1 FROM bank_links o WHERE o.item_id = l.item_id AND o.id <> l.id AND o.status <> 'unlinked')
+		FROM bank_links l WHERE l.id = $1`,
+		linkID).Scan(&accessToken, &shared); err == nil && !shared {
+		if err := bankFeed.RemoveItem(ctx, accessToken); err != nil {
+			log.Printf("Failed to remove Plaid item %s: %v", link.ItemID, err)
+		}
+	}
+	link.Status = "unlinked"
+	c.JSON(http.StatusOK, link)
+}
+
+// PlaidWebhookRequest is the part of a Plaid webhook the service acts on
+type PlaidWebhookRequest struct {
+	WebhookType string `json:"webhook_type" example:"TRANSACTIONS"`
+	WebhookCode string `json:"webhook_code" example:"SYNC_UPDATES_AVAILABLE"`
+	ItemID      string `json:"item_id" example:"eVBnVMp7zdTJLkRNr33Rs6zr7KNJqBFL9DrE6"`
+	Error       *struct {
+		ErrorCode    string `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"error,omitempty"`
+}
+
+// @Summary Receive a Plaid webhook
+// @Description Receive a webhook from Plaid, verified with its Plaid-Verification JWT. SYNC_UPDATES_AVAILABLE syncs the item's links; an item error puts them in error until the customer relinks. Other webhooks are acknowledged and ignored.
+// @Tags bank links
+// @Accept json
+// @Produce json
+// @Param webhook body PlaidWebhookRequest true "Plaid webhook"
+// @Success 200 {object} map[string]int "Links synced or marked in error"
+// @Failure 401 {object} ErrorResponse "Invalid webhook signature"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Bank links are not configured"
+// @Router /plaid/webhook [post]
+func PlaidWebhook(c *gin.Context) {
+	if bankFeed == nil {
+		respondError(c, http.StatusNotImplemented, ErrorResponse{Error: "Bank links are not configured"})
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+		return
+	}
+	ctx := context.WithoutCancel(c.Request.Context())
+	if err := bankFeed.VerifyWebhook(ctx, c.GetHeader(plaid.VerificationHeader), body); err != nil {
+		respondError(c, http.StatusUnauthorized, ErrorResponse{Error: "Invalid webhook signature", Code: "invalid_signature"})
+		return
+	}
+	var req PlaidWebhookRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.ItemID == "" {
+		c.JSON(http.StatusOK, gin.H{"links": 0})
+		return
+	}
+
+	switch {
+	case req.WebhookType == "TRANSACTIONS" && req.WebhookCode == "SYNC_UPDATES_AVAILABLE":
+		ids, err := bankLinkIDs(ctx, "item_id = $1 AND status <> 'unlinked'", req.ItemID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to find bank links"})
+			return
+		}
+		synced := 0
+		for _, id := range ids {
+			if _, claimed, err := syncBankLink(ctx, id); err != nil {
+				log.Printf("Failed to sync bank link %s: %v", id, err)
+			} else if claimed {
+				synced++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"links": synced})
+	case req.WebhookType == "ITEM" && (req.WebhookCode == "ERROR" || req.WebhookCode == "PENDING_EXPIRATION" || req.WebhookCode == "USER_PERMISSION_REVOKED"):
+		reason := "plaid " + req.WebhookCode
+		if req.Error != nil {
+			reason = fmt.Sprintf("plaid %s: %s", req.Error.ErrorCode, req.Error.ErrorMessage)
+		}
+		tag, err := db.Exec(ctx,
+			"UPDATE bank_links SET status = 'error', last_error = $2 WHERE item_id = $1 AND status <> 'unlinked'",
+			req.ItemID, reason)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update bank links"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"links": tag.RowsAffected()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"links": 0})
+	}
+}
+
+// SyncBankLinks syncs every linked account that is not unlinked, returning
+// how many synced without error. It is run on the BANK_SYNC schedule.
+func SyncBankLinks(ctx context.Context, _ time.Time) (int, error) {
+	if bankFeed == nil {
+		return 0, nil
+	}
+	ids, err := bankLinkIDs(ctx, "status <> 'unlinked'")
+	if err != nil {
+		return 0, err
+	}
+	synced := 0
+	for _, id := range ids {
+		result, claimed, err := syncBankLink(ctx, id)
+		switch {
+		case err != nil:
+			log.Printf("Failed to sync bank link %s: %v", id, err)
+		case claimed && result.Error != "":
+			log.Printf("Bank link %s stopped syncing: %s", id, result.Error)
+		case claimed:
+			synced++
+		}
+	}
+	return synced, nil
+}
+
+func bankLinkIDs(ctx context.Context, where string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := db.Query(ctx, "SELECT id FROM bank_links WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// bankLinkState is what a sync needs to know about a link
+type bankLinkState struct {
+	id          uuid.UUID
+	customerID  uuid.UUID
+	accessToken string
+	accountID   string
+	mode        string
+	cursor      string
+}
+
+// syncBankLink claims a link, pulls its changes since the saved cursor and
+// records each new bank transaction, then saves the new cursor and releases
+// the link. Claiming fails, with false, when the link is unknown, unlinked
+// or already syncing; a sync holding it for over ten minutes is taken to
+// have crashed. A sync stopped by Plaid or a posting keeps the old
+// cursor and leaves the link in error with the reason; the error returned
+// is only for failing to save that.
+func syncBankLink(ctx context.Context, linkID uuid.UUID) (BankSyncResult, bool, error) {
+	result := BankSyncResult{LinkID: linkID}
+	var link bankLinkState
+	err := db.QueryRow(ctx,
+		`UPDATE bank_links SET syncing_since = NOW()
+		WHERE id = $1 AND status <> 'unlinked' AND (syncing_since IS NULL OR syncing_since < NOW() - INTERVAL '10 minutes')
+		RETURNING id, customer_id, access_token, account_id, mode, COALESCE(cursor, '')`,
+		linkID).Scan(&link.id, &link.customerID, &link.accessToken, &link.accountID, &link.mode, &link.cursor)
+	if err == pgx.ErrNoRows {
+		return result, false, nil
+	}
+	if err != nil {
+		return result, false, err
+	}
+
+	cursor, syncErr := pullBankTransactions(ctx, link, &result)
+	result.Status = "active"
+	var lastError *string
+	if syncErr != nil {
+		result.Status, result.Error = "error", syncErr.Error()
+		lastError = &result.Error
+		// Pages are only consistent from where the sync started, so it is
+		// restarted from there; what was recorded is not recorded twice
+		cursor = link.cursor
+	}
+	_, err = db.Exec(ctx,
+		`UPDATE bank_links SET cursor = $2, status = $3, last_error = $4, last_synced_at = NOW(), syncing_since = NULL
+		WHERE id = $1 AND status <> 'unlinked'`,
+		link.id, nullableString(cursor), result.Status, lastError)
+	return result, true, err
+}
+
+// pullBankTransactions records every page of changes since link's cursor,
+// returning the cursor after the last page
+func pullBankTransactions(ctx context.Context, link bankLinkState, result *BankSyncResult) (string, error) {
+	cursor := link.cursor
+	for {
+		page, err := bankFeed.SyncTransactions(ctx, link.accessToken, cursor)
+		if err != nil {
+			return "", err
+		}
+		for _, t := range append(page.Added, page.Modified...) {
+			// Pending transactions come back as new ones once posted
+			if t.AccountID != link.accountID || t.Pending {
+				continue
+			}
+			status, err := recordBankTransaction(ctx, link, t)
+			if err != nil {
+				return "", fmt.Errorf("transaction %s: %w", t.TransactionID, err)
+			}
+			switch status {
+			case "posted":
+				result.Posted++
+			case "matched":
+				result.Matched++
+			case "unmatched":
+				result.Unmatched++
+			default:
+				continue
+			}
+			result.Added++
+		}
+		for _, r := range page.Removed {
+			// A posting stays; a bank reversal arrives as its own transaction
+			tag, err := db.Exec(ctx,
+				"DELETE FROM bank_transactions WHERE link_id = $1 AND external_id = $2 AND status <> 'posted'",
+				link.id, r.TransactionID)
+			if err != nil {
+				return "", err
+			}
+			result.Removed += int(tag.RowsAffected())
+		}
+		cursor = page.NextCursor
+		if !page.HasMore {
+			return cursor, nil
+		}
+	}
+}
+
+// recordBankTransaction mirrors or matches a bank transaction and records
+// it, returning its status, or "" when it was already recorded
+func recordBankTransaction(ctx context.Context, link bankLinkState, t plaid.Transaction) (string, error) {
+	var seen bool
+	if err := db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM bank_transactions WHERE link_id = $1 AND external_id = $2)",
+		link.id, t.TransactionID).Scan(&seen); err != nil {
+		return "", err
+	}
+	if seen {
+		return "", nil
+	}
+	date, err := time.Parse(dateLayout, t.Date)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", t.Date)
+	}
+	// Plaid reports money leaving the account as positive
+	direction := "credit"
+	if t.Amount > 0 {
+		direction = "debit"
+	}
+	amount := math.Round(math.Abs(t.Amount)*100) / 100
+	if amount == 0 {
+		return "", nil
+	}
+
+	var transactionID *uuid.UUID
+	status := "unmatched"
+	if link.mode == bankLinkMirror {
+		id, err := postBankTransaction(ctx, link, t, direction, amount)
+		if err != nil {
+			return "", err
+		}
+		transactionID, status = &id, "posted"
+	} else {
+		var id uuid.UUID
+		err := db.QueryRow(ctx,
+			`SELECT t.id FROM transactions t JOIN transaction_types tt ON tt.code = t.type
+			WHERE t.customer_id = $1 AND t.status = 'posted' AND t.amount = $2 AND tt.direction = $3
+				AND t.value_date BETWEEN $4::date - $5::int AND $4::date + $5::int
+				AND NOT EXISTS (SELECT 1 FROM bank_transactions b WHERE b.transaction_id = t.id)
+			ORDER BY ABS(t.value_date - $4::date), t.created_at LIMIT 1`,
+			link.customerID, amount, direction, date, bankMatchDays).Scan(&id)
+		if err != nil && err != pgx.ErrNoRows {
+			return "", err
+		}
+		if err == nil {
+			transactionID, status = &id, "matched"
+		}
+	}
+
+	_, err = db.Exec(ctx,
+		`INSERT INTO bank_transactions (link_id, external_id, amount, direction, currency, date, description, status, transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (link_id, external_id) DO NOTHING`,
+		link.id, t.TransactionID, amount, direction, nullableString(t.ISOCurrencyCode), date,
+		nullableString(truncateDescription(t.Name)), status, transactionID)
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// postBankTransaction mirrors a bank transaction into the ledger. The Plaid
+// transaction ID is the posting's unique reference, so a sync restarted
+// after posting finds the posting instead of making another.
+func postBankTransaction(ctx context.Context, link bankLinkState, t plaid.Transaction, direction string, amount float64) (uuid.UUID, error) {
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID:      link.customerID,
+		Type:            "bank_" + direction,
+		Amount:          amount,
+		Currency:        t.ISOCurrencyCode,
+		Reference:       "plaid:" + t.TransactionID,
+		UniqueReference: true,
+		// Two equal card payments on a day are two bank transactions
+		AllowDuplicate: true,
+	})
+	var duplicate *ledger.DuplicateReferenceError
+	if errors.As(err, &duplicate) {
+		return duplicate.TransactionID, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	invalidateBalances(ctx, link.customerID)
+	return result.TransactionID, nil
+}
+
+// truncateDescription cuts a bank's description to the 140 characters kept
+func truncateDescription(s string) string {
+	if r := []rune(s); len(r) > 140 {
+		return string(r[:140])
+	}
+	return s
+}
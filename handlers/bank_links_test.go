@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/plaid"
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBankFeed struct {
+	pages      []plaid.SyncPage
+	syncErr    error
+	webhookErr error
+	cursors    []string
+}
+
+func (f *testBankFeed) CreateLinkToken(ctx context.Context, userID, webhookURL string) (string, error) {
+	return "link-sandbox-1", nil
+}
+
+func (f *testBankFeed) ExchangePublicToken(ctx context.Context, publicToken string) (string, string, error) {
+	return "access-sandbox-1", "item-1", nil
+}
+
+func (f *testBankFeed) SyncTransactions(ctx context.Context, accessToken, cursor string) (plaid.SyncPage, error) {
+	f.cursors = append(f.cursors, cursor)
+	if f.syncErr != nil {
+		return plaid.SyncPage{}, f.syncErr
+	}
+	page := f.pages[0]
+	f.pages = f.pages[1:]
+	return page, nil
+}
+
+func (f *testBankFeed) RemoveItem(ctx context.Context, accessToken string) error {
+	return nil
+}
+
+func (f *testBankFeed) VerifyWebhook(ctx context.Context, token string, body []byte) error {
+	return f.webhookErr
+}
+
+var bankLinkTestColumns = []string{"id", "customer_id", "item_id", "account_id", "mode", "status", "last_error", "last_synced_at", "created_at", "transactions", "unmatched"}
+
+func TestCreateBankLink(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.POST("/customers/:customer_id/bank-links", CreateBankLink)
+
+	customer := store.Customer{ID: uuid.New(), Name: "Test", AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(context.Background(), &customer))
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/customers/"+customer.ID.String()+"/bank-links", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	InitBankFeed(nil, "")
+	w := send(map[string]interface{}{"public_token": "public-sandbox-1", "account_id": "acc-1"})
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	InitBankFeed(&testBankFeed{}, "")
+	defer InitBankFeed(nil, "")
+	linkID := uuid.New()
+	mock.ExpectQuery(`INSERT INTO bank_links`).
+		WithArgs(pgxmock.AnyArg(), customer.ID, "item-1", "access-sandbox-1", "acc-1", "reconcile").
+		WillReturnRows(pgxmock.NewRows(bankLinkTestColumns).
+			AddRow(linkID, customer.ID, "item-1", "acc-1", "reconcile", "active", "", (*time.Time)(nil), time.Now(), 0, 0))
+	w = send(map[string]interface{}{"public_token": "public-sandbox-1", "account_id": "acc-1", "mode": "reconcile"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var link BankLink
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.Equal(t, linkID, link.ID)
+	assert.Equal(t, "reconcile", link.Mode)
+	assert.NotContains(t, w.Body.String(), "access-sandbox-1", "the access token is never returned")
+
+	mock.ExpectQuery(`INSERT INTO bank_links`).
+		WithArgs(pgxmock.AnyArg(), customer.ID, "item-1", "access-sandbox-1", "acc-1", "mirror").
+		WillReturnRows(pgxmock.NewRows(bankLinkTestColumns))
+	w = send(map[string]interface{}{"public_token": "public-sandbox-1", "account_id": "acc-1"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = send(map[string]interface{}{"public_token": "public-sandbox-1", "account_id": "acc-1", "mode": "copy"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncBankLink(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.POST("/customers/:customer_id/bank-links/:link_id/sync", SyncBankLink)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 50, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	linkID := uuid.New()
+	feed := &testBankFeed{pages: []plaid.SyncPage{
+		{
+			Added: []plaid.Transaction{
+				{TransactionID: "tx-1", AccountID: "acc-1", Amount: -100, Date: "2025-04-07", Name: "Payroll"},
+				{TransactionID: "tx-2", AccountID: "acc-1", Amount: 12.5, Date: "2025-04-07", Name: "Coffee", Pending: true},
+				{TransactionID: "tx-3", AccountID: "acc-2", Amount: 30, Date: "2025-04-07", Name: "Savings"},
+			},
+			NextCursor: "c1",
+			HasMore:    true,
+		},
+		{
+			Added:      []plaid.Transaction{{TransactionID: "tx-4", AccountID: "acc-1", Amount: 12.5, Date: "2025-04-08", Name: "Coffee"}},
+			NextCursor: "c2",
+		},
+	}}
+	InitBankFeed(feed, "")
+	defer InitBankFeed(nil, "")
+	sync := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/customers/"+customer.ID.String()+"/bank-links/"+linkID.String()+"/sync", nil))
+		return w
+	}
+	claimColumns := []string{"id", "customer_id", "access_token", "account_id", "mode", "cursor"}
+	expectRecord := func(externalID string) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM bank_transactions`).
+			WithArgs(linkID, externalID).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(`INSERT INTO bank_transactions`).
+			WithArgs(linkID, externalID, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "posted", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+
+	mock.ExpectQuery(`UPDATE bank_links SET syncing_since = NOW\(\)`).
+		WithArgs(linkID).
+		WillReturnRows(pgxmock.NewRows(claimColumns).AddRow(linkID, customer.ID, "access-sandbox-1", "acc-1", "mirror", "c0"))
+	expectRecord("tx-1")
+	expectRecord("tx-4")
+	mock.ExpectExec(`UPDATE bank_links SET cursor = \$2`).
+		WithArgs(linkID, pgxmock.AnyArg(), "active", (*string)(nil)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	w := sync()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result BankSyncResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "active", result.Status)
+	assert.Equal(t, 2, result.Added)
+	assert.Equal(t, 2, result.Posted)
+	assert.Equal(t, []string{"c0", "c1"}, feed.cursors)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, 137.5, balance.Amount)
+	history, err := memory.ListTransactions(ctx, customer.ID, store.ListOptions{})
+	require.NoError(t, err)
+	var references []string
+	for _, tr := range history {
+		references = append(references, tr.Type+" "+tr.Reference)
+	}
+	assert.ElementsMatch(t, []string{"bank_credit plaid:tx-1", "bank_debit plaid:tx-4"}, references)
+
+	// A failed sync keeps the cursor it started from and puts the link in error
+	feed.syncErr = errors.New("plaid ITEM_LOGIN_REQUIRED: the login details of this item have changed")
+	mock.ExpectQuery(`UPDATE bank_links SET syncing_since = NOW\(\)`).
+		WithArgs(linkID).
+		WillReturnRows(pgxmock.NewRows(claimColumns).AddRow(linkID, customer.ID, "access-sandbox-1", "acc-1", "mirror", "c2"))
+	mock.ExpectExec(`UPDATE bank_links SET cursor = \$2`).
+		WithArgs(linkID, pgxmock.AnyArg(), "error", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	w = sync()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "error", result.Status)
+	assert.Contains(t, result.Error, "ITEM_LOGIN_REQUIRED")
+
+	// A link that cannot be claimed is missing or busy
+	mock.ExpectQuery(`UPDATE bank_links SET syncing_since = NOW\(\)`).
+		WithArgs(linkID).
+		WillReturnRows(pgxmock.NewRows(claimColumns))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM bank_links`).
+		WithArgs(linkID, customer.ID).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+	w = sync()
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlaidWebhook(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST("/plaid/webhook", PlaidWebhook)
+	feed := &testBankFeed{}
+	InitBankFeed(feed, "")
+	defer InitBankFeed(nil, "")
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/plaid/webhook", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(plaid.VerificationHeader, "token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	mock.ExpectExec(`UPDATE bank_links SET status = 'error'`).
+		WithArgs("item-1", "plaid ITEM_LOGIN_REQUIRED: the login details of this item have changed").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	w := send(map[string]interface{}{
+		"webhook_type": "ITEM",
+		"webhook_code": "ERROR",
+		"item_id":      "item-1",
+		"error":        map[string]string{"error_code": "ITEM_LOGIN_REQUIRED", "error_message": "the login details of this item have changed"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"links": 2}`, w.Body.String())
+
+	w = send(map[string]interface{}{"webhook_type": "HOLDINGS", "webhook_code": "DEFAULT_UPDATE", "item_id": "item-1"})
+	assert.JSONEq(t, `{"links": 0}`, w.Body.String())
+
+	feed.webhookErr = plaid.ErrInvalidWebhook
+	w = send(map[string]interface{}{"webhook_type": "ITEM", "webhook_code": "ERROR", "item_id": "item-1"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bindJSON binds and validates the request body like ShouldBindJSON. On
+// routes guarded by middleware.StrictJSON it also rejects unknown fields.
+func bindJSON(c *gin.Context, obj interface{}) error {
+	validatorsOnce.Do(registerValidators)
+	if !c.GetBool(middleware.StrictJSONKey) {
+		return c.ShouldBindJSON(obj)
+	}
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// bindRequest binds obj like bindJSON and answers the request itself when
+// that fails: with one entry per field a binding tag rejected, or with
+// message when the body could not be decoded. It reports whether the
+// handler should continue.
+func bindRequest(c *gin.Context, obj interface{}, message string) bool {
+	err := bindJSON(c, obj)
+	if err == nil {
+		return true
+	}
+	var fields fieldErrors
+	if fields.addBinding(obj, err) {
+		respondValidationError(c, fields)
+	} else {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: message})
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type payload struct {
+		Amount float64 `json:"amount" binding:"required,gt=0"`
+	}
+	tests := []struct {
+		name       string
+		strict     bool
+		body       string
+		wantStatus int
+	}{
+		{name: "valid", strict: true, body: `{"amount": 10}`, wantStatus: http.StatusOK},
+		{name: "unknown field on strict route", strict: true, body: `{"amount": 10, "ammount": 5}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field on lenient route", strict: false, body: `{"amount": 10, "ammount": 5}`, wantStatus: http.StatusOK},
+		{name: "validation still applies", strict: true, body: `{"amount": -1}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			handlers := []gin.HandlerFunc{}
+			if tt.strict {
+				handlers = append(handlers, middleware.StrictJSON(1024))
+			}
+			handlers = append(handlers, func(c *gin.Context) {
+				var p payload
+				if err := bindJSON(c, &p); err != nil {
+					c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+			r.POST("/transactions", handlers...)
+
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
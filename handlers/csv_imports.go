@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"ledger-service/csvimport"
+	"ledger-service/jobqueue"
+	"ledger-service/ledger"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxImportRows is the most rows one CSV file may hold
+const maxImportRows = 1000
+
+// importProfileName is a profile's name: lowercase letters, digits, hyphens
+// and underscores
+var importProfileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ImportProfile is a stored mapping of a bank's CSV export
+// @Description Named mapping of a CSV export's columns and formats to transactions
+type ImportProfile struct {
+	Name string `json:"name" example:"acme-bank"`
+	csvimport.Profile
+	UpdatedAt string `json:"updated_at" format:"date-time"`
+}
+
+// ImportedRow is the outcome of one row of an import
+// @Description Outcome of one row of a CSV import
+type ImportedRow struct {
+	Line int `json:"line" example:"2"`
+	// Status is the posting's status, duplicate for a reference already
+	// imported, or failed
+	Status        string     `json:"status" example:"posted" enums:"posted,held,pending_approval,rejected,duplicate,failed"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+	Type          string     `json:"type" example:"credit"`
+	Amount        float64    `json:"amount" example:"1234.56"`
+	ValueDate     string     `json:"value_date" example:"2025-04-01" format:"date"`
+	Reference     string     `json:"reference,omitempty" example:"Salary April"`
+	Error         string     `json:"error,omitempty" example:"Insufficient balance"`
+}
+
+// ImportResult sums up a CSV import
+// @Description Rows of a CSV import and what became of them
+type ImportResult struct {
+	Profile    string        `json:"profile" example:"acme-bank"`
+	CustomerID uuid.UUID     `json:"customer_id" format:"uuid"`
+	Posted     int           `json:"posted" example:"41"`
+	Held       int           `json:"held" example:"0"`
+	Duplicates int           `json:"duplicates" example:"3"`
+	Failed     int           `json:"failed" example:"1"`
+	Rows       []ImportedRow `json:"rows"`
+}
+
+// validateImportProfile checks a profile with its defaults set, including
+// that the types it posts are postable and go the right way
+func validateImportProfile(p *csvimport.Profile) fieldErrors {
+	var fields fieldErrors
+	for _, e := range p.Validate() {
+		fields.add(e.Field, e.Message)
+	}
+	postable := func(field, code string, direction txtype.Direction) {
+		t, ok := transactionTypes.Lookup(code)
+		switch {
+		case !ok || !t.Postable:
+			fields.add(field, fmt.Sprintf("%s is not a postable transaction type", code))
+		case direction != "" && t.Direction != direction:
+			fields.add(field, fmt.Sprintf("%s must be a %s type", field, direction))
+		}
+	}
+	postable("credit_type", p.CreditType, txtype.Credit)
+	postable("debit_type", p.DebitType, txtype.Debit)
+	for _, code := range p.TypeMap {
+		postable("type_map", code, "")
+	}
+	return fields
+}
+
+// loadImportProfile reads a stored profile, returning pgx.ErrNoRows when
+// there is none by that name
+func loadImportProfile(c *gin.Context, name string) (ImportProfile, error) {
+	profile := ImportProfile{Name: name}
+	var raw []byte
+	var updatedAt time.Time
+	err := db.QueryRow(c.Request.Context(),
+		"SELECT profile, updated_at FROM import_profiles WHERE name = $1", name).Scan(&raw, &updatedAt)
+	if err != nil {
+		return profile, err
+	}
+	if err := json.Unmarshal(raw, &profile.Profile); err != nil {
+		return profile, err
+	}
+	profile.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	return profile, nil
+}
+
+// @Summary List CSV import profiles
+// @Description List the stored mappings of bank CSV exports, by name
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} ImportProfile "Import profiles"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/import-profiles [get]
+func ListImportProfiles(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(), "SELECT name, profile, updated_at FROM import_profiles ORDER BY name")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch import profiles"})
+		return
+	}
+	defer rows.Close()
+	profiles := []ImportProfile{}
+	for rows.Next() {
+		var p ImportProfile
+		var raw []byte
+		var updatedAt time.Time
+		if err := rows.Scan(&p.Name, &raw, &updatedAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan import profile"})
+			return
+		}
+		if err := json.Unmarshal(raw, &p.Profile); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to read import profile"})
+			return
+		}
+		p.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		profiles = append(profiles, p)
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+// @Summary Get a CSV import profile
+// @Description Get a stored mapping of a bank CSV export, with its defaults filled in
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Profile name" example(acme-bank)
+// @Success 200 {object} ImportProfile "Import profile"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Import profile not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/import-profiles/{name} [get]
+func GetImportProfile(c *gin.Context) {
+	profile, err := loadImportProfile(c, c.Param("name"))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Import profile not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch import profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary Create or replace a CSV import profile
+// @Description Store how a bank's CSV export is read: its delimiter, lines to skip and header, which columns hold the date, amount (or separate debit and credit amounts), type and reference, by header name or 1-based position, the date format (YYYY, YY, MM, M, DD and D, e.g. DD.MM.YYYY), and the decimal and thousands separators. Each value of the type column can be mapped to a transaction type; other rows are posted as credit_type or debit_type by the sign of their amount. With unique_references a row's reference can be imported once per customer, so overlapping exports can be imported without posting rows twice. Changing a profile affects later imports only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Profile name: lowercase letters, digits, hyphens and underscores" example(acme-bank)
+// @Param profile body csvimport.Profile true "Mapping"
+// @Success 200 {object} ImportProfile "Import profile stored"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/import-profiles/{name} [put]
+func PutImportProfile(c *gin.Context) {
+	name := c.Param("name")
+	var profile csvimport.Profile
+	if !bindRequest(c, &profile, "Invalid input: a JSON import profile is required") {
+		return
+	}
+	profile.SetDefaults()
+	fields := validateImportProfile(&profile)
+	if !importProfileName.MatchString(name) {
+		fields.add("name", "name must be 1 to 64 lowercase letters, digits, hyphens or underscores")
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	raw, _ := json.Marshal(profile)
+	var updatedAt time.Time
+	err := db.QueryRow(c.Request.Context(),
+		`INSERT INTO import_profiles (name, profile) VALUES ($1, $2)
+		 ON CONFLICT (name) DO UPDATE SET profile = EXCLUDED.profile, updated_at = NOW()
+		 RETURNING updated_at`,
+		name, raw).Scan(&updatedAt)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to store import profile"})
+		return
+	}
+	c.JSON(http.StatusOK, ImportProfile{Name: name, Profile: profile, UpdatedAt: updatedAt.UTC().Format(time.RFC3339)})
+}
+
+// @Summary Delete a CSV import profile
+// @Description Remove a stored mapping of a bank CSV export
+// @Tags admin
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Profile name" example(acme-bank)
+// @Success 204 "Import profile deleted"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Import profile not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/import-profiles/{name} [delete]
+func DeleteImportProfile(c *gin.Context) {
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM import_profiles WHERE name = $1", c.Param("name"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete import profile"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Import profile not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Import a CSV export into a customer's account
+// @Description Post the rows of a bank's CSV export to a customer, read with a stored import profile. The whole file is read first: if any line cannot be read, nothing is posted and every bad line is reported. Rows are then posted in file order, each on the date of its date column as value date, and each row's outcome is reported; a row that cannot be posted, e.g. for lack of funds, does not stop the rest. Rows whose reference was already used are reported as duplicates when the profile has unique_references. At most 1000 rows are imported at once, and the file is bounded by MAX_REQUEST_BODY_BYTES. With Prefer: respond-async the file is still read straight away, but its rows are posted in the background: the response is 202 with the queued job, whose status is at the Location header, and the job's result is the import's outcome. A queued import is attempted once, so rows are never posted twice.
+// @Tags admin
+// @Accept text/csv
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param profile query string true "Import profile name" example(acme-bank)
+// @Param Prefer header string false "respond-async to post the rows in the background"
+// @Param file body string true "CSV export"
+// @Success 200 {object} ImportResult "Rows imported"
+// @Success 202 {object} QueueJob "Import queued"
+// @Header 202 {string} Location "Job status"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID or unreadable file; fields lists the bad lines"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer or import profile not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/imports [post]
+func ImportCSV(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	profile, ok := importProfileParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	rows, ok := readCSVRows(c, profile)
+	if !ok {
+		return
+	}
+	if preferAsync(c) {
+		jobID, err := enqueueJob(ctx, db, JobCSVImport, &customerID, CSVImportJob{
+			Profile:          profile.Name,
+			UniqueReferences: profile.UniqueReferences,
+			Rows:             rows,
+		})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to queue import"})
+			return
+		}
+		respondQueued(c, customerID, jobID, JobCSVImport)
+		return
+	}
+	c.JSON(http.StatusOK, importRows(ctx, customerID, profile.Name, profile.UniqueReferences, rows))
+}
+
+// CSVImportJob is the payload of an import.csv job: the rows of a file
+// already read with its profile
+type CSVImportJob struct {
+	Profile          string          `json:"profile"`
+	UniqueReferences bool            `json:"unique_references"`
+	Rows             []csvimport.Row `json:"rows"`
+}
+
+// runCSVImport posts the rows of an import.csv job; its result is the
+// ImportResult
+func runCSVImport(ctx context.Context, job jobqueue.Job) (interface{}, error) {
+	var payload CSVImportJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || job.CustomerID == nil {
+		return nil, jobqueue.Permanent(fmt.Errorf("invalid import job: %v", err))
+	}
+	return importRows(ctx, *job.CustomerID, payload.Profile, payload.UniqueReferences, payload.Rows), nil
+}
+
+// importRows posts rows in file order, each on its own, and sums up what
+// became of them
+func importRows(ctx context.Context, customerID uuid.UUID, profile string, uniqueReferences bool, rows []csvimport.Row) ImportResult {
+	result := ImportResult{Profile: profile, CustomerID: customerID, Rows: make([]ImportedRow, 0, len(rows))}
+	for _, row := range rows {
+		imported := importRow(ctx, customerID, uniqueReferences, row)
+		switch imported.Status {
+		case ledger.StatusPosted:
+			result.Posted++
+		case ledger.StatusHeld, ledger.StatusPendingApproval:
+			result.Held++
+		case "duplicate":
+			result.Duplicates++
+		default:
+			result.Failed++
+		}
+		result.Rows = append(result.Rows, imported)
+	}
+	if result.Posted+result.Held > 0 {
+		invalidateBalances(ctx, customerID)
+	}
+	return result
+}
+
+// importProfileParam loads the profile named by the profile query
+// parameter, answering the request itself when it cannot. It reports
+// whether the handler should continue.
+func importProfileParam(c *gin.Context) (ImportProfile, bool) {
+	name := c.Query("profile")
+	if name == "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: profile is required"})
+		return ImportProfile{}, false
+	}
+	profile, err := loadImportProfile(c, name)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Import profile not found"})
+		return profile, false
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch import profile"})
+		return profile, false
+	}
+	return profile, true
+}
+
+// readCSVRows reads the CSV file in the request body with profile,
+// answering with 400 and every line that cannot be read when it fails. It
+// reports whether the handler should continue.
+func readCSVRows(c *gin.Context, profile ImportProfile) ([]csvimport.Row, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+		return nil, false
+	}
+	rows, err := profile.Read(body, maxImportRows)
+	var rowErrs csvimport.RowErrors
+	switch {
+	case errors.As(err, &rowErrs):
+		var fields fieldErrors
+		for _, e := range rowErrs {
+			fields.add(fmt.Sprintf("line %d", e.Line), e.Message)
+		}
+		respondValidationError(c, fields)
+		return nil, false
+	case errors.Is(err, csvimport.ErrTooManyRows):
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Invalid input: at most %d rows can be read at once", maxImportRows)})
+		return nil, false
+	case err != nil:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid CSV file: " + err.Error()})
+		return nil, false
+	}
+	return rows, true
+}
+
+// importRow posts one row of an import, reporting what became of it
+func importRow(ctx context.Context, customerID uuid.UUID, uniqueReferences bool, row csvimport.Row) ImportedRow {
+	imported := ImportedRow{
+		Line:      row.Line,
+		Type:      row.Type,
+		Amount:    row.Amount,
+		ValueDate: row.Date.Format(dateLayout),
+		Reference: row.Reference,
+		Status:    "failed",
+	}
+	if msg := validateMoney("amount", row.Amount); msg != "" {
+		imported.Error = msg
+		return imported
+	}
+	// Exports are of the past, so only the future is bounded
+	if row.Date.After(time.Now().UTC().AddDate(0, 0, valueDateWindow)) {
+		imported.Error = fmt.Sprintf("Value date is more than %d days ahead", valueDateWindow)
+		return imported
+	}
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID:      customerID,
+		Type:            row.Type,
+		Amount:          row.Amount,
+		ValueDate:       row.Date,
+		Reference:       row.Reference,
+		UniqueReference: uniqueReferences && row.Reference != "",
+		// A bank export can hold the same payment twice, e.g. two equal
+		// card payments on a day; each row is a transaction of its own
+		AllowDuplicate: true,
+	})
+	if err != nil {
+		var violation *ledger.ViolationError
+		var duplicate *ledger.DuplicateReferenceError
+		switch {
+		case errors.As(err, &duplicate):
+			imported.Status = "duplicate"
+			imported.TransactionID = &duplicate.TransactionID
+		case errors.Is(err, ledger.ErrUnknownTransactionType):
+			imported.Error = "Unknown transaction type " + row.Type
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			imported.Error = "Insufficient balance"
+		case errors.Is(err, ledger.ErrOverpayment):
+			imported.Error = "Payment exceeds the amount owed"
+		case errors.As(err, &violation):
+			imported.Error = violation.Message
+		default:
+			imported.Error = "Failed to post transaction"
+		}
+		return imported
+	}
+	imported.Status = result.Status
+	imported.TransactionID = &result.TransactionID
+	if result.Status == ledger.StatusRejected {
+		imported.Error = "Transaction rejected by fraud rules"
+	}
+	return imported
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutImportProfile(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.PUT("/admin/import-profiles/:name", PutImportProfile)
+	send := func(name string, body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("PUT", "/admin/import-profiles/"+name, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("Acme_Bank", map[string]interface{}{
+		"columns":    map[string]string{"date": "Date", "amount": "Amount"},
+		"type_map":   map[string]string{"FEE": "no_such_type"},
+		"debit_type": "credit",
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	for _, field := range []string{"name", "columns.date", "type_map", "debit_type"} {
+		assert.Contains(t, w.Body.String(), `"field":"`+field+`"`)
+	}
+
+	stored := &capturedArg{}
+	mock.ExpectQuery(`INSERT INTO import_profiles \(name, profile\) VALUES \(\$1, \$2\)`).
+		WithArgs("acme-bank", stored).
+		WillReturnRows(pgxmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	w = send("acme-bank", map[string]interface{}{
+		"delimiter":         ";",
+		"has_header":        true,
+		"columns":           map[string]string{"date": "Date", "amount": "Amount", "type": "Kind"},
+		"date_format":       "DD.MM.YYYY",
+		"decimal_separator": ",",
+		"type_map":          map[string]string{"FEE": "fee"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var profile ImportProfile
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+	assert.Equal(t, "acme-bank", profile.Name)
+	assert.Equal(t, "credit", profile.CreditType, "defaults are filled in")
+	raw, _ := stored.value.([]byte)
+	assert.Contains(t, string(raw), `"debit_type":"debit"`)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportCSV(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.POST("/admin/customers/:customer_id/imports", ImportCSV)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	profile := `{"delimiter": ";", "has_header": true, "columns": {"date": "Datum", "amount": "Betrag", "type": "Art", "reference": "Zweck"},
+		"date_format": "DD.MM.YYYY", "decimal_separator": ",", "thousands_separator": ".", "type_map": {"Entgelt": "fee"},
+		"credit_type": "credit", "debit_type": "debit", "unique_references": true}`
+	expectProfile := func() {
+		mock.ExpectQuery(`SELECT profile, updated_at FROM import_profiles WHERE name = \$1`).
+			WithArgs("sparkasse").
+			WillReturnRows(pgxmock.NewRows([]string{"profile", "updated_at"}).AddRow([]byte(profile), time.Now()))
+	}
+	send := func(customerID uuid.UUID, csv string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/customers/"+customerID.String()+"/imports?profile=sparkasse", strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	today := time.Now().UTC()
+	date := func(daysAgo int) string { return today.AddDate(0, 0, -daysAgo).Format("02.01.2006") }
+
+	// A line that cannot be read stops the whole file
+	expectProfile()
+	w := send(customer.ID, "Datum;Art;Zweck;Betrag\n"+date(3)+";Gutschrift;Gehalt;1.000,00\n31.02.2025;Entgelt;Gebühr;-5,00\n")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"line 3"`)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Zero(t, balance.Amount)
+
+	file := "Datum;Art;Zweck;Betrag\n" +
+		date(3) + ";Gutschrift;Gehalt;1.000,00\n" +
+		date(2) + ";Entgelt;Kontoführung;-4,50\n" +
+		date(1) + ";Lastschrift;Miete;-2.000,00\n"
+	expectProfile()
+	w = send(customer.ID, file)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result ImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Posted)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Rows, 3)
+	assert.Equal(t, "fee", result.Rows[1].Type)
+	assert.Equal(t, 4.5, result.Rows[1].Amount)
+	assert.Equal(t, "Insufficient balance", result.Rows[2].Error)
+	tx, err := memory.GetTransaction(ctx, customer.ID, *result.Rows[0].TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, today.AddDate(0, 0, -3).Format(dateLayout), tx.ValueDate.Format(dateLayout))
+	assert.Equal(t, "Gehalt", tx.Reference)
+	balance, _ = memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, 995.5, balance.Amount)
+
+	// Importing the file again only skips what it posted
+	expectProfile()
+	w = send(customer.ID, file)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Duplicates)
+	assert.Equal(t, 1, result.Failed)
+
+	expectProfile()
+	assert.Equal(t, http.StatusNotFound, send(uuid.New(), file).Code)
+	mock.ExpectQuery(`FROM import_profiles`).WithArgs("sparkasse").WillReturnRows(pgxmock.NewRows([]string{"profile", "updated_at"}))
+	assert.Equal(t, http.StatusNotFound, send(customer.ID, file).Code)
+
+	// A file imported asynchronously is parsed up front and queued
+	expectProfile()
+	mock.ExpectExec(`INSERT INTO queue_jobs \(id, kind, customer_id, payload, max_attempts\)`).
+		WithArgs(pgxmock.AnyArg(), JobCSVImport, &customer.ID, pgxmock.AnyArg(), 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	req := httptest.NewRequest("POST", "/admin/customers/"+customer.ID.String()+"/imports?profile=sparkasse", strings.NewReader(file))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Prefer", "respond-async")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job QueueJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/v1/admin/queue/jobs/"+job.ID.String(), w.Header().Get("Location"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCustomerStats(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.GET("/customers/:customer_id/stats", GetCustomerStats)
+
+	customerID := uuid.New()
+	columns := []string{"currency", "credits", "credit_count", "debits", "debit_count", "count", "average", "first", "last"}
+	first := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	last := time.Date(2025, 4, 8, 17, 9, 17, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		row        []interface{}
+		wantStatus int
+		want       CustomerStats
+	}{
+		{
+			name:       "with transactions",
+			row:        []interface{}{"USD", float64(500), 2, float64(120), 3, 5, float64(124), &first, &last},
+			wantStatus: http.StatusOK,
+			want: CustomerStats{CustomerID: customerID, Currency: "USD", LifetimeCredits: 500, CreditCount: 2, LifetimeDebits: 120, DebitCount: 3,
+				TransactionCount: 5, AverageAmount: 124, FirstActivityAt: "2024-01-15T09:30:00Z", LastActivityAt: "2025-04-08T17:09:17Z"},
+		},
+		{
+			name:       "no transactions yet",
+			row:        []interface{}{"EUR", float64(0), 0, float64(0), 0, 0, float64(0), (*time.Time)(nil), (*time.Time)(nil)},
+			wantStatus: http.StatusOK,
+			want:       CustomerStats{CustomerID: customerID, Currency: "EUR"},
+		},
+		{name: "unknown customer", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := pgxmock.NewRows(columns)
+			if tt.row != nil {
+				rows.AddRow(tt.row...)
+			}
+			mock.ExpectQuery(`FROM customers c LEFT JOIN transactions t ON t.customer_id = c.id AND t.status = 'posted'`).
+				WithArgs(customerID).
+				WillReturnRows(rows)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/"+customerID.String()+"/stats", nil))
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus == http.StatusOK {
+				var stats CustomerStats
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+				assert.Equal(t, tt.want, stats)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/nope/stats", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CustomerTokenRoutes are the routes a self-service customer token may read,
+// for its own customer only
+var CustomerTokenRoutes = []string{
+	"/customers/:customer_id/balance",
+	"/customers/:customer_id/transactions",
+	"/customers/:customer_id/transactions/:transaction_id",
+}
+
+// CustomerToken is a self-service access token bound to one customer
+// @Description Token an end-user app uses to read its own customer's balance and transactions
+type CustomerToken struct {
+	ID         uuid.UUID `json:"token_id" format:"uuid"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	Name       string    `json:"name" example:"Mobile app"`
+	// Token is only returned when the token is issued
+	Token     string `json:"token,omitempty" example:"ctk_3f9a1c0e5b7d2f4a6c8e0b1d3f5a7c9e1b3d5f7a9c0e2b4d6f8a0c2e4b6d8f0a"`
+	ExpiresAt string `json:"expires_at,omitempty" example:"2025-07-08T17:09:17Z" format:"date-time"`
+	RevokedAt string `json:"revoked_at,omitempty" example:"2025-05-01T09:00:00Z" format:"date-time"`
+	CreatedAt string `json:"created_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// CustomerTokenRequest issues a self-service token
+// @Description Label and lifetime of a new customer token
+type CustomerTokenRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"Mobile app"`
+	// ExpiresInDays defaults to a token that does not expire
+	ExpiresInDays *int `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=365" example:"90"`
+}
+
+const customerTokenColumns = "id, customer_id, name, expires_at, revoked_at, created_at"
+
+func scanCustomerToken(row pgx.Row) (CustomerToken, error) {
+	var t CustomerToken
+	var expiresAt, revokedAt *time.Time
+	var createdAt time.Time
+	if err := row.Scan(&t.ID, &t.CustomerID, &t.Name, &expiresAt, &revokedAt, &createdAt); err != nil {
+		return CustomerToken{}, err
+	}
+	if expiresAt != nil {
+		t.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+	if revokedAt != nil {
+		t.RevokedAt = revokedAt.UTC().Format(time.RFC3339)
+	}
+	t.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return t, nil
+}
+
+// newCustomerToken returns a random token and the hash it is stored under.
+// Only the hash is kept, so a leaked table does not leak working tokens.
+func newCustomerToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := middleware.CustomerTokenPrefix + hex.EncodeToString(b)
+	return token, hashCustomerToken(token), nil
+}
+
+func hashCustomerToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// LookupCustomerToken returns the customer a live token is bound to, or ""
+// when the token is unknown, revoked or expired. It satisfies
+// middleware.CustomerTokenLookup.
+func LookupCustomerToken(ctx context.Context, token string) (string, error) {
+	var customerID uuid.UUID
+	err := db.QueryRow(ctx,
+		"SELECT customer_id FROM customer_tokens WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())",
+		hashCustomerToken(token)).Scan(&customerID)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return customerID.String(), nil
+}
+
+// @Summary Issue a customer token
+// @Description Issue a self-service token bound to one customer. Sent as `Authorization: Bearer <token>`, it can only read that customer's balance and transactions; any other request made with it is refused. The token is returned once and only its hash is stored. Issuing is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param token body CustomerTokenRequest true "Token"
+// @Success 201 {object} CustomerToken "Token issued"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/tokens [post]
+func IssueCustomerToken(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req CustomerTokenRequest
+	if !bindRequest(c, &req, "Invalid input: name is required") {
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		t := time.Now().UTC().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+	ctx := c.Request.Context()
+
+	token, hash, err := newCustomerToken()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+		customerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	t, err := scanCustomerToken(tx.QueryRow(ctx,
+		"INSERT INTO customer_tokens (id, customer_id, name, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING "+customerTokenColumns,
+		uuid.New(), customerID, req.Name, hash, expiresAt))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to issue token"})
+		return
+	}
+	if err := recordAudit(ctx, tx, c.GetString(middleware.ActorKey), "customer_token.issued", "customer_token", t.ID, &customerID, map[string]interface{}{
+		"name":       t.Name,
+		"expires_at": t.ExpiresAt,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	t.Token = token
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// @Summary List a customer's tokens
+// @Description List the self-service tokens issued to a customer, newest first, including revoked and expired ones. The tokens themselves are not included.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} CustomerToken "Tokens"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/tokens [get]
+func ListCustomerTokens(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+customerTokenColumns+" FROM customer_tokens WHERE customer_id = $1 ORDER BY created_at DESC",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch tokens"})
+		return
+	}
+	defer rows.Close()
+
+	tokens := []CustomerToken{}
+	for rows.Next() {
+		t, err := scanCustomerToken(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan token"})
+			return
+		}
+		tokens = append(tokens, t)
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary Revoke a customer token
+// @Description Revoke a self-service token so it stops working immediately. Revocation is recorded in the audit log.
+// @Tags admin
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param token_id path string true "Token ID" format(uuid)
+// @Success 204 "Token revoked"
+// @Failure 400 {object} ErrorResponse "Invalid customer or token ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Token not found or already revoked"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/tokens/{token_id} [delete]
+func RevokeCustomerToken(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	tokenID, err := uuid.Parse(c.Param("token_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid token ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE customer_tokens SET revoked_at = NOW() WHERE id = $1 AND customer_id = $2 AND revoked_at IS NULL",
+		tokenID, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke token"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Token not found or already revoked"})
+		return
+	}
+	if err := recordAudit(ctx, tx, c.GetString(middleware.ActorKey), "customer_token.revoked", "customer_token", tokenID, &customerID, map[string]interface{}{}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
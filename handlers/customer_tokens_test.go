@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var customerTokenRowColumns = []string{"id", "customer_id", "name", "expires_at", "revoked_at", "created_at"}
+
+func TestIssueCustomerToken(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/customers/:customer_id/tokens", IssueCustomerToken)
+
+	customerID := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "issues a token",
+			body:       `{"name": "Mobile app", "expires_in_days": 90}`,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM customers WHERE id = \$1\)`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+				expires := time.Now().AddDate(0, 0, 90)
+				mock.ExpectQuery(`INSERT INTO customer_tokens \(id, customer_id, name, token_hash, expires_at\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "Mobile app", pgxmock.AnyArg(), pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows(customerTokenRowColumns).
+						AddRow(uuid.New(), customerID, "Mobile app", &expires, (*time.Time)(nil), time.Now()))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "", "customer_token.issued", "customer_token", pgxmock.AnyArg(), &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "unknown customer",
+			body:       `{"name": "Mobile app"}`,
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "missing name",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "expiry too long",
+			body:       `{"name": "Mobile app", "expires_in_days": 1000}`,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			req := httptest.NewRequest("POST", "/admin/customers/"+customerID.String()+"/tokens", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var token CustomerToken
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &token))
+				assert.True(t, strings.HasPrefix(token.Token, middleware.CustomerTokenPrefix))
+				assert.NotEmpty(t, token.ExpiresAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevokeCustomerToken(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.DELETE("/admin/customers/:customer_id/tokens/:token_id", RevokeCustomerToken)
+
+	customerID, tokenID := uuid.New(), uuid.New()
+	path := "/admin/customers/" + customerID.String() + "/tokens/" + tokenID.String()
+
+	t.Run("revokes the token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE customer_tokens SET revoked_at = NOW\(\) WHERE id = \$1 AND customer_id = \$2 AND revoked_at IS NULL`).
+			WithArgs(tokenID, customerID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(pgxmock.AnyArg(), "", "customer_token.revoked", "customer_token", tokenID, &customerID, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", path, nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE customer_tokens`).
+			WithArgs(tokenID, customerID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+		mock.ExpectRollback()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", path, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLookupCustomerToken(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	customerID := uuid.New()
+	mock.ExpectQuery(`SELECT customer_id FROM customer_tokens WHERE token_hash = \$1 AND revoked_at IS NULL`).
+		WithArgs(hashCustomerToken("ctk_live")).
+		WillReturnRows(pgxmock.NewRows([]string{"customer_id"}).AddRow(customerID))
+	mock.ExpectQuery(`SELECT customer_id FROM customer_tokens`).
+		WithArgs(hashCustomerToken("ctk_revoked")).
+		WillReturnError(pgx.ErrNoRows)
+
+	subject, err := LookupCustomerToken(context.Background(), "ctk_live")
+	assert.NoError(t, err)
+	assert.Equal(t, customerID.String(), subject)
+
+	subject, err = LookupCustomerToken(context.Background(), "ctk_revoked")
+	assert.NoError(t, err)
+	assert.Empty(t, subject)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
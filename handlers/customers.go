@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Address represents a postal address belonging to a customer
+// @Description Customer postal address
+type Address struct {
+	AddressID  uuid.UUID `json:"address_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	Type       string    `json:"type" example:"home" enums:"home,mailing,business"`
+	Line1      string    `json:"line1" example:"1 Main Street" maxLength:"255"`
+	Line2      string    `json:"line2,omitempty" example:"Apt 4" maxLength:"255"`
+	City       string    `json:"city" example:"Springfield" maxLength:"100"`
+	Region     string    `json:"region,omitempty" example:"IL" maxLength:"100"`
+	PostalCode string    `json:"postal_code" example:"62701" maxLength:"20"`
+	Country    string    `json:"country" example:"US" minLength:"2" maxLength:"2"`
+	IsPrimary  bool      `json:"is_primary" example:"true"`
+}
+
+// CustomerUpdateRequest represents a partial update to a customer's details.
+// Omitted fields are left unchanged; an empty email or phone_number clears it.
+type CustomerUpdateRequest struct {
+	Name        *string `json:"name,omitempty" example:"John Doe"`
+	Email       *string `json:"email,omitempty" example:"john.doe@example.com"`
+	PhoneNumber *string `json:"phone_number,omitempty" example:"+15551234567"`
+	Timezone    *string `json:"timezone,omitempty" example:"Europe/Berlin"`
+}
+
+var (
+	countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// customerDayStart and customerMonthStart are SQL expressions for the start
+// of the current calendar day and month in the timezone of the customer
+// bound to $1, so daily and monthly limits reset at the customer's midnight
+const (
+	customerDayStart   = "date_trunc('day', NOW(), (SELECT timezone FROM customers WHERE id = $1))"
+	customerMonthStart = "date_trunc('month', NOW(), (SELECT timezone FROM customers WHERE id = $1))"
+)
+
+// validateContactDetails returns a message describing the first invalid contact field
+func validateContactDetails(email, phone string) string {
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email || len(email) > 255 {
+			return "email must be a valid address"
+		}
+	}
+	if phone != "" && !e164Pattern.MatchString(phone) {
+		return "phone_number must be in E.164 format"
+	}
+	return ""
+}
+
+// validateAddress normalizes an address in place and returns a message
+// describing the first invalid field
+func validateAddress(a *Address) string {
+	a.Type = strings.ToLower(strings.TrimSpace(a.Type))
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+
+	if a.Type == "" {
+		a.Type = "home"
+	}
+	switch {
+	case a.Type != "home" && a.Type != "mailing" && a.Type != "business":
+		return "address type must be one of home, mailing, business"
+	case a.Line1 == "" || len(a.Line1) > 255 || len(a.Line2) > 255:
+		return "address line1 is required and lines must be at most 255 characters"
+	case a.City == "" || len(a.City) > 100 || len(a.Region) > 100:
+		return "address city is required and city/region must be at most 100 characters"
+	case a.PostalCode == "" || len(a.PostalCode) > 20:
+		return "address postal_code is required and must be at most 20 characters"
+	case !countryCodePattern.MatchString(a.Country):
+		return "address country must be an ISO 3166-1 alpha-2 code"
+	}
+	return ""
+}
+
+// nullableString maps an empty string to SQL NULL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func toStoreAddress(a Address) store.Address {
+	return store.Address{
+		ID:         a.AddressID,
+		Type:       a.Type,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		IsPrimary:  a.IsPrimary,
+	}
+}
+
+func fromStoreAddress(a store.Address) Address {
+	return Address{
+		AddressID:  a.ID,
+		Type:       a.Type,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		IsPrimary:  a.IsPrimary,
+	}
+}
+
+func loadCustomer(ctx context.Context, customerID uuid.UUID) (CustomerResponse, error) {
+	customer, err := ledgerStore.GetCustomer(ctx, customerID)
+	if err != nil {
+		return CustomerResponse{CustomerID: customerID}, err
+	}
+	resp := CustomerResponse{
+		CustomerID:         customerID,
+		Name:               customer.Name,
+		Balance:            customer.Balance,
+		VerificationStatus: customer.VerificationStatus,
+		Email:              customer.Email,
+		PhoneNumber:        customer.PhoneNumber,
+		AccountType:        customer.AccountType,
+		Timezone:           customer.Timezone,
+		BalanceType:        customer.BalanceType,
+		CreditLimit:        customer.CreditLimit,
+		Currency:           customer.Currency,
+		Addresses:          make([]Address, len(customer.Addresses)),
+	}
+	if customer.DateOfBirth != nil {
+		resp.DateOfBirth = customer.DateOfBirth.Format("2006-01-02")
+	}
+	for i, a := range customer.Addresses {
+		resp.Addresses[i] = fromStoreAddress(a)
+	}
+	return resp, nil
+}
+
+// @Summary Get a customer
+// @Description Get a customer's account and contact details
+// @Tags customers
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {object} CustomerResponse "Customer details"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id} [get]
+func GetCustomer(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	customer, err := loadCustomer(c.Request.Context(), customerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get customer"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// @Summary Update a customer
+// @Description Update a customer's name, contact details and timezone. The timezone sets where the customer's days and months begin for daily and monthly limits.
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param customer body CustomerUpdateRequest true "Fields to update"
+// @Success 200 {object} CustomerResponse "Customer updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id} [patch]
+func UpdateCustomer(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req CustomerUpdateRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: name, email, phone_number and timezone must be strings"})
+		return
+	}
+	var fields fieldErrors
+	if req.Name != nil {
+		name := normalizeText(*req.Name)
+		if msg := validateName(name); msg != "" {
+			fields.add("name", msg)
+		}
+		req.Name = &name
+	}
+	if req.Email != nil {
+		if msg := validateContactDetails(*req.Email, ""); msg != "" {
+			fields.add("email", msg)
+		}
+	}
+	if req.PhoneNumber != nil {
+		if msg := validateContactDetails("", *req.PhoneNumber); msg != "" {
+			fields.add("phone_number", msg)
+		}
+	}
+	if req.Timezone != nil {
+		if msg := validateTimezone(*req.Timezone); msg != "" {
+			fields.add("timezone", msg)
+		}
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	if err := piiKeys.EncryptAll(req.Name, req.Email, req.PhoneNumber); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to encrypt customer details"})
+		return
+	}
+
+	// Removing the phone number also opts the customer out of SMS
+	tag, err := db.Exec(c.Request.Context(),
+		`UPDATE customers SET
+			name = COALESCE($1, name),
+			email = CASE WHEN $2::text IS NULL THEN email ELSE NULLIF($2, '') END,
+			phone_number = CASE WHEN $3::text IS NULL THEN phone_number ELSE NULLIF($3, '') END,
+			sms_opt_in = CASE WHEN $3::text = '' THEN FALSE ELSE sms_opt_in END,
+			timezone = COALESCE($4, timezone)
+		WHERE id = $5`,
+		req.Name, req.Email, req.PhoneNumber, req.Timezone, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update customer"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	customer, err := loadCustomer(c.Request.Context(), customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get customer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// @Summary Add an address
+// @Description Add a postal address to a customer
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param address body Address true "Address"
+// @Success 201 {object} Address "Address created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/addresses [post]
+func CreateAddress(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var address Address
+	if err := bindJSON(c, &address); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: line1, city, postal_code and country are required"})
+		return
+	}
+	if msg := validateAddress(&address); msg != "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + msg})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := ledgerStore.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	exists, err := tx.CustomerExists(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	stored := toStoreAddress(address)
+	if err := tx.AddAddress(ctx, customerID, &stored); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create address"})
+		return
+	}
+	address.AddressID = stored.ID
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// @Summary Replace an address
+// @Description Replace an existing customer address
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param address_id path string true "Address ID" format(uuid)
+// @Param address body Address true "Address"
+// @Success 200 {object} Address "Address updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/addresses/{address_id} [put]
+func UpdateAddress(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	addressID, err := uuid.Parse(c.Param("address_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid address ID"})
+		return
+	}
+
+	var address Address
+	if err := bindJSON(c, &address); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: line1, city, postal_code and country are required"})
+		return
+	}
+	if msg := validateAddress(&address); msg != "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + msg})
+		return
+	}
+	address.AddressID = addressID
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if address.IsPrimary {
+		if _, err := tx.Exec(ctx,
+			"UPDATE customer_addresses SET is_primary = FALSE WHERE customer_id = $1 AND is_primary AND id <> $2",
+			customerID, addressID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update address"})
+			return
+		}
+	}
+
+	sealed := address
+	if err := piiKeys.EncryptAll(&sealed.Line1, &sealed.Line2, &sealed.City, &sealed.Region, &sealed.PostalCode); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to encrypt address"})
+		return
+	}
+	tag, err := tx.Exec(ctx,
+		"UPDATE customer_addresses SET address_type = $1, line1 = $2, line2 = $3, city = $4, region = $5, postal_code = $6, country = $7, is_primary = $8 WHERE id = $9 AND customer_id = $10",
+		address.Type, sealed.Line1, nullableString(sealed.Line2), sealed.City, nullableString(sealed.Region),
+		sealed.PostalCode, address.Country, address.IsPrimary, addressID, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update address"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Address not found"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// @Summary Delete an address
+// @Description Remove an address from a customer
+// @Tags customers
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param address_id path string true "Address ID" format(uuid)
+// @Success 204 "Address deleted"
+// @Failure 400 {object} ErrorResponse "Invalid customer or address ID"
+// @Failure 404 {object} ErrorResponse "Address not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/addresses/{address_id} [delete]
+func DeleteAddress(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	addressID, err := uuid.Parse(c.Param("address_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid address ID"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(),
+		"DELETE FROM customer_addresses WHERE id = $1 AND customer_id = $2",
+		addressID, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete address"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Address not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/events"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCustomerWithContactDetails(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers", CreateCustomer)
+
+	address := map[string]interface{}{
+		"line1":       "1 Main Street",
+		"city":        "Springfield",
+		"postal_code": "62701",
+		"country":     "us",
+		"is_primary":  true,
+	}
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name: "with email, phone and address",
+			payload: map[string]interface{}{
+				"name":            "John Doe",
+				"initial_balance": 100,
+				"email":           "john.doe@example.com",
+				"phone_number":    "+15551234567",
+				"addresses":       []interface{}{address},
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO customers`).
+					WithArgs(pgxmock.AnyArg(), "John Doe", float64(100), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "checking", "UTC", "deposit", (*float64)(nil), "USD").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE customer_addresses SET is_primary = FALSE`).
+					WithArgs(pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+				mock.ExpectExec(`INSERT INTO customer_addresses`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), "home", "1 Main Street", pgxmock.AnyArg(), "Springfield", pgxmock.AnyArg(), "62701", "US", true).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.CustomerCreated)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "invalid email",
+			payload:    map[string]interface{}{"name": "John Doe", "email": "not-an-email"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "invalid phone number",
+			payload:    map[string]interface{}{"name": "John Doe", "phone_number": "12345"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "invalid address country",
+			payload: map[string]interface{}{
+				"name": "John Doe",
+				"addresses": []interface{}{map[string]interface{}{
+					"line1": "1 Main Street", "city": "Springfield", "postal_code": "62701", "country": "USA",
+				}},
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/customers", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetCustomer(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id", GetCustomer)
+
+	customerID := uuid.New()
+	email := "john.doe@example.com"
+	mock.ExpectQuery(`SELECT name, balance, date_of_birth, verification_status, email, phone_number, account_type, timezone, balance_type, COALESCE\(credit_limit, 0\), currency FROM customers WHERE id = \$1`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"name", "balance", "date_of_birth", "verification_status", "email", "phone_number", "account_type", "timezone", "balance_type", "credit_limit", "currency"}).
+			AddRow("John Doe", float64(100), nil, "verified", &email, nil, "checking", "America/Chicago", "deposit", float64(0), "EUR"))
+	mock.ExpectQuery(`SELECT id, address_type, .* FROM customer_addresses WHERE customer_id = \$1`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "address_type", "line1", "line2", "city", "region", "postal_code", "country", "is_primary"}).
+			AddRow(uuid.New(), "home", "1 Main Street", "", "Springfield", "IL", "62701", "US", true))
+
+	req := httptest.NewRequest("GET", "/customers/"+customerID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var customer CustomerResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &customer))
+	assert.Equal(t, email, customer.Email)
+	assert.Equal(t, "America/Chicago", customer.Timezone)
+	assert.Equal(t, "deposit", customer.BalanceType)
+	assert.Equal(t, "EUR", customer.Currency)
+	assert.Len(t, customer.Addresses, 1)
+
+	missing := uuid.New()
+	mock.ExpectQuery(`SELECT name, balance, date_of_birth, verification_status, email, phone_number, account_type, timezone, balance_type, COALESCE\(credit_limit, 0\), currency FROM customers WHERE id = \$1`).
+		WithArgs(missing).
+		WillReturnError(pgx.ErrNoRows)
+	req = httptest.NewRequest("GET", "/customers/"+missing.String(), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateCustomer(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.PATCH("/customers/:customer_id", UpdateCustomer)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "update email",
+			payload:    map[string]interface{}{"email": "jane@example.com"},
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectExec(`UPDATE customers SET`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectQuery(`SELECT name, balance, date_of_birth, verification_status, email, phone_number, account_type, timezone, balance_type, COALESCE\(credit_limit, 0\), currency FROM customers`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"name", "balance", "date_of_birth", "verification_status", "email", "phone_number", "account_type", "timezone", "balance_type", "credit_limit", "currency"}).
+						AddRow("John Doe", float64(100), nil, "unverified", nil, nil, "checking", "UTC", "deposit", float64(0), "USD"))
+				mock.ExpectQuery(`FROM customer_addresses`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "address_type", "line1", "line2", "city", "region", "postal_code", "country", "is_primary"}))
+			},
+		},
+		{
+			name:       "update timezone",
+			payload:    map[string]interface{}{"timezone": "Europe/Berlin"},
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectExec(`timezone = COALESCE\(\$4, timezone\)`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectQuery(`SELECT name, balance, date_of_birth, verification_status, email, phone_number, account_type, timezone, balance_type, COALESCE\(credit_limit, 0\), currency FROM customers`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"name", "balance", "date_of_birth", "verification_status", "email", "phone_number", "account_type", "timezone", "balance_type", "credit_limit", "currency"}).
+						AddRow("John Doe", float64(100), nil, "unverified", nil, nil, "checking", "Europe/Berlin", "deposit", float64(0), "USD"))
+				mock.ExpectQuery(`FROM customer_addresses`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "address_type", "line1", "line2", "city", "region", "postal_code", "country", "is_primary"}))
+			},
+		},
+		{
+			name:       "empty name",
+			payload:    map[string]interface{}{"name": "  "},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "invalid email",
+			payload:    map[string]interface{}{"email": "jane@"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "invalid timezone",
+			payload:    map[string]interface{}{"timezone": "Mars/Olympus_Mons"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "unknown customer",
+			payload:    map[string]interface{}{"name": "Jane"},
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectExec(`UPDATE customers SET`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("PATCH", "/customers/"+customerID.String(), bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteAddress(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.DELETE("/customers/:customer_id/addresses/:address_id", DeleteAddress)
+
+	customerID := uuid.New()
+	addressID := uuid.New()
+	mock.ExpectExec(`DELETE FROM customer_addresses WHERE id = \$1 AND customer_id = \$2`).
+		WithArgs(addressID, customerID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(`DELETE FROM customer_addresses WHERE id = \$1 AND customer_id = \$2`).
+		WithArgs(addressID, customerID).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req := httptest.NewRequest("DELETE", "/customers/"+customerID.String()+"/addresses/"+addressID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
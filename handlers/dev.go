@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"ledger-service/seed"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Seed demo data
+// @Description Create demo customers with randomized transaction histories. Only registered when APP_ENV=development. Omitted fields use the defaults (50 customers, up to 40 transactions each, over 90 days); reusing a seed reproduces the same data.
+// @Tags dev
+// @Accept json
+// @Produce json
+// @Param options body seed.Options false "Size of the data set"
+// @Success 201 {object} seed.Result "Demo data created"
+// @Failure 400 {object} ErrorResponse "Invalid options"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /dev/seed [post]
+func SeedDemoData(c *gin.Context) {
+	opts := seed.DefaultOptions()
+	if err := bindJSON(c, &opts); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: customers, max_transactions, days and seed must be integers"})
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + err.Error()})
+		return
+	}
+
+	result, err := seed.Generate(c.Request.Context(), db, opts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to seed demo data"})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
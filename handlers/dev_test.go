@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedDemoData(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/dev/seed", SeedDemoData)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "seeds data",
+			body:       `{"customers": 2, "max_transactions": 3, "days": 10, "seed": 1}`,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectCopyFrom(pgx.Identifier{"customers"}, []string{"id", "name", "balance", "email", "phone_number", "account_type"}).
+					WillReturnResult(2)
+				mock.ExpectCopyFrom(pgx.Identifier{"transactions"}, []string{"id", "customer_id", "type", "amount", "status", "created_at"}).
+					WillReturnResult(4)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "too many customers",
+			body:       `{"customers": 1000000}`,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"customers": "lots"}`,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			req := httptest.NewRequest("POST", "/dev/seed", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, float64(2), resp["customers"])
+				assert.Equal(t, float64(1), resp["seed"])
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
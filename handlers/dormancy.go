@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DormancyConfig decides when accounts go dormant and what happens then
+type DormancyConfig struct {
+	// Days without a posted transaction before an account is dormant; zero
+	// disables detection
+	Days int
+	// Fee is charged once when an account goes dormant, if the balance
+	// covers it; zero charges nothing
+	Fee float64
+	// FreezeDebits refuses customer debits from dormant accounts until they
+	// are reactivated
+	FreezeDebits bool
+}
+
+var dormancy DormancyConfig
+
+// InitDormancy sets the dormancy rules
+func InitDormancy(cfg DormancyConfig) {
+	dormancy = cfg
+}
+
+// dormancyActor is the audit log actor for accounts the dormancy job flags
+const dormancyActor = "system"
+
+// dormancyBatchSize is how many candidate accounts are read per query
+const dormancyBatchSize = 100
+
+// errAccountDormant refuses a debit from a dormant account while debits are
+// frozen
+var errAccountDormant = &ledger.ViolationError{Message: "Account is dormant; it must be reactivated before it can be debited"}
+
+// dormantCandidate matches accounts, not yet dormant, with no posted
+// transaction since $1 and opened or last reactivated before it
+const dormantCandidate = "c.dormant_since IS NULL AND COALESCE(c.reactivated_at, c.created_at) < $1 AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.customer_id = c.id AND t.status = 'posted' AND t.created_at >= $1)"
+
+// checkDormantDebit refuses a debit from customerID when debits from dormant
+// accounts are frozen and the account is dormant
+func checkDormantDebit(ctx context.Context, tx pgx.Tx, customerID uuid.UUID) error {
+	if !dormancy.FreezeDebits {
+		return nil
+	}
+	var dormant bool
+	if err := tx.QueryRow(ctx,
+		"SELECT dormant_since IS NOT NULL FROM customers WHERE id = $1",
+		customerID).Scan(&dormant); err != nil {
+		return err
+	}
+	if dormant {
+		return errAccountDormant
+	}
+	return nil
+}
+
+// DormantAccount is an account flagged for inactivity
+// @Description Dormant account
+type DormantAccount struct {
+	CustomerID   uuid.UUID `json:"customer_id" format:"uuid"`
+	Name         string    `json:"name" example:"Jane Doe"`
+	Currency     string    `json:"currency" example:"USD"`
+	Balance      float64   `json:"balance" example:"12.5"`
+	DormantSince string    `json:"dormant_since" example:"2025-04-08T03:00:00Z" format:"date-time"`
+	// LastActivityAt is the last posted transaction before the account went
+	// dormant, omitted when it never had one
+	LastActivityAt string `json:"last_activity_at,omitempty" example:"2024-04-02T10:15:00Z" format:"date-time"`
+}
+
+// ReactivateRequest explains why a dormant account is reactivated
+// @Description Reactivation reason
+type ReactivateRequest struct {
+	Reason string `json:"reason" binding:"required,min=10" example:"Customer called in and verified their identity"`
+}
+
+// ReactivateResponse describes a reactivated account
+// @Description Reactivated account
+type ReactivateResponse struct {
+	CustomerID    uuid.UUID `json:"customer_id" format:"uuid"`
+	DormantSince  string    `json:"dormant_since" example:"2025-04-08T03:00:00Z" format:"date-time"`
+	ReactivatedAt string    `json:"reactivated_at" example:"2025-05-02T09:30:00Z" format:"date-time"`
+	Actor         string    `json:"actor" example:"alice"`
+}
+
+// ProcessDormantAccounts flags every account with no posted transaction in
+// the configured number of days before now and returns how many it flagged
+func ProcessDormantAccounts(ctx context.Context, now time.Time) (int, error) {
+	if dormancy.Days <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -dormancy.Days)
+	flagged := 0
+	after := uuid.Nil
+	for {
+		rows, err := db.Query(ctx,
+			"SELECT c.id FROM customers c WHERE "+dormantCandidate+" AND c.id > $2 ORDER BY c.id LIMIT $3",
+			cutoff, after, dormancyBatchSize)
+		if err != nil {
+			return flagged, err
+		}
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return flagged, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return flagged, err
+		}
+
+		for _, id := range ids {
+			ok, err := markDormant(ctx, id, cutoff)
+			if err != nil {
+				log.Printf("Failed to flag account %s as dormant: %v", id, err)
+			} else if ok {
+				flagged++
+			}
+			after = id
+		}
+		if len(ids) < dormancyBatchSize {
+			return flagged, nil
+		}
+	}
+}
+
+// markDormant flags one account as dormant if it still qualifies, charging
+// the dormancy fee when the balance covers it. It reports whether the
+// account was flagged.
+func markDormant(ctx context.Context, customerID uuid.UUID, cutoff time.Time) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Re-check under the row lock, and skip accounts being posted to
+	var lastActivity *time.Time
+	err = tx.QueryRow(ctx,
+		"SELECT (SELECT MAX(t.created_at) FROM transactions t WHERE t.customer_id = c.id AND t.status = 'posted') FROM customers c WHERE c.id = $2 AND "+dormantCandidate+" FOR UPDATE OF c SKIP LOCKED",
+		cutoff, customerID).Scan(&lastActivity)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, customerID)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE customers SET dormant_since = NOW() WHERE id = $1",
+		customerID); err != nil {
+		return false, err
+	}
+
+	var fee float64
+	if dormancy.Fee > 0 {
+		floor := account
+		floor.AllowNegative, floor.Overdraft = false, 0
+		if _, err := ledger.Apply(floor, txtype.Debit, dormancy.Fee); err == nil {
+			if _, err := bookSystemPosting(ctx, tx, &account, "fee", dormancy.Fee); err != nil {
+				return false, err
+			}
+			fee = dormancy.Fee
+		}
+	}
+
+	if err := recordAudit(ctx, tx, dormancyActor, "customer.dormant", "customer", customerID, &customerID, map[string]interface{}{
+		"last_activity_at": lastActivity,
+		"fee":              fee,
+		"debits_frozen":    dormancy.FreezeDebits,
+	}); err != nil {
+		return false, err
+	}
+	if err := enqueueEvent(ctx, tx, events.AccountDormant, &customerID, AccountDormantEventData{
+		LastActivityAt: lastActivity,
+		Fee:            fee,
+		DebitsFrozen:   dormancy.FreezeDebits,
+	}); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	if fee > 0 {
+		invalidateBalances(ctx, customerID)
+	}
+	return true, nil
+}
+
+// @Summary List dormant accounts
+// @Description List the accounts flagged as dormant for having no posted transaction in DORMANCY_DAYS, most recently flagged first
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param page query int false "Page number (1-based)" minimum(1) default(1)
+// @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Success 200 {array} DormantAccount "Dormant accounts"
+// @Failure 400 {object} ErrorResponse "Invalid pagination parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Header 200 {string} X-Total-Count "Total number of dormant accounts"
+// @Header 200 {string} X-Page "Current page number"
+// @Header 200 {string} X-Page-Size "Items per page"
+// @Header 200 {string} X-Total-Pages "Total number of pages"
+// @Router /admin/dormant-accounts [get]
+func ListDormantAccounts(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var totalCount int
+	if err := db.QueryRow(ctx,
+		"SELECT COUNT(*) FROM customers WHERE dormant_since IS NOT NULL").Scan(&totalCount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get total count"})
+		return
+	}
+
+	// The dormancy fee posts as the account goes dormant, so last activity
+	// only counts transactions from before
+	rows, err := db.Query(ctx,
+		"SELECT c.id, c.name, c.currency, c.balance, c.dormant_since, (SELECT MAX(t.created_at) FROM transactions t WHERE t.customer_id = c.id AND t.status = 'posted' AND t.created_at < c.dormant_since) FROM customers c WHERE c.dormant_since IS NOT NULL ORDER BY c.dormant_since DESC, c.id LIMIT $1 OFFSET $2",
+		pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch dormant accounts"})
+		return
+	}
+	defer rows.Close()
+
+	accounts := []DormantAccount{}
+	for rows.Next() {
+		var a DormantAccount
+		var dormantSince time.Time
+		var lastActivity *time.Time
+		if err := rows.Scan(&a.CustomerID, &a.Name, &a.Currency, &a.Balance, &dormantSince, &lastActivity); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan dormant account"})
+			return
+		}
+		if err := piiKeys.DecryptAll(&a.Name); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to decrypt dormant account"})
+			return
+		}
+		a.DormantSince = dormantSince.UTC().Format(time.RFC3339)
+		if lastActivity != nil {
+			a.LastActivityAt = lastActivity.UTC().Format(time.RFC3339)
+		}
+		accounts = append(accounts, a)
+	}
+
+	c.Header("X-Total-Count", fmt.Sprintf("%d", totalCount))
+	c.Header("X-Page", fmt.Sprintf("%d", page))
+	c.Header("X-Page-Size", fmt.Sprintf("%d", pageSize))
+	c.Header("X-Total-Pages", fmt.Sprintf("%d", (totalCount+pageSize-1)/pageSize))
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// @Summary Reactivate a dormant account
+// @Description Clear an account's dormant flag so it can be debited again. The account goes dormant again after another DORMANCY_DAYS without a posted transaction. Reactivation is recorded in the audit log under the calling operator and publishes an account.reactivated event.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator reactivating the account"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param reactivation body ReactivateRequest true "Reason"
+// @Success 200 {object} ReactivateResponse "Account reactivated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Account is not dormant"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/reactivate [post]
+func ReactivateAccount(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req ReactivateRequest
+	if !bindRequest(c, &req, "Invalid input: reason (at least 10 characters) is required") {
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var dormantSince *time.Time
+	if err := tx.QueryRow(ctx,
+		"SELECT dormant_since FROM customers WHERE id = $1 FOR UPDATE",
+		customerID).Scan(&dormantSince); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get customer"})
+		}
+		return
+	}
+	if dormantSince == nil {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Account is not dormant", Code: "account_not_dormant"})
+		return
+	}
+
+	var reactivatedAt time.Time
+	if err := tx.QueryRow(ctx,
+		"UPDATE customers SET dormant_since = NULL, reactivated_at = NOW() WHERE id = $1 RETURNING reactivated_at",
+		customerID).Scan(&reactivatedAt); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update customer"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "customer.reactivated", "customer", customerID, &customerID, map[string]interface{}{
+		"dormant_since": dormantSince,
+		"reason":        req.Reason,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := enqueueEvent(ctx, tx, events.AccountReactivated, &customerID, AccountReactivatedEventData{
+		DormantSince: *dormantSince,
+		Actor:        actor,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record event"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReactivateResponse{
+		CustomerID:    customerID,
+		DormantSince:  dormantSince.UTC().Format(time.RFC3339),
+		ReactivatedAt: reactivatedAt.UTC().Format(time.RFC3339),
+		Actor:         actor,
+	})
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessDormantAccounts(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	InitDormancy(DormancyConfig{Days: 365, Fee: 5, FreezeDebits: true})
+	defer InitDormancy(DormancyConfig{})
+
+	now := time.Date(2025, 4, 8, 3, 0, 0, 0, time.UTC)
+	cutoff := now.AddDate(-1, 0, 0)
+	funded, empty, busy := uuid.New(), uuid.New(), uuid.New()
+	lastActivity := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT c.id FROM customers c WHERE c.dormant_since IS NULL AND .* AND c.id > \$2 ORDER BY c.id LIMIT \$3`).
+		WithArgs(cutoff, uuid.Nil, dormancyBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(funded).AddRow(empty).AddRow(busy))
+
+	// Funded account: flagged and charged the fee
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM customers c WHERE c.id = \$2 AND .* FOR UPDATE OF c SKIP LOCKED`).
+		WithArgs(cutoff, funded).
+		WillReturnRows(pgxmock.NewRows([]string{"last_activity"}).AddRow(&lastActivity))
+	mock.ExpectQuery(lockCustomerQuery).
+		WithArgs(funded).
+		WillReturnRows(lockedCustomer(40, "checking", false))
+	mock.ExpectExec(`UPDATE customers SET dormant_since = NOW\(\) WHERE id = \$1`).
+		WithArgs(funded).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectSystemPosting(funded, "fee", "fees_income", "credit", 5)
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(pgxmock.AnyArg(), dormancyActor, "customer.dormant", "customer", funded, &funded, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(events.AccountDormant)
+	mock.ExpectCommit()
+
+	// Empty account: flagged without a fee
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE OF c SKIP LOCKED`).
+		WithArgs(cutoff, empty).
+		WillReturnRows(pgxmock.NewRows([]string{"last_activity"}).AddRow((*time.Time)(nil)))
+	mock.ExpectQuery(lockCustomerQuery).
+		WithArgs(empty).
+		WillReturnRows(lockedCustomer(2, "checking", false))
+	mock.ExpectExec(`UPDATE customers SET dormant_since = NOW\(\)`).
+		WithArgs(empty).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(pgxmock.AnyArg(), dormancyActor, "customer.dormant", "customer", empty, &empty, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(events.AccountDormant)
+	mock.ExpectCommit()
+
+	// Busy account: posted to or locked since the candidate query
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE OF c SKIP LOCKED`).
+		WithArgs(cutoff, busy).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	n, err := ProcessDormantAccounts(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("disabled", func(t *testing.T) {
+		InitDormancy(DormancyConfig{})
+		n, err := ProcessDormantAccounts(context.Background(), now)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReactivateAccount(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/customers/:customer_id/reactivate", ReactivateAccount)
+
+	customerID := uuid.New()
+	dormantSince := time.Date(2025, 4, 8, 3, 0, 0, 0, time.UTC)
+	body := `{"reason": "Customer called in and verified their identity"}`
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "reactivates a dormant account",
+			body:       body,
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT dormant_since FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"dormant_since"}).AddRow(&dormantSince))
+				mock.ExpectQuery(`UPDATE customers SET dormant_since = NULL, reactivated_at = NOW\(\) WHERE id = \$1 RETURNING reactivated_at`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"reactivated_at"}).AddRow(time.Now()))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "", "customer.reactivated", "customer", customerID, &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.AccountReactivated)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "account is not dormant",
+			body:       body,
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT dormant_since FROM customers`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"dormant_since"}).AddRow((*time.Time)(nil)))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "unknown customer",
+			body:       body,
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT dormant_since FROM customers`).
+					WithArgs(customerID).
+					WillReturnError(pgx.ErrNoRows)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "reason too short",
+			body:       `{"reason": "ok"}`,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			req := httptest.NewRequest("POST", "/admin/customers/"+customerID.String()+"/reactivate", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var resp ReactivateResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "2025-04-08T03:00:00Z", resp.DormantSince)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDormantAccountDebitsFrozen(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	InitDormancy(DormancyConfig{Days: 365, FreezeDebits: true})
+	defer InitDormancy(DormancyConfig{})
+
+	ctx := context.Background()
+	fromID, toID := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	// The payer is refused before anything is written
+	expectTransferLocks(fromID, toID, 100, 5)
+	mock.ExpectQuery(`SELECT dormant_since IS NOT NULL FROM customers WHERE id = \$1`).
+		WithArgs(fromID).
+		WillReturnRows(pgxmock.NewRows([]string{"dormant"}).AddRow(true))
+	tx, err := db.Begin(ctx)
+	assert.NoError(t, err)
+
+	_, err = postTransfer(ctx, tx, fromID, toID, 40, "")
+	assert.ErrorIs(t, err, errAccountDormant)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
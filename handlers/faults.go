@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"ledger-service/middleware"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// errInjectedDBDrop is returned for database calls on requests that
+// middleware.FaultInjection chose to fail
+var errInjectedDBDrop = errors.New("injected fault: database connection dropped")
+
+// faultInjectingDB fails every call made with a context marked by
+// middleware.WithDBFault, standing in for a dropped connection
+type faultInjectingDB struct {
+	DBConn
+}
+
+// InitFaultInjection makes database calls honour db_drop faults. Call it
+// after InitDB, and only outside production.
+func InitFaultInjection() {
+	db = faultInjectingDB{db}
+}
+
+func (d faultInjectingDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	if middleware.DBFaultFromContext(ctx) {
+		return nil, errInjectedDBDrop
+	}
+	return d.DBConn.Begin(ctx)
+}
+
+func (d faultInjectingDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if middleware.DBFaultFromContext(ctx) {
+		return nil, errInjectedDBDrop
+	}
+	return d.DBConn.BeginTx(ctx, txOptions)
+}
+
+func (d faultInjectingDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if middleware.DBFaultFromContext(ctx) {
+		return pgconn.CommandTag{}, errInjectedDBDrop
+	}
+	return d.DBConn.Exec(ctx, sql, args...)
+}
+
+func (d faultInjectingDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if middleware.DBFaultFromContext(ctx) {
+		return nil, errInjectedDBDrop
+	}
+	return d.DBConn.Query(ctx, sql, args...)
+}
+
+func (d faultInjectingDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if middleware.DBFaultFromContext(ctx) {
+		return errRow{errInjectedDBDrop}
+	}
+	return d.DBConn.QueryRow(ctx, sql, args...)
+}
+
+// errRow is a pgx.Row whose Scan always fails
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...interface{}) error {
+	return r.err
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjectingDB(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	InitFaultInjection()
+
+	router.GET("/customers/:customer_id/balance", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(middleware.WithDBFault(c.Request.Context()))
+		c.Next()
+	}, GetBalance)
+
+	req := httptest.NewRequest("GET", "/customers/"+uuid.New().String()+"/balance", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = db.Begin(middleware.WithDBFault(context.Background()))
+	assert.ErrorIs(t, err, errInjectedDBDrop)
+	_, err = db.Exec(middleware.WithDBFault(context.Background()), "SELECT 1")
+	assert.ErrorIs(t, err, errInjectedDBDrop)
+}
@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"ledger-service/fraud"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// FraudRuleRequest represents a request to create or update a fraud rule
+type FraudRuleRequest struct {
+	Name     string         `json:"name" binding:"required" example:"Large debit spike"`
+	RuleType fraud.RuleType `json:"rule_type" binding:"required" example:"amount_spike" enums:"amount_spike,rapid_debits,unusual_hours,duplicate"`
+	Action   fraud.Action   `json:"action" binding:"required" example:"hold" enums:"flag,hold,reject"`
+	Params   fraud.Params   `json:"params"`
+	Enabled  *bool          `json:"enabled,omitempty" example:"true"`
+}
+
+// FraudDecision represents the fraud engine's verdict on a transaction
+// @Description Fraud decision recorded for review
+type FraudDecision struct {
+	DecisionID    uuid.UUID     `json:"decision_id" format:"uuid"`
+	TransactionID uuid.UUID     `json:"transaction_id" format:"uuid"`
+	CustomerID    uuid.UUID     `json:"customer_id" format:"uuid"`
+	Action        fraud.Action  `json:"action" example:"hold" enums:"flag,hold,reject"`
+	Matches       []fraud.Match `json:"matches"`
+	ReviewStatus  string        `json:"review_status" example:"open" enums:"open,approved,rejected"`
+	ReviewedBy    *string       `json:"reviewed_by,omitempty"`
+	ReviewNote    *string       `json:"review_note,omitempty"`
+	CreatedAt     string        `json:"created_at" format:"date-time"`
+}
+
+// FraudReviewRequest represents an operator's review of a fraud decision
+type FraudReviewRequest struct {
+	Outcome string `json:"outcome" binding:"required,oneof=approve reject" example:"approve" enums:"approve,reject"`
+	Note    string `json:"note" example:"Customer confirmed the purchase"`
+}
+
+var (
+	fraudEngine *fraud.Engine
+)
+
+// InitFraudEngine sets the engine evaluated on every transaction. A nil engine disables fraud checks.
+func InitFraudEngine(e *fraud.Engine) {
+	fraudEngine = e
+}
+
+// LoadFraudRules refreshes the engine's rule set from the database
+func LoadFraudRules(ctx context.Context) error {
+	if fraudEngine == nil {
+		return nil
+	}
+	rules, err := queryFraudRules(ctx)
+	if err != nil {
+		return err
+	}
+	fraudEngine.SetRules(rules)
+	return nil
+}
+
+func queryFraudRules(ctx context.Context) ([]fraud.Rule, error) {
+	rows, err := db.Query(ctx,
+		"SELECT id, name, rule_type, action, params, enabled FROM fraud_rules ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []fraud.Rule{}
+	for rows.Next() {
+		var r fraud.Rule
+		var params []byte
+		if err := rows.Scan(&r.ID, &r.Name, &r.Type, &r.Action, &params, &r.Enabled); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(params, &r.Params); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// rowQuerier is satisfied by both the connection and an open transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// fraudStats answers the engine's history questions from the transactions table
+type fraudStats struct {
+	q rowQuerier
+}
+
+func (s fraudStats) AverageAmount(ctx context.Context, customerID uuid.UUID, txType string, since time.Time) (float64, int, error) {
+	var avg float64
+	var count int
+	err := s.q.QueryRow(ctx,
+		"SELECT COALESCE(AVG(amount), 0), COUNT(*) FROM transactions WHERE customer_id = $1 AND type IN (SELECT code FROM transaction_types WHERE postable AND direction = $2) AND status = 'posted' AND created_at >= $3",
+		customerID, txType, since).Scan(&avg, &count)
+	return avg, count, err
+}
+
+func (s fraudStats) CountSame(ctx context.Context, customerID uuid.UUID, code string, amount float64, since time.Time) (int, error) {
+	var count int
+	err := s.q.QueryRow(ctx,
+		"SELECT COUNT(*) FROM transactions WHERE customer_id = $1 AND type = $2 AND amount = $3 AND status <> 'rejected' AND created_at >= $4",
+		customerID, code, amount, since).Scan(&count)
+	return count, err
+}
+
+func (s fraudStats) CountDebits(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := s.q.QueryRow(ctx,
+		"SELECT COUNT(*) FROM transactions WHERE customer_id = $1 AND type IN (SELECT code FROM transaction_types WHERE postable AND direction = 'debit') AND status <> 'rejected' AND created_at >= $2",
+		customerID, since).Scan(&count)
+	return count, err
+}
+
+// evaluateFraud runs the fraud rules for a posting inside the posting
+// transaction, judging the time of day in the customer's timezone
+func evaluateFraud(ctx context.Context, tx pgx.Tx, p ledger.Posting, timezone string) (fraud.Decision, error) {
+	if fraudEngine == nil || !fraudEngine.Active() {
+		return fraud.Decision{Action: fraud.ActionAllow}, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return fraudEngine.Evaluate(ctx, fraudStats{q: tx}, fraud.Transaction{
+		CustomerID:     p.CustomerID,
+		Type:           string(p.Direction),
+		Code:           p.Type,
+		Amount:         p.Amount,
+		Time:           time.Now().In(loc),
+		AllowDuplicate: p.AllowDuplicate,
+	})
+}
+
+// recordFraudDecision stores a decision for later review
+func recordFraudDecision(ctx context.Context, tx pgx.Tx, transactionID, customerID uuid.UUID, decision fraud.Decision) error {
+	matches, err := json.Marshal(decision.Matches)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO fraud_decisions (id, transaction_id, customer_id, action, matches) VALUES ($1, $2, $3, $4, $5)",
+		uuid.New(), transactionID, customerID, string(decision.Action), matches)
+	return err
+}
+
+// @Summary List fraud rules
+// @Description List all configured fraud rules
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} fraud.Rule "Fraud rules"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fraud/rules [get]
+func ListFraudRules(c *gin.Context) {
+	rules, err := queryFraudRules(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch fraud rules"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// @Summary Create a fraud rule
+// @Description Add a rule evaluated against every new transaction
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param rule body FraudRuleRequest true "Fraud rule"
+// @Success 201 {object} fraud.Rule "Fraud rule created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fraud/rules [post]
+func CreateFraudRule(c *gin.Context) {
+	rule, ok := bindFraudRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = uuid.New()
+
+	params, _ := json.Marshal(rule.Params)
+	_, err := db.Exec(c.Request.Context(),
+		"INSERT INTO fraud_rules (id, name, rule_type, action, params, enabled) VALUES ($1, $2, $3, $4, $5, $6)",
+		rule.ID, rule.Name, string(rule.Type), string(rule.Action), params, rule.Enabled)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create fraud rule"})
+		return
+	}
+	reloadFraudRules(c.Request.Context())
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary Update a fraud rule
+// @Description Replace the configuration of an existing fraud rule
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param rule_id path string true "Rule ID" format(uuid)
+// @Param rule body FraudRuleRequest true "Fraud rule"
+// @Success 200 {object} fraud.Rule "Fraud rule updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fraud/rules/{rule_id} [put]
+func UpdateFraudRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid rule ID"})
+		return
+	}
+	rule, ok := bindFraudRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = ruleID
+
+	params, _ := json.Marshal(rule.Params)
+	tag, err := db.Exec(c.Request.Context(),
+		"UPDATE fraud_rules SET name = $1, rule_type = $2, action = $3, params = $4, enabled = $5, updated_at = NOW() WHERE id = $6",
+		rule.Name, string(rule.Type), string(rule.Action), params, rule.Enabled, rule.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update fraud rule"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Rule not found"})
+		return
+	}
+	reloadFraudRules(c.Request.Context())
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// @Summary Delete a fraud rule
+// @Description Remove a fraud rule so it is no longer evaluated
+// @Tags admin
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param rule_id path string true "Rule ID" format(uuid)
+// @Success 204 "Fraud rule deleted"
+// @Failure 400 {object} ErrorResponse "Invalid rule ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fraud/rules/{rule_id} [delete]
+func DeleteFraudRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid rule ID"})
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM fraud_rules WHERE id = $1", ruleID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete fraud rule"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Rule not found"})
+		return
+	}
+	reloadFraudRules(c.Request.Context())
+
+	c.Status(http.StatusNoContent)
+}
+
+func bindFraudRule(c *gin.Context) (fraud.Rule, bool) {
+	var req FraudRuleRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: name, rule_type and action are required"})
+		return fraud.Rule{}, false
+	}
+	rule := fraud.Rule{
+		Name:    req.Name,
+		Type:    req.RuleType,
+		Action:  req.Action,
+		Params:  req.Params,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := rule.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + err.Error()})
+		return fraud.Rule{}, false
+	}
+	return rule, true
+}
+
+// reloadFraudRules refreshes the in-memory rules after an admin change. A
+// failure leaves the previous rule set active until the next change.
+func reloadFraudRules(ctx context.Context) {
+	_ = LoadFraudRules(ctx)
+}
+
+// @Summary List fraud decisions
+// @Description List recorded fraud decisions, newest first
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param review_status query string false "Filter by review status" Enums(open, approved, rejected)
+// @Param page query int false "Page number (1-based)" minimum(1) default(1)
+// @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Success 200 {array} FraudDecision "Fraud decisions"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fraud/decisions [get]
+func ListFraudDecisions(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	status := c.Query("review_status")
+	if status != "" && status != "open" && status != "approved" && status != "rejected" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid review status"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT id, transaction_id, customer_id, action, matches, review_status, reviewed_by, review_note, created_at FROM fraud_decisions WHERE ($1 = '' OR review_status = $1) ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch fraud decisions"})
+		return
+	}
+	defer rows.Close()
+
+	decisions := []FraudDecision{}
+	for rows.Next() {
+		var d FraudDecision
+		var matches []byte
+		var createdAt time.Time
+		if err := rows.Scan(&d.DecisionID, &d.TransactionID, &d.CustomerID, &d.Action, &matches,
+			&d.ReviewStatus, &d.ReviewedBy, &d.ReviewNote, &createdAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan fraud decision"})
+			return
+		}
+		_ = json.Unmarshal(matches, &d.Matches)
+		d.CreatedAt = createdAt.Format(time.RFC3339)
+		decisions = append(decisions, d)
+	}
+
+	c.JSON(http.StatusOK, decisions)
+}
+
+// @Summary Review a fraud decision
+// @Description Approve or reject a fraud decision. Approving a held transaction posts it; rejecting it discards it.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param decision_id path string true "Decision ID" format(uuid)
+// @Param review body FraudReviewRequest true "Review outcome"
+// @Success 200 {object} FraudDecision "Decision reviewed"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Decision not found"
+// @Failure 409 {object} ErrorResponse "Decision already reviewed"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fraud/decisions/{decision_id}/review [post]
+func ReviewFraudDecision(c *gin.Context) {
+	decisionID, err := uuid.Parse(c.Param("decision_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid decision ID"})
+		return
+	}
+	var req FraudReviewRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: outcome must be approve or reject"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var d FraudDecision
+	var matches []byte
+	var createdAt time.Time
+	var txType, txStatus string
+	var amount float64
+	err = tx.QueryRow(ctx,
+		"SELECT d.transaction_id, d.customer_id, d.action, d.matches, d.review_status, d.created_at, t.type, t.amount, t.status FROM fraud_decisions d JOIN transactions t ON t.id = d.transaction_id WHERE d.id = $1 FOR UPDATE",
+		decisionID).Scan(&d.TransactionID, &d.CustomerID, &d.Action, &matches, &d.ReviewStatus, &createdAt, &txType, &amount, &txStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Decision not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get fraud decision"})
+		}
+		return
+	}
+	if d.ReviewStatus != "open" {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Decision already reviewed"})
+		return
+	}
+
+	// Only held transactions change state on review; flagged ones are already
+	// posted and rejected ones never touched the balance
+	if txStatus == "held" {
+		newStatus := "rejected"
+		if req.Outcome == "approve" {
+			newStatus = "posted"
+
+			account, err := store.NewPostgresTx(tx).LockCustomer(ctx, d.CustomerID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+				return
+			}
+			// Released postings keep the zero floor on accounts allowed to go negative
+			account.AllowNegative = false
+			newBalance, err := ledger.Apply(account, directionOf(txType), amount)
+			if err != nil {
+				respondBalanceError(c, err)
+				return
+			}
+			if _, err = tx.Exec(ctx,
+				"UPDATE customers SET balance = $1 WHERE id = $2",
+				newBalance, d.CustomerID); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update balance"})
+				return
+			}
+		}
+		if _, err = tx.Exec(ctx,
+			"UPDATE transactions SET status = $1 WHERE id = $2",
+			newStatus, d.TransactionID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update transaction"})
+			return
+		}
+		if newStatus == "posted" {
+			if err := postCounterparty(ctx, tx, d.TransactionID, txType, amount); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post general ledger entry"})
+				return
+			}
+			if err := postReleasedRoundingDifference(ctx, tx, d.TransactionID); err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post rounding difference"})
+				return
+			}
+		}
+		if err := enqueueEvent(ctx, tx, transactionEventType(newStatus), &d.CustomerID, TransactionEventData{
+			TransactionID: d.TransactionID,
+			Type:          txType,
+			Amount:        amount,
+			Status:        newStatus,
+		}); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record event"})
+			return
+		}
+	}
+
+	d.DecisionID = decisionID
+	d.ReviewStatus = "rejected"
+	if req.Outcome == "approve" {
+		d.ReviewStatus = "approved"
+	}
+	actor := c.GetString(middleware.ActorKey)
+	d.ReviewedBy = &actor
+	d.ReviewNote = &req.Note
+	if _, err = tx.Exec(ctx,
+		"UPDATE fraud_decisions SET review_status = $1, reviewed_by = $2, review_note = $3, reviewed_at = NOW() WHERE id = $4",
+		d.ReviewStatus, actor, req.Note, decisionID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update fraud decision"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, d.CustomerID)
+
+	_ = json.Unmarshal(matches, &d.Matches)
+	d.CreatedAt = createdAt.Format(time.RFC3339)
+	c.JSON(http.StatusOK, d)
+}
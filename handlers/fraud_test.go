@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/fraud"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransactionFraudRules(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	engine := fraud.NewEngine()
+	InitFraudEngine(engine)
+	defer InitFraudEngine(nil)
+
+	router.POST("/transactions", CreateTransaction)
+
+	customerID := uuid.New()
+	rapid := func(action fraud.Action) []fraud.Rule {
+		return []fraud.Rule{{
+			ID: uuid.New(), Name: "rapid", Type: fraud.RuleRapidDebits, Action: action, Enabled: true,
+			Params: fraud.Params{MaxCount: 2, WindowSeconds: 60},
+		}}
+	}
+
+	tests := []struct {
+		name       string
+		rules      []fraud.Rule
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "held transaction leaves balance untouched",
+			rules:      rapid(fraud.ActionHold),
+			wantStatus: http.StatusAccepted,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectNoDebitLimits(customerID)
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE customer_id = \$1 AND type IN \(SELECT code FROM transaction_types WHERE postable AND direction = 'debit'\)`).
+					WithArgs(customerID, pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "debit", float64(100), "held").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO fraud_decisions`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), customerID, "hold", pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionHeld)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "rejected transaction is recorded",
+			rules:      rapid(fraud.ActionReject),
+			wantStatus: http.StatusUnprocessableEntity,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectNoDebitLimits(customerID)
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE customer_id = \$1 AND type IN \(SELECT code FROM transaction_types WHERE postable AND direction = 'debit'\)`).
+					WithArgs(customerID, pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "debit", float64(100), "rejected").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO fraud_decisions`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), customerID, "reject", pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionRejected)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "flagged transaction is posted",
+			rules:      rapid(fraud.ActionFlag),
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectNoDebitLimits(customerID)
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE customer_id = \$1 AND type IN \(SELECT code FROM transaction_types WHERE postable AND direction = 'debit'\)`).
+					WithArgs(customerID, pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(5))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(900), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "debit", float64(100), "posted").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO fraud_decisions`).
+					WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), customerID, "flag", pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine.SetRules(tt.rules)
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "debit",
+				"amount":      100,
+			})
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreateTransactionDuplicateRule(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	engine := fraud.NewEngine()
+	engine.SetRules([]fraud.Rule{{
+		ID: uuid.New(), Name: "duplicate", Type: fraud.RuleDuplicate, Action: fraud.ActionReject, Enabled: true,
+		Params: fraud.Params{WindowSeconds: 120},
+	}})
+	InitFraudEngine(engine)
+	defer InitFraudEngine(nil)
+
+	router.POST("/transactions", CreateTransaction)
+
+	customerID := uuid.New()
+	send := func(payload map[string]interface{}, idempotencyKey string) *httptest.ResponseRecorder {
+		jsonBytes, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expectPosted := func() {
+		mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+			WithArgs(float64(900), customerID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+			WithArgs(pgxmock.AnyArg(), customerID, "debit", float64(100), "posted").
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectEvent(events.TransactionPosted)
+		mock.ExpectCommit()
+	}
+	payment := map[string]interface{}{"customer_id": customerID, "type": "debit", "amount": 100}
+
+	// The same type and amount within the window is rejected
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCustomerQuery).
+		WithArgs(customerID).
+		WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+	expectNoDebitLimits(customerID)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE customer_id = \$1 AND type = \$2 AND amount = \$3 AND status <> 'rejected' AND created_at >= \$4`).
+		WithArgs(customerID, "debit", float64(100), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+		WithArgs(pgxmock.AnyArg(), customerID, "debit", float64(100), "rejected").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO fraud_decisions`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), customerID, "reject", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(events.TransactionRejected)
+	mock.ExpectCommit()
+	w := send(payment, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"duplicate_transaction"`)
+
+	// allow_duplicate and an idempotency key both skip the check
+	for _, key := range []string{"", "retry-42"} {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(customerID).
+			WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+		expectNoDebitLimits(customerID)
+		expectPosted()
+		body := map[string]interface{}{"customer_id": customerID, "type": "debit", "amount": 100, "allow_duplicate": key == ""}
+		assert.Equal(t, http.StatusCreated, send(body, key).Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateFraudRule(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/fraud/rules", CreateFraudRule)
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name: "valid rule",
+			payload: map[string]interface{}{
+				"name":      "night owl",
+				"rule_type": "unusual_hours",
+				"action":    "flag",
+				"params":    map[string]interface{}{"start_hour": 0, "end_hour": 5},
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectExec(`INSERT INTO fraud_rules`).
+					WithArgs(pgxmock.AnyArg(), "night owl", "unusual_hours", "flag", pgxmock.AnyArg(), true).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name: "invalid params",
+			payload: map[string]interface{}{
+				"name":      "spike",
+				"rule_type": "amount_spike",
+				"action":    "hold",
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "unknown action",
+			payload: map[string]interface{}{
+				"name":      "rapid",
+				"rule_type": "rapid_debits",
+				"action":    "block",
+				"params":    map[string]interface{}{"max_count": 3, "window_seconds": 60},
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/fraud/rules", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReviewFraudDecision(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/fraud/decisions/:decision_id/review", ReviewFraudDecision)
+
+	decisionID := uuid.New()
+	transactionID := uuid.New()
+	customerID := uuid.New()
+	decisionRows := func(reviewStatus, txStatus string) *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"transaction_id", "customer_id", "action", "matches", "review_status", "created_at", "type", "amount", "status"}).
+			AddRow(transactionID, customerID, fraud.ActionHold, []byte(`[]`), reviewStatus, time.Now(), "debit", float64(100), txStatus)
+	}
+
+	tests := []struct {
+		name       string
+		outcome    string
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "approve held transaction",
+			outcome:    "approve",
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT d.transaction_id, .* FROM fraud_decisions d JOIN transactions t`).
+					WithArgs(decisionID).
+					WillReturnRows(decisionRows("open", "held"))
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(500), "checking", false))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(400), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`UPDATE transactions SET status = \$1 WHERE id = \$2`).
+					WithArgs("posted", transactionID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectUnconverted(transactionID)
+				expectEvent(events.TransactionPosted)
+				mock.ExpectExec(`UPDATE fraud_decisions SET review_status = \$1`).
+					WithArgs("approved", pgxmock.AnyArg(), "", decisionID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "reject held transaction",
+			outcome:    "reject",
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT d.transaction_id, .* FROM fraud_decisions d JOIN transactions t`).
+					WithArgs(decisionID).
+					WillReturnRows(decisionRows("open", "held"))
+				mock.ExpectExec(`UPDATE transactions SET status = \$1 WHERE id = \$2`).
+					WithArgs("rejected", transactionID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectEvent(events.TransactionRejected)
+				mock.ExpectExec(`UPDATE fraud_decisions SET review_status = \$1`).
+					WithArgs("rejected", pgxmock.AnyArg(), "", decisionID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "already reviewed",
+			outcome:    "approve",
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT d.transaction_id, .* FROM fraud_decisions d JOIN transactions t`).
+					WithArgs(decisionID).
+					WillReturnRows(decisionRows("approved", "posted"))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(map[string]interface{}{"outcome": tt.outcome})
+			req := httptest.NewRequest("POST", "/admin/fraud/decisions/"+decisionID.String()+"/review", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
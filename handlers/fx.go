@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"ledger-service/fx"
+	"ledger-service/money"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// FXQuoteRequest represents a request for a locked exchange rate
+type FXQuoteRequest struct {
+	CustomerID   uuid.UUID `json:"customer_id" binding:"required,uuid" format:"uuid"`
+	FromCurrency string    `json:"from_currency" binding:"required,currency" example:"USD" enums:"USD,EUR,GBP"`
+	ToCurrency   string    `json:"to_currency" binding:"required,currency" example:"EUR" enums:"USD,EUR,GBP"`
+	Amount       float64   `json:"amount" binding:"required,money" example:"100" minimum:"0.01"`
+}
+
+// FXQuote represents a quoted rate that can be redeemed until it expires
+type FXQuote struct {
+	QuoteID         uuid.UUID `json:"quote_id" format:"uuid"`
+	CustomerID      uuid.UUID `json:"customer_id" format:"uuid"`
+	FromCurrency    string    `json:"from_currency" example:"USD"`
+	ToCurrency      string    `json:"to_currency" example:"EUR"`
+	Amount          float64   `json:"amount" example:"100"`
+	Rate            float64   `json:"rate" example:"0.9123"`
+	ConvertedAmount float64   `json:"converted_amount" example:"91.23"`
+	// Rounding is the mode applied to the converted amount
+	Rounding string `json:"rounding" example:"half_up" enums:"half_up,half_even,truncate,down,up"`
+	// RateProvider names the provider the rate came from, which is a
+	// fallback when the primary failed or was stale
+	RateProvider string `json:"rate_provider,omitempty" example:"exchangerate-api"`
+	ExpiresAt    string `json:"expires_at" example:"2025-04-08T17:09:47Z" format:"date-time"`
+}
+
+// mainCurrency is the default currency of general ledger accounts. Customer
+// balances are in each account's own base currency.
+const mainCurrency = store.DefaultCurrency
+
+var (
+	fxProvider fx.Provider
+	// roundingPolicy rounds converted amounts and loan interest and
+	// installments
+	roundingPolicy money.Policy
+	fxQuoteTTL     = 30 * time.Second
+)
+
+var (
+	errQuoteNotFound = errors.New("quote not found")
+	errQuoteUsed     = errors.New("quote already used")
+	errQuoteExpired  = errors.New("quote expired")
+)
+
+// InitFX sets the exchange rate provider, rounding policy and quote lifetime
+// used for currency conversion. A nil provider disables conversion between
+// currencies.
+func InitFX(p fx.Provider, rounding money.Policy, quoteTTL time.Duration) {
+	fxProvider = p
+	roundingPolicy = rounding
+	fxQuoteTTL = quoteTTL
+}
+
+// consumeFXQuote locks a customer's quote and marks it used, failing when it
+// has expired or was already redeemed
+func consumeFXQuote(ctx context.Context, tx pgx.Tx, quoteID, customerID uuid.UUID) (FXQuote, error) {
+	quote := FXQuote{QuoteID: quoteID, CustomerID: customerID}
+	var expiresAt time.Time
+	var usedAt *time.Time
+	err := tx.QueryRow(ctx,
+		"SELECT from_currency, to_currency, amount, rate, converted_amount, COALESCE(rounding, ''), COALESCE(rate_provider, ''), expires_at, used_at FROM fx_quotes WHERE id = $1 AND customer_id = $2 FOR UPDATE",
+		quoteID, customerID).Scan(&quote.FromCurrency, &quote.ToCurrency, &quote.Amount, &quote.Rate, &quote.ConvertedAmount, &quote.Rounding, &quote.RateProvider, &expiresAt, &usedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return FXQuote{}, errQuoteNotFound
+		}
+		return FXQuote{}, err
+	}
+	if usedAt != nil {
+		return FXQuote{}, errQuoteUsed
+	}
+	if !time.Now().Before(expiresAt) {
+		return FXQuote{}, errQuoteExpired
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE fx_quotes SET used_at = NOW() WHERE id = $1", quoteID); err != nil {
+		return FXQuote{}, err
+	}
+	quote.ExpiresAt = expiresAt.Format(time.RFC3339)
+	return quote, nil
+}
+
+// respondQuoteError maps consumeFXQuote errors to responses
+func respondQuoteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errQuoteNotFound):
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Quote not found"})
+	case errors.Is(err, errQuoteUsed):
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Quote has already been used"})
+	case errors.Is(err, errQuoteExpired):
+		respondError(c, http.StatusGone, ErrorResponse{Error: "Quote has expired"})
+	default:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to redeem quote"})
+	}
+}
+
+// @Summary Create an FX quote
+// @Description Quote a conversion rate and amount that is locked for a short time. Pass the returned quote_id to a move to convert at exactly the quoted rate; each quote can be redeemed once.
+// @Tags fx
+// @Accept json
+// @Produce json
+// @Param quote body FXQuoteRequest true "Quote request"
+// @Success 201 {object} FXQuote "Quote created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 502 {object} ErrorResponse "Exchange rate provider error"
+// @Failure 503 {object} ErrorResponse "Currency conversion is not configured"
+// @Router /fx/quotes [post]
+func CreateFXQuote(c *gin.Context) {
+	var req FXQuoteRequest
+	if !bindRequest(c, &req, "Invalid input: customer_id, from_currency, to_currency and amount (> 0) are required") {
+		return
+	}
+	if req.FromCurrency == req.ToCurrency {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: currencies must differ"})
+		return
+	}
+	if fxProvider == nil {
+		respondError(c, http.StatusServiceUnavailable, ErrorResponse{Error: "Currency conversion is not configured"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var exists bool
+	if err := db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+		req.CustomerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	rate, err := lookupRate(ctx, req.FromCurrency, req.ToCurrency)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to fetch exchange rate"})
+		return
+	}
+	converted, rounding := roundingPolicy.Convert(req.Amount, rate.Value, req.ToCurrency)
+	if converted <= 0 {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: amount is too small to convert"})
+		return
+	}
+
+	quote := FXQuote{
+		QuoteID:         uuid.New(),
+		CustomerID:      req.CustomerID,
+		FromCurrency:    req.FromCurrency,
+		ToCurrency:      req.ToCurrency,
+		Amount:          req.Amount,
+		Rate:            rate.Value,
+		ConvertedAmount: converted,
+		Rounding:        string(rounding),
+		RateProvider:    rate.Provider,
+	}
+	expiresAt := time.Now().Add(fxQuoteTTL).UTC()
+	_, err = db.Exec(ctx,
+		"INSERT INTO fx_quotes (id, customer_id, from_currency, to_currency, amount, rate, converted_amount, rounding, rate_provider, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)",
+		quote.QuoteID, quote.CustomerID, quote.FromCurrency, quote.ToCurrency, quote.Amount, quote.Rate, quote.ConvertedAmount, quote.Rounding, quote.RateProvider, expiresAt)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create quote"})
+		return
+	}
+	quote.ExpiresAt = expiresAt.Format(time.RFC3339)
+
+	c.JSON(http.StatusCreated, quote)
+}
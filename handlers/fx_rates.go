@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/fx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxRateHistory caps the rates returned by one history query
+const maxRateHistory = 1000
+
+var errRateNotFound = errors.New("no stored exchange rate")
+
+// FXRate is a stored exchange rate
+// @Description Rate fetched from a provider, kept so past conversions can be repeated
+type FXRate struct {
+	Rate     float64 `json:"rate" example:"0.9123"`
+	Provider string  `json:"provider,omitempty" example:"exchangerate-api"`
+	// EffectiveAt is when the provider last updated the rate, or when it was
+	// fetched if the provider does not say
+	EffectiveAt string `json:"effective_at" example:"2025-04-08T00:00:01Z" format:"date-time"`
+	FetchedAt   string `json:"fetched_at" example:"2025-04-08T09:14:52Z" format:"date-time"`
+}
+
+// FXRateHistory is the stored history of one currency pair
+// @Description Stored rates of a currency pair, most recent first
+type FXRateHistory struct {
+	Pair  string   `json:"pair" example:"USD/EUR"`
+	Rates []FXRate `json:"rates"`
+}
+
+// lookupRate fetches the current rate for a pair and stores it, so that
+// conversions made now can be repeated later. Failing to store the rate is
+// logged rather than failing the conversion.
+func lookupRate(ctx context.Context, from, to string) (fx.Rate, error) {
+	rate, err := fxProvider.Rate(ctx, from, to)
+	if err != nil || from == to || db == nil {
+		return rate, err
+	}
+	// Providers publish a rate for a period, so one row per update is kept;
+	// rates without an update time are kept at most once a minute
+	effectiveAt := rate.UpdatedAt
+	if effectiveAt.IsZero() {
+		effectiveAt = time.Now().UTC().Truncate(time.Minute)
+	}
+	if _, err := db.Exec(ctx,
+		`INSERT INTO fx_rates (from_currency, to_currency, provider, effective_at, rate) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (from_currency, to_currency, provider, effective_at) DO NOTHING`,
+		from, to, rate.Provider, effectiveAt, rate.Value); err != nil {
+		log.Printf("Failed to store exchange rate %s/%s: %v", from, to, err)
+	}
+	return rate, nil
+}
+
+// storedRate returns the rate for a pair that was in effect at a time: the
+// stored rate with the latest effective time not after it
+func storedRate(ctx context.Context, q rowQuerier, from, to string, at time.Time) (fx.Rate, error) {
+	if from == to {
+		return fx.Rate{Value: 1}, nil
+	}
+	var rate fx.Rate
+	err := q.QueryRow(ctx,
+		"SELECT rate, provider, effective_at FROM fx_rates WHERE from_currency = $1 AND to_currency = $2 AND effective_at <= $3 ORDER BY effective_at DESC, fetched_at DESC LIMIT 1",
+		from, to, at).Scan(&rate.Value, &rate.Provider, &rate.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fx.Rate{}, errRateNotFound
+		}
+		return fx.Rate{}, err
+	}
+	return rate, nil
+}
+
+// parsePair splits a pair written as FROM/TO
+func parsePair(pair string) (string, string, bool) {
+	from, to, ok := strings.Cut(strings.ToUpper(pair), "/")
+	return from, to, ok && isValidCurrency(from) && isValidCurrency(to) && from != to
+}
+
+// @Summary Get stored exchange rates
+// @Description List the exchange rates stored for a currency pair, most recent first. Every rate fetched for a conversion is stored with when it took effect. With date, the rates that applied during that UTC day are listed: the one in effect when the day began and any that took effect during it.
+// @Tags fx
+// @Produce json
+// @Param pair query string true "Currency pair as FROM/TO" example(USD/EUR)
+// @Param date query string false "UTC day (YYYY-MM-DD) to list the rates of" format(date)
+// @Param limit query int false "Maximum rates to return" default(100) maximum(1000)
+// @Success 200 {object} FXRateHistory "Stored rates"
+// @Failure 400 {object} ErrorResponse "Invalid pair, date or limit"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /fx/rates [get]
+func GetFXRates(c *gin.Context) {
+	from, to, ok := parsePair(c.Query("pair"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid pair: use two different supported currencies, e.g. USD/EUR"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > maxRateHistory {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		return
+	}
+
+	const columns = "SELECT rate, provider, effective_at, fetched_at FROM fx_rates WHERE from_currency = $1 AND to_currency = $2"
+	query := columns + " ORDER BY effective_at DESC, fetched_at DESC LIMIT $3"
+	args := []interface{}{from, to, limit}
+	if date := c.Query("date"); date != "" {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid date: use YYYY-MM-DD"})
+			return
+		}
+		query = "(" + columns + " AND effective_at < $3 ORDER BY effective_at DESC, fetched_at DESC LIMIT 1)" +
+			" UNION ALL (" + columns + " AND effective_at >= $3 AND effective_at < $4)" +
+			" ORDER BY effective_at DESC, fetched_at DESC LIMIT $5"
+		args = []interface{}{from, to, day, day.AddDate(0, 0, 1), limit}
+	}
+
+	rows, err := db.Query(c.Request.Context(), query, args...)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch exchange rates"})
+		return
+	}
+	defer rows.Close()
+
+	history := FXRateHistory{Pair: from + "/" + to, Rates: []FXRate{}}
+	for rows.Next() {
+		var r FXRate
+		var effectiveAt, fetchedAt time.Time
+		if err := rows.Scan(&r.Rate, &r.Provider, &effectiveAt, &fetchedAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan exchange rate"})
+			return
+		}
+		r.EffectiveAt = effectiveAt.UTC().Format(time.RFC3339)
+		r.FetchedAt = fetchedAt.UTC().Format(time.RFC3339)
+		history.Rates = append(history.Rates, r)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFXRates(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/fx/rates", GetFXRates)
+
+	day := time.Date(2025, 4, 8, 0, 0, 0, 0, time.UTC)
+	columns := []string{"rate", "provider", "effective_at", "fetched_at"}
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRates  int
+		setupMock  func()
+	}{
+		{
+			name:       "latest rates",
+			query:      "?pair=usd/eur&limit=2",
+			wantStatus: http.StatusOK,
+			wantRates:  2,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT rate, provider, effective_at, fetched_at FROM fx_rates WHERE from_currency = \$1 AND to_currency = \$2 ORDER BY`).
+					WithArgs("USD", "EUR", 2).
+					WillReturnRows(pgxmock.NewRows(columns).
+						AddRow(0.9123, "exchangerate-api", day.Add(time.Hour), day.Add(time.Hour+time.Minute)).
+						AddRow(0.9101, "frankfurter", day, day.Add(time.Minute)))
+			},
+		},
+		{
+			name:       "rates in effect during a day",
+			query:      "?pair=USD/EUR&date=2025-04-08",
+			wantStatus: http.StatusOK,
+			wantRates:  1,
+			setupMock: func() {
+				mock.ExpectQuery(`UNION ALL`).
+					WithArgs("USD", "EUR", day, day.AddDate(0, 0, 1), 100).
+					WillReturnRows(pgxmock.NewRows(columns).AddRow(0.9101, "frankfurter", day.Add(-time.Hour), day.Add(-time.Hour)))
+			},
+		},
+		{
+			name:       "same currency twice",
+			query:      "?pair=USD/USD",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "invalid date",
+			query:      "?pair=USD/EUR&date=08-04-2025",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "limit too large",
+			query:      "?pair=USD/EUR&limit=5000",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/fx/rates"+tt.query, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var history FXRateHistory
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+				assert.Equal(t, "USD/EUR", history.Pair)
+				assert.Len(t, history.Rates, tt.wantRates)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+
+	"ledger-service/money"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Sources of a rounding difference
+const (
+	roundingSourceMove        = "move"
+	roundingSourceTransaction = "transaction"
+)
+
+// FXRoundingSummary reports the rounding differences of one currency
+// @Description What conversions into a currency rounded away, and how much of it is posted
+type FXRoundingSummary struct {
+	Currency string `json:"currency" example:"USD"`
+	// Account is the general ledger account the differences are posted to
+	Account     string `json:"account" example:"fx_rounding_usd"`
+	Conversions int    `json:"conversions" example:"1520"`
+	// Difference is the exact converted amounts less the rounded ones
+	Difference float64 `json:"difference" example:"0.0734"`
+	// Posted is the account's balance, in whole minor units; Carried is the
+	// fraction of a minor unit not posted yet
+	Posted  float64 `json:"posted" example:"0.07"`
+	Carried float64 `json:"carried" example:"0.0034"`
+}
+
+// fxRoundingAccount is the general ledger account collecting what
+// conversions into currency round away
+func fxRoundingAccount(currency string) string {
+	return "fx_rounding_" + strings.ToLower(currency)
+}
+
+// postRoundingDifference records what rounding a conversion into currency
+// took away, exact less rounded, and posts every whole minor unit the
+// differences add up to into the currency's rounding account. Fractions of a
+// minor unit are carried in fx_rounding_totals until they make one, so the
+// general ledger stays in minor units and nothing is dropped. transactionID
+// links the entry to the customer transaction, when there is one.
+func postRoundingDifference(ctx context.Context, tx pgx.Tx, source string, sourceID uuid.UUID, transactionID *uuid.UUID, currency string, exact, rounded float64) error {
+	difference := math.Round((exact-rounded)*1e10) / 1e10
+	if difference == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO fx_rounding_differences (id, source, source_id, currency, exact_amount, rounded_amount, difference) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		uuid.New(), source, sourceID, currency, exact, rounded, difference); err != nil {
+		return err
+	}
+
+	// The upsert locks the currency's row, so concurrent conversions sweep
+	// one at a time
+	var carried float64
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO fx_rounding_totals (currency, carried) VALUES ($1, $2)
+		ON CONFLICT (currency) DO UPDATE SET carried = fx_rounding_totals.carried + EXCLUDED.carried
+		RETURNING carried`,
+		currency, difference).Scan(&carried); err != nil {
+		return err
+	}
+	units := money.Truncate.Round(carried, money.Decimals(currency))
+	if units == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE fx_rounding_totals SET carried = carried - $2 WHERE currency = $1",
+		currency, units); err != nil {
+		return err
+	}
+	// Rounding down keeps money the customer would have had, which the
+	// account owes; rounding up gave away money, which reduces it
+	side := "credit"
+	if units < 0 {
+		side, units = "debit", -units
+	}
+	return postGLEntry(ctx, tx, fxRoundingAccount(currency), transactionID, side, units)
+}
+
+// postReleasedRoundingDifference posts the rounding difference of a held or
+// pending converted transaction once it is released and posted
+func postReleasedRoundingDifference(ctx context.Context, tx pgx.Tx, transactionID uuid.UUID) error {
+	var amount float64
+	var original, rate *float64
+	var currency string
+	err := tx.QueryRow(ctx,
+		"SELECT t.amount, t.original_amount, t.fx_rate, c.currency FROM transactions t JOIN customers c ON c.id = t.customer_id WHERE t.id = $1",
+		transactionID).Scan(&amount, &original, &rate, &currency)
+	if err != nil || original == nil || rate == nil {
+		return err
+	}
+	return postRoundingDifference(ctx, tx, roundingSourceTransaction, transactionID, &transactionID, currency, *original**rate, amount)
+}
+
+// @Summary Get FX rounding differences
+// @Description Report, per currency, what conversions into it (moves between wallets and transactions posted in another currency) rounded away: the exact converted amounts less the rounded ones. Whole minor units are posted to the currency's fx_rounding account; the rest is carried until it makes one.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} FXRoundingSummary "Rounding differences by currency"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/fx/rounding [get]
+func GetFXRoundingDifferences(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		`SELECT d.currency, COUNT(*), SUM(d.difference), COALESCE(MAX(t.carried), 0), COALESCE(MAX(a.balance), 0)
+		FROM fx_rounding_differences d
+		LEFT JOIN fx_rounding_totals t ON t.currency = d.currency
+		LEFT JOIN gl_accounts a ON a.code = 'fx_rounding_' || LOWER(d.currency)
+		GROUP BY d.currency ORDER BY d.currency`)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch rounding differences"})
+		return
+	}
+	defer rows.Close()
+
+	summaries := []FXRoundingSummary{}
+	for rows.Next() {
+		var s FXRoundingSummary
+		if err := rows.Scan(&s.Currency, &s.Conversions, &s.Difference, &s.Carried, &s.Posted); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan rounding differences"})
+			return
+		}
+		s.Account = fxRoundingAccount(s.Currency)
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch rounding differences"})
+		return
+	}
+	c.JSON(http.StatusOK, summaries)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectUnconverted expects the lookup of a released transaction's
+// conversion, for one posted in the account's currency
+func expectUnconverted(transactionID uuid.UUID) {
+	mock.ExpectQuery(`SELECT t.amount, t.original_amount, t.fx_rate, c.currency FROM transactions t`).
+		WithArgs(transactionID).
+		WillReturnRows(pgxmock.NewRows([]string{"amount", "original_amount", "fx_rate", "currency"}).
+			AddRow(float64(100), (*float64)(nil), (*float64)(nil), "USD"))
+}
+
+func TestPostRoundingDifference(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	ctx := context.Background()
+	moveID, transactionID := uuid.New(), uuid.New()
+
+	// A fraction of a cent is only carried
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO fx_rounding_differences`).
+		WithArgs(pgxmock.AnyArg(), "move", moveID, "USD", 109.6337, 109.63, 0.0037).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`INSERT INTO fx_rounding_totals`).
+		WithArgs("USD", 0.0037).
+		WillReturnRows(pgxmock.NewRows([]string{"carried"}).AddRow(0.0037))
+	tx, err := mock.Begin(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, postRoundingDifference(ctx, tx, roundingSourceMove, moveID, nil, "USD", 109.6337, 109.63))
+
+	// Once the carried fractions make a whole cent it is posted
+	mock.ExpectExec(`INSERT INTO fx_rounding_differences`).
+		WithArgs(pgxmock.AnyArg(), "transaction", transactionID, "USD", 20.0066, 20.01, -0.0034).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`INSERT INTO fx_rounding_totals`).
+		WithArgs("USD", -0.0034).
+		WillReturnRows(pgxmock.NewRows([]string{"carried"}).AddRow(-0.0121))
+	mock.ExpectExec(`UPDATE fx_rounding_totals SET carried = carried - \$2 WHERE currency = \$1`).
+		WithArgs("USD", -0.01).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE gl_accounts`).
+		WithArgs("fx_rounding_usd", "debit", 0.01).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO gl_entries`).
+		WithArgs(pgxmock.AnyArg(), "fx_rounding_usd", &transactionID, "debit", 0.01).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	assert.NoError(t, postRoundingDifference(ctx, tx, roundingSourceTransaction, transactionID, &transactionID, "USD", 20.0066, 20.01))
+
+	// Exact conversions have nothing to record
+	assert.NoError(t, postRoundingDifference(ctx, tx, roundingSourceMove, moveID, nil, "EUR", 91.23, 91.23))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetFXRoundingDifferences(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/fx/rounding", GetFXRoundingDifferences)
+
+	mock.ExpectQuery(`SELECT d.currency, COUNT\(\*\), SUM\(d.difference\)`).
+		WillReturnRows(pgxmock.NewRows([]string{"currency", "count", "sum", "carried", "balance"}).
+			AddRow("EUR", 12, -0.0213, -0.0013, -0.02).
+			AddRow("USD", 40, 0.0734, 0.0034, 0.07))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/fx/rounding", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var summaries []FXRoundingSummary
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summaries))
+	if assert.Len(t, summaries, 2) {
+		assert.Equal(t, FXRoundingSummary{Currency: "USD", Account: "fx_rounding_usd", Conversions: 40, Difference: 0.0734, Posted: 0.07, Carried: 0.0034}, summaries[1])
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/fx"
+	"ledger-service/money"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRates serves fixed rates keyed by "FROM/TO", attributed to "stub"
+type stubRates map[string]float64
+
+func (s stubRates) Rate(ctx context.Context, from, to string) (fx.Rate, error) {
+	if rate, ok := s[from+"/"+to]; ok {
+		return fx.Rate{Value: rate, Provider: "stub"}, nil
+	}
+	return fx.Rate{}, errors.New("rate unavailable")
+}
+
+func TestCreateFXQuote(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitFX(stubRates{"USD/EUR": 0.9123}, money.Policy{}, 30*time.Second)
+	defer InitFX(nil, money.Policy{}, 30*time.Second)
+
+	router.POST("/fx/quotes", CreateFXQuote)
+
+	customerID := uuid.New()
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`INSERT INTO fx_rates`).
+		WithArgs("USD", "EUR", "stub", pgxmock.AnyArg(), 0.9123).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO fx_quotes`).
+		WithArgs(pgxmock.AnyArg(), customerID, "USD", "EUR", float64(100), 0.9123, 91.23, "half_up", "stub", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	jsonBytes, _ := json.Marshal(map[string]interface{}{
+		"customer_id":   customerID,
+		"from_currency": "USD",
+		"to_currency":   "EUR",
+		"amount":        100,
+	})
+	req := httptest.NewRequest("POST", "/fx/quotes", bytes.NewBuffer(jsonBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var quote FXQuote
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &quote))
+	assert.Equal(t, 91.23, quote.ConvertedAmount)
+	assert.Equal(t, "stub", quote.RateProvider)
+	expiresAt, err := time.Parse(time.RFC3339, quote.ExpiresAt)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), expiresAt, 2*time.Second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMoveFundsWithQuote(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	// The provider must not be consulted when a quote is redeemed
+	InitFX(stubRates{}, money.Policy{}, 30*time.Second)
+	defer InitFX(nil, money.Policy{}, 30*time.Second)
+
+	router.POST("/customers/:customer_id/moves", MoveFunds)
+
+	customerID := uuid.New()
+	wallet := uuid.New()
+	quoteID := uuid.New()
+	quoteRows := func(expiresAt time.Time, usedAt *time.Time) *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"from_currency", "to_currency", "amount", "rate", "converted_amount", "rounding", "rate_provider", "expires_at", "used_at"}).
+			AddRow("USD", "EUR", float64(100), 0.9123, 91.23, "half_even", "exchangerate-api", expiresAt, usedAt)
+	}
+	usedAt := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name       string
+		amount     float64
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "quoted rate applied",
+			amount:     100,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM fx_quotes WHERE id = \$1 AND customer_id = \$2 FOR UPDATE`).
+					WithArgs(quoteID, customerID).
+					WillReturnRows(quoteRows(time.Now().Add(10*time.Second), nil))
+				mock.ExpectExec(`UPDATE fx_quotes SET used_at = NOW\(\) WHERE id = \$1`).
+					WithArgs(quoteID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectQuery(`SELECT balance, balance_type, currency FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "balance_type", "currency"}).AddRow(float64(1000), "deposit", "USD"))
+				mock.ExpectQuery(`SELECT balance, currency FROM sub_accounts`).
+					WithArgs(wallet, customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(0), "EUR"))
+				mock.ExpectExec(`INSERT INTO moves`).
+					WithArgs(pgxmock.AnyArg(), customerID, (*uuid.UUID)(nil), &wallet, "USD", "EUR", float64(100), 91.23, 0.9123, "half_even", &quoteID, "exchangerate-api").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE customers SET balance`).
+					WithArgs(float64(900), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions`).
+					WithArgs(pgxmock.AnyArg(), customerID, "move_out", float64(100)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE sub_accounts SET balance`).
+					WithArgs(91.23, wallet).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO sub_account_transactions`).
+					WithArgs(pgxmock.AnyArg(), wallet, pgxmock.AnyArg(), "credit", 91.23).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "expired quote",
+			amount:     100,
+			wantStatus: http.StatusGone,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM fx_quotes`).
+					WithArgs(quoteID, customerID).
+					WillReturnRows(quoteRows(time.Now().Add(-time.Second), nil))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "used quote",
+			amount:     100,
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM fx_quotes`).
+					WithArgs(quoteID, customerID).
+					WillReturnRows(quoteRows(time.Now().Add(10*time.Second), &usedAt))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "amount differs from quote",
+			amount:     50,
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM fx_quotes`).
+					WithArgs(quoteID, customerID).
+					WillReturnRows(quoteRows(time.Now().Add(10*time.Second), nil))
+				mock.ExpectExec(`UPDATE fx_quotes`).
+					WithArgs(quoteID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+				WithArgs(customerID).
+				WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+			mock.ExpectQuery(`SELECT currency FROM sub_accounts`).
+				WithArgs(wallet, customerID).
+				WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("EUR"))
+			mock.ExpectBegin()
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"to_sub_account_id": wallet,
+				"amount":            tt.amount,
+				"quote_id":          quoteID,
+			})
+			req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/moves", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
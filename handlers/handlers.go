@@ -2,11 +2,23 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"ledger-service/events"
+	"ledger-service/fraud"
+	"ledger-service/fx"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/notify"
+	"ledger-service/policy"
+	"ledger-service/redact"
+	"ledger-service/store"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -20,6 +32,18 @@ type Customer struct {
 	Name           string    `json:"name" binding:"required" example:"John Doe" minLength:"1" maxLength:"255"`
 	Balance        float64   `json:"balance" example:"1000" minimum:"0"`
 	InitialBalance float64   `json:"initial_balance" example:"1000" minimum:"0"`
+	DateOfBirth    string    `json:"date_of_birth,omitempty" example:"1990-01-31" format:"date"`
+	Email          string    `json:"email,omitempty" example:"john.doe@example.com" format:"email"`
+	PhoneNumber    string    `json:"phone_number,omitempty" example:"+15551234567"`
+	Addresses      []Address `json:"addresses,omitempty"`
+	AccountType    string    `json:"account_type,omitempty" example:"checking" enums:"checking,savings,escrow"`
+	Timezone       string    `json:"timezone,omitempty" example:"America/New_York" default:"UTC"`
+	// BalanceType credit makes the balance what the customer owes, up to
+	// CreditLimit
+	BalanceType string  `json:"balance_type,omitempty" example:"deposit" enums:"deposit,credit" default:"deposit"`
+	CreditLimit float64 `json:"credit_limit,omitempty" example:"5000" minimum:"0.01"`
+	// Currency is the account's base currency; every posting is in it
+	Currency string `json:"currency,omitempty" binding:"omitempty,currency" example:"USD" enums:"USD,EUR,GBP" default:"USD"`
 }
 
 // Transaction represents a financial transaction
@@ -27,38 +51,162 @@ type Customer struct {
 type Transaction struct {
 	ID         uuid.UUID `json:"transaction_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
 	CustomerID uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
-	Type       string    `json:"type" binding:"required,oneof=credit debit" example:"credit" enums:"credit,debit"`
-	Amount     float64   `json:"amount" binding:"required,gt=0" example:"200" minimum:"0.01"`
-	Timestamp  string    `json:"timestamp,omitempty" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	Type       string    `json:"type" binding:"required" example:"purchase" enums:"credit,debit,purchase,refund,fee,interest"`
+	Amount     float64   `json:"amount" binding:"required,money" example:"200" minimum:"0.01"`
+	// Currency defaults to the account's base currency. Amounts in another
+	// currency are converted at the current rate when FX is configured.
+	Currency  string `json:"currency,omitempty" binding:"omitempty,currency" example:"USD" enums:"USD,EUR,GBP"`
+	Timestamp string `json:"timestamp,omitempty" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	// Status is set in history: only posted transactions moved the balance
+	Status string `json:"status,omitempty" example:"posted" enums:"posted,held,pending_approval,rejected"`
+	// RecordedAt is when a backdated transaction was actually written;
+	// Timestamp is the historical time it was posted at
+	RecordedAt string `json:"recorded_at,omitempty" example:"2025-04-10T09:30:00Z" format:"date-time"`
+	// ValueDate is the date the transaction takes effect for interest and
+	// statements. It defaults to the posting date in the customer's
+	// timezone and may be set up to valueDateWindow days either side of it.
+	ValueDate string `json:"value_date,omitempty" example:"2025-04-08" format:"date"`
+	// Reference is the payment's external reference, such as the end-to-end
+	// ID of a bank file line. With UniqueReference the customer may not have
+	// another unique transaction with the same reference that was not rejected.
+	Reference       string `json:"reference,omitempty" binding:"required_if=UniqueReference true,max=140" example:"INV-1234" maxLength:"140"`
+	UniqueReference bool   `json:"unique_reference,omitempty" example:"true"`
+	// AllowDuplicate vouches for a legitimate repeat of a recent transaction
+	// of the same type and amount, which duplicate fraud rules let through.
+	// Requests with an Idempotency-Key are never treated as duplicates.
+	AllowDuplicate bool `json:"allow_duplicate,omitempty" example:"false"`
+	// TransferID is set in reference lookups on a leg of a transfer
+	TransferID *uuid.UUID `json:"transfer_id,omitempty" format:"uuid"`
+	// OriginalAmount, OriginalCurrency, FXRate, RateProvider and Rounding
+	// are set in history on a transaction posted in another currency;
+	// Amount is then the converted amount
+	OriginalAmount   float64 `json:"original_amount,omitempty" example:"185.5"`
+	OriginalCurrency string  `json:"original_currency,omitempty" example:"EUR"`
+	FXRate           float64 `json:"fx_rate,omitempty" example:"1.0782"`
+	RateProvider     string  `json:"rate_provider,omitempty" example:"exchangerate-api"`
+	Rounding         string  `json:"rounding,omitempty" example:"half_up" enums:"half_up,half_even,truncate,down,up"`
 }
 
 // CustomerResponse represents the response for customer operations
 type CustomerResponse struct {
-	CustomerID uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
-	Name       string    `json:"name" example:"John Doe"`
-	Balance    float64   `json:"balance" example:"1000"`
+	CustomerID         uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	Name               string    `json:"name" example:"John Doe"`
+	Balance            float64   `json:"balance" example:"1000"`
+	DateOfBirth        string    `json:"date_of_birth,omitempty" example:"1990-01-31" format:"date"`
+	VerificationStatus string    `json:"verification_status" example:"unverified" enums:"unverified,pending,verified,rejected"`
+	Email              string    `json:"email,omitempty" example:"john.doe@example.com"`
+	PhoneNumber        string    `json:"phone_number,omitempty" example:"+15551234567"`
+	Addresses          []Address `json:"addresses,omitempty"`
+	AccountType        string    `json:"account_type" example:"checking" enums:"checking,savings,escrow"`
+	Timezone           string    `json:"timezone" example:"America/New_York"`
+	// BalanceType deposit means Balance is owed to the customer; credit
+	// means it is owed by the customer
+	BalanceType string  `json:"balance_type" example:"deposit" enums:"deposit,credit"`
+	CreditLimit float64 `json:"credit_limit,omitempty" example:"5000"`
+	Currency    string  `json:"currency" example:"USD" enums:"USD,EUR,GBP"`
 }
 
 // TransactionResponse represents the response for transaction operations
 type TransactionResponse struct {
 	TransactionID uuid.UUID `json:"transaction_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
-	Status        string    `json:"status" example:"success" enums:"success"`
+	Status        string    `json:"status" example:"success" enums:"success,held,pending_approval"`
 	Balance       float64   `json:"balance" example:"800"`
+	// Amount, OriginalAmount, OriginalCurrency, FXRate, RateProvider and
+	// Rounding are set when the transaction was converted into the
+	// account's currency
+	Amount           float64 `json:"amount,omitempty" example:"200"`
+	OriginalAmount   float64 `json:"original_amount,omitempty" example:"185.5"`
+	OriginalCurrency string  `json:"original_currency,omitempty" example:"EUR"`
+	FXRate           float64 `json:"fx_rate,omitempty" example:"1.0782"`
+	RateProvider     string  `json:"rate_provider,omitempty" example:"exchangerate-api"`
+	Rounding         string  `json:"rounding,omitempty" example:"half_up" enums:"half_up,half_even,truncate,down,up"`
+}
+
+// transactionResponse describes a posting's result
+func transactionResponse(result ledger.Result, status string) TransactionResponse {
+	resp := TransactionResponse{TransactionID: result.TransactionID, Status: status, Balance: result.Balance}
+	if o := result.Original; o != nil {
+		resp.Amount = result.Amount
+		resp.OriginalAmount, resp.OriginalCurrency, resp.FXRate, resp.RateProvider = o.Amount, o.Currency, o.Rate, o.Provider
+		resp.Rounding = o.Rounding
+	}
+	return resp
+}
+
+// TransactionValidation is the outcome of a dry run
+// @Description Would-be result of a transaction checked with dry_run=true; nothing is written
+type TransactionValidation struct {
+	DryRun     bool      `json:"dry_run" example:"true"`
+	CustomerID uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	// Status is pending_approval when account rules would hold the
+	// transaction for approval. Fraud rules are not applied to dry runs.
+	Status          string  `json:"status" example:"posted" enums:"posted,pending_approval"`
+	PreviousBalance float64 `json:"previous_balance" example:"1000"`
+	Balance         float64 `json:"balance" example:"800"`
 }
 
 // BalanceResponse represents the response for balance operations
 type BalanceResponse struct {
 	CustomerID uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
 	Balance    float64   `json:"balance" example:"800"`
+	Currency   string    `json:"currency" example:"USD"`
+	// AsOf is set when the balance was asked for at a past time
+	AsOf string `json:"as_of,omitempty" example:"2025-03-31T23:59:59Z" format:"date-time"`
+	// Rate, RateProvider and RateEffectiveAt describe the conversion, when
+	// the balance was converted to another currency
+	Rate            float64 `json:"rate,omitempty" example:"0.9123"`
+	RateProvider    string  `json:"rate_provider,omitempty" example:"exchangerate-api"`
+	RateEffectiveAt string  `json:"rate_effective_at,omitempty" example:"2025-03-31T00:00:01Z" format:"date-time"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse represents an error response. RequestID (and TraceID when the
+// caller sent a traceparent) identify the request in logs and traces.
 type ErrorResponse struct {
-	Error string `json:"error" example:"Invalid input"`
+	Error     string `json:"error" example:"Invalid input"`
+	Code      string `json:"code,omitempty" example:"invalid_json"`
+	RequestID string `json:"request_id,omitempty" example:"3f2c9a5e-1b7d-4c2e-9f4a-8d6b0e1c2a3f"`
+	TraceID   string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	// Fields lists each invalid field when Code is validation_failed
+	Fields []FieldError `json:"fields,omitempty"`
+	// TransactionID is the transaction already holding the reference when
+	// Code is duplicate_reference
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+}
+
+// problemResponse is an RFC 7807 problem carrying any field-level errors
+type problemResponse struct {
+	middleware.Problem
+	Fields        []FieldError `json:"fields,omitempty"`
+	TransactionID *uuid.UUID   `json:"transaction_id,omitempty"`
+}
+
+// respondError writes resp, translated for the caller, with anything
+// sensitive in its messages masked and the request's correlation IDs filled
+// in, or the equivalent RFC 7807 problem when the client accepts
+// problem+json
+func respondError(c *gin.Context, status int, resp ErrorResponse) {
+	r := redact.Default()
+	resp.Error = r.String(middleware.Localize(c, resp.Error))
+	for i := range resp.Fields {
+		resp.Fields[i].Message = r.String(middleware.Localize(c, resp.Fields[i].Message))
+	}
+	if middleware.PrefersProblemJSON(c) {
+		c.Header("Content-Type", middleware.ProblemContentType)
+		c.JSON(status, problemResponse{
+			Problem:       middleware.NewProblem(c, status, resp.Error, resp.Code),
+			Fields:        resp.Fields,
+			TransactionID: resp.TransactionID,
+		})
+		return
+	}
+	resp.RequestID = c.GetString(middleware.RequestIDKey)
+	resp.TraceID = c.GetString(middleware.TraceIDKey)
+	c.JSON(status, resp)
 }
 
 type DBConn interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
@@ -66,13 +214,53 @@ type DBConn interface {
 
 var (
 	db DBConn
+	// ledgerStore holds customers and their transactions; it uses db unless
+	// InitStore replaces it
+	ledgerStore store.Store
 )
 
 func InitDB(conn DBConn) error {
-	db = conn
+	db = requestTaggedDB{conn}
+	ledgerStore = store.NewPostgres(db).EncryptPII(piiKeys)
 	return nil
 }
 
+// InitStore serves customers and transactions from s instead of the
+// database, as the in-memory demo does
+func InitStore(s store.Store) {
+	ledgerStore = s
+}
+
+// pgxTx returns the database transaction behind tx. Features kept only in
+// Postgres (KYC limits, account policies, fraud rules, the general ledger and
+// the event outbox) are skipped when the store is in memory.
+func pgxTx(tx store.Tx) (pgx.Tx, bool) {
+	if t, ok := tx.(*store.PostgresTx); ok {
+		return t.Pgx(), true
+	}
+	return nil, false
+}
+
+// requestTaggedDB labels each database transaction with the request ID so
+// that pg_stat_activity and server logs can be matched to API requests
+type requestTaggedDB struct {
+	DBConn
+}
+
+func (d requestTaggedDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := d.DBConn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id := middleware.RequestIDFromContext(ctx); id != "" {
+		if _, err := tx.Exec(ctx, "SELECT set_config('application_name', $1, true)", "ledger-service "+id); err != nil {
+			tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
 // @Summary Create a new customer account
 // @Description Create a new customer account with initial balance
 // @Tags customers
@@ -85,122 +273,298 @@ func InitDB(conn DBConn) error {
 // @Router /customers [post]
 func CreateCustomer(c *gin.Context) {
 	var customer Customer
-	if err := c.ShouldBindJSON(&customer); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid input: Name is required and balance must be non-negative"})
+	var fields fieldErrors
+	if err := bindJSON(c, &customer); err != nil && !fields.addBinding(&customer, err) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: Name is required and balance must be non-negative"})
 		return
 	}
 
+	customer.Name = normalizeText(customer.Name)
+	if msg := validateName(customer.Name); msg != "" {
+		fields.add("name", msg)
+	}
+
+	if customer.Currency == "" {
+		customer.Currency = store.DefaultCurrency
+	}
+
 	// Use initial_balance if provided, otherwise use balance
-	balance := customer.InitialBalance
+	balance, balanceField := customer.InitialBalance, "initial_balance"
 	if balance == 0 {
-		balance = customer.Balance
+		balance, balanceField = customer.Balance, "balance"
 	}
-
 	if balance < 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid input: Balance must be non-negative"})
+		fields.add(balanceField, balanceField+" must be non-negative")
+	} else if msg := validatePrecision(balanceField, balance, customer.Currency); msg != "" {
+		fields.add(balanceField, msg)
+	}
+
+	dateOfBirth, err := parseDateOfBirth(customer.DateOfBirth)
+	if err != nil {
+		fields.add("date_of_birth", err.Error())
+	}
+
+	if customer.AccountType == "" {
+		customer.AccountType = string(policy.Checking)
+	}
+	if !policy.Valid(policy.AccountType(customer.AccountType)) {
+		fields.add("account_type", "account_type must be one of checking, savings, escrow")
+	}
+
+	if customer.BalanceType == "" {
+		customer.BalanceType = store.BalanceDeposit
+	}
+	switch customer.BalanceType {
+	case store.BalanceDeposit:
+		if customer.CreditLimit != 0 {
+			fields.add("credit_limit", "credit_limit is only allowed on credit accounts")
+		}
+	case store.BalanceCredit:
+		if customer.CreditLimit <= 0 {
+			fields.add("credit_limit", "credit_limit must be greater than 0 on credit accounts")
+		} else if msg := validatePrecision("credit_limit", customer.CreditLimit, customer.Currency); msg != "" {
+			fields.add("credit_limit", msg)
+		} else if balance > customer.CreditLimit {
+			fields.add(balanceField, balanceField+" must not exceed credit_limit")
+		}
+	default:
+		fields.add("balance_type", "balance_type must be one of deposit, credit")
+	}
+
+	if customer.Timezone == "" {
+		customer.Timezone = defaultTimezone
+	}
+	if msg := validateTimezone(customer.Timezone); msg != "" {
+		fields.add("timezone", msg)
+	}
+
+	if msg := validateContactDetails(customer.Email, ""); msg != "" {
+		fields.add("email", msg)
+	}
+	if msg := validateContactDetails("", customer.PhoneNumber); msg != "" {
+		fields.add("phone_number", msg)
+	}
+	for i := range customer.Addresses {
+		if msg := validateAddress(&customer.Addresses[i]); msg != "" {
+			fields.add(fmt.Sprintf("addresses[%d]", i), msg)
+		}
+	}
+
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
 		return
 	}
 
 	customer.ID = uuid.New()
 	customer.Balance = balance
 
-	// Insert customer into database
-	_, err := db.Exec(c.Request.Context(),
-		"INSERT INTO customers (id, name, balance) VALUES ($1, $2, $3)",
-		customer.ID, customer.Name, customer.Balance)
+	// Insert customer and addresses atomically
+	ctx := c.Request.Context()
+	tx, err := ledgerStore.Begin(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create customer"})
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	record := store.Customer{
+		ID:          customer.ID,
+		Name:        customer.Name,
+		Balance:     customer.Balance,
+		DateOfBirth: dateOfBirth,
+		Email:       customer.Email,
+		PhoneNumber: customer.PhoneNumber,
+		AccountType: customer.AccountType,
+		Timezone:    customer.Timezone,
+		BalanceType: customer.BalanceType,
+		CreditLimit: customer.CreditLimit,
+		Currency:    customer.Currency,
+	}
+	if err := tx.CreateCustomer(ctx, &record); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create customer"})
+		return
+	}
+	for i := range customer.Addresses {
+		address := toStoreAddress(customer.Addresses[i])
+		if err := tx.AddAddress(ctx, customer.ID, &address); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create address"})
+			return
+		}
+		customer.Addresses[i].AddressID = address.ID
+	}
+
+	if pg, ok := pgxTx(tx); ok {
+		if err := enqueueEvent(ctx, pg, events.CustomerCreated, &customer.ID, CustomerCreatedEventData{
+			Name:        customer.Name,
+			AccountType: customer.AccountType,
+			Balance:     customer.Balance,
+			Currency:    customer.Currency,
+		}); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record event"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, CustomerResponse{
-		CustomerID: customer.ID,
-		Name:       customer.Name,
-		Balance:    customer.Balance,
+		CustomerID:         customer.ID,
+		Name:               customer.Name,
+		Balance:            customer.Balance,
+		DateOfBirth:        customer.DateOfBirth,
+		VerificationStatus: "unverified",
+		Email:              customer.Email,
+		PhoneNumber:        customer.PhoneNumber,
+		Addresses:          customer.Addresses,
+		AccountType:        customer.AccountType,
+		Timezone:           customer.Timezone,
+		BalanceType:        customer.BalanceType,
+		CreditLimit:        customer.CreditLimit,
+		Currency:           customer.Currency,
 	})
 }
 
 // @Summary Create a new transaction
-// @Description Create a new credit or debit transaction for a customer
+// @Description Create a transaction for a customer. The type must be a postable registered transaction type (see /transaction-types); its direction decides whether the balance is credited or debited. An amount in another currency than the account's is converted at the current rate, and the original amount, currency, rate and provider are kept on the transaction. With unique_reference=true the reference may be used once per customer: a transaction repeating it answers 409 with the original transaction's ID, so re-submitted bank files cannot post a payment twice. With dry_run=true every check runs (customer, currency, value date, dormancy, KYC limits, account type rules and balance) and the would-be balance is returned, but nothing is written; fraud rules are not applied to dry runs. With Prefer: respond-async the transaction is queued once the request and customer check out, and answered with 202, its transaction_id and status accepted; GET /transactions/{transaction_id} then reports what became of it. Dry runs are always answered straight away.
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param transaction body Transaction true "Transaction information"
+// @Param dry_run query bool false "Validate the transaction without posting it"
+// @Param Prefer header string false "respond-async to post the transaction in the background" Enums(respond-async)
+// @Success 200 {object} TransactionValidation "Dry run: the transaction would be accepted"
 // @Success 201 {object} TransactionResponse "Transaction processed successfully"
+// @Success 202 {object} TransactionResponse "Transaction held for fraud review or awaiting escrow approval, or accepted to be posted in the background"
+// @Header 202 {string} Location "Status of a transaction posted in the background"
 // @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 403 {object} ErrorResponse "Transaction exceeds KYC limits or account type rules, or debits a dormant account"
 // @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Unique reference already used; transaction_id is the original transaction"
+// @Failure 422 {object} ErrorResponse "Transaction rejected by fraud rules; code duplicate_transaction when a duplicate rule rejected it"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 502 {object} ErrorResponse "Exchange rate provider error"
 // @Router /transactions [post]
 func CreateTransaction(c *gin.Context) {
 	var transaction Transaction
-	if err := c.ShouldBindJSON(&transaction); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid input: customer_id, type (credit/debit), and amount (> 0) are required"})
+	if !bindRequest(c, &transaction, "Invalid input: customer_id, type, and amount (> 0) are required") {
 		return
 	}
-
-	// Start transaction
-	tx, err := db.Begin(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+	valueDate, ok := parseValueDate(c, transaction.CustomerID, transaction.ValueDate)
+	if !ok {
 		return
 	}
-	defer tx.Rollback(c.Request.Context())
-
-	// Get current balance with row lock
-	var currentBalance float64
-	err = tx.QueryRow(c.Request.Context(),
-		"SELECT balance FROM customers WHERE id = $1 FOR UPDATE",
-		transaction.CustomerID).Scan(&currentBalance)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
-		}
-		return
-	}
-
-	// Calculate new balance
-	var newBalance float64
-	if transaction.Type == "debit" {
-		if currentBalance < transaction.Amount {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+	dryRun := false
+	if v := c.Query("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid dry_run value"})
 			return
 		}
-		newBalance = currentBalance - transaction.Amount
-	} else {
-		newBalance = currentBalance + transaction.Amount
 	}
-
-	// Update customer balance
-	_, err = tx.Exec(c.Request.Context(),
-		"UPDATE customers SET balance = $1 WHERE id = $2",
-		newBalance, transaction.CustomerID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update balance"})
+	// The in-memory store has no job queue, so it posts straight away as
+	// the preference allows
+	if preferAsync(c) && !dryRun && db != nil {
+		submitTransaction(c, transaction)
 		return
 	}
 
-	// Insert transaction
-	transaction.ID = uuid.New()
-	_, err = tx.Exec(c.Request.Context(),
-		"INSERT INTO transactions (id, customer_id, type, amount) VALUES ($1, $2, $3, $4)",
-		transaction.ID, transaction.CustomerID, transaction.Type, transaction.Amount)
+	ctx := c.Request.Context()
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID:      transaction.CustomerID,
+		Type:            transaction.Type,
+		Amount:          transaction.Amount,
+		Currency:        transaction.Currency,
+		ValueDate:       valueDate,
+		Reference:       transaction.Reference,
+		UniqueReference: transaction.UniqueReference,
+		// A request with an idempotency key is retried by the middleware
+		// rather than posted twice, so it is not a duplicate
+		AllowDuplicate: transaction.AllowDuplicate || c.GetHeader(middleware.IdempotencyKeyHeader) != "",
+		DryRun:         dryRun,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create transaction"})
+		status, resp := postingFailure(err, transaction.Type)
+		respondError(c, status, resp)
 		return
 	}
+	if dryRun {
+		c.JSON(http.StatusOK, TransactionValidation{
+			DryRun:          true,
+			CustomerID:      transaction.CustomerID,
+			Status:          result.Status,
+			PreviousBalance: result.PreviousBalance,
+			Balance:         result.Balance,
+		})
+		return
+	}
+	invalidateBalances(ctx, transaction.CustomerID)
 
-	// Commit transaction
-	if err := tx.Commit(c.Request.Context()); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+	switch result.Status {
+	case ledger.StatusRejected:
+		respondError(c, http.StatusUnprocessableEntity, rejection(result))
+		return
+	case ledger.StatusHeld, ledger.StatusPendingApproval:
+		c.JSON(http.StatusAccepted, transactionResponse(result, result.Status))
 		return
 	}
 
-	c.JSON(http.StatusCreated, TransactionResponse{
-		TransactionID: transaction.ID,
-		Status:        "success",
-		Balance:       newBalance,
+	alertPosted(ctx, transaction.CustomerID, transaction.Type, result)
+	c.JSON(http.StatusCreated, transactionResponse(result, "success"))
+}
+
+// postingFailure is the response to a posting that failed with err
+func postingFailure(err error, txType string) (int, ErrorResponse) {
+	var violation *ledger.ViolationError
+	var duplicate *ledger.DuplicateReferenceError
+	switch {
+	case errors.Is(err, ledger.ErrUnknownTransactionType):
+		return http.StatusBadRequest, ErrorResponse{Error: "Invalid input: unknown transaction type " + txType}
+	case errors.Is(err, ledger.ErrCustomerNotFound):
+		return http.StatusNotFound, ErrorResponse{Error: "Customer not found"}
+	case errors.Is(err, ledger.ErrInsufficientBalance):
+		return http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"}
+	case errors.Is(err, ledger.ErrOverpayment):
+		return http.StatusBadRequest, ErrorResponse{Error: "Payment exceeds the amount owed"}
+	case errors.Is(err, ledger.ErrCurrencyMismatch):
+		return http.StatusBadRequest, ErrorResponse{Error: "Currency does not match the account's base currency"}
+	case errors.Is(err, ledger.ErrConversion):
+		return http.StatusBadGateway, ErrorResponse{Error: "Failed to fetch exchange rate"}
+	case errors.As(err, &duplicate):
+		return http.StatusConflict, ErrorResponse{Error: "Reference has already been used", Code: "duplicate_reference", TransactionID: &duplicate.TransactionID}
+	case errors.Is(err, ledger.ErrDuplicateReference):
+		return http.StatusConflict, ErrorResponse{Error: "Reference has already been used", Code: "duplicate_reference"}
+	case errors.As(err, &violation):
+		return http.StatusForbidden, ErrorResponse{Error: violation.Message}
+	}
+	return http.StatusInternalServerError, ErrorResponse{Error: "Failed to create transaction"}
+}
+
+// rejection says why fraud rules rejected a posting
+func rejection(result ledger.Result) ErrorResponse {
+	if checks, ok := result.Screening.Detail.(*postingChecks); ok && checks.decision.Rejects(fraud.RuleDuplicate) {
+		return ErrorResponse{Error: "Transaction looks like a duplicate of a recent one; set allow_duplicate to post it anyway", Code: "duplicate_transaction"}
+	}
+	return ErrorResponse{Error: "Transaction rejected by fraud rules"}
+}
+
+// alertPosted sends the customer's SMS alerts for a posted transaction
+func alertPosted(ctx context.Context, customerID uuid.UUID, txType string, result ledger.Result) {
+	checks, ok := result.Screening.Detail.(*postingChecks)
+	if !ok || checks.phone == nil {
+		return
+	}
+	t, _ := transactionTypes.Lookup(txType)
+	notifyTransaction(ctx, notify.TransactionEvent{
+		CustomerID:      customerID,
+		PhoneNumber:     *checks.phone,
+		OptIn:           checks.smsOptIn,
+		Type:            string(t.Direction),
+		Amount:          result.Amount,
+		PreviousBalance: result.PreviousBalance,
+		Balance:         result.Balance,
 	})
 }
 
@@ -214,94 +578,148 @@ func isValidCurrency(currency string) bool {
 	return validCurrencies[currency]
 }
 
-// Function to get exchange rate
-func getExchangeRate(fromCurrency, toCurrency string, amount float64) (float64, error) {
-	apiKey := "1a7b5574bdb95f1770750778"		//pls dont use pls
-	url := fmt.Sprintf("https://v6.exchangerate-api.com/v6/%s/pair/%s/%s/%.2f",
-		apiKey, fromCurrency, toCurrency, amount)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, fmt.Errorf("failed to call exchange rate API: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Result           string  `json:"result"`
-		Documentation    string  `json:"documentation"`
-		TermsOfUse       string  `json:"terms_of_use"`
-		TimeLastUpdate   int64   `json:"time_last_update_unix"`
-		TimeNextUpdate   int64   `json:"time_next_update_unix"`
-		BaseCode         string  `json:"base_code"`
-		TargetCode       string  `json:"target_code"`
-		ConversionRate   float64 `json:"conversion_rate"`
-		ConversionResult float64 `json:"conversion_result,omitempty"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, fmt.Errorf("failed to decode exchange rate response: %v", err)
-	}
-
-	if result.Result != "success" {
-		return 0, fmt.Errorf("exchange rate API error: %s", result.Result)
-	}
-
-	return result.ConversionResult, nil
-}
-
 // GetBalance returns the current balance for a customer
+// @Summary Get customer balance
+// @Description Get the current balance for a customer in the account's base currency, optionally converted to another currency. On a credit account the balance is the amount the customer owes. With as_of, the balance at that time is rebuilt from the opening balance and the transactions posted up to then, and a conversion uses the stored rate that applied at that time.
+// @Tags customers
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param currency query string false "Currency to convert the balance to; defaults to the account's base currency" Enums(USD, EUR, GBP)
+// @Param as_of query string false "Past time to report the balance at (RFC 3339)" format(date-time)
+// @Param If-None-Match header string false "ETag of a balance already held, to revalidate it"
+// @Success 200 {object} BalanceResponse "Current balance"
+// @Success 304 "Balance unchanged since the given ETag"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID, currency or as_of"
+// @Failure 404 {object} ErrorResponse "Customer not found, or no rate stored for as_of"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 502 {object} ErrorResponse "Exchange rate provider error"
+// @Failure 503 {object} ErrorResponse "Currency conversion is not configured"
+// @Router /customers/{customer_id}/balance [get]
 func GetBalance(c *gin.Context) {
 	customerID, err := uuid.Parse(c.Param("customer_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
 		return
 	}
 
 	// Get target currency from query parameter
-	targetCurrency := c.DefaultQuery("currency", "USD")
-	if !isValidCurrency(targetCurrency) {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
+	targetCurrency := c.Query("currency")
+	if targetCurrency != "" && !isValidCurrency(targetCurrency) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid currency code"})
 		return
 	}
 
-	// Get customer's current balance
-	var currentBalance float64
-	err = db.QueryRow(context.Background(),
-		"SELECT balance FROM customers WHERE id = $1",
-		customerID).Scan(&currentBalance)
+	var asOf time.Time
+	if s := c.Query("as_of"); s != "" {
+		asOf, err = time.Parse(time.RFC3339, s)
+		if err != nil || asOf.After(time.Now()) {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid as_of: use a past RFC 3339 time"})
+			return
+		}
+		if db == nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "as_of needs the database store"})
+			return
+		}
+	}
 
+	// Get customer's current balance, from the cache when enabled, or the
+	// balance at as_of
+	var currentBalance store.Balance
+	if asOf.IsZero() {
+		currentBalance, err = readBalance(c.Request.Context(), customerID)
+	} else {
+		currentBalance, err = balanceAsOf(c.Request.Context(), customerID, asOf)
+	}
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		if errors.Is(err, ledger.ErrCustomerNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
 		} else {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Database error"})
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Database error"})
 		}
 		return
 	}
 
-	// Convert balance using Forex API
-	convertedBalance, err := getExchangeRate("USD", targetCurrency, currentBalance)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-		return
+	if targetCurrency == "" {
+		targetCurrency = currentBalance.Currency
+	}
+	resp := BalanceResponse{CustomerID: customerID, Balance: currentBalance.Amount, Currency: targetCurrency}
+	if !asOf.IsZero() {
+		resp.AsOf = asOf.UTC().Format(time.RFC3339)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"customer_id": customerID,
-		"balance":     convertedBalance,
-		"currency":    targetCurrency,
-	})
+	// Convert balance from the account's base currency using the FX
+	// provider, or the rate stored for as_of
+	if targetCurrency != currentBalance.Currency {
+		var rate fx.Rate
+		if asOf.IsZero() {
+			if fxProvider == nil {
+				respondError(c, http.StatusServiceUnavailable, ErrorResponse{Error: "Currency conversion is not configured"})
+				return
+			}
+			rate, err = lookupRate(c.Request.Context(), currentBalance.Currency, targetCurrency)
+			if err != nil {
+				respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to fetch exchange rate"})
+				return
+			}
+		} else {
+			rate, err = storedRate(c.Request.Context(), db, currentBalance.Currency, targetCurrency, asOf)
+			if errors.Is(err, errRateNotFound) {
+				respondError(c, http.StatusNotFound, ErrorResponse{Error: "No exchange rate stored for " + currentBalance.Currency + "/" + targetCurrency + " at as_of"})
+				return
+			}
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get exchange rate"})
+				return
+			}
+		}
+		resp.Balance, _ = roundingPolicy.Convert(currentBalance.Amount, rate.Value, targetCurrency)
+		resp.Rate, resp.RateProvider = rate.Value, rate.Provider
+		if !rate.UpdatedAt.IsZero() {
+			resp.RateEffectiveAt = rate.UpdatedAt.UTC().Format(time.RFC3339)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// balanceAsOf rebuilds a customer's balance at a past time from the opening
+// balance and the transactions posted up to then, the way the trial balance
+// reconciles it
+func balanceAsOf(ctx context.Context, customerID uuid.UUID, at time.Time) (store.Balance, error) {
+	var balance store.Balance
+	err := db.QueryRow(ctx,
+		`SELECT c.currency, c.opening_balance + COALESCE(SUM(CASE WHEN (tt.direction = 'credit') = (c.balance_type = 'deposit') THEN t.amount ELSE -t.amount END), 0)
+		FROM customers c
+		LEFT JOIN transactions t ON t.customer_id = c.id AND t.status = 'posted' AND t.created_at <= $2
+		LEFT JOIN transaction_types tt ON tt.code = t.type
+		WHERE c.id = $1
+		GROUP BY c.id`,
+		customerID, at).Scan(&balance.Currency, &balance.Amount)
+	if err == pgx.ErrNoRows {
+		return store.Balance{}, ledger.ErrCustomerNotFound
+	}
+	return balance, err
 }
 
 // @Summary Get transaction history
-// @Description Get paginated transaction history for a customer
+// @Description Get paginated transaction history for a customer. format=ofx or format=qif exports every posted transaction matching the value date filters instead of a page, oldest first, for personal finance tools: amounts are signed from the customer's side (money in positive, money out negative), dated by value date, and a credit account is written as a credit card.
 // @Tags transactions
 // @Produce json
+// @Produce application/x-ofx
+// @Produce application/qif
 // @Param customer_id path string true "Customer ID" format(uuid)
 // @Param page query int false "Page number (1-based)" minimum(1) default(1)
 // @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Param sort query string false "Field to sort by" Enums(created_at, amount, type) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Param value_date_from query string false "Only transactions with a value date on or after this date (YYYY-MM-DD)" format(date)
+// @Param value_date_to query string false "Only transactions with a value date on or before this date (YYYY-MM-DD)" format(date)
+// @Param status query string false "Only transactions with this status" Enums(posted, held, pending_approval, rejected)
+// @Param format query string false "Response format" Enums(json, ofx, qif) default(json)
+// @Param If-None-Match header string false "ETag of a page already held, to revalidate it"
 // @Success 200 {array} Transaction "List of transactions"
-// @Failure 400 {object} ErrorResponse "Invalid customer ID format or pagination parameters"
+// @Success 304 "Page unchanged since the given ETag"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID format, pagination, sort, filter or format parameters"
 // @Failure 404 {object} ErrorResponse "Customer not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Header 200 {string} X-Total-Count "Total number of transactions"
@@ -312,80 +730,62 @@ func GetBalance(c *gin.Context) {
 func GetTransactions(c *gin.Context) {
 	customerID, err := uuid.Parse(c.Param("customer_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
 		return
 	}
 
 	// Get pagination parameters with defaults
-	page := 1
-	pageSize := 10
-	if p := c.Query("page"); p != "" {
-		if _, err := fmt.Sscanf(p, "%d", &page); err != nil || page < 1 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
-			return
-		}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
 	}
-	if ps := c.Query("page_size"); ps != "" {
-		if _, err := fmt.Sscanf(ps, "%d", &pageSize); err != nil || pageSize < 1 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page size"})
-			return
-		}
+	opts, ok := parseTransactionSort(c)
+	if !ok {
+		return
+	}
+	if opts.TransactionFilter, ok = parseTransactionFilter(c); !ok {
+		return
+	}
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+	case "ofx", "qif":
+		exportTransactions(c, customerID, format, opts.TransactionFilter)
+		return
+	default:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid format: must be one of json, ofx, qif"})
+		return
 	}
 
 	// Verify customer exists
-	var exists bool
-	err = db.QueryRow(c.Request.Context(),
-		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
-		customerID).Scan(&exists)
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, customerID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify customer"})
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
 		return
 	}
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
 		return
 	}
 
 	// Get total count
-	var totalCount int
-	err = db.QueryRow(c.Request.Context(),
-		"SELECT COUNT(*) FROM transactions WHERE customer_id = $1",
-		customerID).Scan(&totalCount)
+	totalCount, err := ledgerStore.CountTransactions(ctx, customerID, opts.TransactionFilter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get total count"})
 		return
 	}
 
-	// Calculate offset
-	offset := (page - 1) * pageSize
-
-	rows, err := db.Query(c.Request.Context(),
-		"SELECT id, type, amount, created_at FROM transactions WHERE customer_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
-		customerID, pageSize, offset)
+	opts.Limit = pageSize
+	opts.Offset = (page - 1) * pageSize
+	listed, err := ledgerStore.ListTransactions(ctx, customerID, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
 		return
 	}
-	defer rows.Close()
 
 	var transactions []gin.H
-	for rows.Next() {
-		var id uuid.UUID
-		var txType string
-		var amount float64
-		var timestamp time.Time
-		err := rows.Scan(&id, &txType, &amount, &timestamp)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan transaction"})
-			return
-		}
-
-		transactions = append(transactions, gin.H{
-			"transaction_id": id,
-			"type":           txType,
-			"amount":         amount,
-			"timestamp":      timestamp.Format(time.RFC3339),
-		})
+	for _, t := range listed {
+		transactions = append(transactions, historyEntry(t))
 	}
 
 	// Add pagination metadata in headers
@@ -396,3 +796,150 @@ func GetTransactions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, transactions)
 }
+
+// parseTransactionSort reads the sort and order query parameters, writing a
+// 400 response and returning false when they are invalid
+func parseTransactionSort(c *gin.Context) (store.ListOptions, bool) {
+	sort := c.DefaultQuery("sort", "created_at")
+	if _, ok := store.TransactionSortColumns[sort]; !ok {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid sort field: must be one of amount, created_at, type"})
+		return store.ListOptions{}, false
+	}
+	direction := strings.ToUpper(c.DefaultQuery("order", "desc"))
+	if direction != "ASC" && direction != "DESC" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid sort order: must be asc or desc"})
+		return store.ListOptions{}, false
+	}
+	return store.ListOptions{Sort: sort, Descending: direction == "DESC"}, true
+}
+
+// historyEntry is how transaction history and detail show t
+func historyEntry(t store.Transaction) gin.H {
+	entry := gin.H{
+		"transaction_id": t.ID,
+		"type":           t.Type,
+		"amount":         t.Amount,
+		"status":         t.Status,
+		"timestamp":      t.CreatedAt.Format(time.RFC3339),
+		"value_date":     t.ValueDate.Format(dateLayout),
+	}
+	if t.RecordedAt != nil {
+		entry["recorded_at"] = t.RecordedAt.Format(time.RFC3339)
+	}
+	if t.Reference != "" {
+		entry["reference"] = t.Reference
+		if t.UniqueReference {
+			entry["unique_reference"] = true
+		}
+	}
+	if o := t.Original; o != nil {
+		entry["original_amount"] = o.Amount
+		entry["original_currency"] = o.Currency
+		entry["fx_rate"] = o.Rate
+		if o.Provider != "" {
+			entry["rate_provider"] = o.Provider
+		}
+		if o.Rounding != "" {
+			entry["rounding"] = o.Rounding
+		}
+	}
+	return entry
+}
+
+// @Summary Get a transaction
+// @Description Get one of a customer's transactions with its status. Held and pending_approval transactions have not moved the balance yet; rejected ones never will.
+// @Tags transactions
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param transaction_id path string true "Transaction ID" format(uuid)
+// @Success 200 {object} Transaction "Transaction"
+// @Failure 400 {object} ErrorResponse "Invalid customer or transaction ID"
+// @Failure 404 {object} ErrorResponse "Transaction not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/transactions/{transaction_id} [get]
+func GetTransaction(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	transactionID, err := uuid.Parse(c.Param("transaction_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+
+	t, err := ledgerStore.GetTransaction(c.Request.Context(), customerID, transactionID)
+	if errors.Is(err, store.ErrNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, historyEntry(t))
+}
+
+// transactionStatuses are the statuses a transaction can take
+var transactionStatuses = map[string]bool{
+	ledger.StatusPosted:          true,
+	ledger.StatusHeld:            true,
+	ledger.StatusPendingApproval: true,
+	ledger.StatusRejected:        true,
+}
+
+// parseTransactionFilter reads the status, value_date_from and value_date_to
+// query parameters, writing a 400 response and returning false when they
+// are invalid
+func parseTransactionFilter(c *gin.Context) (store.TransactionFilter, bool) {
+	filter := store.TransactionFilter{Status: c.Query("status")}
+	if filter.Status != "" && !transactionStatuses[filter.Status] {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status: must be one of posted, held, pending_approval, rejected"})
+		return store.TransactionFilter{}, false
+	}
+	for _, p := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"value_date_from", &filter.ValueDateFrom},
+		{"value_date_to", &filter.ValueDateTo},
+	} {
+		value := c.Query(p.name)
+		if value == "" {
+			continue
+		}
+		d, err := time.Parse(dateLayout, value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid " + p.name + ": must be in YYYY-MM-DD format"})
+			return store.TransactionFilter{}, false
+		}
+		*p.dest = &d
+	}
+	if filter.ValueDateFrom != nil && filter.ValueDateTo != nil && filter.ValueDateTo.Before(*filter.ValueDateFrom) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid value date range: value_date_to is before value_date_from"})
+		return store.TransactionFilter{}, false
+	}
+	return filter, true
+}
+
+// parsePagination reads page and page_size query parameters, writing a 400
+// response and returning false when they are invalid
+func parsePagination(c *gin.Context) (int, int, bool) {
+	page := 1
+	pageSize := 10
+	if p := c.Query("page"); p != "" {
+		if _, err := fmt.Sscanf(p, "%d", &page); err != nil || page < 1 {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid page number"})
+			return 0, 0, false
+		}
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		if _, err := fmt.Sscanf(ps, "%d", &pageSize); err != nil || pageSize < 1 {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid page size"})
+			return 0, 0, false
+		}
+	}
+	return page, pageSize, true
+}
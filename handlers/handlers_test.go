@@ -9,15 +9,54 @@ import (
 	"testing"
 	"time"
 
+	"ledger-service/events"
+	"ledger-service/middleware"
+	"ledger-service/money"
+	"ledger-service/store"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	pgxmock "github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var mock pgxmock.PgxConnIface
 
+// lockCustomerQuery is the statement store.PostgresTx.LockCustomer runs
+const lockCustomerQuery = `SELECT balance, account_type, timezone, allow_negative, balance_type, COALESCE\(credit_limit, 0\), currency,
+			COALESCE\(\(SELECT overdraft_limit FROM customer_limits WHERE customer_id = \$1\), \(SELECT overdraft_limit FROM limit_defaults\), 0\)
+		FROM customers WHERE id = \$1 FOR UPDATE`
+
+// lockedCustomer is the row LockCustomer reads for a deposit account
+func lockedCustomer(balance float64, accountType string, allowNegative bool) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"balance", "account_type", "timezone", "allow_negative", "balance_type", "credit_limit", "currency", "overdraft_limit"}).
+		AddRow(balance, accountType, "UTC", allowNegative, "deposit", float64(0), "USD", float64(0))
+}
+
+// creditCustomer is the row LockCustomer reads for a credit account
+func creditCustomer(owed, limit float64) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"balance", "account_type", "timezone", "allow_negative", "balance_type", "credit_limit", "currency", "overdraft_limit"}).
+		AddRow(owed, "checking", "UTC", false, "credit", limit, "USD", float64(0))
+}
+
+// customerTimezoneQuery is the statement store CustomerTimezone runs
+const customerTimezoneQuery = `SELECT timezone FROM customers WHERE id = \$1`
+
+// customerIn is the row CustomerTimezone reads for a customer in the
+// timezone
+func customerIn(timezone string) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"timezone"}).AddRow(timezone)
+}
+
+// localToday is today's date in the timezone, at midnight UTC
+func localToday(timezone string) time.Time {
+	loc, _ := time.LoadLocation(timezone)
+	y, m, d := time.Now().In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
 func setupTestRouter() (*gin.Engine, error) {
 	var err error
 	mock, err = pgxmock.NewConn()
@@ -56,11 +95,82 @@ func TestCreateCustomer(t *testing.T) {
 			wantStatus: http.StatusCreated,
 			wantErr:    false,
 			setupMock: func() {
-				mock.ExpectExec(`INSERT INTO customers \(id, name, balance\) VALUES \(\$1, \$2, \$3\)`).
-					WithArgs(pgxmock.AnyArg(), "John Doe", float64(1000)).
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO customers \(id, name, balance, opening_balance, date_of_birth, email, phone_number, account_type, timezone, balance_type, credit_limit, currency\) VALUES \(\$1, \$2, \$3, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11\)`).
+					WithArgs(pgxmock.AnyArg(), "John Doe", float64(1000), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "checking", "UTC", "deposit", (*float64)(nil), "USD").
 					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.CustomerCreated)
+				mock.ExpectCommit()
 			},
 		},
+		{
+			name: "credit account",
+			payload: map[string]interface{}{
+				"name":            "John Doe",
+				"initial_balance": 250,
+				"balance_type":    "credit",
+				"credit_limit":    5000,
+			},
+			wantStatus: http.StatusCreated,
+			wantErr:    false,
+			setupMock: func() {
+				limit := float64(5000)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO customers`).
+					WithArgs(pgxmock.AnyArg(), "John Doe", float64(250), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "checking", "UTC", "credit", &limit, "USD").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.CustomerCreated)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "euro account",
+			payload: map[string]interface{}{
+				"name":            "Jean Dupont",
+				"initial_balance": 80,
+				"currency":        "EUR",
+			},
+			wantStatus: http.StatusCreated,
+			wantErr:    false,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO customers`).
+					WithArgs(pgxmock.AnyArg(), "Jean Dupont", float64(80), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "checking", "UTC", "deposit", (*float64)(nil), "EUR").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.CustomerCreated)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "unsupported currency",
+			payload: map[string]interface{}{
+				"name":     "John Doe",
+				"currency": "JPY",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock:  func() {},
+		},
+		{
+			name: "credit account without a limit",
+			payload: map[string]interface{}{
+				"name":         "John Doe",
+				"balance_type": "credit",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock:  func() {},
+		},
+		{
+			name: "credit limit on a deposit account",
+			payload: map[string]interface{}{
+				"name":         "John Doe",
+				"credit_limit": 5000,
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock:  func() {},
+		},
 		{
 			name: "negative balance",
 			payload: map[string]interface{}{
@@ -114,6 +224,7 @@ func TestCreateTransaction(t *testing.T) {
 	router.POST("/transactions", CreateTransaction)
 
 	customerID := uuid.New()
+	yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
 	tests := []struct {
 		name       string
 		payload    map[string]interface{}
@@ -132,18 +243,230 @@ func TestCreateTransaction(t *testing.T) {
 			wantErr:    false,
 			setupMock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery(`SELECT balance FROM customers WHERE id = \$1 FOR UPDATE`).
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(1200), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "credit", float64(200), "posted").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "back-valued credit",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+				"value_date":  yesterday.Format(dateLayout),
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(customerTimezoneQuery).
+					WithArgs(customerID).
+					WillReturnRows(customerIn("UTC"))
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectQuery(`SELECT closed_through FROM period_closes`).
+					WillReturnRows(pgxmock.NewRows([]string{"closed_through"}))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(1200), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status, value_date\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "credit", float64(200), "posted", yesterday).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "value date in a closed period",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+				"value_date":  yesterday.Format(dateLayout),
+			},
+			wantStatus: http.StatusForbidden,
+			wantErr:    true,
+			setupMock: func() {
+				mock.ExpectQuery(customerTimezoneQuery).
+					WithArgs(customerID).
+					WillReturnRows(customerIn("UTC"))
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectQuery(`SELECT closed_through FROM period_closes`).
+					WillReturnRows(pgxmock.NewRows([]string{"closed_through"}).AddRow(yesterday))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "value date outside the allowed window",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+				"value_date":  yesterday.AddDate(0, 0, -valueDateWindow).Format(dateLayout),
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock: func() {
+				mock.ExpectQuery(customerTimezoneQuery).
+					WithArgs(customerID).
+					WillReturnRows(customerIn("UTC"))
+			},
+		},
+		{
+			// At UTC+14 the window runs from the customer's today, which
+			// is UTC's tomorrow for most of the day
+			name: "value date at the end of the window in the customer's timezone",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+				"value_date":  localToday("Pacific/Kiritimati").AddDate(0, 0, valueDateWindow).Format(dateLayout),
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(customerTimezoneQuery).
+					WithArgs(customerID).
+					WillReturnRows(customerIn("Pacific/Kiritimati"))
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
 					WithArgs(customerID).
-					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(float64(1000)))
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectQuery(`SELECT closed_through FROM period_closes`).
+					WillReturnRows(pgxmock.NewRows([]string{"closed_through"}))
 				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
 					WithArgs(float64(1200), customerID).
 					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount\) VALUES \(\$1, \$2, \$3, \$4\)`).
-					WithArgs(pgxmock.AnyArg(), customerID, "credit", float64(200)).
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status, value_date\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "credit", float64(200), "posted", localToday("Pacific/Kiritimati").AddDate(0, 0, valueDateWindow)).
 					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
 				mock.ExpectCommit()
 			},
 		},
+		{
+			name: "value date for an unknown customer",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+				"value_date":  yesterday.Format(dateLayout),
+			},
+			wantStatus: http.StatusNotFound,
+			wantErr:    true,
+			setupMock: func() {
+				mock.ExpectQuery(customerTimezoneQuery).
+					WithArgs(customerID).
+					WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name: "currency other than the account's",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+				"currency":    "EUR",
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "purchase debits the balance",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "purchase",
+				"amount":      200,
+			},
+			wantStatus: http.StatusCreated,
+			wantErr:    false,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectNoDebitLimits(customerID)
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(800), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "purchase", float64(200), "posted").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "purchase adds to what a credit account owes",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "purchase",
+				"amount":      200,
+			},
+			wantStatus: http.StatusCreated,
+			wantErr:    false,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(creditCustomer(float64(100), float64(500)))
+				expectNoDebitLimits(customerID)
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(300), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "purchase", float64(200), "posted").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "credit beyond what a credit account owes",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      200,
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(creditCustomer(float64(100), float64(500)))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "internal transaction type",
+			payload: map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "transfer_in",
+				"amount":      200,
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock:  func() {},
+		},
 		{
 			name: "invalid transaction type",
 			payload: map[string]interface{}{
@@ -190,41 +513,209 @@ func TestCreateTransaction(t *testing.T) {
 	}
 }
 
-func TestGetBalance(t *testing.T) {
+func TestCreateTransactionDryRun(t *testing.T) {
 	router, err := setupTestRouter()
 	if err != nil {
 		t.Fatalf("Failed to setup test router: %v", err)
 	}
 	defer mock.Close(context.Background())
 
-	router.GET("/customers/:customer_id/balance", GetBalance)
+	router.POST("/transactions", CreateTransaction)
 
 	customerID := uuid.New()
 	tests := []struct {
 		name       string
-		customerID uuid.UUID
+		query      string
+		amount     float64
 		wantStatus int
-		wantErr    bool
 		setupMock  func()
 	}{
 		{
-			name:       "existing customer",
-			customerID: customerID,
+			name:       "returns the would-be balance without writing",
+			query:      "?dry_run=true",
+			amount:     200,
 			wantStatus: http.StatusOK,
-			wantErr:    false,
 			setupMock: func() {
-				mock.ExpectQuery(`SELECT balance FROM customers WHERE id = \$1`).
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectNoDebitLimits(customerID)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "insufficient balance",
+			query:      "?dry_run=1",
+			amount:     2000,
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
 					WithArgs(customerID).
-					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(float64(1000)))
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectNoDebitLimits(customerID)
+				mock.ExpectRollback()
 			},
 		},
+		{
+			name:       "invalid dry_run value",
+			query:      "?dry_run=maybe",
+			amount:     200,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "purchase",
+				"amount":      tt.amount,
+			})
+			req := httptest.NewRequest("POST", "/transactions"+tt.query, bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var response TransactionValidation
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, TransactionValidation{
+					DryRun: true, CustomerID: customerID, Status: "posted",
+					PreviousBalance: 1000, Balance: 800,
+				}, response)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	InitFX(stubRates{"EUR/GBP": 0.8571}, money.Policy{}, time.Minute)
+	defer InitFX(nil, money.Policy{}, time.Minute)
+
+	router.GET("/customers/:customer_id/balance", GetBalance)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name         string
+		customerID   uuid.UUID
+		query        string
+		wantStatus   int
+		wantErr      bool
+		wantBalance  float64
+		wantCurrency string
+		setupMock    func()
+	}{
+		{
+			name:         "existing customer",
+			customerID:   customerID,
+			wantStatus:   http.StatusOK,
+			wantErr:      false,
+			wantBalance:  1000,
+			wantCurrency: "USD",
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT balance, currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(1000), "USD"))
+			},
+		},
+		{
+			name:         "defaults to the account's base currency",
+			customerID:   customerID,
+			wantStatus:   http.StatusOK,
+			wantErr:      false,
+			wantBalance:  250,
+			wantCurrency: "EUR",
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT balance, currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(250), "EUR"))
+			},
+		},
+		{
+			name:         "converts from the account's base currency",
+			customerID:   customerID,
+			query:        "?currency=GBP",
+			wantStatus:   http.StatusOK,
+			wantErr:      false,
+			wantBalance:  171.42,
+			wantCurrency: "GBP",
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT balance, currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(200), "EUR"))
+				mock.ExpectExec(`INSERT INTO fx_rates`).
+					WithArgs("EUR", "GBP", "stub", pgxmock.AnyArg(), 0.8571).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name:         "as of a past time, at the rate stored then",
+			customerID:   customerID,
+			query:        "?currency=GBP&as_of=2025-03-31T23:59:59Z",
+			wantStatus:   http.StatusOK,
+			wantErr:      false,
+			wantBalance:  128.57,
+			wantCurrency: "GBP",
+			setupMock: func() {
+				asOf := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+				mock.ExpectQuery(`SELECT c.currency, c.opening_balance \+ COALESCE\(SUM`).
+					WithArgs(customerID, asOf).
+					WillReturnRows(pgxmock.NewRows([]string{"currency", "balance"}).AddRow("EUR", float64(150)))
+				mock.ExpectQuery(`SELECT rate, provider, effective_at FROM fx_rates`).
+					WithArgs("EUR", "GBP", asOf).
+					WillReturnRows(pgxmock.NewRows([]string{"rate", "provider", "effective_at"}).
+						AddRow(0.8571, "stub", time.Date(2025, 3, 31, 0, 0, 1, 0, time.UTC)))
+			},
+		},
+		{
+			name:       "as of a time with no stored rate",
+			customerID: customerID,
+			query:      "?currency=GBP&as_of=2020-01-01T00:00:00Z",
+			wantStatus: http.StatusNotFound,
+			wantErr:    true,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT c.currency, c.opening_balance \+ COALESCE\(SUM`).
+					WithArgs(customerID, pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows([]string{"currency", "balance"}).AddRow("EUR", float64(0)))
+				mock.ExpectQuery(`SELECT rate, provider, effective_at FROM fx_rates`).
+					WithArgs("EUR", "GBP", pgxmock.AnyArg()).
+					WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:       "as of a future time",
+			customerID: customerID,
+			query:      "?as_of=2999-01-01T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock:  func() {},
+		},
+		{
+			name:       "unsupported currency",
+			customerID: customerID,
+			query:      "?currency=JPY",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    true,
+			setupMock:  func() {},
+		},
 		{
 			name:       "non-existent customer",
 			customerID: uuid.New(),
 			wantStatus: http.StatusNotFound,
 			wantErr:    true,
 			setupMock: func() {
-				mock.ExpectQuery(`SELECT balance FROM customers WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT balance, currency FROM customers WHERE id = \$1`).
 					WithArgs(pgxmock.AnyArg()).
 					WillReturnError(pgx.ErrNoRows)
 			},
@@ -234,7 +725,7 @@ func TestGetBalance(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock()
-			req := httptest.NewRequest("GET", "/customers/"+tt.customerID.String()+"/balance", nil)
+			req := httptest.NewRequest("GET", "/customers/"+tt.customerID.String()+"/balance"+tt.query, nil)
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
 
@@ -244,8 +735,10 @@ func TestGetBalance(t *testing.T) {
 				err := json.Unmarshal(w.Body.Bytes(), &response)
 				assert.NoError(t, err)
 				assert.Contains(t, response, "customer_id")
-				assert.Contains(t, response, "balance")
+				assert.Equal(t, tt.wantBalance, response["balance"])
+				assert.Equal(t, tt.wantCurrency, response["currency"])
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
@@ -285,10 +778,10 @@ func TestGetTransactions(t *testing.T) {
 					WithArgs(customerID).
 					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
 
-				mock.ExpectQuery(`SELECT id, type, amount, created_at FROM transactions WHERE customer_id = \$1 ORDER BY created_at DESC LIMIT \$2 OFFSET \$3`).
+				mock.ExpectQuery(`SELECT id, type, amount, status, created_at, recorded_at, value_date, original_amount, original_currency, fx_rate, rate_provider, rounding, reference, unique_reference FROM transactions WHERE customer_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2 OFFSET \$3`).
 					WithArgs(customerID, 10, 0).
-					WillReturnRows(pgxmock.NewRows([]string{"id", "type", "amount", "created_at"}).
-						AddRow(transactionID, "credit", float64(100), timestampTime))
+					WillReturnRows(transactionRows().
+						AddRow(transactionID, "credit", float64(100), "posted", timestampTime, (*time.Time)(nil), timestampTime.Truncate(24*time.Hour), (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), (*string)(nil), false))
 			},
 		},
 		{
@@ -323,6 +816,8 @@ func TestGetTransactions(t *testing.T) {
 					assert.Equal(t, "credit", tx.Type)
 					assert.Equal(t, float64(100), tx.Amount)
 					assert.Equal(t, timestamp, tx.Timestamp)
+					assert.Equal(t, timestampTime.Format(dateLayout), tx.ValueDate)
+					assert.Equal(t, "posted", tx.Status)
 
 					// Verify pagination headers
 					assert.Equal(t, "1", w.Header().Get("X-Total-Count"))
@@ -334,3 +829,331 @@ func TestGetTransactions(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTransactionsFilter(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/transactions", GetTransactions)
+	customerID := uuid.New()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantWhere  string
+		wantArgs   []interface{}
+	}{
+		{name: "both bounds", query: "?value_date_from=2025-04-01&value_date_to=2025-04-30", wantStatus: http.StatusOK, wantWhere: `customer_id = \$1 AND value_date >= \$2 AND value_date <= \$3`, wantArgs: []interface{}{customerID, from, to}},
+		{name: "upper bound only", query: "?value_date_to=2025-04-30", wantStatus: http.StatusOK, wantWhere: `customer_id = \$1 AND value_date <= \$2`, wantArgs: []interface{}{customerID, to}},
+		{name: "status", query: "?status=held", wantStatus: http.StatusOK, wantWhere: `customer_id = \$1 AND status = \$2`, wantArgs: []interface{}{customerID, "held"}},
+		{name: "status and value date", query: "?status=pending_approval&value_date_from=2025-04-01", wantStatus: http.StatusOK, wantWhere: `customer_id = \$1 AND value_date >= \$2 AND status = \$3`, wantArgs: []interface{}{customerID, from, "pending_approval"}},
+		{name: "unknown status", query: "?status=settled", wantStatus: http.StatusBadRequest},
+		{name: "malformed date", query: "?value_date_from=04/01/2025", wantStatus: http.StatusBadRequest},
+		{name: "inverted range", query: "?value_date_from=2025-04-30&value_date_to=2025-04-01", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantWhere != "" {
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE ` + tt.wantWhere).
+					WithArgs(tt.wantArgs...).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(`FROM transactions WHERE ` + tt.wantWhere + ` ORDER BY`).
+					WithArgs(append(tt.wantArgs, 10, 0)...).
+					WillReturnRows(transactionRows())
+			}
+
+			req := httptest.NewRequest("GET", "/customers/"+customerID.String()+"/transactions"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// transactionRows are the columns the store reads for transaction history
+func transactionRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"id", "type", "amount", "status", "created_at", "recorded_at", "value_date", "original_amount", "original_currency", "fx_rate", "rate_provider", "rounding", "reference", "unique_reference"})
+}
+
+func TestGetTransaction(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/transactions/:transaction_id", GetTransaction)
+	customerID, transactionID := uuid.New(), uuid.New()
+	postedAt := time.Date(2025, 4, 8, 17, 9, 17, 0, time.UTC)
+	originalAmount, originalCurrency, rate, provider, rounding, reference := 74.2, "EUR", 1.0782, "stub", "half_even", "INV-1234"
+
+	mock.ExpectQuery(`SELECT id, type, amount, status, created_at, recorded_at, value_date, original_amount, original_currency, fx_rate, rate_provider, rounding, reference, unique_reference FROM transactions WHERE id = \$1 AND customer_id = \$2`).
+		WithArgs(transactionID, customerID).
+		WillReturnRows(transactionRows().AddRow(transactionID, "purchase", float64(80), "held", postedAt, (*time.Time)(nil), postedAt.Truncate(24*time.Hour), &originalAmount, &originalCurrency, &rate, &provider, &rounding, &reference, true))
+	req := httptest.NewRequest("GET", "/customers/"+customerID.String()+"/transactions/"+transactionID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tx Transaction
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tx))
+	assert.Equal(t, "held", tx.Status)
+	assert.Equal(t, "2025-04-08", tx.ValueDate)
+	assert.Equal(t, float64(80), tx.Amount)
+	assert.Equal(t, 74.2, tx.OriginalAmount)
+	assert.Equal(t, "EUR", tx.OriginalCurrency)
+	assert.Equal(t, 1.0782, tx.FXRate)
+	assert.Equal(t, "stub", tx.RateProvider)
+	assert.Equal(t, "half_even", tx.Rounding)
+	assert.Equal(t, "INV-1234", tx.Reference)
+	assert.True(t, tx.UniqueReference)
+
+	// Another customer's transaction is not found
+	mock.ExpectQuery(`FROM transactions WHERE id = \$1 AND customer_id = \$2`).
+		WithArgs(transactionID, customerID).
+		WillReturnRows(transactionRows())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/"+customerID.String()+"/transactions/"+transactionID.String(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/"+customerID.String()+"/transactions/not-a-uuid", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTransactionsSorting(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/transactions", GetTransactions)
+	customerID := uuid.New()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantOrder  string
+	}{
+		{name: "amount ascending", query: "?sort=amount&order=asc", wantStatus: http.StatusOK, wantOrder: `ORDER BY amount ASC, id ASC`},
+		{name: "type defaults to descending", query: "?sort=type", wantStatus: http.StatusOK, wantOrder: `ORDER BY type DESC, created_at DESC, id DESC`},
+		{name: "order is case-insensitive", query: "?order=ASC", wantStatus: http.StatusOK, wantOrder: `ORDER BY created_at ASC, id ASC`},
+		{name: "unknown sort field", query: "?sort=customer_id", wantStatus: http.StatusBadRequest},
+		{name: "injection attempt", query: "?sort=amount%20DESC%2C%20(SELECT%201)", wantStatus: http.StatusBadRequest},
+		{name: "invalid order", query: "?sort=amount&order=sideways", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantOrder != "" {
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(tt.wantOrder+` LIMIT \$2 OFFSET \$3`).
+					WithArgs(customerID, 10, 0).
+					WillReturnRows(transactionRows())
+			}
+
+			req := httptest.NewRequest("GET", "/customers/"+customerID.String()+"/transactions"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestHandlersWithMemoryStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := ledgerStore
+	defer InitStore(previous)
+	InitStore(store.NewMemory())
+
+	r := gin.New()
+	r.POST("/customers", CreateCustomer)
+	r.POST("/transactions", CreateTransaction)
+	r.GET("/customers/:customer_id/balance", GetBalance)
+	r.GET("/customers/:customer_id/transactions", GetTransactions)
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/customers", map[string]interface{}{"name": "Jane Doe", "initial_balance": 100})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var customer CustomerResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &customer))
+
+	w = send("POST", "/transactions", map[string]interface{}{"customer_id": customer.CustomerID, "type": "debit", "amount": 30})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = send("POST", "/transactions", map[string]interface{}{"customer_id": customer.CustomerID, "type": "debit", "amount": 500})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("GET", "/customers/"+customer.CustomerID.String()+"/balance", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"balance":70`)
+
+	w = send("GET", "/customers/"+customer.CustomerID.String()+"/transactions", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Total-Count"))
+
+	w = send("GET", "/customers/"+uuid.New().String()+"/balance", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateTransactionUniqueReference(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+
+	customer := store.Customer{ID: uuid.New(), Name: "Jane Doe", Balance: 100, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(context.Background(), &customer))
+
+	r := gin.New()
+	r.POST("/transactions", CreateTransaction)
+	send := func(body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	payment := map[string]interface{}{"customer_id": customer.ID, "type": "credit", "amount": 25, "reference": "E2E-0001", "unique_reference": true}
+
+	w := send(payment)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created TransactionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = send(payment)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "duplicate_reference", resp.Code)
+	require.NotNil(t, resp.TransactionID)
+	assert.Equal(t, created.TransactionID, *resp.TransactionID)
+
+	w = send(map[string]interface{}{"customer_id": customer.ID, "type": "credit", "amount": 25, "unique_reference": true})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "reference is required")
+
+	balance, err := memory.GetBalance(context.Background(), customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(125), balance.Amount)
+}
+
+func TestRequestTaggedDB(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('application_name', \$1, true\)`).
+		WithArgs("ledger-service checkout-123").
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectRollback()
+
+	ctx := middleware.WithRequestID(context.Background(), "checkout-123")
+	tx, err := db.Begin(ctx)
+	assert.NoError(t, err)
+	tx.Rollback(ctx)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestErrorResponseIncludesRequestID(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/balance", middleware.RequestID(), GetBalance)
+
+	req := httptest.NewRequest("GET", "/customers/not-a-uuid/balance", nil)
+	req.Header.Set("X-Request-ID", "checkout-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "checkout-123", resp.RequestID)
+	assert.Empty(t, resp.TraceID)
+}
+
+func TestErrorResponseProblemJSON(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/balance", middleware.RequestID(), GetBalance)
+
+	req := httptest.NewRequest("GET", "/customers/not-a-uuid/balance", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	req.Header.Set("X-Request-ID", "checkout-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, middleware.ProblemContentType, w.Header().Get("Content-Type"))
+	var resp middleware.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "about:blank", resp.Type)
+	assert.Equal(t, "Bad Request", resp.Title)
+	assert.Equal(t, http.StatusBadRequest, resp.Status)
+	assert.Equal(t, "Invalid customer ID", resp.Detail)
+	assert.Equal(t, "/customers/not-a-uuid/balance", resp.Instance)
+	assert.Equal(t, "checkout-123", resp.RequestID)
+}
+
+func TestErrorResponseLocalized(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/balance", GetBalance)
+
+	req := httptest.NewRequest("GET", "/customers/not-a-uuid/balance", nil)
+	req.Header.Set("Accept-Language", "es-ES,es;q=0.9,en;q=0.5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "es", w.Header().Get("Content-Language"))
+	var resp ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "ID de cliente no válido", resp.Error)
+}
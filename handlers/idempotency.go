@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyKeys stores idempotency records in Postgres, where the primary
+// key on idempotency_keys lets exactly one replica claim each key. Expired
+// rows are reclaimed on conflict and purged by PurgeIdempotencyKeys.
+type IdempotencyKeys struct {
+	TTL time.Duration
+}
+
+// NewIdempotencyKeys creates a Postgres idempotency store keeping keys for ttl
+func NewIdempotencyKeys(ttl time.Duration) *IdempotencyKeys {
+	return &IdempotencyKeys{TTL: ttl}
+}
+
+// Claim inserts record under key, taking over the row if it has expired
+func (s *IdempotencyKeys) Claim(ctx context.Context, key string, record []byte) (bool, []byte, error) {
+	tag, err := db.Exec(ctx,
+		`INSERT INTO idempotency_keys (key, record, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET record = EXCLUDED.record, created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()`,
+		key, record, time.Now().Add(s.TTL))
+	if err != nil {
+		return false, nil, err
+	}
+	if tag.RowsAffected() == 1 {
+		return true, nil, nil
+	}
+
+	var existing []byte
+	err = db.QueryRow(ctx, "SELECT record FROM idempotency_keys WHERE key = $1", key).Scan(&existing)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil, nil
+	}
+	return false, existing, err
+}
+
+// Save stores the finished request's record under key
+func (s *IdempotencyKeys) Save(ctx context.Context, key string, record []byte) error {
+	_, err := db.Exec(ctx, "UPDATE idempotency_keys SET record = $2 WHERE key = $1", key, record)
+	return err
+}
+
+// Release deletes key
+func (s *IdempotencyKeys) Release(ctx context.Context, key string) error {
+	_, err := db.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	return err
+}
+
+// PurgeIdempotencyKeys deletes every expired idempotency key and returns how
+// many were removed
+func PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	tag, err := db.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
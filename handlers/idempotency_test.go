@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeysClaim(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	store := NewIdempotencyKeys(time.Hour)
+	ctx := context.Background()
+	record := []byte(`{"fingerprint":"a"}`)
+
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("key-1", record, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	claimed, existing, err := store.Claim(ctx, "key-1", record)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Nil(t, existing)
+
+	// Held by another replica
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("key-1", record, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectQuery("SELECT record FROM idempotency_keys WHERE key = \\$1").
+		WithArgs("key-1").
+		WillReturnRows(pgxmock.NewRows([]string{"record"}).AddRow([]byte(`{"fingerprint":"a","status":201}`)))
+	claimed, existing, err = store.Claim(ctx, "key-1", record)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, `{"fingerprint":"a","status":201}`, string(existing))
+
+	// Deleted between the insert and the read
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("key-2", record, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectQuery("SELECT record FROM idempotency_keys WHERE key = \\$1").
+		WithArgs("key-2").
+		WillReturnError(pgx.ErrNoRows)
+	claimed, existing, err = store.Claim(ctx, "key-2", record)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Nil(t, existing)
+
+	mock.ExpectExec("UPDATE idempotency_keys SET record = \\$2 WHERE key = \\$1").
+		WithArgs("key-1", record).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, store.Save(ctx, "key-1", record))
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE key = \\$1").
+		WithArgs("key-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	assert.NoError(t, store.Release(ctx, "key-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeIdempotencyKeys(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE expires_at <= NOW\(\)`).
+		WillReturnResult(pgxmock.NewResult("DELETE", 4))
+
+	n, err := PurgeIdempotencyKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"ledger-service/ingest"
+	"ledger-service/ledger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ingestSources map[string]*ingest.Source
+)
+
+// InitIngestSources sets the providers allowed to post notifications, by
+// name
+func InitIngestSources(sources map[string]*ingest.Source) {
+	ingestSources = sources
+}
+
+// IngestResponse acknowledges a notification
+// @Description Outcome of an inbound notification; a repeat delivery is acknowledged with the transaction it posted the first time
+type IngestResponse struct {
+	// Status is the posting's status, duplicate for a notification already
+	// posted, or ignored when no rule of the source matched it
+	Status        string     `json:"status" example:"posted" enums:"posted,held,pending_approval,duplicate,ignored"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+}
+
+// @Summary Receive a notification from an external provider
+// @Description Accept a signed notification from a configured source, such as a PSP settlement or a bank feed, and post the transaction its rules map it to. The signature is checked against the source's secret before anything is read. The first rule whose match holds picks the transaction type and the paths of the event ID, customer, amount and currency; a notification no rule matches is acknowledged and ignored. The posting uses "<source>:<event ID>" as a unique reference, so a provider redelivering a notification gets the original transaction back instead of a second posting.
+// @Tags ingest
+// @Accept json
+// @Produce json
+// @Param source path string true "Source name from INGEST_SOURCES"
+// @Param notification body object true "Notification in the provider's format"
+// @Success 200 {object} IngestResponse "Notification ignored or already posted"
+// @Success 201 {object} IngestResponse "Transaction posted"
+// @Success 202 {object} IngestResponse "Transaction held for fraud review or awaiting escrow approval"
+// @Failure 401 {object} ErrorResponse "Missing or invalid signature"
+// @Failure 404 {object} ErrorResponse "Unknown source"
+// @Failure 422 {object} ErrorResponse "Notification cannot be mapped or posted"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /ingest/{source} [post]
+func IngestNotification(c *gin.Context) {
+	name := c.Param("source")
+	source, ok := ingestSources[name]
+	if !ok {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Unknown source"})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+		return
+	}
+	if err := source.Verify(c.Request.Header, body, time.Now()); err != nil {
+		respondError(c, http.StatusUnauthorized, ErrorResponse{Error: "Invalid signature", Code: "invalid_signature"})
+		return
+	}
+
+	p, matched, err := source.Map(body)
+	if err != nil {
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Notification cannot be mapped: " + err.Error()})
+		return
+	}
+	if !matched {
+		c.JSON(http.StatusOK, IngestResponse{Status: "ignored"})
+		return
+	}
+	if msg := validateMoney("amount", p.Amount); msg != "" {
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Notification cannot be mapped: " + msg})
+		return
+	}
+	reference := name + ":" + p.EventID
+	if len(reference) > 140 {
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Notification cannot be mapped: event ID is too long"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID:      p.CustomerID,
+		Type:            p.Type,
+		Amount:          p.Amount,
+		Currency:        p.Currency,
+		Reference:       reference,
+		UniqueReference: true,
+		// Providers redeliver rather than repeat, and a redelivery is caught
+		// by the unique reference
+		AllowDuplicate: true,
+	})
+	if err != nil {
+		var violation *ledger.ViolationError
+		var duplicate *ledger.DuplicateReferenceError
+		switch {
+		case errors.As(err, &duplicate):
+			c.JSON(http.StatusOK, IngestResponse{Status: "duplicate", TransactionID: &duplicate.TransactionID})
+		case errors.Is(err, ledger.ErrUnknownTransactionType):
+			respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Unknown transaction type " + p.Type})
+		case errors.Is(err, ledger.ErrCustomerNotFound):
+			respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Customer not found"})
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Insufficient balance"})
+		case errors.Is(err, ledger.ErrOverpayment):
+			respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Payment exceeds the amount owed"})
+		case errors.Is(err, ledger.ErrCurrencyMismatch):
+			respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Currency does not match the account's base currency"})
+		case errors.As(err, &violation):
+			respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: violation.Message})
+		default:
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post notification"})
+		}
+		return
+	}
+	invalidateBalances(ctx, p.CustomerID)
+
+	switch result.Status {
+	case ledger.StatusRejected:
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Transaction rejected by fraud rules"})
+	case ledger.StatusHeld, ledger.StatusPendingApproval:
+		c.JSON(http.StatusAccepted, IngestResponse{Status: result.Status, TransactionID: &result.TransactionID})
+	default:
+		c.JSON(http.StatusCreated, IngestResponse{Status: result.Status, TransactionID: &result.TransactionID})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/ingest"
+	"ledger-service/store"
+	"ledger-service/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestNotification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+
+	sources, err := ingest.ParseSources(`[{"name": "psp", "secret_env": "PSP_SECRET", "rules": [
+		{"match": {"type": "settlement.paid"}, "type": "credit", "event_id": "id", "customer_id": "data.customer", "amount": "data.amount", "amount_scale": 0.01}
+	]}]`, func(string) string { return "psp-secret" })
+	require.NoError(t, err)
+	InitIngestSources(sources)
+	defer InitIngestSources(nil)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 0, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+
+	r := gin.New()
+	r.POST("/ingest/:source", IngestNotification)
+	send := func(source string, body map[string]interface{}, secret string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/ingest/"+source, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, time.Now(), payload))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	settlement := map[string]interface{}{
+		"id":   "evt_1",
+		"type": "settlement.paid",
+		"data": map[string]interface{}{"customer": customer.ID.String(), "amount": 2550},
+	}
+
+	w := send("psp", settlement, "psp-secret")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var posted IngestResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &posted))
+	assert.Equal(t, "posted", posted.Status)
+	require.NotNil(t, posted.TransactionID)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, 25.5, balance.Amount)
+	tx, err := memory.GetTransaction(ctx, customer.ID, *posted.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, "psp:evt_1", tx.Reference)
+
+	// A redelivery answers with the original transaction and posts nothing
+	w = send("psp", settlement, "psp-secret")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var repeat IngestResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &repeat))
+	assert.Equal(t, "duplicate", repeat.Status)
+	assert.Equal(t, posted.TransactionID, repeat.TransactionID)
+	balance, _ = memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, 25.5, balance.Amount)
+
+	w = send("psp", map[string]interface{}{"id": "evt_2", "type": "settlement.pending"}, "psp-secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"ignored"`)
+
+	w = send("psp", settlement, "wrong-secret")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = send("bank", settlement, "psp-secret")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	settlement["id"] = "evt_3"
+	settlement["data"] = map[string]interface{}{"customer": uuid.New().String(), "amount": 100}
+	w = send("psp", settlement, "psp-secret")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "Customer not found")
+}
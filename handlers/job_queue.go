@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"ledger-service/jobqueue"
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Kinds of queued job
+const (
+	JobWebhookDelivery = "webhook.delivery"
+	JobStatementRender = "statement.render"
+	JobCSVImport       = "import.csv"
+	JobSMSNotification = "notification.sms"
+	JobTransactionPost = "transaction.post"
+)
+
+// JobKinds lists the kinds of queued job
+var JobKinds = []string{JobWebhookDelivery, JobStatementRender, JobCSVImport, JobSMSNotification, JobTransactionPost}
+
+// jobMaxAttempts is how many attempts a job of each kind gets. An import is
+// not retried, as a second attempt would post the rows the first got
+// through again.
+var jobMaxAttempts = map[string]int{
+	JobWebhookDelivery: 8,
+	JobStatementRender: 3,
+	JobCSVImport:       1,
+	JobSMSNotification: 3,
+	JobTransactionPost: 5,
+}
+
+// customerJobKinds are the kinds of job customers queue themselves and may
+// look up; the rest are only shown to operators
+var customerJobKinds = []string{JobStatementRender}
+
+// QueueJob is a job in the background queue
+// @Description Queued background job and its outcome
+type QueueJob struct {
+	ID         uuid.UUID  `json:"job_id" format:"uuid"`
+	Kind       string     `json:"kind" example:"statement.render" enums:"webhook.delivery,statement.render,import.csv,notification.sms,transaction.post"`
+	CustomerID *uuid.UUID `json:"customer_id,omitempty" format:"uuid"`
+	// Status is queued while waiting for its first or next attempt, and
+	// dead once it ran out of attempts
+	Status      string `json:"status" example:"succeeded" enums:"queued,running,succeeded,dead,cancelled"`
+	Attempts    int    `json:"attempts" example:"1"`
+	MaxAttempts int    `json:"max_attempts" example:"3"`
+	// RunAt is when the job is next due
+	RunAt     string `json:"run_at" format:"date-time"`
+	LastError string `json:"last_error,omitempty" example:"endpoint answered 503"`
+	// Payload is what the job was given; it is only shown to operators
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+	// Result is what a succeeded job produced, such as the statement it
+	// generated or the outcome of each imported row
+	Result     json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	CreatedAt  string          `json:"created_at" format:"date-time"`
+	UpdatedAt  string          `json:"updated_at" format:"date-time"`
+	FinishedAt string          `json:"finished_at,omitempty" format:"date-time"`
+}
+
+// QueueStats counts the jobs of one kind by status
+// @Description Jobs of one kind by status
+type QueueStats struct {
+	Kind      string `json:"kind" example:"webhook.delivery"`
+	Queued    int    `json:"queued" example:"12"`
+	Running   int    `json:"running" example:"4"`
+	Succeeded int    `json:"succeeded" example:"10452"`
+	Dead      int    `json:"dead" example:"3"`
+	Cancelled int    `json:"cancelled" example:"0"`
+	// OldestDueAt is when the longest-waiting due job became due
+	OldestDueAt string `json:"oldest_due_at,omitempty" format:"date-time"`
+}
+
+const queueJobColumns = `id, kind, customer_id, payload, status, attempts, max_attempts, run_at,
+	COALESCE(last_error, ''), result, created_at, updated_at, finished_at`
+
+func scanQueueJob(row pgx.Row) (QueueJob, error) {
+	var j QueueJob
+	var payload, result []byte
+	var runAt, createdAt, updatedAt time.Time
+	var finishedAt *time.Time
+	if err := row.Scan(&j.ID, &j.Kind, &j.CustomerID, &payload, &j.Status, &j.Attempts, &j.MaxAttempts, &runAt,
+		&j.LastError, &result, &createdAt, &updatedAt, &finishedAt); err != nil {
+		return QueueJob{}, err
+	}
+	j.Payload = payload
+	if result != nil {
+		j.Result = result
+	}
+	j.RunAt = runAt.UTC().Format(time.RFC3339)
+	j.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	j.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	if finishedAt != nil {
+		j.FinishedAt = finishedAt.UTC().Format(time.RFC3339)
+	}
+	return j, nil
+}
+
+// enqueueJob adds a job of kind to the queue, due at once. Callers pass
+// their open transaction so the job is queued if and only if the change
+// that asked for it commits.
+func enqueueJob(ctx context.Context, q execer, kind string, customerID *uuid.UUID, payload interface{}) (uuid.UUID, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	id := uuid.New()
+	_, err = q.Exec(ctx,
+		"INSERT INTO queue_jobs (id, kind, customer_id, payload, max_attempts) VALUES ($1, $2, $3, $4, $5)",
+		id, kind, customerID, data, jobMaxAttempts[kind])
+	return id, err
+}
+
+// JobQueue keeps the background job queue in Postgres. Due jobs are claimed
+// with SKIP LOCKED, so instances share the work without running a job
+// twice. An attempt's outcome is only recorded while its job is still
+// running, so an operator cancelling or retrying it meanwhile has the last
+// word. It satisfies jobqueue.Store.
+type JobQueue struct{}
+
+// NewJobQueue creates the Postgres job queue
+func NewJobQueue() *JobQueue {
+	return &JobQueue{}
+}
+
+// Claim leases up to limit due jobs of kind, oldest due first, including
+// running jobs whose lease passed
+func (q *JobQueue) Claim(ctx context.Context, kind string, limit int, lease time.Duration) ([]jobqueue.Job, error) {
+	rows, err := db.Query(ctx,
+		`UPDATE queue_jobs SET status = 'running', attempts = attempts + 1,
+			locked_until = NOW() + make_interval(secs => $3), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM queue_jobs
+			WHERE kind = $1 AND ((status = 'queued' AND run_at <= NOW()) OR (status = 'running' AND locked_until < NOW()))
+			ORDER BY run_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING id, kind, customer_id, payload, attempts, max_attempts`,
+		kind, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []jobqueue.Job
+	for rows.Next() {
+		var j jobqueue.Job
+		var payload []byte
+		if err := rows.Scan(&j.ID, &j.Kind, &j.CustomerID, &payload, &j.Attempts, &j.MaxAttempts); err != nil {
+			return nil, err
+		}
+		j.Payload = payload
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// Complete records a job's success
+func (q *JobQueue) Complete(ctx context.Context, id uuid.UUID, result json.RawMessage) error {
+	_, err := db.Exec(ctx,
+		`UPDATE queue_jobs SET status = 'succeeded', result = $2, last_error = NULL, locked_until = NULL,
+			finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'`,
+		id, []byte(result))
+	return err
+}
+
+// Retry requeues a failed job to run again at
+func (q *JobQueue) Retry(ctx context.Context, id uuid.UUID, errMsg string, at time.Time) error {
+	_, err := db.Exec(ctx,
+		`UPDATE queue_jobs SET status = 'queued', last_error = $2, run_at = $3, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'running'`,
+		id, errMsg, at)
+	return err
+}
+
+// Bury dead-letters a job
+func (q *JobQueue) Bury(ctx context.Context, id uuid.UUID, errMsg string) error {
+	_, err := db.Exec(ctx,
+		`UPDATE queue_jobs SET status = 'dead', last_error = $2, locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'`,
+		id, errMsg)
+	return err
+}
+
+// JobQueueKinds configures the worker's handler for each kind of job.
+// concurrency overrides how many jobs of a kind each instance runs at once.
+func JobQueueKinds(concurrency map[string]int) map[string]jobqueue.Kind {
+	kinds := map[string]jobqueue.Kind{
+		JobWebhookDelivery: {Handler: runWebhookDelivery, Concurrency: 4, Timeout: time.Minute},
+		JobStatementRender: {Handler: runStatementRender, Concurrency: 2, Timeout: 5 * time.Minute},
+		JobCSVImport:       {Handler: runCSVImport, Concurrency: 1, Timeout: 10 * time.Minute},
+		JobSMSNotification: {Handler: runSMSNotification, Concurrency: 2, Timeout: time.Minute},
+		JobTransactionPost: {Handler: runTransactionPost, Concurrency: 8, Timeout: 30 * time.Second},
+	}
+	for name, n := range concurrency {
+		if kind, ok := kinds[name]; ok && n > 0 {
+			kind.Concurrency = n
+			kinds[name] = kind
+		}
+	}
+	return kinds
+}
+
+// preferAsync reports whether the client asked, with Prefer: respond-async,
+// for long-running work to be queued rather than done before responding
+func preferAsync(c *gin.Context) bool {
+	for _, value := range c.Request.Header.Values("Prefer") {
+		for _, pref := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// respondQueued answers 202 for work queued as job, pointing the client at
+// the job's status: the customer's job route for kinds customers queue, the
+// admin one otherwise
+func respondQueued(c *gin.Context, customerID uuid.UUID, jobID uuid.UUID, kind string) {
+	location := "/v1/admin/queue/jobs/" + jobID.String()
+	for _, k := range customerJobKinds {
+		if k == kind {
+			location = "/v1/customers/" + customerID.String() + "/jobs/" + jobID.String()
+		}
+	}
+	c.Header("Preference-Applied", "respond-async")
+	c.Header("Location", location)
+	now := time.Now().UTC().Format(time.RFC3339)
+	c.JSON(http.StatusAccepted, QueueJob{
+		ID:          jobID,
+		Kind:        kind,
+		CustomerID:  &customerID,
+		Status:      jobqueue.StatusQueued,
+		MaxAttempts: jobMaxAttempts[kind],
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+func parseJobID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid job ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// @Summary Get a customer's job
+// @Description Get the status of work the customer queued with Prefer: respond-async, such as generating a statement. Once the job has succeeded, result holds what it produced.
+// @Tags customers
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param job_id path string true "Job ID" format(uuid)
+// @Success 200 {object} QueueJob "Job"
+// @Failure 400 {object} ErrorResponse "Invalid customer or job ID"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/jobs/{job_id} [get]
+func GetCustomerJob(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+	job, err := scanQueueJob(db.QueryRow(c.Request.Context(),
+		"SELECT "+queueJobColumns+" FROM queue_jobs WHERE id = $1 AND customer_id = $2 AND kind = ANY($3)",
+		id, customerID, customerJobKinds))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Job not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get job"})
+		return
+	}
+	job.Payload = nil
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary List queued jobs
+// @Description List background jobs, latest first, optionally by kind and status. Dead jobs ran out of attempts or failed for good; last_error says why.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param kind query string false "Kind of job" Enums(webhook.delivery, statement.render, import.csv, notification.sms, transaction.post)
+// @Param status query string false "Job status" Enums(queued, running, succeeded, dead, cancelled)
+// @Param customer_id query string false "Customer ID" format(uuid)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Jobs per page" default(10)
+// @Success 200 {array} QueueJob "Jobs"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/queue/jobs [get]
+func ListQueueJobs(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	var kind, status *string
+	if v := c.Query("kind"); v != "" {
+		if _, known := jobMaxAttempts[v]; !known {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid kind: must be one of " + strings.Join(JobKinds, ", ")})
+			return
+		}
+		kind = &v
+	}
+	if v := c.Query("status"); v != "" {
+		switch v {
+		case jobqueue.StatusQueued, jobqueue.StatusRunning, jobqueue.StatusSucceeded, jobqueue.StatusDead, jobqueue.StatusCancelled:
+		default:
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status: must be queued, running, succeeded, dead or cancelled"})
+			return
+		}
+		status = &v
+	}
+	var customerID *uuid.UUID
+	if v := c.Query("customer_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+			return
+		}
+		customerID = &id
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		`SELECT `+queueJobColumns+` FROM queue_jobs
+		WHERE ($1::text IS NULL OR kind = $1) AND ($2::text IS NULL OR status = $2) AND ($3::uuid IS NULL OR customer_id = $3)
+		ORDER BY created_at DESC LIMIT $4 OFFSET $5`,
+		kind, status, customerID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list jobs"})
+		return
+	}
+	defer rows.Close()
+	jobs := []QueueJob{}
+	for rows.Next() {
+		job, err := scanQueueJob(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan job"})
+			return
+		}
+		jobs = append(jobs, job)
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// @Summary Get a queued job
+// @Description Get a background job with its payload, attempts, last error and result
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param job_id path string true "Job ID" format(uuid)
+// @Success 200 {object} QueueJob "Job"
+// @Failure 400 {object} ErrorResponse "Invalid job ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/queue/jobs/{job_id} [get]
+func GetQueueJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+	job, err := scanQueueJob(db.QueryRow(c.Request.Context(),
+		"SELECT "+queueJobColumns+" FROM queue_jobs WHERE id = $1", id))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Job not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Retry a dead or cancelled job
+// @Description Queue a dead or cancelled job to run again straight away, with its attempts counted from zero, e.g. once the webhook endpoint it failed against is back
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param job_id path string true "Job ID" format(uuid)
+// @Success 200 {object} QueueJob "Job queued again"
+// @Failure 400 {object} ErrorResponse "Invalid job ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 409 {object} ErrorResponse "Job is not dead or cancelled"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/queue/jobs/{job_id}/retry [post]
+func RetryQueueJob(c *gin.Context) {
+	changeQueueJob(c, "job.retried",
+		`UPDATE queue_jobs SET status = 'queued', attempts = 0, run_at = NOW(), finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('dead', 'cancelled') RETURNING `+queueJobColumns,
+		"Only dead or cancelled jobs can be retried")
+}
+
+// @Summary Cancel a queued job
+// @Description Stop a job that is waiting for its first or next attempt from running. A job already running finishes its attempt.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param job_id path string true "Job ID" format(uuid)
+// @Success 200 {object} QueueJob "Job cancelled"
+// @Failure 400 {object} ErrorResponse "Invalid job ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 409 {object} ErrorResponse "Job is not queued"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/queue/jobs/{job_id}/cancel [post]
+func CancelQueueJob(c *gin.Context) {
+	changeQueueJob(c, "job.cancelled",
+		`UPDATE queue_jobs SET status = 'cancelled', finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'queued' RETURNING `+queueJobColumns,
+		"Only queued jobs can be cancelled")
+}
+
+// changeQueueJob applies an operator's change to a job's status with update,
+// which returns no row when the job's status does not allow it, and audits
+// it as action
+func changeQueueJob(c *gin.Context, action, update, conflict string) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	if err := tx.QueryRow(ctx, "SELECT status FROM queue_jobs WHERE id = $1 FOR UPDATE", id).Scan(&previous); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Job not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get job"})
+		}
+		return
+	}
+	job, err := scanQueueJob(tx.QueryRow(ctx, update, id))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: conflict + "; this one is " + previous})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update job"})
+		return
+	}
+	if err := recordAudit(ctx, tx, c.GetString(middleware.ActorKey), action, "queue_job", id, job.CustomerID, map[string]interface{}{
+		"kind":            job.Kind,
+		"previous_status": previous,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Get job queue statistics
+// @Description Count the background jobs of each kind by status, with how long the longest-waiting due job has been due, to spot a backlog or a growing dead-letter list
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} QueueStats "Jobs by kind and status"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/queue/stats [get]
+func GetQueueStats(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		`SELECT kind,
+			COUNT(*) FILTER (WHERE status = 'queued'),
+			COUNT(*) FILTER (WHERE status = 'running'),
+			COUNT(*) FILTER (WHERE status = 'succeeded'),
+			COUNT(*) FILTER (WHERE status = 'dead'),
+			COUNT(*) FILTER (WHERE status = 'cancelled'),
+			MIN(run_at) FILTER (WHERE status = 'queued' AND run_at <= NOW())
+		FROM queue_jobs GROUP BY kind ORDER BY kind`)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to count jobs"})
+		return
+	}
+	defer rows.Close()
+	stats := []QueueStats{}
+	for rows.Next() {
+		var s QueueStats
+		var oldest *time.Time
+		if err := rows.Scan(&s.Kind, &s.Queued, &s.Running, &s.Succeeded, &s.Dead, &s.Cancelled, &oldest); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan job counts"})
+			return
+		}
+		if oldest != nil {
+			s.OldestDueAt = oldest.UTC().Format(time.RFC3339)
+		}
+		stats = append(stats, s)
+	}
+	c.JSON(http.StatusOK, stats)
+}
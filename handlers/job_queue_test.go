@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/jobqueue"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var queueJobRowColumns = []string{"id", "kind", "customer_id", "payload", "status", "attempts", "max_attempts", "run_at",
+	"last_error", "result", "created_at", "updated_at", "finished_at"}
+
+func TestJobQueue(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	q := NewJobQueue()
+	ctx := context.Background()
+	jobID, customerID := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`UPDATE queue_jobs SET status = 'running', attempts = attempts \+ 1,\s+locked_until = NOW\(\) \+ make_interval\(secs => \$3\)`).
+		WithArgs(JobStatementRender, 2, float64(90)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "kind", "customer_id", "payload", "attempts", "max_attempts"}).
+			AddRow(jobID, JobStatementRender, &customerID, []byte(`{"from":"2025-04-01","to":"2025-04-30"}`), 1, 3))
+	jobs, err := q.Claim(ctx, JobStatementRender, 2, 90*time.Second)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, jobqueue.Job{ID: jobID, Kind: JobStatementRender, CustomerID: &customerID,
+		Payload: json.RawMessage(`{"from":"2025-04-01","to":"2025-04-30"}`), Attempts: 1, MaxAttempts: 3}, jobs[0])
+
+	// Outcomes only land on jobs still running
+	mock.ExpectExec(`UPDATE queue_jobs SET status = 'succeeded', result = \$2.* WHERE id = \$1 AND status = 'running'`).
+		WithArgs(jobID, []byte(`{"statement_id":"x"}`)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, q.Complete(ctx, jobID, json.RawMessage(`{"statement_id":"x"}`)))
+	at := time.Now().Add(time.Minute)
+	mock.ExpectExec(`UPDATE queue_jobs SET status = 'queued', last_error = \$2, run_at = \$3.* WHERE id = \$1 AND status = 'running'`).
+		WithArgs(jobID, "timeout", at).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, q.Retry(ctx, jobID, "timeout", at))
+	mock.ExpectExec(`UPDATE queue_jobs SET status = 'dead', last_error = \$2.* WHERE id = \$1 AND status = 'running'`).
+		WithArgs(jobID, "customer not found").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.NoError(t, q.Bury(ctx, jobID, "customer not found"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobQueueKinds(t *testing.T) {
+	kinds := JobQueueKinds(map[string]int{JobWebhookDelivery: 16, "unknown": 3, JobCSVImport: 0})
+	assert.Len(t, kinds, len(JobKinds))
+	assert.Equal(t, 16, kinds[JobWebhookDelivery].Concurrency)
+	assert.Equal(t, 1, kinds[JobCSVImport].Concurrency, "zero keeps the default")
+	for _, kind := range JobKinds {
+		assert.NotNil(t, kinds[kind].Handler, kind)
+		assert.Positive(t, jobMaxAttempts[kind], kind)
+	}
+}
+
+func TestPreferAsync(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                              false,
+		"respond-async":                 true,
+		"return=minimal, Respond-Async": true,
+		"wait=10":                       false,
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/", nil)
+		if header != "" {
+			c.Request.Header.Set("Prefer", header)
+		}
+		assert.Equal(t, want, preferAsync(c), header)
+	}
+}
+
+func TestQueueJobsAPI(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.GET("/admin/queue/jobs", ListQueueJobs)
+	router.GET("/admin/queue/jobs/:job_id", GetQueueJob)
+	router.POST("/admin/queue/jobs/:job_id/retry", RetryQueueJob)
+	router.POST("/admin/queue/jobs/:job_id/cancel", CancelQueueJob)
+	router.GET("/admin/queue/stats", GetQueueStats)
+	router.GET("/customers/:customer_id/jobs/:job_id", GetCustomerJob)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	jobID, customerID := uuid.New(), uuid.New()
+	now := time.Now()
+	jobRow := func(status string) *pgxmock.Rows {
+		return pgxmock.NewRows(queueJobRowColumns).
+			AddRow(jobID, JobWebhookDelivery, &customerID, []byte(`{"event_id":"e"}`), status, 8, 8, now,
+				"https://example.com/hook: endpoint returned status 500", []byte(nil), now, now, &now)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		dead := jobqueue.StatusDead
+		mock.ExpectQuery(`SELECT .* FROM queue_jobs\s+WHERE \(\$1::text IS NULL OR kind = \$1\) AND \(\$2::text IS NULL OR status = \$2\)`).
+			WithArgs((*string)(nil), &dead, (*uuid.UUID)(nil), 10, 0).
+			WillReturnRows(jobRow(dead))
+		w := send("GET", "/admin/queue/jobs?status=dead")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var jobs []QueueJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &jobs))
+		require.Len(t, jobs, 1)
+		assert.Equal(t, 8, jobs[0].Attempts)
+		assert.JSONEq(t, `{"event_id":"e"}`, string(jobs[0].Payload))
+		assert.Nil(t, jobs[0].Result)
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/queue/jobs?kind=email").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/queue/jobs?status=lost").Code)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		mock.ExpectQuery(`FROM queue_jobs WHERE id = \$1`).
+			WithArgs(jobID).
+			WillReturnError(pgx.ErrNoRows)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/admin/queue/jobs/"+jobID.String()).Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/queue/jobs/nope").Code)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status FROM queue_jobs WHERE id = \$1 FOR UPDATE`).
+			WithArgs(jobID).
+			WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(jobqueue.StatusDead))
+		mock.ExpectQuery(`UPDATE queue_jobs SET status = 'queued', attempts = 0.* WHERE id = \$1 AND status IN \('dead', 'cancelled'\)`).
+			WithArgs(jobID).
+			WillReturnRows(jobRow(jobqueue.StatusQueued))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(pgxmock.AnyArg(), "", "job.retried", "queue_job", jobID, &customerID, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		w := send("POST", "/admin/queue/jobs/"+jobID.String()+"/retry")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"status":"queued"`)
+
+		// A job still working through its attempts cannot be retried
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status FROM queue_jobs`).
+			WithArgs(jobID).
+			WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(jobqueue.StatusRunning))
+		mock.ExpectQuery(`UPDATE queue_jobs SET status = 'queued'`).
+			WithArgs(jobID).
+			WillReturnError(pgx.ErrNoRows)
+		mock.ExpectRollback()
+		w = send("POST", "/admin/queue/jobs/"+jobID.String()+"/retry")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "this one is running")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status FROM queue_jobs`).
+			WithArgs(jobID).
+			WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(jobqueue.StatusQueued))
+		mock.ExpectQuery(`UPDATE queue_jobs SET status = 'cancelled'.* WHERE id = \$1 AND status = 'queued'`).
+			WithArgs(jobID).
+			WillReturnRows(jobRow(jobqueue.StatusCancelled))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(pgxmock.AnyArg(), "", "job.cancelled", "queue_job", jobID, &customerID, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		assert.Equal(t, http.StatusOK, send("POST", "/admin/queue/jobs/"+jobID.String()+"/cancel").Code)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT status FROM queue_jobs`).
+			WithArgs(jobID).
+			WillReturnError(pgx.ErrNoRows)
+		mock.ExpectRollback()
+		assert.Equal(t, http.StatusNotFound, send("POST", "/admin/queue/jobs/"+jobID.String()+"/cancel").Code)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT kind,\s+COUNT\(\*\) FILTER \(WHERE status = 'queued'\)`).
+			WillReturnRows(pgxmock.NewRows([]string{"kind", "queued", "running", "succeeded", "dead", "cancelled", "oldest"}).
+				AddRow(JobWebhookDelivery, 12, 4, 10452, 3, 0, &now).
+				AddRow(JobStatementRender, 0, 0, 20, 0, 1, (*time.Time)(nil)))
+		w := send("GET", "/admin/queue/stats")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var stats []QueueStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		require.Len(t, stats, 2)
+		assert.Equal(t, 3, stats[0].Dead)
+		assert.NotEmpty(t, stats[0].OldestDueAt)
+		assert.Empty(t, stats[1].OldestDueAt)
+	})
+
+	t.Run("customer job", func(t *testing.T) {
+		result := []byte(`{"statement_id":"s","number":7}`)
+		mock.ExpectQuery(`FROM queue_jobs WHERE id = \$1 AND customer_id = \$2 AND kind = ANY\(\$3\)`).
+			WithArgs(jobID, customerID, customerJobKinds).
+			WillReturnRows(pgxmock.NewRows(queueJobRowColumns).
+				AddRow(jobID, JobStatementRender, &customerID, []byte(`{"from":"2025-04-01","to":"2025-04-30"}`), jobqueue.StatusSucceeded, 1, 3, now,
+					"", result, now, now, &now))
+		w := send("GET", "/customers/"+customerID.String()+"/jobs/"+jobID.String())
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var job QueueJob
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		assert.JSONEq(t, string(result), string(job.Result))
+		assert.Nil(t, job.Payload, "payloads are for operators")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"ledger-service/cron"
+	"ledger-service/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// jobLockClass namespaces scheduled job advisory locks, which are keyed by
+// (jobLockClass, hashtext(name)) so they never collide with single-key locks
+// such as outboxLockKey
+const jobLockClass = 0x6a6f62
+
+var scheduler *cron.Scheduler
+
+// InitScheduler sets the scheduler whose jobs the admin API reports on
+func InitScheduler(s *cron.Scheduler) {
+	scheduler = s
+}
+
+// JobLocker runs each scheduled job inside a transaction holding an advisory
+// lock on the job's name, so only one instance runs it at a time, and
+// records the outcome in scheduled_jobs before releasing the lock. It
+// satisfies cron.Locker. The zero value gives up at once when another
+// instance holds the lock and reports no metrics.
+type JobLocker struct {
+	// Timeout is how long to wait for another instance to release a lock
+	Timeout time.Duration
+
+	waits    *metrics.Histogram
+	attempts *metrics.Counter
+}
+
+// jobLockPoll is how often a held lock is tried again within the timeout
+const jobLockPoll = 250 * time.Millisecond
+
+// NewJobLocker creates a locker waiting up to timeout for each lock and
+// reporting how long that took, and how it ended, to r
+func NewJobLocker(timeout time.Duration, r *metrics.Registry) JobLocker {
+	return JobLocker{
+		Timeout: timeout,
+		waits: r.NewHistogram("ledger_job_lock_wait_seconds",
+			"Time spent acquiring job advisory locks, whether or not they were acquired",
+			[]float64{0.01, 0.1, 0.5, 1, 5, 10, 30, 60}, "job"),
+		attempts: r.NewCounter("ledger_job_lock_attempts_total",
+			"Job advisory lock attempts by outcome: acquired, busy or error", "job", "outcome"),
+	}
+}
+
+// Lock takes the advisory lock on job for the lifetime of tx, so any
+// maintenance work done in tx runs on one instance at a time. It reports
+// false, without an error, when another instance still holds the lock after
+// l.Timeout.
+func (l JobLocker) Lock(ctx context.Context, tx pgx.Tx, job string) (bool, error) {
+	start := time.Now()
+	locked, err := l.tryLock(ctx, tx, job, start.Add(l.Timeout))
+	outcome := "acquired"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case !locked:
+		outcome = "busy"
+	}
+	if l.attempts != nil {
+		l.waits.Observe(time.Since(start).Seconds(), job)
+		l.attempts.Inc(job, outcome)
+	}
+	return locked, err
+}
+
+func (l JobLocker) tryLock(ctx context.Context, tx pgx.Tx, job string, deadline time.Time) (bool, error) {
+	for {
+		var locked bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1, hashtext($2))", jobLockClass, job).Scan(&locked); err != nil {
+			return false, err
+		}
+		wait := time.Until(deadline)
+		if locked || wait <= 0 {
+			return locked, nil
+		}
+		if wait > jobLockPoll {
+			wait = jobLockPoll
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// TryLock takes job's lock, waiting up to l.Timeout for it
+func (l JobLocker) TryLock(ctx context.Context, job string) (func(context.Context, cron.Result) error, bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	locked, err := l.Lock(ctx, tx, job)
+	if err != nil || !locked {
+		tx.Rollback(ctx)
+		return nil, false, err
+	}
+
+	return func(ctx context.Context, r cron.Result) error {
+		defer tx.Rollback(ctx)
+		var runErr *string
+		if r.Err != nil {
+			msg := r.Err.Error()
+			runErr = &msg
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO scheduled_jobs (name, last_status, last_started_at, last_finished_at, last_processed, last_error, last_success_at)
+			VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $2 = 'succeeded' THEN $4::timestamptz END)
+			ON CONFLICT (name) DO UPDATE SET last_status = EXCLUDED.last_status, last_started_at = EXCLUDED.last_started_at,
+				last_finished_at = EXCLUDED.last_finished_at, last_processed = EXCLUDED.last_processed, last_error = EXCLUDED.last_error,
+				last_success_at = COALESCE(EXCLUDED.last_success_at, scheduled_jobs.last_success_at)`,
+			r.Job, r.Status, r.StartedAt, r.FinishedAt, r.Processed, runErr); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}, true, nil
+}
+
+// JobRun is the outcome of a scheduled job's last run on any instance
+// @Description Last run of a scheduled job
+type JobRun struct {
+	Status     string `json:"status" example:"succeeded" enums:"succeeded,failed"`
+	StartedAt  string `json:"started_at" example:"2025-04-08T03:00:00Z" format:"date-time"`
+	FinishedAt string `json:"finished_at" example:"2025-04-08T03:00:04Z" format:"date-time"`
+	DurationMS int64  `json:"duration_ms" example:"4120"`
+	// Processed is how many items the run handled
+	Processed int    `json:"processed" example:"12"`
+	Error     string `json:"error,omitempty" example:"connection reset by peer"`
+}
+
+// ScheduledJob is a background job and how its runs have gone
+// @Description Background job with its schedule and last run
+type ScheduledJob struct {
+	Name     string `json:"name" example:"dormancy"`
+	Schedule string `json:"schedule" example:"0 3 * * *"`
+	// NextRunAt is when this instance next tries the job, before jitter
+	NextRunAt string `json:"next_run_at,omitempty" example:"2025-04-09T03:00:00Z" format:"date-time"`
+	// Running reports whether this instance is running the job now
+	Running       bool    `json:"running" example:"false"`
+	LastRun       *JobRun `json:"last_run,omitempty"`
+	LastSuccessAt string  `json:"last_success_at,omitempty" example:"2025-04-08T03:00:04Z" format:"date-time"`
+}
+
+// @Summary List scheduled jobs
+// @Description List the background jobs run on cron schedules, with each job's schedule, next run on this instance and last run on any instance. A run is skipped while another instance holds the job's lock, so the last run may come from a different instance.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} ScheduledJob "Jobs"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/jobs [get]
+func ListScheduledJobs(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT name, last_status, last_started_at, last_finished_at, last_processed, last_error, last_success_at FROM scheduled_jobs")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch jobs"})
+		return
+	}
+	defer rows.Close()
+
+	type lastRun struct {
+		run         JobRun
+		lastSuccess *time.Time
+	}
+	runs := map[string]lastRun{}
+	for rows.Next() {
+		var name string
+		var r lastRun
+		var started, finished time.Time
+		var runErr *string
+		if err := rows.Scan(&name, &r.run.Status, &started, &finished, &r.run.Processed, &runErr, &r.lastSuccess); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan job"})
+			return
+		}
+		r.run.StartedAt = started.UTC().Format(time.RFC3339)
+		r.run.FinishedAt = finished.UTC().Format(time.RFC3339)
+		r.run.DurationMS = finished.Sub(started).Milliseconds()
+		if runErr != nil {
+			r.run.Error = *runErr
+		}
+		runs[name] = r
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch jobs"})
+		return
+	}
+
+	jobs := []ScheduledJob{}
+	if scheduler != nil {
+		for _, st := range scheduler.Status() {
+			job := ScheduledJob{Name: st.Name, Schedule: st.Spec, Running: st.Running}
+			if !st.NextRunAt.IsZero() {
+				job.NextRunAt = st.NextRunAt.UTC().Format(time.RFC3339)
+			}
+			if r, ok := runs[st.Name]; ok {
+				run := r.run
+				job.LastRun = &run
+				if r.lastSuccess != nil {
+					job.LastSuccessAt = r.lastSuccess.UTC().Format(time.RFC3339)
+				}
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
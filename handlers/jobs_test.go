@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/cron"
+	"ledger-service/metrics"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLocker(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	ctx := context.Background()
+	started := time.Date(2025, 4, 8, 3, 0, 0, 0, time.UTC)
+	finished := started.Add(4 * time.Second)
+
+	t.Run("records the run before releasing the lock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).
+			WithArgs(jobLockClass, "dormancy").
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(true))
+		runErr := "database is down"
+		mock.ExpectExec(`INSERT INTO scheduled_jobs .* ON CONFLICT \(name\) DO UPDATE`).
+			WithArgs("dormancy", cron.StatusFailed, started, finished, 3, &runErr).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		unlock, ok, err := JobLocker{}.TryLock(ctx, "dormancy")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.NoError(t, unlock(ctx, cron.Result{
+			Job: "dormancy", Status: cron.StatusFailed, StartedAt: started, FinishedAt: finished,
+			Processed: 3, Err: errors.New("database is down"),
+		}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("held by another instance", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+			WithArgs(jobLockClass, "dormancy").
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectRollback()
+
+		_, ok, err := JobLocker{}.TryLock(ctx, "dormancy")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("waits for the lock within the timeout", func(t *testing.T) {
+		r := metrics.NewRegistry()
+		locker := NewJobLocker(time.Second, r)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+			WithArgs(jobLockClass, "stripe-reconcile").
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+			WithArgs(jobLockClass, "stripe-reconcile").
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectRollback()
+
+		tx, err := db.Begin(ctx)
+		require.NoError(t, err)
+		ok, err := locker.Lock(ctx, tx, "stripe-reconcile")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		tx.Rollback(ctx)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		var out strings.Builder
+		r.Write(&out)
+		assert.Contains(t, out.String(), `ledger_job_lock_attempts_total{job="stripe-reconcile",outcome="acquired"} 1`)
+		assert.Contains(t, out.String(), `ledger_job_lock_wait_seconds_count{job="stripe-reconcile"} 1`)
+	})
+
+	t.Run("gives up after the timeout", func(t *testing.T) {
+		r := metrics.NewRegistry()
+		locker := NewJobLocker(100*time.Millisecond, r)
+		mock.ExpectBegin()
+		for i := 0; i < 2; i++ {
+			mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+				WithArgs(jobLockClass, "dormancy").
+				WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(false))
+		}
+		mock.ExpectRollback()
+
+		_, ok, err := locker.TryLock(ctx, "dormancy")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		var out strings.Builder
+		r.Write(&out)
+		assert.Contains(t, out.String(), `ledger_job_lock_attempts_total{job="dormancy",outcome="busy"} 1`)
+	})
+}
+
+func TestListScheduledJobs(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	s := cron.NewScheduler(JobLocker{}, 0, metrics.NewRegistry())
+	noop := func(context.Context, time.Time) (int, error) { return 0, nil }
+	require.NoError(t, s.Register(cron.Job{Name: "dormancy", Spec: "0 3 * * *", Schedule: cron.Every(time.Hour), Run: noop}))
+	require.NoError(t, s.Register(cron.Job{Name: "standing-orders", Spec: "@every 300s", Schedule: cron.Every(time.Hour), Run: noop}))
+	InitScheduler(s)
+	defer InitScheduler(nil)
+
+	router.GET("/admin/jobs", ListScheduledJobs)
+
+	started := time.Date(2025, 4, 8, 3, 0, 0, 0, time.UTC)
+	finished := started.Add(4120 * time.Millisecond)
+	mock.ExpectQuery(`SELECT name, last_status, last_started_at, last_finished_at, last_processed, last_error, last_success_at FROM scheduled_jobs`).
+		WillReturnRows(pgxmock.NewRows([]string{"name", "last_status", "last_started_at", "last_finished_at", "last_processed", "last_error", "last_success_at"}).
+			AddRow("dormancy", "succeeded", started, finished, 12, (*string)(nil), &finished))
+
+	req := httptest.NewRequest("GET", "/admin/jobs", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var jobs []ScheduledJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &jobs))
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "dormancy", jobs[0].Name)
+	assert.Equal(t, "0 3 * * *", jobs[0].Schedule)
+	require.NotNil(t, jobs[0].LastRun)
+	assert.Equal(t, JobRun{Status: "succeeded", StartedAt: "2025-04-08T03:00:00Z", FinishedAt: "2025-04-08T03:00:04Z", DurationMS: 4120, Processed: 12}, *jobs[0].LastRun)
+	assert.Equal(t, "2025-04-08T03:00:04Z", jobs[0].LastSuccessAt)
+	assert.Equal(t, "standing-orders", jobs[1].Name)
+	assert.Nil(t, jobs[1].LastRun, "never run")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
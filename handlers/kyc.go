@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// KYCLimits caps activity for customers whose identity is not yet verified.
+// A zero value disables the corresponding limit.
+type KYCLimits struct {
+	MaxTransaction float64
+	DailyLimit     float64
+}
+
+// KYCDocument represents an identity document reference submitted for verification
+// @Description Identity document reference
+type KYCDocument struct {
+	DocumentID   uuid.UUID `json:"document_id" format:"uuid"`
+	DocumentType string    `json:"document_type" binding:"required,oneof=passport drivers_license national_id proof_of_address" example:"passport" enums:"passport,drivers_license,national_id,proof_of_address"`
+	Reference    string    `json:"reference" binding:"required,max=255" example:"P1234567"`
+	CreatedAt    string    `json:"created_at,omitempty" format:"date-time"`
+}
+
+// KYCProfile represents a customer's verification details
+// @Description Customer KYC details
+type KYCProfile struct {
+	CustomerID         uuid.UUID     `json:"customer_id" format:"uuid"`
+	DateOfBirth        string        `json:"date_of_birth,omitempty" example:"1990-01-31" format:"date"`
+	VerificationStatus string        `json:"verification_status" example:"pending" enums:"unverified,pending,verified,rejected"`
+	VerificationNote   *string       `json:"verification_note,omitempty"`
+	VerifiedBy         *string       `json:"verified_by,omitempty"`
+	Documents          []KYCDocument `json:"documents"`
+}
+
+// VerificationUpdateRequest represents an admin decision on a customer's verification
+type VerificationUpdateRequest struct {
+	Status string `json:"status" binding:"required,oneof=unverified pending verified rejected" example:"verified" enums:"unverified,pending,verified,rejected"`
+	Note   string `json:"note" example:"Passport checked"`
+}
+
+var (
+	kycLimits KYCLimits
+)
+
+// InitKYCLimits sets the limits enforced for unverified customers
+func InitKYCLimits(limits KYCLimits) {
+	kycLimits = limits
+}
+
+// parseDateOfBirth validates an optional YYYY-MM-DD date of birth
+func parseDateOfBirth(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	dob, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("date_of_birth must be in YYYY-MM-DD format")
+	}
+	if !dob.Before(time.Now()) {
+		return nil, fmt.Errorf("date_of_birth must be in the past")
+	}
+	return &dob, nil
+}
+
+// checkKYCLimits returns a non-empty violation message when an unverified
+// customer's transaction would exceed the configured limits
+func checkKYCLimits(ctx context.Context, tx pgx.Tx, transaction Transaction) (string, error) {
+	if kycLimits.MaxTransaction <= 0 && kycLimits.DailyLimit <= 0 {
+		return "", nil
+	}
+
+	var status string
+	err := tx.QueryRow(ctx,
+		"SELECT verification_status FROM customers WHERE id = $1",
+		transaction.CustomerID).Scan(&status)
+	if err != nil {
+		return "", err
+	}
+	if status == "verified" {
+		return "", nil
+	}
+
+	if kycLimits.MaxTransaction > 0 && transaction.Amount > kycLimits.MaxTransaction {
+		return fmt.Sprintf("Transaction exceeds the %.2f limit for unverified customers", kycLimits.MaxTransaction), nil
+	}
+	if kycLimits.DailyLimit > 0 {
+		var today float64
+		err := tx.QueryRow(ctx,
+			"SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE customer_id = $1 AND (type = 'transfer_out' OR type IN (SELECT code FROM transaction_types WHERE postable)) AND status = 'posted' AND created_at >= "+customerDayStart,
+			transaction.CustomerID).Scan(&today)
+		if err != nil {
+			return "", err
+		}
+		if today+transaction.Amount > kycLimits.DailyLimit {
+			return fmt.Sprintf("Transaction exceeds the %.2f daily limit for unverified customers", kycLimits.DailyLimit), nil
+		}
+	}
+	return "", nil
+}
+
+// @Summary Get KYC details
+// @Description Get the verification status and submitted documents for a customer
+// @Tags kyc
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {object} KYCProfile "KYC details"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/kyc [get]
+func GetKYCProfile(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	profile := KYCProfile{CustomerID: customerID, Documents: []KYCDocument{}}
+	var dob *string
+	err = db.QueryRow(c.Request.Context(),
+		"SELECT date_of_birth, verification_status, verification_note, verified_by FROM customers WHERE id = $1",
+		customerID).Scan(&dob, &profile.VerificationStatus, &profile.VerificationNote, &profile.VerifiedBy)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get KYC details"})
+		}
+		return
+	}
+	if dob != nil {
+		profile.DateOfBirth = *dob
+	}
+	if err := piiKeys.DecryptAll(&profile.DateOfBirth); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to decrypt KYC details"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT id, document_type, reference, created_at FROM customer_documents WHERE customer_id = $1 ORDER BY created_at",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch documents"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc KYCDocument
+		var createdAt time.Time
+		if err := rows.Scan(&doc.DocumentID, &doc.DocumentType, &doc.Reference, &createdAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan document"})
+			return
+		}
+		if err := piiKeys.DecryptAll(&doc.Reference); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to decrypt document"})
+			return
+		}
+		doc.CreatedAt = createdAt.Format(time.RFC3339)
+		profile.Documents = append(profile.Documents, doc)
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary Submit a KYC document
+// @Description Attach an identity document reference to a customer. Unverified customers move to pending review.
+// @Tags kyc
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param document body KYCDocument true "Document reference"
+// @Success 201 {object} KYCDocument "Document submitted"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/kyc/documents [post]
+func SubmitKYCDocument(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var doc KYCDocument
+	if err := bindJSON(c, &doc); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: document_type and reference are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE customers SET verification_status = 'pending' WHERE id = $1 AND verification_status = 'unverified'",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update verification status"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err := tx.QueryRow(ctx,
+			"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+			customerID).Scan(&exists); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+			return
+		}
+		if !exists {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+			return
+		}
+	}
+
+	reference, err := piiKeys.Encrypt(doc.Reference)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to encrypt document"})
+		return
+	}
+	doc.DocumentID = uuid.New()
+	_, err = tx.Exec(ctx,
+		"INSERT INTO customer_documents (id, customer_id, document_type, reference) VALUES ($1, $2, $3, $4)",
+		doc.DocumentID, customerID, doc.DocumentType, reference)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to store document"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	doc.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	c.JSON(http.StatusCreated, doc)
+}
+
+// @Summary Update verification status
+// @Description Set a customer's KYC verification status
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param verification body VerificationUpdateRequest true "Verification decision"
+// @Success 200 {object} KYCProfile "Verification status updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/verification [put]
+func UpdateVerificationStatus(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req VerificationUpdateRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: status must be one of unverified, pending, verified, rejected"})
+		return
+	}
+
+	actor := c.GetString(middleware.ActorKey)
+	tag, err := db.Exec(c.Request.Context(),
+		"UPDATE customers SET verification_status = $1, verification_note = $2, verified_by = $3, verification_updated_at = NOW() WHERE id = $4",
+		req.Status, req.Note, actor, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update verification status"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, KYCProfile{
+		CustomerID:         customerID,
+		VerificationStatus: req.Status,
+		VerificationNote:   &req.Note,
+		VerifiedBy:         &actor,
+		Documents:          []KYCDocument{},
+	})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/events"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransactionKYCLimits(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitKYCLimits(KYCLimits{MaxTransaction: 500, DailyLimit: 1000})
+	defer InitKYCLimits(KYCLimits{})
+
+	router.POST("/transactions", CreateTransaction)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name       string
+		amount     float64
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "unverified over single transaction limit",
+			amount:     600,
+			wantStatus: http.StatusForbidden,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectQuery(`SELECT verification_status FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"verification_status"}).AddRow("unverified"))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "unverified over daily limit",
+			amount:     300,
+			wantStatus: http.StatusForbidden,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectQuery(`SELECT verification_status FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"verification_status"}).AddRow("pending"))
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM transactions`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(float64(800)))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "verified customer is not limited",
+			amount:     600,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				mock.ExpectQuery(`SELECT verification_status FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"verification_status"}).AddRow("verified"))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(1600), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions`).
+					WithArgs(pgxmock.AnyArg(), customerID, "credit", float64(600), "posted").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "credit",
+				"amount":      tt.amount,
+			})
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreateCustomerDateOfBirth(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers", CreateCustomer)
+
+	for _, dob := range []string{"31-01-1990", "2999-01-01"} {
+		jsonBytes, _ := json.Marshal(map[string]interface{}{"name": "John Doe", "date_of_birth": dob})
+		req := httptest.NewRequest("POST", "/customers", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, dob)
+	}
+}
+
+func TestSubmitKYCDocument(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/kyc/documents", SubmitKYCDocument)
+
+	customerID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE customers SET verification_status = 'pending' WHERE id = \$1 AND verification_status = 'unverified'`).
+		WithArgs(customerID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO customer_documents`).
+		WithArgs(pgxmock.AnyArg(), customerID, "passport", "P1234567").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	jsonBytes, _ := json.Marshal(map[string]interface{}{"document_type": "passport", "reference": "P1234567"})
+	req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/kyc/documents", bytes.NewBuffer(jsonBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	jsonBytes, _ = json.Marshal(map[string]interface{}{"document_type": "selfie", "reference": "x"})
+	req = httptest.NewRequest("POST", "/customers/"+customerID.String()+"/kyc/documents", bytes.NewBuffer(jsonBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateVerificationStatus(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.PUT("/admin/customers/:customer_id/verification", UpdateVerificationStatus)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "verify customer",
+			payload:    map[string]interface{}{"status": "verified", "note": "Passport checked"},
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectExec(`UPDATE customers SET verification_status = \$1`).
+					WithArgs("verified", "Passport checked", pgxmock.AnyArg(), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			},
+		},
+		{
+			name:       "invalid status",
+			payload:    map[string]interface{}{"status": "approved"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "unknown customer",
+			payload:    map[string]interface{}{"status": "rejected"},
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectExec(`UPDATE customers SET verification_status = \$1`).
+					WithArgs("rejected", "", pgxmock.AnyArg(), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("PUT", "/admin/customers/"+customerID.String()+"/verification", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
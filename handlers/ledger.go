@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ledger-service/events"
+	"ledger-service/fraud"
+	"ledger-service/ledger"
+	"ledger-service/policy"
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	postingHooks ledger.Hooks
+)
+
+// InitPostingHooks sets the deployment's hooks run around every posting
+func InitPostingHooks(h ledger.Hooks) {
+	postingHooks = h
+}
+
+// postings returns the ledger service over the current store and
+// transaction types. Postings in other currencies than the account's are
+// converted when an FX provider is configured.
+func postings() *ledger.Service {
+	s := ledger.New(ledgerStore, transactionTypes, ledgerRules{}).WithHooks(postingHooks)
+	if fxProvider != nil {
+		s.WithConverter(convertPosting)
+	}
+	return s
+}
+
+// convertPosting converts a posting amount at the provider's current rate,
+// rounded like moves between wallets
+func convertPosting(ctx context.Context, amount float64, from, to string) (ledger.Conversion, error) {
+	rate, err := lookupRate(ctx, from, to)
+	if err != nil {
+		return ledger.Conversion{}, err
+	}
+	converted, rounding := roundingPolicy.Convert(amount, rate.Value, to)
+	return ledger.Conversion{Amount: converted, Rate: rate.Value, Provider: rate.Provider, Rounding: string(rounding)}, nil
+}
+
+// postingChecks is what ledgerRules carries from screening a posting to
+// recording it
+type postingChecks struct {
+	decision fraud.Decision
+	// phone and smsOptIn are read while the account is locked, for the SMS
+	// alert sent once the posting commits
+	phone    *string
+	smsOptIn bool
+}
+
+// overdraftActor is the audit log actor for postings and transfers that take
+// an account allowed to go negative below zero
+const overdraftActor = "system"
+
+// respondBalanceError maps a posting ledger.Apply refused to a response
+func respondBalanceError(c *gin.Context, err error) {
+	if errors.Is(err, ledger.ErrOverpayment) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payment exceeds the amount owed"})
+		return
+	}
+	respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+}
+
+// ledgerRules applies debit and KYC limits, account policies and fraud rules
+// to postings, books the general ledger side and enqueues events. They are
+// kept in Postgres, so with the in-memory store postings go through
+// unchecked.
+type ledgerRules struct{}
+
+func (ledgerRules) Limit(ctx context.Context, tx store.Tx, p ledger.Posting, _ store.Customer) error {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return nil
+	}
+	if err := checkValueDate(ctx, pg, p); err != nil {
+		return err
+	}
+	if p.Direction == txtype.Debit {
+		if err := checkDormantDebit(ctx, pg, p.CustomerID); err != nil {
+			return err
+		}
+		violation, err := checkDebitLimits(ctx, pg, p.CustomerID, p.Amount)
+		if err != nil {
+			return err
+		}
+		if violation != "" {
+			return &ledger.ViolationError{Message: violation}
+		}
+	}
+	violation, err := checkKYCLimits(ctx, pg, Transaction{CustomerID: p.CustomerID, Type: p.Type, Amount: p.Amount})
+	if err != nil {
+		return err
+	}
+	if violation != "" {
+		return &ledger.ViolationError{Message: violation}
+	}
+	return nil
+}
+
+func (ledgerRules) Screen(ctx context.Context, tx store.Tx, p ledger.Posting, account store.Customer) (ledger.Screening, error) {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return ledger.Screening{}, nil
+	}
+	outcome, err := checkAccountPolicy(ctx, pg, p, account)
+	if err != nil {
+		return ledger.Screening{}, err
+	}
+
+	// Run fraud rules before touching the balance. Dry runs skip them so
+	// clients cannot probe the rules.
+	var decision fraud.Decision
+	if !p.DryRun {
+		decision, err = evaluateFraud(ctx, pg, p, account.Timezone)
+		if err != nil {
+			return ledger.Screening{}, err
+		}
+	}
+	screening := ledger.Screening{Status: ledger.StatusPosted, Detail: &postingChecks{decision: decision}}
+	switch decision.Action {
+	case fraud.ActionHold:
+		screening.Status = ledger.StatusHeld
+	case fraud.ActionReject:
+		screening.Status = ledger.StatusRejected
+	}
+	if screening.Status == ledger.StatusPosted && outcome == policy.RequireApproval {
+		screening.Status = ledger.StatusPendingApproval
+	}
+	return screening, nil
+}
+
+func (ledgerRules) Posted(ctx context.Context, tx store.Tx, p ledger.Posting, r ledger.Result) error {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return nil
+	}
+	checks, _ := r.Screening.Detail.(*postingChecks)
+	if checks == nil {
+		checks = &postingChecks{}
+	}
+
+	if r.Overdrawn {
+		if err := recordAudit(ctx, pg, overdraftActor, "balance.overdrawn", "transaction", r.TransactionID, &p.CustomerID, map[string]interface{}{
+			"type":             p.Type,
+			"amount":           p.Amount,
+			"previous_balance": r.PreviousBalance,
+			"new_balance":      r.Balance,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Book the general ledger side of fees, interest and the like, and what
+	// converting the posting rounded away
+	if r.Status == ledger.StatusPosted {
+		if err := postCounterparty(ctx, pg, r.TransactionID, p.Type, p.Amount); err != nil {
+			return err
+		}
+		if o := p.Original; o != nil {
+			if err := postRoundingDifference(ctx, pg, roundingSourceTransaction, r.TransactionID, &r.TransactionID, p.Currency, o.Amount*o.Rate, p.Amount); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Record the fraud decision for review
+	if len(checks.decision.Matches) > 0 {
+		if err := recordFraudDecision(ctx, pg, r.TransactionID, p.CustomerID, checks.decision); err != nil {
+			return err
+		}
+	}
+
+	if err := enqueueEvent(ctx, pg, transactionEventType(r.Status), &p.CustomerID, TransactionEventData{
+		TransactionID: r.TransactionID,
+		Type:          p.Type,
+		Amount:        p.Amount,
+		Status:        r.Status,
+	}); err != nil {
+		return err
+	}
+
+	// Load SMS contact details while the row is still locked
+	if notifier != nil {
+		var err error
+		checks.phone, checks.smsOptIn, err = loadSMSContact(ctx, pg, p.CustomerID)
+		return err
+	}
+	return nil
+}
+
+// ScreenPayer holds the payer of a transfer, split or reservation to the
+// dormancy, debit limit, KYC and account policy rules of a debit posting,
+// before anything is written. Payers whose policy requires approval, such as escrow
+// accounts, are held for it.
+func (ledgerRules) ScreenPayer(ctx context.Context, tx store.Tx, p ledger.Posting, account store.Customer) (ledger.Screening, error) {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return ledger.Screening{}, nil
+	}
+	if err := checkDormantDebit(ctx, pg, p.CustomerID); err != nil {
+		return ledger.Screening{}, err
+	}
+	violation, err := checkDebitLimits(ctx, pg, p.CustomerID, p.Amount)
+	if err != nil {
+		return ledger.Screening{}, err
+	}
+	if violation != "" {
+		return ledger.Screening{}, &ledger.ViolationError{Message: violation}
+	}
+	violation, err = checkKYCLimits(ctx, pg, Transaction{CustomerID: p.CustomerID, Type: p.Type, Amount: p.Amount})
+	if err != nil {
+		return ledger.Screening{}, err
+	}
+	if violation != "" {
+		return ledger.Screening{}, &ledger.ViolationError{Message: violation}
+	}
+	outcome, err := checkAccountPolicy(ctx, pg, p, account)
+	if err != nil {
+		return ledger.Screening{}, err
+	}
+	if outcome == policy.RequireApproval {
+		return ledger.Screening{Status: ledger.StatusPendingApproval}, nil
+	}
+	return ledger.Screening{}, nil
+}
+
+// checkAccountPolicy applies the account type's policy to a posting,
+// returning its refusal as *ledger.ViolationError
+func checkAccountPolicy(ctx context.Context, pg pgx.Tx, p ledger.Posting, account store.Customer) (policy.Outcome, error) {
+	outcome, err := accountPolicies.Check(ctx, policyUsage{q: pg}, policy.Posting{
+		CustomerID:  p.CustomerID,
+		AccountType: policy.AccountType(account.AccountType),
+		Type:        string(p.Direction),
+		Amount:      p.Amount,
+	})
+	var violation *policy.ViolationError
+	if errors.As(err, &violation) {
+		return outcome, &ledger.ViolationError{Message: violation.Message}
+	}
+	return outcome, err
+}
+
+func (ledgerRules) Transferred(ctx context.Context, tx store.Tx, t ledger.Transfer, r ledger.TransferResult) error {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return nil
+	}
+	if r.Overdrawn {
+		if err := recordAudit(ctx, pg, overdraftActor, "balance.overdrawn", "transfer", r.TransferID, &t.FromCustomerID, map[string]interface{}{
+			"to_customer_id":   t.ToCustomerID,
+			"amount":           t.Amount,
+			"previous_balance": r.FromPrevious,
+			"new_balance":      r.FromBalance,
+		}); err != nil {
+			return err
+		}
+	}
+	return enqueueEvent(ctx, pg, events.TransferCompleted, &t.FromCustomerID, TransferEventData{
+		TransferID:     r.TransferID,
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+		BatchID:        t.BatchID,
+	})
+}
+
+func (ledgerRules) SplitTransferred(ctx context.Context, tx store.Tx, s ledger.Split, r ledger.SplitResult) error {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return nil
+	}
+	if r.Overdrawn {
+		if err := recordAudit(ctx, pg, overdraftActor, "balance.overdrawn", "transfer_batch", r.BatchID, &s.FromCustomerID, map[string]interface{}{
+			"recipients":       len(r.Transfers),
+			"amount":           s.Amount,
+			"previous_balance": r.FromPrevious,
+			"new_balance":      r.FromBalance,
+		}); err != nil {
+			return err
+		}
+	}
+	// Each credit is a transfer of its own to the recipient
+	for _, leg := range r.Transfers {
+		if err := enqueueEvent(ctx, pg, events.TransferCompleted, &s.FromCustomerID, TransferEventData{
+			TransferID:     leg.TransferID,
+			FromCustomerID: s.FromCustomerID,
+			ToCustomerID:   leg.ToCustomerID,
+			Amount:         leg.Amount,
+			Reference:      leg.Reference,
+			BatchID:        &r.BatchID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ledgerRules) Reserved(ctx context.Context, tx store.Tx, r ledger.Reservation) error {
+	pg, ok := pgxTx(tx)
+	if !ok {
+		return nil
+	}
+	data := TransferEventData{
+		TransferID:     r.TransferID,
+		FromCustomerID: r.FromCustomerID,
+		ToCustomerID:   r.ToCustomerID,
+		Amount:         r.Amount,
+		Reference:      r.Reference,
+	}
+	switch r.Status {
+	case ledger.TransferSettled:
+		return enqueueEvent(ctx, pg, events.TransferCompleted, &r.FromCustomerID, data)
+	case ledger.TransferReleased:
+		return enqueueEvent(ctx, pg, events.TransferReleased, &r.FromCustomerID, data)
+	}
+	if r.Overdrawn {
+		if err := recordAudit(ctx, pg, overdraftActor, "balance.overdrawn", "transfer", r.TransferID, &r.FromCustomerID, map[string]interface{}{
+			"to_customer_id":   r.ToCustomerID,
+			"amount":           r.Amount,
+			"previous_balance": r.Previous,
+			"new_balance":      r.Balance,
+		}); err != nil {
+			return err
+		}
+	}
+	return enqueueEvent(ctx, pg, events.TransferReserved, &r.FromCustomerID, data)
+}
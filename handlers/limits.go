@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits cap a customer's debits. A field left out has no limit at this
+// level: a customer's own limits fall back to the defaults field by field,
+// and a limit missing from both does not apply.
+// @Description Debit limits; omitted fields are not limited at this level
+type Limits struct {
+	MaxSingleDebit  *float64 `json:"max_single_debit,omitempty" binding:"omitempty,gt=0" example:"1000"`
+	DailyDebitLimit *float64 `json:"daily_debit_limit,omitempty" binding:"omitempty,gt=0" example:"2500"`
+	// OverdraftLimit is how far below zero a deposit account may go
+	OverdraftLimit   *float64 `json:"overdraft_limit,omitempty" binding:"omitempty,gte=0" example:"500"`
+	MaxDebitsPerHour *int     `json:"max_debits_per_hour,omitempty" binding:"omitempty,gt=0" example:"10"`
+	MaxDebitsPerDay  *int     `json:"max_debits_per_day,omitempty" binding:"omitempty,gt=0" example:"50"`
+}
+
+// LimitDefaults are the limits for customers without their own
+// @Description Deployment-wide default debit limits
+type LimitDefaults struct {
+	Limits
+	UpdatedBy string `json:"updated_by,omitempty" example:"alice"`
+	UpdatedAt string `json:"updated_at,omitempty" example:"2025-04-08T10:00:00Z" format:"date-time"`
+}
+
+// CustomerLimits are a customer's own limits and the ones in force
+// @Description A customer's debit limits, the defaults and the limits in force
+type CustomerLimits struct {
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	Limits     Limits    `json:"limits"`
+	Defaults   Limits    `json:"defaults"`
+	// Effective is each of the customer's limits, or the default where it
+	// has none
+	Effective Limits `json:"effective"`
+	UpdatedBy string `json:"updated_by,omitempty" example:"alice"`
+	UpdatedAt string `json:"updated_at,omitempty" example:"2025-04-08T10:00:00Z" format:"date-time"`
+}
+
+const limitColumns = "max_single_debit, daily_debit_limit, overdraft_limit, max_debits_per_hour, max_debits_per_day"
+
+// or returns l with every limit it lacks taken from defaults
+func (l Limits) or(defaults Limits) Limits {
+	if l.MaxSingleDebit == nil {
+		l.MaxSingleDebit = defaults.MaxSingleDebit
+	}
+	if l.DailyDebitLimit == nil {
+		l.DailyDebitLimit = defaults.DailyDebitLimit
+	}
+	if l.OverdraftLimit == nil {
+		l.OverdraftLimit = defaults.OverdraftLimit
+	}
+	if l.MaxDebitsPerHour == nil {
+		l.MaxDebitsPerHour = defaults.MaxDebitsPerHour
+	}
+	if l.MaxDebitsPerDay == nil {
+		l.MaxDebitsPerDay = defaults.MaxDebitsPerDay
+	}
+	return l
+}
+
+// readLimits loads one row of limitColumns, followed by updated_by and
+// updated_at, leaving l empty when there is none
+func readLimits(ctx context.Context, q rowQuerier, sql string, args ...interface{}) (l Limits, updatedBy, updatedAt string, err error) {
+	var at time.Time
+	err = q.QueryRow(ctx, sql, args...).Scan(&l.MaxSingleDebit, &l.DailyDebitLimit, &l.OverdraftLimit, &l.MaxDebitsPerHour, &l.MaxDebitsPerDay, &updatedBy, &at)
+	if err == pgx.ErrNoRows {
+		return Limits{}, "", "", nil
+	}
+	if err != nil {
+		return Limits{}, "", "", err
+	}
+	return l, updatedBy, at.UTC().Format(time.RFC3339), nil
+}
+
+func readLimitDefaults(ctx context.Context, q rowQuerier) (LimitDefaults, error) {
+	var d LimitDefaults
+	var err error
+	d.Limits, d.UpdatedBy, d.UpdatedAt, err = readLimits(ctx, q, "SELECT "+limitColumns+", updated_by, updated_at FROM limit_defaults")
+	return d, err
+}
+
+func readCustomerLimits(ctx context.Context, q rowQuerier, customerID uuid.UUID) (CustomerLimits, error) {
+	resp := CustomerLimits{CustomerID: customerID}
+	defaults, err := readLimitDefaults(ctx, q)
+	if err != nil {
+		return resp, err
+	}
+	resp.Defaults = defaults.Limits
+	resp.Limits, resp.UpdatedBy, resp.UpdatedAt, err = readLimits(ctx, q,
+		"SELECT "+limitColumns+", updated_by, updated_at FROM customer_limits WHERE customer_id = $1", customerID)
+	resp.Effective = resp.Limits.or(resp.Defaults)
+	return resp, err
+}
+
+// checkDebitLimits returns a non-empty violation message when a debit would
+// break the customer's limits. The overdraft is applied by ledger.Apply.
+func checkDebitLimits(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, amount float64) (string, error) {
+	var l Limits
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(l.max_single_debit, d.max_single_debit), COALESCE(l.daily_debit_limit, d.daily_debit_limit),
+			COALESCE(l.max_debits_per_hour, d.max_debits_per_hour), COALESCE(l.max_debits_per_day, d.max_debits_per_day)
+		FROM (SELECT $1::uuid AS customer_id) c
+		LEFT JOIN customer_limits l ON l.customer_id = c.customer_id
+		LEFT JOIN limit_defaults d ON TRUE`,
+		customerID).Scan(&l.MaxSingleDebit, &l.DailyDebitLimit, &l.MaxDebitsPerHour, &l.MaxDebitsPerDay)
+	if err != nil {
+		return "", err
+	}
+
+	if l.MaxSingleDebit != nil && amount > *l.MaxSingleDebit {
+		return fmt.Sprintf("Debit exceeds the %.2f single debit limit", *l.MaxSingleDebit), nil
+	}
+	if l.DailyDebitLimit == nil && l.MaxDebitsPerHour == nil && l.MaxDebitsPerDay == nil {
+		return "", nil
+	}
+
+	// Debits are counted over the customer's day and the last hour
+	var today float64
+	var todayCount, hourCount int
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount) FILTER (WHERE created_at >= `+customerDayStart+`), 0),
+			COUNT(*) FILTER (WHERE created_at >= `+customerDayStart+`),
+			COUNT(*) FILTER (WHERE created_at >= NOW() - INTERVAL '1 hour')
+		FROM transactions WHERE customer_id = $1 AND `+debitTypes+` AND status = 'posted'
+			AND created_at >= LEAST(`+customerDayStart+`, NOW() - INTERVAL '1 hour')`,
+		customerID).Scan(&today, &todayCount, &hourCount)
+	if err != nil {
+		return "", err
+	}
+	if l.DailyDebitLimit != nil && today+amount > *l.DailyDebitLimit {
+		return fmt.Sprintf("Debit exceeds the %.2f daily debit limit", *l.DailyDebitLimit), nil
+	}
+	if l.MaxDebitsPerHour != nil && hourCount+1 > *l.MaxDebitsPerHour {
+		return fmt.Sprintf("Debit exceeds the limit of %d debits per hour", *l.MaxDebitsPerHour), nil
+	}
+	if l.MaxDebitsPerDay != nil && todayCount+1 > *l.MaxDebitsPerDay {
+		return fmt.Sprintf("Debit exceeds the limit of %d debits per day", *l.MaxDebitsPerDay), nil
+	}
+	return "", nil
+}
+
+// @Summary Get default limits
+// @Description Get the debit limits applied to customers without their own. An omitted field is not limited by default.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} LimitDefaults "Default limits"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/limits [get]
+func GetLimitDefaults(c *gin.Context) {
+	defaults, err := readLimitDefaults(c.Request.Context(), db)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get limits"})
+		return
+	}
+	c.JSON(http.StatusOK, defaults)
+}
+
+// @Summary Set default limits
+// @Description Replace the debit limits applied to customers without their own; an omitted field removes that default. Single, daily and velocity limits apply to debits posted through POST /transactions and to payments out by transfer, and the overdraft lets deposit accounts go that far below zero on any debit. Lowering an overdraft leaves balances already past it alone but refuses further debits. The change is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator making the change"
+// @Param limits body Limits true "Default limits"
+// @Success 200 {object} LimitDefaults "Default limits updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/limits [put]
+func SetLimitDefaults(c *gin.Context) {
+	var req Limits
+	if !bindRequest(c, &req, "Invalid input: limits must be positive and the overdraft may not be negative") {
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO limit_defaults (id, `+limitColumns+`, updated_by, updated_at) VALUES (TRUE, $1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (id) DO UPDATE SET max_single_debit = EXCLUDED.max_single_debit, daily_debit_limit = EXCLUDED.daily_debit_limit,
+			overdraft_limit = EXCLUDED.overdraft_limit, max_debits_per_hour = EXCLUDED.max_debits_per_hour,
+			max_debits_per_day = EXCLUDED.max_debits_per_day, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		req.MaxSingleDebit, req.DailyDebitLimit, req.OverdraftLimit, req.MaxDebitsPerHour, req.MaxDebitsPerDay, actor); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update limits"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "limits.defaults_updated", "limits", uuid.New(), nil, req); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LimitDefaults{Limits: req, UpdatedBy: actor, UpdatedAt: time.Now().UTC().Format(time.RFC3339)})
+}
+
+// @Summary Get a customer's limits
+// @Description Get a customer's own debit limits, the defaults, and the limits in force: each of the customer's limits, or the default where it has none.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {object} CustomerLimits "Limits"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/limits [get]
+func GetCustomerLimits(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	var exists bool
+	if err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)", customerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	resp, err := readCustomerLimits(ctx, db, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get limits"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Set a customer's limits
+// @Description Replace a customer's own debit limits; an omitted field falls back to the default. Sending no limits returns the customer to the defaults. The change is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator making the change"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param limits body Limits true "Customer limits"
+// @Success 200 {object} CustomerLimits "Limits updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/limits [put]
+func SetCustomerLimits(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req Limits
+	if !bindRequest(c, &req, "Invalid input: limits must be positive and the overdraft may not be negative") {
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the customer so the limits change between postings
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, "SELECT id FROM customers WHERE id = $1 FOR UPDATE", customerID).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get customer"})
+		}
+		return
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO customer_limits (customer_id, `+limitColumns+`, updated_by, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (customer_id) DO UPDATE SET max_single_debit = EXCLUDED.max_single_debit, daily_debit_limit = EXCLUDED.daily_debit_limit,
+			overdraft_limit = EXCLUDED.overdraft_limit, max_debits_per_hour = EXCLUDED.max_debits_per_hour,
+			max_debits_per_day = EXCLUDED.max_debits_per_day, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		customerID, req.MaxSingleDebit, req.DailyDebitLimit, req.OverdraftLimit, req.MaxDebitsPerHour, req.MaxDebitsPerDay, actor); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update limits"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "customer.limits_updated", "customer", customerID, &customerID, req); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	resp, err := readCustomerLimits(ctx, tx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get limits"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
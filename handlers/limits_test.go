@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const debitLimitsQuery = `SELECT COALESCE\(l.max_single_debit, d.max_single_debit\)`
+
+// expectNoDebitLimits expects a debit posting to look up the customer's
+// limits and find none
+func expectNoDebitLimits(customerID uuid.UUID) {
+	expectDebitLimits(customerID, nil, nil, nil, nil)
+}
+
+func expectDebitLimits(customerID uuid.UUID, single, daily *float64, perHour, perDay *int) {
+	mock.ExpectQuery(debitLimitsQuery).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"max_single_debit", "daily_debit_limit", "max_debits_per_hour", "max_debits_per_day"}).
+			AddRow(single, daily, perHour, perDay))
+}
+
+func TestCreateTransactionDebitLimits(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/transactions", CreateTransaction)
+
+	customerID := uuid.New()
+	single, daily := 500.0, 1000.0
+	perHour := 3
+	expectDebitStats := func(today float64, todayCount, hourCount int) {
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\) FILTER`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"today", "today_count", "hour_count"}).AddRow(today, todayCount, hourCount))
+	}
+	tests := []struct {
+		name       string
+		amount     float64
+		wantStatus int
+		wantError  string
+		setupMock  func()
+	}{
+		{
+			name:       "over the single debit limit",
+			amount:     600,
+			wantStatus: http.StatusForbidden,
+			wantError:  "Debit exceeds the 500.00 single debit limit",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectDebitLimits(customerID, &single, &daily, &perHour, nil)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "over the daily debit limit",
+			amount:     300,
+			wantStatus: http.StatusForbidden,
+			wantError:  "Debit exceeds the 1000.00 daily debit limit",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectDebitLimits(customerID, &single, &daily, &perHour, nil)
+				expectDebitStats(800, 2, 1)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "too many debits this hour",
+			amount:     50,
+			wantStatus: http.StatusForbidden,
+			wantError:  "Debit exceeds the limit of 3 debits per hour",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+				expectDebitLimits(customerID, &single, &daily, &perHour, nil)
+				expectDebitStats(150, 3, 3)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "within the overdraft",
+			amount:     300,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "account_type", "timezone", "allow_negative", "balance_type", "credit_limit", "currency", "overdraft_limit"}).
+						AddRow(float64(100), "checking", "UTC", false, "deposit", float64(0), "USD", float64(250)))
+				expectDebitLimits(customerID, &single, nil, nil, nil)
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(-200), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions`).
+					WithArgs(pgxmock.AnyArg(), customerID, "purchase", float64(300), "posted").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "system", "balance.overdrawn", "transaction", pgxmock.AnyArg(), &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(events.TransactionPosted)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "beyond the overdraft",
+			amount:     400,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Insufficient balance",
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "account_type", "timezone", "allow_negative", "balance_type", "credit_limit", "currency", "overdraft_limit"}).
+						AddRow(float64(100), "checking", "UTC", false, "deposit", float64(0), "USD", float64(250)))
+				expectNoDebitLimits(customerID)
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"customer_id": customerID,
+				"type":        "purchase",
+				"amount":      tt.amount,
+			})
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantError != "" {
+				var resp ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantError, resp.Error)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSetCustomerLimits(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.PUT("/admin/customers/:customer_id/limits", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "jane")
+	}, SetCustomerLimits)
+
+	customerID := uuid.New()
+	updatedAt := time.Date(2025, 4, 8, 10, 0, 0, 0, time.UTC)
+	limitRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"max_single_debit", "daily_debit_limit", "overdraft_limit", "max_debits_per_hour", "max_debits_per_day", "updated_by", "updated_at"})
+	}
+	single, overdraft, defaultSingle, defaultDaily := 200.0, 0.0, 1000.0, 2500.0
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+		check      func(t *testing.T, resp CustomerLimits)
+	}{
+		{
+			name:       "overrides the defaults and is audited",
+			payload:    map[string]interface{}{"max_single_debit": 200, "overdraft_limit": 0},
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(customerID))
+				mock.ExpectExec(`INSERT INTO customer_limits .* ON CONFLICT \(customer_id\) DO UPDATE`).
+					WithArgs(customerID, &single, (*float64)(nil), &overdraft, (*int)(nil), (*int)(nil), "jane").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "jane", "customer.limits_updated", "customer", customerID, &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectQuery(`SELECT max_single_debit, daily_debit_limit, overdraft_limit, max_debits_per_hour, max_debits_per_day, updated_by, updated_at FROM limit_defaults`).
+					WillReturnRows(limitRows().AddRow(&defaultSingle, &defaultDaily, (*float64)(nil), (*int)(nil), (*int)(nil), "alice", updatedAt))
+				mock.ExpectQuery(`SELECT .* FROM customer_limits WHERE customer_id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(limitRows().AddRow(&single, (*float64)(nil), &overdraft, (*int)(nil), (*int)(nil), "jane", updatedAt))
+				mock.ExpectCommit()
+			},
+			check: func(t *testing.T, resp CustomerLimits) {
+				require.NotNil(t, resp.Effective.MaxSingleDebit)
+				assert.Equal(t, 200.0, *resp.Effective.MaxSingleDebit, "customer's own limit")
+				require.NotNil(t, resp.Effective.DailyDebitLimit)
+				assert.Equal(t, 2500.0, *resp.Effective.DailyDebitLimit, "falls back to the default")
+				require.NotNil(t, resp.Effective.OverdraftLimit)
+				assert.Equal(t, 0.0, *resp.Effective.OverdraftLimit)
+				assert.Nil(t, resp.Effective.MaxDebitsPerDay)
+				assert.Equal(t, "jane", resp.UpdatedBy)
+			},
+		},
+		{
+			name:       "customer not found",
+			payload:    map[string]interface{}{"max_single_debit": 200},
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "negative overdraft",
+			payload:    map[string]interface{}{"overdraft_limit": -100},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "zero debit count",
+			payload:    map[string]interface{}{"max_debits_per_day": 0},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("PUT", "/admin/customers/"+customerID.String()+"/limits", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.check != nil {
+				var resp CustomerLimits
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				tt.check(t, resp)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSetLimitDefaults(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.PUT("/admin/limits", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "alice")
+	}, SetLimitDefaults)
+
+	overdraft, perHour := 100.0, 10
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO limit_defaults .* ON CONFLICT \(id\) DO UPDATE`).
+		WithArgs((*float64)(nil), (*float64)(nil), &overdraft, &perHour, (*int)(nil), "alice").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(pgxmock.AnyArg(), "alice", "limits.defaults_updated", "limits", pgxmock.AnyArg(), (*uuid.UUID)(nil), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest("PUT", "/admin/limits", bytes.NewBufferString(`{"overdraft_limit": 100, "max_debits_per_hour": 10}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp LimitDefaults
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.OverdraftLimit)
+	assert.Equal(t, 100.0, *resp.OverdraftLimit)
+	assert.Nil(t, resp.MaxSingleDebit)
+	assert.Equal(t, "alice", resp.UpdatedBy)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
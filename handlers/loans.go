@@ -0,0 +1,774 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/loan"
+	"ledger-service/middleware"
+	"ledger-service/schedule"
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Loan describes a loan and where its repayment stands
+// @Description Amortizing loan paid out to a customer's balance
+type Loan struct {
+	ID                        uuid.UUID `json:"loan_id" format:"uuid"`
+	CustomerID                uuid.UUID `json:"customer_id" format:"uuid"`
+	Principal                 float64   `json:"principal" example:"5000"`
+	Currency                  string    `json:"currency" example:"USD"`
+	AnnualRate                float64   `json:"annual_rate" example:"0.065"`
+	Installments              int       `json:"installments" example:"24"`
+	Frequency                 string    `json:"frequency" example:"monthly" enums:"daily,weekly,monthly"`
+	Status                    string    `json:"status" example:"active" enums:"active,repaid"`
+	OutstandingPrincipal      float64   `json:"outstanding_principal" example:"4215.3"`
+	AccruedInterest           float64   `json:"accrued_interest" example:"11.42"`
+	PayoffAmount              float64   `json:"payoff_amount" example:"4226.72"`
+	NextDueDate               string    `json:"next_due_date,omitempty" example:"2025-06-15" format:"date"`
+	NextPayment               float64   `json:"next_payment,omitempty" example:"222.73"`
+	DisbursementTransactionID uuid.UUID `json:"disbursement_transaction_id" format:"uuid"`
+	DisbursedOn               string    `json:"disbursed_on" example:"2025-05-15" format:"date"`
+	// Schedule is returned when the loan is disbursed
+	Schedule []LoanInstallment `json:"schedule,omitempty"`
+}
+
+// LoanInstallment is one scheduled repayment of a loan
+// @Description Scheduled loan repayment
+type LoanInstallment struct {
+	Number            int     `json:"number" example:"1"`
+	DueDate           string  `json:"due_date" example:"2025-06-15" format:"date"`
+	Payment           float64 `json:"payment" example:"222.73"`
+	Principal         float64 `json:"principal" example:"195.65"`
+	Interest          float64 `json:"interest" example:"27.08"`
+	Status            string  `json:"status" example:"due" enums:"due,paid,cancelled"`
+	FailedAttempts    int     `json:"failed_attempts" example:"0"`
+	LastFailureReason string  `json:"last_failure_reason,omitempty" example:"Insufficient balance"`
+}
+
+// LoanRequest represents the payload for disbursing a loan
+type LoanRequest struct {
+	CustomerID   uuid.UUID `json:"customer_id" binding:"required,uuid" format:"uuid"`
+	Principal    float64   `json:"principal" binding:"required,money" example:"5000" minimum:"0.01"`
+	AnnualRate   float64   `json:"annual_rate" example:"0.065" minimum:"0" maximum:"0.999999"`
+	Installments int       `json:"installments" binding:"required" example:"24" minimum:"1" maximum:"600"`
+	Frequency    string    `json:"frequency" binding:"required,oneof=daily weekly monthly" example:"monthly" enums:"daily,weekly,monthly"`
+	// FirstDueDate defaults to one period after today
+	FirstDueDate string `json:"first_due_date,omitempty" example:"2025-06-15" format:"date"`
+}
+
+// LoanRepaymentRequest represents an early repayment. Without an amount, or
+// with one at least the outstanding principal, the loan is paid off.
+type LoanRepaymentRequest struct {
+	Amount float64 `json:"amount,omitempty" binding:"omitempty,money" example:"1000" minimum:"0.01"`
+}
+
+// LoanRepaymentResponse describes a posted early repayment
+// @Description Early loan repayment
+type LoanRepaymentResponse struct {
+	RepaymentID          uuid.UUID         `json:"repayment_id" format:"uuid"`
+	LoanID               uuid.UUID         `json:"loan_id" format:"uuid"`
+	Principal            float64           `json:"principal" example:"1000"`
+	Interest             float64           `json:"interest" example:"0"`
+	OutstandingPrincipal float64           `json:"outstanding_principal" example:"3215.3"`
+	Status               string            `json:"status" example:"active" enums:"active,repaid"`
+	Balance              float64           `json:"balance" example:"840.5"`
+	Schedule             []LoanInstallment `json:"schedule"`
+}
+
+// loanColumns is what scanLoan reads, followed by the start of the current
+// interest period and the next installment due. $2 is today's date.
+const loanColumns = `l.id, l.customer_id, l.principal, l.annual_rate, l.installments, l.frequency, l.currency, l.outstanding_principal, l.status, l.disbursement_transaction_id, l.disbursed_on,
+	(SELECT MAX(due_date) FROM loan_installments i WHERE i.loan_id = l.id AND i.status <> 'cancelled' AND i.due_date <= $2), n.due_date, n.principal + n.interest
+	FROM loans l LEFT JOIN LATERAL (SELECT due_date, principal, interest FROM loan_installments i WHERE i.loan_id = l.id AND i.status = 'due' ORDER BY number LIMIT 1) n ON TRUE`
+
+// scanLoan reads a row of loanColumns and works out the interest accrued
+// since the last due date, or since disbursement before the first
+func scanLoan(row pgx.Row, today time.Time) (Loan, error) {
+	var l Loan
+	var disbursedOn time.Time
+	var periodStart, nextDue *time.Time
+	var nextPayment *float64
+	err := row.Scan(&l.ID, &l.CustomerID, &l.Principal, &l.AnnualRate, &l.Installments, &l.Frequency, &l.Currency, &l.OutstandingPrincipal,
+		&l.Status, &l.DisbursementTransactionID, &disbursedOn, &periodStart, &nextDue, &nextPayment)
+	if err != nil {
+		return Loan{}, err
+	}
+	l.DisbursedOn = disbursedOn.Format(dateLayout)
+	if nextDue != nil {
+		l.NextDueDate = nextDue.Format(dateLayout)
+		l.NextPayment = *nextPayment
+	}
+	if l.Status == "active" {
+		if periodStart == nil {
+			periodStart = &disbursedOn
+		}
+		l.AccruedInterest = loan.Accrued(l.OutstandingPrincipal, l.AnnualRate, *periodStart, today, l.Currency, roundingPolicy)
+	}
+	l.PayoffAmount, _ = roundingPolicy.Round(l.OutstandingPrincipal+l.AccruedInterest, l.Currency)
+	return l, nil
+}
+
+// queryer runs queries on the connection or an open transaction
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// loanSchedule reads a loan's installments in order
+func loanSchedule(ctx context.Context, q queryer, loanID uuid.UUID, currency string) ([]LoanInstallment, error) {
+	rows, err := q.Query(ctx,
+		"SELECT number, due_date, principal, interest, status, failed_attempts, COALESCE(last_failure_reason, '') FROM loan_installments WHERE loan_id = $1 ORDER BY number",
+		loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	installments := []LoanInstallment{}
+	for rows.Next() {
+		var i LoanInstallment
+		var due time.Time
+		if err := rows.Scan(&i.Number, &due, &i.Principal, &i.Interest, &i.Status, &i.FailedAttempts, &i.LastFailureReason); err != nil {
+			return nil, err
+		}
+		i.DueDate = due.Format(dateLayout)
+		i.Payment, _ = roundingPolicy.Round(i.Principal+i.Interest, currency)
+		installments = append(installments, i)
+	}
+	return installments, rows.Err()
+}
+
+// bookSystemPosting posts a bank-initiated transaction, such as one leg of a
+// loan flow or a dormancy fee, to the customer's account, locked by the
+// caller within tx, against the type's general ledger account. System
+// postings keep the zero floor on accounts allowed to go negative or with an
+// overdraft.
+func bookSystemPosting(ctx context.Context, tx pgx.Tx, account *store.Customer, txType string, amount float64) (uuid.UUID, error) {
+	floor := *account
+	floor.AllowNegative, floor.Overdraft = false, 0
+	balance, err := ledger.Apply(floor, directionOf(txType), amount)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	transactionID := uuid.New()
+	if _, err := tx.Exec(ctx,
+		"UPDATE customers SET balance = $1 WHERE id = $2",
+		balance, account.ID); err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO transactions (id, customer_id, type, amount, status) VALUES ($1, $2, $3, $4, 'posted')",
+		transactionID, account.ID, txType, amount); err != nil {
+		return uuid.Nil, err
+	}
+	if err := postCounterparty(ctx, tx, transactionID, txType, amount); err != nil {
+		return uuid.Nil, err
+	}
+	if err := enqueueEvent(ctx, tx, events.TransactionPosted, &account.ID, TransactionEventData{
+		TransactionID: transactionID,
+		Type:          txType,
+		Amount:        amount,
+		Status:        "posted",
+	}); err != nil {
+		return uuid.Nil, err
+	}
+	account.Balance = balance
+	return transactionID, nil
+}
+
+// bookLoanRepayment debits principal and interest from the customer as
+// separate transactions, skipping a zero leg, and records the repayment
+func bookLoanRepayment(ctx context.Context, tx pgx.Tx, account *store.Customer, loanID uuid.UUID, installment *int, principal, interest float64) (uuid.UUID, error) {
+	// Check the whole payment first so neither leg posts on its own
+	floor := *account
+	floor.AllowNegative, floor.Overdraft = false, 0
+	if _, err := ledger.Apply(floor, txtype.Debit, principal+interest); err != nil {
+		return uuid.Nil, err
+	}
+
+	var principalTxID, interestTxID *uuid.UUID
+	if principal > 0 {
+		id, err := bookSystemPosting(ctx, tx, account, "loan_repayment", principal)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		principalTxID = &id
+	}
+	if interest > 0 {
+		id, err := bookSystemPosting(ctx, tx, account, "loan_interest", interest)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		interestTxID = &id
+	}
+
+	repaymentID := uuid.New()
+	_, err := tx.Exec(ctx,
+		"INSERT INTO loan_repayments (id, loan_id, installment_number, principal, interest, principal_transaction_id, interest_transaction_id) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		repaymentID, loanID, installment, principal, interest, principalTxID, interestTxID)
+	return repaymentID, err
+}
+
+// @Summary Disburse a loan
+// @Description Pay a loan's principal out to a customer's balance and generate its amortization schedule: equal installments of principal and interest, debited from the balance on each due date. The disbursement is recorded in the audit log under the calling operator.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator disbursing the loan"
+// @Param loan body LoanRequest true "Loan terms"
+// @Success 201 {object} Loan "Loan disbursed, with its schedule"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/loans [post]
+func CreateLoan(c *gin.Context) {
+	var req LoanRequest
+	if !bindRequest(c, &req, "Invalid input: customer_id, principal (> 0), installments and frequency (daily/weekly/monthly) are required") {
+		return
+	}
+	today := schedule.Day(time.Now())
+	firstDue := schedule.Next(today, schedule.Frequency(req.Frequency), today.Day())
+	if req.FirstDueDate != "" {
+		d, err := time.Parse(dateLayout, req.FirstDueDate)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: first_due_date must be YYYY-MM-DD"})
+			return
+		}
+		if !d.After(today) {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: first_due_date must be after today"})
+			return
+		}
+		firstDue = d
+	}
+	terms := loan.Terms{
+		Principal:    req.Principal,
+		AnnualRate:   req.AnnualRate,
+		Installments: req.Installments,
+		Frequency:    schedule.Frequency(req.Frequency),
+		FirstDue:     firstDue,
+	}
+	if err := terms.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + err.Error()})
+		return
+	}
+
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, req.CustomerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+		}
+		return
+	}
+	if account.BalanceType == store.BalanceCredit {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Loans are paid out to deposit accounts"})
+		return
+	}
+	// The loan is in the account's base currency
+	if msg := validatePrecision("principal", req.Principal, account.Currency); msg != "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + msg})
+		return
+	}
+	terms.Currency, terms.Rounding = account.Currency, roundingPolicy
+	installments := loan.Schedule(terms)
+
+	resp := Loan{
+		ID:                   uuid.New(),
+		CustomerID:           req.CustomerID,
+		Principal:            req.Principal,
+		Currency:             account.Currency,
+		AnnualRate:           req.AnnualRate,
+		Installments:         req.Installments,
+		Frequency:            req.Frequency,
+		Status:               "active",
+		OutstandingPrincipal: req.Principal,
+		PayoffAmount:         req.Principal,
+		NextDueDate:          installments[0].DueDate.Format(dateLayout),
+		NextPayment:          installments[0].Payment,
+		DisbursedOn:          today.Format(dateLayout),
+	}
+	resp.DisbursementTransactionID, err = bookSystemPosting(ctx, tx, &account, "loan_disbursement", req.Principal)
+	if err != nil {
+		if errors.Is(err, ledger.ErrInsufficientBalance) || errors.Is(err, ledger.ErrOverpayment) {
+			respondBalanceError(c, err)
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post disbursement"})
+		}
+		return
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO loans (id, customer_id, principal, currency, annual_rate, installments, frequency, outstanding_principal, disbursement_transaction_id, disbursed_on, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $3, $8, $9, $10)",
+		resp.ID, req.CustomerID, req.Principal, account.Currency, req.AnnualRate, req.Installments, req.Frequency, resp.DisbursementTransactionID, today, actor); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create loan"})
+		return
+	}
+
+	numbers := make([]int, len(installments))
+	dueDates := make([]time.Time, len(installments))
+	principals := make([]float64, len(installments))
+	interests := make([]float64, len(installments))
+	resp.Schedule = make([]LoanInstallment, len(installments))
+	for i, inst := range installments {
+		numbers[i], dueDates[i], principals[i], interests[i] = inst.Number, inst.DueDate, inst.Principal, inst.Interest
+		resp.Schedule[i] = LoanInstallment{
+			Number:    inst.Number,
+			DueDate:   inst.DueDate.Format(dateLayout),
+			Payment:   inst.Payment,
+			Principal: inst.Principal,
+			Interest:  inst.Interest,
+			Status:    "due",
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO loan_installments (loan_id, number, due_date, principal, interest) SELECT $1, * FROM unnest($2::int[], $3::date[], $4::numeric[], $5::numeric[])",
+		resp.ID, numbers, dueDates, principals, interests); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create loan schedule"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "loan.disbursed", "loan", resp.ID, &req.CustomerID, map[string]interface{}{
+		"transaction_id": resp.DisbursementTransactionID,
+		"principal":      req.Principal,
+		"annual_rate":    req.AnnualRate,
+		"installments":   req.Installments,
+		"frequency":      req.Frequency,
+		"first_due_date": resp.NextDueDate,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, req.CustomerID)
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// @Summary List loans
+// @Description List a customer's loans, newest first, with the principal outstanding and the interest accrued since the last due date
+// @Tags loans
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} Loan "Loans"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/loans [get]
+func ListLoans(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	today := schedule.Day(time.Now())
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+loanColumns+" WHERE l.customer_id = $1 ORDER BY l.created_at DESC",
+		customerID, today)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list loans"})
+		return
+	}
+	defer rows.Close()
+
+	loans := []Loan{}
+	for rows.Next() {
+		l, err := scanLoan(rows, today)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to read loan"})
+			return
+		}
+		loans = append(loans, l)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list loans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, loans)
+}
+
+// parseLoanPath reads the customer and loan IDs from the path
+func parseLoanPath(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	loanID, err := uuid.Parse(c.Param("loan_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid loan ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return customerID, loanID, true
+}
+
+// @Summary Get a loan
+// @Description Get a loan with its outstanding principal, the interest accrued since the last due date, the amount that would pay it off today and the next installment due
+// @Tags loans
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param loan_id path string true "Loan ID" format(uuid)
+// @Success 200 {object} Loan "Loan"
+// @Failure 400 {object} ErrorResponse "Invalid customer or loan ID"
+// @Failure 404 {object} ErrorResponse "Loan not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/loans/{loan_id} [get]
+func GetLoan(c *gin.Context) {
+	customerID, loanID, ok := parseLoanPath(c)
+	if !ok {
+		return
+	}
+
+	today := schedule.Day(time.Now())
+	l, err := scanLoan(db.QueryRow(c.Request.Context(),
+		"SELECT "+loanColumns+" WHERE l.customer_id = $1 AND l.id = $3",
+		customerID, today, loanID), today)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get loan"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, l)
+}
+
+// @Summary Get a loan's schedule
+// @Description Get a loan's amortization schedule: every installment with its due date, principal and interest split, and whether it is due, paid or cancelled by an early payoff
+// @Tags loans
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param loan_id path string true "Loan ID" format(uuid)
+// @Success 200 {array} LoanInstallment "Installments"
+// @Failure 400 {object} ErrorResponse "Invalid customer or loan ID"
+// @Failure 404 {object} ErrorResponse "Loan not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/loans/{loan_id}/schedule [get]
+func GetLoanSchedule(c *gin.Context) {
+	customerID, loanID, ok := parseLoanPath(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var currency string
+	if err := db.QueryRow(ctx,
+		"SELECT currency FROM loans WHERE id = $1 AND customer_id = $2",
+		loanID, customerID).Scan(&currency); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get loan"})
+		}
+		return
+	}
+	installments, err := loanSchedule(ctx, db, loanID, currency)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get loan schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, installments)
+}
+
+// @Summary Repay a loan early
+// @Description Repay part or all of a loan ahead of schedule from the customer's balance. An amount below the outstanding principal repays that much principal and re-amortizes the remaining installments over the same due dates. Without an amount, or with one at least the outstanding principal, the loan is paid off: the outstanding principal plus the interest accrued since the last due date is debited and the remaining installments are cancelled. Overdue installments must be paid first.
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param loan_id path string true "Loan ID" format(uuid)
+// @Param repayment body LoanRepaymentRequest true "Repayment"
+// @Success 200 {object} LoanRepaymentResponse "Repayment posted"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 404 {object} ErrorResponse "Loan not found"
+// @Failure 409 {object} ErrorResponse "Loan already repaid or an installment is overdue"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/loans/{loan_id}/repay [post]
+func RepayLoan(c *gin.Context) {
+	customerID, loanID, ok := parseLoanPath(c)
+	if !ok {
+		return
+	}
+	var req LoanRepaymentRequest
+	if !bindRequest(c, &req, "Invalid input: amount must be greater than 0") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	today := schedule.Day(time.Now())
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	l, err := scanLoan(tx.QueryRow(ctx,
+		"SELECT "+loanColumns+" WHERE l.customer_id = $1 AND l.id = $3 FOR UPDATE OF l",
+		customerID, today, loanID), today)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Loan not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get loan"})
+		}
+		return
+	}
+	if l.Status != "active" {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Loan is already repaid"})
+		return
+	}
+	if l.NextDueDate != "" && l.NextDueDate <= today.Format(dateLayout) {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Loan has an overdue installment"})
+		return
+	}
+	installments, err := loanSchedule(ctx, tx, loanID, l.Currency)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get loan schedule"})
+		return
+	}
+
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+		return
+	}
+
+	resp := LoanRepaymentResponse{LoanID: loanID, Status: l.Status}
+	payoff := req.Amount == 0 || req.Amount >= l.OutstandingPrincipal
+	if payoff {
+		resp.Principal, resp.Interest = l.OutstandingPrincipal, l.AccruedInterest
+	} else {
+		resp.Principal = req.Amount
+	}
+	resp.RepaymentID, err = bookLoanRepayment(ctx, tx, &account, loanID, nil, resp.Principal, resp.Interest)
+	if err != nil {
+		if errors.Is(err, ledger.ErrInsufficientBalance) || errors.Is(err, ledger.ErrOverpayment) {
+			respondBalanceError(c, err)
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post repayment"})
+		}
+		return
+	}
+	resp.Balance = account.Balance
+
+	var remaining []*LoanInstallment
+	for i := range installments {
+		if installments[i].Status == "due" {
+			remaining = append(remaining, &installments[i])
+		}
+	}
+	if payoff {
+		resp.Status = "repaid"
+		if _, err := tx.Exec(ctx,
+			"UPDATE loan_installments SET status = 'cancelled', retry_on = NULL WHERE loan_id = $1 AND status = 'due'",
+			loanID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan schedule"})
+			return
+		}
+		for _, inst := range remaining {
+			inst.Status = "cancelled"
+		}
+		if _, err := tx.Exec(ctx,
+			"UPDATE loans SET outstanding_principal = 0, status = 'repaid', repaid_at = NOW(), updated_at = NOW() WHERE id = $1",
+			loanID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan"})
+			return
+		}
+	} else {
+		// Keep the remaining due dates and spread what is left over them
+		resp.OutstandingPrincipal, _ = roundingPolicy.Round(l.OutstandingPrincipal-resp.Principal, l.Currency)
+		if len(remaining) > 0 {
+			first, _ := time.Parse(dateLayout, remaining[0].DueDate)
+			rescheduled := loan.Schedule(loan.Terms{
+				Principal:    resp.OutstandingPrincipal,
+				AnnualRate:   l.AnnualRate,
+				Installments: len(remaining),
+				Frequency:    schedule.Frequency(l.Frequency),
+				FirstDue:     first,
+				Currency:     l.Currency,
+				Rounding:     roundingPolicy,
+			})
+			for i, inst := range remaining {
+				inst.Principal, inst.Interest, inst.Payment = rescheduled[i].Principal, rescheduled[i].Interest, rescheduled[i].Payment
+				if _, err := tx.Exec(ctx,
+					"UPDATE loan_installments SET principal = $1, interest = $2 WHERE loan_id = $3 AND number = $4",
+					inst.Principal, inst.Interest, loanID, inst.Number); err != nil {
+					respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan schedule"})
+					return
+				}
+			}
+		}
+		if _, err := tx.Exec(ctx,
+			"UPDATE loans SET outstanding_principal = $1, updated_at = NOW() WHERE id = $2",
+			resp.OutstandingPrincipal, loanID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update loan"})
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, customerID)
+
+	resp.Schedule = installments
+	c.JSON(http.StatusOK, resp)
+}
+
+// ProcessLoanRepayments collects every loan installment due on or before
+// now's date and returns how many were attempted. Installments are paged by
+// loan and number, so one that is still due after its run is not picked up
+// again.
+func ProcessLoanRepayments(ctx context.Context, now time.Time) (int, error) {
+	type due struct {
+		loanID uuid.UUID
+		number int
+	}
+	today := schedule.Day(now)
+	processed := 0
+	var after due
+	for {
+		rows, err := db.Query(ctx,
+			"SELECT i.loan_id, i.number FROM loan_installments i JOIN loans l ON l.id = i.loan_id WHERE i.status = 'due' AND l.status = 'active' AND COALESCE(i.retry_on, i.due_date) <= $1 AND (i.loan_id, i.number) > ($2, $3) ORDER BY i.loan_id, i.number LIMIT 100",
+			today, after.loanID, after.number)
+		if err != nil {
+			return processed, err
+		}
+		var installments []due
+		for rows.Next() {
+			var d due
+			if err := rows.Scan(&d.loanID, &d.number); err != nil {
+				rows.Close()
+				return processed, err
+			}
+			installments = append(installments, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return processed, err
+		}
+
+		for _, d := range installments {
+			if err := runLoanInstallment(ctx, d.loanID, d.number, today); err != nil {
+				log.Printf("Loan %s installment %d failed: %v", d.loanID, d.number, err)
+			}
+			processed++
+			after = d
+		}
+		if len(installments) < 100 {
+			return processed, nil
+		}
+	}
+}
+
+// runLoanInstallment debits one due installment. An installment the customer
+// cannot cover, or that is due from a dormant account while debits are
+// frozen, is retried every day until it is paid, and the customer is alerted
+// on every failure.
+func runLoanInstallment(ctx context.Context, loanID uuid.UUID, number int, today time.Time) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// SKIP LOCKED lets several instances share the work, and skips loans an
+	// early repayment is changing
+	var customerID uuid.UUID
+	var principal, interest float64
+	var failedAttempts int
+	err = tx.QueryRow(ctx,
+		"SELECT l.customer_id, i.principal, i.interest, i.failed_attempts FROM loan_installments i JOIN loans l ON l.id = i.loan_id WHERE i.loan_id = $1 AND i.number = $2 AND i.status = 'due' AND l.status = 'active' AND COALESCE(i.retry_on, i.due_date) <= $3 FOR UPDATE SKIP LOCKED",
+		loanID, number, today).Scan(&customerID, &principal, &interest, &failedAttempts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	account, err := store.NewPostgresTx(tx).LockCustomer(ctx, customerID)
+	if err != nil {
+		return err
+	}
+
+	var alert, reason string
+	err = checkDormantDebit(ctx, tx, customerID)
+	if err == nil {
+		_, err = bookLoanRepayment(ctx, tx, &account, loanID, &number, principal, interest)
+	}
+	switch {
+	case err == nil:
+		var outstanding float64
+		if _, err := tx.Exec(ctx,
+			"UPDATE loan_installments SET status = 'paid', paid_at = NOW(), retry_on = NULL, last_failure_reason = NULL WHERE loan_id = $1 AND number = $2",
+			loanID, number); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(ctx,
+			"UPDATE loans SET outstanding_principal = outstanding_principal - $1, updated_at = NOW() WHERE id = $2 RETURNING outstanding_principal",
+			principal, loanID).Scan(&outstanding); err != nil {
+			return err
+		}
+		if outstanding <= 0 {
+			if _, err := tx.Exec(ctx,
+				"UPDATE loans SET status = 'repaid', repaid_at = NOW() WHERE id = $1",
+				loanID); err != nil {
+				return err
+			}
+		}
+	case errors.Is(err, errInsufficientFunds):
+		reason = "Insufficient balance"
+		alert = fmt.Sprintf("Ledger alert: your loan installment of %.2f could not be paid due to insufficient funds. We will retry tomorrow.", principal+interest)
+	case errors.Is(err, errAccountDormant):
+		reason = errAccountDormant.Message
+		alert = fmt.Sprintf("Ledger alert: your loan installment of %.2f could not be paid because your account is dormant. Please contact us to reactivate it; we will retry tomorrow.", principal+interest)
+	default:
+		return err
+	}
+	if reason != "" {
+		if _, err := tx.Exec(ctx,
+			"UPDATE loan_installments SET retry_on = $1, failed_attempts = $2, last_failure_reason = $3 WHERE loan_id = $4 AND number = $5",
+			today.AddDate(0, 0, 1), failedAttempts+1, reason, loanID, number); err != nil {
+			return err
+		}
+	}
+
+	if alert != "" {
+		if err := alertCustomer(ctx, tx, customerID, alert); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	invalidateBalances(ctx, customerID)
+	return nil
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectSystemPosting expects bookSystemPosting to post amount of txType
+// against glAccount on the given side
+func expectSystemPosting(customerID uuid.UUID, txType, glAccount, side string, amount float64) {
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(pgxmock.AnyArg(), customerID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+		WithArgs(pgxmock.AnyArg(), customerID, txType, amount).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE gl_accounts SET balance`).
+		WithArgs(glAccount, side, amount).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO gl_entries`).
+		WithArgs(pgxmock.AnyArg(), glAccount, pgxmock.AnyArg(), side, amount).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(events.TransactionPosted)
+}
+
+func TestCreateLoan(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/loans", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "jane")
+	}, CreateLoan)
+
+	customerID := uuid.New()
+	payload := map[string]interface{}{
+		"customer_id":  customerID,
+		"principal":    1000,
+		"annual_rate":  0.12,
+		"installments": 3,
+		"frequency":    "monthly",
+	}
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "disburses and schedules",
+			payload:    payload,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(lockedCustomer(50, "checking", false))
+				expectSystemPosting(customerID, "loan_disbursement", "loans_receivable", "debit", 1000)
+				mock.ExpectExec(`INSERT INTO loans`).
+					WithArgs(pgxmock.AnyArg(), customerID, float64(1000), "USD", 0.12, 3, "monthly", pgxmock.AnyArg(), pgxmock.AnyArg(), "jane").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`INSERT INTO loan_installments .* unnest`).
+					WithArgs(pgxmock.AnyArg(), []int{1, 2, 3}, pgxmock.AnyArg(), []float64{330.02, 333.32, 336.66}, []float64{10, 6.7, 3.37}).
+					WillReturnResult(pgxmock.NewResult("INSERT", 3))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "jane", "loan.disbursed", "loan", pgxmock.AnyArg(), &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "credit account",
+			payload:    payload,
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(customerID).
+					WillReturnRows(creditCustomer(0, 500))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "first due date in the past",
+			payload: map[string]interface{}{
+				"customer_id":    customerID,
+				"principal":      1000,
+				"installments":   3,
+				"frequency":      "monthly",
+				"first_due_date": "2020-01-01",
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "rate out of range",
+			payload: map[string]interface{}{
+				"customer_id":  customerID,
+				"principal":    1000,
+				"annual_rate":  1.5,
+				"installments": 3,
+				"frequency":    "monthly",
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/loans", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp Loan
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, 1000.0, resp.OutstandingPrincipal)
+				assert.Equal(t, "USD", resp.Currency)
+				assert.Equal(t, 340.02, resp.NextPayment)
+				assert.Len(t, resp.Schedule, 3)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepayLoan(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/loans/:loan_id/repay", RepayLoan)
+
+	customerID := uuid.New()
+	loanID := uuid.New()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	nextDue := today.AddDate(0, 0, 20)
+	lastDue := today.AddDate(0, 0, -10)
+	nextPayment := 510.0
+
+	expectLoan := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM loans l LEFT JOIN LATERAL .* WHERE l.customer_id = \$1 AND l.id = \$3 FOR UPDATE OF l`).
+			WithArgs(customerID, pgxmock.AnyArg(), loanID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "principal", "annual_rate", "installments", "frequency", "currency",
+				"outstanding_principal", "status", "disbursement_transaction_id", "disbursed_on", "period_start", "next_due", "next_payment"}).
+				AddRow(loanID, customerID, float64(1500), 0.12, 3, "monthly", "USD", float64(1000), "active", uuid.New(),
+					today.AddDate(0, -1, -10), &lastDue, &nextDue, &nextPayment))
+		mock.ExpectQuery(`SELECT number, due_date, principal, interest, status, failed_attempts, COALESCE\(last_failure_reason, ''\) FROM loan_installments`).
+			WithArgs(loanID).
+			WillReturnRows(pgxmock.NewRows([]string{"number", "due_date", "principal", "interest", "status", "failed_attempts", "last_failure_reason"}).
+				AddRow(1, lastDue, float64(500), float64(15), "paid", 0, "").
+				AddRow(2, nextDue, float64(500), float64(10), "due", 0, "").
+				AddRow(3, nextDue.AddDate(0, 1, 0), float64(500), float64(5), "due", 0, ""))
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(customerID).
+			WillReturnRows(lockedCustomer(2000, "checking", false))
+	}
+
+	t.Run("partial repayment re-amortizes", func(t *testing.T) {
+		expectLoan()
+		expectSystemPosting(customerID, "loan_repayment", "loans_receivable", "credit", 400)
+		mock.ExpectExec(`INSERT INTO loan_repayments`).
+			WithArgs(pgxmock.AnyArg(), loanID, (*int)(nil), float64(400), float64(0), pgxmock.AnyArg(), (*uuid.UUID)(nil)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE loan_installments SET principal = \$1, interest = \$2 WHERE loan_id = \$3 AND number = \$4`).
+			WithArgs(298.51, float64(6), loanID, 2).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE loan_installments SET principal = \$1, interest = \$2 WHERE loan_id = \$3 AND number = \$4`).
+			WithArgs(301.49, 3.01, loanID, 3).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE loans SET outstanding_principal = \$1, updated_at = NOW\(\) WHERE id = \$2`).
+			WithArgs(float64(600), loanID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/loans/"+loanID.String()+"/repay", bytes.NewBufferString(`{"amount": 400}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp LoanRepaymentResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "active", resp.Status)
+		assert.Equal(t, 600.0, resp.OutstandingPrincipal)
+		assert.Equal(t, 1600.0, resp.Balance)
+		assert.Equal(t, 304.51, resp.Schedule[1].Payment)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("payoff charges accrued interest and cancels the rest", func(t *testing.T) {
+		expectLoan()
+		// 1000 at 12% for the 10 days since the last due date
+		expectSystemPosting(customerID, "loan_repayment", "loans_receivable", "credit", 1000)
+		expectSystemPosting(customerID, "loan_interest", "interest_income", "credit", 3.29)
+		mock.ExpectExec(`INSERT INTO loan_repayments`).
+			WithArgs(pgxmock.AnyArg(), loanID, (*int)(nil), float64(1000), 3.29, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE loan_installments SET status = 'cancelled'`).
+			WithArgs(loanID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+		mock.ExpectExec(`UPDATE loans SET outstanding_principal = 0, status = 'repaid'`).
+			WithArgs(loanID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/loans/"+loanID.String()+"/repay", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp LoanRepaymentResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "repaid", resp.Status)
+		assert.Equal(t, 3.29, resp.Interest)
+		assert.Equal(t, "cancelled", resp.Schedule[2].Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProcessLoanRepayments(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	loanID := uuid.New()
+	customerID := uuid.New()
+	today := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	number := 3
+
+	expectDueInstallment := func(balance float64) {
+		mock.ExpectQuery(`SELECT i.loan_id, i.number FROM loan_installments i JOIN loans l .* AND \(i.loan_id, i.number\) > \(\$2, \$3\) ORDER BY i.loan_id, i.number`).
+			WithArgs(today, uuid.Nil, 0).
+			WillReturnRows(pgxmock.NewRows([]string{"loan_id", "number"}).AddRow(loanID, number))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM loan_installments i JOIN loans l .* FOR UPDATE SKIP LOCKED`).
+			WithArgs(loanID, number, today).
+			WillReturnRows(pgxmock.NewRows([]string{"customer_id", "principal", "interest", "failed_attempts"}).
+				AddRow(customerID, 336.66, 3.37, 0))
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(customerID).
+			WillReturnRows(lockedCustomer(balance, "checking", false))
+	}
+
+	t.Run("pays the last installment", func(t *testing.T) {
+		expectDueInstallment(500)
+		expectSystemPosting(customerID, "loan_repayment", "loans_receivable", "credit", 336.66)
+		expectSystemPosting(customerID, "loan_interest", "interest_income", "credit", 3.37)
+		mock.ExpectExec(`INSERT INTO loan_repayments`).
+			WithArgs(pgxmock.AnyArg(), loanID, &number, 336.66, 3.37, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE loan_installments SET status = 'paid'`).
+			WithArgs(loanID, number).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery(`UPDATE loans SET outstanding_principal = outstanding_principal - \$1, updated_at = NOW\(\) WHERE id = \$2 RETURNING outstanding_principal`).
+			WithArgs(336.66, loanID).
+			WillReturnRows(pgxmock.NewRows([]string{"outstanding_principal"}).AddRow(float64(0)))
+		mock.ExpectExec(`UPDATE loans SET status = 'repaid'`).
+			WithArgs(loanID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		n, err := ProcessLoanRepayments(context.Background(), today.Add(9*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insufficient funds retries tomorrow", func(t *testing.T) {
+		expectDueInstallment(100)
+		mock.ExpectExec(`UPDATE loan_installments SET retry_on = \$1, failed_attempts = \$2, last_failure_reason = \$3`).
+			WithArgs(today.AddDate(0, 0, 1), 1, "Insufficient balance", loanID, number).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		_, err := ProcessLoanRepayments(context.Background(), today)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("dormant account retries tomorrow", func(t *testing.T) {
+		InitDormancy(DormancyConfig{Days: 365, FreezeDebits: true})
+		defer InitDormancy(DormancyConfig{})
+
+		expectDueInstallment(500)
+		mock.ExpectQuery(`SELECT dormant_since IS NOT NULL FROM customers WHERE id = \$1`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"dormant"}).AddRow(true))
+		mock.ExpectExec(`UPDATE loan_installments SET retry_on = \$1, failed_attempts = \$2, last_failure_reason = \$3`).
+			WithArgs(today.AddDate(0, 0, 1), 1, errAccountDormant.Message, loanID, number).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		_, err := ProcessLoanRepayments(context.Background(), today)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
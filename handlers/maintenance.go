@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// MaintenanceRoute is the switch itself, which stays writable in maintenance
+// mode so it can be switched off again
+const MaintenanceRoute = "/admin/maintenance"
+
+// maintenance is the write switch. Instances share it through the
+// maintenance_mode table and refresh their copy every few seconds; forced
+// comes from MAINTENANCE_MODE and holds regardless of the table.
+var maintenance struct {
+	sync.RWMutex
+	forced     bool
+	retryAfter time.Duration
+	mode       MaintenanceMode
+	// unsaved is set when a switch could not be written to the database, so
+	// the next refresh writes it instead of reading an older one back
+	unsaved bool
+}
+
+// MaintenanceMode is the maintenance switch
+// @Description Whether write requests are refused while the service is maintained
+type MaintenanceMode struct {
+	Enabled bool `json:"enabled" example:"true"`
+	// Forced is set when MAINTENANCE_MODE keeps this instance in maintenance
+	// whatever the switch says
+	Forced            bool   `json:"forced" example:"false"`
+	Reason            string `json:"reason,omitempty" example:"Upgrading Postgres to 16"`
+	RetryAfterSeconds int    `json:"retry_after_seconds" example:"300"`
+	UpdatedBy         string `json:"updated_by,omitempty" example:"alice"`
+	UpdatedAt         string `json:"updated_at,omitempty" example:"2025-04-08T22:00:00Z" format:"date-time"`
+	// Shared is false when the switch could not be saved to the database, so
+	// it only applies to this instance until it can be
+	Shared bool `json:"shared" example:"true"`
+}
+
+// MaintenanceRequest switches maintenance mode on or off
+// @Description New state of the maintenance switch
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required" example:"true"`
+	Reason  string `json:"reason,omitempty" binding:"max=500" example:"Upgrading Postgres to 16"`
+	// RetryAfterSeconds is sent to refused callers in Retry-After, defaulting
+	// to MAINTENANCE_RETRY_AFTER_SECONDS
+	RetryAfterSeconds *int `json:"retry_after_seconds,omitempty" binding:"omitempty,min=1,max=86400" example:"600"`
+}
+
+// InitMaintenance configures maintenance mode. forced keeps writes refused
+// until the setting is removed; retryAfter is the default Retry-After.
+func InitMaintenance(forced bool, retryAfter time.Duration) {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+	maintenance.forced = forced
+	maintenance.retryAfter = retryAfter
+	maintenance.mode = MaintenanceMode{RetryAfterSeconds: int(retryAfter.Seconds()), Shared: true}
+	maintenance.unsaved = false
+}
+
+// CurrentMaintenance reports whether writes are refused, for
+// middleware.Maintenance
+func CurrentMaintenance() middleware.MaintenanceState {
+	maintenance.RLock()
+	defer maintenance.RUnlock()
+	return middleware.MaintenanceState{
+		Enabled:    maintenance.forced || maintenance.mode.Enabled,
+		RetryAfter: time.Duration(maintenance.mode.RetryAfterSeconds) * time.Second,
+	}
+}
+
+func currentMaintenanceMode() MaintenanceMode {
+	maintenance.RLock()
+	defer maintenance.RUnlock()
+	m := maintenance.mode
+	m.Forced = maintenance.forced
+	m.Enabled = m.Enabled || m.Forced
+	return m
+}
+
+// RunMaintenanceRefresher picks up switches made on other instances every
+// interval until ctx is cancelled
+func RunMaintenanceRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := RefreshMaintenanceMode(ctx); err != nil {
+			log.Printf("Maintenance mode refresh failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshMaintenanceMode reads the shared switch, or writes this instance's
+// when a switch made here has not been saved yet. The last known state stays
+// in force when the database cannot be reached.
+func RefreshMaintenanceMode(ctx context.Context) error {
+	maintenance.RLock()
+	unsaved, mode := maintenance.unsaved, maintenance.mode
+	maintenance.RUnlock()
+	if unsaved {
+		if err := saveMaintenanceMode(ctx, mode); err != nil {
+			return err
+		}
+		maintenance.Lock()
+		if maintenance.mode == mode {
+			maintenance.unsaved = false
+			maintenance.mode.Shared = true
+		}
+		maintenance.Unlock()
+		log.Println("Saved maintenance mode switched while the database was unavailable")
+		return nil
+	}
+
+	var m MaintenanceMode
+	var updatedAt time.Time
+	err := db.QueryRow(ctx,
+		"SELECT enabled, reason, retry_after_seconds, updated_by, updated_at FROM maintenance_mode").
+		Scan(&m.Enabled, &m.Reason, &m.RetryAfterSeconds, &m.UpdatedBy, &updatedAt)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	m.Shared = true
+
+	maintenance.Lock()
+	defer maintenance.Unlock()
+	if maintenance.unsaved {
+		// Switched here while reading
+		return nil
+	}
+	if m.Enabled != maintenance.mode.Enabled {
+		log.Printf("Maintenance mode switched %s by %s", onOff(m.Enabled), m.UpdatedBy)
+	}
+	maintenance.mode = m
+	return nil
+}
+
+// saveMaintenanceMode writes the shared switch and records the change in the
+// audit log
+func saveMaintenanceMode(ctx context.Context, m MaintenanceMode) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO maintenance_mode (id, enabled, reason, retry_after_seconds, updated_by, updated_at) VALUES (TRUE, $1, $2, $3, $4, NOW())
+		ON CONFLICT (id) DO UPDATE SET enabled = EXCLUDED.enabled, reason = EXCLUDED.reason, retry_after_seconds = EXCLUDED.retry_after_seconds,
+			updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		m.Enabled, m.Reason, m.RetryAfterSeconds, m.UpdatedBy); err != nil {
+		return err
+	}
+	action := "maintenance.disabled"
+	if m.Enabled {
+		action = "maintenance.enabled"
+	}
+	if err := recordAudit(ctx, tx, m.UpdatedBy, action, "maintenance", uuid.New(), nil, map[string]interface{}{
+		"reason":              m.Reason,
+		"retry_after_seconds": m.RetryAfterSeconds,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// @Summary Get maintenance mode
+// @Description Report whether write requests are being refused with 503 for maintenance
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} MaintenanceMode "Maintenance switch"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Router /admin/maintenance [get]
+func GetMaintenanceMode(c *gin.Context) {
+	c.JSON(http.StatusOK, currentMaintenanceMode())
+}
+
+// @Summary Switch maintenance mode
+// @Description Switch maintenance mode on or off for every instance. While it is on, every write request except this one is refused with 503, code `maintenance` and a Retry-After header; reads and health checks keep working. Other instances pick the switch up within MAINTENANCE_REFRESH_SECONDS. If the database cannot be reached, the switch still applies to this instance at once, `shared` is false, and it is saved when the database is back. The change is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param maintenance body MaintenanceRequest true "Switch"
+// @Success 200 {object} MaintenanceMode "Maintenance switch"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 409 {object} ErrorResponse "Maintenance mode is forced on by configuration"
+// @Router /admin/maintenance [put]
+func SetMaintenanceMode(c *gin.Context) {
+	var req MaintenanceRequest
+	if !bindRequest(c, &req, "Invalid input: enabled is required") {
+		return
+	}
+
+	maintenance.Lock()
+	if maintenance.forced && !*req.Enabled {
+		maintenance.Unlock()
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Maintenance mode is forced on by configuration", Code: "maintenance_forced"})
+		return
+	}
+	m := MaintenanceMode{
+		Enabled:           *req.Enabled,
+		Reason:            req.Reason,
+		RetryAfterSeconds: int(maintenance.retryAfter.Seconds()),
+		UpdatedBy:         c.GetString(middleware.ActorKey),
+		UpdatedAt:         time.Now().UTC().Format(time.RFC3339),
+	}
+	if req.RetryAfterSeconds != nil {
+		m.RetryAfterSeconds = *req.RetryAfterSeconds
+	}
+	// Apply locally first so a database outage cannot stop an operator
+	// taking this instance out of service
+	maintenance.mode, maintenance.unsaved = m, true
+	maintenance.Unlock()
+	log.Printf("Maintenance mode switched %s by %s", onOff(m.Enabled), m.UpdatedBy)
+
+	if err := saveMaintenanceMode(c.Request.Context(), m); err != nil {
+		log.Printf("Failed to save maintenance mode, applying it to this instance only: %v", err)
+	} else {
+		maintenance.Lock()
+		if maintenance.mode == m {
+			maintenance.unsaved = false
+			maintenance.mode.Shared = true
+		}
+		maintenance.Unlock()
+	}
+
+	c.JSON(http.StatusOK, currentMaintenanceMode())
+}
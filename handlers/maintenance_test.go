@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expectMaintenanceSaved(enabled bool, reason string, retryAfter int, actor string) {
+	action := "maintenance.disabled"
+	if enabled {
+		action = "maintenance.enabled"
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO maintenance_mode .* ON CONFLICT \(id\) DO UPDATE`).
+		WithArgs(enabled, reason, retryAfter, actor).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(pgxmock.AnyArg(), actor, action, "maintenance", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+}
+
+func TestSetMaintenanceMode(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	defer InitMaintenance(false, 0)
+
+	router.PUT("/admin/maintenance", SetMaintenanceMode)
+	put := func(body string) (*httptest.ResponseRecorder, MaintenanceMode) {
+		req := httptest.NewRequest("PUT", "/admin/maintenance", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp MaintenanceMode
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("switches every instance", func(t *testing.T) {
+		InitMaintenance(false, 5*time.Minute)
+		expectMaintenanceSaved(true, "Upgrading Postgres", 600, "")
+
+		w, resp := put(`{"enabled": true, "reason": "Upgrading Postgres", "retry_after_seconds": 600}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, resp.Enabled)
+		assert.True(t, resp.Shared)
+		assert.Equal(t, 600, resp.RetryAfterSeconds)
+		assert.True(t, CurrentMaintenance().Enabled)
+		assert.Equal(t, 10*time.Minute, CurrentMaintenance().RetryAfter)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("applies locally while the database is down and saves it later", func(t *testing.T) {
+		InitMaintenance(false, 5*time.Minute)
+		mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+		w, resp := put(`{"enabled": true}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, resp.Enabled)
+		assert.False(t, resp.Shared)
+		assert.True(t, CurrentMaintenance().Enabled)
+
+		// The next refresh writes the switch instead of reading the old one
+		expectMaintenanceSaved(true, "", 300, "")
+		require.NoError(t, RefreshMaintenanceMode(context.Background()))
+		assert.True(t, currentMaintenanceMode().Shared)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cannot switch off when forced by configuration", func(t *testing.T) {
+		InitMaintenance(true, 5*time.Minute)
+
+		w, _ := put(`{"enabled": false}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.True(t, CurrentMaintenance().Enabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("enabled is required", func(t *testing.T) {
+		w, _ := put(`{"reason": "oops"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestRefreshMaintenanceMode(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	InitMaintenance(false, 5*time.Minute)
+	defer InitMaintenance(false, 0)
+
+	updatedAt := time.Date(2025, 4, 8, 22, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT enabled, reason, retry_after_seconds, updated_by, updated_at FROM maintenance_mode`).
+		WillReturnRows(pgxmock.NewRows([]string{"enabled", "reason", "retry_after_seconds", "updated_by", "updated_at"}).
+			AddRow(true, "Failover drill", 120, "alice", updatedAt))
+	require.NoError(t, RefreshMaintenanceMode(context.Background()))
+	assert.Equal(t, MaintenanceMode{
+		Enabled: true, Reason: "Failover drill", RetryAfterSeconds: 120,
+		UpdatedBy: "alice", UpdatedAt: "2025-04-08T22:00:00Z", Shared: true,
+	}, currentMaintenanceMode())
+
+	// An unreachable database keeps the last known state
+	mock.ExpectQuery(`FROM maintenance_mode`).WillReturnError(errors.New("connection refused"))
+	assert.Error(t, RefreshMaintenanceMode(context.Background()))
+	assert.True(t, CurrentMaintenance().Enabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ledger-service/ledger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Mandate represents a customer's authorization for a merchant to pull funds
+// @Description Direct debit mandate
+type Mandate struct {
+	ID                 uuid.UUID `json:"mandate_id" format:"uuid"`
+	CustomerID         uuid.UUID `json:"customer_id" format:"uuid"`
+	MerchantCustomerID uuid.UUID `json:"merchant_customer_id" format:"uuid"`
+	MaxAmount          float64   `json:"max_amount" example:"100"`
+	MonthlyLimit       float64   `json:"monthly_limit,omitempty" example:"300"`
+	Reference          string    `json:"reference,omitempty" example:"Gym membership"`
+	Status             string    `json:"status" example:"active" enums:"active,revoked"`
+	CreatedAt          string    `json:"created_at" format:"date-time"`
+}
+
+// MandateRequest represents the payload for creating or updating a mandate
+type MandateRequest struct {
+	MerchantCustomerID uuid.UUID `json:"merchant_customer_id" format:"uuid"`
+	MaxAmount          float64   `json:"max_amount" binding:"required,money" example:"100" minimum:"0.01"`
+	MonthlyLimit       float64   `json:"monthly_limit,omitempty" binding:"gte=0" example:"300" minimum:"0"`
+	Reference          string    `json:"reference,omitempty" binding:"max=140" example:"Gym membership" maxLength:"140"`
+}
+
+// PullPaymentRequest represents a merchant's request to collect under a mandate
+type PullPaymentRequest struct {
+	MandateID          uuid.UUID `json:"mandate_id" binding:"required,uuid" format:"uuid"`
+	MerchantCustomerID uuid.UUID `json:"merchant_customer_id" binding:"required,uuid" format:"uuid"`
+	Amount             float64   `json:"amount" binding:"required,money" example:"49.99" minimum:"0.01"`
+}
+
+// PullPaymentResponse represents a collected pull payment
+type PullPaymentResponse struct {
+	PaymentID  uuid.UUID `json:"payment_id" format:"uuid"`
+	MandateID  uuid.UUID `json:"mandate_id" format:"uuid"`
+	TransferID uuid.UUID `json:"transfer_id" format:"uuid"`
+	Amount     float64   `json:"amount" example:"49.99"`
+	Status     string    `json:"status" example:"collected"`
+}
+
+const mandateColumns = "id, customer_id, merchant_customer_id, max_amount, COALESCE(monthly_limit, 0), COALESCE(reference, ''), status, created_at"
+
+func scanMandate(row pgx.Row) (Mandate, error) {
+	var m Mandate
+	var createdAt time.Time
+	if err := row.Scan(&m.ID, &m.CustomerID, &m.MerchantCustomerID, &m.MaxAmount, &m.MonthlyLimit, &m.Reference, &m.Status, &createdAt); err != nil {
+		return Mandate{}, err
+	}
+	m.CreatedAt = createdAt.Format(time.RFC3339)
+	return m, nil
+}
+
+// nullableAmount maps a zero amount to NULL for optional limits
+func nullableAmount(v float64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// @Summary Create a mandate
+// @Description Authorize a merchant customer to pull funds from this customer, up to max_amount per payment and optionally monthly_limit per calendar month
+// @Tags mandates
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Paying customer ID" format(uuid)
+// @Param mandate body MandateRequest true "Mandate details"
+// @Success 201 {object} Mandate "Mandate created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer or merchant not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/mandates [post]
+func CreateMandate(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req MandateRequest
+	if !bindRequest(c, &req, "Invalid input: merchant_customer_id and max_amount (> 0) are required") {
+		return
+	}
+	// Updates keep the merchant, so only creation requires it
+	if req.MerchantCustomerID == uuid.Nil {
+		respondValidationError(c, fieldErrors{{Field: "merchant_customer_id", Message: "merchant_customer_id is required"}})
+		return
+	}
+	if req.MerchantCustomerID == customerID {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: merchant must be a different customer"})
+		return
+	}
+	if req.MonthlyLimit > 0 && req.MonthlyLimit < req.MaxAmount {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: monthly_limit must be at least max_amount"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !verifyTransferParties(c, customerID, req.MerchantCustomerID, "Customer not found", "Merchant not found") {
+		return
+	}
+
+	mandate, err := scanMandate(db.QueryRow(ctx,
+		"INSERT INTO mandates (id, customer_id, merchant_customer_id, max_amount, monthly_limit, reference) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+mandateColumns,
+		uuid.New(), customerID, req.MerchantCustomerID, req.MaxAmount, nullableAmount(req.MonthlyLimit), nullableString(req.Reference)))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create mandate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, mandate)
+}
+
+// @Summary List mandates
+// @Description List the mandates a customer has granted
+// @Tags mandates
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} Mandate "Mandates"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/mandates [get]
+func ListMandates(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+mandateColumns+" FROM mandates WHERE customer_id = $1 ORDER BY created_at",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch mandates"})
+		return
+	}
+	defer rows.Close()
+
+	mandates := []Mandate{}
+	for rows.Next() {
+		mandate, err := scanMandate(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan mandate"})
+			return
+		}
+		mandates = append(mandates, mandate)
+	}
+
+	c.JSON(http.StatusOK, mandates)
+}
+
+// mandateParams parses the customer and mandate path parameters
+func mandateParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	mandateID, err := uuid.Parse(c.Param("mandate_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid mandate ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return customerID, mandateID, true
+}
+
+// @Summary Get a mandate
+// @Description Get a single mandate granted by the customer
+// @Tags mandates
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param mandate_id path string true "Mandate ID" format(uuid)
+// @Success 200 {object} Mandate "Mandate"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Mandate not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/mandates/{mandate_id} [get]
+func GetMandate(c *gin.Context) {
+	customerID, mandateID, ok := mandateParams(c)
+	if !ok {
+		return
+	}
+
+	mandate, err := scanMandate(db.QueryRow(c.Request.Context(),
+		"SELECT "+mandateColumns+" FROM mandates WHERE id = $1 AND customer_id = $2",
+		mandateID, customerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Mandate not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get mandate"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, mandate)
+}
+
+// @Summary Update mandate limits
+// @Description Change the limits or reference of an active mandate. The merchant cannot be changed.
+// @Tags mandates
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param mandate_id path string true "Mandate ID" format(uuid)
+// @Param mandate body MandateRequest true "New limits"
+// @Success 200 {object} Mandate "Mandate updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Active mandate not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/mandates/{mandate_id} [put]
+func UpdateMandate(c *gin.Context) {
+	customerID, mandateID, ok := mandateParams(c)
+	if !ok {
+		return
+	}
+
+	var req MandateRequest
+	if !bindRequest(c, &req, "Invalid input: max_amount (> 0) is required") {
+		return
+	}
+	if req.MonthlyLimit > 0 && req.MonthlyLimit < req.MaxAmount {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: monthly_limit must be at least max_amount"})
+		return
+	}
+
+	mandate, err := scanMandate(db.QueryRow(c.Request.Context(),
+		"UPDATE mandates SET max_amount = $1, monthly_limit = $2, reference = $3, updated_at = NOW() WHERE id = $4 AND customer_id = $5 AND status = 'active' RETURNING "+mandateColumns,
+		req.MaxAmount, nullableAmount(req.MonthlyLimit), nullableString(req.Reference), mandateID, customerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Active mandate not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update mandate"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, mandate)
+}
+
+// @Summary Revoke a mandate
+// @Description Revoke a mandate; any later pull payments under it are rejected
+// @Tags mandates
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param mandate_id path string true "Mandate ID" format(uuid)
+// @Success 200 {object} Mandate "Mandate revoked"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Active mandate not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/mandates/{mandate_id} [delete]
+func RevokeMandate(c *gin.Context) {
+	customerID, mandateID, ok := mandateParams(c)
+	if !ok {
+		return
+	}
+
+	mandate, err := scanMandate(db.QueryRow(c.Request.Context(),
+		"UPDATE mandates SET status = 'revoked', revoked_at = NOW(), updated_at = NOW() WHERE id = $1 AND customer_id = $2 AND status = 'active' RETURNING "+mandateColumns,
+		mandateID, customerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Active mandate not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke mandate"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, mandate)
+}
+
+// mandateRejection is a pull payment refused by the mandate's terms
+type mandateRejection struct {
+	status int
+	reason string
+}
+
+// checkMandate returns a rejection when a pull would breach the mandate
+func checkMandate(ctx context.Context, tx pgx.Tx, mandate Mandate, amount float64) (*mandateRejection, error) {
+	if mandate.Status != "active" {
+		return &mandateRejection{http.StatusForbidden, "Mandate has been revoked"}, nil
+	}
+	if amount > mandate.MaxAmount {
+		return &mandateRejection{http.StatusUnprocessableEntity, fmt.Sprintf("Amount exceeds the mandate limit of %.2f per payment", mandate.MaxAmount)}, nil
+	}
+	if mandate.MonthlyLimit > 0 {
+		var collected float64
+		err := tx.QueryRow(ctx,
+			"SELECT COALESCE(SUM(amount), 0) FROM mandate_payments WHERE mandate_id = $2 AND status = 'collected' AND created_at >= "+customerMonthStart,
+			mandate.CustomerID, mandate.ID).Scan(&collected)
+		if err != nil {
+			return nil, err
+		}
+		if collected+amount > mandate.MonthlyLimit {
+			return &mandateRejection{http.StatusUnprocessableEntity, fmt.Sprintf("Amount exceeds the mandate's monthly limit of %.2f", mandate.MonthlyLimit)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// @Summary Collect a pull payment
+// @Description Pull funds from a customer under a mandate they granted to the merchant. Payments on revoked mandates or beyond the mandate's limits are rejected and recorded.
+// @Tags mandates
+// @Accept json
+// @Produce json
+// @Param payment body PullPaymentRequest true "Pull payment"
+// @Success 201 {object} PullPaymentResponse "Payment collected"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 403 {object} ErrorResponse "Mandate has been revoked, or payer refused by its account policy or limits, dormant, or needing approval"
+// @Failure 404 {object} ErrorResponse "Mandate not found"
+// @Failure 422 {object} ErrorResponse "Amount exceeds the mandate limits"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pull-payments [post]
+func CreatePullPayment(c *gin.Context) {
+	var req PullPaymentRequest
+	if !bindRequest(c, &req, "Invalid input: mandate_id, merchant_customer_id and amount (> 0) are required") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the mandate so concurrent pulls cannot both fit under the monthly limit
+	mandate, err := scanMandate(tx.QueryRow(ctx,
+		"SELECT "+mandateColumns+" FROM mandates WHERE id = $1 AND merchant_customer_id = $2 FOR UPDATE",
+		req.MandateID, req.MerchantCustomerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Mandate not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get mandate"})
+		}
+		return
+	}
+
+	rejection, err := checkMandate(ctx, tx, mandate, req.Amount)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to check mandate"})
+		return
+	}
+
+	var result transferResult
+	if rejection == nil {
+		result, err = postTransfer(ctx, tx, mandate.CustomerID, mandate.MerchantCustomerID, req.Amount, mandate.Reference)
+		var violation *ledger.ViolationError
+		if errors.Is(err, errInsufficientFunds) {
+			rejection = &mandateRejection{http.StatusBadRequest, "Insufficient balance"}
+		} else if errors.Is(err, errOverpayment) {
+			rejection = &mandateRejection{http.StatusBadRequest, "Payment exceeds the amount owed"}
+		} else if errors.Is(err, errCurrencyMismatch) {
+			rejection = &mandateRejection{http.StatusBadRequest, "Payer and payee accounts use different currencies"}
+		} else if errors.As(err, &violation) {
+			rejection = &mandateRejection{http.StatusForbidden, violation.Message}
+		} else if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post payment"})
+			return
+		}
+	}
+
+	// Record the attempt, including rejections, so both parties can see it
+	paymentID := uuid.New()
+	status := "collected"
+	var reason *string
+	var transferID *uuid.UUID
+	if rejection != nil {
+		status = "rejected"
+		reason = &rejection.reason
+	} else {
+		transferID = &result.TransferID
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO mandate_payments (id, mandate_id, amount, status, reason, transfer_id) VALUES ($1, $2, $3, $4, $5, $6)",
+		paymentID, mandate.ID, req.Amount, status, reason, transferID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record payment"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, mandate.CustomerID, mandate.MerchantCustomerID)
+
+	if rejection != nil {
+		respondError(c, rejection.status, ErrorResponse{Error: rejection.reason})
+		return
+	}
+	c.JSON(http.StatusCreated, PullPaymentResponse{
+		PaymentID:  paymentID,
+		MandateID:  mandate.ID,
+		TransferID: result.TransferID,
+		Amount:     req.Amount,
+		Status:     status,
+	})
+}
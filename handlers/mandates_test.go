@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePullPayment(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/pull-payments", CreatePullPayment)
+
+	mandateID := uuid.New()
+	payerID := uuid.New()
+	merchantID := uuid.New()
+	mandateRow := func(status string, monthlyLimit float64) *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "customer_id", "merchant_customer_id", "max_amount", "monthly_limit", "reference", "status", "created_at"}).
+			AddRow(mandateID, payerID, merchantID, float64(100), monthlyLimit, "Gym", status, time.Now())
+	}
+	expectRecorded := func(status string) {
+		mock.ExpectExec(`INSERT INTO mandate_payments \(id, mandate_id, amount, status, reason, transfer_id\)`).
+			WithArgs(pgxmock.AnyArg(), mandateID, pgxmock.AnyArg(), status, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+	}
+
+	tests := []struct {
+		name       string
+		amount     float64
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "collected",
+			amount:     50,
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM mandates WHERE id = \$1 AND merchant_customer_id = \$2 FOR UPDATE`).
+					WithArgs(mandateID, merchantID).
+					WillReturnRows(mandateRow("active", 0))
+				expectTransfer(payerID, merchantID, 500, 0, 50)
+				expectRecorded("collected")
+			},
+		},
+		{
+			name:       "revoked mandate",
+			amount:     50,
+			wantStatus: http.StatusForbidden,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM mandates`).
+					WithArgs(mandateID, merchantID).
+					WillReturnRows(mandateRow("revoked", 0))
+				expectRecorded("rejected")
+			},
+		},
+		{
+			name:       "over per-payment limit",
+			amount:     150,
+			wantStatus: http.StatusUnprocessableEntity,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM mandates`).
+					WithArgs(mandateID, merchantID).
+					WillReturnRows(mandateRow("active", 0))
+				expectRecorded("rejected")
+			},
+		},
+		{
+			name:       "over monthly limit",
+			amount:     80,
+			wantStatus: http.StatusUnprocessableEntity,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM mandates`).
+					WithArgs(mandateID, merchantID).
+					WillReturnRows(mandateRow("active", 200))
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM mandate_payments WHERE mandate_id = \$2 AND status = 'collected'`).
+					WithArgs(payerID, mandateID).
+					WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(float64(150)))
+				expectRecorded("rejected")
+			},
+		},
+		{
+			name:       "insufficient funds",
+			amount:     50,
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				mock.ExpectQuery(`FROM mandates`).
+					WithArgs(mandateID, merchantID).
+					WillReturnRows(mandateRow("active", 0))
+				expectTransferLocks(payerID, merchantID, 20, 0)
+				expectRecorded("rejected")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(map[string]interface{}{
+				"mandate_id":           mandateID,
+				"merchant_customer_id": merchantID,
+				"amount":               tt.amount,
+			})
+			req := httptest.NewRequest("POST", "/pull-payments", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevokeMandate(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.DELETE("/customers/:customer_id/mandates/:mandate_id", RevokeMandate)
+
+	mandateID := uuid.New()
+	customerID := uuid.New()
+	mock.ExpectQuery(`UPDATE mandates SET status = 'revoked'`).
+		WithArgs(mandateID, customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "merchant_customer_id", "max_amount", "monthly_limit", "reference", "status", "created_at"}).
+			AddRow(mandateID, customerID, uuid.New(), float64(100), float64(0), "", "revoked", time.Now()))
+
+	req := httptest.NewRequest("DELETE", "/customers/"+customerID.String()+"/mandates/"+mandateID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp Mandate
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "revoked", resp.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
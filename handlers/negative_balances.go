@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AllowNegativeRequest sets whether a customer's balance may go below zero
+type AllowNegativeRequest struct {
+	AllowNegative *bool  `json:"allow_negative" binding:"required" example:"true"`
+	Reason        string `json:"reason" binding:"required,min=10,max=1000" example:"Card scheme settlement account" minLength:"10" maxLength:"1000"`
+}
+
+// AllowNegativeResponse describes a customer's negative balance setting
+// @Description Negative balance setting
+type AllowNegativeResponse struct {
+	CustomerID    uuid.UUID `json:"customer_id" format:"uuid"`
+	AllowNegative bool      `json:"allow_negative" example:"true"`
+	Balance       float64   `json:"balance" example:"-2500"`
+	Actor         string    `json:"actor" example:"jane.doe"`
+}
+
+// @Summary Allow or forbid a negative balance
+// @Description Let an internal or settlement account go below zero: postings and transfers debiting it skip the insufficient-balance check, and each one that takes it below zero is recorded in the audit log as balance.overdrawn. The change itself is audited under the calling operator. The flag cannot be cleared while the balance is negative.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator making the change"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param setting body AllowNegativeRequest true "Setting"
+// @Success 200 {object} AllowNegativeResponse "Setting updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Balance is negative"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/allow-negative [put]
+func SetAllowNegative(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req AllowNegativeRequest
+	if !bindRequest(c, &req, "Invalid input: allow_negative and reason (at least 10 characters) are required") {
+		return
+	}
+	actor := c.GetString(middleware.ActorKey)
+	ctx := c.Request.Context()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var previous bool
+	resp := AllowNegativeResponse{CustomerID: customerID, AllowNegative: *req.AllowNegative, Actor: actor}
+	if err := tx.QueryRow(ctx,
+		"SELECT balance, allow_negative FROM customers WHERE id = $1 FOR UPDATE",
+		customerID).Scan(&resp.Balance, &previous); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get customer"})
+		}
+		return
+	}
+	if !resp.AllowNegative && resp.Balance < 0 {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Balance is negative; bring it to zero before forbidding a negative balance", Code: "balance_negative"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE customers SET allow_negative = $1 WHERE id = $2",
+		resp.AllowNegative, customerID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update customer"})
+		return
+	}
+	if err := recordAudit(ctx, tx, actor, "customer.allow_negative_changed", "customer", customerID, &customerID, map[string]interface{}{
+		"allow_negative": resp.AllowNegative,
+		"previous":       previous,
+		"reason":         req.Reason,
+		"balance":        resp.Balance,
+	}); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to write audit log"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAllowNegative(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.PUT("/admin/customers/:customer_id/allow-negative", func(c *gin.Context) {
+		c.Set(middleware.ActorKey, "jane")
+	}, SetAllowNegative)
+
+	customerID := uuid.New()
+	expectLocked := func(balance float64, allowNegative bool) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance, allow_negative FROM customers WHERE id = \$1 FOR UPDATE`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"balance", "allow_negative"}).AddRow(balance, allowNegative))
+	}
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "allowed and audited",
+			payload:    map[string]interface{}{"allow_negative": true, "reason": "Card scheme settlement account"},
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				expectLocked(100, false)
+				mock.ExpectExec(`UPDATE customers SET allow_negative = \$1 WHERE id = \$2`).
+					WithArgs(true, customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO audit_log`).
+					WithArgs(pgxmock.AnyArg(), "jane", "customer.allow_negative_changed", "customer", customerID, &customerID, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "cannot forbid while negative",
+			payload:    map[string]interface{}{"allow_negative": false, "reason": "Settlement account retired"},
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				expectLocked(-20, true)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "flag is required",
+			payload:    map[string]interface{}{"reason": "Card scheme settlement account"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("PUT", "/admin/customers/"+customerID.String()+"/allow-negative", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	"ledger-service/jobqueue"
+	"ledger-service/notify"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// NotificationPreferences represents a customer's SMS notification settings
+// @Description Customer SMS notification settings
+type NotificationPreferences struct {
+	CustomerID  uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	PhoneNumber string    `json:"phone_number" example:"+15551234567"`
+	SMSOptIn    bool      `json:"sms_opt_in" example:"true"`
+}
+
+// NotificationPreferencesRequest represents a request to update SMS notification settings
+type NotificationPreferencesRequest struct {
+	PhoneNumber string `json:"phone_number" example:"+15551234567"`
+	SMSOptIn    bool   `json:"sms_opt_in" example:"true"`
+}
+
+var (
+	notifier *notify.Notifier
+
+	e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+)
+
+// InitNotifier sets the notifier used for transaction alerts. A nil notifier disables alerts.
+func InitNotifier(n *notify.Notifier) {
+	notifier = n
+}
+
+// SMSNotificationJob is the payload of a notification.sms job. The phone
+// number is looked up when the message is sent, so it is never copied out
+// of the customer's record and an opt-out made meanwhile is respected.
+type SMSNotificationJob struct {
+	Message string `json:"message"`
+}
+
+// notifyTransaction queues the alerts a posted transaction triggers, so SMS
+// delivery never delays the API response and a failed send is retried
+func notifyTransaction(ctx context.Context, ev notify.TransactionEvent) {
+	if notifier == nil || !ev.OptIn || ev.PhoneNumber == "" {
+		return
+	}
+	for _, msg := range notifier.Messages(ev) {
+		if err := alertCustomer(ctx, db, ev.CustomerID, msg); err != nil {
+			log.Printf("Failed to queue SMS to customer %s: %v", ev.CustomerID, err)
+		}
+	}
+}
+
+// alertCustomer queues a one-off SMS to the customer, sent if they have
+// opted in. Callers with an open transaction pass it so the alert is only
+// sent when what it reports commits.
+func alertCustomer(ctx context.Context, q execer, customerID uuid.UUID, msg string) error {
+	if notifier == nil {
+		return nil
+	}
+	_, err := enqueueJob(ctx, q, JobSMSNotification, &customerID, SMSNotificationJob{Message: msg})
+	return err
+}
+
+// runSMSNotification sends the message of a notification.sms job to the
+// customer's phone. Nothing is sent to a customer who has opted out or is
+// over the rate limit, and the job still succeeds.
+func runSMSNotification(ctx context.Context, job jobqueue.Job) (interface{}, error) {
+	var payload SMSNotificationJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || job.CustomerID == nil {
+		return nil, jobqueue.Permanent(fmt.Errorf("invalid SMS job: %v", err))
+	}
+	if notifier == nil {
+		return nil, jobqueue.Permanent(errors.New("SMS notifications are not configured"))
+	}
+	phone, optIn, err := loadSMSContact(ctx, db, *job.CustomerID)
+	if err == pgx.ErrNoRows {
+		return nil, jobqueue.Permanent(errors.New("customer not found"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !optIn || phone == nil || *phone == "" {
+		return map[string]interface{}{"sent": false, "reason": "opted_out"}, nil
+	}
+	err = notifier.Send(ctx, *job.CustomerID, *phone, payload.Message)
+	if errors.Is(err, notify.ErrRateLimited) {
+		return map[string]interface{}{"sent": false, "reason": "rate_limited"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"sent": true}, nil
+}
+
+// @Summary Get notification preferences
+// @Description Get the SMS notification settings for a customer
+// @Tags notifications
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {object} NotificationPreferences "Notification preferences"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/notifications [get]
+func GetNotificationPreferences(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	prefs := NotificationPreferences{CustomerID: customerID}
+	phone, optIn, err := loadSMSContact(c.Request.Context(), db, customerID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get notification preferences"})
+		}
+		return
+	}
+	prefs.SMSOptIn = optIn
+	if phone != nil {
+		prefs.PhoneNumber = *phone
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// @Summary Update notification preferences
+// @Description Set the phone number and SMS opt-in flag for a customer
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param preferences body NotificationPreferencesRequest true "Notification preferences"
+// @Success 200 {object} NotificationPreferences "Notification preferences updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/notifications [put]
+func UpdateNotificationPreferences(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req NotificationPreferencesRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: phone_number and sms_opt_in are expected"})
+		return
+	}
+	if req.PhoneNumber != "" && !e164Pattern.MatchString(req.PhoneNumber) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: phone_number must be in E.164 format"})
+		return
+	}
+	if req.SMSOptIn && req.PhoneNumber == "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: phone_number is required to opt in to SMS"})
+		return
+	}
+
+	phone, err := piiKeys.Encrypt(req.PhoneNumber)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to encrypt phone number"})
+		return
+	}
+	tag, err := db.Exec(c.Request.Context(),
+		"UPDATE customers SET phone_number = $1, sms_opt_in = $2 WHERE id = $3",
+		nullableString(phone), req.SMSOptIn, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update notification preferences"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationPreferences{
+		CustomerID:  customerID,
+		PhoneNumber: req.PhoneNumber,
+		SMSOptIn:    req.SMSOptIn,
+	})
+}
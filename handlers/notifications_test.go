@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/jobqueue"
+	"ledger-service/notify"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.PUT("/customers/:customer_id/notifications", UpdateNotificationPreferences)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name       string
+		customerID uuid.UUID
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "opt in with phone number",
+			customerID: customerID,
+			payload: map[string]interface{}{
+				"phone_number": "+15551234567",
+				"sms_opt_in":   true,
+			},
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				phone := "+15551234567"
+				mock.ExpectExec(`UPDATE customers SET phone_number = \$1, sms_opt_in = \$2 WHERE id = \$3`).
+					WithArgs(&phone, true, customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			},
+		},
+		{
+			name:       "invalid phone number",
+			customerID: customerID,
+			payload: map[string]interface{}{
+				"phone_number": "555-1234",
+				"sms_opt_in":   true,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "opt in without phone number",
+			customerID: customerID,
+			payload: map[string]interface{}{
+				"sms_opt_in": true,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "non-existent customer",
+			customerID: uuid.New(),
+			payload: map[string]interface{}{
+				"sms_opt_in": false,
+			},
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectExec(`UPDATE customers SET phone_number = \$1, sms_opt_in = \$2 WHERE id = \$3`).
+					WithArgs(pgxmock.AnyArg(), false, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("PUT", "/customers/"+tt.customerID.String()+"/notifications", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetNotificationPreferences(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/notifications", GetNotificationPreferences)
+
+	customerID := uuid.New()
+	phone := "+15551234567"
+
+	mock.ExpectQuery(`SELECT phone_number, sms_opt_in FROM customers WHERE id = \$1`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"phone_number", "sms_opt_in"}).AddRow(&phone, true))
+
+	req := httptest.NewRequest("GET", "/customers/"+customerID.String()+"/notifications", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var prefs NotificationPreferences
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &prefs))
+	assert.Equal(t, phone, prefs.PhoneNumber)
+	assert.True(t, prefs.SMSOptIn)
+
+	missing := uuid.New()
+	mock.ExpectQuery(`SELECT phone_number, sms_opt_in FROM customers WHERE id = \$1`).
+		WithArgs(missing).
+		WillReturnError(pgx.ErrNoRows)
+
+	req = httptest.NewRequest("GET", "/customers/"+missing.String()+"/notifications", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+type recordingSMS struct{ sent []string }
+
+func (p *recordingSMS) SendSMS(ctx context.Context, to, body string) error {
+	p.sent = append(p.sent, to+": "+body)
+	return nil
+}
+
+func TestRunSMSNotification(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	provider := &recordingSMS{}
+	InitNotifier(notify.NewNotifier(provider, notify.NewRateLimiter(1, time.Hour), 0, 0))
+	defer InitNotifier(nil)
+
+	customerID := uuid.New()
+	phone := "+15551234567"
+	job := jobqueue.Job{ID: uuid.New(), Kind: JobSMSNotification, CustomerID: &customerID, Payload: json.RawMessage(`{"message":"Low balance"}`)}
+	expectContact := func(phone *string, optIn bool) {
+		mock.ExpectQuery(`SELECT phone_number, sms_opt_in FROM customers WHERE id = \$1`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"phone_number", "sms_opt_in"}).AddRow(phone, optIn))
+	}
+
+	// The contact is looked up when the job runs, so opting out meanwhile counts
+	expectContact(&phone, false)
+	result, err := runSMSNotification(context.Background(), job)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sent": false, "reason": "opted_out"}, result)
+
+	expectContact(&phone, true)
+	result, err = runSMSNotification(context.Background(), job)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sent": true}, result)
+	assert.Equal(t, []string{"+15551234567: Low balance"}, provider.sent)
+
+	expectContact(&phone, true)
+	result, err = runSMSNotification(context.Background(), job)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sent": false, "reason": "rate_limited"}, result)
+
+	mock.ExpectQuery(`SELECT phone_number, sms_opt_in FROM customers WHERE id = \$1`).
+		WithArgs(customerID).
+		WillReturnError(pgx.ErrNoRows)
+	_, err = runSMSNotification(context.Background(), job)
+	assert.True(t, jobqueue.IsPermanent(err))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/jobqueue"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TransactionEventData is the payload of transaction.posted, transaction.held
+// and transaction.rejected events
+type TransactionEventData struct {
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	Type          string    `json:"type" example:"purchase"`
+	Amount        float64   `json:"amount" example:"25.5"`
+	Status        string    `json:"status" example:"posted"`
+}
+
+// TransferEventData is the payload of transfer.completed events
+type TransferEventData struct {
+	TransferID     uuid.UUID `json:"transfer_id" format:"uuid"`
+	FromCustomerID uuid.UUID `json:"from_customer_id" format:"uuid"`
+	ToCustomerID   uuid.UUID `json:"to_customer_id" format:"uuid"`
+	Amount         float64   `json:"amount" example:"40"`
+	Reference      string    `json:"reference,omitempty" example:"Rent"`
+	// BatchID is shared by the transfers of a split transfer
+	BatchID *uuid.UUID `json:"batch_id,omitempty" format:"uuid"`
+}
+
+// BalanceAdjustedEventData is the payload of balance.adjusted events
+type BalanceAdjustedEventData struct {
+	AdjustmentID  uuid.UUID `json:"adjustment_id" format:"uuid"`
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	Type          string    `json:"type" example:"adjustment_credit"`
+	Amount        float64   `json:"amount" example:"15"`
+	ReasonCode    string    `json:"reason_code" example:"goodwill"`
+	Balance       float64   `json:"balance" example:"115"`
+}
+
+// CustomerCreatedEventData is the payload of customer.created events
+type CustomerCreatedEventData struct {
+	Name        string  `json:"name" example:"Jane Doe"`
+	AccountType string  `json:"account_type" example:"checking"`
+	Balance     float64 `json:"balance" example:"100"`
+	Currency    string  `json:"currency" example:"USD"`
+}
+
+// AccountDormantEventData is the payload of account.dormant events
+type AccountDormantEventData struct {
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty" format:"date-time"`
+	// Fee is the dormancy fee charged, zero when none was
+	Fee          float64 `json:"fee" example:"5"`
+	DebitsFrozen bool    `json:"debits_frozen" example:"true"`
+}
+
+// AccountReactivatedEventData is the payload of account.reactivated events
+type AccountReactivatedEventData struct {
+	DormantSince time.Time `json:"dormant_since" format:"date-time"`
+	Actor        string    `json:"actor" example:"alice"`
+}
+
+// transactionEventType maps a transaction status onto the event announcing it
+func transactionEventType(status string) string {
+	switch status {
+	case "posted":
+		return events.TransactionPosted
+	case "rejected":
+		return events.TransactionRejected
+	default:
+		return events.TransactionHeld
+	}
+}
+
+// outboxBatchSize is how many events the relay claims per database transaction
+const outboxBatchSize = 100
+
+// outboxLockKey is the advisory lock that keeps a single relay running across
+// instances, so events leave in the order they were written
+const outboxLockKey = 0x6f7574626f78
+
+var eventPublisher events.Publisher
+
+// InitEventPublisher sets the message bus the outbox relay publishes to. A nil
+// publisher leaves events to webhook delivery only.
+func InitEventPublisher(p events.Publisher) {
+	eventPublisher = p
+}
+
+// enqueueEvent writes an event to the outbox. Callers pass their open
+// transaction so the event is recorded if and only if the change commits.
+func enqueueEvent(ctx context.Context, q execer, eventType string, customerID *uuid.UUID, data interface{}) error {
+	ev, err := events.New(eventType, customerID, data)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		"INSERT INTO outbox (id, event_type, customer_id, payload, created_at) VALUES ($1, $2, $3, $4, $5)",
+		ev.ID, ev.Type, ev.CustomerID, []byte(ev.Data), ev.OccurredAt)
+	return err
+}
+
+// RunOutboxRelay publishes pending outbox events every interval until ctx is cancelled
+func RunOutboxRelay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := RelayOutbox(ctx); err != nil {
+			log.Printf("Outbox relay failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Relayed %d events", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOutbox publishes pending events oldest first and returns how many were
+// sent. An event is marked published only after the bus acknowledges it, so a
+// crash or failed publish means it is sent again on the next run; a failed
+// publish also stops the run so later events never overtake it.
+func RelayOutbox(ctx context.Context) (int, error) {
+	relayed := 0
+	for {
+		n, err := relayOutboxBatch(ctx)
+		relayed += n
+		if err != nil || n < outboxBatchSize {
+			return relayed, err
+		}
+	}
+}
+
+func relayOutboxBatch(ctx context.Context) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT id, event_type, customer_id, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY seq LIMIT $1",
+		outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var pending []events.Event
+	for rows.Next() {
+		var ev events.Event
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.CustomerID, &payload, &ev.OccurredAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ev.Data = payload
+		ev.OccurredAt = ev.OccurredAt.UTC()
+		pending = append(pending, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n, publishErr := publishEvents(ctx, pending)
+	if publishErr != nil && n < len(pending) {
+		publishErr = fmt.Errorf("publish event %s: %w", pending[n].ID, publishErr)
+		if _, err := tx.Exec(ctx,
+			"UPDATE outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2",
+			publishErr.Error(), pending[n].ID); err != nil {
+			return 0, err
+		}
+	}
+	published := make([]uuid.UUID, n)
+	for i, ev := range pending[:n] {
+		published[i] = ev.ID
+	}
+	if len(published) > 0 {
+		if _, err := tx.Exec(ctx,
+			"UPDATE outbox SET published_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = ANY($1)",
+			published); err != nil {
+			return 0, err
+		}
+	}
+	for _, ev := range pending[:n] {
+		if err := enqueueWebhookDelivery(ctx, tx, ev); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(published), publishErr
+}
+
+// publishEvents sends evs to the bus in order, in batches when the publisher
+// supports them, and returns how many leading events were accepted
+func publishEvents(ctx context.Context, evs []events.Event) (int, error) {
+	if eventPublisher == nil || len(evs) == 0 {
+		return len(evs), nil
+	}
+	if bp, ok := eventPublisher.(events.BatchPublisher); ok {
+		return bp.PublishBatch(ctx, evs)
+	}
+	for i, ev := range evs {
+		if err := eventPublisher.Publish(ctx, ev); err != nil {
+			return i, err
+		}
+	}
+	return len(evs), nil
+}
+
+// WebhookDeliveryJob is the payload of a webhook.delivery job
+type WebhookDeliveryJob struct {
+	EventID uuid.UUID `json:"event_id"`
+}
+
+// enqueueWebhookDelivery queues the delivery of ev to the webhooks
+// subscribed to its type, if there are any, in the relay's transaction
+func enqueueWebhookDelivery(ctx context.Context, tx pgx.Tx, ev events.Event) error {
+	payload, err := json.Marshal(WebhookDeliveryJob{EventID: ev.ID})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO queue_jobs (id, kind, customer_id, payload, max_attempts)
+		SELECT $1, $2, $3, $4, $5
+		WHERE EXISTS (SELECT 1 FROM webhooks WHERE enabled AND (cardinality(event_types) = 0 OR $6 = ANY(event_types)))`,
+		uuid.New(), JobWebhookDelivery, ev.CustomerID, payload, jobMaxAttempts[JobWebhookDelivery], ev.Type)
+	return err
+}
+
+// runWebhookDelivery sends an event to every enabled webhook subscribed to
+// its type that has not had it yet, and records each attempt. The job fails,
+// to be retried, while any endpoint has not taken the event; endpoints that
+// have are not sent it again.
+func runWebhookDelivery(ctx context.Context, job jobqueue.Job) (interface{}, error) {
+	var payload WebhookDeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, jobqueue.Permanent(err)
+	}
+	var ev events.Event
+	var data []byte
+	err := db.QueryRow(ctx,
+		"SELECT id, event_type, customer_id, payload, created_at FROM outbox WHERE id = $1",
+		payload.EventID).Scan(&ev.ID, &ev.Type, &ev.CustomerID, &data, &ev.OccurredAt)
+	if err == pgx.ErrNoRows {
+		return nil, jobqueue.Permanent(fmt.Errorf("event %s not found", payload.EventID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	ev.Data = data
+	ev.OccurredAt = ev.OccurredAt.UTC()
+
+	rows, err := db.Query(ctx,
+		`SELECT id, url, secret FROM webhooks w
+		WHERE enabled AND (cardinality(event_types) = 0 OR $1 = ANY(event_types))
+			AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.webhook_id = w.id AND d.event_id = $2 AND d.delivered AND d.replay_id IS NULL)
+		ORDER BY created_at`,
+		ev.Type, ev.ID)
+	if err != nil {
+		return nil, err
+	}
+	type target struct {
+		id          uuid.UUID
+		url, secret string
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.url, &t.secret); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	delivered := 0
+	var lastErr string
+	for _, t := range targets {
+		d := webhookSender.Send(ctx, t.url, t.secret, ev)
+		if _, err := db.Exec(ctx,
+			"INSERT INTO webhook_deliveries (id, webhook_id, event_id, delivered, status_code, duration_ms, error) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			uuid.New(), t.id, ev.ID, d.Delivered, d.StatusCode, d.DurationMS, nullableString(d.Error)); err != nil {
+			log.Printf("Failed to record webhook delivery for event %s: %v", ev.ID, err)
+		}
+		if d.Delivered {
+			delivered++
+		} else {
+			lastErr = fmt.Sprintf("%s: %s", t.url, d.Error)
+		}
+	}
+	if delivered < len(targets) {
+		return nil, fmt.Errorf("%d of %d webhooks did not take the event, last %s", len(targets)-delivered, len(targets), lastErr)
+	}
+	return map[string]int{"delivered": delivered}, nil
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/jobqueue"
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectEvent expects an event of eventType to be written to the outbox
+func expectEvent(eventType string) {
+	mock.ExpectExec(`INSERT INTO outbox \(id, event_type, customer_id, payload, created_at\)`).
+		WithArgs(pgxmock.AnyArg(), eventType, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+}
+
+type fakePublisher struct {
+	published []events.Event
+	failOn    uuid.UUID
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, ev events.Event) error {
+	if ev.ID == f.failOn {
+		return errors.New("bus unavailable")
+	}
+	f.published = append(f.published, ev)
+	return nil
+}
+
+func (f *fakePublisher) Close() error { return nil }
+
+// fakeBatchPublisher accepts events in batches, all of them unless failOn is set
+type fakeBatchPublisher struct {
+	fakePublisher
+	batches int
+}
+
+func (f *fakeBatchPublisher) PublishBatch(ctx context.Context, evs []events.Event) (int, error) {
+	f.batches++
+	for i, ev := range evs {
+		if err := f.Publish(ctx, ev); err != nil {
+			return i, err
+		}
+	}
+	return len(evs), nil
+}
+
+var outboxRowColumns = []string{"id", "event_type", "customer_id", "payload", "created_at"}
+
+func TestRelayOutbox(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+	defer InitEventPublisher(nil)
+
+	customerID := uuid.New()
+	first, second := uuid.New(), uuid.New()
+	expectPending := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(\$1\)`).
+			WithArgs(outboxLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(`SELECT id, event_type, customer_id, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY seq LIMIT \$1`).
+			WithArgs(outboxBatchSize).
+			WillReturnRows(pgxmock.NewRows(outboxRowColumns).
+				AddRow(first, events.TransactionPosted, &customerID, []byte(`{"amount":10}`), time.Now()).
+				AddRow(second, events.TransferCompleted, &customerID, []byte(`{"amount":5}`), time.Now()))
+	}
+	// Deliveries are queued with the events, for the webhooks subscribed
+	expectDelivery := func(eventType string, eventID uuid.UUID) {
+		mock.ExpectExec(`INSERT INTO queue_jobs \(id, kind, customer_id, payload, max_attempts\)\s+SELECT .* WHERE EXISTS \(SELECT 1 FROM webhooks WHERE enabled`).
+			WithArgs(pgxmock.AnyArg(), JobWebhookDelivery, &customerID, []byte(`{"event_id":"`+eventID.String()+`"}`), 8, eventType).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+
+	t.Run("publishes in order", func(t *testing.T) {
+		publisher := &fakePublisher{}
+		InitEventPublisher(publisher)
+
+		expectPending()
+		mock.ExpectExec(`UPDATE outbox SET published_at = NOW\(\)`).
+			WithArgs([]uuid.UUID{first, second}).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+		expectDelivery(events.TransactionPosted, first)
+		expectDelivery(events.TransferCompleted, second)
+		mock.ExpectCommit()
+
+		n, err := RelayOutbox(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Len(t, publisher.published, 2)
+		assert.Equal(t, first, publisher.published[0].ID)
+		assert.JSONEq(t, `{"amount":10}`, string(publisher.published[0].Data))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed publish stops the batch", func(t *testing.T) {
+		publisher := &fakePublisher{failOn: second}
+		InitEventPublisher(publisher)
+
+		expectPending()
+		mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, last_error = \$1 WHERE id = \$2`).
+			WithArgs(pgxmock.AnyArg(), second).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE outbox SET published_at = NOW\(\)`).
+			WithArgs([]uuid.UUID{first}).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectDelivery(events.TransactionPosted, first)
+		mock.ExpectCommit()
+
+		n, err := RelayOutbox(context.Background())
+		assert.ErrorContains(t, err, "bus unavailable")
+		assert.Equal(t, 1, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("batch publisher sends the batch at once", func(t *testing.T) {
+		publisher := &fakeBatchPublisher{fakePublisher: fakePublisher{failOn: second}}
+		InitEventPublisher(publisher)
+
+		expectPending()
+		mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, last_error = \$1 WHERE id = \$2`).
+			WithArgs(pgxmock.AnyArg(), second).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE outbox SET published_at = NOW\(\)`).
+			WithArgs([]uuid.UUID{first}).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectDelivery(events.TransactionPosted, first)
+		mock.ExpectCommit()
+
+		n, err := RelayOutbox(context.Background())
+		assert.ErrorContains(t, err, "publish event "+second.String())
+		assert.Equal(t, 1, n)
+		assert.Equal(t, 1, publisher.batches)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another instance holds the lock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+			WithArgs(outboxLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectRollback()
+
+		n, err := RelayOutbox(context.Background())
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRunWebhookDelivery(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	var delivered []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, webhook.Verify("whsec_test", r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()))
+		delivered = append(delivered, r.Header.Get(webhook.EventHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	customerID := uuid.New()
+	eventID := uuid.New()
+	up, down := uuid.New(), uuid.New()
+	job := jobqueue.Job{ID: uuid.New(), Kind: JobWebhookDelivery, Payload: []byte(`{"event_id":"` + eventID.String() + `"}`), Attempts: 1, MaxAttempts: 8}
+	expectEvent := func() {
+		mock.ExpectQuery(`SELECT id, event_type, customer_id, payload, created_at FROM outbox WHERE id = \$1`).
+			WithArgs(eventID).
+			WillReturnRows(pgxmock.NewRows(outboxRowColumns).
+				AddRow(eventID, events.TransactionPosted, &customerID, []byte(`{"amount":10}`), time.Now()))
+	}
+
+	// Endpoints that already took the event are left out by the query
+	expectEvent()
+	mock.ExpectQuery(`SELECT id, url, secret FROM webhooks w\s+WHERE enabled .* AND NOT EXISTS \(SELECT 1 FROM webhook_deliveries d`).
+		WithArgs(events.TransactionPosted, eventID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "url", "secret"}).
+			AddRow(up, server.URL+"/up", "whsec_test").
+			AddRow(down, server.URL+"/down", "whsec_test"))
+	mock.ExpectExec(`INSERT INTO webhook_deliveries`).
+		WithArgs(pgxmock.AnyArg(), up, eventID, true, http.StatusOK, pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO webhook_deliveries`).
+		WithArgs(pgxmock.AnyArg(), down, eventID, false, http.StatusServiceUnavailable, pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	_, err = runWebhookDelivery(context.Background(), job)
+	assert.ErrorContains(t, err, "1 of 2 webhooks did not take the event")
+	assert.Equal(t, []string{events.TransactionPosted}, delivered)
+
+	// The retry only sends to the endpoint that was down
+	expectEvent()
+	mock.ExpectQuery(`SELECT id, url, secret FROM webhooks w`).
+		WithArgs(events.TransactionPosted, eventID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "url", "secret"}).AddRow(up, server.URL+"/up", "whsec_test"))
+	mock.ExpectExec(`INSERT INTO webhook_deliveries`).
+		WithArgs(pgxmock.AnyArg(), up, eventID, true, http.StatusOK, pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	result, err := runWebhookDelivery(context.Background(), job)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"delivered": 1}, result)
+
+	// An event that is gone cannot be delivered however often it is tried
+	mock.ExpectQuery(`FROM outbox WHERE id = \$1`).
+		WithArgs(eventID).
+		WillReturnError(pgx.ErrNoRows)
+	_, err = runWebhookDelivery(context.Background(), job)
+	assert.ErrorContains(t, err, "not found")
+	assert.True(t, jobqueue.IsPermanent(err))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
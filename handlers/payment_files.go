@@ -0,0 +1,707 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ledger-service/iso20022"
+	"ledger-service/ledger"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	paymentDebtor *iso20022.Party
+)
+
+// InitPaymentFiles sets the account credit transfers are paid from; nil
+// turns credit transfers and payment files off
+func InitPaymentFiles(debtor *iso20022.Party) {
+	paymentDebtor = debtor
+}
+
+// maxPaymentFileTransfers caps the transfers written to one file
+const maxPaymentFileTransfers = 5000
+
+// CreditTransferRequest represents the payload for a credit transfer to a
+// bank account outside the ledger
+type CreditTransferRequest struct {
+	Amount       float64 `json:"amount" binding:"required,money" example:"125.5" minimum:"0.01"`
+	CreditorName string  `json:"creditor_name" binding:"required,max=70" example:"Jane Doe" maxLength:"70"`
+	CreditorIBAN string  `json:"creditor_iban" binding:"required,max=42" example:"DE89370400440532013000"`
+	// CreditorBIC is optional for SEPA payments
+	CreditorBIC string `json:"creditor_bic,omitempty" example:"COBADEFFXXX"`
+	// Reference is passed to the creditor as the remittance information
+	Reference string `json:"reference,omitempty" binding:"max=140" example:"Invoice 42" maxLength:"140"`
+}
+
+// CreditTransfer is a payment to a bank account outside the ledger, sent
+// to the bank in a payment file
+// @Description A credit transfer to an external bank account, debited when created and sent to the bank in a pain.001 payment file
+type CreditTransfer struct {
+	ID            uuid.UUID `json:"transfer_id" format:"uuid"`
+	CustomerID    uuid.UUID `json:"customer_id" format:"uuid"`
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	Amount        float64   `json:"amount" example:"125.5"`
+	Currency      string    `json:"currency" example:"EUR"`
+	CreditorName  string    `json:"creditor_name" example:"Jane Doe"`
+	CreditorIBAN  string    `json:"creditor_iban" example:"DE89370400440532013000"`
+	CreditorBIC   string    `json:"creditor_bic,omitempty" example:"COBADEFFXXX"`
+	Reference     string    `json:"reference,omitempty" example:"Invoice 42"`
+	// EndToEndID identifies the payment in the file and the bank's reports
+	EndToEndID string `json:"end_to_end_id" example:"5b1f0e6c2d7a4c1e9f3b8a6d4c2e0f1a"`
+	// Status is pending until the transfer is written to a file, exported
+	// until the bank reports on it, then accepted, settled or rejected. A
+	// rejected transfer's debit is reversed.
+	Status        string     `json:"status" example:"pending" enums:"pending,exported,accepted,settled,rejected"`
+	StatusReason  string     `json:"status_reason,omitempty" example:"AC01 Incorrect account number"`
+	PaymentFileID *uuid.UUID `json:"payment_file_id,omitempty" format:"uuid"`
+	ReversalID    *uuid.UUID `json:"reversal_transaction_id,omitempty" format:"uuid"`
+	CreatedAt     string     `json:"created_at" format:"date-time"`
+}
+
+// PaymentFileRequest represents the payload for generating a payment file
+type PaymentFileRequest struct {
+	// Currency limits the file to transfers in one currency
+	Currency string `json:"currency,omitempty" binding:"omitempty,len=3" example:"EUR"`
+	// ExecutionDate is when the bank should pay; today when empty
+	ExecutionDate string `json:"execution_date,omitempty" example:"2025-04-09" format:"date"`
+}
+
+// PaymentFile is a pain.001 file of credit transfers and what the bank
+// reported on it
+// @Description A pain.001 credit transfer file and its status from the bank's pain.002 reports
+type PaymentFile struct {
+	ID        uuid.UUID `json:"file_id" format:"uuid"`
+	MessageID string    `json:"message_id" example:"9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d"`
+	// Status is generated until every transfer has been reported on, then
+	// accepted, partially_accepted or rejected
+	Status        string                `json:"status" example:"generated" enums:"generated,accepted,partially_accepted,rejected"`
+	Transfers     int                   `json:"transfers" example:"12"`
+	ControlSum    float64               `json:"control_sum" example:"2210.25"`
+	ExecutionDate string                `json:"execution_date" format:"date"`
+	CreatedAt     string                `json:"created_at" format:"date-time"`
+	Reports       []PaymentStatusReport `json:"reports,omitempty"`
+}
+
+// PaymentStatusReport is a pain.002 report received on a file
+type PaymentStatusReport struct {
+	MessageID   string `json:"message_id" example:"STS-20250409-1"`
+	GroupStatus string `json:"group_status,omitempty" example:"PART"`
+	ReceivedAt  string `json:"received_at" format:"date-time"`
+}
+
+// PaymentStatusResult summarizes applying a pain.002 report
+type PaymentStatusResult struct {
+	FileID     uuid.UUID `json:"file_id" format:"uuid"`
+	FileStatus string    `json:"file_status" example:"partially_accepted"`
+	Accepted   int       `json:"accepted" example:"10"`
+	Settled    int       `json:"settled" example:"1"`
+	Rejected   int       `json:"rejected" example:"1"`
+	// Unknown lists end-to-end IDs the report names that are not in the file
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+// paymentFilesEnabled responds 501 when no debtor account is configured
+func paymentFilesEnabled(c *gin.Context) bool {
+	if paymentDebtor == nil {
+		respondError(c, http.StatusNotImplemented, ErrorResponse{Error: "Credit transfers are not configured"})
+		return false
+	}
+	return true
+}
+
+// endToEndID is a transfer's ID as it goes in a file: its 32 hex digits
+func endToEndID(id uuid.UUID) string {
+	return strings.ReplaceAll(id.String(), "-", "")
+}
+
+const creditTransferColumns = `id, customer_id, transaction_id, amount, currency, creditor_name, creditor_iban, COALESCE(creditor_bic, ''),
+	COALESCE(reference, ''), end_to_end_id, status, COALESCE(status_reason, ''), payment_file_id, reversal_transaction_id, created_at`
+
+func scanCreditTransfer(row pgx.Row) (CreditTransfer, error) {
+	var t CreditTransfer
+	var createdAt time.Time
+	if err := row.Scan(&t.ID, &t.CustomerID, &t.TransactionID, &t.Amount, &t.Currency, &t.CreditorName, &t.CreditorIBAN, &t.CreditorBIC,
+		&t.Reference, &t.EndToEndID, &t.Status, &t.StatusReason, &t.PaymentFileID, &t.ReversalID, &createdAt); err != nil {
+		return CreditTransfer{}, err
+	}
+	t.CreatedAt = createdAt.Format(time.RFC3339)
+	return t, nil
+}
+
+// @Summary Send a credit transfer
+// @Description Debit the customer as a credit_transfer and queue a payment to a bank account outside the ledger. Queued transfers go to the bank in the next pain.001 payment file; a held debit waits for review first. When the bank rejects the payment, the debit is reversed.
+// @Tags credit transfers
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param transfer body CreditTransferRequest true "Credit transfer"
+// @Success 201 {object} CreditTransfer "Transfer queued"
+// @Success 202 {object} CreditTransfer "Transfer queued; its debit is held for review"
+// @Failure 400 {object} ErrorResponse "Invalid input, an invalid IBAN or BIC, or insufficient balance"
+// @Failure 403 {object} ErrorResponse "Debit refused by a limit"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 422 {object} ErrorResponse "Debit rejected by fraud rules"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Credit transfers are not configured"
+// @Router /customers/{customer_id}/credit-transfers [post]
+func CreateCreditTransfer(c *gin.Context) {
+	if !paymentFilesEnabled(c) {
+		return
+	}
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req CreditTransferRequest
+	if !bindRequest(c, &req, "Invalid input: amount, creditor_name and creditor_iban are required") {
+		return
+	}
+	iban := strings.ToUpper(strings.ReplaceAll(req.CreditorIBAN, " ", ""))
+	bic := strings.ToUpper(req.CreditorBIC)
+	var fields fieldErrors
+	if !iso20022.ValidIBAN(iban) {
+		fields.add("creditor_iban", "creditor_iban is not a valid IBAN")
+	}
+	if bic != "" && !iso20022.ValidBIC(bic) {
+		fields.add("creditor_bic", "creditor_bic must be an 8 or 11 character BIC")
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	ctx := c.Request.Context()
+	customer, err := ledgerStore.GetCustomer(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	currency := customer.Currency
+	if currency == "" {
+		currency = store.DefaultCurrency
+	}
+
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID: customerID,
+		Type:       "credit_transfer",
+		Amount:     req.Amount,
+		Reference:  req.Reference,
+	})
+	if err != nil {
+		var violation *ledger.ViolationError
+		switch {
+		case errors.Is(err, ledger.ErrCustomerNotFound):
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+		case errors.As(err, &violation):
+			respondError(c, http.StatusForbidden, ErrorResponse{Error: violation.Message})
+		default:
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to debit credit transfer"})
+		}
+		return
+	}
+	invalidateBalances(ctx, customerID)
+	if result.Status == ledger.StatusRejected {
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Transaction rejected by fraud rules"})
+		return
+	}
+
+	id := uuid.New()
+	transfer, err := scanCreditTransfer(db.QueryRow(ctx,
+		`INSERT INTO credit_transfers (id, customer_id, transaction_id, amount, currency, creditor_name, creditor_iban, creditor_bic, reference, end_to_end_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+creditTransferColumns,
+		id, customerID, result.TransactionID, req.Amount, currency, req.CreditorName, iban, nullableString(bic),
+		nullableString(req.Reference), endToEndID(id)))
+	if err != nil {
+		log.Printf("Failed to queue credit transfer for transaction %s: %v", result.TransactionID, err)
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to queue credit transfer", TransactionID: &result.TransactionID})
+		return
+	}
+	if result.Status != ledger.StatusPosted {
+		c.JSON(http.StatusAccepted, transfer)
+		return
+	}
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// @Summary List a customer's credit transfers
+// @Description List the customer's credit transfers, newest first, with their status from the bank
+// @Tags credit transfers
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Transfers per page" default(10)
+// @Success 200 {array} CreditTransfer "Credit transfers"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Credit transfers are not configured"
+// @Router /customers/{customer_id}/credit-transfers [get]
+func ListCreditTransfers(c *gin.Context) {
+	if !paymentFilesEnabled(c) {
+		return
+	}
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+creditTransferColumns+" FROM credit_transfers WHERE customer_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		customerID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch credit transfers"})
+		return
+	}
+	defer rows.Close()
+	transfers := []CreditTransfer{}
+	for rows.Next() {
+		t, err := scanCreditTransfer(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan credit transfer"})
+			return
+		}
+		transfers = append(transfers, t)
+	}
+	c.JSON(http.StatusOK, transfers)
+}
+
+const paymentFileColumns = "id, message_id, status, transfers, control_sum, execution_date, created_at"
+
+func scanPaymentFile(row pgx.Row) (PaymentFile, error) {
+	var f PaymentFile
+	var executionDate, createdAt time.Time
+	if err := row.Scan(&f.ID, &f.MessageID, &f.Status, &f.Transfers, &f.ControlSum, &executionDate, &createdAt); err != nil {
+		return PaymentFile{}, err
+	}
+	f.ExecutionDate = executionDate.Format(dateLayout)
+	f.CreatedAt = createdAt.Format(time.RFC3339)
+	return f, nil
+}
+
+// @Summary Generate a payment file
+// @Description Write the credit transfers waiting to be sent into a pain.001.001.03 file for the bank's batch payment channel, up to 5000 at a time, and mark them exported. Transfers whose debit is still held are left for a later file. Download the file from /admin/payment-files/{file_id}/xml.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param file body PaymentFileRequest false "Which transfers, and when to pay them"
+// @Success 201 {object} PaymentFile "Payment file generated"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 409 {object} ErrorResponse "No credit transfers are waiting"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Credit transfers are not configured"
+// @Router /admin/payment-files [post]
+func CreatePaymentFile(c *gin.Context) {
+	if !paymentFilesEnabled(c) {
+		return
+	}
+	var req PaymentFileRequest
+	if c.Request.ContentLength != 0 && !bindRequest(c, &req, "Invalid input: currency must be an ISO 4217 code") {
+		return
+	}
+	now := time.Now().UTC()
+	executionDate := now.Truncate(24 * time.Hour)
+	if req.ExecutionDate != "" {
+		d, err := time.Parse(dateLayout, req.ExecutionDate)
+		if err != nil || d.Before(executionDate) {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: execution_date must be today or later (YYYY-MM-DD)"})
+			return
+		}
+		executionDate = d
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Skipping locked rows lets two files be generated at once without
+	// either taking the other's transfers
+	rows, err := tx.Query(ctx,
+		`SELECT o.id, o.amount, o.currency, o.creditor_name, o.creditor_iban, COALESCE(o.creditor_bic, ''), COALESCE(o.reference, ''), o.end_to_end_id
+		FROM credit_transfers o JOIN transactions t ON t.id = o.transaction_id
+		WHERE o.status = 'pending' AND t.status = 'posted' AND ($1 = '' OR o.currency = $1)
+		ORDER BY o.created_at LIMIT $2
+		FOR UPDATE OF o SKIP LOCKED`,
+		strings.ToUpper(req.Currency), maxPaymentFileTransfers)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch credit transfers"})
+		return
+	}
+	var ids []uuid.UUID
+	var transfers []iso20022.CreditTransfer
+	var controlSum float64
+	for rows.Next() {
+		var id uuid.UUID
+		var t iso20022.CreditTransfer
+		if err := rows.Scan(&id, &t.Amount, &t.Currency, &t.Creditor.Name, &t.Creditor.IBAN, &t.Creditor.BIC, &t.Remittance, &t.EndToEndID); err != nil {
+			rows.Close()
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan credit transfer"})
+			return
+		}
+		ids = append(ids, id)
+		transfers = append(transfers, t)
+		controlSum += t.Amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch credit transfers"})
+		return
+	}
+	if len(transfers) == 0 {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "No credit transfers are waiting"})
+		return
+	}
+
+	fileID := uuid.New()
+	content, err := iso20022.Build(iso20022.Pain001{
+		MessageID:       endToEndID(fileID),
+		CreatedAt:       now,
+		InitiatingParty: paymentDebtor.Name,
+		Debtor:          *paymentDebtor,
+		ExecutionDate:   executionDate,
+		Transfers:       transfers,
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to build payment file: " + err.Error()})
+		return
+	}
+	file, err := scanPaymentFile(tx.QueryRow(ctx,
+		`INSERT INTO payment_files (id, message_id, transfers, control_sum, execution_date, content)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+paymentFileColumns,
+		fileID, endToEndID(fileID), len(transfers), controlSum, executionDate, string(content)))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to save payment file"})
+		return
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE credit_transfers SET status = 'exported', payment_file_id = $1, updated_at = NOW() WHERE id = ANY($2)",
+		fileID, ids); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to mark credit transfers exported"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	c.JSON(http.StatusCreated, file)
+}
+
+// @Summary List payment files
+// @Description List the payment files generated, newest first, optionally only those with a status
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param status query string false "Only files with this status" Enums(generated, accepted, partially_accepted, rejected)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Files per page" default(10)
+// @Success 200 {array} PaymentFile "Payment files"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/payment-files [get]
+func ListPaymentFiles(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "generated", "accepted", "partially_accepted", "rejected":
+	default:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status"})
+		return
+	}
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+paymentFileColumns+" FROM payment_files WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payment files"})
+		return
+	}
+	defer rows.Close()
+	files := []PaymentFile{}
+	for rows.Next() {
+		f, err := scanPaymentFile(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan payment file"})
+			return
+		}
+		files = append(files, f)
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// @Summary Get a payment file
+// @Description Get a payment file's status and the pain.002 reports received on it
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param file_id path string true "Payment file ID" format(uuid)
+// @Success 200 {object} PaymentFile "Payment file"
+// @Failure 400 {object} ErrorResponse "Invalid payment file ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Payment file not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/payment-files/{file_id} [get]
+func GetPaymentFile(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("file_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid payment file ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	file, err := scanPaymentFile(db.QueryRow(ctx, "SELECT "+paymentFileColumns+" FROM payment_files WHERE id = $1", fileID))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payment file not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payment file"})
+		return
+	}
+	rows, err := db.Query(ctx,
+		"SELECT message_id, COALESCE(group_status, ''), received_at FROM payment_status_reports WHERE file_id = $1 ORDER BY received_at",
+		fileID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch status reports"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r PaymentStatusReport
+		var receivedAt time.Time
+		if err := rows.Scan(&r.MessageID, &r.GroupStatus, &receivedAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan status report"})
+			return
+		}
+		r.ReceivedAt = receivedAt.Format(time.RFC3339)
+		file.Reports = append(file.Reports, r)
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// @Summary Download a payment file
+// @Description Download a payment file's pain.001 XML, to upload to the bank
+// @Tags admin
+// @Produce application/xml
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param file_id path string true "Payment file ID" format(uuid)
+// @Success 200 {file} file "pain.001 XML"
+// @Failure 400 {object} ErrorResponse "Invalid payment file ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Payment file not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/payment-files/{file_id}/xml [get]
+func DownloadPaymentFile(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("file_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid payment file ID"})
+		return
+	}
+	var messageID, content string
+	err = db.QueryRow(c.Request.Context(), "SELECT message_id, content FROM payment_files WHERE id = $1", fileID).Scan(&messageID, &content)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payment file not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payment file"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="pain001-%s.xml"`, messageID))
+	c.Data(http.StatusOK, "application/xml", []byte(content))
+}
+
+// @Summary Receive a payment status report
+// @Description Apply a pain.002 payment status report from the bank to the file it names by OrgnlMsgId. Payments reported accepted or settled are marked so; rejected ones are marked with the bank's reason and their debit is reversed with a reversal_credit. A report with only a group status applies it to every payment in the file. Reports can be applied again safely: a payment is only reversed once.
+// @Tags admin
+// @Accept application/xml
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param report body string true "pain.002 XML"
+// @Success 200 {object} PaymentStatusResult "Report applied"
+// @Failure 400 {object} ErrorResponse "Not a pain.002 report"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "No payment file has the original message ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/payment-files/status-reports [post]
+func ReceivePaymentStatusReport(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 10<<20))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+		return
+	}
+	report, err := iso20022.ParseStatusReport(body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// A rejection reverses a debit, which must happen even if the client
+	// goes away
+	ctx := context.WithoutCancel(c.Request.Context())
+	var fileID uuid.UUID
+	err = db.QueryRow(ctx, "SELECT id FROM payment_files WHERE message_id = $1", report.OriginalMessageID).Scan(&fileID)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "No payment file has message ID " + report.OriginalMessageID})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payment file"})
+		return
+	}
+
+	statuses := report.Transactions
+	if len(statuses) == 0 && iso20022.Outcome(report.GroupStatus) != "" {
+		if statuses, err = fileStatuses(ctx, fileID, report); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch credit transfers"})
+			return
+		}
+	}
+	result := PaymentStatusResult{FileID: fileID}
+	for _, s := range statuses {
+		outcome := iso20022.Outcome(s.Status)
+		if outcome == "" {
+			continue
+		}
+		known, err := applyTransferStatus(ctx, fileID, s, outcome)
+		if err != nil {
+			log.Printf("Failed to apply status %s to credit transfer %s: %v", s.Status, s.EndToEndID, err)
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to apply status to " + s.EndToEndID})
+			return
+		}
+		switch {
+		case !known:
+			result.Unknown = append(result.Unknown, s.EndToEndID)
+		case outcome == iso20022.OutcomeAccepted:
+			result.Accepted++
+		case outcome == iso20022.OutcomeSettled:
+			result.Settled++
+		case outcome == iso20022.OutcomeRejected:
+			result.Rejected++
+		}
+	}
+
+	if _, err := db.Exec(ctx,
+		`INSERT INTO payment_status_reports (file_id, message_id, group_status) VALUES ($1, $2, $3)
+		ON CONFLICT (file_id, message_id) DO NOTHING`,
+		fileID, report.MessageID, nullableString(report.GroupStatus)); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record status report"})
+		return
+	}
+	// The file is settled once no transfer in it is waiting for a report
+	err = db.QueryRow(ctx,
+		`UPDATE payment_files SET status = CASE
+			WHEN NOT EXISTS (SELECT 1 FROM credit_transfers WHERE payment_file_id = $1 AND status <> 'rejected') THEN 'rejected'
+			WHEN EXISTS (SELECT 1 FROM credit_transfers WHERE payment_file_id = $1 AND status = 'exported') THEN 'generated'
+			WHEN EXISTS (SELECT 1 FROM credit_transfers WHERE payment_file_id = $1 AND status = 'rejected') THEN 'partially_accepted'
+			ELSE 'accepted' END, updated_at = NOW()
+		WHERE id = $1
+		RETURNING status`,
+		fileID).Scan(&result.FileStatus)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update payment file"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// fileStatuses gives every transfer in a file the report's group status
+func fileStatuses(ctx context.Context, fileID uuid.UUID, report iso20022.StatusReport) ([]iso20022.TransactionStatus, error) {
+	rows, err := db.Query(ctx, "SELECT end_to_end_id FROM credit_transfers WHERE payment_file_id = $1 ORDER BY end_to_end_id", fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var statuses []iso20022.TransactionStatus
+	for rows.Next() {
+		s := iso20022.TransactionStatus{Status: report.GroupStatus, Reason: report.GroupReason}
+		if err := rows.Scan(&s.EndToEndID); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
+
+// applyTransferStatus moves a transfer in a file on to an outcome, returning
+// false when the file has no such transfer. A status that does not move it
+// forward, such as a repeat, changes nothing. A rejection reverses the
+// debit in the same database transaction, so it happens exactly once.
+func applyTransferStatus(ctx context.Context, fileID uuid.UUID, s iso20022.TransactionStatus, outcome string) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id, customerID uuid.UUID
+	var status, reference string
+	var amount float64
+	err = tx.QueryRow(ctx,
+		`SELECT id, customer_id, status, amount, COALESCE(reference, '') FROM credit_transfers
+		WHERE payment_file_id = $1 AND end_to_end_id = $2 FOR UPDATE`,
+		fileID, s.EndToEndID).Scan(&id, &customerID, &status, &amount, &reference)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Accepted transfers can still settle or be rejected later
+	moves := status == "exported" || (status == "accepted" && outcome != iso20022.OutcomeAccepted)
+	if !moves {
+		return true, nil
+	}
+
+	var reversalID *uuid.UUID
+	if outcome == iso20022.OutcomeRejected {
+		rid, err := postReversal(ctx, store.NewPostgresTx(tx), customerID, "credit_transfer", amount, reference)
+		if err != nil {
+			return false, err
+		}
+		reversalID = &rid
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE credit_transfers SET status = $2, status_reason = $3, reversal_transaction_id = $4, updated_at = NOW()
+		WHERE id = $1`,
+		id, outcome, nullableString(s.Reason), reversalID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	if reversalID != nil {
+		invalidateBalances(ctx, customerID)
+	}
+	return true, nil
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/iso20022"
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var creditTransferRowColumns = []string{"id", "customer_id", "transaction_id", "amount", "currency", "creditor_name", "creditor_iban", "creditor_bic",
+	"reference", "end_to_end_id", "status", "status_reason", "payment_file_id", "reversal_transaction_id", "created_at"}
+
+var testDebtor = &iso20022.Party{Name: "Ledger Service Ltd", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"}
+
+func TestCreateCreditTransfer(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.POST("/customers/:customer_id/credit-transfers", CreateCreditTransfer)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 500, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/customers/"+customer.ID.String()+"/credit-transfers", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	valid := map[string]interface{}{"amount": 125.5, "creditor_name": "Jane Doe", "creditor_iban": "GB29 NWBK 6016 1331 9268 19", "reference": "Invoice 42"}
+
+	w := send(valid)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	InitPaymentFiles(testDebtor)
+	defer InitPaymentFiles(nil)
+
+	w = send(map[string]interface{}{"amount": 10, "creditor_name": "Jane Doe", "creditor_iban": "GB29NWBK60161331926818", "creditor_bic": "NW"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "creditor_iban")
+	assert.Contains(t, w.Body.String(), "creditor_bic")
+
+	w = send(map[string]interface{}{"amount": 1000, "creditor_name": "Jane Doe", "creditor_iban": "GB29NWBK60161331926819"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Insufficient balance")
+
+	mock.ExpectQuery(`INSERT INTO credit_transfers`).
+		WithArgs(pgxmock.AnyArg(), customer.ID, pgxmock.AnyArg(), 125.5, "USD", "Jane Doe", "GB29NWBK60161331926819", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows(creditTransferRowColumns).
+			AddRow(uuid.New(), customer.ID, uuid.New(), 125.5, "USD", "Jane Doe", "GB29NWBK60161331926819", "", "Invoice 42",
+				"5b1f0e6c2d7a4c1e9f3b8a6d4c2e0f1a", "pending", "", (*uuid.UUID)(nil), (*uuid.UUID)(nil), time.Now()))
+	w = send(valid)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var transfer CreditTransfer
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &transfer))
+	assert.Equal(t, "pending", transfer.Status)
+	assert.Nil(t, transfer.PaymentFileID)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, 374.5, balance.Amount)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePaymentFile(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	InitPaymentFiles(testDebtor)
+	defer InitPaymentFiles(nil)
+	router.POST("/admin/payment-files", CreatePaymentFile)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/payment-files", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	pendingColumns := []string{"id", "amount", "currency", "creditor_name", "creditor_iban", "creditor_bic", "reference", "end_to_end_id"}
+
+	w := send(`{"execution_date": "2001-01-01"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM credit_transfers o JOIN transactions t ON t.id = o.transaction_id`).
+		WithArgs("EUR", maxPaymentFileTransfers).
+		WillReturnRows(pgxmock.NewRows(pendingColumns))
+	mock.ExpectRollback()
+	w = send(`{"currency": "eur"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	first, second := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM credit_transfers o JOIN transactions t ON t.id = o.transaction_id`).
+		WithArgs("", maxPaymentFileTransfers).
+		WillReturnRows(pgxmock.NewRows(pendingColumns).
+			AddRow(first, 125.5, "EUR", "Jane Doe", "FR1420041010050500013M02606", "", "Invoice 42", endToEndID(first)).
+			AddRow(second, 20.0, "GBP", "Sam Lee", "GB29NWBK60161331926819", "NWBKGB2L", "", endToEndID(second)))
+	mock.ExpectQuery(`INSERT INTO payment_files`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), 2, 145.5, pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "message_id", "status", "transfers", "control_sum", "execution_date", "created_at"}).
+			AddRow(uuid.New(), "9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d", "generated", 2, 145.5, time.Now(), time.Now()))
+	mock.ExpectExec(`UPDATE credit_transfers SET status = 'exported', payment_file_id = \$1`).
+		WithArgs(pgxmock.AnyArg(), []uuid.UUID{first, second}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectCommit()
+	w = send("")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var file PaymentFile
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &file))
+	assert.Equal(t, "generated", file.Status)
+	assert.Equal(t, 2, file.Transfers)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceivePaymentStatusReport(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST("/admin/payment-files/status-reports", ReceivePaymentStatusReport)
+
+	fileID, customerID := uuid.New(), uuid.New()
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03">
+  <CstmrPmtStsRpt>
+    <GrpHdr><MsgId>STS-1</MsgId></GrpHdr>
+    <OrgnlGrpInfAndSts><OrgnlMsgId>9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d</OrgnlMsgId><GrpSts>PART</GrpSts></OrgnlGrpInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <TxInfAndSts><OrgnlEndToEndId>a1</OrgnlEndToEndId><TxSts>RJCT</TxSts><StsRsnInf><Rsn><Cd>AC01</Cd></Rsn></StsRsnInf></TxInfAndSts>
+      <TxInfAndSts><OrgnlEndToEndId>a2</OrgnlEndToEndId><TxSts>ACSC</TxSts></TxInfAndSts>
+      <TxInfAndSts><OrgnlEndToEndId>zz</OrgnlEndToEndId><TxSts>ACSC</TxSts></TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+  </CstmrPmtStsRpt>
+</Document>`
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/payment-files/status-reports", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/xml")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expectFile := func() {
+		mock.ExpectQuery(`SELECT id FROM payment_files WHERE message_id = \$1`).
+			WithArgs("9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d").
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(fileID))
+	}
+	expectTransfer := func(endToEndID, status string) uuid.UUID {
+		id := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, customer_id, status, amount, COALESCE\(reference, ''\) FROM credit_transfers`).
+			WithArgs(fileID, endToEndID).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "status", "amount", "reference"}).
+				AddRow(id, customerID, status, 125.5, ""))
+		return id
+	}
+	expectFileStatus := func(status string) {
+		mock.ExpectExec(`INSERT INTO payment_status_reports`).
+			WithArgs(fileID, "STS-1", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectQuery(`UPDATE payment_files SET status = CASE`).
+			WithArgs(fileID).
+			WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(status))
+	}
+
+	w := send("not xml")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The rejected transfer's debit is given back with a reversal_credit
+	expectFile()
+	rejected := expectTransfer("a1", "exported")
+	mock.ExpectQuery(lockCustomerQuery).
+		WithArgs(customerID).
+		WillReturnRows(lockedCustomer(float64(374.5), "checking", false))
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(float64(500), customerID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\)`).
+		WithArgs(pgxmock.AnyArg(), customerID, "reversal_credit", 125.5, "posted").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO outbox \(id, event_type, customer_id, payload, created_at\)`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE credit_transfers SET status = \$2`).
+		WithArgs(rejected, "rejected", pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	settled := expectTransfer("a2", "accepted")
+	mock.ExpectExec(`UPDATE credit_transfers SET status = \$2`).
+		WithArgs(settled, "settled", pgxmock.AnyArg(), (*uuid.UUID)(nil)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM credit_transfers`).
+		WithArgs(fileID, "zz").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "status", "amount", "reference"}))
+	mock.ExpectRollback()
+	expectFileStatus("partially_accepted")
+
+	w = send(report)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result PaymentStatusResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, PaymentStatusResult{FileID: fileID, FileStatus: "partially_accepted", Settled: 1, Rejected: 1, Unknown: []string{"zz"}}, result)
+
+	// Applying the report again changes nothing and reverses nothing
+	expectFile()
+	expectTransfer("a1", "rejected")
+	mock.ExpectRollback()
+	expectTransfer("a2", "settled")
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM credit_transfers`).
+		WithArgs(fileID, "zz").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "status", "amount", "reference"}))
+	mock.ExpectRollback()
+	expectFileStatus("partially_accepted")
+	w = send(report)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mock.ExpectQuery(`SELECT id FROM payment_files WHERE message_id = \$1`).
+		WithArgs("9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+	w = send(report)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
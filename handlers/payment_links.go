@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"ledger-service/ledger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PaymentLink represents a single-use link that pays a customer a fixed amount
+// @Description Shareable payment link
+type PaymentLink struct {
+	ID          uuid.UUID  `json:"payment_link_id" format:"uuid"`
+	Token       string     `json:"token" example:"kq2X9vH1cN3pLr7sTz0aYw4bMd8eFg6h"`
+	URL         string     `json:"url" example:"/v1/payment-links/kq2X9vH1cN3pLr7sTz0aYw4bMd8eFg6h"`
+	CustomerID  uuid.UUID  `json:"customer_id" format:"uuid"`
+	Amount      float64    `json:"amount" example:"25"`
+	Description string     `json:"description,omitempty" example:"Concert ticket"`
+	Status      string     `json:"status" example:"active" enums:"active,paid,expired"`
+	PaidBy      *uuid.UUID `json:"paid_by_customer_id,omitempty" format:"uuid"`
+	TransferID  *uuid.UUID `json:"transfer_id,omitempty" format:"uuid"`
+	ExpiresAt   string     `json:"expires_at" format:"date-time"`
+	CreatedAt   string     `json:"created_at" format:"date-time"`
+}
+
+// PaymentLinkRequest represents the payload for creating a payment link
+type PaymentLinkRequest struct {
+	Amount           float64 `json:"amount" binding:"required,money" example:"25" minimum:"0.01"`
+	Description      string  `json:"description,omitempty" binding:"max=140" example:"Concert ticket" maxLength:"140"`
+	ExpiresInMinutes int     `json:"expires_in_minutes,omitempty" binding:"gte=0,lte=43200" example:"60" minimum:"1" maximum:"43200" default:"1440"`
+}
+
+// PaymentLinkPayment identifies the customer paying a link
+type PaymentLinkPayment struct {
+	PayerCustomerID uuid.UUID `json:"payer_customer_id" binding:"required,uuid" format:"uuid"`
+}
+
+const defaultPaymentLinkExpiry = 24 * time.Hour
+
+// paymentLinkColumns reports active links past their expiry as expired even
+// before the sweeper has recorded it
+const paymentLinkColumns = "id, token, customer_id, amount, COALESCE(description, ''), CASE WHEN status = 'active' AND expires_at <= NOW() THEN 'expired' ELSE status END, paid_by_customer_id, transfer_id, expires_at, created_at"
+
+func scanPaymentLink(row pgx.Row) (PaymentLink, error) {
+	var l PaymentLink
+	var expiresAt, createdAt time.Time
+	if err := row.Scan(&l.ID, &l.Token, &l.CustomerID, &l.Amount, &l.Description, &l.Status, &l.PaidBy, &l.TransferID, &expiresAt, &createdAt); err != nil {
+		return PaymentLink{}, err
+	}
+	l.URL = "/v1/payment-links/" + l.Token
+	l.ExpiresAt = expiresAt.Format(time.RFC3339)
+	l.CreatedAt = createdAt.Format(time.RFC3339)
+	return l, nil
+}
+
+// newPaymentLinkToken returns a random URL-safe token
+func newPaymentLinkToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// @Summary Create a payment link
+// @Description Create a single-use link that anyone holding it can pay, crediting the customer with the amount
+// @Tags payment-links
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param link body PaymentLinkRequest true "Payment link"
+// @Success 201 {object} PaymentLink "Payment link created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/payment-links [post]
+func CreatePaymentLink(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req PaymentLinkRequest
+	if !bindRequest(c, &req, "Invalid input: amount must be greater than 0") {
+		return
+	}
+	expiry := defaultPaymentLinkExpiry
+	if req.ExpiresInMinutes > 0 {
+		expiry = time.Duration(req.ExpiresInMinutes) * time.Minute
+	}
+
+	ctx := c.Request.Context()
+	var exists bool
+	if err := db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+		customerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	token, err := newPaymentLinkToken()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate payment link"})
+		return
+	}
+	link, err := scanPaymentLink(db.QueryRow(ctx,
+		"INSERT INTO payment_links (id, token, customer_id, amount, description, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+paymentLinkColumns,
+		uuid.New(), token, customerID, req.Amount, nullableString(req.Description), time.Now().Add(expiry).UTC()))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create payment link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// @Summary Get payment link status
+// @Description Look up a payment link by its token
+// @Tags payment-links
+// @Produce json
+// @Param token path string true "Payment link token"
+// @Success 200 {object} PaymentLink "Payment link"
+// @Failure 404 {object} ErrorResponse "Payment link not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /payment-links/{token} [get]
+func GetPaymentLink(c *gin.Context) {
+	link, err := scanPaymentLink(db.QueryRow(c.Request.Context(),
+		"SELECT "+paymentLinkColumns+" FROM payment_links WHERE token = $1",
+		c.Param("token")))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payment link not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get payment link"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// @Summary Pay a payment link
+// @Description Pay an active link, transferring its amount from the payer to the link's customer. Each link can be paid once.
+// @Tags payment-links
+// @Accept json
+// @Produce json
+// @Param token path string true "Payment link token"
+// @Param payment body PaymentLinkPayment true "Paying customer"
+// @Success 200 {object} PaymentLink "Payment link paid"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 403 {object} ErrorResponse "Payer refused by its account policy or limits, dormant, or needing approval"
+// @Failure 404 {object} ErrorResponse "Payment link or payer not found"
+// @Failure 409 {object} ErrorResponse "Payment link has already been paid"
+// @Failure 410 {object} ErrorResponse "Payment link has expired"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /payment-links/{token}/pay [post]
+func PayPaymentLink(c *gin.Context) {
+	var req PaymentLinkPayment
+	if !bindRequest(c, &req, "Invalid input: payer_customer_id is required") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	link, err := scanPaymentLink(tx.QueryRow(ctx,
+		"SELECT "+paymentLinkColumns+" FROM payment_links WHERE token = $1 FOR UPDATE",
+		c.Param("token")))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payment link not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get payment link"})
+		}
+		return
+	}
+	switch link.Status {
+	case "active":
+	case "expired":
+		respondError(c, http.StatusGone, ErrorResponse{Error: "Payment link has expired"})
+		return
+	default:
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Payment link has already been paid"})
+		return
+	}
+	if req.PayerCustomerID == link.CustomerID {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: payer must be a different customer"})
+		return
+	}
+
+	result, err := postTransfer(ctx, tx, req.PayerCustomerID, link.CustomerID, link.Amount, link.Description)
+	if err != nil {
+		var violation *ledger.ViolationError
+		switch {
+		case errors.Is(err, errInsufficientFunds):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+		case errors.Is(err, errOverpayment):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payment exceeds the amount owed"})
+		case errors.Is(err, errCurrencyMismatch):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payer and payee accounts use different currencies"})
+		case errors.Is(err, errPayerNotFound):
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payer not found"})
+		case errors.As(err, &violation):
+			respondError(c, http.StatusForbidden, ErrorResponse{Error: violation.Message})
+		default:
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post payment"})
+		}
+		return
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE payment_links SET status = 'paid', paid_by_customer_id = $1, transfer_id = $2, paid_at = NOW() WHERE id = $3",
+		req.PayerCustomerID, result.TransferID, link.ID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update payment link"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, req.PayerCustomerID, link.CustomerID)
+
+	link.Status = "paid"
+	link.PaidBy = &req.PayerCustomerID
+	link.TransferID = &result.TransferID
+	c.JSON(http.StatusOK, link)
+}
+
+// ExpirePaymentLinks records every active link past its expiry as expired and
+// returns how many were updated
+func ExpirePaymentLinks(ctx context.Context) (int64, error) {
+	tag, err := db.Exec(ctx,
+		"UPDATE payment_links SET status = 'expired' WHERE status = 'active' AND expires_at <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
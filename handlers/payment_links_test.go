@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var paymentLinkRowColumns = []string{"id", "token", "customer_id", "amount", "description", "status", "paid_by_customer_id", "transfer_id", "expires_at", "created_at"}
+
+func TestCreatePaymentLink(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/payment-links", CreatePaymentLink)
+
+	customerID := uuid.New()
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM customers WHERE id = \$1\)`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`INSERT INTO payment_links`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), customerID, float64(25), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows(paymentLinkRowColumns).
+			AddRow(uuid.New(), "tok", customerID, float64(25), "", "active", (*uuid.UUID)(nil), (*uuid.UUID)(nil), time.Now().Add(time.Hour), time.Now()))
+
+	jsonBytes, _ := json.Marshal(map[string]interface{}{"amount": 25, "expires_in_minutes": 60})
+	req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/payment-links", bytes.NewBuffer(jsonBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp PaymentLink
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "/v1/payment-links/tok", resp.URL)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPayPaymentLink(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/payment-links/:token/pay", PayPaymentLink)
+
+	linkID := uuid.New()
+	ownerID := uuid.New()
+	payerID := uuid.New()
+	expectLocked := func(status string) {
+		mock.ExpectQuery(`FROM payment_links WHERE token = \$1 FOR UPDATE`).
+			WithArgs("tok").
+			WillReturnRows(pgxmock.NewRows(paymentLinkRowColumns).
+				AddRow(linkID, "tok", ownerID, float64(25), "Ticket", status, (*uuid.UUID)(nil), (*uuid.UUID)(nil), time.Now().Add(time.Hour), time.Now()))
+	}
+
+	tests := []struct {
+		name       string
+		payerID    uuid.UUID
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "paid",
+			payerID:    payerID,
+			wantStatus: http.StatusOK,
+			setupMock: func() {
+				expectLocked("active")
+				expectTransfer(payerID, ownerID, 100, 0, 25)
+				mock.ExpectExec(`UPDATE payment_links SET status = 'paid'`).
+					WithArgs(payerID, pgxmock.AnyArg(), linkID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "already paid",
+			payerID:    payerID,
+			wantStatus: http.StatusConflict,
+			setupMock:  func() { expectLocked("paid") },
+		},
+		{
+			name:       "expired",
+			payerID:    payerID,
+			wantStatus: http.StatusGone,
+			setupMock:  func() { expectLocked("expired") },
+		},
+		{
+			name:       "owner paying own link",
+			payerID:    ownerID,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() { expectLocked("active") },
+		},
+		{
+			name:       "insufficient funds",
+			payerID:    payerID,
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				expectLocked("active")
+				expectTransferLocks(payerID, ownerID, 5, 0)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(map[string]interface{}{"payer_customer_id": tt.payerID})
+			req := httptest.NewRequest("POST", "/payment-links/tok/pay", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExpirePaymentLinks(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	mock.ExpectExec(`UPDATE payment_links SET status = 'expired' WHERE status = 'active' AND expires_at <= NOW\(\)`).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	n, err := ExpirePaymentLinks(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"ledger-service/ledger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PaymentRequest represents one customer asking another for money
+// @Description Request for payment from another customer
+type PaymentRequest struct {
+	ID                  uuid.UUID  `json:"payment_request_id" format:"uuid"`
+	RequesterCustomerID uuid.UUID  `json:"requester_customer_id" format:"uuid"`
+	PayerCustomerID     uuid.UUID  `json:"payer_customer_id" format:"uuid"`
+	Amount              float64    `json:"amount" example:"42.5"`
+	Message             string     `json:"message,omitempty" example:"Dinner on Friday"`
+	Status              string     `json:"status" example:"pending" enums:"pending,accepted,declined,expired"`
+	TransferID          *uuid.UUID `json:"transfer_id,omitempty" format:"uuid"`
+	ExpiresAt           string     `json:"expires_at" format:"date-time"`
+	CreatedAt           string     `json:"created_at" format:"date-time"`
+}
+
+// PaymentRequestCreate represents the payload for requesting a payment
+type PaymentRequestCreate struct {
+	RequesterCustomerID uuid.UUID `json:"requester_customer_id" binding:"required,uuid" format:"uuid"`
+	PayerCustomerID     uuid.UUID `json:"payer_customer_id" binding:"required_without=PayerAlias" format:"uuid"`
+	PayerAlias          string    `json:"payer_alias,omitempty" binding:"omitempty,alias" example:"@jane"`
+	Amount              float64   `json:"amount" binding:"required,money" example:"42.5" minimum:"0.01"`
+	Message             string    `json:"message,omitempty" binding:"max=140" example:"Dinner on Friday" maxLength:"140"`
+	ExpiresInHours      int       `json:"expires_in_hours,omitempty" binding:"gte=0,lte=720" example:"168" minimum:"1" maximum:"720" default:"168"`
+}
+
+// PaymentRequestDecision identifies the payer responding to a request
+type PaymentRequestDecision struct {
+	PayerCustomerID uuid.UUID `json:"payer_customer_id" binding:"required,uuid" format:"uuid"`
+}
+
+const defaultPaymentRequestExpiry = 7 * 24 * time.Hour
+
+// paymentRequestStatus reports pending requests past their expiry as expired
+// without waiting for a write to record it
+const paymentRequestStatus = "CASE WHEN status = 'pending' AND expires_at <= NOW() THEN 'expired' ELSE status END"
+
+const paymentRequestColumns = "id, requester_customer_id, payer_customer_id, amount, COALESCE(message, ''), " + paymentRequestStatus + ", transfer_id, expires_at, created_at"
+
+func scanPaymentRequest(row pgx.Row) (PaymentRequest, error) {
+	var r PaymentRequest
+	var expiresAt, createdAt time.Time
+	if err := row.Scan(&r.ID, &r.RequesterCustomerID, &r.PayerCustomerID, &r.Amount, &r.Message, &r.Status, &r.TransferID, &expiresAt, &createdAt); err != nil {
+		return PaymentRequest{}, err
+	}
+	r.ExpiresAt = expiresAt.Format(time.RFC3339)
+	r.CreatedAt = createdAt.Format(time.RFC3339)
+	return r, nil
+}
+
+// @Summary Request a payment
+// @Description Ask another customer to pay an amount. The payer is given by payer_customer_id or by payer_alias. The payer can accept, which transfers the money, or decline until the request expires.
+// @Tags payment-requests
+// @Accept json
+// @Produce json
+// @Param request body PaymentRequestCreate true "Payment request"
+// @Success 201 {object} PaymentRequest "Payment request created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Requester or payer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /payment-requests [post]
+func CreatePaymentRequest(c *gin.Context) {
+	var req PaymentRequestCreate
+	if !bindRequest(c, &req, "Invalid input: requester_customer_id, payer_customer_id or payer_alias, and amount (> 0) are required") {
+		return
+	}
+	if !resolveParty(c, &req.PayerCustomerID, req.PayerAlias, "Payer not found") {
+		return
+	}
+	if req.RequesterCustomerID == req.PayerCustomerID {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: payer must be a different customer"})
+		return
+	}
+	expiry := defaultPaymentRequestExpiry
+	if req.ExpiresInHours > 0 {
+		expiry = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	ctx := c.Request.Context()
+	if !verifyTransferParties(c, req.PayerCustomerID, req.RequesterCustomerID, "Payer not found", "Requester not found") {
+		return
+	}
+
+	request, err := scanPaymentRequest(db.QueryRow(ctx,
+		"INSERT INTO payment_requests (id, requester_customer_id, payer_customer_id, amount, message, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+paymentRequestColumns,
+		uuid.New(), req.RequesterCustomerID, req.PayerCustomerID, req.Amount, nullableString(req.Message), time.Now().Add(expiry).UTC()))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create payment request"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// @Summary List payment requests
+// @Description List payment requests a customer has received (incoming, the default) or sent (outgoing)
+// @Tags payment-requests
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param direction query string false "Requests received or sent" Enums(incoming, outgoing) default(incoming)
+// @Param status query string false "Filter by status" Enums(pending, accepted, declined, expired)
+// @Success 200 {array} PaymentRequest "Payment requests"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID or filter"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/payment-requests [get]
+func ListPaymentRequests(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	column := "payer_customer_id"
+	switch c.DefaultQuery("direction", "incoming") {
+	case "incoming":
+	case "outgoing":
+		column = "requester_customer_id"
+	default:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid direction"})
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "pending", "accepted", "declined", "expired":
+	default:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status filter"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+paymentRequestColumns+" FROM payment_requests WHERE "+column+" = $1 AND ($2 = '' OR "+paymentRequestStatus+" = $2) ORDER BY created_at DESC",
+		customerID, status)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch payment requests"})
+		return
+	}
+	defer rows.Close()
+
+	requests := []PaymentRequest{}
+	for rows.Next() {
+		request, err := scanPaymentRequest(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan payment request"})
+			return
+		}
+		requests = append(requests, request)
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// @Summary Accept a payment request
+// @Description Pay a pending request, transferring the amount from the payer to the requester
+// @Tags payment-requests
+// @Accept json
+// @Produce json
+// @Param payment_request_id path string true "Payment request ID" format(uuid)
+// @Param decision body PaymentRequestDecision true "Paying customer"
+// @Success 200 {object} PaymentRequest "Payment request accepted"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 403 {object} ErrorResponse "Payer refused by its account policy or limits, dormant, or needing approval"
+// @Failure 404 {object} ErrorResponse "Payment request not found"
+// @Failure 409 {object} ErrorResponse "Payment request is no longer pending"
+// @Failure 410 {object} ErrorResponse "Payment request has expired"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /payment-requests/{payment_request_id}/accept [post]
+func AcceptPaymentRequest(c *gin.Context) {
+	respondToPaymentRequest(c, true)
+}
+
+// @Summary Decline a payment request
+// @Description Decline a pending payment request
+// @Tags payment-requests
+// @Accept json
+// @Produce json
+// @Param payment_request_id path string true "Payment request ID" format(uuid)
+// @Param decision body PaymentRequestDecision true "Paying customer"
+// @Success 200 {object} PaymentRequest "Payment request declined"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Payment request not found"
+// @Failure 409 {object} ErrorResponse "Payment request is no longer pending"
+// @Failure 410 {object} ErrorResponse "Payment request has expired"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /payment-requests/{payment_request_id}/decline [post]
+func DeclinePaymentRequest(c *gin.Context) {
+	respondToPaymentRequest(c, false)
+}
+
+// respondToPaymentRequest settles a pending request on behalf of its payer
+func respondToPaymentRequest(c *gin.Context, accept bool) {
+	requestID, err := uuid.Parse(c.Param("payment_request_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid payment request ID"})
+		return
+	}
+	var decision PaymentRequestDecision
+	if !bindRequest(c, &decision, "Invalid input: payer_customer_id is required") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	request, err := scanPaymentRequest(tx.QueryRow(ctx,
+		"SELECT "+paymentRequestColumns+" FROM payment_requests WHERE id = $1 AND payer_customer_id = $2 FOR UPDATE",
+		requestID, decision.PayerCustomerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payment request not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get payment request"})
+		}
+		return
+	}
+
+	switch request.Status {
+	case "pending":
+	case "expired":
+		// Persist the expiry so the stored status matches what callers see
+		if _, err := tx.Exec(ctx, "UPDATE payment_requests SET status = 'expired', updated_at = NOW() WHERE id = $1", requestID); err == nil {
+			tx.Commit(ctx)
+		}
+		respondError(c, http.StatusGone, ErrorResponse{Error: "Payment request has expired"})
+		return
+	default:
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Payment request is already " + request.Status})
+		return
+	}
+
+	request.Status = "declined"
+	if accept {
+		result, err := postTransfer(ctx, tx, request.PayerCustomerID, request.RequesterCustomerID, request.Amount, request.Message)
+		if err != nil {
+			var violation *ledger.ViolationError
+			switch {
+			case errors.Is(err, errInsufficientFunds):
+				respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+			case errors.Is(err, errOverpayment):
+				respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payment exceeds the amount owed"})
+			case errors.Is(err, errCurrencyMismatch):
+				respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payer and payee accounts use different currencies"})
+			case errors.As(err, &violation):
+				respondError(c, http.StatusForbidden, ErrorResponse{Error: violation.Message})
+			default:
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post payment"})
+			}
+			return
+		}
+		request.Status = "accepted"
+		request.TransferID = &result.TransferID
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE payment_requests SET status = $1, transfer_id = $2, responded_at = NOW(), updated_at = NOW() WHERE id = $3",
+		request.Status, request.TransferID, requestID); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update payment request"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	if request.TransferID != nil {
+		invalidateBalances(ctx, request.PayerCustomerID, request.RequesterCustomerID)
+	}
+
+	c.JSON(http.StatusOK, request)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var paymentRequestRowColumns = []string{"id", "requester_customer_id", "payer_customer_id", "amount", "message", "status", "transfer_id", "expires_at", "created_at"}
+
+func TestCreatePaymentRequest(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/payment-requests", CreatePaymentRequest)
+
+	requesterID := uuid.New()
+	payerID := uuid.New()
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name: "created",
+			payload: map[string]interface{}{
+				"requester_customer_id": requesterID,
+				"payer_customer_id":     payerID,
+				"amount":                42.5,
+				"message":               "Dinner",
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				for _, id := range []uuid.UUID{payerID, requesterID} {
+					mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+						WithArgs(id).
+						WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+				}
+				mock.ExpectQuery(`INSERT INTO payment_requests`).
+					WithArgs(pgxmock.AnyArg(), requesterID, payerID, 42.5, pgxmock.AnyArg(), pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows(paymentRequestRowColumns).
+						AddRow(uuid.New(), requesterID, payerID, 42.5, "Dinner", "pending", (*uuid.UUID)(nil), time.Now().Add(time.Hour), time.Now()))
+			},
+		},
+		{
+			name: "payer not found",
+			payload: map[string]interface{}{
+				"requester_customer_id": requesterID,
+				"payer_customer_id":     payerID,
+				"amount":                10,
+			},
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT currency FROM customers`).
+					WithArgs(payerID).
+					WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name: "requesting from self",
+			payload: map[string]interface{}{
+				"requester_customer_id": requesterID,
+				"payer_customer_id":     requesterID,
+				"amount":                10,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/payment-requests", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRespondToPaymentRequest(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/payment-requests/:payment_request_id/accept", AcceptPaymentRequest)
+	router.POST("/payment-requests/:payment_request_id/decline", DeclinePaymentRequest)
+
+	requestID := uuid.New()
+	requesterID := uuid.New()
+	payerID := uuid.New()
+	requestRow := func(status string) *pgxmock.Rows {
+		return pgxmock.NewRows(paymentRequestRowColumns).
+			AddRow(requestID, requesterID, payerID, float64(30), "Rent", status, (*uuid.UUID)(nil), time.Now().Add(time.Hour), time.Now())
+	}
+	expectLocked := func(status string) {
+		mock.ExpectQuery(`FROM payment_requests WHERE id = \$1 AND payer_customer_id = \$2 FOR UPDATE`).
+			WithArgs(requestID, payerID).
+			WillReturnRows(requestRow(status))
+	}
+	expectResponded := func(status string) {
+		mock.ExpectExec(`UPDATE payment_requests SET status = \$1, transfer_id = \$2`).
+			WithArgs(status, pgxmock.AnyArg(), requestID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+	}
+
+	tests := []struct {
+		name       string
+		action     string
+		wantStatus int
+		wantState  string
+		setupMock  func()
+	}{
+		{
+			name:       "accept transfers funds",
+			action:     "accept",
+			wantStatus: http.StatusOK,
+			wantState:  "accepted",
+			setupMock: func() {
+				expectLocked("pending")
+				expectTransfer(payerID, requesterID, 100, 0, 30)
+				expectResponded("accepted")
+			},
+		},
+		{
+			name:       "decline",
+			action:     "decline",
+			wantStatus: http.StatusOK,
+			wantState:  "declined",
+			setupMock: func() {
+				expectLocked("pending")
+				expectResponded("declined")
+			},
+		},
+		{
+			name:       "accept with insufficient funds",
+			action:     "accept",
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				expectLocked("pending")
+				expectTransferLocks(payerID, requesterID, 10, 0)
+			},
+		},
+		{
+			name:       "expired",
+			action:     "accept",
+			wantStatus: http.StatusGone,
+			setupMock: func() {
+				expectLocked("expired")
+				mock.ExpectExec(`UPDATE payment_requests SET status = 'expired'`).
+					WithArgs(requestID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "already accepted",
+			action:     "decline",
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				expectLocked("accepted")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(map[string]interface{}{"payer_customer_id": payerID})
+			req := httptest.NewRequest("POST", "/payment-requests/"+requestID.String()+"/"+tt.action, bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantState != "" {
+				var resp PaymentRequest
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantState, resp.Status)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PendingSummary totals a customer's transactions awaiting review
+// @Description Held and pending-approval transactions and the balance they leave available
+type PendingSummary struct {
+	CustomerID     uuid.UUID `json:"customer_id" format:"uuid"`
+	Currency       string    `json:"currency" example:"USD"`
+	Balance        float64   `json:"balance" example:"1000"`
+	PendingCount   int       `json:"pending_count" example:"2"`
+	PendingDebits  float64   `json:"pending_debits" example:"150"`
+	PendingCredits float64   `json:"pending_credits" example:"0"`
+	// AvailableBalance is the balance less pending debits, or for a credit
+	// account the unused credit limit less pending debits. Pending credits
+	// are not counted until they post.
+	AvailableBalance float64 `json:"available_balance" example:"850"`
+}
+
+// @Summary Get pending transaction summary
+// @Description Total the customer's held and pending_approval transactions, which have not moved the balance yet, and the balance left available if the pending debits post
+// @Tags transactions
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {object} PendingSummary "Pending summary"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/pending [get]
+func GetPendingSummary(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	summary := PendingSummary{CustomerID: customerID}
+	var balanceType string
+	var creditLimit float64
+	err = db.QueryRow(ctx,
+		"SELECT balance, balance_type, credit_limit, currency FROM customers WHERE id = $1",
+		customerID).Scan(&summary.Balance, &balanceType, &creditLimit, &summary.Currency)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+		return
+	}
+
+	if err := db.QueryRow(ctx,
+		"SELECT COUNT(*), COALESCE(SUM(t.amount) FILTER (WHERE tt.direction = 'debit'), 0), COALESCE(SUM(t.amount) FILTER (WHERE tt.direction = 'credit'), 0) FROM transactions t JOIN transaction_types tt ON tt.code = t.type WHERE t.customer_id = $1 AND t.status IN ('held', 'pending_approval')",
+		customerID).Scan(&summary.PendingCount, &summary.PendingDebits, &summary.PendingCredits); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to total pending transactions"})
+		return
+	}
+
+	summary.AvailableBalance = summary.Balance - summary.PendingDebits
+	if balanceType == store.BalanceCredit {
+		summary.AvailableBalance = creditLimit - summary.Balance - summary.PendingDebits
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPendingSummary(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/customers/:customer_id/pending", GetPendingSummary)
+	customerID := uuid.New()
+
+	tests := []struct {
+		name          string
+		balanceType   string
+		balance       float64
+		wantStatus    int
+		wantAvailable float64
+		found         bool
+	}{
+		{name: "deposit account", balanceType: "deposit", balance: 1000, wantStatus: http.StatusOK, wantAvailable: 850, found: true},
+		{name: "credit account", balanceType: "credit", balance: 200, wantStatus: http.StatusOK, wantAvailable: 650, found: true},
+		{name: "unknown customer", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := pgxmock.NewRows([]string{"balance", "balance_type", "credit_limit", "currency"})
+			if tt.found {
+				rows.AddRow(tt.balance, tt.balanceType, float64(1000), "USD")
+			}
+			mock.ExpectQuery(`SELECT balance, balance_type, credit_limit, currency FROM customers WHERE id = \$1`).
+				WithArgs(customerID).
+				WillReturnRows(rows)
+			if tt.found {
+				mock.ExpectQuery(`FROM transactions t JOIN transaction_types tt ON tt.code = t.type WHERE t.customer_id = \$1 AND t.status IN \('held', 'pending_approval'\)`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"count", "debits", "credits"}).AddRow(3, float64(150), float64(40)))
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/"+customerID.String()+"/pending", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.found {
+				var summary PendingSummary
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+				assert.Equal(t, 3, summary.PendingCount)
+				assert.Equal(t, float64(150), summary.PendingDebits)
+				assert.Equal(t, float64(40), summary.PendingCredits)
+				assert.Equal(t, tt.wantAvailable, summary.AvailableBalance)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ledger-service/pii"
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+)
+
+// piiKeys encrypts customers' personal data before it is stored; nil
+// stores it in the clear
+var piiKeys *pii.Keyring
+
+// InitPII encrypts customer names, contact details, dates of birth,
+// addresses and identity document references with keys
+func InitPII(keys *pii.Keyring) {
+	piiKeys = keys
+	if p, ok := ledgerStore.(*store.Postgres); ok {
+		p.EncryptPII(keys)
+	}
+}
+
+// piiColumns are the encrypted columns of each table holding personal data
+var piiColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"customers", []string{"name", "email", "phone_number", "date_of_birth"}},
+	{"customer_addresses", []string{"line1", "line2", "city", "region", "postal_code"}},
+	{"customer_documents", []string{"reference"}},
+}
+
+const piiRewrapBatchSize = 500
+
+// RewrapPII is the scheduled job that brings stored personal data under
+// the active key: values wrapped with a retired key are rewrapped and
+// values stored before encryption was turned on are encrypted. Once it
+// has caught up, retired keys can be dropped from the keyring. It returns
+// how many rows it changed.
+func RewrapPII(ctx context.Context, _ time.Time) (int, error) {
+	if piiKeys == nil {
+		return 0, nil
+	}
+	rewrapped := 0
+	for _, t := range piiColumns {
+		for {
+			n, err := rewrapBatch(ctx, t.table, t.columns)
+			rewrapped += n
+			if err != nil {
+				return rewrapped, fmt.Errorf("%s: %w", t.table, err)
+			}
+			if n < piiRewrapBatchSize {
+				break
+			}
+		}
+	}
+	return rewrapped, nil
+}
+
+// rewrapBatch rewraps up to piiRewrapBatchSize rows of table
+func rewrapBatch(ctx context.Context, table string, columns []string) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	stale := make([]string, len(columns))
+	assignments := make([]string, len(columns))
+	for i, col := range columns {
+		stale[i] = fmt.Sprintf("(%s <> '' AND NOT starts_with(%s, $1))", col, col)
+		assignments[i] = fmt.Sprintf("%s = $%d", col, i+2)
+	}
+	rows, err := tx.Query(ctx,
+		"SELECT id, "+strings.Join(columns, ", ")+" FROM "+table+" WHERE "+strings.Join(stale, " OR ")+" ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED",
+		piiKeys.ActivePrefix(), piiRewrapBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var ids []uuid.UUID
+	var values [][]*string
+	for rows.Next() {
+		var id uuid.UUID
+		row := make([]*string, len(columns))
+		dest := []interface{}{&id}
+		for i := range row {
+			dest = append(dest, &row[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		values = append(values, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		args := []interface{}{id}
+		for _, v := range values[i] {
+			if v != nil {
+				rewrapped, _, err := piiKeys.Rewrap(*v)
+				if err != nil {
+					return 0, fmt.Errorf("row %s: %w", id, err)
+				}
+				v = &rewrapped
+			}
+			args = append(args, v)
+		}
+		if _, err := tx.Exec(ctx,
+			"UPDATE "+table+" SET "+strings.Join(assignments, ", ")+" WHERE id = $1",
+			args...); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// loadSMSContact reads the phone number SMS alerts go to and whether the
+// customer opted in to them
+func loadSMSContact(ctx context.Context, q rowQuerier, customerID uuid.UUID) (*string, bool, error) {
+	var phone *string
+	var optIn bool
+	if err := q.QueryRow(ctx,
+		"SELECT phone_number, sms_opt_in FROM customers WHERE id = $1",
+		customerID).Scan(&phone, &optIn); err != nil {
+		return nil, false, err
+	}
+	if err := piiKeys.DecryptAll(phone); err != nil {
+		return nil, false, err
+	}
+	return phone, optIn, nil
+}
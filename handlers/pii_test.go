@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/pii"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyring(t *testing.T, ids ...string) *pii.Keyring {
+	keys := map[string][]byte{}
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), 32)
+	}
+	k, err := pii.NewKeyring(ids[0], keys)
+	require.NoError(t, err)
+	return k
+}
+
+func TestCustomerPIIEncrypted(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	keys := testKeyring(t, "k1")
+	InitPII(keys)
+	defer InitPII(nil)
+	router.POST("/customers", CreateCustomer)
+	router.GET("/customers/:customer_id", GetCustomer)
+
+	// What reaches the database is ciphertext
+	name, email := &capturedArg{}, &capturedArg{}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO customers`).
+		WithArgs(pgxmock.AnyArg(), name, float64(100), pgxmock.AnyArg(), email, pgxmock.AnyArg(), "checking", "UTC", "deposit", (*float64)(nil), "USD").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(events.CustomerCreated)
+	mock.ExpectCommit()
+	body, _ := json.Marshal(map[string]interface{}{"name": "Jane Doe", "email": "jane@example.com", "initial_balance": 100})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/customers", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.IsType(t, "", name.value)
+	assert.True(t, pii.IsEncrypted(name.value.(string)))
+	assert.NotContains(t, name.value, "Jane")
+	require.IsType(t, (*string)(nil), email.value)
+	assert.True(t, pii.IsEncrypted(*email.value.(*string)))
+
+	// Reads decrypt, and rows written before encryption read as they are
+	customerID := uuid.New()
+	dob, _ := keys.Encrypt("1990-01-31")
+	mock.ExpectQuery(`SELECT name, balance, date_of_birth, .* FROM customers WHERE id = \$1`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"name", "balance", "date_of_birth", "verification_status", "email", "phone_number", "account_type", "timezone", "balance_type", "credit_limit", "currency"}).
+			AddRow(name.value, float64(100), &dob, "verified", email.value, nil, "checking", "UTC", "deposit", float64(0), "USD"))
+	line1, _ := keys.Encrypt("1 Main Street")
+	mock.ExpectQuery(`FROM customer_addresses WHERE customer_id = \$1`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "address_type", "line1", "line2", "city", "region", "postal_code", "country", "is_primary"}).
+			AddRow(uuid.New(), "home", line1, "", "Springfield", "IL", "62701", "US", true))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/customers/"+customerID.String(), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var customer CustomerResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &customer))
+	assert.Equal(t, "Jane Doe", customer.Name)
+	assert.Equal(t, "jane@example.com", customer.Email)
+	assert.Equal(t, "1990-01-31", customer.DateOfBirth)
+	require.Len(t, customer.Addresses, 1)
+	assert.Equal(t, "1 Main Street", customer.Addresses[0].Line1)
+	assert.Equal(t, "Springfield", customer.Addresses[0].City)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRewrapPII(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	retired := testKeyring(t, "k1")
+	oldName, _ := retired.Encrypt("Jane Doe")
+	rotated := testKeyring(t, "k2", "k1")
+	InitPII(rotated)
+	defer InitPII(nil)
+
+	id := uuid.New()
+	name, email, phone, dob := &capturedArg{}, &capturedArg{}, &capturedArg{}, &capturedArg{}
+	plainEmail := "jane@example.com"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, name, email, phone_number, date_of_birth FROM customers WHERE .*starts_with\(name, \$1\).* LIMIT \$2 FOR UPDATE SKIP LOCKED`).
+		WithArgs(rotated.ActivePrefix(), piiRewrapBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "email", "phone_number", "date_of_birth"}).
+			AddRow(id, &oldName, &plainEmail, (*string)(nil), (*string)(nil)))
+	mock.ExpectExec(`UPDATE customers SET name = \$2, email = \$3, phone_number = \$4, date_of_birth = \$5 WHERE id = \$1`).
+		WithArgs(id, name, email, phone, dob).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	for _, table := range []string{"customer_addresses", "customer_documents"} {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM `+table+` WHERE`).
+			WithArgs(rotated.ActivePrefix(), piiRewrapBatchSize).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+	}
+
+	n, err := RewrapPII(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Nil(t, phone.value.(*string))
+	assert.Nil(t, dob.value.(*string))
+
+	// The rewrapped values no longer need the retired key
+	current := testKeyring(t, "k2")
+	for want, got := range map[string]*capturedArg{"Jane Doe": name, plainEmail: email} {
+		value := *got.value.(*string)
+		assert.Contains(t, value, current.ActivePrefix())
+		plain, err := current.Decrypt(value)
+		require.NoError(t, err)
+		assert.Equal(t, want, plain)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadSMSContact(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	keys := testKeyring(t, "k1")
+	InitPII(keys)
+	defer InitPII(nil)
+
+	customerID := uuid.New()
+	phone, _ := keys.Encrypt("+15551234567")
+	// Each row gets its own copy, as pgx would allocate one
+	stored := phone
+	mock.ExpectQuery(`SELECT phone_number, sms_opt_in FROM customers WHERE id = \$1`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"phone_number", "sms_opt_in"}).AddRow(&stored, true))
+	got, optIn, err := loadSMSContact(context.Background(), mock, customerID)
+	require.NoError(t, err)
+	assert.True(t, optIn)
+	assert.Equal(t, "+15551234567", *got)
+
+	// A value wrapped with a key that is gone cannot be read
+	InitPII(testKeyring(t, "k2"))
+	mock.ExpectQuery(`SELECT phone_number, sms_opt_in FROM customers`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"phone_number", "sms_opt_in"}).AddRow(&phone, true))
+	_, _, err = loadSMSContact(context.Background(), mock, customerID)
+	assert.ErrorIs(t, err, pii.ErrUnknownKey)
+}
@@ -0,0 +1,804 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/reconcile"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Reconciliation line statuses
+const (
+	// lineMatched lines were matched automatically and await confirmation
+	lineMatched   = "matched"
+	lineUnmatched = "unmatched"
+	lineConfirmed = "confirmed"
+	// lineAdjusted lines were settled with an adjusting entry
+	lineAdjusted = "adjusted"
+)
+
+// Rules of lines matched by an operator rather than the engine
+const (
+	ruleManual     = "manual"
+	ruleAdjustment = "adjustment"
+)
+
+// defaultMatchDays is how many days apart a line and transaction may be
+// when the upload does not say
+const defaultMatchDays = 3
+
+// Reconciliation is an external statement matched against the ledger
+// @Description External statement uploaded for a customer and its lines' matches with ledger transactions
+type Reconciliation struct {
+	ID         uuid.UUID `json:"id" format:"uuid"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	// Profile is the CSV import profile the statement was read with
+	Profile string `json:"profile" example:"acme-bank"`
+	// PeriodFrom and PeriodTo are the first and last dates of the
+	// statement's lines
+	PeriodFrom        string  `json:"period_from" example:"2025-04-01" format:"date"`
+	PeriodTo          string  `json:"period_to" example:"2025-04-30" format:"date"`
+	DateToleranceDays int     `json:"date_tolerance_days" example:"3"`
+	AmountTolerance   float64 `json:"amount_tolerance" example:"0.01"`
+	Status            string  `json:"status" example:"open" enums:"open,closed"`
+	// Lines counts the lines by status
+	Lines     map[string]int `json:"lines"`
+	CreatedBy string         `json:"created_by,omitempty" example:"jane.doe"`
+	CreatedAt string         `json:"created_at" format:"date-time"`
+	ClosedBy  string         `json:"closed_by,omitempty" example:"jane.doe"`
+	ClosedAt  string         `json:"closed_at,omitempty" format:"date-time"`
+}
+
+// ReconciliationLine is a line of an uploaded statement
+// @Description Statement line and the ledger transaction it is matched with
+type ReconciliationLine struct {
+	ID uuid.UUID `json:"id" format:"uuid"`
+	// Line is the line's number in the uploaded file
+	Line      int     `json:"line" example:"4"`
+	Date      string  `json:"date" example:"2025-04-03" format:"date"`
+	Direction string  `json:"direction" example:"debit" enums:"credit,debit"`
+	Amount    float64 `json:"amount" example:"800"`
+	Reference string  `json:"reference,omitempty" example:"Rent April"`
+	// Status is matched for a match the engine proposed, confirmed once an
+	// operator accepted or made a match, and adjusted when an adjusting
+	// entry settled the line
+	Status        string     `json:"status" example:"matched" enums:"matched,unmatched,confirmed,adjusted"`
+	MatchRule     string     `json:"match_rule,omitempty" example:"amount_date" enums:"reference,amount_date,manual,adjustment"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+	ResolvedBy    string     `json:"resolved_by,omitempty" example:"jane.doe"`
+	ResolvedAt    string     `json:"resolved_at,omitempty" format:"date-time"`
+}
+
+// ReconciliationTransaction is a ledger transaction no line matches
+// @Description Posted transaction in a reconciliation's period that no statement line is matched with
+type ReconciliationTransaction struct {
+	TransactionID uuid.UUID `json:"transaction_id" format:"uuid"`
+	Type          string    `json:"type" example:"debit"`
+	Amount        float64   `json:"amount" example:"42.5"`
+	ValueDate     string    `json:"value_date" example:"2025-04-08" format:"date"`
+	Reference     string    `json:"reference,omitempty" example:"INV-2025-0042"`
+}
+
+// ReconciliationDetail is a reconciliation with its lines and the
+// transactions left unmatched
+// @Description Reconciliation with its lines and the ledger transactions no line is matched with
+type ReconciliationDetail struct {
+	Reconciliation
+	Items                 []ReconciliationLine        `json:"items"`
+	UnmatchedTransactions []ReconciliationTransaction `json:"unmatched_transactions"`
+}
+
+// ReconciliationMatchRequest confirms a line's match or matches it by hand
+type ReconciliationMatchRequest struct {
+	// TransactionID matches the line with another transaction than the
+	// one proposed; it is required for an unmatched line
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+}
+
+// ReconciliationAdjustmentRequest settles a line with an adjusting entry
+type ReconciliationAdjustmentRequest struct {
+	ReasonCode    string `json:"reason_code" binding:"required,oneof=write_off goodwill error_correction" example:"error_correction" enums:"write_off,goodwill,error_correction"`
+	Justification string `json:"justification" binding:"required,min=10,max=1000" example:"Bank fee missing from the ledger, statement line 12" minLength:"10" maxLength:"1000"`
+}
+
+const reconciliationColumns = `id, customer_id, profile, period_from, period_to, date_tolerance_days, amount_tolerance, status,
+	COALESCE(created_by, ''), created_at, COALESCE(closed_by, ''), closed_at`
+
+func scanReconciliation(row pgx.Row) (Reconciliation, error) {
+	var r Reconciliation
+	var from, to, createdAt time.Time
+	var closedAt *time.Time
+	if err := row.Scan(&r.ID, &r.CustomerID, &r.Profile, &from, &to, &r.DateToleranceDays, &r.AmountTolerance, &r.Status,
+		&r.CreatedBy, &createdAt, &r.ClosedBy, &closedAt); err != nil {
+		return r, err
+	}
+	r.PeriodFrom, r.PeriodTo = from.Format(dateLayout), to.Format(dateLayout)
+	r.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	if closedAt != nil {
+		r.ClosedAt = closedAt.UTC().Format(time.RFC3339)
+	}
+	return r, nil
+}
+
+const reconciliationLineColumns = `id, line, date, direction, amount, COALESCE(reference, ''), status, COALESCE(match_rule, ''),
+	transaction_id, COALESCE(resolved_by, ''), resolved_at`
+
+func scanReconciliationLine(row pgx.Row) (ReconciliationLine, error) {
+	var l ReconciliationLine
+	var date time.Time
+	var resolvedAt *time.Time
+	if err := row.Scan(&l.ID, &l.Line, &date, &l.Direction, &l.Amount, &l.Reference, &l.Status, &l.MatchRule,
+		&l.TransactionID, &l.ResolvedBy, &resolvedAt); err != nil {
+		return l, err
+	}
+	l.Date = date.Format(dateLayout)
+	if resolvedAt != nil {
+		l.ResolvedAt = resolvedAt.UTC().Format(time.RFC3339)
+	}
+	return l, nil
+}
+
+// countLines tallies lines by status
+func countLines(lines []ReconciliationLine) map[string]int {
+	counts := map[string]int{lineMatched: 0, lineUnmatched: 0, lineConfirmed: 0, lineAdjusted: 0}
+	for _, l := range lines {
+		counts[l.Status]++
+	}
+	return counts
+}
+
+// parseMatchTolerance reads the date_tolerance_days and amount_tolerance
+// query parameters, writing a 400 response and returning false when they
+// are invalid
+func parseMatchTolerance(c *gin.Context) (reconcile.Tolerance, bool) {
+	tol := reconcile.Tolerance{Days: defaultMatchDays}
+	if v := c.Query("date_tolerance_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 || days > 31 {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid date_tolerance_days: must be between 0 and 31"})
+			return tol, false
+		}
+		tol.Days = days
+	}
+	if v := c.Query("amount_tolerance"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil || amount < 0 || math.IsInf(amount, 0) || validateMoney("amount_tolerance", amount+1) != "" {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid amount_tolerance: must be 0 or more with at most 2 decimal places"})
+			return tol, false
+		}
+		tol.Amount = amount
+	}
+	return tol, true
+}
+
+// @Summary Reconcile a statement
+// @Description Upload an external statement, such as a bank's CSV export read with a stored import profile, and match its lines against the customer's posted transactions. A line matches a transaction going the same way whose reference is the same, ignoring case and spaces, or else whose amount is within amount_tolerance and whose value date is within date_tolerance_days; the closest pairs are matched first and each transaction matches at most one line of any reconciliation. Matches are proposals until an operator confirms them. Lines left unmatched can be matched by hand or settled with an adjusting entry, and the posted transactions in the statement's period that no line matches are listed with the reconciliation.
+// @Tags admin
+// @Accept text/csv
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param profile query string true "Import profile the statement is read with" example(acme-bank)
+// @Param date_tolerance_days query int false "Days a line's date may be from the transaction's value date" default(3) minimum(0) maximum(31)
+// @Param amount_tolerance query number false "Amount a line may differ from the transaction by" default(0) minimum(0)
+// @Param file body string true "Statement CSV"
+// @Success 201 {object} ReconciliationDetail "Statement reconciled"
+// @Failure 400 {object} ErrorResponse "Invalid parameters or unreadable statement; fields lists the bad lines"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer or import profile not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/reconciliations [post]
+func CreateReconciliation(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	tol, ok := parseMatchTolerance(c)
+	if !ok {
+		return
+	}
+	profile, ok := importProfileParam(c)
+	if !ok {
+		return
+	}
+	rows, ok := readCSVRows(c, profile)
+	if !ok {
+		return
+	}
+	if len(rows) == 0 {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: the statement has no lines"})
+		return
+	}
+
+	r := Reconciliation{
+		ID:                uuid.New(),
+		CustomerID:        customerID,
+		Profile:           profile.Name,
+		DateToleranceDays: tol.Days,
+		AmountTolerance:   tol.Amount,
+		Status:            "open",
+		CreatedBy:         c.GetString(middleware.ActorKey),
+	}
+	from, to := rows[0].Date, rows[0].Date
+	lines := make([]reconcile.Item, len(rows))
+	items := make([]ReconciliationLine, len(rows))
+	for i, row := range rows {
+		if row.Date.Before(from) {
+			from = row.Date
+		}
+		if row.Date.After(to) {
+			to = row.Date
+		}
+		items[i] = ReconciliationLine{
+			ID:        uuid.New(),
+			Line:      row.Line,
+			Date:      row.Date.Format(dateLayout),
+			Direction: string(directionOf(row.Type)),
+			Amount:    row.Amount,
+			Reference: row.Reference,
+			Status:    lineUnmatched,
+		}
+		lines[i] = reconcile.Item{ID: items[i].ID.String(), Direction: items[i].Direction, Amount: row.Amount, Date: row.Date, Reference: row.Reference}
+	}
+	r.PeriodFrom, r.PeriodTo = from.Format(dateLayout), to.Format(dateLayout)
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Reconciliations of a customer are made one at a time so two cannot
+	// match the same transaction
+	if _, err := store.NewPostgresTx(tx).LockCustomer(ctx, customerID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to lock customer"})
+		}
+		return
+	}
+	candidates, err := unreconciledTransactions(ctx, tx, customerID, from.AddDate(0, 0, -tol.Days), to.AddDate(0, 0, tol.Days))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
+		return
+	}
+	transactions := make([]reconcile.Item, len(candidates))
+	for i, t := range candidates {
+		transactions[i] = reconcile.Item{ID: t.TransactionID.String(), Direction: string(directionOf(t.Type)), Amount: t.Amount, Reference: t.Reference}
+		transactions[i].Date, _ = time.Parse(dateLayout, t.ValueDate)
+	}
+	matched := map[string]reconcile.Pair{}
+	for _, p := range reconcile.Match(lines, transactions, tol) {
+		matched[p.Line] = p
+	}
+	for i := range items {
+		if p, ok := matched[items[i].ID.String()]; ok {
+			id := uuid.MustParse(p.Transaction)
+			items[i].Status, items[i].MatchRule, items[i].TransactionID = lineMatched, p.Rule, &id
+		}
+	}
+
+	var createdAt time.Time
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO reconciliations (id, customer_id, profile, period_from, period_to, date_tolerance_days, amount_tolerance, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`,
+		r.ID, customerID, r.Profile, from, to, tol.Days, tol.Amount, nullableString(r.CreatedBy)).Scan(&createdAt); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create reconciliation"})
+		return
+	}
+	r.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	for i, l := range items {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO reconciliation_lines (id, reconciliation_id, line, date, direction, amount, reference, status, match_rule, transaction_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			l.ID, r.ID, l.Line, rows[i].Date, l.Direction, l.Amount, nullableString(l.Reference), l.Status, nullableString(l.MatchRule), l.TransactionID); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record statement line"})
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	// What is left unmatched within the statement's own period is what the
+	// operator has to look into
+	unmatched := []ReconciliationTransaction{}
+	taken := map[uuid.UUID]bool{}
+	for _, l := range items {
+		if l.TransactionID != nil {
+			taken[*l.TransactionID] = true
+		}
+	}
+	for _, t := range candidates {
+		if !taken[t.TransactionID] && t.ValueDate >= r.PeriodFrom && t.ValueDate <= r.PeriodTo {
+			unmatched = append(unmatched, t)
+		}
+	}
+	r.Lines = countLines(items)
+	c.JSON(http.StatusCreated, ReconciliationDetail{Reconciliation: r, Items: items, UnmatchedTransactions: unmatched})
+}
+
+// unreconciledTransactions lists a customer's posted transactions with a
+// value date from from to to that no statement line is matched with
+func unreconciledTransactions(ctx context.Context, q queryer, customerID uuid.UUID, from, to time.Time) ([]ReconciliationTransaction, error) {
+	rows, err := q.Query(ctx,
+		`SELECT t.id, t.type, t.amount, t.value_date, COALESCE(t.reference, '') FROM transactions t
+		WHERE t.customer_id = $1 AND t.status = 'posted' AND t.value_date BETWEEN $2 AND $3
+			AND NOT EXISTS (SELECT 1 FROM reconciliation_lines l WHERE l.transaction_id = t.id)
+		ORDER BY t.value_date, t.created_at`,
+		customerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	transactions := []ReconciliationTransaction{}
+	for rows.Next() {
+		var t ReconciliationTransaction
+		var valueDate time.Time
+		if err := rows.Scan(&t.TransactionID, &t.Type, &t.Amount, &valueDate, &t.Reference); err != nil {
+			return nil, err
+		}
+		t.ValueDate = valueDate.Format(dateLayout)
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// @Summary List a customer's reconciliations
+// @Description List the statements reconciled for a customer, newest first, with their lines counted by status
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param status query string false "Only reconciliations with this status" Enums(open, closed)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Reconciliations per page" default(10)
+// @Success 200 {array} Reconciliation "Reconciliations"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/reconciliations [get]
+func ListReconciliations(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "open", "closed":
+	default:
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status"})
+		return
+	}
+	ctx := c.Request.Context()
+	rows, err := db.Query(ctx,
+		"SELECT "+reconciliationColumns+" FROM reconciliations WHERE customer_id = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC LIMIT $3 OFFSET $4",
+		customerID, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reconciliations"})
+		return
+	}
+	defer rows.Close()
+	reconciliations := []Reconciliation{}
+	index := map[uuid.UUID]int{}
+	for rows.Next() {
+		r, err := scanReconciliation(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan reconciliation"})
+			return
+		}
+		r.Lines = countLines(nil)
+		index[r.ID] = len(reconciliations)
+		reconciliations = append(reconciliations, r)
+	}
+	rows.Close()
+	if len(reconciliations) == 0 {
+		c.JSON(http.StatusOK, reconciliations)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(reconciliations))
+	for _, r := range reconciliations {
+		ids = append(ids, r.ID)
+	}
+	counts, err := db.Query(ctx,
+		"SELECT reconciliation_id, status, COUNT(*) FROM reconciliation_lines WHERE reconciliation_id = ANY($1) GROUP BY reconciliation_id, status", ids)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to count statement lines"})
+		return
+	}
+	defer counts.Close()
+	for counts.Next() {
+		var id uuid.UUID
+		var status string
+		var n int
+		if err := counts.Scan(&id, &status, &n); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to count statement lines"})
+			return
+		}
+		reconciliations[index[id]].Lines[status] = n
+	}
+	c.JSON(http.StatusOK, reconciliations)
+}
+
+// @Summary Get a reconciliation
+// @Description Get a reconciliation with every statement line and its match, and the posted transactions in the statement's period that no line is matched with
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param reconciliation_id path string true "Reconciliation ID" format(uuid)
+// @Success 200 {object} ReconciliationDetail "Reconciliation"
+// @Failure 400 {object} ErrorResponse "Invalid reconciliation ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Reconciliation not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/reconciliations/{reconciliation_id} [get]
+func GetReconciliation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("reconciliation_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid reconciliation ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	r, err := scanReconciliation(db.QueryRow(ctx, "SELECT "+reconciliationColumns+" FROM reconciliations WHERE id = $1", id))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Reconciliation not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reconciliation"})
+		return
+	}
+
+	rows, err := db.Query(ctx, "SELECT "+reconciliationLineColumns+" FROM reconciliation_lines WHERE reconciliation_id = $1 ORDER BY line", id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch statement lines"})
+		return
+	}
+	defer rows.Close()
+	detail := ReconciliationDetail{Reconciliation: r, Items: []ReconciliationLine{}}
+	for rows.Next() {
+		l, err := scanReconciliationLine(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan statement line"})
+			return
+		}
+		detail.Items = append(detail.Items, l)
+	}
+	rows.Close()
+	detail.Lines = countLines(detail.Items)
+
+	from, _ := time.Parse(dateLayout, r.PeriodFrom)
+	to, _ := time.Parse(dateLayout, r.PeriodTo)
+	if detail.UnmatchedTransactions, err = unreconciledTransactions(ctx, db, r.CustomerID, from, to); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// lockedLine is a statement line locked for an operator's decision, with
+// what the decision needs of its reconciliation
+type lockedLine struct {
+	ReconciliationLine
+	customerID uuid.UUID
+}
+
+// lockLine locks a line of a reconciliation, answering the request itself
+// when it cannot: 404 for an unknown line and 409 when the reconciliation
+// is closed. It reports whether the handler should continue.
+func lockLine(c *gin.Context, tx pgx.Tx) (lockedLine, bool) {
+	var l lockedLine
+	reconciliationID, err := uuid.Parse(c.Param("reconciliation_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid reconciliation ID"})
+		return l, false
+	}
+	lineID, err := uuid.Parse(c.Param("line_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid line ID"})
+		return l, false
+	}
+	var status string
+	row := tx.QueryRow(c.Request.Context(),
+		`SELECT r.customer_id, r.status, l.id, l.line, l.date, l.direction, l.amount, COALESCE(l.reference, ''), l.status,
+			COALESCE(l.match_rule, ''), l.transaction_id, COALESCE(l.resolved_by, ''), l.resolved_at
+		FROM reconciliation_lines l JOIN reconciliations r ON r.id = l.reconciliation_id
+		WHERE l.id = $1 AND l.reconciliation_id = $2
+		FOR UPDATE OF l, r`,
+		lineID, reconciliationID)
+	var date time.Time
+	var resolvedAt *time.Time
+	err = row.Scan(&l.customerID, &status, &l.ID, &l.Line, &date, &l.Direction, &l.Amount, &l.Reference, &l.Status,
+		&l.MatchRule, &l.TransactionID, &l.ResolvedBy, &resolvedAt)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Statement line not found"})
+		return l, false
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch statement line"})
+		return l, false
+	}
+	if status != "open" {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Reconciliation is closed"})
+		return l, false
+	}
+	l.Date = date.Format(dateLayout)
+	return l, true
+}
+
+// resolveLine records an operator's decision on a line and answers with
+// the line as it now stands
+func resolveLine(c *gin.Context, tx pgx.Tx, lineID uuid.UUID, status, rule string, transactionID *uuid.UUID) {
+	ctx := c.Request.Context()
+	actor := c.GetString(middleware.ActorKey)
+	resolvedAt := "NOW()"
+	if status == lineUnmatched {
+		resolvedAt, actor = "NULL", ""
+	}
+	line, err := scanReconciliationLine(tx.QueryRow(ctx,
+		`UPDATE reconciliation_lines SET status = $2, match_rule = $3, transaction_id = $4, resolved_by = $5, resolved_at = `+resolvedAt+`
+		WHERE id = $1 RETURNING `+reconciliationLineColumns,
+		lineID, status, nullableString(rule), transactionID, nullableString(actor)))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction is already matched with another statement line"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update statement line"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	c.JSON(http.StatusOK, line)
+}
+
+// @Summary Confirm a statement line's match
+// @Description Accept the transaction the engine matched a line with, or match the line with another of the customer's posted transactions going the same way. A transaction can be matched with one statement line only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param reconciliation_id path string true "Reconciliation ID" format(uuid)
+// @Param line_id path string true "Statement line ID" format(uuid)
+// @Param match body ReconciliationMatchRequest false "Transaction to match the line with instead"
+// @Success 200 {object} ReconciliationLine "Match confirmed"
+// @Failure 400 {object} ErrorResponse "Invalid input, or the transaction goes the other way"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Statement line or transaction not found"
+// @Failure 409 {object} ErrorResponse "Reconciliation closed, line already settled, or transaction matched with another line"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/reconciliations/{reconciliation_id}/lines/{line_id}/confirm [post]
+func ConfirmReconciliationLine(c *gin.Context) {
+	var req ReconciliationMatchRequest
+	if err := bindJSON(c, &req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: transaction_id must be a UUID"})
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	line, ok := lockLine(c, tx)
+	if !ok {
+		return
+	}
+	if line.Status == lineConfirmed || line.Status == lineAdjusted {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Statement line is already " + line.Status})
+		return
+	}
+
+	if req.TransactionID == nil || (line.TransactionID != nil && *req.TransactionID == *line.TransactionID) {
+		if line.TransactionID == nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: transaction_id is required for an unmatched line"})
+			return
+		}
+		resolveLine(c, tx, line.ID, lineConfirmed, line.MatchRule, line.TransactionID)
+		return
+	}
+
+	var txType string
+	err = tx.QueryRow(ctx,
+		"SELECT type FROM transactions WHERE id = $1 AND customer_id = $2 AND status = 'posted'",
+		*req.TransactionID, line.customerID).Scan(&txType)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transaction"})
+		return
+	}
+	if string(directionOf(txType)) != line.Direction {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Invalid input: the line is a %s and the transaction is not", line.Direction)})
+		return
+	}
+	resolveLine(c, tx, line.ID, lineConfirmed, ruleManual, req.TransactionID)
+}
+
+// @Summary Unmatch a statement line
+// @Description Reject the transaction a line is matched with, leaving the line unmatched and the transaction free to match another line
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param reconciliation_id path string true "Reconciliation ID" format(uuid)
+// @Param line_id path string true "Statement line ID" format(uuid)
+// @Success 200 {object} ReconciliationLine "Line unmatched"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Statement line not found"
+// @Failure 409 {object} ErrorResponse "Reconciliation closed, or the line was settled with an adjusting entry"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/reconciliations/{reconciliation_id}/lines/{line_id}/unmatch [post]
+func UnmatchReconciliationLine(c *gin.Context) {
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	line, ok := lockLine(c, tx)
+	if !ok {
+		return
+	}
+	if line.Status == lineAdjusted {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Statement line was settled with an adjusting entry"})
+		return
+	}
+	resolveLine(c, tx, line.ID, lineUnmatched, "", nil)
+}
+
+// @Summary Settle a statement line with an adjusting entry
+// @Description Post an adjustment for an unmatched line, crediting or debiting the customer by the line's amount so the ledger agrees with the statement, and match the line with it. The adjustment is recorded with its reason and justification under the calling operator, like any other.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param X-Actor header string true "Operator making the adjustment"
+// @Param reconciliation_id path string true "Reconciliation ID" format(uuid)
+// @Param line_id path string true "Statement line ID" format(uuid)
+// @Param adjustment body ReconciliationAdjustmentRequest true "Reason for the adjustment"
+// @Success 200 {object} ReconciliationLine "Adjustment posted"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Statement line not found"
+// @Failure 409 {object} ErrorResponse "Reconciliation closed, or the line is matched"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/reconciliations/{reconciliation_id}/lines/{line_id}/adjust [post]
+func AdjustReconciliationLine(c *gin.Context) {
+	var req ReconciliationAdjustmentRequest
+	if !bindRequest(c, &req, "Invalid input: reason_code (write_off/goodwill/error_correction) and justification (at least 10 characters) are required") {
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	line, ok := lockLine(c, tx)
+	if !ok {
+		return
+	}
+	if line.Status != lineUnmatched {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Statement line is " + line.Status + "; only unmatched lines can be adjusted"})
+		return
+	}
+
+	adjustment, err := postAdjustment(ctx, tx, c.GetString(middleware.ActorKey), AdjustmentRequest{
+		CustomerID:    line.customerID,
+		Direction:     line.Direction,
+		Amount:        line.Amount,
+		ReasonCode:    req.ReasonCode,
+		Justification: req.Justification,
+	})
+	switch {
+	case errors.Is(err, ledger.ErrInsufficientBalance), errors.Is(err, ledger.ErrOverpayment):
+		respondBalanceError(c, err)
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post adjustment"})
+		return
+	}
+	resolveLine(c, tx, line.ID, lineAdjusted, ruleAdjustment, &adjustment.TransactionID)
+	if c.Writer.Status() == http.StatusOK {
+		invalidateBalances(ctx, line.customerID)
+	}
+}
+
+// @Summary Close a reconciliation
+// @Description Close a reconciliation once every line is confirmed or adjusted. A closed reconciliation's lines cannot be changed.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param reconciliation_id path string true "Reconciliation ID" format(uuid)
+// @Success 200 {object} Reconciliation "Reconciliation closed"
+// @Failure 400 {object} ErrorResponse "Invalid reconciliation ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Reconciliation not found"
+// @Failure 409 {object} ErrorResponse "Already closed, or lines are still matched without confirmation or unmatched"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/reconciliations/{reconciliation_id}/close [post]
+func CloseReconciliation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("reconciliation_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid reconciliation ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, "SELECT status FROM reconciliations WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Reconciliation not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reconciliation"})
+		return
+	}
+	if status != "open" {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Reconciliation is already closed"})
+		return
+	}
+	var open int
+	if err := tx.QueryRow(ctx,
+		"SELECT COUNT(*) FROM reconciliation_lines WHERE reconciliation_id = $1 AND status IN ('matched', 'unmatched')", id).Scan(&open); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to count statement lines"})
+		return
+	}
+	if open > 0 {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("%d statement lines are not confirmed or adjusted yet", open)})
+		return
+	}
+	r, err := scanReconciliation(tx.QueryRow(ctx,
+		"UPDATE reconciliations SET status = 'closed', closed_by = $2, closed_at = NOW() WHERE id = $1 RETURNING "+reconciliationColumns,
+		id, nullableString(c.GetString(middleware.ActorKey))))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to close reconciliation"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateReconciliation(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST("/admin/customers/:customer_id/reconciliations", CreateReconciliation)
+
+	customerID := uuid.New()
+	send := func(query, csv string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/customers/"+customerID.String()+"/reconciliations?profile=bank"+query, strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expectProfile := func() {
+		mock.ExpectQuery(`SELECT profile, updated_at FROM import_profiles WHERE name = \$1`).
+			WithArgs("bank").
+			WillReturnRows(pgxmock.NewRows([]string{"profile", "updated_at"}).AddRow(
+				[]byte(`{"delimiter": ",", "has_header": true, "columns": {"date": "Date", "amount": "Amount", "reference": "Reference"},
+					"date_format": "YYYY-MM-DD", "decimal_separator": ".", "credit_type": "credit", "debit_type": "debit"}`), time.Now()))
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("&date_tolerance_days=40", "").Code)
+	expectProfile()
+	assert.Equal(t, http.StatusBadRequest, send("", "Date,Amount,Reference\n").Code, "an empty statement has nothing to reconcile")
+
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 0, 0, 0, 0, time.UTC) }
+	salary, rent, stray := uuid.New(), uuid.New(), uuid.New()
+	expectProfile()
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCustomerQuery).WithArgs(customerID).WillReturnRows(lockedCustomer(1000, "checking", false))
+	mock.ExpectQuery(`SELECT t.id, t.type, t.amount, t.value_date, COALESCE\(t.reference, ''\) FROM transactions t`).
+		WithArgs(customerID, day(1).AddDate(0, 0, -3), day(10).AddDate(0, 0, 3)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "type", "amount", "value_date", "reference"}).
+			AddRow(salary, "credit", 2500.0, day(4), "PAYROLL-APR").
+			AddRow(rent, "debit", 800.0, day(2), "").
+			AddRow(stray, "debit", 15.0, day(6), ""))
+	mock.ExpectQuery(`INSERT INTO reconciliations`).
+		WithArgs(pgxmock.AnyArg(), customerID, "bank", day(1), day(10), 3, 0.0, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	for range 3 {
+		mock.ExpectExec(`INSERT INTO reconciliation_lines`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+				pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mock.ExpectCommit()
+	w := send("", "Date,Amount,Reference\n2025-04-01,2500.00,payroll-apr\n2025-04-03,-800.00,Rent\n2025-04-10,-9.99,Card\n")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var detail ReconciliationDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "2025-04-01", detail.PeriodFrom)
+	assert.Equal(t, map[string]int{"matched": 2, "unmatched": 1, "confirmed": 0, "adjusted": 0}, detail.Lines)
+	require.Len(t, detail.Items, 3)
+	assert.Equal(t, "reference", detail.Items[0].MatchRule)
+	assert.Equal(t, salary, *detail.Items[0].TransactionID)
+	assert.Equal(t, rent, *detail.Items[1].TransactionID)
+	assert.Equal(t, "debit", detail.Items[2].Direction)
+	assert.Equal(t, "unmatched", detail.Items[2].Status)
+	require.Len(t, detail.UnmatchedTransactions, 1)
+	assert.Equal(t, stray, detail.UnmatchedTransactions[0].TransactionID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciliationLineActions(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	actor := func(c *gin.Context) { c.Set(middleware.ActorKey, "jane") }
+	router.POST("/admin/reconciliations/:reconciliation_id/lines/:line_id/confirm", actor, ConfirmReconciliationLine)
+	router.POST("/admin/reconciliations/:reconciliation_id/lines/:line_id/unmatch", actor, UnmatchReconciliationLine)
+	router.POST("/admin/reconciliations/:reconciliation_id/close", actor, CloseReconciliation)
+
+	customerID, reconciliationID, lineID, txID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	send := func(action string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		path := "/admin/reconciliations/" + reconciliationID.String() + "/lines/" + lineID.String() + "/" + action
+		if action == "close" {
+			path = "/admin/reconciliations/" + reconciliationID.String() + "/close"
+		}
+		req := httptest.NewRequest("POST", path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expectLine := func(reconciliation, status string, matched *uuid.UUID) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM reconciliation_lines l JOIN reconciliations r ON r.id = l.reconciliation_id`).
+			WithArgs(lineID, reconciliationID).
+			WillReturnRows(pgxmock.NewRows([]string{"customer_id", "reconciliation_status", "id", "line", "date", "direction", "amount",
+				"reference", "status", "match_rule", "transaction_id", "resolved_by", "resolved_at"}).
+				AddRow(customerID, reconciliation, lineID, 2, time.Now(), "debit", 800.0, "", status, "", matched, "", (*time.Time)(nil)))
+	}
+	lineColumns := []string{"id", "line", "date", "direction", "amount", "reference", "status", "match_rule", "transaction_id", "resolved_by", "resolved_at"}
+
+	// An unmatched line needs a transaction to confirm
+	expectLine("open", "unmatched", nil)
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusBadRequest, send("confirm", nil).Code)
+
+	// The transaction must go the same way as the line
+	expectLine("open", "unmatched", nil)
+	mock.ExpectQuery(`SELECT type FROM transactions WHERE id = \$1 AND customer_id = \$2 AND status = 'posted'`).
+		WithArgs(txID, customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"type"}).AddRow("deposit"))
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusBadRequest, send("confirm", map[string]interface{}{"transaction_id": txID}).Code)
+
+	// A transaction matches one line at most
+	expectLine("open", "unmatched", nil)
+	mock.ExpectQuery(`SELECT type FROM transactions`).
+		WithArgs(txID, customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"type"}).AddRow("debit"))
+	mock.ExpectQuery(`UPDATE reconciliation_lines SET status = \$2`).
+		WithArgs(lineID, "confirmed", pgxmock.AnyArg(), &txID, pgxmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusConflict, send("confirm", map[string]interface{}{"transaction_id": txID}).Code)
+
+	expectLine("open", "matched", &txID)
+	mock.ExpectQuery(`UPDATE reconciliation_lines SET status = \$2`).
+		WithArgs(lineID, "confirmed", pgxmock.AnyArg(), &txID, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows(lineColumns).
+			AddRow(lineID, 2, time.Now(), "debit", 800.0, "", "confirmed", "amount_date", &txID, "jane", ptrTime(time.Now())))
+	mock.ExpectCommit()
+	w := send("confirm", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"resolved_by":"jane"`)
+
+	expectLine("open", "adjusted", &txID)
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusConflict, send("unmatch", nil).Code, "an adjusting entry cannot be unmatched")
+
+	expectLine("closed", "confirmed", &txID)
+	mock.ExpectRollback()
+	assert.Equal(t, http.StatusConflict, send("unmatch", nil).Code)
+
+	// Closing waits for every line to be settled
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM reconciliations WHERE id = \$1 FOR UPDATE`).
+		WithArgs(reconciliationID).
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow("open"))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM reconciliation_lines`).
+		WithArgs(reconciliationID).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+	w = send("close", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "2 statement lines")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
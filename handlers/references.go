@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReferenceMatches caps the transactions returned by one reference lookup
+const maxReferenceMatches = 100
+
+// @Summary Find transactions by reference
+// @Description Find the transactions of any customer carrying an external reference, such as an invoice or payment reference, newest first, so support can locate a payment without knowing the customer or transaction ID. Transfer legs match on the transfer's reference. Each transaction includes its customer_id.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param reference query string true "External reference" example(INV-1234)
+// @Param limit query int false "Maximum transactions to return" default(50) maximum(100)
+// @Success 200 {array} Transaction "Matching transactions"
+// @Failure 400 {object} ErrorResponse "Missing reference or invalid limit"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/transactions [get]
+func FindTransactionsByReference(c *gin.Context) {
+	reference := c.Query("reference")
+	if reference == "" || len(reference) > 140 {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid reference: give 1 to 140 characters"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > maxReferenceMatches {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		return
+	}
+
+	transactions, err := ledgerStore.FindByReference(c.Request.Context(), reference, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
+		return
+	}
+	matches := []gin.H{}
+	for _, t := range transactions {
+		entry := historyEntry(t)
+		entry["customer_id"] = t.CustomerID
+		if t.TransferID != nil {
+			entry["transfer_id"] = *t.TransferID
+		}
+		matches = append(matches, entry)
+	}
+	c.JSON(http.StatusOK, matches)
+}
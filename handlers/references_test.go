@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindTransactionsByReference(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/transactions", FindTransactionsByReference)
+
+	postedAt := time.Date(2025, 4, 8, 17, 9, 17, 0, time.UTC)
+	customerID, payeeID, transferID := uuid.New(), uuid.New(), uuid.New()
+	reference := "INV-1234"
+	rows := pgxmock.NewRows([]string{"id", "type", "amount", "status", "created_at", "recorded_at", "value_date", "original_amount", "original_currency", "fx_rate", "rate_provider", "rounding", "reference", "unique_reference", "customer_id", "transfer_id"}).
+		AddRow(uuid.New(), "transfer_in", float64(40), "posted", postedAt, (*time.Time)(nil), postedAt.Truncate(24*time.Hour), (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), (*string)(nil), false, payeeID, &transferID).
+		AddRow(uuid.New(), "credit", float64(250), "posted", postedAt, (*time.Time)(nil), postedAt.Truncate(24*time.Hour), (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), &reference, true, customerID, (*uuid.UUID)(nil))
+	mock.ExpectQuery(`SELECT id, type, amount, .*, customer_id, transfer_id FROM transactions WHERE reference = \$1 OR transfer_id IN \(SELECT id FROM transfers WHERE reference = \$1\) ORDER BY created_at DESC, id DESC LIMIT \$2`).
+		WithArgs("INV-1234", 50).
+		WillReturnRows(rows)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/transactions?reference=INV-1234", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var matches []Transaction
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &matches))
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, payeeID, matches[0].CustomerID)
+		assert.Equal(t, &transferID, matches[0].TransferID)
+		assert.Equal(t, customerID, matches[1].CustomerID)
+		assert.Equal(t, "INV-1234", matches[1].Reference)
+		assert.True(t, matches[1].UniqueReference)
+	}
+
+	for _, query := range []string{"", "?reference=" + strings.Repeat("x", 141), "?reference=INV-1234&limit=101"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/transactions"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"ledger-service/ledger"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReservationRequest represents the payload for reserving a transfer
+type ReservationRequest struct {
+	FromCustomerID uuid.UUID `json:"from_customer_id" binding:"uuid" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	ToCustomerID   uuid.UUID `json:"to_customer_id" binding:"uuid" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8" format:"uuid"`
+	Amount         float64   `json:"amount" binding:"required,money" example:"40" minimum:"0.01"`
+	Reference      string    `json:"reference,omitempty" binding:"max=140" example:"Order 1042" maxLength:"140"`
+}
+
+// ReservationResponse represents a reserve-then-settle transfer
+// @Description A transfer whose funds are held from the payer until it is settled to the payee or released back
+type ReservationResponse struct {
+	TransferID     uuid.UUID `json:"transfer_id" format:"uuid"`
+	FromCustomerID uuid.UUID `json:"from_customer_id" format:"uuid"`
+	ToCustomerID   uuid.UUID `json:"to_customer_id" format:"uuid"`
+	Amount         float64   `json:"amount" example:"40"`
+	Reference      string    `json:"reference,omitempty" example:"Order 1042"`
+	Status         string    `json:"status" example:"reserved" enums:"pending_approval,reserved,settled,released,rejected"`
+	// FromBalance is set after reserving or releasing, ToBalance after
+	// settling
+	FromBalance *float64 `json:"from_balance,omitempty" example:"960"`
+	ToBalance   *float64 `json:"to_balance,omitempty" example:"140"`
+	CreatedAt   string   `json:"created_at,omitempty" example:"2025-04-08T09:14:52Z" format:"date-time"`
+}
+
+// reservationResponse describes a reservation after the step that returned r
+func reservationResponse(r ledger.Reservation) ReservationResponse {
+	resp := ReservationResponse{
+		TransferID:     r.TransferID,
+		FromCustomerID: r.FromCustomerID,
+		ToCustomerID:   r.ToCustomerID,
+		Amount:         r.Amount,
+		Reference:      r.Reference,
+		Status:         r.Status,
+	}
+	balance := r.Balance
+	if r.Status == ledger.TransferSettled {
+		resp.ToBalance = &balance
+	} else {
+		resp.FromBalance = &balance
+	}
+	return resp
+}
+
+// respondReservationError maps a reservation step the ledger refused to a
+// response
+func respondReservationError(c *gin.Context, err error, failure string) {
+	var violation *ledger.ViolationError
+	switch {
+	case errors.Is(err, ledger.ErrInsufficientBalance), errors.Is(err, ledger.ErrOverpayment):
+		respondBalanceError(c, err)
+	case errors.Is(err, ledger.ErrCurrencyMismatch):
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payer and payee accounts use different currencies"})
+	case errors.Is(err, ledger.ErrPayerNotFound):
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payer not found"})
+	case errors.Is(err, ledger.ErrPayeeNotFound):
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payee not found"})
+	case errors.Is(err, ledger.ErrReservationNotFound):
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Reservation not found"})
+	case errors.Is(err, ledger.ErrReservationClosed):
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Reservation is already settled or released", Code: "reservation_closed"})
+	case errors.As(err, &violation):
+		respondError(c, http.StatusForbidden, ErrorResponse{Error: violation.Message})
+	default:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: failure})
+	}
+}
+
+// @Summary Reserve a transfer
+// @Description Start a two-phase transfer for integrations that only learn later whether the receiving side succeeded. The amount is debited from the payer now, with a transfer_out, and held under the returned transfer ID until the reservation is settled, crediting the payee, or released, returning it to the payer. Both accounts must exist and share a base currency, and the payer's balance must allow the debit. The payer is held to the same account policy and limits as a debit: when its policy requires approval, as for escrow accounts, the reservation is pending_approval and nothing is reserved until the payer's transfer_out is approved.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param reservation body ReservationRequest true "Transfer to reserve"
+// @Success 201 {object} ReservationResponse "Funds reserved"
+// @Success 202 {object} ReservationResponse "Reservation awaiting approval"
+// @Failure 400 {object} ErrorResponse "Invalid input, insufficient balance or accounts in different currencies"
+// @Failure 403 {object} ErrorResponse "Payer refused by its account policy or limits, or dormant"
+// @Failure 404 {object} ErrorResponse "Payer or payee not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /transfers/reservations [post]
+func CreateReservation(c *gin.Context) {
+	var req ReservationRequest
+	if !bindRequest(c, &req, "Invalid input: from_customer_id, to_customer_id and amount are required") {
+		return
+	}
+	var fields fieldErrors
+	switch {
+	case req.FromCustomerID == uuid.Nil:
+		fields.add("from_customer_id", "from_customer_id is required")
+	case req.ToCustomerID == uuid.Nil:
+		fields.add("to_customer_id", "to_customer_id is required")
+	case req.ToCustomerID == req.FromCustomerID:
+		fields.add("to_customer_id", "to_customer_id must not be the payer")
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := ledgerStore.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	r, err := postings().Reserve(ctx, tx, ledger.Transfer{
+		FromCustomerID: req.FromCustomerID,
+		ToCustomerID:   req.ToCustomerID,
+		Amount:         req.Amount,
+		Reference:      req.Reference,
+	})
+	if err != nil {
+		respondReservationError(c, err, "Failed to reserve transfer")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	if r.Status == ledger.TransferPendingApproval {
+		c.JSON(http.StatusAccepted, reservationResponse(r))
+		return
+	}
+	invalidateBalances(ctx, r.FromCustomerID)
+
+	c.JSON(http.StatusCreated, reservationResponse(r))
+}
+
+// @Summary Get a reservation
+// @Description Get a reserve-then-settle transfer and whether it is still reserved, settled or released
+// @Tags transfers
+// @Produce json
+// @Param transfer_id path string true "Transfer ID" format(uuid)
+// @Success 200 {object} ReservationResponse "Reservation"
+// @Failure 400 {object} ErrorResponse "Invalid transfer ID"
+// @Failure 404 {object} ErrorResponse "Reservation not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /transfers/reservations/{transfer_id} [get]
+func GetReservation(c *gin.Context) {
+	transferID, err := uuid.Parse(c.Param("transfer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transfer ID"})
+		return
+	}
+	t, err := ledgerStore.GetTransfer(c.Request.Context(), transferID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && t.Status == ledger.TransferCompleted) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Reservation not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reservation"})
+		return
+	}
+	c.JSON(http.StatusOK, ReservationResponse{
+		TransferID:     t.ID,
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+		Status:         t.Status,
+		CreatedAt:      t.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// @Summary Settle a reservation
+// @Description Complete a reserved transfer: the held amount is credited to the payee with a transfer_in, and a transfer.completed event is sent. A credit the payee's account refuses leaves the reservation open, so it can still be released.
+// @Tags transfers
+// @Produce json
+// @Param transfer_id path string true "Transfer ID" format(uuid)
+// @Success 200 {object} ReservationResponse "Reservation settled"
+// @Failure 400 {object} ErrorResponse "Invalid transfer ID or credit refused by the payee's account"
+// @Failure 404 {object} ErrorResponse "Reservation not found"
+// @Failure 409 {object} ErrorResponse "Reservation already settled or released"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /transfers/reservations/{transfer_id}/settle [post]
+func SettleReservation(c *gin.Context) {
+	closeReservation(c, (*ledger.Service).Settle, "Failed to settle reservation")
+}
+
+// @Summary Release a reservation
+// @Description Cancel a reserved transfer: the held amount is returned to the payer with a transfer_release, and a transfer.released event is sent
+// @Tags transfers
+// @Produce json
+// @Param transfer_id path string true "Transfer ID" format(uuid)
+// @Success 200 {object} ReservationResponse "Reservation released"
+// @Failure 400 {object} ErrorResponse "Invalid transfer ID"
+// @Failure 404 {object} ErrorResponse "Reservation not found"
+// @Failure 409 {object} ErrorResponse "Reservation already settled or released"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /transfers/reservations/{transfer_id}/release [post]
+func ReleaseReservation(c *gin.Context) {
+	closeReservation(c, (*ledger.Service).Release, "Failed to release reservation")
+}
+
+// closeReservation settles or releases the reservation in the path
+func closeReservation(c *gin.Context, step func(*ledger.Service, context.Context, store.Tx, uuid.UUID) (ledger.Reservation, error), failure string) {
+	transferID, err := uuid.Parse(c.Param("transfer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transfer ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := ledgerStore.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	r, err := step(postings(), ctx, tx, transferID)
+	if err != nil {
+		respondReservationError(c, err, failure)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, r.FromCustomerID, r.ToCustomerID)
+
+	c.JSON(http.StatusOK, reservationResponse(r))
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+
+	ctx := context.Background()
+	customer := func(balance float64) uuid.UUID {
+		c := store.Customer{ID: uuid.New(), Name: "Test", Balance: balance, AccountType: "checking", Timezone: "UTC"}
+		require.NoError(t, memory.CreateCustomer(ctx, &c))
+		return c.ID
+	}
+	payer, payee := customer(100), customer(0)
+
+	r := gin.New()
+	r.POST("/transfers/reservations", CreateReservation)
+	r.GET("/transfers/reservations/:transfer_id", GetReservation)
+	r.POST("/transfers/reservations/:transfer_id/settle", SettleReservation)
+	r.POST("/transfers/reservations/:transfer_id/release", ReleaseReservation)
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	reserve := func(amount float64) ReservationResponse {
+		w := send("POST", "/transfers/reservations", map[string]interface{}{
+			"from_customer_id": payer, "to_customer_id": payee, "amount": amount, "reference": "Order 3",
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp ReservationResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	held := reserve(30)
+	assert.Equal(t, "reserved", held.Status)
+	require.NotNil(t, held.FromBalance)
+	assert.Equal(t, float64(70), *held.FromBalance)
+
+	w := send("GET", "/transfers/reservations/"+held.TransferID.String(), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"reserved"`)
+
+	w = send("POST", "/transfers/reservations/"+held.TransferID.String()+"/settle", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var settled ReservationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settled))
+	assert.Equal(t, "settled", settled.Status)
+	require.NotNil(t, settled.ToBalance)
+	assert.Equal(t, float64(30), *settled.ToBalance)
+
+	w = send("POST", "/transfers/reservations/"+held.TransferID.String()+"/release", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "reservation_closed")
+
+	held = reserve(20)
+	w = send("POST", "/transfers/reservations/"+held.TransferID.String()+"/release", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	balance, err := memory.GetBalance(ctx, payer)
+	require.NoError(t, err)
+	assert.Equal(t, float64(70), balance.Amount)
+
+	w = send("POST", "/transfers/reservations", map[string]interface{}{
+		"from_customer_id": payer, "to_customer_id": payee, "amount": 500,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Insufficient balance")
+
+	w = send("POST", "/transfers/reservations", map[string]interface{}{
+		"from_customer_id": payer, "to_customer_id": payer, "amount": 5,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "to_customer_id")
+
+	assert.Equal(t, http.StatusNotFound, send("POST", "/transfers/reservations/"+uuid.NewString()+"/settle", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/transfers/reservations/"+uuid.NewString(), nil).Code)
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/transfers/reservations/nope", nil).Code)
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/saga"
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	sagaActions map[string]saga.Action
+)
+
+// InitSagas sets the actions in other services that sagas can run, by name
+func InitSagas(actions map[string]saga.Action) {
+	sagaActions = actions
+}
+
+// SagaRequest represents the payload for starting a saga
+type SagaRequest struct {
+	CustomerID uuid.UUID `json:"customer_id" binding:"required" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	// Type is a postable transaction type, posted before any action runs
+	Type      string  `json:"type" binding:"required" example:"payout"`
+	Amount    float64 `json:"amount" binding:"required,money" example:"250" minimum:"0.01"`
+	Reference string  `json:"reference,omitempty" binding:"max=140" example:"Payout 2025-04-08" maxLength:"140"`
+	// Actions are run in order once the posting succeeds
+	Actions []string `json:"actions" binding:"required,min=1,max=10" example:"payout" maxItems:"10"`
+}
+
+func sagaCoordinator() *saga.Coordinator {
+	return saga.NewCoordinator(sagaLedger{}, sagaActions, sagaStore{})
+}
+
+// sagaStore keeps sagas in Postgres, the steps as JSON
+type sagaStore struct{}
+
+func (sagaStore) Save(ctx context.Context, s *saga.Saga) error {
+	steps, err := json.Marshal(s.Steps)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx,
+		`INSERT INTO sagas (id, customer_id, type, amount, reference, status, error, transaction_id, reversal_transaction_id, steps, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, error = EXCLUDED.error, transaction_id = EXCLUDED.transaction_id,
+			reversal_transaction_id = EXCLUDED.reversal_transaction_id, steps = EXCLUDED.steps, updated_at = EXCLUDED.updated_at`,
+		s.ID, s.CustomerID, s.Type, s.Amount, nullableString(s.Reference), s.Status, nullableString(s.Error),
+		s.TransactionID, s.ReversalID, steps, s.CreatedAt, s.UpdatedAt)
+	return err
+}
+
+const sagaColumns = "id, customer_id, type, amount, COALESCE(reference, ''), status, COALESCE(error, ''), transaction_id, reversal_transaction_id, steps, created_at, updated_at"
+
+func scanSaga(row pgx.Row) (*saga.Saga, error) {
+	var s saga.Saga
+	var steps []byte
+	if err := row.Scan(&s.ID, &s.CustomerID, &s.Type, &s.Amount, &s.Reference, &s.Status, &s.Error,
+		&s.TransactionID, &s.ReversalID, &steps, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(steps, &s.Steps); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// sagaLedger posts a saga's transaction like any other, and reverses it
+// with a system posting in the opposite direction
+type sagaLedger struct{}
+
+func (sagaLedger) Post(ctx context.Context, s *saga.Saga) (uuid.UUID, error) {
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID: s.CustomerID,
+		Type:       s.Type,
+		Amount:     s.Amount,
+		Reference:  s.Reference,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	invalidateBalances(ctx, s.CustomerID)
+	// Actions must not run on money that has not moved; a held transaction
+	// goes through review like any other
+	if result.Status != ledger.StatusPosted {
+		return uuid.Nil, fmt.Errorf("transaction %s was %s", result.TransactionID, result.Status)
+	}
+	return result.TransactionID, nil
+}
+
+func (sagaLedger) Reverse(ctx context.Context, s *saga.Saga) (uuid.UUID, error) {
+	tx, err := ledgerStore.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	transactionID, err := postReversal(ctx, tx, s.CustomerID, s.Type, s.Amount, s.Reference)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
+	invalidateBalances(ctx, s.CustomerID)
+	return transactionID, nil
+}
+
+// postReversal books, within tx, a system posting undoing a posting of
+// originalType: a reversal_credit for a debit and a reversal_debit for a
+// credit
+func postReversal(ctx context.Context, tx store.Tx, customerID uuid.UUID, originalType string, amount float64, reference string) (uuid.UUID, error) {
+	account, err := tx.LockCustomer(ctx, customerID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	original := directionOf(originalType)
+	reversalType, direction := "reversal_debit", txtype.Debit
+	if original == txtype.Debit {
+		// Giving back a debit must not be refused, even when the customer
+		// has since paid off a credit account
+		reversalType, direction = "reversal_credit", txtype.Credit
+		account.AllowNegative = true
+	}
+	balance, err := ledger.Apply(account, direction, amount)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	transactionID := uuid.New()
+	if err := tx.SetBalance(ctx, customerID, balance); err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.InsertTransaction(ctx, &store.Transaction{
+		ID:         transactionID,
+		CustomerID: customerID,
+		Type:       reversalType,
+		Amount:     amount,
+		Status:     ledger.StatusPosted,
+		Reference:  reference,
+	}); err != nil {
+		return uuid.Nil, err
+	}
+	if pg, ok := pgxTx(tx); ok {
+		// Take the posting back off the general ledger account its type
+		// books against
+		if t, ok := transactionTypes.Lookup(originalType); ok && t.GLAccount != "" {
+			if err := postGLEntry(ctx, pg, t.GLAccount, &transactionID, string(original), amount); err != nil {
+				return uuid.Nil, err
+			}
+		}
+		if err := enqueueEvent(ctx, pg, events.TransactionPosted, &customerID, TransactionEventData{
+			TransactionID: transactionID,
+			Type:          reversalType,
+			Amount:        amount,
+			Status:        ledger.StatusPosted,
+		}); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return transactionID, nil
+}
+
+// @Summary Start a saga
+// @Description Post a transaction and then run actions in other services, such as a payout provider or card processor, in order. Each action is configured by name in SAGA_ACTIONS and called over HTTP. The saga is saved before and after every step. When an action fails, the steps taken are compensated last first (the failed one included, as a timeout leaves its outcome unknown) and the posting is reversed with a reversal_credit or reversal_debit. The outcome is in the returned saga's status: completed, failed (the posting was refused, so nothing ran), compensated, or compensation_failed (an operator can retry it).
+// @Tags sagas
+// @Accept json
+// @Produce json
+// @Param saga body SagaRequest true "Saga"
+// @Success 201 {object} saga.Saga "Saga run; see its status for the outcome"
+// @Failure 400 {object} ErrorResponse "Invalid input, a type that cannot be posted or an unknown action"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /sagas [post]
+func CreateSaga(c *gin.Context) {
+	var req SagaRequest
+	if !bindRequest(c, &req, "Invalid input: customer_id, type, amount and actions are required") {
+		return
+	}
+	var fields fieldErrors
+	if t, ok := transactionTypes.Lookup(req.Type); !ok || !t.Postable {
+		fields.add("type", "type must be a postable transaction type")
+	}
+	coordinator := sagaCoordinator()
+	for i, name := range req.Actions {
+		if !coordinator.Known(name) {
+			fields.add(fmt.Sprintf("actions[%d]", i), fmt.Sprintf("actions[%d] is not a configured action", i))
+		}
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	// The saga runs to the end even if the client goes away, so it is never
+	// left between steps
+	ctx := context.WithoutCancel(c.Request.Context())
+	if _, err := ledgerStore.GetCustomer(ctx, req.CustomerID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+
+	s := saga.New(req.CustomerID, req.Type, req.Amount, req.Reference, req.Actions)
+	if err := coordinator.Run(ctx, s); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to save saga"})
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// @Summary Get a saga
+// @Description Get a saga's status and the outcome of each of its steps
+// @Tags sagas
+// @Produce json
+// @Param saga_id path string true "Saga ID" format(uuid)
+// @Success 200 {object} saga.Saga "Saga"
+// @Failure 400 {object} ErrorResponse "Invalid saga ID"
+// @Failure 404 {object} ErrorResponse "Saga not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /sagas/{saga_id} [get]
+func GetSaga(c *gin.Context) {
+	sagaID, err := uuid.Parse(c.Param("saga_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid saga ID"})
+		return
+	}
+	s, err := scanSaga(db.QueryRow(c.Request.Context(), "SELECT "+sagaColumns+" FROM sagas WHERE id = $1", sagaID))
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Saga not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch saga"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// @Summary Retry a saga's compensation
+// @Description Resume undoing a saga whose compensation failed, from the step that failed. Sagas left running or compensating for over five minutes, as after a crash, can be compensated too. The saga is claimed first, so concurrent retries cannot undo a step twice.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param saga_id path string true "Saga ID" format(uuid)
+// @Success 200 {object} saga.Saga "Compensation run; see the saga's status for the outcome"
+// @Failure 400 {object} ErrorResponse "Invalid saga ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Saga not found"
+// @Failure 409 {object} ErrorResponse "Saga is not waiting for compensation"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/sagas/{saga_id}/compensate [post]
+func CompensateSaga(c *gin.Context) {
+	sagaID, err := uuid.Parse(c.Param("saga_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid saga ID"})
+		return
+	}
+	ctx := context.WithoutCancel(c.Request.Context())
+	s, err := scanSaga(db.QueryRow(ctx,
+		`UPDATE sagas SET status = 'compensating', updated_at = NOW()
+		WHERE id = $1 AND (status = 'compensation_failed' OR (status IN ('running', 'compensating') AND updated_at < NOW() - INTERVAL '5 minutes'))
+		RETURNING `+sagaColumns,
+		sagaID))
+	if err == pgx.ErrNoRows {
+		var exists bool
+		if err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM sagas WHERE id = $1)", sagaID).Scan(&exists); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch saga"})
+			return
+		}
+		if !exists {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Saga not found"})
+			return
+		}
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Saga is not waiting for compensation"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch saga"})
+		return
+	}
+	if err := sagaCoordinator().Compensate(ctx, s); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to save saga"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
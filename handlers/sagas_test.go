@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/saga"
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSagaAction struct {
+	err         error
+	compensated *int
+}
+
+func (a testSagaAction) Execute(ctx context.Context, r saga.Request) (string, error) {
+	return "ext_1", a.err
+}
+
+func (a testSagaAction) Compensate(ctx context.Context, r saga.Request) error {
+	*a.compensated++
+	return nil
+}
+
+func TestCreateSaga(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	defer InitSagas(nil)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 500, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	compensated := 0
+	InitSagas(map[string]saga.Action{
+		"payout":   testSagaAction{compensated: &compensated},
+		"declined": testSagaAction{err: errors.New("declined"), compensated: &compensated},
+	})
+
+	router.POST("/sagas", CreateSaga)
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/sagas", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	anyArgs := make([]interface{}, 12)
+	for i := range anyArgs {
+		anyArgs[i] = pgxmock.AnyArg()
+	}
+	expectSaves := func(n int) {
+		for i := 0; i < n; i++ {
+			mock.ExpectExec("INSERT INTO sagas").WithArgs(anyArgs...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+	}
+
+	// Running, posted, action running, action done, completed
+	expectSaves(5)
+	w := send(map[string]interface{}{"customer_id": customer.ID, "type": "debit", "amount": 200, "actions": []string{"payout"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var s saga.Saga
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, saga.StatusCompleted, s.Status)
+	assert.Equal(t, "ext_1", s.Steps[1].ExternalID)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, float64(300), balance.Amount)
+
+	// A failed action is compensated and the debit given back
+	expectSaves(7)
+	w = send(map[string]interface{}{"customer_id": customer.ID, "type": "debit", "amount": 100, "actions": []string{"declined"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, saga.StatusCompensated, s.Status)
+	assert.Equal(t, "declined failed: declined", s.Error)
+	assert.NotNil(t, s.ReversalID)
+	assert.Equal(t, 1, compensated)
+	balance, _ = memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, float64(300), balance.Amount)
+	history, err := memory.ListTransactions(ctx, customer.ID, store.ListOptions{})
+	require.NoError(t, err)
+	var types []string
+	for _, tr := range history {
+		types = append(types, tr.Type)
+	}
+	assert.ElementsMatch(t, []string{"debit", "debit", "reversal_credit"}, types)
+
+	w = send(map[string]interface{}{"customer_id": customer.ID, "type": "debit", "amount": 10, "actions": []string{"wire"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "actions[0] is not a configured action")
+	w = send(map[string]interface{}{"customer_id": customer.ID, "type": "transfer_out", "amount": 10, "actions": []string{"payout"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "postable")
+	w = send(map[string]interface{}{"customer_id": uuid.New(), "type": "debit", "amount": 10, "actions": []string{"payout"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSaga(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.GET("/sagas/:saga_id", GetSaga)
+	router.POST("/admin/sagas/:saga_id/compensate", CompensateSaga)
+
+	sagaID, customerID, transactionID := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now().UTC()
+	columns := []string{"id", "customer_id", "type", "amount", "reference", "status", "error", "transaction_id", "reversal_transaction_id", "steps", "created_at", "updated_at"}
+	mock.ExpectQuery(`SELECT .+ FROM sagas WHERE id = \$1`).
+		WithArgs(sagaID).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(sagaID, customerID, "debit", float64(200), "", saga.StatusCompleted, "",
+			&transactionID, (*uuid.UUID)(nil), []byte(`[{"name":"ledger","status":"done"},{"name":"payout","status":"done","external_id":"po_1"}]`), now, now))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/sagas/"+sagaID.String(), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var s saga.Saga
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, saga.StatusCompleted, s.Status)
+	require.Len(t, s.Steps, 2)
+	assert.Equal(t, "po_1", s.Steps[1].ExternalID)
+
+	// A completed saga has nothing to compensate
+	mock.ExpectQuery(`UPDATE sagas SET status = 'compensating'`).
+		WithArgs(sagaID).
+		WillReturnRows(pgxmock.NewRows(columns))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM sagas WHERE id = \$1\)`).
+		WithArgs(sagaID).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/sagas/"+sagaID.String()+"/compensate", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	missing := uuid.New()
+	mock.ExpectQuery(`SELECT .+ FROM sagas WHERE id = \$1`).
+		WithArgs(missing).
+		WillReturnRows(pgxmock.NewRows(columns))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/sagas/"+missing.String(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
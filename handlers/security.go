@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+)
+
+// RecordLockout publishes a security.lockout event through the outbox, so
+// webhook subscribers and the message bus hear about brute-force attempts.
+// It satisfies middleware.LockoutConfig's OnLockout.
+func RecordLockout(ev middleware.LockoutEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := enqueueEvent(ctx, db, events.SecurityLockout, nil, ev); err != nil {
+		log.Printf("Failed to record lockout of %s %s: %v", ev.Subject, ev.Identifier, err)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLockout(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+
+	expectEvent(events.SecurityLockout)
+	RecordLockout(middleware.LockoutEvent{
+		Subject:     "credential",
+		Identifier:  "#3f9a1c0e5b7d",
+		Failures:    20,
+		LockedUntil: time.Now().Add(15 * time.Minute),
+		Path:        "/v1/admin/summary",
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
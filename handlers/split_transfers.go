@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"ledger-service/ledger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSplitCredits caps the recipients of one split transfer
+const maxSplitCredits = 100
+
+// SplitCreditRequest is one recipient of a split transfer
+type SplitCreditRequest struct {
+	CustomerID uuid.UUID `json:"customer_id" example:"6ba7b810-9dad-11d1-80b4-00c04fd430c8" format:"uuid"`
+	Amount     float64   `json:"amount" example:"60" minimum:"0.01"`
+	// Reference defaults to the split's reference
+	Reference string `json:"reference,omitempty" example:"Order 1042" maxLength:"140"`
+}
+
+// SplitTransferRequest represents the payload for a split transfer
+type SplitTransferRequest struct {
+	FromCustomerID uuid.UUID `json:"from_customer_id" binding:"uuid" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	// Amount is debited once from the payer; the credits must add up to it
+	Amount    float64              `json:"amount" binding:"required,money" example:"100" minimum:"0.01"`
+	Reference string               `json:"reference,omitempty" binding:"max=140" example:"Payout 2025-04-08" maxLength:"140"`
+	Credits   []SplitCreditRequest `json:"credits" binding:"required" maxItems:"100"`
+}
+
+// SplitTransferLeg is one credit of a split transfer
+type SplitTransferLeg struct {
+	TransferID   uuid.UUID `json:"transfer_id" format:"uuid"`
+	ToCustomerID uuid.UUID `json:"to_customer_id" format:"uuid"`
+	Amount       float64   `json:"amount" example:"60"`
+	Reference    string    `json:"reference,omitempty" example:"Order 1042"`
+}
+
+// SplitTransferResponse represents a split transfer
+// @Description One debit from the payer and a transfer to each recipient, sharing a batch ID. A pending_approval split has moved nothing yet.
+type SplitTransferResponse struct {
+	BatchID        uuid.UUID          `json:"batch_id" format:"uuid"`
+	FromCustomerID uuid.UUID          `json:"from_customer_id" format:"uuid"`
+	Amount         float64            `json:"amount" example:"100"`
+	Status         string             `json:"status" example:"completed" enums:"completed,pending_approval"`
+	FromBalance    float64            `json:"from_balance" example:"900"`
+	Transfers      []SplitTransferLeg `json:"transfers"`
+}
+
+// validateSplitCredits checks each credit and that they add up to amount
+func validateSplitCredits(req SplitTransferRequest) fieldErrors {
+	var fields fieldErrors
+	if len(req.Credits) > maxSplitCredits {
+		fields.add("credits", fmt.Sprintf("credits may list at most %d recipients", maxSplitCredits))
+		return fields
+	}
+	var total int64
+	for i, credit := range req.Credits {
+		field := fmt.Sprintf("credits[%d]", i)
+		switch {
+		case credit.CustomerID == uuid.Nil:
+			fields.add(field+".customer_id", field+".customer_id is required")
+		case credit.CustomerID == req.FromCustomerID:
+			fields.add(field+".customer_id", field+".customer_id must not be the payer")
+		}
+		if msg := validateMoney(field+".amount", credit.Amount); msg != "" {
+			fields.add(field+".amount", msg)
+		}
+		if len(credit.Reference) > 140 {
+			fields.add(field+".reference", field+".reference must be at most 140 characters")
+		}
+		total += int64(math.Round(credit.Amount * 100))
+	}
+	if len(fields) == 0 && total != int64(math.Round(req.Amount*100)) {
+		fields.add("credits", "credits must add up to amount")
+	}
+	return fields
+}
+
+// @Summary Create a split transfer
+// @Description Debit one customer once and credit several recipients with the amounts given, such as a marketplace paying out its sellers. The credits must add up to the amount. Everything is posted in one database transaction: the payer gets a single transfer_out for the whole amount, and each recipient a transfer_in, recorded as a transfer sharing the batch ID. All accounts must share a base currency. A recipient may appear more than once. The payer is held to the same account policy and limits as a debit: when its policy requires approval, as for escrow accounts, nothing moves until the payer's transfer_out is approved.
+// @Tags transfers
+// @Accept json
+// @Produce json
+// @Param split body SplitTransferRequest true "Split transfer"
+// @Success 201 {object} SplitTransferResponse "Split transfer posted"
+// @Success 202 {object} SplitTransferResponse "Split transfer awaiting approval"
+// @Failure 400 {object} ErrorResponse "Invalid input, credits not adding up, insufficient balance or accounts in different currencies"
+// @Failure 403 {object} ErrorResponse "Payer refused by its account policy or limits, or dormant"
+// @Failure 404 {object} ErrorResponse "Payer or recipient not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /transfers/split [post]
+func CreateSplitTransfer(c *gin.Context) {
+	var req SplitTransferRequest
+	if !bindRequest(c, &req, "Invalid input: from_customer_id, amount and credits are required") {
+		return
+	}
+	if fields := validateSplitCredits(req); len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	split := ledger.Split{FromCustomerID: req.FromCustomerID, Amount: req.Amount, Reference: req.Reference}
+	for _, credit := range req.Credits {
+		split.Credits = append(split.Credits, ledger.SplitCredit{ToCustomerID: credit.CustomerID, Amount: credit.Amount, Reference: credit.Reference})
+	}
+
+	ctx := c.Request.Context()
+	tx, err := ledgerStore.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := postings().SplitTransfer(ctx, tx, split)
+	if err != nil {
+		var violation *ledger.ViolationError
+		switch {
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+		case errors.Is(err, ledger.ErrOverpayment):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payment exceeds the amount owed"})
+		case errors.Is(err, ledger.ErrCurrencyMismatch):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payer and recipient accounts use different currencies"})
+		case errors.Is(err, ledger.ErrPayerNotFound):
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Payer not found"})
+		case errors.Is(err, ledger.ErrPayeeNotFound):
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Recipient not found"})
+		case errors.As(err, &violation):
+			respondError(c, http.StatusForbidden, ErrorResponse{Error: violation.Message})
+		default:
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post split transfer"})
+		}
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	resp := SplitTransferResponse{
+		BatchID:        result.BatchID,
+		FromCustomerID: req.FromCustomerID,
+		Amount:         req.Amount,
+		Status:         result.Status,
+		FromBalance:    result.FromBalance,
+	}
+	parties := []uuid.UUID{req.FromCustomerID}
+	for _, leg := range result.Transfers {
+		resp.Transfers = append(resp.Transfers, SplitTransferLeg{
+			TransferID:   leg.TransferID,
+			ToCustomerID: leg.ToCustomerID,
+			Amount:       leg.Amount,
+			Reference:    leg.Reference,
+		})
+		parties = append(parties, leg.ToCustomerID)
+	}
+	if result.Status == ledger.TransferPendingApproval {
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+	invalidateBalances(ctx, parties...)
+
+	c.JSON(http.StatusCreated, resp)
+}
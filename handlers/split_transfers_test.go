@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSplitTransfer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+
+	ctx := context.Background()
+	customer := func(balance float64) uuid.UUID {
+		c := store.Customer{ID: uuid.New(), Name: "Test", Balance: balance, AccountType: "checking", Timezone: "UTC"}
+		require.NoError(t, memory.CreateCustomer(ctx, &c))
+		return c.ID
+	}
+	payer, seller, other := customer(100), customer(0), customer(0)
+
+	r := gin.New()
+	r.POST("/transfers/split", CreateSplitTransfer)
+	send := func(body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/transfers/split", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(map[string]interface{}{
+		"from_customer_id": payer,
+		"amount":           75,
+		"reference":        "Payout",
+		"credits": []map[string]interface{}{
+			{"customer_id": seller, "amount": 50},
+			{"customer_id": other, "amount": 25, "reference": "Order 7"},
+		},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp SplitTransferResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEqual(t, uuid.Nil, resp.BatchID)
+	assert.Equal(t, float64(25), resp.FromBalance)
+	require.Len(t, resp.Transfers, 2)
+	assert.Equal(t, "Payout", resp.Transfers[0].Reference)
+	assert.Equal(t, "Order 7", resp.Transfers[1].Reference)
+	balance, err := memory.GetBalance(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, float64(25), balance.Amount)
+
+	w = send(map[string]interface{}{
+		"from_customer_id": payer,
+		"amount":           10,
+		"credits":          []map[string]interface{}{{"customer_id": seller, "amount": 9.99}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "credits must add up to amount")
+
+	w = send(map[string]interface{}{
+		"from_customer_id": payer,
+		"amount":           10,
+		"credits":          []map[string]interface{}{{"customer_id": payer, "amount": 10}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "credits[0].customer_id")
+
+	w = send(map[string]interface{}{
+		"from_customer_id": payer,
+		"amount":           50,
+		"credits":          []map[string]interface{}{{"customer_id": seller, "amount": 50}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Insufficient balance")
+
+	w = send(map[string]interface{}{
+		"from_customer_id": payer,
+		"amount":           2,
+		"credits":          []map[string]interface{}{{"customer_id": seller, "amount": 1}, {"customer_id": uuid.New(), "amount": 1}},
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	balance, err = memory.GetBalance(ctx, payer)
+	require.NoError(t, err)
+	assert.Equal(t, float64(25), balance.Amount)
+}
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"ledger-service/schedule"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// StandingOrder represents a recurring transfer to another customer
+// @Description Recurring transfer between two customers
+type StandingOrder struct {
+	ID                uuid.UUID `json:"standing_order_id" format:"uuid"`
+	CustomerID        uuid.UUID `json:"customer_id" format:"uuid"`
+	PayeeCustomerID   uuid.UUID `json:"payee_customer_id" format:"uuid"`
+	Amount            float64   `json:"amount" example:"250"`
+	Frequency         string    `json:"frequency" example:"monthly" enums:"daily,weekly,monthly"`
+	StartDate         string    `json:"start_date" example:"2025-05-01" format:"date"`
+	EndDate           string    `json:"end_date,omitempty" example:"2025-12-01" format:"date"`
+	NextRunDate       string    `json:"next_run_date" example:"2025-05-01" format:"date"`
+	Reference         string    `json:"reference,omitempty" example:"Rent"`
+	Status            string    `json:"status" example:"active" enums:"active,paused,cancelled,completed"`
+	FailedAttempts    int       `json:"failed_attempts" example:"0"`
+	LastFailureReason string    `json:"last_failure_reason,omitempty" example:"Insufficient balance"`
+}
+
+// StandingOrderRequest represents the payload for creating a standing order
+type StandingOrderRequest struct {
+	PayeeCustomerID uuid.UUID `json:"payee_customer_id" binding:"required_without=PayeeAlias" format:"uuid"`
+	PayeeAlias      string    `json:"payee_alias,omitempty" binding:"omitempty,alias" example:"@landlord"`
+	Amount          float64   `json:"amount" binding:"required,money" example:"250" minimum:"0.01"`
+	Frequency       string    `json:"frequency" binding:"required" example:"monthly" enums:"daily,weekly,monthly"`
+	StartDate       string    `json:"start_date" binding:"required" example:"2025-05-01" format:"date"`
+	EndDate         string    `json:"end_date,omitempty" example:"2025-12-01" format:"date"`
+	Reference       string    `json:"reference,omitempty" binding:"max=140" example:"Rent" maxLength:"140"`
+}
+
+// dateLayout is the wire format for calendar dates
+const dateLayout = "2006-01-02"
+
+var (
+	standingOrderMaxRetries = 3
+)
+
+// InitStandingOrders sets how many consecutive days a standing order retries
+// a payment the ledger refused before the occurrence is skipped
+func InitStandingOrders(maxRetries int) {
+	standingOrderMaxRetries = maxRetries
+}
+
+const standingOrderColumns = "id, customer_id, payee_customer_id, amount, frequency, start_date, end_date, next_run_date, COALESCE(reference, ''), status, failed_attempts, COALESCE(last_failure_reason, '')"
+
+func scanStandingOrder(row pgx.Row) (StandingOrder, error) {
+	var o StandingOrder
+	var startDate, nextRunDate time.Time
+	var endDate *time.Time
+	err := row.Scan(&o.ID, &o.CustomerID, &o.PayeeCustomerID, &o.Amount, &o.Frequency, &startDate, &endDate,
+		&nextRunDate, &o.Reference, &o.Status, &o.FailedAttempts, &o.LastFailureReason)
+	if err != nil {
+		return StandingOrder{}, err
+	}
+	o.StartDate = startDate.Format(dateLayout)
+	o.NextRunDate = nextRunDate.Format(dateLayout)
+	if endDate != nil {
+		o.EndDate = endDate.Format(dateLayout)
+	}
+	return o, nil
+}
+
+// parseScheduleDate parses an optional YYYY-MM-DD date
+func parseScheduleDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// @Summary Create a standing order
+// @Description Schedule a recurring transfer from the customer to another customer. Transfers run on the start date and then on every daily, weekly or monthly occurrence until the end date. The payee is given by payee_customer_id or by payee_alias.
+// @Tags standing-orders
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Paying customer ID" format(uuid)
+// @Param standing_order body StandingOrderRequest true "Standing order details"
+// @Success 201 {object} StandingOrder "Standing order created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer or payee not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/standing-orders [post]
+func CreateStandingOrder(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req StandingOrderRequest
+	if !bindRequest(c, &req, "Invalid input: payee_customer_id or payee_alias, amount (> 0), frequency and start_date are required") {
+		return
+	}
+	if !resolveParty(c, &req.PayeeCustomerID, req.PayeeAlias, "Payee not found") {
+		return
+	}
+	if !schedule.Valid(schedule.Frequency(req.Frequency)) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: frequency must be one of daily, weekly, monthly"})
+		return
+	}
+	if req.PayeeCustomerID == customerID {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: payee must be a different customer"})
+		return
+	}
+	startDate, err := parseScheduleDate(req.StartDate)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: start_date must be YYYY-MM-DD"})
+		return
+	}
+	if startDate.Before(schedule.Day(time.Now())) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: start_date cannot be in the past"})
+		return
+	}
+	endDate, err := parseScheduleDate(req.EndDate)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: end_date must be YYYY-MM-DD"})
+		return
+	}
+	if endDate != nil && endDate.Before(*startDate) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: end_date must not be before start_date"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !verifyTransferParties(c, customerID, req.PayeeCustomerID, "Customer not found", "Payee not found") {
+		return
+	}
+
+	order := StandingOrder{
+		ID:              uuid.New(),
+		CustomerID:      customerID,
+		PayeeCustomerID: req.PayeeCustomerID,
+		Amount:          req.Amount,
+		Frequency:       req.Frequency,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		NextRunDate:     req.StartDate,
+		Reference:       req.Reference,
+		Status:          "active",
+	}
+	_, err = db.Exec(ctx,
+		"INSERT INTO standing_orders (id, customer_id, payee_customer_id, amount, frequency, start_date, end_date, next_run_date, reference) VALUES ($1, $2, $3, $4, $5, $6, $7, $6, $8)",
+		order.ID, customerID, req.PayeeCustomerID, req.Amount, req.Frequency, startDate, endDate, nullableString(req.Reference))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create standing order"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// @Summary List standing orders
+// @Description List the standing orders a customer pays
+// @Tags standing-orders
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} StandingOrder "Standing orders"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/standing-orders [get]
+func ListStandingOrders(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+standingOrderColumns+" FROM standing_orders WHERE customer_id = $1 ORDER BY created_at",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch standing orders"})
+		return
+	}
+	defer rows.Close()
+
+	orders := []StandingOrder{}
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan standing order"})
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// @Summary Pause a standing order
+// @Description Stop an active standing order from running until it is resumed
+// @Tags standing-orders
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param standing_order_id path string true "Standing order ID" format(uuid)
+// @Success 200 {object} StandingOrder "Standing order paused"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Standing order not found"
+// @Failure 409 {object} ErrorResponse "Standing order is not active"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/standing-orders/{standing_order_id}/pause [post]
+func PauseStandingOrder(c *gin.Context) {
+	changeStandingOrderStatus(c, "paused", "active")
+}
+
+// @Summary Resume a standing order
+// @Description Resume a paused standing order. Occurrences missed while paused are skipped.
+// @Tags standing-orders
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param standing_order_id path string true "Standing order ID" format(uuid)
+// @Success 200 {object} StandingOrder "Standing order resumed"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Standing order not found"
+// @Failure 409 {object} ErrorResponse "Standing order is not paused"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/standing-orders/{standing_order_id}/resume [post]
+func ResumeStandingOrder(c *gin.Context) {
+	changeStandingOrderStatus(c, "active", "paused")
+}
+
+// @Summary Cancel a standing order
+// @Description Permanently cancel a standing order
+// @Tags standing-orders
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param standing_order_id path string true "Standing order ID" format(uuid)
+// @Success 200 {object} StandingOrder "Standing order cancelled"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Standing order not found"
+// @Failure 409 {object} ErrorResponse "Standing order has already ended"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/standing-orders/{standing_order_id} [delete]
+func CancelStandingOrder(c *gin.Context) {
+	changeStandingOrderStatus(c, "cancelled", "active", "paused")
+}
+
+// changeStandingOrderStatus moves a standing order to status when it is
+// currently in one of from
+func changeStandingOrderStatus(c *gin.Context, status string, from ...string) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	orderID, err := uuid.Parse(c.Param("standing_order_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid standing order ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	order, err := scanStandingOrder(tx.QueryRow(ctx,
+		"SELECT "+standingOrderColumns+" FROM standing_orders WHERE id = $1 AND customer_id = $2 FOR UPDATE",
+		orderID, customerID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Standing order not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get standing order"})
+		}
+		return
+	}
+
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || order.Status == s
+	}
+	if !allowed {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("Standing order is %s", order.Status)})
+		return
+	}
+
+	// Skip occurrences that fell due while the order was paused
+	if status == "active" {
+		next, _ := time.Parse(dateLayout, order.NextRunDate)
+		start, _ := time.Parse(dateLayout, order.StartDate)
+		today := schedule.Day(time.Now())
+		for next.Before(today) {
+			next = schedule.Next(next, schedule.Frequency(order.Frequency), start.Day())
+		}
+		order.NextRunDate = next.Format(dateLayout)
+		order.FailedAttempts = 0
+		order.LastFailureReason = ""
+		if order.EndDate != "" && order.NextRunDate > order.EndDate {
+			status = "completed"
+		}
+	}
+	order.Status = status
+
+	_, err = tx.Exec(ctx,
+		"UPDATE standing_orders SET status = $1, next_run_date = $2, retry_on = NULL, failed_attempts = $3, last_failure_reason = $4, updated_at = NOW() WHERE id = $5",
+		order.Status, order.NextRunDate, order.FailedAttempts, nullableString(order.LastFailureReason), orderID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update standing order"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// ProcessStandingOrders runs every standing order due on or before now's date
+// and returns how many were attempted. Orders are paged by ID, so one that
+// is still due after its run is not picked up again.
+func ProcessStandingOrders(ctx context.Context, now time.Time) (int, error) {
+	today := schedule.Day(now)
+	processed := 0
+	after := uuid.Nil
+	for {
+		rows, err := db.Query(ctx,
+			"SELECT id FROM standing_orders WHERE status = 'active' AND COALESCE(retry_on, next_run_date) <= $1 AND id > $2 ORDER BY id LIMIT 100",
+			today, after)
+		if err != nil {
+			return processed, err
+		}
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return processed, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return processed, err
+		}
+
+		for _, id := range ids {
+			if err := runStandingOrder(ctx, id, today); err != nil {
+				log.Printf("Standing order %s failed: %v", id, err)
+			}
+			processed++
+			after = id
+		}
+		if len(ids) < 100 {
+			return processed, nil
+		}
+	}
+}
+
+// runStandingOrder executes one occurrence of a standing order. A payment the
+// ledger refuses, for insufficient funds or a limit or account rule, is
+// recorded as a failed run and retried the next day up to
+// standingOrderMaxRetries times, after which the occurrence is skipped; the
+// payer is alerted on every failure. Other errors leave the order to the
+// next run.
+func runStandingOrder(ctx context.Context, id uuid.UUID, today time.Time) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// SKIP LOCKED lets several instances share the work without running an order twice
+	order, err := scanStandingOrder(tx.QueryRow(ctx,
+		"SELECT "+standingOrderColumns+" FROM standing_orders WHERE id = $1 AND status = 'active' AND COALESCE(retry_on, next_run_date) <= $2 FOR UPDATE SKIP LOCKED",
+		id, today))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	start, _ := time.Parse(dateLayout, order.StartDate)
+	next, _ := time.Parse(dateLayout, order.NextRunDate)
+	advance := func() {
+		next = schedule.Next(next, schedule.Frequency(order.Frequency), start.Day())
+		order.NextRunDate = next.Format(dateLayout)
+		order.FailedAttempts = 0
+		if order.EndDate != "" && order.NextRunDate > order.EndDate {
+			order.Status = "completed"
+		}
+	}
+
+	var alert string
+	var transferID *uuid.UUID
+	result, err := postTransfer(ctx, tx, order.CustomerID, order.PayeeCustomerID, order.Amount, order.Reference)
+	reason := transferRefusal(err)
+	switch {
+	case err == nil:
+		transferID = &result.TransferID
+		advance()
+		_, err = tx.Exec(ctx,
+			"UPDATE standing_orders SET next_run_date = $1, retry_on = NULL, failed_attempts = 0, last_failure_reason = NULL, status = $2, last_run_at = NOW(), updated_at = NOW() WHERE id = $3",
+			order.NextRunDate, order.Status, id)
+	case reason != "":
+		attempts := order.FailedAttempts + 1
+		var retryOn *time.Time
+		if attempts < standingOrderMaxRetries {
+			retry := today.AddDate(0, 0, 1)
+			retryOn = &retry
+			order.FailedAttempts = attempts
+			alert = fmt.Sprintf("Ledger alert: your standing order of %.2f could not be paid: %s. We will retry tomorrow.", order.Amount, reason)
+		} else {
+			advance()
+			alert = fmt.Sprintf("Ledger alert: your standing order of %.2f was skipped after %d failed attempts. Next payment: %s.", order.Amount, attempts, order.NextRunDate)
+		}
+		_, err = tx.Exec(ctx,
+			"UPDATE standing_orders SET next_run_date = $1, retry_on = $2, failed_attempts = $3, last_failure_reason = $4, status = $5, last_run_at = NOW(), updated_at = NOW() WHERE id = $6",
+			order.NextRunDate, retryOn, order.FailedAttempts, reason, order.Status, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	status := "executed"
+	if transferID == nil {
+		status = "failed"
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO standing_order_runs (id, standing_order_id, run_date, status, transfer_id, reason) VALUES ($1, $2, $3, $4, $5, $6)",
+		uuid.New(), id, today, status, transferID, nullableString(reason)); err != nil {
+		return err
+	}
+
+	if alert != "" {
+		if err := alertCustomer(ctx, tx, order.CustomerID, alert); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	invalidateBalances(ctx, order.CustomerID, order.PayeeCustomerID)
+	return nil
+}
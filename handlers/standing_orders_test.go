@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/schedule"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateStandingOrder(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/standing-orders", CreateStandingOrder)
+
+	customerID := uuid.New()
+	payeeID := uuid.New()
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name: "monthly order",
+			payload: map[string]interface{}{
+				"payee_customer_id": payeeID,
+				"amount":            250,
+				"frequency":         "monthly",
+				"start_date":        tomorrow,
+				"reference":         "Rent",
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				for _, id := range []uuid.UUID{customerID, payeeID} {
+					mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+						WithArgs(id).
+						WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+				}
+				mock.ExpectExec(`INSERT INTO standing_orders`).
+					WithArgs(pgxmock.AnyArg(), customerID, payeeID, float64(250), "monthly", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name: "payee by alias",
+			payload: map[string]interface{}{
+				"payee_alias": "@Landlord",
+				"amount":      250,
+				"frequency":   "monthly",
+				"start_date":  tomorrow,
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT customer_id FROM account_aliases WHERE alias = \$1`).
+					WithArgs("landlord").
+					WillReturnRows(pgxmock.NewRows([]string{"customer_id"}).AddRow(payeeID))
+				for _, id := range []uuid.UUID{customerID, payeeID} {
+					mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+						WithArgs(id).
+						WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+				}
+				mock.ExpectExec(`INSERT INTO standing_orders`).
+					WithArgs(pgxmock.AnyArg(), customerID, payeeID, float64(250), "monthly", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name: "unknown payee alias",
+			payload: map[string]interface{}{
+				"payee_alias": "@nobody",
+				"amount":      250,
+				"frequency":   "monthly",
+				"start_date":  tomorrow,
+			},
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT customer_id FROM account_aliases`).
+					WithArgs("nobody").
+					WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name: "payee ID and alias",
+			payload: map[string]interface{}{
+				"payee_customer_id": payeeID,
+				"payee_alias":       "@landlord",
+				"amount":            250,
+				"frequency":         "monthly",
+				"start_date":        tomorrow,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "payee in another currency",
+			payload: map[string]interface{}{
+				"payee_customer_id": payeeID,
+				"amount":            250,
+				"frequency":         "monthly",
+				"start_date":        tomorrow,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT currency FROM customers`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+				mock.ExpectQuery(`SELECT currency FROM customers`).
+					WithArgs(payeeID).
+					WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("EUR"))
+			},
+		},
+		{
+			name: "start date in the past",
+			payload: map[string]interface{}{
+				"payee_customer_id": payeeID,
+				"amount":            250,
+				"frequency":         "monthly",
+				"start_date":        "2020-01-01",
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "paying yourself",
+			payload: map[string]interface{}{
+				"payee_customer_id": customerID,
+				"amount":            250,
+				"frequency":         "weekly",
+				"start_date":        tomorrow,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name: "unknown frequency",
+			payload: map[string]interface{}{
+				"payee_customer_id": payeeID,
+				"amount":            250,
+				"frequency":         "yearly",
+				"start_date":        tomorrow,
+			},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/standing-orders", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp StandingOrder
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tomorrow, resp.NextRunDate)
+				assert.Equal(t, "active", resp.Status)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProcessStandingOrders(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitStandingOrders(3)
+	defer InitStandingOrders(3)
+
+	orderID := uuid.New()
+	payerID := uuid.New()
+	payeeID := uuid.New()
+	today := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "customer_id", "payee_customer_id", "amount", "frequency", "start_date", "end_date",
+		"next_run_date", "reference", "status", "failed_attempts", "last_failure_reason"}
+
+	expectDueOrder := func(failedAttempts int) {
+		mock.ExpectQuery(`SELECT id FROM standing_orders WHERE status = 'active' AND COALESCE\(retry_on, next_run_date\) <= \$1 AND id > \$2 ORDER BY id`).
+			WithArgs(today, uuid.Nil).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(orderID))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM standing_orders WHERE id = \$1 AND status = 'active' .* FOR UPDATE SKIP LOCKED`).
+			WithArgs(orderID, today).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(orderID, payerID, payeeID, float64(250), "monthly",
+				time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), nil, today, "Rent", "active", failedAttempts, ""))
+	}
+	feb28 := "2025-02-28"
+
+	t.Run("executes and advances", func(t *testing.T) {
+		expectDueOrder(0)
+		expectTransfer(payerID, payeeID, 1000, 10, 250)
+		mock.ExpectExec(`UPDATE standing_orders SET next_run_date = \$1, retry_on = NULL`).
+			WithArgs(feb28, "active", orderID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO standing_order_runs`).
+			WithArgs(pgxmock.AnyArg(), orderID, today, "executed", pgxmock.AnyArg(), (*string)(nil)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		n, err := ProcessStandingOrders(context.Background(), today.Add(9*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insufficient funds retries tomorrow", func(t *testing.T) {
+		expectDueOrder(0)
+		expectTransferLocks(payerID, payeeID, 100, 10)
+		tomorrow := today.AddDate(0, 0, 1)
+		mock.ExpectExec(`UPDATE standing_orders SET next_run_date = \$1, retry_on = \$2, failed_attempts = \$3, last_failure_reason = \$4`).
+			WithArgs("2025-01-31", &tomorrow, 1, "Insufficient balance", "active", orderID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		reason := "Insufficient balance"
+		mock.ExpectExec(`INSERT INTO standing_order_runs`).
+			WithArgs(pgxmock.AnyArg(), orderID, today, "failed", (*uuid.UUID)(nil), &reason).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		_, err := ProcessStandingOrders(context.Background(), today)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skips occurrence after max retries", func(t *testing.T) {
+		expectDueOrder(2)
+		expectTransferLocks(payerID, payeeID, 100, 10)
+		mock.ExpectExec(`UPDATE standing_orders SET next_run_date = \$1, retry_on = \$2, failed_attempts = \$3, last_failure_reason = \$4`).
+			WithArgs(feb28, (*time.Time)(nil), 0, "Insufficient balance", "active", orderID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO standing_order_runs`).
+			WithArgs(pgxmock.AnyArg(), orderID, today, "failed", (*uuid.UUID)(nil), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		_, err := ProcessStandingOrders(context.Background(), today)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("dormant payer is recorded and retried", func(t *testing.T) {
+		InitDormancy(DormancyConfig{Days: 365, FreezeDebits: true})
+		defer InitDormancy(DormancyConfig{})
+
+		expectDueOrder(0)
+		expectTransferLocks(payerID, payeeID, 1000, 10)
+		mock.ExpectQuery(`SELECT dormant_since IS NOT NULL FROM customers WHERE id = \$1`).
+			WithArgs(payerID).
+			WillReturnRows(pgxmock.NewRows([]string{"dormant"}).AddRow(true))
+		reason := errAccountDormant.Message
+		tomorrow := today.AddDate(0, 0, 1)
+		mock.ExpectExec(`UPDATE standing_orders SET next_run_date = \$1, retry_on = \$2, failed_attempts = \$3, last_failure_reason = \$4`).
+			WithArgs("2025-01-31", &tomorrow, 1, reason, "active", orderID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO standing_order_runs`).
+			WithArgs(pgxmock.AnyArg(), orderID, today, "failed", (*uuid.UUID)(nil), &reason).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		_, err := ProcessStandingOrders(context.Background(), today)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("limit refusal is recorded and retried", func(t *testing.T) {
+		expectDueOrder(0)
+		expectTransferLocks(payerID, payeeID, 1000, 10)
+		single := 100.0
+		expectDebitLimits(payerID, &single, nil, nil, nil)
+		reason := "Debit exceeds the 100.00 single debit limit"
+		tomorrow := today.AddDate(0, 0, 1)
+		mock.ExpectExec(`UPDATE standing_orders SET next_run_date = \$1, retry_on = \$2, failed_attempts = \$3, last_failure_reason = \$4`).
+			WithArgs("2025-01-31", &tomorrow, 1, reason, "active", orderID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO standing_order_runs`).
+			WithArgs(pgxmock.AnyArg(), orderID, today, "failed", (*uuid.UUID)(nil), &reason).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		_, err := ProcessStandingOrders(context.Background(), today)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCancelStandingOrder(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.DELETE("/customers/:customer_id/standing-orders/:standing_order_id", CancelStandingOrder)
+
+	orderID := uuid.New()
+	customerID := uuid.New()
+	columns := []string{"id", "customer_id", "payee_customer_id", "amount", "frequency", "start_date", "end_date",
+		"next_run_date", "reference", "status", "failed_attempts", "last_failure_reason"}
+	start := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		status     string
+		wantStatus int
+	}{
+		{"paused", http.StatusOK},
+		{"cancelled", http.StatusConflict},
+	} {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM standing_orders WHERE id = \$1 AND customer_id = \$2 FOR UPDATE`).
+			WithArgs(orderID, customerID).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(orderID, customerID, uuid.New(), float64(250), "monthly",
+				start, nil, start, "", tt.status, 0, ""))
+		if tt.wantStatus == http.StatusOK {
+			mock.ExpectExec(`UPDATE standing_orders SET status = \$1`).
+				WithArgs("cancelled", "2025-05-01", 0, (*string)(nil), orderID).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			mock.ExpectCommit()
+		} else {
+			mock.ExpectRollback()
+		}
+
+		req := httptest.NewRequest("DELETE", "/customers/"+customerID.String()+"/standing-orders/"+orderID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.wantStatus, w.Code, tt.status)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResumeStandingOrder(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/standing-orders/:standing_order_id/resume", ResumeStandingOrder)
+
+	orderID := uuid.New()
+	customerID := uuid.New()
+	columns := []string{"id", "customer_id", "payee_customer_id", "amount", "frequency", "start_date", "end_date",
+		"next_run_date", "reference", "status", "failed_attempts", "last_failure_reason"}
+	start := schedule.Day(time.Now()).AddDate(0, 0, 1)
+
+	// Resuming starts the order afresh, so the last failure is cleared too
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM standing_orders WHERE id = \$1 AND customer_id = \$2 FOR UPDATE`).
+		WithArgs(orderID, customerID).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(orderID, customerID, uuid.New(), float64(250), "monthly",
+			start, nil, start, "", "paused", 2, "Insufficient balance"))
+	mock.ExpectExec(`UPDATE standing_orders SET status = \$1, next_run_date = \$2, retry_on = NULL, failed_attempts = \$3, last_failure_reason = \$4`).
+		WithArgs("active", start.Format(dateLayout), 0, (*string)(nil), orderID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/standing-orders/"+orderID.String()+"/resume", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "last_failure_reason")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"ledger-service/jobqueue"
+	"ledger-service/ledger"
+	"ledger-service/statement"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// StatementRequest represents the payload for generating an MT940 statement
+type StatementRequest struct {
+	// From and To are the first and last value dates covered, inclusive;
+	// To must be before today in the customer's timezone
+	From string `json:"from" binding:"required" example:"2025-04-01" format:"date"`
+	To   string `json:"to" binding:"required" example:"2025-04-30" format:"date"`
+}
+
+// Statement is an MT940 statement generated for a customer
+// @Description An MT940 statement of a customer's posted transactions over a period of value dates
+type Statement struct {
+	ID         uuid.UUID `json:"statement_id" format:"uuid"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	// Number is the statement's number for the account (field 28C)
+	Number int `json:"number" example:"7"`
+	// AccountIdentifier is the account named in field 25
+	AccountIdentifier string  `json:"account_identifier" example:"DE89370400440532013000"`
+	From              string  `json:"from" format:"date"`
+	To                string  `json:"to" format:"date"`
+	Currency          string  `json:"currency" example:"EUR"`
+	OpeningBalance    float64 `json:"opening_balance" example:"1520.4"`
+	ClosingBalance    float64 `json:"closing_balance" example:"1310.9"`
+	Transactions      int     `json:"transactions" example:"14"`
+	CreatedAt         string  `json:"created_at" format:"date-time"`
+}
+
+// StatementAccountRequest represents the payload for setting how a
+// customer's MT940 statements identify and number the account
+type StatementAccountRequest struct {
+	// AccountIdentifier is written in field 25, such as an IBAN or a bank
+	// code and account number; the customer ID without dashes when not set
+	AccountIdentifier string `json:"account_identifier,omitempty" example:"DE89370400440532013000" maxLength:"35"`
+	// NextStatementNumber is the number the next statement gets, to carry
+	// on from statements the customer received before
+	NextStatementNumber *int `json:"next_statement_number,omitempty" example:"42" minimum:"1" maximum:"99999"`
+}
+
+// StatementAccount is how a customer's MT940 statements identify and number
+// the account
+type StatementAccount struct {
+	CustomerID          uuid.UUID `json:"customer_id" format:"uuid"`
+	AccountIdentifier   string    `json:"account_identifier" example:"DE89370400440532013000"`
+	NextStatementNumber int       `json:"next_statement_number" example:"42"`
+}
+
+// maxStatementNumber is the largest number field 28C holds; numbering
+// starts again at 1 after it
+const maxStatementNumber = 99999
+
+// statementAccountPattern is the SWIFT character set field 25 is written in
+var statementAccountPattern = regexp.MustCompile(`^[A-Za-z0-9/\-?:().,'+ ]{1,35}$`)
+
+// defaultStatementAccount identifies an account whose identifier is not set
+func defaultStatementAccount(customerID uuid.UUID) string {
+	return strings.ReplaceAll(customerID.String(), "-", "")
+}
+
+const statementColumns = `id, customer_id, statement_number, account_identifier, period_from, period_to, currency,
+	opening_balance, closing_balance, transactions, created_at`
+
+func scanStatement(row pgx.Row) (Statement, error) {
+	var s Statement
+	var from, to, createdAt time.Time
+	if err := row.Scan(&s.ID, &s.CustomerID, &s.Number, &s.AccountIdentifier, &from, &to, &s.Currency,
+		&s.OpeningBalance, &s.ClosingBalance, &s.Transactions, &createdAt); err != nil {
+		return Statement{}, err
+	}
+	s.From = from.Format(dateLayout)
+	s.To = to.Format(dateLayout)
+	s.CreatedAt = createdAt.Format(time.RFC3339)
+	return s, nil
+}
+
+// @Summary Generate an MT940 statement
+// @Description Generate a SWIFT MT940 statement of the customer's posted transactions value-dated in a period of past days, for treasury systems that import statement files. The opening and closing balances are the booked balances before and after the period. Each statement takes the account's next statement number; asking again for the same period returns the statement already generated, with its number. Download the file from /customers/{customer_id}/statements/{statement_id}/mt940. With Prefer: respond-async the statement is generated in the background: the response is 202 with the queued job, whose status is at the Location header, and the job's result is the statement once it has succeeded.
+// @Tags statements
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param Prefer header string false "respond-async to generate the statement in the background"
+// @Param statement body StatementRequest true "Statement period"
+// @Success 201 {object} Statement "Statement generated"
+// @Success 200 {object} Statement "Statement already generated for the period"
+// @Success 202 {object} QueueJob "Statement queued"
+// @Header 202 {string} Location "Job status"
+// @Failure 400 {object} ErrorResponse "Invalid period"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/statements [post]
+func CreateStatement(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req StatementRequest
+	if !bindRequest(c, &req, "Invalid input: from and to are required (YYYY-MM-DD)") {
+		return
+	}
+	invalidPeriod := ErrorResponse{Error: "Invalid period: from and to must be dates (YYYY-MM-DD), from no later than to, and to before today"}
+	from, fromErr := time.Parse(dateLayout, req.From)
+	to, toErr := time.Parse(dateLayout, req.To)
+	if fromErr != nil || toErr != nil || to.Before(from) {
+		respondError(c, http.StatusBadRequest, invalidPeriod)
+		return
+	}
+
+	// The period must have ended where the customer is, so a customer
+	// ahead of UTC gets yesterday's statement as soon as their day starts
+	ctx := c.Request.Context()
+	today, err := customerToday(ctx, customerID)
+	if errors.Is(err, store.ErrNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	if !to.Before(today) {
+		respondError(c, http.StatusBadRequest, invalidPeriod)
+		return
+	}
+
+	if preferAsync(c) {
+		jobID, err := enqueueJob(ctx, db, JobStatementRender, &customerID, req)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to queue statement"})
+			return
+		}
+		respondQueued(c, customerID, jobID, JobStatementRender)
+		return
+	}
+
+	s, created, err := generateStatement(ctx, customerID, from, to)
+	var failure *statementFailure
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+	case errors.As(err, &failure):
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: failure.msg})
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate statement"})
+	case created:
+		c.JSON(http.StatusCreated, s)
+	default:
+		c.JSON(http.StatusOK, s)
+	}
+}
+
+// statementFailure is a statement that could not be generated, with the
+// message the API answers with
+type statementFailure struct {
+	msg string
+	err error
+}
+
+func (f *statementFailure) Error() string { return f.msg + ": " + f.err.Error() }
+func (f *statementFailure) Unwrap() error { return f.err }
+
+func failStatement(msg string, err error) error {
+	return &statementFailure{msg: msg, err: err}
+}
+
+// runStatementRender generates the statement a statement.render job asks for
+func runStatementRender(ctx context.Context, job jobqueue.Job) (interface{}, error) {
+	var req StatementRequest
+	if err := json.Unmarshal(job.Payload, &req); err != nil || job.CustomerID == nil {
+		return nil, jobqueue.Permanent(fmt.Errorf("invalid statement job: %v", err))
+	}
+	from, fromErr := time.Parse(dateLayout, req.From)
+	to, toErr := time.Parse(dateLayout, req.To)
+	if fromErr != nil || toErr != nil {
+		return nil, jobqueue.Permanent(errors.New("invalid statement period"))
+	}
+	s, _, err := generateStatement(ctx, *job.CustomerID, from, to)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, jobqueue.Permanent(errors.New("customer not found"))
+	}
+	return s, err
+}
+
+// generateStatement writes the customer's MT940 statement for the period
+// from to to, reporting false with the statement already generated for it
+// when there is one. It returns store.ErrNotFound when the customer does not
+// exist.
+func generateStatement(ctx context.Context, customerID uuid.UUID, from, to time.Time) (Statement, bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return Statement{}, false, failStatement("Failed to start transaction", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Holding the customer keeps postings out while the balance and the
+	// transactions are read, so the two agree
+	st := store.NewPostgresTx(tx)
+	account, err := st.LockCustomer(ctx, customerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return Statement{}, false, err
+	}
+	if err != nil {
+		return Statement{}, false, failStatement("Failed to lock customer", err)
+	}
+	existing, err := scanStatement(tx.QueryRow(ctx,
+		"SELECT "+statementColumns+" FROM mt940_statements WHERE customer_id = $1 AND period_from = $2 AND period_to = $3",
+		customerID, from, to))
+	if err == nil {
+		return existing, false, nil
+	}
+	if err != pgx.ErrNoRows {
+		return Statement{}, false, failStatement("Failed to fetch statements", err)
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO statement_accounts (customer_id) VALUES ($1) ON CONFLICT (customer_id) DO NOTHING",
+		customerID); err != nil {
+		return Statement{}, false, failStatement("Failed to number statement", err)
+	}
+	var identifier string
+	var number int
+	var numberYear *int
+	if err := tx.QueryRow(ctx,
+		"SELECT COALESCE(account_identifier, ''), next_number, number_year FROM statement_accounts WHERE customer_id = $1 FOR UPDATE",
+		customerID).Scan(&identifier, &number, &numberYear); err != nil {
+		return Statement{}, false, failStatement("Failed to number statement", err)
+	}
+	if identifier == "" {
+		identifier = defaultStatementAccount(customerID)
+	}
+	// Yearly numbering restarts with the first statement ending in a new
+	// year; one for an earlier year carries on the running number
+	if yearlyStatementNumbers && numberYear != nil && *numberYear < to.Year() {
+		number = 1
+	}
+
+	s := statement.Statement{
+		AccountID: identifier,
+		Currency:  account.Currency,
+		From:      from,
+		To:        to,
+	}
+	if s.Currency == "" {
+		s.Currency = store.DefaultCurrency
+	}
+	// Work back from the current balance: take off what was value-dated
+	// after the period to get the closing balance, then what was in it to
+	// get the opening one
+	s.Balance = account.Balance
+	if account.BalanceType == store.BalanceCredit {
+		s.Balance = -s.Balance
+	}
+	opts := store.ListOptions{
+		TransactionFilter: store.TransactionFilter{Status: ledger.StatusPosted, ValueDateFrom: &from},
+		Sort:              "created_at",
+		Limit:             exportBatchSize,
+	}
+	var moved float64
+	for {
+		listed, err := st.ListTransactions(ctx, customerID, opts)
+		if err != nil {
+			return Statement{}, false, failStatement("Failed to fetch transactions", err)
+		}
+		for _, t := range listed {
+			e := statementEntry(t)
+			if t.ValueDate.After(to) {
+				s.Balance -= e.Amount
+				continue
+			}
+			moved += e.Amount
+			s.Entries = append(s.Entries, e)
+		}
+		if len(listed) < exportBatchSize {
+			break
+		}
+		opts.Offset += exportBatchSize
+	}
+	s.Opening = s.Balance - moved
+
+	id := uuid.New()
+	var content bytes.Buffer
+	if err := statement.WriteMT940(&content, s, strings.ReplaceAll(id.String(), "-", "")[:16], number); err != nil {
+		return Statement{}, false, failStatement("Failed to write statement: "+err.Error(), err)
+	}
+	created, err := scanStatement(tx.QueryRow(ctx,
+		`INSERT INTO mt940_statements (id, customer_id, statement_number, account_identifier, period_from, period_to, currency,
+			opening_balance, closing_balance, transactions, content)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+statementColumns,
+		id, customerID, number, identifier, from, to, s.Currency, s.Opening, s.Balance, len(s.Entries), content.String()))
+	if err != nil {
+		return Statement{}, false, failStatement("Failed to save statement", err)
+	}
+	next := number + 1
+	if next > maxStatementNumber {
+		next = 1
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE statement_accounts SET next_number = $2, number_year = GREATEST(number_year, $3), updated_at = NOW() WHERE customer_id = $1",
+		customerID, next, to.Year()); err != nil {
+		return Statement{}, false, failStatement("Failed to number statement", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Statement{}, false, failStatement("Failed to commit transaction", err)
+	}
+	return created, true, nil
+}
+
+// @Summary List MT940 statements
+// @Description List the MT940 statements generated for the customer, latest period first
+// @Tags statements
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Statements per page" default(10)
+// @Success 200 {array} Statement "Statements"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/statements [get]
+func ListStatements(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+statementColumns+" FROM mt940_statements WHERE customer_id = $1 ORDER BY period_to DESC, created_at DESC LIMIT $2 OFFSET $3",
+		customerID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch statements"})
+		return
+	}
+	defer rows.Close()
+	statements := []Statement{}
+	for rows.Next() {
+		s, err := scanStatement(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan statement"})
+			return
+		}
+		statements = append(statements, s)
+	}
+	c.JSON(http.StatusOK, statements)
+}
+
+// @Summary Download an MT940 statement
+// @Description Download a statement as MT940 text: the fields of the SWIFT message, with CRLF line ends, ending in a hyphen
+// @Tags statements
+// @Produce text/plain
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param statement_id path string true "Statement ID" format(uuid)
+// @Success 200 {file} file "MT940 statement"
+// @Failure 400 {object} ErrorResponse "Invalid customer or statement ID"
+// @Failure 404 {object} ErrorResponse "Statement not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/statements/{statement_id}/mt940 [get]
+func DownloadStatement(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	statementID, err := uuid.Parse(c.Param("statement_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid statement ID"})
+		return
+	}
+	var number int
+	var content string
+	err = db.QueryRow(c.Request.Context(),
+		"SELECT statement_number, content FROM mt940_statements WHERE id = $1 AND customer_id = $2",
+		statementID, customerID).Scan(&number, &content)
+	if err == pgx.ErrNoRows {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Statement not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch statement"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="mt940-%s-%05d.sta"`, customerID, number))
+	c.Data(http.StatusOK, "text/plain; charset=us-ascii", []byte(content))
+}
+
+// @Summary Set a customer's statement account
+// @Description Set the account identification MT940 statements give the customer's account in field 25, such as an IBAN, and optionally the number the next statement gets
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param account body StatementAccountRequest true "Statement account"
+// @Success 200 {object} StatementAccount "Statement account set"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/statement-account [put]
+func SetStatementAccount(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req StatementAccountRequest
+	if !bindRequest(c, &req, "Invalid input") {
+		return
+	}
+	var fields fieldErrors
+	if req.AccountIdentifier != "" && !statementAccountPattern.MatchString(req.AccountIdentifier) {
+		fields.add("account_identifier", "account_identifier must be up to 35 letters, digits, spaces or / - ? : ( ) . , ' +")
+	}
+	if n := req.NextStatementNumber; n != nil && (*n < 1 || *n > maxStatementNumber) {
+		fields.add("next_statement_number", "next_statement_number must be between 1 and 99999")
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	// Forgetting the year means a number set by hand is used as it is, even
+	// when numbering restarts each year
+	account := StatementAccount{CustomerID: customerID}
+	err = db.QueryRow(ctx,
+		`INSERT INTO statement_accounts (customer_id, account_identifier, next_number) VALUES ($1, $2, COALESCE($3, 1))
+		ON CONFLICT (customer_id) DO UPDATE SET
+			account_identifier = EXCLUDED.account_identifier,
+			next_number = COALESCE($3, statement_accounts.next_number),
+			number_year = CASE WHEN $3 IS NULL THEN statement_accounts.number_year END,
+			updated_at = NOW()
+		RETURNING COALESCE(account_identifier, ''), next_number`,
+		customerID, nullableString(req.AccountIdentifier), req.NextStatementNumber).Scan(&account.AccountIdentifier, &account.NextStatementNumber)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to set statement account"})
+		return
+	}
+	if account.AccountIdentifier == "" {
+		account.AccountIdentifier = defaultStatementAccount(customerID)
+	}
+	c.JSON(http.StatusOK, account)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturedArg matches any argument, keeping it so the test can look at it
+type capturedArg struct{ value interface{} }
+
+func (a *capturedArg) Match(v interface{}) bool {
+	a.value = v
+	return true
+}
+
+func TestCreateStatement(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST("/customers/:customer_id/statements", CreateStatement)
+	InitStatements("LEDGER", true)
+	defer InitStatements("LEDGER", false)
+
+	customerID := uuid.New()
+	from, to := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC)
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/statements", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	statementRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "customer_id", "statement_number", "account_identifier", "period_from", "period_to", "currency",
+			"opening_balance", "closing_balance", "transactions", "created_at"})
+	}
+	expectCustomer := func(timezone string) {
+		mock.ExpectQuery(customerTimezoneQuery).
+			WithArgs(customerID).
+			WillReturnRows(customerIn(timezone))
+	}
+	expectLocked := func() {
+		expectCustomer("UTC")
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(customerID).
+			WillReturnRows(lockedCustomer(float64(1000), "checking", false))
+	}
+
+	w := send(map[string]interface{}{"from": "2025-04-30", "to": "2025-04-01"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	expectCustomer("UTC")
+	w = send(map[string]interface{}{"from": "2025-04-01", "to": time.Now().UTC().Format(dateLayout)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Today is the customer's: at UTC-11 it can still be what UTC calls
+	// yesterday, and at UTC+14 what UTC calls today can be over
+	expectCustomer("Pacific/Pago_Pago")
+	w = send(map[string]interface{}{"from": "2025-04-01", "to": localToday("Pacific/Pago_Pago").Format(dateLayout)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	expectCustomer("Pacific/Kiritimati")
+	mock.ExpectBegin().WillReturnError(assert.AnError)
+	w = send(map[string]interface{}{"from": "2025-04-01", "to": localToday("Pacific/Kiritimati").AddDate(0, 0, -1).Format(dateLayout)})
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "the period has ended where the customer is")
+	mock.ExpectQuery(customerTimezoneQuery).
+		WithArgs(customerID).
+		WillReturnError(pgx.ErrNoRows)
+	w = send(map[string]interface{}{"from": "2025-04-01", "to": "2025-04-30"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The balance is worked back from the current 1000: a credit of 200
+	// value-dated after the period makes the closing balance 800, and the
+	// period's credit of 500 and fee of 10 an opening balance of 310
+	expectLocked()
+	mock.ExpectQuery(`FROM mt940_statements WHERE customer_id = \$1 AND period_from = \$2 AND period_to = \$3`).
+		WithArgs(customerID, from, to).
+		WillReturnRows(statementRows())
+	mock.ExpectExec(`INSERT INTO statement_accounts \(customer_id\) VALUES \(\$1\) ON CONFLICT`).
+		WithArgs(customerID).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	lastYear := 2024
+	mock.ExpectQuery(`SELECT COALESCE\(account_identifier, ''\), next_number, number_year FROM statement_accounts WHERE customer_id = \$1 FOR UPDATE`).
+		WithArgs(customerID).
+		WillReturnRows(pgxmock.NewRows([]string{"account_identifier", "next_number", "number_year"}).AddRow("DE89370400440532013000", 57, &lastYear))
+	reference := "INV-1"
+	mock.ExpectQuery(`SELECT .* FROM transactions WHERE customer_id = \$1 AND value_date >= \$2 AND status = \$3 ORDER BY`).
+		WithArgs(customerID, from, "posted", exportBatchSize, 0).
+		WillReturnRows(transactionRows().
+			AddRow(uuid.New(), "credit", float64(500), "posted", from.Add(50*time.Hour), (*time.Time)(nil), from.AddDate(0, 0, 2), (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), &reference, false).
+			AddRow(uuid.New(), "fee", float64(10), "posted", to.Add(time.Hour), (*time.Time)(nil), to, (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), (*string)(nil), false).
+			AddRow(uuid.New(), "credit", float64(200), "posted", to.AddDate(0, 0, 2), (*time.Time)(nil), to.AddDate(0, 0, 2), (*float64)(nil), (*string)(nil), (*float64)(nil), (*string)(nil), (*string)(nil), (*string)(nil), false))
+	content := &capturedArg{}
+	mock.ExpectQuery(`INSERT INTO mt940_statements`).
+		WithArgs(pgxmock.AnyArg(), customerID, 1, "DE89370400440532013000", from, to, "USD", float64(310), float64(800), 2, content).
+		WillReturnRows(statementRows().AddRow(uuid.New(), customerID, 1, "DE89370400440532013000", from, to, "USD", float64(310), float64(800), 2, time.Now()))
+	mock.ExpectExec(`UPDATE statement_accounts SET next_number = \$2, number_year = GREATEST\(number_year, \$3\)`).
+		WithArgs(customerID, 2, 2025).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	w = send(map[string]interface{}{"from": "2025-04-01", "to": "2025-04-30"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var s Statement
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, 1, s.Number, "yearly numbering restarts in 2025")
+	assert.Equal(t, "2025-04-30", s.To)
+	mt940, _ := content.value.(string)
+	assert.Contains(t, mt940, ":25:DE89370400440532013000\r\n:28C:00001/00001\r\n:60F:C250401USD310,00\r\n")
+	assert.Contains(t, mt940, "C500,00NMSCINV-1//")
+	assert.Contains(t, mt940, ":61:2504300430D10,00NCHGNONREF//")
+	assert.Contains(t, mt940, ":62F:C250430USD800,00\r\n-\r\n")
+
+	// Asking for the same period again returns the statement with its number
+	expectLocked()
+	mock.ExpectQuery(`FROM mt940_statements WHERE customer_id = \$1 AND period_from = \$2 AND period_to = \$3`).
+		WithArgs(customerID, from, to).
+		WillReturnRows(statementRows().AddRow(s.ID, customerID, 1, "DE89370400440532013000", from, to, "USD", float64(310), float64(800), 2, time.Now()))
+	mock.ExpectRollback()
+	w = send(map[string]interface{}{"from": "2025-04-01", "to": "2025-04-30"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Asking for it asynchronously queues it and points at the job
+	expectCustomer("UTC")
+	mock.ExpectExec(`INSERT INTO queue_jobs \(id, kind, customer_id, payload, max_attempts\)`).
+		WithArgs(pgxmock.AnyArg(), JobStatementRender, &customerID, pgxmock.AnyArg(), 3).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	payload, _ := json.Marshal(map[string]interface{}{"from": "2025-04-01", "to": "2025-04-30"})
+	req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/statements", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "respond-async")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var job QueueJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/v1/customers/"+customerID.String()+"/jobs/"+job.ID.String(), w.Header().Get("Location"))
+	assert.Equal(t, "respond-async", w.Header().Get("Preference-Applied"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetStatementAccount(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.PUT("/admin/customers/:customer_id/statement-account", SetStatementAccount)
+
+	customer := store.Customer{ID: uuid.New(), Name: "Test", AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(context.Background(), &customer))
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("PUT", "/admin/customers/"+customer.ID.String()+"/statement-account", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(map[string]interface{}{"account_identifier": "DE89 3704 0044 0532 0130 00 + a much longer tail", "next_statement_number": 0})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "account_identifier")
+	assert.Contains(t, w.Body.String(), "next_statement_number")
+
+	next := 42
+	mock.ExpectQuery(`INSERT INTO statement_accounts \(customer_id, account_identifier, next_number\)`).
+		WithArgs(customer.ID, pgxmock.AnyArg(), &next).
+		WillReturnRows(pgxmock.NewRows([]string{"account_identifier", "next_number"}).AddRow("37040044/0532013000", 42))
+	w = send(map[string]interface{}{"account_identifier": "37040044/0532013000", "next_statement_number": 42})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var account StatementAccount
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &account))
+	assert.Equal(t, StatementAccount{CustomerID: customer.ID, AccountIdentifier: "37040044/0532013000", NextStatementNumber: 42}, account)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,592 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ledger-service/ledger"
+	"ledger-service/saga"
+	"ledger-service/store"
+	"ledger-service/stripe"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PayoutProvider sends customers' withdrawals out and reports what its
+// balance holds; *stripe.Client implements it
+type PayoutProvider interface {
+	CreatePayout(ctx context.Context, p stripe.PayoutParams, idempotencyKey string) (stripe.Payout, error)
+	GetPayout(ctx context.Context, id, account string) (stripe.Payout, error)
+	CancelPayout(ctx context.Context, id, account string) (stripe.Payout, error)
+	GetBalance(ctx context.Context) (stripe.Balance, error)
+}
+
+var (
+	stripeAPI           PayoutProvider
+	stripeWebhookSecret atomic.Pointer[string]
+)
+
+// InitStripe sets the Stripe API withdrawals are paid out through and the
+// secret its webhook events are signed with; a nil API turns Stripe off
+func InitStripe(api PayoutProvider, webhookSecret string) {
+	stripeAPI = api
+	SetStripeWebhookSecret(webhookSecret)
+}
+
+// SetStripeWebhookSecret replaces the secret Stripe webhook events are
+// signed with, for example after it is rolled
+func SetStripeWebhookSecret(secret string) {
+	stripeWebhookSecret.Store(&secret)
+}
+
+// StripePayoutAction names the saga action paying a withdrawal out through
+// Stripe
+const StripePayoutAction = "stripe_payout"
+
+// stripeSignatureTolerance is how old a webhook event's signature may be,
+// as Stripe's own libraries allow
+const stripeSignatureTolerance = 5 * time.Minute
+
+// StripeAccountRequest represents the payload for setting the Stripe
+// connected account a customer's withdrawals are paid out from
+type StripeAccountRequest struct {
+	AccountID string `json:"account_id" binding:"required,max=100,startswith=acct_" example:"acct_1PgK2nQ9x3yZ7aBc"`
+}
+
+// StripeAccount is a customer's Stripe connected account
+type StripeAccount struct {
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	AccountID  string    `json:"account_id" example:"acct_1PgK2nQ9x3yZ7aBc"`
+}
+
+// WithdrawalRequest represents the payload for a withdrawal
+type WithdrawalRequest struct {
+	Amount    float64 `json:"amount" binding:"required,money" example:"125.5" minimum:"0.01"`
+	Reference string  `json:"reference,omitempty" binding:"max=140" example:"Withdrawal to checking" maxLength:"140"`
+}
+
+// StripeWebhookResponse acknowledges a Stripe webhook event
+// @Description Outcome of a Stripe webhook event; a redelivered charge is acknowledged with the transaction it posted the first time
+type StripeWebhookResponse struct {
+	// Status is posted or held for a charge credited, duplicate for one
+	// already credited, updated for a payout's new status, reversed when a
+	// failed payout's withdrawal was reversed, and ignored otherwise
+	Status        string     `json:"status" example:"posted" enums:"posted,held,pending_approval,duplicate,updated,reversed,compensation_failed,ignored"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+}
+
+// StripeReconciliation compares what Stripe holds with what the ledger
+// recorded through Stripe, by currency
+type StripeReconciliation struct {
+	Currencies  []StripeCurrencyTotals `json:"currencies"`
+	GeneratedAt string                 `json:"generated_at" format:"date-time"`
+}
+
+// StripeCurrencyTotals is one currency of a Stripe reconciliation
+type StripeCurrencyTotals struct {
+	Currency string `json:"currency" example:"USD"`
+	// Available and Pending are the platform's Stripe balance
+	Available float64 `json:"available" example:"1840.25"`
+	Pending   float64 `json:"pending" example:"120"`
+	// Charges is what the ledger credited for Stripe charges
+	Charges float64 `json:"charges" example:"2210.25"`
+	// PayoutsPaid and PayoutsOpen are withdrawals debited and paid out, or
+	// on their way; PayoutsReversed are those that failed and were given back
+	PayoutsPaid     float64 `json:"payouts_paid" example:"250"`
+	PayoutsOpen     float64 `json:"payouts_open" example:"125.5"`
+	PayoutsReversed float64 `json:"payouts_reversed" example:"0"`
+	OpenPayouts     int     `json:"open_payouts" example:"1"`
+}
+
+// stripeEnabled responds 501 when Stripe is not configured
+func stripeEnabled(c *gin.Context) bool {
+	if stripeAPI == nil {
+		respondError(c, http.StatusNotImplemented, ErrorResponse{Error: "Stripe is not configured"})
+		return false
+	}
+	return true
+}
+
+func stripeAccountOf(ctx context.Context, customerID uuid.UUID) (string, error) {
+	var account string
+	err := db.QueryRow(ctx, "SELECT account_id FROM stripe_accounts WHERE customer_id = $1", customerID).Scan(&account)
+	return account, err
+}
+
+// StripePayout is the saga action paying a withdrawal out from the
+// customer's Stripe connected account. The saga ID is the payout's
+// idempotency key, so running the step again returns the same payout.
+type StripePayout struct{}
+
+func (StripePayout) Execute(ctx context.Context, r saga.Request) (string, error) {
+	if stripeAPI == nil {
+		return "", errors.New("stripe is not configured")
+	}
+	account, err := stripeAccountOf(ctx, r.CustomerID)
+	if err == pgx.ErrNoRows {
+		return "", errors.New("customer has no stripe account")
+	}
+	if err != nil {
+		return "", err
+	}
+	customer, err := ledgerStore.GetCustomer(ctx, r.CustomerID)
+	if err != nil {
+		return "", err
+	}
+	currency := customer.Currency
+	if currency == "" {
+		currency = store.DefaultCurrency
+	}
+	metadata := map[string]string{"saga_id": r.SagaID.String(), "customer_id": r.CustomerID.String()}
+	if r.TransactionID != nil {
+		metadata["transaction_id"] = r.TransactionID.String()
+	}
+	payout, err := stripeAPI.CreatePayout(ctx, stripe.PayoutParams{
+		Amount:      stripe.ToMinor(r.Amount, currency),
+		Currency:    currency,
+		Account:     account,
+		Description: r.Reference,
+		Metadata:    metadata,
+	}, r.SagaID.String()+":payout")
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(ctx,
+		`INSERT INTO stripe_objects (id, kind, account_id, customer_id, transaction_id, saga_id, amount, currency, status)
+		VALUES ($1, 'payout', $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO NOTHING`,
+		payout.ID, account, r.CustomerID, r.TransactionID, r.SagaID, r.Amount, currency, payout.Status)
+	if err != nil {
+		return "", err
+	}
+	return payout.ID, nil
+}
+
+// Compensate cancels the payout while it is pending. A payout that failed
+// or was canceled needs nothing; one paid or in transit cannot be undone.
+func (StripePayout) Compensate(ctx context.Context, r saga.Request) error {
+	if stripeAPI == nil {
+		return errors.New("stripe is not configured")
+	}
+	id := r.ExternalID
+	var account string
+	err := db.QueryRow(ctx,
+		"SELECT id, account_id FROM stripe_objects WHERE saga_id = $1 AND kind = 'payout'",
+		r.SagaID).Scan(&id, &account)
+	if err == pgx.ErrNoRows {
+		if id == "" {
+			// The payout is recorded as soon as Stripe creates it
+			return nil
+		}
+		account, err = stripeAccountOf(ctx, r.CustomerID)
+	}
+	if err != nil {
+		return err
+	}
+	payout, err := stripeAPI.GetPayout(ctx, id, account)
+	if err != nil {
+		return err
+	}
+	switch payout.Status {
+	case stripe.PayoutFailed, stripe.PayoutCanceled:
+	case stripe.PayoutPending:
+		if payout, err = stripeAPI.CancelPayout(ctx, id, account); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("payout %s is already %s", id, payout.Status)
+	}
+	_, err = db.Exec(ctx, "UPDATE stripe_objects SET status = $2, updated_at = NOW() WHERE id = $1", id, payout.Status)
+	return err
+}
+
+// @Summary Withdraw to a customer's bank
+// @Description Debit a withdrawal and pay it out from the customer's Stripe connected account, as a saga: when Stripe refuses the payout the withdrawal is reversed at once. Payouts that fail later, as Stripe reports by webhook, are reversed then. The outcome is in the returned saga's status.
+// @Tags stripe
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param withdrawal body WithdrawalRequest true "Withdrawal"
+// @Success 201 {object} saga.Saga "Withdrawal run; see its status for the outcome"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "Customer has no Stripe account"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Stripe is not configured"
+// @Router /customers/{customer_id}/withdrawals [post]
+func CreateWithdrawal(c *gin.Context) {
+	if !stripeEnabled(c) {
+		return
+	}
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req WithdrawalRequest
+	if !bindRequest(c, &req, "Invalid input: amount is required") {
+		return
+	}
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	if _, err := ledgerStore.GetCustomer(ctx, customerID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	if _, err := stripeAccountOf(ctx, customerID); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusConflict, ErrorResponse{Error: "Customer has no Stripe account"})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch Stripe account"})
+		return
+	}
+
+	s := saga.New(customerID, "withdrawal", req.Amount, req.Reference, []string{StripePayoutAction})
+	if err := sagaCoordinator().Run(ctx, s); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to save saga"})
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// @Summary Set a customer's Stripe account
+// @Description Set the Stripe connected account the customer's withdrawals are paid out from
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param account body StripeAccountRequest true "Stripe account"
+// @Success 200 {object} StripeAccount "Stripe account set"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/customers/{customer_id}/stripe-account [put]
+func SetStripeAccount(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	var req StripeAccountRequest
+	if !bindRequest(c, &req, "Invalid input: account_id must be a Stripe account ID (acct_...)") {
+		return
+	}
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	_, err = db.Exec(ctx,
+		`INSERT INTO stripe_accounts (customer_id, account_id) VALUES ($1, $2)
+		ON CONFLICT (customer_id) DO UPDATE SET account_id = EXCLUDED.account_id, updated_at = NOW()`,
+		customerID, req.AccountID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to set Stripe account"})
+		return
+	}
+	c.JSON(http.StatusOK, StripeAccount{CustomerID: customerID, AccountID: req.AccountID})
+}
+
+// @Summary Receive a Stripe webhook
+// @Description Receive an event from Stripe, verified with its Stripe-Signature header and STRIPE_WEBHOOK_SECRET. Payout events update the payout's status; a withdrawal whose payout failed or was canceled is reversed. A succeeded charge whose metadata names a customer_id is credited to them as a card_payment, with "stripe:<charge ID>" as its unique reference so a redelivered event is not credited twice. Other events are acknowledged and ignored.
+// @Tags stripe
+// @Accept json
+// @Produce json
+// @Param event body object true "Stripe event"
+// @Success 200 {object} StripeWebhookResponse "Event handled or ignored"
+// @Failure 401 {object} ErrorResponse "Invalid signature"
+// @Failure 422 {object} ErrorResponse "Charge cannot be credited"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Stripe is not configured"
+// @Router /stripe/webhook [post]
+func StripeWebhook(c *gin.Context) {
+	if !stripeEnabled(c) {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body"})
+		return
+	}
+	event, err := stripe.ParseEvent(body, c.GetHeader(stripe.SignatureHeader), *stripeWebhookSecret.Load(), stripeSignatureTolerance, time.Now())
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, ErrorResponse{Error: "Invalid signature", Code: "invalid_signature"})
+		return
+	}
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	switch {
+	case strings.HasPrefix(event.Type, "payout."):
+		var payout stripe.Payout
+		if err := json.Unmarshal(event.Data.Object, &payout); err != nil || payout.ID == "" {
+			c.JSON(http.StatusOK, StripeWebhookResponse{Status: "ignored"})
+			return
+		}
+		resp, err := applyPayoutStatus(ctx, payout)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update payout"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	case event.Type == "charge.succeeded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Object, &charge); err != nil {
+			c.JSON(http.StatusOK, StripeWebhookResponse{Status: "ignored"})
+			return
+		}
+		creditStripeCharge(ctx, c, charge)
+	default:
+		c.JSON(http.StatusOK, StripeWebhookResponse{Status: "ignored"})
+	}
+}
+
+// applyPayoutStatus records a payout's status. When it failed or was
+// canceled after its withdrawal completed, the saga is claimed and
+// compensated, which reverses the withdrawal.
+func applyPayoutStatus(ctx context.Context, payout stripe.Payout) (StripeWebhookResponse, error) {
+	var sagaID *uuid.UUID
+	err := db.QueryRow(ctx,
+		`UPDATE stripe_objects SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND kind = 'payout'
+		RETURNING saga_id`,
+		payout.ID, payout.Status).Scan(&sagaID)
+	if err == pgx.ErrNoRows {
+		return StripeWebhookResponse{Status: "ignored"}, nil
+	}
+	if err != nil {
+		return StripeWebhookResponse{}, err
+	}
+	if sagaID == nil || (payout.Status != stripe.PayoutFailed && payout.Status != stripe.PayoutCanceled) {
+		return StripeWebhookResponse{Status: "updated"}, nil
+	}
+
+	s, err := scanSaga(db.QueryRow(ctx,
+		`UPDATE sagas SET status = 'compensating', updated_at = NOW()
+		WHERE id = $1 AND status = 'completed'
+		RETURNING `+sagaColumns,
+		*sagaID))
+	if err == pgx.ErrNoRows {
+		// Already compensating, or compensated when the payout was refused
+		return StripeWebhookResponse{Status: "updated"}, nil
+	}
+	if err != nil {
+		return StripeWebhookResponse{}, err
+	}
+	reason := payout.FailureMessage
+	if reason == "" {
+		reason = "payout " + payout.Status
+	}
+	s.Error = fmt.Sprintf("%s failed: %s", StripePayoutAction, reason)
+	if err := sagaCoordinator().Compensate(ctx, s); err != nil {
+		return StripeWebhookResponse{}, err
+	}
+	if s.Status != saga.StatusCompensated {
+		log.Printf("Failed to reverse withdrawal %s after payout %s %s", s.ID, payout.ID, payout.Status)
+		return StripeWebhookResponse{Status: s.Status}, nil
+	}
+	return StripeWebhookResponse{Status: "reversed", TransactionID: s.ReversalID}, nil
+}
+
+// creditStripeCharge credits a succeeded charge to the customer its
+// metadata names and records the charge against the posting
+func creditStripeCharge(ctx context.Context, c *gin.Context, charge stripe.Charge) {
+	customerID, err := uuid.Parse(charge.Metadata["customer_id"])
+	if err != nil || !charge.Paid || charge.ID == "" {
+		// Charges not made for a ledger customer are none of its business
+		c.JSON(http.StatusOK, StripeWebhookResponse{Status: "ignored"})
+		return
+	}
+	currency := strings.ToUpper(charge.Currency)
+	amount := stripe.FromMinor(charge.Amount, currency)
+	result, err := postings().Post(ctx, ledger.Posting{
+		CustomerID:      customerID,
+		Type:            "card_payment",
+		Amount:          amount,
+		Currency:        currency,
+		Reference:       "stripe:" + charge.ID,
+		UniqueReference: true,
+		// Stripe redelivers rather than repeats, and a redelivery is caught
+		// by the unique reference
+		AllowDuplicate: true,
+	})
+	var duplicate *ledger.DuplicateReferenceError
+	var violation *ledger.ViolationError
+	switch {
+	case errors.As(err, &duplicate):
+		c.JSON(http.StatusOK, StripeWebhookResponse{Status: "duplicate", TransactionID: &duplicate.TransactionID})
+		return
+	case errors.Is(err, ledger.ErrCustomerNotFound), errors.Is(err, ledger.ErrCurrencyMismatch), errors.As(err, &violation):
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Charge cannot be credited: " + err.Error()})
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to credit charge"})
+		return
+	}
+	invalidateBalances(ctx, customerID)
+	if result.Status == ledger.StatusRejected {
+		respondError(c, http.StatusUnprocessableEntity, ErrorResponse{Error: "Transaction rejected by fraud rules"})
+		return
+	}
+
+	_, err = db.Exec(ctx,
+		`INSERT INTO stripe_objects (id, kind, customer_id, transaction_id, amount, currency, status)
+		VALUES ($1, 'charge', $2, $3, $4, $5, 'succeeded')
+		ON CONFLICT (id) DO NOTHING`,
+		charge.ID, customerID, result.TransactionID, amount, currency)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record charge"})
+		return
+	}
+	c.JSON(http.StatusOK, StripeWebhookResponse{Status: result.Status, TransactionID: &result.TransactionID})
+}
+
+// @Summary Reconcile with Stripe
+// @Description Compare the platform's Stripe balance with what the ledger credited for Stripe charges and paid out for withdrawals, by currency, with the payouts still on their way
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} StripeReconciliation "Reconciliation"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 501 {object} ErrorResponse "Stripe is not configured"
+// @Failure 502 {object} ErrorResponse "Stripe error"
+// @Router /admin/stripe/reconciliation [get]
+func GetStripeReconciliation(c *gin.Context) {
+	if !stripeEnabled(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	balance, err := stripeAPI.GetBalance(ctx)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to fetch Stripe balance: " + err.Error()})
+		return
+	}
+
+	totals := map[string]*StripeCurrencyTotals{}
+	var currencies []string
+	totalsFor := func(currency string) *StripeCurrencyTotals {
+		currency = strings.ToUpper(currency)
+		t, ok := totals[currency]
+		if !ok {
+			t = &StripeCurrencyTotals{Currency: currency}
+			totals[currency] = t
+			currencies = append(currencies, currency)
+		}
+		return t
+	}
+	for _, a := range balance.Available {
+		totalsFor(a.Currency).Available += stripe.FromMinor(a.Amount, a.Currency)
+	}
+	for _, a := range balance.Pending {
+		totalsFor(a.Currency).Pending += stripe.FromMinor(a.Amount, a.Currency)
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT currency, kind, status, COALESCE(SUM(amount), 0), COUNT(*)
+		FROM stripe_objects GROUP BY currency, kind, status ORDER BY currency`)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch Stripe records"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var currency, kind, status string
+		var amount float64
+		var count int
+		if err := rows.Scan(&currency, &kind, &status, &amount, &count); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch Stripe records"})
+			return
+		}
+		t := totalsFor(currency)
+		switch {
+		case kind == "charge":
+			t.Charges += amount
+		case status == stripe.PayoutPaid:
+			t.PayoutsPaid += amount
+		case status == stripe.PayoutFailed || status == stripe.PayoutCanceled:
+			t.PayoutsReversed += amount
+		default:
+			t.PayoutsOpen += amount
+			t.OpenPayouts += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch Stripe records"})
+		return
+	}
+
+	report := StripeReconciliation{Currencies: []StripeCurrencyTotals{}, GeneratedAt: time.Now().UTC().Format(time.RFC3339)}
+	for _, currency := range currencies {
+		report.Currencies = append(report.Currencies, *totals[currency])
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RefreshStripePayouts fetches every payout still pending or in transit
+// from Stripe and applies its status, so a missed webhook still settles or
+// reverses the withdrawal. It returns how many changed status and is run
+// on the STRIPE_RECONCILE schedule.
+func RefreshStripePayouts(ctx context.Context, _ time.Time) (int, error) {
+	if stripeAPI == nil {
+		return 0, nil
+	}
+	rows, err := db.Query(ctx,
+		`SELECT id, COALESCE(account_id, ''), status FROM stripe_objects
+		WHERE kind = 'payout' AND status IN ('pending', 'in_transit') ORDER BY created_at`)
+	if err != nil {
+		return 0, err
+	}
+	type open struct{ id, account, status string }
+	var payouts []open
+	for rows.Next() {
+		var p open
+		if err := rows.Scan(&p.id, &p.account, &p.status); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		payouts = append(payouts, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, p := range payouts {
+		payout, err := stripeAPI.GetPayout(ctx, p.id, p.account)
+		if err != nil {
+			log.Printf("Failed to fetch Stripe payout %s: %v", p.id, err)
+			continue
+		}
+		if payout.Status == p.status {
+			continue
+		}
+		if _, err := applyPayoutStatus(ctx, payout); err != nil {
+			log.Printf("Failed to update Stripe payout %s: %v", p.id, err)
+			continue
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/saga"
+	"ledger-service/store"
+	"ledger-service/stripe"
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPayoutProvider struct {
+	payouts  map[string]stripe.Payout
+	created  []stripe.PayoutParams
+	canceled []string
+}
+
+func (p *testPayoutProvider) CreatePayout(ctx context.Context, params stripe.PayoutParams, idempotencyKey string) (stripe.Payout, error) {
+	p.created = append(p.created, params)
+	payout := stripe.Payout{ID: "po_1", Amount: params.Amount, Currency: params.Currency, Status: stripe.PayoutPending}
+	p.payouts[payout.ID] = payout
+	return payout, nil
+}
+
+func (p *testPayoutProvider) GetPayout(ctx context.Context, id, account string) (stripe.Payout, error) {
+	return p.payouts[id], nil
+}
+
+func (p *testPayoutProvider) CancelPayout(ctx context.Context, id, account string) (stripe.Payout, error) {
+	p.canceled = append(p.canceled, id)
+	payout := p.payouts[id]
+	payout.Status = stripe.PayoutCanceled
+	p.payouts[id] = payout
+	return payout, nil
+}
+
+func (p *testPayoutProvider) GetBalance(ctx context.Context) (stripe.Balance, error) {
+	return stripe.Balance{
+		Available: []stripe.Amount{{Amount: 184025, Currency: "usd"}},
+		Pending:   []stripe.Amount{{Amount: 12000, Currency: "usd"}},
+	}, nil
+}
+
+func expectSagaSaves(n int) {
+	args := make([]interface{}, 12)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	for i := 0; i < n; i++ {
+		mock.ExpectExec("INSERT INTO sagas").WithArgs(args...).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+}
+
+func TestCreateWithdrawal(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.POST("/customers/:customer_id/withdrawals", CreateWithdrawal)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 500, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	send := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/customers/"+customer.ID.String()+"/withdrawals", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(map[string]interface{}{"amount": 125.5})
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	provider := &testPayoutProvider{payouts: map[string]stripe.Payout{}}
+	InitStripe(provider, "whsec_1")
+	defer InitStripe(nil, "")
+	InitSagas(map[string]saga.Action{StripePayoutAction: StripePayout{}})
+	defer InitSagas(nil)
+	expectAccount := func() {
+		mock.ExpectQuery(`SELECT account_id FROM stripe_accounts`).
+			WithArgs(customer.ID).
+			WillReturnRows(pgxmock.NewRows([]string{"account_id"}).AddRow("acct_1"))
+	}
+
+	// Running, posted, payout running, payout done, completed
+	expectAccount()
+	expectSagaSaves(3)
+	expectAccount()
+	mock.ExpectExec(`INSERT INTO stripe_objects`).
+		WithArgs("po_1", "acct_1", customer.ID, pgxmock.AnyArg(), pgxmock.AnyArg(), 125.5, "USD", stripe.PayoutPending).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectSagaSaves(2)
+	w = send(map[string]interface{}{"amount": 125.5, "reference": "Withdrawal to checking"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var s saga.Saga
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+	assert.Equal(t, saga.StatusCompleted, s.Status)
+	assert.Equal(t, "withdrawal", s.Type)
+	assert.Equal(t, "po_1", s.Steps[1].ExternalID)
+	require.Len(t, provider.created, 1)
+	assert.Equal(t, int64(12550), provider.created[0].Amount)
+	assert.Equal(t, "acct_1", provider.created[0].Account)
+	assert.Equal(t, s.TransactionID.String(), provider.created[0].Metadata["transaction_id"])
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, 374.5, balance.Amount)
+
+	mock.ExpectQuery(`SELECT account_id FROM stripe_accounts`).
+		WithArgs(customer.ID).
+		WillReturnRows(pgxmock.NewRows([]string{"account_id"}))
+	w = send(map[string]interface{}{"amount": 10})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStripeWebhook(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.POST("/stripe/webhook", StripeWebhook)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 374.5, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	provider := &testPayoutProvider{payouts: map[string]stripe.Payout{
+		"po_1": {ID: "po_1", Amount: 12550, Currency: "usd", Status: stripe.PayoutFailed, FailureMessage: "The bank account has been closed"},
+	}}
+	InitStripe(provider, "whsec_1")
+	defer InitStripe(nil, "")
+	InitSagas(map[string]saga.Action{StripePayoutAction: StripePayout{}})
+	defer InitSagas(nil)
+	send := func(event map[string]interface{}, secret string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(event)
+		req := httptest.NewRequest("POST", "/stripe/webhook", bytes.NewReader(payload))
+		req.Header.Set(stripe.SignatureHeader, webhook.Sign(secret, time.Now(), payload))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	event := func(kind string, object interface{}) map[string]interface{} {
+		return map[string]interface{}{"id": "evt_1", "type": kind, "data": map[string]interface{}{"object": object}}
+	}
+
+	// A failed payout reverses its completed withdrawal
+	sagaID, transactionID := uuid.New(), uuid.New()
+	now := time.Now().UTC()
+	mock.ExpectQuery(`UPDATE stripe_objects SET status = \$2`).
+		WithArgs("po_1", stripe.PayoutFailed).
+		WillReturnRows(pgxmock.NewRows([]string{"saga_id"}).AddRow(&sagaID))
+	mock.ExpectQuery(`UPDATE sagas SET status = 'compensating'`).
+		WithArgs(sagaID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "customer_id", "type", "amount", "reference", "status", "error", "transaction_id", "reversal_transaction_id", "steps", "created_at", "updated_at"}).
+			AddRow(sagaID, customer.ID, "withdrawal", 125.5, "", saga.StatusCompleted, "", &transactionID, (*uuid.UUID)(nil),
+				[]byte(`[{"name":"ledger","status":"done"},{"name":"stripe_payout","status":"done","external_id":"po_1"}]`), now, now))
+	expectSagaSaves(1)
+	mock.ExpectQuery(`SELECT id, account_id FROM stripe_objects`).
+		WithArgs(sagaID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "account_id"}).AddRow("po_1", "acct_1"))
+	mock.ExpectExec(`UPDATE stripe_objects SET status = \$2`).
+		WithArgs("po_1", stripe.PayoutFailed).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	expectSagaSaves(3)
+	w := send(event("payout.failed", provider.payouts["po_1"]), "whsec_1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp StripeWebhookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "reversed", resp.Status)
+	require.NotNil(t, resp.TransactionID)
+	assert.Empty(t, provider.canceled)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, float64(500), balance.Amount)
+
+	// A succeeded charge is credited once, however often it is delivered
+	charge := map[string]interface{}{"id": "ch_1", "amount": 2000, "currency": "usd", "status": "succeeded", "paid": true,
+		"metadata": map[string]string{"customer_id": customer.ID.String()}}
+	mock.ExpectExec(`INSERT INTO stripe_objects`).
+		WithArgs("ch_1", customer.ID, pgxmock.AnyArg(), float64(20), "USD").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	w = send(event("charge.succeeded", charge), "whsec_1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "posted", resp.Status)
+	posted := *resp.TransactionID
+	w = send(event("charge.succeeded", charge), "whsec_1")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "duplicate", resp.Status)
+	assert.Equal(t, posted, *resp.TransactionID)
+	balance, _ = memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, float64(520), balance.Amount)
+
+	w = send(event("charge.succeeded", map[string]interface{}{"id": "ch_2", "amount": 500, "currency": "usd", "paid": true}), "whsec_1")
+	assert.JSONEq(t, `{"status": "ignored"}`, w.Body.String())
+	w = send(event("customer.created", map[string]interface{}{"id": "cus_1"}), "whsec_1")
+	assert.JSONEq(t, `{"status": "ignored"}`, w.Body.String())
+	w = send(event("charge.succeeded", charge), "whsec_other")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetStripeReconciliation(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.GET("/admin/stripe/reconciliation", GetStripeReconciliation)
+	InitStripe(&testPayoutProvider{}, "whsec_1")
+	defer InitStripe(nil, "")
+
+	mock.ExpectQuery(`SELECT currency, kind, status`).
+		WillReturnRows(pgxmock.NewRows([]string{"currency", "kind", "status", "sum", "count"}).
+			AddRow("USD", "charge", "succeeded", 2210.25, 12).
+			AddRow("USD", "payout", stripe.PayoutPaid, float64(250), 2).
+			AddRow("USD", "payout", stripe.PayoutInTransit, 125.5, 1).
+			AddRow("EUR", "payout", stripe.PayoutFailed, float64(40), 1))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/stripe/reconciliation", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report StripeReconciliation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Len(t, report.Currencies, 2)
+	assert.Equal(t, StripeCurrencyTotals{
+		Currency: "USD", Available: 1840.25, Pending: 120, Charges: 2210.25, PayoutsPaid: 250, PayoutsOpen: 125.5, OpenPayouts: 1,
+	}, report.Currencies[0])
+	assert.Equal(t, StripeCurrencyTotals{Currency: "EUR", PayoutsReversed: 40}, report.Currencies[1])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,546 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ledger-service/fx"
+	"ledger-service/money"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SubAccount represents a named wallet held under a customer
+// @Description Named sub-account with its own balance
+type SubAccount struct {
+	ID         uuid.UUID `json:"sub_account_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	CustomerID uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	Name       string    `json:"name" example:"vacation fund"`
+	Currency   string    `json:"currency" example:"USD" enums:"USD,EUR,GBP"`
+	Balance    float64   `json:"balance" example:"250"`
+	CreatedAt  string    `json:"created_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// SubAccountRequest represents the payload for opening a sub-account
+type SubAccountRequest struct {
+	Name     string `json:"name" binding:"required,max=100" example:"vacation fund" maxLength:"100"`
+	Currency string `json:"currency" binding:"omitempty,currency" example:"USD" enums:"USD,EUR,GBP"`
+}
+
+// MoveRequest represents an internal move between a customer's balances.
+// An omitted sub-account ID refers to the customer's main balance.
+type MoveRequest struct {
+	FromSubAccountID *uuid.UUID `json:"from_sub_account_id,omitempty" format:"uuid"`
+	ToSubAccountID   *uuid.UUID `json:"to_sub_account_id,omitempty" format:"uuid"`
+	Amount           float64    `json:"amount" binding:"required,money" example:"100" minimum:"0.01"`
+	QuoteID          *uuid.UUID `json:"quote_id,omitempty" format:"uuid"`
+}
+
+// MoveResponse represents the result of an internal move
+type MoveResponse struct {
+	MoveID           uuid.UUID  `json:"move_id" format:"uuid"`
+	FromSubAccountID *uuid.UUID `json:"from_sub_account_id,omitempty" format:"uuid"`
+	ToSubAccountID   *uuid.UUID `json:"to_sub_account_id,omitempty" format:"uuid"`
+	Amount           float64    `json:"amount" example:"100"`
+	FromCurrency     string     `json:"from_currency" example:"USD"`
+	ToCurrency       string     `json:"to_currency" example:"EUR"`
+	Rate             float64    `json:"rate" example:"0.9123"`
+	ConvertedAmount  float64    `json:"converted_amount" example:"91.23"`
+	// Rounding is the mode applied to the converted amount
+	Rounding string `json:"rounding" example:"half_up" enums:"half_up,half_even,truncate,down,up"`
+	// RateProvider names the provider the rate came from
+	RateProvider string     `json:"rate_provider,omitempty" example:"exchangerate-api"`
+	QuoteID      *uuid.UUID `json:"quote_id,omitempty" format:"uuid"`
+	FromBalance  float64    `json:"from_balance" example:"150"`
+	ToBalance    float64    `json:"to_balance" example:"341.23"`
+}
+
+var (
+	errMoveNotFound = errors.New("sub-account not found")
+	// errMoveCreditAccount refuses moves to or from the main balance of a
+	// credit account, which holds what the customer owes rather than funds
+	errMoveCreditAccount = errors.New("main balance is a credit account")
+)
+
+// moveLeg is one locked side of an internal move
+type moveLeg struct {
+	subAccountID *uuid.UUID
+	balance      float64
+	currency     string
+}
+
+// lockMoveLegs locks the customer row and any sub-accounts involved in a move.
+// The customer is always locked first and sub-accounts in ID order so that
+// concurrent moves cannot deadlock.
+func lockMoveLegs(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, from, to *uuid.UUID) (moveLeg, moveLeg, error) {
+	var mainBalance float64
+	var balanceType, baseCurrency string
+	err := tx.QueryRow(ctx,
+		"SELECT balance, balance_type, currency FROM customers WHERE id = $1 FOR UPDATE",
+		customerID).Scan(&mainBalance, &balanceType, &baseCurrency)
+	if err != nil {
+		return moveLeg{}, moveLeg{}, err
+	}
+	if balanceType == store.BalanceCredit && (from == nil || to == nil) {
+		return moveLeg{}, moveLeg{}, errMoveCreditAccount
+	}
+
+	legs := map[uuid.UUID]*moveLeg{}
+	var ids []uuid.UUID
+	for _, id := range []*uuid.UUID{from, to} {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	if len(ids) == 2 && strings.Compare(ids[0].String(), ids[1].String()) > 0 {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	for _, id := range ids {
+		id := id
+		leg := &moveLeg{subAccountID: &id}
+		err := tx.QueryRow(ctx,
+			"SELECT balance, currency FROM sub_accounts WHERE id = $1 AND customer_id = $2 FOR UPDATE",
+			id, customerID).Scan(&leg.balance, &leg.currency)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return moveLeg{}, moveLeg{}, errMoveNotFound
+			}
+			return moveLeg{}, moveLeg{}, err
+		}
+		legs[id] = leg
+	}
+
+	leg := func(id *uuid.UUID) moveLeg {
+		if id == nil {
+			return moveLeg{balance: mainBalance, currency: baseCurrency}
+		}
+		return *legs[*id]
+	}
+	return leg(from), leg(to), nil
+}
+
+// moveCurrency returns the currency of one side of a move without locking it.
+// Currencies never change, so the rate can be fetched before any row locks
+// are taken. The main balance is in the account's base currency.
+func moveCurrency(ctx context.Context, customerID uuid.UUID, subAccountID *uuid.UUID) (string, error) {
+	var currency string
+	if subAccountID == nil {
+		err := db.QueryRow(ctx, "SELECT currency FROM customers WHERE id = $1", customerID).Scan(&currency)
+		return currency, err
+	}
+	err := db.QueryRow(ctx,
+		"SELECT currency FROM sub_accounts WHERE id = $1 AND customer_id = $2",
+		*subAccountID, customerID).Scan(&currency)
+	if err == pgx.ErrNoRows {
+		return "", errMoveNotFound
+	}
+	return currency, err
+}
+
+// applyMoveLeg writes the new balance for one side of a move and records it in
+// that balance's history
+func applyMoveLeg(ctx context.Context, tx pgx.Tx, customerID, moveID uuid.UUID, leg moveLeg, txType string, amount float64) error {
+	if leg.subAccountID == nil {
+		if _, err := tx.Exec(ctx,
+			"UPDATE customers SET balance = $1 WHERE id = $2",
+			leg.balance, customerID); err != nil {
+			return err
+		}
+		mainType := "move_in"
+		if txType == "debit" {
+			mainType = "move_out"
+		}
+		_, err := tx.Exec(ctx,
+			"INSERT INTO transactions (id, customer_id, type, amount, status) VALUES ($1, $2, $3, $4, 'posted')",
+			uuid.New(), customerID, mainType, amount)
+		return err
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE sub_accounts SET balance = $1 WHERE id = $2",
+		leg.balance, *leg.subAccountID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx,
+		"INSERT INTO sub_account_transactions (id, sub_account_id, move_id, type, amount) VALUES ($1, $2, $3, $4, $5)",
+		uuid.New(), *leg.subAccountID, moveID, txType, amount)
+	return err
+}
+
+// @Summary Open a sub-account
+// @Description Open a named sub-account (wallet) under a customer. Sub-accounts start with a zero balance.
+// @Tags sub-accounts
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param sub_account body SubAccountRequest true "Sub-account details"
+// @Success 201 {object} SubAccount "Sub-account created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 409 {object} ErrorResponse "A sub-account with this name already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/sub-accounts [post]
+func CreateSubAccount(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req SubAccountRequest
+	if !bindRequest(c, &req, "Invalid input: name is required (max 100 characters)") {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: name is required (max 100 characters)"})
+		return
+	}
+
+	// Sub-accounts default to the account's base currency
+	ctx := c.Request.Context()
+	var baseCurrency string
+	if err := db.QueryRow(ctx,
+		"SELECT currency FROM customers WHERE id = $1",
+		customerID).Scan(&baseCurrency); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		}
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = baseCurrency
+	}
+
+	account := SubAccount{
+		ID:         uuid.New(),
+		CustomerID: customerID,
+		Name:       req.Name,
+		Currency:   req.Currency,
+	}
+	var createdAt time.Time
+	err = db.QueryRow(ctx,
+		"INSERT INTO sub_accounts (id, customer_id, name, currency) VALUES ($1, $2, $3, $4) RETURNING created_at",
+		account.ID, customerID, account.Name, account.Currency).Scan(&createdAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			respondError(c, http.StatusConflict, ErrorResponse{Error: "A sub-account with this name already exists"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create sub-account"})
+		}
+		return
+	}
+	account.CreatedAt = createdAt.Format(time.RFC3339)
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// @Summary List sub-accounts
+// @Description List a customer's sub-accounts and their balances
+// @Tags sub-accounts
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Success 200 {array} SubAccount "Sub-accounts"
+// @Failure 400 {object} ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} ErrorResponse "Customer not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /customers/{customer_id}/sub-accounts [get]
+func ListSubAccounts(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var exists bool
+	if err := db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+		customerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+
+	rows, err := db.Query(ctx,
+		"SELECT id, name, currency, balance, created_at FROM sub_accounts WHERE customer_id = $1 ORDER BY created_at",
+		customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch sub-accounts"})
+		return
+	}
+	defer rows.Close()
+
+	accounts := []SubAccount{}
+	for rows.Next() {
+		account := SubAccount{CustomerID: customerID}
+		var createdAt time.Time
+		if err := rows.Scan(&account.ID, &account.Name, &account.Currency, &account.Balance, &createdAt); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan sub-account"})
+			return
+		}
+		account.CreatedAt = createdAt.Format(time.RFC3339)
+		accounts = append(accounts, account)
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// @Summary Get sub-account history
+// @Description Get paginated transaction history for a sub-account
+// @Tags sub-accounts
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param sub_account_id path string true "Sub-account ID" format(uuid)
+// @Param page query int false "Page number (1-based)" minimum(1) default(1)
+// @Param page_size query int false "Number of items per page" minimum(1) maximum(100) default(10)
+// @Param If-None-Match header string false "ETag of a page already held, to revalidate it"
+// @Success 200 {array} Transaction "List of transactions"
+// @Success 304 "Page unchanged since the given ETag"
+// @Failure 400 {object} ErrorResponse "Invalid ID format or pagination parameters"
+// @Failure 404 {object} ErrorResponse "Sub-account not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Header 200 {string} X-Total-Count "Total number of transactions"
+// @Header 200 {string} X-Page "Current page number"
+// @Header 200 {string} X-Page-Size "Items per page"
+// @Header 200 {string} X-Total-Pages "Total number of pages"
+// @Router /customers/{customer_id}/sub-accounts/{sub_account_id}/transactions [get]
+func GetSubAccountTransactions(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+	subAccountID, err := uuid.Parse(c.Param("sub_account_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid sub-account ID"})
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var exists bool
+	if err := db.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM sub_accounts WHERE id = $1 AND customer_id = $2)",
+		subAccountID, customerID).Scan(&exists); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify sub-account"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Sub-account not found"})
+		return
+	}
+
+	var totalCount int
+	if err := db.QueryRow(ctx,
+		"SELECT COUNT(*) FROM sub_account_transactions WHERE sub_account_id = $1",
+		subAccountID).Scan(&totalCount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get total count"})
+		return
+	}
+
+	rows, err := db.Query(ctx,
+		"SELECT id, type, amount, created_at FROM sub_account_transactions WHERE sub_account_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		subAccountID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
+		return
+	}
+	defer rows.Close()
+
+	transactions := []Transaction{}
+	for rows.Next() {
+		t := Transaction{CustomerID: customerID}
+		var timestamp time.Time
+		if err := rows.Scan(&t.ID, &t.Type, &t.Amount, &timestamp); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan transaction"})
+			return
+		}
+		t.Timestamp = timestamp.Format(time.RFC3339)
+		transactions = append(transactions, t)
+	}
+
+	c.Header("X-Total-Count", fmt.Sprintf("%d", totalCount))
+	c.Header("X-Page", fmt.Sprintf("%d", page))
+	c.Header("X-Page-Size", fmt.Sprintf("%d", pageSize))
+	c.Header("X-Total-Pages", fmt.Sprintf("%d", (totalCount+pageSize-1)/pageSize))
+
+	c.JSON(http.StatusOK, transactions)
+}
+
+// @Summary Move funds between balances
+// @Description Move money between a customer's main balance and sub-accounts. Moves are internal bookkeeping: they bypass KYC limits, account type rules and fraud rules. Omit a sub-account ID to use the main balance. Moves between currencies are converted at the provider's current rate, or at the rate of a previously issued FX quote, which is stored on the move.
+// @Tags sub-accounts
+// @Accept json
+// @Produce json
+// @Param customer_id path string true "Customer ID" format(uuid)
+// @Param move body MoveRequest true "Move details"
+// @Success 201 {object} MoveResponse "Move completed"
+// @Failure 400 {object} ErrorResponse "Invalid input data or insufficient balance"
+// @Failure 404 {object} ErrorResponse "Customer, sub-account or quote not found"
+// @Failure 409 {object} ErrorResponse "Quote has already been used"
+// @Failure 410 {object} ErrorResponse "Quote has expired"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 502 {object} ErrorResponse "Exchange rate provider error"
+// @Failure 503 {object} ErrorResponse "Currency conversion is not configured"
+// @Router /customers/{customer_id}/moves [post]
+func MoveFunds(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid customer ID"})
+		return
+	}
+
+	var req MoveRequest
+	if !bindRequest(c, &req, "Invalid input: amount (> 0) is required") {
+		return
+	}
+	if req.FromSubAccountID == nil && req.ToSubAccountID == nil ||
+		req.FromSubAccountID != nil && req.ToSubAccountID != nil && *req.FromSubAccountID == *req.ToSubAccountID {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: source and destination must differ"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var currencies [2]string
+	for i, id := range []*uuid.UUID{req.FromSubAccountID, req.ToSubAccountID} {
+		currencies[i], err = moveCurrency(ctx, customerID, id)
+		if err != nil {
+			switch {
+			case err == pgx.ErrNoRows:
+				respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+			case errors.Is(err, errMoveNotFound):
+				respondError(c, http.StatusNotFound, ErrorResponse{Error: "Sub-account not found"})
+			default:
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get sub-account"})
+			}
+			return
+		}
+	}
+
+	// Fetch the rate before locking any balances, unless a quote fixes it
+	rate := fx.Rate{Value: 1}
+	if req.QuoteID == nil && currencies[0] != currencies[1] {
+		if fxProvider == nil {
+			respondError(c, http.StatusServiceUnavailable, ErrorResponse{Error: "Currency conversion is not configured"})
+			return
+		}
+		rate, err = lookupRate(ctx, currencies[0], currencies[1])
+		if err != nil {
+			respondError(c, http.StatusBadGateway, ErrorResponse{Error: "Failed to fetch exchange rate"})
+			return
+		}
+	}
+	converted, rounding := roundingPolicy.Convert(req.Amount, rate.Value, currencies[1])
+	if converted <= 0 {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: amount is too small to convert"})
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if req.QuoteID != nil {
+		quote, err := consumeFXQuote(ctx, tx, *req.QuoteID, customerID)
+		if err != nil {
+			respondQuoteError(c, err)
+			return
+		}
+		if quote.FromCurrency != currencies[0] || quote.ToCurrency != currencies[1] || quote.Amount != req.Amount {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Quote does not match this move"})
+			return
+		}
+		rate = fx.Rate{Value: quote.Rate, Provider: quote.RateProvider}
+		converted, rounding = quote.ConvertedAmount, money.Rounding(quote.Rounding)
+	}
+
+	from, to, err := lockMoveLegs(ctx, tx, customerID, req.FromSubAccountID, req.ToSubAccountID)
+	if err != nil {
+		switch {
+		case err == pgx.ErrNoRows:
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		case errors.Is(err, errMoveNotFound):
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Sub-account not found"})
+		case errors.Is(err, errMoveCreditAccount):
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Moves to or from the main balance need a deposit account"})
+		default:
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get current balance"})
+		}
+		return
+	}
+
+	if from.balance < req.Amount {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Insufficient balance"})
+		return
+	}
+	from.balance -= req.Amount
+	to.balance += converted
+
+	// Record the move with the captured rate, its provider, rounding and both amounts
+	moveID := uuid.New()
+	_, err = tx.Exec(ctx,
+		"INSERT INTO moves (id, customer_id, from_sub_account_id, to_sub_account_id, from_currency, to_currency, amount, converted_amount, rate, rounding, quote_id, rate_provider) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''))",
+		moveID, customerID, req.FromSubAccountID, req.ToSubAccountID, from.currency, to.currency, req.Amount, converted, rate.Value, string(rounding), req.QuoteID, rate.Provider)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to record move"})
+		return
+	}
+
+	if err := applyMoveLeg(ctx, tx, customerID, moveID, from, "debit", req.Amount); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update balance"})
+		return
+	}
+	if err := applyMoveLeg(ctx, tx, customerID, moveID, to, "credit", converted); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update balance"})
+		return
+	}
+	if from.currency != to.currency {
+		if err := postRoundingDifference(ctx, tx, roundingSourceMove, moveID, nil, to.currency, req.Amount*rate.Value, converted); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to post rounding difference"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to commit transaction"})
+		return
+	}
+	invalidateBalances(ctx, customerID)
+
+	c.JSON(http.StatusCreated, MoveResponse{
+		MoveID:           moveID,
+		FromSubAccountID: req.FromSubAccountID,
+		ToSubAccountID:   req.ToSubAccountID,
+		Amount:           req.Amount,
+		FromCurrency:     from.currency,
+		ToCurrency:       to.currency,
+		Rate:             rate.Value,
+		ConvertedAmount:  converted,
+		Rounding:         string(rounding),
+		RateProvider:     rate.Provider,
+		QuoteID:          req.QuoteID,
+		FromBalance:      from.balance,
+		ToBalance:        to.balance,
+	})
+}
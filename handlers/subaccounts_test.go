@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/money"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSubAccount(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/sub-accounts", CreateSubAccount)
+
+	customerID := uuid.New()
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "defaults to the account's base currency",
+			payload:    map[string]interface{}{"name": "vacation fund"},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("EUR"))
+				mock.ExpectQuery(`INSERT INTO sub_accounts \(id, customer_id, name, currency\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING created_at`).
+					WithArgs(pgxmock.AnyArg(), customerID, "vacation fund", "EUR").
+					WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+			},
+		},
+		{
+			name:       "duplicate name",
+			payload:    map[string]interface{}{"name": "reserve", "currency": "EUR"},
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+				mock.ExpectQuery(`INSERT INTO sub_accounts`).
+					WithArgs(pgxmock.AnyArg(), customerID, "reserve", "EUR").
+					WillReturnError(&pgconn.PgError{Code: "23505"})
+			},
+		},
+		{
+			name:       "invalid currency",
+			payload:    map[string]interface{}{"name": "reserve", "currency": "JPY"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/sub-accounts", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var resp SubAccount
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "EUR", resp.Currency)
+				assert.Equal(t, float64(0), resp.Balance)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMoveFunds(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers/:customer_id/moves", MoveFunds)
+
+	customerID := uuid.New()
+	vacation := uuid.New()
+	reserve := uuid.New()
+	first, second := vacation, reserve
+	if first.String() > second.String() {
+		first, second = second, first
+	}
+	balances := map[uuid.UUID]float64{vacation: 300, reserve: 50}
+	expectCurrency := func(id uuid.UUID, currency string) {
+		mock.ExpectQuery(`SELECT currency FROM sub_accounts WHERE id = \$1 AND customer_id = \$2`).
+			WithArgs(id, customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow(currency))
+	}
+	expectMainCurrency := func() {
+		mock.ExpectQuery(`SELECT currency FROM customers WHERE id = \$1`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"currency"}).AddRow("USD"))
+	}
+
+	InitFX(stubRates{"EUR/USD": 1.0963}, money.Policy{}, time.Minute)
+	defer InitFX(nil, money.Policy{}, time.Minute)
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		wantRate   float64
+		setupMock  func()
+	}{
+		{
+			name:       "between sub-accounts",
+			payload:    map[string]interface{}{"from_sub_account_id": vacation, "to_sub_account_id": reserve, "amount": 100},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				expectCurrency(vacation, "USD")
+				expectCurrency(reserve, "USD")
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance, balance_type, currency FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "balance_type", "currency"}).AddRow(float64(1000), "deposit", "USD"))
+				for _, id := range []uuid.UUID{first, second} {
+					mock.ExpectQuery(`SELECT balance, currency FROM sub_accounts WHERE id = \$1 AND customer_id = \$2 FOR UPDATE`).
+						WithArgs(id, customerID).
+						WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(balances[id], "USD"))
+				}
+				mock.ExpectExec(`INSERT INTO moves`).
+					WithArgs(pgxmock.AnyArg(), customerID, &vacation, &reserve, "USD", "USD", float64(100), float64(100), float64(1), "half_up", (*uuid.UUID)(nil), "").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE sub_accounts SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(200), vacation).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO sub_account_transactions`).
+					WithArgs(pgxmock.AnyArg(), vacation, pgxmock.AnyArg(), "debit", float64(100)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE sub_accounts SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(150), reserve).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO sub_account_transactions`).
+					WithArgs(pgxmock.AnyArg(), reserve, pgxmock.AnyArg(), "credit", float64(100)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "from main balance",
+			payload:    map[string]interface{}{"to_sub_account_id": reserve, "amount": 100},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				expectMainCurrency()
+				expectCurrency(reserve, "USD")
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance, balance_type, currency FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "balance_type", "currency"}).AddRow(float64(1000), "deposit", "USD"))
+				mock.ExpectQuery(`SELECT balance, currency FROM sub_accounts`).
+					WithArgs(reserve, customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(50), "USD"))
+				mock.ExpectExec(`INSERT INTO moves`).
+					WithArgs(pgxmock.AnyArg(), customerID, (*uuid.UUID)(nil), &reserve, "USD", "USD", float64(100), float64(100), float64(1), "half_up", (*uuid.UUID)(nil), "").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(900), customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status\) VALUES \(\$1, \$2, \$3, \$4, 'posted'\)`).
+					WithArgs(pgxmock.AnyArg(), customerID, "move_out", float64(100)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE sub_accounts SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(150), reserve).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO sub_account_transactions`).
+					WithArgs(pgxmock.AnyArg(), reserve, pgxmock.AnyArg(), "credit", float64(100)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "insufficient balance",
+			payload:    map[string]interface{}{"from_sub_account_id": reserve, "amount": 100},
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				expectCurrency(reserve, "USD")
+				expectMainCurrency()
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance, balance_type, currency FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "balance_type", "currency"}).AddRow(float64(1000), "deposit", "USD"))
+				mock.ExpectQuery(`SELECT balance, currency FROM sub_accounts`).
+					WithArgs(reserve, customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(50), "USD"))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:       "across currencies",
+			payload:    map[string]interface{}{"from_sub_account_id": vacation, "amount": 100},
+			wantStatus: http.StatusCreated,
+			wantRate:   1.0963,
+			setupMock: func() {
+				expectCurrency(vacation, "EUR")
+				expectMainCurrency()
+				mock.ExpectExec(`INSERT INTO fx_rates`).
+					WithArgs("EUR", "USD", "stub", pgxmock.AnyArg(), 1.0963).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance, balance_type, currency FROM customers WHERE id = \$1 FOR UPDATE`).
+					WithArgs(customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "balance_type", "currency"}).AddRow(float64(1000), "deposit", "USD"))
+				mock.ExpectQuery(`SELECT balance, currency FROM sub_accounts`).
+					WithArgs(vacation, customerID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance", "currency"}).AddRow(float64(300), "EUR"))
+				mock.ExpectExec(`INSERT INTO moves`).
+					WithArgs(pgxmock.AnyArg(), customerID, &vacation, (*uuid.UUID)(nil), "EUR", "USD", float64(100), 109.63, 1.0963, "half_up", (*uuid.UUID)(nil), "stub").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE sub_accounts SET balance = \$1 WHERE id = \$2`).
+					WithArgs(float64(200), vacation).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO sub_account_transactions`).
+					WithArgs(pgxmock.AnyArg(), vacation, pgxmock.AnyArg(), "debit", float64(100)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+					WithArgs(1109.63, customerID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(`INSERT INTO transactions`).
+					WithArgs(pgxmock.AnyArg(), customerID, "move_in", 109.63).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:       "rate provider failure",
+			payload:    map[string]interface{}{"from_sub_account_id": reserve, "amount": 100},
+			wantStatus: http.StatusBadGateway,
+			setupMock: func() {
+				expectCurrency(reserve, "GBP")
+				expectMainCurrency()
+			},
+		},
+		{
+			name:       "same source and destination",
+			payload:    map[string]interface{}{"from_sub_account_id": reserve, "to_sub_account_id": reserve, "amount": 10},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/customers/"+customerID.String()+"/moves", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantRate != 0 {
+				var resp MoveResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantRate, resp.Rate)
+				assert.Equal(t, 109.63, resp.ConvertedAmount)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
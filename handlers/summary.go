@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// activeAccountDays is how recently an account must have posted a
+// transaction to count as active in the admin summary
+const activeAccountDays = 30
+
+// AdminSummary gathers the figures an operations dashboard shows
+// @Description Ledger-wide counts and totals
+type AdminSummary struct {
+	AsOf      string `json:"as_of" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	Customers int    `json:"customers" example:"1200"`
+	// ActiveAccounts have posted a transaction in the last 30 days
+	ActiveAccounts int `json:"active_accounts" example:"830"`
+	// TransactionsToday counts transactions posted since midnight UTC;
+	// TransactionValueToday totals them per account currency
+	TransactionsToday     int                `json:"transactions_today" example:"5400"`
+	TransactionValueToday map[string]float64 `json:"transaction_value_today"`
+	PendingTransactions   int                `json:"pending_transactions" example:"3"`
+	// FailedWebhookDeliveries counts failed attempts in the last 24 hours
+	FailedWebhookDeliveries int `json:"failed_webhook_deliveries" example:"2"`
+	// OutboxBacklog counts events not yet relayed; OldestUnpublishedAt is
+	// when the oldest of them was written
+	OutboxBacklog       int    `json:"outbox_backlog" example:"0"`
+	OldestUnpublishedAt string `json:"oldest_unpublished_at,omitempty" example:"2025-04-08T17:09:00Z" format:"date-time"`
+}
+
+// adminSummarySQL computes every summary figure in one round trip
+const adminSummarySQL = `SELECT
+	(SELECT COUNT(*) FROM customers),
+	(SELECT COUNT(DISTINCT customer_id) FROM transactions WHERE status = 'posted' AND created_at >= $2),
+	(SELECT COUNT(*) FROM transactions WHERE status = 'posted' AND created_at >= $1),
+	(SELECT COALESCE(jsonb_object_agg(currency, total), '{}') FROM (
+		SELECT c.currency, SUM(t.amount) AS total FROM transactions t JOIN customers c ON c.id = t.customer_id
+		WHERE t.status = 'posted' AND t.created_at >= $1 GROUP BY c.currency) v),
+	(SELECT COUNT(*) FROM transactions WHERE status IN ('held', 'pending_approval')),
+	(SELECT COUNT(*) FROM webhook_deliveries WHERE NOT delivered AND created_at >= $3),
+	(SELECT COUNT(*) FROM outbox WHERE published_at IS NULL),
+	(SELECT MIN(created_at) FROM outbox WHERE published_at IS NULL)`
+
+// @Summary Admin dashboard summary
+// @Description Counts and totals for an operations dashboard in one call: customers, accounts active in the last 30 days, transactions posted today (UTC) and their value per currency, transactions awaiting review, webhook deliveries that failed in the last 24 hours, and the event outbox backlog
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} AdminSummary "Summary"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/summary [get]
+func GetAdminSummary(c *gin.Context) {
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+
+	summary := AdminSummary{AsOf: now.Format(time.RFC3339)}
+	var values []byte
+	var oldest *time.Time
+	if err := db.QueryRow(c.Request.Context(), adminSummarySQL,
+		today, today.AddDate(0, 0, -activeAccountDays), now.Add(-24*time.Hour)).Scan(
+		&summary.Customers, &summary.ActiveAccounts, &summary.TransactionsToday, &values,
+		&summary.PendingTransactions, &summary.FailedWebhookDeliveries, &summary.OutboxBacklog, &oldest); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute summary"})
+		return
+	}
+	if err := json.Unmarshal(values, &summary.TransactionValueToday); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute summary"})
+		return
+	}
+	if oldest != nil {
+		summary.OldestUnpublishedAt = oldest.UTC().Format(time.RFC3339)
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAdminSummary(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/summary", GetAdminSummary)
+
+	columns := []string{"customers", "active", "today", "value", "pending", "failed", "backlog", "oldest"}
+	oldest := time.Date(2025, 4, 8, 17, 9, 0, 0, time.UTC)
+
+	t.Run("computes the summary", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \(SELECT COUNT\(\*\) FROM customers\)`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(1200, 830, 5400, []byte(`{"USD": 125000.5, "EUR": 300}`), 3, 2, 4, &oldest))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/summary", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var summary AdminSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, 1200, summary.Customers)
+		assert.Equal(t, 830, summary.ActiveAccounts)
+		assert.Equal(t, map[string]float64{"USD": 125000.5, "EUR": 300}, summary.TransactionValueToday)
+		assert.Equal(t, 4, summary.OutboxBacklog)
+		assert.Equal(t, "2025-04-08T17:09:00Z", summary.OldestUnpublishedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty outbox", func(t *testing.T) {
+		mock.ExpectQuery(`FROM outbox WHERE published_at IS NULL`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(0, 0, 0, []byte(`{}`), 0, 0, 0, (*time.Time)(nil)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/summary", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "oldest_unpublished_at")
+		assert.Contains(t, w.Body.String(), `"transaction_value_today":{}`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
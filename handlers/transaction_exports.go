@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ledger-service/ledger"
+	"ledger-service/statement"
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	statementBankID = "LEDGER"
+	// yearlyStatementNumbers restarts MT940 statement numbers at 1 each year
+	yearlyStatementNumbers bool
+)
+
+// InitStatements sets the bank ID exported statements give their accounts,
+// which finance tools match later imports by, and whether MT940 statement
+// numbers restart each year
+func InitStatements(bankID string, yearlyNumbers bool) {
+	statementBankID = bankID
+	yearlyStatementNumbers = yearlyNumbers
+}
+
+// transactionExportFormats are the formats transaction history exports to,
+// besides the default JSON page
+var transactionExportFormats = map[string]string{
+	"ofx": "application/x-ofx",
+	"qif": "application/qif",
+}
+
+// exportBatchSize is how many transactions an export reads at a time
+const exportBatchSize = 500
+
+// exportTransactions writes every posted transaction of a customer matching
+// filter, oldest first, as an OFX or QIF file
+func exportTransactions(c *gin.Context, customerID uuid.UUID, format string, filter store.TransactionFilter) {
+	if filter.Status != "" && filter.Status != ledger.StatusPosted {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid status: OFX and QIF exports only hold posted transactions"})
+		return
+	}
+	filter.Status = ledger.StatusPosted
+
+	ctx := c.Request.Context()
+	customer, err := ledgerStore.GetCustomer(ctx, customerID)
+	if errors.Is(err, store.ErrNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	balance, err := ledgerStore.GetBalance(ctx, customerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch balance"})
+		return
+	}
+	now := time.Now().UTC()
+	credit := customer.BalanceType == store.BalanceCredit
+	s := statement.Statement{
+		BankID:    statementBankID,
+		AccountID: customerID.String(),
+		Currency:  balance.Currency,
+		Credit:    credit,
+		Balance:   balance.Amount,
+		BalanceAt: now,
+	}
+	if s.Currency == "" {
+		s.Currency = store.DefaultCurrency
+	}
+	// A credit account's balance is what the customer owes, which finance
+	// tools show as a negative card balance
+	if credit {
+		s.Balance = -s.Balance
+	}
+
+	opts := store.ListOptions{TransactionFilter: filter, Sort: "created_at", Limit: exportBatchSize}
+	for {
+		listed, err := ledgerStore.ListTransactions(ctx, customerID, opts)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transactions"})
+			return
+		}
+		for _, t := range listed {
+			s.Entries = append(s.Entries, statementEntry(t))
+		}
+		if len(listed) < exportBatchSize {
+			break
+		}
+		opts.Offset += exportBatchSize
+	}
+
+	// The period runs from the filter's dates, or else the first value date
+	// exported to today
+	today := now.Truncate(24 * time.Hour)
+	s.From, s.To = today, today
+	if len(s.Entries) > 0 {
+		s.From = s.Entries[0].Date
+		for _, e := range s.Entries {
+			if e.Date.Before(s.From) {
+				s.From = e.Date
+			}
+		}
+	}
+	if filter.ValueDateFrom != nil {
+		s.From = *filter.ValueDateFrom
+	}
+	if filter.ValueDateTo != nil {
+		s.To = *filter.ValueDateTo
+	}
+
+	filename := fmt.Sprintf("transactions-%s-%s.%s", customerID, now.Format("20060102"), format)
+	c.Header("Content-Type", transactionExportFormats[format])
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if format == "ofx" {
+		err = statement.WriteOFX(c.Writer, s, now)
+	} else {
+		err = statement.WriteQIF(c.Writer, s)
+	}
+	if err != nil {
+		log.Printf("Failed to write %s export for customer %s: %v", format, customerID, err)
+	}
+}
+
+// statementEntry is how an exported statement shows t: signed from the
+// customer's side, on its value date, named by its reference or else by
+// what its type is
+func statementEntry(t store.Transaction) statement.Entry {
+	e := statement.Entry{
+		ID:        t.ID.String(),
+		Date:      t.ValueDate,
+		Booked:    t.CreatedAt,
+		Amount:    t.Amount,
+		Type:      t.Type,
+		Reference: t.Reference,
+		Payee:     t.Reference,
+		Memo:      strings.ReplaceAll(t.Type, "_", " "),
+	}
+	if directionOf(t.Type) == txtype.Debit {
+		e.Amount = -t.Amount
+	}
+	if e.Payee == "" {
+		if tt, ok := transactionTypes.Lookup(t.Type); ok && tt.Description != "" {
+			e.Payee = tt.Description
+		} else {
+			e.Payee = e.Memo
+		}
+	}
+	return e
+}
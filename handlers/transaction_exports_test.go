@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTransactions(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.GET("/customers/:customer_id/transactions", GetTransactions)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 957.5, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 0, 0, 0, 0, time.UTC) }
+	for _, tx := range []store.Transaction{
+		{Type: "credit", Amount: 1000, Status: "posted", ValueDate: day(3), Reference: "Salary"},
+		{Type: "purchase", Amount: 42.5, Status: "posted", ValueDate: day(8)},
+		{Type: "debit", Amount: 300, Status: "held", ValueDate: day(9)},
+		{Type: "debit", Amount: 5, Status: "posted", ValueDate: day(20)},
+	} {
+		tx.ID, tx.CustomerID = uuid.New(), customer.ID
+		require.NoError(t, memory.InsertTransaction(ctx, &tx))
+	}
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/customers/"+customer.ID.String()+"/transactions?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("format=ofx&value_date_to=2025-04-10")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/x-ofx", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".ofx")
+	ofx := w.Body.String()
+	assert.Contains(t, ofx, "<DTSTART>20250403\r\n<DTEND>20250410\r\n")
+	assert.Contains(t, ofx, "<TRNTYPE>CREDIT\r\n<DTPOSTED>20250403\r\n<TRNAMT>1000.00\r\n")
+	assert.Contains(t, ofx, "<NAME>Salary\r\n")
+	assert.Contains(t, ofx, "<TRNTYPE>POS\r\n<DTPOSTED>20250408\r\n<TRNAMT>-42.50\r\n")
+	assert.NotContains(t, ofx, "-300.00", "held transactions have not moved the balance")
+	assert.NotContains(t, ofx, "20250420")
+	assert.Contains(t, ofx, "<BALAMT>957.50\r\n")
+
+	w = get("format=qif")
+	require.Equal(t, http.StatusOK, w.Code)
+	qif := w.Body.String()
+	assert.True(t, strings.HasPrefix(qif, "!Type:Bank\nD04/03/2025\nT1000.00\nPSalary\n"))
+	assert.Contains(t, qif, "D04/20/2025\nT-5.00\n")
+	assert.Equal(t, 3, strings.Count(qif, "^\n"))
+
+	assert.Equal(t, http.StatusBadRequest, get("format=ofx&status=held").Code)
+	assert.Equal(t, http.StatusBadRequest, get("format=csv").Code)
+	req := httptest.NewRequest("GET", "/customers/"+uuid.New().String()+"/transactions?format=qif", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
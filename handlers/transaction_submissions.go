@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ledger-service/jobqueue"
+	"ledger-service/ledger"
+	"ledger-service/middleware"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses of a submitted transaction before it is posted, or when it could
+// not be
+const (
+	SubmissionAccepted   = "accepted"
+	SubmissionProcessing = "processing"
+	SubmissionFailed     = "failed"
+	SubmissionCancelled  = "cancelled"
+)
+
+// TransactionSubmission is a transaction submitted with Prefer: respond-async
+// @Description Transaction accepted to be posted in the background, and what became of it
+type TransactionSubmission struct {
+	TransactionID uuid.UUID `json:"transaction_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	CustomerID    uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	// Status is accepted until a worker takes the transaction up and
+	// processing while it is posted. Then it is the transaction's own
+	// status, or failed when the posting was refused.
+	Status string `json:"status" example:"posted" enums:"accepted,processing,posted,held,pending_approval,rejected,failed,cancelled"`
+	// Balance is the customer's balance right after the posting
+	Balance *float64 `json:"balance,omitempty" example:"800"`
+	// Amount, OriginalAmount, OriginalCurrency, FXRate, RateProvider and
+	// Rounding are set when the transaction was converted into the
+	// account's currency
+	Amount           float64 `json:"amount,omitempty" example:"200"`
+	OriginalAmount   float64 `json:"original_amount,omitempty" example:"185.5"`
+	OriginalCurrency string  `json:"original_currency,omitempty" example:"EUR"`
+	FXRate           float64 `json:"fx_rate,omitempty" example:"1.0782"`
+	RateProvider     string  `json:"rate_provider,omitempty" example:"exchangerate-api"`
+	Rounding         string  `json:"rounding,omitempty" example:"half_up" enums:"half_up,half_even,truncate,down,up"`
+	// Error and Code say why a failed or rejected transaction was refused,
+	// as the synchronous response would have
+	Error string `json:"error,omitempty" example:"Insufficient balance"`
+	Code  string `json:"code,omitempty" example:"duplicate_reference"`
+	// DuplicateOf is the transaction already holding the reference when
+	// Code is duplicate_reference
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty" format:"uuid"`
+	SubmittedAt string     `json:"submitted_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	ProcessedAt string     `json:"processed_at,omitempty" example:"2025-04-08T17:09:18Z" format:"date-time"`
+}
+
+// TransactionJob is the payload of a transaction submitted to be posted in
+// the background; the job's ID is the transaction's
+type TransactionJob struct {
+	Type            string  `json:"type"`
+	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency,omitempty"`
+	ValueDate       string  `json:"value_date,omitempty"`
+	Reference       string  `json:"reference,omitempty"`
+	UniqueReference bool    `json:"unique_reference,omitempty"`
+	AllowDuplicate  bool    `json:"allow_duplicate,omitempty"`
+}
+
+// submitTransaction queues a transaction to be posted by the job queue and
+// answers 202 with its ID. Only the request itself and the customer are
+// checked up front; everything else is checked when it is posted.
+func submitTransaction(c *gin.Context, transaction Transaction) {
+	ctx := c.Request.Context()
+	exists, err := ledgerStore.CustomerExists(ctx, transaction.CustomerID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return
+	}
+	id, err := enqueueJob(ctx, db, JobTransactionPost, &transaction.CustomerID, TransactionJob{
+		Type:            transaction.Type,
+		Amount:          transaction.Amount,
+		Currency:        transaction.Currency,
+		ValueDate:       transaction.ValueDate,
+		Reference:       transaction.Reference,
+		UniqueReference: transaction.UniqueReference,
+		// As when posting straight away, a request with an idempotency key
+		// is never a duplicate
+		AllowDuplicate: transaction.AllowDuplicate || c.GetHeader(middleware.IdempotencyKeyHeader) != "",
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to queue transaction"})
+		return
+	}
+	c.Header("Preference-Applied", "respond-async")
+	c.Header("Location", "/v1/transactions/"+id.String())
+	c.JSON(http.StatusAccepted, TransactionSubmission{
+		TransactionID: id,
+		CustomerID:    transaction.CustomerID,
+		Status:        SubmissionAccepted,
+		SubmittedAt:   time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// runTransactionPost posts a submitted transaction. Refusals are its
+// outcome rather than failed attempts, so only trouble reaching the
+// database or a rate provider is retried. The transaction is posted under
+// the job's ID, so an attempt cut short after the posting committed is
+// not posted again.
+func runTransactionPost(ctx context.Context, job jobqueue.Job) (interface{}, error) {
+	var payload TransactionJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || job.CustomerID == nil {
+		return nil, jobqueue.Permanent(fmt.Errorf("invalid transaction job: %v", err))
+	}
+	customerID := *job.CustomerID
+	t, err := ledgerStore.GetTransaction(ctx, customerID, job.ID)
+	if err == nil {
+		return TransactionSubmission{Status: t.Status}, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	p := ledger.Posting{
+		ID:              job.ID,
+		CustomerID:      customerID,
+		Type:            payload.Type,
+		Amount:          payload.Amount,
+		Currency:        payload.Currency,
+		Reference:       payload.Reference,
+		UniqueReference: payload.UniqueReference,
+		AllowDuplicate:  payload.AllowDuplicate,
+	}
+	if payload.ValueDate != "" {
+		if p.ValueDate, err = time.Parse(dateLayout, payload.ValueDate); err != nil {
+			return nil, jobqueue.Permanent(fmt.Errorf("invalid value date %s", payload.ValueDate))
+		}
+	}
+	result, err := postings().Post(ctx, p)
+	if err != nil {
+		status, resp := postingFailure(err, payload.Type)
+		if status >= http.StatusInternalServerError {
+			return nil, err
+		}
+		return TransactionSubmission{Status: SubmissionFailed, Error: resp.Error, Code: resp.Code, DuplicateOf: resp.TransactionID}, nil
+	}
+	invalidateBalances(ctx, customerID)
+
+	resp := transactionResponse(result, result.Status)
+	outcome := TransactionSubmission{
+		Status:           result.Status,
+		Balance:          &resp.Balance,
+		Amount:           resp.Amount,
+		OriginalAmount:   resp.OriginalAmount,
+		OriginalCurrency: resp.OriginalCurrency,
+		FXRate:           resp.FXRate,
+		RateProvider:     resp.RateProvider,
+		Rounding:         resp.Rounding,
+	}
+	switch result.Status {
+	case ledger.StatusRejected:
+		rejected := rejection(result)
+		outcome.Error, outcome.Code = rejected.Error, rejected.Code
+	case ledger.StatusPosted:
+		alertPosted(ctx, customerID, payload.Type, result)
+	}
+	return outcome, nil
+}
+
+// @Summary Get a submitted transaction
+// @Description Get the status of a transaction submitted with Prefer: respond-async. It is accepted until a worker takes it up and processing while it is posted. Then it has the status the synchronous request would have given it, with the balance after posting, or failed with the error and code the request would have been refused with. A held or pending_approval transaction shows its current status, so its approval or rejection is seen here too.
+// @Tags transactions
+// @Produce json
+// @Param transaction_id path string true "Transaction ID" format(uuid)
+// @Success 200 {object} TransactionSubmission "Submitted transaction"
+// @Failure 400 {object} ErrorResponse "Invalid transaction ID"
+// @Failure 404 {object} ErrorResponse "No transaction was submitted with this ID"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /transactions/{transaction_id} [get]
+func GetTransactionSubmission(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("transaction_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid transaction ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	job, err := scanQueueJob(db.QueryRow(ctx,
+		"SELECT "+queueJobColumns+" FROM queue_jobs WHERE id = $1 AND kind = $2",
+		id, JobTransactionPost))
+	if err == pgx.ErrNoRows || err == nil && job.CustomerID == nil {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transaction"})
+		return
+	}
+
+	var submission TransactionSubmission
+	switch job.Status {
+	case jobqueue.StatusQueued:
+		submission.Status = SubmissionAccepted
+	case jobqueue.StatusRunning:
+		submission.Status = SubmissionProcessing
+	case jobqueue.StatusCancelled:
+		submission.Status = SubmissionCancelled
+	case jobqueue.StatusDead:
+		submission.Status = SubmissionFailed
+		submission.Error = "Failed to create transaction"
+	case jobqueue.StatusSucceeded:
+		if err := json.Unmarshal(job.Result, &submission); err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transaction"})
+			return
+		}
+		submission.ProcessedAt = job.FinishedAt
+	}
+	if submission.Status == ledger.StatusHeld || submission.Status == ledger.StatusPendingApproval {
+		t, err := ledgerStore.GetTransaction(ctx, *job.CustomerID, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch transaction"})
+			return
+		}
+		submission.Status = t.Status
+	}
+	submission.TransactionID = id
+	submission.CustomerID = *job.CustomerID
+	submission.SubmittedAt = job.CreatedAt
+	c.JSON(http.StatusOK, submission)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/jobqueue"
+	"ledger-service/ledger"
+	"ledger-service/store"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitTransaction(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	router.POST("/transactions", CreateTransaction)
+
+	customerID := uuid.New()
+	send := func(body map[string]interface{}, idempotencyKey string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Prefer", "respond-async")
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expectCustomer := func(exists bool) {
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM customers WHERE id = \$1\)`).
+			WithArgs(customerID).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(exists))
+	}
+
+	// The request is still checked before it is accepted
+	w := send(map[string]interface{}{"customer_id": customerID, "type": "debit"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	expectCustomer(false)
+	w = send(map[string]interface{}{"customer_id": customerID, "type": "debit", "amount": 25}, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	payload := &capturedArg{}
+	expectCustomer(true)
+	mock.ExpectExec(`INSERT INTO queue_jobs \(id, kind, customer_id, payload, max_attempts\)`).
+		WithArgs(pgxmock.AnyArg(), JobTransactionPost, &customerID, payload, 5).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	w = send(map[string]interface{}{"customer_id": customerID, "type": "debit", "amount": 25, "reference": "INV-1"}, "key-1")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var submission TransactionSubmission
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &submission))
+	assert.Equal(t, SubmissionAccepted, submission.Status)
+	assert.Equal(t, customerID, submission.CustomerID)
+	assert.Equal(t, "/v1/transactions/"+submission.TransactionID.String(), w.Header().Get("Location"))
+	assert.Equal(t, "respond-async", w.Header().Get("Preference-Applied"))
+	raw, _ := payload.value.([]byte)
+	assert.JSONEq(t, `{"type":"debit","amount":25,"reference":"INV-1","allow_duplicate":true}`, string(raw),
+		"a request with an idempotency key is not a duplicate")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunTransactionPost(t *testing.T) {
+	_, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 100, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	job := func(payload string) jobqueue.Job {
+		return jobqueue.Job{ID: uuid.New(), Kind: JobTransactionPost, CustomerID: &customer.ID, Payload: json.RawMessage(payload), Attempts: 1, MaxAttempts: 5}
+	}
+
+	debit := job(`{"type":"debit","amount":30}`)
+	result, err := runTransactionPost(ctx, debit)
+	require.NoError(t, err)
+	outcome := result.(TransactionSubmission)
+	assert.Equal(t, ledger.StatusPosted, outcome.Status)
+	require.NotNil(t, outcome.Balance)
+	assert.Equal(t, float64(70), *outcome.Balance)
+	_, err = memory.GetTransaction(ctx, customer.ID, debit.ID)
+	assert.NoError(t, err, "the transaction is recorded under the ID it was accepted with")
+
+	// A second attempt finds the transaction and does not post it again
+	result, err = runTransactionPost(ctx, debit)
+	require.NoError(t, err)
+	assert.Equal(t, ledger.StatusPosted, result.(TransactionSubmission).Status)
+	balance, _ := memory.GetBalance(ctx, customer.ID)
+	assert.Equal(t, float64(70), balance.Amount)
+
+	// A refused posting is the job's outcome, not a failed attempt
+	result, err = runTransactionPost(ctx, job(`{"type":"debit","amount":500}`))
+	require.NoError(t, err)
+	assert.Equal(t, TransactionSubmission{Status: SubmissionFailed, Error: "Insufficient balance"}, result)
+
+	_, err = runTransactionPost(ctx, job(`{"type":`))
+	assert.True(t, jobqueue.IsPermanent(err))
+}
+
+func TestGetTransactionSubmission(t *testing.T) {
+	router, err := setupTestRouter()
+	require.NoError(t, err)
+	defer mock.Close(context.Background())
+	previous := ledgerStore
+	defer InitStore(previous)
+	memory := store.NewMemory()
+	InitStore(memory)
+	router.GET("/transactions/:transaction_id", GetTransactionSubmission)
+
+	ctx := context.Background()
+	customer := store.Customer{ID: uuid.New(), Name: "Test", Balance: 100, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, memory.CreateCustomer(ctx, &customer))
+	id := uuid.New()
+	now := time.Now()
+	expectJob := func(status string, result []byte) {
+		mock.ExpectQuery(`SELECT .* FROM queue_jobs WHERE id = \$1 AND kind = \$2`).
+			WithArgs(id, JobTransactionPost).
+			WillReturnRows(pgxmock.NewRows(queueJobRowColumns).
+				AddRow(id, JobTransactionPost, &customer.ID, []byte(`{"type":"debit","amount":30}`), status, 1, 5, now, "", result, now, now, &now))
+	}
+	get := func() TransactionSubmission {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/transactions/"+id.String(), nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var submission TransactionSubmission
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &submission))
+		return submission
+	}
+
+	expectJob(jobqueue.StatusQueued, nil)
+	submission := get()
+	assert.Equal(t, SubmissionAccepted, submission.Status)
+	assert.Equal(t, customer.ID, submission.CustomerID)
+	assert.Empty(t, submission.ProcessedAt)
+
+	expectJob(jobqueue.StatusSucceeded, []byte(`{"status":"posted","balance":70}`))
+	submission = get()
+	assert.Equal(t, ledger.StatusPosted, submission.Status)
+	assert.Equal(t, float64(70), *submission.Balance)
+	assert.NotEmpty(t, submission.ProcessedAt)
+
+	// A held transaction shows its current status
+	require.NoError(t, memory.InsertTransaction(ctx, &store.Transaction{ID: id, CustomerID: customer.ID, Type: "debit", Amount: 30, Status: ledger.StatusRejected}))
+	expectJob(jobqueue.StatusSucceeded, []byte(`{"status":"held","balance":100}`))
+	assert.Equal(t, ledger.StatusRejected, get().Status)
+
+	expectJob(jobqueue.StatusDead, nil)
+	submission = get()
+	assert.Equal(t, SubmissionFailed, submission.Status)
+	assert.Equal(t, "Failed to create transaction", submission.Error)
+
+	mock.ExpectQuery(`FROM queue_jobs WHERE id = \$1 AND kind = \$2`).
+		WithArgs(id, JobTransactionPost).
+		WillReturnRows(pgxmock.NewRows(queueJobRowColumns))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/transactions/"+id.String(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ledger-service/txtype"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TransactionTypeRequest represents the payload for registering a transaction type
+type TransactionTypeRequest struct {
+	Code        string `json:"code" binding:"required" example:"cashback"`
+	Direction   string `json:"direction" binding:"required,oneof=credit debit" example:"credit" enums:"credit,debit"`
+	Description string `json:"description" binding:"max=255" example:"Card cashback reward" maxLength:"255"`
+	Postable    *bool  `json:"postable,omitempty" example:"true" default:"true"`
+	GLAccount   string `json:"gl_account,omitempty" example:"card_rewards_expense"`
+}
+
+var (
+	transactionTypes = txtype.Default()
+)
+
+// InitTransactionTypes sets the registry used to validate postings
+func InitTransactionTypes(r *txtype.Registry) {
+	transactionTypes = r
+}
+
+// LoadTransactionTypes adds the types stored in the database to the registry
+func LoadTransactionTypes(ctx context.Context) error {
+	rows, err := db.Query(ctx,
+		"SELECT code, direction, COALESCE(description, ''), postable, COALESCE(gl_account, '') FROM transaction_types ORDER BY code")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t txtype.Type
+		var direction string
+		if err := rows.Scan(&t.Code, &direction, &t.Description, &t.Postable, &t.GLAccount); err != nil {
+			return err
+		}
+		t.Direction = txtype.Direction(direction)
+		if err := transactionTypes.Register(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// directionOf returns the direction a transaction type posts in; unknown
+// types credit
+func directionOf(txType string) txtype.Direction {
+	if t, ok := transactionTypes.Lookup(txType); ok {
+		return t.Direction
+	}
+	return txtype.Credit
+}
+
+// @Summary List transaction types
+// @Description List the registered transaction types and the balance direction each one posts in
+// @Tags transactions
+// @Produce json
+// @Success 200 {array} txtype.Type "Transaction types"
+// @Router /transaction-types [get]
+func ListTransactionTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, transactionTypes.List())
+}
+
+// @Summary Register a transaction type
+// @Description Add a transaction type that can be posted from then on. Types that move the bank's own money name the general ledger account (see /admin/accounts) posted on the other side.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param type body TransactionTypeRequest true "Transaction type"
+// @Success 201 {object} txtype.Type "Transaction type registered"
+// @Failure 400 {object} ErrorResponse "Invalid input data or unknown general ledger account"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 409 {object} ErrorResponse "Transaction type already exists"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/transaction-types [post]
+func CreateTransactionType(c *gin.Context) {
+	var req TransactionTypeRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: code and direction (credit/debit) are required"})
+		return
+	}
+	t := txtype.Type{
+		Code:        req.Code,
+		Direction:   txtype.Direction(req.Direction),
+		Description: req.Description,
+		Postable:    req.Postable == nil || *req.Postable,
+		GLAccount:   req.GLAccount,
+	}
+	if err := t.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: " + err.Error()})
+		return
+	}
+	if _, ok := transactionTypes.Lookup(t.Code); ok {
+		respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction type already exists"})
+		return
+	}
+
+	_, err := db.Exec(c.Request.Context(),
+		"INSERT INTO transaction_types (code, direction, description, postable, gl_account) VALUES ($1, $2, $3, $4, $5)",
+		t.Code, string(t.Direction), nullableString(t.Description), t.Postable, nullableString(t.GLAccount))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			respondError(c, http.StatusConflict, ErrorResponse{Error: "Transaction type already exists"})
+		} else if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: unknown general ledger account " + t.GLAccount})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create transaction type"})
+		}
+		return
+	}
+	transactionTypes.Register(t)
+
+	c.JSON(http.StatusCreated, t)
+}
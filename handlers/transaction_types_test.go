@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/txtype"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTransactionTypes(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitTransactionTypes(txtype.Default())
+	defer InitTransactionTypes(txtype.Default())
+
+	mock.ExpectQuery(`SELECT code, direction, COALESCE\(description, ''\), postable, COALESCE\(gl_account, ''\) FROM transaction_types`).
+		WillReturnRows(pgxmock.NewRows([]string{"code", "direction", "description", "postable", "gl_account"}).
+			AddRow("purchase", "debit", "Card or merchant purchase", true, "").
+			AddRow("cashback", "credit", "Card cashback reward", true, "card_rewards_expense"))
+
+	assert.NoError(t, LoadTransactionTypes(context.Background()))
+	cashback, ok := transactionTypes.Lookup("cashback")
+	assert.True(t, ok)
+	assert.Equal(t, txtype.Credit, cashback.Direction)
+	assert.Equal(t, "card_rewards_expense", cashback.GLAccount)
+	assert.Equal(t, txtype.Credit, directionOf("cashback"))
+	assert.Equal(t, txtype.Debit, directionOf("purchase"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateTransactionType(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	InitTransactionTypes(txtype.Default())
+	defer InitTransactionTypes(txtype.Default())
+
+	router.POST("/admin/transaction-types", CreateTransactionType)
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "registered",
+			payload:    map[string]interface{}{"code": "cashback", "direction": "credit", "description": "Card cashback reward"},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectExec(`INSERT INTO transaction_types \(code, direction, description, postable, gl_account\)`).
+					WithArgs("cashback", "credit", pgxmock.AnyArg(), true, pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name:       "builtin code",
+			payload:    map[string]interface{}{"code": "fee", "direction": "debit"},
+			wantStatus: http.StatusConflict,
+			setupMock:  func() {},
+		},
+		{
+			name:       "registered elsewhere",
+			payload:    map[string]interface{}{"code": "chargeback", "direction": "credit"},
+			wantStatus: http.StatusConflict,
+			setupMock: func() {
+				mock.ExpectExec(`INSERT INTO transaction_types`).
+					WithArgs("chargeback", "credit", pgxmock.AnyArg(), true, pgxmock.AnyArg()).
+					WillReturnError(&pgconn.PgError{Code: "23505"})
+			},
+		},
+		{
+			name:       "unknown gl account",
+			payload:    map[string]interface{}{"code": "rewards", "direction": "credit", "gl_account": "nowhere"},
+			wantStatus: http.StatusBadRequest,
+			setupMock: func() {
+				mock.ExpectExec(`INSERT INTO transaction_types`).
+					WithArgs("rewards", "credit", pgxmock.AnyArg(), true, pgxmock.AnyArg()).
+					WillReturnError(&pgconn.PgError{Code: "23503"})
+			},
+		},
+		{
+			name:       "invalid code",
+			payload:    map[string]interface{}{"code": "Cash Back", "direction": "credit"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/transaction-types", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	_, ok := transactionTypes.Lookup("cashback")
+	assert.True(t, ok)
+}
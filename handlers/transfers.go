@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ledger-service/ledger"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	errPayerNotFound     = ledger.ErrPayerNotFound
+	errPayeeNotFound     = ledger.ErrPayeeNotFound
+	errInsufficientFunds = ledger.ErrInsufficientBalance
+	errOverpayment       = ledger.ErrOverpayment
+	errCurrencyMismatch  = ledger.ErrCurrencyMismatch
+)
+
+// transferResult describes a posted transfer between two customers
+type transferResult = ledger.TransferResult
+
+// postTransfer moves amount from one customer's main balance to another's
+// within tx; see ledger.Service.Transfer. The payments it serves settle at
+// once, so a payer needing approval is refused. errInsufficientFunds,
+// errOverpayment, errCurrencyMismatch and *ledger.ViolationError are
+// returned before anything is written, so callers may still commit tx to
+// record the failure.
+func postTransfer(ctx context.Context, tx pgx.Tx, fromID, toID uuid.UUID, amount float64, reference string) (transferResult, error) {
+	return postings().Transfer(ctx, store.NewPostgresTx(tx), ledger.Transfer{
+		FromCustomerID: fromID,
+		ToCustomerID:   toID,
+		Amount:         amount,
+		Reference:      reference,
+		Immediate:      true,
+	})
+}
+
+// transferRefusal says why the ledger refused a transfer postTransfer
+// failed with, or returns "" for failures that are not a refusal, such as
+// the database being unreachable
+func transferRefusal(err error) string {
+	var violation *ledger.ViolationError
+	switch {
+	case errors.Is(err, errInsufficientFunds):
+		return "Insufficient balance"
+	case errors.Is(err, errOverpayment):
+		return "Payment exceeds the amount owed"
+	case errors.Is(err, errCurrencyMismatch):
+		return "Payer and payee accounts use different currencies"
+	case errors.Is(err, errPayerNotFound):
+		return "Payer not found"
+	case errors.Is(err, errPayeeNotFound):
+		return "Payee not found"
+	case errors.As(err, &violation):
+		return violation.Message
+	}
+	return ""
+}
+
+// verifyTransferParties checks that the payer and payee of future transfers
+// exist and share a base currency, responding with notFound for whichever
+// is missing. It reports whether both checks passed.
+func verifyTransferParties(c *gin.Context, payerID, payeeID uuid.UUID, payerNotFound, payeeNotFound string) bool {
+	var currencies [2]string
+	for i, id := range []uuid.UUID{payerID, payeeID} {
+		err := db.QueryRow(c.Request.Context(),
+			"SELECT currency FROM customers WHERE id = $1",
+			id).Scan(&currencies[i])
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: []string{payerNotFound, payeeNotFound}[i]})
+			return false
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to verify customer"})
+			return false
+		}
+	}
+	if currencies[0] != currencies[1] {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Payer and payee accounts use different currencies"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ledger-service/events"
+	"ledger-service/ledger"
+	"ledger-service/policy"
+
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectTransferLocks expects postTransfer to lock both customers in ID order
+func expectTransferLocks(fromID, toID uuid.UUID, fromBalance, toBalance float64) {
+	balances := map[uuid.UUID]float64{fromID: fromBalance, toID: toBalance}
+	first, second := fromID, toID
+	if first.String() > second.String() {
+		first, second = second, first
+	}
+	for _, id := range []uuid.UUID{first, second} {
+		mock.ExpectQuery(lockCustomerQuery).
+			WithArgs(id).
+			WillReturnRows(lockedCustomer(balances[id], "checking", false))
+	}
+}
+
+// expectTransfer expects a successful postTransfer of amount between two customers
+func expectTransfer(fromID, toID uuid.UUID, fromBalance, toBalance, amount float64) {
+	expectTransferLocks(fromID, toID, fromBalance, toBalance)
+	expectNoDebitLimits(fromID)
+	mock.ExpectExec(`INSERT INTO transfers`).
+		WithArgs(pgxmock.AnyArg(), fromID, toID, amount, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(fromBalance-amount, fromID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status, transfer_id\)`).
+		WithArgs(pgxmock.AnyArg(), fromID, "transfer_out", amount, "posted", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+		WithArgs(toBalance+amount, toID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, customer_id, type, amount, status, transfer_id\)`).
+		WithArgs(pgxmock.AnyArg(), toID, "transfer_in", amount, "posted", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	expectEvent(events.TransferCompleted)
+}
+
+func TestPostTransfer(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	ctx := context.Background()
+	fromID := uuid.New()
+	toID := uuid.New()
+
+	t.Run("posts both legs", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectTransfer(fromID, toID, 100, 5, 40)
+		tx, err := db.Begin(ctx)
+		assert.NoError(t, err)
+
+		result, err := postTransfer(ctx, tx, fromID, toID, 40, "Dinner")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(60), result.FromBalance)
+		assert.Equal(t, float64(45), result.ToBalance)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("overdrawing a payer allowed to go negative is audited", func(t *testing.T) {
+		mock.ExpectBegin()
+		first, second := fromID, toID
+		if first.String() > second.String() {
+			first, second = second, first
+		}
+		for _, id := range []uuid.UUID{first, second} {
+			mock.ExpectQuery(lockCustomerQuery).
+				WithArgs(id).
+				WillReturnRows(lockedCustomer(float64(10), "checking", id == fromID))
+		}
+		expectNoDebitLimits(fromID)
+		mock.ExpectExec(`INSERT INTO transfers`).
+			WithArgs(pgxmock.AnyArg(), fromID, toID, float64(40), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+			WithArgs(float64(-30), fromID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO transactions`).
+			WithArgs(pgxmock.AnyArg(), fromID, "transfer_out", float64(40), "posted", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE customers SET balance = \$1 WHERE id = \$2`).
+			WithArgs(float64(50), toID).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO transactions`).
+			WithArgs(pgxmock.AnyArg(), toID, "transfer_in", float64(40), "posted", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(pgxmock.AnyArg(), "system", "balance.overdrawn", "transfer", pgxmock.AnyArg(), &fromID, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectEvent(events.TransferCompleted)
+		tx, err := db.Begin(ctx)
+		assert.NoError(t, err)
+
+		result, err := postTransfer(ctx, tx, fromID, toID, 40, "Settlement")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(-30), result.FromBalance)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insufficient funds writes nothing", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectTransferLocks(fromID, toID, 10, 5)
+		tx, err := db.Begin(ctx)
+		assert.NoError(t, err)
+
+		_, err = postTransfer(ctx, tx, fromID, toID, 40, "")
+		assert.ErrorIs(t, err, errInsufficientFunds)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("payers are held to their account policy", func(t *testing.T) {
+		InitAccountPolicies(policy.Default(3))
+		defer InitAccountPolicies(nil)
+		expectPayerLocks := func(accountType string) {
+			first, second := fromID, toID
+			if first.String() > second.String() {
+				first, second = second, first
+			}
+			for _, id := range []uuid.UUID{first, second} {
+				payee := "checking"
+				if id == fromID {
+					payee = accountType
+				}
+				mock.ExpectQuery(lockCustomerQuery).
+					WithArgs(id).
+					WillReturnRows(lockedCustomer(float64(100), payee, false))
+			}
+			expectNoDebitLimits(fromID)
+		}
+
+		// Transfers count towards the savings monthly debit limit
+		mock.ExpectBegin()
+		expectPayerLocks("savings")
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions WHERE customer_id = \$1 AND \(type = 'transfer_out' OR`).
+			WithArgs(fromID).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+		tx, err := db.Begin(ctx)
+		assert.NoError(t, err)
+		var violation *ledger.ViolationError
+		_, err = postTransfer(ctx, tx, fromID, toID, 40, "")
+		assert.ErrorAs(t, err, &violation)
+
+		// Escrow payers need approval, which a payment settling at once
+		// cannot wait for
+		expectPayerLocks("escrow")
+		_, err = postTransfer(ctx, tx, fromID, toID, 40, "")
+		assert.ErrorAs(t, err, &violation)
+		assert.Equal(t, "Payments from this account require approval", violation.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("payers are held to their debit limits", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectTransferLocks(fromID, toID, 100, 5)
+		daily := float64(50)
+		expectDebitLimits(fromID, nil, &daily, nil, nil)
+		mock.ExpectQuery(`FROM transactions WHERE customer_id = \$1 AND \(type = 'transfer_out' OR type IN \(SELECT code FROM transaction_types WHERE postable AND direction = 'debit'\)\) AND status = 'posted'`).
+			WithArgs(fromID).
+			WillReturnRows(pgxmock.NewRows([]string{"today", "today_count", "hour_count"}).AddRow(float64(30), 1, 1))
+		tx, err := db.Begin(ctx)
+		assert.NoError(t, err)
+
+		var violation *ledger.ViolationError
+		_, err = postTransfer(ctx, tx, fromID, toID, 40, "")
+		assert.ErrorAs(t, err, &violation)
+		assert.Equal(t, "Debit exceeds the 50.00 daily debit limit", violation.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
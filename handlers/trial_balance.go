@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TrialBalanceLine totals one ledger in one currency
+type TrialBalanceLine struct {
+	Ledger     string  `json:"ledger" example:"customer" enums:"customer,credit,sub_account"`
+	Currency   string  `json:"currency" example:"USD"`
+	Accounts   int     `json:"accounts" example:"1200"`
+	Balance    float64 `json:"balance" example:"1523400.5"`
+	Expected   float64 `json:"expected" example:"1523400.5"`
+	Difference float64 `json:"difference" example:"0"`
+	Imbalanced int     `json:"imbalanced_accounts" example:"0"`
+}
+
+// Imbalance is an account whose stored balance disagrees with its movements
+type Imbalance struct {
+	Ledger         string    `json:"ledger" example:"customer" enums:"customer,credit,sub_account"`
+	AccountID      uuid.UUID `json:"account_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	CustomerID     uuid.UUID `json:"customer_id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	Currency       string    `json:"currency" example:"USD"`
+	Balance        float64   `json:"balance" example:"800"`
+	Expected       float64   `json:"expected" example:"750"`
+	Difference     float64   `json:"difference" example:"50"`
+	Movements      int       `json:"movements" example:"14"`
+	LastMovementAt string    `json:"last_movement_at,omitempty" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// TrialBalance reconciles every ledger at a single point in time
+type TrialBalance struct {
+	AsOf       string             `json:"as_of" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	Balanced   bool               `json:"balanced" example:"true"`
+	Lines      []TrialBalanceLine `json:"lines"`
+	Imbalances []Imbalance        `json:"imbalances"`
+}
+
+// customerLedgerSQL computes the expected balance of each customer account
+// of one balance type from the opening balance and posted movements. Credits
+// add to deposit accounts and debits add to credit accounts, so the two are
+// reported as separate ledgers.
+func customerLedgerSQL(balanceType string) string {
+	return `SELECT c.id, c.id AS customer_id, c.currency, c.balance,
+		c.opening_balance + COALESCE(SUM(CASE WHEN (tt.direction = 'credit') = (c.balance_type = 'deposit') THEN t.amount ELSE -t.amount END), 0) AS expected,
+		COUNT(t.id) AS movements, MAX(t.created_at) AS last_movement_at
+	FROM customers c
+	LEFT JOIN transactions t ON t.customer_id = c.id AND t.status = 'posted'
+	LEFT JOIN transaction_types tt ON tt.code = t.type
+	WHERE c.balance_type = '` + balanceType + `'
+	GROUP BY c.id`
+}
+
+// subAccountLedgerSQL does the same for sub-accounts, which open at zero
+const subAccountLedgerSQL = `SELECT s.id, s.customer_id, s.currency, s.balance,
+		COALESCE(SUM(CASE WHEN st.type = 'credit' THEN st.amount ELSE -st.amount END), 0) AS expected,
+		COUNT(st.id) AS movements, MAX(st.created_at) AS last_movement_at
+	FROM sub_accounts s
+	LEFT JOIN sub_account_transactions st ON st.sub_account_id = s.id
+	GROUP BY s.id`
+
+// maxImbalances caps the drill-down list
+const maxImbalances = 500
+
+// @Summary Trial balance
+// @Description Sum every customer and sub-account balance and reconcile it against the ledger movements (opening balance plus posted credits minus posted debits, or the reverse for credit accounts), all from one consistent snapshot. Deposit and credit accounts are reported as separate ledgers. Accounts that disagree are listed, largest difference first.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param limit query int false "Maximum imbalanced accounts to list" default(100) maximum(500)
+// @Success 200 {object} TrialBalance "Trial balance"
+// @Failure 400 {object} ErrorResponse "Invalid limit"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/trial-balance [get]
+func GetTrialBalance(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 || limit > maxImbalances {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	resp := TrialBalance{
+		AsOf:       time.Now().UTC().Format(time.RFC3339),
+		Balanced:   true,
+		Lines:      []TrialBalanceLine{},
+		Imbalances: []Imbalance{},
+	}
+	for _, ledger := range []struct{ name, sql string }{
+		{"customer", customerLedgerSQL(store.BalanceDeposit)},
+		{"credit", customerLedgerSQL(store.BalanceCredit)},
+		{"sub_account", subAccountLedgerSQL},
+	} {
+		rows, err := tx.Query(ctx,
+			`WITH ledger AS (`+ledger.sql+`)
+			SELECT currency, COUNT(*), COALESCE(SUM(balance), 0), COALESCE(SUM(expected), 0),
+				COALESCE(SUM(balance - expected), 0), COUNT(*) FILTER (WHERE balance <> expected)
+			FROM ledger GROUP BY currency ORDER BY currency`)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute trial balance"})
+			return
+		}
+		imbalanced := 0
+		for rows.Next() {
+			line := TrialBalanceLine{Ledger: ledger.name}
+			if err := rows.Scan(&line.Currency, &line.Accounts, &line.Balance, &line.Expected, &line.Difference, &line.Imbalanced); err != nil {
+				rows.Close()
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute trial balance"})
+				return
+			}
+			imbalanced += line.Imbalanced
+			resp.Lines = append(resp.Lines, line)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to compute trial balance"})
+			return
+		}
+
+		if imbalanced == 0 {
+			continue
+		}
+		resp.Balanced = false
+		if len(resp.Imbalances) >= limit {
+			continue
+		}
+		rows, err = tx.Query(ctx,
+			`WITH ledger AS (`+ledger.sql+`)
+			SELECT id, customer_id, currency, balance, expected, balance - expected, movements, last_movement_at
+			FROM ledger WHERE balance <> expected
+			ORDER BY ABS(balance - expected) DESC, id LIMIT $1`,
+			limit-len(resp.Imbalances))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list imbalances"})
+			return
+		}
+		for rows.Next() {
+			im := Imbalance{Ledger: ledger.name}
+			var lastMovement *time.Time
+			if err := rows.Scan(&im.AccountID, &im.CustomerID, &im.Currency, &im.Balance, &im.Expected, &im.Difference, &im.Movements, &lastMovement); err != nil {
+				rows.Close()
+				respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list imbalances"})
+				return
+			}
+			if lastMovement != nil {
+				im.LastMovementAt = lastMovement.UTC().Format(time.RFC3339)
+			}
+			resp.Imbalances = append(resp.Imbalances, im)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list imbalances"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrialBalance(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/trial-balance", GetTrialBalance)
+	snapshot := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	lineColumns := []string{"currency", "accounts", "balance", "expected", "difference", "imbalanced"}
+	imbalanceColumns := []string{"id", "customer_id", "currency", "balance", "expected", "difference", "movements", "last_movement_at"}
+
+	t.Run("balanced ledgers", func(t *testing.T) {
+		mock.ExpectBeginTx(snapshot)
+		mock.ExpectQuery(`FROM customers c .* WHERE c.balance_type = 'deposit'`).
+			WillReturnRows(pgxmock.NewRows(lineColumns).AddRow("USD", 3, 1500.0, 1500.0, 0.0, 0))
+		mock.ExpectQuery(`FROM customers c .* WHERE c.balance_type = 'credit'`).
+			WillReturnRows(pgxmock.NewRows(lineColumns).AddRow("USD", 1, 300.0, 300.0, 0.0, 0))
+		mock.ExpectQuery(`FROM sub_accounts s`).
+			WillReturnRows(pgxmock.NewRows(lineColumns).AddRow("EUR", 1, 200.0, 200.0, 0.0, 0))
+		mock.ExpectRollback()
+
+		req := httptest.NewRequest("GET", "/admin/trial-balance", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp TrialBalance
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Balanced)
+		assert.Len(t, resp.Lines, 3)
+		assert.Equal(t, "credit", resp.Lines[1].Ledger)
+		assert.Equal(t, "sub_account", resp.Lines[2].Ledger)
+		assert.Empty(t, resp.Imbalances)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lists imbalanced accounts", func(t *testing.T) {
+		customerID := uuid.New()
+		lastMovement := time.Date(2025, 4, 8, 17, 9, 17, 0, time.UTC)
+		mock.ExpectBeginTx(snapshot)
+		mock.ExpectQuery(`GROUP BY currency`).
+			WillReturnRows(pgxmock.NewRows(lineColumns).AddRow("USD", 3, 1550.0, 1500.0, 50.0, 1))
+		mock.ExpectQuery(`WHERE balance <> expected`).
+			WithArgs(10).
+			WillReturnRows(pgxmock.NewRows(imbalanceColumns).
+				AddRow(customerID, customerID, "USD", 800.0, 750.0, 50.0, 14, &lastMovement))
+		mock.ExpectQuery(`GROUP BY currency`).
+			WillReturnRows(pgxmock.NewRows(lineColumns))
+		mock.ExpectQuery(`GROUP BY currency`).
+			WillReturnRows(pgxmock.NewRows(lineColumns))
+		mock.ExpectRollback()
+
+		req := httptest.NewRequest("GET", "/admin/trial-balance?limit=10", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp TrialBalance
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.False(t, resp.Balanced)
+		assert.Len(t, resp.Imbalances, 1)
+		assert.Equal(t, customerID, resp.Imbalances[0].AccountID)
+		assert.Equal(t, 50.0, resp.Imbalances[0].Difference)
+		assert.Equal(t, "2025-04-08T17:09:17Z", resp.Imbalances[0].LastMovementAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/trial-balance?limit=1000", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
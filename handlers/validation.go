@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"ledger-service/money"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field" example:"name"`
+	Message string `json:"message" example:"name must be between 1 and 255 characters"`
+}
+
+// fieldErrors collects validation failures, keeping only the first per field
+type fieldErrors []FieldError
+
+func (f *fieldErrors) add(field, message string) {
+	for _, e := range *f {
+		if e.Field == field {
+			return
+		}
+	}
+	*f = append(*f, FieldError{Field: field, Message: message})
+}
+
+// addBinding records the struct tag failures from a bind error. It reports
+// false when err is not a validation error, e.g. malformed JSON or a field of
+// the wrong type, in which case obj could not be decoded.
+func (f *fieldErrors) addBinding(obj interface{}, err error) bool {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return false
+	}
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, ve := range verrs {
+		field := ve.Field()
+		if sf, ok := t.FieldByName(ve.StructField()); ok {
+			if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" {
+				field = name
+			}
+		}
+		switch ve.Tag() {
+		case "required", "required_without", "required_if":
+			f.add(field, field+" is required")
+		case "gt":
+			f.add(field, fmt.Sprintf("%s must be greater than %s", field, ve.Param()))
+		case "max":
+			f.add(field, fmt.Sprintf("%s must be at most %s characters", field, ve.Param()))
+		case "oneof":
+			f.add(field, fmt.Sprintf("%s must be one of %s", field, strings.ReplaceAll(ve.Param(), " ", ", ")))
+		case "money":
+			amount, _ := ve.Value().(float64)
+			f.add(field, validateMoney(field, amount))
+		case "currency":
+			f.add(field, field+" must be one of USD, EUR, GBP")
+		case "uuid":
+			f.add(field, field+" must be a valid UUID")
+		case "alias":
+			f.add(field, field+" must be 3 to 30 letters, digits or hyphens, optionally starting with @")
+		default:
+			f.add(field, field+" is invalid")
+		}
+	}
+	return true
+}
+
+// maxMoneyDecimals is the precision of every supported currency
+const maxMoneyDecimals = 2
+
+var (
+	// maxAmount caps every monetary input; zero leaves amounts uncapped
+	maxAmount      float64
+	validatorsOnce sync.Once
+)
+
+// InitMaxAmount sets the largest amount a request may carry. Zero removes
+// the cap.
+func InitMaxAmount(max float64) {
+	maxAmount = max
+}
+
+// registerValidators adds the binding tags for request fields:
+//   - money: a positive amount with at most two decimal places, within the
+//     cap set by InitMaxAmount
+//   - currency: a supported ISO 4217 code
+//   - uuid: a UUID other than the nil UUID, as uuid.UUID or a string
+//   - alias: an account alias, with or without its leading @
+func registerValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterValidation("money", func(fl validator.FieldLevel) bool {
+		return fl.Field().CanFloat() && validateMoney("", fl.Field().Float()) == ""
+	})
+	v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return isValidCurrency(fl.Field().String())
+	})
+	v.RegisterValidation("uuid", func(fl validator.FieldLevel) bool {
+		switch id := fl.Field().Interface().(type) {
+		case uuid.UUID:
+			return id != uuid.Nil
+		case string:
+			parsed, err := uuid.Parse(id)
+			return err == nil && parsed != uuid.Nil
+		}
+		return false
+	})
+	v.RegisterValidation("alias", func(fl validator.FieldLevel) bool {
+		_, ok := normalizeAlias(fl.Field().String())
+		return ok
+	})
+}
+
+// validateMoney checks an amount sent by a client
+func validateMoney(field string, amount float64) string {
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return field + " must be greater than 0"
+	}
+	scaled := amount * math.Pow10(maxMoneyDecimals)
+	if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+		return fmt.Sprintf("%s must have at most %d decimal places", field, maxMoneyDecimals)
+	}
+	if maxAmount > 0 && amount > maxAmount {
+		return fmt.Sprintf("%s must not exceed %s", field, strconv.FormatFloat(maxAmount, 'f', -1, 64))
+	}
+	return ""
+}
+
+// respondValidationError rejects the request with one entry per invalid field
+func respondValidationError(c *gin.Context, fields fieldErrors) {
+	respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input", Code: "validation_failed", Fields: fields})
+}
+
+// normalizeText trims surrounding whitespace and puts s in Unicode NFC form
+// so visually identical names are stored identically
+func normalizeText(s string) string {
+	return norm.NFC.String(strings.TrimSpace(s))
+}
+
+// validateName checks a normalized customer name
+func validateName(name string) string {
+	if n := utf8.RuneCountInString(name); n < 1 || n > 255 {
+		return "name must be between 1 and 255 characters"
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return "name must not contain control characters"
+		}
+	}
+	return ""
+}
+
+// defaultTimezone is the timezone of customers that never chose one
+const defaultTimezone = "UTC"
+
+// validateTimezone checks that tz is an IANA timezone name such as
+// Europe/Berlin
+func validateTimezone(tz string) string {
+	if tz == "" || tz == "Local" || len(tz) > 64 {
+		return "timezone must be an IANA timezone name such as America/New_York"
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "timezone must be an IANA timezone name such as America/New_York"
+	}
+	return ""
+}
+
+// validatePrecision checks that amount has no more decimal places than the
+// currency's minor unit allows
+func validatePrecision(field string, amount float64, currency string) string {
+	decimals := money.Decimals(currency)
+	scaled := amount * math.Pow10(decimals)
+	if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+		return fmt.Sprintf("%s must have at most %d decimal places for %s", field, decimals, currency)
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ledger-service/events"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{name: "plain", input: "John Doe", valid: true},
+		{name: "accented", input: "Zoë Ångström", valid: true},
+		{name: "empty after trim", input: "   ", valid: false},
+		{name: "255 runes", input: strings.Repeat("é", 255), valid: true},
+		{name: "256 runes", input: strings.Repeat("é", 256), valid: false},
+		{name: "control character", input: "John\x00Doe", valid: false},
+		{name: "embedded newline", input: "John\nDoe", valid: false},
+		{name: "invalid UTF-8", input: "John\xffDoe", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := validateName(normalizeText(tt.input))
+			assert.Equal(t, tt.valid, msg == "", msg)
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	// "e" followed by a combining acute accent composes to a single "é"
+	assert.Equal(t, "José", normalizeText("  Jose\u0301 "))
+}
+
+func TestValidatePrecision(t *testing.T) {
+	assert.Empty(t, validatePrecision("initial_balance", 1000, "USD"))
+	assert.Empty(t, validatePrecision("initial_balance", 10.25, "USD"))
+	assert.Empty(t, validatePrecision("initial_balance", 0.1+0.2, "USD"))
+	assert.Equal(t, "initial_balance must have at most 2 decimal places for USD", validatePrecision("initial_balance", 10.005, "USD"))
+}
+
+func TestValidateMoney(t *testing.T) {
+	defer InitMaxAmount(0)
+	assert.Empty(t, validateMoney("amount", 0.01))
+	assert.Empty(t, validateMoney("amount", 0.1+0.2))
+	assert.Equal(t, "amount must be greater than 0", validateMoney("amount", -5))
+	assert.Equal(t, "amount must have at most 2 decimal places", validateMoney("amount", 1.005))
+
+	InitMaxAmount(10000)
+	assert.Empty(t, validateMoney("amount", 10000))
+	assert.Equal(t, "amount must not exceed 10000", validateMoney("amount", 10000.01))
+}
+
+func TestValidateTimezone(t *testing.T) {
+	assert.Empty(t, validateTimezone("UTC"))
+	assert.Empty(t, validateTimezone("America/New_York"))
+	assert.NotEmpty(t, validateTimezone(""))
+	assert.NotEmpty(t, validateTimezone("Local"))
+	assert.NotEmpty(t, validateTimezone("Mars/Olympus_Mons"))
+}
+
+func TestCreateCustomerFieldErrors(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/customers", CreateCustomer)
+
+	t.Run("reports every invalid field", func(t *testing.T) {
+		jsonBytes, _ := json.Marshal(map[string]interface{}{
+			"initial_balance": 10.001,
+			"email":           "not-an-email",
+			"account_type":    "brokerage",
+		})
+		req := httptest.NewRequest("POST", "/customers", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var resp ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "validation_failed", resp.Code)
+		assert.Equal(t, []FieldError{
+			{Field: "name", Message: "name is required"},
+			{Field: "initial_balance", Message: "initial_balance must have at most 2 decimal places for USD"},
+			{Field: "account_type", Message: "account_type must be one of checking, savings, escrow"},
+			{Field: "email", Message: "email must be a valid address"},
+		}, resp.Fields)
+	})
+
+	t.Run("stores the normalized name", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO customers`).
+			WithArgs(pgxmock.AnyArg(), "José", float64(50), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "checking", "UTC", "deposit", (*float64)(nil), "USD").
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectEvent(events.CustomerCreated)
+		mock.ExpectCommit()
+
+		jsonBytes, _ := json.Marshal(map[string]interface{}{
+			"name":            " Jose\u0301 ",
+			"initial_balance": 50,
+		})
+		req := httptest.NewRequest("POST", "/customers", bytes.NewBuffer(jsonBytes))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMonetaryFieldErrors(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/fx/quotes", CreateFXQuote)
+
+	jsonBytes, _ := json.Marshal(map[string]interface{}{
+		"customer_id":   "00000000-0000-0000-0000-000000000000",
+		"from_currency": "USD",
+		"to_currency":   "XYZ",
+		"amount":        12.345,
+	})
+	req := httptest.NewRequest("POST", "/fx/quotes", bytes.NewBuffer(jsonBytes))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "validation_failed", resp.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "customer_id", Message: "customer_id is required"},
+		{Field: "to_currency", Message: "to_currency must be one of USD, EUR, GBP"},
+		{Field: "amount", Message: "amount must have at most 2 decimal places"},
+	}, resp.Fields)
+}
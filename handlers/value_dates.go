@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ledger-service/ledger"
+	"ledger-service/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// valueDateWindow is how many days before or after the posting date a
+// transaction's value date may fall
+const valueDateWindow = 30
+
+// parseValueDate parses an optional value date for a posting the customer
+// makes today, in their timezone, writing an error response and returning
+// false when it is malformed or outside valueDateWindow or the customer does
+// not exist. An empty value returns the zero time, which the store takes as
+// the posting date.
+func parseValueDate(c *gin.Context, customerID uuid.UUID, value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: value_date must be in YYYY-MM-DD format"})
+		return time.Time{}, false
+	}
+	today, err := customerToday(c.Request.Context(), customerID)
+	if errors.Is(err, store.ErrNotFound) {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Customer not found"})
+		return time.Time{}, false
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer"})
+		return time.Time{}, false
+	}
+	if d.Before(today.AddDate(0, 0, -valueDateWindow)) || d.After(today.AddDate(0, 0, valueDateWindow)) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Invalid input: value_date must be within %d days of today", valueDateWindow)})
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+// customerToday is the current date in the customer's timezone, at midnight
+// UTC like the dates parsed from requests. It returns store.ErrNotFound when
+// the customer does not exist.
+func customerToday(ctx context.Context, customerID uuid.UUID) (time.Time, error) {
+	timezone, err := ledgerStore.CustomerTimezone(ctx, customerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	y, m, d := time.Now().In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// checkValueDate refuses a back-valued posting whose value date falls in a
+// closed accounting period
+func checkValueDate(ctx context.Context, tx pgx.Tx, p ledger.Posting) error {
+	if p.ValueDate.IsZero() {
+		return nil
+	}
+	latest, err := latestPeriodClose(ctx, tx, "SHARE")
+	if err != nil {
+		return err
+	}
+	if latest != nil && !p.ValueDate.After(*latest) {
+		return &ledger.ViolationError{Message: "Value date falls in a period closed through " + latest.Format(dateLayout)}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"ledger-service/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// WebhookReplay re-delivers past events to one webhook
+// @Description Webhook replay and its progress
+type WebhookReplay struct {
+	ID        uuid.UUID `json:"replay_id" format:"uuid"`
+	WebhookID uuid.UUID `json:"webhook_id" format:"uuid"`
+	From      string    `json:"from" example:"2025-04-08T00:00:00Z" format:"date-time"`
+	To        string    `json:"to" example:"2025-04-08T06:00:00Z" format:"date-time"`
+	Status    string    `json:"status" example:"running" enums:"pending,running,completed"`
+	// Total is how many events the replay covers; Delivered and Failed count
+	// the attempts made so far
+	Total       int    `json:"total" example:"120"`
+	Delivered   int    `json:"delivered" example:"80"`
+	Failed      int    `json:"failed" example:"0"`
+	CreatedAt   string `json:"created_at" example:"2025-04-08T09:00:00Z" format:"date-time"`
+	CompletedAt string `json:"completed_at,omitempty" example:"2025-04-08T09:02:00Z" format:"date-time"`
+}
+
+// replayBatchSize is how many events a replay delivers per database
+// transaction. The replay stays locked while they are sent, so it is kept
+// small.
+const replayBatchSize = 20
+
+const webhookReplayColumns = "id, webhook_id, from_time, to_time, status, total, delivered, failed, created_at, completed_at"
+
+func scanWebhookReplay(row pgx.Row) (WebhookReplay, error) {
+	var r WebhookReplay
+	var from, to, createdAt time.Time
+	var completedAt *time.Time
+	if err := row.Scan(&r.ID, &r.WebhookID, &from, &to, &r.Status, &r.Total, &r.Delivered, &r.Failed, &createdAt, &completedAt); err != nil {
+		return WebhookReplay{}, err
+	}
+	r.From = from.UTC().Format(time.RFC3339)
+	r.To = to.UTC().Format(time.RFC3339)
+	r.CreatedAt = createdAt.Format(time.RFC3339)
+	if completedAt != nil {
+		r.CompletedAt = completedAt.Format(time.RFC3339)
+	}
+	return r, nil
+}
+
+// replayEventFilter selects the already relayed outbox events in a replay's
+// window that a webhook's event_types subscribe to
+const replayEventFilter = "created_at >= $1 AND created_at < $2 AND published_at IS NOT NULL AND (cardinality($3::text[]) = 0 OR event_type = ANY($3))"
+
+// @Summary Replay events to a webhook
+// @Description Re-deliver the events relayed between from and to to one webhook, oldest first, e.g. after the subscriber's outage. Events keep their original IDs so receivers can drop ones they already have. Delivery runs in the background; poll the returned replay for progress. Events not yet relayed are left to the relay.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Param from query string true "Replay events from this time (RFC 3339)" format(date-time)
+// @Param to query string false "Replay events before this time (RFC 3339); defaults to now" format(date-time)
+// @Success 202 {object} WebhookReplay "Replay started"
+// @Failure 400 {object} ErrorResponse "Invalid webhook ID or time range"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id}/replay [post]
+func ReplayWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid from: must be an RFC 3339 time"})
+		return
+	}
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid to: must be an RFC 3339 time"})
+			return
+		}
+	}
+	if !from.Before(to) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid time range: from must be before to"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	var eventTypes []string
+	if err := db.QueryRow(ctx,
+		"SELECT event_types FROM webhooks WHERE id = $1", id).Scan(&eventTypes); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Webhook not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get webhook"})
+		}
+		return
+	}
+
+	// Start just before the first event in the window so the worker does
+	// not scan the outbox from the beginning
+	var total int
+	var startSeq int64
+	if err := db.QueryRow(ctx,
+		"SELECT COUNT(*), COALESCE(MIN(seq) - 1, 0) FROM outbox WHERE "+replayEventFilter,
+		from, to, eventTypes).Scan(&total, &startSeq); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to count events"})
+		return
+	}
+
+	r, err := scanWebhookReplay(db.QueryRow(ctx,
+		"INSERT INTO webhook_replays (id, webhook_id, from_time, to_time, total, last_seq) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+webhookReplayColumns,
+		uuid.New(), id, from, to, total, startSeq))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to start replay"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, r)
+}
+
+// @Summary List a webhook's replays
+// @Description List a webhook's replays with their progress, newest first
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Success 200 {array} WebhookReplay "Replays"
+// @Failure 400 {object} ErrorResponse "Invalid webhook ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id}/replays [get]
+func ListWebhookReplays(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+webhookReplayColumns+" FROM webhook_replays WHERE webhook_id = $1 ORDER BY created_at DESC", id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list replays"})
+		return
+	}
+	defer rows.Close()
+
+	replays := []WebhookReplay{}
+	for rows.Next() {
+		r, err := scanWebhookReplay(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan replay"})
+			return
+		}
+		replays = append(replays, r)
+	}
+
+	c.JSON(http.StatusOK, replays)
+}
+
+// @Summary Get a webhook replay
+// @Description Get a replay's status and how many of its events have been delivered or failed so far
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Param replay_id path string true "Replay ID" format(uuid)
+// @Success 200 {object} WebhookReplay "Replay"
+// @Failure 400 {object} ErrorResponse "Invalid webhook or replay ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Replay not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id}/replays/{replay_id} [get]
+func GetWebhookReplay(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	replayID, err := uuid.Parse(c.Param("replay_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid replay ID"})
+		return
+	}
+	r, err := scanWebhookReplay(db.QueryRow(c.Request.Context(),
+		"SELECT "+webhookReplayColumns+" FROM webhook_replays WHERE id = $1 AND webhook_id = $2", replayID, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Replay not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get replay"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// RunWebhookReplays works through unfinished replays every interval until
+// ctx is cancelled
+func RunWebhookReplays(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := ProcessWebhookReplays(ctx); err != nil {
+			log.Printf("Webhook replay failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Replayed %d events", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessWebhookReplays delivers the events of every unfinished replay and
+// returns how many were attempted. Each replay sends its events in outbox
+// order, one batch at a time; a failed delivery is recorded and counted but
+// does not stop the replay. Replays are claimed with SKIP LOCKED, so
+// instances share the work without sending an event twice.
+func ProcessWebhookReplays(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		n, claimed, err := replayWebhookBatch(ctx)
+		attempted += n
+		if err != nil || !claimed {
+			return attempted, err
+		}
+	}
+}
+
+func replayWebhookBatch(ctx context.Context) (int, bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var replayID, webhookID uuid.UUID
+	var from, to time.Time
+	var lastSeq int64
+	var url, secret string
+	var eventTypes []string
+	err = tx.QueryRow(ctx,
+		"SELECT r.id, r.webhook_id, r.from_time, r.to_time, r.last_seq, w.url, w.secret, w.event_types FROM webhook_replays r JOIN webhooks w ON w.id = r.webhook_id WHERE r.status <> 'completed' ORDER BY r.created_at LIMIT 1 FOR UPDATE OF r SKIP LOCKED").
+		Scan(&replayID, &webhookID, &from, &to, &lastSeq, &url, &secret, &eventTypes)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT seq, id, event_type, customer_id, payload, created_at FROM outbox WHERE "+replayEventFilter+" AND seq > $4 ORDER BY seq LIMIT $5",
+		from, to, eventTypes, lastSeq, replayBatchSize)
+	if err != nil {
+		return 0, false, err
+	}
+	var batch []events.Event
+	for rows.Next() {
+		var ev events.Event
+		var payload []byte
+		if err := rows.Scan(&lastSeq, &ev.ID, &ev.Type, &ev.CustomerID, &payload, &ev.OccurredAt); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		ev.Data = payload
+		ev.OccurredAt = ev.OccurredAt.UTC()
+		batch = append(batch, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+
+	delivered, failed := 0, 0
+	for _, ev := range batch {
+		d := webhookSender.Send(ctx, url, secret, ev)
+		if d.Delivered {
+			delivered++
+		} else {
+			failed++
+		}
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO webhook_deliveries (id, webhook_id, event_id, delivered, status_code, duration_ms, error, replay_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			uuid.New(), webhookID, ev.ID, d.Delivered, d.StatusCode, d.DurationMS, nullableString(d.Error), replayID); err != nil {
+			return 0, false, err
+		}
+	}
+
+	status := "running"
+	if len(batch) < replayBatchSize {
+		status = "completed"
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE webhook_replays SET status = $1, last_seq = $2, delivered = delivered + $3, failed = failed + $4, updated_at = NOW(), completed_at = CASE WHEN $1 = 'completed' THEN NOW() END WHERE id = $5",
+		status, lastSeq, delivered, failed, replayID); err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return len(batch), true, nil
+}
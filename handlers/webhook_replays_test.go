@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookReplayRowColumns = []string{"id", "webhook_id", "from_time", "to_time", "status", "total", "delivered", "failed", "created_at", "completed_at"}
+
+func TestReplayWebhook(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/webhooks/:webhook_id/replay", ReplayWebhook)
+
+	webhookID := uuid.New()
+	from := time.Date(2025, 4, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 8, 6, 0, 0, 0, time.UTC)
+	window := "?from=2025-04-08T00:00:00Z&to=2025-04-08T06:00:00Z"
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "starts a replay",
+			query:      window,
+			wantStatus: http.StatusAccepted,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT event_types FROM webhooks WHERE id = \$1`).
+					WithArgs(webhookID).
+					WillReturnRows(pgxmock.NewRows([]string{"event_types"}).AddRow([]string{events.TransactionPosted}))
+				mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(MIN\(seq\) - 1, 0\) FROM outbox WHERE created_at >= \$1 AND created_at < \$2 AND published_at IS NOT NULL`).
+					WithArgs(from, to, []string{events.TransactionPosted}).
+					WillReturnRows(pgxmock.NewRows([]string{"count", "start"}).AddRow(2, int64(41)))
+				mock.ExpectQuery(`INSERT INTO webhook_replays \(id, webhook_id, from_time, to_time, total, last_seq\)`).
+					WithArgs(pgxmock.AnyArg(), webhookID, from, to, 2, int64(41)).
+					WillReturnRows(pgxmock.NewRows(webhookReplayRowColumns).
+						AddRow(uuid.New(), webhookID, from, to, "pending", 2, 0, 0, time.Now(), (*time.Time)(nil)))
+			},
+		},
+		{
+			name:       "unknown webhook",
+			query:      window,
+			wantStatus: http.StatusNotFound,
+			setupMock: func() {
+				mock.ExpectQuery(`SELECT event_types FROM webhooks`).
+					WithArgs(webhookID).
+					WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name:       "missing from",
+			query:      "?to=2025-04-08T06:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "from after to",
+			query:      "?from=2025-04-08T06:00:00Z&to=2025-04-08T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+			req := httptest.NewRequest("POST", "/admin/webhooks/"+webhookID.String()+"/replay"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusAccepted {
+				var r WebhookReplay
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+				assert.Equal(t, "pending", r.Status)
+				assert.Equal(t, 2, r.Total)
+				assert.Equal(t, "2025-04-08T00:00:00Z", r.From)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProcessWebhookReplays(t *testing.T) {
+	_, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	var delivered []uuid.UUID
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = append(delivered, uuid.MustParse(r.Header.Get(webhook.IDHeader)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	replayID, webhookID := uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+	from := time.Date(2025, 4, 8, 0, 0, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM webhook_replays r JOIN webhooks w ON w.id = r.webhook_id WHERE r.status <> 'completed' ORDER BY r.created_at LIMIT 1 FOR UPDATE OF r SKIP LOCKED`).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "webhook_id", "from_time", "to_time", "last_seq", "url", "secret", "event_types"}).
+			AddRow(replayID, webhookID, from, to, int64(41), server.URL, "whsec_test", []string{}))
+	mock.ExpectQuery(`SELECT seq, id, event_type, customer_id, payload, created_at FROM outbox WHERE .* AND seq > \$4 ORDER BY seq LIMIT \$5`).
+		WithArgs(from, to, []string{}, int64(41), replayBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"seq", "id", "event_type", "customer_id", "payload", "created_at"}).
+			AddRow(int64(42), first, events.TransactionPosted, (*uuid.UUID)(nil), []byte(`{"amount":10}`), from.Add(time.Hour)).
+			AddRow(int64(45), second, events.TransferCompleted, (*uuid.UUID)(nil), []byte(`{"amount":5}`), from.Add(2*time.Hour)))
+	for _, id := range []uuid.UUID{first, second} {
+		mock.ExpectExec(`INSERT INTO webhook_deliveries \(id, webhook_id, event_id, delivered, status_code, duration_ms, error, replay_id\)`).
+			WithArgs(pgxmock.AnyArg(), webhookID, id, true, http.StatusOK, pgxmock.AnyArg(), pgxmock.AnyArg(), replayID).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mock.ExpectExec(`UPDATE webhook_replays SET status = \$1, last_seq = \$2`).
+		WithArgs("completed", int64(45), 2, 0, replayID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	// Nothing left to replay
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM webhook_replays r`).WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	n, err := ProcessWebhookReplays(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []uuid.UUID{first, second}, delivered)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Webhook is a subscription that receives ledger events over HTTP
+// @Description Webhook subscription
+type Webhook struct {
+	ID          uuid.UUID `json:"webhook_id" format:"uuid"`
+	URL         string    `json:"url" example:"https://example.com/hooks/ledger"`
+	Description string    `json:"description,omitempty" example:"Accounting sync"`
+	EventTypes  []string  `json:"event_types" example:"transaction.posted,transfer.completed"`
+	Enabled     bool      `json:"enabled" example:"true"`
+	// Secret signs deliveries; it is only returned when the webhook is created
+	Secret    string `json:"secret,omitempty" example:"whsec_5f2b..."`
+	CreatedAt string `json:"created_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+	UpdatedAt string `json:"updated_at" example:"2025-04-08T17:09:17Z" format:"date-time"`
+}
+
+// WebhookRequest represents the payload for subscribing a webhook endpoint.
+// An empty event_types list subscribes to every event.
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required" example:"https://example.com/hooks/ledger"`
+	Description string   `json:"description" binding:"max=255" example:"Accounting sync" maxLength:"255"`
+	EventTypes  []string `json:"event_types" example:"transaction.posted,transfer.completed"`
+	Enabled     *bool    `json:"enabled,omitempty" example:"true" default:"true"`
+}
+
+// WebhookUpdateRequest represents a partial update to a webhook. Omitted
+// fields are left unchanged.
+type WebhookUpdateRequest struct {
+	URL         *string   `json:"url,omitempty" example:"https://example.com/hooks/ledger"`
+	Description *string   `json:"description,omitempty" example:"Accounting sync"`
+	EventTypes  *[]string `json:"event_types,omitempty" example:"transaction.posted"`
+	Enabled     *bool     `json:"enabled,omitempty" example:"false"`
+}
+
+// WebhookTestResponse reports the outcome of a test delivery
+type WebhookTestResponse struct {
+	EventID uuid.UUID `json:"event_id" format:"uuid"`
+	webhook.Delivery
+}
+
+var (
+	webhookSender = webhook.NewSender(10 * time.Second)
+)
+
+// InitWebhooks sets the sender used for webhook deliveries
+func InitWebhooks(s *webhook.Sender) {
+	webhookSender = s
+}
+
+const webhookColumns = "id, url, COALESCE(description, ''), event_types, enabled, created_at, updated_at"
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
+	var w Webhook
+	var createdAt, updatedAt time.Time
+	if err := row.Scan(&w.ID, &w.URL, &w.Description, &w.EventTypes, &w.Enabled, &createdAt, &updatedAt); err != nil {
+		return Webhook{}, err
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	w.CreatedAt = createdAt.Format(time.RFC3339)
+	w.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return w, nil
+}
+
+// validateWebhook records field errors for a webhook's URL and event filter
+func validateWebhook(fields *fieldErrors, url *string, eventTypes *[]string) {
+	if url != nil {
+		if err := webhook.ValidateURL(*url); err != nil {
+			fields.add("url", err.Error())
+		}
+	}
+	if eventTypes != nil {
+		for _, t := range *eventTypes {
+			if !events.Valid(t) {
+				fields.add("event_types", "event_types must be drawn from "+strings.Join(events.Types, ", "))
+				return
+			}
+		}
+	}
+}
+
+func parseWebhookID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid webhook ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// @Summary Create a webhook
+// @Description Subscribe an HTTP endpoint to ledger events, optionally filtered by event type. The response carries the signing secret, which is not shown again; every delivery is signed with it in the X-Webhook-Signature header.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook body WebhookRequest true "Webhook"
+// @Success 201 {object} Webhook "Webhook created"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	var fields fieldErrors
+	if err := bindJSON(c, &req); err != nil && !fields.addBinding(&req, err) {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: url is required"})
+		return
+	}
+	if req.EventTypes == nil {
+		req.EventTypes = []string{}
+	}
+	validateWebhook(&fields, &req.URL, &req.EventTypes)
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate webhook secret"})
+		return
+	}
+	w, err := scanWebhook(db.QueryRow(c.Request.Context(),
+		"INSERT INTO webhooks (id, url, description, event_types, enabled, secret) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+webhookColumns,
+		uuid.New(), req.URL, nullableString(req.Description), req.EventTypes, req.Enabled == nil || *req.Enabled, secret))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to create webhook"})
+		return
+	}
+	w.Secret = secret
+
+	c.JSON(http.StatusCreated, w)
+}
+
+// @Summary List webhooks
+// @Description List webhook subscriptions. Secrets are not included.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} Webhook "Webhooks"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	rows, err := db.Query(c.Request.Context(),
+		"SELECT "+webhookColumns+" FROM webhooks ORDER BY created_at")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to list webhooks"})
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to scan webhook"})
+			return
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Get a webhook
+// @Description Get a webhook subscription. The secret is not included.
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Success 200 {object} Webhook "Webhook"
+// @Failure 400 {object} ErrorResponse "Invalid webhook ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id} [get]
+func GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	w, err := scanWebhook(db.QueryRow(c.Request.Context(),
+		"SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Webhook not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get webhook"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+// @Summary Update a webhook
+// @Description Change a webhook's URL, description or event filter, or enable and disable it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Param webhook body WebhookUpdateRequest true "Fields to update"
+// @Success 200 {object} Webhook "Webhook updated"
+// @Failure 400 {object} ErrorResponse "Invalid input data"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id} [patch]
+func UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	var req WebhookUpdateRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrorResponse{Error: "Invalid input: url and description must be strings, event_types a list and enabled a boolean"})
+		return
+	}
+	var fields fieldErrors
+	validateWebhook(&fields, req.URL, req.EventTypes)
+	if req.Description != nil && len(*req.Description) > 255 {
+		fields.add("description", "description must be at most 255 characters")
+	}
+	if len(fields) > 0 {
+		respondValidationError(c, fields)
+		return
+	}
+
+	var eventTypes interface{}
+	if req.EventTypes != nil {
+		eventTypes = *req.EventTypes
+	}
+	w, err := scanWebhook(db.QueryRow(c.Request.Context(),
+		`UPDATE webhooks SET
+			url = COALESCE($1, url),
+			description = CASE WHEN $2::text IS NULL THEN description ELSE NULLIF($2, '') END,
+			event_types = COALESCE($3, event_types),
+			enabled = COALESCE($4, enabled),
+			updated_at = NOW()
+		WHERE id = $5 RETURNING `+webhookColumns,
+		req.URL, req.Description, eventTypes, req.Enabled, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Webhook not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to update webhook"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+// @Summary Delete a webhook
+// @Description Remove a webhook subscription so it receives no further events
+// @Tags admin
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Success 204 "Webhook deleted"
+// @Failure 400 {object} ErrorResponse "Invalid webhook ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	tag, err := db.Exec(c.Request.Context(), "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete webhook"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		respondError(c, http.StatusNotFound, ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Send a test event to a webhook
+// @Description Deliver a signed sample webhook.test event to the endpoint right away, whether or not the webhook is enabled, and report how the endpoint responded
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param webhook_id path string true "Webhook ID" format(uuid)
+// @Success 200 {object} WebhookTestResponse "Delivery attempted"
+// @Failure 400 {object} ErrorResponse "Invalid webhook ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin credentials"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/webhooks/{webhook_id}/test [post]
+func TestWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var url, secret string
+	if err := db.QueryRow(c.Request.Context(),
+		"SELECT url, secret FROM webhooks WHERE id = $1", id).Scan(&url, &secret); err != nil {
+		if err == pgx.ErrNoRows {
+			respondError(c, http.StatusNotFound, ErrorResponse{Error: "Webhook not found"})
+		} else {
+			respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to get webhook"})
+		}
+		return
+	}
+
+	ev, err := events.New(events.WebhookTest, nil, map[string]interface{}{
+		"webhook_id": id,
+		"message":    "This is a test event from ledger-service",
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrorResponse{Error: "Failed to build test event"})
+		return
+	}
+	// Detach from the request so a client disconnect does not cut the delivery short
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	c.JSON(http.StatusOK, WebhookTestResponse{
+		EventID:  ev.ID,
+		Delivery: webhookSender.Send(ctx, url, secret, ev),
+	})
+}
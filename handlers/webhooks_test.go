@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/events"
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookRowColumns = []string{"id", "url", "description", "event_types", "enabled", "created_at", "updated_at"}
+
+func TestCreateWebhook(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/webhooks", CreateWebhook)
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+		setupMock  func()
+	}{
+		{
+			name:       "all events",
+			payload:    map[string]interface{}{"url": "https://example.com/hooks"},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`INSERT INTO webhooks \(id, url, description, event_types, enabled, secret\)`).
+					WithArgs(pgxmock.AnyArg(), "https://example.com/hooks", pgxmock.AnyArg(), []string{}, true, pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows(webhookRowColumns).
+						AddRow(uuid.New(), "https://example.com/hooks", "", []string{}, true, time.Now(), time.Now()))
+			},
+		},
+		{
+			name: "filtered and disabled",
+			payload: map[string]interface{}{
+				"url":         "https://example.com/hooks",
+				"description": "Accounting sync",
+				"event_types": []string{events.TransactionPosted, events.TransferCompleted},
+				"enabled":     false,
+			},
+			wantStatus: http.StatusCreated,
+			setupMock: func() {
+				mock.ExpectQuery(`INSERT INTO webhooks`).
+					WithArgs(pgxmock.AnyArg(), "https://example.com/hooks", pgxmock.AnyArg(),
+						[]string{events.TransactionPosted, events.TransferCompleted}, false, pgxmock.AnyArg()).
+					WillReturnRows(pgxmock.NewRows(webhookRowColumns).
+						AddRow(uuid.New(), "https://example.com/hooks", "Accounting sync",
+							[]string{events.TransactionPosted, events.TransferCompleted}, false, time.Now(), time.Now()))
+			},
+		},
+		{
+			name:       "missing url",
+			payload:    map[string]interface{}{"description": "Accounting sync"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "unsupported scheme",
+			payload:    map[string]interface{}{"url": "ftp://example.com/hooks"},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+		{
+			name:       "unknown event type",
+			payload:    map[string]interface{}{"url": "https://example.com/hooks", "event_types": []string{"transaction.deleted"}},
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func() {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/admin/webhooks", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var created Webhook
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+				assert.Regexp(t, `^whsec_[0-9a-f]{64}$`, created.Secret)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestManageWebhooks(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.GET("/admin/webhooks", ListWebhooks)
+	router.GET("/admin/webhooks/:webhook_id", GetWebhook)
+	router.PATCH("/admin/webhooks/:webhook_id", UpdateWebhook)
+	router.DELETE("/admin/webhooks/:webhook_id", DeleteWebhook)
+
+	webhookID := uuid.New()
+
+	t.Run("list", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT id, url, COALESCE\(description, ''\), event_types, enabled, created_at, updated_at FROM webhooks ORDER BY created_at`).
+			WillReturnRows(pgxmock.NewRows(webhookRowColumns).
+				AddRow(webhookID, "https://example.com/hooks", "", []string{}, true, time.Now(), time.Now()))
+
+		req := httptest.NewRequest("GET", "/admin/webhooks", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var webhooks []Webhook
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &webhooks))
+		assert.Len(t, webhooks, 1)
+		assert.Empty(t, webhooks[0].Secret)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("get not found", func(t *testing.T) {
+		mock.ExpectQuery(`FROM webhooks WHERE id = \$1`).
+			WithArgs(webhookID).
+			WillReturnError(pgx.ErrNoRows)
+
+		req := httptest.NewRequest("GET", "/admin/webhooks/"+webhookID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE webhooks SET`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), webhookID).
+			WillReturnRows(pgxmock.NewRows(webhookRowColumns).
+				AddRow(webhookID, "https://example.com/hooks", "", []string{}, false, time.Now(), time.Now()))
+
+		req := httptest.NewRequest("PATCH", "/admin/webhooks/"+webhookID.String(), bytes.NewBufferString(`{"enabled": false}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var updated Webhook
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.False(t, updated.Enabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update rejects unknown event type", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/admin/webhooks/"+webhookID.String(), bytes.NewBufferString(`{"event_types": ["balance.deleted"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM webhooks WHERE id = \$1`).
+			WithArgs(webhookID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		req := httptest.NewRequest("DELETE", "/admin/webhooks/"+webhookID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM webhooks`).
+			WithArgs(webhookID).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		req := httptest.NewRequest("DELETE", "/admin/webhooks/"+webhookID.String(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/webhooks/not-a-uuid", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestTestWebhook(t *testing.T) {
+	router, err := setupTestRouter()
+	if err != nil {
+		t.Fatalf("Failed to setup test router: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	router.POST("/admin/webhooks/:webhook_id/test", TestWebhook)
+
+	var verifyErr error
+	var gotEvent events.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = webhook.Verify("whsec_test", r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now())
+		json.Unmarshal(body, &gotEvent)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	webhookID := uuid.New()
+
+	t.Run("delivered", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT url, secret FROM webhooks WHERE id = \$1`).
+			WithArgs(webhookID).
+			WillReturnRows(pgxmock.NewRows([]string{"url", "secret"}).AddRow(server.URL, "whsec_test"))
+
+		req := httptest.NewRequest("POST", "/admin/webhooks/"+webhookID.String()+"/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp WebhookTestResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Delivered)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NoError(t, verifyErr)
+		assert.Equal(t, events.WebhookTest, gotEvent.Type)
+		assert.Equal(t, resp.EventID, gotEvent.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT url, secret FROM webhooks`).
+			WithArgs(webhookID).
+			WillReturnError(pgx.ErrNoRows)
+
+		req := httptest.NewRequest("POST", "/admin/webhooks/"+webhookID.String()+"/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,107 @@
+// Package i18n translates client-facing error messages. The English message
+// is the lookup key, so untranslated messages fall back to English as written.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog holds the translations for a set of languages
+type Catalog struct {
+	tags     []language.Tag
+	matcher  language.Matcher
+	messages map[language.Tag]map[string]string
+}
+
+var defaultCatalog = mustLoad()
+
+// Default returns the catalog built from the embedded locale files
+func Default() *Catalog {
+	return defaultCatalog
+}
+
+func mustLoad() *Catalog {
+	c, err := Load(locales, "locales")
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Load reads one <language>.json file per language from dir. English is
+// always supported and needs no file.
+func Load(fsys fs.FS, dir string) (*Catalog, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{
+		tags:     []language.Tag{language.English},
+		messages: map[language.Tag]map[string]string{},
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || path.Ext(name) != ".json" {
+			continue
+		}
+		tag, err := language.Parse(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("locale %s: %w", name, err)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("locale %s: %w", name, err)
+		}
+		c.tags = append(c.tags, tag)
+		c.messages[tag] = messages
+	}
+	c.matcher = language.NewMatcher(c.tags)
+	return c, nil
+}
+
+// Match picks the best supported language for an Accept-Language header,
+// falling back to English
+func (c *Catalog) Match(acceptLanguage string) language.Tag {
+	prefs, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(prefs) == 0 {
+		return language.English
+	}
+	_, i, confidence := c.matcher.Match(prefs...)
+	if confidence == language.No {
+		return language.English
+	}
+	return c.tags[i]
+}
+
+// Translate returns msg in the given language. A message of the form
+// "prefix: detail" with no translation of its own is translated piecewise,
+// so "Invalid input: name is required" reuses both entries.
+func (c *Catalog) Translate(tag language.Tag, msg string) string {
+	messages := c.messages[tag]
+	if messages == nil {
+		return msg
+	}
+	if t, ok := messages[msg]; ok {
+		return t
+	}
+	if prefix, detail, ok := strings.Cut(msg, ": "); ok {
+		if t, ok := messages[prefix]; ok {
+			return t + ": " + c.Translate(tag, detail)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestMatch(t *testing.T) {
+	c := Default()
+	tests := []struct {
+		header string
+		want   language.Tag
+	}{
+		{header: "", want: language.English},
+		{header: "fr-CH, fr;q=0.9, en;q=0.8", want: language.French},
+		{header: "es-MX", want: language.Spanish},
+		{header: "ja, de;q=0.5", want: language.German},
+		{header: "ja", want: language.English},
+		{header: "not a language header;;", want: language.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.Match(tt.header))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	c := Default()
+	assert.Equal(t, "Cliente no encontrado", c.Translate(language.Spanish, "Customer not found"))
+	assert.Equal(t, "Solde insuffisant", c.Translate(language.French, "Insufficient balance"))
+	assert.Equal(t, "Ungültige Eingabe: Name ist erforderlich", c.Translate(language.German, "Invalid input: name is required"))
+	assert.Equal(t, "Entrada no válida: start_date cannot be in the past", c.Translate(language.Spanish, "Invalid input: start_date cannot be in the past"))
+	assert.Equal(t, "Customer not found", c.Translate(language.English, "Customer not found"))
+	assert.Equal(t, "Something new", c.Translate(language.French, "Something new"))
+}
+
+func TestCatalogsCoverSameMessages(t *testing.T) {
+	c := Default()
+	var reference map[string]string
+	for _, tag := range c.tags[1:] {
+		if reference == nil {
+			reference = c.messages[tag]
+			continue
+		}
+		for msg := range reference {
+			assert.Contains(t, c.messages[tag], msg, "%s is missing a translation", tag)
+		}
+		assert.Len(t, c.messages[tag], len(reference), tag.String())
+	}
+}
+
+func TestLoadRejectsBadLocale(t *testing.T) {
+	_, err := Load(fstest.MapFS{"locales/xx-invalid-.json": {Data: []byte(`{}`)}}, "locales")
+	assert.Error(t, err)
+
+	_, err = Load(fstest.MapFS{"locales/fr.json": {Data: []byte(`{"a":`)}}, "locales")
+	assert.Error(t, err)
+}
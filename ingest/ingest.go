@@ -0,0 +1,252 @@
+// Package ingest verifies notifications posted by external providers, such
+// as PSP settlements or bank feeds, and maps them to ledger postings with
+// per-source rules
+package ingest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Signature schemes
+const (
+	// SchemeTimestamped is t=<unix seconds>,v1=<hex HMAC-SHA256 of
+	// "<t>.<body>">, the format the service signs its own webhooks with
+	SchemeTimestamped = "timestamped"
+	// SchemeHMAC is a hex HMAC-SHA256 of the body, optionally prefixed with
+	// "sha256="
+	SchemeHMAC = "hmac-sha256"
+)
+
+// Source is a provider allowed to post notifications
+type Source struct {
+	Name string `json:"name"`
+	// SecretEnv names the environment variable holding the signing secret,
+	// so secrets stay out of the source definitions
+	SecretEnv string `json:"secret_env"`
+	// Scheme defaults to timestamped and Header to X-Webhook-Signature
+	Scheme string `json:"scheme"`
+	Header string `json:"header"`
+	// ToleranceSeconds bounds the age of a timestamped signature, 300 when
+	// unset
+	ToleranceSeconds int `json:"tolerance_seconds"`
+	// Rules are tried in order; the first whose Match holds maps the
+	// notification
+	Rules []Rule `json:"rules"`
+
+	secret string
+}
+
+// Rule maps a notification to a posting. Fields name values in the JSON
+// body by dot-separated paths, e.g. "data.object.amount".
+type Rule struct {
+	// Match lists paths and the values they must hold, compared as text
+	Match map[string]string `json:"match"`
+	// Type is the transaction type posted
+	Type       string `json:"type"`
+	EventID    string `json:"event_id"`
+	CustomerID string `json:"customer_id"`
+	Amount     string `json:"amount"`
+	// AmountScale multiplies the amount, e.g. 0.01 for amounts in cents;
+	// 1 when unset
+	AmountScale float64 `json:"amount_scale"`
+	// Currency is optional; postings default to the account's currency
+	Currency string `json:"currency"`
+}
+
+// Posting is what a notification asks to post
+type Posting struct {
+	// EventID is the provider's ID for the notification, which a repeat
+	// delivery shares
+	EventID    string
+	CustomerID uuid.UUID
+	Type       string
+	Amount     float64
+	Currency   string
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,39}$`)
+
+// ParseSources reads a JSON array of sources, resolving each secret with
+// getenv
+func ParseSources(raw string, getenv func(string) string) (map[string]*Source, error) {
+	sources := map[string]*Source{}
+	if strings.TrimSpace(raw) == "" {
+		return sources, nil
+	}
+	var list []*Source
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		return nil, fmt.Errorf("sources must be a JSON array: %w", err)
+	}
+	for i, s := range list {
+		if err := s.prepare(getenv); err != nil {
+			return nil, fmt.Errorf("source %d: %w", i, err)
+		}
+		if _, dup := sources[s.Name]; dup {
+			return nil, fmt.Errorf("source %d: %s is listed twice", i, s.Name)
+		}
+		sources[s.Name] = s
+	}
+	return sources, nil
+}
+
+func (s *Source) prepare(getenv func(string) string) error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("name must be 1-40 lowercase letters, digits, dashes or underscores")
+	}
+	if s.SecretEnv == "" {
+		return fmt.Errorf("secret_env is required")
+	}
+	if s.secret = getenv(s.SecretEnv); s.secret == "" {
+		return fmt.Errorf("%s is not set", s.SecretEnv)
+	}
+	switch s.Scheme {
+	case "":
+		s.Scheme = SchemeTimestamped
+	case SchemeTimestamped, SchemeHMAC:
+	default:
+		return fmt.Errorf("scheme must be %s or %s", SchemeTimestamped, SchemeHMAC)
+	}
+	if s.Header == "" {
+		s.Header = webhook.SignatureHeader
+	}
+	if s.ToleranceSeconds < 0 {
+		return fmt.Errorf("tolerance_seconds must not be negative")
+	}
+	if s.ToleranceSeconds == 0 {
+		s.ToleranceSeconds = 300
+	}
+	if len(s.Rules) == 0 {
+		return fmt.Errorf("rules are required")
+	}
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		switch {
+		case r.Type == "":
+			return fmt.Errorf("rule %d: type is required", i)
+		case r.EventID == "" || r.CustomerID == "" || r.Amount == "":
+			return fmt.Errorf("rule %d: event_id, customer_id and amount paths are required", i)
+		case r.AmountScale < 0:
+			return fmt.Errorf("rule %d: amount_scale must be positive", i)
+		}
+		if r.AmountScale == 0 {
+			r.AmountScale = 1
+		}
+	}
+	return nil
+}
+
+// Verify checks the notification's signature
+func (s *Source) Verify(h http.Header, body []byte, now time.Time) error {
+	header := h.Get(s.Header)
+	if header == "" {
+		return fmt.Errorf("missing %s header", s.Header)
+	}
+	if s.Scheme == SchemeTimestamped {
+		return webhook.Verify(s.secret, header, body, time.Duration(s.ToleranceSeconds)*time.Second, now)
+	}
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(strings.ToLower(strings.TrimPrefix(header, "sha256="))), []byte(want)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// Map applies the first matching rule to a notification body. It returns
+// false when no rule matches, which callers acknowledge without posting.
+func (s *Source) Map(body []byte) (Posting, bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return Posting{}, false, fmt.Errorf("body is not JSON: %w", err)
+	}
+	for _, r := range s.Rules {
+		if !r.matches(doc) {
+			continue
+		}
+		p, err := r.apply(doc)
+		return p, true, err
+	}
+	return Posting{}, false, nil
+}
+
+func (r Rule) matches(doc interface{}) bool {
+	for path, want := range r.Match {
+		got, ok := text(doc, path)
+		if !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Rule) apply(doc interface{}) (Posting, error) {
+	p := Posting{Type: r.Type}
+	var ok bool
+	if p.EventID, ok = text(doc, r.EventID); !ok || p.EventID == "" {
+		return Posting{}, fmt.Errorf("%s is missing", r.EventID)
+	}
+	customerID, _ := text(doc, r.CustomerID)
+	id, err := uuid.Parse(customerID)
+	if err != nil {
+		return Posting{}, fmt.Errorf("%s is not a customer ID", r.CustomerID)
+	}
+	p.CustomerID = id
+	amount, _ := text(doc, r.Amount)
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return Posting{}, fmt.Errorf("%s is not a number", r.Amount)
+	}
+	// Scaled amounts are rounded well below the minor unit, so 1234 cents
+	// at 0.01 gives exactly 12.34
+	p.Amount = math.Round(value*r.AmountScale*1e6) / 1e6
+	if p.Amount <= 0 {
+		return Posting{}, fmt.Errorf("%s must be positive", r.Amount)
+	}
+	if r.Currency != "" {
+		currency, _ := text(doc, r.Currency)
+		p.Currency = strings.ToUpper(currency)
+	}
+	return p, nil
+}
+
+// text returns the value at a dot-separated path as text. Strings, numbers
+// and booleans have a text form; objects, arrays and null do not.
+func text(doc interface{}, path string) (string, bool) {
+	v := doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if v, ok = obj[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getenv(key string) string {
+	return map[string]string{"PSP_SECRET": "psp-secret", "BANK_SECRET": "bank-secret"}[key]
+}
+
+const sources = `[
+	{"name": "psp", "secret_env": "PSP_SECRET", "rules": [
+		{"match": {"type": "settlement.paid"}, "type": "credit", "event_id": "id",
+		 "customer_id": "data.customer", "amount": "data.amount", "amount_scale": 0.01, "currency": "data.currency"}
+	]},
+	{"name": "bank", "secret_env": "BANK_SECRET", "scheme": "hmac-sha256", "header": "X-Bank-Signature", "rules": [
+		{"match": {"direction": "in"}, "type": "credit", "event_id": "ref", "customer_id": "account", "amount": "amount"},
+		{"match": {"direction": "out"}, "type": "debit", "event_id": "ref", "customer_id": "account", "amount": "amount"}
+	]}
+]`
+
+func TestParseSources(t *testing.T) {
+	parsed, err := ParseSources(sources, getenv)
+	require.NoError(t, err)
+	require.Len(t, parsed, 2)
+	assert.Equal(t, SchemeTimestamped, parsed["psp"].Scheme)
+	assert.Equal(t, webhook.SignatureHeader, parsed["psp"].Header)
+	assert.Equal(t, 300, parsed["psp"].ToleranceSeconds)
+	assert.Equal(t, float64(1), parsed["bank"].Rules[0].AmountScale)
+
+	empty, err := ParseSources("", getenv)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for name, raw := range map[string]string{
+		"not an array":   `{"name": "psp"}`,
+		"bad name":       `[{"name": "PSP", "secret_env": "PSP_SECRET", "rules": [{"type": "credit", "event_id": "id", "customer_id": "c", "amount": "a"}]}]`,
+		"unset secret":   `[{"name": "psp", "secret_env": "MISSING", "rules": [{"type": "credit", "event_id": "id", "customer_id": "c", "amount": "a"}]}]`,
+		"unknown scheme": `[{"name": "psp", "secret_env": "PSP_SECRET", "scheme": "md5", "rules": [{"type": "credit", "event_id": "id", "customer_id": "c", "amount": "a"}]}]`,
+		"no rules":       `[{"name": "psp", "secret_env": "PSP_SECRET"}]`,
+		"missing path":   `[{"name": "psp", "secret_env": "PSP_SECRET", "rules": [{"type": "credit", "event_id": "id", "amount": "a"}]}]`,
+		"duplicate":      `[{"name": "psp", "secret_env": "PSP_SECRET", "rules": [{"type": "credit", "event_id": "id", "customer_id": "c", "amount": "a"}]}, {"name": "psp", "secret_env": "PSP_SECRET", "rules": [{"type": "credit", "event_id": "id", "customer_id": "c", "amount": "a"}]}]`,
+	} {
+		_, err := ParseSources(raw, getenv)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	parsed, err := ParseSources(sources, getenv)
+	require.NoError(t, err)
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+
+	h := http.Header{}
+	h.Set(webhook.SignatureHeader, webhook.Sign("psp-secret", now, body))
+	assert.NoError(t, parsed["psp"].Verify(h, body, now))
+	assert.Error(t, parsed["psp"].Verify(h, body, now.Add(10*time.Minute)), "stale signature")
+	assert.Error(t, parsed["psp"].Verify(h, []byte(`{"id":"evt_2"}`), now), "altered body")
+	assert.Error(t, parsed["psp"].Verify(http.Header{}, body, now), "missing header")
+
+	mac := hmac.New(sha256.New, []byte("bank-secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+	h = http.Header{}
+	h.Set("X-Bank-Signature", signature)
+	assert.NoError(t, parsed["bank"].Verify(h, body, now))
+	h.Set("X-Bank-Signature", "sha256="+signature)
+	assert.NoError(t, parsed["bank"].Verify(h, body, now))
+	h.Set("X-Bank-Signature", webhook.Sign("bank-secret", now, body))
+	assert.Error(t, parsed["bank"].Verify(h, body, now))
+}
+
+func TestMap(t *testing.T) {
+	parsed, err := ParseSources(sources, getenv)
+	require.NoError(t, err)
+	customerID := uuid.New()
+
+	p, ok, err := parsed["psp"].Map([]byte(`{"id":"evt_1","type":"settlement.paid","data":{"customer":"` + customerID.String() + `","amount":1234,"currency":"eur"}}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, Posting{EventID: "evt_1", CustomerID: customerID, Type: "credit", Amount: 12.34, Currency: "EUR"}, p)
+
+	p, ok, err = parsed["bank"].Map([]byte(`{"ref":"B-7","direction":"out","account":"` + customerID.String() + `","amount":"50.5"}`))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "debit", p.Type)
+	assert.Equal(t, 50.5, p.Amount)
+	assert.Empty(t, p.Currency)
+
+	_, ok, err = parsed["psp"].Map([]byte(`{"id":"evt_2","type":"settlement.pending"}`))
+	require.NoError(t, err)
+	assert.False(t, ok, "no rule matches")
+
+	for name, body := range map[string]string{
+		"not json":      `settlement`,
+		"no event id":   `{"type":"settlement.paid","data":{"customer":"` + customerID.String() + `","amount":1}}`,
+		"bad customer":  `{"id":"evt_3","type":"settlement.paid","data":{"customer":"acme","amount":1}}`,
+		"bad amount":    `{"id":"evt_3","type":"settlement.paid","data":{"customer":"` + customerID.String() + `","amount":"lots"}}`,
+		"zero amount":   `{"id":"evt_3","type":"settlement.paid","data":{"customer":"` + customerID.String() + `","amount":0}}`,
+		"object amount": `{"id":"evt_3","type":"settlement.paid","data":{"customer":"` + customerID.String() + `","amount":{"value":1}}}`,
+	} {
+		_, _, err := parsed["psp"].Map([]byte(body))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,422 @@
+// Package iso20022 writes ISO 20022 pain.001 customer credit transfer
+// initiations, the files banks take in their batch payment channels, and
+// reads the pain.002 payment status reports they answer with
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/money"
+)
+
+// Pain001Namespace is the version of pain.001 Build writes, the one banks
+// most widely accept
+const Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+// Transaction status codes of a pain.002 report
+const (
+	StatusReceived              = "RCVD"
+	StatusPending               = "PDNG"
+	StatusAcceptedTechnical     = "ACTC"
+	StatusAcceptedCustomer      = "ACCP"
+	StatusAcceptedSettlement    = "ACSP"
+	StatusAcceptedWithChange    = "ACWC"
+	StatusAcceptedSettledCredit = "ACSC"
+	StatusPartiallyAccepted     = "PART"
+	StatusRejected              = "RJCT"
+)
+
+// Outcomes a status code stands for
+const (
+	OutcomeAccepted = "accepted"
+	OutcomeSettled  = "settled"
+	OutcomeRejected = "rejected"
+)
+
+// Outcome reduces a status code to accepted, settled or rejected, or ""
+// when it says nothing final yet
+func Outcome(code string) string {
+	switch code {
+	case StatusAcceptedTechnical, StatusAcceptedCustomer, StatusAcceptedSettlement, StatusAcceptedWithChange:
+		return OutcomeAccepted
+	case StatusAcceptedSettledCredit:
+		return OutcomeSettled
+	case StatusRejected:
+		return OutcomeRejected
+	}
+	return ""
+}
+
+// Party is an account holder and their bank
+type Party struct {
+	Name string
+	IBAN string
+	// BIC is optional for SEPA payments
+	BIC string
+}
+
+// CreditTransfer is one payment in a file
+type CreditTransfer struct {
+	// EndToEndID identifies the payment through to the creditor and in
+	// status reports; at most 35 characters
+	EndToEndID string
+	Amount     float64
+	Currency   string
+	Creditor   Party
+	Remittance string
+}
+
+// Pain001 is a credit transfer initiation: payments from one debtor
+// account, executed on one date
+type Pain001 struct {
+	// MessageID identifies the file to the bank; at most 35 characters
+	MessageID       string
+	CreatedAt       time.Time
+	InitiatingParty string
+	Debtor          Party
+	ExecutionDate   time.Time
+	Transfers       []CreditTransfer
+}
+
+// Build writes the initiation as pain.001.001.03 XML, with a payment
+// information block per currency. EUR payments are marked SEPA.
+func Build(m Pain001) ([]byte, error) {
+	if err := validateID("message ID", m.MessageID); err != nil {
+		return nil, err
+	}
+	if len(m.Transfers) == 0 {
+		return nil, fmt.Errorf("no transfers")
+	}
+	if !ValidIBAN(m.Debtor.IBAN) {
+		return nil, fmt.Errorf("invalid debtor IBAN")
+	}
+	byCurrency := map[string][]CreditTransfer{}
+	for _, t := range m.Transfers {
+		if err := validateID("end-to-end ID", t.EndToEndID); err != nil {
+			return nil, err
+		}
+		if !ValidIBAN(t.Creditor.IBAN) {
+			return nil, fmt.Errorf("transfer %s: invalid creditor IBAN", t.EndToEndID)
+		}
+		if t.Amount <= 0 {
+			return nil, fmt.Errorf("transfer %s: amount must be positive", t.EndToEndID)
+		}
+		currency := strings.ToUpper(t.Currency)
+		byCurrency[currency] = append(byCurrency[currency], t)
+	}
+	currencies := make([]string, 0, len(byCurrency))
+	for currency := range byCurrency {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	doc := pain001Document{
+		Xmlns: Pain001Namespace,
+		Initiation: initiation{
+			GroupHeader: groupHeader{
+				MessageID:       m.MessageID,
+				CreatedAt:       m.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+				Transactions:    len(m.Transfers),
+				InitiatingParty: name{Name: Text(m.InitiatingParty, 70)},
+			},
+		},
+	}
+	var total float64
+	for _, currency := range currencies {
+		transfers := byCurrency[currency]
+		block := paymentInfo{
+			ID:            fmt.Sprintf("%.31s-%s", m.MessageID, currency),
+			Method:        "TRF",
+			BatchBooking:  true,
+			Transactions:  len(transfers),
+			ExecutionDate: m.ExecutionDate.Format("2006-01-02"),
+			Debtor:        name{Name: Text(m.Debtor.Name, 70)},
+			DebtorAccount: account{IBAN: compactIBAN(m.Debtor.IBAN), Currency: currency},
+			DebtorAgent:   agentOf(m.Debtor.BIC, true),
+			ChargeBearer:  "SLEV",
+		}
+		if currency == "EUR" {
+			block.TypeInfo = &typeInfo{ServiceLevel: code{Code: "SEPA"}}
+		}
+		var sum float64
+		for _, t := range transfers {
+			amount := Amount(t.Amount, currency)
+			sum += t.Amount
+			tx := transaction{
+				PaymentID:       paymentID{EndToEndID: t.EndToEndID},
+				Amount:          instructedAmount{Value: amount, Currency: currency},
+				CreditorAgent:   agentOf(t.Creditor.BIC, false),
+				Creditor:        name{Name: Text(t.Creditor.Name, 70)},
+				CreditorAccount: account{IBAN: compactIBAN(t.Creditor.IBAN)},
+			}
+			if t.Remittance != "" {
+				tx.Remittance = &remittance{Unstructured: Text(t.Remittance, 140)}
+			}
+			block.Transfers = append(block.Transfers, tx)
+		}
+		block.ControlSum = Amount(sum, currency)
+		total += sum
+		doc.Initiation.Payments = append(doc.Initiation.Payments, block)
+	}
+	doc.Initiation.GroupHeader.ControlSum = strconv.FormatFloat(total, 'f', 2, 64)
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// Amount formats an amount with the currency's minor-unit digits
+func Amount(amount float64, currency string) string {
+	d := money.Decimals(currency)
+	return strconv.FormatFloat(math.Round(amount*math.Pow10(d))/math.Pow10(d), 'f', d, 64)
+}
+
+var textReplacer = regexp.MustCompile(`[^A-Za-z0-9/\-?:().,'+ ]`)
+
+// Text keeps to the Latin character set SEPA allows, at most max
+// characters; anything else becomes a space
+func Text(s string, max int) string {
+	s = strings.TrimSpace(textReplacer.ReplaceAllString(s, " "))
+	if len(s) > max {
+		s = strings.TrimSpace(s[:max])
+	}
+	return s
+}
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9/\-?:().,'+]{1,35}$`)
+
+func validateID(what, id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%s %q must be 1-35 characters without spaces", what, id)
+	}
+	return nil
+}
+
+var ibanPattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// ValidIBAN checks an IBAN's shape and its mod-97 check digits; spaces are
+// ignored
+func ValidIBAN(iban string) bool {
+	iban = compactIBAN(iban)
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+var bicPattern = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+
+// ValidBIC checks a BIC's shape: 8 or 11 characters
+func ValidBIC(bic string) bool {
+	return bicPattern.MatchString(bic)
+}
+
+func compactIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// agentOf names a bank by BIC. The debtor's bank is required, and is
+// NOTPROVIDED when its BIC is not known.
+func agentOf(bic string, required bool) *agent {
+	switch {
+	case bic != "":
+		return &agent{BIC: bic}
+	case required:
+		return &agent{Other: &otherID{ID: "NOTPROVIDED"}}
+	}
+	return nil
+}
+
+type pain001Document struct {
+	XMLName    xml.Name   `xml:"Document"`
+	Xmlns      string     `xml:"xmlns,attr"`
+	Initiation initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type initiation struct {
+	GroupHeader groupHeader   `xml:"GrpHdr"`
+	Payments    []paymentInfo `xml:"PmtInf"`
+}
+
+type groupHeader struct {
+	MessageID       string `xml:"MsgId"`
+	CreatedAt       string `xml:"CreDtTm"`
+	Transactions    int    `xml:"NbOfTxs"`
+	ControlSum      string `xml:"CtrlSum"`
+	InitiatingParty name   `xml:"InitgPty"`
+}
+
+type paymentInfo struct {
+	ID            string        `xml:"PmtInfId"`
+	Method        string        `xml:"PmtMtd"`
+	BatchBooking  bool          `xml:"BtchBookg"`
+	Transactions  int           `xml:"NbOfTxs"`
+	ControlSum    string        `xml:"CtrlSum"`
+	TypeInfo      *typeInfo     `xml:"PmtTpInf,omitempty"`
+	ExecutionDate string        `xml:"ReqdExctnDt"`
+	Debtor        name          `xml:"Dbtr"`
+	DebtorAccount account       `xml:"DbtrAcct"`
+	DebtorAgent   *agent        `xml:"DbtrAgt>FinInstnId"`
+	ChargeBearer  string        `xml:"ChrgBr"`
+	Transfers     []transaction `xml:"CdtTrfTxInf"`
+}
+
+type typeInfo struct {
+	ServiceLevel code `xml:"SvcLvl"`
+}
+
+type code struct {
+	Code string `xml:"Cd"`
+}
+
+type name struct {
+	Name string `xml:"Nm"`
+}
+
+type account struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Currency string `xml:"Ccy,omitempty"`
+}
+
+type agent struct {
+	BIC   string   `xml:"BIC,omitempty"`
+	Other *otherID `xml:"Othr,omitempty"`
+}
+
+type otherID struct {
+	ID string `xml:"Id"`
+}
+
+type transaction struct {
+	PaymentID       paymentID        `xml:"PmtId"`
+	Amount          instructedAmount `xml:"Amt>InstdAmt"`
+	CreditorAgent   *agent           `xml:"CdtrAgt>FinInstnId,omitempty"`
+	Creditor        name             `xml:"Cdtr"`
+	CreditorAccount account          `xml:"CdtrAcct"`
+	Remittance      *remittance      `xml:"RmtInf,omitempty"`
+}
+
+type paymentID struct {
+	EndToEndID string `xml:"EndToEndId"`
+}
+
+type instructedAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type remittance struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+// StatusReport is a pain.002 payment status report on a file sent earlier
+type StatusReport struct {
+	MessageID string
+	// OriginalMessageID is the MsgId of the pain.001 file reported on
+	OriginalMessageID string
+	// GroupStatus applies to every payment in the file not reported on
+	// separately; it may be empty
+	GroupStatus  string
+	GroupReason  string
+	Transactions []TransactionStatus
+}
+
+// TransactionStatus is the status of one payment
+type TransactionStatus struct {
+	EndToEndID string
+	Status     string
+	// Reason is the ISO reason code, such as AC01 for an incorrect account
+	// number, followed by any text the bank added
+	Reason string
+}
+
+// ParseStatusReport reads a pain.002 report of any version from 001.03 on.
+// A payment without a status of its own takes its payment block's, and
+// failing that the group's.
+func ParseStatusReport(data []byte) (StatusReport, error) {
+	var doc struct {
+		Report struct {
+			GroupHeader struct {
+				MessageID string `xml:"MsgId"`
+			} `xml:"GrpHdr"`
+			Group struct {
+				OriginalMessageID string         `xml:"OrgnlMsgId"`
+				Status            string         `xml:"GrpSts"`
+				Reasons           []statusReason `xml:"StsRsnInf"`
+			} `xml:"OrgnlGrpInfAndSts"`
+			Payments []struct {
+				Status       string         `xml:"PmtInfSts"`
+				Reasons      []statusReason `xml:"StsRsnInf"`
+				Transactions []struct {
+					EndToEndID string         `xml:"OrgnlEndToEndId"`
+					Status     string         `xml:"TxSts"`
+					Reasons    []statusReason `xml:"StsRsnInf"`
+				} `xml:"TxInfAndSts"`
+			} `xml:"OrgnlPmtInfAndSts"`
+		} `xml:"CstmrPmtStsRpt"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return StatusReport{}, fmt.Errorf("invalid pain.002: %v", err)
+	}
+	r := doc.Report
+	if r.Group.OriginalMessageID == "" {
+		return StatusReport{}, fmt.Errorf("invalid pain.002: no OrgnlGrpInfAndSts/OrgnlMsgId")
+	}
+	report := StatusReport{
+		MessageID:         r.GroupHeader.MessageID,
+		OriginalMessageID: r.Group.OriginalMessageID,
+		GroupStatus:       r.Group.Status,
+		GroupReason:       reasonOf(r.Group.Reasons),
+	}
+	for _, p := range r.Payments {
+		for _, t := range p.Transactions {
+			status := TransactionStatus{EndToEndID: t.EndToEndID, Status: t.Status, Reason: reasonOf(t.Reasons)}
+			if status.Status == "" {
+				status.Status, status.Reason = p.Status, reasonOf(p.Reasons)
+			}
+			if status.Status == "" {
+				status.Status, status.Reason = report.GroupStatus, report.GroupReason
+			}
+			report.Transactions = append(report.Transactions, status)
+		}
+	}
+	return report, nil
+}
+
+type statusReason struct {
+	Code        string   `xml:"Rsn>Cd"`
+	Proprietary string   `xml:"Rsn>Prtry"`
+	Info        []string `xml:"AddtlInf"`
+}
+
+func reasonOf(reasons []statusReason) string {
+	if len(reasons) == 0 {
+		return ""
+	}
+	r := reasons[0]
+	parts := []string{r.Code}
+	if r.Code == "" {
+		parts[0] = r.Proprietary
+	}
+	parts = append(parts, r.Info...)
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
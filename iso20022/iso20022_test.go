@@ -0,0 +1,104 @@
+package iso20022
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	m := Pain001{
+		MessageID:       "9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d",
+		CreatedAt:       time.Date(2025, 4, 8, 9, 30, 0, 0, time.UTC),
+		InitiatingParty: "Ledger Service",
+		Debtor:          Party{Name: "Ledger Service Ltd", IBAN: "DE89 3704 0044 0532 0130 00", BIC: "COBADEFFXXX"},
+		ExecutionDate:   time.Date(2025, 4, 9, 0, 0, 0, 0, time.UTC),
+		Transfers: []CreditTransfer{
+			{EndToEndID: "a1", Amount: 125.5, Currency: "EUR", Creditor: Party{Name: "Zoë Müller", IBAN: "FR1420041010050500013M02606"}, Remittance: "Invoice #42"},
+			{EndToEndID: "a2", Amount: 20, Currency: "GBP", Creditor: Party{Name: "Sam Lee", IBAN: "GB29NWBK60161331926819", BIC: "NWBKGB2L"}},
+		},
+	}
+	out, err := Build(m)
+	require.NoError(t, err)
+	doc := string(out)
+	assert.True(t, strings.HasPrefix(doc, "<?xml"))
+	assert.Contains(t, doc, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">`)
+	assert.Contains(t, doc, "<NbOfTxs>2</NbOfTxs>")
+	assert.Contains(t, doc, "<CtrlSum>145.50</CtrlSum>")
+	assert.Contains(t, doc, "<PmtInfId>9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5-EUR</PmtInfId>")
+	assert.Contains(t, doc, "<Cd>SEPA</Cd>")
+	assert.Equal(t, 1, strings.Count(doc, "<Cd>SEPA</Cd>"), "only EUR payments are SEPA")
+	assert.Contains(t, doc, "<IBAN>DE89370400440532013000</IBAN>")
+	assert.Contains(t, doc, `<InstdAmt Ccy="EUR">125.50</InstdAmt>`)
+	assert.Contains(t, doc, "<Nm>Zo  M ller</Nm>")
+	assert.Contains(t, doc, "<Ustrd>Invoice  42</Ustrd>")
+	assert.Contains(t, doc, "<BIC>NWBKGB2L</BIC>")
+	assert.Contains(t, doc, "<ReqdExctnDt>2025-04-09</ReqdExctnDt>")
+
+	m.Debtor.BIC = ""
+	out, err = Build(m)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "<Othr>\n            <Id>NOTPROVIDED</Id>")
+
+	m.Transfers[0].Creditor.IBAN = "FR1420041010050500013M02607"
+	_, err = Build(m)
+	assert.ErrorContains(t, err, "invalid creditor IBAN")
+	m.Transfers = nil
+	_, err = Build(m)
+	assert.Error(t, err)
+}
+
+func TestValidIBAN(t *testing.T) {
+	assert.True(t, ValidIBAN("GB29NWBK60161331926819"))
+	assert.True(t, ValidIBAN("de89 3704 0044 0532 0130 00"))
+	assert.False(t, ValidIBAN("GB29NWBK60161331926818"))
+	assert.False(t, ValidIBAN("12345"))
+	assert.True(t, ValidBIC("COBADEFFXXX"))
+	assert.False(t, ValidBIC("COBA"))
+}
+
+func TestParseStatusReport(t *testing.T) {
+	report, err := ParseStatusReport([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03">
+  <CstmrPmtStsRpt>
+    <GrpHdr><MsgId>STS-1</MsgId><CreDtTm>2025-04-09T08:00:00</CreDtTm></GrpHdr>
+    <OrgnlGrpInfAndSts>
+      <OrgnlMsgId>9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d</OrgnlMsgId>
+      <OrgnlMsgNmId>pain.001.001.03</OrgnlMsgNmId>
+      <GrpSts>PART</GrpSts>
+    </OrgnlGrpInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <OrgnlPmtInfId>9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5-EUR</OrgnlPmtInfId>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>a1</OrgnlEndToEndId>
+        <TxSts>RJCT</TxSts>
+        <StsRsnInf><Rsn><Cd>AC01</Cd></Rsn><AddtlInf>Incorrect account number</AddtlInf></StsRsnInf>
+      </TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <OrgnlPmtInfId>9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5-GBP</OrgnlPmtInfId>
+      <PmtInfSts>ACSC</PmtInfSts>
+      <TxInfAndSts><OrgnlEndToEndId>a2</OrgnlEndToEndId></TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+  </CstmrPmtStsRpt>
+</Document>`))
+	require.NoError(t, err)
+	assert.Equal(t, "STS-1", report.MessageID)
+	assert.Equal(t, "9f1c2b7e4d6a4e0b8c3d5f7a9b1c3e5d", report.OriginalMessageID)
+	assert.Equal(t, StatusPartiallyAccepted, report.GroupStatus)
+	assert.Equal(t, []TransactionStatus{
+		{EndToEndID: "a1", Status: StatusRejected, Reason: "AC01 Incorrect account number"},
+		{EndToEndID: "a2", Status: StatusAcceptedSettledCredit},
+	}, report.Transactions)
+	assert.Equal(t, OutcomeRejected, Outcome(report.Transactions[0].Status))
+	assert.Equal(t, OutcomeSettled, Outcome(report.Transactions[1].Status))
+	assert.Equal(t, "", Outcome(StatusPending))
+
+	_, err = ParseStatusReport([]byte(`<Document><CstmrPmtStsRpt/></Document>`))
+	assert.Error(t, err)
+	_, err = ParseStatusReport([]byte(`not xml`))
+	assert.Error(t, err)
+}
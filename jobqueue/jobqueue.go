@@ -0,0 +1,264 @@
+// Package jobqueue runs background work kept in a durable queue, such as
+// webhook deliveries, statement rendering, imports and notifications. Jobs
+// survive restarts, a failed attempt is retried with exponential backoff,
+// and a job that runs out of attempts is dead-lettered for an operator to
+// look at. Each kind of job has its own handler and concurrency limit.
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ledger-service/metrics"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	// StatusQueued jobs run once their run_at passes, including failed
+	// attempts waiting to be retried
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	// StatusDead jobs ran out of attempts or failed permanently; only an
+	// operator retrying them runs them again
+	StatusDead      = "dead"
+	StatusCancelled = "cancelled"
+)
+
+// Job is a claimed job about to run
+type Job struct {
+	ID         uuid.UUID
+	Kind       string
+	CustomerID *uuid.UUID
+	Payload    json.RawMessage
+	// Attempts counts this one
+	Attempts    int
+	MaxAttempts int
+}
+
+// Handler runs a job, returning what it produced for the job's status
+type Handler func(ctx context.Context, job Job) (result interface{}, err error)
+
+// Kind configures one kind of job
+type Kind struct {
+	Handler Handler
+	// Concurrency is how many jobs of the kind each worker runs at once
+	Concurrency int
+	// Timeout bounds each attempt
+	Timeout time.Duration
+}
+
+// Store keeps the queue
+type Store interface {
+	// Claim marks up to limit due jobs of kind as running until lease
+	// passes, counting an attempt for each. A job whose lease passed
+	// without an outcome, because its worker stopped, is due again.
+	Claim(ctx context.Context, kind string, limit int, lease time.Duration) ([]Job, error)
+	// Complete records a job's success and what it produced
+	Complete(ctx context.Context, id uuid.UUID, result json.RawMessage) error
+	// Retry requeues a failed job to run again at
+	Retry(ctx context.Context, id uuid.UUID, errMsg string, at time.Time) error
+	// Bury dead-letters a job
+	Bury(ctx context.Context, id uuid.UUID, errMsg string) error
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error retrying cannot fix, such as a job for a
+// customer that no longer exists; the job is dead-lettered at once
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// Backoff is how long a job waits after its n-th failed attempt: base,
+// doubling with every attempt, up to max
+func Backoff(n int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// leaseGrace is how long past its timeout a job stays leased, so a slow
+// attempt is not claimed again while it is still recording its outcome
+const leaseGrace = 30 * time.Second
+
+// Worker claims jobs from a Store and runs them
+type Worker struct {
+	store Store
+	kinds map[string]Kind
+	// BaseDelay and MaxDelay bound the backoff between attempts
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	now       func() time.Time
+
+	runs      *metrics.Counter
+	durations *metrics.Histogram
+}
+
+// NewWorker creates a worker running kinds, keyed by name, and reporting
+// their runs to r
+func NewWorker(store Store, kinds map[string]Kind, r *metrics.Registry) *Worker {
+	return &Worker{
+		store:     store,
+		kinds:     kinds,
+		BaseDelay: 10 * time.Second,
+		MaxDelay:  time.Hour,
+		now:       time.Now,
+		runs: r.NewCounter("ledger_queue_jobs_total",
+			"Queued job attempts by kind and outcome: succeeded, retried or dead", "kind", "outcome"),
+		durations: r.NewHistogram("ledger_queue_job_duration_seconds",
+			"Time taken by queued job attempts",
+			[]float64{0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300}, "kind"),
+	}
+}
+
+// Run polls for due jobs of every kind every poll interval until ctx is
+// cancelled, then waits for the jobs already running
+func (w *Worker) Run(ctx context.Context, poll time.Duration) {
+	var wg sync.WaitGroup
+	for name, kind := range w.kinds {
+		wg.Add(1)
+		go func(name string, kind Kind) {
+			defer wg.Done()
+			w.runKind(ctx, name, kind, poll)
+		}(name, kind)
+	}
+	wg.Wait()
+}
+
+func (w *Worker) runKind(ctx context.Context, name string, kind Kind, poll time.Duration) {
+	concurrency := kind.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		if free := concurrency - len(slots); free > 0 {
+			jobs, err := w.store.Claim(ctx, name, free, kind.Timeout+leaseGrace)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to claim %s jobs: %v", name, err)
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				running.Add(1)
+				go func(job Job) {
+					defer func() { <-slots; running.Done() }()
+					// The outcome is recorded even when shutdown cancels ctx
+					w.run(context.WithoutCancel(ctx), kind, job)
+				}(job)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims and runs the due jobs of kind, up to its concurrency, and
+// returns how many ran
+func (w *Worker) RunOnce(ctx context.Context, name string) (int, error) {
+	kind, ok := w.kinds[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown job kind %s", name)
+	}
+	concurrency := kind.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs, err := w.store.Claim(ctx, name, concurrency, kind.Timeout+leaseGrace)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			w.run(ctx, kind, job)
+		}(job)
+	}
+	wg.Wait()
+	return len(jobs), nil
+}
+
+// run makes one attempt at job and records its outcome
+func (w *Worker) run(ctx context.Context, kind Kind, job Job) {
+	// A job claimed again after its worker stopped mid-attempt may have run
+	// its last attempt already
+	if job.Attempts > job.MaxAttempts {
+		w.record(job, StatusDead, w.store.Bury(ctx, job.ID, "interrupted on its last attempt"))
+		return
+	}
+
+	attemptCtx := ctx
+	if kind.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, kind.Timeout)
+		defer cancel()
+	}
+	start := w.now()
+	result, err := safeRun(attemptCtx, kind.Handler, job)
+	w.durations.Observe(w.now().Sub(start).Seconds(), job.Kind)
+
+	switch {
+	case err == nil:
+		data, merr := json.Marshal(result)
+		if merr != nil {
+			w.record(job, StatusDead, w.store.Bury(ctx, job.ID, "invalid result: "+merr.Error()))
+			return
+		}
+		w.record(job, StatusSucceeded, w.store.Complete(ctx, job.ID, data))
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %s (%s) failed for good after %d attempts: %v", job.ID, job.Kind, job.Attempts, err)
+		w.record(job, StatusDead, w.store.Bury(ctx, job.ID, err.Error()))
+	default:
+		at := w.now().Add(Backoff(job.Attempts, w.BaseDelay, w.MaxDelay))
+		w.record(job, "retried", w.store.Retry(ctx, job.ID, err.Error(), at))
+	}
+}
+
+func (w *Worker) record(job Job, outcome string, err error) {
+	if err != nil {
+		log.Printf("Failed to record the outcome of job %s (%s): %v", job.ID, job.Kind, err)
+		return
+	}
+	w.runs.Inc(job.Kind, outcome)
+}
+
+// safeRun turns a panicking handler into a failed attempt
+func safeRun(ctx context.Context, h Handler, job Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
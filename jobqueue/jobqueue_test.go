@@ -0,0 +1,186 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ledger-service/metrics"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type outcome struct {
+	status string
+	result string
+	err    string
+	at     time.Time
+}
+
+type fakeStore struct {
+	mu       sync.Mutex
+	due      []Job
+	leases   []time.Duration
+	limits   []int
+	outcomes map[uuid.UUID]outcome
+}
+
+func (s *fakeStore) Claim(_ context.Context, kind string, limit int, lease time.Duration) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leases = append(s.leases, lease)
+	s.limits = append(s.limits, limit)
+	var claimed []Job
+	for len(s.due) > 0 && len(claimed) < limit {
+		claimed = append(claimed, s.due[0])
+		s.due = s.due[1:]
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) set(id uuid.UUID, o outcome) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcomes == nil {
+		s.outcomes = map[uuid.UUID]outcome{}
+	}
+	s.outcomes[id] = o
+	return nil
+}
+
+func (s *fakeStore) Complete(_ context.Context, id uuid.UUID, result json.RawMessage) error {
+	return s.set(id, outcome{status: StatusSucceeded, result: string(result)})
+}
+
+func (s *fakeStore) Retry(_ context.Context, id uuid.UUID, errMsg string, at time.Time) error {
+	return s.set(id, outcome{status: StatusQueued, err: errMsg, at: at})
+}
+
+func (s *fakeStore) Bury(_ context.Context, id uuid.UUID, errMsg string) error {
+	return s.set(id, outcome{status: StatusDead, err: errMsg})
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := 10*time.Second, 5*time.Minute
+	assert.Equal(t, 10*time.Second, Backoff(1, base, max))
+	assert.Equal(t, 20*time.Second, Backoff(2, base, max))
+	assert.Equal(t, 80*time.Second, Backoff(4, base, max))
+	assert.Equal(t, max, Backoff(10, base, max))
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := Permanent(errors.New("webhook was deleted"))
+	assert.True(t, IsPermanent(err))
+	assert.True(t, IsPermanent(fmt.Errorf("delivery: %w", err)))
+	assert.False(t, IsPermanent(errors.New("endpoint answered 503")))
+	assert.EqualError(t, err, "webhook was deleted")
+}
+
+func TestWorker(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	job := func(attempts, max int) Job {
+		return Job{ID: uuid.New(), Kind: "webhook.delivery", Payload: json.RawMessage(`{"n":1}`), Attempts: attempts, MaxAttempts: max}
+	}
+	ok, flaky, broken, lost, invalid, panics := job(1, 3), job(2, 3), job(1, 3), job(4, 3), job(3, 3), job(1, 3)
+	store := &fakeStore{due: []Job{ok, flaky, broken, lost, invalid, panics}}
+
+	r := metrics.NewRegistry()
+	w := NewWorker(store, map[string]Kind{
+		"webhook.delivery": {
+			Concurrency: 6,
+			Timeout:     time.Minute,
+			Handler: func(ctx context.Context, j Job) (interface{}, error) {
+				_, hasDeadline := ctx.Deadline()
+				assert.True(t, hasDeadline, "attempts are bounded by the kind's timeout")
+
+				switch j.ID {
+				case flaky.ID, invalid.ID:
+					return nil, errors.New("endpoint answered 503")
+				case broken.ID:
+					return nil, Permanent(errors.New("webhook was deleted"))
+				case panics.ID:
+					panic("nil map")
+				}
+				return map[string]int{"status": 200}, nil
+			},
+		},
+	}, r)
+	w.now = func() time.Time { return now }
+
+	n, err := w.RunOnce(context.Background(), "webhook.delivery")
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, []time.Duration{time.Minute + leaseGrace}, store.leases)
+
+	assert.Equal(t, outcome{status: StatusSucceeded, result: `{"status":200}`}, store.outcomes[ok.ID])
+	// The second failed attempt waits twice the base delay
+	assert.Equal(t, outcome{status: StatusQueued, err: "endpoint answered 503", at: now.Add(20 * time.Second)}, store.outcomes[flaky.ID])
+	assert.Equal(t, outcome{status: StatusDead, err: "webhook was deleted"}, store.outcomes[broken.ID])
+	assert.Equal(t, outcome{status: StatusDead, err: "interrupted on its last attempt"}, store.outcomes[lost.ID])
+	assert.Equal(t, outcome{status: StatusDead, err: "endpoint answered 503"}, store.outcomes[invalid.ID], "the last attempt failed")
+	assert.Equal(t, StatusQueued, store.outcomes[panics.ID].status)
+	assert.Contains(t, store.outcomes[panics.ID].err, "panic: nil map")
+
+	var out strings.Builder
+	r.Write(&out)
+	assert.Contains(t, out.String(), `ledger_queue_jobs_total{kind="webhook.delivery",outcome="dead"} 3`)
+	assert.Contains(t, out.String(), `ledger_queue_jobs_total{kind="webhook.delivery",outcome="retried"} 2`)
+
+	_, err = w.RunOnce(context.Background(), "statement.render")
+	assert.ErrorContains(t, err, "unknown job kind")
+}
+
+func TestWorkerConcurrency(t *testing.T) {
+	var due []Job
+	for i := 0; i < 5; i++ {
+		due = append(due, Job{ID: uuid.New(), Kind: "notification.sms", Attempts: 1, MaxAttempts: 3})
+	}
+	store := &fakeStore{due: due}
+	var mu sync.Mutex
+	running, peak, done := 0, 0, 0
+	release := make(chan struct{})
+	w := NewWorker(store, map[string]Kind{
+		"notification.sms": {
+			Concurrency: 2,
+			Handler: func(ctx context.Context, j Job) (interface{}, error) {
+				mu.Lock()
+				running++
+				if running > peak {
+					peak = running
+				}
+				mu.Unlock()
+				<-release
+				mu.Lock()
+				running--
+				done++
+				mu.Unlock()
+				return nil, nil
+			},
+		},
+	}, metrics.NewRegistry())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	go func() {
+		w.Run(ctx, 5*time.Millisecond)
+		close(finished)
+	}()
+	for i := 0; i < 5; i++ {
+		release <- struct{}{}
+	}
+	cancel()
+	<-finished
+
+	assert.Equal(t, 5, done)
+	assert.LessOrEqual(t, peak, 2, "no more than the kind's concurrency run at once")
+	for _, limit := range store.limits {
+		assert.LessOrEqual(t, limit, 2)
+	}
+}
@@ -0,0 +1,1162 @@
+// Package ledger holds the rules for moving money: posting transactions
+// against a customer's balance, reading balances and transferring between
+// customers. It knows nothing about HTTP, so the same rules serve the API,
+// background workers and command line tools. Failures a caller should act on
+// are returned as the errors below.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrPayerNotFound    = errors.New("payer not found")
+	ErrPayeeNotFound    = errors.New("payee not found")
+	// ErrUnknownTransactionType is returned for types that are not
+	// registered or may not be posted directly
+	ErrUnknownTransactionType = errors.New("unknown transaction type")
+	ErrInsufficientBalance    = errors.New("insufficient balance")
+	// ErrOverpayment is returned for a credit to a credit account larger
+	// than what the customer owes
+	ErrOverpayment = errors.New("payment exceeds amount owed")
+	// ErrCurrencyMismatch is returned for a posting in another currency than
+	// the account's when the service has no Converter, or a transfer between
+	// accounts in different currencies
+	ErrCurrencyMismatch = errors.New("currency does not match the account")
+	// ErrConversion wraps a Converter's failure to convert a posting
+	ErrConversion = errors.New("currency conversion failed")
+	// ErrSelfTransfer is returned for a transfer or reservation whose payer
+	// is also its payee
+	ErrSelfTransfer = errors.New("payer and payee are the same customer")
+	// ErrInvalidSplit is returned for a split transfer without credits, with
+	// a credit that is not positive or one paying the payer
+	ErrInvalidSplit = errors.New("invalid split transfer")
+	// ErrSplitTotal is returned for a split transfer whose credits do not
+	// add up to its amount
+	ErrSplitTotal = errors.New("split credits do not add up to the amount")
+	// ErrDuplicateReference is returned for a posting with a unique
+	// reference the customer already has; see DuplicateReferenceError
+	ErrDuplicateReference = errors.New("duplicate payment reference")
+	// ErrReservationNotFound is returned for a transfer ID that is not a
+	// reservation
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationClosed is returned for settling or releasing a
+	// reservation that was already settled or released
+	ErrReservationClosed = errors.New("reservation already closed")
+	// ErrNotAwaitingApproval is returned for approving a transaction that is
+	// not the payer's transfer_out of a transfer held for approval
+	ErrNotAwaitingApproval = errors.New("transfer not awaiting approval")
+)
+
+// Transfer statuses. A transfer completes at once; a reservation holds the
+// payer's funds until it is settled to the payee or released back. Either
+// waits as pending approval, nothing moved, when Rules.ScreenPayer asks for
+// it, until it is approved or rejected.
+const (
+	TransferCompleted       = "completed"
+	TransferReserved        = "reserved"
+	TransferSettled         = "settled"
+	TransferReleased        = "released"
+	TransferPendingApproval = "pending_approval"
+	TransferRejected        = "rejected"
+)
+
+// DuplicateReferenceError is a posting refused because its unique reference
+// was already used by TransactionID
+type DuplicateReferenceError struct {
+	TransactionID uuid.UUID
+}
+
+func (e *DuplicateReferenceError) Error() string {
+	return fmt.Sprintf("%v: already used by transaction %s", ErrDuplicateReference, e.TransactionID)
+}
+
+func (e *DuplicateReferenceError) Unwrap() error {
+	return ErrDuplicateReference
+}
+
+// ViolationError is a posting refused by a limit or account rule
+type ViolationError struct {
+	Message string
+}
+
+func (e *ViolationError) Error() string {
+	return e.Message
+}
+
+// Statuses a posted transaction can take
+const (
+	StatusPosted          = "posted"
+	StatusHeld            = "held"
+	StatusPendingApproval = "pending_approval"
+	StatusRejected        = "rejected"
+)
+
+// Posting asks for a transaction against a customer's balance
+type Posting struct {
+	// ID, when set, is the ID the transaction is recorded under; a new one
+	// is generated otherwise. A transaction submitted to be posted later
+	// keeps the ID it was given when it was accepted.
+	ID         uuid.UUID
+	CustomerID uuid.UUID
+	Type       string
+	Amount     float64
+	// Currency, when set and not the account's base currency, is converted
+	// from by the service's Converter; Post then moves Amount into Original
+	// and replaces it with the converted amount
+	Currency string
+	Original *store.Original
+	// ValueDate, when set, is the date the posting takes effect; the store
+	// defaults it to the posting date in the customer's timezone
+	ValueDate time.Time
+	// Reference is the payment's external reference. With UniqueReference
+	// set, a posting whose reference the customer already holds as a unique
+	// one, on a transaction not rejected, fails with DuplicateReferenceError.
+	Reference       string
+	UniqueReference bool
+	// AllowDuplicate vouches for a legitimate repeat of a recent posting, so
+	// rules looking for duplicates let it through
+	AllowDuplicate bool
+	// Direction is filled in from the registered type
+	Direction txtype.Direction
+	// DryRun runs every check and works out the result without writing
+	// anything. Rules see it so they can skip checks that must not be
+	// probed, such as fraud rules.
+	DryRun bool
+}
+
+// Result is the outcome of a posting. Only posted transactions change the
+// balance; held, pending and rejected ones are recorded for review.
+type Result struct {
+	TransactionID   uuid.UUID
+	Status          string
+	Balance         float64
+	PreviousBalance float64
+	Screening       Screening
+	// Overdrawn is set when the posting took an account allowed to go
+	// negative, or with an overdraft, below zero
+	Overdrawn bool
+	// Amount is the amount posted, in the account's currency, and Original
+	// what it was converted from, if anything
+	Amount   float64
+	Original *store.Original
+}
+
+// Screening is the status Rules.Screen gives a posting
+type Screening struct {
+	// Status is StatusPosted when empty
+	Status string
+	// Detail carries whatever the rules need from Screen to Posted
+	Detail interface{}
+}
+
+// Conversion is an amount converted into an account's currency, and the
+// rounding mode applied to it
+type Conversion struct {
+	Amount   float64
+	Rate     float64
+	Provider string
+	Rounding string
+}
+
+// Converter converts amount from one currency to another for postings made
+// in a currency other than the account's
+type Converter func(ctx context.Context, amount float64, from, to string) (Conversion, error)
+
+// Transfer asks to move money between two customers' balances
+type Transfer struct {
+	FromCustomerID uuid.UUID
+	ToCustomerID   uuid.UUID
+	Amount         float64
+	Reference      string
+	// BatchID is set on the transfers of a split transfer
+	BatchID *uuid.UUID
+	// Immediate refuses a payer Rules.ScreenPayer holds for approval with
+	// *ViolationError instead, for payments that must settle at once
+	Immediate bool
+}
+
+// TransferResult describes a completed transfer, or one held for approval
+// when Status is TransferPendingApproval; balances are then unchanged
+type TransferResult struct {
+	TransferID   uuid.UUID
+	Status       string
+	FromBalance  float64
+	ToBalance    float64
+	FromPrevious float64
+	// Overdrawn is set when the transfer took a payer allowed to go
+	// negative, or with an overdraft, below zero
+	Overdrawn bool
+}
+
+// Split asks to debit one customer once and credit several others, such as a
+// marketplace paying out its sellers
+type Split struct {
+	FromCustomerID uuid.UUID
+	// Amount is debited from the payer; the credits must add up to it
+	Amount    float64
+	Reference string
+	Credits   []SplitCredit
+}
+
+// SplitCredit is one recipient of a split transfer. Reference defaults to
+// the split's.
+type SplitCredit struct {
+	ToCustomerID uuid.UUID
+	Amount       float64
+	Reference    string
+}
+
+// SplitResult describes a completed split transfer, or one held for
+// approval when Status is TransferPendingApproval. Every credit is a
+// transfer from the payer, and all of them share BatchID.
+type SplitResult struct {
+	BatchID      uuid.UUID
+	Status       string
+	FromBalance  float64
+	FromPrevious float64
+	// Transfers holds one transfer per credit, in order
+	Transfers []SplitTransfer
+	// Overdrawn is set when the split took a payer allowed to go negative,
+	// or with an overdraft, below zero
+	Overdrawn bool
+}
+
+// SplitTransfer is one credit of a completed split transfer
+type SplitTransfer struct {
+	TransferID   uuid.UUID
+	ToCustomerID uuid.UUID
+	Amount       float64
+	Reference    string
+	ToBalance    float64
+}
+
+// Reservation describes a reserve-then-settle transfer after its latest step
+type Reservation struct {
+	TransferID     uuid.UUID
+	FromCustomerID uuid.UUID
+	ToCustomerID   uuid.UUID
+	Amount         float64
+	Reference      string
+	Status         string
+	// Balance and Previous are those of the account the step moved: the
+	// payer on reserving and releasing, the payee on settling
+	Balance  float64
+	Previous float64
+	// Overdrawn is set when reserving took a payer allowed to go negative,
+	// or with an overdraft, below zero
+	Overdrawn bool
+}
+
+// Rules add a deployment's checks and bookkeeping to postings. Every method
+// runs inside the store transaction doing the posting, so returning an error
+// undoes it.
+type Rules interface {
+	// Limit runs once the account is locked, before the balance is checked
+	Limit(ctx context.Context, tx store.Tx, p Posting, account store.Customer) error
+	// Screen runs once the balance allows the posting and decides its status
+	Screen(ctx context.Context, tx store.Tx, p Posting, account store.Customer) (Screening, error)
+	// Posted runs after the transaction is written, whatever its status
+	Posted(ctx context.Context, tx store.Tx, p Posting, r Result) error
+	// ScreenPayer runs for the payer of a transfer, split transfer or
+	// reservation once the debit is allowed, before anything is written. p
+	// is the payer's transfer_out. Returning *ViolationError refuses it;
+	// StatusPendingApproval holds it for approval, and any other status
+	// lets it through.
+	ScreenPayer(ctx context.Context, tx store.Tx, p Posting, account store.Customer) (Screening, error)
+	// Transferred runs after both legs of a transfer are written
+	Transferred(ctx context.Context, tx store.Tx, t Transfer, r TransferResult) error
+	// SplitTransferred runs after every leg of a split transfer is written
+	SplitTransferred(ctx context.Context, tx store.Tx, s Split, r SplitResult) error
+	// Reserved runs after each step of a reservation is written; r.Status
+	// tells which
+	Reserved(ctx context.Context, tx store.Tx, r Reservation) error
+}
+
+// NoRules posts without extra checks
+type NoRules struct{}
+
+func (NoRules) Limit(context.Context, store.Tx, Posting, store.Customer) error { return nil }
+func (NoRules) Screen(context.Context, store.Tx, Posting, store.Customer) (Screening, error) {
+	return Screening{}, nil
+}
+func (NoRules) Posted(context.Context, store.Tx, Posting, Result) error { return nil }
+func (NoRules) ScreenPayer(context.Context, store.Tx, Posting, store.Customer) (Screening, error) {
+	return Screening{}, nil
+}
+func (NoRules) Transferred(context.Context, store.Tx, Transfer, TransferResult) error {
+	return nil
+}
+func (NoRules) SplitTransferred(context.Context, store.Tx, Split, SplitResult) error {
+	return nil
+}
+func (NoRules) Reserved(context.Context, store.Tx, Reservation) error { return nil }
+
+// PrePostingHook checks a posting once the account is locked, before the
+// built-in limits. Returning *ViolationError refuses the posting; any other
+// error fails it. Either way nothing is written.
+type PrePostingHook func(ctx context.Context, p Posting, account store.Customer) error
+
+// PostPostingHook sees a posting after it has committed, whatever its
+// status. The posting cannot be undone, so errors are only logged.
+type PostPostingHook func(ctx context.Context, p Posting, r Result) error
+
+// Hooks are a deployment's own Go functions run around every posting. Each
+// list runs in order; the first failing pre-posting hook stops the rest.
+type Hooks struct {
+	PrePosting  []PrePostingHook
+	PostPosting []PostPostingHook
+}
+
+// Apply returns the balance of account after moving amount in direction d.
+// Credits raise a deposit account and debits lower it; a credit account's
+// balance is what the customer owes, so debits raise it and credits repay
+// it. A deposit account may not go below zero (ErrInsufficientBalance) nor a
+// credit account above its credit limit (ErrInsufficientBalance) or below
+// zero (ErrOverpayment). Overdraft lowers a deposit account's floor to
+// -Overdraft, and AllowNegative lifts the floor for both.
+func Apply(account store.Customer, d txtype.Direction, amount float64) (float64, error) {
+	credit := account.BalanceType == store.BalanceCredit
+	balance := account.Balance + amount
+	if (d == txtype.Debit) != credit {
+		balance = account.Balance - amount
+	}
+	floor := 0.0
+	if !credit {
+		floor = -account.Overdraft
+	}
+	switch {
+	case balance > account.Balance:
+		if credit && balance > account.CreditLimit {
+			return 0, ErrInsufficientBalance
+		}
+	case balance < floor && !account.AllowNegative:
+		if credit {
+			return 0, ErrOverpayment
+		}
+		return 0, ErrInsufficientBalance
+	}
+	return balance, nil
+}
+
+// TypeLookup resolves transaction type codes; *txtype.Registry satisfies it
+type TypeLookup interface {
+	Lookup(code string) (txtype.Type, bool)
+}
+
+// Service applies the ledger's rules to a store
+type Service struct {
+	store   store.Store
+	types   TypeLookup
+	rules   Rules
+	hooks   Hooks
+	convert Converter
+}
+
+// New creates a service; rules may be nil
+func New(s store.Store, types TypeLookup, rules Rules) *Service {
+	if rules == nil {
+		rules = NoRules{}
+	}
+	return &Service{store: s, types: types, rules: rules}
+}
+
+// WithHooks runs h around every posting
+func (s *Service) WithHooks(h Hooks) *Service {
+	s.hooks = h
+	return s
+}
+
+// WithConverter accepts postings in other currencies than the account's,
+// converting them with c
+func (s *Service) WithConverter(c Converter) *Service {
+	s.convert = c
+	return s
+}
+
+// Balance returns a customer's current balance in the account's base currency
+func (s *Service) Balance(ctx context.Context, customerID uuid.UUID) (store.Balance, error) {
+	balance, err := s.store.GetBalance(ctx, customerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return store.Balance{}, ErrCustomerNotFound
+	}
+	return balance, err
+}
+
+// Post records a transaction against a customer's balance. Postings Apply
+// refuses, or in a currency other than the account's that cannot be
+// converted, fail before anything is written. Rejected postings are still
+// recorded and returned without error. A DryRun posting returns the
+// would-be result, without a transaction ID, and is rolled back before the
+// post-posting hooks.
+func (s *Service) Post(ctx context.Context, p Posting) (Result, error) {
+	t, ok := s.types.Lookup(p.Type)
+	if !ok || !t.Postable {
+		return Result{}, ErrUnknownTransactionType
+	}
+	p.Direction = t.Direction
+	if err := s.convertPosting(ctx, &p); err != nil {
+		return Result{}, err
+	}
+
+	tx, err := s.store.Begin(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	account, err := tx.LockCustomer(ctx, p.CustomerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return Result{}, ErrCustomerNotFound
+		}
+		return Result{}, err
+	}
+	if p.Currency != "" && p.Currency != account.Currency {
+		return Result{}, ErrCurrencyMismatch
+	}
+	// The account lock serializes the customer's postings, so nothing can
+	// take the reference between this check and the insert
+	if p.UniqueReference {
+		existing, err := tx.FindUniqueReference(ctx, p.CustomerID, p.Reference)
+		if err == nil {
+			return Result{}, &DuplicateReferenceError{TransactionID: existing.ID}
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			return Result{}, err
+		}
+	}
+	for _, hook := range s.hooks.PrePosting {
+		if err := hook(ctx, p, account); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := s.rules.Limit(ctx, tx, p, account); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{PreviousBalance: account.Balance, Amount: p.Amount, Original: p.Original}
+	newBalance, err := Apply(account, p.Direction, p.Amount)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result.Screening, err = s.rules.Screen(ctx, tx, p, account)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Status = result.Screening.Status
+	if result.Status == "" {
+		result.Status = StatusPosted
+	}
+	if p.DryRun {
+		result.Balance = account.Balance
+		if result.Status == StatusPosted {
+			result.Balance = newBalance
+			result.Overdrawn = newBalance < 0 && newBalance < account.Balance
+		}
+		return result, nil
+	}
+
+	// Held, pending and rejected transactions leave the balance untouched
+	result.Balance = account.Balance
+	if result.Status == StatusPosted {
+		if err := tx.SetBalance(ctx, p.CustomerID, newBalance); err != nil {
+			return Result{}, err
+		}
+		result.Balance = newBalance
+		result.Overdrawn = newBalance < 0 && newBalance < account.Balance
+	}
+
+	result.TransactionID = p.ID
+	if result.TransactionID == uuid.Nil {
+		result.TransactionID = uuid.New()
+	}
+	if err := tx.InsertTransaction(ctx, &store.Transaction{
+		ID:              result.TransactionID,
+		CustomerID:      p.CustomerID,
+		Type:            p.Type,
+		Amount:          p.Amount,
+		Status:          result.Status,
+		ValueDate:       p.ValueDate,
+		Original:        p.Original,
+		Reference:       p.Reference,
+		UniqueReference: p.UniqueReference,
+	}); err != nil {
+		if errors.Is(err, store.ErrDuplicateReference) {
+			return Result{}, ErrDuplicateReference
+		}
+		return Result{}, err
+	}
+	if err := s.rules.Posted(ctx, tx, p, result); err != nil {
+		return Result{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Result{}, err
+	}
+	for i, hook := range s.hooks.PostPosting {
+		if err := hook(ctx, p, result); err != nil {
+			log.Printf("Post-posting hook %d failed for transaction %s: %v", i, result.TransactionID, err)
+		}
+	}
+	return result, nil
+}
+
+// convertPosting converts a posting in another currency than the account's
+// into it. The rate is fetched before the account is locked, so a slow
+// provider does not hold the lock; the account's currency cannot change in
+// between.
+func (s *Service) convertPosting(ctx context.Context, p *Posting) error {
+	if p.Currency == "" || s.convert == nil {
+		return nil
+	}
+	balance, err := s.store.GetBalance(ctx, p.CustomerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrCustomerNotFound
+		}
+		return err
+	}
+	if p.Currency == balance.Currency {
+		return nil
+	}
+	c, err := s.convert(ctx, p.Amount, p.Currency, balance.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConversion, err)
+	}
+	p.Original = &store.Original{Amount: p.Amount, Currency: p.Currency, Rate: c.Rate, Provider: c.Provider, Rounding: c.Rounding}
+	p.Amount, p.Currency = c.Amount, balance.Currency
+	return nil
+}
+
+// Transfer moves money between two customers within tx, recording the
+// transfer and a transfer_out/transfer_in transaction pair. Both customers
+// are locked in ID order so opposing transfers cannot deadlock.
+// Legs Apply refuses, payers Rules.ScreenPayer refuses, transfers across
+// currencies and transfers to the payer itself return their error before
+// anything is written, so callers may still commit tx to record the
+// failure. A payer held for approval leaves the transfer pending; see
+// Approve.
+func (s *Service) Transfer(ctx context.Context, tx store.Tx, t Transfer) (TransferResult, error) {
+	if t.FromCustomerID == t.ToCustomerID {
+		return TransferResult{}, ErrSelfTransfer
+	}
+	accounts, err := lockParties(ctx, tx, t.FromCustomerID, t.ToCustomerID)
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	payer := accounts[t.FromCustomerID]
+	if payer.Currency != accounts[t.ToCustomerID].Currency {
+		return TransferResult{}, ErrCurrencyMismatch
+	}
+	result := TransferResult{TransferID: uuid.New(), Status: TransferCompleted, FromPrevious: payer.Balance}
+	if result.FromBalance, err = Apply(payer, txtype.Debit, t.Amount); err != nil {
+		return TransferResult{}, err
+	}
+	if result.ToBalance, err = Apply(accounts[t.ToCustomerID], txtype.Credit, t.Amount); err != nil {
+		return TransferResult{}, err
+	}
+	result.Overdrawn = result.FromBalance < 0 && result.FromBalance < payer.Balance
+
+	debit := payerDebit(t.FromCustomerID, t.Amount, t.Reference)
+	screening, err := s.rules.ScreenPayer(ctx, tx, debit, payer)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	if screening.Status == StatusPendingApproval {
+		if t.Immediate {
+			return TransferResult{}, &ViolationError{Message: "Payments from this account require approval"}
+		}
+		pending := store.Transfer{
+			ID:             result.TransferID,
+			FromCustomerID: t.FromCustomerID,
+			ToCustomerID:   t.ToCustomerID,
+			Amount:         t.Amount,
+			Reference:      t.Reference,
+			BatchID:        t.BatchID,
+			ApprovedStatus: TransferCompleted,
+		}
+		if err := s.holdPayerDebit(ctx, tx, debit, payer, screening, []store.Transfer{pending}, nil); err != nil {
+			return TransferResult{}, err
+		}
+		return TransferResult{
+			TransferID:   result.TransferID,
+			Status:       TransferPendingApproval,
+			FromBalance:  payer.Balance,
+			ToBalance:    accounts[t.ToCustomerID].Balance,
+			FromPrevious: payer.Balance,
+		}, nil
+	}
+
+	if err := tx.InsertTransfer(ctx, &store.Transfer{
+		ID:             result.TransferID,
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+		BatchID:        t.BatchID,
+	}); err != nil {
+		return TransferResult{}, err
+	}
+
+	legs := []struct {
+		customerID uuid.UUID
+		txType     string
+		balance    float64
+	}{
+		{t.FromCustomerID, "transfer_out", result.FromBalance},
+		{t.ToCustomerID, "transfer_in", result.ToBalance},
+	}
+	for _, leg := range legs {
+		if err := tx.SetBalance(ctx, leg.customerID, leg.balance); err != nil {
+			return TransferResult{}, err
+		}
+		if err := tx.InsertTransaction(ctx, &store.Transaction{
+			ID:         uuid.New(),
+			CustomerID: leg.customerID,
+			Type:       leg.txType,
+			Amount:     t.Amount,
+			Status:     StatusPosted,
+			TransferID: &result.TransferID,
+		}); err != nil {
+			return TransferResult{}, err
+		}
+	}
+	if err := s.rules.Transferred(ctx, tx, t, result); err != nil {
+		return TransferResult{}, err
+	}
+	return result, nil
+}
+
+// SplitTransfer debits one customer once and credits each recipient within
+// tx, recording a transfer per credit under a shared batch ID. The payer's
+// history gets a single transfer_out for the whole amount and each
+// recipient a transfer_in. Every account is locked in ID order, so splits
+// and transfers cannot deadlock. Like Transfer, refusals are returned
+// before anything is written, and a payer held for approval leaves every
+// transfer pending.
+func (s *Service) SplitTransfer(ctx context.Context, tx store.Tx, sp Split) (SplitResult, error) {
+	if len(sp.Credits) == 0 {
+		return SplitResult{}, ErrInvalidSplit
+	}
+	payees := make([]uuid.UUID, len(sp.Credits))
+	total := 0.0
+	for i, credit := range sp.Credits {
+		if credit.Amount <= 0 || credit.ToCustomerID == sp.FromCustomerID {
+			return SplitResult{}, ErrInvalidSplit
+		}
+		payees[i] = credit.ToCustomerID
+		total += credit.Amount
+	}
+	// Compare well below the minor unit so float sums like 0.1 + 0.2 match
+	if math.Round(total*1e6) != math.Round(sp.Amount*1e6) {
+		return SplitResult{}, ErrSplitTotal
+	}
+
+	accounts, err := lockParties(ctx, tx, sp.FromCustomerID, payees...)
+	if err != nil {
+		return SplitResult{}, err
+	}
+	payer := accounts[sp.FromCustomerID]
+	for _, id := range payees {
+		if accounts[id].Currency != payer.Currency {
+			return SplitResult{}, ErrCurrencyMismatch
+		}
+	}
+
+	result := SplitResult{BatchID: uuid.New(), Status: TransferCompleted, FromPrevious: payer.Balance}
+	if result.FromBalance, err = Apply(payer, txtype.Debit, sp.Amount); err != nil {
+		return SplitResult{}, err
+	}
+	result.Overdrawn = result.FromBalance < 0 && result.FromBalance < payer.Balance
+	// A recipient may be paid more than once, so credits build on the
+	// balance left by the previous one
+	unchanged := maps.Clone(accounts)
+	for _, credit := range sp.Credits {
+		account := accounts[credit.ToCustomerID]
+		if account.Balance, err = Apply(account, txtype.Credit, credit.Amount); err != nil {
+			return SplitResult{}, err
+		}
+		accounts[credit.ToCustomerID] = account
+		reference := credit.Reference
+		if reference == "" {
+			reference = sp.Reference
+		}
+		result.Transfers = append(result.Transfers, SplitTransfer{
+			TransferID:   uuid.New(),
+			ToCustomerID: credit.ToCustomerID,
+			Amount:       credit.Amount,
+			Reference:    reference,
+			ToBalance:    account.Balance,
+		})
+	}
+
+	debit := payerDebit(sp.FromCustomerID, sp.Amount, sp.Reference)
+	screening, err := s.rules.ScreenPayer(ctx, tx, debit, payer)
+	if err != nil {
+		return SplitResult{}, err
+	}
+	if screening.Status == StatusPendingApproval {
+		pending := make([]store.Transfer, len(result.Transfers))
+		for i, leg := range result.Transfers {
+			pending[i] = store.Transfer{
+				ID:             leg.TransferID,
+				FromCustomerID: sp.FromCustomerID,
+				ToCustomerID:   leg.ToCustomerID,
+				Amount:         leg.Amount,
+				Reference:      leg.Reference,
+				BatchID:        &result.BatchID,
+				ApprovedStatus: TransferCompleted,
+			}
+			result.Transfers[i].ToBalance = unchanged[leg.ToCustomerID].Balance
+		}
+		if err := s.holdPayerDebit(ctx, tx, debit, payer, screening, pending, &result.BatchID); err != nil {
+			return SplitResult{}, err
+		}
+		result.Status, result.FromBalance, result.Overdrawn = TransferPendingApproval, payer.Balance, false
+		return result, nil
+	}
+
+	if err := tx.SetBalance(ctx, sp.FromCustomerID, result.FromBalance); err != nil {
+		return SplitResult{}, err
+	}
+	if err := tx.InsertTransaction(ctx, &store.Transaction{
+		ID:         uuid.New(),
+		CustomerID: sp.FromCustomerID,
+		Type:       "transfer_out",
+		Amount:     sp.Amount,
+		Status:     StatusPosted,
+		BatchID:    &result.BatchID,
+	}); err != nil {
+		return SplitResult{}, err
+	}
+	for i := range result.Transfers {
+		leg := &result.Transfers[i]
+		if err := tx.InsertTransfer(ctx, &store.Transfer{
+			ID:             leg.TransferID,
+			FromCustomerID: sp.FromCustomerID,
+			ToCustomerID:   leg.ToCustomerID,
+			Amount:         leg.Amount,
+			Reference:      leg.Reference,
+			BatchID:        &result.BatchID,
+		}); err != nil {
+			return SplitResult{}, err
+		}
+		if err := tx.InsertTransaction(ctx, &store.Transaction{
+			ID:         uuid.New(),
+			CustomerID: leg.ToCustomerID,
+			Type:       "transfer_in",
+			Amount:     leg.Amount,
+			Status:     StatusPosted,
+			TransferID: &leg.TransferID,
+			BatchID:    &result.BatchID,
+		}); err != nil {
+			return SplitResult{}, err
+		}
+	}
+	for id, account := range accounts {
+		if id == sp.FromCustomerID {
+			continue
+		}
+		if err := tx.SetBalance(ctx, id, account.Balance); err != nil {
+			return SplitResult{}, err
+		}
+	}
+	if err := s.rules.SplitTransferred(ctx, tx, sp, result); err != nil {
+		return SplitResult{}, err
+	}
+	return result, nil
+}
+
+// Reserve debits the payer of t within tx and holds the funds in a transfer
+// with status reserved until Settle or Release closes it. The payer's
+// history gets the transfer_out now; the payee is only checked, and credited
+// on settling. Refusals are returned before anything is written, as with
+// Transfer. A payer held for approval leaves the reservation pending, with
+// nothing reserved until it is approved.
+func (s *Service) Reserve(ctx context.Context, tx store.Tx, t Transfer) (Reservation, error) {
+	if t.FromCustomerID == t.ToCustomerID {
+		return Reservation{}, ErrSelfTransfer
+	}
+	accounts, err := lockParties(ctx, tx, t.FromCustomerID, t.ToCustomerID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	payer := accounts[t.FromCustomerID]
+	if payer.Currency != accounts[t.ToCustomerID].Currency {
+		return Reservation{}, ErrCurrencyMismatch
+	}
+	r := Reservation{
+		TransferID:     uuid.New(),
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+		Status:         TransferReserved,
+		Previous:       payer.Balance,
+	}
+	if r.Balance, err = Apply(payer, txtype.Debit, t.Amount); err != nil {
+		return Reservation{}, err
+	}
+	r.Overdrawn = r.Balance < 0 && r.Balance < payer.Balance
+
+	debit := payerDebit(t.FromCustomerID, t.Amount, t.Reference)
+	screening, err := s.rules.ScreenPayer(ctx, tx, debit, payer)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if screening.Status == StatusPendingApproval {
+		pending := store.Transfer{
+			ID:             r.TransferID,
+			FromCustomerID: t.FromCustomerID,
+			ToCustomerID:   t.ToCustomerID,
+			Amount:         t.Amount,
+			Reference:      t.Reference,
+			ApprovedStatus: TransferReserved,
+		}
+		if err := s.holdPayerDebit(ctx, tx, debit, payer, screening, []store.Transfer{pending}, nil); err != nil {
+			return Reservation{}, err
+		}
+		r.Status, r.Balance, r.Overdrawn = TransferPendingApproval, payer.Balance, false
+		return r, nil
+	}
+
+	if err := tx.InsertTransfer(ctx, &store.Transfer{
+		ID:             r.TransferID,
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+		Status:         TransferReserved,
+	}); err != nil {
+		return Reservation{}, err
+	}
+	if err := s.postReservationLeg(ctx, tx, r, t.FromCustomerID, "transfer_out"); err != nil {
+		return Reservation{}, err
+	}
+	if err := s.rules.Reserved(ctx, tx, r); err != nil {
+		return Reservation{}, err
+	}
+	return r, nil
+}
+
+// payerDebit is the transfer_out a transfer, split transfer or reservation
+// takes from its payer, as Rules.ScreenPayer sees it
+func payerDebit(customerID uuid.UUID, amount float64, reference string) Posting {
+	return Posting{CustomerID: customerID, Type: "transfer_out", Amount: amount, Reference: reference, Direction: txtype.Debit}
+}
+
+// holdPayerDebit records transfers whose payer's debit awaits approval: the
+// transfers as pending and the payer's transfer_out, linked to its transfer
+// or, for a split, to the split's batch. No balance moves, and payees get
+// no leg until Approve.
+func (s *Service) holdPayerDebit(ctx context.Context, tx store.Tx, p Posting, payer store.Customer, screening Screening, transfers []store.Transfer, batchID *uuid.UUID) error {
+	leg := store.Transaction{
+		ID:         uuid.New(),
+		CustomerID: p.CustomerID,
+		Type:       p.Type,
+		Amount:     p.Amount,
+		Status:     StatusPendingApproval,
+		BatchID:    batchID,
+	}
+	if batchID == nil {
+		leg.TransferID = &transfers[0].ID
+	}
+	for i := range transfers {
+		transfers[i].Status = TransferPendingApproval
+		if err := tx.InsertTransfer(ctx, &transfers[i]); err != nil {
+			return err
+		}
+	}
+	if err := tx.InsertTransaction(ctx, &leg); err != nil {
+		return err
+	}
+	return s.rules.Posted(ctx, tx, p, Result{
+		TransactionID:   leg.ID,
+		Status:          StatusPendingApproval,
+		Balance:         payer.Balance,
+		PreviousBalance: payer.Balance,
+		Screening:       screening,
+		Amount:          p.Amount,
+	})
+}
+
+// Approve carries out, within tx, the transfer, split transfer or
+// reservation held for approval whose payer's transfer_out is transactionID:
+// the payer is debited, and the payees of a transfer credited, or the funds
+// reserved. Like approved postings, it keeps the zero floor on payers allowed
+// to go negative. It returns the payer's new balance and the payees it
+// credited; refusals are returned before anything is written.
+func (s *Service) Approve(ctx context.Context, tx store.Tx, customerID, transactionID uuid.UUID) (balance float64, credited []uuid.UUID, err error) {
+	leg, err := tx.GetTransaction(ctx, customerID, transactionID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil, ErrNotAwaitingApproval
+		}
+		return 0, nil, err
+	}
+	if leg.Type != "transfer_out" || leg.Status != StatusPendingApproval {
+		return 0, nil, ErrNotAwaitingApproval
+	}
+	transfers, err := tx.LockTransactionTransfers(ctx, transactionID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(transfers) == 0 {
+		return 0, nil, ErrNotAwaitingApproval
+	}
+	payees := make([]uuid.UUID, len(transfers))
+	for i, t := range transfers {
+		if t.Status != TransferPendingApproval {
+			return 0, nil, ErrNotAwaitingApproval
+		}
+		payees[i] = t.ToCustomerID
+	}
+
+	accounts, err := lockParties(ctx, tx, customerID, payees...)
+	if err != nil {
+		return 0, nil, err
+	}
+	payer := accounts[customerID]
+	payer.AllowNegative = false
+	if balance, err = Apply(payer, txtype.Debit, leg.Amount); err != nil {
+		return 0, nil, err
+	}
+	reserving := transfers[0].ApprovedStatus == TransferReserved
+	if !reserving {
+		for _, t := range transfers {
+			account := accounts[t.ToCustomerID]
+			if account.Balance, err = Apply(account, txtype.Credit, t.Amount); err != nil {
+				return 0, nil, err
+			}
+			accounts[t.ToCustomerID] = account
+		}
+	}
+
+	if err := tx.SetBalance(ctx, customerID, balance); err != nil {
+		return 0, nil, err
+	}
+	if err := tx.SetTransactionStatus(ctx, transactionID, StatusPosted); err != nil {
+		return 0, nil, err
+	}
+	if reserving {
+		t := transfers[0]
+		if err := tx.SetTransferStatus(ctx, t.ID, TransferReserved); err != nil {
+			return 0, nil, err
+		}
+		return balance, nil, s.rules.Reserved(ctx, tx, Reservation{
+			TransferID:     t.ID,
+			FromCustomerID: t.FromCustomerID,
+			ToCustomerID:   t.ToCustomerID,
+			Amount:         t.Amount,
+			Reference:      t.Reference,
+			Status:         TransferReserved,
+			Balance:        balance,
+			Previous:       payer.Balance,
+		})
+	}
+
+	for _, t := range transfers {
+		if err := tx.SetTransferStatus(ctx, t.ID, TransferCompleted); err != nil {
+			return 0, nil, err
+		}
+		if err := tx.InsertTransaction(ctx, &store.Transaction{
+			ID:         uuid.New(),
+			CustomerID: t.ToCustomerID,
+			Type:       "transfer_in",
+			Amount:     t.Amount,
+			Status:     StatusPosted,
+			TransferID: &t.ID,
+			BatchID:    t.BatchID,
+		}); err != nil {
+			return 0, nil, err
+		}
+	}
+	for _, id := range payees {
+		if err := tx.SetBalance(ctx, id, accounts[id].Balance); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if batchID := transfers[0].BatchID; batchID != nil {
+		split := Split{FromCustomerID: customerID, Amount: leg.Amount}
+		result := SplitResult{BatchID: *batchID, Status: TransferCompleted, FromBalance: balance, FromPrevious: payer.Balance}
+		for _, t := range transfers {
+			split.Credits = append(split.Credits, SplitCredit{ToCustomerID: t.ToCustomerID, Amount: t.Amount, Reference: t.Reference})
+			result.Transfers = append(result.Transfers, SplitTransfer{
+				TransferID:   t.ID,
+				ToCustomerID: t.ToCustomerID,
+				Amount:       t.Amount,
+				Reference:    t.Reference,
+				ToBalance:    accounts[t.ToCustomerID].Balance,
+			})
+		}
+		return balance, payees, s.rules.SplitTransferred(ctx, tx, split, result)
+	}
+	t := transfers[0]
+	return balance, payees, s.rules.Transferred(ctx, tx, Transfer{
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+	}, TransferResult{
+		TransferID:   t.ID,
+		Status:       TransferCompleted,
+		FromBalance:  balance,
+		ToBalance:    accounts[t.ToCustomerID].Balance,
+		FromPrevious: payer.Balance,
+	})
+}
+
+// Settle credits a reservation's payee with the reserved funds within tx,
+// recording a transfer_in, and marks it settled. A credit Apply refuses is
+// returned before anything is written; the reservation stays open and may
+// still be released.
+func (s *Service) Settle(ctx context.Context, tx store.Tx, transferID uuid.UUID) (Reservation, error) {
+	r, err := lockReservation(ctx, tx, transferID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	payee, err := tx.LockCustomer(ctx, r.ToCustomerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return Reservation{}, ErrPayeeNotFound
+		}
+		return Reservation{}, err
+	}
+	r.Status, r.Previous = TransferSettled, payee.Balance
+	if r.Balance, err = Apply(payee, txtype.Credit, r.Amount); err != nil {
+		return Reservation{}, err
+	}
+	if err := s.closeReservation(ctx, tx, r, r.ToCustomerID, "transfer_in"); err != nil {
+		return Reservation{}, err
+	}
+	return r, nil
+}
+
+// Release returns a reservation's funds to the payer within tx, recording a
+// transfer_release, and marks it released
+func (s *Service) Release(ctx context.Context, tx store.Tx, transferID uuid.UUID) (Reservation, error) {
+	r, err := lockReservation(ctx, tx, transferID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	payer, err := tx.LockCustomer(ctx, r.FromCustomerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return Reservation{}, ErrPayerNotFound
+		}
+		return Reservation{}, err
+	}
+	// Giving back what was taken must not be refused, even when the payer
+	// has since paid off a credit account
+	payer.AllowNegative = true
+	r.Status, r.Previous = TransferReleased, payer.Balance
+	if r.Balance, err = Apply(payer, txtype.Credit, r.Amount); err != nil {
+		return Reservation{}, err
+	}
+	if err := s.closeReservation(ctx, tx, r, r.FromCustomerID, "transfer_release"); err != nil {
+		return Reservation{}, err
+	}
+	return r, nil
+}
+
+// lockReservation locks an open reservation
+func lockReservation(ctx context.Context, tx store.Tx, transferID uuid.UUID) (Reservation, error) {
+	t, err := tx.LockTransfer(ctx, transferID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return Reservation{}, ErrReservationNotFound
+		}
+		return Reservation{}, err
+	}
+	switch t.Status {
+	case TransferReserved:
+	case TransferSettled, TransferReleased:
+		return Reservation{}, ErrReservationClosed
+	default:
+		return Reservation{}, ErrReservationNotFound
+	}
+	return Reservation{
+		TransferID:     t.ID,
+		FromCustomerID: t.FromCustomerID,
+		ToCustomerID:   t.ToCustomerID,
+		Amount:         t.Amount,
+		Reference:      t.Reference,
+	}, nil
+}
+
+// closeReservation writes the final leg of a reservation and its status
+func (s *Service) closeReservation(ctx context.Context, tx store.Tx, r Reservation, customerID uuid.UUID, txType string) error {
+	if err := s.postReservationLeg(ctx, tx, r, customerID, txType); err != nil {
+		return err
+	}
+	if err := tx.SetTransferStatus(ctx, r.TransferID, r.Status); err != nil {
+		return err
+	}
+	return s.rules.Reserved(ctx, tx, r)
+}
+
+// postReservationLeg sets the balance a reservation step moved and records
+// its transaction
+func (s *Service) postReservationLeg(ctx context.Context, tx store.Tx, r Reservation, customerID uuid.UUID, txType string) error {
+	if err := tx.SetBalance(ctx, customerID, r.Balance); err != nil {
+		return err
+	}
+	return tx.InsertTransaction(ctx, &store.Transaction{
+		ID:         uuid.New(),
+		CustomerID: customerID,
+		Type:       txType,
+		Amount:     r.Amount,
+		Status:     StatusPosted,
+		TransferID: &r.TransferID,
+	})
+}
+
+// lockParties locks a payer and payees in ID order, each once, returning
+// their accounts by ID
+func lockParties(ctx context.Context, tx store.Tx, payer uuid.UUID, payees ...uuid.UUID) (map[uuid.UUID]store.Customer, error) {
+	ids := []uuid.UUID{payer}
+	seen := map[uuid.UUID]bool{payer: true}
+	for _, id := range payees {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return strings.Compare(ids[i].String(), ids[j].String()) < 0
+	})
+
+	accounts := make(map[uuid.UUID]store.Customer, len(ids))
+	for _, id := range ids {
+		account, err := tx.LockCustomer(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				if id == payer {
+					return nil, ErrPayerNotFound
+				}
+				return nil, ErrPayeeNotFound
+			}
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
@@ -0,0 +1,698 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ledger-service/store"
+	"ledger-service/txtype"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCustomer(t *testing.T, s store.Store, balance float64) uuid.UUID {
+	t.Helper()
+	c := store.Customer{ID: uuid.New(), Name: "Test", Balance: balance, AccountType: "checking", Timezone: "UTC"}
+	require.NoError(t, s.CreateCustomer(context.Background(), &c))
+	return c.ID
+}
+
+// holdRules holds every posting and refuses amounts over a limit
+type holdRules struct {
+	NoRules
+	limit float64
+}
+
+func (r holdRules) Limit(_ context.Context, _ store.Tx, p Posting, _ store.Customer) error {
+	if p.Amount > r.limit {
+		return &ViolationError{Message: "over limit"}
+	}
+	return nil
+}
+
+func (holdRules) Screen(context.Context, store.Tx, Posting, store.Customer) (Screening, error) {
+	return Screening{Status: StatusHeld}, nil
+}
+
+func TestPost(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	id := newCustomer(t, s, 100)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: id, Type: "debit", Amount: 30})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPosted, result.Status)
+	assert.Equal(t, float64(70), result.Balance)
+	assert.Equal(t, float64(100), result.PreviousBalance)
+
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "debit", Amount: 500})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "bogus", Amount: 5})
+	assert.ErrorIs(t, err, ErrUnknownTransactionType)
+	_, err = svc.Post(ctx, Posting{CustomerID: uuid.New(), Type: "credit", Amount: 5})
+	assert.ErrorIs(t, err, ErrCustomerNotFound)
+
+	balance, err := svc.Balance(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, float64(70), balance.Amount)
+	count, _ := s.CountTransactions(ctx, id, store.TransactionFilter{})
+	assert.Equal(t, 1, count, "failed postings write nothing")
+
+	// A posting given an ID is recorded under it
+	given := uuid.New()
+	result, err = svc.Post(ctx, Posting{ID: given, CustomerID: id, Type: "credit", Amount: 5})
+	require.NoError(t, err)
+	assert.Equal(t, given, result.TransactionID)
+	_, err = s.GetTransaction(ctx, id, given)
+	assert.NoError(t, err)
+}
+
+func TestPostDryRun(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	posted := false
+	svc := New(s, txtype.Default(), nil).WithHooks(Hooks{
+		PostPosting: []PostPostingHook{func(context.Context, Posting, Result) error {
+			posted = true
+			return nil
+		}},
+	})
+	id := newCustomer(t, s, 100)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: id, Type: "debit", Amount: 30, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPosted, result.Status)
+	assert.Equal(t, float64(70), result.Balance)
+	assert.Equal(t, float64(100), result.PreviousBalance)
+	assert.Equal(t, uuid.Nil, result.TransactionID)
+	assert.False(t, posted, "post-posting hooks only see real postings")
+
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "debit", Amount: 500, DryRun: true})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	balance, err := svc.Balance(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), balance.Amount)
+	count, _ := s.CountTransactions(ctx, id, store.TransactionFilter{})
+	assert.Zero(t, count, "dry runs write nothing")
+}
+
+func TestPostWithRules(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), holdRules{limit: 50})
+	id := newCustomer(t, s, 100)
+
+	_, err := svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 80})
+	var violation *ViolationError
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, "over limit", violation.Message)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 20})
+	require.NoError(t, err)
+	assert.Equal(t, StatusHeld, result.Status)
+	assert.Equal(t, float64(100), result.Balance, "held postings leave the balance alone")
+	count, _ := s.CountTransactions(ctx, id, store.TransactionFilter{})
+	assert.Equal(t, 1, count)
+}
+
+func TestPostWithHooks(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	var calls []string
+	svc := New(s, txtype.Default(), nil).WithHooks(Hooks{
+		PrePosting: []PrePostingHook{
+			func(_ context.Context, p Posting, account store.Customer) error {
+				calls = append(calls, "first")
+				if p.Amount == 13 {
+					return &ViolationError{Message: "unlucky amount"}
+				}
+				return nil
+			},
+			func(context.Context, Posting, store.Customer) error {
+				calls = append(calls, "second")
+				return nil
+			},
+		},
+		PostPosting: []PostPostingHook{
+			func(context.Context, Posting, Result) error {
+				calls = append(calls, "export")
+				return errors.New("export unavailable")
+			},
+			func(_ context.Context, _ Posting, r Result) error {
+				calls = append(calls, "after "+r.Status)
+				return nil
+			},
+		},
+	})
+	id := newCustomer(t, s, 100)
+
+	_, err := svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 13})
+	var violation *ViolationError
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, []string{"first"}, calls, "a failing pre-posting hook stops the rest")
+	count, _ := s.CountTransactions(ctx, id, store.TransactionFilter{})
+	assert.Zero(t, count)
+
+	calls = nil
+	result, err := svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 20})
+	require.NoError(t, err, "post-posting errors do not fail the posting")
+	assert.Equal(t, float64(120), result.Balance)
+	assert.Equal(t, []string{"first", "second", "export", "after posted"}, calls)
+}
+
+func TestUniqueReference(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	id := newCustomer(t, s, 100)
+	other := newCustomer(t, s, 100)
+
+	first, err := svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 10, Reference: "INV-1234", UniqueReference: true})
+	require.NoError(t, err)
+
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 10, Reference: "INV-1234", UniqueReference: true})
+	var duplicate *DuplicateReferenceError
+	require.ErrorAs(t, err, &duplicate)
+	assert.Equal(t, first.TransactionID, duplicate.TransactionID)
+	assert.ErrorIs(t, err, ErrDuplicateReference)
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 10, Reference: "INV-1234", UniqueReference: true, DryRun: true})
+	assert.ErrorIs(t, err, ErrDuplicateReference, "dry runs report the duplicate too")
+
+	// The reference is only unique per customer, and only among postings
+	// asking for it
+	_, err = svc.Post(ctx, Posting{CustomerID: other, Type: "credit", Amount: 10, Reference: "INV-1234", UniqueReference: true})
+	assert.NoError(t, err)
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 10, Reference: "INV-1234"})
+	assert.NoError(t, err)
+
+	balance, _ := svc.Balance(ctx, id)
+	assert.Equal(t, float64(120), balance.Amount)
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	from := newCustomer(t, s, 100)
+	to := newCustomer(t, s, 5)
+
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	result, err := svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: to, Amount: 40})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, float64(60), result.FromBalance)
+	assert.Equal(t, float64(45), result.ToBalance)
+	assert.Equal(t, float64(100), result.FromPrevious)
+
+	tx, err = s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: to, Amount: 400})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: uuid.New(), Amount: 1})
+	assert.ErrorIs(t, err, ErrPayeeNotFound)
+	_, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: uuid.New(), ToCustomerID: to, Amount: 1})
+	assert.ErrorIs(t, err, ErrPayerNotFound)
+	require.NoError(t, tx.Rollback(ctx))
+
+	balance, _ := svc.Balance(ctx, to)
+	assert.Equal(t, float64(45), balance.Amount)
+
+	// Paying oneself would credit the starting balance over the debit
+	tx, err = s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: from, Amount: 10})
+	assert.ErrorIs(t, err, ErrSelfTransfer)
+	_, err = svc.Reserve(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: from, Amount: 10})
+	assert.ErrorIs(t, err, ErrSelfTransfer)
+	require.NoError(t, tx.Commit(ctx))
+	balance, _ = svc.Balance(ctx, from)
+	assert.Equal(t, float64(60), balance.Amount)
+}
+
+func TestSplitTransfer(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	from := newCustomer(t, s, 100)
+	first := newCustomer(t, s, 5)
+	second := newCustomer(t, s, 0)
+
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	result, err := svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 60, Reference: "Payout", Credits: []SplitCredit{
+		{ToCustomerID: first, Amount: 30.1},
+		{ToCustomerID: second, Amount: 19.7, Reference: "Order 7"},
+		{ToCustomerID: first, Amount: 10.2},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, float64(40), result.FromBalance)
+	assert.Equal(t, float64(100), result.FromPrevious)
+	require.Len(t, result.Transfers, 3)
+	assert.Equal(t, "Payout", result.Transfers[0].Reference)
+	assert.Equal(t, "Order 7", result.Transfers[1].Reference)
+	assert.InDelta(t, 45.3, result.Transfers[2].ToBalance, 1e-9, "a repeated recipient is credited again")
+
+	// The payer sees one debit for the whole amount, tagged with the batch
+	debits, err := s.ListTransactions(ctx, from, store.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, debits, 1)
+	assert.Equal(t, "transfer_out", debits[0].Type)
+	assert.Equal(t, float64(60), debits[0].Amount)
+	assert.Equal(t, &result.BatchID, debits[0].BatchID)
+	credits, err := s.ListTransactions(ctx, first, store.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, credits, 2)
+	assert.Equal(t, &result.BatchID, credits[0].BatchID)
+
+	balance, _ := svc.Balance(ctx, second)
+	assert.InDelta(t, 19.7, balance.Amount, 1e-9)
+
+	tx, err = s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 10, Credits: []SplitCredit{{ToCustomerID: first, Amount: 9}}})
+	assert.ErrorIs(t, err, ErrSplitTotal)
+	_, err = svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 10})
+	assert.ErrorIs(t, err, ErrInvalidSplit)
+	_, err = svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 10, Credits: []SplitCredit{{ToCustomerID: from, Amount: 10}}})
+	assert.ErrorIs(t, err, ErrInvalidSplit)
+	_, err = svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 400, Credits: []SplitCredit{{ToCustomerID: first, Amount: 400}}})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 2, Credits: []SplitCredit{{ToCustomerID: first, Amount: 1}, {ToCustomerID: uuid.New(), Amount: 1}}})
+	assert.ErrorIs(t, err, ErrPayeeNotFound)
+	require.NoError(t, tx.Rollback(ctx))
+
+	euro := store.Customer{ID: uuid.New(), Name: "Euro", AccountType: "checking", Timezone: "UTC", Currency: "EUR"}
+	require.NoError(t, s.CreateCustomer(ctx, &euro))
+	tx, err = s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 2, Credits: []SplitCredit{{ToCustomerID: first, Amount: 1}, {ToCustomerID: euro.ID, Amount: 1}}})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	require.NoError(t, tx.Rollback(ctx))
+
+	balance, _ = svc.Balance(ctx, from)
+	assert.Equal(t, float64(40), balance.Amount, "failed splits leave the payer untouched")
+}
+
+func TestReservation(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	from := newCustomer(t, s, 100)
+	to := newCustomer(t, s, 10)
+
+	reserve := func(amount float64) (Reservation, error) {
+		tx, err := s.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+		r, err := svc.Reserve(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: to, Amount: amount, Reference: "Order 9"})
+		if err == nil {
+			require.NoError(t, tx.Commit(ctx))
+		}
+		return r, err
+	}
+	step := func(run func(context.Context, store.Tx, uuid.UUID) (Reservation, error), id uuid.UUID) (Reservation, error) {
+		tx, err := s.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+		r, err := run(ctx, tx, id)
+		if err == nil {
+			require.NoError(t, tx.Commit(ctx))
+		}
+		return r, err
+	}
+
+	// Reserving debits the payer at once; the payee waits for settlement
+	held, err := reserve(60)
+	require.NoError(t, err)
+	assert.Equal(t, TransferReserved, held.Status)
+	assert.Equal(t, float64(40), held.Balance)
+	balance, _ := svc.Balance(ctx, to)
+	assert.Equal(t, float64(10), balance.Amount)
+	_, err = reserve(50)
+	assert.ErrorIs(t, err, ErrInsufficientBalance, "reserved funds cannot be spent twice")
+
+	settled, err := step(svc.Settle, held.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferSettled, settled.Status)
+	assert.Equal(t, float64(70), settled.Balance)
+	assert.Equal(t, "Order 9", settled.Reference)
+	_, err = step(svc.Release, held.TransferID)
+	assert.ErrorIs(t, err, ErrReservationClosed)
+
+	// Releasing returns the funds to the payer
+	held, err = reserve(25)
+	require.NoError(t, err)
+	released, err := step(svc.Release, held.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferReleased, released.Status)
+	assert.Equal(t, float64(40), released.Balance)
+	_, err = step(svc.Settle, held.TransferID)
+	assert.ErrorIs(t, err, ErrReservationClosed)
+	transfer, err := s.GetTransfer(ctx, held.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferReleased, transfer.Status)
+
+	history, err := s.ListTransactions(ctx, from, store.ListOptions{})
+	require.NoError(t, err)
+	var types []string
+	for _, tr := range history {
+		types = append(types, tr.Type)
+	}
+	assert.ElementsMatch(t, []string{"transfer_out", "transfer_out", "transfer_release"}, types)
+
+	_, err = step(svc.Settle, uuid.New())
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	plain, err := svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: to, Amount: 1})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	_, err = step(svc.Release, plain.TransferID)
+	assert.ErrorIs(t, err, ErrReservationNotFound, "completed transfers are not reservations")
+}
+
+// approvalRules refuses one payer and holds payers' debits over a limit for
+// approval
+type approvalRules struct {
+	NoRules
+	limit   float64
+	refused uuid.UUID
+}
+
+func (r approvalRules) ScreenPayer(_ context.Context, _ store.Tx, p Posting, _ store.Customer) (Screening, error) {
+	if p.CustomerID == r.refused {
+		return Screening{}, &ViolationError{Message: "account frozen"}
+	}
+	if p.Amount > r.limit {
+		return Screening{Status: StatusPendingApproval}, nil
+	}
+	return Screening{}, nil
+}
+
+func TestPayerScreening(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	from := newCustomer(t, s, 100)
+	to := newCustomer(t, s, 10)
+	other := newCustomer(t, s, 0)
+	frozen := newCustomer(t, s, 100)
+	svc := New(s, txtype.Default(), approvalRules{limit: 50, refused: frozen})
+
+	run := func(step func(store.Tx) error) error {
+		tx, err := s.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+		if err := step(tx); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+	approve := func(id uuid.UUID) (float64, error) {
+		var balance float64
+		err := run(func(tx store.Tx) (err error) {
+			balance, _, err = svc.Approve(ctx, tx, from, id)
+			return err
+		})
+		return balance, err
+	}
+	pendingDebit := func() uuid.UUID {
+		history, err := s.ListTransactions(ctx, from, store.ListOptions{})
+		require.NoError(t, err)
+		for _, tr := range history {
+			if tr.Status == StatusPendingApproval {
+				return tr.ID
+			}
+		}
+		t.Fatal("no transfer_out awaiting approval")
+		return uuid.Nil
+	}
+	balanceOf := func(id uuid.UUID) float64 {
+		balance, err := svc.Balance(ctx, id)
+		require.NoError(t, err)
+		return balance.Amount
+	}
+
+	// A refused payer writes nothing, whichever way the money leaves
+	var violation *ViolationError
+	err := run(func(tx store.Tx) error {
+		_, err := svc.Transfer(ctx, tx, Transfer{FromCustomerID: frozen, ToCustomerID: to, Amount: 10})
+		return err
+	})
+	assert.ErrorAs(t, err, &violation)
+	err = run(func(tx store.Tx) error {
+		_, err := svc.SplitTransfer(ctx, tx, Split{FromCustomerID: frozen, Amount: 10, Credits: []SplitCredit{{ToCustomerID: to, Amount: 10}}})
+		return err
+	})
+	assert.ErrorAs(t, err, &violation)
+	err = run(func(tx store.Tx) error {
+		_, err := svc.Reserve(ctx, tx, Transfer{FromCustomerID: frozen, ToCustomerID: to, Amount: 10})
+		return err
+	})
+	assert.ErrorAs(t, err, &violation)
+	count, _ := s.CountTransactions(ctx, frozen, store.TransactionFilter{})
+	assert.Zero(t, count)
+	assert.Equal(t, float64(100), balanceOf(frozen))
+
+	// A held transfer moves nothing until it is approved
+	var held TransferResult
+	require.NoError(t, run(func(tx store.Tx) (err error) {
+		held, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: to, Amount: 60})
+		return err
+	}))
+	assert.Equal(t, TransferPendingApproval, held.Status)
+	assert.Equal(t, float64(100), held.FromBalance)
+	assert.Equal(t, float64(100), balanceOf(from))
+	assert.Equal(t, float64(10), balanceOf(to))
+	count, _ = s.CountTransactions(ctx, to, store.TransactionFilter{})
+	assert.Zero(t, count, "the payee gets no leg while the transfer waits")
+	debit := pendingDebit()
+	balance, err := approve(debit)
+	require.NoError(t, err)
+	assert.Equal(t, float64(40), balance)
+	assert.Equal(t, float64(70), balanceOf(to))
+	transfer, err := s.GetTransfer(ctx, held.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferCompleted, transfer.Status)
+	_, err = approve(debit)
+	assert.ErrorIs(t, err, ErrNotAwaitingApproval)
+
+	// Held funds stay spendable, so approval checks the balance again,
+	// without letting the payer go negative
+	credit := func(amount float64) {
+		_, err := svc.Post(ctx, Posting{CustomerID: from, Type: "credit", Amount: amount})
+		require.NoError(t, err)
+	}
+	credit(60)
+	require.NoError(t, run(func(tx store.Tx) error {
+		_, err := svc.SplitTransfer(ctx, tx, Split{FromCustomerID: from, Amount: 80, Credits: []SplitCredit{
+			{ToCustomerID: to, Amount: 50},
+			{ToCustomerID: other, Amount: 30},
+		}})
+		return err
+	}))
+	require.NoError(t, run(func(tx store.Tx) error {
+		_, err := svc.Transfer(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: to, Amount: 40})
+		return err
+	}))
+	_, err = approve(pendingDebit())
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	credit(40)
+	balance, err = approve(pendingDebit())
+	require.NoError(t, err)
+	assert.Equal(t, float64(20), balance)
+	assert.Equal(t, float64(160), balanceOf(to))
+	assert.Equal(t, float64(30), balanceOf(other))
+
+	// An approved reservation is reserved, and settles as usual
+	credit(80)
+	var reservation Reservation
+	require.NoError(t, run(func(tx store.Tx) (err error) {
+		reservation, err = svc.Reserve(ctx, tx, Transfer{FromCustomerID: from, ToCustomerID: other, Amount: 70})
+		return err
+	}))
+	assert.Equal(t, TransferPendingApproval, reservation.Status)
+	assert.Equal(t, float64(100), reservation.Balance)
+	err = run(func(tx store.Tx) error {
+		_, err := svc.Settle(ctx, tx, reservation.TransferID)
+		return err
+	})
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+	balance, err = approve(pendingDebit())
+	require.NoError(t, err)
+	assert.Equal(t, float64(30), balance)
+	transfer, err = s.GetTransfer(ctx, reservation.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferReserved, transfer.Status)
+	require.NoError(t, run(func(tx store.Tx) error {
+		_, err := svc.Settle(ctx, tx, reservation.TransferID)
+		return err
+	}))
+	assert.Equal(t, float64(100), balanceOf(other))
+}
+
+func TestAllowNegative(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	settlement := store.Customer{ID: uuid.New(), Name: "Settlement", Balance: 10, AccountType: "checking", Timezone: "UTC", AllowNegative: true}
+	require.NoError(t, s.CreateCustomer(ctx, &settlement))
+	payee := newCustomer(t, s, 0)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: settlement.ID, Type: "debit", Amount: 25})
+	require.NoError(t, err)
+	assert.Equal(t, float64(-15), result.Balance)
+	assert.True(t, result.Overdrawn)
+
+	result, err = svc.Post(ctx, Posting{CustomerID: settlement.ID, Type: "credit", Amount: 5})
+	require.NoError(t, err)
+	assert.False(t, result.Overdrawn, "credits never overdraw")
+
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	transfer, err := svc.Transfer(ctx, tx, Transfer{FromCustomerID: settlement.ID, ToCustomerID: payee, Amount: 40})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, float64(-50), transfer.FromBalance)
+	assert.True(t, transfer.Overdrawn)
+
+	// The payee has no such flag
+	tx, err = s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: payee, ToCustomerID: settlement.ID, Amount: 41})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	require.NoError(t, tx.Rollback(ctx))
+}
+
+func TestOverdraft(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	c := store.Customer{ID: uuid.New(), Name: "Test", Balance: 100, AccountType: "checking", Timezone: "UTC", Overdraft: 250}
+	require.NoError(t, s.CreateCustomer(ctx, &c))
+
+	_, err := svc.Post(ctx, Posting{CustomerID: c.ID, Type: "debit", Amount: 351})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: c.ID, Type: "debit", Amount: 350})
+	require.NoError(t, err)
+	assert.Equal(t, float64(-250), result.Balance)
+	assert.True(t, result.Overdrawn)
+
+	// A credit account's floor stays at zero
+	credit := store.Customer{BalanceType: store.BalanceCredit, CreditLimit: 500, Balance: 100, Overdraft: 250}
+	_, err = Apply(credit, txtype.Credit, 150)
+	assert.ErrorIs(t, err, ErrOverpayment)
+}
+
+func TestCreditAccount(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	card := store.Customer{ID: uuid.New(), Name: "Card", Balance: 100, AccountType: "checking", Timezone: "UTC",
+		BalanceType: store.BalanceCredit, CreditLimit: 500}
+	require.NoError(t, s.CreateCustomer(ctx, &card))
+	deposit := newCustomer(t, s, 1000)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: card.ID, Type: "purchase", Amount: 150})
+	require.NoError(t, err)
+	assert.Equal(t, float64(250), result.Balance, "debits add to what is owed")
+
+	_, err = svc.Post(ctx, Posting{CustomerID: card.ID, Type: "purchase", Amount: 251})
+	assert.ErrorIs(t, err, ErrInsufficientBalance, "debits stop at the credit limit")
+
+	result, err = svc.Post(ctx, Posting{CustomerID: card.ID, Type: "refund", Amount: 50})
+	require.NoError(t, err)
+	assert.Equal(t, float64(200), result.Balance, "credits repay")
+
+	_, err = svc.Post(ctx, Posting{CustomerID: card.ID, Type: "credit", Amount: 201})
+	assert.ErrorIs(t, err, ErrOverpayment)
+
+	// A repayment from a deposit account lowers both balances
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	transfer, err := svc.Transfer(ctx, tx, Transfer{FromCustomerID: deposit, ToCustomerID: card.ID, Amount: 200})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, float64(800), transfer.FromBalance)
+	assert.Equal(t, float64(0), transfer.ToBalance)
+
+	// Paying out of the credit account borrows against it
+	tx, err = s.Begin(ctx)
+	require.NoError(t, err)
+	transfer, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: card.ID, ToCustomerID: deposit, Amount: 300})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+	assert.Equal(t, float64(300), transfer.FromBalance)
+	assert.False(t, transfer.Overdrawn)
+}
+
+func TestBaseCurrency(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil)
+	euro := store.Customer{ID: uuid.New(), Name: "Euro", Balance: 100, AccountType: "checking", Timezone: "UTC", Currency: "EUR"}
+	require.NoError(t, s.CreateCustomer(ctx, &euro))
+	dollar := newCustomer(t, s, 100)
+
+	balance, err := svc.Balance(ctx, dollar)
+	require.NoError(t, err)
+	assert.Equal(t, store.Balance{Amount: 100, Currency: store.DefaultCurrency}, balance, "accounts default to USD")
+
+	result, err := svc.Post(ctx, Posting{CustomerID: euro.ID, Type: "debit", Amount: 30, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, float64(70), result.Balance)
+	_, err = svc.Post(ctx, Posting{CustomerID: euro.ID, Type: "debit", Amount: 30, Currency: "USD"})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	balance, err = svc.Balance(ctx, euro.ID)
+	require.NoError(t, err)
+	assert.Equal(t, store.Balance{Amount: 70, Currency: "EUR"}, balance)
+
+	tx, err := s.Begin(ctx)
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, tx, Transfer{FromCustomerID: euro.ID, ToCustomerID: dollar, Amount: 10})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	require.NoError(t, tx.Rollback(ctx))
+}
+
+func TestConvertedPosting(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	svc := New(s, txtype.Default(), nil).WithConverter(func(_ context.Context, amount float64, from, to string) (Conversion, error) {
+		if from != "EUR" || to != "USD" {
+			return Conversion{}, errors.New("rate unavailable")
+		}
+		return Conversion{Amount: amount * 1.5, Rate: 1.5, Provider: "stub", Rounding: "half_even"}, nil
+	})
+	id := newCustomer(t, s, 100)
+
+	result, err := svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 20, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, float64(130), result.Balance)
+	assert.Equal(t, float64(30), result.Amount)
+	original := &store.Original{Amount: 20, Currency: "EUR", Rate: 1.5, Provider: "stub", Rounding: "half_even"}
+	assert.Equal(t, original, result.Original)
+
+	stored, err := s.GetTransaction(ctx, id, result.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(30), stored.Amount)
+	assert.Equal(t, original, stored.Original)
+
+	// Postings in the base currency are not converted
+	result, err = svc.Post(ctx, Posting{CustomerID: id, Type: "debit", Amount: 10, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Nil(t, result.Original)
+	assert.Equal(t, float64(120), result.Balance)
+
+	_, err = svc.Post(ctx, Posting{CustomerID: id, Type: "credit", Amount: 10, Currency: "GBP"})
+	assert.ErrorIs(t, err, ErrConversion)
+	_, err = svc.Post(ctx, Posting{CustomerID: uuid.New(), Type: "credit", Amount: 10, Currency: "EUR"})
+	assert.ErrorIs(t, err, ErrCustomerNotFound)
+}
@@ -0,0 +1,113 @@
+// Package loan works out amortizing loan schedules: the equal installments
+// that repay a principal at a fixed rate, and the interest accrued between
+// them. Amounts round to the loan currency's minor unit by the configured
+// rounding policy, half up unless it says otherwise.
+package loan
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"ledger-service/money"
+	"ledger-service/schedule"
+)
+
+// Terms describes a loan to amortize
+type Terms struct {
+	Principal float64
+	// AnnualRate is the nominal yearly rate, e.g. 0.065 for 6.5%
+	AnnualRate   float64
+	Installments int
+	Frequency    schedule.Frequency
+	// FirstDue is the due date of the first installment; later installments
+	// follow at Frequency, monthly ones staying on its day of month
+	FirstDue time.Time
+	Currency string
+	// Rounding rounds every amount in Currency
+	Rounding money.Policy
+}
+
+// Installment is one scheduled repayment
+type Installment struct {
+	Number    int
+	DueDate   time.Time
+	Payment   float64
+	Principal float64
+	Interest  float64
+}
+
+// Validate reports whether t can be amortized
+func (t Terms) Validate() error {
+	if t.Principal <= 0 {
+		return fmt.Errorf("principal must be greater than 0")
+	}
+	if t.AnnualRate < 0 || t.AnnualRate >= 1 {
+		return fmt.Errorf("annual_rate must be at least 0 and below 1")
+	}
+	if t.Installments < 1 || t.Installments > 600 {
+		return fmt.Errorf("installments must be between 1 and 600")
+	}
+	if !schedule.Valid(t.Frequency) {
+		return fmt.Errorf("frequency must be daily, weekly or monthly")
+	}
+	return nil
+}
+
+// PeriodsPerYear is how many installments of frequency f fall in a year
+func PeriodsPerYear(f schedule.Frequency) float64 {
+	switch f {
+	case schedule.Daily:
+		return 365
+	case schedule.Weekly:
+		return 52
+	default:
+		return 12
+	}
+}
+
+// Schedule amortizes t into equal installments, numbered from 1. Each
+// installment's interest is the periodic rate on the balance still owed and
+// the rest of the payment repays principal; the last installment repays
+// whatever principal is left, absorbing the rounding of the others.
+func Schedule(t Terms) []Installment {
+	round := func(x float64) float64 {
+		rounded, _ := t.Rounding.Round(x, t.Currency)
+		return rounded
+	}
+
+	n := t.Installments
+	r := t.AnnualRate / PeriodsPerYear(t.Frequency)
+	payment := t.Principal / float64(n)
+	if r > 0 {
+		payment = t.Principal * r / (1 - math.Pow(1+r, -float64(n)))
+	}
+	payment = round(payment)
+
+	installments := make([]Installment, n)
+	balance := t.Principal
+	due := t.FirstDue
+	for i := range installments {
+		interest := round(balance * r)
+		principal := round(payment - interest)
+		if i == n-1 || principal > balance {
+			principal = round(balance)
+		}
+		installments[i] = Installment{Number: i + 1, DueDate: due, Payment: round(principal + interest), Principal: principal, Interest: interest}
+		balance = round(balance - principal)
+		due = schedule.Next(due, t.Frequency, t.FirstDue.Day())
+	}
+	return installments
+}
+
+// Accrued is the simple interest on outstanding at annualRate from one date
+// to another, counting actual days over a 365-day year, rounded in currency
+// by rounding
+func Accrued(outstanding, annualRate float64, from, to time.Time, currency string, rounding money.Policy) float64 {
+	days := schedule.Day(to).Sub(schedule.Day(from)).Hours() / 24
+	if days <= 0 {
+		return 0
+	}
+	interest, _ := rounding.Round(outstanding*annualRate*days/365, currency)
+	return interest
+}
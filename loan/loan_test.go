@@ -0,0 +1,96 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"ledger-service/money"
+	"ledger-service/schedule"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestSchedule(t *testing.T) {
+	installments := Schedule(Terms{
+		Principal:    1000,
+		AnnualRate:   0.12,
+		Installments: 3,
+		Frequency:    schedule.Monthly,
+		FirstDue:     date(2025, 1, 31),
+		Currency:     "USD",
+	})
+	require.Len(t, installments, 3)
+
+	assert.Equal(t, Installment{Number: 1, DueDate: date(2025, 1, 31), Payment: 340.02, Principal: 330.02, Interest: 10}, installments[0])
+	assert.Equal(t, Installment{Number: 2, DueDate: date(2025, 2, 28), Payment: 340.02, Principal: 333.32, Interest: 6.7}, installments[1])
+	// The last installment repays what is left, absorbing the rounding
+	assert.Equal(t, Installment{Number: 3, DueDate: date(2025, 3, 31), Payment: 340.03, Principal: 336.66, Interest: 3.37}, installments[2])
+	assert.Equal(t, 340.03, installments[2].Payment)
+
+	var repaid float64
+	for _, i := range installments {
+		repaid += i.Principal
+	}
+	assert.InDelta(t, 1000, repaid, 1e-9)
+}
+
+func TestScheduleWithoutInterest(t *testing.T) {
+	installments := Schedule(Terms{
+		Principal:    100,
+		Installments: 3,
+		Frequency:    schedule.Weekly,
+		FirstDue:     date(2025, 5, 5),
+	})
+	require.Len(t, installments, 3)
+	assert.Equal(t, 33.33, installments[0].Payment)
+	assert.Equal(t, 33.33, installments[1].Payment)
+	assert.Equal(t, 33.34, installments[2].Payment)
+	assert.Equal(t, date(2025, 5, 19), installments[2].DueDate)
+}
+
+func TestAccrued(t *testing.T) {
+	assert.Equal(t, 9.86, Accrued(1000, 0.12, date(2025, 1, 1), date(2025, 1, 31), "USD", money.Policy{}))
+	assert.Equal(t, 0.0, Accrued(1000, 0.12, date(2025, 1, 31), date(2025, 1, 31), "USD", money.Policy{}))
+	assert.Equal(t, 0.0, Accrued(1000, 0.12, date(2025, 2, 1), date(2025, 1, 31), "USD", money.Policy{}))
+	// 9.863... rounds by the configured policy, and by currency over it
+	up := money.Policy{Default: money.Up, Currencies: map[string]money.Rounding{"EUR": money.Truncate}}
+	assert.Equal(t, 9.87, Accrued(1000, 0.12, date(2025, 1, 1), date(2025, 1, 31), "USD", up))
+	assert.Equal(t, 9.86, Accrued(1000, 0.12, date(2025, 1, 1), date(2025, 1, 31), "EUR", up))
+}
+
+func TestScheduleRounding(t *testing.T) {
+	installments := Schedule(Terms{
+		Principal:    100,
+		Installments: 3,
+		Frequency:    schedule.Weekly,
+		FirstDue:     date(2025, 5, 5),
+		Currency:     "USD",
+		Rounding:     money.Policy{Default: money.Up},
+	})
+	require.Len(t, installments, 3)
+	assert.Equal(t, 33.34, installments[0].Payment)
+	assert.Equal(t, 33.34, installments[1].Payment)
+	// The last installment still repays exactly what is left
+	assert.Equal(t, 33.32, installments[2].Payment)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Terms{Principal: 500, AnnualRate: 0.05, Installments: 12, Frequency: schedule.Monthly}
+	assert.NoError(t, valid.Validate())
+
+	for name, mutate := range map[string]func(*Terms){
+		"zero principal":    func(t *Terms) { t.Principal = 0 },
+		"negative rate":     func(t *Terms) { t.AnnualRate = -0.01 },
+		"no installments":   func(t *Terms) { t.Installments = 0 },
+		"unknown frequency": func(t *Terms) { t.Frequency = "yearly" },
+	} {
+		terms := valid
+		mutate(&terms)
+		assert.Error(t, terms.Validate(), name)
+	}
+}
@@ -0,0 +1,123 @@
+// Package merkle builds Merkle trees the way Certificate Transparency does
+// (RFC 6962) and proves and verifies that a leaf is included in one, so a
+// single ledger entry can be checked against a published root
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Hash is a SHA-256 digest
+type Hash [32]byte
+
+// String is the hash in hex
+func (h Hash) String() string { return hex.EncodeToString(h[:]) }
+
+// ParseHash reads a hash written in hex
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("invalid hash %q", s)
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// Leaves and interior nodes are hashed with different prefixes, so an
+// interior node can never pass for a leaf
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash hashes a leaf's data
+func LeafHash(data []byte) Hash {
+	return sha256.Sum256(append([]byte{leafPrefix}, data...))
+}
+
+func nodeHash(left, right Hash) Hash {
+	b := make([]byte, 0, 1+2*len(left))
+	b = append(b, nodePrefix)
+	b = append(b, left[:]...)
+	b = append(b, right[:]...)
+	return sha256.Sum256(b)
+}
+
+// Root is the root of the tree over leaves, given as leaf hashes. The
+// root of an empty tree is the hash of nothing.
+func Root(leaves []Hash) Hash {
+	switch len(leaves) {
+	case 0:
+		return sha256.Sum256(nil)
+	case 1:
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return nodeHash(Root(leaves[:k]), Root(leaves[k:]))
+}
+
+// Proof is the audit path of the leaf at index: the sibling hashes from the
+// leaf up that, with the leaf, recompute the root
+func Proof(leaves []Hash, index int) ([]Hash, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf %d is outside a tree of %d", index, len(leaves))
+	}
+	return path(leaves, index), nil
+}
+
+func path(leaves []Hash, index int) []Hash {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if index < k {
+		return append(path(leaves[:k], index), Root(leaves[k:]))
+	}
+	return append(path(leaves[k:], index-k), Root(leaves[:k]))
+}
+
+// split is the largest power of two smaller than n, where the tree over n
+// leaves divides
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// Verify reports whether proof shows the leaf hash leaf at index in a tree
+// of size leaves whose root is root (RFC 9162 section 2.1.3.2)
+func Verify(leaf Hash, index, size int, proof []Hash, root Hash) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && r == root
+}
+
+// Chain links a root to the anchors before it: the chain hash of an anchor
+// commits to its root and, through previous, to every earlier root
+func Chain(previous, root Hash) Hash {
+	return sha256.Sum256(append(previous[:], root[:]...))
+}
@@ -0,0 +1,55 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoot(t *testing.T) {
+	// Test vectors from RFC 6962's reference implementation: leaf data
+	// "", 0x00, 0x10, 0x2021, 0x3031, 0x40414243, ...
+	data := [][]byte{{}, {0x00}, {0x10}, {0x20, 0x21}, {0x30, 0x31}, {0x40, 0x41, 0x42, 0x43}, {0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57}, {0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f}}
+	leaves := make([]Hash, len(data))
+	for i, d := range data {
+		leaves[i] = LeafHash(d)
+	}
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Root(nil).String())
+	assert.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", Root(leaves[:1]).String())
+	assert.Equal(t, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328", Root(leaves).String())
+}
+
+func TestProof(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := make([]Hash, size)
+		for i := range leaves {
+			leaves[i] = LeafHash([]byte(fmt.Sprintf("transaction %d", i)))
+		}
+		root := Root(leaves)
+		for i := range leaves {
+			proof, err := Proof(leaves, i)
+			require.NoError(t, err)
+			assert.True(t, Verify(leaves[i], i, size, proof, root), "leaf %d of %d", i, size)
+
+			// The proof is for this leaf at this position only
+			assert.False(t, Verify(LeafHash([]byte("forged")), i, size, proof, root))
+			if size > 1 {
+				assert.False(t, Verify(leaves[i], (i+1)%size, size, proof, root))
+			}
+		}
+	}
+
+	_, err := Proof(make([]Hash, 3), 3)
+	assert.Error(t, err)
+}
+
+func TestParseHash(t *testing.T) {
+	h := LeafHash([]byte("x"))
+	parsed, err := ParseHash(h.String())
+	require.NoError(t, err)
+	assert.Equal(t, h, parsed)
+	_, err = ParseHash("abc")
+	assert.Error(t, err)
+}
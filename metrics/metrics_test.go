@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests served", "method")
+	latency := r.NewHistogram("latency_seconds", "Request latency", []float64{0.1, 1})
+	r.NewGaugeFunc("open_connections", "Open connections", func() float64 { return 3 })
+	r.NewCounterFunc("acquires_total", "Connections acquired", func() float64 { return 42 })
+	r.NewGaugeVecFunc("lag_seconds", "Replica lag", []string{"replica"}, func() []Sample {
+		return []Sample{{LabelValues: []string{"replica-a"}, Value: 0.5}}
+	})
+
+	requests.Inc("GET")
+	requests.Add(2, "POST")
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(2)
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP requests_total Requests served
+# TYPE requests_total counter
+requests_total{method="GET"} 1
+requests_total{method="POST"} 2
+# HELP latency_seconds Request latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 2.55
+latency_seconds_count 3
+# HELP open_connections Open connections
+# TYPE open_connections gauge
+open_connections 3
+# HELP acquires_total Connections acquired
+# TYPE acquires_total counter
+acquires_total 42
+# HELP lag_seconds Replica lag
+# TYPE lag_seconds gauge
+lag_seconds{replica="replica-a"} 0.5
+`, w.Body.String())
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorKey is the context key holding the identity of the admin making a request
+const ActorKey = "actor"
+
+// RolesKey is the context key holding the ledger roles of an operator signed
+// in through SSO
+const RolesKey = "roles"
+
+// RoleAdmin is the ledger role that grants the admin API
+const RoleAdmin = "admin"
+
+// OperatorVerifier validates an operator's SSO access token, returning the
+// operator's name and the ledger roles their provider groups grant
+type OperatorVerifier func(ctx context.Context, token string) (actor string, roles []string, err error)
+
+// AdminAuth guards admin routes with a shared API key passed in the
+// X-Admin-Key header or as a bearer token. An empty key disables the admin API.
+// The optional X-Actor header names the operator for audit purposes.
+func AdminAuth(apiKey string) gin.HandlerFunc {
+	return OperatorAuth(apiKey, nil)
+}
+
+// OperatorAuth is AdminAuth that also accepts a JWT bearer token validated by
+// sso. The token's operator becomes the actor, and X-Actor is ignored; the
+// token must grant RoleAdmin. With a nil sso it behaves exactly like
+// AdminAuth, and with an empty apiKey only SSO tokens are accepted. Requests
+// ScopedKeyAuth let through pass too.
+func OperatorAuth(apiKey string, sso OperatorVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentAPIKey(c) != nil {
+			c.Next()
+			return
+		}
+		if apiKey == "" && sso == nil {
+			abortWithError(c, http.StatusForbidden, "Admin API is disabled", "")
+			return
+		}
+
+		provided := c.GetHeader("X-Admin-Key")
+		if provided == "" {
+			provided = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		if sso != nil && strings.Count(provided, ".") == 2 {
+			actor, roles, err := sso(c.Request.Context(), provided)
+			if err != nil {
+				log.Printf("Rejected operator token: %v", err)
+				abortWithError(c, http.StatusUnauthorized, "Invalid operator token", "")
+				return
+			}
+			if !hasRole(roles, RoleAdmin) {
+				abortWithError(c, http.StatusForbidden, "Operator is not permitted to use the admin API", "role_required")
+				return
+			}
+			c.Set(ActorKey, actor)
+			c.Set(RolesKey, roles)
+			c.Next()
+			return
+		}
+
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, "Invalid admin credentials", "")
+			return
+		}
+
+		actor := c.GetHeader("X-Actor")
+		if actor == "" {
+			actor = "admin"
+		}
+		c.Set(ActorKey, actor)
+		c.Next()
+	}
+}
+
+func hasRole(roles []string, want string) bool {
+	for _, r := range roles {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerKey is the context key holding the customer a self-service token is
+// bound to
+const CustomerKey = "token_customer_id"
+
+// CustomerTokenPrefix marks self-service customer tokens so they can be told
+// apart from the admin key
+const CustomerTokenPrefix = "ctk_"
+
+// CustomerTokenLookup returns the customer ID a self-service token is bound
+// to, or "" when the token is unknown, revoked or expired
+type CustomerTokenLookup func(ctx context.Context, token string) (string, error)
+
+// CustomerAuth confines requests carrying a self-service customer token, a
+// bearer token starting with CustomerTokenPrefix, to GET requests on routes
+// (matched as suffixes of the route pattern, so versioned and legacy paths
+// both match) whose customer_id is the token's subject. Requests without a
+// customer token pass through unchanged.
+func CustomerAuth(lookup CustomerTokenLookup, routes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !strings.HasPrefix(token, CustomerTokenPrefix) {
+			c.Next()
+			return
+		}
+
+		subject, err := lookup(c.Request.Context(), token)
+		if err != nil {
+			abortWithError(c, http.StatusInternalServerError, "Failed to verify customer token", "")
+			return
+		}
+		if subject == "" {
+			abortWithError(c, http.StatusUnauthorized, "Invalid customer token", "")
+			return
+		}
+
+		allowed := false
+		if c.Request.Method == http.MethodGet {
+			for _, route := range routes {
+				if strings.HasSuffix(c.FullPath(), route) {
+					allowed = true
+					break
+				}
+			}
+		}
+		if !allowed {
+			abortWithError(c, http.StatusForbidden, "Customer token does not grant access to this endpoint", "token_scope")
+			return
+		}
+		if !strings.EqualFold(c.Param("customer_id"), subject) {
+			abortWithError(c, http.StatusForbidden, "Customer token does not grant access to this customer", "token_scope")
+			return
+		}
+
+		c.Set(CustomerKey, subject)
+		c.Next()
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		key        string
+		headers    map[string]string
+		wantStatus int
+		wantActor  string
+	}{
+		{name: "disabled", key: "", headers: map[string]string{"X-Admin-Key": ""}, wantStatus: http.StatusForbidden},
+		{name: "missing key", key: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", key: "secret", headers: map[string]string{"X-Admin-Key": "nope"}, wantStatus: http.StatusUnauthorized},
+		{name: "header key", key: "secret", headers: map[string]string{"X-Admin-Key": "secret"}, wantStatus: http.StatusOK, wantActor: "admin"},
+		{
+			name:       "bearer token with actor",
+			key:        "secret",
+			headers:    map[string]string{"Authorization": "Bearer secret", "X-Actor": "alice"},
+			wantStatus: http.StatusOK,
+			wantActor:  "alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			var actor string
+			r.GET("/admin", AdminAuth(tt.key), func(c *gin.Context) {
+				actor = c.GetString(ActorKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/admin", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantActor, actor)
+		})
+	}
+}
+
+func TestOperatorAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sso := func(ctx context.Context, token string) (string, []string, error) {
+		switch token {
+		case "admin.jwt.sig":
+			return "alice", []string{RoleAdmin}, nil
+		case "viewer.jwt.sig":
+			return "bob", nil, nil
+		}
+		return "", nil, errors.New("bad signature")
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		headers    map[string]string
+		wantStatus int
+		wantActor  string
+	}{
+		{name: "SSO admin", key: "secret", headers: map[string]string{"Authorization": "Bearer admin.jwt.sig", "X-Actor": "mallory"}, wantStatus: http.StatusOK, wantActor: "alice"},
+		{name: "SSO without admin role", key: "secret", headers: map[string]string{"Authorization": "Bearer viewer.jwt.sig"}, wantStatus: http.StatusForbidden},
+		{name: "invalid SSO token", key: "secret", headers: map[string]string{"Authorization": "Bearer forged.jwt.sig"}, wantStatus: http.StatusUnauthorized},
+		{name: "API key still accepted", key: "secret", headers: map[string]string{"X-Admin-Key": "secret"}, wantStatus: http.StatusOK, wantActor: "admin"},
+		{name: "SSO only", key: "", headers: map[string]string{"Authorization": "Bearer admin.jwt.sig"}, wantStatus: http.StatusOK, wantActor: "alice"},
+		{name: "SSO only refuses keys", key: "", headers: map[string]string{"X-Admin-Key": ""}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			var actor string
+			r.GET("/admin", OperatorAuth(tt.key, sso), func(c *gin.Context) {
+				actor = c.GetString(ActorKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/admin", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantActor, actor)
+		})
+	}
+}
+
+func TestCustomerAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const customer = "6f1c2a3e-8d4b-4f5a-9c7e-1b2d3e4f5a6b"
+	lookup := func(ctx context.Context, token string) (string, error) {
+		switch token {
+		case "ctk_valid":
+			return customer, nil
+		case "ctk_broken":
+			return "", errors.New("database down")
+		}
+		return "", nil
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "no token passes through", method: "GET", path: "/customers/other/balance", wantStatus: http.StatusOK},
+		{name: "admin bearer passes through", method: "GET", path: "/admin/audit", token: "secret", wantStatus: http.StatusOK},
+		{name: "own balance", method: "GET", path: "/customers/" + customer + "/balance", token: "ctk_valid", wantStatus: http.StatusOK},
+		{name: "own transactions", method: "GET", path: "/v1/customers/" + customer + "/transactions", token: "ctk_valid", wantStatus: http.StatusOK},
+		{name: "another customer", method: "GET", path: "/customers/7a1c2a3e-8d4b-4f5a-9c7e-1b2d3e4f5a6b/balance", token: "ctk_valid", wantStatus: http.StatusForbidden},
+		{name: "route outside scope", method: "GET", path: "/customers/" + customer + "/kyc", token: "ctk_valid", wantStatus: http.StatusForbidden},
+		{name: "writes refused", method: "POST", path: "/customers/" + customer + "/balance", token: "ctk_valid", wantStatus: http.StatusForbidden},
+		{name: "admin routes refused", method: "GET", path: "/admin/audit", token: "ctk_valid", wantStatus: http.StatusForbidden},
+		{name: "unknown token", method: "GET", path: "/customers/" + customer + "/balance", token: "ctk_nope", wantStatus: http.StatusUnauthorized},
+		{name: "lookup failure", method: "GET", path: "/customers/" + customer + "/balance", token: "ctk_broken", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(CustomerAuth(lookup, "/customers/:customer_id/balance", "/customers/:customer_id/transactions"))
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			for _, prefix := range []string{"", "/v1"} {
+				r.GET(prefix+"/customers/:customer_id/balance", ok)
+				r.POST(prefix+"/customers/:customer_id/balance", ok)
+				r.GET(prefix+"/customers/:customer_id/transactions", ok)
+				r.GET(prefix+"/customers/:customer_id/kyc", ok)
+			}
+			r.GET("/admin/audit", ok)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl sets Cache-Control to directive on successful GET responses
+// and tags them with an ETag, answering 304 Not Modified when the client's
+// If-None-Match already holds it. With a directive such as "private,
+// max-age=0, must-revalidate", clients revalidate on every use and only
+// download a body that has changed. An empty directive leaves responses
+// untouched.
+func CacheControl(directive string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if directive == "" || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.Next()
+			return
+		}
+		w := &bufferWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() == http.StatusOK {
+			h := c.Writer.Header()
+			sum := sha256.Sum256(w.body.Bytes())
+			// Weak, because compression changes the bytes but not the content
+			etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+			h.Set("Cache-Control", directive)
+			h.Set("ETag", etag)
+			if etagMatches(c.GetHeader("If-None-Match"), etag) {
+				h.Del("Content-Type")
+				h.Del("Content-Length")
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+		w.flushTo(c.Writer)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// MicroCache answers repeated GET requests for the same URL from memory for
+// ttl, so health checks and pollers calling many times a second mostly skip
+// the handler. Only 200 responses are kept, with their Content-Type. ttl is
+// meant to be well under a second so nobody sees a noticeably stale answer;
+// zero disables the cache.
+func MicroCache(ttl time.Duration) gin.HandlerFunc {
+	if ttl <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	var mu sync.Mutex
+	entries := map[string]microCacheEntry{}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		key := c.Request.URL.RequestURI()
+		mu.Lock()
+		entry, ok := entries[key]
+		mu.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			c.Data(http.StatusOK, entry.contentType, entry.body)
+			c.Abort()
+			return
+		}
+
+		w := &bufferWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() == http.StatusOK {
+			now := time.Now()
+			mu.Lock()
+			// Query strings make the key space open-ended, so drop what has
+			// expired whenever the map grows
+			if len(entries) >= 64 {
+				for k, e := range entries {
+					if !now.Before(e.expires) {
+						delete(entries, k)
+					}
+				}
+			}
+			entries[key] = microCacheEntry{
+				contentType: c.Writer.Header().Get("Content-Type"),
+				body:        bytes.Clone(w.body.Bytes()),
+				expires:     now.Add(ttl),
+			}
+			mu.Unlock()
+		}
+		w.flushTo(c.Writer)
+	}
+}
+
+type microCacheEntry struct {
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// bufferWriter holds back the whole response so the middleware can look at
+// it before anything is sent
+type bufferWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferWriter) WriteHeaderNow() {}
+
+func (w *bufferWriter) Flush() {}
+
+// flushTo sends the held-back response through dst
+func (w *bufferWriter) flushTo(dst gin.ResponseWriter) {
+	dst.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		dst.Write(w.body.Bytes())
+	}
+}
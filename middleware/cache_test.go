@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	balance := 100
+	r := gin.New()
+	r.GET("/balance", CacheControl("private, max-age=0, must-revalidate"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"balance": balance})
+	})
+	r.GET("/missing", CacheControl("private, max-age=0, must-revalidate"), func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	})
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/balance", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, max-age=0, must-revalidate", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"balance":100}`, w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+
+	// Revalidating an unchanged body answers 304 without it
+	w = get("/balance", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// A changed body is sent in full with a new tag
+	balance = 75
+	w = get("/balance", `"stale", `+etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"balance":75}`, w.Body.String())
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// Errors are neither tagged nor marked cacheable
+	w = get("/missing", "*")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"error":"Customer not found"}`, w.Body.String())
+}
+
+func TestMicroCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	status := http.StatusOK
+	r := gin.New()
+	r.GET("/health", MicroCache(50*time.Millisecond), func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"calls": calls})
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		w := get("/health")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"calls":1}`, w.Body.String())
+	}
+	assert.Equal(t, 1, calls)
+
+	// Each URL is cached separately
+	assert.JSONEq(t, `{"calls":2}`, get("/health?verbose=true").Body.String())
+
+	// Once expired the handler runs again, and failures are not kept
+	time.Sleep(60 * time.Millisecond)
+	status = http.StatusServiceUnavailable
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health").Code)
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	large := strings.Repeat(`{"transaction_id":"550e8400-e29b-41d4-a716-446655440000"},`, 50)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+	}{
+		{name: "large json", acceptEncoding: "gzip, deflate", contentType: "application/json; charset=utf-8", body: large, wantEncoding: "gzip"},
+		{name: "below minimum size", acceptEncoding: "gzip", contentType: "application/json", body: `{"status":"ok"}`},
+		{name: "client without gzip", acceptEncoding: "br", contentType: "application/json", body: large},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large},
+		{name: "content type not listed", acceptEncoding: "gzip", contentType: "image/png", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Gzip(CompressionConfig{MinSize: 1024}))
+			r.GET("/transactions", func(c *gin.Context) {
+				c.Data(http.StatusOK, tt.contentType, []byte(tt.body))
+			})
+
+			req := httptest.NewRequest("GET", "/transactions", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantEncoding, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+			body := w.Body.Bytes()
+			if tt.wantEncoding == "gzip" {
+				assert.Less(t, len(body), len(tt.body))
+				zr, err := gzip.NewReader(w.Body)
+				assert.NoError(t, err)
+				body, err = io.ReadAll(zr)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestGzipNoBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Gzip(CompressionConfig{MinSize: 1}))
+	r.DELETE("/addresses/1", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest("DELETE", "/addresses/1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Body.Bytes())
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadlineConfig sets how long a request may run before its context is
+// cancelled. A zero duration leaves requests of that kind without a deadline.
+type DeadlineConfig struct {
+	// Read applies to GET and HEAD requests
+	Read time.Duration
+	// Write applies to every other method
+	Write time.Duration
+	// Routes overrides both for route patterns ending in the key, such as
+	// /admin/export, so one entry covers the /v1 and legacy paths
+	Routes map[string]time.Duration
+}
+
+func (cfg DeadlineConfig) timeout(c *gin.Context) time.Duration {
+	route := c.FullPath()
+	for suffix, d := range cfg.Routes {
+		if strings.HasSuffix(route, suffix) {
+			return d
+		}
+	}
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return cfg.Read
+	}
+	return cfg.Write
+}
+
+// Deadline attaches a deadline to each request's context so a slow database
+// call cannot hold a connection for the whole server write timeout. When the
+// deadline passes before the handler has answered successfully, the
+// handler's error response is replaced with a 504 carrying the code
+// request_timeout. Responses already being streamed are left alone.
+func Deadline(cfg DeadlineConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := cfg.timeout(c)
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		w := &deadlineWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.timedOut || (!c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			abortWithError(c, http.StatusGatewayTimeout, "Request timed out", "request_timeout")
+		}
+	}
+}
+
+// deadlineWriter drops a handler's error response once the deadline has
+// passed, leaving the middleware to send the 504 instead
+type deadlineWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	decided  bool
+	timedOut bool
+}
+
+// decide checks, before the first byte goes out, whether the response is an
+// error caused by the deadline
+func (w *deadlineWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.timedOut = w.ResponseWriter.Status() >= http.StatusBadRequest &&
+		errors.Is(w.ctx.Err(), context.DeadlineExceeded)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	w.decide()
+	if w.timedOut {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *deadlineWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *deadlineWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *deadlineWriter) Flush() {
+	w.decide()
+	if !w.timedOut {
+		w.ResponseWriter.Flush()
+	}
+}
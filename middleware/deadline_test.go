@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowQuery waits like a database call until ctx is done or d has passed
+func slowQuery(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Deadline(DeadlineConfig{
+		Read:   20 * time.Millisecond,
+		Write:  time.Second,
+		Routes: map[string]time.Duration{"/admin/export": 0},
+	}))
+	handler := func(c *gin.Context) {
+		if err := slowQuery(c.Request.Context(), 50*time.Millisecond); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get balance"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"balance": 100})
+	}
+	router.GET("/v1/customers/:customer_id/balance", handler)
+	router.POST("/v1/transactions", handler)
+	router.GET("/v1/admin/export", handler)
+	router.GET("/v1/silent", func(c *gin.Context) {
+		slowQuery(c.Request.Context(), 50*time.Millisecond)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"read past its deadline", http.MethodGet, "/v1/customers/123/balance", http.StatusGatewayTimeout},
+		{"write within its deadline", http.MethodPost, "/v1/transactions", http.StatusOK},
+		{"route without a deadline", http.MethodGet, "/v1/admin/export", http.StatusOK},
+		{"handler that never answers", http.MethodGet, "/v1/silent", http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusGatewayTimeout {
+				assert.JSONEq(t, `{"error": "Request timed out", "code": "request_timeout"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeadlineKeepsSuccessfulResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Deadline(DeadlineConfig{Write: 10 * time.Millisecond}))
+	router.POST("/v1/transactions", func(c *gin.Context) {
+		// The posting committed just before the deadline passed
+		time.Sleep(20 * time.Millisecond)
+		c.JSON(http.StatusCreated, gin.H{"status": "success"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"status": "success"}`, w.Body.String())
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks every response as coming from a deprecated API. It sets the
+// Deprecation header, a Sunset header when sunset is non-zero, and a Link
+// header pointing at the same path under successorPrefix (e.g. "/v1").
+func Deprecated(sunset time.Time, successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successorPrefix != "" {
+			c.Header("Link", "<"+successorPrefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
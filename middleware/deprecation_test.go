@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		sunset     time.Time
+		wantSunset string
+	}{
+		{name: "with sunset", sunset: time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC), wantSunset: "Wed, 30 Jun 2027 00:00:00 GMT"},
+		{name: "without sunset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/customers/:customer_id", Deprecated(tt.sunset, "/v1"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/customers/42", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "true", w.Header().Get("Deprecation"))
+			assert.Equal(t, tt.wantSunset, w.Header().Get("Sunset"))
+			assert.Equal(t, `</v1/customers/42>; rel="successor-version"`, w.Header().Get("Link"))
+		})
+	}
+}
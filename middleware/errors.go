@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"ledger-service/i18n"
+	"ledger-service/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithError stops the chain with the same error body the handlers
+// return, including the correlation IDs assigned by RequestID. Clients that
+// accept problem+json get an RFC 7807 document instead.
+func abortWithError(c *gin.Context, status int, message, code string) {
+	c.Abort()
+	message = redact.Default().String(Localize(c, message))
+	if PrefersProblemJSON(c) {
+		WriteProblem(c, NewProblem(c, status, message, code))
+		return
+	}
+
+	body := gin.H{"error": message}
+	if code != "" {
+		body["code"] = code
+	}
+	if id := c.GetString(RequestIDKey); id != "" {
+		body["request_id"] = id
+	}
+	if id := c.GetString(TraceIDKey); id != "" {
+		body["trace_id"] = id
+	}
+	c.JSON(status, body)
+}
+
+// Localize translates an error message into the language preferred by the
+// caller's Accept-Language header and reports the choice in Content-Language
+func Localize(c *gin.Context, message string) string {
+	catalog := i18n.Default()
+	tag := catalog.Match(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", tag.String())
+	return catalog.Translate(tag, message)
+}
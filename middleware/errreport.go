@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+
+	"ledger-service/errreport"
+	"ledger-service/redact"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorReporter receives events for panics and server errors
+type ErrorReporter interface {
+	Capture(ev errreport.Event)
+}
+
+// ReportErrors sends panics and 5xx responses to r, tagged with the route,
+// customer ID and request ID. Panics are re-raised so the recovery
+// middleware still answers with a 500.
+func ReportErrors(r ErrorReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec != http.ErrAbortHandler {
+					ev := requestEvent(c, "fatal", fmt.Sprintf("panic: %v", rec))
+					ev.Exception = &errreport.Exception{
+						Type:       "panic",
+						Value:      fmt.Sprint(rec),
+						Stacktrace: string(debug.Stack()),
+					}
+					r.Capture(ev)
+				}
+				panic(rec)
+			}
+		}()
+
+		c.Next()
+
+		if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+			msg := fmt.Sprintf("%d on %s %s", status, c.Request.Method, route(c))
+			if len(c.Errors) > 0 {
+				msg += ": " + c.Errors.String()
+			}
+			r.Capture(requestEvent(c, "error", msg))
+		}
+	}
+}
+
+func requestEvent(c *gin.Context, level, msg string) errreport.Event {
+	tags := map[string]string{
+		"route":  route(c),
+		"method": c.Request.Method,
+		"status": strconv.Itoa(c.Writer.Status()),
+	}
+	if id := c.Param("customer_id"); id != "" {
+		tags["customer_id"] = redact.Default().Field("customer_id", id)
+	}
+	if id := c.GetString(RequestIDKey); id != "" {
+		tags["request_id"] = id
+	}
+	if id := c.GetString(TraceIDKey); id != "" {
+		tags["trace_id"] = id
+	}
+	return errreport.Event{
+		Level:   level,
+		Message: msg,
+		Tags:    tags,
+		Request: &errreport.Request{Method: c.Request.Method, URL: redactedPath(c)},
+	}
+}
+
+// route is the matched route pattern, or the raw path when no route matched
+func route(c *gin.Context) string {
+	if r := c.FullPath(); r != "" {
+		return r
+	}
+	return c.Request.URL.Path
+}
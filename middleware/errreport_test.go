@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ledger-service/errreport"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeReporter struct {
+	events []errreport.Event
+}
+
+func (f *fakeReporter) Capture(ev errreport.Event) {
+	f.events = append(f.events, ev)
+}
+
+func TestReportErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantLevel  string
+	}{
+		{name: "success", handler: func(c *gin.Context) { c.Status(http.StatusOK) }, wantStatus: http.StatusOK},
+		{name: "client error", handler: func(c *gin.Context) { c.Status(http.StatusBadRequest) }, wantStatus: http.StatusBadRequest},
+		{name: "server error", handler: func(c *gin.Context) { c.Status(http.StatusInternalServerError) }, wantStatus: http.StatusInternalServerError, wantLevel: "error"},
+		{name: "panic", handler: func(c *gin.Context) { panic("boom") }, wantStatus: http.StatusInternalServerError, wantLevel: "fatal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &fakeReporter{}
+			r := gin.New()
+			r.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}))
+			r.Use(RequestID(), ReportErrors(reporter))
+			r.GET("/customers/:customer_id/balance", tt.handler)
+
+			req := httptest.NewRequest("GET", "/customers/42/balance", nil)
+			req.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantLevel == "" {
+				assert.Empty(t, reporter.events)
+				return
+			}
+			if assert.Len(t, reporter.events, 1) {
+				ev := reporter.events[0]
+				assert.Equal(t, tt.wantLevel, ev.Level)
+				assert.Equal(t, "/customers/:customer_id/balance", ev.Tags["route"])
+				assert.Equal(t, "42", ev.Tags["customer_id"])
+				assert.Equal(t, "req-1", ev.Tags["request_id"])
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fault kinds understood by FaultInjection
+const (
+	FaultLatency = "latency"
+	FaultError   = "error"
+	FaultDBDrop  = "db_drop"
+)
+
+// FaultRule injects one kind of failure into a share of matching requests.
+// Route is a gin route pattern such as /v1/customers/:customer_id/balance,
+// or "*" for every route; an empty Method matches any method.
+type FaultRule struct {
+	Method    string  `json:"method,omitempty"`
+	Route     string  `json:"route"`
+	Percent   float64 `json:"percent"`
+	Fault     string  `json:"fault"`
+	LatencyMS int     `json:"latency_ms,omitempty"`
+	Status    int     `json:"status,omitempty"`
+}
+
+// ParseFaultRules decodes a JSON array of fault rules and checks each one
+func ParseFaultRules(s string) ([]FaultRule, error) {
+	var rules []FaultRule
+	if err := json.Unmarshal([]byte(s), &rules); err != nil {
+		return nil, fmt.Errorf("fault rules must be a JSON array: %w", err)
+	}
+	for i, r := range rules {
+		switch {
+		case r.Route == "":
+			return nil, fmt.Errorf("fault rule %d: route is required", i)
+		case r.Percent <= 0 || r.Percent > 100:
+			return nil, fmt.Errorf("fault rule %d: percent must be in (0, 100]", i)
+		case r.Fault == FaultLatency && r.LatencyMS <= 0:
+			return nil, fmt.Errorf("fault rule %d: latency_ms must be positive", i)
+		case r.Fault == FaultError && r.Status != 0 && (r.Status < 500 || r.Status > 599):
+			return nil, fmt.Errorf("fault rule %d: status must be a 5xx code", i)
+		case r.Fault != FaultLatency && r.Fault != FaultError && r.Fault != FaultDBDrop:
+			return nil, fmt.Errorf("fault rule %d: fault must be one of latency, error, db_drop", i)
+		}
+	}
+	return rules, nil
+}
+
+func (r FaultRule) matches(c *gin.Context) bool {
+	if r.Method != "" && r.Method != c.Request.Method {
+		return false
+	}
+	return r.Route == "*" || r.Route == c.FullPath()
+}
+
+type dbFaultContextKey struct{}
+
+// WithDBFault marks ctx so that database calls made with it fail as if the
+// connection had dropped
+func WithDBFault(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dbFaultContextKey{}, true)
+}
+
+// DBFaultFromContext reports whether ctx was marked by WithDBFault
+func DBFaultFromContext(ctx context.Context) bool {
+	fault, _ := ctx.Value(dbFaultContextKey{}).(bool)
+	return fault
+}
+
+// FaultInjection applies rules to incoming requests so clients and retry
+// logic can be exercised against realistic failures. It must never be
+// installed in production. Every injected fault is named in the
+// X-Fault-Injected response header. A nil roll uses math/rand.
+func FaultInjection(rules []FaultRule, roll func() float64) gin.HandlerFunc {
+	if roll == nil {
+		roll = rand.Float64
+	}
+	return func(c *gin.Context) {
+		for _, r := range rules {
+			if !r.matches(c) || roll()*100 >= r.Percent {
+				continue
+			}
+			c.Writer.Header().Add("X-Fault-Injected", r.Fault)
+			switch r.Fault {
+			case FaultLatency:
+				select {
+				case <-time.After(time.Duration(r.LatencyMS) * time.Millisecond):
+				case <-c.Request.Context().Done():
+				}
+			case FaultError:
+				status := r.Status
+				if status == 0 {
+					status = http.StatusInternalServerError
+				}
+				abortWithError(c, status, "Injected fault", "fault_injected")
+				return
+			case FaultDBDrop:
+				c.Request = c.Request.WithContext(WithDBFault(c.Request.Context()))
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFaultRules(t *testing.T) {
+	rules, err := ParseFaultRules(`[
+		{"method": "POST", "route": "/v1/transactions", "percent": 10, "fault": "error", "status": 503},
+		{"route": "*", "percent": 50, "fault": "latency", "latency_ms": 200},
+		{"route": "/v1/customers/:customer_id/balance", "percent": 5, "fault": "db_drop"}
+	]`)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	for _, bad := range []string{
+		`{"route": "*"}`,
+		`[{"percent": 10, "fault": "error"}]`,
+		`[{"route": "*", "percent": 0, "fault": "error"}]`,
+		`[{"route": "*", "percent": 150, "fault": "error"}]`,
+		`[{"route": "*", "percent": 10, "fault": "latency"}]`,
+		`[{"route": "*", "percent": 10, "fault": "error", "status": 404}]`,
+		`[{"route": "*", "percent": 10, "fault": "explode"}]`,
+	} {
+		_, err := ParseFaultRules(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		rules      []FaultRule
+		roll       float64
+		method     string
+		wantStatus int
+		wantFaults []string
+		wantDBDrop bool
+		minLatency time.Duration
+	}{
+		{
+			name:       "error on matching route",
+			rules:      []FaultRule{{Method: "POST", Route: "/v1/customers/:customer_id", Percent: 20, Fault: FaultError, Status: 503}},
+			roll:       0.1,
+			method:     "POST",
+			wantStatus: http.StatusServiceUnavailable,
+			wantFaults: []string{"error"},
+		},
+		{
+			name:       "roll outside percentage",
+			rules:      []FaultRule{{Route: "*", Percent: 20, Fault: FaultError}},
+			roll:       0.5,
+			method:     "POST",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other method",
+			rules:      []FaultRule{{Method: "GET", Route: "*", Percent: 100, Fault: FaultError}},
+			roll:       0,
+			method:     "POST",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "other route",
+			rules:      []FaultRule{{Route: "/v1/transactions", Percent: 100, Fault: FaultError}},
+			roll:       0,
+			method:     "POST",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "latency and dropped DB combine",
+			rules: []FaultRule{
+				{Route: "*", Percent: 100, Fault: FaultLatency, LatencyMS: 20},
+				{Route: "/v1/customers/:customer_id", Percent: 100, Fault: FaultDBDrop},
+			},
+			roll:       0.99,
+			method:     "POST",
+			wantStatus: http.StatusOK,
+			wantFaults: []string{"latency", "db_drop"},
+			wantDBDrop: true,
+			minLatency: 20 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			var dbDrop bool
+			r.Use(FaultInjection(tt.rules, func() float64 { return tt.roll }))
+			r.POST("/v1/customers/:customer_id", func(c *gin.Context) {
+				dbDrop = DBFaultFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			start := time.Now()
+			req := httptest.NewRequest(tt.method, "/v1/customers/abc", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantFaults, w.Header().Values("X-Fault-Injected"))
+			assert.Equal(t, tt.wantDBDrop, dbDrop)
+			assert.GreaterOrEqual(t, time.Since(start), tt.minLatency)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseHook rewrites a JSON response before it is sent. It receives the
+// status and body the handler wrote and returns the body to send instead; it
+// may also set headers through c.
+type ResponseHook func(c *gin.Context, status int, body []byte) ([]byte, error)
+
+// PreResponse runs hooks on every JSON response, in order, each receiving
+// the body the one before returned. When a hook fails the response is
+// replaced with a 500 carrying the code response_hook_failed. Other content
+// types, such as CSV exports, are streamed untouched.
+func PreResponse(hooks ...ResponseHook) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &hookWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if !w.buffering {
+			return
+		}
+
+		body := w.body.Bytes()
+		for i, hook := range hooks {
+			var err error
+			if body, err = hook(c, w.Status(), body); err != nil {
+				log.Printf("Pre-response hook %d failed for %s %s: %v", i, c.Request.Method, c.Request.URL.Path, err)
+				abortWithError(c, http.StatusInternalServerError, "Failed to prepare response", "response_hook_failed")
+				return
+			}
+		}
+		c.Writer.Header().Del("Content-Length")
+		c.Writer.Write(body)
+	}
+}
+
+// hookWriter holds back JSON responses so the hooks can see them whole
+type hookWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// decide checks, before the first byte goes out, whether the response is JSON
+func (w *hookWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	contentType := w.Header().Get("Content-Type")
+	w.buffering = strings.HasPrefix(contentType, "application/json") ||
+		strings.HasPrefix(contentType, ProblemContentType)
+}
+
+func (w *hookWriter) Write(b []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *hookWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *hookWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *hookWriter) Flush() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var order []string
+	router := gin.New()
+	router.Use(PreResponse(
+		func(c *gin.Context, status int, body []byte) ([]byte, error) {
+			order = append(order, "first")
+			if c.Query("fail") != "" {
+				return nil, errors.New("enrichment unavailable")
+			}
+			c.Header("X-Region", "eu-west-1")
+			return bytes.Replace(body, []byte(`}`), []byte(`,"region":"eu-west-1"}`), 1), nil
+		},
+		func(c *gin.Context, status int, body []byte) ([]byte, error) {
+			order = append(order, "second")
+			assert.Equal(t, http.StatusCreated, status)
+			return bytes.ToUpper(body), nil
+		},
+	))
+	router.POST("/v1/customers", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"name": "ada"})
+	})
+	router.GET("/v1/admin/export", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte("id,amount\n"))
+	})
+
+	t.Run("hooks run in order on JSON", func(t *testing.T) {
+		order = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/customers", nil))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, `{"NAME":"ADA","REGION":"EU-WEST-1"}`, w.Body.String())
+		assert.Equal(t, "eu-west-1", w.Header().Get("X-Region"))
+		assert.Equal(t, []string{"first", "second"}, order)
+	})
+
+	t.Run("failing hook replaces the response", func(t *testing.T) {
+		order = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/customers?fail=1", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"response_hook_failed"`)
+		assert.Equal(t, []string{"first"}, order)
+	})
+
+	t.Run("other content types pass through", func(t *testing.T) {
+		order = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/export", nil))
+		assert.Equal(t, "id,amount\n", w.Body.String())
+		assert.Empty(t, order)
+	})
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader names the header clients set to make a write safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds keys so they fit the Postgres column
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyStore holds idempotency records shared by every replica. Records
+// are opaque to the store; each one expires after the store's TTL.
+type IdempotencyStore interface {
+	// Claim stores record under key unless an unexpired record is already
+	// there, in which case it reports claimed false and returns that record
+	// (nil if it vanished in the meantime)
+	Claim(ctx context.Context, key string, record []byte) (claimed bool, existing []byte, err error)
+	// Save replaces the record under a key this replica has claimed
+	Save(ctx context.Context, key string, record []byte) error
+	// Release drops a claimed key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// idempotencyRecord is what the store holds for a key: the request's
+// fingerprint and, once the request has finished, its response
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests that carry an
+// Idempotency-Key header run at most once across all replicas. A retry with
+// the same key and body gets the first response again, marked with
+// Idempotent-Replayed; a retry while the first attempt is still running gets
+// 409, and reusing a key for a different request gets 422. Server errors
+// release the key so the client can try again. If the store cannot be
+// reached the request is refused with 503 rather than risk running twice.
+// Dry runs (dry_run=true) change nothing and are never recorded, so the
+// same key can then be used for the real request.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			key = ""
+		}
+		if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+			key = ""
+		}
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters", "invalid_idempotency_key")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "Failed to read request body", "invalid_body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		ctx := context.WithoutCancel(c.Request.Context())
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		claimed, existing, err := store.Claim(ctx, key, pending)
+		if err != nil {
+			log.Printf("Idempotency store claim failed for key %q: %v", key, err)
+			abortWithError(c, http.StatusServiceUnavailable, "Idempotency store unavailable", "idempotency_unavailable")
+			return
+		}
+		if !claimed {
+			var prior idempotencyRecord
+			if existing == nil || json.Unmarshal(existing, &prior) != nil || prior.Status == 0 {
+				abortWithError(c, http.StatusConflict, "A request with this Idempotency-Key is already in progress", "idempotency_key_in_use")
+				return
+			}
+			if prior.Fingerprint != fingerprint {
+				abortWithError(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", "idempotency_key_reused")
+				return
+			}
+			c.Abort()
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(prior.Status, prior.ContentType, prior.Body)
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		completed := false
+		defer func() {
+			// A panic or server error leaves nothing worth replaying
+			if completed {
+				return
+			}
+			if err := store.Release(ctx, key); err != nil {
+				log.Printf("Idempotency store release failed for key %q: %v", key, err)
+			}
+		}()
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError {
+			return
+		}
+		// The request has taken effect; if saving fails the key stays claimed
+		// until it expires rather than letting a retry run it again
+		completed = true
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      w.Status(),
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        w.body.Bytes(),
+		})
+		if err := store.Save(ctx, key, record); err != nil {
+			log.Printf("Idempotency store save failed for key %q: %v", key, err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by method, path and body so a key
+// reused for something else can be told apart from a retry
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body as it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyStore stands in for the Postgres and Redis stores
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+	err     error
+}
+
+func (s *memoryIdempotencyStore) Claim(ctx context.Context, key string, record []byte) (bool, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, nil, s.err
+	}
+	if existing, ok := s.records[key]; ok {
+		return false, existing, nil
+	}
+	s.records[key] = record
+	return true, nil, nil
+}
+
+func (s *memoryIdempotencyStore) Save(ctx context.Context, key string, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: map[string][]byte{}}
+	calls := 0
+	router := gin.New()
+	router.Use(Idempotency(store))
+	router.POST("/transactions", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	router.POST("/fail", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+	})
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("/transactions", "key-1", `{"amount":10}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.JSONEq(t, `{"call":1}`, first.Body.String())
+
+	replay := send("/transactions", "key-1", `{"amount":10}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.JSONEq(t, `{"call":1}`, replay.Body.String())
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Contains(t, replay.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, 1, calls)
+
+	reused := send("/transactions", "key-1", `{"amount":20}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Contains(t, reused.Body.String(), "idempotency_key_reused")
+
+	// Requests without a key always run
+	send("/transactions", "", `{"amount":10}`)
+	send("/transactions", "", `{"amount":10}`)
+	assert.Equal(t, 3, calls)
+
+	// Server errors free the key for another attempt
+	assert.Equal(t, http.StatusInternalServerError, send("/fail", "key-2", `{}`).Code)
+	assert.Equal(t, http.StatusInternalServerError, send("/fail", "key-2", `{}`).Code)
+	assert.Equal(t, 5, calls)
+
+	assert.Equal(t, http.StatusBadRequest, send("/transactions", strings.Repeat("k", 256), `{}`).Code)
+
+	// Dry runs are not recorded, so the key still posts the real request
+	assert.JSONEq(t, `{"call":6}`, send("/transactions?dry_run=true", "key-3", `{"amount":10}`).Body.String())
+	assert.JSONEq(t, `{"call":7}`, send("/transactions", "key-3", `{"amount":10}`).Body.String())
+}
+
+func TestIdempotencyInProgressAndStoreErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: map[string][]byte{
+		"running": []byte(`{"fingerprint":"abc"}`),
+	}}
+	router := gin.New()
+	router.Use(Idempotency(store))
+	router.POST("/transactions", func(c *gin.Context) {
+		t.Error("handler should not run")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set(IdempotencyKeyHeader, "running")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "idempotency_key_in_use")
+
+	store.err = errors.New("connection refused")
+	req = httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set(IdempotencyKeyHeader, "other")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
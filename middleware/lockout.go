@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutConfig sets how hard repeated authentication failures are slowed
+// down
+type LockoutConfig struct {
+	// FreeAttempts is how many failures go unpunished
+	FreeAttempts int
+	// BaseDelay is the wait after the first punished failure; it doubles
+	// with every further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Threshold failures lock the caller out for Duration. Failures are
+	// forgotten once Duration passes without one.
+	Threshold int
+	Duration  time.Duration
+	// Exempt lists routes whose 401s are not failed logins, such as provider
+	// notifications with a bad signature; they are left out of the guard
+	Exempt []string
+	// OnLockout is told about every lockout, for example to alert on it
+	OnLockout func(LockoutEvent)
+	// Now is the clock, time.Now when nil
+	Now func() time.Time
+}
+
+// LockoutEvent describes a caller locked out after too many failures
+type LockoutEvent struct {
+	// Subject is "ip" for a client address, or "credential" for a key or
+	// token tried from anywhere
+	Subject string `json:"subject" example:"credential"`
+	// Identifier is the client address, or a fingerprint of the credential
+	Identifier  string    `json:"identifier" example:"#3f9a1c0e5b7d"`
+	Failures    int       `json:"failures" example:"20"`
+	LockedUntil time.Time `json:"locked_until" format:"date-time"`
+	Path        string    `json:"path" example:"/v1/admin/summary"`
+}
+
+type failureRecord struct {
+	failures    int
+	last        time.Time
+	retryAt     time.Time
+	lockedUntil time.Time
+}
+
+// BruteForceGuard counts the 401 responses each client address and each
+// credential (admin key, API key or token) gets. After cfg.FreeAttempts
+// failures every further one makes the caller wait, twice as long each
+// time; after cfg.Threshold they are locked out. A caller that must wait
+// gets 429 with Retry-After, without its credentials being checked. A
+// success clears the credential's failures but not the address's. Counts
+// are kept per process. The address is gin's ClientIP, so the engine's
+// trusted proxies decide whether forwarding headers are believed.
+func BruteForceGuard(cfg LockoutConfig) gin.HandlerFunc {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	var mu sync.Mutex
+	records := map[string]*failureRecord{}
+
+	return func(c *gin.Context) {
+		for _, route := range cfg.Exempt {
+			if strings.HasSuffix(c.FullPath(), route) {
+				c.Next()
+				return
+			}
+		}
+		keys := []string{"ip:" + c.ClientIP()}
+		credential := presentedCredential(c)
+		if credential != "" {
+			keys = append(keys, "credential:"+fingerprint(credential))
+		}
+
+		now := cfg.Now()
+		var wait time.Duration
+		mu.Lock()
+		for _, key := range keys {
+			if r := records[key]; r != nil {
+				for _, until := range []time.Time{r.retryAt, r.lockedUntil} {
+					if d := until.Sub(now); d > wait {
+						wait = d
+					}
+				}
+			}
+		}
+		mu.Unlock()
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+			abortWithError(c, http.StatusTooManyRequests, "Too many failed authentication attempts, try again later", "locked_out")
+			return
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status != http.StatusUnauthorized {
+			if status < http.StatusBadRequest && credential != "" {
+				mu.Lock()
+				delete(records, keys[len(keys)-1])
+				mu.Unlock()
+			}
+			return
+		}
+
+		var lockouts []LockoutEvent
+		mu.Lock()
+		for _, key := range keys {
+			r := records[key]
+			if r == nil || now.Sub(r.last) > cfg.Duration {
+				r = &failureRecord{}
+				records[key] = r
+			}
+			r.failures++
+			r.last = now
+			switch {
+			case cfg.Threshold > 0 && r.failures >= cfg.Threshold:
+				r.lockedUntil = now.Add(cfg.Duration)
+				subject, identifier, _ := strings.Cut(key, ":")
+				lockouts = append(lockouts, LockoutEvent{
+					Subject:     subject,
+					Identifier:  identifier,
+					Failures:    r.failures,
+					LockedUntil: r.lockedUntil,
+					Path:        c.FullPath(),
+				})
+				r.failures = 0
+			case r.failures > cfg.FreeAttempts:
+				delay := cfg.BaseDelay << (r.failures - cfg.FreeAttempts - 1)
+				if delay > cfg.MaxDelay || delay <= 0 {
+					delay = cfg.MaxDelay
+				}
+				r.retryAt = now.Add(delay)
+			}
+		}
+		// Forget callers that have stopped failing, so the table stays small
+		if len(records) > 10000 {
+			for key, r := range records {
+				if now.Sub(r.last) > cfg.Duration && now.After(r.lockedUntil) {
+					delete(records, key)
+				}
+			}
+		}
+		mu.Unlock()
+
+		for _, ev := range lockouts {
+			log.Printf("Locked out %s %s until %s after %d failed authentication attempts",
+				ev.Subject, ev.Identifier, ev.LockedUntil.Format(time.RFC3339), ev.Failures)
+			if cfg.OnLockout != nil {
+				cfg.OnLockout(ev)
+			}
+		}
+	}
+}
+
+// presentedCredential is the key or token a request authenticates with
+func presentedCredential(c *gin.Context) string {
+	for _, header := range []string{"X-Admin-Key", "X-API-Key"} {
+		if v := c.GetHeader(header); v != "" {
+			return v
+		}
+	}
+	auth := c.GetHeader("Authorization")
+	if scheme, token, ok := strings.Cut(auth, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return token
+	}
+	return ""
+}
+
+// fingerprint identifies a credential in events and logs without revealing it
+func fingerprint(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return "#" + hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBruteForceGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)
+	var lockouts []LockoutEvent
+	r := gin.New()
+	r.Use(BruteForceGuard(LockoutConfig{
+		FreeAttempts: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     4 * time.Second,
+		Threshold:    6,
+		Duration:     15 * time.Minute,
+		OnLockout:    func(ev LockoutEvent) { lockouts = append(lockouts, ev) },
+		Now:          func() time.Time { return now },
+	}))
+	r.GET("/admin/summary", AdminAuth("secret"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	try := func(key, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/summary", nil)
+		req.Header.Set("X-Admin-Key", key)
+		req.RemoteAddr = ip + ":4321"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Two failures are free, then each one doubles the wait
+	assert.Equal(t, http.StatusUnauthorized, try("guess1", "198.51.100.7").Code)
+	assert.Equal(t, http.StatusUnauthorized, try("guess2", "198.51.100.7").Code)
+	assert.Equal(t, http.StatusUnauthorized, try("guess3", "198.51.100.7").Code)
+	w := try("secret", "198.51.100.7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "even the right key waits")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"locked_out"`)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, try("guess4", "198.51.100.7").Code)
+	assert.Equal(t, "2", try("guess5", "198.51.100.7").Header().Get("Retry-After"))
+
+	// Other addresses are not held up
+	assert.Equal(t, http.StatusOK, try("secret", "203.0.113.9").Code)
+
+	// The same credential is tracked wherever it is tried from
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, try("stolen", "192.0.2.10").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, try("stolen", "192.0.2.11").Code)
+
+	// Reaching the threshold locks the caller out and reports it
+	for _, d := range []time.Duration{2, 4} {
+		now = now.Add(d * time.Second)
+		assert.Equal(t, http.StatusUnauthorized, try("guess", "198.51.100.7").Code)
+	}
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, "ip", lockouts[0].Subject)
+	assert.Equal(t, "198.51.100.7", lockouts[0].Identifier)
+	assert.Equal(t, 6, lockouts[0].Failures)
+	assert.Equal(t, "/admin/summary", lockouts[0].Path)
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, http.StatusTooManyRequests, try("secret", "198.51.100.7").Code)
+	now = now.Add(5 * time.Minute)
+	assert.Equal(t, http.StatusOK, try("secret", "198.51.100.7").Code)
+}
+
+func TestBruteForceGuardClientAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)
+	r := gin.New()
+	require.NoError(t, r.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	r.Use(BruteForceGuard(LockoutConfig{
+		Threshold: 3,
+		Duration:  15 * time.Minute,
+		Exempt:    []string{"/stripe/webhook"},
+		Now:       func() time.Time { return now },
+	}))
+	r.GET("/admin/summary", AdminAuth("secret"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/stripe/webhook", func(c *gin.Context) { c.Status(http.StatusUnauthorized) })
+
+	try := func(method, path, key, remote, forwardedFor string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Admin-Key", key)
+		req.RemoteAddr = remote + ":4321"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A client cannot pick its own address by sending X-Forwarded-For
+	for _, spoofed := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		assert.Equal(t, http.StatusUnauthorized, try("GET", "/admin/summary", "guess-"+spoofed, "198.51.100.7", spoofed))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, try("GET", "/admin/summary", "another", "198.51.100.7", "192.0.2.4"))
+	// so neither can it lock out the address it names
+	assert.Equal(t, http.StatusOK, try("GET", "/admin/summary", "secret", "192.0.2.1", ""))
+
+	// Behind a trusted proxy the forwarded address is the client's
+	now = now.Add(time.Hour)
+	for _, key := range []string{"guess1", "guess2", "guess3"} {
+		assert.Equal(t, http.StatusUnauthorized, try("GET", "/admin/summary", key, "10.0.0.5", "203.0.113.9"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, try("GET", "/admin/summary", "another", "10.0.0.6", "203.0.113.9"))
+	assert.Equal(t, http.StatusOK, try("GET", "/admin/summary", "secret", "10.0.0.5", ""), "the proxy itself is not locked out")
+
+	// Bad signatures on provider notifications are not failed logins
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusUnauthorized, try("POST", "/stripe/webhook", "", "192.0.2.50", ""))
+	}
+	assert.Equal(t, http.StatusOK, try("GET", "/admin/summary", "secret", "192.0.2.50", ""))
+}
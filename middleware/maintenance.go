@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceState is whether writes are switched off and for how long
+// callers should wait before retrying
+type MaintenanceState struct {
+	Enabled    bool
+	RetryAfter time.Duration
+}
+
+// Maintenance refuses write requests with 503 and a Retry-After header while
+// current reports maintenance mode, so the database can be migrated or
+// failed over. GET, HEAD and OPTIONS requests are served as usual, and so are
+// routes whose path ends with one of exempt, such as the switch itself.
+func Maintenance(current func() MaintenanceState, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		state := current()
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+		for _, route := range exempt {
+			if strings.HasSuffix(c.FullPath(), route) {
+				c.Next()
+				return
+			}
+		}
+
+		if seconds := int(state.RetryAfter.Seconds()); seconds > 0 {
+			c.Header("Retry-After", strconv.Itoa(seconds))
+		}
+		abortWithError(c, http.StatusServiceUnavailable, "Service is in maintenance mode; try again later", "maintenance")
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	state := MaintenanceState{Enabled: true, RetryAfter: 5 * time.Minute}
+	r := gin.New()
+	v1 := r.Group("/v1", Maintenance(func() MaintenanceState { return state }, "/admin/maintenance"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	v1.GET("/customers/:customer_id/balance", ok)
+	v1.POST("/transactions", ok)
+	v1.PUT("/admin/maintenance", ok)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		enabled    bool
+		wantStatus int
+	}{
+		{"reads are served", "GET", "/v1/customers/42/balance", true, http.StatusOK},
+		{"writes are refused", "POST", "/v1/transactions", true, http.StatusServiceUnavailable},
+		{"the switch stays writable", "PUT", "/v1/admin/maintenance", true, http.StatusOK},
+		{"writes are served when off", "POST", "/v1/transactions", false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state.Enabled = tt.enabled
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				assert.Equal(t, "300", w.Header().Get("Retry-After"))
+				assert.Contains(t, w.Body.String(), `"code":"maintenance"`)
+			} else {
+				assert.Empty(t, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the RFC 7807 media type for error details
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the machine-readable error code to form the
+// problem type URI. Errors without a code use about:blank as RFC 7807 suggests.
+const problemTypeBase = "urn:ledger-service:problem:"
+
+// Problem is an RFC 7807 problem details document. Code, RequestID and
+// TraceID are extension members carrying the same values as the legacy
+// error body.
+type Problem struct {
+	Type      string `json:"type" example:"urn:ledger-service:problem:invalid_json"`
+	Title     string `json:"title" example:"Bad Request"`
+	Status    int    `json:"status" example:"400"`
+	Detail    string `json:"detail,omitempty" example:"Invalid JSON: unexpected end of JSON input"`
+	Instance  string `json:"instance,omitempty" example:"/v1/transactions"`
+	Code      string `json:"code,omitempty" example:"invalid_json"`
+	RequestID string `json:"request_id,omitempty" example:"3f2c9a5e-1b7d-4c2e-9f4a-8d6b0e1c2a3f"`
+	TraceID   string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+}
+
+// NewProblem describes an error on the current request
+func NewProblem(c *gin.Context, status int, detail, code string) Problem {
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: c.GetString(RequestIDKey),
+		TraceID:   c.GetString(TraceIDKey),
+	}
+	if code != "" {
+		p.Type = problemTypeBase + code
+	}
+	return p
+}
+
+// WriteProblem sends p with the problem+json content type
+func WriteProblem(c *gin.Context, p Problem) {
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(p.Status, p)
+}
+
+// PrefersProblemJSON reports whether the client asked for problem+json
+// error responses in its Accept header. Clients that do not mention it keep
+// receiving the legacy {"error": ...} shape.
+func PrefersProblemJSON(c *gin.Context) bool {
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		params := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), ProblemContentType) {
+			continue
+		}
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefersProblemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "application/json", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/problem+json", want: true},
+		{accept: "application/json, application/problem+json;q=0.9", want: true},
+		{accept: "Application/Problem+JSON", want: true},
+		{accept: "application/problem+json;q=0", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/", nil)
+			c.Request.Header.Set("Accept", tt.accept)
+			assert.Equal(t, tt.want, PrefersProblemJSON(c))
+		})
+	}
+}
+
+func TestAbortWithProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/transactions", RequestID(), StrictJSON(16), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		accept      string
+		wantType    string
+		wantProblem bool
+	}{
+		{name: "legacy shape", accept: "application/json"},
+		{name: "problem details", accept: "application/problem+json", wantType: "urn:ledger-service:problem:body_too_large", wantProblem: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/transactions", http.NoBody)
+			req.ContentLength = 1024
+			req.Header.Set("Accept", tt.accept)
+			req.Header.Set("X-Request-ID", "checkout-123")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "checkout-123", body["request_id"])
+			assert.Equal(t, "body_too_large", body["code"])
+			if !tt.wantProblem {
+				assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+				assert.Contains(t, body, "error")
+				assert.NotContains(t, body, "type")
+				return
+			}
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantType, body["type"])
+			assert.Equal(t, "Request Entity Too Large", body["title"])
+			assert.Equal(t, float64(http.StatusRequestEntityTooLarge), body["status"])
+			assert.Equal(t, "Request body exceeds 16 bytes", body["detail"])
+			assert.Equal(t, "/v1/transactions", body["instance"])
+			assert.NotContains(t, body, "error")
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"ledger-service/redact"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDKey and TraceIDKey are the context keys holding a request's
+// correlation identifiers
+const (
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+)
+
+type requestIDContextKey struct{}
+
+var (
+	requestIDPattern   = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+	traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// RequestID gives every request a correlation ID. It takes the caller's
+// X-Request-ID when it is well formed, otherwise the trace ID from a W3C
+// traceparent header, and otherwise generates one. The ID is stored on the
+// gin and request contexts and echoed in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := ""
+		if m := traceparentPattern.FindStringSubmatch(c.GetHeader("traceparent")); m != nil && m[1] != "00000000000000000000000000000000" {
+			traceID = m[1]
+			c.Set(TraceIDKey, traceID)
+			c.Header("traceparent", c.GetHeader("traceparent"))
+		}
+
+		id := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = traceID
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		c.Set(RequestIDKey, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+// WithRequestID returns a copy of ctx carrying a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// logPathKey holds the masked path Logger writes
+const logPathKey = "log_path"
+
+// Logger logs one line per request in gin's default layout, prefixed with
+// the request ID. Sensitive path parameters, query values and error text
+// are masked by the default redactor.
+func Logger() gin.HandlerFunc {
+	logger := gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		if p.Latency > time.Minute {
+			p.Latency = p.Latency.Truncate(time.Second)
+		}
+		requestID, _ := p.Keys[RequestIDKey].(string)
+		path, _ := p.Keys[logPathKey].(string)
+		return fmt.Sprintf("[GIN] %v | %s | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			requestID,
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			path,
+			redact.Default().String(p.ErrorMessage),
+		)
+	})
+	return func(c *gin.Context) {
+		c.Set(logPathKey, redactedPath(c))
+		logger(c)
+	}
+}
+
+// redactedPath is the request path and query with the values of sensitive
+// route parameters, such as payment link tokens, and query values masked
+func redactedPath(c *gin.Context) string {
+	r := redact.Default()
+	path := c.Request.URL.Path
+	for _, p := range c.Params {
+		if p.Value != "" && r.Policy(p.Key) != redact.Keep {
+			path = strings.Replace(path, "/"+p.Value, "/"+r.Field(p.Key, p.Value), 1)
+		}
+	}
+	if c.Request.URL.RawQuery != "" {
+		path += "?" + c.Request.URL.RawQuery
+	}
+	return r.URL(path)
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantID      string
+		wantTraceID string
+	}{
+		{name: "caller request ID", headers: map[string]string{"X-Request-ID": "checkout-123"}, wantID: "checkout-123"},
+		{name: "trace ID from traceparent", headers: map[string]string{"traceparent": traceparent}, wantID: "4bf92f3577b34da6a3ce929d0e0e4736", wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "request ID and traceparent", headers: map[string]string{"X-Request-ID": "checkout-123", "traceparent": traceparent}, wantID: "checkout-123", wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "malformed request ID", headers: map[string]string{"X-Request-ID": "bad id\nwith newline"}},
+		{name: "generated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			var ctxID, requestCtxID, traceID string
+			r.GET("/health", RequestID(), func(c *gin.Context) {
+				ctxID = c.GetString(RequestIDKey)
+				traceID = c.GetString(TraceIDKey)
+				requestCtxID = RequestIDFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/health", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.NotEmpty(t, ctxID)
+			if tt.wantID != "" {
+				assert.Equal(t, tt.wantID, ctxID)
+			}
+			assert.Equal(t, ctxID, requestCtxID)
+			assert.Equal(t, ctxID, w.Header().Get("X-Request-ID"))
+			assert.Equal(t, tt.wantTraceID, traceID)
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &buf
+	defer func() { gin.DefaultWriter = defaultWriter }()
+
+	r := gin.New()
+	r.Use(RequestID(), Logger())
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Request-ID", "checkout-123")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, buf.String(), "| checkout-123 |")
+	assert.Contains(t, buf.String(), `"/health"`)
+}
+
+func TestLoggerRedacts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &buf
+	defer func() { gin.DefaultWriter = defaultWriter }()
+
+	r := gin.New()
+	r.Use(RequestID(), Logger())
+	r.GET("/payment-links/:token", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/customers/:customer_id/transactions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/payment-links/q3M1vJ0rV6tW8yZ2aB4cD6eF", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/customers/550e8400/transactions?page=2&email=jane%40example.com", nil))
+
+	assert.NotContains(t, buf.String(), "q3M1vJ0rV6tW8yZ2aB4cD6eF")
+	assert.Contains(t, buf.String(), `"/payment-links/[REDACTED]"`)
+	assert.Contains(t, buf.String(), `"/customers/550e8400/transactions?page=2&email=j***@example.com"`)
+}
+
+func TestAbortWithErrorIncludesCorrelationIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/ping", RequestID(), AdminAuth(""), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/admin/ping", nil)
+	req.Header.Set("X-Request-ID", "checkout-123")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "checkout-123", body["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", body["trace_id"])
+}
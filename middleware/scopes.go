@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyPrefix marks scoped API keys so they can be told apart from the
+// shared keys and customer tokens
+const APIKeyPrefix = "lsk_"
+
+// APIKeyContextKey is the context key holding the *APIKey a request was
+// authenticated with
+const APIKeyContextKey = "api_key"
+
+// APIKey is a scoped API key as its lookup returns it
+type APIKey struct {
+	ID     string
+	Name   string
+	Scopes []string
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	return hasRole(k.Scopes, scope)
+}
+
+// APIKeyLookup returns the live key a secret belongs to, or nil when it is
+// unknown, revoked or expired. It is called on every request, so changes to
+// a key's scopes apply to its next request.
+type APIKeyLookup func(ctx context.Context, key string) (*APIKey, error)
+
+// RouteScope returns the scope a key needs for a route pattern. A route
+// scoped keys may not use at all returns allowed false; one any key may use
+// returns an empty scope.
+type RouteScope func(method, route string) (scope string, allowed bool)
+
+// ScopedKeyAuth authenticates requests carrying a scoped API key, a key
+// starting with APIKeyPrefix in the X-API-Key header or as a bearer token,
+// and lets them through only when the key has the scope scopeOf says the
+// route needs. The key is stored under APIKeyContextKey, which the surface
+// guards accept in place of their shared keys, and names the actor in the
+// audit log. Requests without a scoped key pass through unchanged.
+func ScopedKeyAuth(lookup APIKeyLookup, scopeOf RouteScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-API-Key")
+		if provided == "" {
+			provided = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if !strings.HasPrefix(provided, APIKeyPrefix) || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+
+		key, err := lookup(c.Request.Context(), provided)
+		if err != nil {
+			abortWithError(c, http.StatusInternalServerError, "Failed to verify API key", "")
+			return
+		}
+		if key == nil {
+			abortWithError(c, http.StatusUnauthorized, "Invalid API key", "")
+			return
+		}
+
+		scope, allowed := scopeOf(c.Request.Method, c.FullPath())
+		if !allowed {
+			abortWithError(c, http.StatusForbidden, "API keys cannot use this endpoint", "scope_required")
+			return
+		}
+		if scope != "" && !key.HasScope(scope) {
+			abortWithError(c, http.StatusForbidden, "API key lacks the "+scope+" scope", "scope_required")
+			return
+		}
+
+		c.Set(APIKeyContextKey, key)
+		c.Set(ActorKey, "api-key:"+key.Name)
+		c.Next()
+	}
+}
+
+// CurrentAPIKey returns the scoped key a request was authenticated with, or
+// nil
+func CurrentAPIKey(c *gin.Context) *APIKey {
+	key, _ := c.Get(APIKeyContextKey)
+	k, _ := key.(*APIKey)
+	return k
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StrictJSONKey is the context key set on requests whose JSON body has passed StrictJSON
+const StrictJSONKey = "strict_json"
+
+// StrictJSON guards write endpoints. Request bodies larger than maxBytes are
+// refused with 413, and bodies that are not a single well-formed JSON value,
+// repeat an object key, or hold a number outside the float64 range are
+// refused with 400. Bodies declared as another media type, such as CSV or
+// XML uploads, are only size-limited. Handlers can check StrictJSONKey to
+// also reject unknown fields when binding.
+func StrictJSON(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			abortTooLarge(c, maxBytes)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortTooLarge(c, maxBytes)
+			} else {
+				abortWithError(c, http.StatusBadRequest, "Failed to read request body", "invalid_body")
+			}
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		if !declaresJSON(c) {
+			c.Next()
+			return
+		}
+
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err := checkJSON(data); err != nil {
+				abortWithError(c, http.StatusBadRequest, "Invalid JSON: "+err.Error(), "invalid_json")
+				return
+			}
+		}
+		c.Set(StrictJSONKey, true)
+		c.Next()
+	}
+}
+
+// declaresJSON reports whether a request's body is JSON or has no declared
+// media type, which is taken as JSON
+func declaresJSON(c *gin.Context) bool {
+	ct := c.ContentType()
+	return ct == "" || ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+func abortTooLarge(c *gin.Context, maxBytes int64) {
+	abortWithError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytes), "body_too_large")
+}
+
+// checkJSON verifies that data holds exactly one JSON value with no repeated
+// object keys and only finite numbers
+func checkJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := checkValue(dec); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the top-level value")
+	}
+	return nil
+}
+
+func checkValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			seen := map[string]bool{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				// Fields bind case-insensitively, so "Amount" repeats "amount"
+				key := keyTok.(string)
+				folded := strings.ToLower(key)
+				if seen[folded] {
+					return fmt.Errorf("duplicate key %q", key)
+				}
+				seen[folded] = true
+				if err := checkValue(dec); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for dec.More() {
+				if err := checkValue(dec); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := dec.Token() // closing delimiter
+		return err
+	case json.Number:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Errorf("number %s is out of range", v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{name: "valid body", method: "POST", body: `{"customer_id": "a", "amount": 10.5, "tags": [1, 2]}`, wantStatus: http.StatusOK},
+		{name: "empty body", method: "POST", body: "", wantStatus: http.StatusOK},
+		{name: "too large", method: "POST", body: `{"note": "` + strings.Repeat("x", 100) + `"}`, wantStatus: http.StatusRequestEntityTooLarge, wantCode: "body_too_large"},
+		{name: "duplicate key", method: "POST", body: `{"amount": 1, "amount": 1000}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "duplicate key in another case", method: "POST", body: `{"amount": 1, "Amount": 1000}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "nested duplicate key", method: "PUT", body: `{"a": [{"b": 1, "b": 2}]}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "same key in sibling objects", method: "POST", body: `[{"b": 1}, {"b": 2}]`, wantStatus: http.StatusOK},
+		{name: "overflowing number", method: "POST", body: `{"amount": 1e400}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "NaN literal", method: "PATCH", body: `{"amount": NaN}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "trailing data", method: "POST", body: `{"amount": 1} {"amount": 2}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "json content type", method: "POST", contentType: "application/json; charset=utf-8", body: `{"amount": 1, "amount": 2}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+		{name: "csv is not checked", method: "POST", contentType: "text/csv", body: "date,amount\n2025-04-01,1", wantStatus: http.StatusOK},
+		{name: "csv is size-limited", method: "POST", contentType: "text/csv", body: strings.Repeat("2025-04-01,1\n", 10), wantStatus: http.StatusRequestEntityTooLarge, wantCode: "body_too_large"},
+		{name: "get is not checked", method: "GET", body: `{"amount": 1, "amount": 2}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			var got string
+			r.Handle(tt.method, "/transactions", StrictJSON(64), func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				got = string(body)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/transactions", bytes.NewBufferString(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				var resp map[string]string
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantCode, resp["code"])
+				assert.NotEmpty(t, resp["error"])
+			} else {
+				assert.Equal(t, tt.body, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnly answers 405 to every request that is not a GET, HEAD or OPTIONS.
+// It guards the public read surface, and in read-only deployments the whole
+// router, so a write can never slip through a route registered by mistake.
+func ReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		c.Header("Allow", "GET, HEAD, OPTIONS")
+		abortWithError(c, http.StatusMethodNotAllowed, "This API is read-only", "read_only")
+	}
+}
+
+// ClientAuth guards the customer surface, where customers are created,
+// their personal data is read and money is moved, with a shared API key
+// passed in the X-API-Key header or as a bearer token. An empty key leaves
+// the surface open, as it was before the key existed. Requests
+// ScopedKeyAuth let through pass too.
+func ClientAuth(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" || CurrentAPIKey(c) != nil {
+			c.Next()
+			return
+		}
+
+		provided := c.GetHeader("X-API-Key")
+		if provided == "" {
+			provided = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, "Invalid API credentials", "")
+			return
+		}
+		c.Next()
+	}
+}
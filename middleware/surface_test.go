@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ReadOnly())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/balance", ok)
+	r.POST("/transactions", ok)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/balance", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/transactions", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.Contains(t, w.Body.String(), `"code":"read_only"`)
+}
+
+func TestClientAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		key        string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{name: "open without a key", key: "", wantStatus: http.StatusOK},
+		{name: "missing key", key: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "admin key is not a client key", key: "secret", headers: map[string]string{"X-Admin-Key": "secret"}, wantStatus: http.StatusUnauthorized},
+		{name: "header key", key: "secret", headers: map[string]string{"X-API-Key": "secret"}, wantStatus: http.StatusOK},
+		{name: "bearer token", key: "secret", headers: map[string]string{"Authorization": "Bearer secret"}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/customers", ClientAuth(tt.key), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest("POST", "/customers", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
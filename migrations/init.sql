@@ -1,20 +1,1145 @@
--- Create customers table
-CREATE TABLE IF NOT EXISTS customers (
-    id UUID PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    balance DECIMAL(15,2) NOT NULL CHECK (balance >= 0)
-);
-
--- Create transactions table
-CREATE TABLE IF NOT EXISTS transactions (
-    id UUID PRIMARY KEY,
-    customer_id UUID NOT NULL REFERENCES customers(id),
-    type VARCHAR(10) NOT NULL CHECK (type IN ('credit', 'debit')),
-    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (customer_id) REFERENCES customers(id)
-);
-
--- Create index for faster transaction lookups
-CREATE INDEX IF NOT EXISTS idx_transactions_customer_id ON transactions(customer_id);
-CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at DESC); 
\ No newline at end of file
+-- Create customers table
+CREATE TABLE IF NOT EXISTS customers (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL CHECK (balance >= 0)
+);
+
+-- Create transactions table
+CREATE TABLE IF NOT EXISTS transactions (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+);
+
+-- Create index for faster transaction lookups
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_id ON transactions(customer_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at DESC); 
+
+-- Add SMS notification settings to customers
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_number VARCHAR(20);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS sms_opt_in BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Track transaction lifecycle (held/rejected transactions do not affect the balance)
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'posted'
+    CHECK (status IN ('posted', 'held', 'rejected'));
+
+-- Create fraud rules table
+CREATE TABLE IF NOT EXISTS fraud_rules (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    rule_type VARCHAR(30) NOT NULL CHECK (rule_type IN ('amount_spike', 'rapid_debits', 'unusual_hours')),
+    action VARCHAR(10) NOT NULL CHECK (action IN ('flag', 'hold', 'reject')),
+    params JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create fraud decisions table
+CREATE TABLE IF NOT EXISTS fraud_decisions (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    action VARCHAR(10) NOT NULL CHECK (action IN ('flag', 'hold', 'reject')),
+    matches JSONB NOT NULL DEFAULT '[]',
+    review_status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (review_status IN ('open', 'approved', 'rejected')),
+    reviewed_by VARCHAR(255),
+    review_note TEXT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fraud_decisions_review_status ON fraud_decisions(review_status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_type_created_at ON transactions(customer_id, type, created_at);
+
+-- Add KYC fields to customers
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS date_of_birth DATE;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS verification_status VARCHAR(20) NOT NULL DEFAULT 'unverified'
+    CHECK (verification_status IN ('unverified', 'pending', 'verified', 'rejected'));
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS verification_note TEXT;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS verified_by VARCHAR(255);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS verification_updated_at TIMESTAMP WITH TIME ZONE;
+
+-- Create customer documents table
+CREATE TABLE IF NOT EXISTS customer_documents (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    document_type VARCHAR(30) NOT NULL CHECK (document_type IN ('passport', 'drivers_license', 'national_id', 'proof_of_address')),
+    reference VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_documents_customer_id ON customer_documents(customer_id);
+
+-- Add contact details to customers
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+
+-- Create customer addresses table
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    address_type VARCHAR(20) NOT NULL DEFAULT 'home' CHECK (address_type IN ('home', 'mailing', 'business')),
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255),
+    city VARCHAR(100) NOT NULL,
+    region VARCHAR(100),
+    postal_code VARCHAR(20) NOT NULL,
+    country CHAR(2) NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_customer_id ON customer_addresses(customer_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_addresses_primary ON customer_addresses(customer_id) WHERE is_primary;
+
+-- Add account types to customers
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS account_type VARCHAR(20) NOT NULL DEFAULT 'checking'
+    CHECK (account_type IN ('checking', 'savings', 'escrow'));
+
+-- Allow transactions to wait for escrow approvals
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('posted', 'held', 'pending_approval', 'rejected'));
+
+-- Create transaction approvals table
+CREATE TABLE IF NOT EXISTS transaction_approvals (
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    approver VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (transaction_id, approver)
+);
+
+-- Create sub-accounts table
+CREATE TABLE IF NOT EXISTS sub_accounts (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    name VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (customer_id, name)
+);
+
+-- Create sub-account transactions table
+CREATE TABLE IF NOT EXISTS sub_account_transactions (
+    id UUID PRIMARY KEY,
+    sub_account_id UUID NOT NULL REFERENCES sub_accounts(id),
+    move_id UUID NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sub_account_transactions_sub_account_id ON sub_account_transactions(sub_account_id, created_at DESC);
+
+-- Record internal moves on the main balance
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_type_check
+    CHECK (type IN ('credit', 'debit', 'move_in', 'move_out'));
+
+-- Create moves table capturing the rate and both amounts of each internal move
+CREATE TABLE IF NOT EXISTS moves (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    from_sub_account_id UUID REFERENCES sub_accounts(id),
+    to_sub_account_id UUID REFERENCES sub_accounts(id),
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    converted_amount DECIMAL(15,2) NOT NULL CHECK (converted_amount > 0),
+    rate DECIMAL(20,10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moves_customer_id ON moves(customer_id, created_at DESC);
+
+-- Create FX quotes table
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    rate DECIMAL(20,10) NOT NULL CHECK (rate > 0),
+    converted_amount DECIMAL(15,2) NOT NULL CHECK (converted_amount > 0),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Link moves to the quote whose rate they used
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS quote_id UUID REFERENCES fx_quotes(id);
+
+-- Create transfers table for customer-to-customer payments
+CREATE TABLE IF NOT EXISTS transfers (
+    id UUID PRIMARY KEY,
+    from_customer_id UUID NOT NULL REFERENCES customers(id),
+    to_customer_id UUID NOT NULL REFERENCES customers(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    reference VARCHAR(140),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Record transfer legs on the transactions of both customers
+ALTER TABLE transactions ALTER COLUMN type TYPE VARCHAR(20);
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_type_check
+    CHECK (type IN ('credit', 'debit', 'move_in', 'move_out', 'transfer_in', 'transfer_out'));
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transfer_id UUID REFERENCES transfers(id);
+
+-- Create standing orders table
+CREATE TABLE IF NOT EXISTS standing_orders (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    payee_customer_id UUID NOT NULL REFERENCES customers(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_run_date DATE NOT NULL,
+    retry_on DATE,
+    reference VARCHAR(140),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled', 'completed')),
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failure_reason TEXT,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_customer_id ON standing_orders(customer_id);
+CREATE INDEX IF NOT EXISTS idx_standing_orders_due ON standing_orders(next_run_date) WHERE status = 'active';
+
+-- Create standing order runs table
+CREATE TABLE IF NOT EXISTS standing_order_runs (
+    id UUID PRIMARY KEY,
+    standing_order_id UUID NOT NULL REFERENCES standing_orders(id),
+    run_date DATE NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('executed', 'failed')),
+    transfer_id UUID REFERENCES transfers(id),
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create direct debit mandates table
+CREATE TABLE IF NOT EXISTS mandates (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    merchant_customer_id UUID NOT NULL REFERENCES customers(id),
+    max_amount DECIMAL(15,2) NOT NULL CHECK (max_amount > 0),
+    monthly_limit DECIMAL(15,2) CHECK (monthly_limit > 0),
+    reference VARCHAR(140),
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'revoked')),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mandates_customer_id ON mandates(customer_id);
+CREATE INDEX IF NOT EXISTS idx_mandates_merchant_customer_id ON mandates(merchant_customer_id);
+
+-- Create mandate payments table
+CREATE TABLE IF NOT EXISTS mandate_payments (
+    id UUID PRIMARY KEY,
+    mandate_id UUID NOT NULL REFERENCES mandates(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(10) NOT NULL CHECK (status IN ('collected', 'rejected')),
+    reason TEXT,
+    transfer_id UUID REFERENCES transfers(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mandate_payments_mandate_id ON mandate_payments(mandate_id, created_at);
+
+-- Create payment requests table
+CREATE TABLE IF NOT EXISTS payment_requests (
+    id UUID PRIMARY KEY,
+    requester_customer_id UUID NOT NULL REFERENCES customers(id),
+    payer_customer_id UUID NOT NULL REFERENCES customers(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    message VARCHAR(140),
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'expired')),
+    transfer_id UUID REFERENCES transfers(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_requests_payer ON payment_requests(payer_customer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_requester ON payment_requests(requester_customer_id, created_at DESC);
+
+-- Create payment links table
+CREATE TABLE IF NOT EXISTS payment_links (
+    id UUID PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    description VARCHAR(140),
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paid', 'expired')),
+    paid_by_customer_id UUID REFERENCES customers(id),
+    transfer_id UUID REFERENCES transfers(id),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_links_customer ON payment_links(customer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_payment_links_active_expiry ON payment_links(expires_at) WHERE status = 'active';
+
+-- Create audit log table
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    customer_id UUID REFERENCES customers(id),
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_customer ON audit_log(customer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+
+-- Allow manual balance adjustments
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_type_check
+    CHECK (type IN ('credit', 'debit', 'move_in', 'move_out', 'transfer_in', 'transfer_out', 'adjustment_credit', 'adjustment_debit'));
+
+-- Create adjustments table
+CREATE TABLE IF NOT EXISTS adjustments (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    reason_code VARCHAR(20) NOT NULL CHECK (reason_code IN ('write_off', 'goodwill', 'error_correction')),
+    justification TEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_adjustments_customer ON adjustments(customer_id, created_at DESC);
+
+-- Create transaction types table
+CREATE TABLE IF NOT EXISTS transaction_types (
+    code VARCHAR(20) PRIMARY KEY,
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('credit', 'debit')),
+    description VARCHAR(255),
+    postable BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO transaction_types (code, direction, description, postable) VALUES
+    ('credit', 'credit', 'Generic credit', TRUE),
+    ('debit', 'debit', 'Generic debit', TRUE),
+    ('purchase', 'debit', 'Card or merchant purchase', TRUE),
+    ('refund', 'credit', 'Refund of an earlier purchase', TRUE),
+    ('fee', 'debit', 'Fee charged to the customer', TRUE),
+    ('interest', 'credit', 'Interest paid to the customer', TRUE),
+    ('adjustment_credit', 'credit', 'Manual correction increasing the balance', FALSE),
+    ('adjustment_debit', 'debit', 'Manual correction decreasing the balance', FALSE),
+    ('transfer_in', 'credit', 'Incoming transfer from another customer', FALSE),
+    ('transfer_out', 'debit', 'Outgoing transfer to another customer', FALSE),
+    ('move_in', 'credit', 'Move from one of the customer''s sub-accounts', FALSE),
+    ('move_out', 'debit', 'Move to one of the customer''s sub-accounts', FALSE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Validate transaction types against the registry instead of a fixed list
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_type_fkey
+    FOREIGN KEY (type) REFERENCES transaction_types(code);
+
+-- Record each customer's opening balance so the trial balance can reconcile
+-- balances against posted movements. Existing customers are backfilled from
+-- their current balance, which becomes the baseline for later checks.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS opening_balance DECIMAL(15,2);
+UPDATE customers c SET opening_balance = c.balance - COALESCE((
+    SELECT SUM(CASE WHEN tt.direction = 'credit' THEN t.amount ELSE -t.amount END)
+    FROM transactions t JOIN transaction_types tt ON tt.code = t.type
+    WHERE t.customer_id = c.id AND t.status = 'posted'), 0)
+WHERE opening_balance IS NULL;
+ALTER TABLE customers ALTER COLUMN opening_balance SET DEFAULT 0;
+ALTER TABLE customers ALTER COLUMN opening_balance SET NOT NULL;
+
+-- Create the chart of accounts: general ledger accounts held by the bank
+-- itself, posted against customer balances for fees, interest and
+-- corrections. Balances are kept on each account's normal side.
+CREATE TABLE IF NOT EXISTS gl_accounts (
+    code VARCHAR(30) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    category VARCHAR(10) NOT NULL CHECK (category IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    normal_balance VARCHAR(6) NOT NULL CHECK (normal_balance IN ('credit', 'debit')),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0,
+    system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO gl_accounts (code, name, category, normal_balance, system) VALUES
+    ('fees_income', 'Fees income', 'income', 'credit', TRUE),
+    ('interest_expense', 'Interest expense', 'expense', 'debit', TRUE),
+    ('fx_gains', 'FX gains', 'income', 'credit', TRUE),
+    ('fx_losses', 'FX losses', 'expense', 'debit', TRUE),
+    ('suspense', 'Suspense', 'liability', 'credit', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS gl_entries (
+    id UUID PRIMARY KEY,
+    account_code VARCHAR(30) NOT NULL REFERENCES gl_accounts(code),
+    transaction_id UUID REFERENCES transactions(id),
+    type VARCHAR(6) NOT NULL CHECK (type IN ('credit', 'debit')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_gl_entries_account_code ON gl_entries(account_code, created_at DESC);
+
+-- Name the counterparty account of transaction types that move the bank's own money
+ALTER TABLE transaction_types ADD COLUMN IF NOT EXISTS gl_account VARCHAR(30) REFERENCES gl_accounts(code);
+UPDATE transaction_types SET gl_account = 'fees_income' WHERE code = 'fee' AND gl_account IS NULL;
+UPDATE transaction_types SET gl_account = 'interest_expense' WHERE code = 'interest' AND gl_account IS NULL;
+UPDATE transaction_types SET gl_account = 'suspense' WHERE code IN ('adjustment_credit', 'adjustment_debit') AND gl_account IS NULL;
+
+-- Back each sort order of the transaction listing with a composite index
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_created_at_id ON transactions(customer_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_amount_id ON transactions(customer_id, amount, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_type_created_at_id ON transactions(customer_id, type, created_at, id);
+
+-- Store each customer's IANA timezone; daily and monthly limit windows start
+-- at the customer's local midnight
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Webhook subscriptions; an empty event_types list receives every event
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    description VARCHAR(255),
+    secret VARCHAR(80) NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transactional outbox: events are written in the same transaction as the
+-- change they describe and relayed to the message bus and webhooks in seq order
+CREATE TABLE IF NOT EXISTS outbox (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    customer_id UUID,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(seq) WHERE published_at IS NULL;
+
+-- One row per attempt to deliver an event to a webhook
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    delivered BOOLEAN NOT NULL,
+    status_code INTEGER,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- Idempotency keys shared by every replica; the primary key lets one request
+-- claim a key and record holds its response for replay until expires_at
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    record JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- Record the rounding mode applied to each converted amount
+ALTER TABLE fx_quotes ADD COLUMN IF NOT EXISTS rounding VARCHAR(10);
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS rounding VARCHAR(10);
+
+-- Let internal and settlement accounts go below zero
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS allow_negative BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_balance_check;
+ALTER TABLE customers ADD CONSTRAINT customers_balance_check CHECK (balance >= 0 OR allow_negative);
+
+-- Credit accounts hold what the customer owes, up to credit_limit, so debits
+-- raise their balance and credits repay it
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS balance_type VARCHAR(10) NOT NULL DEFAULT 'deposit'
+    CHECK (balance_type IN ('deposit', 'credit'));
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(15,2)
+    CHECK (credit_limit > 0);
+
+-- Loans: the principal is paid out to the customer's balance and repaid by
+-- amortizing installments debited on their due dates
+INSERT INTO gl_accounts (code, name, category, normal_balance, system) VALUES
+    ('loans_receivable', 'Loans receivable', 'asset', 'debit', TRUE),
+    ('interest_income', 'Interest income', 'income', 'credit', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO transaction_types (code, direction, description, postable, gl_account) VALUES
+    ('loan_disbursement', 'credit', 'Loan principal paid out to the customer', FALSE, 'loans_receivable'),
+    ('loan_repayment', 'debit', 'Repayment of loan principal', FALSE, 'loans_receivable'),
+    ('loan_interest', 'debit', 'Interest charged on a loan', FALSE, 'interest_income')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS loans (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    principal DECIMAL(15,2) NOT NULL CHECK (principal > 0),
+    annual_rate DECIMAL(7,6) NOT NULL CHECK (annual_rate >= 0),
+    installments INTEGER NOT NULL CHECK (installments > 0),
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    outstanding_principal DECIMAL(15,2) NOT NULL CHECK (outstanding_principal >= 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'repaid')),
+    disbursement_transaction_id UUID NOT NULL REFERENCES transactions(id),
+    disbursed_on DATE NOT NULL,
+    repaid_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_loans_customer_id ON loans(customer_id, created_at DESC);
+
+-- The amortization schedule; retry_on is set while an installment the
+-- customer could not cover waits for its next attempt
+CREATE TABLE IF NOT EXISTS loan_installments (
+    loan_id UUID NOT NULL REFERENCES loans(id),
+    number INTEGER NOT NULL,
+    due_date DATE NOT NULL,
+    principal DECIMAL(15,2) NOT NULL CHECK (principal >= 0),
+    interest DECIMAL(15,2) NOT NULL CHECK (interest >= 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'due' CHECK (status IN ('due', 'paid', 'cancelled')),
+    retry_on DATE,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failure_reason TEXT,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (loan_id, number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_installments_due ON loan_installments(due_date) WHERE status = 'due';
+
+-- Every repayment, scheduled or early, with the transactions that booked it
+CREATE TABLE IF NOT EXISTS loan_repayments (
+    id UUID PRIMARY KEY,
+    loan_id UUID NOT NULL REFERENCES loans(id),
+    installment_number INTEGER,
+    principal DECIMAL(15,2) NOT NULL,
+    interest DECIMAL(15,2) NOT NULL,
+    principal_transaction_id UUID REFERENCES transactions(id),
+    interest_transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_repayments_loan_id ON loan_repayments(loan_id, created_at);
+
+-- Store each customer's base currency; every posting to the main balance is
+-- in it. Existing accounts were all opened in USD.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE loans ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Backdated transactions carry their historical created_at; recorded_at is
+-- when they were actually written. It is NULL for everything posted live.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS recorded_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounting period closes. Nothing may be backdated on or before the latest
+-- closed_through date.
+CREATE TABLE IF NOT EXISTS period_closes (
+    id UUID PRIMARY KEY,
+    closed_through DATE NOT NULL UNIQUE,
+    closed_by VARCHAR(255) NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The date a transaction takes effect for interest and statements. It
+-- defaults to the posting date and can be set a limited number of days
+-- either side of it.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS value_date DATE;
+UPDATE transactions SET value_date = (created_at AT TIME ZONE 'UTC')::date WHERE value_date IS NULL;
+ALTER TABLE transactions ALTER COLUMN value_date SET DEFAULT CURRENT_DATE;
+ALTER TABLE transactions ALTER COLUMN value_date SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_value_date ON transactions(customer_id, value_date);
+
+-- Re-deliveries of past events to one webhook, e.g. after the subscriber's
+-- outage. last_seq is the outbox position reached so far.
+CREATE TABLE IF NOT EXISTS webhook_replays (
+    id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    from_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed')),
+    total INTEGER NOT NULL,
+    delivered INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_replays_webhook_id ON webhook_replays(webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_replays_active ON webhook_replays(created_at) WHERE status <> 'completed';
+CREATE INDEX IF NOT EXISTS idx_outbox_created_at ON outbox(created_at);
+
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS replay_id UUID REFERENCES webhook_replays(id) ON DELETE SET NULL;
+
+-- Support querying the audit log by time and actor
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, created_at DESC);
+
+-- Create self-service customer tokens table; only a hash of each token is kept
+CREATE TABLE IF NOT EXISTS customer_tokens (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_tokens_customer_id ON customer_tokens(customer_id, created_at DESC);
+
+-- Support counting recent failed webhook deliveries for the admin summary
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_failed ON webhook_deliveries(created_at) WHERE NOT delivered;
+
+-- Dormant accounts: flagged after a period without posted transactions, and
+-- only the dormancy job or an operator's reactivation changes the flag
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS dormant_since TIMESTAMP WITH TIME ZONE;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS reactivated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_customers_dormant_since ON customers(dormant_since DESC) WHERE dormant_since IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_status_created_at ON transactions(customer_id, created_at) WHERE status = 'posted';
+
+-- Create scheduled jobs table recording each background job's last run across instances
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    last_status VARCHAR(20) NOT NULL,
+    last_started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_processed INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_success_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create maintenance mode table holding the single write switch shared by every instance
+CREATE TABLE IF NOT EXISTS maintenance_mode (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    retry_after_seconds INTEGER NOT NULL,
+    updated_by VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create debit limits: deployment-wide defaults in a single row, and
+-- per-customer limits whose empty columns fall back to the defaults
+CREATE TABLE IF NOT EXISTS limit_defaults (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    max_single_debit DECIMAL(15,2) CHECK (max_single_debit > 0),
+    daily_debit_limit DECIMAL(15,2) CHECK (daily_debit_limit > 0),
+    overdraft_limit DECIMAL(15,2) CHECK (overdraft_limit >= 0),
+    max_debits_per_hour INTEGER CHECK (max_debits_per_hour > 0),
+    max_debits_per_day INTEGER CHECK (max_debits_per_day > 0),
+    updated_by VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customer_limits (
+    customer_id UUID PRIMARY KEY REFERENCES customers(id),
+    max_single_debit DECIMAL(15,2) CHECK (max_single_debit > 0),
+    daily_debit_limit DECIMAL(15,2) CHECK (daily_debit_limit > 0),
+    overdraft_limit DECIMAL(15,2) CHECK (overdraft_limit >= 0),
+    max_debits_per_hour INTEGER CHECK (max_debits_per_hour > 0),
+    max_debits_per_day INTEGER CHECK (max_debits_per_day > 0),
+    updated_by VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Let deposit accounts go below zero within their overdraft. The floor
+-- depends on the limits tables, so a trigger replaces the check constraint.
+-- Only balances going down are checked, so lowering an overdraft leaves
+-- accounts already past it able to take credits.
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_balance_check;
+CREATE OR REPLACE FUNCTION check_customer_balance() RETURNS trigger AS $$
+BEGIN
+    IF NEW.balance < 0 AND NOT NEW.allow_negative
+        AND (TG_OP = 'INSERT' OR NEW.balance < OLD.balance OR OLD.allow_negative)
+        AND (NEW.balance_type <> 'deposit' OR NEW.balance < -COALESCE(
+        (SELECT overdraft_limit FROM customer_limits WHERE customer_id = NEW.id),
+        (SELECT overdraft_limit FROM limit_defaults), 0)) THEN
+        RAISE EXCEPTION 'balance of customer % is below its floor', NEW.id USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS customers_balance_check ON customers;
+CREATE TRIGGER customers_balance_check BEFORE INSERT OR UPDATE OF balance, allow_negative ON customers
+    FOR EACH ROW EXECUTE FUNCTION check_customer_balance();
+
+-- Account aliases: a short handle such as @jane that resolves to one
+-- customer. Reservations keep an alias from being taken, either by an
+-- operator or for a while after its owner let it go.
+CREATE TABLE IF NOT EXISTS account_aliases (
+    alias VARCHAR(30) PRIMARY KEY,
+    customer_id UUID NOT NULL UNIQUE REFERENCES customers(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alias_reservations (
+    alias VARCHAR(30) PRIMARY KEY,
+    customer_id UUID REFERENCES customers(id),
+    reason TEXT NOT NULL,
+    reserved_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alias_changes (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    alias VARCHAR(30),
+    previous_alias VARCHAR(30),
+    changed_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alias_changes_customer ON alias_changes(customer_id, created_at DESC);
+
+-- Record which exchange rate provider each quoted or applied rate came from
+ALTER TABLE fx_quotes ADD COLUMN IF NOT EXISTS rate_provider VARCHAR(50);
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS rate_provider VARCHAR(50);
+
+-- Exchange rates fetched for conversions, one row per provider update, so a
+-- past conversion can be repeated at the rate that applied then
+CREATE TABLE IF NOT EXISTS fx_rates (
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    provider VARCHAR(50) NOT NULL DEFAULT '',
+    effective_at TIMESTAMPTZ NOT NULL,
+    rate DECIMAL(20,10) NOT NULL CHECK (rate > 0),
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (from_currency, to_currency, provider, effective_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fx_rates_pair_effective ON fx_rates(from_currency, to_currency, effective_at DESC);
+
+-- What a transaction posted in another currency than the account's was
+-- posted as, before conversion
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS original_amount DECIMAL(15,2);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS original_currency CHAR(3);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate DECIMAL(20,10);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS rate_provider VARCHAR(50);
+
+-- What conversions round away. Each difference is exact less rounded, kept
+-- to well below the minor unit; whole minor units are posted to the
+-- currency's fx_rounding account and the remainder carried per currency.
+INSERT INTO gl_accounts (code, name, category, normal_balance, currency, system) VALUES
+    ('fx_rounding_usd', 'FX rounding differences (USD)', 'liability', 'credit', 'USD', TRUE),
+    ('fx_rounding_eur', 'FX rounding differences (EUR)', 'liability', 'credit', 'EUR', TRUE),
+    ('fx_rounding_gbp', 'FX rounding differences (GBP)', 'liability', 'credit', 'GBP', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS fx_rounding_differences (
+    id UUID PRIMARY KEY,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('move', 'transaction')),
+    source_id UUID NOT NULL,
+    currency CHAR(3) NOT NULL,
+    exact_amount DECIMAL(24,10) NOT NULL,
+    rounded_amount DECIMAL(15,2) NOT NULL,
+    difference DECIMAL(20,10) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fx_rounding_differences_currency ON fx_rounding_differences(currency, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS fx_rounding_totals (
+    currency CHAR(3) PRIMARY KEY,
+    carried DECIMAL(20,10) NOT NULL DEFAULT 0
+);
+
+-- Split transfers: one debit from the payer and a transfer per recipient,
+-- all sharing a batch ID
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS batch_id UUID;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS batch_id UUID;
+CREATE INDEX IF NOT EXISTS idx_transfers_batch_id ON transfers(batch_id) WHERE batch_id IS NOT NULL;
+
+-- Payment references: a reference marked unique may be held by one
+-- transaction per customer, so re-submitted bank files cannot post a payment
+-- twice; rejected transactions free it again
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reference VARCHAR(140);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS unique_reference BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_unique_reference ON transactions(customer_id, reference)
+    WHERE unique_reference AND status <> 'rejected';
+
+-- Reference lookups across customers
+CREATE INDEX IF NOT EXISTS idx_transactions_reference ON transactions(reference) WHERE reference IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transfers_reference ON transfers(reference) WHERE reference IS NOT NULL;
+
+-- Duplicate detection: a fraud rule matching a recent transaction of the
+-- same customer, type and amount
+ALTER TABLE fraud_rules DROP CONSTRAINT IF EXISTS fraud_rules_rule_type_check;
+ALTER TABLE fraud_rules ADD CONSTRAINT fraud_rules_rule_type_check
+    CHECK (rule_type IN ('amount_spike', 'rapid_debits', 'unusual_hours', 'duplicate'));
+
+-- Reserve-then-settle transfers: the payer is debited when funds are
+-- reserved, and the reservation is later settled to the payee or released
+-- back with a transfer_release
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'completed'
+    CHECK (status IN ('completed', 'reserved', 'settled', 'released'));
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_transfers_reserved ON transfers(created_at) WHERE status = 'reserved';
+
+INSERT INTO transaction_types (code, direction, description, postable) VALUES
+    ('transfer_release', 'credit', 'Reserved transfer released back to the payer', FALSE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Sagas: a posting coordinated with actions in other services, saved after
+-- every step; the steps and their outcomes are kept as JSON
+CREATE TABLE IF NOT EXISTS sagas (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    type VARCHAR(20) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    reference VARCHAR(140),
+    status VARCHAR(20) NOT NULL
+        CHECK (status IN ('running', 'completed', 'failed', 'compensating', 'compensated', 'compensation_failed')),
+    error TEXT,
+    transaction_id UUID REFERENCES transactions(id),
+    reversal_transaction_id UUID REFERENCES transactions(id),
+    steps JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sagas_customer ON sagas(customer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sagas_unfinished ON sagas(updated_at)
+    WHERE status IN ('running', 'compensating', 'compensation_failed');
+
+INSERT INTO transaction_types (code, direction, description, postable) VALUES
+    ('reversal_credit', 'credit', 'Reversal of a debit whose saga failed', FALSE),
+    ('reversal_debit', 'debit', 'Reversal of a credit whose saga failed', FALSE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Bank links: a customer's bank account linked through Plaid, whose
+-- transactions are synced from a cursor and either mirrored into the ledger
+-- or matched against postings already made. syncing_since claims a link for
+-- one sync at a time.
+CREATE TABLE IF NOT EXISTS bank_links (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    item_id VARCHAR(100) NOT NULL,
+    access_token TEXT NOT NULL,
+    account_id VARCHAR(100) NOT NULL,
+    mode VARCHAR(20) NOT NULL DEFAULT 'mirror' CHECK (mode IN ('mirror', 'reconcile')),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'error', 'unlinked')),
+    cursor TEXT,
+    last_error TEXT,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    syncing_since TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (item_id, account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_links_customer ON bank_links(customer_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS bank_transactions (
+    link_id UUID NOT NULL REFERENCES bank_links(id),
+    external_id VARCHAR(100) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('credit', 'debit')),
+    currency VARCHAR(3),
+    date DATE NOT NULL,
+    description VARCHAR(140),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('posted', 'matched', 'unmatched')),
+    transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (link_id, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_transactions_date ON bank_transactions(link_id, date DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_transactions_transaction ON bank_transactions(transaction_id) WHERE transaction_id IS NOT NULL;
+
+INSERT INTO transaction_types (code, direction, description, postable) VALUES
+    ('bank_credit', 'credit', 'Money into a linked bank account, mirrored from the bank', TRUE),
+    ('bank_debit', 'debit', 'Money out of a linked bank account, mirrored from the bank', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Stripe: the connected account each customer's withdrawals are paid out
+-- from, and the Stripe payouts and charges the ledger posted, by Stripe ID
+CREATE TABLE IF NOT EXISTS stripe_accounts (
+    customer_id UUID PRIMARY KEY REFERENCES customers(id),
+    account_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS stripe_objects (
+    id VARCHAR(100) PRIMARY KEY,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('payout', 'charge')),
+    account_id VARCHAR(100),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    transaction_id UUID REFERENCES transactions(id),
+    saga_id UUID REFERENCES sagas(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stripe_objects_customer ON stripe_objects(customer_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stripe_objects_saga ON stripe_objects(saga_id) WHERE saga_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_stripe_objects_open ON stripe_objects(created_at)
+    WHERE kind = 'payout' AND status IN ('pending', 'in_transit');
+
+INSERT INTO transaction_types (code, direction, description, postable) VALUES
+    ('withdrawal', 'debit', 'Withdrawal paid out to the customer''s bank through Stripe', TRUE),
+    ('card_payment', 'credit', 'Card payment collected through Stripe', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Payment files: credit transfers to external bank accounts, sent to the
+-- bank as ISO 20022 pain.001 files, and the pain.002 status reports received
+CREATE TABLE IF NOT EXISTS payment_files (
+    id UUID PRIMARY KEY,
+    message_id VARCHAR(35) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'generated'
+        CHECK (status IN ('generated', 'accepted', 'partially_accepted', 'rejected')),
+    transfers INTEGER NOT NULL,
+    control_sum DECIMAL(15,2) NOT NULL,
+    execution_date DATE NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_files_created ON payment_files(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS credit_transfers (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    creditor_name VARCHAR(70) NOT NULL,
+    creditor_iban VARCHAR(34) NOT NULL,
+    creditor_bic VARCHAR(11),
+    reference VARCHAR(140),
+    end_to_end_id VARCHAR(35) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'exported', 'accepted', 'settled', 'rejected')),
+    status_reason TEXT,
+    payment_file_id UUID REFERENCES payment_files(id),
+    reversal_transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_transfers_customer ON credit_transfers(customer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_credit_transfers_file ON credit_transfers(payment_file_id);
+CREATE INDEX IF NOT EXISTS idx_credit_transfers_pending ON credit_transfers(created_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS payment_status_reports (
+    file_id UUID NOT NULL REFERENCES payment_files(id),
+    message_id VARCHAR(35) NOT NULL,
+    group_status VARCHAR(4),
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (file_id, message_id)
+);
+
+INSERT INTO transaction_types (code, direction, description, postable) VALUES
+    ('credit_transfer', 'debit', 'Credit transfer to an external bank account, sent in a payment file', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+-- MT940 statements: how each customer's statements identify and number the
+-- account, and the statements generated
+CREATE TABLE IF NOT EXISTS statement_accounts (
+    customer_id UUID PRIMARY KEY REFERENCES customers(id),
+    account_identifier VARCHAR(35),
+    next_number INTEGER NOT NULL DEFAULT 1 CHECK (next_number BETWEEN 1 AND 99999),
+    number_year INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mt940_statements (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    statement_number INTEGER NOT NULL,
+    account_identifier VARCHAR(35) NOT NULL,
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    opening_balance DECIMAL(15,2) NOT NULL,
+    closing_balance DECIMAL(15,2) NOT NULL,
+    transactions INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (customer_id, period_from, period_to)
+);
+
+-- CSV import profiles: how each bank's transaction export is read
+CREATE TABLE IF NOT EXISTS import_profiles (
+    name VARCHAR(64) PRIMARY KEY,
+    profile JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Statement reconciliations: external statement lines matched against
+-- ledger transactions
+CREATE TABLE IF NOT EXISTS reconciliations (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    profile VARCHAR(64) NOT NULL,
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL,
+    date_tolerance_days INTEGER NOT NULL DEFAULT 3,
+    amount_tolerance DECIMAL(15,2) NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    created_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    closed_by VARCHAR(100),
+    closed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliations_customer ON reconciliations(customer_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS reconciliation_lines (
+    id UUID PRIMARY KEY,
+    reconciliation_id UUID NOT NULL REFERENCES reconciliations(id),
+    line INTEGER NOT NULL,
+    date DATE NOT NULL,
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('credit', 'debit')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    reference VARCHAR(140),
+    status VARCHAR(10) NOT NULL CHECK (status IN ('matched', 'unmatched', 'confirmed', 'adjusted')),
+    match_rule VARCHAR(20),
+    transaction_id UUID REFERENCES transactions(id),
+    resolved_by VARCHAR(100),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_lines_reconciliation ON reconciliation_lines(reconciliation_id, line);
+-- A transaction is matched with one statement line at most
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_lines_transaction ON reconciliation_lines(transaction_id) WHERE transaction_id IS NOT NULL;
+
+-- Ledger anchors: Merkle roots over batches of posted transactions, each
+-- chained to the one before, with the leaf hash of every anchored
+-- transaction for inclusion proofs
+CREATE TABLE IF NOT EXISTS ledger_anchors (
+    sequence BIGINT PRIMARY KEY,
+    root CHAR(64) NOT NULL,
+    size INTEGER NOT NULL CHECK (size > 0),
+    previous_chain_hash CHAR(64) NOT NULL,
+    chain_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ledger_anchor_leaves (
+    transaction_id UUID PRIMARY KEY REFERENCES transactions(id),
+    anchor_sequence BIGINT NOT NULL REFERENCES ledger_anchors(sequence),
+    leaf_index INTEGER NOT NULL,
+    leaf_hash CHAR(64) NOT NULL,
+    UNIQUE (anchor_sequence, leaf_index)
+);
+
+-- Personal data may be stored encrypted (see PII_ENCRYPTION_KEYS), which
+-- outgrows the original column widths; dates of birth are kept as
+-- YYYY-MM-DD text so they can be encrypted too
+ALTER TABLE customers ALTER COLUMN name TYPE TEXT;
+ALTER TABLE customers ALTER COLUMN email TYPE TEXT;
+ALTER TABLE customers ALTER COLUMN phone_number TYPE TEXT;
+ALTER TABLE customers ALTER COLUMN date_of_birth TYPE TEXT USING date_of_birth::text;
+ALTER TABLE customer_addresses ALTER COLUMN line1 TYPE TEXT;
+ALTER TABLE customer_addresses ALTER COLUMN line2 TYPE TEXT;
+ALTER TABLE customer_addresses ALTER COLUMN city TYPE TEXT;
+ALTER TABLE customer_addresses ALTER COLUMN region TYPE TEXT;
+ALTER TABLE customer_addresses ALTER COLUMN postal_code TYPE TEXT;
+ALTER TABLE customer_documents ALTER COLUMN reference TYPE TEXT;
+
+-- Scoped API keys for integrations; only the hash of each key is kept, and
+-- scopes are read on every request so changes apply without rotation
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Durable job queue for background work: webhook deliveries, statement
+-- rendering, CSV imports and SMS notifications. Workers claim due jobs
+-- with SKIP LOCKED and lease them until locked_until; a job whose worker
+-- stopped is claimed again once the lease passes.
+CREATE TABLE IF NOT EXISTS queue_jobs (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    customer_id UUID REFERENCES customers(id),
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(10) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'dead', 'cancelled')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL CHECK (max_attempts > 0),
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    result JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_jobs_due ON queue_jobs(kind, run_at) WHERE status IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS idx_queue_jobs_status ON queue_jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_queue_jobs_customer ON queue_jobs(customer_id, created_at) WHERE customer_id IS NOT NULL;
+
+-- Customer statistics sum each customer's posted transactions; the covering
+-- index answers them without reading the table
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_posted_stats ON transactions(customer_id) INCLUDE (type, amount, created_at) WHERE status = 'posted';
+
+-- Record the rounding mode applied to a transaction converted into the
+-- account's currency, as moves and quotes do
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS rounding VARCHAR(10);
+
+-- A transaction's value date defaults to the date it posted in the
+-- customer's timezone rather than the database session's. A column default
+-- cannot read the customer, so a trigger fills it in.
+ALTER TABLE transactions ALTER COLUMN value_date DROP DEFAULT;
+CREATE OR REPLACE FUNCTION default_transaction_value_date() RETURNS trigger AS $$
+BEGIN
+    IF NEW.value_date IS NULL THEN
+        NEW.value_date := (COALESCE(NEW.created_at, CURRENT_TIMESTAMP) AT TIME ZONE
+            COALESCE((SELECT timezone FROM customers WHERE id = NEW.customer_id), 'UTC'))::date;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS transactions_value_date ON transactions;
+CREATE TRIGGER transactions_value_date BEFORE INSERT ON transactions
+    FOR EACH ROW EXECUTE FUNCTION default_transaction_value_date();
+
+-- A transfer, split transfer or reservation whose payer's debit needs
+-- approval waits as pending_approval, nothing moved, and records the status
+-- it takes once approved. Rejected ones never move money.
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS approved_status VARCHAR(20);
+ALTER TABLE transfers DROP CONSTRAINT IF EXISTS transfers_status_check;
+ALTER TABLE transfers ADD CONSTRAINT transfers_status_check
+    CHECK (status IN ('completed', 'reserved', 'settled', 'released', 'pending_approval', 'rejected'));
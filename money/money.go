@@ -0,0 +1,130 @@
+// Package money rounds amounts to a currency's minor unit. The rounding mode
+// is chosen per currency by a Policy, so conversions and any fee or interest
+// the service derives round the same way.
+package money
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Rounding is how an amount is rounded to the minor unit
+type Rounding string
+
+const (
+	// HalfUp rounds ties away from zero
+	HalfUp Rounding = "half_up"
+	// HalfEven rounds ties to the even digit (banker's rounding)
+	HalfEven Rounding = "half_even"
+	// Truncate drops the extra digits, rounding toward zero
+	Truncate Rounding = "truncate"
+	// Down rounds toward negative infinity
+	Down Rounding = "down"
+	// Up rounds toward positive infinity
+	Up Rounding = "up"
+)
+
+// ParseRounding validates a rounding mode name, defaulting to half_up when empty
+func ParseRounding(s string) (Rounding, error) {
+	switch r := Rounding(s); r {
+	case "":
+		return HalfUp, nil
+	case HalfUp, HalfEven, Truncate, Down, Up:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q (want half_up, half_even, truncate, down or up)", s)
+	}
+}
+
+// Round rounds amount to the given number of decimal places
+func (r Rounding) Round(amount float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	// Snap away float noise (e.g. 1.005*100 = 100.49999...) before rounding
+	units := math.Round(amount*scale*1e6) / 1e6
+	switch r {
+	case HalfEven:
+		units = math.RoundToEven(units)
+	case Truncate:
+		units = math.Trunc(units)
+	case Down:
+		units = math.Floor(units)
+	case Up:
+		units = math.Ceil(units)
+	default:
+		units = math.Round(units)
+	}
+	return units / scale
+}
+
+// decimals is the number of minor-unit digits of each supported currency
+var decimals = map[string]int{
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+}
+
+// Decimals returns the number of minor-unit digits of a currency, 2 for
+// currencies it does not know
+func Decimals(currency string) int {
+	if d, ok := decimals[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// Policy picks the rounding mode for each currency
+type Policy struct {
+	// Default applies to currencies without an override; half_up when empty
+	Default Rounding
+	// Currencies overrides the mode by ISO 4217 code
+	Currencies map[string]Rounding
+}
+
+// For returns the rounding mode used for amounts in currency
+func (p Policy) For(currency string) Rounding {
+	if r, ok := p.Currencies[currency]; ok {
+		return r
+	}
+	if p.Default == "" {
+		return HalfUp
+	}
+	return p.Default
+}
+
+// Round rounds an amount in currency to its minor unit, returning the mode
+// it applied so callers can record it
+func (p Policy) Round(amount float64, currency string) (float64, Rounding) {
+	r := p.For(currency)
+	return r.Round(amount, Decimals(currency)), r
+}
+
+// Convert applies an exchange rate and rounds the result in the target
+// currency
+func (p Policy) Convert(amount, rate float64, to string) (float64, Rounding) {
+	return p.Round(amount*rate, to)
+}
+
+// ParsePolicy builds a policy from a default mode and comma-separated
+// per-currency overrides such as "EUR=half_even,GBP=truncate"
+func ParsePolicy(def, overrides string) (Policy, error) {
+	r, err := ParseRounding(def)
+	if err != nil {
+		return Policy{}, err
+	}
+	p := Policy{Default: r, Currencies: map[string]Rounding{}}
+	for _, entry := range strings.Split(overrides, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		currency, mode, ok := strings.Cut(entry, "=")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !ok || len(currency) != 3 {
+			return Policy{}, fmt.Errorf("invalid rounding override %q (want CUR=mode)", entry)
+		}
+		if p.Currencies[currency], err = ParseRounding(strings.TrimSpace(mode)); err != nil {
+			return Policy{}, fmt.Errorf("currency %s: %w", currency, err)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,77 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   map[Rounding]float64
+	}{
+		// Ties, where half_up and half_even differ
+		{1.005, map[Rounding]float64{HalfUp: 1.01, HalfEven: 1, Truncate: 1, Down: 1, Up: 1.01}},
+		{1.015, map[Rounding]float64{HalfUp: 1.02, HalfEven: 1.02, Truncate: 1.01, Down: 1.01, Up: 1.02}},
+		{-1.005, map[Rounding]float64{HalfUp: -1.01, HalfEven: -1, Truncate: -1, Down: -1.01, Up: -1}},
+		// Below and above the tie
+		{1.004, map[Rounding]float64{HalfUp: 1, HalfEven: 1, Truncate: 1, Down: 1, Up: 1.01}},
+		{1.019, map[Rounding]float64{HalfUp: 1.02, HalfEven: 1.02, Truncate: 1.01, Down: 1.01, Up: 1.02}},
+		{-1.019, map[Rounding]float64{HalfUp: -1.02, HalfEven: -1.02, Truncate: -1.01, Down: -1.02, Up: -1.01}},
+		// Already in cents, including values float arithmetic smears
+		{1.01, map[Rounding]float64{HalfUp: 1.01, HalfEven: 1.01, Truncate: 1.01, Down: 1.01, Up: 1.01}},
+		{0.1 + 0.2, map[Rounding]float64{HalfUp: 0.3, HalfEven: 0.3, Truncate: 0.3, Down: 0.3, Up: 0.3}},
+		{0, map[Rounding]float64{HalfUp: 0, HalfEven: 0, Truncate: 0, Down: 0, Up: 0}},
+	}
+	for _, tt := range tests {
+		for _, r := range []Rounding{HalfUp, HalfEven, Truncate, Down, Up} {
+			assert.Equal(t, tt.want[r], r.Round(tt.amount, 2), "%s(%v)", r, tt.amount)
+		}
+	}
+
+	assert.Equal(t, float64(2), HalfEven.Round(2.5, 0))
+	assert.Equal(t, float64(3), HalfUp.Round(2.5, 0))
+	assert.Equal(t, 1.235, HalfUp.Round(1.2345, 3))
+}
+
+func TestParseRounding(t *testing.T) {
+	r, err := ParseRounding("")
+	assert.NoError(t, err)
+	assert.Equal(t, HalfUp, r)
+
+	for _, name := range []string{"half_up", "half_even", "truncate", "down", "up"} {
+		r, err = ParseRounding(name)
+		assert.NoError(t, err)
+		assert.Equal(t, Rounding(name), r)
+	}
+
+	_, err = ParseRounding("bankers")
+	assert.Error(t, err)
+}
+
+func TestPolicy(t *testing.T) {
+	p, err := ParsePolicy("half_even", " eur=truncate , GBP=up")
+	require.NoError(t, err)
+	assert.Equal(t, HalfEven, p.For("USD"))
+	assert.Equal(t, Truncate, p.For("EUR"))
+	assert.Equal(t, Up, p.For("GBP"))
+
+	amount, applied := p.Convert(100, 0.91239, "EUR")
+	assert.Equal(t, 91.23, amount)
+	assert.Equal(t, Truncate, applied)
+	amount, applied = p.Round(10.005, "USD")
+	assert.Equal(t, float64(10), amount)
+	assert.Equal(t, HalfEven, applied)
+
+	assert.Equal(t, HalfUp, Policy{}.For("USD"), "the zero policy rounds half up")
+	assert.Equal(t, 2, Decimals("XYZ"))
+
+	_, err = ParsePolicy("", "EUR")
+	assert.Error(t, err)
+	_, err = ParsePolicy("", "EUR=bankers")
+	assert.Error(t, err)
+	_, err = ParsePolicy("ceiling", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SMSProvider sends a text message to a single recipient
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// TwilioProvider sends SMS through the Twilio Messages API or any service
+// exposing a compatible endpoint
+type TwilioProvider struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	BaseURL    string
+	Client     *http.Client
+}
+
+// NewTwilioProvider creates a provider targeting the public Twilio API
+func NewTwilioProvider(accountSID, authToken, from string) *TwilioProvider {
+	return &TwilioProvider{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		BaseURL:    "https://api.twilio.com",
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SendSMS posts a message to the provider's Messages endpoint
+func (p *TwilioProvider) SendSMS(ctx context.Context, to, body string) error {
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json",
+		strings.TrimRight(p.BaseURL, "/"), url.PathEscape(p.AccountSID))
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", p.From)
+	form.Set("Body", body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %v", err)
+	}
+	req.SetBasicAuth(p.AccountSID, p.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call SMS provider: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS provider returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// TransactionEvent describes a posted transaction for notification purposes
+type TransactionEvent struct {
+	CustomerID      uuid.UUID
+	PhoneNumber     string
+	OptIn           bool
+	Type            string // balance direction, credit or debit
+	Amount          float64
+	PreviousBalance float64
+	Balance         float64
+}
+
+// Notifier decides which alerts a transaction triggers and sends them
+type Notifier struct {
+	provider            SMSProvider
+	limiter             *RateLimiter
+	HighValueThreshold  float64
+	LowBalanceThreshold float64
+}
+
+// NewNotifier creates a notifier. A zero threshold disables the matching alert.
+func NewNotifier(provider SMSProvider, limiter *RateLimiter, highValue, lowBalance float64) *Notifier {
+	return &Notifier{
+		provider:            provider,
+		limiter:             limiter,
+		HighValueThreshold:  highValue,
+		LowBalanceThreshold: lowBalance,
+	}
+}
+
+// Messages returns the alert texts a transaction should produce
+func (n *Notifier) Messages(ev TransactionEvent) []string {
+	var messages []string
+	if n.HighValueThreshold > 0 && ev.Amount >= n.HighValueThreshold {
+		messages = append(messages, fmt.Sprintf(
+			"Ledger alert: a %s of %.2f was posted to your account. New balance: %.2f",
+			ev.Type, ev.Amount, ev.Balance))
+	}
+	if n.LowBalanceThreshold > 0 && ev.Type == "debit" &&
+		ev.PreviousBalance >= n.LowBalanceThreshold && ev.Balance < n.LowBalanceThreshold {
+		messages = append(messages, fmt.Sprintf(
+			"Ledger warning: your balance is low (%.2f)", ev.Balance))
+	}
+	return messages
+}
+
+// TransactionPosted sends any alerts for the event to an opted-in customer.
+// Messages over the customer's rate limit are dropped.
+func (n *Notifier) TransactionPosted(ctx context.Context, ev TransactionEvent) {
+	if !ev.OptIn || ev.PhoneNumber == "" {
+		return
+	}
+	for _, msg := range n.Messages(ev) {
+		if n.limiter != nil && !n.limiter.Allow(ev.CustomerID.String()) {
+			log.Printf("SMS rate limit reached for customer %s", ev.CustomerID)
+			return
+		}
+		if err := n.provider.SendSMS(ctx, ev.PhoneNumber, msg); err != nil {
+			log.Printf("Failed to send SMS to customer %s: %v", ev.CustomerID, err)
+		}
+	}
+}
+
+// ErrRateLimited is returned by Send when the customer has had as many
+// messages as the rate limit allows
+var ErrRateLimited = errors.New("SMS rate limit reached")
+
+// Send sends a single message to a customer's phone, subject to the rate limit
+func (n *Notifier) Send(ctx context.Context, customerID uuid.UUID, phone, msg string) error {
+	if n.limiter != nil && !n.limiter.Allow(customerID.String()) {
+		return ErrRateLimited
+	}
+	return n.provider.SendSMS(ctx, phone, msg)
+}
+
+// Alert sends a single message to an opted-in customer, subject to the rate limit
+func (n *Notifier) Alert(ctx context.Context, customerID uuid.UUID, phone string, optIn bool, msg string) {
+	if !optIn || phone == "" {
+		return
+	}
+	switch err := n.Send(ctx, customerID, phone, msg); {
+	case errors.Is(err, ErrRateLimited):
+		log.Printf("SMS rate limit reached for customer %s", customerID)
+	case err != nil:
+		log.Printf("Failed to send SMS to customer %s: %v", customerID, err)
+	}
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProvider struct {
+	sent []string
+}
+
+func (f *fakeProvider) SendSMS(ctx context.Context, to, body string) error {
+	f.sent = append(f.sent, to+": "+body)
+	return nil
+}
+
+func TestNotifierMessages(t *testing.T) {
+	n := NewNotifier(&fakeProvider{}, nil, 1000, 100)
+
+	tests := []struct {
+		name  string
+		event TransactionEvent
+		want  int
+	}{
+		{
+			name:  "small credit",
+			event: TransactionEvent{Type: "credit", Amount: 50, PreviousBalance: 500, Balance: 550},
+			want:  0,
+		},
+		{
+			name:  "high value credit",
+			event: TransactionEvent{Type: "credit", Amount: 1500, PreviousBalance: 500, Balance: 2000},
+			want:  1,
+		},
+		{
+			name:  "debit crossing low balance threshold",
+			event: TransactionEvent{Type: "debit", Amount: 450, PreviousBalance: 500, Balance: 50},
+			want:  1,
+		},
+		{
+			name:  "debit already below threshold",
+			event: TransactionEvent{Type: "debit", Amount: 10, PreviousBalance: 50, Balance: 40},
+			want:  0,
+		},
+		{
+			name:  "high value debit crossing threshold",
+			event: TransactionEvent{Type: "debit", Amount: 1950, PreviousBalance: 2000, Balance: 50},
+			want:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Len(t, n.Messages(tt.event), tt.want)
+		})
+	}
+}
+
+func TestNotifierTransactionPosted(t *testing.T) {
+	event := TransactionEvent{
+		CustomerID:  uuid.New(),
+		PhoneNumber: "+15551234567",
+		OptIn:       true,
+		Type:        "credit",
+		Amount:      5000,
+		Balance:     5000,
+	}
+
+	t.Run("opted in", func(t *testing.T) {
+		provider := &fakeProvider{}
+		NewNotifier(provider, nil, 1000, 0).TransactionPosted(context.Background(), event)
+		assert.Len(t, provider.sent, 1)
+	})
+
+	t.Run("opted out", func(t *testing.T) {
+		provider := &fakeProvider{}
+		ev := event
+		ev.OptIn = false
+		NewNotifier(provider, nil, 1000, 0).TransactionPosted(context.Background(), ev)
+		assert.Empty(t, provider.sent)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		provider := &fakeProvider{}
+		n := NewNotifier(provider, NewRateLimiter(2, time.Hour), 1000, 0)
+		for i := 0; i < 5; i++ {
+			n.TransactionPosted(context.Background(), event)
+		}
+		assert.Len(t, provider.sent, 2)
+	})
+}
+
+func TestNotifierAlert(t *testing.T) {
+	provider := &fakeProvider{}
+	n := NewNotifier(provider, NewRateLimiter(1, time.Hour), 0, 0)
+	customerID := uuid.New()
+
+	n.Alert(context.Background(), customerID, "+15551234567", false, "ignored")
+	n.Alert(context.Background(), customerID, "+15551234567", true, "first")
+	n.Alert(context.Background(), customerID, "+15551234567", true, "limited")
+	assert.Equal(t, []string{"+15551234567: first"}, provider.sent)
+}
+
+func TestNotifierSend(t *testing.T) {
+	provider := &fakeProvider{}
+	n := NewNotifier(provider, NewRateLimiter(1, time.Hour), 0, 0)
+	customerID := uuid.New()
+
+	assert.NoError(t, n.Send(context.Background(), customerID, "+15551234567", "first"))
+	assert.ErrorIs(t, n.Send(context.Background(), customerID, "+15551234567", "limited"), ErrRateLimited)
+	assert.Equal(t, []string{"+15551234567: first"}, provider.sent)
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	now := time.Now()
+	r := NewRateLimiter(1, time.Minute)
+	r.now = func() time.Time { return now }
+
+	assert.True(t, r.Allow("a"))
+	assert.False(t, r.Allow("a"))
+	assert.True(t, r.Allow("b"))
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, r.Allow("a"))
+}
+
+func TestTwilioProviderSendSMS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "secret", pass)
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "+15551234567", r.PostForm.Get("To"))
+		assert.Equal(t, "+15550000000", r.PostForm.Get("From"))
+		assert.Equal(t, "hello", r.PostForm.Get("Body"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	p := NewTwilioProvider("AC123", "secret", "+15550000000")
+	p.BaseURL = server.URL
+	assert.NoError(t, p.SendSMS(context.Background(), "+15551234567", "hello"))
+
+	p.BaseURL = server.URL + "/missing"
+	server.Config.Handler = http.NotFoundHandler()
+	assert.Error(t, p.SendSMS(context.Background(), "+15551234567", "hello"))
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most Limit sends per key within a sliding Window
+type RateLimiter struct {
+	Limit  int
+	Window time.Duration
+
+	mu   sync.Mutex
+	sent map[string][]time.Time
+	now  func() time.Time
+}
+
+// NewRateLimiter creates a limiter allowing limit sends per key per window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		Limit:  limit,
+		Window: window,
+		sent:   make(map[string][]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Allow records a send for key and reports whether it is within the limit
+func (r *RateLimiter) Allow(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	cutoff := now.Add(-r.Window)
+	recent := r.sent[key][:0]
+	for _, t := range r.sent[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= r.Limit {
+		r.sent[key] = recent
+		return false
+	}
+	r.sent[key] = append(recent, now)
+	return true
+}
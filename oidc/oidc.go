@@ -0,0 +1,356 @@
+// Package oidc validates access tokens issued by an external OpenID Connect
+// provider and maps the provider's groups to ledger roles, so operators can
+// sign in with corporate SSO
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken is wrapped by every error for a token that is malformed,
+// badly signed, expired or issued for someone else
+var ErrInvalidToken = errors.New("invalid token")
+
+// leeway absorbs clock skew between the provider and this service
+const leeway = time.Minute
+
+// minRefreshInterval limits how often a token with an unknown key ID can make
+// the verifier refetch the key set
+const minRefreshInterval = time.Minute
+
+// Config describes the provider and how its tokens map to ledger roles
+type Config struct {
+	// Issuer must match the token's iss claim exactly. The signing keys are
+	// discovered from its /.well-known/openid-configuration.
+	Issuer string
+	// Audience must appear in the token's aud claim
+	Audience string
+	// JWKSURL overrides the discovered key set URL
+	JWKSURL string
+	// GroupsClaim names the claim listing the operator's groups, "groups" when empty
+	GroupsClaim string
+	// GroupRoles maps provider groups to the ledger roles they grant
+	GroupRoles map[string][]string
+	// CacheTTL is how long fetched signing keys are reused, an hour when zero
+	CacheTTL time.Duration
+	// Client fetches discovery documents and key sets
+	Client *http.Client
+}
+
+// Identity is an operator authenticated by a valid token
+type Identity struct {
+	Subject string
+	// Name is the preferred_username, email or subject, in that order
+	Name   string
+	Groups []string
+	// Roles are the ledger roles granted by Groups, sorted
+	Roles []string
+}
+
+// Verifier checks tokens against the provider's cached signing keys
+type Verifier struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewVerifier creates a verifier for cfg. Keys are fetched on first use.
+func NewVerifier(cfg Config) *Verifier {
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Hour
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Verifier{cfg: cfg, now: time.Now}
+}
+
+// ParseGroupRoles parses a comma-separated list of group=role pairs, such as
+// "ledger-admins=admin,finance-ops=admin". A group may appear more than once
+// to grant several roles.
+func ParseGroupRoles(spec string) (map[string][]string, error) {
+	mapping := map[string][]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid group mapping %q: want group=role", pair)
+		}
+		mapping[group] = append(mapping[group], role)
+	}
+	return mapping, nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	Expiry            float64  `json:"exp"`
+	NotBefore         float64  `json:"nbf"`
+	Email             string   `json:"email"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience accepts the aud claim as either a string or an array
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, fmt.Sprintf(format, args...))
+}
+
+// Verify checks raw's signature, issuer, audience and lifetime and returns
+// the operator it identifies. Tokens must be signed with RS256 or ES256.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Identity, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, invalid("not a JWT")
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, invalid("malformed header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed signature")
+	}
+
+	key, err := v.key(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch h.Alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, invalid("bad signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 ||
+			!ecdsa.Verify(ecKey, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+			return nil, invalid("bad signature")
+		}
+	default:
+		return nil, invalid("unsupported algorithm %q", h.Alg)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, invalid("malformed claims")
+	}
+	now := v.now()
+	if c.Issuer != v.cfg.Issuer {
+		return nil, invalid("unexpected issuer %q", c.Issuer)
+	}
+	if !contains(c.Audience, v.cfg.Audience) {
+		return nil, invalid("audience does not include %q", v.cfg.Audience)
+	}
+	if c.Expiry == 0 || now.After(time.Unix(int64(c.Expiry), 0).Add(leeway)) {
+		return nil, invalid("expired")
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(int64(c.NotBefore), 0)) {
+		return nil, invalid("not valid yet")
+	}
+	if c.Subject == "" {
+		return nil, invalid("missing subject")
+	}
+
+	id := &Identity{Subject: c.Subject, Name: c.Subject}
+	if c.Email != "" {
+		id.Name = c.Email
+	}
+	if c.PreferredUsername != "" {
+		id.Name = c.PreferredUsername
+	}
+	var all map[string]json.RawMessage
+	if err := decodeSegment(parts[1], &all); err == nil {
+		if groups, ok := all[v.cfg.GroupsClaim]; ok {
+			// Providers send a single group as a plain string
+			if json.Unmarshal(groups, &id.Groups) != nil {
+				var group string
+				if json.Unmarshal(groups, &group) == nil {
+					id.Groups = []string{group}
+				}
+			}
+		}
+	}
+	roles := map[string]bool{}
+	for _, g := range id.Groups {
+		for _, r := range v.cfg.GroupRoles[g] {
+			roles[r] = true
+		}
+	}
+	for r := range roles {
+		id.Roles = append(id.Roles, r)
+	}
+	sort.Strings(id.Roles)
+	return id, nil
+}
+
+func decodeSegment(segment string, dest interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dest)
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the signing key with kid, refetching the key set when the
+// cache has expired or, at most once a minute, when kid is not in it so
+// rotated keys are picked up
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	key, ok := v.keys[kid]
+	stale := v.keys == nil || now.Sub(v.fetchedAt) > v.cfg.CacheTTL
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(v.fetchedAt) < minRefreshInterval {
+		return nil, invalid("unknown key %q", kid)
+	}
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		// Keep using the previous keys while the provider is unreachable
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	v.keys, v.fetchedAt = keys, now
+	if key, ok = keys[kid]; !ok {
+		return nil, invalid("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	jwksURL := v.cfg.JWKSURL
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimRight(v.cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("OIDC discovery document has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, jwksURL, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if k.Crv != "P-256" || errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request for %s: %v", url, err)
+	}
+	resp, err := v.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func sign(t *testing.T, key crypto.Signer, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	signingInput := b64(h) + "." + b64(c)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + b64(sig)
+}
+
+func TestVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var jwksFetches int
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		jwksFetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+		}})
+	})
+
+	v := NewVerifier(Config{
+		Issuer:     server.URL,
+		Audience:   "ledger",
+		GroupRoles: map[string][]string{"ledger-admins": {"admin"}},
+	})
+	now := time.Now()
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":                server.URL,
+			"sub":                "00u1",
+			"aud":                []string{"ledger", "other"},
+			"exp":                now.Add(time.Hour).Unix(),
+			"preferred_username": "alice",
+			"groups":             []string{"ledger-admins", "everyone"},
+		}
+	}
+	with := func(key, value string) map[string]interface{} {
+		c := valid()
+		c[key] = value
+		return c
+	}
+
+	t.Run("valid RS256 token", func(t *testing.T) {
+		id, err := v.Verify(context.Background(), sign(t, rsaKey, "RS256", "rsa-1", valid()))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", id.Name)
+		assert.Equal(t, []string{"ledger-admins", "everyone"}, id.Groups)
+		assert.Equal(t, []string{"admin"}, id.Roles)
+	})
+
+	t.Run("valid ES256 token without roles", func(t *testing.T) {
+		c := valid()
+		c["groups"] = "everyone"
+		c["aud"] = "ledger"
+		delete(c, "preferred_username")
+		c["email"] = "bob@example.com"
+		id, err := v.Verify(context.Background(), sign(t, ecKey, "ES256", "ec-1", c))
+		require.NoError(t, err)
+		assert.Equal(t, "bob@example.com", id.Name)
+		assert.Equal(t, []string{"everyone"}, id.Groups)
+		assert.Empty(t, id.Roles)
+	})
+
+	invalidTokens := map[string]string{
+		"wrong issuer":    sign(t, rsaKey, "RS256", "rsa-1", with("iss", "https://evil.example.com")),
+		"wrong audience":  sign(t, rsaKey, "RS256", "rsa-1", with("aud", "billing")),
+		"expired":         sign(t, rsaKey, "RS256", "rsa-1", map[string]interface{}{"iss": server.URL, "sub": "00u1", "aud": "ledger", "exp": now.Add(-time.Hour).Unix()}),
+		"forged":          sign(t, otherKey, "RS256", "rsa-1", valid()),
+		"algorithm none":  sign(t, rsaKey, "none", "rsa-1", valid()),
+		"key type switch": sign(t, rsaKey, "ES256", "rsa-1", valid()),
+		"not a JWT":       "opaque-token",
+	}
+	for name, token := range invalidTokens {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.Verify(context.Background(), token)
+			assert.True(t, errors.Is(err, ErrInvalidToken), "got %v", err)
+		})
+	}
+
+	t.Run("keys are cached", func(t *testing.T) {
+		assert.Equal(t, 1, jwksFetches)
+		// An unknown key ID does not refetch again within a minute
+		_, err := v.Verify(context.Background(), sign(t, rsaKey, "RS256", "rotated", valid()))
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+		assert.Equal(t, 1, jwksFetches)
+
+		v.now = func() time.Time { return now.Add(2 * time.Minute) }
+		_, err = v.Verify(context.Background(), sign(t, rsaKey, "RS256", "rotated", valid()))
+		assert.True(t, errors.Is(err, ErrInvalidToken))
+		assert.Equal(t, 2, jwksFetches)
+	})
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	mapping, err := ParseGroupRoles("ledger-admins=admin, finance-ops = admin,ledger-admins=auditor")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"ledger-admins": {"admin", "auditor"},
+		"finance-ops":   {"admin"},
+	}, mapping)
+
+	_, err = ParseGroupRoles("ledger-admins")
+	assert.Error(t, err)
+	_, err = ParseGroupRoles("=admin")
+	assert.Error(t, err)
+}
@@ -0,0 +1,283 @@
+// Package openapi derives an OpenAPI 3.1 document from the Swagger 2.0 spec
+// that swag generates from the handler annotations, so both stay in sync
+// without a second set of annotations
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Version is the OpenAPI version of converted documents
+const Version = "3.1.0"
+
+const (
+	problemSchema       = "middleware.Problem"
+	errorResponseSchema = "handlers.ErrorResponse"
+)
+
+// commonParameterNames lists the components/parameters every operation accepts
+var commonParameterNames = []string{"Accept-Language", "X-Request-ID", "traceparent"}
+
+// schemaKeys are the Swagger 2.0 parameter and header fields that move into
+// a "schema" object in OpenAPI 3
+var schemaKeys = []string{
+	"type", "format", "items", "enum", "default", "minimum", "maximum",
+	"exclusiveMinimum", "exclusiveMaximum", "minLength", "maxLength", "pattern",
+	"minItems", "maxItems", "uniqueItems", "example",
+}
+
+// Convert translates a Swagger 2.0 document into OpenAPI 3.1. Every
+// operation also gains the cross-cutting request headers (X-Request-ID,
+// traceparent, Accept-Language), the X-Request-ID response header, and an
+// application/problem+json alternative for error responses.
+func Convert(swagger []byte) ([]byte, error) {
+	var src map[string]interface{}
+	if err := json.Unmarshal(swagger, &src); err != nil {
+		return nil, fmt.Errorf("parse swagger document: %w", err)
+	}
+	if v, _ := src["swagger"].(string); v != "2.0" {
+		return nil, fmt.Errorf("unsupported swagger version %q", v)
+	}
+	rewriteRefs(src)
+
+	doc := map[string]interface{}{
+		"openapi": Version,
+		"info":    src["info"],
+	}
+	if basePath, _ := src["basePath"].(string); basePath != "" {
+		doc["servers"] = []interface{}{map[string]interface{}{"url": basePath}}
+	}
+
+	schemas, _ := src["definitions"].(map[string]interface{})
+	if schemas == nil {
+		schemas = map[string]interface{}{}
+	}
+	schemas[problemSchema] = problemDefinition()
+	doc["components"] = map[string]interface{}{
+		"schemas":    schemas,
+		"parameters": commonParameters(),
+		"headers":    commonHeaders(),
+	}
+
+	paths := map[string]interface{}{}
+	srcPaths, _ := src["paths"].(map[string]interface{})
+	for path, item := range srcPaths {
+		ops, _ := item.(map[string]interface{})
+		converted := map[string]interface{}{}
+		for method, op := range ops {
+			o, ok := op.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			converted[method] = convertOperation(o)
+		}
+		paths[path] = converted
+	}
+	doc["paths"] = paths
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func convertOperation(op map[string]interface{}) map[string]interface{} {
+	consumes := mediaTypes(op["consumes"])
+	produces := mediaTypes(op["produces"])
+
+	out := map[string]interface{}{}
+	for _, key := range []string{"summary", "description", "tags", "operationId", "deprecated", "security"} {
+		if v, ok := op[key]; ok {
+			out[key] = v
+		}
+	}
+
+	var params []interface{}
+	srcParams, _ := op["parameters"].([]interface{})
+	for _, p := range srcParams {
+		param, _ := p.(map[string]interface{})
+		if param["in"] == "body" {
+			body := map[string]interface{}{
+				"content": content(consumes, param["schema"]),
+			}
+			if d, ok := param["description"]; ok {
+				body["description"] = d
+			}
+			if r, ok := param["required"]; ok {
+				body["required"] = r
+			}
+			out["requestBody"] = body
+			continue
+		}
+		params = append(params, convertParameter(param))
+	}
+	for _, name := range commonParameterNames {
+		params = append(params, map[string]interface{}{"$ref": "#/components/parameters/" + name})
+	}
+	out["parameters"] = params
+
+	responses := map[string]interface{}{}
+	srcResponses, _ := op["responses"].(map[string]interface{})
+	for status, r := range srcResponses {
+		resp, _ := r.(map[string]interface{})
+		converted := map[string]interface{}{
+			"description": resp["description"],
+			"headers": map[string]interface{}{
+				"X-Request-ID": map[string]interface{}{"$ref": "#/components/headers/X-Request-ID"},
+			},
+		}
+		headers, _ := resp["headers"].(map[string]interface{})
+		for name, h := range headers {
+			header, _ := h.(map[string]interface{})
+			converted["headers"].(map[string]interface{})[name] = convertHeader(header)
+		}
+		if schema, ok := resp["schema"]; ok {
+			c := content(produces, schema)
+			if isErrorSchema(schema) {
+				c["application/problem+json"] = map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/" + problemSchema},
+				}
+			}
+			converted["content"] = c
+		}
+		responses[status] = converted
+	}
+	out["responses"] = responses
+	return out
+}
+
+func convertParameter(p map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"schema": extractSchema(p)}
+	for _, key := range []string{"name", "in", "description", "required"} {
+		if v, ok := p[key]; ok {
+			out[key] = v
+		}
+	}
+	if p["in"] == "path" {
+		out["required"] = true
+	}
+	return out
+}
+
+func convertHeader(h map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"schema": extractSchema(h)}
+	if d, ok := h["description"]; ok {
+		out["description"] = d
+	}
+	return out
+}
+
+// extractSchema gathers the schema fields of a Swagger 2.0 parameter or header
+func extractSchema(v map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, key := range schemaKeys {
+		if val, ok := v[key]; ok {
+			schema[key] = val
+		}
+	}
+	return schema
+}
+
+func content(types []string, schema interface{}) map[string]interface{} {
+	if len(types) == 0 {
+		types = []string{"application/json"}
+	}
+	c := map[string]interface{}{}
+	for _, t := range types {
+		c[t] = map[string]interface{}{"schema": schema}
+	}
+	return c
+}
+
+func mediaTypes(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var types []string
+	for _, t := range list {
+		if s, ok := t.(string); ok {
+			types = append(types, s)
+		}
+	}
+	return types
+}
+
+func isErrorSchema(schema interface{}) bool {
+	s, _ := schema.(map[string]interface{})
+	ref, _ := s["$ref"].(string)
+	return ref == "#/components/schemas/"+errorResponseSchema
+}
+
+// rewriteRefs points every Swagger 2.0 definition reference at components/schemas
+func rewriteRefs(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				t[k] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				continue
+			}
+			rewriteRefs(val)
+		}
+	case []interface{}:
+		for _, val := range t {
+			rewriteRefs(val)
+		}
+	}
+}
+
+func commonParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"X-Request-ID": map[string]interface{}{
+			"name":        "X-Request-ID",
+			"in":          "header",
+			"description": "Caller-supplied correlation ID (1-64 letters, digits, '.', '_', ':' or '-'); generated when absent",
+			"schema":      map[string]interface{}{"type": "string", "pattern": "^[A-Za-z0-9._:-]{1,64}$"},
+		},
+		"traceparent": map[string]interface{}{
+			"name":        "traceparent",
+			"in":          "header",
+			"description": "W3C Trace Context header; its trace ID is reported as trace_id in errors",
+			"schema":      map[string]interface{}{"type": "string"},
+		},
+		"Accept-Language": map[string]interface{}{
+			"name":        "Accept-Language",
+			"in":          "header",
+			"description": "Preferred language for error messages (en, es, fr, de)",
+			"schema":      map[string]interface{}{"type": "string"},
+		},
+	}
+}
+
+func commonHeaders() map[string]interface{} {
+	return map[string]interface{}{
+		"X-Request-ID": map[string]interface{}{
+			"description": "Correlation ID of the request, echoed or generated",
+			"schema":      map[string]interface{}{"type": "string"},
+		},
+	}
+}
+
+// problemDefinition describes middleware.Problem, which handlers return in
+// place of ErrorResponse when the client accepts application/problem+json
+func problemDefinition() map[string]interface{} {
+	str := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	return map[string]interface{}{
+		"type":        "object",
+		"description": "RFC 7807 problem details",
+		"required":    []interface{}{"type", "title", "status"},
+		"properties": map[string]interface{}{
+			"type":       str("Problem type URI, about:blank when the error has no code"),
+			"title":      str("HTTP status text"),
+			"status":     map[string]interface{}{"type": "integer", "description": "HTTP status code"},
+			"detail":     str("Human-readable explanation, localized via Accept-Language"),
+			"instance":   str("Request path"),
+			"code":       str("Machine-readable error code"),
+			"request_id": str("Request correlation ID"),
+			"trace_id":   str("Trace ID when the request carried a traceparent header"),
+			"fields": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"$ref": "#/components/schemas/handlers.FieldError"},
+			},
+		},
+	}
+}
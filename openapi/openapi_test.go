@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ledger-service/docs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	swagger := `{
+		"swagger": "2.0",
+		"info": {"title": "Ledger Service API", "version": "1.0"},
+		"basePath": "/v1",
+		"paths": {
+			"/customers/{customer_id}/balance": {
+				"get": {
+					"produces": ["application/json"],
+					"parameters": [
+						{"type": "string", "format": "uuid", "name": "customer_id", "in": "path", "required": true},
+						{"enum": ["USD", "EUR"], "type": "string", "default": "USD", "name": "currency", "in": "query"}
+					],
+					"responses": {
+						"200": {"description": "OK", "schema": {"$ref": "#/definitions/handlers.BalanceResponse"}},
+						"404": {"description": "Customer not found", "schema": {"$ref": "#/definitions/handlers.ErrorResponse"}}
+					}
+				}
+			},
+			"/customers": {
+				"post": {
+					"consumes": ["application/json"],
+					"parameters": [
+						{"name": "customer", "in": "body", "required": true, "description": "Customer", "schema": {"$ref": "#/definitions/handlers.Customer"}}
+					],
+					"responses": {"201": {"description": "Created", "headers": {"Location": {"type": "string"}}}}
+				}
+			}
+		},
+		"definitions": {
+			"handlers.Customer": {"type": "object", "properties": {"addresses": {"type": "array", "items": {"$ref": "#/definitions/handlers.Address"}}}}
+		}
+	}`
+
+	out, err := Convert([]byte(swagger))
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, Version, doc["openapi"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "/v1"}}, doc["servers"])
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Parameters  []map[string]interface{}          `json:"parameters"`
+			RequestBody map[string]interface{}            `json:"requestBody"`
+			Responses   map[string]map[string]interface{} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(out, &spec))
+
+	balance := spec.Paths["/customers/{customer_id}/balance"]["get"]
+	assert.Equal(t, map[string]interface{}{
+		"name": "currency", "in": "query",
+		"schema": map[string]interface{}{"type": "string", "enum": []interface{}{"USD", "EUR"}, "default": "USD"},
+	}, balance.Parameters[1])
+	assert.Contains(t, balance.Parameters, map[string]interface{}{"$ref": "#/components/parameters/X-Request-ID"})
+
+	notFound := balance.Responses["404"]["content"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/handlers.ErrorResponse"}, notFound["application/json"].(map[string]interface{})["schema"])
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/middleware.Problem"}, notFound["application/problem+json"].(map[string]interface{})["schema"])
+	okContent := balance.Responses["200"]["content"].(map[string]interface{})
+	assert.NotContains(t, okContent, "application/problem+json")
+
+	create := spec.Paths["/customers"]["post"]
+	assert.Equal(t, true, create.RequestBody["required"])
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/handlers.Customer"},
+		create.RequestBody["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"])
+	headers := create.Responses["201"]["headers"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}, headers["Location"])
+	assert.Contains(t, headers, "X-Request-ID")
+
+	items := spec.Components.Schemas["handlers.Customer"]["properties"].(map[string]interface{})["addresses"].(map[string]interface{})["items"]
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/handlers.Address"}, items)
+	assert.Contains(t, spec.Components.Schemas, "middleware.Problem")
+}
+
+func TestConvertRejectsOtherVersions(t *testing.T) {
+	_, err := Convert([]byte(`{"openapi": "3.0.0"}`))
+	assert.Error(t, err)
+	_, err = Convert([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+// TestConvertGeneratedDocs guards against annotations that produce a
+// document the converter cannot handle or that references missing schemas
+func TestConvertGeneratedDocs(t *testing.T) {
+	out, err := Convert([]byte(docs.SwaggerInfo.ReadDoc()))
+	require.NoError(t, err)
+
+	var doc struct {
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(out, &doc))
+
+	var refs []string
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, val := range t {
+				if s, ok := val.(string); ok && k == "$ref" {
+					refs = append(refs, s)
+				}
+				collect(val)
+			}
+		case []interface{}:
+			for _, val := range t {
+				collect(val)
+			}
+		}
+	}
+	var raw interface{}
+	require.NoError(t, json.Unmarshal(out, &raw))
+	collect(raw)
+
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.NotContains(t, ref, "#/definitions/")
+		const prefix = "#/components/schemas/"
+		if strings.HasPrefix(ref, prefix) {
+			assert.Contains(t, doc.Components.Schemas, strings.TrimPrefix(ref, prefix))
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package pii encrypts personal data before it is stored. Each value is
+// sealed with its own data key, and the data key is wrapped with a key
+// encryption key from a keyring, so rotating the keyring only rewraps the
+// small data keys and never touches the sealed values.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix starts every encrypted value; anything else is stored in the clear
+const prefix = "pii:v1:"
+
+// ErrUnknownKey is returned for a value wrapped with a key the keyring does
+// not hold
+var ErrUnknownKey = errors.New("value is encrypted with a key that is not configured")
+
+// Keyring holds the key encryption keys. New values are wrapped with the
+// active key; the others are kept to read values written before a rotation.
+// A nil keyring stores values in the clear.
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring wrapping with keys[active]. Keys are 32-byte
+// AES-256 keys; IDs may not contain a colon.
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", active)
+	}
+	k := &Keyring{active: active, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeyring reads a comma-separated list of id:base64-key pairs. The
+// first key is the active one. An empty spec is a nil keyring.
+func ParseKeyring(spec string) (*Keyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var active string
+	keys := map[string][]byte{}
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("key %q must be id:base64-key", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64", id)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("key %q is listed twice", id)
+		}
+		keys[id] = key
+		if active == "" {
+			active = id
+		}
+	}
+	return NewKeyring(active, keys)
+}
+
+// ActiveKeyID is the ID of the key new values are wrapped with
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.active
+}
+
+// Encrypt seals plaintext under a fresh data key wrapped with the active
+// key. Empty values stay empty so optional fields can still be cleared.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(data, []byte(plaintext), []byte(prefix))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dek, []byte(k.active))
+	if err != nil {
+		return "", err
+	}
+	return format(k.active, wrapped, sealed), nil
+}
+
+// Decrypt opens a value written by Encrypt. Values stored before
+// encryption was turned on are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, sealed, []byte(prefix))
+	if err != nil {
+		return "", fmt.Errorf("decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// EncryptAll encrypts each value in place
+func (k *Keyring) EncryptAll(values ...*string) error {
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		enc, err := k.Encrypt(*v)
+		if err != nil {
+			return err
+		}
+		*v = enc
+	}
+	return nil
+}
+
+// DecryptAll decrypts each value in place
+func (k *Keyring) DecryptAll(values ...*string) error {
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		dec, err := k.Decrypt(*v)
+		if err != nil {
+			return err
+		}
+		*v = dec
+	}
+	return nil
+}
+
+// Rewrap brings a stored value under the active key: its data key is
+// unwrapped and wrapped again, and a value stored in the clear is
+// encrypted. It reports whether the value changed.
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	if k == nil || value == "" {
+		return value, false, nil
+	}
+	if !IsEncrypted(value) {
+		enc, err := k.Encrypt(value)
+		return enc, err == nil, err
+	}
+	id, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	if id == k.active {
+		return value, false, nil
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	wrapped, err = seal(k.keys[k.active], dek, []byte(k.active))
+	if err != nil {
+		return "", false, err
+	}
+	return format(k.active, wrapped, sealed), true, nil
+}
+
+// IsEncrypted reports whether value was written by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// ActivePrefix is how every value wrapped with the active key begins, for
+// finding the values a rotation has left behind
+func (k *Keyring) ActivePrefix() string {
+	return prefix + k.ActiveKeyID() + ":"
+}
+
+func (k *Keyring) unwrap(id string, wrapped []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	kek, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	dek, err := open(kek, wrapped, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	return dek, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce, which leads the result
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, sealed[:n], sealed[n:], additional)
+}
+
+// An encrypted value is pii:v1:<key id>:<wrapped data key>:<sealed value>
+func format(id string, wrapped, sealed []byte) string {
+	enc := base64.RawURLEncoding
+	return prefix + id + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(sealed)
+}
+
+func parse(value string) (id string, wrapped, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	enc := base64.RawURLEncoding
+	if wrapped, err = enc.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	if sealed, err = enc.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+	return parts[0], wrapped, sealed, nil
+}
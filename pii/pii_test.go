@@ -0,0 +1,103 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestEncrypt(t *testing.T) {
+	k, err := ParseKeyring("k1:" + testKey(1))
+	require.NoError(t, err)
+
+	enc, err := k.Encrypt("Jane Doe")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enc, k.ActivePrefix()))
+	assert.NotContains(t, enc, "Jane")
+	again, _ := k.Encrypt("Jane Doe")
+	assert.NotEqual(t, enc, again, "every value has its own data key and nonce")
+
+	dec, err := k.Decrypt(enc)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", dec)
+
+	// Values stored before encryption read as they are, and empty stays empty
+	dec, err = k.Decrypt("John Smith")
+	require.NoError(t, err)
+	assert.Equal(t, "John Smith", dec)
+	empty, _ := k.Encrypt("")
+	assert.Equal(t, "", empty)
+
+	// A tampered value does not open
+	parts := strings.Split(enc, ":")
+	parts[4] = base64.RawURLEncoding.EncodeToString(bytes.Repeat([]byte{7}, 40))
+	_, err = k.Decrypt(strings.Join(parts, ":"))
+	assert.Error(t, err)
+
+	// Without the key the value stays sealed
+	other, _ := ParseKeyring("k2:" + testKey(2))
+	_, err = other.Decrypt(enc)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	var none *Keyring
+	_, err = none.Decrypt(enc)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	plain, _ := none.Encrypt("Jane Doe")
+	assert.Equal(t, "Jane Doe", plain)
+}
+
+func TestRewrap(t *testing.T) {
+	old, _ := ParseKeyring("k1:" + testKey(1))
+	enc, err := old.Encrypt("+15551234567")
+	require.NoError(t, err)
+
+	rotated, err := ParseKeyring("k2:" + testKey(2) + ",k1:" + testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, "k2", rotated.ActiveKeyID())
+
+	rewrapped, changed, err := rotated.Rewrap(enc)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(rewrapped, rotated.ActivePrefix()))
+	// Only the data key is rewrapped; the sealed value is untouched
+	assert.Equal(t, enc[strings.LastIndex(enc, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+
+	// Once rewrapped, the retired key is no longer needed
+	current, _ := ParseKeyring("k2:" + testKey(2))
+	dec, err := current.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "+15551234567", dec)
+
+	_, changed, err = rotated.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Values stored in the clear are encrypted
+	rewrapped, changed, err = rotated.Rewrap("+15551234567")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, IsEncrypted(rewrapped))
+}
+
+func TestParseKeyring(t *testing.T) {
+	k, err := ParseKeyring("")
+	require.NoError(t, err)
+	assert.Nil(t, k)
+
+	for _, spec := range []string{
+		"k1",
+		"k1:not base64",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"k1:" + testKey(1) + ",k1:" + testKey(2),
+	} {
+		_, err := ParseKeyring(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,274 @@
+// Package plaid is a client for the parts of the Plaid API that link a bank
+// account and pull its transactions: Link tokens, public token exchange,
+// transaction sync, item removal and webhook verification
+package plaid
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VerificationHeader carries the JWT Plaid signs webhooks with
+const VerificationHeader = "Plaid-Verification"
+
+// ErrInvalidWebhook is returned for a webhook whose signature does not hold
+var ErrInvalidWebhook = errors.New("invalid plaid webhook")
+
+// environments are the API hosts by Plaid environment
+var environments = map[string]string{
+	"sandbox":     "https://sandbox.plaid.com",
+	"development": "https://development.plaid.com",
+	"production":  "https://production.plaid.com",
+}
+
+// Error is an error the Plaid API answered with
+type Error struct {
+	Status  int
+	Type    string `json:"error_type"`
+	Code    string `json:"error_code"`
+	Message string `json:"error_message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("plaid returned status %d", e.Status)
+	}
+	return fmt.Sprintf("plaid %s: %s", e.Code, e.Message)
+}
+
+// Transaction is a bank transaction. Amount is positive for money leaving
+// the account and negative for money coming in, as Plaid reports it.
+type Transaction struct {
+	TransactionID   string  `json:"transaction_id"`
+	AccountID       string  `json:"account_id"`
+	Amount          float64 `json:"amount"`
+	ISOCurrencyCode string  `json:"iso_currency_code"`
+	// Date is the posted date, YYYY-MM-DD
+	Date    string `json:"date"`
+	Name    string `json:"name"`
+	Pending bool   `json:"pending"`
+}
+
+// SyncPage is one page of changes since a cursor
+type SyncPage struct {
+	Added    []Transaction `json:"added"`
+	Modified []Transaction `json:"modified"`
+	Removed  []struct {
+		TransactionID string `json:"transaction_id"`
+	} `json:"removed"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Client calls the Plaid API
+type Client struct {
+	BaseURL  string
+	ClientID string
+	Secret   string
+	// ClientName and CountryCodes are shown and offered in Link
+	ClientName   string
+	CountryCodes []string
+	Client       *http.Client
+	Now          func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*ecdsa.PublicKey
+}
+
+// NewClient creates a client for a Plaid environment: sandbox, development
+// or production
+func NewClient(env, clientID, secret string) (*Client, error) {
+	baseURL, ok := environments[env]
+	if !ok {
+		return nil, fmt.Errorf("unknown plaid environment %q", env)
+	}
+	return &Client{
+		BaseURL:      baseURL,
+		ClientID:     clientID,
+		Secret:       secret,
+		ClientName:   "Ledger Service",
+		CountryCodes: []string{"US"},
+		Client:       &http.Client{Timeout: 30 * time.Second},
+		Now:          time.Now,
+	}, nil
+}
+
+// CreateLinkToken returns a token that starts Plaid Link for a user, with
+// webhooks for the item sent to webhookURL when set
+func (c *Client) CreateLinkToken(ctx context.Context, userID, webhookURL string) (string, error) {
+	req := map[string]interface{}{
+		"client_name":   c.ClientName,
+		"language":      "en",
+		"country_codes": c.CountryCodes,
+		"user":          map[string]string{"client_user_id": userID},
+		"products":      []string{"transactions"},
+	}
+	if webhookURL != "" {
+		req["webhook"] = webhookURL
+	}
+	var resp struct {
+		LinkToken string `json:"link_token"`
+	}
+	if err := c.call(ctx, "/link/token/create", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.LinkToken, nil
+}
+
+// ExchangePublicToken trades the public token Link returns for the item's
+// access token
+func (c *Client) ExchangePublicToken(ctx context.Context, publicToken string) (accessToken, itemID string, err error) {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ItemID      string `json:"item_id"`
+	}
+	if err := c.call(ctx, "/item/public_token/exchange", map[string]string{"public_token": publicToken}, &resp); err != nil {
+		return "", "", err
+	}
+	return resp.AccessToken, resp.ItemID, nil
+}
+
+// SyncTransactions returns the changes to an item's transactions since
+// cursor; an empty cursor starts from the beginning of its history
+func (c *Client) SyncTransactions(ctx context.Context, accessToken, cursor string) (SyncPage, error) {
+	req := map[string]interface{}{"access_token": accessToken, "count": 500}
+	if cursor != "" {
+		req["cursor"] = cursor
+	}
+	var page SyncPage
+	err := c.call(ctx, "/transactions/sync", req, &page)
+	return page, err
+}
+
+// RemoveItem revokes an item's access token
+func (c *Client) RemoveItem(ctx context.Context, accessToken string) error {
+	return c.call(ctx, "/item/remove", map[string]string{"access_token": accessToken}, nil)
+}
+
+func (c *Client) call(ctx context.Context, path string, body interface{}, dest interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.BaseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build plaid request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PLAID-CLIENT-ID", c.ClientID)
+	req.Header.Set("PLAID-SECRET", c.Secret)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call plaid: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{Status: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+	if dest == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode plaid response: %v", err)
+	}
+	return nil
+}
+
+// VerifyWebhook checks the ES256 JWT in a webhook's Plaid-Verification
+// header: its signature against Plaid's key, that it was issued in the last
+// five minutes and that it covers body
+func (c *Client) VerifyWebhook(ctx context.Context, token string, body []byte) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: not a JWT", ErrInvalidWebhook)
+	}
+	var h struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &h); err != nil || h.Alg != "ES256" {
+		return fmt.Errorf("%w: want an ES256 header", ErrInvalidWebhook)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidWebhook)
+	}
+	key, err := c.key(ctx, h.Kid)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidWebhook)
+	}
+
+	var claims struct {
+		IssuedAt          int64  `json:"iat"`
+		RequestBodySHA256 string `json:"request_body_sha256"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return fmt.Errorf("%w: malformed claims", ErrInvalidWebhook)
+	}
+	if c.Now().Sub(time.Unix(claims.IssuedAt, 0)) > 5*time.Minute {
+		return fmt.Errorf("%w: issued over five minutes ago", ErrInvalidWebhook)
+	}
+	sum := sha256.Sum256(body)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(claims.RequestBodySHA256)) != 1 {
+		return fmt.Errorf("%w: body does not match", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// key returns Plaid's webhook signing key with kid, fetching it once
+func (c *Client) key(ctx context.Context, kid string) (*ecdsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	var resp struct {
+		Key struct {
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"key"`
+	}
+	if err := c.call(ctx, "/webhook_verification_key/get", map[string]string{"key_id": kid}, &resp); err != nil {
+		return nil, err
+	}
+	x, errX := base64.RawURLEncoding.DecodeString(resp.Key.X)
+	y, errY := base64.RawURLEncoding.DecodeString(resp.Key.Y)
+	if resp.Key.Crv != "P-256" || errX != nil || errY != nil {
+		return nil, fmt.Errorf("%w: unusable verification key %s", ErrInvalidWebhook, kid)
+	}
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if c.keys == nil {
+		c.keys = map[string]*ecdsa.PublicKey{}
+	}
+	c.keys[kid] = key
+	return key, nil
+}
+
+func decodeSegment(segment string, dest interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dest)
+}
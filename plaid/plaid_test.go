@@ -0,0 +1,137 @@
+package plaid
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signingInput + "." + b64(signature)
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := NewClient("sandbox", "id", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sandbox.plaid.com", c.BaseURL)
+	_, err = NewClient("staging", "id", "secret")
+	assert.Error(t, err)
+}
+
+func TestSyncTransactions(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "client-1", r.Header.Get("PLAID-CLIENT-ID"))
+		assert.Equal(t, "secret-1", r.Header.Get("PLAID-SECRET"))
+		got = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got["access_token"] == "expired" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error_type":"ITEM_ERROR","error_code":"ITEM_LOGIN_REQUIRED","error_message":"the login details of this item have changed"}`))
+			return
+		}
+		assert.Equal(t, "/transactions/sync", r.URL.Path)
+		w.Write([]byte(`{
+			"added": [{"transaction_id": "tx-1", "account_id": "acc-1", "amount": 12.5, "iso_currency_code": "USD", "date": "2025-04-08", "name": "Coffee", "pending": false}],
+			"modified": [],
+			"removed": [{"transaction_id": "tx-0"}],
+			"next_cursor": "c2",
+			"has_more": false
+		}`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient("sandbox", "client-1", "secret-1")
+	require.NoError(t, err)
+	c.BaseURL = server.URL
+
+	page, err := c.SyncTransactions(context.Background(), "access-1", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "c1", got["cursor"])
+	assert.Equal(t, "access-1", got["access_token"])
+	require.Len(t, page.Added, 1)
+	assert.Equal(t, 12.5, page.Added[0].Amount)
+	assert.Equal(t, "tx-0", page.Removed[0].TransactionID)
+	assert.Equal(t, "c2", page.NextCursor)
+
+	_, err = c.SyncTransactions(context.Background(), "access-1", "")
+	require.NoError(t, err)
+	assert.NotContains(t, got, "cursor", "the first sync sends no cursor")
+
+	_, err = c.SyncTransactions(context.Background(), "expired", "c1")
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "ITEM_LOGIN_REQUIRED", apiErr.Code)
+	assert.EqualError(t, err, "plaid ITEM_LOGIN_REQUIRED: the login details of this item have changed")
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/webhook_verification_key/get", r.URL.Path)
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req["key_id"] != "key-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"key": map[string]string{
+			"kty": "EC", "crv": "P-256", "kid": "key-1", "x": b64(key.X.Bytes()), "y": b64(key.Y.Bytes()),
+		}})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	c, err := NewClient("sandbox", "client-1", "secret-1")
+	require.NoError(t, err)
+	c.BaseURL = server.URL
+	c.Now = func() time.Time { return now }
+
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+	sum := sha256.Sum256(body)
+	token := sign(t, key, "key-1", map[string]interface{}{"iat": now.Unix(), "request_body_sha256": hex.EncodeToString(sum[:])})
+	require.NoError(t, c.VerifyWebhook(context.Background(), token, body))
+	require.NoError(t, c.VerifyWebhook(context.Background(), token, body))
+	assert.Equal(t, 1, fetches, "the key is cached")
+
+	assert.ErrorIs(t, c.VerifyWebhook(context.Background(), token, []byte(`{}`)), ErrInvalidWebhook)
+	stale := sign(t, key, "key-1", map[string]interface{}{"iat": now.Add(-10 * time.Minute).Unix(), "request_body_sha256": hex.EncodeToString(sum[:])})
+	assert.ErrorIs(t, c.VerifyWebhook(context.Background(), stale, body), ErrInvalidWebhook)
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	forged := sign(t, other, "key-1", map[string]interface{}{"iat": now.Unix(), "request_body_sha256": hex.EncodeToString(sum[:])})
+	assert.ErrorIs(t, c.VerifyWebhook(context.Background(), forged, body), ErrInvalidWebhook)
+
+	assert.Error(t, c.VerifyWebhook(context.Background(), sign(t, key, "key-2", map[string]interface{}{"iat": now.Unix()}), body))
+	assert.ErrorIs(t, c.VerifyWebhook(context.Background(), "not-a-jwt", body), ErrInvalidWebhook)
+}
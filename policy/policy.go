@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// AccountType determines which posting rules apply to a customer account
+type AccountType string
+
+const (
+	Checking AccountType = "checking"
+	Savings  AccountType = "savings"
+	Escrow   AccountType = "escrow"
+)
+
+// Valid reports whether t is a known account type
+func Valid(t AccountType) bool {
+	switch t {
+	case Checking, Savings, Escrow:
+		return true
+	}
+	return false
+}
+
+// Rules are the type-specific constraints on an account
+type Rules struct {
+	// MonthlyDebitLimit caps the number of posted debits per calendar month (0 = unlimited)
+	MonthlyDebitLimit int
+	// DualApprovalDebits requires two distinct approvers before a debit posts
+	DualApprovalDebits bool
+	// RequiredApprovals is the number of distinct approvers needed when DualApprovalDebits is set
+	RequiredApprovals int
+}
+
+// Policies maps each account type to its rules
+type Policies map[AccountType]Rules
+
+// Default returns the standard policy set: unrestricted checking, savings
+// limited to savingsMonthlyDebits debits per month, and dual-approval escrow
+func Default(savingsMonthlyDebits int) Policies {
+	return Policies{
+		Checking: {},
+		Savings:  {MonthlyDebitLimit: savingsMonthlyDebits},
+		Escrow:   {DualApprovalDebits: true, RequiredApprovals: 2},
+	}
+}
+
+// For returns the rules for an account type; unknown types get no restrictions
+func (p Policies) For(t AccountType) Rules {
+	return p[t]
+}
+
+// Posting is a transaction being checked against an account's policy
+type Posting struct {
+	CustomerID  uuid.UUID
+	AccountType AccountType
+	Type        string // balance direction, credit or debit
+	Amount      float64
+}
+
+// Usage provides the account history the policies depend on
+type Usage interface {
+	// MonthlyDebitCount returns the number of posted debits in the current calendar month
+	MonthlyDebitCount(ctx context.Context, customerID uuid.UUID) (int, error)
+}
+
+// Outcome is what the posting path must do with a transaction
+type Outcome int
+
+const (
+	// Post applies the transaction immediately
+	Post Outcome = iota
+	// RequireApproval stores the transaction until enough approvals are collected
+	RequireApproval
+)
+
+// ViolationError reports that a posting breaks its account type's rules
+type ViolationError struct {
+	Message string
+}
+
+func (e *ViolationError) Error() string {
+	return e.Message
+}
+
+// Check evaluates a posting. It returns a *ViolationError when the posting is
+// not allowed, and otherwise whether it can post or needs approval.
+func (p Policies) Check(ctx context.Context, usage Usage, posting Posting) (Outcome, error) {
+	rules := p.For(posting.AccountType)
+	if posting.Type != "debit" {
+		return Post, nil
+	}
+
+	if rules.MonthlyDebitLimit > 0 {
+		count, err := usage.MonthlyDebitCount(ctx, posting.CustomerID)
+		if err != nil {
+			return Post, err
+		}
+		if count >= rules.MonthlyDebitLimit {
+			return Post, &ViolationError{Message: fmt.Sprintf(
+				"Monthly debit limit of %d reached for %s account", rules.MonthlyDebitLimit, posting.AccountType)}
+		}
+	}
+
+	if rules.DualApprovalDebits {
+		return RequireApproval, nil
+	}
+	return Post, nil
+}
@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUsage struct {
+	debits int
+	calls  int
+}
+
+func (f *fakeUsage) MonthlyDebitCount(ctx context.Context, customerID uuid.UUID) (int, error) {
+	f.calls++
+	return f.debits, nil
+}
+
+func TestPoliciesCheck(t *testing.T) {
+	policies := Default(6)
+
+	tests := []struct {
+		name        string
+		posting     Posting
+		debits      int
+		wantOutcome Outcome
+		wantErr     bool
+		wantCalls   int
+	}{
+		{name: "checking debit", posting: Posting{AccountType: Checking, Type: "debit", Amount: 10}, wantOutcome: Post},
+		{name: "savings credit", posting: Posting{AccountType: Savings, Type: "credit", Amount: 10}, debits: 10, wantOutcome: Post},
+		{name: "savings debit under limit", posting: Posting{AccountType: Savings, Type: "debit", Amount: 10}, debits: 5, wantOutcome: Post, wantCalls: 1},
+		{name: "savings debit at limit", posting: Posting{AccountType: Savings, Type: "debit", Amount: 10}, debits: 6, wantErr: true, wantCalls: 1},
+		{name: "escrow debit", posting: Posting{AccountType: Escrow, Type: "debit", Amount: 10}, wantOutcome: RequireApproval},
+		{name: "escrow credit", posting: Posting{AccountType: Escrow, Type: "credit", Amount: 10}, wantOutcome: Post},
+		{name: "unknown type", posting: Posting{AccountType: "brokerage", Type: "debit", Amount: 10}, wantOutcome: Post},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := &fakeUsage{debits: tt.debits}
+			outcome, err := policies.Check(context.Background(), usage, tt.posting)
+			if tt.wantErr {
+				var violation *ViolationError
+				assert.True(t, errors.As(err, &violation))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantOutcome, outcome)
+			}
+			assert.Equal(t, tt.wantCalls, usage.calls)
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(Checking))
+	assert.True(t, Valid(Savings))
+	assert.True(t, Valid(Escrow))
+	assert.False(t, Valid("brokerage"))
+}
@@ -0,0 +1,129 @@
+// Package reconcile matches the lines of an external statement, such as a
+// bank's, one to one against ledger transactions
+package reconcile
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Match rules, from the strongest
+const (
+	// RuleReference matched a line to the transaction with its reference
+	RuleReference = "reference"
+	// RuleAmountDate matched a line to a transaction of the same amount,
+	// within tolerance, on a nearby date
+	RuleAmountDate = "amount_date"
+)
+
+// Item is a statement line or a ledger transaction
+type Item struct {
+	ID string
+	// Direction is credit for money into the account, debit for money out
+	Direction string
+	Amount    float64
+	Date      time.Time
+	Reference string
+}
+
+// Tolerance is how far a line may be from the transaction it matches
+type Tolerance struct {
+	// Days the dates may be apart; reference matches ignore dates
+	Days int
+	// Amount the amounts may differ by, e.g. 0.01 for rounding
+	Amount float64
+}
+
+// Pair is a statement line matched with a ledger transaction
+type Pair struct {
+	Line        string
+	Transaction string
+	Rule        string
+}
+
+// candidate is a line and transaction that could match, and how close
+// they are
+type candidate struct {
+	line, transaction int
+	amountDiff        float64
+	days              int
+}
+
+// Match pairs lines with transactions, each used at most once. Lines are
+// first matched on equal references, then on amount and date. Within a
+// rule the closest pairs win: the smallest amount difference, then the
+// fewest days apart, then the earliest line and transaction given.
+func Match(lines, transactions []Item, tol Tolerance) []Pair {
+	lineTaken := make([]bool, len(lines))
+	txTaken := make([]bool, len(transactions))
+	var matches []Pair
+
+	assign := func(rule string, eligible func(l, t Item, days int) bool) {
+		var candidates []candidate
+		for i, l := range lines {
+			if lineTaken[i] {
+				continue
+			}
+			for j, t := range transactions {
+				if txTaken[j] || t.Direction != l.Direction {
+					continue
+				}
+				diff := math.Abs(l.Amount - t.Amount)
+				// Amounts are compared in cents so float noise does not
+				// put an exact tolerance out of reach
+				if math.Round(diff*100) > math.Round(tol.Amount*100) {
+					continue
+				}
+				days := daysApart(l.Date, t.Date)
+				if eligible(l, t, days) {
+					candidates = append(candidates, candidate{line: i, transaction: j, amountDiff: math.Round(diff * 100), days: days})
+				}
+			}
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			ca, cb := candidates[a], candidates[b]
+			if ca.amountDiff != cb.amountDiff {
+				return ca.amountDiff < cb.amountDiff
+			}
+			if ca.days != cb.days {
+				return ca.days < cb.days
+			}
+			if ca.line != cb.line {
+				return ca.line < cb.line
+			}
+			return ca.transaction < cb.transaction
+		})
+		for _, c := range candidates {
+			if lineTaken[c.line] || txTaken[c.transaction] {
+				continue
+			}
+			lineTaken[c.line], txTaken[c.transaction] = true, true
+			matches = append(matches, Pair{Line: lines[c.line].ID, Transaction: transactions[c.transaction].ID, Rule: rule})
+		}
+	}
+
+	assign(RuleReference, func(l, t Item, _ int) bool {
+		ref := normalizeReference(l.Reference)
+		return ref != "" && ref == normalizeReference(t.Reference)
+	})
+	assign(RuleAmountDate, func(_, _ Item, days int) bool {
+		return days <= tol.Days
+	})
+	return matches
+}
+
+// normalizeReference compares references ignoring case and spacing, which
+// banks often change
+func normalizeReference(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+// daysApart is the number of calendar days between two dates
+func daysApart(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	d := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC).Sub(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC))
+	return int(math.Abs(d.Hours()) / 24)
+}
@@ -0,0 +1,58 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 0, 0, 0, 0, time.UTC) }
+	lines := []Item{
+		{ID: "salary", Direction: "credit", Amount: 2500, Date: day(1), Reference: "PAYROLL 04/25"},
+		{ID: "rent", Direction: "debit", Amount: 800, Date: day(3)},
+		{ID: "coffee-1", Direction: "debit", Amount: 4.5, Date: day(5)},
+		{ID: "coffee-2", Direction: "debit", Amount: 4.5, Date: day(5)},
+		{ID: "card", Direction: "debit", Amount: 19.99, Date: day(9)},
+		{ID: "fee", Direction: "debit", Amount: 2, Date: day(30)},
+		{ID: "refund", Direction: "credit", Amount: 4.5, Date: day(5)},
+	}
+	transactions := []Item{
+		// The reference matches even though the ledger booked it late
+		{ID: "t-salary", Direction: "credit", Amount: 2500, Date: day(7), Reference: "payroll 04/25"},
+		// Equal amounts: the closer date wins
+		{ID: "t-rent-far", Direction: "debit", Amount: 800, Date: day(6)},
+		{ID: "t-rent", Direction: "debit", Amount: 800, Date: day(2)},
+		{ID: "t-coffee-1", Direction: "debit", Amount: 4.5, Date: day(5)},
+		{ID: "t-coffee-2", Direction: "debit", Amount: 4.5, Date: day(6)},
+		// Off by a cent, within tolerance
+		{ID: "t-card", Direction: "debit", Amount: 20, Date: day(9)},
+		// Too far from the fee line
+		{ID: "t-fee", Direction: "debit", Amount: 2, Date: day(20)},
+	}
+
+	matches := Match(lines, transactions, Tolerance{Days: 3, Amount: 0.01})
+	got := map[string]string{}
+	rules := map[string]string{}
+	for _, m := range matches {
+		got[m.Line] = m.Transaction
+		rules[m.Line] = m.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"salary":   "t-salary",
+		"rent":     "t-rent",
+		"coffee-1": "t-coffee-1",
+		"coffee-2": "t-coffee-2",
+		"card":     "t-card",
+	}, got, "the fee is too far apart and the refund has no credit to match")
+	assert.Equal(t, RuleReference, rules["salary"])
+	assert.Equal(t, RuleAmountDate, rules["card"])
+
+	// Without an amount tolerance the card line stays unmatched
+	matches = Match(lines, transactions, Tolerance{Days: 3})
+	for _, m := range matches {
+		assert.NotEqual(t, "card", m.Line)
+	}
+	assert.Len(t, matches, 4)
+}
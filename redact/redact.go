@@ -0,0 +1,270 @@
+// Package redact masks personal data and secrets before they leave the
+// service in logs, error reports and error responses. Each field name has
+// a masking policy; free text is scanned for key=value pairs, JSON members
+// and values that look like emails, phone numbers, IBANs and tokens.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Policy says how a field's value is masked
+type Policy string
+
+const (
+	// Keep leaves the value as it is
+	Keep Policy = "keep"
+	// Full replaces the value with [REDACTED]
+	Full Policy = "full"
+	// Partial keeps the first character of each word, and an email's domain
+	Partial Policy = "partial"
+	// Last4 keeps the last four characters
+	Last4 Policy = "last4"
+	// Hash replaces the value with a short fingerprint, so log lines about
+	// the same value can still be matched up
+	Hash Policy = "hash"
+)
+
+// Redacted replaces values masked with Full
+const Redacted = "[REDACTED]"
+
+// DefaultPolicies are the policies of fields no override names
+var DefaultPolicies = map[string]Policy{
+	"name":            Partial,
+	"email":           Partial,
+	"phone":           Last4,
+	"phone_number":    Last4,
+	"date_of_birth":   Full,
+	"line1":           Full,
+	"line2":           Full,
+	"postal_code":     Full,
+	"alias":           Partial,
+	"iban":            Last4,
+	"account_number":  Last4,
+	"token":           Full,
+	"secret":          Full,
+	"password":        Full,
+	"api_key":         Full,
+	"authorization":   Full,
+	"signature":       Full,
+	"idempotency_key": Hash,
+	"customer_id":     Keep,
+}
+
+// Redactor masks values according to per-field policies
+type Redactor struct {
+	policies map[string]Policy
+	// suffixes are the masked fields longest first, so webhook_secret
+	// takes the policy of secret
+	suffixes []string
+}
+
+// New creates a redactor with DefaultPolicies and overrides on top
+func New(overrides map[string]Policy) *Redactor {
+	r := &Redactor{policies: map[string]Policy{}}
+	for field, p := range DefaultPolicies {
+		r.policies[field] = p
+	}
+	for field, p := range overrides {
+		r.policies[normalize(field)] = p
+	}
+	for field, p := range r.policies {
+		if p != Keep {
+			r.suffixes = append(r.suffixes, field)
+		}
+	}
+	sort.Slice(r.suffixes, func(i, j int) bool { return len(r.suffixes[i]) > len(r.suffixes[j]) })
+	return r
+}
+
+// ParsePolicies reads a comma-separated list of field=policy pairs, such as
+// "name=full,customer_id=hash"
+func ParsePolicies(spec string) (map[string]Policy, error) {
+	policies := map[string]Policy{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, policy, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(field) == "" {
+			return nil, fmt.Errorf("%q must be field=policy", entry)
+		}
+		p := Policy(strings.ToLower(strings.TrimSpace(policy)))
+		switch p {
+		case Keep, Full, Partial, Last4, Hash:
+		default:
+			return nil, fmt.Errorf("unknown policy %q for %s (want keep, full, partial, last4 or hash)", policy, field)
+		}
+		policies[strings.TrimSpace(field)] = p
+	}
+	return policies, nil
+}
+
+var defaultRedactor atomic.Pointer[Redactor]
+
+func init() {
+	defaultRedactor.Store(New(nil))
+}
+
+// Default is the redactor logs, error reports and error responses use
+func Default() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// SetDefault replaces the default redactor
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// Policy is the policy of field: its own, or that of the longest masked
+// field it ends with, or Keep
+func (r *Redactor) Policy(field string) Policy {
+	field = normalize(field)
+	if p, ok := r.policies[field]; ok {
+		return p
+	}
+	for _, suffix := range r.suffixes {
+		if strings.HasSuffix(field, "_"+suffix) {
+			return r.policies[suffix]
+		}
+	}
+	return Keep
+}
+
+// Field masks value by field's policy
+func (r *Redactor) Field(field, value string) string {
+	return Mask(r.Policy(field), value)
+}
+
+// Mask masks value by p
+func Mask(p Policy, value string) string {
+	if value == "" {
+		return value
+	}
+	switch p {
+	case Keep:
+		return value
+	case Partial:
+		if local, domain, ok := strings.Cut(value, "@"); ok && local != "" {
+			return initials(local) + "@" + domain
+		}
+		return initials(value)
+	case Last4:
+		if utf8.RuneCountInString(value) <= 4 {
+			return "****"
+		}
+		runes := []rune(value)
+		return "****" + string(runes[len(runes)-4:])
+	case Hash:
+		sum := sha256.Sum256([]byte(value))
+		return "#" + hex.EncodeToString(sum[:6])
+	}
+	return Redacted
+}
+
+// initials keeps the first character of each word
+func initials(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		first, _ := utf8.DecodeRuneInString(w)
+		if first == '@' && len(w) > 1 {
+			second, _ := utf8.DecodeRuneInString(w[1:])
+			words[i] = "@" + string(second) + "***"
+			continue
+		}
+		words[i] = string(first) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+var (
+	jsonMember   = regexp.MustCompile(`"([A-Za-z][A-Za-z0-9_-]*)"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	keyValue     = regexp.MustCompile(`\b([A-Za-z][A-Za-z0-9_-]*)=([^\s&,;"']+)`)
+	credentials  = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]{8,}`)
+	urlPassword  = regexp.MustCompile(`(\b[a-z][a-z0-9+.-]*://[^:/\s@]+):[^@\s/]+@`)
+	customerKey  = regexp.MustCompile(`\b(?:ctk|lsk)_[0-9a-f]+\b`)
+	emailAddress = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)
+	phoneNumber  = regexp.MustCompile(`\+[1-9]\d{7,14}\b`)
+	iban         = regexp.MustCompile(`\b[A-Z]{2}\d{2}[A-Z0-9]{11,30}\b`)
+)
+
+// String masks what looks sensitive in free text: the values of masked
+// fields written as key=value or as JSON members, credentials, passwords in
+// URLs, and emails, phone numbers and IBANs wherever they appear
+func (r *Redactor) String(s string) string {
+	s = jsonMember.ReplaceAllStringFunc(s, func(m string) string {
+		sub := jsonMember.FindStringSubmatch(m)
+		if r.Policy(sub[1]) == Keep {
+			return m
+		}
+		return `"` + sub[1] + `":"` + r.Field(sub[1], sub[2]) + `"`
+	})
+	s = keyValue.ReplaceAllStringFunc(s, func(m string) string {
+		sub := keyValue.FindStringSubmatch(m)
+		if r.Policy(sub[1]) == Keep {
+			return m
+		}
+		return sub[1] + "=" + r.Field(sub[1], sub[2])
+	})
+	s = credentials.ReplaceAllString(s, "$1 "+Redacted)
+	s = urlPassword.ReplaceAllString(s, "$1:"+Redacted+"@")
+	s = customerKey.ReplaceAllStringFunc(s, func(m string) string { return r.Field("token", m) })
+	s = emailAddress.ReplaceAllStringFunc(s, func(m string) string { return r.Field("email", m) })
+	s = phoneNumber.ReplaceAllStringFunc(s, func(m string) string { return r.Field("phone_number", m) })
+	s = iban.ReplaceAllStringFunc(s, func(m string) string { return r.Field("iban", m) })
+	return s
+}
+
+// URL masks the query parameters of a URL or request path by their names,
+// then masks the whole as free text
+func (r *Redactor) URL(raw string) string {
+	path, query, ok := strings.Cut(raw, "?")
+	if !ok {
+		return r.String(raw)
+	}
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil || r.Policy(name) == Keep {
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		params[i] = key + "=" + r.Field(name, value)
+	}
+	return r.String(path + "?" + strings.Join(params, "&"))
+}
+
+// Writer masks everything written to w as free text. Each write is masked
+// on its own, which suits loggers that write a line at a time.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return writer{r: r, w: w}
+}
+
+type writer struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func normalize(field string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(field)), "-", "_")
+}
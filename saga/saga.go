@@ -0,0 +1,357 @@
+// Package saga coordinates a ledger posting with actions in other services,
+// such as a payout provider or card processor. Every step is saved before
+// and after it runs, and when an action fails the steps already taken are
+// compensated in reverse order, ending with a reversal of the posting.
+package saga
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Saga statuses
+const (
+	StatusRunning = "running"
+	// StatusCompleted is set once the posting and every action succeeded
+	StatusCompleted = "completed"
+	// StatusFailed is set when the posting itself failed, so nothing ran
+	StatusFailed       = "failed"
+	StatusCompensating = "compensating"
+	// StatusCompensated is set once every step taken was undone
+	StatusCompensated = "compensated"
+	// StatusCompensationFailed is set when undoing a step failed; the steps
+	// before it are left alone until compensation is retried
+	StatusCompensationFailed = "compensation_failed"
+)
+
+// Step statuses. A step that is running or failed may have taken effect
+// (a timeout leaves that unknown), so both are compensated.
+const (
+	StepPending            = "pending"
+	StepRunning            = "running"
+	StepDone               = "done"
+	StepFailed             = "failed"
+	StepCompensated        = "compensated"
+	StepCompensationFailed = "compensation_failed"
+)
+
+// LedgerStep names the posting, always a saga's first step
+const LedgerStep = "ledger"
+
+var (
+	// ErrUnknownAction is returned for a step naming an action that is not
+	// configured
+	ErrUnknownAction = errors.New("unknown saga action")
+	// ErrPostingUnknown is returned for compensating a posting whose
+	// outcome was not saved, such as after a crash while posting
+	ErrPostingUnknown = errors.New("posting outcome unknown")
+)
+
+// Step is one step of a saga and how it went
+type Step struct {
+	Name   string `json:"name" example:"payout"`
+	Status string `json:"status" example:"done" enums:"pending,running,done,failed,compensated,compensation_failed"`
+	// ExternalID is what the action's service calls the step, if it says
+	ExternalID string `json:"external_id,omitempty" example:"po_8f2k1"`
+	Error      string `json:"error,omitempty" example:"action returned status 502"`
+}
+
+// Saga is a ledger posting followed by actions in other services
+// @Description A ledger posting coordinated with actions in other services, compensated in reverse order when one fails
+type Saga struct {
+	ID         uuid.UUID `json:"id" format:"uuid"`
+	CustomerID uuid.UUID `json:"customer_id" format:"uuid"`
+	Type       string    `json:"type" example:"payout"`
+	Amount     float64   `json:"amount" example:"250"`
+	Reference  string    `json:"reference,omitempty" example:"Payout 2025-04-08"`
+	Status     string    `json:"status" example:"completed" enums:"running,completed,failed,compensating,compensated,compensation_failed"`
+	Error      string    `json:"error,omitempty" example:"payout failed: action returned status 502"`
+	// TransactionID is the posting; ReversalID the transaction undoing it
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" format:"uuid"`
+	ReversalID    *uuid.UUID `json:"reversal_transaction_id,omitempty" format:"uuid"`
+	// Steps starts with the ledger posting, followed by the actions in order
+	Steps     []Step    `json:"steps"`
+	CreatedAt time.Time `json:"created_at" format:"date-time"`
+	UpdatedAt time.Time `json:"updated_at" format:"date-time"`
+}
+
+// New creates a saga posting a transaction of txType and then running the
+// named actions in order
+func New(customerID uuid.UUID, txType string, amount float64, reference string, actions []string) *Saga {
+	now := time.Now().UTC()
+	s := &Saga{
+		ID:         uuid.New(),
+		CustomerID: customerID,
+		Type:       txType,
+		Amount:     amount,
+		Reference:  reference,
+		Status:     StatusRunning,
+		Steps:      []Step{{Name: LedgerStep, Status: StepPending}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	for _, name := range actions {
+		s.Steps = append(s.Steps, Step{Name: name, Status: StepPending})
+	}
+	return s
+}
+
+// Ledger books a saga's posting and reverses it
+type Ledger interface {
+	// Post books the saga's transaction, returning its ID. An error means
+	// nothing was posted.
+	Post(ctx context.Context, s *Saga) (uuid.UUID, error)
+	// Reverse books a transaction undoing the posting, returning its ID
+	Reverse(ctx context.Context, s *Saga) (uuid.UUID, error)
+}
+
+// Request tells an action what to do. The same request is sent to execute
+// and to compensate a step.
+type Request struct {
+	SagaID        uuid.UUID  `json:"saga_id"`
+	Step          string     `json:"step"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
+	Type          string     `json:"type"`
+	Amount        float64    `json:"amount"`
+	Reference     string     `json:"reference,omitempty"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"`
+	// ExternalID is set on compensation when executing returned one
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// Action is a step run in another service. Both calls must be idempotent,
+// and compensating a step that never took effect must succeed.
+type Action interface {
+	Execute(ctx context.Context, r Request) (externalID string, err error)
+	Compensate(ctx context.Context, r Request) error
+}
+
+// Store saves a saga's state
+type Store interface {
+	Save(ctx context.Context, s *Saga) error
+}
+
+// Coordinator runs sagas against a ledger and the configured actions
+type Coordinator struct {
+	ledger  Ledger
+	actions map[string]Action
+	store   Store
+}
+
+// NewCoordinator creates a coordinator running actions by name
+func NewCoordinator(l Ledger, actions map[string]Action, s Store) *Coordinator {
+	return &Coordinator{ledger: l, actions: actions, store: s}
+}
+
+// Known reports whether an action is configured
+func (c *Coordinator) Known(name string) bool {
+	_, ok := c.actions[name]
+	return ok
+}
+
+// Run posts the saga's transaction and runs its actions in order, saving
+// the saga before and after each step. When an action fails, the steps
+// taken are compensated. The saga's outcome is in its status; the error is
+// only set when saving failed.
+func (c *Coordinator) Run(ctx context.Context, s *Saga) error {
+	s.Status = StatusRunning
+	ledgerStep := &s.Steps[0]
+	ledgerStep.Status = StepRunning
+	if err := c.save(ctx, s); err != nil {
+		return err
+	}
+	id, err := c.ledger.Post(ctx, s)
+	if err != nil {
+		ledgerStep.Status, ledgerStep.Error = StepFailed, err.Error()
+		s.Status, s.Error = StatusFailed, err.Error()
+		return c.save(ctx, s)
+	}
+	s.TransactionID = &id
+	ledgerStep.Status = StepDone
+	if err := c.save(ctx, s); err != nil {
+		return err
+	}
+
+	for i := 1; i < len(s.Steps); i++ {
+		step := &s.Steps[i]
+		step.Status = StepRunning
+		if err := c.save(ctx, s); err != nil {
+			return err
+		}
+		externalID, err := c.execute(ctx, s, step)
+		if err != nil {
+			step.Status, step.Error = StepFailed, err.Error()
+			s.Error = fmt.Sprintf("%s failed: %v", step.Name, err)
+			return c.Compensate(ctx, s)
+		}
+		step.Status, step.ExternalID = StepDone, externalID
+		if err := c.save(ctx, s); err != nil {
+			return err
+		}
+	}
+	s.Status = StatusCompleted
+	return c.save(ctx, s)
+}
+
+func (c *Coordinator) execute(ctx context.Context, s *Saga, step *Step) (string, error) {
+	action, ok := c.actions[step.Name]
+	if !ok {
+		return "", ErrUnknownAction
+	}
+	return action.Execute(ctx, request(s, step))
+}
+
+// Compensate undoes the steps taken, last first, and reverses the posting.
+// It stops at the first step it cannot undo, leaving the saga
+// compensation_failed, so calling it again resumes from there.
+func (c *Coordinator) Compensate(ctx context.Context, s *Saga) error {
+	s.Status = StatusCompensating
+	if err := c.save(ctx, s); err != nil {
+		return err
+	}
+	for i := len(s.Steps) - 1; i >= 0; i-- {
+		step := &s.Steps[i]
+		switch step.Status {
+		case StepRunning, StepDone, StepFailed, StepCompensationFailed:
+		default:
+			continue
+		}
+		if i == 0 && step.Status == StepFailed {
+			// A failed posting wrote nothing
+			continue
+		}
+		if err := c.compensate(ctx, s, step); err != nil {
+			step.Status, step.Error = StepCompensationFailed, err.Error()
+			s.Status = StatusCompensationFailed
+			return c.save(ctx, s)
+		}
+		step.Status = StepCompensated
+		if err := c.save(ctx, s); err != nil {
+			return err
+		}
+	}
+	s.Status = StatusCompensated
+	return c.save(ctx, s)
+}
+
+func (c *Coordinator) compensate(ctx context.Context, s *Saga, step *Step) error {
+	if step.Name == LedgerStep {
+		if s.TransactionID == nil {
+			return ErrPostingUnknown
+		}
+		id, err := c.ledger.Reverse(ctx, s)
+		if err != nil {
+			return err
+		}
+		s.ReversalID = &id
+		return nil
+	}
+	action, ok := c.actions[step.Name]
+	if !ok {
+		return ErrUnknownAction
+	}
+	return action.Compensate(ctx, request(s, step))
+}
+
+func (c *Coordinator) save(ctx context.Context, s *Saga) error {
+	s.UpdatedAt = time.Now().UTC()
+	return c.store.Save(ctx, s)
+}
+
+func request(s *Saga, step *Step) Request {
+	return Request{
+		SagaID:        s.ID,
+		Step:          step.Name,
+		CustomerID:    s.CustomerID,
+		Type:          s.Type,
+		Amount:        s.Amount,
+		Reference:     s.Reference,
+		TransactionID: s.TransactionID,
+		ExternalID:    step.ExternalID,
+	}
+}
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,29}$`)
+
+// ValidateName checks that an action name can be configured
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("action name %q must be 2-30 lowercase letters, digits or underscores", name)
+	}
+	if name == LedgerStep {
+		return fmt.Errorf("action name %q is reserved", name)
+	}
+	return nil
+}
+
+// HTTPAction runs a step by posting its request to a URL, with "action" set
+// to "execute" or "compensate". Requests are signed like webhook deliveries
+// when Secret is set and carry an Idempotency-Key per step and action. Any
+// 2xx response succeeds, and may name the step with {"external_id": "..."}.
+type HTTPAction struct {
+	URL    string
+	Secret string
+	Client *http.Client
+	Now    func() time.Time
+}
+
+// NewHTTPAction creates an action posting to url whose requests time out
+// after timeout
+func NewHTTPAction(url, secret string, timeout time.Duration) *HTTPAction {
+	return &HTTPAction{URL: url, Secret: secret, Client: &http.Client{Timeout: timeout}, Now: time.Now}
+}
+
+func (a *HTTPAction) Execute(ctx context.Context, r Request) (string, error) {
+	return a.call(ctx, "execute", r)
+}
+
+func (a *HTTPAction) Compensate(ctx context.Context, r Request) error {
+	_, err := a.call(ctx, "compensate", r)
+	return err
+}
+
+func (a *HTTPAction) call(ctx context.Context, action string, r Request) (string, error) {
+	body, err := json.Marshal(struct {
+		Action string `json:"action"`
+		Request
+	}{action, r})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", r.SagaID.String()+":"+r.Step+":"+action)
+	if a.Secret != "" {
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(a.Secret, a.Now(), body))
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("action returned status %d", resp.StatusCode)
+	}
+	var result struct {
+		ExternalID string `json:"external_id"`
+	}
+	// The body is optional, so one that is not JSON only loses the ID
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16)); err == nil {
+		_ = json.Unmarshal(data, &result)
+	}
+	return result.ExternalID, nil
+}
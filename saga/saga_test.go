@@ -0,0 +1,196 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/webhook"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLedger struct {
+	postErr, reverseErr error
+	reversed            int
+}
+
+func (f *fakeLedger) Post(ctx context.Context, s *Saga) (uuid.UUID, error) {
+	return uuid.New(), f.postErr
+}
+
+func (f *fakeLedger) Reverse(ctx context.Context, s *Saga) (uuid.UUID, error) {
+	if f.reverseErr != nil {
+		return uuid.Nil, f.reverseErr
+	}
+	f.reversed++
+	return uuid.New(), nil
+}
+
+type fakeAction struct {
+	name                      string
+	executeErr, compensateErr error
+	calls                     *[]string
+}
+
+func (f fakeAction) Execute(ctx context.Context, r Request) (string, error) {
+	*f.calls = append(*f.calls, "execute "+f.name)
+	if f.executeErr != nil {
+		return "", f.executeErr
+	}
+	return "ext_" + f.name, nil
+}
+
+func (f fakeAction) Compensate(ctx context.Context, r Request) error {
+	*f.calls = append(*f.calls, "compensate "+f.name+" "+r.ExternalID)
+	return f.compensateErr
+}
+
+type fakeStore struct {
+	statuses []string
+}
+
+func (f *fakeStore) Save(ctx context.Context, s *Saga) error {
+	f.statuses = append(f.statuses, s.Status)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("provider unavailable")
+
+	t.Run("completes", func(t *testing.T) {
+		var calls []string
+		l, st := &fakeLedger{}, &fakeStore{}
+		c := NewCoordinator(l, map[string]Action{
+			"payout": fakeAction{name: "payout", calls: &calls},
+			"card":   fakeAction{name: "card", calls: &calls},
+		}, st)
+		s := New(uuid.New(), "payout", 250, "Payout 7", []string{"card", "payout"})
+		require.NoError(t, c.Run(ctx, s))
+		assert.Equal(t, StatusCompleted, s.Status)
+		assert.NotNil(t, s.TransactionID)
+		assert.Equal(t, []string{"execute card", "execute payout"}, calls)
+		assert.Equal(t, "ext_payout", s.Steps[2].ExternalID)
+		for _, step := range s.Steps {
+			assert.Equal(t, StepDone, step.Status)
+		}
+		assert.Equal(t, StatusCompleted, st.statuses[len(st.statuses)-1])
+	})
+
+	t.Run("posting fails", func(t *testing.T) {
+		var calls []string
+		c := NewCoordinator(&fakeLedger{postErr: errors.New("insufficient balance")},
+			map[string]Action{"payout": fakeAction{name: "payout", calls: &calls}}, &fakeStore{})
+		s := New(uuid.New(), "payout", 250, "", []string{"payout"})
+		require.NoError(t, c.Run(ctx, s))
+		assert.Equal(t, StatusFailed, s.Status)
+		assert.Equal(t, "insufficient balance", s.Error)
+		assert.Nil(t, s.TransactionID)
+		assert.Empty(t, calls, "no action runs without the posting")
+		assert.Equal(t, StepPending, s.Steps[1].Status)
+	})
+
+	t.Run("action fails and earlier steps are compensated", func(t *testing.T) {
+		var calls []string
+		l := &fakeLedger{}
+		c := NewCoordinator(l, map[string]Action{
+			"card":   fakeAction{name: "card", calls: &calls},
+			"payout": fakeAction{name: "payout", executeErr: boom, calls: &calls},
+		}, &fakeStore{})
+		s := New(uuid.New(), "payout", 250, "", []string{"card", "payout"})
+		require.NoError(t, c.Run(ctx, s))
+		assert.Equal(t, StatusCompensated, s.Status)
+		assert.Equal(t, "payout failed: provider unavailable", s.Error)
+		// The failed step may have gone through, so it is compensated first
+		assert.Equal(t, []string{"execute card", "execute payout", "compensate payout ", "compensate card ext_card"}, calls)
+		assert.Equal(t, 1, l.reversed)
+		assert.NotNil(t, s.ReversalID)
+		for _, step := range s.Steps {
+			assert.Equal(t, StepCompensated, step.Status)
+		}
+	})
+
+	t.Run("compensation stops at a failure and resumes", func(t *testing.T) {
+		var calls []string
+		l := &fakeLedger{}
+		actions := map[string]Action{
+			"card":   fakeAction{name: "card", compensateErr: boom, calls: &calls},
+			"payout": fakeAction{name: "payout", executeErr: boom, calls: &calls},
+		}
+		c := NewCoordinator(l, actions, &fakeStore{})
+		s := New(uuid.New(), "payout", 250, "", []string{"card", "payout"})
+		require.NoError(t, c.Run(ctx, s))
+		assert.Equal(t, StatusCompensationFailed, s.Status)
+		assert.Equal(t, StepCompensationFailed, s.Steps[1].Status)
+		assert.Equal(t, StepDone, s.Steps[0].Status, "the posting stands while an action is not undone")
+		assert.Zero(t, l.reversed)
+
+		actions["card"] = fakeAction{name: "card", calls: &calls}
+		calls = nil
+		require.NoError(t, c.Compensate(ctx, s))
+		assert.Equal(t, StatusCompensated, s.Status)
+		assert.Equal(t, []string{"compensate card ext_card"}, calls, "compensated steps are not undone twice")
+		assert.Equal(t, 1, l.reversed)
+	})
+
+	t.Run("unsaved posting", func(t *testing.T) {
+		c := NewCoordinator(&fakeLedger{}, nil, &fakeStore{})
+		s := New(uuid.New(), "payout", 250, "", nil)
+		s.Steps[0].Status = StepRunning
+		require.NoError(t, c.Compensate(ctx, s))
+		assert.Equal(t, StatusCompensationFailed, s.Status)
+		assert.Equal(t, ErrPostingUnknown.Error(), s.Steps[0].Error)
+	})
+}
+
+func TestValidateName(t *testing.T) {
+	assert.NoError(t, ValidateName("payout"))
+	assert.NoError(t, ValidateName("card_processor2"))
+	assert.Error(t, ValidateName("Payout"))
+	assert.Error(t, ValidateName("p"))
+	assert.Error(t, ValidateName(LedgerStep))
+}
+
+func TestHTTPAction(t *testing.T) {
+	var got map[string]interface{}
+	var header http.Header
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		header = r.Header.Clone()
+		got = nil
+		require.NoError(t, json.Unmarshal(body, &got))
+		require.NoError(t, webhook.Verify("secret", r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()))
+		w.WriteHeader(status)
+		if status == http.StatusOK && got["action"] == "execute" {
+			w.Write([]byte(`{"external_id":"po_123"}`))
+		}
+	}))
+	defer server.Close()
+
+	a := NewHTTPAction(server.URL, "secret", 5*time.Second)
+	r := Request{SagaID: uuid.New(), Step: "payout", CustomerID: uuid.New(), Type: "payout", Amount: 25}
+	externalID, err := a.Execute(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, "po_123", externalID)
+	assert.Equal(t, "execute", got["action"])
+	assert.Equal(t, float64(25), got["amount"])
+	assert.Equal(t, r.SagaID.String()+":payout:execute", header.Get("Idempotency-Key"))
+
+	r.ExternalID = externalID
+	require.NoError(t, a.Compensate(context.Background(), r))
+	assert.Equal(t, "compensate", got["action"])
+	assert.Equal(t, "po_123", got["external_id"])
+
+	status = http.StatusBadGateway
+	_, err = a.Execute(context.Background(), r)
+	assert.EqualError(t, err, "action returned status 502")
+}
@@ -0,0 +1,53 @@
+package schedule
+
+import (
+	"time"
+)
+
+// Frequency is how often a recurring payment falls due
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+)
+
+// Valid reports whether f is a known frequency
+func Valid(f Frequency) bool {
+	switch f {
+	case Daily, Weekly, Monthly:
+		return true
+	}
+	return false
+}
+
+// Next returns the occurrence after date. Monthly schedules stay on anchorDay
+// (the start date's day of month), falling back to the last day of shorter
+// months so a schedule starting on the 31st runs on Feb 28/29 and then on the
+// 31st again.
+func Next(date time.Time, f Frequency, anchorDay int) time.Time {
+	switch f {
+	case Daily:
+		return date.AddDate(0, 0, 1)
+	case Weekly:
+		return date.AddDate(0, 0, 7)
+	default:
+		first := time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, date.Location())
+		day := anchorDay
+		if last := daysIn(first); day > last {
+			day = last
+		}
+		return first.AddDate(0, 0, day-1)
+	}
+}
+
+// Day truncates t to midnight UTC, the granularity schedules run at
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func daysIn(firstOfMonth time.Time) int {
+	return firstOfMonth.AddDate(0, 1, -1).Day()
+}
@@ -0,0 +1,45 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   time.Time
+		freq   Frequency
+		anchor int
+		want   time.Time
+	}{
+		{"daily", date(2025, 4, 30), Daily, 30, date(2025, 5, 1)},
+		{"weekly", date(2025, 4, 28), Weekly, 28, date(2025, 5, 5)},
+		{"monthly", date(2025, 4, 15), Monthly, 15, date(2025, 5, 15)},
+		{"monthly clamps to short month", date(2025, 1, 31), Monthly, 31, date(2025, 2, 28)},
+		{"monthly returns to anchor", date(2025, 2, 28), Monthly, 31, date(2025, 3, 31)},
+		{"monthly leap year", date(2024, 1, 30), Monthly, 30, date(2024, 2, 29)},
+		{"monthly across year end", date(2025, 12, 10), Monthly, 10, date(2026, 1, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Next(tt.from, tt.freq, tt.anchor))
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(Monthly))
+	assert.False(t, Valid("yearly"))
+}
+
+func TestDay(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*60*60)
+	assert.Equal(t, date(2025, 4, 7), Day(time.Date(2025, 4, 8, 5, 0, 0, 0, loc)))
+}
@@ -0,0 +1,50 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"ledger-service/events"
+)
+
+// AWSSecretsManager reads secrets from AWS Secrets Manager. The path is the
+// secret's name or ARN. A secret stored as a JSON object has its keys as
+// fields; any other secret string is a single value.
+type AWSSecretsManager struct {
+	Region string
+	// Endpoint overrides https://secretsmanager.{region}.amazonaws.com
+	Endpoint    string
+	Credentials events.AWSCredentialProvider
+	Client      *http.Client
+}
+
+// NewAWSSecretsManager creates a source reading secrets in region
+func NewAWSSecretsManager(region, endpoint string, creds events.AWSCredentialProvider, timeout time.Duration) *AWSSecretsManager {
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com/"
+	}
+	return &AWSSecretsManager{
+		Region:      region,
+		Endpoint:    endpoint,
+		Credentials: creds,
+		Client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Fetch reads the current version of the secret id
+func (s *AWSSecretsManager) Fetch(ctx context.Context, id string) (map[string]string, error) {
+	var out struct {
+		SecretString string
+	}
+	if err := events.AWSJSON(ctx, s.Client, s.Credentials, s.Endpoint, s.Region, "secretsmanager",
+		"secretsmanager.GetSecretValue", map[string]string{"SecretId": id}, &out); err != nil {
+		return nil, err
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(out.SecretString), &data); err != nil || data == nil {
+		return map[string]string{"": out.SecretString}, nil
+	}
+	return stringFields(data), nil
+}
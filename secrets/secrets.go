@@ -0,0 +1,223 @@
+// Package secrets reads settings from a secrets manager instead of the
+// environment. A setting whose value is a reference, such as
+// vault:secret/data/ledger#database_url or aws-sm:ledger/prod#fx_api_key,
+// stands for the field of the secret it names. Secrets are fetched once and
+// can be refreshed periodically, telling watchers about values that changed.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source is a secrets manager
+type Source interface {
+	// Fetch returns the fields of the secret at path. A secret that is a
+	// single value rather than a set of fields has it under "".
+	Fetch(ctx context.Context, path string) (map[string]string, error)
+}
+
+// Schemes of the references each source resolves
+const (
+	SchemeVault = "vault"
+	SchemeAWS   = "aws-sm"
+)
+
+// Reference is a parsed secret reference
+type Reference struct {
+	Scheme string
+	Path   string
+	// Field of the secret; empty for a secret holding a single value
+	Field string
+}
+
+// ParseReference parses scheme:path#field, returning false when value is
+// not a reference to a known scheme
+func ParseReference(value string) (Reference, bool) {
+	scheme, rest, ok := strings.Cut(value, ":")
+	if !ok || (scheme != SchemeVault && scheme != SchemeAWS) {
+		return Reference{}, false
+	}
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return Reference{}, false
+	}
+	return Reference{Scheme: scheme, Path: path, Field: field}, true
+}
+
+// IsReference reports whether value names a secret rather than being one
+func IsReference(value string) bool {
+	_, ok := ParseReference(value)
+	return ok
+}
+
+type watcher struct {
+	ref  Reference
+	last string
+	fn   func(string)
+}
+
+// Resolver resolves references through the sources configured for their
+// schemes, fetching each secret once
+type Resolver struct {
+	sources map[string]Source
+
+	mu       sync.Mutex
+	secrets  map[string]map[string]string
+	watchers []*watcher
+	err      error
+}
+
+// NewResolver resolves references with sources, keyed by scheme
+func NewResolver(sources map[string]Source) *Resolver {
+	return &Resolver{sources: sources, secrets: map[string]map[string]string{}}
+}
+
+// Resolve returns value itself, or the secret it refers to
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+	r.mu.Lock()
+	fields, cached := r.secrets[ref.Scheme+":"+ref.Path]
+	r.mu.Unlock()
+	if !cached {
+		var err error
+		if fields, err = r.fetch(ctx, ref); err != nil {
+			return "", err
+		}
+		r.mu.Lock()
+		r.secrets[ref.Scheme+":"+ref.Path] = fields
+		r.mu.Unlock()
+	}
+	return field(ref, fields)
+}
+
+func (r *Resolver) fetch(ctx context.Context, ref Reference) (map[string]string, error) {
+	source, ok := r.sources[ref.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("%s secrets are not configured", ref.Scheme)
+	}
+	fields, err := source.Fetch(ctx, ref.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s: %w", ref.Scheme, ref.Path, err)
+	}
+	return fields, nil
+}
+
+func field(ref Reference, fields map[string]string) (string, error) {
+	if ref.Field == "" && len(fields) == 1 {
+		for _, v := range fields {
+			return v, nil
+		}
+	}
+	v, ok := fields[ref.Field]
+	if !ok {
+		if ref.Field == "" {
+			return "", fmt.Errorf("%s:%s has several fields; name one with #field", ref.Scheme, ref.Path)
+		}
+		return "", fmt.Errorf("%s:%s has no field %q", ref.Scheme, ref.Path, ref.Field)
+	}
+	return v, nil
+}
+
+// Getenv wraps getenv so references are resolved. A reference that cannot
+// be resolved reads as unset, and the first such failure is kept for Err.
+func (r *Resolver) Getenv(getenv func(string) string) func(string) string {
+	return func(key string) string {
+		value, err := r.Resolve(context.Background(), getenv(key))
+		if err != nil {
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = fmt.Errorf("unable to read %s from the secrets manager: %w", key, err)
+			}
+			r.mu.Unlock()
+			return ""
+		}
+		return value
+	}
+}
+
+// Err is the first failure Getenv read as unset
+func (r *Resolver) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Watch calls fn with the new value of the secret value refers to whenever
+// a refresh changes it. It does nothing when value is not a reference.
+func (r *Resolver) Watch(value string, fn func(string)) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return
+	}
+	current, _ := r.Resolve(context.Background(), value)
+	r.mu.Lock()
+	r.watchers = append(r.watchers, &watcher{ref: ref, last: current, fn: fn})
+	r.mu.Unlock()
+}
+
+// Refresh fetches every secret read so far again and tells watchers about
+// values that changed. A secret that cannot be fetched keeps its last value.
+func (r *Resolver) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	refs := make([]Reference, 0, len(r.secrets))
+	for key := range r.secrets {
+		scheme, path, _ := strings.Cut(key, ":")
+		refs = append(refs, Reference{Scheme: scheme, Path: path})
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	for _, ref := range refs {
+		fields, err := r.fetch(ctx, ref)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.mu.Lock()
+		r.secrets[ref.Scheme+":"+ref.Path] = fields
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	var changed []func()
+	for _, w := range r.watchers {
+		fields := r.secrets[w.ref.Scheme+":"+w.ref.Path]
+		value, err := field(w.ref, fields)
+		if err != nil || value == w.last {
+			continue
+		}
+		w.last = value
+		fn := w.fn
+		changed = append(changed, func() { fn(value) })
+	}
+	r.mu.Unlock()
+	for _, notify := range changed {
+		notify()
+	}
+	return errors.Join(errs...)
+}
+
+// Run refreshes the secrets every interval until ctx is cancelled
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.Refresh(ctx); err != nil {
+			log.Printf("Secrets refresh failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets from HashiCorp Vault's KV engine, version 1 or 2, with
+// a token. The path is the API path after /v1/, e.g. secret/data/ledger for
+// a KV v2 mount called secret.
+type Vault struct {
+	// Address is the server, e.g. https://vault.internal:8200
+	Address string
+	Token   string
+	// Namespace is the Vault Enterprise namespace, if any
+	Namespace string
+	Client    *http.Client
+}
+
+// NewVault creates a source reading from the server at address
+func NewVault(address, token, namespace string, timeout time.Duration) *Vault {
+	return &Vault{
+		Address:   address,
+		Token:     token,
+		Namespace: namespace,
+		Client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Fetch reads the secret at path
+func (v *Vault) Fetch(ctx context.Context, path string) (map[string]string, error) {
+	endpoint := strings.TrimRight(v.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(body, &e)
+		if len(e.Errors) > 0 {
+			return nil, fmt.Errorf("vault: %s: %s", resp.Status, strings.Join(e.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault: %s", resp.Status)
+	}
+
+	var out struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("vault: invalid response: %w", err)
+	}
+	// KV v2 nests the fields under data.data, next to the version metadata
+	data := out.Data
+	if nested, ok := data["data"]; ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return nil, fmt.Errorf("vault: invalid response: %w", err)
+			}
+		}
+	}
+	return stringFields(data), nil
+}
+
+// stringFields converts secret fields to strings; fields that are not
+// strings keep their JSON encoding
+func stringFields(data map[string]json.RawMessage) map[string]string {
+	fields := make(map[string]string, len(data))
+	for k, raw := range data {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+		fields[k] = s
+	}
+	return fields
+}
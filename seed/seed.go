@@ -0,0 +1,216 @@
+// Package seed generates realistic demo data: customers with randomized but
+// internally consistent transaction histories, for local development, demos
+// and load testing
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits on a single run, protecting the dev endpoint from runaway requests
+const (
+	MaxCustomers    = 10000
+	MaxTransactions = 1000
+	MaxDays         = 3650
+)
+
+// Options controls the size and shape of the generated data. A zero Seed
+// picks a random one; reusing a Seed reproduces the same data set.
+type Options struct {
+	Customers       int   `json:"customers" example:"50"`
+	MaxTransactions int   `json:"max_transactions" example:"40"`
+	Days            int   `json:"days" example:"90"`
+	Seed            int64 `json:"seed,omitempty" example:"42"`
+}
+
+// DefaultOptions is a small data set suitable for local development
+func DefaultOptions() Options {
+	return Options{Customers: 50, MaxTransactions: 40, Days: 90}
+}
+
+// Validate checks the options are within the supported limits
+func (o Options) Validate() error {
+	switch {
+	case o.Customers < 1 || o.Customers > MaxCustomers:
+		return fmt.Errorf("customers must be between 1 and %d", MaxCustomers)
+	case o.MaxTransactions < 0 || o.MaxTransactions > MaxTransactions:
+		return fmt.Errorf("max_transactions must be between 0 and %d", MaxTransactions)
+	case o.Days < 1 || o.Days > MaxDays:
+		return fmt.Errorf("days must be between 1 and %d", MaxDays)
+	}
+	return nil
+}
+
+// Result summarizes what a run inserted
+type Result struct {
+	Customers    int   `json:"customers" example:"50"`
+	Transactions int   `json:"transactions" example:"1012"`
+	Seed         int64 `json:"seed" example:"42"`
+}
+
+// Beginner starts a database transaction
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var (
+	firstNames = []string{"Ava", "Liam", "Olivia", "Noah", "Emma", "Mateo", "Sofia", "Lucas", "Amara", "Kenji",
+		"Priya", "Omar", "Chloé", "Jonas", "Zoë", "Diego", "Aisha", "Tomás", "Mei", "Elijah"}
+	lastNames = []string{"Smith", "García", "Okafor", "Nguyen", "Müller", "Rossi", "Kowalski", "Tanaka",
+		"Patel", "Johansson", "O'Brien", "Haddad", "Dubois", "Silva", "Cohen", "Ivanova"}
+	accountTypes = []string{"checking", "checking", "checking", "savings", "savings", "escrow"}
+)
+
+// Customer is a generated customer row
+type Customer struct {
+	ID          uuid.UUID
+	Name        string
+	Email       string
+	PhoneNumber string
+	AccountType string
+	Balance     float64
+	CreatedAt   time.Time
+}
+
+// Transaction is a generated posted transaction
+type Transaction struct {
+	ID         uuid.UUID
+	CustomerID uuid.UUID
+	Type       string
+	Amount     float64
+	CreatedAt  time.Time
+}
+
+// Build generates the data set for opts without touching the database. Every
+// customer's balance equals the sum of their transaction history, which
+// never goes negative.
+func Build(opts Options, now time.Time) ([]Customer, []Transaction) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	newID := func() uuid.UUID { return uuid.Must(uuid.NewRandomFromReader(rng)) }
+	start := now.AddDate(0, 0, -opts.Days)
+	span := now.Sub(start)
+
+	customers := make([]Customer, 0, opts.Customers)
+	var transactions []Transaction
+	for i := 0; i < opts.Customers; i++ {
+		first := firstNames[rng.Intn(len(firstNames))]
+		last := lastNames[rng.Intn(len(lastNames))]
+		c := Customer{
+			ID:          newID(),
+			Name:        first + " " + last,
+			Email:       fmt.Sprintf("%s.%s.%d@example.com", emailPart(first), emailPart(last), i+1),
+			PhoneNumber: fmt.Sprintf("+1555%07d", rng.Intn(10000000)),
+			AccountType: accountTypes[rng.Intn(len(accountTypes))],
+			CreatedAt:   start.Add(time.Duration(rng.Int63n(int64(span) / 4))),
+		}
+
+		// Each history opens with a deposit, then mixes income and spending
+		times := make([]time.Time, rng.Intn(opts.MaxTransactions+1))
+		for j := range times {
+			times[j] = c.CreatedAt.Add(time.Duration(rng.Int63n(int64(now.Sub(c.CreatedAt)) + 1)))
+		}
+		sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+
+		deposit := money(100 + rng.Float64()*4900)
+		transactions = append(transactions, Transaction{ID: newID(), CustomerID: c.ID, Type: "credit", Amount: deposit, CreatedAt: c.CreatedAt})
+		c.Balance = deposit
+		for _, at := range times {
+			typ, amount := randomEntry(rng, c.Balance)
+			if amount <= 0 {
+				continue
+			}
+			if typ == "credit" || typ == "refund" || typ == "interest" {
+				c.Balance = money(c.Balance + amount)
+			} else {
+				c.Balance = money(c.Balance - amount)
+			}
+			transactions = append(transactions, Transaction{ID: newID(), CustomerID: c.ID, Type: typ, Amount: amount, CreatedAt: at})
+		}
+		customers = append(customers, c)
+	}
+	return customers, transactions
+}
+
+// randomEntry picks a transaction type and amount. Debits are capped at the
+// available balance and come back as zero when nothing can be spent.
+func randomEntry(rng *rand.Rand, balance float64) (string, float64) {
+	switch r := rng.Intn(100); {
+	case r < 15:
+		return "credit", money(500 + rng.Float64()*2500)
+	case r < 20:
+		return "refund", money(5 + rng.Float64()*95)
+	case r < 23:
+		return "interest", money(math.Max(balance*0.001, 0.01))
+	case r < 28:
+		return "fee", math.Min(money(1+rng.Float64()*14), balance)
+	case r < 38:
+		return "debit", math.Min(money(20+rng.Float64()*480), balance)
+	default:
+		return "purchase", math.Min(money(2+rng.Float64()*148), balance)
+	}
+}
+
+func money(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func emailPart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Generate builds a data set and inserts it in one database transaction
+func Generate(ctx context.Context, db Beginner, opts Options) (Result, error) {
+	if err := opts.Validate(); err != nil {
+		return Result{}, err
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	customers, transactions := Build(opts, time.Now().UTC())
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	customerRows := make([][]interface{}, len(customers))
+	for i, c := range customers {
+		customerRows[i] = []interface{}{c.ID, c.Name, c.Balance, c.Email, c.PhoneNumber, c.AccountType}
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"customers"},
+		[]string{"id", "name", "balance", "email", "phone_number", "account_type"},
+		pgx.CopyFromRows(customerRows)); err != nil {
+		return Result{}, fmt.Errorf("insert customers: %w", err)
+	}
+
+	transactionRows := make([][]interface{}, len(transactions))
+	for i, t := range transactions {
+		transactionRows[i] = []interface{}{t.ID, t.CustomerID, t.Type, t.Amount, "posted", t.CreatedAt}
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"transactions"},
+		[]string{"id", "customer_id", "type", "amount", "status", "created_at"},
+		pgx.CopyFromRows(transactionRows)); err != nil {
+		return Result{}, fmt.Errorf("insert transactions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Result{}, err
+	}
+	return Result{Customers: len(customers), Transactions: len(transactions), Seed: opts.Seed}, nil
+}
@@ -0,0 +1,70 @@
+package seed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2025, 4, 8, 12, 0, 0, 0, time.UTC)
+	opts := Options{Customers: 25, MaxTransactions: 60, Days: 30, Seed: 42}
+	customers, transactions := Build(opts, now)
+
+	assert.Len(t, customers, 25)
+	balances := map[uuid.UUID]float64{}
+	for _, tx := range transactions {
+		assert.Greater(t, tx.Amount, 0.0)
+		assert.False(t, tx.CreatedAt.After(now))
+		assert.False(t, tx.CreatedAt.Before(now.AddDate(0, 0, -opts.Days)))
+		switch tx.Type {
+		case "credit", "refund", "interest":
+			balances[tx.CustomerID] += tx.Amount
+		default:
+			balances[tx.CustomerID] -= tx.Amount
+		}
+		assert.GreaterOrEqual(t, balances[tx.CustomerID], -1e-9, "balance went negative")
+	}
+	for _, c := range customers {
+		assert.InDelta(t, c.Balance, balances[c.ID], 1e-6, c.Name)
+		assert.Contains(t, c.Email, "@example.com")
+	}
+
+	again, _ := Build(opts, now)
+	assert.Equal(t, customers, again, "same seed should reproduce the data set")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, DefaultOptions().Validate())
+	assert.Error(t, Options{Customers: 0, Days: 1}.Validate())
+	assert.Error(t, Options{Customers: MaxCustomers + 1, Days: 1}.Validate())
+	assert.Error(t, Options{Customers: 1, MaxTransactions: -1, Days: 1}.Validate())
+	assert.Error(t, Options{Customers: 1, Days: 0}.Validate())
+}
+
+func TestGenerate(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer mock.Close(context.Background())
+
+	mock.ExpectBegin()
+	mock.ExpectCopyFrom(pgx.Identifier{"customers"}, []string{"id", "name", "balance", "email", "phone_number", "account_type"}).
+		WillReturnResult(3)
+	mock.ExpectCopyFrom(pgx.Identifier{"transactions"}, []string{"id", "customer_id", "type", "amount", "status", "created_at"}).
+		WillReturnResult(10)
+	mock.ExpectCommit()
+
+	result, err := Generate(context.Background(), mock, Options{Customers: 3, MaxTransactions: 5, Days: 7, Seed: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Customers)
+	assert.Equal(t, int64(7), result.Seed)
+	assert.GreaterOrEqual(t, result.Transactions, 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// mt940Codes gives the SWIFT transaction type code, written after an N
+// for a non-SWIFT transfer, of the ledger types that have one more
+// specific than MSC (miscellaneous)
+var mt940Codes = map[string]string{
+	"fee":             "CHG",
+	"interest":        "INT",
+	"loan_interest":   "INT",
+	"transfer_in":     "TRF",
+	"transfer_out":    "TRF",
+	"move_in":         "TRF",
+	"move_out":        "TRF",
+	"credit_transfer": "TRF",
+	"bank_credit":     "TRF",
+	"bank_debit":      "TRF",
+	"withdrawal":      "TRF",
+}
+
+// mt940Mark is an entry's debit/credit mark. A reversal is marked as what
+// it reverses: RD for a credit giving back a debit, RC for a debit taking
+// back a credit.
+func mt940Mark(e Entry) string {
+	switch {
+	case e.Type == "reversal_credit":
+		return "RD"
+	case e.Type == "reversal_debit":
+		return "RC"
+	case e.Amount < 0:
+		return "D"
+	default:
+		return "C"
+	}
+}
+
+var swiftReplacer = regexp.MustCompile(`[^A-Za-z0-9/\-?:().,'+ ]`)
+
+// swiftText keeps to the SWIFT x character set, at most max characters;
+// anything else becomes a space
+func swiftText(s string, max int) string {
+	s = strings.TrimSpace(swiftReplacer.ReplaceAllString(s, " "))
+	if len(s) > max {
+		s = strings.TrimSpace(s[:max])
+	}
+	return s
+}
+
+// swiftReference is a 16 character reference field, which may not start
+// or end with a slash or hold two together
+func swiftReference(s string) string {
+	s = strings.ReplaceAll(swiftText(s, 16), " ", "")
+	for strings.Contains(s, "//") {
+		s = strings.ReplaceAll(s, "//", "/")
+	}
+	return strings.Trim(s, "/")
+}
+
+// mt940Amount writes an unsigned amount with a decimal comma, as SWIFT
+// wants
+func mt940Amount(v float64, currency string) string {
+	return strings.Replace(amount(math.Abs(v), currency), ".", ",", 1)
+}
+
+// mt940Balance writes a balance field: its mark, date, currency and amount
+func mt940Balance(v float64, date time.Time, currency string) string {
+	mark := "C"
+	if v < 0 {
+		mark = "D"
+	}
+	return mark + date.Format("060102") + currency + mt940Amount(v, currency)
+}
+
+// WriteMT940 writes s as a SWIFT MT940 customer statement: the text of
+// the message's block 4, as treasury systems import from files. reference
+// identifies the message and number is the account's statement number.
+// The statement is written as a single page; s.Opening is the booked
+// balance at the start of s.From and s.Balance at the end of s.To.
+func WriteMT940(w io.Writer, s Statement, reference string, number int) error {
+	if number < 1 || number > 99999 {
+		return fmt.Errorf("statement number %d is not between 1 and 99999", number)
+	}
+	account := swiftText(s.AccountID, 35)
+	if account == "" {
+		return fmt.Errorf("statement has no account identification")
+	}
+	if len(s.Currency) != 3 {
+		return fmt.Errorf("invalid currency %q", s.Currency)
+	}
+	b := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(b, format+"\r\n", args...)
+	}
+	line(":20:%s", swiftReference(reference))
+	line(":25:%s", account)
+	line(":28C:%05d/00001", number)
+	line(":60F:%s", mt940Balance(s.Opening, s.From, s.Currency))
+	for _, e := range s.Entries {
+		booked := e.Booked
+		if booked.IsZero() {
+			booked = e.Date
+		}
+		code, ok := mt940Codes[e.Type]
+		if !ok {
+			code = "MSC"
+		}
+		ownerRef := swiftReference(e.Reference)
+		if ownerRef == "" {
+			ownerRef = "NONREF"
+		}
+		line(":61:%s%s%s%s%s%s//%s", e.Date.Format("060102"), booked.Format("0102"), mt940Mark(e),
+			mt940Amount(e.Amount, s.Currency), "N"+code, ownerRef, swiftReference(strings.ReplaceAll(e.ID, "-", "")))
+		// Information to the account owner: up to six lines of 65. A colon
+		// could make a continuation line look like a new field, and a lone
+		// hyphen like the end of the message.
+		info := swiftText(strings.ReplaceAll(e.Payee+" "+e.Memo, ":", " "), 6*65)
+		info = strings.TrimRight(info, "- ")
+		for i := 0; i < len(info); i += 65 {
+			prefix := ""
+			if i == 0 {
+				prefix = ":86:"
+			}
+			line("%s%s", prefix, info[i:min(i+65, len(info))])
+		}
+	}
+	line(":62F:%s", mt940Balance(s.Balance, s.To, s.Currency))
+	line("-")
+	return b.Flush()
+}
@@ -0,0 +1,203 @@
+// Package statement writes a customer's posted transactions in the file
+// formats personal finance, accounting and treasury tools import: OFX, QIF
+// and SWIFT MT940
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/money"
+)
+
+// Statement is an account's transactions over a period, with its balance
+type Statement struct {
+	// BankID and AccountID identify the account to the importing tool,
+	// which matches later imports to the same account by them
+	BankID    string
+	AccountID string
+	Currency  string
+	// Credit is set for credit accounts, which are written as credit cards
+	Credit bool
+	// From and To are the first and last value dates covered
+	From, To time.Time
+	Entries  []Entry
+	// Balance is the account holder's balance at BalanceAt: negative on a
+	// credit account that is owed
+	Balance   float64
+	BalanceAt time.Time
+	// Opening is the balance before From, which only MT940 carries
+	Opening float64
+}
+
+// Entry is one posted transaction
+type Entry struct {
+	ID string
+	// Date is the value date, when the transaction moved the balance
+	Date time.Time
+	// Booked is when the transaction was posted
+	Booked time.Time
+	// Amount is signed from the account holder's side: money in is
+	// positive, money out negative
+	Amount float64
+	// Type is the ledger transaction type, such as fee or interest
+	Type string
+	// Reference is the payment's external reference, if it has one
+	Reference string
+	Payee     string
+	Memo      string
+}
+
+// ofxTypes gives the OFX TRNTYPE of the ledger types that have one more
+// specific than CREDIT or DEBIT
+var ofxTypes = map[string]string{
+	"interest":      "INT",
+	"loan_interest": "INT",
+	"fee":           "FEE",
+	"purchase":      "POS",
+	"card_payment":  "DEP",
+	"bank_credit":   "DEP",
+	"withdrawal":    "CASH",
+	"transfer_in":   "XFER",
+	"transfer_out":  "XFER",
+	"move_in":       "XFER",
+	"move_out":      "XFER",
+}
+
+// ofxType is an entry's OFX TRNTYPE, falling back to its sign
+func ofxType(e Entry) string {
+	if t, ok := ofxTypes[e.Type]; ok {
+		return t
+	}
+	if e.Amount < 0 {
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+// amount formats an amount to the currency's minor unit
+func amount(v float64, currency string) string {
+	d := money.Decimals(currency)
+	return strconv.FormatFloat(math.Round(v*math.Pow10(d))/math.Pow10(d), 'f', d, 64)
+}
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", " ", "\n", " ")
+
+// ofxText escapes s for an OFX element, cut to at most max characters
+func ofxText(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > max {
+		s = strings.TrimSpace(string(r[:max]))
+	}
+	return ofxEscaper.Replace(s)
+}
+
+// ofxDate writes a value date as a bare date, so the importing tool does
+// not shift it a day by converting from UTC to its own zone
+func ofxDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// ofxTime writes an instant in UTC with its zone, as OFX wants
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// WriteOFX writes s as an OFX 1.0.2 bank statement download, or a credit
+// card statement for a credit account. now is the server time it reports.
+func WriteOFX(w io.Writer, s Statement, now time.Time) error {
+	b := bufio.NewWriter(w)
+	msgs, trnrs, stmtrs, acctFrom := "BANKMSGSRSV1", "STMTTRNRS", "STMTRS", "BANKACCTFROM"
+	if s.Credit {
+		msgs, trnrs, stmtrs, acctFrom = "CREDITCARDMSGSRSV1", "CCSTMTTRNRS", "CCSTMTRS", "CCACCTFROM"
+	}
+	// The SGML header takes CRLF line ends; the body is one element a line
+	fmt.Fprint(b, "OFXHEADER:100\r\nDATA:OFXSGML\r\nVERSION:102\r\nSECURITY:NONE\r\nENCODING:UTF-8\r\nCHARSET:NONE\r\n"+
+		"COMPRESSION:NONE\r\nOLDFILEUID:NONE\r\nNEWFILEUID:NONE\r\n\r\n")
+	lines := []string{
+		"<OFX>",
+		"<SIGNONMSGSRSV1>", "<SONRS>",
+		"<STATUS>", "<CODE>0", "<SEVERITY>INFO", "</STATUS>",
+		"<DTSERVER>" + ofxTime(now), "<LANGUAGE>ENG",
+		"</SONRS>", "</SIGNONMSGSRSV1>",
+		"<" + msgs + ">", "<" + trnrs + ">",
+		"<TRNUID>0",
+		"<STATUS>", "<CODE>0", "<SEVERITY>INFO", "</STATUS>",
+		"<" + stmtrs + ">",
+		"<CURDEF>" + s.Currency,
+		"<" + acctFrom + ">",
+	}
+	if !s.Credit {
+		lines = append(lines, "<BANKID>"+ofxEscaper.Replace(s.BankID))
+	}
+	lines = append(lines, "<ACCTID>"+ofxEscaper.Replace(s.AccountID))
+	if !s.Credit {
+		lines = append(lines, "<ACCTTYPE>CHECKING")
+	}
+	lines = append(lines,
+		"</"+acctFrom+">",
+		"<BANKTRANLIST>",
+		"<DTSTART>"+ofxDate(s.From),
+		"<DTEND>"+ofxDate(s.To),
+	)
+	for _, e := range s.Entries {
+		lines = append(lines,
+			"<STMTTRN>",
+			"<TRNTYPE>"+ofxType(e),
+			"<DTPOSTED>"+ofxDate(e.Date),
+			"<TRNAMT>"+amount(e.Amount, s.Currency),
+			"<FITID>"+ofxText(e.ID, 255),
+			"<NAME>"+ofxText(e.Payee, 32),
+		)
+		if e.Memo != "" {
+			lines = append(lines, "<MEMO>"+ofxText(e.Memo, 255))
+		}
+		lines = append(lines, "</STMTTRN>")
+	}
+	lines = append(lines,
+		"</BANKTRANLIST>",
+		"<LEDGERBAL>",
+		"<BALAMT>"+amount(s.Balance, s.Currency),
+		"<DTASOF>"+ofxTime(s.BalanceAt),
+		"</LEDGERBAL>",
+		"</"+stmtrs+">", "</"+trnrs+">", "</"+msgs+">",
+		"</OFX>",
+	)
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\r\n")
+	}
+	return b.Flush()
+}
+
+var qifEscaper = strings.NewReplacer("\r", " ", "\n", " ")
+
+// WriteQIF writes s's entries as a QIF bank account, or a credit card for
+// a credit account. Dates are MM/DD/YYYY, which Quicken, GnuCash and
+// most other tools read by default. QIF carries no balance or account ID.
+func WriteQIF(w io.Writer, s Statement) error {
+	b := bufio.NewWriter(w)
+	if s.Credit {
+		b.WriteString("!Type:CCard\n")
+	} else {
+		b.WriteString("!Type:Bank\n")
+	}
+	for _, e := range s.Entries {
+		fmt.Fprintf(b, "D%s\n", e.Date.Format("01/02/2006"))
+		fmt.Fprintf(b, "T%s\n", amount(e.Amount, s.Currency))
+		if e.Payee != "" {
+			fmt.Fprintf(b, "P%s\n", qifEscaper.Replace(e.Payee))
+		}
+		if e.Memo != "" {
+			fmt.Fprintf(b, "M%s\n", qifEscaper.Replace(e.Memo))
+		}
+		// Everything exported has posted, so it is cleared
+		b.WriteString("CX\n^\n")
+	}
+	return b.Flush()
+}
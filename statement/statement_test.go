@@ -0,0 +1,111 @@
+package statement
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStatement() Statement {
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 0, 0, 0, 0, time.UTC) }
+	return Statement{
+		BankID:    "LEDGER",
+		AccountID: "550e8400-e29b-41d4-a716-446655440000",
+		Currency:  "USD",
+		From:      day(1),
+		To:        day(30),
+		Entries: []Entry{
+			{ID: "t1", Date: day(3), Amount: 1000, Type: "credit", Payee: "Salary & bonus"},
+			{ID: "t2", Date: day(8), Amount: -42.5, Type: "purchase", Payee: "Coffee <Corner>", Memo: "purchase"},
+			{ID: "t3", Date: day(30), Amount: -2, Type: "custom_debit", Payee: "Monthly account maintenance charge"},
+		},
+		Balance:   955.5,
+		BalanceAt: time.Date(2025, 4, 30, 18, 0, 0, 0, time.FixedZone("EST", -5*3600)),
+	}
+}
+
+func TestWriteOFX(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, WriteOFX(&buf, testStatement(), now))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "OFXHEADER:100\r\nDATA:OFXSGML\r\nVERSION:102\r\n"))
+	assert.Contains(t, out, "<DTSERVER>20250501090000.000[0:GMT]\r\n")
+	assert.Contains(t, out, "<BANKACCTFROM>\r\n<BANKID>LEDGER\r\n<ACCTID>550e8400-e29b-41d4-a716-446655440000\r\n<ACCTTYPE>CHECKING\r\n</BANKACCTFROM>")
+	assert.Contains(t, out, "<DTSTART>20250401\r\n<DTEND>20250430\r\n")
+	assert.Contains(t, out, "<TRNTYPE>CREDIT\r\n<DTPOSTED>20250403\r\n<TRNAMT>1000.00\r\n<FITID>t1\r\n<NAME>Salary &amp; bonus\r\n</STMTTRN>")
+	assert.Contains(t, out, "<TRNTYPE>POS\r\n<DTPOSTED>20250408\r\n<TRNAMT>-42.50\r\n<FITID>t2\r\n<NAME>Coffee &lt;Corner&gt;\r\n<MEMO>purchase\r\n")
+	assert.Contains(t, out, "<TRNTYPE>DEBIT\r\n<DTPOSTED>20250430\r\n<TRNAMT>-2.00\r\n<FITID>t3\r\n<NAME>Monthly account maintenance char\r\n")
+	assert.Contains(t, out, "<BALAMT>955.50\r\n<DTASOF>20250430230000.000[0:GMT]\r\n")
+	assert.True(t, strings.HasSuffix(out, "</STMTRS>\r\n</STMTTRNRS>\r\n</BANKMSGSRSV1>\r\n</OFX>\r\n"))
+
+	s := testStatement()
+	s.Credit = true
+	s.Balance = -1200
+	buf.Reset()
+	require.NoError(t, WriteOFX(&buf, s, now))
+	out = buf.String()
+	assert.Contains(t, out, "<CREDITCARDMSGSRSV1>\r\n<CCSTMTTRNRS>")
+	assert.Contains(t, out, "<CCACCTFROM>\r\n<ACCTID>550e8400-e29b-41d4-a716-446655440000\r\n</CCACCTFROM>")
+	assert.NotContains(t, out, "<BANKID>")
+	assert.Contains(t, out, "<TRNAMT>-42.50\r\n")
+	assert.Contains(t, out, "<BALAMT>-1200.00\r\n")
+}
+
+func TestWriteQIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteQIF(&buf, testStatement()))
+	assert.Equal(t, "!Type:Bank\n"+
+		"D04/03/2025\nT1000.00\nPSalary & bonus\nCX\n^\n"+
+		"D04/08/2025\nT-42.50\nPCoffee <Corner>\nMpurchase\nCX\n^\n"+
+		"D04/30/2025\nT-2.00\nPMonthly account maintenance charge\nCX\n^\n", buf.String())
+
+	s := testStatement()
+	s.Credit = true
+	s.Entries = nil
+	buf.Reset()
+	require.NoError(t, WriteQIF(&buf, s))
+	assert.Equal(t, "!Type:CCard\n", buf.String())
+}
+
+func TestWriteMT940(t *testing.T) {
+	s := testStatement()
+	s.AccountID = "DE89370400440532013000"
+	s.Entries[0].Booked = time.Date(2025, 4, 4, 9, 0, 0, 0, time.UTC)
+	s.Entries[0].Reference = "PAYROLL/APR//25"
+	s.Entries = append(s.Entries, Entry{ID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Date: s.To, Amount: 42.5, Type: "reversal_credit",
+		Payee: "Reversal of a debit whose saga failed", Memo: "reversal credit"})
+	s.Balance = 998
+	var buf bytes.Buffer
+	require.NoError(t, WriteMT940(&buf, s, "5b1f0e6c-2d7a-4c1e", 7))
+	assert.Equal(t, ":20:5b1f0e6c-2d7a-4c\r\n"+
+		":25:DE89370400440532013000\r\n"+
+		":28C:00007/00001\r\n"+
+		":60F:C250401USD0,00\r\n"+
+		":61:2504030404C1000,00NMSCPAYROLL/APR/25//t1\r\n"+
+		":86:Salary   bonus\r\n"+
+		":61:2504080408D42,50NMSCNONREF//t2\r\n"+
+		":86:Coffee  Corner  purchase\r\n"+
+		":61:2504300430D2,00NMSCNONREF//t3\r\n"+
+		":86:Monthly account maintenance charge\r\n"+
+		":61:2504300430RD42,50NMSCNONREF//7c9e6679742540de\r\n"+
+		":86:Reversal of a debit whose saga failed reversal credit\r\n"+
+		":62F:C250430USD998,00\r\n"+
+		"-\r\n", buf.String())
+
+	s.Entries = []Entry{{ID: "t4", Date: s.To, Amount: -1, Type: "fee", Payee: strings.Repeat("x", 100)}}
+	s.Balance = -20
+	buf.Reset()
+	require.NoError(t, WriteMT940(&buf, s, "r", 1))
+	assert.Contains(t, buf.String(), "D1,00NCHGNONREF//t4\r\n:86:"+strings.Repeat("x", 65)+"\r\n"+strings.Repeat("x", 35)+"\r\n")
+	assert.Contains(t, buf.String(), ":62F:D250430USD20,00\r\n")
+
+	assert.Error(t, WriteMT940(&buf, s, "r", 100000))
+	s.AccountID = ""
+	assert.Error(t, WriteMT940(&buf, s, "r", 1))
+}
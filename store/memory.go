@@ -0,0 +1,498 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Memory keeps the ledger in process. Transactions run one at a time, which
+// makes LockCustomer trivially safe; nothing survives a restart.
+type Memory struct {
+	mu   sync.Mutex
+	data memoryData
+}
+
+// NewMemory creates an empty store
+func NewMemory() *Memory {
+	return &Memory{data: memoryData{customers: map[uuid.UUID]*Customer{}}}
+}
+
+func (m *Memory) CreateCustomer(ctx context.Context, c *Customer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.CreateCustomer(ctx, c)
+}
+
+func (m *Memory) GetCustomer(ctx context.Context, id uuid.UUID) (Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.GetCustomer(ctx, id)
+}
+
+func (m *Memory) GetBalance(ctx context.Context, id uuid.UUID) (Balance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.GetBalance(ctx, id)
+}
+
+func (m *Memory) GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Balance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.GetBalances(ctx, ids)
+}
+
+func (m *Memory) CustomerExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.CustomerExists(ctx, id)
+}
+
+func (m *Memory) CustomerTimezone(ctx context.Context, id uuid.UUID) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.CustomerTimezone(ctx, id)
+}
+
+func (m *Memory) SetBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.SetBalance(ctx, id, balance)
+}
+
+func (m *Memory) AddAddress(ctx context.Context, customerID uuid.UUID, a *Address) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.AddAddress(ctx, customerID, a)
+}
+
+func (m *Memory) InsertTransaction(ctx context.Context, t *Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.InsertTransaction(ctx, t)
+}
+
+func (m *Memory) InsertTransfer(ctx context.Context, t *Transfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.InsertTransfer(ctx, t)
+}
+
+func (m *Memory) GetTransfer(ctx context.Context, id uuid.UUID) (Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.GetTransfer(ctx, id)
+}
+
+func (m *Memory) SetTransferStatus(ctx context.Context, id uuid.UUID, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.SetTransferStatus(ctx, id, status)
+}
+
+func (m *Memory) SetTransactionStatus(ctx context.Context, id uuid.UUID, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.SetTransactionStatus(ctx, id, status)
+}
+
+func (m *Memory) GetTransaction(ctx context.Context, customerID, id uuid.UUID) (Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.GetTransaction(ctx, customerID, id)
+}
+
+func (m *Memory) FindUniqueReference(ctx context.Context, customerID uuid.UUID, reference string) (Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.FindUniqueReference(ctx, customerID, reference)
+}
+
+func (m *Memory) FindByReference(ctx context.Context, reference string, limit int) ([]Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.FindByReference(ctx, reference, limit)
+}
+
+func (m *Memory) CountTransactions(ctx context.Context, customerID uuid.UUID, filter TransactionFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.CountTransactions(ctx, customerID, filter)
+}
+
+func (m *Memory) ListTransactions(ctx context.Context, customerID uuid.UUID, opts ListOptions) ([]Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data.ListTransactions(ctx, customerID, opts)
+}
+
+// Begin holds the store until the transaction commits or rolls back
+func (m *Memory) Begin(ctx context.Context) (Tx, error) {
+	m.mu.Lock()
+	return &memoryTx{memoryData: &m.data, m: m, snapshot: m.data.clone()}, nil
+}
+
+// memoryTx writes straight to the store, restoring the snapshot taken at
+// Begin on rollback
+type memoryTx struct {
+	*memoryData
+	m        *Memory
+	snapshot memoryData
+	done     bool
+}
+
+func (t *memoryTx) LockCustomer(ctx context.Context, id uuid.UUID) (Customer, error) {
+	c, ok := t.customers[id]
+	if !ok {
+		return Customer{}, ErrNotFound
+	}
+	return Customer{ID: id, Balance: c.Balance, AccountType: c.AccountType, Timezone: c.Timezone, AllowNegative: c.AllowNegative,
+		BalanceType: c.BalanceType, CreditLimit: c.CreditLimit, Overdraft: c.Overdraft, Currency: c.Currency}, nil
+}
+
+// LockTransfer needs no lock beyond the store's, which the transaction holds
+func (t *memoryTx) LockTransfer(ctx context.Context, id uuid.UUID) (Transfer, error) {
+	return t.GetTransfer(ctx, id)
+}
+
+func (t *memoryTx) LockTransactionTransfers(ctx context.Context, transactionID uuid.UUID) ([]Transfer, error) {
+	var leg *Transaction
+	for i := range t.transactions {
+		if t.transactions[i].ID == transactionID {
+			leg = &t.transactions[i]
+		}
+	}
+	if leg == nil {
+		return nil, nil
+	}
+	var transfers []Transfer
+	for _, transfer := range t.transfers {
+		if (leg.TransferID != nil && transfer.ID == *leg.TransferID) ||
+			(leg.BatchID != nil && transfer.BatchID != nil && *transfer.BatchID == *leg.BatchID) {
+			if transfer.Status == "" {
+				transfer.Status = "completed"
+			}
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
+
+func (t *memoryTx) Commit(ctx context.Context) error {
+	if !t.done {
+		t.done = true
+		t.m.mu.Unlock()
+	}
+	return nil
+}
+
+func (t *memoryTx) Rollback(ctx context.Context) error {
+	if !t.done {
+		t.done = true
+		*t.memoryData = t.snapshot
+		t.m.mu.Unlock()
+	}
+	return nil
+}
+
+// memoryData is the store's content; callers hold Memory.mu
+type memoryData struct {
+	customers    map[uuid.UUID]*Customer
+	transactions []Transaction
+	transfers    []Transfer
+}
+
+func (d memoryData) clone() memoryData {
+	customers := make(map[uuid.UUID]*Customer, len(d.customers))
+	for id, c := range d.customers {
+		copied := copyCustomer(*c)
+		customers[id] = &copied
+	}
+	return memoryData{
+		customers:    customers,
+		transactions: append([]Transaction(nil), d.transactions...),
+		transfers:    append([]Transfer(nil), d.transfers...),
+	}
+}
+
+func copyCustomer(c Customer) Customer {
+	c.Addresses = append([]Address{}, c.Addresses...)
+	return c
+}
+
+func (d *memoryData) CreateCustomer(ctx context.Context, c *Customer) error {
+	stored := copyCustomer(*c)
+	stored.Addresses = nil
+	if stored.VerificationStatus == "" {
+		stored.VerificationStatus = "unverified"
+	}
+	stored.BalanceType = balanceType(stored.BalanceType)
+	stored.Currency = currency(stored.Currency)
+	d.customers[c.ID] = &stored
+	for i := range c.Addresses {
+		if err := d.AddAddress(ctx, c.ID, &c.Addresses[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *memoryData) GetCustomer(ctx context.Context, id uuid.UUID) (Customer, error) {
+	c, ok := d.customers[id]
+	if !ok {
+		return Customer{ID: id}, ErrNotFound
+	}
+	found := copyCustomer(*c)
+	// Primary first, then in the order they were added
+	sort.SliceStable(found.Addresses, func(i, j int) bool {
+		return found.Addresses[i].IsPrimary && !found.Addresses[j].IsPrimary
+	})
+	return found, nil
+}
+
+func (d *memoryData) GetBalance(ctx context.Context, id uuid.UUID) (Balance, error) {
+	c, ok := d.customers[id]
+	if !ok {
+		return Balance{}, ErrNotFound
+	}
+	return Balance{Amount: c.Balance, Currency: c.Currency}, nil
+}
+
+func (d *memoryData) GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Balance, error) {
+	balances := make(map[uuid.UUID]Balance, len(ids))
+	for _, id := range ids {
+		if c, ok := d.customers[id]; ok {
+			balances[id] = Balance{Amount: c.Balance, Currency: c.Currency}
+		}
+	}
+	return balances, nil
+}
+
+func (d *memoryData) CustomerExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	_, ok := d.customers[id]
+	return ok, nil
+}
+
+func (d *memoryData) CustomerTimezone(ctx context.Context, id uuid.UUID) (string, error) {
+	c, ok := d.customers[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return c.Timezone, nil
+}
+
+func (d *memoryData) SetBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	c, ok := d.customers[id]
+	if !ok {
+		return ErrNotFound
+	}
+	c.Balance = balance
+	return nil
+}
+
+func (d *memoryData) AddAddress(ctx context.Context, customerID uuid.UUID, a *Address) error {
+	c, ok := d.customers[customerID]
+	if !ok {
+		return ErrNotFound
+	}
+	if a.IsPrimary {
+		for i := range c.Addresses {
+			c.Addresses[i].IsPrimary = false
+		}
+	}
+	a.ID = uuid.New()
+	c.Addresses = append(c.Addresses, *a)
+	return nil
+}
+
+func (d *memoryData) InsertTransaction(ctx context.Context, t *Transaction) error {
+	c, ok := d.customers[t.CustomerID]
+	if !ok {
+		return ErrNotFound
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	// Like the database, the value date defaults to the posting date in
+	// the customer's timezone
+	if t.ValueDate.IsZero() {
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		y, m, day := t.CreatedAt.In(loc).Date()
+		t.ValueDate = time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+	}
+	if t.UniqueReference {
+		if _, err := d.FindUniqueReference(ctx, t.CustomerID, t.Reference); err == nil {
+			return ErrDuplicateReference
+		}
+	}
+	d.transactions = append(d.transactions, *t)
+	return nil
+}
+
+func (d *memoryData) InsertTransfer(ctx context.Context, t *Transfer) error {
+	for _, id := range []uuid.UUID{t.FromCustomerID, t.ToCustomerID} {
+		if _, ok := d.customers[id]; !ok {
+			return ErrNotFound
+		}
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	d.transfers = append(d.transfers, *t)
+	return nil
+}
+
+func (d *memoryData) GetTransfer(ctx context.Context, id uuid.UUID) (Transfer, error) {
+	for _, t := range d.transfers {
+		if t.ID == id {
+			if t.Status == "" {
+				t.Status = "completed"
+			}
+			return t, nil
+		}
+	}
+	return Transfer{}, ErrNotFound
+}
+
+func (d *memoryData) SetTransferStatus(ctx context.Context, id uuid.UUID, status string) error {
+	for i := range d.transfers {
+		if d.transfers[i].ID == id {
+			d.transfers[i].Status = status
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (d *memoryData) SetTransactionStatus(ctx context.Context, id uuid.UUID, status string) error {
+	for i := range d.transactions {
+		if d.transactions[i].ID == id {
+			d.transactions[i].Status = status
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (d *memoryData) FindUniqueReference(ctx context.Context, customerID uuid.UUID, reference string) (Transaction, error) {
+	for _, t := range d.transactions {
+		if t.CustomerID == customerID && t.UniqueReference && t.Reference == reference && t.Status != "rejected" {
+			return t, nil
+		}
+	}
+	return Transaction{}, ErrNotFound
+}
+
+func (d *memoryData) FindByReference(ctx context.Context, reference string, limit int) ([]Transaction, error) {
+	transfers := map[uuid.UUID]bool{}
+	for _, t := range d.transfers {
+		if t.Reference == reference {
+			transfers[t.ID] = true
+		}
+	}
+	var matching []Transaction
+	for _, t := range d.transactions {
+		if t.Reference == reference || (t.TransferID != nil && transfers[*t.TransferID]) {
+			matching = append(matching, t)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return compareTransactions(matching[i], matching[j], TransactionSortColumns["created_at"]) > 0
+	})
+	if len(matching) > limit {
+		matching = matching[:limit]
+	}
+	return matching, nil
+}
+
+func (d *memoryData) GetTransaction(ctx context.Context, customerID, id uuid.UUID) (Transaction, error) {
+	for _, t := range d.transactions {
+		if t.ID == id && t.CustomerID == customerID {
+			return t, nil
+		}
+	}
+	return Transaction{}, ErrNotFound
+}
+
+func (d *memoryData) CountTransactions(ctx context.Context, customerID uuid.UUID, filter TransactionFilter) (int, error) {
+	count := 0
+	for _, t := range d.transactions {
+		if t.CustomerID == customerID && filter.matches(t) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (d *memoryData) ListTransactions(ctx context.Context, customerID uuid.UUID, opts ListOptions) ([]Transaction, error) {
+	var matching []Transaction
+	for _, t := range d.transactions {
+		if t.CustomerID == customerID && opts.matches(t) {
+			matching = append(matching, t)
+		}
+	}
+	columns, ok := TransactionSortColumns[opts.Sort]
+	if !ok {
+		columns = TransactionSortColumns["created_at"]
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		c := compareTransactions(matching[i], matching[j], columns)
+		if opts.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	if opts.Offset >= len(matching) {
+		return nil, nil
+	}
+	matching = matching[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(matching) {
+		matching = matching[:opts.Limit]
+	}
+	return matching, nil
+}
+
+// matches reports whether t passes f
+func (f TransactionFilter) matches(t Transaction) bool {
+	if f.Status != "" && t.Status != f.Status {
+		return false
+	}
+	if f.ValueDateFrom != nil && t.ValueDate.Before(*f.ValueDateFrom) {
+		return false
+	}
+	return f.ValueDateTo == nil || !t.ValueDate.After(*f.ValueDateTo)
+}
+
+// compareTransactions orders a and b by the first of columns where they differ
+func compareTransactions(a, b Transaction, columns []string) int {
+	for _, col := range columns {
+		var c int
+		switch col {
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case "amount":
+			switch {
+			case a.Amount < b.Amount:
+				c = -1
+			case a.Amount > b.Amount:
+				c = 1
+			}
+		case "type":
+			c = strings.Compare(a.Type, b.Type)
+		case "id":
+			c = strings.Compare(a.ID.String(), b.ID.String())
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCustomers(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	c := Customer{ID: uuid.New(), Name: "Jane", Balance: 50, AccountType: "checking", Timezone: "UTC",
+		Addresses: []Address{{Line1: "1 Main St"}, {Line1: "2 High St", IsPrimary: true}}}
+	require.NoError(t, m.CreateCustomer(ctx, &c))
+	assert.NotEqual(t, uuid.Nil, c.Addresses[0].ID)
+
+	got, err := m.GetCustomer(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "unverified", got.VerificationStatus)
+	assert.Equal(t, "2 High St", got.Addresses[0].Line1, "primary address first")
+
+	require.NoError(t, m.AddAddress(ctx, c.ID, &Address{Line1: "3 Low St", IsPrimary: true}))
+	got, _ = m.GetCustomer(ctx, c.ID)
+	assert.Equal(t, "3 Low St", got.Addresses[0].Line1)
+	assert.False(t, got.Addresses[1].IsPrimary, "previous primary demoted")
+
+	_, err = m.GetBalance(ctx, uuid.New())
+	assert.True(t, errors.Is(err, ErrNotFound))
+	exists, err := m.CustomerExists(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	balances, err := m.GetBalances(ctx, []uuid.UUID{c.ID, uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]Balance{c.ID: {Amount: c.Balance, Currency: DefaultCurrency}}, balances)
+}
+
+func TestMemoryTxRollback(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	c := Customer{ID: uuid.New(), Balance: 100}
+	require.NoError(t, m.CreateCustomer(ctx, &c))
+
+	tx, err := m.Begin(ctx)
+	require.NoError(t, err)
+	locked, err := tx.LockCustomer(ctx, c.ID)
+	require.NoError(t, err)
+	require.NoError(t, tx.SetBalance(ctx, c.ID, locked.Balance-40))
+	require.NoError(t, tx.InsertTransaction(ctx, &Transaction{ID: uuid.New(), CustomerID: c.ID, Type: "debit", Amount: 40}))
+	require.NoError(t, tx.Rollback(ctx))
+
+	balance, _ := m.GetBalance(ctx, c.ID)
+	assert.Equal(t, Balance{Amount: 100, Currency: DefaultCurrency}, balance)
+	count, _ := m.CountTransactions(ctx, c.ID, TransactionFilter{})
+	assert.Equal(t, 0, count)
+
+	tx, err = m.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.SetBalance(ctx, c.ID, 60))
+	require.NoError(t, tx.Commit(ctx))
+	assert.NoError(t, tx.Rollback(ctx), "rollback after commit does nothing")
+	balance, _ = m.GetBalance(ctx, c.ID)
+	assert.Equal(t, float64(60), balance.Amount)
+}
+
+func TestMemoryListTransactions(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	c := Customer{ID: uuid.New()}
+	require.NoError(t, m.CreateCustomer(ctx, &c))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, amount := range []float64{30, 10, 20} {
+		require.NoError(t, m.InsertTransaction(ctx, &Transaction{
+			ID: uuid.New(), CustomerID: c.ID, Type: "credit", Amount: amount, CreatedAt: start.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	byAmount, err := m.ListTransactions(ctx, c.ID, ListOptions{Sort: "amount", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, byAmount, 2)
+	assert.Equal(t, float64(10), byAmount[0].Amount)
+	assert.Equal(t, float64(20), byAmount[1].Amount)
+
+	newest, err := m.ListTransactions(ctx, c.ID, ListOptions{Sort: "created_at", Descending: true, Limit: 10, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, newest, 2)
+	assert.Equal(t, float64(10), newest[0].Amount)
+
+	// Value dates default to the posting date
+	nextDay := start.AddDate(0, 0, 1)
+	require.NoError(t, m.InsertTransaction(ctx, &Transaction{
+		ID: uuid.New(), CustomerID: c.ID, Type: "credit", Amount: 40, CreatedAt: start, ValueDate: nextDay,
+	}))
+	valued, err := m.ListTransactions(ctx, c.ID, ListOptions{TransactionFilter: TransactionFilter{ValueDateFrom: &nextDay}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, valued, 1)
+	assert.Equal(t, float64(40), valued[0].Amount)
+	count, _ := m.CountTransactions(ctx, c.ID, TransactionFilter{ValueDateTo: &start})
+	assert.Equal(t, 3, count)
+	count, _ = m.CountTransactions(ctx, c.ID, TransactionFilter{Status: "held"})
+	assert.Equal(t, 0, count)
+
+	assert.True(t, errors.Is(m.InsertTransaction(ctx, &Transaction{CustomerID: uuid.New()}), ErrNotFound))
+}
+
+func TestMemoryValueDateTimezone(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	c := Customer{ID: uuid.New(), Timezone: "Pacific/Kiritimati"}
+	require.NoError(t, m.CreateCustomer(ctx, &c))
+
+	// Noon UTC is already the next day at UTC+14
+	tx := Transaction{ID: uuid.New(), CustomerID: c.ID, Type: "credit", Amount: 10, CreatedAt: time.Date(2025, 4, 8, 12, 0, 0, 0, time.UTC)}
+	require.NoError(t, m.InsertTransaction(ctx, &tx))
+	assert.Equal(t, time.Date(2025, 4, 9, 0, 0, 0, 0, time.UTC), tx.ValueDate)
+}
+
+func TestMemoryFindByReference(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	a, b := Customer{ID: uuid.New()}, Customer{ID: uuid.New()}
+	require.NoError(t, m.CreateCustomer(ctx, &a))
+	require.NoError(t, m.CreateCustomer(ctx, &b))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, m.InsertTransaction(ctx, &Transaction{ID: uuid.New(), CustomerID: a.ID, Type: "credit", Amount: 10, CreatedAt: start, Reference: "INV-1234"}))
+	require.NoError(t, m.InsertTransaction(ctx, &Transaction{ID: uuid.New(), CustomerID: a.ID, Type: "credit", Amount: 20, CreatedAt: start, Reference: "INV-9999"}))
+	transfer := Transfer{ID: uuid.New(), FromCustomerID: a.ID, ToCustomerID: b.ID, Amount: 5, Reference: "INV-1234"}
+	require.NoError(t, m.InsertTransfer(ctx, &transfer))
+	for _, leg := range []Transaction{
+		{ID: uuid.New(), CustomerID: a.ID, Type: "transfer_out", Amount: 5, CreatedAt: start.Add(time.Hour), TransferID: &transfer.ID},
+		{ID: uuid.New(), CustomerID: b.ID, Type: "transfer_in", Amount: 5, CreatedAt: start.Add(2 * time.Hour), TransferID: &transfer.ID},
+	} {
+		require.NoError(t, m.InsertTransaction(ctx, &leg))
+	}
+
+	found, err := m.FindByReference(ctx, "INV-1234", 10)
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	assert.Equal(t, b.ID, found[0].CustomerID, "newest first, across customers")
+	assert.Equal(t, float64(10), found[2].Amount)
+
+	found, _ = m.FindByReference(ctx, "INV-1234", 1)
+	assert.Len(t, found, 1)
+	found, _ = m.FindByReference(ctx, "nothing", 10)
+	assert.Empty(t, found)
+
+	// A unique reference may be held once per customer
+	require.NoError(t, m.InsertTransaction(ctx, &Transaction{ID: uuid.New(), CustomerID: a.ID, Type: "credit", Amount: 1, Reference: "E2E-1", UniqueReference: true}))
+	assert.ErrorIs(t, m.InsertTransaction(ctx, &Transaction{ID: uuid.New(), CustomerID: a.ID, Type: "credit", Amount: 1, Reference: "E2E-1", UniqueReference: true}), ErrDuplicateReference)
+	assert.NoError(t, m.InsertTransaction(ctx, &Transaction{ID: uuid.New(), CustomerID: b.ID, Type: "credit", Amount: 1, Reference: "E2E-1", UniqueReference: true}))
+}
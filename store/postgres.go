@@ -0,0 +1,551 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ledger-service/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Conn is a database connection or pool; *pgxpool.Pool satisfies it
+type Conn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	querier
+}
+
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Postgres keeps the ledger in the tables created by migrations/init.sql
+type Postgres struct {
+	queries
+	conn Conn
+}
+
+// NewPostgres creates a store using conn
+func NewPostgres(conn Conn) *Postgres {
+	return &Postgres{queries: queries{q: conn}, conn: conn}
+}
+
+// EncryptPII encrypts customer names, contact details, dates of birth and
+// addresses with keys before they are stored, and decrypts them on reads
+func (p *Postgres) EncryptPII(keys *pii.Keyring) *Postgres {
+	p.keys = keys
+	return p
+}
+
+// Begin starts a database transaction
+func (p *Postgres) Begin(ctx context.Context) (Tx, error) {
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresTx{queries: queries{q: tx, keys: p.keys}, tx: tx}, nil
+}
+
+// PostgresTx is a database transaction
+type PostgresTx struct {
+	queries
+	tx pgx.Tx
+}
+
+// NewPostgresTx uses a transaction begun outside the store, so store writes
+// commit together with the caller's own statements. It does not encrypt
+// personal data; use Begin for customer details.
+func NewPostgresTx(tx pgx.Tx) *PostgresTx {
+	return &PostgresTx{queries: queries{q: tx}, tx: tx}
+}
+
+// Pgx returns the underlying transaction, for work outside the store that
+// must commit together with it
+func (t *PostgresTx) Pgx() pgx.Tx {
+	return t.tx
+}
+
+// LockCustomer locks the customer's row
+func (t *PostgresTx) LockCustomer(ctx context.Context, id uuid.UUID) (Customer, error) {
+	c := Customer{ID: id}
+	err := t.tx.QueryRow(ctx,
+		`SELECT balance, account_type, timezone, allow_negative, balance_type, COALESCE(credit_limit, 0), currency,
+			COALESCE((SELECT overdraft_limit FROM customer_limits WHERE customer_id = $1), (SELECT overdraft_limit FROM limit_defaults), 0)
+		FROM customers WHERE id = $1 FOR UPDATE`,
+		id).Scan(&c.Balance, &c.AccountType, &c.Timezone, &c.AllowNegative, &c.BalanceType, &c.CreditLimit, &c.Currency, &c.Overdraft)
+	return c, notFound(err)
+}
+
+func (t *PostgresTx) LockTransfer(ctx context.Context, id uuid.UUID) (Transfer, error) {
+	return scanTransfer(t.tx.QueryRow(ctx,
+		"SELECT "+transferColumns+" FROM transfers WHERE id = $1 FOR UPDATE",
+		id))
+}
+
+func (t *PostgresTx) LockTransactionTransfers(ctx context.Context, transactionID uuid.UUID) ([]Transfer, error) {
+	rows, err := t.tx.Query(ctx,
+		"SELECT "+transferColumns+" FROM transfers WHERE id = (SELECT transfer_id FROM transactions WHERE id = $1) OR batch_id = (SELECT batch_id FROM transactions WHERE id = $1) ORDER BY created_at, id FOR UPDATE",
+		transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []Transfer
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+func (t *PostgresTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t *PostgresTx) Rollback(ctx context.Context) error {
+	err := t.tx.Rollback(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return nil
+	}
+	return err
+}
+
+// queries runs the store's statements on a pool or within a transaction
+type queries struct {
+	q querier
+	// keys encrypts personal data; nil stores it in the clear
+	keys *pii.Keyring
+}
+
+func (s queries) CreateCustomer(ctx context.Context, c *Customer) error {
+	name, email, phone := c.Name, c.Email, c.PhoneNumber
+	var dob string
+	if c.DateOfBirth != nil {
+		dob = c.DateOfBirth.Format("2006-01-02")
+	}
+	if err := s.keys.EncryptAll(&name, &email, &phone, &dob); err != nil {
+		return err
+	}
+	if _, err := s.q.Exec(ctx,
+		"INSERT INTO customers (id, name, balance, opening_balance, date_of_birth, email, phone_number, account_type, timezone, balance_type, credit_limit, currency) VALUES ($1, $2, $3, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		c.ID, name, c.Balance, nullableString(dob), nullableString(email), nullableString(phone), c.AccountType, c.Timezone, balanceType(c.BalanceType), nullableAmount(c.CreditLimit), currency(c.Currency)); err != nil {
+		return err
+	}
+	for i := range c.Addresses {
+		if err := s.AddAddress(ctx, c.ID, &c.Addresses[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s queries) GetCustomer(ctx context.Context, id uuid.UUID) (Customer, error) {
+	c := Customer{ID: id}
+	var dob, email, phone *string
+	err := s.q.QueryRow(ctx,
+		"SELECT name, balance, date_of_birth, verification_status, email, phone_number, account_type, timezone, balance_type, COALESCE(credit_limit, 0), currency FROM customers WHERE id = $1",
+		id).Scan(&c.Name, &c.Balance, &dob, &c.VerificationStatus, &email, &phone, &c.AccountType, &c.Timezone, &c.BalanceType, &c.CreditLimit, &c.Currency)
+	if err != nil {
+		return c, notFound(err)
+	}
+	if err := s.keys.DecryptAll(&c.Name, dob, email, phone); err != nil {
+		return c, err
+	}
+	if dob != nil {
+		parsed, err := time.Parse("2006-01-02", *dob)
+		if err != nil {
+			return c, fmt.Errorf("date_of_birth: %w", err)
+		}
+		c.DateOfBirth = &parsed
+	}
+	if email != nil {
+		c.Email = *email
+	}
+	if phone != nil {
+		c.PhoneNumber = *phone
+	}
+
+	rows, err := s.q.Query(ctx,
+		"SELECT id, address_type, line1, COALESCE(line2, ''), city, COALESCE(region, ''), postal_code, country, is_primary FROM customer_addresses WHERE customer_id = $1 ORDER BY is_primary DESC, created_at",
+		id)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+	c.Addresses = []Address{}
+	for rows.Next() {
+		var a Address
+		if err := rows.Scan(&a.ID, &a.Type, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.IsPrimary); err != nil {
+			return c, err
+		}
+		if err := s.keys.DecryptAll(&a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode); err != nil {
+			return c, err
+		}
+		c.Addresses = append(c.Addresses, a)
+	}
+	return c, rows.Err()
+}
+
+func (s queries) GetBalance(ctx context.Context, id uuid.UUID) (Balance, error) {
+	var b Balance
+	err := s.q.QueryRow(ctx,
+		"SELECT balance, currency FROM customers WHERE id = $1",
+		id).Scan(&b.Amount, &b.Currency)
+	return b, notFound(err)
+}
+
+func (s queries) GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Balance, error) {
+	rows, err := s.q.Query(ctx,
+		"SELECT id, balance, currency FROM customers WHERE id = ANY($1)",
+		ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := make(map[uuid.UUID]Balance, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var b Balance
+		if err := rows.Scan(&id, &b.Amount, &b.Currency); err != nil {
+			return nil, err
+		}
+		balances[id] = b
+	}
+	return balances, rows.Err()
+}
+
+func (s queries) CustomerExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.q.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)",
+		id).Scan(&exists)
+	return exists, err
+}
+
+func (s queries) CustomerTimezone(ctx context.Context, id uuid.UUID) (string, error) {
+	var timezone string
+	err := s.q.QueryRow(ctx,
+		"SELECT timezone FROM customers WHERE id = $1",
+		id).Scan(&timezone)
+	return timezone, notFound(err)
+}
+
+func (s queries) SetBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	tag, err := s.q.Exec(ctx,
+		"UPDATE customers SET balance = $1 WHERE id = $2",
+		balance, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s queries) AddAddress(ctx context.Context, customerID uuid.UUID, a *Address) error {
+	if a.IsPrimary {
+		if _, err := s.q.Exec(ctx,
+			"UPDATE customer_addresses SET is_primary = FALSE WHERE customer_id = $1 AND is_primary",
+			customerID); err != nil {
+			return err
+		}
+	}
+	sealed := *a
+	if err := s.keys.EncryptAll(&sealed.Line1, &sealed.Line2, &sealed.City, &sealed.Region, &sealed.PostalCode); err != nil {
+		return err
+	}
+	a.ID = uuid.New()
+	_, err := s.q.Exec(ctx,
+		"INSERT INTO customer_addresses (id, customer_id, address_type, line1, line2, city, region, postal_code, country, is_primary) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		a.ID, customerID, a.Type, sealed.Line1, nullableString(sealed.Line2), sealed.City, nullableString(sealed.Region), sealed.PostalCode, a.Country, a.IsPrimary)
+	return err
+}
+
+func (s queries) InsertTransaction(ctx context.Context, t *Transaction) error {
+	columns := []string{"id", "customer_id", "type", "amount", "status"}
+	args := []interface{}{t.ID, t.CustomerID, t.Type, t.Amount, t.Status}
+	switch {
+	case t.TransferID != nil:
+		columns = append(columns, "transfer_id")
+		args = append(args, *t.TransferID)
+	case !t.ValueDate.IsZero():
+		columns = append(columns, "value_date")
+		args = append(args, t.ValueDate)
+	}
+	if t.BatchID != nil {
+		columns = append(columns, "batch_id")
+		args = append(args, *t.BatchID)
+	}
+	if o := t.Original; o != nil {
+		columns = append(columns, "original_amount", "original_currency", "fx_rate", "rate_provider", "rounding")
+		args = append(args, o.Amount, o.Currency, o.Rate, nullableString(o.Provider), nullableString(o.Rounding))
+	}
+	if t.Reference != "" {
+		columns = append(columns, "reference", "unique_reference")
+		args = append(args, t.Reference, t.UniqueReference)
+	}
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	_, err := s.q.Exec(ctx,
+		"INSERT INTO transactions ("+strings.Join(columns, ", ")+") VALUES ("+strings.Join(placeholders, ", ")+")",
+		args...)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == uniqueReferenceIndex {
+		return ErrDuplicateReference
+	}
+	return err
+}
+
+// uniqueReferenceIndex enforces unique references; see migrations/init.sql
+const uniqueReferenceIndex = "idx_transactions_unique_reference"
+
+func (s queries) InsertTransfer(ctx context.Context, t *Transfer) error {
+	columns := []string{"id", "from_customer_id", "to_customer_id", "amount", "reference"}
+	args := []interface{}{t.ID, t.FromCustomerID, t.ToCustomerID, t.Amount, nullableString(t.Reference)}
+	if t.BatchID != nil {
+		columns = append(columns, "batch_id")
+		args = append(args, *t.BatchID)
+	}
+	if t.Status != "" {
+		columns = append(columns, "status")
+		args = append(args, t.Status)
+	}
+	if t.ApprovedStatus != "" {
+		columns = append(columns, "approved_status")
+		args = append(args, t.ApprovedStatus)
+	}
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	_, err := s.q.Exec(ctx,
+		"INSERT INTO transfers ("+strings.Join(columns, ", ")+") VALUES ("+strings.Join(placeholders, ", ")+")",
+		args...)
+	return err
+}
+
+const transferColumns = "id, from_customer_id, to_customer_id, amount, reference, batch_id, status, approved_status, created_at"
+
+func scanTransfer(row pgx.Row) (Transfer, error) {
+	var t Transfer
+	var reference, approved *string
+	err := row.Scan(&t.ID, &t.FromCustomerID, &t.ToCustomerID, &t.Amount, &reference, &t.BatchID, &t.Status, &approved, &t.CreatedAt)
+	if reference != nil {
+		t.Reference = *reference
+	}
+	if approved != nil {
+		t.ApprovedStatus = *approved
+	}
+	return t, notFound(err)
+}
+
+func (s queries) GetTransfer(ctx context.Context, id uuid.UUID) (Transfer, error) {
+	return scanTransfer(s.q.QueryRow(ctx,
+		"SELECT "+transferColumns+" FROM transfers WHERE id = $1",
+		id))
+}
+
+// SetTransferStatus closes the transfer, unless it is approved into an open
+// reservation
+func (s queries) SetTransferStatus(ctx context.Context, id uuid.UUID, status string) error {
+	tag, err := s.q.Exec(ctx,
+		"UPDATE transfers SET status = $2, closed_at = CASE WHEN $2 = 'reserved' THEN NULL ELSE NOW() END WHERE id = $1",
+		id, status)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s queries) SetTransactionStatus(ctx context.Context, id uuid.UUID, status string) error {
+	tag, err := s.q.Exec(ctx,
+		"UPDATE transactions SET status = $2 WHERE id = $1",
+		id, status)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s queries) CountTransactions(ctx context.Context, customerID uuid.UUID, filter TransactionFilter) (int, error) {
+	where, args := transactionWhere(customerID, filter)
+	var count int
+	err := s.q.QueryRow(ctx,
+		"SELECT COUNT(*) FROM transactions WHERE "+where,
+		args...).Scan(&count)
+	return count, err
+}
+
+const transactionColumns = "id, type, amount, status, created_at, recorded_at, value_date, original_amount, original_currency, fx_rate, rate_provider, rounding, reference, unique_reference"
+
+// scanTransaction reads transactionColumns, then any further columns into
+// more
+func scanTransaction(row pgx.Row, customerID uuid.UUID, more ...interface{}) (Transaction, error) {
+	t := Transaction{CustomerID: customerID}
+	var amount, rate *float64
+	var currency, provider, rounding, reference *string
+	dest := []interface{}{&t.ID, &t.Type, &t.Amount, &t.Status, &t.CreatedAt, &t.RecordedAt, &t.ValueDate, &amount, &currency, &rate, &provider, &rounding, &reference, &t.UniqueReference}
+	err := row.Scan(append(dest, more...)...)
+	if reference != nil {
+		t.Reference = *reference
+	}
+	if err == nil && amount != nil && currency != nil && rate != nil {
+		t.Original = &Original{Amount: *amount, Currency: *currency, Rate: *rate}
+		if provider != nil {
+			t.Original.Provider = *provider
+		}
+		if rounding != nil {
+			t.Original.Rounding = *rounding
+		}
+	}
+	return t, err
+}
+
+func (s queries) GetTransaction(ctx context.Context, customerID, id uuid.UUID) (Transaction, error) {
+	t, err := scanTransaction(s.q.QueryRow(ctx,
+		"SELECT "+transactionColumns+" FROM transactions WHERE id = $1 AND customer_id = $2",
+		id, customerID), customerID)
+	return t, notFound(err)
+}
+
+func (s queries) FindUniqueReference(ctx context.Context, customerID uuid.UUID, reference string) (Transaction, error) {
+	t, err := scanTransaction(s.q.QueryRow(ctx,
+		"SELECT "+transactionColumns+" FROM transactions WHERE customer_id = $1 AND reference = $2 AND unique_reference AND status <> 'rejected'",
+		customerID, reference), customerID)
+	return t, notFound(err)
+}
+
+func (s queries) FindByReference(ctx context.Context, reference string, limit int) ([]Transaction, error) {
+	rows, err := s.q.Query(ctx,
+		"SELECT "+transactionColumns+", customer_id, transfer_id FROM transactions WHERE reference = $1 OR transfer_id IN (SELECT id FROM transfers WHERE reference = $1) ORDER BY created_at DESC, id DESC LIMIT $2",
+		reference, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var customerID uuid.UUID
+		var transferID *uuid.UUID
+		t, err := scanTransaction(rows, uuid.Nil, &customerID, &transferID)
+		if err != nil {
+			return nil, err
+		}
+		t.CustomerID, t.TransferID = customerID, transferID
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+func (s queries) ListTransactions(ctx context.Context, customerID uuid.UUID, opts ListOptions) ([]Transaction, error) {
+	where, args := transactionWhere(customerID, opts.TransactionFilter)
+	args = append(args, opts.Limit, opts.Offset)
+	rows, err := s.q.Query(ctx,
+		fmt.Sprintf("SELECT %s FROM transactions WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d", transactionColumns, where, orderBy(opts), len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows, customerID)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// transactionWhere builds the WHERE clause selecting a customer's
+// transactions that pass filter, with its arguments
+func transactionWhere(customerID uuid.UUID, filter TransactionFilter) (string, []interface{}) {
+	where := "customer_id = $1"
+	args := []interface{}{customerID}
+	if filter.ValueDateFrom != nil {
+		args = append(args, *filter.ValueDateFrom)
+		where += fmt.Sprintf(" AND value_date >= $%d", len(args))
+	}
+	if filter.ValueDateTo != nil {
+		args = append(args, *filter.ValueDateTo)
+		where += fmt.Sprintf(" AND value_date <= $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	return where, args
+}
+
+// orderBy builds the ORDER BY clause for opts, defaulting to created_at
+func orderBy(opts ListOptions) string {
+	columns, ok := TransactionSortColumns[opts.Sort]
+	if !ok {
+		columns = TransactionSortColumns["created_at"]
+	}
+	direction := "ASC"
+	if opts.Descending {
+		direction = "DESC"
+	}
+	terms := make([]string, len(columns))
+	for i, col := range columns {
+		terms[i] = col + " " + direction
+	}
+	return strings.Join(terms, ", ")
+}
+
+// nullableString maps an empty string to SQL NULL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nullableAmount maps a zero amount to SQL NULL
+func nullableAmount(f float64) *float64 {
+	if f == 0 {
+		return nil
+	}
+	return &f
+}
+
+// currency defaults an empty currency to DefaultCurrency
+func currency(code string) string {
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// balanceType defaults an empty balance type to deposit
+func balanceType(t string) string {
+	if t == "" {
+		return BalanceDeposit
+	}
+	return t
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
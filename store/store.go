@@ -0,0 +1,230 @@
+// Package store keeps customer accounts and their transactions behind
+// interfaces so request handling does not depend on pgx. Postgres is the
+// production implementation; Memory backs unit tests and the --memory demo.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when the customer asked for does not exist
+var ErrNotFound = errors.New("store: not found")
+
+// ErrDuplicateReference is returned when inserting a transaction with a
+// unique reference the customer already has
+var ErrDuplicateReference = errors.New("store: duplicate reference")
+
+// Customer is a customer account
+type Customer struct {
+	ID                 uuid.UUID
+	Name               string
+	Balance            float64
+	DateOfBirth        *time.Time
+	VerificationStatus string
+	Email              string
+	PhoneNumber        string
+	AccountType        string
+	Timezone           string
+	// AllowNegative lets postings and transfers take the balance below zero
+	AllowNegative bool
+	// BalanceType is BalanceDeposit or BalanceCredit; empty means deposit
+	BalanceType string
+	// CreditLimit caps what a credit account may owe
+	CreditLimit float64
+	// Overdraft is how far below zero a deposit account may go, from the
+	// customer's limits or the defaults; LockCustomer fills it in
+	Overdraft float64
+	// Currency is the ISO 4217 code the balance is kept in; empty means
+	// DefaultCurrency
+	Currency  string
+	Addresses []Address
+}
+
+// DefaultCurrency is the base currency of accounts opened without one
+const DefaultCurrency = "USD"
+
+// Balance is an account's balance in its base currency
+type Balance struct {
+	Amount   float64
+	Currency string
+}
+
+// Balance types. A deposit account's balance is what the business owes the
+// customer. A credit account's balance is what the customer owes the
+// business, so postings move it the other way.
+const (
+	BalanceDeposit = "deposit"
+	BalanceCredit  = "credit"
+)
+
+// Address is a customer's postal address
+type Address struct {
+	ID         uuid.UUID
+	Type       string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	IsPrimary  bool
+}
+
+// Transaction is a posting against a customer's balance
+type Transaction struct {
+	ID         uuid.UUID
+	CustomerID uuid.UUID
+	Type       string
+	Amount     float64
+	Status     string
+	// TransferID links the two legs of a transfer between customers
+	TransferID *uuid.UUID
+	CreatedAt  time.Time
+	// RecordedAt is set on backdated transactions to when they were written;
+	// CreatedAt holds the historical time they were posted at
+	RecordedAt *time.Time
+	// ValueDate is the date the transaction takes effect for interest and
+	// statements. Zero on insert means the posting date in the customer's
+	// timezone.
+	ValueDate time.Time
+	// BatchID links the legs of a split transfer
+	BatchID *uuid.UUID
+	// Reference is the payer's or bank's reference for the payment. With
+	// UniqueReference set, no other transaction of the customer marked
+	// unique and not rejected may carry the same reference.
+	Reference       string
+	UniqueReference bool
+	// Original is set on a transaction posted in another currency than the
+	// account's; Amount is then the converted amount
+	Original *Original
+}
+
+// Original is what a converted transaction was posted as: Amount in
+// Currency, converted at Rate quoted by Provider and rounded by Rounding
+type Original struct {
+	Amount   float64
+	Currency string
+	Rate     float64
+	Provider string
+	Rounding string
+}
+
+// Transfer moves money from one customer's balance to another's
+type Transfer struct {
+	ID             uuid.UUID
+	FromCustomerID uuid.UUID
+	ToCustomerID   uuid.UUID
+	Amount         float64
+	Reference      string
+	// BatchID is shared by the transfers of a split transfer
+	BatchID *uuid.UUID
+	// Status is empty on insert for a transfer that completes at once; see
+	// the ledger's transfer statuses
+	Status string
+	// ApprovedStatus is the status a transfer awaiting approval takes once
+	// approved
+	ApprovedStatus string
+	CreatedAt      time.Time
+}
+
+// TransactionSortColumns maps each sort key accepted when listing
+// transactions to its ordering columns. Ties fall back to later columns so
+// pages never overlap, and each list is covered by a composite index.
+var TransactionSortColumns = map[string][]string{
+	"created_at": {"created_at", "id"},
+	"amount":     {"amount", "id"},
+	"type":       {"type", "created_at", "id"},
+}
+
+// TransactionFilter narrows a customer's transactions by value date and
+// status. Either value date bound may be nil; both are inclusive.
+type TransactionFilter struct {
+	ValueDateFrom *time.Time
+	ValueDateTo   *time.Time
+	// Status, when set, keeps only transactions with that status
+	Status string
+}
+
+// ListOptions selects one page of a customer's transactions
+type ListOptions struct {
+	TransactionFilter
+	// Sort is a key of TransactionSortColumns
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// CustomerStore reads and writes customer accounts
+type CustomerStore interface {
+	// CreateCustomer stores c and its addresses, assigning address IDs. The
+	// opening balance is c.Balance.
+	CreateCustomer(ctx context.Context, c *Customer) error
+	// GetCustomer loads a customer with its addresses, primary first
+	GetCustomer(ctx context.Context, id uuid.UUID) (Customer, error)
+	GetBalance(ctx context.Context, id uuid.UUID) (Balance, error)
+	// GetBalances returns the balances of the customers among ids that
+	// exist, keyed by customer ID, in a single read
+	GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Balance, error)
+	CustomerExists(ctx context.Context, id uuid.UUID) (bool, error)
+	// CustomerTimezone returns the customer's IANA timezone, or ErrNotFound
+	CustomerTimezone(ctx context.Context, id uuid.UUID) (string, error)
+	SetBalance(ctx context.Context, id uuid.UUID, balance float64) error
+	// AddAddress stores a, assigning its ID and demoting any existing
+	// primary address when a is primary
+	AddAddress(ctx context.Context, customerID uuid.UUID, a *Address) error
+}
+
+// TransactionStore reads and writes transactions
+type TransactionStore interface {
+	InsertTransaction(ctx context.Context, t *Transaction) error
+	InsertTransfer(ctx context.Context, t *Transfer) error
+	// GetTransfer returns a transfer, or ErrNotFound
+	GetTransfer(ctx context.Context, id uuid.UUID) (Transfer, error)
+	SetTransferStatus(ctx context.Context, id uuid.UUID, status string) error
+	SetTransactionStatus(ctx context.Context, id uuid.UUID, status string) error
+	// GetTransaction returns one of a customer's transactions, or
+	// ErrNotFound
+	GetTransaction(ctx context.Context, customerID, id uuid.UUID) (Transaction, error)
+	// FindUniqueReference returns the customer's transaction that is not
+	// rejected and holds reference as a unique reference, or ErrNotFound
+	FindUniqueReference(ctx context.Context, customerID uuid.UUID, reference string) (Transaction, error)
+	// FindByReference returns up to limit transactions of any customer that
+	// carry reference, or are a leg of a transfer that does, newest first
+	FindByReference(ctx context.Context, reference string, limit int) ([]Transaction, error)
+	CountTransactions(ctx context.Context, customerID uuid.UUID, filter TransactionFilter) (int, error)
+	ListTransactions(ctx context.Context, customerID uuid.UUID, opts ListOptions) ([]Transaction, error)
+}
+
+// Store is the data a ledger keeps
+type Store interface {
+	CustomerStore
+	TransactionStore
+	// Begin starts a transaction; changes made through it are only visible
+	// to others once it commits
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// Tx is a unit of work against a Store. Rollback after Commit does nothing,
+// so it can always be deferred.
+type Tx interface {
+	CustomerStore
+	TransactionStore
+	// LockCustomer holds the customer's account until the transaction ends
+	// and returns its balance, account type, timezone, AllowNegative,
+	// balance type, credit limit and currency
+	LockCustomer(ctx context.Context, id uuid.UUID) (Customer, error)
+	// LockTransfer holds a transfer until the transaction ends and returns
+	// it, or ErrNotFound
+	LockTransfer(ctx context.Context, id uuid.UUID) (Transfer, error)
+	// LockTransactionTransfers holds the transfers a payer's transfer_out
+	// pays until the transaction ends and returns them: its transfer, or
+	// every transfer of its split transfer
+	LockTransactionTransfers(ctx context.Context, transactionID uuid.UUID) ([]Transfer, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
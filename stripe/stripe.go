@@ -0,0 +1,226 @@
+// Package stripe is a client for the parts of the Stripe API the ledger
+// uses: payouts to customers' connected accounts, balances and webhook
+// events
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/money"
+	"ledger-service/webhook"
+)
+
+// SignatureHeader carries the signature of a webhook event
+const SignatureHeader = "Stripe-Signature"
+
+// Payout statuses
+const (
+	PayoutPending   = "pending"
+	PayoutInTransit = "in_transit"
+	PayoutPaid      = "paid"
+	PayoutFailed    = "failed"
+	PayoutCanceled  = "canceled"
+)
+
+// ErrInvalidSignature is returned for a webhook event whose signature does
+// not hold
+var ErrInvalidSignature = errors.New("invalid stripe signature")
+
+// Error is an error the Stripe API answered with
+type Error struct {
+	Status  int
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("stripe returned status %d", e.Status)
+	}
+	return "stripe: " + e.Message
+}
+
+// Payout is money sent from a Stripe balance to a bank account. Amount is
+// in the currency's minor unit, as Stripe counts it.
+type Payout struct {
+	ID             string            `json:"id"`
+	Amount         int64             `json:"amount"`
+	Currency       string            `json:"currency"`
+	Status         string            `json:"status"`
+	FailureMessage string            `json:"failure_message"`
+	Metadata       map[string]string `json:"metadata"`
+}
+
+// PayoutParams describes a payout to create
+type PayoutParams struct {
+	Amount   int64
+	Currency string
+	// Account is the connected account paying out; empty pays out from the
+	// platform's own balance
+	Account     string
+	Description string
+	Metadata    map[string]string
+}
+
+// Charge is a payment collected from a card or other method
+type Charge struct {
+	ID       string            `json:"id"`
+	Amount   int64             `json:"amount"`
+	Currency string            `json:"currency"`
+	Status   string            `json:"status"`
+	Paid     bool              `json:"paid"`
+	Customer string            `json:"customer"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Balance is what a Stripe account holds, per currency in minor units
+type Balance struct {
+	Available []Amount `json:"available"`
+	Pending   []Amount `json:"pending"`
+}
+
+// Amount is an amount of one currency
+type Amount struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Event is a webhook event; Object is the payout, charge or other object it
+// is about
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Account string `json:"account"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Client calls the Stripe API
+type Client struct {
+	BaseURL   string
+	SecretKey string
+	Client    *http.Client
+}
+
+// NewClient creates a client using a secret API key
+func NewClient(secretKey string) *Client {
+	return &Client{
+		BaseURL:   "https://api.stripe.com",
+		SecretKey: secretKey,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// CreatePayout creates a payout. Stripe answers a repeat of idempotencyKey
+// with the payout it created the first time.
+func (c *Client) CreatePayout(ctx context.Context, p PayoutParams, idempotencyKey string) (Payout, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(p.Amount, 10))
+	form.Set("currency", strings.ToLower(p.Currency))
+	if p.Description != "" {
+		form.Set("description", p.Description)
+	}
+	for k, v := range p.Metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+	var payout Payout
+	err := c.call(ctx, http.MethodPost, "/v1/payouts", p.Account, form, idempotencyKey, &payout)
+	return payout, err
+}
+
+// GetPayout fetches a payout
+func (c *Client) GetPayout(ctx context.Context, id, account string) (Payout, error) {
+	var payout Payout
+	err := c.call(ctx, http.MethodGet, "/v1/payouts/"+url.PathEscape(id), account, nil, "", &payout)
+	return payout, err
+}
+
+// CancelPayout cancels a payout that is still pending
+func (c *Client) CancelPayout(ctx context.Context, id, account string) (Payout, error) {
+	var payout Payout
+	err := c.call(ctx, http.MethodPost, "/v1/payouts/"+url.PathEscape(id)+"/cancel", account, url.Values{}, "", &payout)
+	return payout, err
+}
+
+// GetBalance fetches the platform's balance
+func (c *Client) GetBalance(ctx context.Context) (Balance, error) {
+	var balance Balance
+	err := c.call(ctx, http.MethodGet, "/v1/balance", "", nil, "", &balance)
+	return balance, err
+}
+
+func (c *Client) call(ctx context.Context, method, path, account string, form url.Values, idempotencyKey string, dest interface{}) error {
+	var encoded string
+	if form != nil {
+		encoded = form.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, strings.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to build stripe request: %v", err)
+	}
+	req.SetBasicAuth(c.SecretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if account != "" {
+		req.Header.Set("Stripe-Account", account)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call stripe: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var wrapper struct {
+			Error *Error `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&wrapper)
+		apiErr := wrapper.Error
+		if apiErr == nil {
+			apiErr = &Error{}
+		}
+		apiErr.Status = resp.StatusCode
+		return apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode stripe response: %v", err)
+	}
+	return nil
+}
+
+// ParseEvent verifies a webhook event's Stripe-Signature header against the
+// endpoint's signing secret and decodes the event
+func ParseEvent(body []byte, header, secret string, tolerance time.Duration, now time.Time) (Event, error) {
+	if err := webhook.Verify(secret, header, body, tolerance, now); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return Event{}, fmt.Errorf("failed to decode stripe event: %v", err)
+	}
+	return e, nil
+}
+
+// ToMinor converts an amount to the currency's minor unit
+func ToMinor(amount float64, currency string) int64 {
+	return int64(math.Round(amount * math.Pow10(money.Decimals(strings.ToUpper(currency)))))
+}
+
+// FromMinor converts an amount in the currency's minor unit back
+func FromMinor(amount int64, currency string) float64 {
+	return float64(amount) / math.Pow10(money.Decimals(strings.ToUpper(currency)))
+}
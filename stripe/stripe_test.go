@@ -0,0 +1,74 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ledger-service/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "sk_test_1", user)
+		assert.Equal(t, "/v1/payouts", r.URL.Path)
+		assert.Equal(t, "acct_1", r.Header.Get("Stripe-Account"))
+		assert.Equal(t, "saga-1:payout", r.Header.Get("Idempotency-Key"))
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("amount") == "0" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"invalid_request_error","code":"parameter_invalid_integer","message":"Invalid positive integer"}}`))
+			return
+		}
+		assert.Equal(t, "12550", r.PostForm.Get("amount"))
+		assert.Equal(t, "usd", r.PostForm.Get("currency"))
+		assert.Equal(t, "tx-1", r.PostForm.Get("metadata[transaction_id]"))
+		w.Write([]byte(`{"id":"po_1","amount":12550,"currency":"usd","status":"pending"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("sk_test_1")
+	c.BaseURL = server.URL
+	params := PayoutParams{Amount: 12550, Currency: "USD", Account: "acct_1", Metadata: map[string]string{"transaction_id": "tx-1"}}
+	payout, err := c.CreatePayout(context.Background(), params, "saga-1:payout")
+	require.NoError(t, err)
+	assert.Equal(t, "po_1", payout.ID)
+	assert.Equal(t, PayoutPending, payout.Status)
+
+	params.Amount = 0
+	_, err = c.CreatePayout(context.Background(), params, "saga-1:payout")
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+	assert.Equal(t, "parameter_invalid_integer", apiErr.Code)
+	assert.EqualError(t, err, "stripe: Invalid positive integer")
+}
+
+func TestParseEvent(t *testing.T) {
+	now := time.Unix(1712596157, 0)
+	body := []byte(`{"id":"evt_1","type":"payout.failed","data":{"object":{"id":"po_1","status":"failed"}}}`)
+	header := webhook.Sign("whsec_1", now, body)
+
+	e, err := ParseEvent(body, header, "whsec_1", 5*time.Minute, now)
+	require.NoError(t, err)
+	assert.Equal(t, "payout.failed", e.Type)
+	assert.JSONEq(t, `{"id":"po_1","status":"failed"}`, string(e.Data.Object))
+
+	_, err = ParseEvent(body, header, "whsec_2", 5*time.Minute, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = ParseEvent(body, header, "whsec_1", 5*time.Minute, now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestMinorUnits(t *testing.T) {
+	assert.Equal(t, int64(12550), ToMinor(125.5, "usd"))
+	assert.Equal(t, int64(1999), ToMinor(19.99, "EUR"))
+	assert.Equal(t, 125.5, FromMinor(12550, "usd"))
+}
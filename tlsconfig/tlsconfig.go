@@ -0,0 +1,87 @@
+// Package tlsconfig sets up native TLS for the API server, either from a
+// certificate and key on disk or with certificates obtained automatically
+// over ACME, and provides the HTTP to HTTPS redirect.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Config describes where the server's certificate comes from
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// AutocertHosts enables ACME for the listed hostnames when no cert file is set
+	AutocertHosts    []string
+	AutocertCacheDir string
+	AutocertEmail    string
+}
+
+// Enabled reports whether TLS has been configured at all
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || len(c.AutocertHosts) > 0
+}
+
+// Load returns the server's TLS configuration. For ACME it also returns the
+// manager whose HTTPHandler must answer challenges on port 80; it is nil when
+// certificates are loaded from disk.
+func (c Config) Load() (*tls.Config, *autocert.Manager, error) {
+	if c.CertFile != "" || c.KeyFile != "" {
+		if len(c.AutocertHosts) > 0 {
+			return nil, nil, errors.New("configure either a certificate file or autocert hosts, not both")
+		}
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, nil, errors.New("both a certificate file and a key file are required")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}, nil, nil
+	}
+	if len(c.AutocertHosts) == 0 {
+		return nil, nil, errors.New("TLS is not configured")
+	}
+
+	cacheDir := c.AutocertCacheDir
+	if cacheDir == "" {
+		cacheDir = "certs"
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(c.AutocertHosts...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      c.AutocertEmail,
+	}
+	cfg := m.TLSConfig()
+	cfg.MinVersion = tls.VersionTLS12
+	return cfg, m, nil
+}
+
+// RedirectHandler sends every plain HTTP request to the same host and path
+// over HTTPS on httpsPort. GET and HEAD requests get a 301; other methods get
+// a 308 so clients repeat them with the same body.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
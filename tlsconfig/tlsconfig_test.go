@@ -0,0 +1,65 @@
+package tlsconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		wantEnabled bool
+		wantErr     bool
+		wantManager bool
+	}{
+		{name: "not configured", cfg: Config{}, wantErr: true},
+		{name: "key without cert", cfg: Config{KeyFile: "server.key"}, wantEnabled: true, wantErr: true},
+		{name: "missing files", cfg: Config{CertFile: "missing.crt", KeyFile: "missing.key"}, wantEnabled: true, wantErr: true},
+		{name: "files and autocert", cfg: Config{CertFile: "server.crt", KeyFile: "server.key", AutocertHosts: []string{"ledger.example.com"}}, wantEnabled: true, wantErr: true},
+		{name: "autocert", cfg: Config{AutocertHosts: []string{"ledger.example.com"}, AutocertCacheDir: t.TempDir()}, wantEnabled: true, wantManager: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantEnabled, tt.cfg.Enabled())
+			cfg, manager, err := tt.cfg.Load()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, cfg.GetCertificate)
+			assert.Equal(t, tt.wantManager, manager != nil)
+		})
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		httpsPort    string
+		method       string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "get on default port", httpsPort: "443", method: "GET", target: "http://ledger.example.com/v1/customers?page=2", wantStatus: http.StatusMovedPermanently, wantLocation: "https://ledger.example.com/v1/customers?page=2"},
+		{name: "post keeps method", httpsPort: "443", method: "POST", target: "http://ledger.example.com:80/v1/transactions", wantStatus: http.StatusPermanentRedirect, wantLocation: "https://ledger.example.com/v1/transactions"},
+		{name: "custom port", httpsPort: "8443", method: "GET", target: "http://localhost:8080/health", wantStatus: http.StatusMovedPermanently, wantLocation: "https://localhost:8443/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			RedirectHandler(tt.httpsPort).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package txtype holds the registry of transaction types. Each type carries
+// its business meaning in its code and maps to the direction it moves the
+// customer's balance.
+package txtype
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Direction is the way a transaction type moves the balance
+type Direction string
+
+const (
+	Credit Direction = "credit"
+	Debit  Direction = "debit"
+)
+
+// Type describes one registered transaction type
+type Type struct {
+	Code        string    `json:"code" example:"purchase"`
+	Direction   Direction `json:"direction" example:"debit" enums:"credit,debit"`
+	Description string    `json:"description" example:"Card or merchant purchase"`
+	// Postable types can be posted directly through the transactions API;
+	// the rest are only written by internal flows such as transfers
+	Postable bool `json:"postable" example:"true"`
+	// GLAccount is the general ledger account posted on the other side of
+	// the customer's balance, for types whose money comes from or goes to
+	// the bank itself rather than another customer
+	GLAccount string `json:"gl_account,omitempty" example:"fees_income"`
+}
+
+// Builtin lists the types every ledger starts with
+var Builtin = []Type{
+	{Code: "credit", Direction: Credit, Description: "Generic credit", Postable: true},
+	{Code: "debit", Direction: Debit, Description: "Generic debit", Postable: true},
+	{Code: "purchase", Direction: Debit, Description: "Card or merchant purchase", Postable: true},
+	{Code: "refund", Direction: Credit, Description: "Refund of an earlier purchase", Postable: true},
+	{Code: "fee", Direction: Debit, Description: "Fee charged to the customer", Postable: true, GLAccount: "fees_income"},
+	{Code: "interest", Direction: Credit, Description: "Interest paid to the customer", Postable: true, GLAccount: "interest_expense"},
+	{Code: "adjustment_credit", Direction: Credit, Description: "Manual correction increasing the balance", GLAccount: "suspense"},
+	{Code: "adjustment_debit", Direction: Debit, Description: "Manual correction decreasing the balance", GLAccount: "suspense"},
+	{Code: "transfer_in", Direction: Credit, Description: "Incoming transfer from another customer"},
+	{Code: "transfer_out", Direction: Debit, Description: "Outgoing transfer to another customer"},
+	{Code: "transfer_release", Direction: Credit, Description: "Reserved transfer released back to the payer"},
+	{Code: "reversal_credit", Direction: Credit, Description: "Reversal of a debit whose saga failed"},
+	{Code: "reversal_debit", Direction: Debit, Description: "Reversal of a credit whose saga failed"},
+	{Code: "bank_credit", Direction: Credit, Description: "Money into a linked bank account, mirrored from the bank", Postable: true},
+	{Code: "bank_debit", Direction: Debit, Description: "Money out of a linked bank account, mirrored from the bank", Postable: true},
+	{Code: "withdrawal", Direction: Debit, Description: "Withdrawal paid out to the customer's bank through Stripe", Postable: true},
+	{Code: "card_payment", Direction: Credit, Description: "Card payment collected through Stripe", Postable: true},
+	{Code: "credit_transfer", Direction: Debit, Description: "Credit transfer to an external bank account, sent in a payment file", Postable: true},
+	{Code: "move_in", Direction: Credit, Description: "Move from one of the customer's sub-accounts"},
+	{Code: "move_out", Direction: Debit, Description: "Move to one of the customer's sub-accounts"},
+	{Code: "loan_disbursement", Direction: Credit, Description: "Loan principal paid out to the customer", GLAccount: "loans_receivable"},
+	{Code: "loan_repayment", Direction: Debit, Description: "Repayment of loan principal", GLAccount: "loans_receivable"},
+	{Code: "loan_interest", Direction: Debit, Description: "Interest charged on a loan", GLAccount: "interest_income"},
+}
+
+var codePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,19}$`)
+
+// Validate reports whether t can be registered
+func (t Type) Validate() error {
+	if !codePattern.MatchString(t.Code) {
+		return fmt.Errorf("code must be 2-20 lowercase letters, digits or underscores")
+	}
+	if t.Direction != Credit && t.Direction != Debit {
+		return fmt.Errorf("direction must be credit or debit")
+	}
+	return nil
+}
+
+// Registry is a concurrency-safe set of transaction types keyed by code
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]Type
+}
+
+// NewRegistry returns a registry holding the given types
+func NewRegistry(types ...Type) *Registry {
+	r := &Registry{types: map[string]Type{}}
+	for _, t := range types {
+		r.types[t.Code] = t
+	}
+	return r
+}
+
+// Default returns a registry holding the builtin types
+func Default() *Registry {
+	return NewRegistry(Builtin...)
+}
+
+// Lookup returns the type registered under code
+func (r *Registry) Lookup(code string) (Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[code]
+	return t, ok
+}
+
+// Register adds or replaces a type
+func (r *Registry) Register(t Type) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[t.Code] = t
+	return nil
+}
+
+// List returns every registered type ordered by code
+func (r *Registry) List() []Type {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]Type, 0, len(r.types))
+	for _, t := range r.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Code < types[j].Code })
+	return types
+}
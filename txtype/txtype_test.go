@@ -0,0 +1,57 @@
+package txtype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	r := Default()
+
+	purchase, ok := r.Lookup("purchase")
+	assert.True(t, ok)
+	assert.Equal(t, Debit, purchase.Direction)
+	assert.True(t, purchase.Postable)
+
+	transferIn, ok := r.Lookup("transfer_in")
+	assert.True(t, ok)
+	assert.Equal(t, Credit, transferIn.Direction)
+	assert.False(t, transferIn.Postable)
+	assert.Empty(t, transferIn.GLAccount)
+
+	fee, ok := r.Lookup("fee")
+	assert.True(t, ok)
+	assert.Equal(t, "fees_income", fee.GLAccount)
+
+	_, ok = r.Lookup("bogus")
+	assert.False(t, ok)
+	assert.Len(t, r.List(), len(Builtin))
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     Type
+		wantErr bool
+	}{
+		{name: "valid", typ: Type{Code: "cashback", Direction: Credit, Postable: true}},
+		{name: "bad code", typ: Type{Code: "Cash Back", Direction: Credit}, wantErr: true},
+		{name: "bad direction", typ: Type{Code: "cashback", Direction: "sideways"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			err := r.Register(tt.typ)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			got, ok := r.Lookup(tt.typ.Code)
+			assert.True(t, ok)
+			assert.Equal(t, tt.typ, got)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package webhook signs ledger events and delivers them to subscriber
+// endpoints over HTTP
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ledger-service/events"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	IDHeader        = "X-Webhook-ID"
+)
+
+// secretPrefix marks webhook signing secrets so they are easy to spot in logs
+// and secret scanners
+const secretPrefix = "whsec_"
+
+// NewSecret generates a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// ValidateURL checks that raw is an absolute http or https URL a delivery
+// can be posted to
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url must use http or https")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	return nil
+}
+
+// Sign returns the signature header value for body sent at ts:
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + mac(secret, t, body)
+}
+
+// Verify checks a signature header produced by Sign, rejecting timestamps
+// further than tolerance from now. A header may carry several v1
+// signatures, as a sender rolling its secret signs with both; one matching
+// is enough.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("malformed signature")
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("signature timestamp outside tolerance")
+	}
+	expected := []byte(mac(secret, t, body))
+	for _, v1 := range signatures {
+		if hmac.Equal([]byte(v1), expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature mismatch")
+}
+
+func mac(secret, t string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Delivery reports the outcome of one delivery attempt
+type Delivery struct {
+	Delivered  bool   `json:"delivered" example:"true"`
+	StatusCode int    `json:"status_code,omitempty" example:"200"`
+	DurationMS int64  `json:"duration_ms" example:"84"`
+	Error      string `json:"error,omitempty" example:"endpoint returned status 500"`
+}
+
+// Sender posts signed events to subscriber endpoints
+type Sender struct {
+	Client *http.Client
+	Now    func() time.Time
+}
+
+// NewSender creates a sender whose requests time out after timeout
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{Client: &http.Client{Timeout: timeout}, Now: time.Now}
+}
+
+// Send posts ev to endpoint, signed with secret. Any 2xx response counts as
+// delivered.
+func (s *Sender) Send(ctx context.Context, endpoint, secret string, ev events.Event) Delivery {
+	start := s.Now()
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return Delivery{Error: fmt.Sprintf("failed to encode event: %v", err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Delivery{Error: fmt.Sprintf("failed to build request: %v", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ledger-service-webhooks/1.0")
+	req.Header.Set(IDHeader, ev.ID.String())
+	req.Header.Set(EventHeader, ev.Type)
+	req.Header.Set(SignatureHeader, Sign(secret, start, body))
+
+	resp, err := s.Client.Do(req)
+	d := Delivery{DurationMS: s.Now().Sub(start).Milliseconds()}
+	if err != nil {
+		d.Error = fmt.Sprintf("failed to call endpoint: %v", err)
+		return d
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	d.StatusCode = resp.StatusCode
+	d.Delivered = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !d.Delivered {
+		d.Error = fmt.Sprintf("endpoint returned status %d", resp.StatusCode)
+	}
+	return d
+}